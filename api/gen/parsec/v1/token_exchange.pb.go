@@ -176,9 +176,13 @@ type ExchangeResponse struct {
 	// OPTIONAL. Scope of the issued security token.
 	Scope string `protobuf:"bytes,5,opt,name=scope,proto3" json:"scope,omitempty"`
 	// OPTIONAL. A refresh token (typically not issued for transaction tokens).
-	RefreshToken  string `protobuf:"bytes,6,opt,name=refresh_token,json=refreshToken,proto3" json:"refresh_token,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	RefreshToken string `protobuf:"bytes,6,opt,name=refresh_token,json=refreshToken,proto3" json:"refresh_token,omitempty"`
+	// OPTIONAL. Names of request_context claims dropped because the actor is
+	// not permitted to assert them. Only set when the server is configured to
+	// report rejected claims.
+	RejectedClaims []string `protobuf:"bytes,7,rep,name=rejected_claims,json=rejectedClaims,proto3" json:"rejected_claims,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *ExchangeResponse) Reset() {
@@ -253,6 +257,13 @@ func (x *ExchangeResponse) GetRefreshToken() string {
 	return ""
 }
 
+func (x *ExchangeResponse) GetRejectedClaims() []string {
+	if x != nil {
+		return x.RejectedClaims
+	}
+	return nil
+}
+
 var File_parsec_v1_token_exchange_proto protoreflect.FileDescriptor
 
 const file_parsec_v1_token_exchange_proto_rawDesc = "" +
@@ -271,7 +282,7 @@ const file_parsec_v1_token_exchange_proto_rawDesc = "" +
 	"actorToken\x12(\n" +
 	"\x10actor_token_type\x18\t \x01(\tR\x0eactorTokenType\x12'\n" +
 	"\x0frequest_context\x18\n" +
-	" \x01(\tR\x0erequestContext\"\xda\x01\n" +
+	" \x01(\tR\x0erequestContext\"\x83\x02\n" +
 	"\x10ExchangeResponse\x12!\n" +
 	"\faccess_token\x18\x01 \x01(\tR\vaccessToken\x12*\n" +
 	"\x11issued_token_type\x18\x02 \x01(\tR\x0fissuedTokenType\x12\x1d\n" +
@@ -280,7 +291,8 @@ const file_parsec_v1_token_exchange_proto_rawDesc = "" +
 	"\n" +
 	"expires_in\x18\x04 \x01(\x03R\texpiresIn\x12\x14\n" +
 	"\x05scope\x18\x05 \x01(\tR\x05scope\x12#\n" +
-	"\rrefresh_token\x18\x06 \x01(\tR\frefreshToken2q\n" +
+	"\rrefresh_token\x18\x06 \x01(\tR\frefreshToken\x12'\n" +
+	"\x0frejected_claims\x18\a \x03(\tR\x0erejectedClaims2q\n" +
 	"\x14TokenExchangeService\x12Y\n" +
 	"\bExchange\x12\x1a.parsec.v1.ExchangeRequest\x1a\x1b.parsec.v1.ExchangeResponse\"\x14\x82\xd3\xe4\x93\x02\x0e:\x01*\"\t/v1/tokenB=Z;github.com/project-kessel/parsec/api/gen/parsec/v1;parsecv1b\x06proto3"
 
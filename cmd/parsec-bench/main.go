@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/project-kessel/parsec/internal/bench"
+)
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// loadFlags are the flags shared by every target
+type loadFlags struct {
+	concurrency  int
+	duration     time.Duration
+	requests     int
+	warmup       int
+	minRPS       float64
+	maxP99       time.Duration
+	maxErrorRate float64
+}
+
+func (f *loadFlags) register(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&f.concurrency, "concurrency", 10, "requests kept in flight")
+	cmd.Flags().DurationVar(&f.duration, "duration", 10*time.Second, "how long to send requests (0 to send --requests)")
+	cmd.Flags().IntVar(&f.requests, "requests", 0, "number of requests to send (0 for no limit)")
+	cmd.Flags().IntVar(&f.warmup, "warmup", 10, "requests sent before measuring")
+	cmd.Flags().Float64Var(&f.minRPS, "min-rps", 0, "fail unless throughput reaches this many req/s")
+	cmd.Flags().DurationVar(&f.maxP99, "max-p99", 0, "fail if p99 latency exceeds this")
+	cmd.Flags().Float64Var(&f.maxErrorRate, "max-error-rate", 0, "fail if more than this fraction of requests fail")
+}
+
+// run sends the load, prints the report and checks the targets
+func (f *loadFlags) run(cmd *cobra.Command, request bench.Request) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	report, err := bench.Run(ctx, bench.Config{
+		Concurrency: f.concurrency,
+		Duration:    f.duration,
+		Requests:    f.requests,
+		Warmup:      f.warmup,
+	}, request)
+	if err != nil {
+		return err
+	}
+	report.Print(cmd.OutOrStdout())
+
+	return bench.Targets{
+		MinThroughput: f.minRPS,
+		MaxP99:        f.maxP99,
+		MaxErrorRate:  f.maxErrorRate,
+	}.Check(report)
+}
+
+func newRootCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "parsec-bench",
+		Short: "Load test a running parsec",
+		Long: `parsec-bench sends token exchange or ext_authz Check requests to a running
+parsec and reports the throughput and latency it sustains.
+
+Run parsec with the configuration under test, e.g.
+configs/examples/parsec-bench.yaml (signed tokens, CEL mappers and a Lua data
+source) or parsec-bench-stub.yaml (stub issuer and mappers), then point
+parsec-bench at it. Targets (--min-rps, --max-p99, --max-error-rate) make the
+command fail when missed, so it can gate CI.
+
+Examples:
+  # Exchange tokens for 30s with 50 requests in flight
+  parsec-bench exchange --url http://localhost:8080 --subject-token user-token --duration 30s --concurrency 50
+
+  # Check requests, failing below 2000 req/s or above 20ms p99
+  parsec-bench check --address localhost:9090 --token user-token --min-rps 2000 --max-p99 20ms`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	cmd.AddCommand(newExchangeCmd())
+	cmd.AddCommand(newCheckCmd())
+	return cmd
+}
+
+func newExchangeCmd() *cobra.Command {
+	var (
+		load   loadFlags
+		target bench.ExchangeTarget
+	)
+	cmd := &cobra.Command{
+		Use:   "exchange",
+		Short: "Load test token exchange (POST /v1/token)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			request, err := bench.NewExchangeRequest(target)
+			if err != nil {
+				return err
+			}
+			return load.run(cmd, request)
+		},
+	}
+	load.register(cmd)
+	cmd.Flags().StringVar(&target.URL, "url", "http://localhost:8080", "parsec HTTP base URL")
+	cmd.Flags().StringVar(&target.SubjectToken, "subject-token", "", "subject token to exchange (required)")
+	cmd.Flags().StringVar(&target.SubjectTokenType, "subject-token-type", "", "subject token type (default: access token)")
+	cmd.Flags().StringVar(&target.RequestedTokenType, "requested-token-type", "", "requested token type (default: transaction token)")
+	cmd.Flags().StringVar(&target.Audience, "audience", "", "audience of the requested token")
+	cmd.Flags().StringVar(&target.ActorToken, "actor-token", "", "bearer token authenticating the caller")
+	return cmd
+}
+
+func newCheckCmd() *cobra.Command {
+	var (
+		load   loadFlags
+		target bench.CheckTarget
+	)
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Load test ext_authz Check (gRPC)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			request, closeConn, err := bench.NewCheckRequest(target)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = closeConn() }()
+			return load.run(cmd, request)
+		},
+	}
+	load.register(cmd)
+	cmd.Flags().StringVar(&target.Address, "address", "localhost:9090", "parsec gRPC address")
+	cmd.Flags().StringVar(&target.Token, "token", "", "bearer token of the checked request (required)")
+	cmd.Flags().StringVar(&target.Method, "method", "GET", "method of the checked request")
+	cmd.Flags().StringVar(&target.Path, "path", "/", "path of the checked request")
+	cmd.Flags().StringVar(&target.Host, "host", "", "host of the checked request")
+	return cmd
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/project-kessel/parsec/pkg/client"
+)
+
+// exchangeFlags are the flags of the exchange command
+type exchangeFlags struct {
+	url                string
+	subjectToken       string
+	subjectTokenType   string
+	requestedTokenType string
+	audience           string
+	scope              string
+	resource           string
+	requestContext     string
+	actorToken         string
+	actorTokenFile     string
+	output             string
+	timeout            time.Duration
+}
+
+func newExchangeCmd() *cobra.Command {
+	var flags exchangeFlags
+	cmd := &cobra.Command{
+		Use:   "exchange",
+		Short: "Exchange a subject token for a parsec-issued token",
+		Long: `Performs an RFC 8693 token exchange (POST /v1/token) against a running parsec.
+
+By default the response is printed as JSON together with the issued token's
+decoded (unverified) claims. --output token prints only the issued token, for
+piping into "parsec-cli inspect" or a request header.
+
+Examples:
+  parsec-cli exchange --subject-token "$USER_TOKEN" --audience parsec.example.com \
+    --request-context '{"method":"GET","path":"/orders"}'
+
+  # Read the subject token from stdin
+  echo "$USER_TOKEN" | parsec-cli exchange --subject-token - --output token`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExchange(cmd, flags)
+		},
+	}
+	cmd.Flags().StringVar(&flags.url, "url", "http://localhost:8080", "parsec HTTP base URL")
+	cmd.Flags().StringVar(&flags.subjectToken, "subject-token", "", `subject token to exchange, or "-" to read it from stdin (required)`)
+	cmd.Flags().StringVar(&flags.subjectTokenType, "subject-token-type", "", "subject token type (default: access token)")
+	cmd.Flags().StringVar(&flags.requestedTokenType, "requested-token-type", "", "requested token type (default: transaction token)")
+	cmd.Flags().StringVar(&flags.audience, "audience", "", "audience (trust domain) of the requested token")
+	cmd.Flags().StringVar(&flags.scope, "scope", "", "space-separated scope of the requested token")
+	cmd.Flags().StringVar(&flags.resource, "resource", "", "resource the token is for")
+	cmd.Flags().StringVar(&flags.requestContext, "request-context", "", "request context as a JSON object")
+	cmd.Flags().StringVar(&flags.actorToken, "actor-token", "", "bearer token authenticating the caller")
+	cmd.Flags().StringVar(&flags.actorTokenFile, "actor-token-file", "", "file holding the bearer token authenticating the caller")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "json", "output format: json or token")
+	cmd.Flags().DurationVar(&flags.timeout, "timeout", 10*time.Second, "timeout of the exchange, including retries")
+	_ = cmd.MarkFlagRequired("subject-token")
+	cmd.MarkFlagsMutuallyExclusive("actor-token", "actor-token-file")
+	return cmd
+}
+
+func runExchange(cmd *cobra.Command, flags exchangeFlags) error {
+	if flags.output != "json" && flags.output != "token" {
+		return fmt.Errorf("unknown output format %q (expected json or token)", flags.output)
+	}
+	subjectToken, err := readToken(cmd, flags.subjectToken)
+	if err != nil {
+		return err
+	}
+
+	req := client.ExchangeRequest{
+		SubjectToken:       subjectToken,
+		SubjectTokenType:   flags.subjectTokenType,
+		RequestedTokenType: flags.requestedTokenType,
+		Audience:           flags.audience,
+		Scope:              flags.scope,
+		Resource:           flags.resource,
+	}
+	if flags.requestContext != "" {
+		if err := json.Unmarshal([]byte(flags.requestContext), &req.RequestContext); err != nil {
+			return fmt.Errorf("--request-context must be a JSON object: %w", err)
+		}
+	}
+
+	cfg := client.Config{URL: flags.url}
+	switch {
+	case flags.actorToken != "":
+		cfg.ActorCredentials = client.StaticToken(flags.actorToken)
+	case flags.actorTokenFile != "":
+		cfg.ActorCredentials = client.TokenFile(flags.actorTokenFile)
+	}
+	c, err := client.New(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := contextWithTimeout(cmd, flags.timeout)
+	defer cancel()
+	token, err := c.Exchange(ctx, req)
+	if err != nil {
+		return fmt.Errorf("exchange failed: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	if flags.output == "token" {
+		_, _ = fmt.Fprintln(out, token.Value)
+		return nil
+	}
+
+	result := map[string]any{
+		"access_token":      token.Value,
+		"issued_token_type": token.IssuedTokenType,
+		"token_type":        token.TokenType,
+		"expires_in":        int64(token.ExpiresIn / time.Second),
+	}
+	if token.Scope != "" {
+		result["scope"] = token.Scope
+	}
+	if len(token.RejectedClaims) > 0 {
+		result["rejected_claims"] = token.RejectedClaims
+	}
+	// Opaque tokens have no claims to show
+	if claims, err := token.Claims(); err == nil {
+		result["claims"] = claims.Raw
+	}
+	return printJSON(out, result)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/project-kessel/parsec/pkg/verify"
+)
+
+// inspectFlags are the flags of the inspect command
+type inspectFlags struct {
+	jwksURL     string
+	trustDomain string
+	issuer      string
+	types       []string
+	leeway      time.Duration
+	timeout     time.Duration
+}
+
+func newInspectCmd() *cobra.Command {
+	var flags inspectFlags
+	cmd := &cobra.Command{
+		Use:   "inspect [token]",
+		Short: "Decode and verify a parsec-issued token",
+		Long: `Decodes a JWT and prints its header and claims, with its timestamps in a
+readable form. The token is read from stdin when it is "-" or not given.
+
+With --jwks-url the token is also verified as a transaction token: its typ
+header, its signature against the published keys, its timestamps and its
+audience (--trust-domain, required with --jwks-url). The command fails when
+verification fails, after printing what it decoded.
+
+Examples:
+  parsec-cli inspect "$TXN_TOKEN"
+
+  parsec-cli inspect --jwks-url http://localhost:8080/v1/jwks.json \
+    --trust-domain parsec.example.com "$TXN_TOKEN"`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var token string
+			if len(args) > 0 {
+				token = args[0]
+			}
+			return runInspect(cmd, flags, token)
+		},
+	}
+	cmd.Flags().StringVar(&flags.jwksURL, "jwks-url", "", "verify the token against the keys published at this URL, e.g. http://localhost:8080/v1/jwks.json")
+	cmd.Flags().StringVar(&flags.trustDomain, "trust-domain", "", "audience the token must carry (required with --jwks-url)")
+	cmd.Flags().StringVar(&flags.issuer, "issuer", "", "issuer the token must carry (optional)")
+	cmd.Flags().StringSliceVar(&flags.types, "type", nil, "accepted typ header values (default: txntoken+jwt)")
+	cmd.Flags().DurationVar(&flags.leeway, "leeway", 0, "clock skew tolerated when checking exp, nbf and iat")
+	cmd.Flags().DurationVar(&flags.timeout, "timeout", 10*time.Second, "timeout of fetching the keys")
+	return cmd
+}
+
+func runInspect(cmd *cobra.Command, flags inspectFlags, value string) error {
+	if flags.jwksURL != "" && flags.trustDomain == "" {
+		return fmt.Errorf("--trust-domain is required with --jwks-url")
+	}
+	token, err := readToken(cmd, value)
+	if err != nil {
+		return err
+	}
+
+	header, err := decodeHeader(token)
+	if err != nil {
+		return err
+	}
+	claims, err := verify.ParseClaims(token)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	_, _ = fmt.Fprintln(out, "Header:")
+	if err := printJSON(out, header); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(out, "Claims:")
+	if err := printJSON(out, claims.Raw); err != nil {
+		return err
+	}
+	printTimes(out, claims, time.Now())
+
+	if flags.jwksURL == "" {
+		_, _ = fmt.Fprintln(out, "Verified:   no (use --jwks-url to verify the signature)")
+		return nil
+	}
+	verifier, err := verify.New(verify.Config{
+		JWKSURL:     flags.jwksURL,
+		TrustDomain: flags.trustDomain,
+		Issuer:      flags.issuer,
+		Types:       flags.types,
+		Leeway:      flags.leeway,
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := contextWithTimeout(cmd, flags.timeout)
+	defer cancel()
+	if _, err := verifier.Verify(ctx, token); err != nil {
+		_, _ = fmt.Fprintln(out, "Verified:   FAILED")
+		return fmt.Errorf("verification failed: %w", err)
+	}
+	_, _ = fmt.Fprintf(out, "Verified:   yes (keys from %s)\n", flags.jwksURL)
+	return nil
+}
+
+// decodeHeader decodes the JOSE header of a compact JWS
+func decodeHeader(token string) (map[string]any, error) {
+	segment, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, fmt.Errorf("token is not a JWT")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode token header: %w", err)
+	}
+	var header map[string]any
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("failed to decode token header: %w", err)
+	}
+	return header, nil
+}
+
+// printTimes prints the token's timestamps relative to now
+func printTimes(out io.Writer, claims *verify.Claims, now time.Time) {
+	for _, t := range []struct {
+		label string
+		at    time.Time
+	}{
+		{"Issued:", claims.IssuedAt},
+		{"Not before:", claims.NotBefore},
+		{"Expires:", claims.ExpiresAt},
+	} {
+		if t.at.IsZero() {
+			continue
+		}
+		_, _ = fmt.Fprintf(out, "%-11s %s (%s)\n", t.label, t.at.UTC().Format(time.RFC3339), relative(t.at, now))
+	}
+}
+
+// relative describes t relative to now, e.g. "in 4m30s" or "2m0s ago"
+func relative(t, now time.Time) string {
+	d := t.Sub(now).Round(time.Second)
+	if d >= 0 {
+		return "in " + d.String()
+	}
+	return (-d).String() + " ago"
+}
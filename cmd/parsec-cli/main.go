@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func newRootCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "parsec-cli",
+		Short: "Exchange and inspect parsec tokens",
+		Long: `parsec-cli performs token exchanges against a running parsec and decodes
+the tokens it issues, for debugging during development.
+
+Examples:
+  # Exchange a token and print the issued transaction token with its claims
+  parsec-cli exchange --url http://localhost:8080 --subject-token user-token --audience parsec.example.com
+
+  # Decode a token and verify it against parsec's published keys
+  parsec-cli exchange --subject-token user-token --output token |
+    parsec-cli inspect --jwks-url http://localhost:8080/v1/jwks.json --trust-domain parsec.example.com`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	cmd.AddCommand(newExchangeCmd())
+	cmd.AddCommand(newInspectCmd())
+	return cmd
+}
+
+// readToken returns value, or the token on stdin when value is "-" or empty
+func readToken(cmd *cobra.Command, value string) (string, error) {
+	if value != "" && value != "-" {
+		return value, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(cmd.InOrStdin(), 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read token from stdin: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("no token given")
+	}
+	return token, nil
+}
+
+// printJSON prints v as indented JSON
+func printJSON(out io.Writer, v any) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// contextWithTimeout derives a context for a command's requests
+func contextWithTimeout(cmd *cobra.Command, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+)
+
+// fakeParsec issues a signed transaction token for every exchange and
+// publishes its key
+func fakeParsec(t *testing.T) *httptest.Server {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := jwk.Import(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = public.Set(jwk.KeyIDKey, "key-1")
+	_ = public.Set(jwk.AlgorithmKey, jwa.ES256())
+	set := jwk.NewSet()
+	_ = set.AddKey(public)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(set)
+	})
+	mux.HandleFunc("POST /v1/token", func(w http.ResponseWriter, r *http.Request) {
+		var reqCtx map[string]any
+		_ = json.Unmarshal([]byte(r.FormValue("request_context")), &reqCtx)
+		token, err := jwt.NewBuilder().
+			Subject(r.FormValue("subject_token")).
+			Audience([]string{r.FormValue("audience")}).
+			IssuedAt(time.Now()).
+			Expiration(time.Now().Add(5*time.Minute)).
+			Claim("txn", "txn-1").
+			Claim("req_ctx", reqCtx).
+			Build()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		headers := jws.NewHeaders()
+		_ = headers.Set(jws.KeyIDKey, "key-1")
+		_ = headers.Set(jws.TypeKey, "txntoken+jwt")
+		signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256(), key, jws.WithProtectedHeaders(headers)))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"access_token":      string(signed),
+			"issued_token_type": "urn:ietf:params:oauth:token-type:txn_token",
+			"token_type":        "N_A",
+			"expires_in":        "300",
+		})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// run runs parsec-cli with args and stdin, returning its output
+func run(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+	cmd := newRootCmd()
+	var out bytes.Buffer
+	cmd.SetArgs(args)
+	cmd.SetIn(strings.NewReader(stdin))
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestExchangeAndInspect(t *testing.T) {
+	server := fakeParsec(t)
+
+	out, err := run(t, "", "exchange", "--url", server.URL, "--subject-token", "alice",
+		"--audience", "parsec.test", "--request-context", `{"path":"/orders"}`)
+	if err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+	var result struct {
+		AccessToken string         `json:"access_token"`
+		ExpiresIn   int64          `json:"expires_in"`
+		Claims      map[string]any `json:"claims"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", out, err)
+	}
+	if result.ExpiresIn != 300 || result.Claims["sub"] != "alice" {
+		t.Errorf("unexpected exchange output: %s", out)
+	}
+
+	token, err := run(t, "alice\n", "exchange", "--url", server.URL, "--subject-token", "-",
+		"--audience", "parsec.test", "--output", "token")
+	if err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+	token = strings.TrimSpace(token)
+	if strings.Count(token, ".") != 2 {
+		t.Fatalf("expected only the token, got %q", token)
+	}
+
+	t.Run("decodes without verifying", func(t *testing.T) {
+		out, err := run(t, "", "inspect", token)
+		if err != nil {
+			t.Fatalf("inspect failed: %v", err)
+		}
+		for _, want := range []string{`"typ": "txntoken+jwt"`, `"sub": "alice"`, "Expires:", "Verified:   no"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("expected %q in output:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("verifies against the JWKS", func(t *testing.T) {
+		out, err := run(t, token, "inspect", "--jwks-url", server.URL+"/v1/jwks.json", "--trust-domain", "parsec.test")
+		if err != nil {
+			t.Fatalf("expected the token to verify: %v\n%s", err, out)
+		}
+		if !strings.Contains(out, "Verified:   yes") {
+			t.Errorf("expected a verified token:\n%s", out)
+		}
+	})
+
+	t.Run("fails for another trust domain", func(t *testing.T) {
+		out, err := run(t, "", "inspect", "--jwks-url", server.URL+"/v1/jwks.json", "--trust-domain", "other.test", token)
+		if err == nil {
+			t.Fatal("expected verification to fail")
+		}
+		if !strings.Contains(out, `"sub": "alice"`) || !strings.Contains(out, "Verified:   FAILED") {
+			t.Errorf("expected the decoded token before the failure:\n%s", out)
+		}
+	})
+
+	t.Run("requires a trust domain to verify", func(t *testing.T) {
+		if _, err := run(t, "", "inspect", "--jwks-url", server.URL+"/v1/jwks.json", token); err == nil {
+			t.Error("expected --trust-domain to be required")
+		}
+	})
+}
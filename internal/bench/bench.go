@@ -0,0 +1,188 @@
+// Package bench drives load against a running parsec and summarizes the
+// throughput and latency it sustains
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request sends one request to the server under test
+type Request func(ctx context.Context) error
+
+// Config configures a load run
+type Config struct {
+	// Concurrency is the number of requests kept in flight (default: 1)
+	Concurrency int
+
+	// Duration bounds the run (optional when Requests is set)
+	Duration time.Duration
+
+	// Requests bounds the number of requests sent (optional when Duration is set)
+	Requests int
+
+	// Warmup is sent before measuring, e.g. to fill caches and start signers (optional)
+	Warmup int
+}
+
+// Report summarizes a load run
+type Report struct {
+	Requests int
+	Errors   int
+	Elapsed  time.Duration
+
+	// Latencies of all requests, sorted
+	Latencies []time.Duration
+
+	// FirstError is the first error a request returned, if any
+	FirstError error
+}
+
+// Run sends requests until the configured duration or number of requests is
+// reached, or ctx is done
+func Run(ctx context.Context, cfg Config, request Request) (*Report, error) {
+	if cfg.Duration <= 0 && cfg.Requests <= 0 {
+		return nil, fmt.Errorf("duration or requests is required")
+	}
+	concurrency := max(cfg.Concurrency, 1)
+
+	for range cfg.Warmup {
+		if err := request(ctx); err != nil {
+			return nil, fmt.Errorf("warmup request failed: %w", err)
+		}
+	}
+
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	var (
+		mu      sync.Mutex
+		report  Report
+		started int
+		wg      sync.WaitGroup
+	)
+	// next claims the next request, unless the run is over
+	next := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil || (cfg.Requests > 0 && started >= cfg.Requests) {
+			return false
+		}
+		started++
+		return true
+	}
+
+	start := time.Now()
+	for range concurrency {
+		wg.Go(func() {
+			var latencies []time.Duration
+			failed := 0
+			var firstErr error
+			for next() {
+				requestStart := time.Now()
+				err := request(ctx)
+				// Requests cut short by the end of the run are not counted
+				if err != nil && ctx.Err() != nil {
+					break
+				}
+				latencies = append(latencies, time.Since(requestStart))
+				if err != nil {
+					failed++
+					if firstErr == nil {
+						firstErr = err
+					}
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Latencies = append(report.Latencies, latencies...)
+			report.Errors += failed
+			if report.FirstError == nil {
+				report.FirstError = firstErr
+			}
+		})
+	}
+	wg.Wait()
+
+	report.Elapsed = time.Since(start)
+	report.Requests = len(report.Latencies)
+	slices.Sort(report.Latencies)
+	return &report, nil
+}
+
+// Throughput returns the requests completed per second
+func (r *Report) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Elapsed.Seconds()
+}
+
+// ErrorRate returns the fraction of requests that failed
+func (r *Report) ErrorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Requests)
+}
+
+// Percentile returns the latency below which p percent of requests completed
+func (r *Report) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(r.Latencies))*p/100+0.5) - 1
+	return r.Latencies[min(max(i, 0), len(r.Latencies)-1)]
+}
+
+// Print writes a human-readable summary
+func (r *Report) Print(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "requests:   %d (%d errors, %.2f%%)\n", r.Requests, r.Errors, 100*r.ErrorRate())
+	_, _ = fmt.Fprintf(w, "elapsed:    %s\n", r.Elapsed.Round(time.Millisecond))
+	_, _ = fmt.Fprintf(w, "throughput: %.1f req/s\n", r.Throughput())
+	_, _ = fmt.Fprintf(w, "latency:    p50 %s  p90 %s  p99 %s  max %s\n",
+		r.Percentile(50).Round(time.Microsecond), r.Percentile(90).Round(time.Microsecond),
+		r.Percentile(99).Round(time.Microsecond), r.Percentile(100).Round(time.Microsecond))
+	if r.FirstError != nil {
+		_, _ = fmt.Fprintf(w, "first error: %v\n", r.FirstError)
+	}
+}
+
+// Targets are the throughput and latency a run must reach
+type Targets struct {
+	// MinThroughput is the lowest acceptable req/s (0: not checked)
+	MinThroughput float64
+
+	// MaxP99 is the highest acceptable p99 latency (0: not checked)
+	MaxP99 time.Duration
+
+	// MaxErrorRate is the fraction of requests allowed to fail (default: none)
+	MaxErrorRate float64
+}
+
+// Check returns the targets the report misses, if any
+func (t Targets) Check(r *Report) error {
+	var missed []string
+	if t.MinThroughput > 0 && r.Throughput() < t.MinThroughput {
+		missed = append(missed, fmt.Sprintf("throughput %.1f req/s below %.1f", r.Throughput(), t.MinThroughput))
+	}
+	if t.MaxP99 > 0 && r.Percentile(99) > t.MaxP99 {
+		missed = append(missed, fmt.Sprintf("p99 latency %s above %s", r.Percentile(99), t.MaxP99))
+	}
+	if r.ErrorRate() > t.MaxErrorRate {
+		missed = append(missed, fmt.Sprintf("error rate %.2f%% above %.2f%%", 100*r.ErrorRate(), 100*t.MaxErrorRate))
+	}
+	if len(missed) > 0 {
+		return fmt.Errorf("missed targets: %s", strings.Join(missed, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	t.Run("sends the configured number of requests", func(t *testing.T) {
+		var sent atomic.Int32
+		report, err := Run(context.Background(), Config{Concurrency: 4, Requests: 100, Warmup: 5}, func(ctx context.Context) error {
+			sent.Add(1)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if report.Requests != 100 || len(report.Latencies) != 100 {
+			t.Errorf("expected 100 measured requests, got %d", report.Requests)
+		}
+		if n := sent.Load(); n != 105 {
+			t.Errorf("expected 105 requests including warmup, got %d", n)
+		}
+		if report.Errors != 0 || report.FirstError != nil {
+			t.Errorf("expected no errors, got %d: %v", report.Errors, report.FirstError)
+		}
+	})
+
+	t.Run("stops after the duration", func(t *testing.T) {
+		start := time.Now()
+		report, err := Run(context.Background(), Config{Concurrency: 2, Duration: 50 * time.Millisecond}, func(ctx context.Context) error {
+			select {
+			case <-time.After(time.Millisecond):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("expected the run to stop after its duration, took %s", elapsed)
+		}
+		if report.Requests == 0 {
+			t.Error("expected some requests")
+		}
+		if report.Errors != 0 {
+			t.Errorf("expected requests cut short by the end of the run not to count as errors, got %d", report.Errors)
+		}
+	})
+
+	t.Run("counts errors", func(t *testing.T) {
+		var n atomic.Int32
+		errBackend := errors.New("backend down")
+		report, err := Run(context.Background(), Config{Requests: 10}, func(ctx context.Context) error {
+			if n.Add(1)%2 == 0 {
+				return errBackend
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if report.Errors != 5 || report.ErrorRate() != 0.5 {
+			t.Errorf("expected 5 errors, got %d (%v)", report.Errors, report.ErrorRate())
+		}
+		if !errors.Is(report.FirstError, errBackend) {
+			t.Errorf("expected first error, got %v", report.FirstError)
+		}
+	})
+
+	t.Run("failed warmup aborts the run", func(t *testing.T) {
+		_, err := Run(context.Background(), Config{Requests: 10, Warmup: 1}, func(ctx context.Context) error {
+			return errors.New("connection refused")
+		})
+		if err == nil || !strings.Contains(err.Error(), "warmup") {
+			t.Errorf("expected warmup error, got %v", err)
+		}
+	})
+
+	t.Run("duration or requests is required", func(t *testing.T) {
+		if _, err := Run(context.Background(), Config{}, nil); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestReport(t *testing.T) {
+	report := &Report{Requests: 100, Errors: 2, Elapsed: 2 * time.Second}
+	for i := 1; i <= 100; i++ {
+		report.Latencies = append(report.Latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	if got := report.Throughput(); got != 50 {
+		t.Errorf("expected 50 req/s, got %v", got)
+	}
+	for p, want := range map[float64]time.Duration{
+		50:  50 * time.Millisecond,
+		99:  99 * time.Millisecond,
+		100: 100 * time.Millisecond,
+		0:   time.Millisecond,
+	} {
+		if got := report.Percentile(p); got != want {
+			t.Errorf("expected p%v %s, got %s", p, want, got)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		targets Targets
+		missed  []string
+	}{
+		{"met", Targets{MinThroughput: 40, MaxP99: 100 * time.Millisecond, MaxErrorRate: 0.05}, nil},
+		{"throughput", Targets{MinThroughput: 60, MaxErrorRate: 1}, []string{"throughput"}},
+		{"latency", Targets{MaxP99: 10 * time.Millisecond, MaxErrorRate: 1}, []string{"p99"}},
+		{"errors by default", Targets{}, []string{"error rate"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.targets.Check(report)
+			if len(tt.missed) == 0 {
+				if err != nil {
+					t.Errorf("expected targets met, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected missed targets")
+			}
+			for _, missed := range tt.missed {
+				if !strings.Contains(err.Error(), missed) {
+					t.Errorf("expected %q in %v", missed, err)
+				}
+			}
+		})
+	}
+}
+
+func TestNewExchangeRequest(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		got = r
+		if r.PostForm.Get("subject_token") != "user-token" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"txn"}`))
+	}))
+	defer srv.Close()
+
+	request, err := NewExchangeRequest(ExchangeTarget{
+		URL:          srv.URL + "/",
+		SubjectToken: "user-token",
+		Audience:     "parsec.test",
+		ActorToken:   "client-token",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := request(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.URL.Path != "/v1/token" {
+		t.Errorf("expected /v1/token, got %s", got.URL.Path)
+	}
+	if got.Header.Get("Authorization") != "Bearer client-token" {
+		t.Errorf("expected actor token, got %q", got.Header.Get("Authorization"))
+	}
+	if got.PostForm.Get("requested_token_type") != "urn:ietf:params:oauth:token-type:txn_token" ||
+		got.PostForm.Get("audience") != "parsec.test" {
+		t.Errorf("unexpected form %v", got.PostForm)
+	}
+
+	failing, err := NewExchangeRequest(ExchangeTarget{URL: srv.URL, SubjectToken: "other"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := failing(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("expected the error response, got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// ExchangeTarget configures token exchange requests (POST /v1/token)
+type ExchangeTarget struct {
+	// URL is the server's HTTP base URL, e.g. http://localhost:8080
+	URL string
+
+	// SubjectToken is exchanged in every request
+	SubjectToken string
+
+	// SubjectTokenType defaults to an access token
+	SubjectTokenType string
+
+	// RequestedTokenType defaults to a transaction token
+	RequestedTokenType string
+
+	// Audience of the requested token (optional)
+	Audience string
+
+	// ActorToken is sent as the bearer Authorization of the caller (optional)
+	ActorToken string
+
+	// Client sends the requests (defaults to a client keeping enough idle
+	// connections for the run's concurrency)
+	Client *http.Client
+}
+
+// NewExchangeRequest returns a Request exchanging the target's subject token
+func NewExchangeRequest(target ExchangeTarget) (Request, error) {
+	if target.URL == "" || target.SubjectToken == "" {
+		return nil, fmt.Errorf("url and subject token are required")
+	}
+	if target.SubjectTokenType == "" {
+		target.SubjectTokenType = "urn:ietf:params:oauth:token-type:access_token"
+	}
+	if target.RequestedTokenType == "" {
+		target.RequestedTokenType = "urn:ietf:params:oauth:token-type:txn_token"
+	}
+	client := target.Client
+	if client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConnsPerHost = 1024
+		client = &http.Client{Transport: transport}
+	}
+
+	form := url.Values{
+		"grant_type":           {"urn:ietf:params:oauth:grant-type:token-exchange"},
+		"subject_token":        {target.SubjectToken},
+		"subject_token_type":   {target.SubjectTokenType},
+		"requested_token_type": {target.RequestedTokenType},
+	}
+	if target.Audience != "" {
+		form.Set("audience", target.Audience)
+	}
+	body := form.Encode()
+	endpoint := strings.TrimSuffix(target.URL, "/") + "/v1/token"
+
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if target.ActorToken != "" {
+			req.Header.Set("Authorization", "Bearer "+target.ActorToken)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		respBody, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("token exchange returned %s: %s", resp.Status, respBody)
+		}
+		return nil
+	}, nil
+}
+
+// CheckTarget configures ext_authz Check requests
+type CheckTarget struct {
+	// Address is the server's gRPC address, e.g. localhost:9090
+	Address string
+
+	// Token is sent as the bearer Authorization of the checked request
+	Token string
+
+	// Method and Path of the checked request (default: GET /)
+	Method string
+	Path   string
+
+	// Host of the checked request (optional)
+	Host string
+}
+
+// NewCheckRequest returns a Request checking the target's request, and a
+// function closing its connection
+func NewCheckRequest(target CheckTarget) (Request, func() error, error) {
+	if target.Address == "" || target.Token == "" {
+		return nil, nil, fmt.Errorf("address and token are required")
+	}
+	if target.Method == "" {
+		target.Method = http.MethodGet
+	}
+	if target.Path == "" {
+		target.Path = "/"
+	}
+
+	conn, err := grpc.NewClient(target.Address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to %s: %w", target.Address, err)
+	}
+	client := authv3.NewAuthorizationClient(conn)
+
+	checkReq := &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{
+					Method: target.Method,
+					Path:   target.Path,
+					Host:   target.Host,
+					Headers: map[string]string{
+						"authorization": "Bearer " + target.Token,
+					},
+				},
+			},
+			Source: &authv3.AttributeContext_Peer{
+				Address: &corev3.Address{
+					Address: &corev3.Address_SocketAddress{
+						SocketAddress: &corev3.SocketAddress{Address: "127.0.0.1"},
+					},
+				},
+			},
+		},
+	}
+
+	return func(ctx context.Context) error {
+		resp, err := client.Check(ctx, checkReq)
+		if err != nil {
+			return err
+		}
+		if code := codes.Code(resp.GetStatus().GetCode()); code != codes.OK {
+			return fmt.Errorf("check denied with %s: %s", code, resp.GetStatus().GetMessage())
+		}
+		return nil
+	}, conn.Close, nil
+}
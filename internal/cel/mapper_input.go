@@ -22,6 +22,7 @@ type DataSourceRegistry interface {
 // This provides compile-time declarations for:
 //   - datasource(name) - function to fetch data from a named data source
 //   - subject, actor, request - variables containing identity and request data
+//   - risk - the risk assessment of the issuance (level and reasons), empty if there is none
 //
 // Pass nil for registry to create a test/validation environment.
 func MapperInputLibrary(ctx context.Context, registry *service.DataSourceRegistry, dsInput *service.DataSourceInput) cel.EnvOption {
@@ -54,6 +55,7 @@ func (lib *mapperInputLib) CompileOptions() []cel.EnvOption {
 		cel.Variable("subject", cel.DynType),
 		cel.Variable("actor", cel.DynType),
 		cel.Variable("request", cel.DynType),
+		cel.Variable("risk", cel.DynType),
 	}
 }
 
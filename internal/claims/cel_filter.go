@@ -0,0 +1,131 @@
+package claims
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+
+	"github.com/project-kessel/parsec/internal/trustdomain"
+)
+
+// ActorAwareClaimsFilter is a ClaimsFilter whose decisions depend on the calling actor
+// Registries bind the actor before filtering
+type ActorAwareClaimsFilter interface {
+	ClaimsFilter
+
+	// WithActor returns a filter bound to the given actor (as a map, e.g. a converted trust.Result)
+	WithActor(actor map[string]any) ClaimsFilter
+}
+
+// ClaimsFilterLibrary creates a CEL library for claim-level filter predicates.
+//
+// This provides compile-time declarations for:
+//   - actor - the calling actor as a map (subject, issuer, trust_domain, claims, etc.)
+//   - name - the claim name
+//   - value - the claim value
+//
+// Example expressions:
+//   - name in ["method", "path", "ip_address"]
+//   - actor.trust_domain == "gateways.internal" || !name.startsWith("internal_")
+//   - name != "user_agent" || size(string(value)) < 256
+func ClaimsFilterLibrary() cel.EnvOption {
+	return cel.Lib(&claimsFilterLib{})
+}
+
+type claimsFilterLib struct{}
+
+func (lib *claimsFilterLib) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		// trustDomainMatches(value, pattern)
+		trustdomain.CELLibrary(),
+		cel.Variable("actor", cel.DynType),
+		cel.Variable("name", cel.StringType),
+		cel.Variable("value", cel.DynType),
+	}
+}
+
+func (lib *claimsFilterLib) ProgramOptions() []cel.ProgramOption {
+	return []cel.ProgramOption{}
+}
+
+// CelClaimsFilter keeps each claim for which a CEL predicate evaluates to true.
+// Claims whose predicate fails to evaluate are dropped.
+type CelClaimsFilter struct {
+	program cel.Program
+	script  string
+	actor   map[string]any
+}
+
+// NewCelClaimsFilter creates a new CEL-based claims filter
+// The script should be a CEL expression that evaluates to a boolean
+func NewCelClaimsFilter(script string) (*CelClaimsFilter, error) {
+	if script == "" {
+		return nil, fmt.Errorf("CEL claims filter script cannot be empty")
+	}
+
+	env, err := cel.NewEnv(ClaimsFilterLibrary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(script)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("failed to compile CEL claims filter script: %w", issues.Err())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL program: %w", err)
+	}
+
+	return &CelClaimsFilter{
+		program: program,
+		script:  script,
+		actor:   map[string]any{},
+	}, nil
+}
+
+// WithActor implements ActorAwareClaimsFilter
+func (f *CelClaimsFilter) WithActor(actor map[string]any) ClaimsFilter {
+	if actor == nil {
+		actor = map[string]any{}
+	}
+	return &CelClaimsFilter{
+		program: f.program,
+		script:  f.script,
+		actor:   actor,
+	}
+}
+
+// Filter implements ClaimsFilter
+func (f *CelClaimsFilter) Filter(c Claims) Claims {
+	if c == nil {
+		return nil
+	}
+	filtered := make(Claims)
+	for key, value := range c {
+		if f.allows(key, value) {
+			filtered[key] = value
+		}
+	}
+	return filtered
+}
+
+// allows evaluates the predicate for a single claim
+func (f *CelClaimsFilter) allows(name string, value any) bool {
+	result, _, err := f.program.Eval(map[string]any{
+		"actor": f.actor,
+		"name":  name,
+		"value": value,
+	})
+	if err != nil {
+		return false
+	}
+	return result.Type() == types.BoolType && result.Value().(bool)
+}
+
+// Script returns the CEL script used by this filter
+func (f *CelClaimsFilter) Script() string {
+	return f.script
+}
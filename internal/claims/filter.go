@@ -3,6 +3,8 @@ package claims
 // ClaimsFilter defines which claims should be passed through from a credential
 type ClaimsFilter interface {
 	// Filter filters the claims, returning only those that should be passed through
+	// The result may be c itself when every claim passes, so callers must not
+	// modify the result or c afterwards.
 	Filter(c Claims) Claims
 }
 
@@ -23,17 +25,9 @@ func NewAllowListClaimsFilter(allowedClaims []string) *AllowListClaimsFilter {
 }
 
 // Filter implements ClaimsFilter
+// Returns c itself when every claim is allowed.
 func (f *AllowListClaimsFilter) Filter(c Claims) Claims {
-	if c == nil {
-		return nil
-	}
-	filtered := make(Claims)
-	for key, value := range c {
-		if f.allowedClaims[key] {
-			filtered[key] = value
-		}
-	}
-	return filtered
+	return c.keep(func(key string) bool { return f.allowedClaims[key] })
 }
 
 // DenyListClaimsFilter blocks claims in the deny list
@@ -53,23 +47,40 @@ func NewDenyListClaimsFilter(deniedClaims []string) *DenyListClaimsFilter {
 }
 
 // Filter implements ClaimsFilter
+// Returns c itself when no claim is denied.
 func (f *DenyListClaimsFilter) Filter(c Claims) Claims {
+	return c.keep(func(key string) bool { return !f.deniedClaims[key] })
+}
+
+// PassthroughClaimsFilter passes all claims through
+type PassthroughClaimsFilter struct{}
+
+// Filter implements ClaimsFilter
+// Returns c itself.
+func (f *PassthroughClaimsFilter) Filter(c Claims) Claims {
+	return c
+}
+
+// keep returns the claims whose key passes, copying c only if some do not
+func (c Claims) keep(pass func(key string) bool) Claims {
 	if c == nil {
 		return nil
 	}
-	filtered := make(Claims)
+	dropped := 0
+	for key := range c {
+		if !pass(key) {
+			dropped++
+		}
+	}
+	if dropped == 0 {
+		return c
+	}
+
+	filtered := make(Claims, len(c)-dropped)
 	for key, value := range c {
-		if !f.deniedClaims[key] {
+		if pass(key) {
 			filtered[key] = value
 		}
 	}
 	return filtered
 }
-
-// PassthroughClaimsFilter passes all claims through
-type PassthroughClaimsFilter struct{}
-
-// Filter implements ClaimsFilter
-func (f *PassthroughClaimsFilter) Filter(c Claims) Claims {
-	return c.Copy()
-}
@@ -0,0 +1,73 @@
+package claims
+
+import (
+	"reflect"
+	"testing"
+)
+
+func sameMap(a, b Claims) bool {
+	return reflect.ValueOf(a).UnsafePointer() == reflect.ValueOf(b).UnsafePointer()
+}
+
+func TestClaimsFilters(t *testing.T) {
+	input := Claims{"email": "alice@example.com", "role": "admin", "internal": "secret"}
+
+	tests := []struct {
+		name   string
+		filter ClaimsFilter
+		want   []string
+		copied bool
+	}{
+		{"passthrough", &PassthroughClaimsFilter{}, []string{"email", "role", "internal"}, false},
+		{"allow list keeping all", NewAllowListClaimsFilter([]string{"email", "role", "internal", "other"}), []string{"email", "role", "internal"}, false},
+		{"allow list dropping some", NewAllowListClaimsFilter([]string{"email"}), []string{"email"}, true},
+		{"deny list denying none present", NewDenyListClaimsFilter([]string{"other"}), []string{"email", "role", "internal"}, false},
+		{"deny list dropping some", NewDenyListClaimsFilter([]string{"internal"}), []string{"email", "role"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.filter.Filter(input)
+			if len(got) != len(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			for _, key := range tt.want {
+				if got[key] != input[key] {
+					t.Errorf("expected %s=%v, got %v", key, input[key], got[key])
+				}
+			}
+			if sameMap(got, input) == tt.copied {
+				t.Errorf("expected copied=%v", tt.copied)
+			}
+			if len(input) != 3 {
+				t.Errorf("filter modified its input: %v", input)
+			}
+		})
+	}
+
+	t.Run("nil claims", func(t *testing.T) {
+		if got := NewDenyListClaimsFilter([]string{"internal"}).Filter(nil); got != nil {
+			t.Errorf("expected nil, got %v", got)
+		}
+	})
+}
+
+func BenchmarkClaimsFilter(b *testing.B) {
+	input := Claims{"method": "GET", "path": "/api", "ip_address": "10.0.0.1", "user_agent": "curl"}
+	filters := []struct {
+		name   string
+		filter ClaimsFilter
+	}{
+		{"passthrough", &PassthroughClaimsFilter{}},
+		{"allow_all", NewAllowListClaimsFilter([]string{"method", "path", "ip_address", "user_agent"})},
+		{"allow_some", NewAllowListClaimsFilter([]string{"method", "path"})},
+		{"deny_none", NewDenyListClaimsFilter([]string{"headers"})},
+	}
+	for _, f := range filters {
+		b.Run(f.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				_ = f.filter.Filter(input)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package claims
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// ClaimsTransformer rewrites claims, e.g. to strip personal data before the claims
+// are embedded in a token. Transformers may modify and return the claims they are given.
+type ClaimsTransformer interface {
+	Transform(c Claims) (Claims, error)
+}
+
+// DefaultRedaction replaces redacted claim values
+const DefaultRedaction = "[REDACTED]"
+
+// RedactTransformer replaces the values of the named claims
+type RedactTransformer struct {
+	names       []string
+	replacement string
+}
+
+// NewRedactTransformer creates a transformer that replaces the named claims' values
+// with replacement (DefaultRedaction if empty). Absent claims are not added.
+func NewRedactTransformer(replacement string, names ...string) *RedactTransformer {
+	if replacement == "" {
+		replacement = DefaultRedaction
+	}
+	return &RedactTransformer{
+		names:       names,
+		replacement: replacement,
+	}
+}
+
+// Transform implements ClaimsTransformer
+func (t *RedactTransformer) Transform(c Claims) (Claims, error) {
+	for _, name := range t.names {
+		if _, ok := c[name]; ok {
+			c[name] = t.replacement
+		}
+	}
+	return c, nil
+}
+
+// HashTransformer replaces the values of the named claims with a salted hash
+//
+// The hash is a hex-encoded HMAC-SHA256 keyed by the salt, so equal values still
+// correlate across tokens while the original values cannot be recovered by
+// brute-forcing small input spaces (such as IPv4 addresses) without the salt.
+type HashTransformer struct {
+	names []string
+	salt  []byte
+}
+
+// NewHashTransformer creates a salted hashing transformer for the named claims
+func NewHashTransformer(salt []byte, names ...string) (*HashTransformer, error) {
+	if len(salt) == 0 {
+		return nil, fmt.Errorf("hash transformer requires a salt")
+	}
+	return &HashTransformer{
+		names: names,
+		salt:  salt,
+	}, nil
+}
+
+// Transform implements ClaimsTransformer
+// String values are hashed as-is; other values are hashed by their JSON encoding
+func (t *HashTransformer) Transform(c Claims) (Claims, error) {
+	for _, name := range t.names {
+		value, ok := c[name]
+		if !ok || value == nil {
+			continue
+		}
+
+		var data []byte
+		if s, ok := value.(string); ok {
+			data = []byte(s)
+		} else {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode claim %s for hashing: %w", name, err)
+			}
+			data = encoded
+		}
+
+		mac := hmac.New(sha256.New, t.salt)
+		mac.Write(data)
+		c[name] = hex.EncodeToString(mac.Sum(nil))
+	}
+	return c, nil
+}
+
+// TruncateTransformer shortens string claims to a maximum number of characters
+type TruncateTransformer struct {
+	names     []string
+	maxLength int
+}
+
+// NewTruncateTransformer creates a transformer that truncates the named string claims
+// to maxLength characters. Non-string values are left untouched.
+func NewTruncateTransformer(maxLength int, names ...string) (*TruncateTransformer, error) {
+	if maxLength <= 0 {
+		return nil, fmt.Errorf("truncate transformer requires a positive max length")
+	}
+	return &TruncateTransformer{
+		names:     names,
+		maxLength: maxLength,
+	}, nil
+}
+
+// Transform implements ClaimsTransformer
+func (t *TruncateTransformer) Transform(c Claims) (Claims, error) {
+	for _, name := range t.names {
+		s, ok := c[name].(string)
+		if !ok {
+			continue
+		}
+		if runes := []rune(s); len(runes) > t.maxLength {
+			c[name] = string(runes[:t.maxLength])
+		}
+	}
+	return c, nil
+}
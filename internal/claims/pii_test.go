@@ -0,0 +1,80 @@
+package claims
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestRedactTransformer(t *testing.T) {
+	c, err := NewRedactTransformer("", "email", "missing").Transform(Claims{"email": "a@example.com", "method": "GET"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c["email"] != DefaultRedaction {
+		t.Errorf("expected email redacted, got %v", c["email"])
+	}
+	if c["method"] != "GET" {
+		t.Errorf("expected method untouched, got %v", c["method"])
+	}
+	if c.Has("missing") {
+		t.Error("expected absent claim to stay absent")
+	}
+}
+
+func TestHashTransformer(t *testing.T) {
+	if _, err := NewHashTransformer(nil, "ip_address"); err == nil {
+		t.Fatal("expected error without salt")
+	}
+
+	transformer, err := NewHashTransformer([]byte("pepper"), "ip_address", "port")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c, err := transformer.Transform(Claims{"ip_address": "10.0.0.1", "port": 443})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte("pepper"))
+	mac.Write([]byte("10.0.0.1"))
+	if want := hex.EncodeToString(mac.Sum(nil)); c["ip_address"] != want {
+		t.Errorf("expected ip_address hash %s, got %v", want, c["ip_address"])
+	}
+
+	mac = hmac.New(sha256.New, []byte("pepper"))
+	mac.Write([]byte("443"))
+	if want := hex.EncodeToString(mac.Sum(nil)); c["port"] != want {
+		t.Errorf("expected port hashed by JSON encoding %s, got %v", want, c["port"])
+	}
+
+	other, _ := NewHashTransformer([]byte("salt"), "ip_address")
+	c2, _ := other.Transform(Claims{"ip_address": "10.0.0.1"})
+	if c2["ip_address"] == c["ip_address"] {
+		t.Error("expected different salts to produce different hashes")
+	}
+}
+
+func TestTruncateTransformer(t *testing.T) {
+	if _, err := NewTruncateTransformer(0, "user_agent"); err == nil {
+		t.Fatal("expected error for non-positive max length")
+	}
+
+	transformer, err := NewTruncateTransformer(4, "user_agent", "count")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c, err := transformer.Transform(Claims{"user_agent": "héllo wörld", "count": 123456})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c["user_agent"] != "héll" {
+		t.Errorf("expected rune-aware truncation, got %q", c["user_agent"])
+	}
+	if c["count"] != 123456 {
+		t.Errorf("expected non-string untouched, got %v", c["count"])
+	}
+}
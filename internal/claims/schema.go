@@ -0,0 +1,396 @@
+package claims
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema validates claims against a JSON Schema document.
+//
+// Only a subset of JSON Schema is supported: type, properties, required,
+// additionalProperties, items, enum, const, pattern, minLength, maxLength,
+// minimum, maximum, minItems and maxItems. The annotation keywords listed in
+// annotationKeywords are ignored. Any other keyword, at any depth, is rejected
+// when the schema is compiled, so a schema is never silently enforced more
+// loosely than written.
+type Schema struct {
+	types          []string
+	properties     map[string]*Schema
+	required       []string
+	additional     *Schema
+	denyAdditional bool
+	items          *Schema
+	enum           []any
+	constValue     any
+	hasConst       bool
+	pattern        *regexp.Regexp
+	minLength      *int
+	maxLength      *int
+	minimum        *float64
+	maximum        *float64
+	minItems       *int
+	maxItems       *int
+}
+
+// SchemaError lists every violation found while validating claims
+type SchemaError struct {
+	Violations []string
+}
+
+func (e *SchemaError) Error() string {
+	return "schema validation failed: " + strings.Join(e.Violations, "; ")
+}
+
+// annotationKeywords carry no validation semantics and are ignored
+var annotationKeywords = map[string]bool{
+	"$schema":     true,
+	"$id":         true,
+	"$comment":    true,
+	"title":       true,
+	"description": true,
+	"default":     true,
+	"examples":    true,
+}
+
+// ParseSchema compiles a JSON Schema from its JSON encoding
+func ParseSchema(data []byte) (*Schema, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse schema JSON: %w", err)
+	}
+	return CompileSchema(doc)
+}
+
+// CompileSchema compiles a JSON Schema from its decoded form
+func CompileSchema(doc map[string]any) (*Schema, error) {
+	return compileSchema(doc, "")
+}
+
+func compileSchema(doc map[string]any, path string) (*Schema, error) {
+	s := &Schema{}
+
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := doc[key]
+		where := path + "/" + key
+
+		var err error
+		switch key {
+		case "type":
+			s.types, err = compileTypes(value)
+		case "properties":
+			props, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be an object", where)
+			}
+			s.properties = make(map[string]*Schema, len(props))
+			for name, sub := range props {
+				s.properties[name], err = compileSubschema(sub, where+"/"+name)
+				if err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			s.required, err = toStrings(value)
+		case "additionalProperties":
+			if allowed, ok := value.(bool); ok {
+				s.denyAdditional = !allowed
+			} else {
+				s.additional, err = compileSubschema(value, where)
+			}
+		case "items":
+			s.items, err = compileSubschema(value, where)
+		case "enum":
+			values, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be an array", where)
+			}
+			s.enum = values
+		case "const":
+			s.constValue, s.hasConst = value, true
+		case "pattern":
+			pattern, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be a string", where)
+			}
+			s.pattern, err = regexp.Compile(pattern)
+		case "minLength":
+			s.minLength, err = toCount(value)
+		case "maxLength":
+			s.maxLength, err = toCount(value)
+		case "minItems":
+			s.minItems, err = toCount(value)
+		case "maxItems":
+			s.maxItems, err = toCount(value)
+		case "minimum":
+			s.minimum, err = toNumberPtr(value)
+		case "maximum":
+			s.maximum, err = toNumberPtr(value)
+		default:
+			if annotationKeywords[key] {
+				continue
+			}
+			return nil, fmt.Errorf("%s: unsupported schema keyword", where)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", where, err)
+		}
+	}
+
+	return s, nil
+}
+
+func compileSubschema(value any, path string) (*Schema, error) {
+	doc, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be a schema object", path)
+	}
+	return compileSchema(doc, path)
+}
+
+func compileTypes(value any) ([]string, error) {
+	var types []string
+	switch v := value.(type) {
+	case string:
+		types = []string{v}
+	default:
+		var err error
+		if types, err = toStrings(v); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range types {
+		switch t {
+		case "string", "number", "integer", "boolean", "object", "array", "null":
+		default:
+			return nil, fmt.Errorf("unknown type %q", t)
+		}
+	}
+	return types, nil
+}
+
+// Validate checks the claims against the schema
+// Returns a *SchemaError describing every violation
+func (s *Schema) Validate(c Claims) error {
+	var violations []string
+	s.validate(map[string]any(c), "", &violations)
+	if len(violations) > 0 {
+		return &SchemaError{Violations: violations}
+	}
+	return nil
+}
+
+func (s *Schema) validate(value any, path string, violations *[]string) {
+	report := func(format string, args ...any) {
+		where := path
+		if where == "" {
+			where = "/"
+		}
+		*violations = append(*violations, where+": "+fmt.Sprintf(format, args...))
+	}
+
+	if len(s.types) > 0 && !matchesAnyType(value, s.types) {
+		report("expected %s, got %s", strings.Join(s.types, " or "), jsonType(value))
+		return
+	}
+
+	if s.hasConst && !valuesEqual(value, s.constValue) {
+		report("must equal %v", s.constValue)
+	}
+	if len(s.enum) > 0 {
+		found := false
+		for _, allowed := range s.enum {
+			if valuesEqual(value, allowed) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			report("must be one of %v", s.enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.minLength != nil && length < *s.minLength {
+			report("must be at least %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			report("must be at most %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			report("must match pattern %q", s.pattern.String())
+		}
+	case map[string]any:
+		s.validateObject(v, path, violations, report)
+	case Claims:
+		s.validateObject(v, path, violations, report)
+	case []any:
+		if s.minItems != nil && len(v) < *s.minItems {
+			report("must have at least %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			report("must have at most %d items", *s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range v {
+				s.items.validate(item, fmt.Sprintf("%s/%d", path, i), violations)
+			}
+		}
+	default:
+		if n, ok := toNumber(value); ok {
+			if s.minimum != nil && n < *s.minimum {
+				report("must be >= %v", *s.minimum)
+			}
+			if s.maximum != nil && n > *s.maximum {
+				report("must be <= %v", *s.maximum)
+			}
+		}
+	}
+}
+
+func (s *Schema) validateObject(obj map[string]any, path string, violations *[]string, report func(string, ...any)) {
+	for _, name := range s.required {
+		if _, ok := obj[name]; !ok {
+			report("missing required property %q", name)
+		}
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		child := path + "/" + name
+		if prop, ok := s.properties[name]; ok {
+			prop.validate(obj[name], child, violations)
+			continue
+		}
+		if s.denyAdditional {
+			*violations = append(*violations, child+": property is not allowed")
+		} else if s.additional != nil {
+			s.additional.validate(obj[name], child, violations)
+		}
+	}
+}
+
+// matchesAnyType reports whether the value has one of the JSON types
+func matchesAnyType(value any, types []string) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual {
+			return true
+		}
+		if t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON Schema type name of a decoded value
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case map[string]any, Claims:
+		return "object"
+	case []any:
+		return "array"
+	default:
+		if n, ok := toNumber(v); ok {
+			if n == math.Trunc(n) && !math.IsInf(n, 0) {
+				return "integer"
+			}
+			return "number"
+		}
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// valuesEqual compares decoded JSON values, treating all numeric types alike
+func valuesEqual(a, b any) bool {
+	if na, ok := toNumber(a); ok {
+		nb, ok := toNumber(b)
+		return ok && na == nb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// toNumber converts any Go numeric type to float64
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func toNumberPtr(value any) (*float64, error) {
+	n, ok := toNumber(value)
+	if !ok {
+		return nil, fmt.Errorf("must be a number")
+	}
+	return &n, nil
+}
+
+func toCount(value any) (*int, error) {
+	n, ok := toNumber(value)
+	if !ok || n < 0 || n != math.Trunc(n) {
+		return nil, fmt.Errorf("must be a non-negative integer")
+	}
+	count := int(n)
+	return &count, nil
+}
+
+func toStrings(value any) ([]string, error) {
+	values, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("must be an array of strings")
+	}
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be an array of strings")
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
@@ -0,0 +1,95 @@
+package claims
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSchema_Validate(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "gateway request context",
+		"type": "object",
+		"required": ["method", "path"],
+		"additionalProperties": false,
+		"properties": {
+			"method": {"enum": ["GET", "POST"]},
+			"path": {"type": "string", "pattern": "^/", "maxLength": 64},
+			"port": {"type": "integer", "minimum": 1, "maximum": 65535},
+			"tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		claims  string
+		wantErr []string
+	}{
+		{name: "valid", claims: `{"method": "GET", "path": "/orders", "port": 443, "tags": ["a"]}`},
+		{name: "missing required", claims: `{"method": "GET"}`, wantErr: []string{`/: missing required property "path"`}},
+		{name: "enum", claims: `{"method": "DELETE", "path": "/"}`, wantErr: []string{"/method: must be one of"}},
+		{name: "pattern", claims: `{"method": "GET", "path": "orders"}`, wantErr: []string{"/path: must match pattern"}},
+		{name: "integer type", claims: `{"method": "GET", "path": "/", "port": 44.5}`, wantErr: []string{"/port: expected integer, got number"}},
+		{name: "maximum", claims: `{"method": "GET", "path": "/", "port": 70000}`, wantErr: []string{"/port: must be <= 65535"}},
+		{name: "array items", claims: `{"method": "GET", "path": "/", "tags": ["a", 1, "c"]}`, wantErr: []string{"/tags: must have at most 2 items", "/tags/1: expected string, got integer"}},
+		{name: "additional property", claims: `{"method": "GET", "path": "/", "junk": true}`, wantErr: []string{"/junk: property is not allowed"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Claims
+			if err := json.Unmarshal([]byte(tt.claims), &c); err != nil {
+				t.Fatalf("failed to parse claims: %v", err)
+			}
+
+			err := schema.Validate(c)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var schemaErr *SchemaError
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("expected SchemaError, got %v", err)
+			}
+			if len(schemaErr.Violations) != len(tt.wantErr) {
+				t.Fatalf("expected %d violations, got %v", len(tt.wantErr), schemaErr.Violations)
+			}
+			for i, want := range tt.wantErr {
+				if !strings.HasPrefix(schemaErr.Violations[i], want) {
+					t.Errorf("violation %d: expected prefix %q, got %q", i, want, schemaErr.Violations[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseSchema_RejectsUnsupportedKeywords(t *testing.T) {
+	_, err := ParseSchema([]byte(`{"type": "object", "properties": {"id": {"format": "uuid"}}}`))
+	if err == nil || !strings.Contains(err.Error(), "/properties/id/format: unsupported schema keyword") {
+		t.Fatalf("expected unsupported keyword error, got %v", err)
+	}
+
+	for _, doc := range []string{
+		`{"oneOf": [{"type": "string"}, {"type": "number"}]}`,
+		`{"$ref": "#/$defs/id"}`,
+		`{"items": {"uniqueItems": true}}`,
+		`{"additionalProperties": {"propertyNames": {"pattern": "^x-"}}}`,
+	} {
+		if _, err := ParseSchema([]byte(doc)); err == nil || !strings.Contains(err.Error(), "unsupported schema keyword") {
+			t.Errorf("expected unsupported keyword error for %s, got %v", doc, err)
+		}
+	}
+
+	_, err = ParseSchema([]byte(`{"type": "decimal"}`))
+	if err == nil || !strings.Contains(err.Error(), `unknown type "decimal"`) {
+		t.Fatalf("expected unknown type error, got %v", err)
+	}
+}
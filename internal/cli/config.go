@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/cobra"
+
+	"github.com/project-kessel/parsec/internal/config"
+)
+
+// NewConfigCmd creates the config command group
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect and migrate parsec configuration",
+	}
+
+	cmd.AddCommand(newConfigShowCmd())
+	cmd.AddCommand(newConfigMigrateCmd())
+
+	return cmd
+}
+
+// newConfigShowCmd creates the config show command
+func newConfigShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print the effective configuration with secrets redacted",
+		Long: `Print the effective configuration after merging the config file, its includes,
+overlays, environment variables, and flags, and resolving ${ENV_VAR} and
+secretRef references.
+
+Values resolved from secretRef, and values of sensitive keys (key, salt,
+api_key, client_secret, password, ...), are printed as [REDACTED].`,
+		RunE: runConfigShow,
+	}
+
+	config.RegisterFlags(cmd.Flags())
+
+	return cmd
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	loader, err := config.NewLoaderWithFlags(resolveConfigPath(), cmd.Flags(), resolveOverlays()...)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	out, err := yaml.Marshal(loader.Redacted())
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	_, err = cmd.OutOrStdout().Write(out)
+	return err
+}
+
+// newConfigMigrateCmd creates the config migrate command
+func newConfigMigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate [file]",
+		Short: "Rewrite a config file to the current schema version",
+		Long: `Rewrite a config file to the current schema version, renaming deprecated
+fields and dropping removed ones. Each rewritten field is listed on stderr.
+
+The file defaults to --config or PARSEC_CONFIG. Only that file is migrated;
+files it includes are left alone. The file keeps its format, but comments and
+key order are not preserved.
+
+Examples:
+  # Print the migrated config
+  parsec config migrate --config /etc/parsec/config.yaml
+
+  # Rewrite the file in place
+  parsec config migrate ./config.yaml --write`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runConfigMigrate,
+	}
+
+	cmd.Flags().BoolP("write", "w", false, "rewrite the file in place instead of printing it")
+
+	return cmd
+}
+
+func runConfigMigrate(cmd *cobra.Command, args []string) error {
+	path := resolveConfigPath()
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if path == "" {
+		return fmt.Errorf("no config file given (use an argument, --config, or PARSEC_CONFIG)")
+	}
+
+	out, deprecations, err := config.MigrateFile(path)
+	if err != nil {
+		return err
+	}
+	for _, deprecation := range deprecations {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "migrated %s\n", deprecation)
+	}
+
+	if write, _ := cmd.Flags().GetBool("write"); !write {
+		_, err = cmd.OutOrStdout().Write(out)
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write config file %s: %w", path, err)
+	}
+	_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "migrated %s to schema version %d\n", path, config.SchemaVersion)
+	return nil
+}
+
+// printDeprecations warns about deprecated fields found while loading config
+func printDeprecations(cmd *cobra.Command, loader *config.Loader) {
+	deprecations := loader.Deprecations()
+	for _, deprecation := range deprecations {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s\n", deprecation)
+	}
+	if len(deprecations) > 0 {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "warning: run 'parsec config migrate' to update config files to the current schema version")
+	}
+}
+
+// resolveConfigPath returns the config file from --config or PARSEC_CONFIG
+func resolveConfigPath() string {
+	if configFile != "" {
+		return configFile
+	}
+	return os.Getenv("PARSEC_CONFIG")
+}
+
+// resolveOverlays returns the overlay files from --overlay or PARSEC_CONFIG_OVERLAYS
+func resolveOverlays() []string {
+	if len(overlayFiles) > 0 {
+		return overlayFiles
+	}
+	var overlays []string
+	for _, path := range strings.Split(os.Getenv("PARSEC_CONFIG_OVERLAYS"), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			overlays = append(overlays, path)
+		}
+	}
+	return overlays
+}
@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/project-kessel/parsec/internal/config"
+	"github.com/project-kessel/parsec/internal/httpfixture"
+)
+
+// demoConfig is the configuration parsec demo serves
+//
+//go:embed demo.yaml
+var demoConfig []byte
+
+// demoIdPIssuer is the issuer of the demo IdP's JWKS fixture in demoConfig
+const demoIdPIssuer = "https://idp.parsec.demo"
+
+// NewDemoCmd creates the demo command
+func NewDemoCmd() *cobra.Command {
+	var (
+		subject     string
+		tokenExpiry time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "demo",
+		Short: "Start a self-contained parsec to try token exchange",
+		Long: `Start parsec with a built-in configuration that needs no other service:
+
+  - a fixture identity provider, whose keys sign a sample subject token
+  - sample data sources: a Lua script calling a fixture directory API, and
+    one computed in Lua
+  - CEL claim mappers building the transaction context from the subject's
+    claims and the data sources
+  - in-memory signing keys, published at /v1/jwks.json
+
+Once the server is up, the command prints the sample subject token together
+with curl and grpcurl commands exchanging it for a transaction token. The
+configuration is written next to the token so it can be copied and adapted;
+--overlay files are merged on top of it as with parsec serve.
+
+Examples:
+  # Start the demo
+  parsec demo
+
+  # Use other ports and a different subject
+  parsec demo --server-http-port 8081 --server-grpc-port 9091 --subject bob`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDemo(cmd, subject, tokenExpiry)
+		},
+	}
+
+	config.RegisterFlags(cmd.Flags())
+	cmd.Flags().StringVar(&subject, "subject", "alice", "subject (sub claim) of the sample subject token")
+	cmd.Flags().DurationVar(&tokenExpiry, "token-expiry", 24*time.Hour, "lifetime of the sample subject token")
+
+	return cmd
+}
+
+func runDemo(cmd *cobra.Command, subject string, tokenExpiry time.Duration) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir, err := os.MkdirTemp("", "parsec-demo-")
+	if err != nil {
+		return fmt.Errorf("failed to create demo directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	configPath := filepath.Join(dir, "parsec-demo.yaml")
+	if err := os.WriteFile(configPath, demoConfig, 0o600); err != nil {
+		return fmt.Errorf("failed to write demo config: %w", err)
+	}
+
+	loader, err := config.NewLoaderWithFlags(configPath, cmd.Flags(), resolveOverlays()...)
+	if err != nil {
+		return fmt.Errorf("failed to load demo config: %w", err)
+	}
+	cfg, err := loader.Get()
+	if err != nil {
+		return fmt.Errorf("failed to parse demo config: %w", err)
+	}
+
+	current, err := newServeInstance(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if err := current.start(ctx); err != nil {
+		return err
+	}
+	if err := current.warmup.Wait(ctx); err != nil {
+		_ = current.stop(ctx)
+		return fmt.Errorf("failed to warm trust store: %w", err)
+	}
+
+	token, err := mintDemoToken(current.provider, subject, tokenExpiry)
+	if err != nil {
+		_ = current.stop(ctx)
+		return err
+	}
+	tokenPath := filepath.Join(dir, "subject-token")
+	if err := os.WriteFile(tokenPath, []byte(token), 0o600); err != nil {
+		_ = current.stop(ctx)
+		return fmt.Errorf("failed to write subject token: %w", err)
+	}
+
+	printDemo(cmd.OutOrStdout(), demoEndpoints{
+		grpcPort:    current.serverCfg.GRPCPort,
+		httpPort:    current.serverCfg.HTTPPort,
+		trustDomain: current.provider.TrustDomain(),
+		configPath:  configPath,
+		tokenPath:   tokenPath,
+		subject:     subject,
+		token:       token,
+	})
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	<-sigCh
+
+	fmt.Println("\nShutting down...")
+	if err := current.stop(ctx); err != nil {
+		return fmt.Errorf("error during shutdown: %w", err)
+	}
+	return nil
+}
+
+// mintDemoToken signs a subject token with the demo IdP's fixture keys
+func mintDemoToken(provider *config.Provider, subject string, expiry time.Duration) (string, error) {
+	fixtures, ok := provider.HTTPFixtureProvider().(*httpfixture.CompositeFixtureProvider)
+	if !ok {
+		return "", fmt.Errorf("demo config has no fixtures")
+	}
+	idp := fixtures.JWKSFixture(demoIdPIssuer)
+	if idp == nil {
+		return "", fmt.Errorf("demo config has no jwks fixture for %s", demoIdPIssuer)
+	}
+
+	token, err := idp.CreateAndSignTokenWithExpiry(map[string]interface{}{
+		"sub":            subject,
+		"email":          subject + "@parsec.demo",
+		"email_verified": true,
+	}, idp.Clock().Now().Add(expiry))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign subject token: %w", err)
+	}
+	return token, nil
+}
+
+// demoEndpoints is what printDemo tells the user about the running demo
+type demoEndpoints struct {
+	grpcPort    int
+	httpPort    int
+	trustDomain string
+	configPath  string
+	tokenPath   string
+	subject     string
+	token       string
+}
+
+// printDemo prints the running demo's endpoints and commands to try them
+func printDemo(out io.Writer, d demoEndpoints) {
+	p := func(format string, args ...any) { _, _ = fmt.Fprintf(out, format, args...) }
+
+	p("parsec demo is running\n")
+	p("  gRPC (ext_authz, token exchange): localhost:%d\n", d.grpcPort)
+	p("  HTTP (token exchange):            http://localhost:%d/v1/token\n", d.httpPort)
+	p("  HTTP (JWKS):                      http://localhost:%d/v1/jwks.json\n", d.httpPort)
+	p("  Trust Domain:                     %s\n", d.trustDomain)
+	p("  Config:                           %s\n", d.configPath)
+	p("\n")
+	p("Subject token for %s, signed by the demo IdP (%s):\n", d.subject, demoIdPIssuer)
+	p("  %s\n", d.tokenPath)
+	p("\n")
+	p("Try it:\n")
+	p("\n")
+	p("  export SUBJECT_TOKEN=$(cat %s)\n", d.tokenPath)
+	p("\n")
+	p("  # Exchange the subject token for a transaction token\n")
+	p("  curl -s -X POST http://localhost:%d/v1/token \\\n", d.httpPort)
+	p("    -H \"Content-Type: application/x-www-form-urlencoded\" \\\n")
+	p("    -d \"grant_type=urn:ietf:params:oauth:grant-type:token-exchange\" \\\n")
+	p("    -d \"subject_token=$SUBJECT_TOKEN\" \\\n")
+	p("    -d \"subject_token_type=urn:ietf:params:oauth:token-type:jwt\" \\\n")
+	p("    -d \"audience=%s\"\n", d.trustDomain)
+	p("\n")
+	p("  # The same exchange over gRPC\n")
+	p("  grpcurl -plaintext -d \"{\\\"grant_type\\\": \\\"urn:ietf:params:oauth:grant-type:token-exchange\\\", \\\"subject_token\\\": \\\"$SUBJECT_TOKEN\\\", \\\"subject_token_type\\\": \\\"urn:ietf:params:oauth:token-type:jwt\\\", \\\"audience\\\": \\\"%s\\\"}\" \\\n", d.trustDomain)
+	p("    localhost:%d parsec.v1.TokenExchangeService/Exchange\n", d.grpcPort)
+	p("\n")
+	p("  # Authorize a request as Envoy's ext_authz filter would; the transaction\n")
+	p("  # token is returned in the Transaction-Token header\n")
+	p("  grpcurl -plaintext -d \"{\\\"attributes\\\": {\\\"request\\\": {\\\"http\\\": {\\\"method\\\": \\\"GET\\\", \\\"path\\\": \\\"/orders\\\", \\\"headers\\\": {\\\"authorization\\\": \\\"Bearer $SUBJECT_TOKEN\\\"}}}}}\" \\\n")
+	p("    localhost:%d envoy.service.auth.v3.Authorization/Check\n", d.grpcPort)
+	p("\n")
+	p("  # Decode and verify an issued token\n")
+	p("  go run ./cmd/parsec-cli exchange --url http://localhost:%d --subject-token \"$SUBJECT_TOKEN\" \\\n", d.httpPort)
+	p("    --subject-token-type urn:ietf:params:oauth:token-type:jwt --audience %s --output token |\n", d.trustDomain)
+	p("    go run ./cmd/parsec-cli inspect --jwks-url http://localhost:%d/v1/jwks.json --trust-domain %s\n", d.httpPort, d.trustDomain)
+	p("\n")
+	p("Press Ctrl+C to stop.\n")
+}
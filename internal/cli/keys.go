@@ -0,0 +1,277 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/project-kessel/parsec/internal/keys"
+)
+
+// adminFlags locate and authenticate to a running parsec's admin API
+type adminFlags struct {
+	url       string
+	token     string
+	tokenFile string
+	output    string
+	timeout   time.Duration
+}
+
+func (f *adminFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.url, "url", "http://localhost:8080", "parsec HTTP base URL")
+	cmd.Flags().StringVar(&f.token, "token", "", "bearer credential of an allowed admin caller")
+	cmd.Flags().StringVar(&f.tokenFile, "token-file", "", "file holding the bearer credential of an allowed admin caller")
+	cmd.Flags().StringVarP(&f.output, "output", "o", "table", "output format: table or json")
+	cmd.Flags().DurationVar(&f.timeout, "timeout", 30*time.Second, "timeout of the request")
+	cmd.MarkFlagsMutuallyExclusive("token", "token-file")
+}
+
+// NewKeysCmd creates the keys command group
+func NewKeysCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage the signing keys of a running parsec",
+		Long: `Inspect, rotate, and revoke the signing keys of a running parsec through its
+admin API (admin_server.enabled). Calls are authenticated with --token or
+--token-file unless the admin endpoints allow unauthenticated callers.
+migrate-store copies key slots between key slot stores directly instead.
+
+Each signer keeps its keys in two slots, A and B. A slot's key is pending
+(published, not yet signing), active (signing), retiring (published so tokens
+it signed still verify), or expired.`,
+	}
+
+	cmd.AddCommand(newKeysListCmd())
+	cmd.AddCommand(newKeysRotateCmd())
+	cmd.AddCommand(newKeysRevokeCmd())
+	cmd.AddCommand(newKeysMigrateStoreCmd())
+
+	return cmd
+}
+
+// newKeysListCmd creates the keys list command
+func newKeysListCmd() *cobra.Command {
+	var flags adminFlags
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Show the key slots of every signer, by namespace",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var resp keysListResponse
+			if err := flags.call(cmd, http.MethodGet, "/v1/admin/keys", nil, &resp); err != nil {
+				return err
+			}
+			if flags.output == "json" {
+				return writeIndentedJSON(cmd.OutOrStdout(), resp)
+			}
+			return printKeySlots(cmd.OutOrStdout(), resp.Signers, time.Now())
+		},
+	}
+	flags.register(cmd)
+	return cmd
+}
+
+// newKeysRotateCmd creates the keys rotate command
+func newKeysRotateCmd() *cobra.Command {
+	var flags adminFlags
+	cmd := &cobra.Command{
+		Use:   "rotate <signer>",
+		Short: "Generate a new key for a signer ahead of schedule",
+		Long: `Generate a new key for a signer now instead of when its rotation schedule
+would. The new key replaces the signer's oldest key, is published immediately,
+and signs tokens once its grace period ends.
+
+A rotation is refused when it would replace the key still signing because
+the newest key is in its grace period.
+
+Examples:
+  parsec keys rotate txn-signer --token-file /var/run/secrets/tokens/parsec-admin`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var resp keyChangeResponse
+			if err := flags.call(cmd, http.MethodPost, "/v1/admin/keys/"+url.PathEscape(args[0])+"/rotate", nil, &resp); err != nil {
+				return err
+			}
+			return flags.printKeyChange(cmd, "generated", resp)
+		},
+	}
+	flags.register(cmd)
+	return cmd
+}
+
+// newKeysRevokeCmd creates the keys revoke command
+func newKeysRevokeCmd() *cobra.Command {
+	var flags adminFlags
+	cmd := &cobra.Command{
+		Use:   "revoke <signer> <key-id>",
+		Short: "Replace a compromised signing key",
+		Long: `Replace the key with the given key ID (kid) by a new key, so that it is no
+longer published or used to sign tokens. Tokens it signed stop verifying
+once relying parties refresh parsec's JWKS.
+
+If the revoked key was signing, the signer's other key takes over when it
+can; otherwise the new key signs immediately.
+
+Examples:
+  parsec keys revoke txn-signer 3Jd9pZ... --token "$ADMIN_TOKEN"`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body, err := json.Marshal(map[string]string{"key_id": args[1]})
+			if err != nil {
+				return err
+			}
+			var resp keyChangeResponse
+			if err := flags.call(cmd, http.MethodPost, "/v1/admin/keys/"+url.PathEscape(args[0])+"/revoke", body, &resp); err != nil {
+				return err
+			}
+			return flags.printKeyChange(cmd, "revoked "+args[1]+", generated", resp)
+		},
+	}
+	flags.register(cmd)
+	return cmd
+}
+
+// keysListResponse is the admin API's list of key slots
+type keysListResponse struct {
+	Signers []signerKeySlots `json:"signers"`
+}
+
+type signerKeySlots struct {
+	Signer    string            `json:"signer"`
+	Namespace string            `json:"namespace"`
+	Slots     []keys.SlotStatus `json:"slots"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// keyChangeResponse is the admin API's report of a rotated or revoked key
+type keyChangeResponse struct {
+	Signer string          `json:"signer"`
+	Slot   keys.SlotStatus `json:"slot"`
+}
+
+// call sends an admin API request and decodes its JSON response into resp
+func (f *adminFlags) call(cmd *cobra.Command, method, path string, body []byte, resp any) error {
+	if f.output != "table" && f.output != "json" {
+		return fmt.Errorf("unknown output format %q (expected table or json)", f.output)
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), f.timeout)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(f.url, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	token := f.token
+	if f.tokenFile != "" {
+		data, err := os.ReadFile(f.tokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read token file: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	httpResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(httpResp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		var oauthErr struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if json.Unmarshal(data, &oauthErr) == nil && oauthErr.Error != "" {
+			return fmt.Errorf("%s: %s (%s)", httpResp.Status, oauthErr.Description, oauthErr.Error)
+		}
+		if httpResp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%s: is admin_server enabled?", httpResp.Status)
+		}
+		return fmt.Errorf("%s: %s", httpResp.Status, strings.TrimSpace(string(data)))
+	}
+	if err := json.Unmarshal(data, resp); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// printKeyChange prints the slot a rotation or revocation changed
+func (f *adminFlags) printKeyChange(cmd *cobra.Command, what string, resp keyChangeResponse) error {
+	out := cmd.OutOrStdout()
+	if f.output == "json" {
+		return writeIndentedJSON(out, resp)
+	}
+	slot := resp.Slot
+	_, err := fmt.Fprintf(out, "%s: %s key %s in slot %s (%s, signs from %s)\n",
+		resp.Signer, what, slot.KeyID, slot.Position, slot.State, slot.UsableAt.Local().Format(time.RFC3339))
+	return err
+}
+
+// printKeySlots prints a table of key slots grouped by namespace
+func printKeySlots(out io.Writer, signers []signerKeySlots, now time.Time) error {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAMESPACE\tSIGNER\tSLOT\tSTATE\tKID\tALG\tGENERATED\tROTATES\tEXPIRES")
+	for _, signer := range signers {
+		if signer.Error != "" {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t-\terror: %s\t\t\t\t\t\n", signer.Namespace, signer.Signer, signer.Error)
+			continue
+		}
+		for _, slot := range signer.Slots {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				signer.Namespace, signer.Signer, slot.Position, slot.State,
+				orDash(slot.KeyID), orDash(slot.Algorithm),
+				sinceOrUntil(slot.GeneratedAt, now), sinceOrUntil(slot.RotatesAt, now), sinceOrUntil(slot.ExpiresAt, now))
+		}
+	}
+	return tw.Flush()
+}
+
+// sinceOrUntil describes t relative to now, e.g. "3h ago" or "in 20m"
+func sinceOrUntil(t, now time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := t.Sub(now).Round(time.Second)
+	if d < 0 {
+		return (-d).String() + " ago"
+	}
+	return "in " + d.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func writeIndentedJSON(out io.Writer, v any) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
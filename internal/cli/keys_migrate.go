@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/project-kessel/parsec/internal/config"
+	"github.com/project-kessel/parsec/internal/keys"
+)
+
+// newKeysMigrateStoreCmd creates the keys migrate-store command
+func newKeysMigrateStoreCmd() *cobra.Command {
+	var from, to, output string
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "migrate-store",
+		Short: "Copy the key slots of rotating signers to another key slot store",
+		Long: `Copy the key slots of rotating signers from one key slot store to another, so
+signers keep their keys when key_slot_store changes instead of generating new
+ones. Stores are given as file:<path>, the file key slot store.
+
+Nothing is written when the destination holds different state for any slot:
+it is likely in use, and overwriting it could unpublish keys that still sign.
+After copying, the destination is read back to verify every slot. Stop parsec
+while its slots are migrated, then point key_slot_store at the destination.
+
+Unlike the other keys commands, migrate-store works on the stores directly,
+not through a running parsec.
+
+Examples:
+  parsec keys migrate-store --from file:/var/lib/parsec/slots.json --to file:/mnt/new/slots.json --dry-run
+  parsec keys migrate-store --from file:/var/lib/parsec/slots.json --to file:/mnt/new/slots.json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "table" && output != "json" {
+				return fmt.Errorf("unknown output format %q (expected table or json)", output)
+			}
+			if from == to {
+				return fmt.Errorf("--from and --to must be different stores")
+			}
+			source, err := openKeySlotStore(from)
+			if err != nil {
+				return fmt.Errorf("invalid --from: %w", err)
+			}
+			destination, err := openKeySlotStore(to)
+			if err != nil {
+				return fmt.Errorf("invalid --to: %w", err)
+			}
+
+			migration, err := keys.MigrateSlots(cmd.Context(), source, destination, keys.MigrateSlotsOptions{DryRun: dryRun})
+			if err != nil {
+				return err
+			}
+			if len(migration.Copied) == 0 && len(migration.Unchanged) == 0 {
+				return fmt.Errorf("%s holds no key slots", from)
+			}
+			if output == "json" {
+				return writeIndentedJSON(cmd.OutOrStdout(), slotMigrationResponse{
+					DryRun:    dryRun,
+					Copied:    migrationSlots(migration.Copied),
+					Unchanged: migrationSlots(migration.Unchanged),
+				})
+			}
+			return printSlotMigration(cmd.OutOrStdout(), migration, dryRun)
+		},
+	}
+	cmd.Flags().StringVar(&from, "from", "", "key slot store to copy from, e.g. file:/var/lib/parsec/slots.json")
+	cmd.Flags().StringVar(&to, "to", "", "key slot store to copy to")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "report the slots that would be copied without writing them")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "output format: table or json")
+	_ = cmd.MarkFlagRequired("from")
+	_ = cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+// openKeySlotStore opens a key slot store given as <type>:<location>
+func openKeySlotStore(spec string) (keys.KeySlotStore, error) {
+	storeType, location, ok := strings.Cut(spec, ":")
+	if !ok || location == "" {
+		return nil, fmt.Errorf("store %q must be given as file:<path>", spec)
+	}
+	if storeType != "file" {
+		return nil, fmt.Errorf("unsupported key slot store %q (supported: file)", storeType)
+	}
+	return config.NewKeySlotStore(&config.KeySlotStoreConfig{Type: storeType, Path: location})
+}
+
+// slotMigrationResponse reports a migration as JSON
+type slotMigrationResponse struct {
+	DryRun    bool            `json:"dry_run"`
+	Copied    []migrationSlot `json:"copied"`
+	Unchanged []migrationSlot `json:"unchanged"`
+}
+
+type migrationSlot struct {
+	Namespace     string `json:"namespace"`
+	KeyProviderID string `json:"key_provider_id"`
+	Position      string `json:"position"`
+}
+
+func migrationSlots(slots []*keys.KeySlot) []migrationSlot {
+	out := []migrationSlot{}
+	for _, slot := range slots {
+		out = append(out, migrationSlot{Namespace: slot.Namespace, KeyProviderID: slot.KeyProviderID, Position: string(slot.Position)})
+	}
+	return out
+}
+
+// printSlotMigration prints a table of the migrated slots and a summary
+func printSlotMigration(out io.Writer, migration keys.SlotMigration, dryRun bool) error {
+	copied := "copied"
+	if dryRun {
+		copied = "would copy"
+	}
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAMESPACE\tKEY PROVIDER\tSLOT\tACTION")
+	for _, slot := range migration.Copied {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", slot.Namespace, slot.KeyProviderID, slot.Position, copied)
+	}
+	for _, slot := range migration.Unchanged {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\tunchanged\n", slot.Namespace, slot.KeyProviderID, slot.Position)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if dryRun {
+		_, err := fmt.Fprintf(out, "dry run: %d slot(s) would be copied, %d unchanged\n", len(migration.Copied), len(migration.Unchanged))
+		return err
+	}
+	_, err := fmt.Fprintf(out, "%d slot(s) copied and verified, %d unchanged\n", len(migration.Copied), len(migration.Unchanged))
+	return err
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/keys"
+)
+
+func TestKeysMigrateStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "source.json")
+	destPath := filepath.Join(dir, "dest.json")
+
+	source, err := keys.NewFileKeySlotStore(sourcePath, nil)
+	if err != nil {
+		t.Fatalf("NewFileKeySlotStore failed: %v", err)
+	}
+	completed := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	slot := &keys.KeySlot{Position: keys.SlotPositionA, Namespace: "txn", KeyProviderID: "disk", RotationCompletedAt: &completed}
+	if _, err := source.SaveSlot(ctx, slot, "0"); err != nil {
+		t.Fatalf("SaveSlot failed: %v", err)
+	}
+
+	run := func(args ...string) (string, error) {
+		cmd := NewKeysCmd()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		cmd.SetArgs(append([]string{"migrate-store"}, args...))
+		err := cmd.ExecuteContext(ctx)
+		return out.String(), err
+	}
+
+	t.Run("dry run writes nothing", func(t *testing.T) {
+		out, err := run("--from", "file:"+sourcePath, "--to", "file:"+destPath, "--dry-run")
+		if err != nil {
+			t.Fatalf("migrate-store failed: %v", err)
+		}
+		if !strings.Contains(out, "would copy") || !strings.Contains(out, "1 slot(s) would be copied") {
+			t.Errorf("expected the slot to be reported, got:\n%s", out)
+		}
+		if _, err := os.Stat(destPath); !os.IsNotExist(err) {
+			t.Errorf("expected no destination to be written, got %v", err)
+		}
+	})
+
+	t.Run("copies and verifies slots", func(t *testing.T) {
+		out, err := run("--from", "file:"+sourcePath, "--to", "file:"+destPath)
+		if err != nil {
+			t.Fatalf("migrate-store failed: %v", err)
+		}
+		if !strings.Contains(out, "1 slot(s) copied and verified") {
+			t.Errorf("expected the slot to be copied, got:\n%s", out)
+		}
+		dest, err := keys.NewFileKeySlotStore(destPath, nil)
+		if err != nil {
+			t.Fatalf("NewFileKeySlotStore failed: %v", err)
+		}
+		slots, _, err := dest.ListSlots(ctx)
+		if err != nil || len(slots) != 1 || !slots[0].RotationCompletedAt.Equal(completed) {
+			t.Fatalf("expected the slot in the destination, got %v, %v", slots, err)
+		}
+
+		out, err = run("--from", "file:"+sourcePath, "--to", "file:"+destPath, "-o", "json")
+		if err != nil {
+			t.Fatalf("migrate-store failed: %v", err)
+		}
+		if !strings.Contains(out, `"copied": []`) || !strings.Contains(out, `"key_provider_id": "disk"`) {
+			t.Errorf("expected the slot to be unchanged on a second run, got:\n%s", out)
+		}
+	})
+
+	t.Run("conflicting destination is refused", func(t *testing.T) {
+		conflictPath := filepath.Join(dir, "conflict.json")
+		conflict, err := keys.NewFileKeySlotStore(conflictPath, nil)
+		if err != nil {
+			t.Fatalf("NewFileKeySlotStore failed: %v", err)
+		}
+		later := completed.Add(time.Hour)
+		other := *slot
+		other.RotationCompletedAt = &later
+		if _, err := conflict.SaveSlot(ctx, &other, "0"); err != nil {
+			t.Fatalf("SaveSlot failed: %v", err)
+		}
+		if _, err := run("--from", "file:"+sourcePath, "--to", "file:"+conflictPath); err == nil || !strings.Contains(err.Error(), "differs") {
+			t.Errorf("expected a conflict, got %v", err)
+		}
+	})
+
+	t.Run("invalid stores", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"--from", "file:" + filepath.Join(dir, "missing.json"), "--to", "file:" + destPath},
+			{"--from", "file:" + sourcePath, "--to", "file:" + sourcePath},
+			{"--from", sourcePath, "--to", "file:" + destPath},
+			{"--from", "etcd:slots", "--to", "file:" + destPath},
+		} {
+			if _, err := run(args...); err == nil {
+				t.Errorf("expected %v to fail", args)
+			}
+		}
+	})
+}
@@ -9,7 +9,8 @@ import (
 
 var (
 	// Global flags
-	configFile string
+	configFile   string
+	overlayFiles []string
 )
 
 // NewRootCmd creates the root command for parsec
@@ -28,9 +29,14 @@ Both services issue transaction tokens following the draft-ietf-oauth-transactio
 
 	// Global flags available to all commands
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "config file path (if not provided, uses PARSEC_CONFIG env var or env vars/flags only)")
+	rootCmd.PersistentFlags().StringSliceVar(&overlayFiles, "overlay", nil, "overlay config file merged on top of the config file; repeatable, later overlays win (if not provided, uses comma-separated PARSEC_CONFIG_OVERLAYS env var)")
 
 	// Add subcommands
 	rootCmd.AddCommand(NewServeCmd())
+	rootCmd.AddCommand(NewValidateCmd())
+	rootCmd.AddCommand(NewConfigCmd())
+	rootCmd.AddCommand(NewKeysCmd())
+	rootCmd.AddCommand(NewDemoCmd())
 
 	return rootCmd
 }
@@ -2,15 +2,24 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/spf13/cobra"
 
 	"github.com/project-kessel/parsec/internal/config"
+	"github.com/project-kessel/parsec/internal/decisionlog"
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/ledger"
 	"github.com/project-kessel/parsec/internal/server"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/telemetry"
+	"github.com/project-kessel/parsec/internal/tokenwebhook"
+	"github.com/project-kessel/parsec/internal/trust"
 )
 
 // NewServeCmd creates the serve command
@@ -24,6 +33,10 @@ The server will:
   - Listen for gRPC requests (ext_authz, token exchange)
   - Listen for HTTP requests (token exchange via gRPC-gateway transcoding)
   - Load configuration from file, environment variables, and command-line flags
+  - Reload when remote configuration sources (https://, s3://, configmap://)
+    change, keeping the current configuration if the new one fails to build.
+    Handlers are replaced while the listeners keep serving; changing ports
+    requires a restart
 
 Configuration precedence (highest to lowest):
   1. Command-line flags
@@ -45,12 +58,17 @@ Examples:
   parsec serve --config /etc/parsec/config.yaml
 
   # Combine multiple overrides
-  parsec serve --config ./my-config.yaml --server-grpc-port 9091`,
+  parsec serve --config ./my-config.yaml --server-grpc-port 9091
+
+  # Load config from a remote source, polling for changes every minute
+  parsec serve --config s3://my-bucket/parsec.yaml --config-poll-interval 1m`,
 		RunE: runServe,
 	}
 
 	// Auto-register all config flags
 	config.RegisterFlags(cmd.Flags())
+	cmd.Flags().Duration("config-poll-interval", config.DefaultPollInterval,
+		"how often to poll remote config sources for changes (0 disables polling)")
 
 	return cmd
 }
@@ -59,25 +77,116 @@ func runServe(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// 1. Determine config file path
-	configPath := configFile
-	if configPath == "" {
-		// Check environment variable
-		configPath = os.Getenv("PARSEC_CONFIG")
-	}
-	// If still empty, configPath remains empty and we'll use env vars/flags only
+	// 1. Determine config file path (--config, then PARSEC_CONFIG)
+	// If empty, we'll use env vars/flags only
+	configPath := resolveConfigPath()
 
 	// 2. Load configuration (file + env vars + flags)
-	loader, err := config.NewLoaderWithFlags(configPath, cmd.Flags())
+	loader, err := config.NewLoaderWithFlags(configPath, cmd.Flags(), resolveOverlays()...)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	printDeprecations(cmd, loader)
 
 	cfg, err := loader.Get()
 	if err != nil {
 		return fmt.Errorf("failed to parse config: %w", err)
 	}
 
+	// 3-7. Build all components from config
+	current, err := newServeInstance(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	// 8. Start server; /readyz reports the JWKS warmup until it is done
+	if err := current.start(ctx); err != nil {
+		return err
+	}
+	if err := current.warmup.Wait(ctx); err != nil {
+		_ = current.stop(ctx)
+		return fmt.Errorf("failed to warm trust store: %w", err)
+	}
+
+	fmt.Println("parsec is running")
+	fmt.Printf("  gRPC (ext_authz):      localhost:%d\n", current.serverCfg.GRPCPort)
+	fmt.Printf("  HTTP (token exchange): http://localhost:%d/v1/token\n", current.serverCfg.HTTPPort)
+	fmt.Printf("  HTTP (JWKS):           http://localhost:%d/v1/jwks.json\n", current.serverCfg.HTTPPort)
+	fmt.Printf("                         http://localhost:%d/.well-known/jwks.json\n", current.serverCfg.HTTPPort)
+	fmt.Printf("  HTTP (readiness):      http://localhost:%d/readyz\n", current.serverCfg.HTTPPort)
+	if current.serverCfg.IntrospectionServer != nil {
+		fmt.Printf("  HTTP (introspection):  http://localhost:%d/v1/introspect\n", current.serverCfg.HTTPPort)
+	}
+	if current.serverCfg.Metrics != nil {
+		fmt.Printf("  HTTP (metrics):        http://localhost:%d%s\n", current.serverCfg.HTTPPort, current.serverCfg.MetricsPath)
+	}
+	if current.serverCfg.AdminServer != nil {
+		fmt.Printf("  HTTP (admin):          http://localhost:%d/v1/admin/cache/invalidate\n", current.serverCfg.HTTPPort)
+	}
+	fmt.Printf("  Trust Domain:          %s\n", current.provider.TrustDomain())
+	fmt.Printf("  Config:                %s\n", configPath)
+	if overlays := resolveOverlays(); len(overlays) > 0 {
+		fmt.Printf("  Overlays:              %s\n", strings.Join(overlays, ", "))
+	}
+
+	// Remote config sources are polled and the server is rebuilt when they change
+	reloads := make(chan *config.Config, 1)
+	if pollInterval, _ := cmd.Flags().GetDuration("config-poll-interval"); pollInterval > 0 && loader.HasRemoteSources() {
+		loader.SetPollInterval(pollInterval)
+		go func() {
+			_ = loader.Watch(ctx, func(cfg *config.Config) error {
+				// Only the latest configuration matters
+				select {
+				case <-reloads:
+				default:
+				}
+				reloads <- cfg
+				return nil
+			})
+		}()
+		fmt.Printf("  Config poll interval:  %s\n", pollInterval)
+	}
+
+	// 9. Wait for interrupt signal, applying configuration changes meanwhile
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	for running := true; running; {
+		select {
+		case <-sigCh:
+			running = false
+		case cfg := <-reloads:
+			current = reloadServeInstance(ctx, current, cfg)
+		}
+	}
+
+	fmt.Println("\nShutting down...")
+
+	// 10. Graceful shutdown
+	if err := current.stop(ctx); err != nil {
+		return fmt.Errorf("error during shutdown: %w", err)
+	}
+
+	fmt.Println("Shutdown complete")
+	return nil
+}
+
+// serveInstance is the set of running components built from one configuration
+type serveInstance struct {
+	provider    *config.Provider
+	serverCfg   server.Config
+	jwksServer  *server.JWKSServer
+	srv         *server.Server
+	warmup      *trust.Warmup
+	signers     *keys.SignerRegistry
+	plugins     *config.Plugins
+	decisionLog *decisionlog.Logger
+	webhook     *tokenwebhook.Notifier
+	telemetry   *telemetry.Telemetry
+}
+
+// newServeInstance builds all components for cfg without starting them,
+// except for the trust store warmup, which fetches validator JWKS meanwhile
+func newServeInstance(ctx context.Context, cfg *config.Config) (_ *serveInstance, err error) {
 	// 3. Create provider to build all components from config
 	provider := config.NewProvider(cfg)
 
@@ -86,84 +195,351 @@ func runServe(cmd *cobra.Command, args []string) error {
 
 	observer, err := config.NewObserverWithLogger(cfg.Observability, logger)
 	if err != nil {
-		return fmt.Errorf("failed to create observer: %w", err)
+		return nil, fmt.Errorf("failed to create observer: %w", err)
+	}
+
+	// Decision events are recorded alongside the other observers
+	decisionLog, err := config.NewDecisionLogger(cfg.DecisionLog, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create decision log: %w", err)
+	}
+	if decisionLog != nil {
+		defer func() {
+			if err != nil {
+				_ = decisionLog.Close(ctx)
+			}
+		}()
+		observer = service.NewCompositeObserver(observer, decisionlog.NewObserver(decisionLog))
+	}
+
+	// So are the summaries of issued tokens sent to the token webhook
+	webhook, err := config.NewTokenWebhook(cfg.TokenWebhook, provider.HTTPTransport(), logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token webhook: %w", err)
+	}
+	if webhook != nil {
+		defer func() {
+			if err != nil {
+				_ = webhook.Close(ctx)
+			}
+		}()
+		observer = service.NewCompositeObserver(observer, tokenwebhook.NewObserver(webhook))
+	}
+
+	// And issued tokens are recorded in the token ledger, for the admin endpoints
+	tokenLedger, err := provider.TokenLedger()
+	if err != nil {
+		return nil, err
+	}
+	if tokenLedger != nil {
+		observer = service.NewCompositeObserver(observer, ledger.NewObserver(tokenLedger))
+	}
+
+	// Metrics are recorded alongside the other observers, too
+	metrics, err := config.NewTelemetry(ctx, cfg.Telemetry, provider.TrustDomain())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create telemetry: %w", err)
+	}
+	if metrics != nil {
+		defer func() {
+			if err != nil {
+				_ = metrics.Shutdown(ctx)
+			}
+		}()
+		metricsObserver, err := telemetry.NewObserver(metrics.Meter())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create metrics observer: %w", err)
+		}
+		observer = service.NewCompositeObserver(observer, metricsObserver)
+		if webhook != nil {
+			if err := telemetry.RegisterTokenWebhookMetrics(metrics.Meter(), webhook); err != nil {
+				return nil, fmt.Errorf("failed to register token webhook metrics: %w", err)
+			}
+		}
 	}
 
 	// Inject into provider so TokenService and other internal components use the same observer
 	provider.SetObserver(observer)
 
+	// Plugins may be started by any component built below
+	plugins, err := provider.Plugins()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = plugins.Stop(ctx)
+		}
+	}()
+
 	// 5. Build components via provider
 	trustStore, err := provider.TrustStore()
 	if err != nil {
-		return fmt.Errorf("failed to create trust store: %w", err)
+		return nil, fmt.Errorf("failed to create trust store: %w", err)
+	}
+
+	warmup, err := provider.StartTrustStoreWarmup(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	tokenService, err := provider.TokenService()
 	if err != nil {
-		return fmt.Errorf("failed to create token service: %w", err)
+		return nil, fmt.Errorf("failed to create token service: %w", err)
 	}
 
 	authzTokenTypes, err := provider.AuthzServerTokenTypes()
 	if err != nil {
-		return fmt.Errorf("failed to get authz token types: %w", err)
+		return nil, fmt.Errorf("failed to get authz token types: %w", err)
 	}
 
 	claimsFilterRegistry, err := provider.ExchangeServerClaimsFilterRegistry()
 	if err != nil {
-		return fmt.Errorf("failed to get exchange server claims filter registry: %w", err)
+		return nil, fmt.Errorf("failed to get exchange server claims filter registry: %w", err)
+	}
+
+	delegationPolicy, err := provider.ExchangeServerDelegationPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange server delegation policy: %w", err)
+	}
+
+	selfIssuancePolicy, err := provider.ExchangeServerSelfIssuancePolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange server client_credentials policy: %w", err)
+	}
+
+	schemaRegistry, err := provider.ExchangeServerRequestContextSchemas()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange server request_context schemas: %w", err)
+	}
+
+	actorCredentials, err := provider.ActorCredentialExtractor()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get actor credential extractor: %w", err)
+	}
+
+	headerSizeLimit, err := provider.AuthzServerHeaderSizeLimit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authz server header size limit: %w", err)
+	}
+
+	denyCache, err := provider.AuthzServerDenyCache()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authz server deny cache: %w", err)
+	}
+
+	clientPolicy, err := provider.ClientPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client policy: %w", err)
+	}
+
+	attributeSources, err := provider.AuthzServerRequestAttributeSources()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authz server request attributes: %w", err)
+	}
+
+	dynamicMetadata, err := provider.AuthzServerDynamicMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authz server dynamic metadata: %w", err)
+	}
+
+	ipPolicy, err := provider.AuthzServerIPPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authz server ip policy: %w", err)
+	}
+
+	bodyHash, err := provider.AuthzServerBodyHash()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authz server body hash: %w", err)
+	}
+
+	skipPaths, err := provider.AuthzServerSkipPaths()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authz server skip paths: %w", err)
+	}
+
+	responseHeaders, err := provider.AuthzServerResponseHeaders()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authz server response headers: %w", err)
+	}
+
+	trustedProxies, err := provider.TrustedProxies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trusted proxies: %w", err)
+	}
+
+	subjectCredentials, err := provider.SubjectCredentialExtractor()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subject credential extractor: %w", err)
 	}
 
 	issuerRegistry, err := provider.IssuerRegistry()
 	if err != nil {
-		return fmt.Errorf("failed to get issuer registry: %w", err)
+		return nil, fmt.Errorf("failed to get issuer registry: %w", err)
+	}
+
+	signers, err := provider.SignerRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signer registry: %w", err)
+	}
+	if metrics != nil && signers != nil {
+		if err := telemetry.RegisterKeyUsageMetrics(metrics.Meter(), signers); err != nil {
+			return nil, fmt.Errorf("failed to register key usage metrics: %w", err)
+		}
+	}
+
+	if err := plugins.Start(ctx); err != nil {
+		return nil, fmt.Errorf("failed to start plugins: %w", err)
+	}
+
+	introspectionServer, err := provider.IntrospectionServer(logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create introspection server: %w", err)
+	}
+
+	cachePeers, cachePeersPath, err := provider.DistributedCachePeers(logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up distributed cache: %w", err)
 	}
 
-	// 6. Create service handlers with observability
-	authzServer := server.NewAuthzServer(trustStore, tokenService, authzTokenTypes, observer)
-	exchangeServer := server.NewExchangeServer(trustStore, tokenService, claimsFilterRegistry, observer)
 	jwksServer := server.NewJWKSServer(server.JWKSServerConfig{
 		IssuerRegistry: issuerRegistry,
 		Logger:         logger,
 	})
 
-	// Start JWKS background refresh
-	if err := jwksServer.Start(ctx); err != nil {
-		return fmt.Errorf("failed to start JWKS server: %w", err)
+	// Invalidations are forwarded to the cache peers, when there are any
+	var adminPeers server.PeerList
+	if cachePeers != nil {
+		adminPeers = cachePeers
+	}
+	adminServer, err := provider.AdminServer(logger, adminPeers, jwksServer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin server: %w", err)
 	}
-	defer jwksServer.Stop()
+
+	// 6. Create service handlers with observability
+	authzServer := server.NewAuthzServer(trustStore, tokenService, authzTokenTypes, observer,
+		server.WithAuthzActorCredentialExtractor(actorCredentials),
+		server.WithSubjectCredentialExtractor(subjectCredentials),
+		server.WithHeaderSizeLimit(headerSizeLimit),
+		server.WithResponseHeaders(responseHeaders...),
+		server.WithDenyCache(denyCache),
+		server.WithSkipPaths(skipPaths...),
+		server.WithIPPolicy(ipPolicy),
+		server.WithBodyHash(bodyHash),
+		server.WithRequestAttributeSources(attributeSources...),
+		server.WithDynamicMetadata(dynamicMetadata),
+		server.WithAuthzClientPolicy(clientPolicy),
+	)
+	exchangeServer := server.NewExchangeServer(trustStore, tokenService, claimsFilterRegistry, observer,
+		server.WithActorCredentialExtractor(actorCredentials),
+		server.WithDelegationPolicy(delegationPolicy),
+		server.WithSelfIssuancePolicy(selfIssuancePolicy),
+		server.WithRejectedClaimsWarning(provider.ExchangeServerRejectedClaimsWarning()),
+		server.WithRequestContextSchemas(schemaRegistry),
+		server.WithTrustedProxies(trustedProxies),
+		server.WithAllowedAudiences(provider.ExchangeServerAllowedAudiences()),
+		server.WithClientPolicy(clientPolicy),
+	)
 
 	// 7. Create server configuration
 	serverCfg := provider.ServerConfig()
 	serverCfg.AuthzServer = authzServer
 	serverCfg.ExchangeServer = exchangeServer
 	serverCfg.JWKSServer = jwksServer
+	serverCfg.IntrospectionServer = introspectionServer
+	serverCfg.AdminServer = adminServer
+	serverCfg.Warmup = warmup
+	serverCfg.Signers = signers
+	if cachePeers != nil {
+		serverCfg.DistributedCache = cachePeers
+		serverCfg.DistributedCachePath = cachePeersPath
+	}
+	if metrics != nil && metrics.Handler() != nil {
+		serverCfg.Metrics = metrics.Handler()
+		serverCfg.MetricsPath = config.MetricsPath(cfg.Telemetry)
+	}
+
+	return &serveInstance{
+		provider:    provider,
+		serverCfg:   serverCfg,
+		jwksServer:  jwksServer,
+		srv:         server.New(serverCfg),
+		warmup:      warmup,
+		signers:     signers,
+		plugins:     plugins,
+		decisionLog: decisionLog,
+		webhook:     webhook,
+		telemetry:   metrics,
+	}, nil
+}
 
-	// 8. Create and start server
-	srv := server.New(serverCfg)
-	if err := srv.Start(ctx); err != nil {
+// start starts the JWKS background refresh and the servers
+func (i *serveInstance) start(ctx context.Context) error {
+	if err := i.jwksServer.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start JWKS server: %w", err)
+	}
+	if err := i.srv.Start(ctx); err != nil {
+		i.jwksServer.Stop()
 		return fmt.Errorf("failed to start server: %w", err)
 	}
+	return nil
+}
 
-	fmt.Println("parsec is running")
-	fmt.Printf("  gRPC (ext_authz):      localhost:%d\n", serverCfg.GRPCPort)
-	fmt.Printf("  HTTP (token exchange): http://localhost:%d/v1/token\n", serverCfg.HTTPPort)
-	fmt.Printf("  HTTP (JWKS):           http://localhost:%d/v1/jwks.json\n", serverCfg.HTTPPort)
-	fmt.Printf("                         http://localhost:%d/.well-known/jwks.json\n", serverCfg.HTTPPort)
-	fmt.Printf("  Trust Domain:          %s\n", provider.TrustDomain())
-	fmt.Printf("  Config:                %s\n", configPath)
+// stop gracefully stops the servers, the JWKS background refresh, the key
+// rotation of the signers and the plugins, then flushes the decision log, the
+// token webhook and metrics
+func (i *serveInstance) stop(ctx context.Context) error {
+	defer i.jwksServer.Stop()
+	return errors.Join(i.srv.Stop(ctx), i.signers.Stop(ctx), i.plugins.Stop(ctx), i.decisionLog.Close(ctx), i.webhook.Close(ctx), i.telemetry.Shutdown(ctx))
+}
 
-	// 9. Wait for interrupt signal
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-	<-sigCh
+// discard releases the components of an instance that never served
+func (i *serveInstance) discard(ctx context.Context) {
+	_ = i.signers.Stop(ctx)
+	_ = i.plugins.Stop(ctx)
+	_ = i.decisionLog.Close(ctx)
+	_ = i.webhook.Close(ctx)
+	_ = i.telemetry.Shutdown(ctx)
+}
 
-	fmt.Println("\nShutting down...")
+// reloadServeInstance replaces current with an instance built from cfg and
+// returns the instance that is serving afterwards. The new handlers are swapped
+// in behind the running listeners once their validators are warmed, so there
+// is no window without a server or with cold validators. If the new
+// configuration cannot be built, warmed or started, the current instance keeps
+// serving.
+func reloadServeInstance(ctx context.Context, current *serveInstance, cfg *config.Config) *serveInstance {
+	next, err := newServeInstance(ctx, cfg)
+	if err != nil {
+		fmt.Printf("config reload rejected, keeping current configuration: %v\n", err)
+		return current
+	}
+	if err := next.warmup.Wait(ctx); err != nil {
+		next.discard(ctx)
+		fmt.Printf("config reload rejected, keeping current configuration: failed to warm trust store: %v\n", err)
+		return current
+	}
 
-	// 10. Graceful shutdown
-	if err := srv.Stop(ctx); err != nil {
-		return fmt.Errorf("error during shutdown: %w", err)
+	if err := next.jwksServer.Start(ctx); err != nil {
+		next.discard(ctx)
+		fmt.Printf("config reload rejected, keeping current configuration: failed to start JWKS server: %v\n", err)
+		return current
+	}
+	if err := current.srv.SetHandlers(next.serverCfg); err != nil {
+		next.jwksServer.Stop()
+		next.discard(ctx)
+		fmt.Printf("config reload rejected, keeping current configuration: %v\n", err)
+		return current
 	}
 
-	fmt.Println("Shutdown complete")
-	return nil
+	// The listeners belong to the running server, which now serves next's handlers
+	next.srv = current.srv
+	current.jwksServer.Stop()
+	if err := errors.Join(current.signers.Stop(ctx), current.plugins.Stop(ctx), current.decisionLog.Close(ctx), current.webhook.Close(ctx), current.telemetry.Shutdown(ctx)); err != nil {
+		fmt.Printf("warning: %v\n", err)
+	}
+
+	fmt.Println("configuration reloaded")
+	return next
 }
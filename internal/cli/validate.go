@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/project-kessel/parsec/internal/config"
+)
+
+// NewValidateCmd creates the validate command
+func NewValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate configuration without starting the server",
+		Long: `Validate parsec configuration as a dry run.
+
+Builds every component the server would (validators, data sources, key
+providers, signers, issuers, claim mappers, exchange and authz policies),
+compiling all CEL and Lua scripts, but does not start signers or listeners.
+All errors are reported at once, each with the config path it belongs to.
+
+Examples:
+  # Validate a config file
+  parsec validate --config /etc/parsec/config.yaml
+
+  # Validate with environment and flag overrides applied
+  PARSEC_TRUST_DOMAIN=prod.example.com parsec validate --config ./config.yaml`,
+		RunE: runValidate,
+	}
+
+	config.RegisterFlags(cmd.Flags())
+
+	return cmd
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	configPath := resolveConfigPath()
+
+	loader, err := config.NewLoaderWithFlags(configPath, cmd.Flags(), resolveOverlays()...)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	printDeprecations(cmd, loader)
+
+	cfg, err := loader.Get()
+	if err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	if err := config.Validate(cfg, config.NewProvider(cfg).HTTPTransport()); err != nil {
+		var validationErrs config.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			for _, validationErr := range validationErrs {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "  %s\n", validationErr.Error())
+			}
+			return fmt.Errorf("%d configuration error(s) found", len(validationErrs))
+		}
+		return err
+	}
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "configuration is valid: %s\n", displayConfigPath(configPath))
+	return nil
+}
+
+// displayConfigPath describes where configuration was loaded from
+func displayConfigPath(configPath string) string {
+	if configPath == "" {
+		return "(environment and flags only)"
+	}
+	return configPath
+}
@@ -0,0 +1,167 @@
+package clients
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// mapDataSource returns the JSON of the actor's subject from a map
+type mapDataSource map[string]string
+
+func (mapDataSource) Name() string { return "clients" }
+
+func (ds mapDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	data, ok := ds[input.Actor.Subject]
+	if !ok {
+		return nil, nil
+	}
+	if data == "error" {
+		return nil, errors.New("database unavailable")
+	}
+	return &service.DataSourceResult{Data: []byte(data), ContentType: service.ContentTypeJSON}, nil
+}
+
+func TestSecretHash(t *testing.T) {
+	// echo -n s3cret | sha256sum
+	hash, err := ParseSecretHash("1ec1c26b50d5d3c58d9583181af8076655fe00756bf7285940ba3670f99fcba0")
+	if err != nil {
+		t.Fatalf("ParseSecretHash failed: %v", err)
+	}
+	if hash != HashSecret("s3cret") {
+		t.Errorf("expected the hash of s3cret, got %s", hex.EncodeToString(hash[:]))
+	}
+
+	for _, s := range []string{"", "not hex", "4e738ca5"} {
+		if _, err := ParseSecretHash(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+
+	client := &Client{ID: "billing", SecretHashes: []SecretHash{HashSecret("old"), HashSecret("new")}}
+	if !client.VerifySecret("old") || !client.VerifySecret("new") || client.VerifySecret("other") {
+		t.Error("expected both rotated secrets, and only them, to verify")
+	}
+}
+
+func TestStaticRegistry(t *testing.T) {
+	registry, err := NewStaticRegistry([]Client{{ID: "billing", SecretHashes: []SecretHash{HashSecret("s3cret")}}})
+	if err != nil {
+		t.Fatalf("NewStaticRegistry failed: %v", err)
+	}
+	if client, err := registry.Client(context.Background(), "billing"); err != nil || client == nil || client.ID != "billing" {
+		t.Errorf("expected the billing client, got %v, %v", client, err)
+	}
+	if client, err := registry.Client(context.Background(), "unknown"); err != nil || client != nil {
+		t.Errorf("expected no client, got %v, %v", client, err)
+	}
+
+	secret := []SecretHash{HashSecret("s3cret")}
+	for name, clients := range map[string][]Client{
+		"no ID":     {{SecretHashes: secret}},
+		"bad quota": {{ID: "billing", Quota: &Quota{Limit: 0, Window: time.Minute}}},
+		"duplicate": {{ID: "billing", SecretHashes: secret}, {ID: "billing", SecretHashes: secret}},
+	} {
+		if _, err := NewStaticRegistry(clients); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestDataSourceRegistry(t *testing.T) {
+	ctx := context.Background()
+	registry := NewDataSourceRegistry(mapDataSource{
+		"billing": `{"secret_sha256": ["1ec1c26b50d5d3c58d9583181af8076655fe00756bf7285940ba3670f99fcba0"], "claims": {"team": "billing"}}`,
+		"batch":   `{"trust_domain": "jobs.example.com", "audiences": ["billing.example.com"], "token_types": ["urn:ietf:params:oauth:token-type:txn_token"], "allowed_claims": ["purpose"], "quota": {"limit": 10, "window": "1m"}}`,
+		"broken":  `{"secret_sha256": ["nope"]}`,
+		"eager":   `{"quota": {"limit": 10, "window": "soon"}}`,
+		"down":    "error",
+	})
+
+	client, err := registry.Client(ctx, "billing")
+	if err != nil || client == nil {
+		t.Fatalf("expected the billing client, got %v, %v", client, err)
+	}
+	if !client.VerifySecret("s3cret") || client.Claims.GetString("team") != "billing" {
+		t.Errorf("unexpected client: %+v", client)
+	}
+
+	// Clients authenticating otherwise have policies but no secret
+	batch, err := registry.Client(ctx, "batch")
+	if err != nil || batch == nil {
+		t.Fatalf("expected the batch client, got %v, %v", batch, err)
+	}
+	if batch.VerifySecret("") || batch.TrustDomain != "jobs.example.com" || !slices.Equal(batch.Audiences, []string{"billing.example.com"}) {
+		t.Errorf("unexpected client: %+v", batch)
+	}
+	if !batch.AllowsTokenType(service.TokenTypeTransactionToken) || batch.AllowsTokenType(service.TokenTypeAccessToken) {
+		t.Errorf("expected only transaction tokens to be allowed, got %v", batch.TokenTypes)
+	}
+	if filtered := batch.ClaimsFilter.Filter(claims.Claims{"purpose": "sync", "tenant": "acme"}); len(filtered) != 1 || filtered["purpose"] != "sync" {
+		t.Errorf("expected an allowlist of purpose, got %v", filtered)
+	}
+	if batch.Quota == nil || *batch.Quota != (Quota{Limit: 10, Window: time.Minute}) {
+		t.Errorf("unexpected quota: %+v", batch.Quota)
+	}
+
+	if client, err := registry.Client(ctx, "unknown"); err != nil || client != nil {
+		t.Errorf("expected no client, got %v, %v", client, err)
+	}
+	for _, id := range []string{"broken", "eager", "down"} {
+		if _, err := registry.Client(ctx, id); err == nil {
+			t.Errorf("%s: expected an error", id)
+		}
+	}
+}
+
+func TestValidator(t *testing.T) {
+	ctx := context.Background()
+	registry, err := NewStaticRegistry([]Client{{
+		ID:           "billing",
+		SecretHashes: []SecretHash{HashSecret("s3cret")},
+		Claims:       claims.Claims{"team": "billing"},
+	}})
+	if err != nil {
+		t.Fatalf("NewStaticRegistry failed: %v", err)
+	}
+	validator, err := NewValidator(ValidatorConfig{Registry: registry, TrustDomain: "clients.example.com"})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	result, err := validator.Validate(ctx, &trust.ClientSecretCredential{ClientID: "billing", ClientSecret: "s3cret"})
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if result.Subject != "billing" || result.TrustDomain != "clients.example.com" ||
+		result.Claims.GetString("client_id") != "billing" || result.Claims.GetString("team") != "billing" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	for name, cred := range map[string]trust.Credential{
+		"wrong secret":   &trust.ClientSecretCredential{ClientID: "billing", ClientSecret: "guess"},
+		"unknown client": &trust.ClientSecretCredential{ClientID: "unknown", ClientSecret: "s3cret"},
+		"no secret":      &trust.ClientSecretCredential{ClientID: "billing"},
+	} {
+		if _, err := validator.Validate(ctx, cred); !errors.Is(err, trust.ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+	if _, err := validator.Validate(ctx, &trust.BearerCredential{Token: "token"}); err == nil {
+		t.Error("expected an error for a bearer credential")
+	}
+
+	if _, err := NewValidator(ValidatorConfig{TrustDomain: "clients.example.com"}); err == nil {
+		t.Error("expected an error without a registry")
+	}
+	if _, err := NewValidator(ValidatorConfig{Registry: registry}); err == nil {
+		t.Error("expected an error without a trust domain")
+	}
+}
@@ -0,0 +1,99 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// DataSourceRegistry looks up clients in a data source, e.g. an HTTP or SQL
+// lookup against an existing client database
+//
+// The data source is fetched with the client ID as the actor's subject
+// (.actor.subject in templates), and returns the client as JSON, or no
+// result if there is no such client:
+//
+//	{"secret_sha256": ["9f86d081..."], "claims": {"team": "billing"},
+//	 "trust_domain": "clients.example.com",
+//	 "audiences": ["billing.example.com"],
+//	 "token_types": ["urn:ietf:params:oauth:token-type:txn_token"],
+//	 "allowed_claims": ["purpose"],
+//	 "quota": {"limit": 100, "window": "1m"}}
+//
+// All fields are optional. allowed_claims assigns the client an allowlist
+// claims filter.
+//
+// Lookups are cached as configured for the data source.
+type DataSourceRegistry struct {
+	dataSource service.DataSource
+}
+
+// NewDataSourceRegistry creates a registry backed by a data source
+func NewDataSourceRegistry(dataSource service.DataSource) *DataSourceRegistry {
+	return &DataSourceRegistry{dataSource: dataSource}
+}
+
+// dataSourceClient is the JSON form of a client returned by a data source
+type dataSourceClient struct {
+	SecretSHA256  []string            `json:"secret_sha256"`
+	Claims        claims.Claims       `json:"claims"`
+	TrustDomain   string              `json:"trust_domain"`
+	Audiences     []string            `json:"audiences"`
+	TokenTypes    []service.TokenType `json:"token_types"`
+	AllowedClaims []string            `json:"allowed_claims"`
+	Quota         *struct {
+		Limit  int64  `json:"limit"`
+		Window string `json:"window"`
+	} `json:"quota"`
+}
+
+// Client implements Registry
+func (r *DataSourceRegistry) Client(ctx context.Context, id string) (*Client, error) {
+	result, err := r.dataSource.Fetch(ctx, &service.DataSourceInput{
+		Actor: &trust.Result{Subject: id},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up client %s in data source %s: %w", id, r.dataSource.Name(), err)
+	}
+	if result == nil || len(result.Data) == 0 {
+		return nil, nil
+	}
+
+	var record dataSourceClient
+	if err := json.Unmarshal(result.Data, &record); err != nil {
+		return nil, fmt.Errorf("invalid client %s from data source %s: %w", id, r.dataSource.Name(), err)
+	}
+	client := &Client{
+		ID:          id,
+		TrustDomain: record.TrustDomain,
+		Claims:      record.Claims,
+		Audiences:   record.Audiences,
+		TokenTypes:  record.TokenTypes,
+	}
+	for _, s := range record.SecretSHA256 {
+		hash, err := ParseSecretHash(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client %s from data source %s: %w", id, r.dataSource.Name(), err)
+		}
+		client.SecretHashes = append(client.SecretHashes, hash)
+	}
+	if record.AllowedClaims != nil {
+		client.ClaimsFilter = claims.NewAllowListClaimsFilter(record.AllowedClaims)
+	}
+	if record.Quota != nil {
+		window, err := time.ParseDuration(record.Quota.Window)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client %s from data source %s: invalid quota window: %w", id, r.dataSource.Name(), err)
+		}
+		client.Quota = &Quota{Limit: record.Quota.Limit, Window: window}
+	}
+	if err := client.validate(); err != nil {
+		return nil, fmt.Errorf("invalid client from data source %s: %w", r.dataSource.Name(), err)
+	}
+	return client, nil
+}
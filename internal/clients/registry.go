@@ -0,0 +1,152 @@
+// Package clients implements the registry of clients calling parsec: the
+// actors allowed to exchange tokens or to have tokens issued for the requests
+// they check, and what each of them may do.
+//
+// A registered client may authenticate with a client ID and secret rather than
+// a token, e.g. with HTTP basic authentication, checked by the Validator.
+// Clients authenticating otherwise (mTLS, bearer tokens) are registered by the
+// subject of their credential, so the same policies apply to them: the
+// audiences and token types they may request, the request_context claims they
+// may provide, and their quota of tokens.
+package clients
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// SecretHash is the SHA-256 digest of a client secret
+// Client secrets are generated with enough entropy that a fast hash suffices,
+// as for API keys.
+type SecretHash [sha256.Size]byte
+
+// HashSecret returns the hash of a client secret
+func HashSecret(secret string) SecretHash {
+	return sha256.Sum256([]byte(secret))
+}
+
+// ParseSecretHash parses a hex-encoded secret hash, as printed by
+// `echo -n "$secret" | sha256sum`
+func ParseSecretHash(s string) (SecretHash, error) {
+	var hash SecretHash
+	raw, err := hex.DecodeString(s)
+	if err != nil || len(raw) != len(hash) {
+		return hash, fmt.Errorf("invalid secret hash: expected %d hex-encoded bytes", len(hash))
+	}
+	copy(hash[:], raw)
+	return hash, nil
+}
+
+// Client is a registered client
+type Client struct {
+	// ID is the client ID, or the subject of the client's credential if it
+	// doesn't authenticate with a secret
+	ID string
+
+	// TrustDomain restricts the client's policies to actors of this trust
+	// domain, or of those matching a pattern such as "*.example.com"
+	// (optional), so an actor of another trust domain with the same subject
+	// doesn't get them
+	TrustDomain string
+
+	// SecretHashes are the hashes of the client's secrets (optional)
+	// Several secrets are valid at once while a secret is rotated.
+	SecretHashes []SecretHash
+
+	// Claims are added to the client's validation result
+	Claims claims.Claims
+
+	// Audiences are the audiences, or audience patterns such as
+	// "*.example.com", the client may request tokens for besides the trust
+	// domain, in place of the server's allowed audiences (nil keeps the
+	// server's)
+	Audiences []string
+
+	// TokenTypes are the token types the client may be issued (nil allows all)
+	TokenTypes []service.TokenType
+
+	// ClaimsFilter filters the request_context claims the client provides, in
+	// place of the server's claims filter for it (optional)
+	ClaimsFilter claims.ClaimsFilter
+
+	// Quota caps the tokens issued to the client (optional)
+	Quota *Quota
+}
+
+// Quota caps the tokens issued to a client per fixed window
+type Quota struct {
+	// Limit is the number of tokens that may be issued per window
+	Limit int64
+
+	// Window is the length of the windows tokens are counted in
+	Window time.Duration
+}
+
+// validate checks the client's policies
+func (c *Client) validate() error {
+	if c.Quota != nil && (c.Quota.Limit <= 0 || c.Quota.Window <= 0) {
+		return fmt.Errorf("client %s: quota limit and window must be positive", c.ID)
+	}
+	return nil
+}
+
+// AllowsTokenType reports whether the client may be issued tokens of tokenType
+func (c *Client) AllowsTokenType(tokenType service.TokenType) bool {
+	return c.TokenTypes == nil || slices.Contains(c.TokenTypes, tokenType)
+}
+
+// VerifySecret reports whether secret is one of the client's secrets
+// Clients without secrets can't authenticate with one.
+func (c *Client) VerifySecret(secret string) bool {
+	hash := HashSecret(secret)
+	valid := 0
+	for _, h := range c.SecretHashes {
+		valid |= subtle.ConstantTimeCompare(hash[:], h[:])
+	}
+	return valid == 1
+}
+
+// Registry looks up registered clients
+type Registry interface {
+	// Client returns the client with the given ID
+	// Returns nil client and nil error if there is no such client.
+	Client(ctx context.Context, id string) (*Client, error)
+}
+
+// StaticRegistry is a registry of a fixed set of clients, e.g. from the
+// configuration
+type StaticRegistry struct {
+	clients map[string]*Client
+}
+
+// NewStaticRegistry creates a registry of the given clients
+func NewStaticRegistry(clients []Client) (*StaticRegistry, error) {
+	r := &StaticRegistry{clients: make(map[string]*Client, len(clients))}
+	for i := range clients {
+		client := &clients[i]
+		if client.ID == "" {
+			return nil, fmt.Errorf("clients[%d]: client ID is required", i)
+		}
+		if err := client.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := r.clients[client.ID]; ok {
+			return nil, fmt.Errorf("client %s is registered more than once", client.ID)
+		}
+		r.clients[client.ID] = client
+	}
+	return r, nil
+}
+
+// Client implements Registry
+func (r *StaticRegistry) Client(ctx context.Context, id string) (*Client, error) {
+	return r.clients[id], nil
+}
@@ -0,0 +1,74 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// Validator validates client secret credentials against a client registry
+// The result's subject is the client ID, and its claims are the client's
+// claims and a "client_id" claim.
+type Validator struct {
+	registry    Registry
+	trustDomain string
+}
+
+// ValidatorConfig configures a client secret validator
+type ValidatorConfig struct {
+	// Registry is the registry of clients
+	Registry Registry
+
+	// TrustDomain is the trust domain of the clients
+	TrustDomain string
+}
+
+// NewValidator creates a client secret validator
+func NewValidator(cfg ValidatorConfig) (*Validator, error) {
+	if cfg.Registry == nil {
+		return nil, fmt.Errorf("client registry is required")
+	}
+	if cfg.TrustDomain == "" {
+		return nil, fmt.Errorf("trust domain is required")
+	}
+	return &Validator{registry: cfg.Registry, trustDomain: cfg.TrustDomain}, nil
+}
+
+// CredentialTypes implements trust.Validator
+func (v *Validator) CredentialTypes() []trust.CredentialType {
+	return []trust.CredentialType{trust.CredentialTypeClientSecret}
+}
+
+// Validate implements trust.Validator
+// Unknown clients and wrong secrets fail alike, so callers can't probe for
+// client IDs.
+func (v *Validator) Validate(ctx context.Context, credential trust.Credential) (*trust.Result, error) {
+	cred, ok := credential.(*trust.ClientSecretCredential)
+	if !ok {
+		return nil, fmt.Errorf("unsupported credential type for client secret validator: %T", credential)
+	}
+	if cred.ClientID == "" || cred.ClientSecret == "" {
+		return nil, fmt.Errorf("%w: client ID and secret are required", trust.ErrInvalidToken)
+	}
+
+	client, err := v.registry.Client(ctx, cred.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil || !client.VerifySecret(cred.ClientSecret) {
+		return nil, fmt.Errorf("%w: invalid client credentials", trust.ErrInvalidToken)
+	}
+
+	resultClaims := make(claims.Claims, len(client.Claims)+1)
+	maps.Copy(resultClaims, client.Claims)
+	resultClaims["client_id"] = client.ID
+
+	return &trust.Result{
+		Subject:     client.ID,
+		TrustDomain: v.trustDomain,
+		Claims:      resultClaims,
+	}, nil
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/project-kessel/parsec/internal/server"
+)
+
+// NewActorCredentialExtractor creates the actor credential extractor chain shared
+// by the ext_authz and token exchange servers
+// Returns the default chain (mtls, then bearer) if no sources are configured.
+func NewActorCredentialExtractor(cfgs []ActorCredentialSourceConfig) (server.ActorCredentialExtractor, error) {
+	if len(cfgs) == 0 {
+		return server.DefaultActorCredentialExtractor(), nil
+	}
+
+	chain := make(server.ActorCredentialExtractorChain, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		extractor, err := newActorCredentialSource(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid actor credential source %d: %w", i, err)
+		}
+		chain = append(chain, extractor)
+	}
+	return chain, nil
+}
+
+func newActorCredentialSource(cfg ActorCredentialSourceConfig) (server.ActorCredentialExtractor, error) {
+	switch cfg.Type {
+	case "mtls":
+		return server.MTLSActorCredentialExtractor{}, nil
+	case "bearer":
+		return server.BearerActorCredentialExtractor{Key: cfg.Key}, nil
+	case "header":
+		if cfg.Key == "" {
+			return nil, fmt.Errorf("key is required for header actor credentials")
+		}
+		return server.HeaderActorCredentialExtractor{Key: cfg.Key}, nil
+	case "client_secret":
+		return server.ClientSecretActorCredentialExtractor{}, nil
+	default:
+		return nil, fmt.Errorf("unknown actor credential source type: %s (supported: mtls, bearer, header, client_secret)", cfg.Type)
+	}
+}
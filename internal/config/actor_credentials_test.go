@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/server"
+)
+
+func TestNewActorCredentialExtractor(t *testing.T) {
+	extractor, err := NewActorCredentialExtractor([]ActorCredentialSourceConfig{
+		{Type: "header", Key: "x-actor-token"},
+		{Type: "mtls"},
+		{Type: "bearer"},
+		{Type: "client_secret"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	chain, ok := extractor.(server.ActorCredentialExtractorChain)
+	if !ok || len(chain) != 4 {
+		t.Fatalf("expected chain of 4 extractors, got %#v", extractor)
+	}
+	if header, ok := chain[0].(server.HeaderActorCredentialExtractor); !ok || header.Key != "x-actor-token" {
+		t.Errorf("expected header extractor first, got %#v", chain[0])
+	}
+
+	for _, cfg := range []ActorCredentialSourceConfig{{Type: "header"}, {Type: "cookie"}} {
+		if _, err := NewActorCredentialExtractor([]ActorCredentialSourceConfig{cfg}); err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+}
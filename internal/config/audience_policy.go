@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trustdomain"
+)
+
+// NewAudiencePolicy creates the policy deciding the audiences tokens may be
+// requested for
+// Returns nil if cfg is nil (the token service allows the trust domain only).
+func NewAudiencePolicy(cfg *AudiencePolicyConfig, trustDomain string) (service.AudiencePolicy, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	switch cfg.Type {
+	case "exact":
+		return &service.ExactAudiencePolicy{Audience: trustDomain}, nil
+	case "allowlist":
+		if len(cfg.Audiences) == 0 {
+			return nil, fmt.Errorf("allowlist audience policy requires audiences")
+		}
+		if err := trustdomain.ValidatePatterns(cfg.Audiences); err != nil {
+			return nil, fmt.Errorf("allowlist audience policy: %w", err)
+		}
+		return &service.AllowlistAudiencePolicy{Audiences: cfg.Audiences}, nil
+	case "cel":
+		return service.NewCelAudiencePolicy(cfg.Script, trustDomain)
+	default:
+		return nil, fmt.Errorf("unknown audience policy type: %s (supported: exact, allowlist, cel)", cfg.Type)
+	}
+}
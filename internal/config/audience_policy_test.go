@@ -0,0 +1,71 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestNewAudiencePolicy(t *testing.T) {
+	if policy, err := NewAudiencePolicy(nil, "parsec.test"); policy != nil || err != nil {
+		t.Errorf("expected no policy without config, got %v (%v)", policy, err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     AudiencePolicyConfig
+		allowed []string
+		denied  []string
+	}{
+		{
+			name:    "exact",
+			cfg:     AudiencePolicyConfig{Type: "exact"},
+			allowed: []string{"parsec.test"},
+			denied:  []string{"orders.parsec.test"},
+		},
+		{
+			name:    "allowlist",
+			cfg:     AudiencePolicyConfig{Type: "allowlist", Audiences: []string{"parsec.test", "*.apis.parsec.test"}},
+			allowed: []string{"parsec.test", "orders.apis.parsec.test"},
+			denied:  []string{"apis.parsec.test", "orders.parsec.test"},
+		},
+		{
+			name:    "cel",
+			cfg:     AudiencePolicyConfig{Type: "cel", Script: `audience == trust_domain || audience == subject.subject + ".parsec.test"`},
+			allowed: []string{"parsec.test", "alice.parsec.test"},
+			denied:  []string{"bob.parsec.test"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewAudiencePolicy(&tt.cfg, "parsec.test")
+			if err != nil {
+				t.Fatalf("NewAudiencePolicy failed: %v", err)
+			}
+			subject := &trust.Result{Subject: "alice"}
+			for _, audience := range tt.allowed {
+				if ok, err := policy.AllowsAudience(context.Background(), audience, subject, nil); !ok || err != nil {
+					t.Errorf("expected %q to be allowed, got %v (%v)", audience, ok, err)
+				}
+			}
+			for _, audience := range tt.denied {
+				if ok, err := policy.AllowsAudience(context.Background(), audience, subject, nil); ok || err != nil {
+					t.Errorf("expected %q to be denied, got %v (%v)", audience, ok, err)
+				}
+			}
+		})
+	}
+
+	for _, cfg := range []AudiencePolicyConfig{
+		{Type: "allowlist"},
+		{Type: "allowlist", Audiences: []string{"api.*.parsec.test"}},
+		{Type: "cel"},
+		{Type: "cel", Script: "audience +"},
+		{Type: "everything"},
+	} {
+		if _, err := NewAudiencePolicy(&cfg, "parsec.test"); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/project-kessel/parsec/internal/server"
+)
+
+// defaultBodyHashMethods are the state-changing methods whose bodies are
+// hashed by default
+var defaultBodyHashMethods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// NewBodyHash creates the ext_authz hashing of request bodies, or nil if it
+// isn't configured
+func NewBodyHash(cfg *BodyHashConfig) (*server.BodyHash, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	methods := defaultBodyHashMethods
+	if len(cfg.Methods) > 0 {
+		methods = make([]string, len(cfg.Methods))
+		for i, method := range cfg.Methods {
+			if strings.TrimSpace(method) == "" {
+				return nil, fmt.Errorf("body_hash methods must not be empty")
+			}
+			methods[i] = strings.ToUpper(strings.TrimSpace(method))
+		}
+	}
+	return &server.BodyHash{Methods: methods, Required: cfg.Required}, nil
+}
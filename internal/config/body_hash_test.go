@@ -0,0 +1,32 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewBodyHash(t *testing.T) {
+	if hash, err := NewBodyHash(nil); hash != nil || err != nil {
+		t.Errorf("expected no body hash, got %v, %v", hash, err)
+	}
+
+	hash, err := NewBodyHash(&BodyHashConfig{})
+	if err != nil {
+		t.Fatalf("NewBodyHash failed: %v", err)
+	}
+	if !slices.Equal(hash.Methods, []string{"POST", "PUT", "PATCH", "DELETE"}) || hash.Required {
+		t.Errorf("unexpected default body hash %+v", hash)
+	}
+
+	hash, err = NewBodyHash(&BodyHashConfig{Methods: []string{"post", " Put "}, Required: true})
+	if err != nil {
+		t.Fatalf("NewBodyHash failed: %v", err)
+	}
+	if !slices.Equal(hash.Methods, []string{"POST", "PUT"}) || !hash.Required {
+		t.Errorf("unexpected body hash %+v", hash)
+	}
+
+	if _, err := NewBodyHash(&BodyHashConfig{Methods: []string{""}}); err == nil {
+		t.Error("expected error for an empty method")
+	}
+}
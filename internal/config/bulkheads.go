@@ -0,0 +1,27 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// newBulkhead creates the named bulkhead, or returns nil if none is configured
+// A nil clock defaults to the system clock.
+func newBulkhead(name string, cfg *BulkheadConfig, observer service.BulkheadObserver, clk clock.Clock) (*service.Bulkhead, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	bulkhead, err := service.NewBulkhead(service.BulkheadConfig{
+		Name:          name,
+		MaxConcurrent: cfg.MaxConcurrent,
+		MaxQueued:     cfg.MaxQueued,
+		Observer:      observer,
+		Clock:         clk,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid bulkhead: %w", err)
+	}
+	return bulkhead, nil
+}
@@ -2,8 +2,12 @@ package config
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/project-kessel/parsec/internal/claims"
 	"github.com/project-kessel/parsec/internal/server"
+	"github.com/project-kessel/parsec/internal/trust"
+	"github.com/project-kessel/parsec/internal/trustdomain"
 )
 
 // NewClaimsFilterRegistry creates a claims filter registry from configuration
@@ -12,7 +16,150 @@ func NewClaimsFilterRegistry(cfg ClaimsFilterConfig) (server.ClaimsFilterRegistr
 	case "stub", "":
 		// Default to stub (passthrough) filter
 		return server.NewStubClaimsFilterRegistry(), nil
+	case "allowlist", "denylist", "cel":
+		filter, err := newClaimsFilter(ClaimsFilterSpecConfig{
+			Type:          cfg.Type,
+			Script:        cfg.Script,
+			AllowedClaims: cfg.AllowedClaims,
+			DeniedClaims:  cfg.DeniedClaims,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return server.NewStubClaimsFilterRegistryWithFilter(filter), nil
+	case "rules":
+		return newRuleClaimsFilterRegistry(cfg)
 	default:
-		return nil, fmt.Errorf("unknown claims filter type: %s (supported: stub)", cfg.Type)
+		return nil, fmt.Errorf("unknown claims filter type: %s (supported: stub, allowlist, denylist, cel, rules)", cfg.Type)
 	}
 }
+
+// newRuleClaimsFilterRegistry creates a registry that selects filters per actor
+func newRuleClaimsFilterRegistry(cfg ClaimsFilterConfig) (server.ClaimsFilterRegistry, error) {
+	rules := make([]server.ClaimsFilterRule, 0, len(cfg.Rules))
+	for i, ruleCfg := range cfg.Rules {
+		filter, err := newClaimsFilter(ruleCfg.ClaimsFilterSpecConfig)
+		if err != nil {
+			return nil, fmt.Errorf("invalid claims filter rule %d: %w", i, err)
+		}
+		if err := trustdomain.ValidatePattern(ruleCfg.TrustDomain); err != nil {
+			return nil, fmt.Errorf("invalid claims filter rule %d: %w", i, err)
+		}
+		rules = append(rules, server.ClaimsFilterRule{
+			TrustDomain: ruleCfg.TrustDomain,
+			Subject:     ruleCfg.Subject,
+			Filter:      filter,
+		})
+	}
+
+	var defaultFilter claims.ClaimsFilter
+	if cfg.Default != nil {
+		filter, err := newClaimsFilter(*cfg.Default)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default claims filter: %w", err)
+		}
+		defaultFilter = filter
+	}
+
+	return server.NewRuleClaimsFilterRegistry(rules, defaultFilter), nil
+}
+
+// newClaimsFilter creates a single claims filter from configuration
+func newClaimsFilter(cfg ClaimsFilterSpecConfig) (claims.ClaimsFilter, error) {
+	switch cfg.Type {
+	case "passthrough":
+		return &claims.PassthroughClaimsFilter{}, nil
+	case "allowlist":
+		return claims.NewAllowListClaimsFilter(cfg.AllowedClaims), nil
+	case "denylist":
+		return claims.NewDenyListClaimsFilter(cfg.DeniedClaims), nil
+	case "cel":
+		if cfg.Script == "" {
+			return nil, fmt.Errorf("cel claims filter requires script")
+		}
+		return claims.NewCelClaimsFilter(cfg.Script)
+	default:
+		return nil, fmt.Errorf("unknown claims filter: %q (supported: passthrough, allowlist, denylist, cel)", cfg.Type)
+	}
+}
+
+// NewRequestContextSchemaRegistry creates a request_context schema registry from configuration
+// Returns nil if no schemas are configured
+func NewRequestContextSchemaRegistry(cfgs []RequestContextSchemaConfig) (server.RequestContextSchemaRegistry, error) {
+	if len(cfgs) == 0 {
+		return nil, nil
+	}
+
+	rules := make([]server.RequestContextSchemaRule, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		schema, err := loadRequestContextSchema(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid request_context schema %d: %w", i, err)
+		}
+		if err := trustdomain.ValidatePattern(cfg.TrustDomain); err != nil {
+			return nil, fmt.Errorf("invalid request_context schema %d: %w", i, err)
+		}
+		rules = append(rules, server.RequestContextSchemaRule{
+			TrustDomain: cfg.TrustDomain,
+			Subject:     cfg.Subject,
+			Schema:      schema,
+		})
+	}
+
+	return server.NewRuleRequestContextSchemaRegistry(rules), nil
+}
+
+// loadRequestContextSchema compiles an inline or file-based schema
+func loadRequestContextSchema(cfg RequestContextSchemaConfig) (*claims.Schema, error) {
+	var data []byte
+	switch {
+	case cfg.Schema != "" && cfg.SchemaFile != "":
+		return nil, fmt.Errorf("schema and schema_file are mutually exclusive")
+	case cfg.Schema != "":
+		data = []byte(cfg.Schema)
+	case cfg.SchemaFile != "":
+		var err error
+		data, err = os.ReadFile(cfg.SchemaFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema file: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("schema or schema_file is required")
+	}
+
+	return claims.ParseSchema(data)
+}
+
+// NewDelegationPolicy creates a delegation policy from configuration
+// A nil configuration allows all delegations
+func NewDelegationPolicy(cfg *DelegationConfig) (trust.DelegationPolicy, error) {
+	if cfg == nil {
+		return &trust.AllowAllDelegationPolicy{}, nil
+	}
+
+	switch cfg.Type {
+	case "allow_all", "":
+		return &trust.AllowAllDelegationPolicy{}, nil
+	case "may_act":
+		return trust.NewMayActDelegationPolicy(cfg.RequireMayAct), nil
+	case "cel":
+		if cfg.Script == "" {
+			return nil, fmt.Errorf("cel delegation policy requires script")
+		}
+		return trust.NewCelDelegationPolicy(cfg.Script)
+	default:
+		return nil, fmt.Errorf("unknown delegation policy type: %s (supported: allow_all, may_act, cel)", cfg.Type)
+	}
+}
+
+// NewSelfIssuancePolicy creates the policy gating the client_credentials grant
+// Returns nil (grant disabled) if cfg is nil.
+func NewSelfIssuancePolicy(cfg *ClientCredentialsConfig) (trust.SelfIssuancePolicy, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.Script == "" {
+		return nil, fmt.Errorf("client_credentials requires script")
+	}
+	return trust.NewCelSelfIssuancePolicy(cfg.Script)
+}
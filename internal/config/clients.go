@@ -0,0 +1,110 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/clients"
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/server"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trustdomain"
+)
+
+// NewClientRegistry creates the client registry from configuration
+// Returns nil if cfg is nil. dataSources may be nil when only validating the
+// configuration, in which case the data source is not looked up.
+func NewClientRegistry(cfg *ClientsConfig, dataSources *service.DataSourceRegistry) (clients.Registry, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	if cfg.DataSource != "" {
+		if len(cfg.Static) > 0 {
+			return nil, fmt.Errorf("static and data_source are mutually exclusive")
+		}
+		if dataSources == nil {
+			return nil, nil
+		}
+		ds := dataSources.Get(cfg.DataSource)
+		if ds == nil {
+			return nil, fmt.Errorf("unknown data source: %s", cfg.DataSource)
+		}
+		return clients.NewDataSourceRegistry(ds), nil
+	}
+
+	static := make([]clients.Client, 0, len(cfg.Static))
+	for i, clientCfg := range cfg.Static {
+		if err := trustdomain.ValidatePatterns(append([]string{clientCfg.TrustDomain}, clientCfg.Audiences...)); err != nil {
+			return nil, fmt.Errorf("static[%d]: %w", i, err)
+		}
+		client := clients.Client{
+			ID:          clientCfg.ClientID,
+			TrustDomain: clientCfg.TrustDomain,
+			Claims:      claims.Claims(clientCfg.Claims),
+			Audiences:   clientCfg.Audiences,
+		}
+		if clientCfg.TokenTypes != nil {
+			client.TokenTypes = make([]service.TokenType, len(clientCfg.TokenTypes))
+			for j, tokenType := range clientCfg.TokenTypes {
+				client.TokenTypes[j] = service.TokenType(tokenType)
+			}
+		}
+		if clientCfg.ClaimsFilter != nil {
+			filter, err := newClaimsFilter(*clientCfg.ClaimsFilter)
+			if err != nil {
+				return nil, fmt.Errorf("static[%d].claims_filter: %w", i, err)
+			}
+			client.ClaimsFilter = filter
+		}
+		if clientCfg.Quota != nil {
+			window, err := time.ParseDuration(clientCfg.Quota.Window)
+			if err != nil {
+				return nil, fmt.Errorf("static[%d].quota: invalid window: %w", i, err)
+			}
+			client.Quota = &clients.Quota{Limit: clientCfg.Quota.Limit, Window: window}
+		}
+		for _, secret := range clientCfg.Secrets {
+			if secret == "" {
+				return nil, fmt.Errorf("static[%d]: empty secret", i)
+			}
+			client.SecretHashes = append(client.SecretHashes, clients.HashSecret(secret))
+		}
+		for _, s := range clientCfg.SecretSHA256 {
+			hash, err := clients.ParseSecretHash(s)
+			if err != nil {
+				return nil, fmt.Errorf("static[%d]: %w", i, err)
+			}
+			client.SecretHashes = append(client.SecretHashes, hash)
+		}
+		static = append(static, client)
+	}
+	return clients.NewStaticRegistry(static)
+}
+
+// NewClientPolicy creates the policy applying the registered clients' policies
+// to the servers' exchanges and checks
+// Returns nil if registry is nil.
+func NewClientPolicy(registry clients.Registry, clk clock.Clock) *server.ClientPolicy {
+	if registry == nil {
+		return nil
+	}
+	return server.NewClientPolicy(registry, clk)
+}
+
+// lazyClientRegistry resolves the client registry on first use, so the trust
+// store can be built before the data sources
+type lazyClientRegistry struct {
+	registry func() (clients.Registry, error)
+}
+
+// Client implements clients.Registry
+func (r *lazyClientRegistry) Client(ctx context.Context, id string) (*clients.Client, error) {
+	registry, err := r.registry()
+	if err != nil {
+		return nil, err
+	}
+	return registry.Client(ctx, id)
+}
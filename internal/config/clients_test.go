@@ -0,0 +1,92 @@
+package config
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestNewClientRegistry(t *testing.T) {
+	if registry, err := NewClientRegistry(nil, nil); registry != nil || err != nil {
+		t.Errorf("expected no registry without config, got %v (%v)", registry, err)
+	}
+
+	registry, err := NewClientRegistry(&ClientsConfig{Static: []ClientConfig{
+		{ClientID: "billing", Secrets: []string{"s3cret"}, Claims: map[string]any{"team": "billing"}},
+		{ClientID: "reports", SecretSHA256: []string{"1ec1c26b50d5d3c58d9583181af8076655fe00756bf7285940ba3670f99fcba0"}},
+	}}, nil)
+	if err != nil {
+		t.Fatalf("NewClientRegistry failed: %v", err)
+	}
+
+	validator, err := newValidator(ValidatorConfig{Type: "client_secret_validator", TrustDomain: "clients.example.com"}, nil, nil, nil, registry)
+	if err != nil {
+		t.Fatalf("newValidator failed: %v", err)
+	}
+	for _, id := range []string{"billing", "reports"} {
+		result, err := validator.Validate(context.Background(), &trust.ClientSecretCredential{ClientID: id, ClientSecret: "s3cret"})
+		if err != nil || result.Subject != id || result.TrustDomain != "clients.example.com" {
+			t.Errorf("expected %s to authenticate, got %v, %v", id, result, err)
+		}
+	}
+	if _, err := validator.Validate(context.Background(), &trust.ClientSecretCredential{ClientID: "billing", ClientSecret: "guess"}); err == nil {
+		t.Error("expected a wrong secret to be rejected")
+	}
+}
+
+func TestNewClientRegistry_Policies(t *testing.T) {
+	registry, err := NewClientRegistry(&ClientsConfig{Static: []ClientConfig{{
+		ClientID:     "spiffe://jobs.example.com/batch",
+		TrustDomain:  "jobs.example.com",
+		Audiences:    []string{"billing.example.com"},
+		TokenTypes:   []string{"urn:ietf:params:oauth:token-type:txn_token"},
+		ClaimsFilter: &ClaimsFilterSpecConfig{Type: "allowlist", AllowedClaims: []string{"purpose"}},
+		Quota:        &ClientQuotaConfig{Limit: 100, Window: "1m"},
+	}}}, nil)
+	if err != nil {
+		t.Fatalf("NewClientRegistry failed: %v", err)
+	}
+
+	client, err := registry.Client(context.Background(), "spiffe://jobs.example.com/batch")
+	if err != nil || client == nil {
+		t.Fatalf("expected the batch client, got %v, %v", client, err)
+	}
+	if client.TrustDomain != "jobs.example.com" || !slices.Equal(client.Audiences, []string{"billing.example.com"}) {
+		t.Errorf("unexpected client: %+v", client)
+	}
+	if !client.AllowsTokenType(service.TokenTypeTransactionToken) || client.AllowsTokenType(service.TokenTypeAccessToken) {
+		t.Errorf("expected only transaction tokens to be allowed, got %v", client.TokenTypes)
+	}
+	if filtered := client.ClaimsFilter.Filter(claims.Claims{"purpose": "sync", "tenant": "acme"}); len(filtered) != 1 {
+		t.Errorf("expected an allowlist of purpose, got %v", filtered)
+	}
+	if client.Quota == nil || client.Quota.Limit != 100 || client.Quota.Window != time.Minute {
+		t.Errorf("unexpected quota: %+v", client.Quota)
+	}
+
+	if policy := NewClientPolicy(nil, nil); policy != nil {
+		t.Errorf("expected no policy without a registry, got %v", policy)
+	}
+}
+
+func TestNewClientRegistry_Errors(t *testing.T) {
+	for name, cfg := range map[string]*ClientsConfig{
+		"no client ID":       {Static: []ClientConfig{{Secrets: []string{"s3cret"}}}},
+		"invalid filter":     {Static: []ClientConfig{{ClientID: "billing", ClaimsFilter: &ClaimsFilterSpecConfig{Type: "regex"}}}},
+		"invalid window":     {Static: []ClientConfig{{ClientID: "billing", Quota: &ClientQuotaConfig{Limit: 10, Window: "soon"}}}},
+		"no quota limit":     {Static: []ClientConfig{{ClientID: "billing", Quota: &ClientQuotaConfig{Window: "1m"}}}},
+		"empty secret":       {Static: []ClientConfig{{ClientID: "billing", Secrets: []string{""}}}},
+		"invalid hash":       {Static: []ClientConfig{{ClientID: "billing", SecretSHA256: []string{"abc"}}}},
+		"duplicate client":   {Static: []ClientConfig{{ClientID: "billing", Secrets: []string{"a"}}, {ClientID: "billing", Secrets: []string{"b"}}}},
+		"static and lookups": {Static: []ClientConfig{{ClientID: "billing", Secrets: []string{"a"}}}, DataSource: "clients"},
+	} {
+		if _, err := NewClientRegistry(cfg, nil); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -15,21 +15,74 @@ type Config struct {
 	// ExchangeServer configuration for token exchange service
 	ExchangeServer *ExchangeServerConfig `koanf:"exchange_server"`
 
+	// IntrospectionServer configuration for token introspection (RFC 7662)
+	IntrospectionServer *IntrospectionServerConfig `koanf:"introspection_server"`
+
+	// AdminServer configuration for the admin endpoints (cache invalidation)
+	AdminServer *AdminServerConfig `koanf:"admin_server"`
+
 	// TrustStore configuration (validators and filtering)
 	TrustStore TrustStoreConfig `koanf:"trust_store"`
 
 	// DataSources for token enrichment
 	DataSources []DataSourceConfig `koanf:"data_sources"`
 
+	// DistributedCache configures the groupcache peer pool shared by data sources
+	// with distributed caching
+	DistributedCache *DistributedCacheConfig `koanf:"distributed_cache"`
+
 	// KeyProviders defines named key provider instances
 	KeyProviders []KeyProviderConfig `koanf:"key_providers"`
 
 	// Signers defines named signer instances (e.g., rotating key signers)
 	Signers []SignerConfig `koanf:"signers"`
 
+	// KeySlotStore persists the key slots of rotating signers (optional;
+	// in memory by default)
+	KeySlotStore *KeySlotStoreConfig `koanf:"key_slot_store"`
+
 	// Issuers configuration for different token types
 	Issuers []IssuerConfig `koanf:"issuers"`
 
+	// Plugins defines named out-of-process plugins for plugin issuers and data sources
+	Plugins []PluginConfig `koanf:"plugins"`
+
+	// IssuanceTimeout bounds each token issuance, including claim mapping, data
+	// source fetches and signing (default: 10s, "0" disables)
+	IssuanceTimeout string `koanf:"issuance_timeout" usage:"deadline for each token issuance (e.g. 5s, 0 disables)"`
+
+	// IdentityMapping translates external subjects into canonical principal IDs
+	// before tokens are issued (optional)
+	IdentityMapping *IdentityMappingConfig `koanf:"identity_mapping"`
+
+	// Quotas cap the tokens issued per subject, actor or other key per time
+	// window (optional)
+	Quotas *QuotasConfig `koanf:"quotas"`
+
+	// Risk assesses each issuance before tokens are issued (optional)
+	Risk *RiskConfig `koanf:"risk"`
+
+	// AudiencePolicy decides the audiences tokens may be requested for
+	// (optional, default: the trust domain only)
+	AudiencePolicy *AudiencePolicyConfig `koanf:"audience_policy"`
+
+	// Clients is the registry of clients: the actors allowed to call parsec,
+	// their credentials and their policies (optional)
+	Clients *ClientsConfig `koanf:"clients"`
+
+	// DecisionLog streams an audit event for every token issuance (optional)
+	DecisionLog *DecisionLogConfig `koanf:"decision_log"`
+
+	// TokenWebhook POSTs a summary of every issued token to a webhook (optional)
+	TokenWebhook *TokenWebhookConfig `koanf:"token_webhook"`
+
+	// TokenLedger records recently issued tokens for the admin endpoints to
+	// query (optional)
+	TokenLedger *TokenLedgerConfig `koanf:"token_ledger"`
+
+	// Telemetry exports metrics for scraping or pushes them with OTLP (optional)
+	Telemetry *TelemetryConfig `koanf:"telemetry"`
+
 	// Fixtures for hermetic testing (HTTP rules, etc.)
 	Fixtures []FixtureConfig `koanf:"fixtures"`
 
@@ -44,12 +97,200 @@ type ServerConfig struct {
 
 	// HTTPPort is the port for HTTP services (gRPC-gateway transcoding)
 	HTTPPort int `koanf:"http_port" usage:"HTTP server port (gRPC-gateway transcoding)"`
+
+	// ActorCredentials are the sources of the calling actor's credential, tried
+	// in order by both the ext_authz and token exchange servers
+	// Defaults to mtls, then bearer
+	ActorCredentials []ActorCredentialSourceConfig `koanf:"actor_credentials"`
+
+	// TrustedProxies are the CIDRs of proxies whose x-forwarded-for entries
+	// are trusted when deriving the caller's address (loopback is always trusted)
+	TrustedProxies []string `koanf:"trusted_proxies"`
+}
+
+// ActorCredentialSourceConfig configures a source of actor credentials
+type ActorCredentialSourceConfig struct {
+	// Type selects where the credential is read from
+	// Options: "mtls" (peer certificate), "bearer" (Bearer token in a metadata key),
+	// "header" (whole metadata value as a bearer token), "client_secret"
+	// (HTTP basic authentication, or client_id and client_secret metadata)
+	Type string `koanf:"type"`
+
+	// Key is the gRPC metadata key (bearer default: "authorization"; required for header)
+	Key string `koanf:"key"`
 }
 
 // AuthzServerConfig configures the ext_authz authorization server
 type AuthzServerConfig struct {
 	// TokenTypes specifies which token types to issue and how to deliver them
 	TokenTypes []TokenTypeConfig `koanf:"token_types"`
+
+	// SubjectCredentials are the places in the checked request the subject's
+	// credential is read from, tried in order
+	// Defaults to a Bearer token in the Authorization header
+	SubjectCredentials []SubjectCredentialSourceConfig `koanf:"subject_credentials"`
+
+	// HeaderSizeLimit bounds the size of the tokens emitted in response headers (optional)
+	HeaderSizeLimit *HeaderSizeLimitConfig `koanf:"header_size_limit"`
+
+	// ResponseHeaders are additional headers emitted alongside the tokens,
+	// with values derived by claim mappers (optional)
+	ResponseHeaders []ResponseHeaderConfig `koanf:"response_headers"`
+
+	// DenyCache caches subject credentials that failed validation (optional)
+	DenyCache *DenyCacheConfig `koanf:"deny_cache"`
+
+	// SkipPaths are request paths allowed without validation or token
+	// issuance, such as health checks and public assets (optional)
+	SkipPaths []SkipPathConfig `koanf:"skip_paths"`
+
+	// IPPolicy allows or denies checks by the client address (optional)
+	IPPolicy *IPPolicyConfig `koanf:"ip_policy"`
+
+	// BodyHash records the SHA-256 digest of forwarded request bodies as the
+	// body_sha256 request attribute (optional)
+	BodyHash *BodyHashConfig `koanf:"body_hash"`
+
+	// RequestAttributes are extra attributes derived from the checked request
+	// for trust store filters and claim mappers (optional)
+	RequestAttributes []RequestAttributeConfig `koanf:"request_attributes"`
+
+	// DynamicMetadata sets identity attributes in Envoy dynamic metadata
+	// (optional)
+	DynamicMetadata *DynamicMetadataConfig `koanf:"dynamic_metadata"`
+}
+
+// DynamicMetadataConfig selects the identity attributes ext_authz sets in
+// Envoy dynamic metadata
+type DynamicMetadataConfig struct {
+	// Fields are the identity attributes to set
+	// Options: "subject", "issuer", "trust_domain", "actor",
+	// "actor_trust_domain", "txn"
+	Fields []string `koanf:"fields"`
+
+	// Claims are subject claims to set under "claims"
+	Claims []string `koanf:"claims"`
+}
+
+// RequestAttributeConfig configures an extractor of request attributes
+type RequestAttributeConfig struct {
+	// Type selects the extractor
+	// Options: "jwt_claims" (unverified claims of a JWT in Header),
+	// "filter_metadata" (Envoy dynamic metadata in Namespace),
+	// "query_params" (query parameters in Names, or all)
+	Type string `koanf:"type"`
+
+	// Key is the request_attributes.additional key of the attributes
+	Key string `koanf:"key"`
+
+	// Header and optional Scheme locate the JWT (jwt_claims only)
+	Header string `koanf:"header"`
+	Scheme string `koanf:"scheme"`
+
+	// Namespace is the filter metadata namespace (filter_metadata only)
+	Namespace string `koanf:"namespace"`
+
+	// Names are the query parameters to read (query_params only)
+	Names []string `koanf:"names"`
+}
+
+// BodyHashConfig configures the hashing of request bodies Envoy forwards to
+// ext_authz
+type BodyHashConfig struct {
+	// Methods are the HTTP methods whose bodies are hashed
+	// Defaults to POST, PUT, PATCH and DELETE.
+	Methods []string `koanf:"methods"`
+
+	// Required denies checks of those methods whose complete body was not
+	// forwarded
+	Required bool `koanf:"required"`
+}
+
+// IPPolicyConfig configures the ext_authz client address policy
+// CIDRs may be bare addresses, matching that address only.
+type IPPolicyConfig struct {
+	// Allow are the client CIDRs allowed; empty allows all that are not denied
+	Allow []string `koanf:"allow"`
+
+	// Deny are the client CIDRs denied, even if allowed
+	Deny []string `koanf:"deny"`
+
+	// TrustedProxies are the CIDRs of proxies in front of Envoy whose
+	// x-forwarded-for entries are trusted when deriving the client address
+	// (loopback is always trusted)
+	TrustedProxies []string `koanf:"trusted_proxies"`
+}
+
+// SkipPathConfig matches request paths checked without validation
+type SkipPathConfig struct {
+	// Type selects how Path is matched
+	// Options: "exact", "prefix", "regex"
+	Type string `koanf:"type"`
+
+	// Path is the path, path prefix or regular expression to match, without
+	// a query string
+	Path string `koanf:"path"`
+}
+
+// DenyCacheConfig configures the ext_authz cache of validation failures
+type DenyCacheConfig struct {
+	// MaxEntries bounds the number of cached failures (default: 10000)
+	MaxEntries int `koanf:"max_entries"`
+
+	// TTL bounds how long a failure is cached (default: 10s)
+	TTL string `koanf:"ttl"`
+}
+
+// ResponseHeaderConfig configures a header derived by a claim mapper
+type ResponseHeaderConfig struct {
+	// HeaderName is the HTTP header to emit
+	HeaderName string `koanf:"header_name"`
+
+	// Mapper derives the header value from the subject, actor and request
+	Mapper ClaimMapperConfig `koanf:"mapper"`
+
+	// Claim is the mapped claim holding the value
+	// If empty, all mapped claims are emitted as a JSON object.
+	Claim string `koanf:"claim"`
+
+	// Encoding of the value
+	// Options: "" (default, as is), "base64"
+	Encoding string `koanf:"encoding"`
+}
+
+// HeaderSizeLimitConfig bounds the size of tokens emitted by ext_authz
+type HeaderSizeLimitConfig struct {
+	// MaxTokenSize is the largest token value emitted in a header, in bytes
+	MaxTokenSize int `koanf:"max_token_size"`
+
+	// OnExceeded selects what happens to a token over MaxTokenSize
+	// Options: "deny" (default, with a token_too_large status), "reference_token"
+	// (issue ReferenceTokenType in its place)
+	OnExceeded string `koanf:"on_exceeded"`
+
+	// ReferenceTokenType is the token type issued in place of oversized tokens,
+	// typically one with a reference_token issuer
+	ReferenceTokenType string `koanf:"reference_token_type"`
+}
+
+// SubjectCredentialSourceConfig configures a source of subject credentials
+type SubjectCredentialSourceConfig struct {
+	// Type selects where the credential is read from
+	// Options: "header", "cookie", "query" (query parameter),
+	// "rh_identity" (base64 JSON in the x-rh-identity header)
+	Type string `koanf:"type"`
+
+	// Name is the header, cookie, or query parameter name
+	// Not used by rh_identity.
+	Name string `koanf:"name"`
+
+	// Scheme is an authorization scheme the header value must start with,
+	// e.g. "Bearer". Values with another scheme are skipped. Header only.
+	Scheme string `koanf:"scheme"`
+
+	// CredentialType is the credential built from the value
+	// Options: "bearer" (default), "jwt", "json" (rh_identity default)
+	CredentialType string `koanf:"credential_type"`
 }
 
 // TokenTypeConfig specifies a token type to issue via ext_authz
@@ -70,6 +311,98 @@ type TokenTypeConfig struct {
 type ExchangeServerConfig struct {
 	// ClaimsFilter determines which request_context claims actors can provide
 	ClaimsFilter ClaimsFilterConfig `koanf:"claims_filter"`
+
+	// Delegation determines whether an actor may act on behalf of a subject
+	Delegation *DelegationConfig `koanf:"delegation"`
+
+	// ClientCredentials enables the client_credentials grant, which issues an
+	// authenticated actor a token representing itself (disabled if nil)
+	ClientCredentials *ClientCredentialsConfig `koanf:"client_credentials"`
+
+	// RejectedClaimsWarning returns the names of request_context claims dropped
+	// by the claims filter in the parsec-rejected-claims response metadata
+	RejectedClaimsWarning bool `koanf:"rejected_claims_warning" usage:"report request_context claims dropped by the claims filter in response metadata"`
+
+	// RequestContextSchemas select a JSON Schema that filtered request_context
+	// claims must satisfy, evaluated in order; the first match wins
+	RequestContextSchemas []RequestContextSchemaConfig `koanf:"request_context_schemas"`
+
+	// AllowedAudiences are the audiences tokens may be requested for with the
+	// audience and resource parameters, besides the trust domain
+	AllowedAudiences []string `koanf:"allowed_audiences"`
+}
+
+// RequestContextSchemaConfig selects a request_context schema for matching actors
+type RequestContextSchemaConfig struct {
+	// TrustDomain matches the actor's trust domain exactly (empty matches any)
+	TrustDomain string `koanf:"trust_domain"`
+
+	// Subject matches the actor's subject exactly, or by prefix when ending in "*"
+	Subject string `koanf:"subject"`
+
+	// Schema is an inline JSON Schema document
+	Schema string `koanf:"schema"`
+
+	// SchemaFile is a path to a JSON Schema document (alternative to Schema)
+	SchemaFile string `koanf:"schema_file"`
+}
+
+// DelegationConfig configures the delegation policy for token exchange
+type DelegationConfig struct {
+	// Type selects the delegation policy implementation
+	// Options: "allow_all", "may_act", "cel"
+	Type string `koanf:"type" usage:"delegation policy type: allow_all, may_act, cel"`
+
+	// RequireMayAct denies exchanges whose subject has no may_act claim (may_act type)
+	RequireMayAct bool `koanf:"require_may_act" usage:"deny delegation when the subject has no may_act claim"`
+
+	// Script is the CEL expression deciding delegation (cel type)
+	Script string `koanf:"script" usage:"CEL script for delegation policy"`
+}
+
+// ClientCredentialsConfig configures the client_credentials grant
+type ClientCredentialsConfig struct {
+	// Script is the CEL expression deciding which actors may obtain tokens for
+	// themselves, with "actor" and "request" variables (required)
+	Script string `koanf:"script"`
+}
+
+// IntrospectionServerConfig configures the token introspection endpoint
+type IntrospectionServerConfig struct {
+	// Enabled serves POST /v1/introspect on the HTTP port
+	Enabled bool `koanf:"enabled" usage:"serve the token introspection endpoint (RFC 7662)"`
+
+	// AllowUnauthenticated skips caller authentication
+	// By default callers must present a bearer credential accepted by the trust store
+	AllowUnauthenticated bool `koanf:"allow_unauthenticated" usage:"allow introspection without caller authentication"`
+
+	// AllowedCallers are the authenticated actors allowed to introspect tokens
+	// Required unless AllowUnauthenticated is set
+	AllowedCallers []AllowedCallerConfig `koanf:"allowed_callers"`
+}
+
+// AdminServerConfig configures the admin endpoints
+type AdminServerConfig struct {
+	// Enabled serves POST /v1/admin/cache/invalidate, /v1/admin/keys and the
+	// other admin endpoints on the HTTP port
+	Enabled bool `koanf:"enabled" usage:"serve the admin endpoints"`
+
+	// AllowUnauthenticated skips caller authentication
+	// By default callers must present a bearer credential accepted by the trust store
+	AllowUnauthenticated bool `koanf:"allow_unauthenticated" usage:"allow admin calls without caller authentication"`
+
+	// AllowedCallers are the authenticated actors allowed to call the admin endpoints
+	// Required unless AllowUnauthenticated is set
+	AllowedCallers []AllowedCallerConfig `koanf:"allowed_callers"`
+}
+
+// AllowedCallerConfig matches actors allowed to call an endpoint
+type AllowedCallerConfig struct {
+	// TrustDomain matches the caller's trust domain exactly
+	TrustDomain string `koanf:"trust_domain"`
+
+	// Subject matches the caller's subject exactly, or by prefix when ending in "*"
+	Subject string `koanf:"subject"`
 }
 
 // TrustStoreConfig configures the trust store and its validators
@@ -83,6 +416,35 @@ type TrustStoreConfig struct {
 
 	// Filter configuration (only used when Type is "filtered_store")
 	Filter *ValidatorFilterConfig `koanf:"filter"`
+
+	// ResultCache caches successful validations of bearer tokens (optional)
+	ResultCache *ResultCacheConfig `koanf:"result_cache"`
+
+	// Warmup configures fetching the validators' JWKS at startup (optional)
+	Warmup *WarmupConfig `koanf:"warmup"`
+}
+
+// WarmupConfig configures fetching the validators' JWKS at startup and after
+// a configuration reload, in parallel and with retries
+type WarmupConfig struct {
+	// Attempts bounds the fetches of each JWKS (default: 3)
+	Attempts int `koanf:"attempts"`
+
+	// Backoff is the wait before retrying, doubling for each retry (default: 1s)
+	Backoff string `koanf:"backoff"`
+
+	// AttemptTimeout bounds each fetch (default: 10s)
+	AttemptTimeout string `koanf:"attempt_timeout"`
+}
+
+// ResultCacheConfig configures the trust store's validation result cache
+type ResultCacheConfig struct {
+	// MaxEntries bounds the number of cached results (default: 10000)
+	MaxEntries int `koanf:"max_entries"`
+
+	// TTL bounds how long a result is cached; results never outlive their
+	// token's expiry (default: 1m)
+	TTL string `koanf:"ttl"`
 }
 
 // NamedValidatorConfig is a validator with a name (for FilteredStore)
@@ -97,7 +459,9 @@ type NamedValidatorConfig struct {
 // ValidatorConfig configures a credential validator
 type ValidatorConfig struct {
 	// Type selects the validator implementation
-	// Options: "jwt_validator", "json_validator", "stub_validator"
+	// Options: "jwt_validator", "introspection_validator", "spiffe_validator",
+	// "json_validator", "rh_identity_validator", "client_secret_validator",
+	// "txn_token_validator", "stub_validator"
 	Type string `koanf:"type"`
 
 	// JWT Validator fields
@@ -105,34 +469,103 @@ type ValidatorConfig struct {
 	JWKSURL         string `koanf:"jwks_url"`
 	TrustDomain     string `koanf:"trust_domain"`
 	RefreshInterval string `koanf:"refresh_interval"` // Duration string like "15m"
+	JWKSCacheDir    string `koanf:"jwks_cache_dir"`   // Directory persisting the last fetched JWKS (optional)
+
+	// Leeway is the clock skew tolerated when checking exp, nbf and iat, like
+	// "30s" (jwt_validator, spiffe_validator and txn_token_validator)
+	Leeway string `koanf:"leeway"`
+
+	// ClientIDs are the OIDC clients whose ID tokens the jwt_validator
+	// accepts as id_token subject tokens (none if empty)
+	ClientIDs []string `koanf:"client_ids"`
+
+	// Introspection Validator fields (RFC 7662)
+	// (Issuer and TrustDomain are shared)
+	Endpoint     string `koanf:"endpoint"`      // Introspection endpoint URL
+	ClientID     string `koanf:"client_id"`     // Basic auth client ID (optional)
+	ClientSecret string `koanf:"client_secret"` // Basic auth client secret
+
+	// Transaction Token Validator fields (parsec's own txn tokens, verified
+	// against its issuer keys; Issuer, TrustDomain and Audiences are shared)
+
+	// SPIFFE Validator fields (JWT-SVIDs)
+	// (TrustDomain and RefreshInterval are shared)
+	BundleURL string   `koanf:"bundle_url"` // JWT bundle (JWK Set) URL for the trust domain
+	Audiences []string `koanf:"audiences"`  // Accepted audiences (any if empty)
 
 	// JSON Validator fields
 	// (TrustDomain is shared)
+	RequireIssuer bool `koanf:"require_issuer"`
+
+	// x-rh-identity Validator fields
+	// (TrustDomain and Issuer are shared)
+	IdentityTypes []string `koanf:"identity_types"` // Accepted identity types, e.g. ["User"] (all if empty)
 
 	// Stub Validator fields
 	CredentialTypes []string `koanf:"credential_types"` // e.g., ["bearer", "jwt"]
+
+	// Hooks normalize or augment the validator's results, applied in order
+	Hooks []ResultHookConfig `koanf:"hooks"`
+}
+
+// ResultHookConfig configures a hook changing a validator's results before
+// they reach the token service
+type ResultHookConfig struct {
+	// Type selects the hook implementation
+	// Options: "cel", "lua"
+	Type string `koanf:"type"`
+
+	// Script is the CEL expression, or the Lua script defining enrich(result)
+	Script     string `koanf:"script"`
+	ScriptFile string `koanf:"script_file"` // Path to the script (alternative to Script)
+
+	// Config values available to Lua scripts via config.get()
+	Config map[string]any `koanf:"config"`
 }
 
 // ValidatorFilterConfig configures validator filtering for actors
 type ValidatorFilterConfig struct {
 	// Type selects the filter implementation
-	// Options: "cel", "any", "passthrough"
-	Type string `koanf:"type" usage:"validator filter type: cel, any, passthrough"`
+	// Options: "cel", "rego", "static", "any", "passthrough"
+	Type string `koanf:"type" usage:"validator filter type: cel, rego, static, any, passthrough"`
+
+	// CEL and Rego filter fields
+	Script     string `koanf:"script" usage:"CEL script or Rego policy for validator filtering"`
+	ScriptFile string `koanf:"script_file"` // Path to CEL script or Rego policy file (alternative to Script)
+
+	// Query is the Rego rule deciding whether a validator is allowed
+	// (default: data.parsec.allow)
+	Query string `koanf:"query"`
 
-	// CEL filter fields
-	Script string `koanf:"script" usage:"CEL script for validator filtering"`
+	// Static filter fields
+	// Mappings are evaluated in order; the first matching actor mapping wins
+	Mappings []ValidatorMappingConfig `koanf:"mappings"`
+	// DefaultValidators may be used by actors matching no mapping (none if empty)
+	DefaultValidators []string `koanf:"default_validators"`
 
 	// Any filter fields (composite filter - allows if any sub-filter allows)
 	Filters []ValidatorFilterConfig `koanf:"filters"`
 }
 
+// ValidatorMappingConfig maps matching actors to the validators they may use
+type ValidatorMappingConfig struct {
+	// TrustDomain matches the actor's trust domain exactly (empty matches any)
+	TrustDomain string `koanf:"trust_domain"`
+
+	// Subject matches the actor's subject exactly, or by prefix when ending in "*"
+	Subject string `koanf:"subject"`
+
+	// Validators are the names of the validators matching actors may use
+	Validators []string `koanf:"validators"`
+}
+
 // DataSourceConfig configures a data source
 type DataSourceConfig struct {
 	// Name uniquely identifies this data source
 	Name string `koanf:"name"`
 
 	// Type selects the data source implementation
-	// Options: "lua"
+	// Options: "lua", "http", "sql", "grpc", "groups", "geoip", "plugin"
 	Type string `koanf:"type"`
 
 	// Lua data source fields
@@ -140,48 +573,295 @@ type DataSourceConfig struct {
 	Script     string         `koanf:"script"`      // Inline Lua script (alternative to ScriptFile)
 	Config     map[string]any `koanf:"config"`      // Config values available to script
 
-	// HTTP configuration
+	// HTTP configuration (HTTP client for lua, request for http)
 	HTTPConfig *HTTPConfig `koanf:"http"`
 
+	// SQL configuration (sql only)
+	SQL *SQLConfig `koanf:"sql"`
+
+	// gRPC configuration (grpc only)
+	GRPC *GRPCConfig `koanf:"grpc"`
+
+	// Group backends (groups only)
+	Groups *GroupsConfig `koanf:"groups"`
+
+	// GeoIP database (geoip only)
+	GeoIP *GeoIPConfig `koanf:"geoip"`
+
+	// Plugin names the plugin fetching the data (plugin only)
+	Plugin string `koanf:"plugin"`
+
+	// PluginTimeout bounds each fetch from the plugin (plugin only, default: 5s)
+	PluginTimeout string `koanf:"plugin_timeout"`
+
 	// Caching configuration
 	Caching *CachingConfig `koanf:"caching"`
+
+	// Bulkhead bounds concurrent fetches from this data source (optional)
+	// Cache hits do not take a slot.
+	Bulkhead *BulkheadConfig `koanf:"bulkhead"`
 }
 
-// HTTPConfig configures HTTP client for Lua data sources
+// HTTPConfig configures the HTTP client for Lua data sources and the request
+// made by http data sources
 type HTTPConfig struct {
 	// Timeout for HTTP requests (default: 30s)
 	Timeout string `koanf:"timeout"` // Duration string like "30s"
+
+	// Request fields (http data sources only)
+	// The URL, header values, and body are Go templates over the data source
+	// input, e.g. "https://users.example.com/v1/users/{{ .subject.subject }}"
+	URL     string            `koanf:"url"`
+	Method  string            `koanf:"method"` // Default: GET
+	Headers map[string]string `koanf:"headers"`
+	Body    string            `koanf:"body"`
+}
+
+// SQLConfig configures a sql data source
+type SQLConfig struct {
+	// Driver is the database/sql driver name; the driver must be linked into the binary
+	Driver string `koanf:"driver"`
+
+	// DSN is the driver-specific data source name
+	DSN string `koanf:"dsn"`
+
+	// Query is the parameterized query, using the driver's placeholder syntax
+	Query string `koanf:"query"`
+
+	// Args are templates for the query parameters, e.g. ["{{ .subject.subject }}"]
+	Args []string `koanf:"args"`
+
+	// SingleRow returns the first row as an object instead of an array of rows
+	SingleRow bool `koanf:"single_row"`
+
+	// Timeout bounds each query (default: 5s)
+	Timeout string `koanf:"timeout"`
+}
+
+// GeoIPConfig configures a geoip data source, which looks up addresses in a
+// MaxMind DB file
+type GeoIPConfig struct {
+	// Path is the path of the .mmdb file, e.g. a GeoLite2-Country or
+	// GeoLite2-ASN database
+	Path string `koanf:"path"`
+
+	// IP is a template of the address looked up
+	// (default: "{{ .request_attributes.ip_address }}")
+	IP string `koanf:"ip"`
+
+	// ReloadInterval is how often the file is checked for changes
+	// (default: 1m; "-1s" disables reloading)
+	ReloadInterval string `koanf:"reload_interval"`
+}
+
+// GroupsConfig configures a groups data source, which merges the groups of
+// the subject from every backend into a single roles array
+type GroupsConfig struct {
+	// Backends are queried concurrently; any failure fails the fetch
+	Backends []GroupBackendConfig `koanf:"backends"`
+}
+
+// GroupBackendConfig configures one source of group membership
+type GroupBackendConfig struct {
+	// Type selects the backend implementation
+	// Options: "static", "scim", "ldap"
+	Type string `koanf:"type"`
+
+	// Members maps group names to the subjects in them (static only)
+	Members map[string][]string `koanf:"members"`
+
+	// File is a YAML or JSON file mapping group names to members (static only,
+	// merged with Members)
+	File string `koanf:"file"`
+
+	// URL is the SCIM base URL (scim) or directory URL (ldap)
+	URL string `koanf:"url"`
+
+	// Member is a template of the SCIM member value groups are filtered on
+	// (scim only, default: "{{ .subject.subject }}")
+	Member string `koanf:"member"`
+
+	// Headers are request header templates (scim only)
+	Headers map[string]string `koanf:"headers"`
+
+	// MaxPages bounds the pages read per lookup (scim only, default: 50)
+	MaxPages int `koanf:"max_pages"`
+
+	// LDAP search fields (ldap only)
+	// Filter is a template whose values are escaped for LDAP filters
+	BindDN       string `koanf:"bind_dn"`
+	BindPassword string `koanf:"bind_password"`
+	BaseDN       string `koanf:"base_dn"`
+	Filter       string `koanf:"filter"`
+	Attribute    string `koanf:"attribute"` // Default: cn
+
+	// PageSize is the number of results requested per page (default: 100)
+	PageSize int `koanf:"page_size"`
+
+	// Timeout bounds each lookup (default: 30s for scim, 5s for ldap)
+	Timeout string `koanf:"timeout"`
+}
+
+// GRPCConfig configures a grpc data source
+type GRPCConfig struct {
+	// Address is the target address (host:port or any gRPC target URI)
+	Address string `koanf:"address"`
+
+	// Method is the full unary method name, e.g. "/users.v1.UserService/GetUser"
+	Method string `koanf:"method"`
+
+	// DescriptorSetFile is a FileDescriptorSet (protoc --descriptor_set_out
+	// --include_imports) describing the service. If empty, only services linked
+	// into the binary can be called.
+	DescriptorSetFile string `koanf:"descriptor_set_file"`
+
+	// Request is the request message template in protobuf JSON form
+	Request string `koanf:"request"`
+
+	// Insecure disables TLS (default: TLS with the system roots)
+	Insecure bool `koanf:"insecure"`
+
+	// Timeout bounds each call (default: 5s)
+	Timeout string `koanf:"timeout"`
 }
 
 // CachingConfig configures caching for a data source
 type CachingConfig struct {
 	// Type selects the caching implementation
-	// Options: "in_memory", "distributed", "none"
+	// Options: "in_memory" (or "local"), "distributed", "redis", "none"
 	Type string `koanf:"type"`
 
-	// TTL is the cache time-to-live
+	// TTL is the cache time-to-live (default: 5m)
 	TTL string `koanf:"ttl"` // Duration string like "5m"
 
+	// Key lists the input fields (dotted JSON paths such as "subject.subject")
+	// that determine the result; other fields are masked out of the cache key.
+	// If empty, lua data sources use their cache key function and other data
+	// sources key on the whole input.
+	Key []string `koanf:"key"`
+
+	// CacheKeyFunc names the Lua function computing the cache key (default: "cache_key")
+	CacheKeyFunc string `koanf:"cache_key_func"`
+
 	// Distributed caching fields
 	GroupName string `koanf:"group_name"` // For groupcache
 	CacheSize int64  `koanf:"cache_size"` // Cache size in bytes
+
+	// Redis caching fields
+	Redis *RedisConfig `koanf:"redis"`
+}
+
+// RedisConfig configures a Redis cache
+type RedisConfig struct {
+	Address   string `koanf:"address"`    // host:port
+	Password  string `koanf:"password"`   // Optional AUTH password
+	DB        int    `koanf:"db"`         // Database number (default: 0)
+	KeyPrefix string `koanf:"key_prefix"` // Default: "parsec:datasource:<name>:"
+	Timeout   string `koanf:"timeout"`    // Per-command timeout (default: 1s)
+}
+
+// DistributedCacheConfig configures the groupcache peer pool
+//
+// Peers fetch entries owned by other instances over HTTP at BasePath on Port,
+// apart from the public HTTP server port, so every URL must reach Port. Peer
+// requests carry Secret, and requests without it are refused.
+type DistributedCacheConfig struct {
+	// Port serves peer requests and forwarded cache invalidations (required)
+	Port int `koanf:"port"`
+
+	// Secret is shared by all peers to authenticate peer requests (required)
+	// Supports ${ENV} expansion.
+	Secret string `koanf:"secret"`
+
+	// AllowedCIDRs restrict the addresses peer requests are served to
+	// (optional; any address when empty)
+	AllowedCIDRs []string `koanf:"allowed_cidrs"`
+
+	// Self is this instance's base URL as reachable by its peers
+	Self string `koanf:"self"`
+
+	// Peers are the base URLs of all instances, including Self
+	Peers []string `koanf:"peers"`
+
+	// Discovery finds the peers in place of a static Peers list (optional)
+	Discovery *PeerDiscoveryConfig `koanf:"discovery"`
+
+	// BasePath is the HTTP path prefix for peer requests (default: "/_groupcache/")
+	BasePath string `koanf:"base_path"`
+}
+
+// PeerDiscoveryConfig configures how distributed cache peers are found
+//
+// Peers are rediscovered every RefreshInterval. Self is always a peer, even
+// before it is discovered.
+type PeerDiscoveryConfig struct {
+	// Type selects the discovery mechanism
+	// Options: "static" (the Peers list), "dns_srv", "kubernetes"
+	Type string `koanf:"type"`
+
+	// RefreshInterval is how often peers are rediscovered (default: 30s)
+	RefreshInterval string `koanf:"refresh_interval"`
+
+	// Scheme of the discovered peer URLs (default: "http")
+	Scheme string `koanf:"scheme"`
+
+	// DNS SRV fields
+	Name string `koanf:"name"` // SRV record, e.g. "_http._tcp.parsec.my-ns.svc.cluster.local"
+
+	// Kubernetes fields
+	Service   string `koanf:"service"`   // Service whose ready endpoints are the peers
+	Namespace string `koanf:"namespace"` // Default: the pod's namespace
+	Port      string `koanf:"port"`      // Endpoint port name (default: the service's only port)
 }
 
 // ClaimMapperConfig configures a claim mapper
 type ClaimMapperConfig struct {
 	// Type selects the mapper implementation
-	// Options: "cel", "passthrough", "request_attributes", "stub"
+	// Options: "cel", "passthrough", "request_attributes", "request_context", "risk", "stub"
 	Type string `koanf:"type"`
 
 	// Optional name for the mapper
 	Name string `koanf:"name"`
 
+	// Optional omits the mapper's claims when it fails, e.g. because an
+	// enrichment data source is down, rather than failing the issuance
+	Optional bool `koanf:"optional"`
+
 	// CEL mapper fields
 	ScriptFile string `koanf:"script_file"` // Path to CEL script file
 	Script     string `koanf:"script"`      // Inline CEL script (alternative to ScriptFile)
 
 	// Stub mapper fields
 	Claims map[string]any `koanf:"claims"`
+
+	// Request context mapper fields, applied in order
+	Transforms []RequestContextTransformConfig `koanf:"transforms"`
+}
+
+// RequestContextTransformConfig configures one step of a request_context mapper
+type RequestContextTransformConfig struct {
+	// Type selects the transform
+	// Options: "rename", "drop", "lowercase", "uppercase", "user_agent", "cel",
+	// "redact", "hash", "truncate"
+	Type string `koanf:"type"`
+
+	// Field is the claim the transform reads (or, for cel, writes)
+	Field string `koanf:"field"`
+
+	// To is the destination claim (rename, user_agent)
+	To string `koanf:"to"`
+
+	// Expression is the CEL expression deriving Field (cel)
+	Expression string `koanf:"expression"`
+
+	// Replacement is the redacted value (redact, defaults to "[REDACTED]")
+	Replacement string `koanf:"replacement"`
+
+	// Salt keys the HMAC-SHA256 hash (hash)
+	Salt string `koanf:"salt"`
+
+	// MaxLength is the maximum number of characters kept (truncate)
+	MaxLength int `koanf:"max_length"`
 }
 
 // IssuerConfig configures a token issuer
@@ -194,28 +874,213 @@ type IssuerConfig struct {
 	TokenType string `koanf:"token_type"`
 
 	// Type selects the issuer implementation
-	// Options: "stub", "unsigned", "transaction_token", "rh_identity"
+	// Options: "stub", "unsigned", "transaction_token", "jwt_access_token", "jwt_svid", "reference_token", "rh_identity", "plugin"
 	Type string `koanf:"type"`
 
 	// Common fields
 	IssuerURL string `koanf:"issuer_url"`
 	TTL       string `koanf:"ttl"` // Duration string like "5m"
 
+	// Leeway backdates iat (and nbf) of issued tokens by a duration like "30s",
+	// for clients whose clocks run behind (transaction_token, jwt_access_token
+	// and jwt_svid types)
+	Leeway string `koanf:"leeway"`
+
+	// NotBeforeSkew backdates nbf by a duration like "10s" instead of leeway,
+	// for verifiers whose clocks run behind (transaction_token type); the
+	// jwt_access_token and jwt_svid types only carry nbf when it is set
+	NotBeforeSkew string `koanf:"not_before_skew"`
+
 	// SignerID references a named signer from the global signers config
 	// Used for transaction tokens to configure the signer
 	SignerID string `koanf:"signer_id"`
 
+	// AudienceSigners sign the tokens of specific audiences with other signers
+	// than signer_id, e.g. RS256 for a legacy consumer (transaction_token,
+	// jwt_access_token and jwt_svid types)
+	AudienceSigners []AudienceSignerConfig `koanf:"audience_signers"`
+
 	// Transaction token issuer fields (stub, transaction_token types)
 	// These mappers build the "tctx" and "req_ctx" claims
 	TransactionContextMappers []ClaimMapperConfig `koanf:"transaction_context"`
 	RequestContextMappers     []ClaimMapperConfig `koanf:"request_context"`
 
-	// Simple issuer fields (unsigned, rh_identity types)
+	// TransactionContextClaims declares defaults and required members of the
+	// "tctx" claim (transaction_token type)
+	TransactionContextClaims *ClaimsContractConfig `koanf:"transaction_context_claims"`
+
+	// AuthorizationDetailsMappers build the "azd" claim (transaction_token type)
+	AuthorizationDetailsMappers []ClaimMapperConfig `koanf:"authorization_details"`
+
+	// TransactionIDFormat selects how the "txn" claim is generated (transaction_token type)
+	// Options: "uuidv7" (default), "uuidv4", "ulid", "snowflake", "trace_id"
+	TransactionIDFormat string `koanf:"transaction_id_format"`
+
+	// TransactionIDNodeID is this replica's node ID (0-1023), required for the
+	// "snowflake" format. Each replica must have its own node ID for IDs to be
+	// unique, e.g. set from the pod ordinal with "${NODE_ID}"
+	TransactionIDNodeID *int `koanf:"transaction_id_node_id"`
+
+	// TransactionIDFallback is the format used for the "trace_id" format when the
+	// request has no trace ID: "uuidv7" (default), "uuidv4", "ulid"
+	TransactionIDFallback string `koanf:"transaction_id_fallback"`
+
+	// Purpose is the default "purp" claim (transaction_token type)
+	Purpose string `koanf:"purpose"`
+
+	// PurposeFromScope uses the requested scope as "purp" when no purpose is requested
+	PurposeFromScope bool `koanf:"purpose_from_scope"`
+
+	// TTLPolicy computes the token TTL per request, overriding TTL (transaction_token type)
+	TTLPolicy *TTLPolicyConfig `koanf:"ttl_policy"`
+
+	// Replacement restricts the claims a replacement for one of this issuer's
+	// own transaction tokens may change (transaction_token type)
+	Replacement *TokenReplacementConfig `koanf:"replacement"`
+
+	// Plugin names the plugin issuing the tokens (plugin type)
+	Plugin string `koanf:"plugin"`
+
+	// Simple issuer fields (unsigned, rh_identity, jwt_access_token, plugin types)
 	// These mappers build the token's claim structure
 	ClaimMappers []ClaimMapperConfig `koanf:"claim_mappers"`
 
-	// Stub issuer fields (deprecated - use mappers instead)
-	IncludeRequestContext bool `koanf:"include_request_context"`
+	// JWT access token issuer fields (jwt_access_token type)
+	Audience string `koanf:"audience"`  // aud claim (defaults to the trust domain)
+	ClientID string `koanf:"client_id"` // client_id claim when no actor is present
+
+	// SPIFFETrustDomain is the trust domain JWT-SVIDs are issued in (jwt_svid type)
+	// The "sub" produced by claim_mappers is a SPIFFE ID or path within this trust domain
+	// Audience above also applies to jwt_svid
+	SPIFFETrustDomain string `koanf:"spiffe_trust_domain"`
+
+	// Reference token issuer fields (reference_token type)
+	// Store selects where the claims behind reference tokens are kept
+	Store *ReferenceTokenStoreConfig `koanf:"store"`
+
+	// SizeBudget bounds the encoded token size (transaction_token, jwt_access_token types)
+	SizeBudget *SizeBudgetConfig `koanf:"size_budget"`
+
+	// Encryption wraps issued tokens in a JWE for a recipient (optional, any type except reference_token)
+	Encryption *TokenEncryptionConfig `koanf:"encryption"`
+
+	// Bulkhead bounds concurrent issuance for this token type (optional)
+	Bulkhead *BulkheadConfig `koanf:"bulkhead"`
+
+	// Provenance records which claim mapper, and which data sources it
+	// fetched, produced each claim in decision log events (not the token)
+	Provenance bool `koanf:"provenance"`
+}
+
+// AudienceSignerConfig selects the signer of tokens for some audiences
+type AudienceSignerConfig struct {
+	// Audiences are the audiences whose tokens the signer signs
+	Audiences []string `koanf:"audiences"`
+
+	// SignerID references a named signer from the global signers config
+	SignerID string `koanf:"signer_id"`
+}
+
+// BulkheadConfig bounds the concurrent calls to an issuer or data source
+type BulkheadConfig struct {
+	// MaxConcurrent is the number of calls that may run at once (required)
+	MaxConcurrent int `koanf:"max_concurrent"`
+
+	// MaxQueued is the number of calls that may wait for a slot (default: 0)
+	// Further calls fail immediately with issuer_unavailable.
+	MaxQueued int `koanf:"max_queued"`
+}
+
+// TokenReplacementConfig configures which claims a replacement transaction
+// token may change relative to the token it replaces
+// "txn" and the replaced token's "tctx" values are always kept, and iat, nbf,
+// exp, jti and a narrowed aud are always refreshed.
+type TokenReplacementConfig struct {
+	// MutableClaims are the top-level claims that may change, e.g. "req_ctx", "purp", "azd"
+	MutableClaims []string `koanf:"mutable_claims"`
+}
+
+// ClaimsContractConfig declares what consumers rely on in a claim built by
+// mappers
+type ClaimsContractConfig struct {
+	// Defaults are the values of members the mappers leave unset or null
+	Defaults map[string]any `koanf:"defaults"`
+
+	// Required are the members every token must carry, after defaults;
+	// issuance fails listing those missing
+	Required []string `koanf:"required"`
+}
+
+// TTLPolicyConfig configures a CEL expression that computes token TTL
+type TTLPolicyConfig struct {
+	// Script is a CEL expression over subject, actor, request, scope, and audience
+	// that evaluates to a duration or integer seconds
+	Script string `koanf:"script"`
+
+	// Min is the lower bound for the computed TTL (duration string, default: 0)
+	Min string `koanf:"min"`
+
+	// Max is the upper bound for the computed TTL (duration string, default: the issuer's ttl)
+	Max string `koanf:"max"`
+}
+
+// SizeBudgetConfig configures the maximum encoded token size and how to compact oversized tokens
+type SizeBudgetConfig struct {
+	// MaxBytes is the maximum encoded token size
+	MaxBytes int `koanf:"max_bytes"`
+
+	// Compaction steps are applied in order until the token fits
+	Compaction []CompactionStepConfig `koanf:"compaction"`
+}
+
+// CompactionStepConfig configures a single compaction step
+type CompactionStepConfig struct {
+	// Claim is the claim path, using "." for nested claims (e.g., "tctx.groups")
+	Claim string `koanf:"claim"`
+
+	// Action is what to do with the claim
+	// Options: "drop", "reference"
+	Action string `koanf:"action"`
+
+	// DataSource names the data source holding the full value (reference action)
+	DataSource string `koanf:"data_source"`
+}
+
+// TokenEncryptionConfig configures JWE encryption of issued tokens
+// Exactly one of Key, KeyFile, or JWKSURL provides the recipient's public key
+type TokenEncryptionConfig struct {
+	// Key is the recipient public key as an inline JWK or PEM document
+	Key string `koanf:"key"`
+
+	// KeyFile is a path to the recipient public key (JWK or PEM)
+	KeyFile string `koanf:"key_file"`
+
+	// JWKSURL is the recipient's JWKS URL
+	JWKSURL string `koanf:"jwks_url"`
+
+	// KeyID selects a key from the JWKS by "kid" (jwks_url only)
+	KeyID string `koanf:"key_id"`
+
+	// RefreshInterval for the recipient JWKS cache (jwks_url only)
+	RefreshInterval string `koanf:"refresh_interval"`
+
+	// KeyAlgorithm is the JWE "alg" (e.g., "RSA-OAEP-256", "ECDH-ES+A256KW")
+	// Defaults based on the recipient key type
+	KeyAlgorithm string `koanf:"key_algorithm"`
+
+	// ContentEncryption is the JWE "enc" (default: "A256GCM")
+	ContentEncryption string `koanf:"content_encryption"`
+}
+
+// ReferenceTokenStoreConfig configures storage for reference token claims
+type ReferenceTokenStoreConfig struct {
+	// Type selects the store implementation
+	// Options: "memory" (default)
+	Type string `koanf:"type"`
+
+	// MaxEntries bounds the unexpired records a memory store holds; tokens
+	// are refused once it is full (default: 100000)
+	MaxEntries int `koanf:"max_entries"`
 }
 
 // KeyProviderConfig configures a key provider
@@ -242,6 +1107,29 @@ type KeyProviderConfig struct {
 
 	// Disk key provider fields
 	KeysPath string `koanf:"keys_path"` // Path to directory for storing keys
+	Seed     string `koanf:"seed"`      // Derives keys deterministically, for local development (optional, EC only)
+
+	// EncryptionKey encrypts private keys at rest with AES-256-GCM (optional;
+	// base64-encoded 32 bytes, typically given through a secretRef). Once it
+	// is set, unencrypted key files are refused unless MigrateKeyFiles is set.
+	EncryptionKey string `koanf:"encryption_key"`
+
+	// MigrateKeyFiles rewrites key files written by earlier versions, such as
+	// unencrypted ones, in the current format when they are loaded (optional)
+	MigrateKeyFiles bool `koanf:"migrate_key_files"`
+}
+
+// KeySlotStoreConfig configures where rotating signers keep their key slots
+//
+// In memory, signers generate new keys on every restart; a file keeps their
+// slots, and so their keys, across restarts.
+type KeySlotStoreConfig struct {
+	// Type selects the store
+	// Options: "memory" (default), "file"
+	Type string `koanf:"type"`
+
+	// Path is the JSON file slots are kept in (file only)
+	Path string `koanf:"path"`
 }
 
 // SignerConfig configures a signer
@@ -267,11 +1155,74 @@ type SignerConfig struct {
 	PrepareTimeout    string `koanf:"prepare_timeout"`    // Duration string like "1m"
 }
 
+// PluginConfig configures an out-of-process plugin
+type PluginConfig struct {
+	// Name uniquely identifies this plugin
+	Name string `koanf:"name"`
+
+	// Command is the plugin executable parsec starts (one of command or address)
+	Command string `koanf:"command"`
+
+	// Args are the arguments of the command
+	Args []string `koanf:"args"`
+
+	// Env are extra "KEY=value" environment variables for the command
+	Env []string `koanf:"env"`
+
+	// Address is the host:port of a plugin running on its own, e.g. as a
+	// sidecar (one of command or address)
+	Address string `koanf:"address"`
+
+	// StartTimeout bounds how long the command may take to start serving
+	// (duration string, default: "10s")
+	StartTimeout string `koanf:"start_timeout"`
+}
+
+// IdentityMappingConfig configures the mapping of external subjects to principals
+type IdentityMappingConfig struct {
+	// Type selects the mapper implementation
+	// Options: "static" (principals table), "datasource"
+	Type string `koanf:"type"`
+
+	// Principals lists each principal with the external subjects mapped to it (static type)
+	Principals []PrincipalConfig `koanf:"principals"`
+
+	// DataSource is the name of the data source looking subjects up (datasource type)
+	DataSource string `koanf:"data_source"`
+
+	// Field is the data source result field holding the principal ID
+	// (datasource type, default: principal)
+	Field string `koanf:"field"`
+
+	// Required rejects subjects not mapped to a principal instead of issuing
+	// tokens with their external subject
+	Required bool `koanf:"required"`
+}
+
+// PrincipalConfig is a principal and the external subjects identifying it
+type PrincipalConfig struct {
+	// ID is the canonical principal ID embedded in tokens
+	ID string `koanf:"id"`
+
+	// Subjects are the principal's subjects at each identity provider
+	Subjects []ExternalSubjectConfig `koanf:"subjects"`
+}
+
+// ExternalSubjectConfig identifies a subject of an identity provider
+type ExternalSubjectConfig struct {
+	// TrustDomain is the trust domain of the validator accepting the subject's credentials
+	TrustDomain string `koanf:"trust_domain"`
+
+	// Subject is the subject as the identity provider asserts it
+	Subject string `koanf:"subject"`
+}
+
 // ClaimsFilterConfig configures the claims filter registry
 type ClaimsFilterConfig struct {
 	// Type selects the filter registry implementation
-	// Options: "stub", "cel", "allowlist"
-	Type string `koanf:"type" usage:"claims filter type: stub, cel, allowlist"`
+	// Options: "stub", "allowlist", "denylist", "cel" (one filter for all actors),
+	// "rules" (per-actor filters)
+	Type string `koanf:"type" usage:"claims filter type: stub, allowlist, denylist, cel, rules"`
 
 	// CEL-based filter
 	Script string `koanf:"script" usage:"CEL script for claims filtering"`
@@ -279,25 +1230,83 @@ type ClaimsFilterConfig struct {
 	// Allowlist-based filter
 	AllowedClaims []string `koanf:"allowed_claims"`
 
-	// Per-actor rules
-	ActorRules map[string][]string `koanf:"actor_rules"` // Map of actor pattern to allowed claims
+	// Denylist-based filter
+	DeniedClaims []string `koanf:"denied_claims"`
+
+	// Per-actor rules (rules type), evaluated in order; the first match wins
+	// Files before schema version 2 may set them as actor_rules, a map of
+	// actor subject pattern to allowed claims (see migrations)
+	Rules []ClaimsFilterRuleConfig `koanf:"rules"`
+
+	// Default applies to actors matching no rule (rules type)
+	// If unset, such actors may not provide any claims
+	Default *ClaimsFilterSpecConfig `koanf:"default"`
+}
+
+// ClaimsFilterRuleConfig selects a claims filter for matching actors
+type ClaimsFilterRuleConfig struct {
+	// TrustDomain matches the actor's trust domain exactly (empty matches any)
+	TrustDomain string `koanf:"trust_domain"`
+
+	// Subject matches the actor's subject exactly, or by prefix when ending in "*"
+	Subject string `koanf:"subject"`
+
+	// ClaimsFilterSpecConfig is the filter applied to matching actors
+	ClaimsFilterSpecConfig `koanf:",squash"`
+}
+
+// ClaimsFilterSpecConfig configures a single claims filter
+type ClaimsFilterSpecConfig struct {
+	// Type selects the filter implementation
+	// Options: "passthrough", "allowlist", "denylist", "cel"
+	Type string `koanf:"type"`
+
+	// Script is the CEL predicate over actor, name, and value (cel type)
+	Script string `koanf:"script"`
+
+	// AllowedClaims lists permitted claims (allowlist type)
+	AllowedClaims []string `koanf:"allowed_claims"`
+
+	// DeniedClaims lists blocked claims (denylist type)
+	DeniedClaims []string `koanf:"denied_claims"`
 }
 
 // FixtureConfig configures a fixture for hermetic testing
 type FixtureConfig struct {
 	// Type selects the fixture type
-	// Options: "http_rule", "jwks"
+	// Options: "http_rule", "jwks", "clock", "file", "record"
 	Type string `koanf:"type"`
 
 	// HTTP rule fields (when Type is "http_rule")
 	Request  FixtureRequest  `koanf:"request"`
 	Response FixtureResponse `koanf:"response"`
 
+	// Responses, if set, replaces Response with responses for consecutive
+	// matching requests; the last one repeats (http_rule type)
+	Responses []FixtureResponse `koanf:"responses"`
+
 	// JWKS fields (when Type is "jwks")
 	Issuer    string `koanf:"issuer"`    // Issuer URL (iss claim)
 	JWKSURL   string `koanf:"jwks_url"`  // URL where JWKS will be served
 	KeyID     string `koanf:"key_id"`    // Optional key identifier (defaults to "test-key-1")
 	Algorithm string `koanf:"algorithm"` // Optional algorithm (defaults to "RS256")
+
+	// PrivateKey is an optional PEM-encoded RSA signing key (jwks type)
+	// A fixed key lets tokens be minted outside the process, e.g. for local demos
+	// If neither PrivateKey nor PrivateKeyFile is set, a key is generated on startup
+	PrivateKey string `koanf:"private_key"`
+
+	// PrivateKeyFile is a path to a PEM-encoded RSA signing key (jwks type)
+	PrivateKeyFile string `koanf:"private_key_file"`
+
+	// Time is the fixed starting time of the fixture clock in RFC 3339 format (clock type)
+	// All components that depend on time (validators, issuers, JWKS fixtures)
+	// share this clock, so issued and validated timestamps are deterministic
+	Time string `koanf:"time"`
+
+	// Path is a fixture rules file (JSON or YAML) or a directory of them to
+	// replay (file type), or the file real outbound HTTP is recorded to (record type)
+	Path string `koanf:"path"`
 }
 
 // FixtureRequest defines request matching criteria for HTTP fixtures
@@ -326,6 +1335,170 @@ type FixtureResponse struct {
 
 	// Body is the response body content
 	Body string `koanf:"body"`
+
+	// Delay is latency injected before responding, like "200ms"
+	Delay string `koanf:"delay"`
+
+	// Fault fails the request without a response
+	// Options: "connection_reset", "timeout" (blocks until the request times out)
+	Fault string `koanf:"fault"`
+
+	// ErrorRate is the fraction of requests (0 to 1) answered with ErrorStatus instead
+	ErrorRate float64 `koanf:"error_rate"`
+
+	// ErrorStatus is the status of requests failed by ErrorRate (default: 503)
+	ErrorStatus int `koanf:"error_status"`
+}
+
+// DecisionLogConfig configures the decision log, which records an audit event
+// for every token issuance
+type DecisionLogConfig struct {
+	// Sink selects where events are delivered
+	// Options: "kafka", "file", "stdout"
+	Sink string `koanf:"sink"`
+
+	// Kafka configures the kafka sink
+	Kafka *KafkaSinkConfig `koanf:"kafka"`
+
+	// File is the JSON lines file events are appended to (file sink or fallback)
+	File string `koanf:"file"`
+
+	// Fallback receives events the sink does not accept after retries
+	// Options: "stdout", "file", "none" (default: stdout for kafka, none otherwise)
+	Fallback string `koanf:"fallback"`
+
+	// Delivery selects when events are delivered
+	// Options: "async" (default, buffered in the background), "sync" (before
+	// the issuance returns)
+	Delivery string `koanf:"delivery"`
+
+	// BufferSize is the number of events buffered for async delivery (default: 1024)
+	BufferSize int `koanf:"buffer_size"`
+
+	// BatchSize is the maximum number of events written at once (default: 100)
+	BatchSize int `koanf:"batch_size"`
+
+	// Retries is the number of retries of a failed write (default: 3, -1 disables)
+	Retries int `koanf:"retries"`
+}
+
+// KafkaSinkConfig configures a kafka decision log sink
+type KafkaSinkConfig struct {
+	// Brokers are the seed brokers (host:port)
+	Brokers []string `koanf:"brokers"`
+
+	// Topic is the topic events are produced to
+	Topic string `koanf:"topic"`
+
+	// ClientID identifies parsec to the brokers (default: parsec)
+	ClientID string `koanf:"client_id"`
+
+	// TLS enables TLS to the brokers, verified with the system roots or CAFile
+	TLS    bool   `koanf:"tls"`
+	CAFile string `koanf:"ca_file"`
+
+	// SASL authenticates to the brokers (optional)
+	SASL *KafkaSASLConfig `koanf:"sasl"`
+
+	// DeliveryTimeout bounds how long a record is retried (default: 30s)
+	DeliveryTimeout string `koanf:"delivery_timeout"`
+}
+
+// KafkaSASLConfig configures SASL authentication to kafka brokers
+type KafkaSASLConfig struct {
+	// Mechanism selects the SASL mechanism
+	// Options: "plain", "scram-sha-256", "scram-sha-512"
+	Mechanism string `koanf:"mechanism"`
+	Username  string `koanf:"username"`
+	Password  string `koanf:"password"`
+}
+
+// TokenWebhookConfig configures the token webhook, which is sent a signed
+// summary of every issued token
+type TokenWebhookConfig struct {
+	// URL receives the summaries (http or https)
+	URL string `koanf:"url"`
+
+	// Secret signs requests with HMAC-SHA256 and keys the subject hashes
+	Secret string `koanf:"secret"`
+
+	// Timeout bounds each request (default: 5s)
+	Timeout string `koanf:"timeout"`
+
+	// BufferSize is the number of summaries buffered (default: 1024)
+	BufferSize int `koanf:"buffer_size"`
+
+	// BatchSize is the maximum number of summaries sent at once (default: 100)
+	BatchSize int `koanf:"batch_size"`
+
+	// Retries is the number of retries of a failed request (default: 3, -1 disables)
+	Retries int `koanf:"retries"`
+}
+
+// TokenLedgerConfig configures the token ledger, which keeps a bounded record
+// of recently issued tokens in memory, listed under /v1/admin/tokens
+type TokenLedgerConfig struct {
+	// MaxEntries is the number of tokens recorded (default: 10000)
+	MaxEntries int `koanf:"max_entries"`
+
+	// Retention is how long tokens are recorded (default: 24h)
+	Retention string `koanf:"retention"`
+}
+
+// TelemetryConfig configures metrics export
+type TelemetryConfig struct {
+	// ServiceName is the service.name resource attribute (default: parsec)
+	ServiceName string `koanf:"service_name"`
+
+	// Instance is the service.instance.id resource attribute (default: the hostname)
+	Instance string `koanf:"instance"`
+
+	// ResourceAttributes are added to the resource attached to every metric,
+	// along with parsec.trust_domain
+	ResourceAttributes map[string]string `koanf:"resource_attributes"`
+
+	// Metrics selects the metrics exporters
+	Metrics *MetricsConfig `koanf:"metrics"`
+}
+
+// MetricsConfig configures the metrics exporters; any number may be enabled
+type MetricsConfig struct {
+	// Prometheus serves metrics for scraping over HTTP
+	Prometheus *PrometheusMetricsConfig `koanf:"prometheus"`
+
+	// OTLP pushes metrics to a collector
+	OTLP *OTLPMetricsConfig `koanf:"otlp"`
+}
+
+// PrometheusMetricsConfig configures the Prometheus scrape endpoint
+type PrometheusMetricsConfig struct {
+	Enabled bool `koanf:"enabled"`
+
+	// Path is the HTTP path metrics are served at (default: /metrics)
+	Path string `koanf:"path"`
+}
+
+// OTLPMetricsConfig configures pushing metrics with OTLP
+type OTLPMetricsConfig struct {
+	// Protocol selects the OTLP transport
+	// Options: "grpc" (default), "http"
+	Protocol string `koanf:"protocol"`
+
+	// Endpoint is the collector's host:port (default: localhost:4317 for grpc,
+	// localhost:4318 for http)
+	Endpoint string `koanf:"endpoint"`
+
+	// Insecure disables TLS to the collector
+	Insecure bool `koanf:"insecure"`
+
+	// Headers are sent with every export, e.g. for authentication
+	Headers map[string]string `koanf:"headers"`
+
+	// Interval between exports (default: 60s)
+	Interval string `koanf:"interval"`
+
+	// Timeout of each export (default: 30s)
+	Timeout string `koanf:"timeout"`
 }
 
 // ObservabilityConfig configures application observability
@@ -344,6 +1517,11 @@ type ObservabilityConfig struct {
 	// Default: "json"
 	LogFormat string `koanf:"log_format" usage:"log format: json, text"`
 
+	// SampleRate is the fraction of operations this observer records, from 0
+	// to 1, unless overridden per event. Metrics are never sampled.
+	// Default: 1
+	SampleRate *float64 `koanf:"sample_rate" usage:"fraction of operations observed, 0 to 1"`
+
 	// Event-specific logging configuration
 	TokenIssuance *EventLoggingConfig `koanf:"token_issuance"`
 	TokenExchange *EventLoggingConfig `koanf:"token_exchange"`
@@ -366,4 +1544,144 @@ type EventLoggingConfig struct {
 	// Enabled controls whether this event type is logged
 	// Default: true
 	Enabled *bool `koanf:"enabled" usage:"enable/disable logging for this event type"`
+
+	// SampleRate overrides the fraction of these events' operations observed
+	SampleRate *float64 `koanf:"sample_rate" usage:"event-specific fraction of operations observed, 0 to 1"`
+}
+
+// QuotasConfig configures token issuance quotas
+type QuotasConfig struct {
+	// Store holds the counters
+	// Options: "memory" (default; counts per replica)
+	Store string `koanf:"store"`
+
+	// FailOpen admits issuances when the store fails, rather than refusing them
+	FailOpen bool `koanf:"fail_open"`
+
+	// Rules are the quotas, all of which an issuance must fit in
+	Rules []QuotaRuleConfig `koanf:"rules"`
+}
+
+// QuotaRuleConfig caps the tokens issued per key and window
+type QuotaRuleConfig struct {
+	// Name identifies the quota in errors
+	Name string `koanf:"name"`
+
+	// Selector is a CEL expression selecting the key issuances count against,
+	// e.g. "actor.subject"; null or "" leaves an issuance uncounted
+	Selector string `koanf:"selector"`
+
+	// Limit is the number of tokens that may be issued per key and window
+	Limit int64 `koanf:"limit"`
+
+	// Window is the length of the fixed windows tokens are counted in (e.g. "1m")
+	Window string `koanf:"window"`
+
+	// TokenTypes are the token type URNs the quota counts; empty counts all
+	TokenTypes []string `koanf:"token_types"`
+}
+
+// AudiencePolicyConfig configures the audiences tokens may be requested for
+type AudiencePolicyConfig struct {
+	// Type selects the audience policy implementation
+	// Options: "exact", "allowlist", "cel"
+	Type string `koanf:"type"`
+
+	// Audiences are the allowed audiences, exact or patterns such as
+	// "*.example.com" (allowlist type)
+	Audiences []string `koanf:"audiences"`
+
+	// Script is the CEL expression deciding each audience (cel type)
+	Script string `koanf:"script"`
+}
+
+// RiskConfig configures the risk evaluation of issuances
+type RiskConfig struct {
+	// FailOpen ignores evaluators that fail, rather than failing the issuance
+	FailOpen bool `koanf:"fail_open"`
+
+	// Evaluators assess each issuance; their assessments are combined
+	Evaluators []RiskEvaluatorConfig `koanf:"evaluators"`
+}
+
+// RiskEvaluatorConfig configures a risk evaluator
+type RiskEvaluatorConfig struct {
+	// Type selects the evaluator implementation
+	// Options: "velocity", "http"
+	Type string `koanf:"type"`
+
+	// Name identifies the evaluator, and is the reason given for velocity
+	// assessments (default: the type)
+	Name string `koanf:"name"`
+
+	// Velocity evaluator fields
+	Selector  string `koanf:"selector"`  // CEL expression selecting the key issuances count against
+	Window    string `koanf:"window"`    // Length of the fixed windows issuances are counted in (e.g. "1m")
+	Threshold int64  `koanf:"threshold"` // Issuances per key and window past which issuances are assessed
+	Level     string `koanf:"level"`     // Risk level of assessed issuances: low, medium or high (default: high)
+	MaxTTL    string `koanf:"max_ttl"`   // Caps the lifetime of the tokens of assessed issuances (optional)
+	Deny      bool   `koanf:"deny"`      // Denies assessed issuances
+
+	// HTTP evaluator fields
+	URL     string            `koanf:"url"`     // Endpoint of the risk engine
+	Headers map[string]string `koanf:"headers"` // Request headers, e.g. for authorization
+	Timeout string            `koanf:"timeout"` // Request timeout (default: 5s)
+}
+
+// ClientsConfig configures the registry of clients
+// Clients authenticating with a client ID and secret are checked by
+// client_secret_validators; the policies of every registered client apply to
+// the exchanges and checks of its actors. Clients are either listed statically
+// or looked up in a data source.
+type ClientsConfig struct {
+	// Static lists the registered clients
+	Static []ClientConfig `koanf:"static"`
+
+	// DataSource is the name of a data source looking up clients by ID
+	// (.actor.subject), as an alternative to Static
+	DataSource string `koanf:"data_source"`
+}
+
+// ClientConfig configures a statically registered client
+type ClientConfig struct {
+	// ClientID is the client ID, or the subject of the client's credential
+	// if it doesn't authenticate with a secret
+	ClientID string `koanf:"client_id"`
+
+	// TrustDomain restricts the client's policies to actors of this trust
+	// domain (optional)
+	TrustDomain string `koanf:"trust_domain"`
+
+	// Secrets are the client's secrets, typically given through secretRefs
+	Secrets []string `koanf:"secrets"`
+
+	// SecretSHA256 are hex-encoded SHA-256 hashes of the client's secrets, so
+	// that the secrets themselves need not be in the configuration
+	SecretSHA256 []string `koanf:"secret_sha256"`
+
+	// Claims are added to the client's validation result (optional)
+	Claims map[string]any `koanf:"claims"`
+
+	// Audiences the client may request tokens for besides the trust domain,
+	// in place of exchange_server.allowed_audiences (optional)
+	Audiences []string `koanf:"audiences"`
+
+	// TokenTypes are the token type URNs the client may be issued (all if unset)
+	TokenTypes []string `koanf:"token_types"`
+
+	// ClaimsFilter filters the request_context claims the client provides, in
+	// place of the exchange server's claims filter (optional)
+	ClaimsFilter *ClaimsFilterSpecConfig `koanf:"claims_filter"`
+
+	// Quota caps the tokens issued to the client (optional)
+	Quota *ClientQuotaConfig `koanf:"quota"`
+}
+
+// ClientQuotaConfig caps the tokens issued to a client per fixed window
+type ClientQuotaConfig struct {
+	// Limit is the number of tokens that may be issued per window
+	Limit int64 `koanf:"limit"`
+
+	// Window is the length of the windows tokens are counted in (e.g. "1m")
+	Window string `koanf:"window"`
 }
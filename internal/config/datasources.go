@@ -1,47 +1,140 @@
 package config
 
 import (
+	"crypto/tls"
+	"database/sql"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"net/netip"
 	"os"
 	"time"
 
+	"github.com/goccy/go-yaml"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/project-kessel/parsec/internal/clock"
 	"github.com/project-kessel/parsec/internal/datasource"
 	luaservices "github.com/project-kessel/parsec/internal/lua"
 	"github.com/project-kessel/parsec/internal/service"
 )
 
 // NewDataSourceRegistry creates a data source registry from configuration
-func NewDataSourceRegistry(cfg []DataSourceConfig, transport http.RoundTripper) (*service.DataSourceRegistry, error) {
+// Plugin data sources fetch through plugins, and the observer observes data
+// source bulkheads (both optional).
+func NewDataSourceRegistry(cfg []DataSourceConfig, plugins *Plugins, transport http.RoundTripper, observer service.BulkheadObserver) (*service.DataSourceRegistry, error) {
 	registry := service.NewDataSourceRegistry()
 
 	for _, dsCfg := range cfg {
-		ds, err := newDataSource(dsCfg, transport)
+		ds, err := newDataSource(dsCfg, plugins, transport, observer)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create data source %s: %w", dsCfg.Name, err)
 		}
-		registry.Register(ds)
+		registry.RegisterWithInfo(dataSourceInfo(dsCfg), ds)
 	}
 
 	return registry, nil
 }
 
-// newDataSource creates a data source from configuration
-func newDataSource(cfg DataSourceConfig, transport http.RoundTripper) (service.DataSource, error) {
-	switch cfg.Type {
-	case "lua":
-		return newLuaDataSource(cfg, transport)
+// dataSourceInfo describes a data source created from valid configuration
+func dataSourceInfo(cfg DataSourceConfig) service.DataSourceInfo {
+	info := service.DataSourceInfo{Name: cfg.Name, Type: cfg.Type}
+	if cfg.Caching == nil {
+		return info
+	}
+	switch cfg.Caching.Type {
+	case "none", "":
+	case "local":
+		info.Cacheable, info.Cache = true, "in_memory"
 	default:
-		return nil, fmt.Errorf("unknown data source type: %s (supported: lua)", cfg.Type)
+		info.Cacheable, info.Cache = true, cfg.Caching.Type
 	}
+	if info.Cacheable {
+		info.CacheTTL, _ = cacheTTL(cfg.Caching)
+	}
+	return info
 }
 
-// newLuaDataSource creates a Lua data source with optional caching
-func newLuaDataSource(cfg DataSourceConfig, transport http.RoundTripper) (service.DataSource, error) {
+// cacheTTL returns the TTL of cached data source results (default: 5m)
+func cacheTTL(cfg *CachingConfig) (time.Duration, error) {
+	if cfg == nil || cfg.TTL == "" {
+		return 5 * time.Minute, nil
+	}
+	ttl, err := time.ParseDuration(cfg.TTL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid caching ttl: %w", err)
+	}
+	if ttl == 0 {
+		ttl = 5 * time.Minute
+	}
+	return ttl, nil
+}
+
+// newDataSource creates a data source from configuration, wrapped with a
+// bulkhead and caching if configured
+func newDataSource(cfg DataSourceConfig, plugins *Plugins, transport http.RoundTripper, observer service.BulkheadObserver) (service.DataSource, error) {
 	if cfg.Name == "" {
 		return nil, fmt.Errorf("data source name is required")
 	}
 
+	bulkhead, err := newBulkhead("data_source:"+cfg.Name, cfg.Bulkhead, observer, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := cacheTTL(cfg.Caching)
+	if err != nil {
+		return nil, err
+	}
+
+	var ds service.DataSource
+	switch cfg.Type {
+	case "lua":
+		return newLuaDataSource(cfg, transport, ttl, bulkhead)
+	case "http":
+		ds, err = newHTTPDataSource(cfg, transport)
+	case "sql":
+		ds, err = newSQLDataSource(cfg)
+	case "grpc":
+		ds, err = newGRPCDataSource(cfg)
+	case "groups":
+		ds, err = newGroupsDataSource(cfg, transport)
+	case "geoip":
+		ds, err = newGeoIPDataSource(cfg)
+	case "plugin":
+		ds, err = newPluginDataSource(cfg, plugins)
+	default:
+		return nil, fmt.Errorf("unknown data source type: %s (supported: lua, http, sql, grpc, groups, geoip, plugin)", cfg.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ds = withBulkhead(ds, bulkhead)
+
+	if cfg.Caching != nil {
+		return wrapWithCaching(datasource.NewCacheableDataSource(ds, ttl, cfg.Caching.Key...), *cfg.Caching)
+	}
+	return ds, nil
+}
+
+// withBulkhead wraps a data source with a bulkhead, if there is one
+func withBulkhead(ds service.DataSource, bulkhead *service.Bulkhead) service.DataSource {
+	if bulkhead == nil {
+		return ds
+	}
+	return datasource.NewBulkheadDataSource(ds, bulkhead)
+}
+
+// newLuaDataSource creates a Lua data source with optional caching
+// Cached Lua data sources compute their cache key with a Lua function unless
+// key fields are configured
+func newLuaDataSource(cfg DataSourceConfig, transport http.RoundTripper, ttl time.Duration, bulkhead *service.Bulkhead) (service.DataSource, error) {
 	// Get script content (either from file or inline)
 	script := cfg.Script
 	if cfg.ScriptFile != "" {
@@ -85,12 +178,301 @@ func newLuaDataSource(cfg DataSourceConfig, transport http.RoundTripper) (servic
 		return nil, fmt.Errorf("failed to create lua data source: %w", err)
 	}
 
-	// Wrap with caching if configured
-	if cfg.Caching != nil {
-		return wrapWithCaching(baseDS, *cfg.Caching)
+	if cfg.Caching == nil {
+		return withBulkhead(baseDS, bulkhead), nil
+	}
+
+	// Cache keys come from the configured key fields, else from the script's
+	// cache key function (required if named explicitly), else the whole input
+	var cacheable service.DataSource = datasource.NewCacheableDataSource(withBulkhead(baseDS, bulkhead), ttl, cfg.Caching.Key...)
+	if len(cfg.Caching.Key) == 0 {
+		cacheKeyFunc := cfg.Caching.CacheKeyFunc
+		if cacheKeyFunc == "" {
+			cacheKeyFunc = "cache_key"
+		}
+		cacheableDS, err := datasource.NewCacheableLuaDataSource(datasource.CacheableLuaDataSourceConfig{
+			Name:         luaDSConfig.Name,
+			Script:       luaDSConfig.Script,
+			ConfigSource: luaDSConfig.ConfigSource,
+			HTTPConfig:   luaDSConfig.HTTPConfig,
+			CacheKeyFunc: cacheKeyFunc,
+			CacheTTL:     ttl,
+		})
+		switch {
+		case err == nil:
+			cacheable = withBulkhead(cacheableDS, bulkhead)
+		case cfg.Caching.CacheKeyFunc != "":
+			return nil, fmt.Errorf("failed to create lua data source: %w", err)
+		}
+	}
+
+	return wrapWithCaching(cacheable, *cfg.Caching)
+}
+
+// newHTTPDataSource creates an HTTP data source
+func newHTTPDataSource(cfg DataSourceConfig, transport http.RoundTripper) (service.DataSource, error) {
+	if cfg.HTTPConfig == nil || cfg.HTTPConfig.URL == "" {
+		return nil, fmt.Errorf("http data source requires http.url")
+	}
+
+	timeout, err := parseOptionalDuration(cfg.HTTPConfig.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid http timeout: %w", err)
+	}
+
+	ds, err := datasource.NewHTTPDataSource(datasource.HTTPDataSourceConfig{
+		Name:      cfg.Name,
+		URL:       cfg.HTTPConfig.URL,
+		Method:    cfg.HTTPConfig.Method,
+		Headers:   cfg.HTTPConfig.Headers,
+		Body:      cfg.HTTPConfig.Body,
+		Timeout:   timeout,
+		Transport: transport,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http data source: %w", err)
+	}
+	return ds, nil
+}
+
+// newSQLDataSource creates a SQL data source
+// The database handle is opened lazily by database/sql, so no connection is made here
+func newSQLDataSource(cfg DataSourceConfig) (service.DataSource, error) {
+	if cfg.SQL == nil || cfg.SQL.Driver == "" || cfg.SQL.DSN == "" || cfg.SQL.Query == "" {
+		return nil, fmt.Errorf("sql data source requires sql.driver, sql.dsn, and sql.query")
+	}
+
+	timeout, err := parseOptionalDuration(cfg.SQL.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sql timeout: %w", err)
+	}
+
+	db, err := sql.Open(cfg.SQL.Driver, cfg.SQL.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	ds, err := datasource.NewSQLDataSource(datasource.SQLDataSourceConfig{
+		Name:      cfg.Name,
+		DB:        db,
+		Query:     cfg.SQL.Query,
+		Args:      cfg.SQL.Args,
+		SingleRow: cfg.SQL.SingleRow,
+		Timeout:   timeout,
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create sql data source: %w", err)
+	}
+	return ds, nil
+}
+
+// newGRPCDataSource creates a gRPC data source
+// The connection is established lazily by gRPC, so no connection is made here
+func newGRPCDataSource(cfg DataSourceConfig) (service.DataSource, error) {
+	if cfg.GRPC == nil || cfg.GRPC.Address == "" || cfg.GRPC.Method == "" {
+		return nil, fmt.Errorf("grpc data source requires grpc.address and grpc.method")
+	}
+
+	timeout, err := parseOptionalDuration(cfg.GRPC.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid grpc timeout: %w", err)
+	}
+
+	var files *protoregistry.Files
+	if cfg.GRPC.DescriptorSetFile != "" {
+		files, err = loadDescriptorSet(cfg.GRPC.DescriptorSetFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if cfg.GRPC.Insecure {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.NewClient(cfg.GRPC.Address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create grpc client: %w", err)
+	}
+
+	ds, err := datasource.NewGRPCDataSource(datasource.GRPCDataSourceConfig{
+		Name:    cfg.Name,
+		Conn:    conn,
+		Method:  cfg.GRPC.Method,
+		Files:   files,
+		Request: cfg.GRPC.Request,
+		Timeout: timeout,
+	})
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to create grpc data source: %w", err)
 	}
+	return ds, nil
+}
+
+// newPluginDataSource creates a data source fetching through a plugin
+func newPluginDataSource(cfg DataSourceConfig, plugins *Plugins) (service.DataSource, error) {
+	if cfg.Plugin == "" {
+		return nil, fmt.Errorf("plugin data source requires plugin")
+	}
+	client, err := plugins.Get(cfg.Plugin)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := parseOptionalDuration(cfg.PluginTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plugin_timeout: %w", err)
+	}
+
+	ds, err := datasource.NewPluginDataSource(datasource.PluginDataSourceConfig{
+		Name:    cfg.Name,
+		Plugin:  client,
+		Timeout: timeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plugin data source: %w", err)
+	}
+	return ds, nil
+}
+
+// newGeoIPDataSource creates a geoip data source, loading its database
+func newGeoIPDataSource(cfg DataSourceConfig) (service.DataSource, error) {
+	if cfg.GeoIP == nil || cfg.GeoIP.Path == "" {
+		return nil, fmt.Errorf("geoip data source requires geoip.path")
+	}
+
+	reloadInterval, err := parseOptionalDuration(cfg.GeoIP.ReloadInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid geoip reload_interval: %w", err)
+	}
+
+	ds, err := datasource.NewGeoIPDataSource(datasource.GeoIPDataSourceConfig{
+		Name:           cfg.Name,
+		Path:           cfg.GeoIP.Path,
+		IP:             cfg.GeoIP.IP,
+		ReloadInterval: reloadInterval,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create geoip data source: %w", err)
+	}
+	return ds, nil
+}
+
+// newGroupsDataSource creates a groups data source
+func newGroupsDataSource(cfg DataSourceConfig, transport http.RoundTripper) (service.DataSource, error) {
+	if cfg.Groups == nil || len(cfg.Groups.Backends) == 0 {
+		return nil, fmt.Errorf("groups data source requires groups.backends")
+	}
+
+	backends := make([]datasource.GroupBackend, len(cfg.Groups.Backends))
+	for i, backendCfg := range cfg.Groups.Backends {
+		backend, err := newGroupBackend(backendCfg, transport)
+		if err != nil {
+			return nil, fmt.Errorf("groups.backends[%d]: %w", i, err)
+		}
+		backends[i] = backend
+	}
+
+	ds, err := datasource.NewGroupsDataSource(datasource.GroupsDataSourceConfig{
+		Name:     cfg.Name,
+		Backends: backends,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create groups data source: %w", err)
+	}
+	return ds, nil
+}
+
+// newGroupBackend creates a group backend from configuration
+func newGroupBackend(cfg GroupBackendConfig, transport http.RoundTripper) (datasource.GroupBackend, error) {
+	timeout, err := parseOptionalDuration(cfg.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timeout: %w", err)
+	}
+	if cfg.PageSize < 0 {
+		return nil, fmt.Errorf("page_size cannot be negative")
+	}
+
+	switch cfg.Type {
+	case "static":
+		members, err := loadGroupMembers(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return datasource.NewStaticGroupBackend(members), nil
+	case "scim":
+		return datasource.NewSCIMGroupBackend(datasource.SCIMGroupBackendConfig{
+			URL:       cfg.URL,
+			Member:    cfg.Member,
+			Headers:   cfg.Headers,
+			PageSize:  cfg.PageSize,
+			MaxPages:  cfg.MaxPages,
+			Timeout:   timeout,
+			Transport: transport,
+		})
+	case "ldap":
+		return datasource.NewLDAPGroupBackend(datasource.LDAPGroupBackendConfig{
+			URL:          cfg.URL,
+			BindDN:       cfg.BindDN,
+			BindPassword: cfg.BindPassword,
+			BaseDN:       cfg.BaseDN,
+			Filter:       cfg.Filter,
+			Attribute:    cfg.Attribute,
+			PageSize:     uint32(cfg.PageSize),
+			Timeout:      timeout,
+		})
+	default:
+		return nil, fmt.Errorf("unknown group backend type: %s (supported: static, scim, ldap)", cfg.Type)
+	}
+}
+
+// loadGroupMembers returns the members of a static group backend, merging
+// the file's groups with the inline ones
+func loadGroupMembers(cfg GroupBackendConfig) (map[string][]string, error) {
+	if cfg.File == "" && len(cfg.Members) == 0 {
+		return nil, fmt.Errorf("static group backend requires members or file")
+	}
+
+	members := make(map[string][]string, len(cfg.Members))
+	if cfg.File != "" {
+		data, err := os.ReadFile(cfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read groups file %s: %w", cfg.File, err)
+		}
+		if err := yaml.Unmarshal(data, &members); err != nil {
+			return nil, fmt.Errorf("failed to parse groups file %s: %w", cfg.File, err)
+		}
+	}
+	for group, subjects := range cfg.Members {
+		members[group] = append(members[group], subjects...)
+	}
+	return members, nil
+}
+
+// loadDescriptorSet reads a serialized FileDescriptorSet
+func loadDescriptorSet(path string) (*protoregistry.Files, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set file: %w", err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse descriptor set file: %w", err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %w", err)
+	}
+	return files, nil
+}
 
-	return baseDS, nil
+// parseOptionalDuration parses a duration, treating the empty string as zero
+func parseOptionalDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
 }
 
 // buildHTTPConfig creates an HTTPServiceConfig from the config structure
@@ -119,7 +501,7 @@ func buildHTTPConfig(cfg *HTTPConfig, transport http.RoundTripper) (*luaservices
 // wrapWithCaching wraps a data source with the configured caching layer
 func wrapWithCaching(ds service.DataSource, cfg CachingConfig) (service.DataSource, error) {
 	switch cfg.Type {
-	case "in_memory":
+	case "in_memory", "local":
 		// In-memory caching uses the Cacheable interface from the data source
 		return datasource.NewInMemoryCachingDataSource(ds), nil
 
@@ -141,11 +523,76 @@ func wrapWithCaching(ds service.DataSource, cfg CachingConfig) (service.DataSour
 
 		return datasource.NewDistributedCachingDataSource(ds, cachingCfg), nil
 
+	case "redis":
+		if cfg.Redis == nil || cfg.Redis.Address == "" {
+			return nil, fmt.Errorf("redis caching requires redis.address")
+		}
+		timeout, err := parseOptionalDuration(cfg.Redis.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis timeout: %w", err)
+		}
+		return datasource.NewRedisCachingDataSource(ds, datasource.RedisCachingConfig{
+			Address:   cfg.Redis.Address,
+			Password:  cfg.Redis.Password,
+			DB:        cfg.Redis.DB,
+			KeyPrefix: cfg.Redis.KeyPrefix,
+			Timeout:   timeout,
+		})
+
 	case "none", "":
 		// No caching
 		return ds, nil
 
 	default:
-		return nil, fmt.Errorf("unknown caching type: %s (supported: in_memory, distributed, none)", cfg.Type)
+		return nil, fmt.Errorf("unknown caching type: %s (supported: in_memory, local, distributed, redis, none)", cfg.Type)
+	}
+}
+
+// NewDistributedCachePeers sets up the groupcache peer pool and returns the
+// peers serving peer requests, or nil if no distributed cache is configured.
+// The logger reports failed peer discovery.
+func NewDistributedCachePeers(cfg *DistributedCacheConfig, clk clock.Clock, logger *slog.Logger) (*datasource.GroupcachePeers, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if isPeerDiscovery(cfg.Discovery) && len(cfg.Peers) > 0 {
+		return nil, fmt.Errorf("peers cannot be listed with %s peer discovery", cfg.Discovery.Type)
+	}
+	discovery, refreshInterval, err := newPeerDiscovery(cfg.Discovery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer discovery: %w", err)
+	}
+	networks, err := parseCachePeerNetworks(cfg.AllowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	peers, err := datasource.SetGroupcachePeers(datasource.GroupcachePeersConfig{
+		Self:            cfg.Self,
+		Peers:           cfg.Peers,
+		Discovery:       discovery,
+		RefreshInterval: refreshInterval,
+		BasePath:        cfg.BasePath,
+		Secret:          cfg.Secret,
+		AllowedNetworks: networks,
+		Clock:           clk,
+		Logger:          logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up distributed cache peers: %w", err)
+	}
+	return peers, nil
+}
+
+// parseCachePeerNetworks parses the networks cache peer requests may come from
+func parseCachePeerNetworks(cidrs []string) ([]netip.Prefix, error) {
+	var networks []netip.Prefix
+	for _, cidr := range cidrs {
+		network, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed CIDR %q: %w", cidr, err)
+		}
+		networks = append(networks, network.Masked())
 	}
+	return networks, nil
 }
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/mmdb/mmdbtest"
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestNewDataSourceRegistry_HTTPWithCaching(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = fmt.Fprintf(w, `{"user":%q}`, strings.TrimPrefix(r.URL.Path, "/users/"))
+	}))
+	defer server.Close()
+
+	registry, err := NewDataSourceRegistry([]DataSourceConfig{
+		{
+			Name: "profile",
+			Type: "http",
+			HTTPConfig: &HTTPConfig{
+				URL:     server.URL + "/users/{{ .subject.subject | urlquery }}",
+				Timeout: "5s",
+			},
+			Caching: &CachingConfig{
+				Type: "local",
+				TTL:  "1m",
+				Key:  []string{"subject.subject"},
+			},
+		},
+	}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create registry: %v", err)
+	}
+
+	ds := registry.Get("profile")
+	if ds == nil {
+		t.Fatal("expected profile data source")
+	}
+	info, err := registry.Describe("profile")
+	if err != nil || info.Type != "http" || !info.Cacheable || info.Cache != "in_memory" || info.CacheTTL != time.Minute {
+		t.Errorf("unexpected description %+v (%v)", info, err)
+	}
+
+	ctx := context.Background()
+	for _, path := range []string{"/a", "/b"} {
+		result, err := ds.Fetch(ctx, &service.DataSourceInput{
+			Subject: &trust.Result{Subject: "alice", Issuer: "https://idp.example.com" + path},
+		})
+		if err != nil {
+			t.Fatalf("fetch failed: %v", err)
+		}
+		if string(result.Data) != `{"user":"alice"}` {
+			t.Errorf("unexpected data: %s", result.Data)
+		}
+	}
+
+	// The issuer is not part of the cache key, so the second fetch is a hit
+	if got := requests.Load(); got != 1 {
+		t.Errorf("expected 1 upstream request, got %d", got)
+	}
+}
+
+func TestNewDataSource_LuaCaching(t *testing.T) {
+	const fetchOnly = `
+function fetch(input)
+  return {data = '{"ok":true}', content_type = "application/json"}
+end`
+
+	t.Run("falls back to whole-input key without cache_key function", func(t *testing.T) {
+		_, err := newDataSource(DataSourceConfig{
+			Name:    "lua",
+			Type:    "lua",
+			Script:  fetchOnly,
+			Caching: &CachingConfig{Type: "in_memory"},
+		}, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("expected data source, got %v", err)
+		}
+	})
+
+	t.Run("requires an explicitly named cache key function", func(t *testing.T) {
+		_, err := newDataSource(DataSourceConfig{
+			Name:    "lua",
+			Type:    "lua",
+			Script:  fetchOnly,
+			Caching: &CachingConfig{Type: "in_memory", CacheKeyFunc: "roles_key"},
+		}, nil, nil, nil)
+		if err == nil {
+			t.Fatal("expected error for missing cache key function")
+		}
+	})
+}
+
+func TestNewDataSource_Groups(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"totalResults": 1, "Resources": [{"displayName": "engineering"}]}`)
+	}))
+	defer server.Close()
+
+	groupsFile := filepath.Join(t.TempDir(), "groups.yaml")
+	if err := os.WriteFile(groupsFile, []byte("admins: [alice]\nemployees: [alice, bob]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := newDataSource(DataSourceConfig{
+		Name: "groups",
+		Type: "groups",
+		Groups: &GroupsConfig{Backends: []GroupBackendConfig{
+			{Type: "static", File: groupsFile, Members: map[string][]string{"employees": {"carol"}}},
+			{Type: "scim", URL: server.URL, PageSize: 10},
+		}},
+	}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create data source: %v", err)
+	}
+
+	result, err := ds.Fetch(context.Background(), &service.DataSourceInput{Subject: &trust.Result{Subject: "alice"}})
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if got := string(result.Data); got != `{"roles":["admins","employees","engineering"]}` {
+		t.Errorf("unexpected roles: %s", got)
+	}
+}
+
+func TestNewDataSource_GeoIP(t *testing.T) {
+	buf, err := mmdbtest.Build(mmdbtest.Database{Networks: []mmdbtest.Network{{
+		Prefix: netip.MustParsePrefix("203.0.113.0/24"),
+		Record: map[string]any{"country": map[string]any{"iso_code": "AU"}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "country.mmdb")
+	if err := os.WriteFile(path, buf, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := newDataSource(DataSourceConfig{
+		Name:  "geo",
+		Type:  "geoip",
+		GeoIP: &GeoIPConfig{Path: path, ReloadInterval: "30s"},
+	}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create data source: %v", err)
+	}
+
+	result, err := ds.Fetch(context.Background(), &service.DataSourceInput{
+		RequestAttributes: &request.RequestAttributes{IPAddress: "203.0.113.7"},
+	})
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if got := string(result.Data); got != `{"country":{"iso_code":"AU"}}` {
+		t.Errorf("unexpected record: %s", got)
+	}
+}
+
+func TestNewDataSource_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  DataSourceConfig
+	}{
+		{"http without url", DataSourceConfig{Name: "a", Type: "http"}},
+		{"sql without query", DataSourceConfig{Name: "a", Type: "sql", SQL: &SQLConfig{Driver: "postgres", DSN: "x"}}},
+		{"grpc without method", DataSourceConfig{Name: "a", Type: "grpc", GRPC: &GRPCConfig{Address: "localhost:1"}}},
+		{"grpc with unknown service", DataSourceConfig{Name: "a", Type: "grpc", GRPC: &GRPCConfig{Address: "localhost:1", Method: "/no.Such/Call"}}},
+		{"redis without address", DataSourceConfig{Name: "a", Type: "http", HTTPConfig: &HTTPConfig{URL: "http://x"}, Caching: &CachingConfig{Type: "redis"}}},
+		{"invalid ttl", DataSourceConfig{Name: "a", Type: "http", HTTPConfig: &HTTPConfig{URL: "http://x"}, Caching: &CachingConfig{Type: "local", TTL: "soon"}}},
+		{"unknown caching type", DataSourceConfig{Name: "a", Type: "http", HTTPConfig: &HTTPConfig{URL: "http://x"}, Caching: &CachingConfig{Type: "memcached"}}},
+		{"groups without backends", DataSourceConfig{Name: "a", Type: "groups"}},
+		{"unknown group backend", DataSourceConfig{Name: "a", Type: "groups", Groups: &GroupsConfig{Backends: []GroupBackendConfig{{Type: "nis"}}}}},
+		{"ldap group backend without filter", DataSourceConfig{Name: "a", Type: "groups", Groups: &GroupsConfig{Backends: []GroupBackendConfig{{Type: "ldap", URL: "ldap://x", BaseDN: "dc=x"}}}}},
+		{"geoip without path", DataSourceConfig{Name: "a", Type: "geoip"}},
+		{"geoip with invalid reload interval", DataSourceConfig{Name: "a", Type: "geoip", GeoIP: &GeoIPConfig{Path: "x.mmdb", ReloadInterval: "often"}}},
+		{"unknown type", DataSourceConfig{Name: "a", Type: "ldap"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newDataSource(tt.cfg, nil, nil, nil); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+
+	"github.com/project-kessel/parsec/internal/decisionlog"
+)
+
+// NewDecisionLogger creates the decision logger
+// Returns nil if cfg is nil (decisions are not logged). The logger reports
+// delivery failures to logger.
+func NewDecisionLogger(cfg *DecisionLogConfig, logger *slog.Logger) (*decisionlog.Logger, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	sink, err := newDecisionLogSink(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	fallback, err := newDecisionLogFallback(cfg)
+	if err != nil {
+		_ = sink.Close()
+		return nil, err
+	}
+
+	l, err := decisionlog.NewLogger(decisionlog.LoggerConfig{
+		Sink:       sink,
+		Fallback:   fallback,
+		Delivery:   decisionlog.Delivery(cfg.Delivery),
+		BufferSize: cfg.BufferSize,
+		BatchSize:  cfg.BatchSize,
+		Retries:    cfg.Retries,
+		Logger:     logger,
+	})
+	if err != nil {
+		err = errors.Join(err, sink.Close())
+		if fallback != nil {
+			err = errors.Join(err, fallback.Close())
+		}
+		return nil, err
+	}
+	return l, nil
+}
+
+// newDecisionLogSink creates the primary decision log sink
+func newDecisionLogSink(cfg *DecisionLogConfig) (decisionlog.Sink, error) {
+	switch cfg.Sink {
+	case "kafka":
+		return newKafkaSink(cfg.Kafka)
+	case "file":
+		if cfg.File == "" {
+			return nil, fmt.Errorf("file decision log sink requires file")
+		}
+		return decisionlog.NewFileSink(cfg.File)
+	case "stdout":
+		return decisionlog.NewStdoutSink(), nil
+	default:
+		return nil, fmt.Errorf("unknown decision log sink: %s (supported: kafka, file, stdout)", cfg.Sink)
+	}
+}
+
+// newDecisionLogFallback creates the fallback decision log sink (nil for none)
+func newDecisionLogFallback(cfg *DecisionLogConfig) (decisionlog.Sink, error) {
+	fallback := cfg.Fallback
+	if fallback == "" {
+		fallback = "none"
+		if cfg.Sink == "kafka" {
+			fallback = "stdout"
+		}
+	}
+
+	switch fallback {
+	case "none":
+		return nil, nil
+	case "stdout":
+		return decisionlog.NewStdoutSink(), nil
+	case "file":
+		if cfg.File == "" {
+			return nil, fmt.Errorf("file decision log fallback requires file")
+		}
+		if cfg.Sink == "file" {
+			return nil, fmt.Errorf("file decision log sink cannot fall back to itself")
+		}
+		return decisionlog.NewFileSink(cfg.File)
+	default:
+		return nil, fmt.Errorf("unknown decision log fallback: %s (supported: stdout, file, none)", fallback)
+	}
+}
+
+// newKafkaSink creates a kafka decision log sink
+func newKafkaSink(cfg *KafkaSinkConfig) (decisionlog.Sink, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("kafka decision log sink requires kafka.brokers and kafka.topic")
+	}
+
+	deliveryTimeout, err := parseOptionalDuration(cfg.DeliveryTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kafka delivery_timeout: %w", err)
+	}
+
+	var tlsConfig *tls.Config
+	if cfg.TLS {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		if cfg.CAFile != "" {
+			pem, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read kafka ca_file: %w", err)
+			}
+			roots := x509.NewCertPool()
+			if !roots.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("kafka ca_file %s contains no certificates", cfg.CAFile)
+			}
+			tlsConfig.RootCAs = roots
+		}
+	}
+
+	mechanism, err := newKafkaSASL(cfg.SASL)
+	if err != nil {
+		return nil, err
+	}
+
+	return decisionlog.NewKafkaSink(decisionlog.KafkaSinkConfig{
+		Brokers:         cfg.Brokers,
+		Topic:           cfg.Topic,
+		ClientID:        cfg.ClientID,
+		TLS:             tlsConfig,
+		SASL:            mechanism,
+		DeliveryTimeout: deliveryTimeout,
+	})
+}
+
+// newKafkaSASL creates the SASL mechanism for kafka (nil if not configured)
+func newKafkaSASL(cfg *KafkaSASLConfig) (sasl.Mechanism, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.Username == "" || cfg.Password == "" {
+		return nil, fmt.Errorf("kafka sasl requires username and password")
+	}
+
+	switch cfg.Mechanism {
+	case "plain":
+		return plain.Auth{User: cfg.Username, Pass: cfg.Password}.AsMechanism(), nil
+	case "scram-sha-256":
+		return scram.Auth{User: cfg.Username, Pass: cfg.Password}.AsSha256Mechanism(), nil
+	case "scram-sha-512":
+		return scram.Auth{User: cfg.Username, Pass: cfg.Password}.AsSha512Mechanism(), nil
+	default:
+		return nil, fmt.Errorf("unknown kafka sasl mechanism: %s (supported: plain, scram-sha-256, scram-sha-512)", cfg.Mechanism)
+	}
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/decisionlog"
+)
+
+func TestNewDecisionLogger_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	logger, err := NewDecisionLogger(&DecisionLogConfig{Sink: "file", File: path, Delivery: "sync"}, nil)
+	if err != nil {
+		t.Fatalf("NewDecisionLogger failed: %v", err)
+	}
+
+	logger.Record(context.Background(), decisionlog.Event{SchemaVersion: decisionlog.SchemaVersion, ID: "event-1"})
+	if err := logger.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"id":"event-1"`) {
+		t.Errorf("expected the event in the file, got %s", data)
+	}
+
+	if logger, err := NewDecisionLogger(nil, nil); logger != nil || err != nil {
+		t.Errorf("expected no decision log without config, got %v (%v)", logger, err)
+	}
+}
+
+func TestNewDecisionLogger_Kafka(t *testing.T) {
+	logger, err := NewDecisionLogger(&DecisionLogConfig{
+		Sink: "kafka",
+		Kafka: &KafkaSinkConfig{
+			Brokers: []string{"localhost:9092"},
+			Topic:   "parsec.decisions",
+			TLS:     true,
+			SASL:    &KafkaSASLConfig{Mechanism: "scram-sha-512", Username: "parsec", Password: "secret"},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewDecisionLogger failed: %v", err)
+	}
+	_ = logger.Close(context.Background())
+}
+
+func TestNewDecisionLogger_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *DecisionLogConfig
+	}{
+		{"unknown sink", &DecisionLogConfig{Sink: "syslog"}},
+		{"file without path", &DecisionLogConfig{Sink: "file"}},
+		{"kafka without config", &DecisionLogConfig{Sink: "kafka"}},
+		{"kafka without topic", &DecisionLogConfig{Sink: "kafka", Kafka: &KafkaSinkConfig{Brokers: []string{"localhost:9092"}}}},
+		{"unknown sasl mechanism", &DecisionLogConfig{Sink: "kafka", Kafka: &KafkaSinkConfig{
+			Brokers: []string{"localhost:9092"}, Topic: "t",
+			SASL: &KafkaSASLConfig{Mechanism: "gssapi", Username: "u", Password: "p"},
+		}}},
+		{"unknown fallback", &DecisionLogConfig{Sink: "stdout", Fallback: "syslog"}},
+		{"file falling back to itself", &DecisionLogConfig{Sink: "file", File: "decisions.jsonl", Fallback: "file"}},
+		{"unknown delivery", &DecisionLogConfig{Sink: "stdout", Delivery: "eventually"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cfg.File != "" {
+				tt.cfg.File = filepath.Join(t.TempDir(), tt.cfg.File)
+			}
+			if _, err := NewDecisionLogger(tt.cfg, nil); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/project-kessel/parsec/internal/server"
+)
+
+// NewDenyCache creates the ext_authz cache of validation failures, or nil if
+// it isn't configured
+func NewDenyCache(cfg *DenyCacheConfig) (*server.DenyCache, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.MaxEntries < 0 {
+		return nil, fmt.Errorf("deny_cache.max_entries must not be negative")
+	}
+	ttl, err := parseOptionalDuration(cfg.TTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deny_cache.ttl: %w", err)
+	}
+	if ttl < 0 {
+		return nil, fmt.Errorf("deny_cache.ttl must not be negative")
+	}
+	return server.NewDenyCache(server.DenyCacheConfig{
+		MaxEntries: cfg.MaxEntries,
+		TTL:        ttl,
+	}), nil
+}
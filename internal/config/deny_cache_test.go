@@ -0,0 +1,27 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestNewDenyCache(t *testing.T) {
+	cache, err := NewDenyCache(nil)
+	if err != nil || cache != nil {
+		t.Fatalf("expected no cache, got %v, %v", cache, err)
+	}
+
+	cache, err = NewDenyCache(&DenyCacheConfig{MaxEntries: 100, TTL: "5s"})
+	if err != nil || cache == nil {
+		t.Fatalf("expected a cache, got %v, %v", cache, err)
+	}
+
+	for name, cfg := range map[string]*DenyCacheConfig{
+		"negative entries": {MaxEntries: -1},
+		"invalid ttl":      {TTL: "soon"},
+		"negative ttl":     {TTL: "-5s"},
+	} {
+		if _, err := NewDenyCache(cfg); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
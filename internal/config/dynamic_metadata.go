@@ -0,0 +1,30 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/project-kessel/parsec/internal/server"
+)
+
+// NewDynamicMetadata creates the selection of identity attributes ext_authz
+// sets in Envoy dynamic metadata, or nil if it isn't configured
+func NewDynamicMetadata(cfg *DynamicMetadataConfig) (*server.DynamicMetadata, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if len(cfg.Fields) == 0 && len(cfg.Claims) == 0 {
+		return nil, fmt.Errorf("dynamic_metadata requires fields or claims")
+	}
+
+	metadata := &server.DynamicMetadata{Claims: cfg.Claims}
+	for _, field := range cfg.Fields {
+		switch server.DynamicMetadataField(field) {
+		case server.DynamicMetadataSubject, server.DynamicMetadataIssuer, server.DynamicMetadataTrustDomain,
+			server.DynamicMetadataActor, server.DynamicMetadataActorTrustDomain, server.DynamicMetadataTransactionID:
+			metadata.Fields = append(metadata.Fields, server.DynamicMetadataField(field))
+		default:
+			return nil, fmt.Errorf("unknown dynamic metadata field: %s (supported: subject, issuer, trust_domain, actor, actor_trust_domain, txn)", field)
+		}
+	}
+	return metadata, nil
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/server"
+)
+
+func TestNewDynamicMetadata(t *testing.T) {
+	metadata, err := NewDynamicMetadata(&DynamicMetadataConfig{Fields: []string{"subject", "txn"}, Claims: []string{"org_id"}})
+	if err != nil {
+		t.Fatalf("NewDynamicMetadata failed: %v", err)
+	}
+	if len(metadata.Fields) != 2 || metadata.Fields[1] != server.DynamicMetadataTransactionID || metadata.Claims[0] != "org_id" {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+
+	if metadata, err := NewDynamicMetadata(nil); metadata != nil || err != nil {
+		t.Errorf("expected no metadata, got %v, %v", metadata, err)
+	}
+	for name, cfg := range map[string]*DynamicMetadataConfig{
+		"empty":         {},
+		"unknown field": {Fields: []string{"email"}},
+	} {
+		if _, err := NewDynamicMetadata(cfg); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
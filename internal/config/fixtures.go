@@ -1,7 +1,12 @@
 package config
 
 import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/lestrrat-go/jwx/v3/jwa"
 
@@ -28,6 +33,10 @@ func BuildHTTPFixtureProvider(fixtures []FixtureConfig, clk clock.Clock) (httpfi
 			continue
 		}
 
+		response, err := buildFixtureResponse(f.Response)
+		if err != nil {
+			return nil, fmt.Errorf("http_rule fixture for %s: %w", f.Request.URL, err)
+		}
 		rule := httpfixture.HTTPFixtureRule{
 			Request: httpfixture.FixtureRequest{
 				Method:  f.Request.Method,
@@ -35,11 +44,14 @@ func BuildHTTPFixtureProvider(fixtures []FixtureConfig, clk clock.Clock) (httpfi
 				URLType: f.Request.URLType,
 				Headers: f.Request.Headers,
 			},
-			Response: httpfixture.Fixture{
-				StatusCode: f.Response.StatusCode,
-				Headers:    f.Response.Headers,
-				Body:       f.Response.Body,
-			},
+			Response: response,
+		}
+		for i, r := range f.Responses {
+			response, err := buildFixtureResponse(r)
+			if err != nil {
+				return nil, fmt.Errorf("http_rule fixture for %s: responses[%d]: %w", f.Request.URL, i, err)
+			}
+			rule.Sequence = append(rule.Sequence, response)
 		}
 		rules = append(rules, rule)
 	}
@@ -69,12 +81,18 @@ func BuildHTTPFixtureProvider(fixtures []FixtureConfig, clk clock.Clock) (httpfi
 
 		}
 
+		privateKey, err := loadFixturePrivateKey(f)
+		if err != nil {
+			return nil, fmt.Errorf("jwks fixture for issuer %s: %w", f.Issuer, err)
+		}
+
 		jwksFixture, err := httpfixture.NewJWKSFixture(httpfixture.JWKSFixtureConfig{
-			Issuer:    f.Issuer,
-			JWKSURL:   f.JWKSURL,
-			KeyID:     f.KeyID, // Can be empty, will use default
-			Algorithm: algo,    // Can be zero value, will use default
-			Clock:     clk,
+			Issuer:     f.Issuer,
+			JWKSURL:    f.JWKSURL,
+			KeyID:      f.KeyID,    // Can be empty, will use default
+			Algorithm:  algo,       // Can be zero value, will use default
+			PrivateKey: privateKey, // Can be nil, will generate a key
+			Clock:      clk,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to create JWKS fixture for issuer %s: %w", f.Issuer, err)
@@ -90,6 +108,18 @@ func BuildHTTPFixtureProvider(fixtures []FixtureConfig, clk clock.Clock) (httpfi
 		providers = append(providers, httpfixture.NewRuleBasedProvider(rules))
 	}
 
+	// Recorded fixture files, after the inline rules so those take precedence
+	for _, f := range fixtures {
+		if f.Type != "file" {
+			continue
+		}
+		provider, err := loadFixtureFile(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
 	for _, jwks := range jwksFixtures {
 		providers = append(providers, jwks)
 	}
@@ -97,3 +127,131 @@ func BuildHTTPFixtureProvider(fixtures []FixtureConfig, clk clock.Clock) (httpfi
 	// Always return a valid CompositeFixtureProvider, even if empty
 	return httpfixture.NewCompositeFixtureProvider(providers, jwksFixtures), nil
 }
+
+// buildFixtureResponse creates the response of an http_rule fixture
+func buildFixtureResponse(cfg FixtureResponse) (httpfixture.Fixture, error) {
+	fixture := httpfixture.Fixture{
+		StatusCode:  cfg.StatusCode,
+		Headers:     cfg.Headers,
+		Body:        cfg.Body,
+		Fault:       httpfixture.Fault(cfg.Fault),
+		ErrorRate:   cfg.ErrorRate,
+		ErrorStatus: cfg.ErrorStatus,
+	}
+	if cfg.Delay != "" {
+		delay, err := time.ParseDuration(cfg.Delay)
+		if err != nil {
+			return httpfixture.Fixture{}, fmt.Errorf("invalid delay: %w", err)
+		}
+		fixture.Delay = &delay
+	}
+	if !fixture.Fault.Valid() {
+		return httpfixture.Fixture{}, fmt.Errorf("unknown fault %q (must be connection_reset or timeout)", cfg.Fault)
+	}
+	if cfg.ErrorRate < 0 || cfg.ErrorRate > 1 {
+		return httpfixture.Fixture{}, fmt.Errorf("error_rate must be between 0 and 1")
+	}
+	return fixture, nil
+}
+
+// loadFixtureFile loads the rules of a file fixture from a file or directory
+func loadFixtureFile(path string) (*httpfixture.RuleBasedProvider, error) {
+	if path == "" {
+		return nil, fmt.Errorf("file fixture missing required field: path")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("file fixture: %w", err)
+	}
+	if info.IsDir() {
+		return httpfixture.LoadFixturesFromDir(path)
+	}
+	return httpfixture.LoadFixturesFromFile(path)
+}
+
+// BuildHTTPRecorder creates the recorder declared in fixture configurations
+// Requests that no other fixture matches are sent to the real network and
+// recorded to the file, which can be replayed later with a file fixture.
+// Returns nil if no record fixture is configured.
+func BuildHTTPRecorder(fixtures []FixtureConfig) (*httpfixture.Recorder, error) {
+	var recorder *httpfixture.Recorder
+	for _, f := range fixtures {
+		if f.Type != "record" {
+			continue
+		}
+
+		if recorder != nil {
+			return nil, fmt.Errorf("only one record fixture may be configured")
+		}
+		if f.Path == "" {
+			return nil, fmt.Errorf("record fixture missing required field: path")
+		}
+		recorder = httpfixture.NewRecorder(httpfixture.RecorderConfig{Path: f.Path})
+	}
+
+	return recorder, nil
+}
+
+// loadFixturePrivateKey loads the configured signing key of a JWKS fixture
+// Returns nil if no key is configured
+func loadFixturePrivateKey(f FixtureConfig) (*rsa.PrivateKey, error) {
+	if f.PrivateKey != "" && f.PrivateKeyFile != "" {
+		return nil, fmt.Errorf("only one of private_key or private_key_file may be set")
+	}
+
+	content := []byte(f.PrivateKey)
+	if f.PrivateKeyFile != "" {
+		var err error
+		content, err = os.ReadFile(f.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key file %s: %w", f.PrivateKeyFile, err)
+		}
+	}
+	if len(content) == 0 {
+		return nil, nil
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("private key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key must be an RSA key, got %T", parsed)
+	}
+	return key, nil
+}
+
+// BuildFixtureClock creates the fixture clock declared in fixture configurations
+// Returns nil if no clock fixture is configured (components use the system clock)
+func BuildFixtureClock(fixtures []FixtureConfig) (*clock.FixtureClock, error) {
+	var clk *clock.FixtureClock
+	for _, f := range fixtures {
+		if f.Type != "clock" {
+			continue
+		}
+
+		if clk != nil {
+			return nil, fmt.Errorf("only one clock fixture may be configured")
+		}
+		if f.Time == "" {
+			return nil, fmt.Errorf("clock fixture missing required field: time")
+		}
+
+		start, err := time.Parse(time.RFC3339, f.Time)
+		if err != nil {
+			return nil, fmt.Errorf("clock fixture has invalid time %q: %w", f.Time, err)
+		}
+		clk = clock.NewFixtureClock(start)
+	}
+
+	return clk, nil
+}
@@ -0,0 +1,249 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+
+	"github.com/project-kessel/parsec/internal/httpfixture"
+)
+
+func TestBuildFixtureClock(t *testing.T) {
+	t.Run("no clock fixture", func(t *testing.T) {
+		clk, err := BuildFixtureClock([]FixtureConfig{{Type: "http_rule"}})
+		if err != nil || clk != nil {
+			t.Errorf("expected nil clock and error, got %v, %v", clk, err)
+		}
+	})
+
+	t.Run("fixed time", func(t *testing.T) {
+		clk, err := BuildFixtureClock([]FixtureConfig{{Type: "clock", Time: "2024-06-15T10:00:00Z"}})
+		if err != nil {
+			t.Fatalf("failed to build clock: %v", err)
+		}
+		if want := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC); !clk.Now().Equal(want) {
+			t.Errorf("expected %v, got %v", want, clk.Now())
+		}
+	})
+
+	for name, fixtures := range map[string][]FixtureConfig{
+		"missing time": {{Type: "clock"}},
+		"invalid time": {{Type: "clock", Time: "yesterday"}},
+		"multiple clocks": {
+			{Type: "clock", Time: "2024-06-15T10:00:00Z"},
+			{Type: "clock", Time: "2024-06-16T10:00:00Z"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := BuildFixtureClock(fixtures); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestProvider_FixtureClockIsShared(t *testing.T) {
+	provider := NewProvider(&Config{
+		Fixtures: []FixtureConfig{
+			{Type: "clock", Time: "2024-06-15T10:00:00Z"},
+			{
+				Type:    "jwks",
+				Issuer:  "https://idp.example.com",
+				JWKSURL: "https://idp.example.com/.well-known/jwks.json",
+			},
+		},
+	})
+
+	clk, err := provider.Clock()
+	if err != nil {
+		t.Fatalf("failed to get clock: %v", err)
+	}
+
+	fixtures, ok := provider.HTTPFixtureProvider().(*httpfixture.CompositeFixtureProvider)
+	if !ok {
+		t.Fatalf("expected composite fixture provider, got %T", provider.HTTPFixtureProvider())
+	}
+	jwksFixture := fixtures.JWKSFixture("https://idp.example.com")
+	if jwksFixture == nil {
+		t.Fatal("expected JWKS fixture")
+	}
+	if jwksFixture.Clock() != clk {
+		t.Error("expected JWKS fixture to use the provider clock")
+	}
+
+	token, err := jwksFixture.CreateAndSignToken(map[string]interface{}{"sub": "alice"})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	parsed, err := jwt.ParseInsecure([]byte(token))
+	if err != nil {
+		t.Fatalf("failed to parse token: %v", err)
+	}
+	iat, _ := parsed.IssuedAt()
+	if !iat.Equal(clk.Now()) {
+		t.Errorf("expected iat %v, got %v", clk.Now(), iat)
+	}
+}
+
+func TestBuildHTTPFixtureProvider_JWKSPrivateKey(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	}))
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyFile, []byte(keyPEM), 0o600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+
+	jwksFixture := func(f FixtureConfig) (*httpfixture.JWKSFixture, error) {
+		f.Type = "jwks"
+		f.Issuer = "https://idp.example.com"
+		f.JWKSURL = "https://idp.example.com/.well-known/jwks.json"
+		provider, err := BuildHTTPFixtureProvider([]FixtureConfig{f}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return provider.(*httpfixture.CompositeFixtureProvider).JWKSFixture(f.Issuer), nil
+	}
+
+	for name, f := range map[string]FixtureConfig{
+		"inline": {PrivateKey: keyPEM},
+		"file":   {PrivateKeyFile: keyFile},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fixture, err := jwksFixture(f)
+			if err != nil {
+				t.Fatalf("failed to build fixture: %v", err)
+			}
+
+			publicKey, err := jwk.Import(privateKey.PublicKey)
+			if err != nil {
+				t.Fatalf("failed to import key: %v", err)
+			}
+			fixtureToken, err := fixture.CreateAndSignToken(map[string]interface{}{"sub": "alice"})
+			if err != nil {
+				t.Fatalf("failed to sign fixture token: %v", err)
+			}
+			if _, err := jwt.Parse([]byte(fixtureToken), jwt.WithKey(jwa.RS256(), publicKey), jwt.WithValidate(false)); err != nil {
+				t.Errorf("expected fixture to sign with the configured key: %v", err)
+			}
+		})
+	}
+
+	for name, f := range map[string]FixtureConfig{
+		"both inline and file": {PrivateKey: keyPEM, PrivateKeyFile: keyFile},
+		"not PEM":              {PrivateKey: "not a key"},
+		"missing file":         {PrivateKeyFile: filepath.Join(t.TempDir(), "missing.pem")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := jwksFixture(f); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestProvider_RecordThenReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"roles": ["admin"]}`))
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "recorded.yaml")
+
+	// Recording sends unmatched requests to the real server
+	recording := NewProvider(&Config{Fixtures: []FixtureConfig{{Type: "record", Path: path}}})
+	client := &http.Client{Transport: recording.HTTPTransport()}
+	resp, err := client.Get(server.URL + "/roles/alice")
+	if err != nil {
+		t.Fatalf("recorded request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	// Replaying serves the recorded response, failing anything unrecorded
+	server.Close()
+	replaying := NewProvider(&Config{Fixtures: []FixtureConfig{{Type: "file", Path: path}}})
+	client = &http.Client{Transport: replaying.HTTPTransport()}
+	resp, err = client.Get(server.URL + "/roles/alice")
+	if err != nil {
+		t.Fatalf("replayed request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != `{"roles": ["admin"]}` {
+		t.Errorf("expected the recorded body, got %s", body)
+	}
+	if _, err := client.Get(server.URL + "/roles/bob"); err == nil {
+		t.Error("expected an unrecorded request to fail")
+	}
+}
+
+func TestBuildHTTPRecorder(t *testing.T) {
+	for name, fixtures := range map[string][]FixtureConfig{
+		"missing path":       {{Type: "record"}},
+		"multiple recorders": {{Type: "record", Path: "a.json"}, {Type: "record", Path: "b.json"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := BuildHTTPRecorder(fixtures); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestBuildHTTPFixtureProvider_Faults(t *testing.T) {
+	provider, err := BuildHTTPFixtureProvider([]FixtureConfig{
+		{
+			Type:    "http_rule",
+			Request: FixtureRequest{Method: "GET", URL: "https://api.example.com/roles"},
+			Responses: []FixtureResponse{
+				{Fault: "connection_reset"},
+				{StatusCode: 500, Delay: "10ms"},
+				{StatusCode: 200, Body: "ok"},
+			},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to build provider: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "https://api.example.com/roles", nil)
+	if f := provider.GetFixture(req); f.Fault != httpfixture.FaultConnectionReset {
+		t.Errorf("expected a connection reset first, got %+v", f)
+	}
+	if f := provider.GetFixture(req); f.StatusCode != 500 || f.Delay == nil || *f.Delay != 10*time.Millisecond {
+		t.Errorf("expected a delayed 500 second, got %+v", f)
+	}
+	if f := provider.GetFixture(req); f.StatusCode != 200 {
+		t.Errorf("expected a 200 last, got %+v", f)
+	}
+
+	for name, response := range map[string]FixtureResponse{
+		"unknown fault":      {Fault: "meltdown"},
+		"invalid delay":      {Delay: "a while"},
+		"invalid error rate": {ErrorRate: 1.5},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := BuildHTTPFixtureProvider([]FixtureConfig{{Type: "http_rule", Response: response}}, nil)
+			if err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/project-kessel/parsec/internal/server"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// NewHeaderSizeLimit creates the limit on tokens emitted by ext_authz
+// A reference token type must have one of issuers.
+func NewHeaderSizeLimit(cfg *HeaderSizeLimitConfig, issuers []IssuerConfig) (*server.HeaderSizeLimit, error) {
+	if cfg.MaxTokenSize <= 0 {
+		return nil, fmt.Errorf("header_size_limit requires a positive max_token_size")
+	}
+
+	limit := &server.HeaderSizeLimit{MaxTokenSize: cfg.MaxTokenSize}
+	switch cfg.OnExceeded {
+	case "deny", "":
+		if cfg.ReferenceTokenType != "" {
+			return nil, fmt.Errorf("header_size_limit reference_token_type requires on_exceeded: reference_token")
+		}
+	case "reference_token":
+		if cfg.ReferenceTokenType == "" {
+			return nil, fmt.Errorf("header_size_limit on_exceeded: reference_token requires reference_token_type")
+		}
+		hasIssuer := slices.ContainsFunc(issuers, func(issuerCfg IssuerConfig) bool {
+			return issuerCfg.TokenType == cfg.ReferenceTokenType
+		})
+		if !hasIssuer {
+			return nil, fmt.Errorf("header_size_limit reference_token_type %s has no issuer", cfg.ReferenceTokenType)
+		}
+		limit.ReferenceTokenType = service.TokenType(cfg.ReferenceTokenType)
+	default:
+		return nil, fmt.Errorf("unknown header_size_limit on_exceeded: %s (supported: deny, reference_token)", cfg.OnExceeded)
+	}
+	return limit, nil
+}
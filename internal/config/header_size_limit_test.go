@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestNewHeaderSizeLimit(t *testing.T) {
+	issuers := []IssuerConfig{{TokenType: "urn:parsec:token-type:reference", Type: "reference_token"}}
+
+	limit, err := NewHeaderSizeLimit(&HeaderSizeLimitConfig{MaxTokenSize: 8192}, issuers)
+	if err != nil {
+		t.Fatalf("NewHeaderSizeLimit failed: %v", err)
+	}
+	if limit.MaxTokenSize != 8192 || limit.ReferenceTokenType != "" {
+		t.Errorf("expected a denying limit, got %+v", limit)
+	}
+
+	limit, err = NewHeaderSizeLimit(&HeaderSizeLimitConfig{
+		MaxTokenSize:       8192,
+		OnExceeded:         "reference_token",
+		ReferenceTokenType: "urn:parsec:token-type:reference",
+	}, issuers)
+	if err != nil {
+		t.Fatalf("NewHeaderSizeLimit failed: %v", err)
+	}
+	if limit.ReferenceTokenType != "urn:parsec:token-type:reference" {
+		t.Errorf("expected the reference token type, got %+v", limit)
+	}
+
+	for name, cfg := range map[string]*HeaderSizeLimitConfig{
+		"no size":            {OnExceeded: "deny"},
+		"unknown mode":       {MaxTokenSize: 8192, OnExceeded: "truncate"},
+		"no reference type":  {MaxTokenSize: 8192, OnExceeded: "reference_token"},
+		"no issuer":          {MaxTokenSize: 8192, OnExceeded: "reference_token", ReferenceTokenType: "urn:example:opaque"},
+		"reference and deny": {MaxTokenSize: 8192, ReferenceTokenType: "urn:parsec:token-type:reference"},
+	} {
+		if _, err := NewHeaderSizeLimit(cfg, issuers); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// NewIdentityMapper creates the identity mapper of the token service
+// Returns nil if cfg is nil (subjects are not mapped).
+func NewIdentityMapper(cfg *IdentityMappingConfig, dataSources *service.DataSourceRegistry) (service.IdentityMapper, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	switch cfg.Type {
+	case "static":
+		if len(cfg.Principals) == 0 {
+			return nil, fmt.Errorf("static identity mapping requires principals")
+		}
+		var mappings []service.IdentityMapping
+		for i, p := range cfg.Principals {
+			if p.ID == "" {
+				return nil, fmt.Errorf("principals[%d] requires id", i)
+			}
+			if len(p.Subjects) == 0 {
+				return nil, fmt.Errorf("principal %s requires at least one subject", p.ID)
+			}
+			for _, s := range p.Subjects {
+				mappings = append(mappings, service.IdentityMapping{
+					Principal:   p.ID,
+					TrustDomain: s.TrustDomain,
+					Subject:     s.Subject,
+				})
+			}
+		}
+		return service.NewStaticIdentityMapper(mappings)
+	case "datasource":
+		if cfg.DataSource == "" {
+			return nil, fmt.Errorf("datasource identity mapping requires data_source")
+		}
+		return service.NewDataSourceIdentityMapper(dataSources, cfg.DataSource, cfg.Field)
+	default:
+		return nil, fmt.Errorf("unknown identity mapping type: %s (supported: static, datasource)", cfg.Type)
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestNewIdentityMapper_Static(t *testing.T) {
+	mapper, err := NewIdentityMapper(&IdentityMappingConfig{
+		Type: "static",
+		Principals: []PrincipalConfig{
+			{
+				ID: "p-alice",
+				Subjects: []ExternalSubjectConfig{
+					{TrustDomain: "corp.example.com", Subject: "alice"},
+					{TrustDomain: "github.com", Subject: "1234567"},
+				},
+			},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewIdentityMapper failed: %v", err)
+	}
+
+	principal, err := mapper.MapIdentity(context.Background(), &trust.Result{Subject: "1234567", TrustDomain: "github.com"})
+	if err != nil || principal != "p-alice" {
+		t.Errorf("expected p-alice, got %q (%v)", principal, err)
+	}
+
+	if mapper, err := NewIdentityMapper(nil, nil); mapper != nil || err != nil {
+		t.Errorf("expected no mapper without config, got %v (%v)", mapper, err)
+	}
+}
+
+func TestNewIdentityMapper_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *IdentityMappingConfig
+	}{
+		{"unknown type", &IdentityMappingConfig{Type: "ldap"}},
+		{"static without principals", &IdentityMappingConfig{Type: "static"}},
+		{"principal without subjects", &IdentityMappingConfig{Type: "static", Principals: []PrincipalConfig{{ID: "p-alice"}}}},
+		{"datasource without name", &IdentityMappingConfig{Type: "datasource"}},
+		{"unknown data source", &IdentityMappingConfig{Type: "datasource", DataSource: "directory"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewIdentityMapper(tt.cfg, nil); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
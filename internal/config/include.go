@@ -0,0 +1,346 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// includeKey is the top-level key listing files a config file builds on
+const includeKey = "include"
+
+// deleteKey marks a keyed list entry in a patch for removal from the base
+const deleteKey = "$delete"
+
+// listIdentityKeys are the fields that identify entries of configuration lists
+// (data sources and validators by name, issuers by token type, signers and key
+// providers by id), in order of preference
+var listIdentityKeys = []string{"name", "token_type", "id"}
+
+// loadConfigFiles reads a base config file and overlays and merges them into a
+// single configuration map
+//
+// Each file may list other files under the top-level "include" key, as a single
+// path or a list of paths and glob patterns relative to the including file.
+// Included files are merged in order, globs in lexical order, and the including
+// file is merged on top of them. Overlays are then merged on top of the base in
+// order, so the last file wins. See mergeConfig for how values are merged.
+//
+// Any of these may be a remote source URL (see remote.go); includes in a remote
+// file are resolved relative to its URL. Each file is migrated to the current
+// schema version before merging (see migrate.go).
+//
+// Returns the merged configuration and the sources that were read.
+func loadConfigFiles(configPath string, overlays []string) (map[string]any, configSources, error) {
+	l := &fileLoader{sources: configSources{remote: map[string]remoteVersion{}}}
+
+	merged := map[string]any{}
+	for _, path := range append([]string{configPath}, overlays...) {
+		if path == "" {
+			continue
+		}
+		data, err := l.load(path, nil)
+		if err != nil {
+			return nil, configSources{}, err
+		}
+		merged = mergeConfig(merged, data)
+	}
+
+	// Deletion markers are kept while merging so a patch made of several files
+	// still applies as a whole; drop any left without an entry to delete
+	return stripDeleted(merged), l.sources, nil
+}
+
+// configSources records where a configuration was read from
+type configSources struct {
+	// files are the local files that were read
+	files []string
+
+	// remote maps each remote source URL that was read to its fetched version
+	remote map[string]remoteVersion
+
+	// deprecations are the deprecated fields migrated while reading
+	deprecations []Deprecation
+}
+
+// fileLoader loads config files and resolves their includes
+type fileLoader struct {
+	sources configSources
+}
+
+// load reads a file and its includes; stack holds the files currently being
+// loaded and is used to detect include cycles
+func (l *fileLoader) load(path string, stack []string) (map[string]any, error) {
+	remote := isRemoteLocation(path)
+
+	id, formatPath := path, path
+	if remote {
+		formatPath = remoteFormatPath(path)
+	} else {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve config file %s: %w", path, err)
+		}
+		id = absPath
+	}
+	for _, loading := range stack {
+		if loading == id {
+			return nil, fmt.Errorf("config include cycle: %s", strings.Join(append(stack, id), " -> "))
+		}
+	}
+	stack = append(stack, id)
+
+	var content []byte
+	var err error
+	if remote {
+		var version remoteVersion
+		content, version, err = fetchRemote(path)
+		if err != nil {
+			return nil, err
+		}
+		l.sources.remote[path] = version
+		path = redactLocation(path)
+	} else {
+		content, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config file %s: %w", path, err)
+		}
+		l.sources.files = append(l.sources.files, path)
+	}
+
+	parser, err := getParserForFile(formatPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := parser.Unmarshal(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config file %s: %w", path, err)
+	}
+
+	deprecations, err := migrateConfig(data, path)
+	if err != nil {
+		return nil, err
+	}
+	l.sources.deprecations = append(l.sources.deprecations, deprecations...)
+
+	includes, err := includePaths(data[includeKey], id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include in %s: %w", path, err)
+	}
+	delete(data, includeKey)
+
+	merged := map[string]any{}
+	for _, include := range includes {
+		included, err := l.load(include, stack)
+		if err != nil {
+			return nil, err
+		}
+		merged = mergeConfig(merged, included)
+	}
+
+	return mergeConfig(merged, data), nil
+}
+
+// includePaths expands the include value of the file at parent into file paths
+// and remote source URLs
+func includePaths(value any, parent string) ([]string, error) {
+	var patterns []string
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		patterns = []string{v}
+	case []any:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("include entries must be strings, got %T", item)
+			}
+			patterns = append(patterns, s)
+		}
+	default:
+		return nil, fmt.Errorf("include must be a path or list of paths, got %T", value)
+	}
+
+	var paths []string
+	for _, pattern := range patterns {
+		if pattern == "" {
+			return nil, fmt.Errorf("include path is empty")
+		}
+		if isRemoteLocation(pattern) {
+			paths = append(paths, pattern)
+			continue
+		}
+		if isRemoteLocation(parent) {
+			// Remote files can only include by reference, relative to their URL
+			if strings.ContainsAny(pattern, "*?[") {
+				return nil, fmt.Errorf("glob includes are not supported in remote config: %s", pattern)
+			}
+			base, err := url.Parse(parent)
+			if err != nil {
+				return nil, err
+			}
+			ref, err := url.Parse(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid include %s: %w", pattern, err)
+			}
+			paths = append(paths, base.ResolveReference(ref).String())
+			continue
+		}
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(parent), pattern)
+		}
+
+		// Plain paths must exist; globs may match nothing
+		if !strings.ContainsAny(pattern, "*?[") {
+			paths = append(paths, pattern)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern %s: %w", pattern, err)
+		}
+		// filepath.Glob returns matches in lexical order
+		paths = append(paths, matches...)
+	}
+
+	return paths, nil
+}
+
+// mergeConfig merges patch on top of base and returns the result
+//
+//   - Maps are merged key by key, recursively
+//   - Lists whose entries all carry the same identity field (name, token_type
+//     or id) are merged entry by entry: entries with matching identities are
+//     merged, new entries are appended in patch order, and a patch entry with
+//     "$delete: true" removes the matching base entry (unmatched markers are
+//     kept for later merges; see stripDeleted)
+//   - All other values, including other lists, are replaced by the patch
+func mergeConfig(base, patch map[string]any) map[string]any {
+	out := make(map[string]any, len(base)+len(patch))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range patch {
+		out[k] = mergeValue(out[k], v)
+	}
+	return out
+}
+
+func mergeValue(base, patch any) any {
+	switch p := patch.(type) {
+	case map[string]any:
+		if b, ok := base.(map[string]any); ok {
+			return mergeConfig(b, p)
+		}
+		return patch
+	case []any:
+		if b, ok := base.([]any); ok {
+			if key := listIdentityKey(b, p); key != "" {
+				return mergeKeyedList(b, p, key)
+			}
+		}
+		return patch
+	default:
+		return patch
+	}
+}
+
+// listIdentityKey returns the identity field shared by every entry of both
+// lists, or "" if the lists cannot be merged by key
+func listIdentityKey(base, patch []any) string {
+	for _, key := range listIdentityKeys {
+		ok := true
+		for _, entry := range append(append([]any{}, base...), patch...) {
+			if entryIdentity(entry, key) == "" {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return key
+		}
+	}
+	return ""
+}
+
+func entryIdentity(entry any, key string) string {
+	m, ok := entry.(map[string]any)
+	if !ok {
+		return ""
+	}
+	id, _ := m[key].(string)
+	return id
+}
+
+func mergeKeyedList(base, patch []any, key string) []any {
+	out := append([]any{}, base...)
+	for _, entry := range patch {
+		id := entryIdentity(entry, key)
+		idx := -1
+		for i, existing := range out {
+			if entryIdentity(existing, key) == id {
+				idx = i
+				break
+			}
+		}
+
+		switch {
+		case isDeleted(entry) && idx >= 0:
+			out = append(out[:idx], out[idx+1:]...)
+		case idx >= 0:
+			merged := mergeConfig(out[idx].(map[string]any), entry.(map[string]any))
+			// A later entry revives one previously marked for deletion
+			delete(merged, deleteKey)
+			out[idx] = merged
+		default:
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+func isDeleted(entry any) bool {
+	m, ok := entry.(map[string]any)
+	if !ok {
+		return false
+	}
+	deleted, _ := m[deleteKey].(bool)
+	return deleted
+}
+
+// stripDeleted drops deletion markers, and the entries they mark, that were
+// never matched to an entry to delete
+func stripDeleted(m map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		if k == deleteKey {
+			continue
+		}
+		switch child := v.(type) {
+		case map[string]any:
+			out[k] = stripDeleted(child)
+		case []any:
+			out[k] = stripDeletedList(child)
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func stripDeletedList(list []any) []any {
+	out := make([]any, 0, len(list))
+	for _, entry := range list {
+		if isDeleted(entry) {
+			continue
+		}
+		if m, ok := entry.(map[string]any); ok {
+			entry = stripDeleted(m)
+		}
+		out = append(out, entry)
+	}
+	return out
+}
@@ -0,0 +1,210 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeConfigFiles writes files relative to a new temp dir and returns the dir
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestNewLoader_IncludesAndOverlays(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"parsec.yaml": `
+include:
+  - conf.d/*.yaml
+  - issuers.json
+trust_domain: base.example.com
+server:
+  grpc_port: 9000
+`,
+		"conf.d/10-datasources.yaml": `
+trust_domain: ignored.example.com
+data_sources:
+  - name: profile
+    type: http
+    http:
+      url: https://users.example.com/{{ .subject.subject }}
+      timeout: 5s
+  - name: roles
+    type: http
+    http:
+      url: https://roles.example.com
+`,
+		"conf.d/20-server.yaml": `
+server:
+  http_port: 8000
+`,
+		"issuers.json": `{
+  "issuers": [
+    {"token_type": "urn:ietf:params:oauth:token-type:txn_token", "type": "unsigned", "ttl": "5m"},
+    {"token_type": "urn:ietf:params:oauth:token-type:access_token", "type": "stub", "issuer_url": "https://base.example.com"}
+  ]
+}`,
+		"prod.yaml": `
+trust_domain: prod.example.com
+data_sources:
+  - name: profile
+    http:
+      timeout: 1s
+  - name: roles
+    $delete: true
+  - name: tenant
+    type: http
+    http:
+      url: https://tenants.example.com
+issuers:
+  - token_type: urn:ietf:params:oauth:token-type:txn_token
+    ttl: 1m
+`,
+	})
+
+	loader, err := NewLoader(filepath.Join(dir, "parsec.yaml"), filepath.Join(dir, "prod.yaml"))
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg, err := loader.Get()
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+
+	if cfg.TrustDomain != "prod.example.com" {
+		t.Errorf("expected overlay trust domain, got %s", cfg.TrustDomain)
+	}
+	if cfg.Server.GRPCPort != 9000 || cfg.Server.HTTPPort != 8000 {
+		t.Errorf("expected ports 9000/8000 from base and include, got %d/%d", cfg.Server.GRPCPort, cfg.Server.HTTPPort)
+	}
+
+	var names []string
+	for _, ds := range cfg.DataSources {
+		names = append(names, ds.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"profile", "tenant"}) {
+		t.Fatalf("expected data sources [profile tenant], got %v", names)
+	}
+	profile := cfg.DataSources[0]
+	if profile.Type != "http" || profile.HTTPConfig == nil ||
+		!strings.HasPrefix(profile.HTTPConfig.URL, "https://users.example.com/") || profile.HTTPConfig.Timeout != "1s" {
+		t.Errorf("expected overlay to patch only the profile timeout, got %+v", profile.HTTPConfig)
+	}
+
+	if len(cfg.Issuers) != 2 {
+		t.Fatalf("expected 2 issuers, got %d", len(cfg.Issuers))
+	}
+	if cfg.Issuers[0].Type != "unsigned" || cfg.Issuers[0].TTL != "1m" {
+		t.Errorf("expected txn_token issuer patched by token_type, got %+v", cfg.Issuers[0])
+	}
+	if cfg.Issuers[1].IssuerURL != "https://base.example.com" {
+		t.Errorf("expected access_token issuer unchanged, got %+v", cfg.Issuers[1])
+	}
+
+	if len(loader.files) != 5 {
+		t.Errorf("expected all 5 files to be tracked for watching, got %v", loader.files)
+	}
+}
+
+func TestLoadConfigFiles_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name:  "include cycle",
+			files: map[string]string{"parsec.yaml": "include: a.yaml\n", "a.yaml": "include: parsec.yaml\n"},
+			want:  "include cycle",
+		},
+		{
+			name:  "missing include",
+			files: map[string]string{"parsec.yaml": "include: missing.yaml\n"},
+			want:  "missing.yaml",
+		},
+		{
+			name:  "invalid include value",
+			files: map[string]string{"parsec.yaml": "include:\n  path: a.yaml\n"},
+			want:  "include must be a path or list of paths",
+		},
+		{
+			name:  "unsupported include format",
+			files: map[string]string{"parsec.yaml": "include: a.ini\n", "a.ini": "x=1\n"},
+			want:  "unsupported config file format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigFiles(t, tt.files)
+			_, _, err := loadConfigFiles(filepath.Join(dir, "parsec.yaml"), nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestMergeConfig(t *testing.T) {
+	base := map[string]any{
+		"server": map[string]any{"grpc_port": 9090, "http_port": 8080},
+		"fixtures": []any{
+			map[string]any{"type": "jwks", "issuer": "https://a.example.com"},
+		},
+		"validators": []any{
+			map[string]any{"name": "a", "type": "jwt_validator"},
+			map[string]any{"type": "stub_validator"},
+		},
+	}
+	patch := map[string]any{
+		"server": map[string]any{"http_port": 8081},
+		"fixtures": []any{
+			map[string]any{"type": "clock", "time": "2024-06-15T10:00:00Z"},
+		},
+		"validators": []any{
+			map[string]any{"name": "a", "issuer": "https://a.example.com"},
+		},
+		"data_sources": []any{
+			map[string]any{"name": "new"},
+			map[string]any{"name": "gone", "$delete": true},
+		},
+	}
+
+	got := stripDeleted(mergeConfig(base, patch))
+	want := map[string]any{
+		"server": map[string]any{"grpc_port": 9090, "http_port": 8081},
+		// Lists without identity fields are replaced
+		"fixtures": []any{
+			map[string]any{"type": "clock", "time": "2024-06-15T10:00:00Z"},
+		},
+		// Lists are only merged by key if every entry has one
+		"validators": []any{
+			map[string]any{"name": "a", "issuer": "https://a.example.com"},
+		},
+		// Deletion markers without a base entry are dropped
+		"data_sources": []any{
+			map[string]any{"name": "new"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected merge result:\n got: %v\nwant: %v", got, want)
+	}
+
+	// The inputs are not modified
+	if base["server"].(map[string]any)["http_port"] != 8080 {
+		t.Error("expected base to be unchanged")
+	}
+}
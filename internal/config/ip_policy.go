@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/project-kessel/parsec/internal/server"
+)
+
+// NewIPPolicy creates the ext_authz client address policy, or nil if it isn't
+// configured
+func NewIPPolicy(cfg *IPPolicyConfig) (*server.IPPolicy, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if len(cfg.Allow) == 0 && len(cfg.Deny) == 0 {
+		return nil, fmt.Errorf("ip_policy requires allow or deny CIDRs")
+	}
+
+	allow, err := parsePrefixes("allow", cfg.Allow)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := parsePrefixes("deny", cfg.Deny)
+	if err != nil {
+		return nil, err
+	}
+	trustedProxies, err := NewTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	return &server.IPPolicy{Allow: allow, Deny: deny, TrustedProxies: trustedProxies}, nil
+}
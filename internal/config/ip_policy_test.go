@@ -0,0 +1,30 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestNewIPPolicy(t *testing.T) {
+	policy, err := NewIPPolicy(&IPPolicyConfig{
+		Allow:          []string{"10.0.0.0/8", "192.0.2.7"},
+		Deny:           []string{"10.1.0.0/16"},
+		TrustedProxies: []string{"172.16.0.0/12"},
+	})
+	if err != nil {
+		t.Fatalf("NewIPPolicy failed: %v", err)
+	}
+	if len(policy.Allow) != 2 || policy.Allow[1].Bits() != 32 || len(policy.Deny) != 1 || len(policy.TrustedProxies) != 1 {
+		t.Errorf("unexpected policy %+v", policy)
+	}
+
+	for name, cfg := range map[string]*IPPolicyConfig{
+		"no networks":           {TrustedProxies: []string{"172.16.0.0/12"}},
+		"invalid allow":         {Allow: []string{"10.0.0.0/33"}},
+		"invalid deny":          {Deny: []string{"internal"}},
+		"invalid trusted proxy": {Allow: []string{"10.0.0.0/8"}, TrustedProxies: []string{"proxy"}},
+	} {
+		if _, err := NewIPPolicy(cfg); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
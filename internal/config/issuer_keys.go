@@ -0,0 +1,71 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// issuerKeySource provides the public keys of parsec's own issuers to
+// validators of locally issued tokens
+// The registry is resolved on first use, so the trust store can be built
+// before the issuers.
+type issuerKeySource struct {
+	registry func() (service.Registry, error)
+}
+
+// newIssuerKeySource returns a key source over the issuers of a lazily built registry
+func newIssuerKeySource(registry func() (service.Registry, error)) trust.KeySource {
+	return &issuerKeySource{registry: registry}
+}
+
+// KeySet implements trust.KeySource
+func (s *issuerKeySource) KeySet(ctx context.Context) (jwk.Set, error) {
+	registry, err := s.registry()
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys from issuers that did fail are still usable, as for the JWKS endpoint
+	publicKeys, err := registry.GetAllPublicKeys(ctx)
+	if len(publicKeys) == 0 && err != nil {
+		return nil, fmt.Errorf("failed to get public keys: %w", err)
+	}
+
+	set := jwk.NewSet()
+	for _, pk := range publicKeys {
+		key, err := publicKeyToJWK(pk)
+		if err != nil {
+			// Skip keys that can't be converted
+			continue
+		}
+		if err := set.AddKey(key); err != nil {
+			return nil, fmt.Errorf("failed to add key %s: %w", pk.KeyID, err)
+		}
+	}
+	return set, nil
+}
+
+// publicKeyToJWK converts an issuer public key to a JWK with its key ID and algorithm
+func publicKeyToJWK(pk service.PublicKey) (jwk.Key, error) {
+	key, err := jwk.Import(pk.Key)
+	if err != nil {
+		return nil, err
+	}
+	alg, ok := jwa.LookupSignatureAlgorithm(pk.Algorithm)
+	if !ok {
+		return nil, fmt.Errorf("unsupported signature algorithm: %s", pk.Algorithm)
+	}
+	if err := key.Set(jwk.KeyIDKey, pk.KeyID); err != nil {
+		return nil, err
+	}
+	if err := key.Set(jwk.AlgorithmKey, alg); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
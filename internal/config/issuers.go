@@ -2,22 +2,50 @@ package config
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"maps"
+	"net/http"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/clock"
 	"github.com/project-kessel/parsec/internal/issuer"
 	"github.com/project-kessel/parsec/internal/keys"
 	"github.com/project-kessel/parsec/internal/mapper"
 	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trustdomain"
 )
 
 // NewIssuerRegistry creates an issuer registry from configuration
-func NewIssuerRegistry(cfg Config) (service.Registry, error) {
-	registry := service.NewSimpleRegistry()
+// The transport is used for fetching recipient JWKS for token encryption (nil uses the default)
+// The clock is the time source for token timestamps (nil uses the system clock)
+func NewIssuerRegistry(cfg Config, transport http.RoundTripper, clk clock.Clock, observer service.BulkheadObserver) (service.Registry, error) {
+	signerRegistry, err := NewSignerRegistry(cfg, clk)
+	if err != nil {
+		return nil, err
+	}
 
+	// Start all signers
+	if err := signerRegistry.Start(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to start signers: %w", err)
+	}
+
+	// Plugins are started by their first call
+	plugins, err := NewPlugins(cfg.Plugins)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plugins: %w", err)
+	}
+
+	return newIssuerRegistry(cfg, signerRegistry, plugins, transport, clk, observer)
+}
+
+// NewSignerRegistry creates the configured signers without starting them
+// The clock is the time source for key rotation (nil uses the system clock)
+func NewSignerRegistry(cfg Config, clk clock.Clock) (*keys.SignerRegistry, error) {
 	// Build key provider registry from global config
 	providerRegistry, err := buildKeyProviderRegistry(cfg.KeyProviders)
 	if err != nil {
@@ -25,41 +53,134 @@ func NewIssuerRegistry(cfg Config) (service.Registry, error) {
 	}
 
 	// Create shared key slot store
-	slotStore := keys.NewInMemoryKeySlotStore()
+	slotStore, err := NewKeySlotStore(cfg.KeySlotStore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key slot store: %w", err)
+	}
 
 	// Build signer registry from global config
-	signerRegistry, err := buildSignerRegistry(cfg.Signers, cfg.TrustDomain, providerRegistry, slotStore)
+	signerRegistry, err := buildSignerRegistry(cfg.Signers, cfg.TrustDomain, providerRegistry, slotStore, clk)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build signer registry: %w", err)
 	}
+	return signerRegistry, nil
+}
 
-	// Start all signers
-	ctx := context.Background()
-	if err := signerRegistry.Start(ctx); err != nil {
-		return nil, fmt.Errorf("failed to start signers: %w", err)
+// NewKeySlotStore creates the key slot store shared by rotating signers
+// A nil cfg keeps slots in memory.
+func NewKeySlotStore(cfg *KeySlotStoreConfig) (keys.KeySlotStore, error) {
+	if err := checkKeySlotStore(cfg); err != nil {
+		return nil, err
+	}
+	if cfg == nil || cfg.Type != "file" {
+		return keys.NewInMemoryKeySlotStore(), nil
+	}
+	return keys.NewFileKeySlotStore(cfg.Path, nil)
+}
+
+// checkKeySlotStore checks a key slot store configuration without creating
+// the store
+func checkKeySlotStore(cfg *KeySlotStoreConfig) error {
+	if cfg == nil {
+		return nil
 	}
+	switch cfg.Type {
+	case "", "memory":
+		if cfg.Path != "" {
+			return fmt.Errorf("path is only supported by the file key slot store")
+		}
+		return nil
+	case "file":
+		if cfg.Path == "" {
+			return fmt.Errorf("file key slot store requires a path")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown key slot store type: %s (supported: memory, file)", cfg.Type)
+	}
+}
+
+// newIssuerRegistry creates the configured issuers, signing with the started
+// signers and issuing through plugins
+func newIssuerRegistry(cfg Config, signerRegistry *keys.SignerRegistry, plugins *Plugins, transport http.RoundTripper, clk clock.Clock, observer service.BulkheadObserver) (service.Registry, error) {
+	registry := service.NewSimpleRegistry()
 
 	for _, issuerCfg := range cfg.Issuers {
 		if issuerCfg.TokenType == "" {
 			return nil, fmt.Errorf("token_type is required for issuer")
 		}
 
-		// Use token type directly as service.TokenType (it's already a URN string)
-		tokenType := service.TokenType(issuerCfg.TokenType)
+		// Configure JWE encryption, if any
+		var encryption *issuer.EncryptionConfig
+		if issuerCfg.Encryption != nil {
+			if issuerCfg.Type == "reference_token" {
+				return nil, fmt.Errorf("encryption is not supported for reference_token issuer %s", issuerCfg.TokenType)
+			}
+			var err error
+			encryption, err = newEncryptionConfig(issuerCfg.Encryption, transport)
+			if err != nil {
+				return nil, fmt.Errorf("failed to configure encryption for token type %s: %w", issuerCfg.TokenType, err)
+			}
+		}
 
 		// Create issuer (now using signer registry instead of building signers inline)
-		iss, err := newIssuer(issuerCfg, signerRegistry)
+		iss, err := newIssuer(issuerCfg, signerRegistry, plugins, encryption, clk)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create issuer for token type %s: %w", issuerCfg.TokenType, err)
 		}
 
+		// Wrap with JWE encryption unless the issuer encrypts its own tokens
+		if encryption != nil && !encryptsOwnTokens(issuerCfg.Type) {
+			iss, err = issuer.NewEncryptingIssuer(iss, *encryption)
+			if err != nil {
+				return nil, fmt.Errorf("failed to configure encryption for token type %s: %w", issuerCfg.TokenType, err)
+			}
+		}
+
+		// Bound concurrent issuance if configured
+		bulkhead, err := newBulkhead("issuer:"+issuerCfg.TokenType, issuerCfg.Bulkhead, observer, clk)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure bulkhead for token type %s: %w", issuerCfg.TokenType, err)
+		}
+		if bulkhead != nil {
+			iss = issuer.NewBulkheadIssuer(iss, bulkhead)
+		}
+
+		// Record where claims came from, for decision log events
+		if issuerCfg.Provenance {
+			iss = issuer.NewProvenanceIssuer(iss)
+		}
+
 		// Register issuer
-		registry.Register(tokenType, iss)
+		registry.RegisterWithInfo(issuerInfo(issuerCfg), iss)
 	}
 
 	return registry, nil
 }
 
+// issuerInfo describes an issuer created from valid configuration
+func issuerInfo(cfg IssuerConfig) service.IssuerInfo {
+	info := service.IssuerInfo{
+		TokenType:  service.TokenType(cfg.TokenType),
+		Type:       cfg.Type,
+		TTLPolicy:  cfg.TTLPolicy != nil,
+		SignerID:   cfg.SignerID,
+		Plugin:     cfg.Plugin,
+		Encrypted:  cfg.Encryption != nil,
+		Provenance: cfg.Provenance,
+	}
+	// These types issue tokens for their ttl; the others leave it to the
+	// token or plugin
+	switch cfg.Type {
+	case "stub", "transaction_token", "jwt_access_token", "jwt_svid", "reference_token":
+		info.TTL = 5 * time.Minute
+		if ttl, err := time.ParseDuration(cfg.TTL); err == nil {
+			info.TTL = ttl
+		}
+	}
+	return info
+}
+
 // buildKeyProviderRegistry creates a map of KeyProvider instances from configuration
 func buildKeyProviderRegistry(configs []KeyProviderConfig) (map[string]keys.KeyProvider, error) {
 	registry := make(map[string]keys.KeyProvider)
@@ -90,10 +211,19 @@ func buildKeyProviderRegistry(configs []KeyProviderConfig) (map[string]keys.KeyP
 			if cfg.KeysPath == "" {
 				return nil, fmt.Errorf("disk key provider %s requires keys_path", cfg.ID)
 			}
+			var encryptionKey []byte
+			if cfg.EncryptionKey != "" {
+				if encryptionKey, err = base64.StdEncoding.DecodeString(cfg.EncryptionKey); err != nil {
+					return nil, fmt.Errorf("disk key provider %s: invalid encryption_key: %w", cfg.ID, err)
+				}
+			}
 			provider, err = keys.NewDiskKeyProvider(keys.DiskKeyProviderConfig{
-				KeyType:   keyType,
-				Algorithm: cfg.Algorithm,
-				KeysPath:  cfg.KeysPath,
+				KeyType:         keyType,
+				Algorithm:       cfg.Algorithm,
+				KeysPath:        cfg.KeysPath,
+				Seed:            cfg.Seed,
+				EncryptionKey:   encryptionKey,
+				MigrateKeyFiles: cfg.MigrateKeyFiles,
 			})
 			if err != nil {
 				return nil, fmt.Errorf("failed to create disk key provider %s: %w", cfg.ID, err)
@@ -127,7 +257,7 @@ func buildKeyProviderRegistry(configs []KeyProviderConfig) (map[string]keys.KeyP
 }
 
 // buildSignerRegistry creates a SignerRegistry from configuration
-func buildSignerRegistry(configs []SignerConfig, trustDomain string, providerRegistry map[string]keys.KeyProvider, slotStore keys.KeySlotStore) (*keys.SignerRegistry, error) {
+func buildSignerRegistry(configs []SignerConfig, trustDomain string, providerRegistry map[string]keys.KeyProvider, slotStore keys.KeySlotStore, clk clock.Clock) (*keys.SignerRegistry, error) {
 	registry := keys.NewSignerRegistry()
 
 	for _, cfg := range configs {
@@ -206,6 +336,7 @@ func buildSignerRegistry(configs []SignerConfig, trustDomain string, providerReg
 				KeyProviderID:       cfg.KeyProviderID,
 				KeyProviderRegistry: providerRegistry,
 				SlotStore:           slotStore,
+				Clock:               clk,
 				KeyTTL:              keyTTL,
 				RotationThreshold:   rotationThreshold,
 				GracePeriod:         gracePeriod,
@@ -224,24 +355,79 @@ func buildSignerRegistry(configs []SignerConfig, trustDomain string, providerReg
 	return registry, nil
 }
 
+// sizeBudgetIssuerTypes are the issuer types that support a size budget
+var sizeBudgetIssuerTypes = []string{"transaction_token", "jwt_access_token"}
+
+// audienceSignerIssuerTypes are the issuer types that can sign per audience
+var audienceSignerIssuerTypes = []string{"transaction_token", "jwt_access_token", "jwt_svid"}
+
+// newAudienceSigners looks up the signers of an issuer's audience_signers
+func newAudienceSigners(cfg IssuerConfig, signerRegistry *keys.SignerRegistry) (issuer.AudienceSigners, error) {
+	if len(cfg.AudienceSigners) == 0 {
+		return nil, nil
+	}
+	signers := make(issuer.AudienceSigners)
+	for i, audienceCfg := range cfg.AudienceSigners {
+		if len(audienceCfg.Audiences) == 0 {
+			return nil, fmt.Errorf("audience_signers[%d] requires audiences", i)
+		}
+		signer, err := signerRegistry.Get(audienceCfg.SignerID)
+		if err != nil {
+			return nil, fmt.Errorf("audience_signers[%d]: signer not found: %s", i, audienceCfg.SignerID)
+		}
+		for _, audience := range audienceCfg.Audiences {
+			if err := trustdomain.ValidatePattern(audience); err != nil {
+				return nil, fmt.Errorf("audience_signers[%d]: %w", i, err)
+			}
+			if _, exists := signers[audience]; exists {
+				return nil, fmt.Errorf("audience_signers[%d]: duplicate audience: %s", i, audience)
+			}
+			signers[audience] = signer
+		}
+	}
+	return signers, nil
+}
+
+// encryptsOwnTokens reports whether an issuer type encrypts its tokens itself,
+// so that its size budget measures the encrypted token
+func encryptsOwnTokens(issuerType string) bool {
+	return slices.Contains(sizeBudgetIssuerTypes, issuerType)
+}
+
 // newIssuer creates an issuer from configuration
-func newIssuer(cfg IssuerConfig, signerRegistry *keys.SignerRegistry) (service.Issuer, error) {
+// Issuer types that encrypt their own tokens use encryption (nil if not encrypted)
+func newIssuer(cfg IssuerConfig, signerRegistry *keys.SignerRegistry, plugins *Plugins, encryption *issuer.EncryptionConfig, clk clock.Clock) (service.Issuer, error) {
+	if cfg.SizeBudget != nil && !slices.Contains(sizeBudgetIssuerTypes, cfg.Type) {
+		return nil, fmt.Errorf("size_budget is not supported for %s issuers (supported: %s)", cfg.Type, strings.Join(sizeBudgetIssuerTypes, ", "))
+	}
+	if len(cfg.AudienceSigners) > 0 && !slices.Contains(audienceSignerIssuerTypes, cfg.Type) {
+		return nil, fmt.Errorf("audience_signers is not supported for %s issuers (supported: %s)", cfg.Type, strings.Join(audienceSignerIssuerTypes, ", "))
+	}
+
 	switch cfg.Type {
 	case "stub":
-		return newStubIssuer(cfg)
+		return newStubIssuer(cfg, clk)
 	case "unsigned":
-		return newUnsignedIssuer(cfg)
+		return newUnsignedIssuer(cfg, clk)
 	case "transaction_token":
-		return newTransactionTokenIssuer(cfg, signerRegistry)
+		return newTransactionTokenIssuer(cfg, signerRegistry, encryption, clk)
+	case "jwt_access_token":
+		return newAccessTokenIssuer(cfg, signerRegistry, encryption, clk)
+	case "jwt_svid":
+		return newJWTSVIDIssuer(cfg, signerRegistry, clk)
+	case "reference_token":
+		return newReferenceTokenIssuer(cfg, clk)
 	case "rh_identity":
-		return newRHIdentityIssuer(cfg)
+		return newRHIdentityIssuer(cfg, clk)
+	case "plugin":
+		return newPluginIssuer(cfg, plugins, clk)
 	default:
-		return nil, fmt.Errorf("unknown issuer type: %s (supported: stub, unsigned, transaction_token, rh_identity)", cfg.Type)
+		return nil, fmt.Errorf("unknown issuer type: %s (supported: stub, unsigned, transaction_token, jwt_access_token, jwt_svid, reference_token, rh_identity, plugin)", cfg.Type)
 	}
 }
 
 // newStubIssuer creates a stub issuer for testing
-func newStubIssuer(cfg IssuerConfig) (service.Issuer, error) {
+func newStubIssuer(cfg IssuerConfig, clk clock.Clock) (service.Issuer, error) {
 	if cfg.IssuerURL == "" {
 		return nil, fmt.Errorf("stub issuer requires issuer_url")
 	}
@@ -263,7 +449,7 @@ func newStubIssuer(cfg IssuerConfig) (service.Issuer, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to create transaction context mapper %d: %w", i, err)
 		}
-		txnMappers = append(txnMappers, m)
+		txnMappers = append(txnMappers, wrapClaimMapper(m, cfg, mapperCfg, "transaction_context", i, "tctx"))
 	}
 
 	// Create request context mappers
@@ -273,7 +459,7 @@ func newStubIssuer(cfg IssuerConfig) (service.Issuer, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to create request context mapper %d: %w", i, err)
 		}
-		reqMappers = append(reqMappers, m)
+		reqMappers = append(reqMappers, wrapClaimMapper(m, cfg, mapperCfg, "request_context", i, "req_ctx"))
 	}
 
 	return issuer.NewStubIssuer(issuer.StubIssuerConfig{
@@ -281,12 +467,13 @@ func newStubIssuer(cfg IssuerConfig) (service.Issuer, error) {
 		TTL:                       ttl,
 		TransactionContextMappers: txnMappers,
 		RequestContextMappers:     reqMappers,
+		Clock:                     clk,
 	}), nil
 }
 
 // newTransactionTokenIssuer creates a transaction token issuer.
 // This issuer signs transaction tokens using a signer from the global signer registry.
-func newTransactionTokenIssuer(cfg IssuerConfig, signerRegistry *keys.SignerRegistry) (service.Issuer, error) {
+func newTransactionTokenIssuer(cfg IssuerConfig, signerRegistry *keys.SignerRegistry, encryption *issuer.EncryptionConfig, clk clock.Clock) (service.Issuer, error) {
 	if cfg.IssuerURL == "" {
 		return nil, fmt.Errorf("transaction_token issuer requires issuer_url")
 	}
@@ -296,11 +483,29 @@ func newTransactionTokenIssuer(cfg IssuerConfig, signerRegistry *keys.SignerRegi
 		return nil, fmt.Errorf("transaction_token issuer requires signer_id")
 	}
 
+	var replacementPolicy *issuer.ReplacementPolicy
+	if cfg.Replacement != nil {
+		for _, claim := range cfg.Replacement.MutableClaims {
+			if slices.Contains([]string{"iss", "sub", "txn"}, claim) {
+				return nil, fmt.Errorf("invalid replacement: %s is preserved by every replacement and cannot be mutable", claim)
+			}
+		}
+		replacementPolicy = &issuer.ReplacementPolicy{MutableClaims: cfg.Replacement.MutableClaims}
+	}
+	transactionContextContract, err := newClaimsContract(cfg.TransactionContextClaims)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction_context_claims: %w", err)
+	}
+
 	// Get signer from registry
 	signer, err := signerRegistry.Get(cfg.SignerID)
 	if err != nil {
 		return nil, fmt.Errorf("signer not found: %s", cfg.SignerID)
 	}
+	audienceSigners, err := newAudienceSigners(cfg, signerRegistry)
+	if err != nil {
+		return nil, err
+	}
 
 	// Parse TTL
 	ttl := 5 * time.Minute // default
@@ -312,6 +517,15 @@ func newTransactionTokenIssuer(cfg IssuerConfig, signerRegistry *keys.SignerRegi
 		ttl = duration
 	}
 
+	leeway, err := parseLeeway(cfg.Leeway)
+	if err != nil {
+		return nil, err
+	}
+	notBeforeSkew, err := parseNotBeforeSkew(cfg.NotBeforeSkew)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create transaction context mappers
 	var txnMappers []service.ClaimMapper
 	for i, mapperCfg := range cfg.TransactionContextMappers {
@@ -319,7 +533,7 @@ func newTransactionTokenIssuer(cfg IssuerConfig, signerRegistry *keys.SignerRegi
 		if err != nil {
 			return nil, fmt.Errorf("failed to create transaction context mapper %d: %w", i, err)
 		}
-		txnMappers = append(txnMappers, m)
+		txnMappers = append(txnMappers, wrapClaimMapper(m, cfg, mapperCfg, "transaction_context", i, "tctx"))
 	}
 
 	// Create request context mappers
@@ -329,20 +543,357 @@ func newTransactionTokenIssuer(cfg IssuerConfig, signerRegistry *keys.SignerRegi
 		if err != nil {
 			return nil, fmt.Errorf("failed to create request context mapper %d: %w", i, err)
 		}
-		reqMappers = append(reqMappers, m)
+		reqMappers = append(reqMappers, wrapClaimMapper(m, cfg, mapperCfg, "request_context", i, "req_ctx"))
+	}
+
+	// Create authorization details mappers
+	var azdMappers []service.ClaimMapper
+	for i, mapperCfg := range cfg.AuthorizationDetailsMappers {
+		m, err := newClaimMapper(mapperCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create authorization details mapper %d: %w", i, err)
+		}
+		azdMappers = append(azdMappers, wrapClaimMapper(m, cfg, mapperCfg, "authorization_details", i, "azd"))
+	}
+
+	txnIDGenerator, err := newTxnIDGenerator(cfg, clk)
+	if err != nil {
+		return nil, err
+	}
+
+	sizeBudget, err := newSizeBudget(cfg.SizeBudget)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypter, err := newTokenEncrypter(encryption)
+	if err != nil {
+		return nil, err
+	}
+
+	var ttlPolicy issuer.TTLPolicy
+	if cfg.TTLPolicy != nil {
+		ttlPolicy, err = newTTLPolicy(cfg.TTLPolicy, ttl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ttl_policy: %w", err)
+		}
 	}
 
 	return issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
-		IssuerURL:                 cfg.IssuerURL,
-		TTL:                       ttl,
-		Signer:                    signer,
-		TransactionContextMappers: txnMappers,
-		RequestContextMappers:     reqMappers,
+		IssuerURL:                   cfg.IssuerURL,
+		TTL:                         ttl,
+		TTLPolicy:                   ttlPolicy,
+		Signer:                      signer,
+		AudienceSigners:             audienceSigners,
+		TransactionContextMappers:   txnMappers,
+		TransactionContextContract:  transactionContextContract,
+		RequestContextMappers:       reqMappers,
+		AuthorizationDetailsMappers: azdMappers,
+		TxnIDGenerator:              txnIDGenerator,
+		Purpose:                     cfg.Purpose,
+		PurposeFromScope:            cfg.PurposeFromScope,
+		SizeBudget:                  sizeBudget,
+		Encrypter:                   encrypter,
+		Clock:                       clk,
+		Leeway:                      leeway,
+		NotBeforeSkew:               notBeforeSkew,
+		ReplacementPolicy:           replacementPolicy,
+	}), nil
+}
+
+// newTxnIDGenerator creates the generator for the "txn" claim of a transaction token issuer
+func newTxnIDGenerator(cfg IssuerConfig, clk clock.Clock) (issuer.TxnIDGenerator, error) {
+	format := issuer.TransactionIDFormat(cfg.TransactionIDFormat)
+	switch format {
+	case "", issuer.TransactionIDFormatUUIDv7, issuer.TransactionIDFormatUUIDv4, issuer.TransactionIDFormatULID:
+		return issuer.NewTxnIDGenerator(format, clk)
+	case issuer.TransactionIDFormatSnowflake:
+		if cfg.TransactionIDNodeID == nil {
+			return nil, fmt.Errorf("transaction_id_node_id is required for the snowflake transaction_id_format")
+		}
+		gen, err := issuer.NewSnowflakeTxnIDGenerator(int64(*cfg.TransactionIDNodeID), clk)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transaction_id_node_id: %w", err)
+		}
+		return gen, nil
+	case issuer.TransactionIDFormatTraceID:
+		switch issuer.TransactionIDFormat(cfg.TransactionIDFallback) {
+		case issuer.TransactionIDFormatTraceID, issuer.TransactionIDFormatSnowflake:
+			return nil, fmt.Errorf("unsupported transaction_id_fallback: %s (supported: uuidv7, uuidv4, ulid)", cfg.TransactionIDFallback)
+		}
+		fallback, err := issuer.NewTxnIDGenerator(issuer.TransactionIDFormat(cfg.TransactionIDFallback), clk)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported transaction_id_fallback: %s (supported: uuidv7, uuidv4, ulid)", cfg.TransactionIDFallback)
+		}
+		return &issuer.TraceTxnIDGenerator{Fallback: fallback}, nil
+	default:
+		return nil, fmt.Errorf("unknown transaction_id_format: %s (supported: uuidv7, uuidv4, ulid, snowflake, trace_id)", cfg.TransactionIDFormat)
+	}
+}
+
+// newSizeBudget creates a token size budget from configuration
+// Returns nil if no budget is configured
+func newSizeBudget(cfg *SizeBudgetConfig) (*issuer.SizeBudget, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	budget := &issuer.SizeBudget{
+		MaxBytes: cfg.MaxBytes,
+	}
+	for _, step := range cfg.Compaction {
+		budget.Steps = append(budget.Steps, issuer.CompactionStep{
+			Claim:      step.Claim,
+			Action:     issuer.CompactionAction(step.Action),
+			DataSource: step.DataSource,
+		})
+	}
+
+	if err := budget.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid size_budget: %w", err)
+	}
+	return budget, nil
+}
+
+// newTTLPolicy creates a CEL TTL policy bounded by min and max
+// The issuer's static TTL is the default upper bound
+func newTTLPolicy(cfg *TTLPolicyConfig, defaultMax time.Duration) (issuer.TTLPolicy, error) {
+	var minTTL time.Duration
+	if cfg.Min != "" {
+		duration, err := time.ParseDuration(cfg.Min)
+		if err != nil {
+			return nil, fmt.Errorf("invalid min: %w", err)
+		}
+		minTTL = duration
+	}
+
+	maxTTL := defaultMax
+	if cfg.Max != "" {
+		duration, err := time.ParseDuration(cfg.Max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max: %w", err)
+		}
+		maxTTL = duration
+	}
+
+	return issuer.NewCelTTLPolicy(cfg.Script, minTTL, maxTTL)
+}
+
+// newAccessTokenIssuer creates an RFC 9068 JWT access token issuer.
+// This issuer signs access tokens using a signer from the global signer registry.
+func newAccessTokenIssuer(cfg IssuerConfig, signerRegistry *keys.SignerRegistry, encryption *issuer.EncryptionConfig, clk clock.Clock) (service.Issuer, error) {
+	if cfg.IssuerURL == "" {
+		return nil, fmt.Errorf("jwt_access_token issuer requires issuer_url")
+	}
+
+	if cfg.SignerID == "" {
+		return nil, fmt.Errorf("jwt_access_token issuer requires signer_id")
+	}
+
+	if cfg.ClientID == "" {
+		return nil, fmt.Errorf("jwt_access_token issuer requires client_id")
+	}
+
+	signer, err := signerRegistry.Get(cfg.SignerID)
+	if err != nil {
+		return nil, fmt.Errorf("signer not found: %s", cfg.SignerID)
+	}
+	audienceSigners, err := newAudienceSigners(cfg, signerRegistry)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse TTL
+	ttl := 5 * time.Minute // default
+	if cfg.TTL != "" {
+		duration, err := time.ParseDuration(cfg.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ttl: %w", err)
+		}
+		ttl = duration
+	}
+
+	leeway, err := parseLeeway(cfg.Leeway)
+	if err != nil {
+		return nil, err
+	}
+	notBeforeSkew, err := parseNotBeforeSkew(cfg.NotBeforeSkew)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create claim mappers
+	var mappers []service.ClaimMapper
+	for i, mapperCfg := range cfg.ClaimMappers {
+		m, err := newClaimMapper(mapperCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create claim mapper %d: %w", i, err)
+		}
+		mappers = append(mappers, wrapClaimMapper(m, cfg, mapperCfg, "claim_mappers", i, ""))
+	}
+
+	sizeBudget, err := newSizeBudget(cfg.SizeBudget)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypter, err := newTokenEncrypter(encryption)
+	if err != nil {
+		return nil, err
+	}
+
+	return issuer.NewAccessTokenIssuer(issuer.AccessTokenIssuerConfig{
+		IssuerURL:       cfg.IssuerURL,
+		TTL:             ttl,
+		Signer:          signer,
+		AudienceSigners: audienceSigners,
+		Audience:        cfg.Audience,
+		ClientID:        cfg.ClientID,
+		ClaimMappers:    mappers,
+		SizeBudget:      sizeBudget,
+		Encrypter:       encrypter,
+		Clock:           clk,
+		Leeway:          leeway,
+		NotBeforeSkew:   notBeforeSkew,
+	}), nil
+}
+
+// newJWTSVIDIssuer creates a SPIFFE JWT-SVID issuer.
+// This issuer signs SVIDs using a signer from the global signer registry.
+func newJWTSVIDIssuer(cfg IssuerConfig, signerRegistry *keys.SignerRegistry, clk clock.Clock) (service.Issuer, error) {
+	if cfg.SPIFFETrustDomain == "" {
+		return nil, fmt.Errorf("jwt_svid issuer requires spiffe_trust_domain")
+	}
+
+	if cfg.SignerID == "" {
+		return nil, fmt.Errorf("jwt_svid issuer requires signer_id")
+	}
+
+	signer, err := signerRegistry.Get(cfg.SignerID)
+	if err != nil {
+		return nil, fmt.Errorf("signer not found: %s", cfg.SignerID)
+	}
+	audienceSigners, err := newAudienceSigners(cfg, signerRegistry)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse TTL
+	ttl := 5 * time.Minute // default
+	if cfg.TTL != "" {
+		duration, err := time.ParseDuration(cfg.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ttl: %w", err)
+		}
+		ttl = duration
+	}
+
+	leeway, err := parseLeeway(cfg.Leeway)
+	if err != nil {
+		return nil, err
+	}
+	notBeforeSkew, err := parseNotBeforeSkew(cfg.NotBeforeSkew)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create claim mappers (must produce the "sub" SPIFFE ID)
+	if len(cfg.ClaimMappers) == 0 {
+		return nil, fmt.Errorf("jwt_svid issuer requires claim_mappers producing a sub claim")
+	}
+	var mappers []service.ClaimMapper
+	for i, mapperCfg := range cfg.ClaimMappers {
+		m, err := newClaimMapper(mapperCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create claim mapper %d: %w", i, err)
+		}
+		mappers = append(mappers, wrapClaimMapper(m, cfg, mapperCfg, "claim_mappers", i, ""))
+	}
+
+	var audience []string
+	if cfg.Audience != "" {
+		audience = []string{cfg.Audience}
+	}
+
+	return issuer.NewJWTSVIDIssuer(issuer.JWTSVIDIssuerConfig{
+		TokenType:       cfg.TokenType,
+		TrustDomain:     cfg.SPIFFETrustDomain,
+		TTL:             ttl,
+		Signer:          signer,
+		AudienceSigners: audienceSigners,
+		Audience:        audience,
+		ClaimMappers:    mappers,
+		Clock:           clk,
+		Leeway:          leeway,
+		NotBeforeSkew:   notBeforeSkew,
+	})
+}
+
+// newReferenceTokenIssuer creates an opaque reference token issuer.
+// Claims are kept server-side and can only be read via introspection.
+func newReferenceTokenIssuer(cfg IssuerConfig, clk clock.Clock) (service.Issuer, error) {
+	if cfg.IssuerURL == "" {
+		return nil, fmt.Errorf("reference_token issuer requires issuer_url")
+	}
+
+	// Parse TTL
+	ttl := 5 * time.Minute // default
+	if cfg.TTL != "" {
+		duration, err := time.ParseDuration(cfg.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ttl: %w", err)
+		}
+		ttl = duration
+	}
+
+	// Create claim mappers
+	var mappers []service.ClaimMapper
+	for i, mapperCfg := range cfg.ClaimMappers {
+		m, err := newClaimMapper(mapperCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create claim mapper %d: %w", i, err)
+		}
+		mappers = append(mappers, wrapClaimMapper(m, cfg, mapperCfg, "claim_mappers", i, ""))
+	}
+
+	store, err := newReferenceTokenStore(cfg.Store, clk)
+	if err != nil {
+		return nil, err
+	}
+
+	return issuer.NewReferenceTokenIssuer(issuer.ReferenceTokenIssuerConfig{
+		TokenType:    cfg.TokenType,
+		IssuerURL:    cfg.IssuerURL,
+		TTL:          ttl,
+		ClaimMappers: mappers,
+		Store:        store,
+		Clock:        clk,
 	}), nil
 }
 
+// newReferenceTokenStore creates a reference token store from configuration
+func newReferenceTokenStore(cfg *ReferenceTokenStoreConfig, clk clock.Clock) (issuer.ReferenceTokenStore, error) {
+	storeType, maxEntries := "", 0
+	if cfg != nil {
+		storeType, maxEntries = cfg.Type, cfg.MaxEntries
+	}
+	if maxEntries < 0 {
+		return nil, fmt.Errorf("reference token store max_entries must not be negative")
+	}
+
+	switch storeType {
+	case "", "memory":
+		return issuer.NewInMemoryReferenceTokenStore(issuer.InMemoryReferenceTokenStoreConfig{
+			MaxEntries: maxEntries,
+			Clock:      clk,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown reference token store type: %s (supported: memory)", storeType)
+	}
+}
+
 // newUnsignedIssuer creates an unsigned issuer (for development/testing)
-func newUnsignedIssuer(cfg IssuerConfig) (service.Issuer, error) {
+func newUnsignedIssuer(cfg IssuerConfig, clk clock.Clock) (service.Issuer, error) {
 	// Create claim mappers
 	var mappers []service.ClaimMapper
 	for i, mapperCfg := range cfg.ClaimMappers {
@@ -350,17 +901,18 @@ func newUnsignedIssuer(cfg IssuerConfig) (service.Issuer, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to create claim mapper %d: %w", i, err)
 		}
-		mappers = append(mappers, m)
+		mappers = append(mappers, wrapClaimMapper(m, cfg, mapperCfg, "claim_mappers", i, ""))
 	}
 
 	return issuer.NewUnsignedIssuer(issuer.UnsignedIssuerConfig{
 		TokenType:    cfg.TokenType,
 		ClaimMappers: mappers,
+		Clock:        clk,
 	}), nil
 }
 
 // newRHIdentityIssuer creates a Red Hat identity issuer
-func newRHIdentityIssuer(cfg IssuerConfig) (service.Issuer, error) {
+func newRHIdentityIssuer(cfg IssuerConfig, clk clock.Clock) (service.Issuer, error) {
 	// Create claim mappers
 	var mappers []service.ClaimMapper
 	for i, mapperCfg := range cfg.ClaimMappers {
@@ -368,15 +920,163 @@ func newRHIdentityIssuer(cfg IssuerConfig) (service.Issuer, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to create claim mapper %d: %w", i, err)
 		}
-		mappers = append(mappers, m)
+		mappers = append(mappers, wrapClaimMapper(m, cfg, mapperCfg, "claim_mappers", i, ""))
 	}
 
 	return issuer.NewRHIdentityIssuer(issuer.RHIdentityIssuerConfig{
 		TokenType:    cfg.TokenType,
 		ClaimMappers: mappers,
+		Clock:        clk,
 	}), nil
 }
 
+// newPluginIssuer creates an issuer implemented by a plugin
+func newPluginIssuer(cfg IssuerConfig, plugins *Plugins, clk clock.Clock) (service.Issuer, error) {
+	if cfg.Plugin == "" {
+		return nil, fmt.Errorf("plugin issuer requires plugin")
+	}
+	client, err := plugins.Get(cfg.Plugin)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create claim mappers
+	var mappers []service.ClaimMapper
+	for i, mapperCfg := range cfg.ClaimMappers {
+		m, err := newClaimMapper(mapperCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create claim mapper %d: %w", i, err)
+		}
+		mappers = append(mappers, wrapClaimMapper(m, cfg, mapperCfg, "claim_mappers", i, ""))
+	}
+
+	return issuer.NewPluginIssuer(issuer.PluginIssuerConfig{
+		TokenType:    cfg.TokenType,
+		Plugin:       client,
+		ClaimMappers: mappers,
+		Clock:        clk,
+	}), nil
+}
+
+// newEncryptionConfig creates the JWE encryption settings of an issuer
+func newEncryptionConfig(cfg *TokenEncryptionConfig, transport http.RoundTripper) (*issuer.EncryptionConfig, error) {
+	keySource, err := newEncryptionKeySource(cfg, transport)
+	if err != nil {
+		return nil, err
+	}
+
+	encryption := &issuer.EncryptionConfig{
+		KeySource:         keySource,
+		KeyAlgorithm:      cfg.KeyAlgorithm,
+		ContentEncryption: cfg.ContentEncryption,
+	}
+
+	// Check the algorithms now rather than when a token is issued
+	if _, err := issuer.NewTokenEncrypter(*encryption); err != nil {
+		return nil, err
+	}
+	return encryption, nil
+}
+
+// newTokenEncrypter creates the encrypter for issuers that encrypt their own
+// tokens. Returns nil if the issuer is not encrypted.
+func newTokenEncrypter(encryption *issuer.EncryptionConfig) (*issuer.TokenEncrypter, error) {
+	if encryption == nil {
+		return nil, nil
+	}
+	return issuer.NewTokenEncrypter(*encryption)
+}
+
+// newEncryptionKeySource creates the recipient key source from configuration
+func newEncryptionKeySource(cfg *TokenEncryptionConfig, transport http.RoundTripper) (issuer.EncryptionKeySource, error) {
+	sources := 0
+	for _, v := range []string{cfg.Key, cfg.KeyFile, cfg.JWKSURL} {
+		if v != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, fmt.Errorf("encryption requires exactly one of key, key_file, or jwks_url")
+	}
+
+	if cfg.JWKSURL != "" {
+		sourceCfg := issuer.JWKSEncryptionKeySourceConfig{
+			URL:   cfg.JWKSURL,
+			KeyID: cfg.KeyID,
+		}
+		if cfg.RefreshInterval != "" {
+			duration, err := time.ParseDuration(cfg.RefreshInterval)
+			if err != nil {
+				return nil, fmt.Errorf("invalid refresh_interval: %w", err)
+			}
+			sourceCfg.RefreshInterval = duration
+		}
+		if transport != nil {
+			sourceCfg.HTTPClient = &http.Client{
+				Transport: transport,
+			}
+		}
+		return issuer.NewJWKSEncryptionKeySource(context.Background(), sourceCfg)
+	}
+
+	data := []byte(cfg.Key)
+	if cfg.KeyFile != "" {
+		content, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file %s: %w", cfg.KeyFile, err)
+		}
+		data = content
+	}
+
+	key, err := issuer.ParseEncryptionKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return issuer.NewStaticEncryptionKeySource(key), nil
+}
+
+// newClaimsContract creates a claims contract from configuration
+// Returns nil if cfg is nil.
+func newClaimsContract(cfg *ClaimsContractConfig) (*issuer.ClaimsContract, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	for i, name := range cfg.Required {
+		if name == "" {
+			return nil, fmt.Errorf("required claim %d is empty", i)
+		}
+		if slices.Contains(cfg.Required[:i], name) {
+			return nil, fmt.Errorf("duplicate required claim: %s", name)
+		}
+	}
+	return &issuer.ClaimsContract{
+		Defaults: claims.Claims(cfg.Defaults),
+		Required: cfg.Required,
+	}, nil
+}
+
+// wrapClaimMapper wraps the mapper configured at index i of an issuer's
+// mapper field, whose claims are nested in claim: it is made optional if
+// configured so, and labeled if the issuer records claim provenance
+func wrapClaimMapper(m service.ClaimMapper, cfg IssuerConfig, mapperCfg ClaimMapperConfig, field string, i int, claim string) service.ClaimMapper {
+	name := fmt.Sprintf("%s[%d]", field, i)
+	if mapperCfg.Optional {
+		optionalName := name
+		if mapperCfg.Name != "" {
+			optionalName = mapperCfg.Name
+		}
+		m = service.OptionalClaimMapper(m, optionalName)
+	}
+	if !cfg.Provenance {
+		return m
+	}
+	return service.LabelClaimMapper(m, service.ClaimMapperLabel{
+		Name:  name,
+		Type:  mapperCfg.Type,
+		Claim: claim,
+	})
+}
+
 // newClaimMapper creates a claim mapper from configuration
 func newClaimMapper(cfg ClaimMapperConfig) (service.ClaimMapper, error) {
 	switch cfg.Type {
@@ -386,10 +1086,64 @@ func newClaimMapper(cfg ClaimMapperConfig) (service.ClaimMapper, error) {
 		return service.NewPassthroughSubjectMapper(), nil
 	case "request_attributes":
 		return service.NewRequestAttributesMapper(), nil
+	case "request_context":
+		return newRequestContextMapper(cfg)
+	case "risk":
+		return service.NewRiskMapper(), nil
 	case "stub":
 		return newStubMapper(cfg)
 	default:
-		return nil, fmt.Errorf("unknown claim mapper type: %s (supported: cel, passthrough, request_attributes, stub)", cfg.Type)
+		return nil, fmt.Errorf("unknown claim mapper type: %s (supported: cel, passthrough, request_attributes, request_context, risk, stub)", cfg.Type)
+	}
+}
+
+// newRequestContextMapper creates a mapper that transforms the request context
+func newRequestContextMapper(cfg ClaimMapperConfig) (service.ClaimMapper, error) {
+	transforms := make([]mapper.RequestContextTransform, 0, len(cfg.Transforms))
+	for i, transformCfg := range cfg.Transforms {
+		transform, err := newRequestContextTransform(transformCfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid request context transform %d: %w", i, err)
+		}
+		transforms = append(transforms, transform)
+	}
+	return mapper.NewRequestContextMapper(transforms...), nil
+}
+
+// newRequestContextTransform creates a single request context transform
+func newRequestContextTransform(cfg RequestContextTransformConfig) (mapper.RequestContextTransform, error) {
+	if cfg.Field == "" {
+		return nil, fmt.Errorf("%s transform requires field", cfg.Type)
+	}
+
+	switch cfg.Type {
+	case "rename":
+		if cfg.To == "" {
+			return nil, fmt.Errorf("rename transform requires to")
+		}
+		return mapper.RenameField(cfg.Field, cfg.To), nil
+	case "drop":
+		return mapper.DropField(cfg.Field), nil
+	case "lowercase":
+		return mapper.LowercaseField(cfg.Field), nil
+	case "uppercase":
+		return mapper.UppercaseField(cfg.Field), nil
+	case "user_agent":
+		target := cfg.To
+		if target == "" {
+			target = cfg.Field
+		}
+		return mapper.ParseUserAgent(cfg.Field, target), nil
+	case "cel":
+		return mapper.NewCELTransform(cfg.Field, cfg.Expression)
+	case "redact":
+		return claims.NewRedactTransformer(cfg.Replacement, cfg.Field), nil
+	case "hash":
+		return claims.NewHashTransformer([]byte(cfg.Salt), cfg.Field)
+	case "truncate":
+		return claims.NewTruncateTransformer(cfg.MaxLength, cfg.Field)
+	default:
+		return nil, fmt.Errorf("unknown request context transform: %q (supported: rename, drop, lowercase, uppercase, user_agent, cel, redact, hash, truncate)", cfg.Type)
 	}
 }
 
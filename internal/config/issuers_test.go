@@ -0,0 +1,343 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/issuer"
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestNewTxnIDGenerator(t *testing.T) {
+	nodeID := func(id int) *int { return &id }
+	tests := []struct {
+		name    string
+		cfg     IssuerConfig
+		wantErr bool
+	}{
+		{name: "default", cfg: IssuerConfig{}},
+		{name: "ulid", cfg: IssuerConfig{TransactionIDFormat: "ulid"}},
+		{name: "snowflake", cfg: IssuerConfig{TransactionIDFormat: "snowflake", TransactionIDNodeID: nodeID(12)}},
+		{name: "snowflake node ID 0", cfg: IssuerConfig{TransactionIDFormat: "snowflake", TransactionIDNodeID: nodeID(0)}},
+		{name: "snowflake without node ID", cfg: IssuerConfig{TransactionIDFormat: "snowflake"}, wantErr: true},
+		{name: "snowflake node ID out of range", cfg: IssuerConfig{TransactionIDFormat: "snowflake", TransactionIDNodeID: nodeID(1024)}, wantErr: true},
+		{name: "trace_id", cfg: IssuerConfig{TransactionIDFormat: "trace_id", TransactionIDFallback: "ulid"}},
+		{name: "trace_id cannot fall back to itself", cfg: IssuerConfig{TransactionIDFormat: "trace_id", TransactionIDFallback: "trace_id"}, wantErr: true},
+		{name: "trace_id unknown fallback", cfg: IssuerConfig{TransactionIDFormat: "trace_id", TransactionIDFallback: "uuidv1"}, wantErr: true},
+		{name: "unknown format", cfg: IssuerConfig{TransactionIDFormat: "sequential"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen, err := newTxnIDGenerator(tt.cfg, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if gen == nil {
+				t.Fatal("expected a generator")
+			}
+		})
+	}
+
+	t.Run("trace_id uses the configured fallback", func(t *testing.T) {
+		gen, err := newTxnIDGenerator(IssuerConfig{TransactionIDFormat: "trace_id", TransactionIDFallback: "uuidv4"}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		trace, ok := gen.(*issuer.TraceTxnIDGenerator)
+		if !ok {
+			t.Fatalf("expected a trace ID generator, got %T", gen)
+		}
+		if _, ok := trace.Fallback.(issuer.UUIDv4TxnIDGenerator); !ok {
+			t.Errorf("expected UUIDv4 fallback, got %T", trace.Fallback)
+		}
+	})
+}
+
+func TestShippedRHIdentityScript(t *testing.T) {
+	ctx := context.Background()
+
+	iss, err := newIssuer(IssuerConfig{
+		TokenType:    string(service.TokenTypeRHIdentity),
+		Type:         "rh_identity",
+		ClaimMappers: []ClaimMapperConfig{{Type: "cel", ScriptFile: "../../configs/scripts/redhat_identity.cel"}},
+	}, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create issuer: %v", err)
+	}
+
+	issue := func(subjectClaims claims.Claims) error {
+		_, err := iss.Issue(ctx, &service.IssueContext{
+			Subject:            &trust.Result{Subject: "alice", Claims: subjectClaims},
+			DataSourceRegistry: service.NewDataSourceRegistry(),
+		})
+		return err
+	}
+
+	tests := []struct {
+		name    string
+		claims  claims.Claims
+		wantErr bool
+	}{
+		{
+			name:   "service account with organization",
+			claims: claims.Claims{"preferred_username": "service-account-abc", "organization": map[string]any{"id": "12345"}},
+		},
+		{
+			name:   "service account with rh-org-id",
+			claims: claims.Claims{"preferred_username": "service-account-abc", "rh-org-id": "12345"},
+		},
+		{
+			name:    "service account without organization",
+			claims:  claims.Claims{"preferred_username": "service-account-abc"},
+			wantErr: true,
+		},
+		{
+			name:   "console user with organization",
+			claims: claims.Claims{"scope": "openid api.console", "organization": map[string]any{"id": "12345"}},
+		},
+		{
+			name:    "console user without organization",
+			claims:  claims.Claims{"scope": "openid api.console"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := issue(tt.claims)
+			if tt.wantErr && (err == nil || !strings.Contains(err.Error(), "missing_org_id")) {
+				t.Errorf("expected the script to deny an identity without an organization, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestIssuerInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  IssuerConfig
+		want service.IssuerInfo
+	}{
+		{
+			name: "configured ttl",
+			cfg:  IssuerConfig{TokenType: "urn:example:txn", Type: "transaction_token", TTL: "2m", SignerID: "txn", TTLPolicy: &TTLPolicyConfig{}},
+			want: service.IssuerInfo{TokenType: "urn:example:txn", Type: "transaction_token", TTL: 2 * time.Minute, TTLPolicy: true, SignerID: "txn"},
+		},
+		{
+			name: "default ttl",
+			cfg:  IssuerConfig{TokenType: "urn:example:at", Type: "jwt_access_token", Encryption: &TokenEncryptionConfig{}},
+			want: service.IssuerInfo{TokenType: "urn:example:at", Type: "jwt_access_token", TTL: 5 * time.Minute, Encrypted: true},
+		},
+		{
+			name: "no ttl",
+			cfg:  IssuerConfig{TokenType: "urn:example:legacy", Type: "plugin", Plugin: "legacy", TTL: "1h"},
+			want: service.IssuerInfo{TokenType: "urn:example:legacy", Type: "plugin", Plugin: "legacy"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := issuerInfo(tt.cfg); got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewIssuerRegistry_Provenance(t *testing.T) {
+	cfg := Config{Issuers: []IssuerConfig{{
+		TokenType:    "urn:example:unsigned",
+		Type:         "unsigned",
+		Provenance:   true,
+		ClaimMappers: []ClaimMapperConfig{{Type: "passthrough"}},
+	}}}
+	registry, err := newIssuerRegistry(cfg, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("newIssuerRegistry failed: %v", err)
+	}
+	iss, err := registry.GetIssuer("urn:example:unsigned")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := iss.Issue(context.Background(), &service.IssueContext{
+		Subject:            &trust.Result{Subject: "alice", Claims: claims.Claims{"email": "alice@example.com"}},
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	})
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if len(token.Provenance) == 0 {
+		t.Fatal("expected claim provenance")
+	}
+	for _, source := range token.Provenance {
+		if source.Mapper != "claim_mappers[0]" || source.MapperType != "passthrough" {
+			t.Errorf("unexpected claim source %+v", source)
+		}
+	}
+	if info, err := registry.Describe("urn:example:unsigned"); err != nil || !info.Provenance {
+		t.Errorf("expected provenance in the description, got %+v (%v)", info, err)
+	}
+}
+
+func TestNewIssuerRegistry_OptionalMapper(t *testing.T) {
+	mappers := []ClaimMapperConfig{
+		{Type: "passthrough"},
+		{Type: "cel", Name: "enrichment", Script: `{"team": datasource("teams").name}`, Optional: true},
+	}
+	newIssuer := func(t *testing.T, mappers []ClaimMapperConfig) service.Issuer {
+		t.Helper()
+		registry, err := newIssuerRegistry(Config{Issuers: []IssuerConfig{{
+			TokenType:    "urn:example:unsigned",
+			Type:         "unsigned",
+			Provenance:   true,
+			ClaimMappers: mappers,
+		}}}, nil, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("newIssuerRegistry failed: %v", err)
+		}
+		iss, err := registry.GetIssuer("urn:example:unsigned")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return iss
+	}
+	issueCtx := &service.IssueContext{
+		Subject:            &trust.Result{Subject: "alice", Claims: claims.Claims{"email": "alice@example.com"}},
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	}
+
+	// The teams data source is unknown, so the enrichment mapper fails
+	token, err := newIssuer(t, mappers).Issue(context.Background(), issueCtx)
+	if err != nil {
+		t.Fatalf("expected the optional mapper's failure to be tolerated, got %v", err)
+	}
+	for _, source := range token.Provenance {
+		if source.Mapper != "claim_mappers[0]" {
+			t.Errorf("expected no claims from the failed mapper, got %+v", source)
+		}
+	}
+
+	mappers[1].Optional = false
+	if _, err := newIssuer(t, mappers).Issue(context.Background(), issueCtx); err == nil {
+		t.Error("expected a required mapper's failure to fail issuance")
+	}
+}
+
+func TestNewTransactionTokenIssuer_Replacement(t *testing.T) {
+	cfg := IssuerConfig{
+		TokenType:   "urn:ietf:params:oauth:token-type:txn_token",
+		Type:        "transaction_token",
+		IssuerURL:   "https://parsec.test",
+		SignerID:    "txn",
+		Replacement: &TokenReplacementConfig{MutableClaims: []string{"req_ctx", "txn"}},
+	}
+	_, err := newTransactionTokenIssuer(cfg, nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "txn is preserved") {
+		t.Errorf("expected a mutable txn to be rejected, got %v", err)
+	}
+}
+
+func TestNewIssuer_AudienceSigners(t *testing.T) {
+	_, err := newIssuer(IssuerConfig{
+		TokenType:       "urn:example:stub",
+		Type:            "stub",
+		AudienceSigners: []AudienceSignerConfig{{Audiences: []string{"legacy"}, SignerID: "rsa"}},
+	}, nil, nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "audience_signers is not supported for stub issuers") {
+		t.Errorf("expected audience_signers to be rejected for stub issuers, got %v", err)
+	}
+
+	_, err = newAudienceSigners(IssuerConfig{AudienceSigners: []AudienceSignerConfig{{SignerID: "rsa"}}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "audience_signers[0] requires audiences") {
+		t.Errorf("expected an entry without audiences to be rejected, got %v", err)
+	}
+}
+
+func TestBuildKeyProviderRegistry_EncryptionKey(t *testing.T) {
+	keysPath := t.TempDir()
+	kek := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+
+	registry, err := buildKeyProviderRegistry([]KeyProviderConfig{
+		{ID: "disk", Type: "disk", KeyType: "EC-P256", KeysPath: keysPath, EncryptionKey: kek},
+	})
+	if err != nil {
+		t.Fatalf("buildKeyProviderRegistry failed: %v", err)
+	}
+	if _, ok := registry["disk"]; !ok {
+		t.Fatal("expected the disk key provider")
+	}
+
+	for _, encryptionKey := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		_, err := buildKeyProviderRegistry([]KeyProviderConfig{
+			{ID: "disk", Type: "disk", KeyType: "EC-P256", KeysPath: keysPath, EncryptionKey: encryptionKey},
+		})
+		if err == nil {
+			t.Errorf("expected encryption_key %q to be rejected", encryptionKey)
+		}
+	}
+}
+
+func TestNewKeySlotStore(t *testing.T) {
+	store, err := NewKeySlotStore(nil)
+	if err != nil {
+		t.Fatalf("NewKeySlotStore failed: %v", err)
+	}
+	if _, ok := store.(*keys.InMemoryKeySlotStore); !ok {
+		t.Errorf("expected an in-memory store by default, got %T", store)
+	}
+
+	store, err = NewKeySlotStore(&KeySlotStoreConfig{Type: "file", Path: filepath.Join(t.TempDir(), "slots.json")})
+	if err != nil {
+		t.Fatalf("NewKeySlotStore failed: %v", err)
+	}
+	if _, ok := store.(*keys.FileKeySlotStore); !ok {
+		t.Errorf("expected a file store, got %T", store)
+	}
+
+	for _, cfg := range []*KeySlotStoreConfig{{Type: "file"}, {Type: "memory", Path: "/tmp/slots.json"}, {Type: "etcd"}} {
+		if _, err := NewKeySlotStore(cfg); err == nil {
+			t.Errorf("expected %+v to be rejected", cfg)
+		}
+	}
+}
+
+func TestNewClaimsContract(t *testing.T) {
+	if contract, err := newClaimsContract(nil); contract != nil || err != nil {
+		t.Errorf("expected no contract without config, got %v (%v)", contract, err)
+	}
+	contract, err := newClaimsContract(&ClaimsContractConfig{
+		Defaults: map[string]any{"region": "us-east-1"},
+		Required: []string{"tenant", "region"},
+	})
+	if err != nil {
+		t.Fatalf("newClaimsContract failed: %v", err)
+	}
+	if contract.Defaults["region"] != "us-east-1" || len(contract.Required) != 2 {
+		t.Errorf("unexpected contract %+v", contract)
+	}
+
+	for name, cfg := range map[string]ClaimsContractConfig{
+		"empty required claim":     {Required: []string{""}},
+		"duplicate required claim": {Required: []string{"tenant", "tenant"}},
+	} {
+		if _, err := newClaimsContract(&cfg); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// serviceAccountDir holds the Kubernetes service account token, CA and namespace
+// used to read ConfigMaps and discover peers (overridden in tests)
+var serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// kubernetesClients caches API server clients by service account directory so
+// polling does not rebuild a TLS config on every request
+var (
+	kubernetesClientsMu sync.Mutex
+	kubernetesClients   = map[string]*http.Client{}
+)
+
+// kubernetesAPI reads from the in-cluster API server as the pod's service account
+type kubernetesAPI struct {
+	server string
+	client *http.Client
+}
+
+// newKubernetesAPI connects to the API server of the cluster the process runs
+// in; what names the feature needing it in errors
+func newKubernetesAPI(what string) (*kubernetesAPI, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("%s require running in a Kubernetes cluster (KUBERNETES_SERVICE_HOST is not set)", what)
+	}
+
+	client, err := kubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+	return &kubernetesAPI{
+		server: "https://" + net.JoinHostPort(host, port),
+		client: client,
+	}, nil
+}
+
+func kubernetesClient() (*http.Client, error) {
+	kubernetesClientsMu.Lock()
+	defer kubernetesClientsMu.Unlock()
+
+	if client, ok := kubernetesClients[serviceAccountDir]; ok {
+		return client, nil
+	}
+
+	caPEM, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("failed to parse cluster CA")
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+	}
+	kubernetesClients[serviceAccountDir] = client
+	return client, nil
+}
+
+// get reads the JSON resource at path (and query) on the API server
+func (k *kubernetesAPI) get(ctx context.Context, path string) ([]byte, error) {
+	// Projected service account tokens rotate, so read the token on each request
+	token, err := os.ReadFile(filepath.Join(serviceAccountDir, "token"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.server+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+
+	body, _, err := doConditionalGet(k.client, req)
+	return body, err
+}
+
+// serviceAccountNamespace returns the namespace the pod runs in
+func serviceAccountNamespace() (string, error) {
+	namespace, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(namespace)), nil
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/ledger"
+)
+
+// NewTokenLedger creates the token ledger
+// Returns nil if cfg is nil (issued tokens are not recorded).
+func NewTokenLedger(cfg *TokenLedgerConfig, clk clock.Clock) (*ledger.Ledger, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	retention, err := parseOptionalDuration(cfg.Retention)
+	if err != nil {
+		return nil, fmt.Errorf("invalid retention: %w", err)
+	}
+	return ledger.New(ledger.Config{
+		MaxEntries: cfg.MaxEntries,
+		Retention:  retention,
+		Clock:      clk,
+	})
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewTokenLedger(t *testing.T) {
+	l, err := NewTokenLedger(&TokenLedgerConfig{MaxEntries: 100, Retention: "1h"}, nil)
+	if err != nil {
+		t.Fatalf("NewTokenLedger failed: %v", err)
+	}
+	if l.Retention() != time.Hour {
+		t.Errorf("expected 1h retention, got %s", l.Retention())
+	}
+
+	if l, err := NewTokenLedger(nil, nil); l != nil || err != nil {
+		t.Errorf("expected no token ledger without config, got %v (%v)", l, err)
+	}
+
+	for _, cfg := range []*TokenLedgerConfig{{Retention: "a while"}, {Retention: "-1h"}, {MaxEntries: -1}} {
+		if _, err := NewTokenLedger(cfg, nil); err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/knadh/koanf/parsers/json"
 	"github.com/knadh/koanf/parsers/toml/v2"
@@ -20,23 +22,49 @@ import (
 // Loader is a lightweight wrapper around koanf for loading configuration
 // from files and environment variables
 type Loader struct {
+	mu         sync.Mutex
 	k          *koanf.Koanf
+	redacted   map[string]any
 	configPath string
+	overlays   []string
+	flags      *pflag.FlagSet
+	files      []string                 // every local config file read, including includes
+	remote     map[string]remoteVersion // every remote config source read, by URL
+
+	deprecations []Deprecation
+
+	pollInterval time.Duration
 }
 
+// DefaultPollInterval is how often Watch polls remote config sources for changes
+const DefaultPollInterval = 30 * time.Second
+
 // NewLoader creates a new configuration loader that reads from a file
 // and overlays environment variable overrides with PARSEC_ prefix.
 //
+// Overlay files (e.g. environment-specific patches) are merged on top of the
+// config file in order. Any file may pull in other files with a top-level
+// include key; see loadConfigFiles for the merge rules. The config file,
+// overlays and includes may also be remote sources: http(s)://, s3:// or
+// configmap:// URLs (see remote.go).
+//
+// Files written against an older schema version have deprecated fields
+// rewritten to their replacements; see Deprecations.
+//
+// After all sources are merged, ${ENV_VAR} references in string values are
+// expanded and secretRef nodes are resolved (see secrets.go).
+//
 // The file format (YAML, JSON, or TOML) is auto-detected from the extension.
 // Environment variables like PARSEC_SERVER__GRPC_PORT map to server.grpc_port
 // If configPath is empty, only environment variables and defaults will be loaded.
 //
 // Configuration precedence (highest to lowest):
 //  1. Environment variables (PARSEC_*)
-//  2. Configuration file (if provided)
-//  3. Built-in defaults
-func NewLoader(configPath string) (*Loader, error) {
-	return newLoader(configPath, nil)
+//  2. Overlay files (later overlays first)
+//  3. Configuration file (if provided) and its includes
+//  4. Built-in defaults
+func NewLoader(configPath string, overlays ...string) (*Loader, error) {
+	return newLoader(configPath, overlays, nil)
 }
 
 // NewLoaderWithFlags creates a new configuration loader with command-line flag support.
@@ -45,10 +73,11 @@ func NewLoader(configPath string) (*Loader, error) {
 // Configuration precedence (highest to lowest):
 //  1. Command-line flags
 //  2. Environment variables (PARSEC_*)
-//  3. Configuration file (if provided)
-//  4. Built-in defaults
-func NewLoaderWithFlags(configPath string, flags *pflag.FlagSet) (*Loader, error) {
-	return newLoader(configPath, flags)
+//  3. Overlay files (later overlays first)
+//  4. Configuration file (if provided) and its includes
+//  5. Built-in defaults
+func NewLoaderWithFlags(configPath string, flags *pflag.FlagSet, overlays ...string) (*Loader, error) {
+	return newLoader(configPath, overlays, flags)
 }
 
 // getDefaults returns the default configuration values
@@ -62,7 +91,7 @@ func getDefaults() map[string]interface{} {
 }
 
 // newLoader is the internal loader implementation
-func newLoader(configPath string, flags *pflag.FlagSet) (*Loader, error) {
+func newLoader(configPath string, overlays []string, flags *pflag.FlagSet) (*Loader, error) {
 	k := koanf.New(".")
 
 	// Load defaults (lowest precedence)
@@ -70,18 +99,10 @@ func newLoader(configPath string, flags *pflag.FlagSet) (*Loader, error) {
 		return nil, fmt.Errorf("failed to load defaults: %w", err)
 	}
 
-	// Load from file if provided
-	if configPath != "" {
-		// Auto-detect parser based on file extension
-		parser, err := getParserForFile(configPath)
-		if err != nil {
-			return nil, err
-		}
-
-		// Load from file
-		if err := k.Load(file.Provider(configPath), parser); err != nil {
-			return nil, fmt.Errorf("failed to load config file %s: %w", configPath, err)
-		}
+	// Load from files if provided (format auto-detected per file)
+	sources, err := loadFiles(k, configPath, overlays)
+	if err != nil {
+		return nil, err
 	}
 
 	// Load environment variable overrides with PARSEC_ prefix
@@ -92,37 +113,95 @@ func newLoader(configPath string, flags *pflag.FlagSet) (*Loader, error) {
 	}
 
 	// Load command-line flags (highest precedence)
-	if flags != nil {
-		// Build mapping from flag names to config keys
-		flagMapping := GetFlagMapping()
-
-		if err := k.Load(posflag.ProviderWithFlag(flags, ".", k, func(f *pflag.Flag) (string, any) {
-			// Look up the config key for this flag
-			configKey, ok := flagMapping[f.Name]
-			if !ok {
-				// Not a valid config flag, skip it
-				return "", nil
-			}
-
-			// Only override if the flag was explicitly set
-			if !f.Changed {
-				return "", nil
-			}
+	if err := loadFlags(k, flags); err != nil {
+		return nil, err
+	}
 
-			return configKey, posflag.FlagVal(flags, f)
-		}), nil); err != nil {
-			return nil, fmt.Errorf("failed to load command-line flags: %w", err)
-		}
+	k, redacted, err := resolveSecrets(k)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Loader{
 		k:          k,
+		redacted:   redacted,
 		configPath: configPath,
+		overlays:   overlays,
+		flags:      flags,
+		files:      sources.files,
+		remote:     sources.remote,
+
+		deprecations: sources.deprecations,
+		pollInterval: DefaultPollInterval,
 	}, nil
 }
 
+// loadFlags loads explicitly set command-line flags into k
+func loadFlags(k *koanf.Koanf, flags *pflag.FlagSet) error {
+	if flags == nil {
+		return nil
+	}
+
+	// Build mapping from flag names to config keys
+	flagMapping := GetFlagMapping()
+
+	if err := k.Load(posflag.ProviderWithFlag(flags, ".", k, func(f *pflag.Flag) (string, any) {
+		// Look up the config key for this flag
+		configKey, ok := flagMapping[f.Name]
+		if !ok {
+			// Not a valid config flag, skip it
+			return "", nil
+		}
+
+		// Only override if the flag was explicitly set
+		if !f.Changed {
+			return "", nil
+		}
+
+		return configKey, posflag.FlagVal(flags, f)
+	}), nil); err != nil {
+		return fmt.Errorf("failed to load command-line flags: %w", err)
+	}
+	return nil
+}
+
+// loadFiles merges the config file, its includes, and overlays into k
+// Returns the sources that were read
+func loadFiles(k *koanf.Koanf, configPath string, overlays []string) (configSources, error) {
+	if configPath == "" && len(overlays) == 0 {
+		return configSources{}, nil
+	}
+
+	merged, sources, err := loadConfigFiles(configPath, overlays)
+	if err != nil {
+		return configSources{}, err
+	}
+	if err := k.Load(confmap.Provider(merged, ""), nil); err != nil {
+		return configSources{}, fmt.Errorf("failed to load config files: %w", err)
+	}
+	return sources, nil
+}
+
+// resolveSecrets expands environment variable references and secretRef nodes
+// Returns the resolved configuration and a copy with secret values redacted
+func resolveSecrets(k *koanf.Koanf) (*koanf.Koanf, map[string]any, error) {
+	resolved, redacted, err := newSecretResolver().resolve(k.Raw())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve config references: %w", err)
+	}
+
+	out := koanf.New(".")
+	if err := out.Load(confmap.Provider(resolved, ""), nil); err != nil {
+		return nil, nil, fmt.Errorf("failed to load resolved config: %w", err)
+	}
+	return out, redacted, nil
+}
+
 // Get unmarshals the configuration into a Config struct
 func (l *Loader) Get() (*Config, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	var cfg Config
 	if err := l.k.Unmarshal("", &cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
@@ -130,70 +209,159 @@ func (l *Loader) Get() (*Config, error) {
 	return &cfg, nil
 }
 
-// Watch watches the config file for changes and calls onChange with the new config.
+// Redacted returns the merged configuration with secret values replaced by
+// RedactedValue. Use this for any configuration dump or log output.
+func (l *Loader) Redacted() map[string]any {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.redacted
+}
+
+// Deprecations returns the deprecated fields found in the config files, which
+// were migrated to their replacements at load time
+func (l *Loader) Deprecations() []Deprecation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.deprecations
+}
+
+// HasRemoteSources reports whether any of the configuration was read from a
+// remote source
+func (l *Loader) HasRemoteSources() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.remote) > 0
+}
+
+// SetPollInterval sets how often Watch polls remote sources for changes
+// (default: DefaultPollInterval). Must be called before Watch.
+func (l *Loader) SetPollInterval(interval time.Duration) {
+	l.pollInterval = interval
+}
+
+// Watch watches the config files for changes and calls onChange with the new config.
+// All files read at load time are watched, including overlays and includes.
+// Remote sources are polled every poll interval and reload the configuration
+// when their ETag or content checksum changes.
 // This runs until the context is cancelled or an error occurs.
 //
 // Note: Not all components can be safely hot-reloaded. Use with caution in production.
 // If no config file is configured, this will block until context is cancelled.
 func (l *Loader) Watch(ctx context.Context, onChange func(*Config) error) error {
-	// If no config file, just block until cancelled
-	if l.configPath == "" {
-		<-ctx.Done()
-		return ctx.Err()
+	if l.HasRemoteSources() && l.pollInterval > 0 {
+		go l.poll(ctx, onChange)
 	}
 
-	// Use file provider with watch enabled
-	fp := file.Provider(l.configPath)
-
-	// Set up file watcher
-	if err := fp.Watch(func(event interface{}, err error) {
-		if err != nil {
-			// Log error but continue watching
-			fmt.Printf("config watch error: %v\n", err)
-			return
+	// Set up a file watcher per file; any change reloads the whole set
+	for _, path := range l.files {
+		fp := file.Provider(path)
+		if err := fp.Watch(func(event interface{}, err error) {
+			if err != nil {
+				// Log error but continue watching
+				fmt.Printf("config watch error: %v\n", err)
+				return
+			}
+			l.reload(onChange)
+		}); err != nil {
+			return fmt.Errorf("failed to watch config file %s: %w", path, err)
 		}
+	}
 
-		// Reload the config
-		parser, err := getParserForFile(l.configPath)
-		if err != nil {
-			fmt.Printf("config parser error: %v\n", err)
-			return
-		}
+	// Block until context is cancelled
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// poll checks the remote sources for changes every poll interval until ctx is
+// cancelled, reloading the configuration when any has changed
+func (l *Loader) poll(ctx context.Context, onChange func(*Config) error) {
+	ticker := time.NewTicker(l.pollInterval)
+	defer ticker.Stop()
 
-		// Create new koanf instance for reload
-		k := koanf.New(".")
-		if err := k.Load(fp, parser); err != nil {
-			fmt.Printf("config reload error: %v\n", err)
+	for {
+		select {
+		case <-ctx.Done():
 			return
+		case <-ticker.C:
 		}
 
-		// Reload env vars
-		if err := k.Load(env.Provider("PARSEC_", ".", envTransform), nil); err != nil {
-			fmt.Printf("env reload error: %v\n", err)
-			return
+		l.mu.Lock()
+		remote := make(map[string]remoteVersion, len(l.remote))
+		for location, version := range l.remote {
+			remote[location] = version
 		}
+		l.mu.Unlock()
 
-		// Unmarshal new config
-		var cfg Config
-		if err := k.Unmarshal("", &cfg); err != nil {
-			fmt.Printf("config unmarshal error: %v\n", err)
-			return
+		for location, version := range remote {
+			changed, err := remoteChanged(ctx, location, version)
+			if err != nil {
+				// Keep the current config; the next poll retries
+				fmt.Printf("config poll error: %v\n", err)
+				continue
+			}
+			if changed {
+				l.reload(onChange)
+				break
+			}
 		}
+	}
+}
 
-		// Update loader's koanf instance
-		l.k = k
+// reload re-reads the config files and environment and calls onChange
+func (l *Loader) reload(onChange func(*Config) error) {
+	cfg, err := l.load()
+	if err != nil {
+		fmt.Printf("config reload error: %v\n", err)
+		return
+	}
 
-		// Call onChange callback
-		if err := onChange(&cfg); err != nil {
-			fmt.Printf("config onChange error: %v\n", err)
-		}
-	}); err != nil {
-		return fmt.Errorf("failed to watch config file: %w", err)
+	// Call onChange callback
+	if err := onChange(cfg); err != nil {
+		fmt.Printf("config onChange error: %v\n", err)
+	}
+}
+
+// load re-reads all sources and replaces the loaded configuration
+func (l *Loader) load() (*Config, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Create new koanf instance for reload
+	k := koanf.New(".")
+	if err := k.Load(confmap.Provider(getDefaults(), "."), nil); err != nil {
+		return nil, fmt.Errorf("failed to load defaults: %w", err)
+	}
+	sources, err := loadFiles(k, l.configPath, l.overlays)
+	if err != nil {
+		return nil, err
 	}
 
-	// Block until context is cancelled
-	<-ctx.Done()
-	return ctx.Err()
+	// Reload env vars and flags
+	if err := k.Load(env.Provider("PARSEC_", ".", envTransform), nil); err != nil {
+		return nil, fmt.Errorf("failed to load environment variables: %w", err)
+	}
+	if err := loadFlags(k, l.flags); err != nil {
+		return nil, err
+	}
+
+	k, redacted, err := resolveSecrets(k)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal new config
+	var cfg Config
+	if err := k.Unmarshal("", &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	// Update loader's koanf instance; includes may have changed, so the set of
+	// remote sources is refreshed too
+	l.k = k
+	l.redacted = redacted
+	l.remote = sources.remote
+	l.deprecations = sources.deprecations
+	return &cfg, nil
 }
 
 // getParserForFile returns the appropriate koanf parser based on file extension
@@ -0,0 +1,269 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// SchemaVersion is the current configuration schema version
+//
+// Config files declare the version they are written against with a top-level
+// version key; files without one are treated as version 1. Fields renamed or
+// removed since a file's version are migrated when the file is loaded, with a
+// Deprecation reported for each, and `parsec config migrate` rewrites the file
+// to the current version.
+const SchemaVersion = 2
+
+// versionKey is the top-level key declaring a config file's schema version
+const versionKey = "version"
+
+// migration describes a field renamed or removed in a schema version
+//
+// Paths are dotted keys from the config root; a segment ending in "[]" is a
+// list whose entries are each migrated (e.g. "issuers[].ttl").
+type migration struct {
+	// version is the schema version that made the change
+	version int
+
+	// from is the deprecated field path
+	from string
+
+	// to is the field's new name within the same parent, or "" if the field
+	// was removed
+	to string
+
+	// note explains what to do instead
+	note string
+
+	// convert rewrites the field's value for its new name (optional); it may
+	// also update the field's parent
+	convert func(parent map[string]any, value any) (any, error)
+}
+
+// migrations lists every schema change, oldest first
+var migrations = []migration{
+	{
+		version: 2,
+		from:    "issuers[].size_budget.compaction[].datasource",
+		to:      "data_source",
+	},
+	{
+		version: 2,
+		from:    "issuers[].include_request_context",
+		note:    "it had no effect, use request_context claim mappers instead",
+	},
+	{
+		version: 2,
+		from:    "exchange_server.claims_filter.actor_rules",
+		to:      "rules",
+		convert: actorRulesToRules,
+	},
+}
+
+// actorRulesToRules converts a map of actor subject pattern to allowed claims
+// into allowlist rules, selecting the rules registry unless another type is set
+//
+// Exact subjects are matched before prefix patterns, and longer prefixes
+// before shorter ones, since rules are matched in order.
+func actorRulesToRules(parent map[string]any, value any) (any, error) {
+	actorRules, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("actor_rules must map actor patterns to allowed claims, got %T", value)
+	}
+
+	patterns := slices.Collect(maps.Keys(actorRules))
+	slices.SortFunc(patterns, func(a, b string) int {
+		aPrefix, bPrefix := strings.HasSuffix(a, "*"), strings.HasSuffix(b, "*")
+		if aPrefix != bPrefix {
+			if aPrefix {
+				return 1
+			}
+			return -1
+		}
+		if c := cmp.Compare(len(b), len(a)); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	rules := make([]any, 0, len(patterns))
+	for _, pattern := range patterns {
+		rules = append(rules, map[string]any{
+			"subject":        pattern,
+			"type":           "allowlist",
+			"allowed_claims": actorRules[pattern],
+		})
+	}
+	if t, _ := parent["type"].(string); t == "" || t == "stub" {
+		parent["type"] = "rules"
+	}
+	return rules, nil
+}
+
+// Deprecation reports a deprecated field found in a config file
+type Deprecation struct {
+	// Source is the config file or remote URL the field was read from
+	Source string
+
+	// Path locates the field, e.g. "issuers[1].include_request_context"
+	Path string
+
+	// Replacement is the path of the field that replaces it, or "" if the
+	// field was removed
+	Replacement string
+
+	// Note explains what to do instead
+	Note string
+}
+
+func (d Deprecation) String() string {
+	msg := fmt.Sprintf("%s: %s is deprecated", d.Source, d.Path)
+	if d.Replacement != "" {
+		msg += fmt.Sprintf(", use %s", d.Replacement)
+	}
+	if d.Note != "" {
+		msg += "; " + d.Note
+	}
+	return msg
+}
+
+// migrateConfig rewrites the deprecated fields of a config file's data to the
+// current schema version and removes its version key
+// Returns a Deprecation for every field that was migrated.
+func migrateConfig(data map[string]any, source string) ([]Deprecation, error) {
+	version, err := schemaVersion(data[versionKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s in %s: %w", versionKey, source, err)
+	}
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("config %s has schema version %d, newer than supported version %d", source, version, SchemaVersion)
+	}
+	delete(data, versionKey)
+
+	var deprecations []Deprecation
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+		segments := strings.Split(m.from, ".")
+		var convertErr error
+		applyMigration(data, segments, "", func(parent map[string]any, path string) {
+			d := Deprecation{Source: source, Path: path, Note: m.note}
+			value := parent[segments[len(segments)-1]]
+			delete(parent, segments[len(segments)-1])
+			if m.to != "" {
+				d.Replacement = path[:strings.LastIndex(path, ".")+1] + m.to
+				if _, exists := parent[m.to]; exists {
+					d.Note = fmt.Sprintf("ignored because %s is also set", d.Replacement)
+				} else if m.convert != nil {
+					converted, err := m.convert(parent, value)
+					if err != nil {
+						convertErr = errors.Join(convertErr, fmt.Errorf("invalid %s in %s: %w", path, source, err))
+						return
+					}
+					parent[m.to] = converted
+				} else {
+					parent[m.to] = value
+				}
+			}
+			deprecations = append(deprecations, d)
+		})
+		if convertErr != nil {
+			return nil, convertErr
+		}
+	}
+	return deprecations, nil
+}
+
+// applyMigration walks data along segments and calls migrate with the map
+// holding the final segment, and its config path, wherever that field is set
+func applyMigration(data map[string]any, segments []string, prefix string, migrate func(parent map[string]any, path string)) {
+	key := segments[0]
+	if len(segments) == 1 {
+		if _, ok := data[key]; ok {
+			migrate(data, prefix+key)
+		}
+		return
+	}
+
+	if list, ok := strings.CutSuffix(key, "[]"); ok {
+		entries, _ := data[list].([]any)
+		for i, entry := range entries {
+			if child, ok := entry.(map[string]any); ok {
+				applyMigration(child, segments[1:], fmt.Sprintf("%s%s[%d].", prefix, list, i), migrate)
+			}
+		}
+		return
+	}
+	if child, ok := data[key].(map[string]any); ok {
+		applyMigration(child, segments[1:], prefix+key+".", migrate)
+	}
+}
+
+// schemaVersion reads a version value as parsed from YAML, JSON, or TOML
+func schemaVersion(value any) (int, error) {
+	var version int
+	switch v := value.(type) {
+	case nil:
+		return 1, nil
+	case int:
+		version = v
+	case int64:
+		version = int(v)
+	case uint64:
+		version = int(v)
+	case float64:
+		if v != float64(int(v)) {
+			return 0, fmt.Errorf("version must be an integer, got %v", v)
+		}
+		version = int(v)
+	default:
+		return 0, fmt.Errorf("version must be an integer, got %T", value)
+	}
+	if version < 1 {
+		return 0, fmt.Errorf("version must be at least 1, got %d", version)
+	}
+	return version, nil
+}
+
+// MigrateFile rewrites a local config file to the current schema version
+//
+// Only the file itself is migrated; files it includes are left alone and can
+// be migrated separately. The result is encoded in the file's format with the
+// version key set to SchemaVersion. Comments and key order are not preserved.
+// Returns the migrated content and the deprecated fields that were rewritten.
+func MigrateFile(path string) ([]byte, []Deprecation, error) {
+	if isRemoteLocation(path) {
+		return nil, nil, fmt.Errorf("cannot migrate remote config %s; migrate a local copy", redactLocation(path))
+	}
+
+	parser, err := getParserForFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	data, err := parser.Unmarshal(content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	deprecations, err := migrateConfig(data, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	data[versionKey] = SchemaVersion
+
+	out, err := parser.Marshal(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode config file %s: %w", path, err)
+	}
+	return out, deprecations, nil
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/knadh/koanf/parsers/yaml"
+)
+
+const deprecatedIssuerConfig = `
+issuers:
+  - token_type: "urn:ietf:params:oauth:token-type:txn_token"
+    type: unsigned
+    include_request_context: true
+    size_budget:
+      max_bytes: 2048
+      compaction:
+        - claim: req_ctx
+          action: drop
+        - claim: tctx.groups
+          action: reference
+          datasource: user_groups
+`
+
+func TestNewLoader_MigratesDeprecatedFields(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{"parsec.yaml": deprecatedIssuerConfig})
+	path := filepath.Join(dir, "parsec.yaml")
+
+	loader, err := NewLoader(path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg, err := loader.Get()
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+
+	if got := cfg.Issuers[0].SizeBudget.Compaction[1].DataSource; got != "user_groups" {
+		t.Errorf("expected migrated data_source user_groups, got %q", got)
+	}
+
+	deprecations := loader.Deprecations()
+	if len(deprecations) != 2 {
+		t.Fatalf("expected 2 deprecations, got %v", deprecations)
+	}
+	if d := deprecations[0]; d.Path != "issuers[0].size_budget.compaction[1].datasource" ||
+		d.Replacement != "issuers[0].size_budget.compaction[1].data_source" || d.Source != path {
+		t.Errorf("unexpected rename deprecation: %+v", d)
+	}
+	if d := deprecations[1]; d.Path != "issuers[0].include_request_context" || d.Replacement != "" {
+		t.Errorf("unexpected removal deprecation: %+v", d)
+	}
+	if _, ok := loader.Redacted()[versionKey]; ok {
+		t.Error("expected version key to be removed from the merged config")
+	}
+}
+
+func TestNewLoader_CurrentVersionSkipsMigrations(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"parsec.yaml": "version: 2\n" + deprecatedIssuerConfig,
+	})
+
+	loader, err := NewLoader(filepath.Join(dir, "parsec.yaml"))
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if deprecations := loader.Deprecations(); len(deprecations) != 0 {
+		t.Errorf("expected no deprecations for a current config, got %v", deprecations)
+	}
+}
+
+func TestNewLoader_RejectsNewerVersion(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{"parsec.yaml": "version: 99\ntrust_domain: example.com\n"})
+
+	_, err := NewLoader(filepath.Join(dir, "parsec.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "newer than supported") {
+		t.Fatalf("expected newer version error, got %v", err)
+	}
+}
+
+func TestMigrateConfig_KeepsReplacementWhenBothSet(t *testing.T) {
+	data := map[string]any{
+		"issuers": []any{map[string]any{
+			"size_budget": map[string]any{
+				"compaction": []any{map[string]any{"datasource": "old", "data_source": "new"}},
+			},
+		}},
+	}
+
+	deprecations, err := migrateConfig(data, "parsec.yaml")
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	step := data["issuers"].([]any)[0].(map[string]any)["size_budget"].(map[string]any)["compaction"].([]any)[0].(map[string]any)
+	if step["data_source"] != "new" {
+		t.Errorf("expected data_source to be kept, got %v", step["data_source"])
+	}
+	if _, ok := step["datasource"]; ok {
+		t.Error("expected deprecated datasource to be removed")
+	}
+	if len(deprecations) != 1 || !strings.Contains(deprecations[0].Note, "ignored") {
+		t.Errorf("expected ignored deprecation, got %v", deprecations)
+	}
+}
+
+func TestNewLoader_MigratesActorRules(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{"parsec.yaml": `
+exchange_server:
+  claims_filter:
+    actor_rules:
+      "edge-*": [method, path]
+      edge-admin: [method, path, ip_address]
+      "*": [purpose]
+`})
+
+	loader, err := NewLoader(filepath.Join(dir, "parsec.yaml"))
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg, err := loader.Get()
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+
+	filterCfg := cfg.ExchangeServer.ClaimsFilter
+	if filterCfg.Type != "rules" {
+		t.Errorf("expected rules claims filter, got %q", filterCfg.Type)
+	}
+	var subjects []string
+	for _, rule := range filterCfg.Rules {
+		subjects = append(subjects, rule.Subject)
+		if rule.Type != "allowlist" {
+			t.Errorf("expected allowlist rule for %s, got %q", rule.Subject, rule.Type)
+		}
+	}
+	if want := []string{"edge-admin", "edge-*", "*"}; !slices.Equal(subjects, want) {
+		t.Errorf("expected rules for %v, got %v", want, subjects)
+	}
+	if got := filterCfg.Rules[0].AllowedClaims; !slices.Equal(got, []string{"method", "path", "ip_address"}) {
+		t.Errorf("expected edge-admin claims, got %v", got)
+	}
+
+	deprecations := loader.Deprecations()
+	if len(deprecations) != 1 || deprecations[0].Path != "exchange_server.claims_filter.actor_rules" ||
+		deprecations[0].Replacement != "exchange_server.claims_filter.rules" {
+		t.Errorf("unexpected deprecations: %v", deprecations)
+	}
+	if _, err := NewClaimsFilterRegistry(filterCfg); err != nil {
+		t.Errorf("expected migrated rules to build a registry: %v", err)
+	}
+}
+
+func TestMigrateConfig_InvalidActorRules(t *testing.T) {
+	data := map[string]any{
+		"exchange_server": map[string]any{
+			"claims_filter": map[string]any{"actor_rules": []any{"edge-*"}},
+		},
+	}
+	if _, err := migrateConfig(data, "parsec.yaml"); err == nil || !strings.Contains(err.Error(), "actor_rules") {
+		t.Fatalf("expected invalid actor_rules error, got %v", err)
+	}
+}
+
+func TestMigrateFile(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{"parsec.yaml": "include: base.yaml\n" + deprecatedIssuerConfig})
+	path := filepath.Join(dir, "parsec.yaml")
+
+	out, deprecations, err := MigrateFile(path)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if len(deprecations) != 2 {
+		t.Errorf("expected 2 deprecations, got %v", deprecations)
+	}
+
+	data, err := yaml.Parser().Unmarshal(out)
+	if err != nil {
+		t.Fatalf("migrated config is not valid YAML: %v", err)
+	}
+	if version, _ := schemaVersion(data[versionKey]); version != SchemaVersion {
+		t.Errorf("expected version %d, got %v", SchemaVersion, data[versionKey])
+	}
+	if data[includeKey] != "base.yaml" {
+		t.Errorf("expected include to be kept, got %v", data[includeKey])
+	}
+
+	// Migrating an already migrated file changes nothing
+	if err := os.WriteFile(path, out, 0o600); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	_, deprecations, err = MigrateFile(path)
+	if err != nil {
+		t.Fatalf("failed to migrate again: %v", err)
+	}
+	if len(deprecations) != 0 {
+		t.Errorf("expected no deprecations after migration, got %v", deprecations)
+	}
+}
@@ -25,18 +25,68 @@ func NewObserverWithLogger(cfg *ObservabilityConfig, logger *slog.Logger) (servi
 		return &service.NoOpApplicationObserver{}, nil
 	}
 
+	rates, sampled, err := samplingRates(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var observer service.ApplicationObserver
 	switch cfg.Type {
 	case "logging":
-		return probe.NewLoggingObserverWithConfig(probe.LoggingObserverConfig{
+		observer = probe.NewLoggingObserverWithConfig(probe.LoggingObserverConfig{
 			Logger: logger,
-		}), nil
+		})
 	case "noop", "":
 		return &service.NoOpApplicationObserver{}, nil
 	case "composite":
-		return newCompositeObserver(cfg)
+		observer, err = newCompositeObserver(cfg)
+		if err != nil {
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf("unknown observability type: %s (supported: logging, noop, composite)", cfg.Type)
 	}
+
+	if sampled {
+		observer = probe.NewSamplingObserver(observer, rates)
+	}
+	return observer, nil
+}
+
+// samplingRates resolves the sampling rate of each probe, reporting whether
+// any operations are left out
+func samplingRates(cfg *ObservabilityConfig) (probe.SamplingRates, bool, error) {
+	rate := func(event string, eventCfg *EventLoggingConfig) (float64, error) {
+		value := cfg.SampleRate
+		if eventCfg != nil && eventCfg.SampleRate != nil {
+			value = eventCfg.SampleRate
+		}
+		if value == nil {
+			return 1, nil
+		}
+		if *value < 0 || *value > 1 {
+			return 0, fmt.Errorf("invalid sample_rate for %s: %v (must be between 0 and 1)", event, *value)
+		}
+		return *value, nil
+	}
+
+	var rates probe.SamplingRates
+	var err error
+	if rates.TokenIssuance, err = rate("token_issuance", cfg.TokenIssuance); err != nil {
+		return rates, false, err
+	}
+	if rates.TokenExchange, err = rate("token_exchange", cfg.TokenExchange); err != nil {
+		return rates, false, err
+	}
+	if rates.AuthzCheck, err = rate("authz_check", cfg.AuthzCheck); err != nil {
+		return rates, false, err
+	}
+	if rates.Bulkhead, err = rate("bulkhead", nil); err != nil {
+		return rates, false, err
+	}
+
+	sampled := min(rates.TokenIssuance, rates.TokenExchange, rates.AuthzCheck, rates.Bulkhead) < 1
+	return rates, sampled, nil
 }
 
 // NewLogger creates a structured logger from the observability configuration.
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/probe"
+)
+
+func TestSamplingRates(t *testing.T) {
+	half, tenth, all := 0.5, 0.1, 1.0
+
+	rates, sampled, err := samplingRates(&ObservabilityConfig{
+		SampleRate:    &half,
+		TokenIssuance: &EventLoggingConfig{SampleRate: &tenth},
+		AuthzCheck:    &EventLoggingConfig{LogLevel: "warn"},
+	})
+	if err != nil {
+		t.Fatalf("samplingRates failed: %v", err)
+	}
+	want := probe.SamplingRates{TokenIssuance: 0.1, TokenExchange: 0.5, AuthzCheck: 0.5, Bulkhead: 0.5}
+	if rates != want || !sampled {
+		t.Errorf("expected %+v, got %+v (sampled: %v)", want, rates, sampled)
+	}
+
+	if _, sampled, _ := samplingRates(&ObservabilityConfig{SampleRate: &all}); sampled {
+		t.Error("expected no sampling at rate 1")
+	}
+	if _, sampled, _ := samplingRates(&ObservabilityConfig{}); sampled {
+		t.Error("expected no sampling by default")
+	}
+
+	tooHigh := 1.5
+	if _, _, err := samplingRates(&ObservabilityConfig{TokenExchange: &EventLoggingConfig{SampleRate: &tooHigh}}); err == nil {
+		t.Error("expected error for a rate above 1")
+	}
+}
+
+func TestNewObserver_InvalidSampleRate(t *testing.T) {
+	negative := -0.1
+	if _, err := NewObserver(&ObservabilityConfig{Type: "logging", SampleRate: &negative}); err == nil {
+		t.Error("expected error for a negative rate")
+	}
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/datasource"
+)
+
+// lookupSRV resolves DNS SRV records (overridden in tests)
+var lookupSRV = net.DefaultResolver.LookupSRV
+
+// isPeerDiscovery reports whether cfg discovers peers rather than taking the
+// static list
+func isPeerDiscovery(cfg *PeerDiscoveryConfig) bool {
+	return cfg != nil && cfg.Type != "" && cfg.Type != "static"
+}
+
+// validatePeerDiscovery checks a peer discovery configuration without
+// contacting DNS or the cluster
+func validatePeerDiscovery(cfg *PeerDiscoveryConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Scheme != "" && cfg.Scheme != "http" && cfg.Scheme != "https" {
+		return fmt.Errorf("unsupported peer scheme %q (supported: http, https)", cfg.Scheme)
+	}
+	if _, err := parseOptionalDuration(cfg.RefreshInterval); err != nil {
+		return fmt.Errorf("invalid refresh_interval: %w", err)
+	}
+
+	switch cfg.Type {
+	case "static", "":
+		return nil
+	case "dns_srv":
+		if cfg.Name == "" {
+			return fmt.Errorf("name is required for dns_srv peer discovery")
+		}
+		return nil
+	case "kubernetes":
+		if cfg.Service == "" {
+			return fmt.Errorf("service is required for kubernetes peer discovery")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown peer discovery type: %s (supported: static, dns_srv, kubernetes)", cfg.Type)
+	}
+}
+
+// newPeerDiscovery creates the configured peer discovery, or returns nil for a
+// static list of peers
+func newPeerDiscovery(cfg *PeerDiscoveryConfig) (datasource.PeerDiscovery, time.Duration, error) {
+	if err := validatePeerDiscovery(cfg); err != nil {
+		return nil, 0, err
+	}
+	if !isPeerDiscovery(cfg) {
+		return nil, 0, nil
+	}
+
+	refreshInterval, _ := parseOptionalDuration(cfg.RefreshInterval)
+	scheme := cfg.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	if cfg.Type == "dns_srv" {
+		return &dnsSRVPeers{name: cfg.Name, scheme: scheme}, refreshInterval, nil
+	}
+
+	namespace := cfg.Namespace
+	if namespace == "" {
+		var err error
+		if namespace, err = serviceAccountNamespace(); err != nil {
+			return nil, 0, fmt.Errorf("failed to determine namespace for service %s: %w", cfg.Service, err)
+		}
+	}
+	api, err := newKubernetesAPI("kubernetes peer discovery")
+	if err != nil {
+		return nil, 0, err
+	}
+	return &kubernetesPeers{
+		namespace: namespace,
+		service:   cfg.Service,
+		port:      cfg.Port,
+		scheme:    scheme,
+		api:       api,
+	}, refreshInterval, nil
+}
+
+// dnsSRVPeers finds peers as the targets of a DNS SRV record
+type dnsSRVPeers struct {
+	name   string
+	scheme string
+}
+
+func (d *dnsSRVPeers) Peers(ctx context.Context) ([]string, error) {
+	_, records, err := lookupSRV(ctx, "", "", d.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up SRV record %s: %w", d.name, err)
+	}
+
+	peers := make([]string, 0, len(records))
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Target, ".")
+		peers = append(peers, d.scheme+"://"+net.JoinHostPort(host, strconv.Itoa(int(record.Port))))
+	}
+	return peers, nil
+}
+
+// kubernetesPeers finds peers as the ready endpoints of a Kubernetes Service
+type kubernetesPeers struct {
+	namespace string
+	service   string
+	port      string
+	scheme    string
+	api       *kubernetesAPI
+}
+
+// endpointSliceList is the part of a discovery.k8s.io/v1 EndpointSliceList
+// needed to find peers
+type endpointSliceList struct {
+	Items []struct {
+		Ports []struct {
+			Name string `json:"name"`
+			Port int    `json:"port"`
+		} `json:"ports"`
+		Endpoints []struct {
+			Addresses  []string `json:"addresses"`
+			Conditions struct {
+				// Ready is unset when the readiness is unknown, which is
+				// treated as ready
+				Ready *bool `json:"ready"`
+			} `json:"conditions"`
+		} `json:"endpoints"`
+	} `json:"items"`
+}
+
+func (k *kubernetesPeers) Peers(ctx context.Context) ([]string, error) {
+	query := url.Values{"labelSelector": {"kubernetes.io/service-name=" + k.service}}
+	body, err := k.api.get(ctx, fmt.Sprintf("/apis/discovery.k8s.io/v1/namespaces/%s/endpointslices?%s",
+		url.PathEscape(k.namespace), query.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list endpoints of service %s/%s: %w", k.namespace, k.service, err)
+	}
+
+	var list endpointSliceList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode endpoint slices: %w", err)
+	}
+
+	var peers []string
+	for _, slice := range list.Items {
+		if len(slice.Endpoints) == 0 {
+			continue
+		}
+		port := 0
+		for _, p := range slice.Ports {
+			if p.Name == k.port || (k.port == "" && len(slice.Ports) == 1) {
+				port = p.Port
+			}
+		}
+		if port == 0 {
+			if k.port == "" {
+				return nil, fmt.Errorf("service %s/%s has several ports, port must name one", k.namespace, k.service)
+			}
+			return nil, fmt.Errorf("service %s/%s has no port named %q", k.namespace, k.service, k.port)
+		}
+
+		for _, endpoint := range slice.Endpoints {
+			if ready := endpoint.Conditions.Ready; ready != nil && !*ready {
+				continue
+			}
+			for _, address := range endpoint.Addresses {
+				peers = append(peers, k.scheme+"://"+net.JoinHostPort(address, strconv.Itoa(port)))
+			}
+		}
+	}
+	return peers, nil
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPeerDiscovery_DNSSRV(t *testing.T) {
+	previous := lookupSRV
+	defer func() { lookupSRV = previous }()
+
+	var gotName string
+	lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		gotName = name
+		return name, []*net.SRV{
+			{Target: "parsec-0.parsec.default.svc.cluster.local.", Port: 8080},
+			{Target: "parsec-1.parsec.default.svc.cluster.local.", Port: 8080},
+		}, nil
+	}
+
+	discovery, _, err := newPeerDiscovery(&PeerDiscoveryConfig{Type: "dns_srv", Name: "_http._tcp.parsec.default.svc.cluster.local"})
+	if err != nil {
+		t.Fatalf("failed to create discovery: %v", err)
+	}
+	peers, err := discovery.Peers(context.Background())
+	if err != nil {
+		t.Fatalf("failed to discover peers: %v", err)
+	}
+
+	want := []string{
+		"http://parsec-0.parsec.default.svc.cluster.local:8080",
+		"http://parsec-1.parsec.default.svc.cluster.local:8080",
+	}
+	if gotName != "_http._tcp.parsec.default.svc.cluster.local" || !slices.Equal(peers, want) {
+		t.Errorf("expected %v from %s, got %v from %s", want, "_http._tcp.parsec.default.svc.cluster.local", peers, gotName)
+	}
+
+	lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		return "", nil, errors.New("no such host")
+	}
+	if _, err := discovery.Peers(context.Background()); err == nil {
+		t.Error("expected lookup failure to be returned")
+	}
+}
+
+func TestPeerDiscovery_Kubernetes(t *testing.T) {
+	ready, notReady := true, false
+	var gotPath, gotSelector, gotAuth string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotSelector = r.URL.Query().Get("labelSelector")
+		gotAuth = r.Header.Get("Authorization")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"items": []any{
+				map[string]any{
+					"ports": []any{
+						map[string]any{"name": "grpc", "port": 9090},
+						map[string]any{"name": "http", "port": 8080},
+					},
+					"endpoints": []any{
+						map[string]any{"addresses": []string{"10.0.0.1"}, "conditions": map[string]any{"ready": ready}},
+						map[string]any{"addresses": []string{"10.0.0.2"}, "conditions": map[string]any{"ready": notReady}},
+						map[string]any{"addresses": []string{"fd00::3"}, "conditions": map[string]any{}},
+					},
+				},
+				map[string]any{"ports": []any{}, "endpoints": []any{}},
+			},
+		})
+	}))
+	defer srv.Close()
+	useFakeCluster(t, srv)
+
+	discovery, _, err := newPeerDiscovery(&PeerDiscoveryConfig{Type: "kubernetes", Service: "parsec", Port: "http"})
+	if err != nil {
+		t.Fatalf("failed to create discovery: %v", err)
+	}
+	peers, err := discovery.Peers(context.Background())
+	if err != nil {
+		t.Fatalf("failed to discover peers: %v", err)
+	}
+
+	if want := []string{"http://10.0.0.1:8080", "http://[fd00::3]:8080"}; !slices.Equal(peers, want) {
+		t.Errorf("expected ready endpoints %v, got %v", want, peers)
+	}
+	if gotPath != "/apis/discovery.k8s.io/v1/namespaces/parsec-system/endpointslices" {
+		t.Errorf("expected endpoint slices in service account namespace, got %s", gotPath)
+	}
+	if gotSelector != "kubernetes.io/service-name=parsec" {
+		t.Errorf("expected slices of the service, got selector %q", gotSelector)
+	}
+	if gotAuth != "Bearer sa-token" {
+		t.Errorf("expected service account token, got %q", gotAuth)
+	}
+
+	// Without a port name, a service with several ports is ambiguous
+	discovery, _, err = newPeerDiscovery(&PeerDiscoveryConfig{Type: "kubernetes", Service: "parsec"})
+	if err != nil {
+		t.Fatalf("failed to create discovery: %v", err)
+	}
+	if _, err := discovery.Peers(context.Background()); err == nil || !strings.Contains(err.Error(), "several ports") {
+		t.Errorf("expected ambiguous port error, got %v", err)
+	}
+}
+
+func TestNewPeerDiscovery_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *PeerDiscoveryConfig
+		wantErr string
+	}{
+		{"unknown type", &PeerDiscoveryConfig{Type: "consul"}, "unknown peer discovery type"},
+		{"dns_srv without name", &PeerDiscoveryConfig{Type: "dns_srv"}, "name is required"},
+		{"kubernetes without service", &PeerDiscoveryConfig{Type: "kubernetes"}, "service is required"},
+		{"bad scheme", &PeerDiscoveryConfig{Type: "dns_srv", Name: "peers", Scheme: "ftp"}, "unsupported peer scheme"},
+		{"bad refresh interval", &PeerDiscoveryConfig{Type: "dns_srv", Name: "peers", RefreshInterval: "often"}, "invalid refresh_interval"},
+		{"kubernetes outside a cluster", &PeerDiscoveryConfig{Type: "kubernetes", Service: "parsec", Namespace: "default"}, "require running in a Kubernetes cluster"},
+	}
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := newPeerDiscovery(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
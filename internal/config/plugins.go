@@ -0,0 +1,92 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/project-kessel/parsec/pkg/plugin"
+)
+
+// Plugins are the configured out-of-process plugins, by name
+// Plugins started from a command run until Stop.
+type Plugins struct {
+	clients map[string]*plugin.Client
+}
+
+// NewPlugins creates clients for the configured plugins without starting them
+// A plugin that isn't started by Start is started by its first call.
+func NewPlugins(cfgs []PluginConfig) (*Plugins, error) {
+	plugins := &Plugins{clients: make(map[string]*plugin.Client, len(cfgs))}
+	for _, cfg := range cfgs {
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("plugin name is required")
+		}
+		if _, exists := plugins.clients[cfg.Name]; exists {
+			return nil, fmt.Errorf("duplicate plugin name: %s", cfg.Name)
+		}
+
+		var startTimeout time.Duration
+		if cfg.StartTimeout != "" {
+			var err error
+			startTimeout, err = time.ParseDuration(cfg.StartTimeout)
+			if err != nil {
+				return nil, fmt.Errorf("plugin %s has invalid start_timeout: %w", cfg.Name, err)
+			}
+		}
+		client, err := plugin.NewClient(plugin.ClientConfig{
+			Command:      cfg.Command,
+			Args:         cfg.Args,
+			Env:          cfg.Env,
+			Address:      cfg.Address,
+			StartTimeout: startTimeout,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", cfg.Name, err)
+		}
+		plugins.clients[cfg.Name] = client
+	}
+	return plugins, nil
+}
+
+// Get returns the plugin with the name
+func (p *Plugins) Get(name string) (*plugin.Client, error) {
+	if p == nil {
+		return nil, fmt.Errorf("unknown plugin: %s", name)
+	}
+	client, ok := p.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown plugin: %s", name)
+	}
+	return client, nil
+}
+
+// Start starts all plugins and checks that they are healthy, so a broken
+// plugin fails startup rather than the first request that uses it
+func (p *Plugins) Start(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	var errs []error
+	for name, client := range p.clients {
+		if err := client.Start(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Stop stops the plugins parsec started
+func (p *Plugins) Stop(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	var errs []error
+	for name, client := range p.clients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
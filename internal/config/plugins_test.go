@@ -0,0 +1,143 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/datasource"
+	"github.com/project-kessel/parsec/internal/issuer"
+)
+
+func TestNewPlugins(t *testing.T) {
+	plugins, err := NewPlugins([]PluginConfig{
+		{Name: "custom", Command: "/usr/local/bin/custom-issuer", Args: []string{"--verbose"}, StartTimeout: "30s"},
+		{Name: "sidecar", Address: "localhost:9000"},
+	})
+	if err != nil {
+		t.Fatalf("NewPlugins failed: %v", err)
+	}
+	for _, name := range []string{"custom", "sidecar"} {
+		if _, err := plugins.Get(name); err != nil {
+			t.Errorf("expected plugin %s: %v", name, err)
+		}
+	}
+	if _, err := plugins.Get("other"); err == nil {
+		t.Error("expected error for unknown plugin")
+	}
+	if err := plugins.Stop(context.Background()); err != nil {
+		t.Errorf("Stop failed: %v", err)
+	}
+
+	for name, cfgs := range map[string][]PluginConfig{
+		"missing name":          {{Command: "plugin"}},
+		"duplicate name":        {{Name: "p", Command: "plugin"}, {Name: "p", Address: "localhost:9000"}},
+		"no command or address": {{Name: "p"}},
+		"command and address":   {{Name: "p", Command: "plugin", Address: "localhost:9000"}},
+		"invalid start_timeout": {{Name: "p", Command: "plugin", StartTimeout: "soon"}},
+	} {
+		if _, err := NewPlugins(cfgs); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestNewIssuer_Plugin(t *testing.T) {
+	plugins, err := NewPlugins([]PluginConfig{{Name: "custom", Address: "localhost:9000"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iss, err := newIssuer(IssuerConfig{
+		TokenType:    "urn:example:token-type:custom",
+		Type:         "plugin",
+		Plugin:       "custom",
+		ClaimMappers: []ClaimMapperConfig{{Type: "passthrough"}},
+	}, nil, plugins, nil, nil)
+	if err != nil {
+		t.Fatalf("newIssuer failed: %v", err)
+	}
+	if _, ok := iss.(*issuer.PluginIssuer); !ok {
+		t.Errorf("expected a plugin issuer, got %T", iss)
+	}
+
+	for name, cfg := range map[string]IssuerConfig{
+		"missing plugin": {TokenType: "urn:example:token-type:custom", Type: "plugin"},
+		"unknown plugin": {TokenType: "urn:example:token-type:custom", Type: "plugin", Plugin: "other"},
+	} {
+		if _, err := newIssuer(cfg, nil, plugins, nil, nil); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestNewDataSource_Plugin(t *testing.T) {
+	plugins, err := NewPlugins([]PluginConfig{{Name: "enrichment", Address: "localhost:9000"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := newDataSource(DataSourceConfig{
+		Name:          "roles",
+		Type:          "plugin",
+		Plugin:        "enrichment",
+		PluginTimeout: "2s",
+	}, plugins, nil, nil)
+	if err != nil {
+		t.Fatalf("newDataSource failed: %v", err)
+	}
+	if _, ok := ds.(*datasource.PluginDataSource); !ok || ds.Name() != "roles" {
+		t.Errorf("expected a plugin data source named roles, got %T", ds)
+	}
+
+	for name, cfg := range map[string]DataSourceConfig{
+		"missing plugin":         {Name: "roles", Type: "plugin"},
+		"unknown plugin":         {Name: "roles", Type: "plugin", Plugin: "other"},
+		"invalid plugin_timeout": {Name: "roles", Type: "plugin", Plugin: "enrichment", PluginTimeout: "soon"},
+	} {
+		if _, err := newDataSource(cfg, plugins, nil, nil); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestValidate_Plugins(t *testing.T) {
+	cfg := &Config{
+		TrustDomain: "parsec.test",
+		TrustStore:  TrustStoreConfig{Type: "stub_store"},
+		Plugins: []PluginConfig{
+			{Name: "custom", Command: "/usr/local/bin/custom-issuer"},
+			{Name: "custom", Address: "localhost:9000"},
+			{Name: "broken"},
+		},
+		DataSources: []DataSourceConfig{
+			{Name: "roles", Type: "plugin", Plugin: "custom"},
+			{Name: "groups", Type: "plugin", Plugin: "broken"},
+		},
+		Issuers: []IssuerConfig{
+			{TokenType: "urn:example:token-type:custom", Type: "plugin", Plugin: "custom"},
+			{TokenType: "urn:example:token-type:other", Type: "plugin", Plugin: "missing"},
+		},
+	}
+
+	err := Validate(cfg, nil)
+
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	var paths []string
+	for _, e := range validationErrs {
+		paths = append(paths, e.Path)
+	}
+	want := []string{"plugins[1].name", "plugins[2]", "data_sources[1]", "issuers[1]"}
+	if len(paths) != len(want) {
+		t.Fatalf("expected errors at %v, got %v", want, validationErrs)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("expected errors at %v, got %v", want, paths)
+			break
+		}
+	}
+}
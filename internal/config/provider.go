@@ -1,13 +1,23 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"net/netip"
+	"time"
 
+	"github.com/project-kessel/parsec/internal/clients"
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/datasource"
 	"github.com/project-kessel/parsec/internal/httpfixture"
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/ledger"
 	"github.com/project-kessel/parsec/internal/server"
 	"github.com/project-kessel/parsec/internal/service"
 	"github.com/project-kessel/parsec/internal/trust"
+	"github.com/project-kessel/parsec/internal/trustdomain"
 )
 
 // Provider constructs all application components from configuration
@@ -18,11 +28,17 @@ type Provider struct {
 	// Lazily constructed components (cached after first call)
 	trustStore           trust.Store
 	dataSourceRegistry   *service.DataSourceRegistry
+	clientRegistry       clients.Registry
+	clientPolicy         *server.ClientPolicy
 	issuerRegistry       service.Registry
+	signerRegistry       *keys.SignerRegistry
+	plugins              *Plugins
 	claimsFilterRegistry server.ClaimsFilterRegistry
 	tokenService         *service.TokenService
+	tokenLedger          *ledger.Ledger
 	httpFixtureProvider  httpfixture.FixtureProvider
 	httpFixtureBuilt     bool
+	clock                clock.Clock
 	observer             service.ApplicationObserver
 }
 
@@ -63,8 +79,20 @@ func (p *Provider) TrustStore() (trust.Store, error) {
 		return p.trustStore, nil
 	}
 
+	clk, err := p.Clock()
+	if err != nil {
+		return nil, err
+	}
+
 	transport := p.HTTPTransport()
-	store, err := NewTrustStore(p.config.TrustStore, transport)
+	// txn_token_validator verifies against parsec's own issuers, built on first use
+	keys := newIssuerKeySource(p.IssuerRegistry)
+	// client_secret_validator looks up clients, possibly in data sources built on first use
+	var clientRegistry clients.Registry
+	if p.config.Clients != nil {
+		clientRegistry = &lazyClientRegistry{registry: p.ClientRegistry}
+	}
+	store, err := NewTrustStore(p.config.TrustStore, transport, clk, keys, clientRegistry)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create trust store: %w", err)
 	}
@@ -73,14 +101,40 @@ func (p *Provider) TrustStore() (trust.Store, error) {
 	return store, nil
 }
 
+// StartTrustStoreWarmup starts fetching the JWKS of the trust store's
+// validators in the background, in parallel and with retries
+// Validators don't fetch their JWKS when they are created, so callers wait
+// for the warmup before validating.
+func (p *Provider) StartTrustStoreWarmup(ctx context.Context) (*trust.Warmup, error) {
+	store, err := p.TrustStore()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := newWarmupConfig(p.config.TrustStore.Warmup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trust store warmup: %w", err)
+	}
+	return trust.StartWarmup(ctx, cfg, trust.Warmers(store)), nil
+}
+
 // DataSourceRegistry returns the configured data source registry
 func (p *Provider) DataSourceRegistry() (*service.DataSourceRegistry, error) {
 	if p.dataSourceRegistry != nil {
 		return p.dataSourceRegistry, nil
 	}
 
+	observer, err := p.Observer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get observer: %w", err)
+	}
+
+	plugins, err := p.Plugins()
+	if err != nil {
+		return nil, err
+	}
+
 	transport := p.HTTPTransport()
-	registry, err := NewDataSourceRegistry(p.config.DataSources, transport)
+	registry, err := NewDataSourceRegistry(p.config.DataSources, plugins, transport, observer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create data source registry: %w", err)
 	}
@@ -89,13 +143,118 @@ func (p *Provider) DataSourceRegistry() (*service.DataSourceRegistry, error) {
 	return registry, nil
 }
 
+// ClientRegistry returns the configured client registry, or nil if no
+// clients are configured
+func (p *Provider) ClientRegistry() (clients.Registry, error) {
+	if p.clientRegistry != nil || p.config.Clients == nil {
+		return p.clientRegistry, nil
+	}
+
+	var dataSources *service.DataSourceRegistry
+	if p.config.Clients.DataSource != "" {
+		var err error
+		dataSources, err = p.DataSourceRegistry()
+		if err != nil {
+			return nil, err
+		}
+	}
+	registry, err := NewClientRegistry(p.config.Clients, dataSources)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client registry: %w", err)
+	}
+
+	p.clientRegistry = registry
+	return registry, nil
+}
+
+// ClientPolicy returns the policy applying the registered clients' policies
+// to the servers, or nil if no clients are configured
+// Both servers share it, so a client's quota counts the tokens of both.
+func (p *Provider) ClientPolicy() (*server.ClientPolicy, error) {
+	if p.clientPolicy != nil {
+		return p.clientPolicy, nil
+	}
+
+	registry, err := p.ClientRegistry()
+	if err != nil || registry == nil {
+		return nil, err
+	}
+	clk, err := p.Clock()
+	if err != nil {
+		return nil, err
+	}
+
+	p.clientPolicy = NewClientPolicy(registry, clk)
+	return p.clientPolicy, nil
+}
+
+// TokenLedger returns the token ledger
+// Returns nil if issued tokens are not recorded
+func (p *Provider) TokenLedger() (*ledger.Ledger, error) {
+	if p.tokenLedger != nil || p.config.TokenLedger == nil {
+		return p.tokenLedger, nil
+	}
+
+	clk, err := p.Clock()
+	if err != nil {
+		return nil, err
+	}
+	l, err := NewTokenLedger(p.config.TokenLedger, clk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token ledger: %w", err)
+	}
+
+	p.tokenLedger = l
+	return l, nil
+}
+
+// DistributedCachePeers returns the data source cache peers and the path their
+// requests must be served at, or nil peers if no distributed cache is
+// configured
+func (p *Provider) DistributedCachePeers(logger *slog.Logger) (*datasource.GroupcachePeers, string, error) {
+	clk, err := p.Clock()
+	if err != nil {
+		return nil, "", err
+	}
+	peers, err := NewDistributedCachePeers(p.config.DistributedCache, clk, logger)
+	if err != nil || peers == nil {
+		return nil, "", err
+	}
+	basePath := p.config.DistributedCache.BasePath
+	if basePath == "" {
+		basePath = "/_groupcache/"
+	}
+	return peers, basePath, nil
+}
+
 // IssuerRegistry returns the configured issuer registry
 func (p *Provider) IssuerRegistry() (service.Registry, error) {
 	if p.issuerRegistry != nil {
 		return p.issuerRegistry, nil
 	}
 
-	registry, err := NewIssuerRegistry(*p.config)
+	clk, err := p.Clock()
+	if err != nil {
+		return nil, err
+	}
+
+	observer, err := p.Observer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get observer: %w", err)
+	}
+
+	signerRegistry, err := p.SignerRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	plugins, err := p.Plugins()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := p.HTTPTransport()
+	registry, err := newIssuerRegistry(*p.config, signerRegistry, plugins, transport, clk, observer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create issuer registry: %w", err)
 	}
@@ -104,6 +263,46 @@ func (p *Provider) IssuerRegistry() (service.Registry, error) {
 	return registry, nil
 }
 
+// Plugins returns the configured plugins, not yet started
+// Callers stop the plugins when done with them.
+func (p *Provider) Plugins() (*Plugins, error) {
+	if p.plugins != nil {
+		return p.plugins, nil
+	}
+
+	plugins, err := NewPlugins(p.config.Plugins)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plugins: %w", err)
+	}
+
+	p.plugins = plugins
+	return plugins, nil
+}
+
+// SignerRegistry returns the configured signers, started
+// Callers own their rotation and stop the registry when done with it.
+func (p *Provider) SignerRegistry() (*keys.SignerRegistry, error) {
+	if p.signerRegistry != nil {
+		return p.signerRegistry, nil
+	}
+
+	clk, err := p.Clock()
+	if err != nil {
+		return nil, err
+	}
+
+	registry, err := NewSignerRegistry(*p.config, clk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signer registry: %w", err)
+	}
+	if err := registry.Start(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to start signers: %w", err)
+	}
+
+	p.signerRegistry = registry
+	return registry, nil
+}
+
 // ExchangeServerClaimsFilterRegistry returns the claims filter registry for the exchange server
 func (p *Provider) ExchangeServerClaimsFilterRegistry() (server.ClaimsFilterRegistry, error) {
 	if p.claimsFilterRegistry != nil {
@@ -125,6 +324,277 @@ func (p *Provider) ExchangeServerClaimsFilterRegistry() (server.ClaimsFilterRegi
 	return registry, nil
 }
 
+// ExchangeServerDelegationPolicy returns the delegation policy for the exchange server
+func (p *Provider) ExchangeServerDelegationPolicy() (trust.DelegationPolicy, error) {
+	var delegationCfg *DelegationConfig
+	if p.config.ExchangeServer != nil {
+		delegationCfg = p.config.ExchangeServer.Delegation
+	}
+
+	policy, err := NewDelegationPolicy(delegationCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create delegation policy: %w", err)
+	}
+
+	return policy, nil
+}
+
+// ExchangeServerSelfIssuancePolicy returns the policy gating the client_credentials
+// grant, or nil if the grant is disabled
+func (p *Provider) ExchangeServerSelfIssuancePolicy() (trust.SelfIssuancePolicy, error) {
+	if p.config.ExchangeServer == nil {
+		return nil, nil
+	}
+
+	policy, err := NewSelfIssuancePolicy(p.config.ExchangeServer.ClientCredentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client_credentials policy: %w", err)
+	}
+
+	return policy, nil
+}
+
+// ExchangeServerRequestContextSchemas returns the request_context schema registry for the exchange server
+// Returns nil if no schemas are configured
+func (p *Provider) ExchangeServerRequestContextSchemas() (server.RequestContextSchemaRegistry, error) {
+	if p.config.ExchangeServer == nil {
+		return nil, nil
+	}
+
+	registry, err := NewRequestContextSchemaRegistry(p.config.ExchangeServer.RequestContextSchemas)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request_context schema registry: %w", err)
+	}
+
+	return registry, nil
+}
+
+// ActorCredentialExtractor returns the actor credential extractor shared by
+// the ext_authz and token exchange servers
+func (p *Provider) ActorCredentialExtractor() (server.ActorCredentialExtractor, error) {
+	extractor, err := NewActorCredentialExtractor(p.config.Server.ActorCredentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create actor credential extractor: %w", err)
+	}
+	return extractor, nil
+}
+
+// TrustedProxies returns the proxies whose x-forwarded-for entries the
+// exchange server trusts
+func (p *Provider) TrustedProxies() ([]netip.Prefix, error) {
+	return NewTrustedProxies(p.config.Server.TrustedProxies)
+}
+
+// SubjectCredentialExtractor returns the ext_authz subject credential extractor
+func (p *Provider) SubjectCredentialExtractor() (server.SubjectCredentialExtractor, error) {
+	var cfgs []SubjectCredentialSourceConfig
+	if p.config.AuthzServer != nil {
+		cfgs = p.config.AuthzServer.SubjectCredentials
+	}
+	extractor, err := NewSubjectCredentialExtractor(cfgs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subject credential extractor: %w", err)
+	}
+	return extractor, nil
+}
+
+// AuthzServerHeaderSizeLimit returns the limit on tokens emitted by ext_authz
+// Returns nil if no limit is configured.
+func (p *Provider) AuthzServerHeaderSizeLimit() (*server.HeaderSizeLimit, error) {
+	if p.config.AuthzServer == nil || p.config.AuthzServer.HeaderSizeLimit == nil {
+		return nil, nil
+	}
+	return NewHeaderSizeLimit(p.config.AuthzServer.HeaderSizeLimit, p.config.Issuers)
+}
+
+// AuthzServerDenyCache returns the ext_authz cache of validation failures
+// Returns nil if it isn't configured.
+func (p *Provider) AuthzServerDenyCache() (*server.DenyCache, error) {
+	if p.config.AuthzServer == nil {
+		return nil, nil
+	}
+	return NewDenyCache(p.config.AuthzServer.DenyCache)
+}
+
+// AuthzServerRequestAttributeSources returns the extra request attributes
+// ext_authz derives from checked requests
+func (p *Provider) AuthzServerRequestAttributeSources() ([]server.RequestAttributeSource, error) {
+	if p.config.AuthzServer == nil {
+		return nil, nil
+	}
+	return NewRequestAttributeSources(p.config.AuthzServer.RequestAttributes)
+}
+
+// AuthzServerDynamicMetadata returns the identity attributes ext_authz sets
+// in Envoy dynamic metadata
+// Returns nil if none are configured.
+func (p *Provider) AuthzServerDynamicMetadata() (*server.DynamicMetadata, error) {
+	if p.config.AuthzServer == nil {
+		return nil, nil
+	}
+	return NewDynamicMetadata(p.config.AuthzServer.DynamicMetadata)
+}
+
+// AuthzServerIPPolicy returns the ext_authz client address policy
+// Returns nil if it isn't configured.
+func (p *Provider) AuthzServerIPPolicy() (*server.IPPolicy, error) {
+	if p.config.AuthzServer == nil {
+		return nil, nil
+	}
+	return NewIPPolicy(p.config.AuthzServer.IPPolicy)
+}
+
+// AuthzServerBodyHash returns the ext_authz hashing of request bodies
+// Returns nil if it isn't configured.
+func (p *Provider) AuthzServerBodyHash() (*server.BodyHash, error) {
+	if p.config.AuthzServer == nil {
+		return nil, nil
+	}
+	return NewBodyHash(p.config.AuthzServer.BodyHash)
+}
+
+// AuthzServerSkipPaths returns the paths ext_authz allows without validation
+func (p *Provider) AuthzServerSkipPaths() ([]server.SkipPath, error) {
+	if p.config.AuthzServer == nil {
+		return nil, nil
+	}
+	return NewSkipPaths(p.config.AuthzServer.SkipPaths)
+}
+
+// AuthzServerResponseHeaders returns the headers ext_authz derives with claim mappers
+func (p *Provider) AuthzServerResponseHeaders() ([]server.ResponseHeaderSpec, error) {
+	if p.config.AuthzServer == nil {
+		return nil, nil
+	}
+	return NewResponseHeaders(p.config.AuthzServer.ResponseHeaders)
+}
+
+// ExchangeServerRejectedClaimsWarning reports whether the exchange server
+// should return the names of rejected request_context claims to callers
+func (p *Provider) ExchangeServerRejectedClaimsWarning() bool {
+	return p.config.ExchangeServer != nil && p.config.ExchangeServer.RejectedClaimsWarning
+}
+
+// ExchangeServerAllowedAudiences returns the audiences tokens may be requested
+// for besides the trust domain
+func (p *Provider) ExchangeServerAllowedAudiences() []string {
+	if p.config.ExchangeServer == nil {
+		return nil
+	}
+	return p.config.ExchangeServer.AllowedAudiences
+}
+
+// IntrospectionServer returns the token introspection server
+// Returns nil if introspection is not enabled
+func (p *Provider) IntrospectionServer(logger *slog.Logger) (*server.IntrospectionServer, error) {
+	cfg := p.config.IntrospectionServer
+	if cfg == nil || !cfg.Enabled {
+		return nil, nil
+	}
+
+	issuerRegistry, err := p.IssuerRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	var trustStore trust.Store
+	if !cfg.AllowUnauthenticated {
+		trustStore, err = p.TrustStore()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	allowedCallers, err := newAllowedCallers(cfg.AllowUnauthenticated, cfg.AllowedCallers)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.NewIntrospectionServer(server.IntrospectionServerConfig{
+		IssuerRegistry: issuerRegistry,
+		TrustStore:     trustStore,
+		AllowedCallers: allowedCallers,
+		Logger:         logger,
+	}), nil
+}
+
+// AdminServer returns the admin server, forwarding cache invalidations to peers
+// (optional) and refreshing jwks (optional) when signing keys change
+// Returns nil if the admin endpoints are not enabled
+func (p *Provider) AdminServer(logger *slog.Logger, peers server.PeerList, jwks *server.JWKSServer) (*server.AdminServer, error) {
+	cfg := p.config.AdminServer
+	if cfg == nil || !cfg.Enabled {
+		return nil, nil
+	}
+
+	dataSourceRegistry, err := p.DataSourceRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	issuerRegistry, err := p.IssuerRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	signerRegistry, err := p.SignerRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	var trustStore trust.Store
+	if !cfg.AllowUnauthenticated {
+		trustStore, err = p.TrustStore()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	allowedCallers, err := newAllowedCallers(cfg.AllowUnauthenticated, cfg.AllowedCallers)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenLedger, err := p.TokenLedger()
+	if err != nil {
+		return nil, err
+	}
+
+	return server.NewAdminServer(server.AdminServerConfig{
+		DataSourceRegistry: dataSourceRegistry,
+		IssuerRegistry:     issuerRegistry,
+		Signers:            signerRegistry,
+		JWKSServer:         jwks,
+		Ledger:             tokenLedger,
+		Peers:              peers,
+		TrustStore:         trustStore,
+		AllowedCallers:     allowedCallers,
+		Logger:             logger,
+	}), nil
+}
+
+// newAllowedCallers converts the callers allowed to use an endpoint
+// Authenticated endpoints need at least one, or every caller is refused
+func newAllowedCallers(allowUnauthenticated bool, cfg []AllowedCallerConfig) ([]server.AllowedCaller, error) {
+	if !allowUnauthenticated && len(cfg) == 0 {
+		return nil, fmt.Errorf("allowed_callers is required unless allow_unauthenticated is set")
+	}
+	callers := make([]server.AllowedCaller, 0, len(cfg))
+	for i, caller := range cfg {
+		if caller.TrustDomain == "" && caller.Subject == "" {
+			return nil, fmt.Errorf("allowed caller %d: trust_domain or subject is required", i)
+		}
+		if err := trustdomain.ValidatePattern(caller.TrustDomain); err != nil {
+			return nil, fmt.Errorf("allowed caller %d: %w", i, err)
+		}
+		callers = append(callers, server.AllowedCaller{
+			TrustDomain: caller.TrustDomain,
+			Subject:     caller.Subject,
+		})
+	}
+	return callers, nil
+}
+
 // TokenService returns the configured token service
 func (p *Provider) TokenService() (*service.TokenService, error) {
 	if p.tokenService != nil {
@@ -148,24 +618,88 @@ func (p *Provider) TokenService() (*service.TokenService, error) {
 		return nil, fmt.Errorf("failed to get observer: %w", err)
 	}
 
+	timeout, err := parseIssuanceTimeout(p.config.IssuanceTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	clk, err := p.Clock()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []service.TokenServiceOption{
+		service.WithIssuanceTimeout(timeout),
+		service.WithClock(clk),
+	}
+	identityMapper, err := NewIdentityMapper(p.config.IdentityMapping, dataSourceRegistry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create identity mapper: %w", err)
+	}
+	if identityMapper != nil {
+		opts = append(opts, service.WithIdentityMapper(identityMapper, p.config.IdentityMapping.Required))
+	}
+	limiter, err := NewIssuanceLimiter(p.config.Quotas, clk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create issuance quotas: %w", err)
+	}
+	if limiter != nil {
+		opts = append(opts, service.WithIssuanceLimiter(limiter))
+	}
+	evaluators, err := NewRiskEvaluators(p.config.Risk, p.HTTPTransport(), clk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create risk evaluators: %w", err)
+	}
+	if len(evaluators) > 0 {
+		opts = append(opts, service.WithRiskEvaluators(p.config.Risk.FailOpen, evaluators...))
+	}
+	audiencePolicy, err := NewAudiencePolicy(p.config.AudiencePolicy, p.config.TrustDomain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audience policy: %w", err)
+	}
+	opts = append(opts, service.WithAudiencePolicy(audiencePolicy))
+
 	// Create token service
 	tokenService := service.NewTokenService(
 		p.config.TrustDomain,
 		dataSourceRegistry,
 		issuerRegistry,
 		observer, // Application observer for observability
+		opts...,
 	)
 
 	p.tokenService = tokenService
 	return tokenService, nil
 }
 
+// defaultIssuanceTimeout bounds token issuance when issuance_timeout is not set
+const defaultIssuanceTimeout = 10 * time.Second
+
+// parseIssuanceTimeout parses issuance_timeout, where zero disables the deadline
+func parseIssuanceTimeout(s string) (time.Duration, error) {
+	if s == "" {
+		return defaultIssuanceTimeout, nil
+	}
+	timeout, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid issuance_timeout: %w", err)
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("invalid issuance_timeout: must not be negative")
+	}
+	return timeout, nil
+}
+
 // ServerConfig returns the server configuration
 func (p *Provider) ServerConfig() server.Config {
-	return server.Config{
+	cfg := server.Config{
 		GRPCPort: p.config.Server.GRPCPort,
 		HTTPPort: p.config.Server.HTTPPort,
 	}
+	if p.config.DistributedCache != nil {
+		cfg.DistributedCachePort = p.config.DistributedCache.Port
+	}
+	return cfg
 }
 
 // TrustDomain returns the configured trust domain
@@ -180,9 +714,25 @@ func (p *Provider) HTTPTransport() http.RoundTripper {
 	if fixtureProvider == nil {
 		return nil
 	}
+	clk, _ := p.Clock() // Clock errors already surfaced by HTTPFixtureProvider
+
+	// When recording, requests no fixture matches reach the real network
+	recorder, err := BuildHTTPRecorder(p.config.Fixtures)
+	if err != nil {
+		panic(fmt.Sprintf("failed to build HTTP recorder: %v", err))
+	}
+	if recorder != nil {
+		return httpfixture.NewTransport(httpfixture.TransportConfig{
+			Provider: fixtureProvider,
+			Fallback: recorder,
+			Clock:    clk,
+		})
+	}
+
 	return httpfixture.NewTransport(httpfixture.TransportConfig{
 		Provider: fixtureProvider,
 		Strict:   true,
+		Clock:    clk,
 	})
 }
 
@@ -193,7 +743,12 @@ func (p *Provider) HTTPFixtureProvider() httpfixture.FixtureProvider {
 		return p.httpFixtureProvider
 	}
 
-	provider, err := BuildHTTPFixtureProvider(p.config.Fixtures, nil)
+	clk, err := p.Clock()
+	if err != nil {
+		panic(fmt.Sprintf("failed to build fixture clock: %v", err))
+	}
+
+	provider, err := BuildHTTPFixtureProvider(p.config.Fixtures, clk)
 	if err != nil {
 		// In production mode, fixture errors should fail fast
 		// This is a configuration error, not a runtime error
@@ -205,6 +760,26 @@ func (p *Provider) HTTPFixtureProvider() httpfixture.FixtureProvider {
 	return p.httpFixtureProvider
 }
 
+// Clock returns the time source shared by all components
+// This is the fixture clock if one is configured (hermetic testing), otherwise the system clock
+func (p *Provider) Clock() (clock.Clock, error) {
+	if p.clock != nil {
+		return p.clock, nil
+	}
+
+	fixtureClock, err := BuildFixtureClock(p.config.Fixtures)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fixture clock: %w", err)
+	}
+
+	if fixtureClock != nil {
+		p.clock = fixtureClock
+	} else {
+		p.clock = clock.NewSystemClock()
+	}
+	return p.clock, nil
+}
+
 // AuthzServerTokenTypes returns the configured token types for ext_authz
 func (p *Provider) AuthzServerTokenTypes() ([]server.TokenTypeSpec, error) {
 	// If no authz server config, return nil (will use defaults)
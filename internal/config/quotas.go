@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/quota"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// NewIssuanceLimiter creates the limiter enforcing the token issuance quotas
+// Returns nil if cfg is nil (issuance is not limited).
+func NewIssuanceLimiter(cfg *QuotasConfig, clk clock.Clock) (*quota.Limiter, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if len(cfg.Rules) == 0 {
+		return nil, fmt.Errorf("quotas require at least one rule")
+	}
+
+	var store quota.CounterStore
+	switch cfg.Store {
+	case "", "memory":
+		store = quota.NewMemoryStore()
+	default:
+		return nil, fmt.Errorf("unknown quota store: %s", cfg.Store)
+	}
+
+	rules := make([]quota.Rule, len(cfg.Rules))
+	for i, ruleCfg := range cfg.Rules {
+		selector, err := quota.NewCELSelector(ruleCfg.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("quota %q: %w", ruleCfg.Name, err)
+		}
+		window, err := time.ParseDuration(ruleCfg.Window)
+		if err != nil {
+			return nil, fmt.Errorf("quota %q: invalid window: %w", ruleCfg.Name, err)
+		}
+		rules[i] = quota.Rule{
+			Name:     ruleCfg.Name,
+			Selector: selector,
+			Limit:    ruleCfg.Limit,
+			Window:   window,
+		}
+		for _, tokenType := range ruleCfg.TokenTypes {
+			rules[i].TokenTypes = append(rules[i].TokenTypes, service.TokenType(tokenType))
+		}
+	}
+
+	return quota.NewLimiter(quota.Config{
+		Rules:    rules,
+		Store:    store,
+		FailOpen: cfg.FailOpen,
+		Clock:    clk,
+	})
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestNewIssuanceLimiter(t *testing.T) {
+	if limiter, err := NewIssuanceLimiter(nil, nil); limiter != nil || err != nil {
+		t.Errorf("expected no limiter without config, got %v (%v)", limiter, err)
+	}
+
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	limiter, err := NewIssuanceLimiter(&QuotasConfig{
+		Rules: []QuotaRuleConfig{{
+			Name:       "per-actor",
+			Selector:   "actor.subject",
+			Limit:      1,
+			Window:     "1m",
+			TokenTypes: []string{string(service.TokenTypeTransactionToken)},
+		}},
+	}, clk)
+	if err != nil {
+		t.Fatalf("NewIssuanceLimiter failed: %v", err)
+	}
+
+	issueCtx := &service.IssueContext{Actor: &trust.Result{Subject: "gateway"}}
+	txn := []service.TokenType{service.TokenTypeTransactionToken}
+	if err := limiter.Admit(context.Background(), issueCtx, txn); err != nil {
+		t.Fatalf("first issuance refused: %v", err)
+	}
+	if err := limiter.Admit(context.Background(), issueCtx, txn); !perr.HasCode(err, perr.ErrCodeRateLimited) {
+		t.Errorf("expected rate_limited, got %v", err)
+	}
+}
+
+func TestNewIssuanceLimiter_Errors(t *testing.T) {
+	rule := QuotaRuleConfig{Name: "q", Selector: "actor.subject", Limit: 1, Window: "1m"}
+	withRule := func(edit func(*QuotaRuleConfig)) *QuotasConfig {
+		r := rule
+		edit(&r)
+		return &QuotasConfig{Rules: []QuotaRuleConfig{r}}
+	}
+
+	for name, cfg := range map[string]*QuotasConfig{
+		"no rules":         {},
+		"unknown store":    {Store: "redis", Rules: []QuotaRuleConfig{rule}},
+		"invalid selector": withRule(func(r *QuotaRuleConfig) { r.Selector = "actor." }),
+		"no selector":      withRule(func(r *QuotaRuleConfig) { r.Selector = "" }),
+		"invalid window":   withRule(func(r *QuotaRuleConfig) { r.Window = "soon" }),
+		"no limit":         withRule(func(r *QuotaRuleConfig) { r.Limit = 0 }),
+		"no name":          withRule(func(r *QuotaRuleConfig) { r.Name = "" }),
+	} {
+		if _, err := NewIssuanceLimiter(cfg, nil); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
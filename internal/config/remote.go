@@ -0,0 +1,381 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+)
+
+// Remote configuration sources
+//
+// Config files, overlays and includes may be given as URLs instead of paths:
+//
+//	https://host/path/parsec.yaml           plain GET (basic auth via userinfo)
+//	http://host/path/parsec.yaml#sha256=hex plain HTTP, only with a pinned checksum
+//	s3://bucket/path/parsec.yaml            SigV4-signed GET using the default AWS credential chain
+//	configmap://[namespace/]name/key.yaml   a key of a Kubernetes ConfigMap, read in-cluster
+//
+// The format is detected from the extension of the URL path (or ConfigMap key).
+// Remote sources are polled for changes; see Loader.Watch. Configuration
+// fetched over plain HTTP could be altered in transit, so http:// sources must
+// pin the SHA-256 checksum of the document in the URL fragment.
+
+// remoteFetchTimeout bounds a single fetch of a remote config source
+const remoteFetchTimeout = 30 * time.Second
+
+// maxRemoteConfigSize bounds the size of a remote config document
+const maxRemoteConfigSize = 10 << 20
+
+// errNotModified is returned by remoteSource.Fetch when the document still
+// matches the given ETag
+var errNotModified = errors.New("config source not modified")
+
+// remoteHTTPClient fetches http(s) config sources (overridden in tests)
+var remoteHTTPClient = &http.Client{}
+
+// checksumFragmentPrefix prefixes the pinned checksum of an http:// source
+const checksumFragmentPrefix = "sha256="
+
+// remoteSource fetches a config document from outside the local filesystem
+type remoteSource interface {
+	// Fetch returns the document and its ETag, if the source provides one.
+	// If etag is set and the document is unchanged, Fetch may return
+	// errNotModified instead.
+	Fetch(ctx context.Context, etag string) (content []byte, newETag string, err error)
+}
+
+// remoteVersion identifies the fetched revision of a remote document, by ETag
+// if the source provides one and by content checksum otherwise
+type remoteVersion struct {
+	etag     string
+	checksum string
+}
+
+func newRemoteVersion(content []byte, etag string) remoteVersion {
+	sum := sha256.Sum256(content)
+	return remoteVersion{etag: etag, checksum: hex.EncodeToString(sum[:])}
+}
+
+// isRemoteLocation reports whether location is a remote config source URL
+func isRemoteLocation(location string) bool {
+	u, err := url.Parse(location)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https", "s3", "configmap":
+		return true
+	default:
+		return false
+	}
+}
+
+// newRemoteSource creates the source for a remote config location
+func newRemoteSource(location string) (remoteSource, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config source %s: %w", location, err)
+	}
+
+	switch u.Scheme {
+	case "https":
+		return &httpConfigSource{url: u, client: remoteHTTPClient}, nil
+	case "http":
+		checksum, ok := strings.CutPrefix(u.Fragment, checksumFragmentPrefix)
+		if !ok || len(checksum) != sha256.Size*2 {
+			return nil, fmt.Errorf("config source %s uses plain http: use https, or pin the document's checksum with #sha256=<hex>", redactLocation(location))
+		}
+		return &httpConfigSource{url: u, client: remoteHTTPClient, checksum: strings.ToLower(checksum)}, nil
+	case "s3":
+		key := strings.TrimPrefix(u.Path, "/")
+		if u.Host == "" || key == "" {
+			return nil, fmt.Errorf("invalid config source %s: expected s3://bucket/key", location)
+		}
+		return &s3ConfigSource{bucket: u.Host, key: key}, nil
+	case "configmap":
+		return newConfigMapSource(u)
+	default:
+		return nil, fmt.Errorf("unsupported config source scheme %q in %s", u.Scheme, location)
+	}
+}
+
+// fetchRemote fetches a remote config location with the default timeout
+func fetchRemote(location string) ([]byte, remoteVersion, error) {
+	src, err := newRemoteSource(location)
+	if err != nil {
+		return nil, remoteVersion{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), remoteFetchTimeout)
+	defer cancel()
+
+	content, etag, err := src.Fetch(ctx, "")
+	if err != nil {
+		return nil, remoteVersion{}, fmt.Errorf("failed to fetch config source %s: %w", redactLocation(location), err)
+	}
+	return content, newRemoteVersion(content, etag), nil
+}
+
+// remoteChanged reports whether a remote location no longer matches version
+func remoteChanged(ctx context.Context, location string, version remoteVersion) (bool, error) {
+	src, err := newRemoteSource(location)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, remoteFetchTimeout)
+	defer cancel()
+
+	content, etag, err := src.Fetch(ctx, version.etag)
+	if errors.Is(err, errNotModified) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch config source %s: %w", redactLocation(location), err)
+	}
+	return newRemoteVersion(content, etag).checksum != version.checksum, nil
+}
+
+// remoteFormatPath returns the path whose extension determines the format of a
+// remote config document
+func remoteFormatPath(location string) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+	return u.Path
+}
+
+// redactLocation removes credentials from a config source URL for messages
+func redactLocation(location string) string {
+	u, err := url.Parse(location)
+	if err != nil || u.User == nil {
+		return location
+	}
+	return u.Redacted()
+}
+
+// readRemoteBody reads a response body of bounded size
+func readRemoteBody(body io.Reader) ([]byte, error) {
+	content, err := io.ReadAll(io.LimitReader(body, maxRemoteConfigSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(content) > maxRemoteConfigSize {
+		return nil, fmt.Errorf("config document exceeds %d bytes", maxRemoteConfigSize)
+	}
+	return content, nil
+}
+
+// httpDoer sends HTTP requests (satisfied by *http.Client and aws.HTTPClient)
+type httpDoer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// doConditionalGet sends a GET, which may carry If-None-Match, and returns the
+// body and ETag
+func doConditionalGet(client httpDoer, req *http.Request) ([]byte, string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, "", errNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	content, err := readRemoteBody(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return content, resp.Header.Get("ETag"), nil
+}
+
+// httpConfigSource reads a config document over HTTP(S)
+// If checksum is set, the document must have that SHA-256 checksum.
+type httpConfigSource struct {
+	url      *url.URL
+	client   *http.Client
+	checksum string
+}
+
+func (s *httpConfigSource) Fetch(ctx context.Context, etag string) ([]byte, string, error) {
+	content, newETag, err := s.fetch(ctx, etag)
+	if err != nil || s.checksum == "" {
+		return content, newETag, err
+	}
+	if sum := sha256.Sum256(content); hex.EncodeToString(sum[:]) != s.checksum {
+		return nil, "", fmt.Errorf("config document does not match the pinned checksum")
+	}
+	return content, newETag, nil
+}
+
+func (s *httpConfigSource) fetch(ctx context.Context, etag string) ([]byte, string, error) {
+	u := *s.url
+	u.User = nil
+	u.Fragment = ""
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if s.url.User != nil {
+		password, _ := s.url.User.Password()
+		req.SetBasicAuth(s.url.User.Username(), password)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	return doConditionalGet(s.client, req)
+}
+
+// emptyPayloadHash is the SHA-256 of an empty request body, as SigV4 requires
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// s3ConfigSource reads a config document from an S3 object
+//
+// Requests are signed with credentials from the default AWS chain. The object
+// is addressed virtual-hosted style at the regional endpoint, or path style at
+// AWS_ENDPOINT_URL_S3 (or AWS_ENDPOINT_URL) for S3-compatible stores.
+type s3ConfigSource struct {
+	bucket string
+	key    string
+}
+
+func (s *s3ConfigSource) Fetch(ctx context.Context, etag string) ([]byte, string, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	if awsCfg.Region == "" {
+		return nil, "", fmt.Errorf("AWS region is required for s3 config sources (set AWS_REGION)")
+	}
+	creds, err := awsCfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+
+	u, err := s.objectURL(awsCfg.Region)
+	if err != nil {
+		return nil, "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	// S3 object keys are signed as-is rather than escaped a second time
+	signer := v4.NewSigner(func(o *v4.SignerOptions) { o.DisableURIPathEscaping = true })
+	if err := signer.SignHTTP(ctx, creds, req, emptyPayloadHash, "s3", awsCfg.Region, time.Now()); err != nil {
+		return nil, "", fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	var client httpDoer = http.DefaultClient
+	if awsCfg.HTTPClient != nil {
+		client = awsCfg.HTTPClient
+	}
+	return doConditionalGet(client, req)
+}
+
+func (s *s3ConfigSource) objectURL(region string) (*url.URL, error) {
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_S3")
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if endpoint == "" {
+		return &url.URL{
+			Scheme: "https",
+			Host:   fmt.Sprintf("%s.s3.%s.amazonaws.com", s.bucket, region),
+			Path:   "/" + s.key,
+		}, nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint %s: %w", endpoint, err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + s.key
+	return u, nil
+}
+
+// configMapSource reads a config document from a key of a Kubernetes ConfigMap
+// using the pod's service account
+type configMapSource struct {
+	namespace string
+	name      string
+	key       string
+	api       *kubernetesAPI
+}
+
+func newConfigMapSource(u *url.URL) (*configMapSource, error) {
+	// configmap://name/key or configmap://namespace/name/key
+	parts := []string{u.Host}
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		parts = append(parts, strings.Split(path, "/")...)
+	}
+
+	src := &configMapSource{}
+	switch len(parts) {
+	case 2:
+		namespace, err := serviceAccountNamespace()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine namespace for configmap %s: %w", parts[0], err)
+		}
+		src.namespace = namespace
+		src.name, src.key = parts[0], parts[1]
+	case 3:
+		src.namespace, src.name, src.key = parts[0], parts[1], parts[2]
+	default:
+		return nil, fmt.Errorf("invalid config source %s: expected configmap://[namespace/]name/key", u.String())
+	}
+
+	api, err := newKubernetesAPI("configmap config sources")
+	if err != nil {
+		return nil, err
+	}
+	src.api = api
+	return src, nil
+}
+
+func (s *configMapSource) Fetch(ctx context.Context, _ string) ([]byte, string, error) {
+	body, err := s.api.get(ctx, fmt.Sprintf("/api/v1/namespaces/%s/configmaps/%s",
+		url.PathEscape(s.namespace), url.PathEscape(s.name)))
+	if err != nil {
+		return nil, "", err
+	}
+
+	var cm struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(body, &cm); err != nil {
+		return nil, "", fmt.Errorf("failed to decode configmap: %w", err)
+	}
+	content, ok := cm.Data[s.key]
+	if !ok {
+		return nil, "", fmt.Errorf("configmap %s/%s has no key %q", s.namespace, s.name, s.key)
+	}
+
+	// The resource version changes with any key of the ConfigMap, so changes
+	// are detected by checksum of this key only
+	return []byte(content), "", nil
+}
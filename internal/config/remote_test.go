@@ -0,0 +1,305 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mutableDocument serves a config document that tests can change, with an ETag
+type mutableDocument struct {
+	mu      sync.Mutex
+	content string
+	etag    string
+}
+
+func (d *mutableDocument) set(content, etag string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.content, d.etag = content, etag
+}
+
+func (d *mutableDocument) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.etag != "" && r.Header.Get("If-None-Match") == d.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", d.etag)
+	_, _ = w.Write([]byte(d.content))
+}
+
+func TestNewLoader_HTTPSource(t *testing.T) {
+	doc := &mutableDocument{}
+	doc.set("include: datasources.yaml\ntrust_domain: remote.example.com\n", `"v1"`)
+
+	mux := http.NewServeMux()
+	mux.Handle("/config/parsec.yaml", doc)
+	mux.HandleFunc("/config/datasources.yaml", func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "parsec" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("data_sources:\n  - name: profile\n    type: http\n"))
+	})
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+	useRemoteHTTPClient(t, srv.Client())
+
+	location := strings.Replace(srv.URL, "https://", "https://parsec:secret@", 1) + "/config/parsec.yaml"
+	loader, err := NewLoader(location)
+	if err != nil {
+		t.Fatalf("failed to load remote config: %v", err)
+	}
+	cfg, err := loader.Get()
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+	if cfg.TrustDomain != "remote.example.com" {
+		t.Errorf("expected remote trust domain, got %s", cfg.TrustDomain)
+	}
+	if len(cfg.DataSources) != 1 || cfg.DataSources[0].Name != "profile" {
+		t.Errorf("expected data source from remote include, got %+v", cfg.DataSources)
+	}
+	if !loader.HasRemoteSources() || len(loader.remote) != 2 {
+		t.Fatalf("expected 2 remote sources, got %v", loader.remote)
+	}
+
+	version := loader.remote[location]
+	if version.etag != `"v1"` {
+		t.Errorf("expected ETag to be recorded, got %q", version.etag)
+	}
+	changed, err := remoteChanged(context.Background(), location, version)
+	if err != nil || changed {
+		t.Errorf("expected unchanged source, got %v, %v", changed, err)
+	}
+	doc.set("trust_domain: other.example.com\n", `"v2"`)
+	changed, err = remoteChanged(context.Background(), location, version)
+	if err != nil || !changed {
+		t.Errorf("expected changed source, got %v, %v", changed, err)
+	}
+}
+
+func TestLoader_WatchPollsRemoteSources(t *testing.T) {
+	// No ETag: changes are detected by checksum
+	doc := &mutableDocument{}
+	doc.set("trust_domain: v1.example.com\n", "")
+	srv := httptest.NewTLSServer(doc)
+	defer srv.Close()
+	useRemoteHTTPClient(t, srv.Client())
+
+	loader, err := NewLoader(srv.URL + "/parsec.yaml")
+	if err != nil {
+		t.Fatalf("failed to load remote config: %v", err)
+	}
+	loader.SetPollInterval(10 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloaded := make(chan *Config, 1)
+	go func() {
+		_ = loader.Watch(ctx, func(cfg *Config) error {
+			reloaded <- cfg
+			return nil
+		})
+	}()
+
+	doc.set("trust_domain: v2.example.com\n", "")
+	select {
+	case cfg := <-reloaded:
+		if cfg.TrustDomain != "v2.example.com" {
+			t.Errorf("expected reloaded trust domain, got %s", cfg.TrustDomain)
+		}
+		if cfg.Server.GRPCPort != 9090 {
+			t.Errorf("expected defaults to apply on reload, got grpc port %d", cfg.Server.GRPCPort)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected config to be reloaded")
+	}
+
+	current, err := loader.Get()
+	if err != nil || current.TrustDomain != "v2.example.com" {
+		t.Errorf("expected loader to hold reloaded config, got %+v, %v", current, err)
+	}
+}
+
+func TestS3ConfigSource(t *testing.T) {
+	var gotPath, gotAuth, gotContentHash string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		gotContentHash = r.Header.Get("X-Amz-Content-Sha256")
+		w.Header().Set("ETag", `"abc"`)
+		_, _ = w.Write([]byte("trust_domain: s3.example.com\n"))
+	}))
+	defer srv.Close()
+
+	t.Setenv("AWS_ENDPOINT_URL_S3", srv.URL)
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+
+	loader, err := NewLoader("s3://config-bucket/envs/prod/parsec.yaml")
+	if err != nil {
+		t.Fatalf("failed to load s3 config: %v", err)
+	}
+	cfg, err := loader.Get()
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+	if cfg.TrustDomain != "s3.example.com" {
+		t.Errorf("expected s3 trust domain, got %s", cfg.TrustDomain)
+	}
+	if gotPath != "/config-bucket/envs/prod/parsec.yaml" {
+		t.Errorf("expected path-style object path, got %s", gotPath)
+	}
+	if !strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(gotAuth, "/us-east-1/s3/aws4_request") {
+		t.Errorf("expected SigV4 authorization for s3, got %q", gotAuth)
+	}
+	if gotContentHash != emptyPayloadHash {
+		t.Errorf("expected empty payload hash, got %q", gotContentHash)
+	}
+}
+
+func TestConfigMapSource(t *testing.T) {
+	var gotPath, gotAuth string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]string{"parsec.yaml": "trust_domain: cm.example.com\n"},
+		})
+	}))
+	defer srv.Close()
+
+	useFakeCluster(t, srv)
+
+	loader, err := NewLoader("configmap://parsec-config/parsec.yaml")
+	if err != nil {
+		t.Fatalf("failed to load configmap config: %v", err)
+	}
+	cfg, err := loader.Get()
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+	if cfg.TrustDomain != "cm.example.com" {
+		t.Errorf("expected configmap trust domain, got %s", cfg.TrustDomain)
+	}
+	if gotPath != "/api/v1/namespaces/parsec-system/configmaps/parsec-config" {
+		t.Errorf("expected configmap in service account namespace, got %s", gotPath)
+	}
+	if gotAuth != "Bearer sa-token" {
+		t.Errorf("expected service account token, got %q", gotAuth)
+	}
+
+	if _, err := NewLoader("configmap://other-ns/parsec-config/missing.yaml"); err == nil ||
+		!strings.Contains(err.Error(), `no key "missing.yaml"`) {
+		t.Errorf("expected missing key error, got %v", err)
+	}
+}
+
+func TestRemoteSources_Errors(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/glob.yaml":
+			_, _ = w.Write([]byte("include: conf.d/*.yaml\n"))
+		case "/parsec.ini":
+			_, _ = w.Write([]byte("x=1\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	useRemoteHTTPClient(t, srv.Client())
+
+	tests := []struct {
+		location string
+		want     string
+	}{
+		{srv.URL + "/missing.yaml", "unexpected status 404"},
+		{"http://config.example.com/parsec.yaml", "uses plain http"},
+		{srv.URL + "/glob.yaml", "glob includes are not supported"},
+		{srv.URL + "/parsec.ini", "unsupported config file format"},
+		{"s3://bucket-only", "expected s3://bucket/key"},
+		{"configmap://name", "expected configmap://[namespace/]name/key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			_, _, err := loadConfigFiles(tt.location, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRemoteSources_PinnedHTTPChecksum(t *testing.T) {
+	doc := &mutableDocument{}
+	doc.set("trust_domain: pinned.example.com\n", "")
+	srv := httptest.NewServer(doc)
+	defer srv.Close()
+
+	sum := sha256.Sum256([]byte("trust_domain: pinned.example.com\n"))
+	location := srv.URL + "/parsec.yaml#sha256=" + hex.EncodeToString(sum[:])
+
+	loader, err := NewLoader(location)
+	if err != nil {
+		t.Fatalf("failed to load pinned config: %v", err)
+	}
+	cfg, err := loader.Get()
+	if err != nil || cfg.TrustDomain != "pinned.example.com" {
+		t.Fatalf("expected pinned config, got %+v, %v", cfg, err)
+	}
+
+	doc.set("trust_domain: tampered.example.com\n", "")
+	if _, err := NewLoader(location); err == nil || !strings.Contains(err.Error(), "pinned checksum") {
+		t.Errorf("expected checksum mismatch, got %v", err)
+	}
+}
+
+// useRemoteHTTPClient fetches http(s) config sources with client for the test
+func useRemoteHTTPClient(t *testing.T, client *http.Client) {
+	t.Helper()
+	previous := remoteHTTPClient
+	remoteHTTPClient = client
+	t.Cleanup(func() { remoteHTTPClient = previous })
+}
+
+// useFakeCluster points Kubernetes API access at srv, as the service account
+// "sa-token" in namespace "parsec-system"
+func useFakeCluster(t *testing.T, srv *httptest.Server) {
+	t.Helper()
+
+	dir := t.TempDir()
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	for name, content := range map[string][]byte{
+		"ca.crt":    caPEM,
+		"token":     []byte("sa-token\n"),
+		"namespace": []byte("parsec-system"),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	previous := serviceAccountDir
+	serviceAccountDir = dir
+	t.Cleanup(func() { serviceAccountDir = previous })
+
+	host, port, _ := strings.Cut(strings.TrimPrefix(srv.URL, "https://"), ":")
+	t.Setenv("KUBERNETES_SERVICE_HOST", host)
+	t.Setenv("KUBERNETES_SERVICE_PORT", port)
+}
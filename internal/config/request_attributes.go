@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/project-kessel/parsec/internal/server"
+)
+
+// NewRequestAttributeSources creates the extractors of extra request attributes
+// for ext_authz
+func NewRequestAttributeSources(cfgs []RequestAttributeConfig) ([]server.RequestAttributeSource, error) {
+	sources := make([]server.RequestAttributeSource, 0, len(cfgs))
+	seen := make(map[string]bool, len(cfgs))
+	for i, cfg := range cfgs {
+		if cfg.Key == "" {
+			return nil, fmt.Errorf("request attribute %d requires a key", i)
+		}
+		if server.IsReservedAttributeKey(cfg.Key) {
+			return nil, fmt.Errorf("request attribute key %s is reserved", cfg.Key)
+		}
+		if seen[cfg.Key] {
+			return nil, fmt.Errorf("duplicate request attribute key: %s", cfg.Key)
+		}
+		seen[cfg.Key] = true
+
+		extractor, err := newRequestAttributeExtractor(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("request attribute %s: %w", cfg.Key, err)
+		}
+		sources = append(sources, server.RequestAttributeSource{Key: cfg.Key, Extractor: extractor})
+	}
+	return sources, nil
+}
+
+func newRequestAttributeExtractor(cfg RequestAttributeConfig) (server.RequestAttributeExtractor, error) {
+	switch cfg.Type {
+	case "jwt_claims":
+		if cfg.Header == "" {
+			return nil, fmt.Errorf("jwt_claims requires a header")
+		}
+		return server.JWTClaimsExtractor{Header: cfg.Header, Scheme: cfg.Scheme}, nil
+	case "filter_metadata":
+		if cfg.Namespace == "" {
+			return nil, fmt.Errorf("filter_metadata requires a namespace")
+		}
+		return server.FilterMetadataExtractor{Namespace: cfg.Namespace}, nil
+	case "query_params":
+		return server.QueryParametersExtractor{Names: cfg.Names}, nil
+	default:
+		return nil, fmt.Errorf("unknown request attribute type: %s (supported: jwt_claims, filter_metadata, query_params)", cfg.Type)
+	}
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/server"
+)
+
+func TestNewRequestAttributeSources(t *testing.T) {
+	sources, err := NewRequestAttributeSources([]RequestAttributeConfig{
+		{Type: "jwt_claims", Key: "gateway", Header: "x-gateway-token", Scheme: "Bearer"},
+		{Type: "filter_metadata", Key: "jwt_authn", Namespace: "envoy.filters.http.jwt_authn"},
+		{Type: "query_params", Key: "query", Names: []string{"tenant"}},
+	})
+	if err != nil {
+		t.Fatalf("NewRequestAttributeSources failed: %v", err)
+	}
+	if len(sources) != 3 {
+		t.Fatalf("expected 3 sources, got %d", len(sources))
+	}
+	if jwt, ok := sources[0].Extractor.(server.JWTClaimsExtractor); !ok || jwt.Header != "x-gateway-token" || sources[0].Key != "gateway" {
+		t.Errorf("unexpected jwt_claims source %+v", sources[0])
+	}
+
+	for name, cfgs := range map[string][]RequestAttributeConfig{
+		"no key":        {{Type: "query_params"}},
+		"reserved key":  {{Type: "query_params", Key: "host"}},
+		"duplicate key": {{Type: "query_params", Key: "query"}, {Type: "query_params", Key: "query"}},
+		"unknown type":  {{Type: "cookies", Key: "cookies"}},
+		"no header":     {{Type: "jwt_claims", Key: "gateway"}},
+		"no namespace":  {{Type: "filter_metadata", Key: "metadata"}},
+	} {
+		if _, err := NewRequestAttributeSources(cfgs); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/project-kessel/parsec/internal/server"
+)
+
+// NewResponseHeaders creates the headers ext_authz derives with claim mappers
+func NewResponseHeaders(cfgs []ResponseHeaderConfig) ([]server.ResponseHeaderSpec, error) {
+	specs := make([]server.ResponseHeaderSpec, 0, len(cfgs))
+	seen := make(map[string]bool, len(cfgs))
+	for i, cfg := range cfgs {
+		if cfg.HeaderName == "" {
+			return nil, fmt.Errorf("response header %d requires a header_name", i)
+		}
+		name := http.CanonicalHeaderKey(cfg.HeaderName)
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate response header: %s", cfg.HeaderName)
+		}
+		seen[name] = true
+
+		if cfg.Mapper.Optional {
+			return nil, fmt.Errorf("response header %s: optional mappers are only supported by issuers", cfg.HeaderName)
+		}
+		mapper, err := newClaimMapper(cfg.Mapper)
+		if err != nil {
+			return nil, fmt.Errorf("response header %s: %w", cfg.HeaderName, err)
+		}
+
+		spec := server.ResponseHeaderSpec{
+			HeaderName: cfg.HeaderName,
+			Mapper:     mapper,
+			Claim:      cfg.Claim,
+		}
+		switch cfg.Encoding {
+		case "":
+		case "base64":
+			spec.Base64 = true
+		default:
+			return nil, fmt.Errorf("unknown response header encoding: %s (supported: base64)", cfg.Encoding)
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestNewResponseHeaders(t *testing.T) {
+	specs, err := NewResponseHeaders([]ResponseHeaderConfig{
+		{HeaderName: "x-request-id", Mapper: ClaimMapperConfig{Type: "request_attributes"}, Claim: "request_id"},
+		{HeaderName: "x-rh-identity", Mapper: ClaimMapperConfig{Type: "stub", Claims: map[string]any{"identity": "user"}}, Encoding: "base64"},
+	})
+	if err != nil {
+		t.Fatalf("NewResponseHeaders failed: %v", err)
+	}
+	if len(specs) != 2 || specs[0].Claim != "request_id" || specs[0].Base64 || !specs[1].Base64 || specs[1].Mapper == nil {
+		t.Errorf("unexpected specs %+v", specs)
+	}
+
+	for name, cfgs := range map[string][]ResponseHeaderConfig{
+		"no header name":   {{Mapper: ClaimMapperConfig{Type: "passthrough"}}},
+		"unknown mapper":   {{HeaderName: "x-identity", Mapper: ClaimMapperConfig{Type: "lua"}}},
+		"unknown encoding": {{HeaderName: "x-identity", Mapper: ClaimMapperConfig{Type: "passthrough"}, Encoding: "hex"}},
+		"optional mapper":  {{HeaderName: "x-identity", Mapper: ClaimMapperConfig{Type: "passthrough", Optional: true}}},
+		"duplicate header": {
+			{HeaderName: "x-identity", Mapper: ClaimMapperConfig{Type: "passthrough"}},
+			{HeaderName: "X-Identity", Mapper: ClaimMapperConfig{Type: "passthrough"}},
+		},
+	} {
+		if _, err := NewResponseHeaders(cfgs); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/quota"
+	"github.com/project-kessel/parsec/internal/risk"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// NewRiskEvaluators creates the evaluators assessing the risk of issuances
+// Returns nil if cfg is nil (issuances are not assessed).
+func NewRiskEvaluators(cfg *RiskConfig, transport http.RoundTripper, clk clock.Clock) ([]service.RiskEvaluator, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if len(cfg.Evaluators) == 0 {
+		return nil, fmt.Errorf("risk requires at least one evaluator")
+	}
+
+	evaluators := make([]service.RiskEvaluator, len(cfg.Evaluators))
+	for i, evaluatorCfg := range cfg.Evaluators {
+		evaluator, err := newRiskEvaluator(evaluatorCfg, transport, clk)
+		if err != nil {
+			return nil, fmt.Errorf("risk evaluator %d: %w", i, err)
+		}
+		evaluators[i] = evaluator
+	}
+	return evaluators, nil
+}
+
+// newRiskEvaluator creates a single risk evaluator
+func newRiskEvaluator(cfg RiskEvaluatorConfig, transport http.RoundTripper, clk clock.Clock) (service.RiskEvaluator, error) {
+	switch cfg.Type {
+	case "velocity":
+		return newVelocityRiskEvaluator(cfg, clk)
+	case "http":
+		timeout, err := parseOptionalDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+		return risk.NewHTTP(risk.HTTPConfig{
+			URL:       cfg.URL,
+			Headers:   cfg.Headers,
+			Timeout:   timeout,
+			Transport: transport,
+		})
+	default:
+		return nil, fmt.Errorf("unknown risk evaluator type: %s (supported: velocity, http)", cfg.Type)
+	}
+}
+
+// newVelocityRiskEvaluator creates a velocity risk evaluator
+func newVelocityRiskEvaluator(cfg RiskEvaluatorConfig, clk clock.Clock) (service.RiskEvaluator, error) {
+	selector, err := quota.NewCELSelector(cfg.Selector)
+	if err != nil {
+		return nil, err
+	}
+	window, err := time.ParseDuration(cfg.Window)
+	if err != nil {
+		return nil, fmt.Errorf("invalid window: %w", err)
+	}
+	maxTTL, err := parseOptionalDuration(cfg.MaxTTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid max_ttl: %w", err)
+	}
+	var level service.RiskLevel
+	if cfg.Level != "" {
+		if level, err = service.ParseRiskLevel(cfg.Level); err != nil {
+			return nil, err
+		}
+	}
+
+	return risk.NewVelocity(risk.VelocityConfig{
+		Name:      cfg.Name,
+		Selector:  selector,
+		Window:    window,
+		Threshold: cfg.Threshold,
+		Level:     level,
+		MaxTTL:    maxTTL,
+		Deny:      cfg.Deny,
+		Clock:     clk,
+	})
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestNewRiskEvaluators(t *testing.T) {
+	if evaluators, err := NewRiskEvaluators(nil, nil, nil); evaluators != nil || err != nil {
+		t.Errorf("expected no evaluators without config, got %v (%v)", evaluators, err)
+	}
+
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	evaluators, err := NewRiskEvaluators(&RiskConfig{
+		Evaluators: []RiskEvaluatorConfig{
+			{Type: "velocity", Selector: "subject.subject", Window: "1m", Threshold: 1, Level: "medium", MaxTTL: "30s"},
+			{Type: "http", URL: "https://risk.example.com/assess", Timeout: "2s"},
+		},
+	}, nil, clk)
+	if err != nil {
+		t.Fatalf("NewRiskEvaluators failed: %v", err)
+	}
+	if len(evaluators) != 2 {
+		t.Fatalf("expected 2 evaluators, got %d", len(evaluators))
+	}
+
+	issueCtx := &service.IssueContext{Subject: &trust.Result{Subject: "alice"}}
+	if assessment, err := evaluators[0].Evaluate(context.Background(), issueCtx); err != nil || assessment != nil {
+		t.Fatalf("expected no opinion on the first issuance, got %+v, %v", assessment, err)
+	}
+	assessment, err := evaluators[0].Evaluate(context.Background(), issueCtx)
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if assessment == nil || assessment.Level != service.RiskLevelMedium || assessment.MaxTTL != 30*time.Second {
+		t.Errorf("unexpected assessment: %+v", assessment)
+	}
+}
+
+func TestNewRiskEvaluators_Errors(t *testing.T) {
+	velocity := RiskEvaluatorConfig{Type: "velocity", Selector: "subject.subject", Window: "1m", Threshold: 1}
+	withEvaluator := func(edit func(*RiskEvaluatorConfig)) *RiskConfig {
+		e := velocity
+		edit(&e)
+		return &RiskConfig{Evaluators: []RiskEvaluatorConfig{e}}
+	}
+
+	for name, cfg := range map[string]*RiskConfig{
+		"no evaluators":    {},
+		"unknown type":     withEvaluator(func(e *RiskEvaluatorConfig) { e.Type = "oracle" }),
+		"invalid selector": withEvaluator(func(e *RiskEvaluatorConfig) { e.Selector = "subject." }),
+		"invalid window":   withEvaluator(func(e *RiskEvaluatorConfig) { e.Window = "soon" }),
+		"invalid max_ttl":  withEvaluator(func(e *RiskEvaluatorConfig) { e.MaxTTL = "short" }),
+		"unknown level":    withEvaluator(func(e *RiskEvaluatorConfig) { e.Level = "severe" }),
+		"no threshold":     withEvaluator(func(e *RiskEvaluatorConfig) { e.Threshold = 0 }),
+		"http without url": {Evaluators: []RiskEvaluatorConfig{{Type: "http"}}},
+		"http bad timeout": {Evaluators: []RiskEvaluatorConfig{{Type: "http", URL: "https://risk.example.com", Timeout: "later"}}},
+	} {
+		if _, err := NewRiskEvaluators(cfg, nil, nil); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RedactedValue replaces secret values in config dumps
+const RedactedValue = "[REDACTED]"
+
+// secretRefKey marks a config node whose value is resolved from a secret store:
+//
+//	api_key:
+//	  secretRef:
+//	    file: /var/run/secrets/api-key      # file contents (trailing newline trimmed)
+//	    env: API_KEY                        # environment variable
+//	    vault: {path: secret/data/parsec, key: api_key}  # Vault KV secret
+const secretRefKey = "secretRef"
+
+// sensitiveKeys are config keys whose values are redacted in dumps even when
+// given inline
+var sensitiveKeys = map[string]bool{
+	"authorization":  true,
+	"api_key":        true,
+	"client_secret":  true,
+	"dsn":            true,
+	"encryption_key": true,
+	"key":            true,
+	"password":       true,
+	"private_key":    true,
+	"salt":           true,
+	"secret":         true,
+}
+
+// literalKeys are config keys holding code (CEL and Lua scripts, JSON Schemas
+// with regex patterns) whose values are taken verbatim: ${...} in them is not
+// expanded, since it is more likely script syntax than an environment variable
+var literalKeys = map[string]bool{
+	"expression": true,
+	"schema":     true,
+	"script":     true,
+}
+
+// interpolationPattern matches ${VAR} and ${VAR:-default}, and the $${ escape
+var interpolationPattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// secretResolver expands ${ENV_VAR} references and resolves secretRef nodes
+// throughout a raw configuration tree, except under literalKeys
+type secretResolver struct {
+	lookupEnv  func(string) (string, bool)
+	readFile   func(string) ([]byte, error)
+	httpClient *http.Client
+}
+
+// newSecretResolver creates a resolver backed by the process environment and filesystem
+func newSecretResolver() *secretResolver {
+	return &secretResolver{
+		lookupEnv: os.LookupEnv,
+		readFile:  os.ReadFile,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// resolve returns the resolved configuration tree and a copy with secrets redacted
+func (r *secretResolver) resolve(raw map[string]any) (resolved, redacted map[string]any, err error) {
+	res, red, err := r.resolveNode(raw, "", false, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res.(map[string]any), red.(map[string]any), nil
+}
+
+func (r *secretResolver) resolveNode(node any, path string, sensitive, literal bool) (resolved, redacted any, err error) {
+	switch v := node.(type) {
+	case map[string]any:
+		if ref, ok := v[secretRefKey]; ok && len(v) == 1 {
+			secret, err := r.resolveSecretRef(ref)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", path, err)
+			}
+			return secret, RedactedValue, nil
+		}
+
+		resolvedMap := make(map[string]any, len(v))
+		redactedMap := make(map[string]any, len(v))
+		for key, child := range v {
+			lowerKey := strings.ToLower(key)
+			res, red, err := r.resolveNode(child, joinPath(path, key), sensitive || sensitiveKeys[lowerKey], literal || literalKeys[lowerKey])
+			if err != nil {
+				return nil, nil, err
+			}
+			resolvedMap[key] = res
+			redactedMap[key] = red
+		}
+		return resolvedMap, redactedMap, nil
+
+	case []any:
+		resolvedSlice := make([]any, len(v))
+		redactedSlice := make([]any, len(v))
+		for i, child := range v {
+			res, red, err := r.resolveNode(child, fmt.Sprintf("%s[%d]", path, i), sensitive, literal)
+			if err != nil {
+				return nil, nil, err
+			}
+			resolvedSlice[i] = res
+			redactedSlice[i] = red
+		}
+		return resolvedSlice, redactedSlice, nil
+
+	case string:
+		expanded := v
+		if !literal {
+			expanded, err = r.interpolate(v)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		if sensitive && expanded != "" {
+			return expanded, RedactedValue, nil
+		}
+		return expanded, expanded, nil
+
+	default:
+		return v, v, nil
+	}
+}
+
+// interpolate expands ${VAR} and ${VAR:-default}; "$${" yields a literal "${"
+// Referencing an unset variable without a default is an error
+func (r *secretResolver) interpolate(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var missing []string
+	expanded := interpolationPattern.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+		groups := interpolationPattern.FindStringSubmatch(match)
+		if value, ok := r.lookupEnv(groups[1]); ok {
+			return value
+		}
+		if strings.Contains(match, ":-") {
+			return groups[2]
+		}
+		missing = append(missing, groups[1])
+		return match
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("undefined environment variable(s): %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// resolveSecretRef resolves a secretRef node to its secret value
+func (r *secretResolver) resolveSecretRef(node any) (string, error) {
+	ref, ok := node.(map[string]any)
+	if !ok || len(ref) != 1 {
+		return "", fmt.Errorf("secretRef requires exactly one of file, env, or vault")
+	}
+
+	for source, value := range ref {
+		switch source {
+		case "file":
+			path, ok := value.(string)
+			if !ok || path == "" {
+				return "", fmt.Errorf("secretRef file must be a path")
+			}
+			data, err := r.readFile(path)
+			if err != nil {
+				return "", fmt.Errorf("failed to read secret file: %w", err)
+			}
+			return strings.TrimRight(string(data), "\r\n"), nil
+
+		case "env":
+			name, ok := value.(string)
+			if !ok || name == "" {
+				return "", fmt.Errorf("secretRef env must be a variable name")
+			}
+			secret, ok := r.lookupEnv(name)
+			if !ok {
+				return "", fmt.Errorf("secret environment variable %s is not set", name)
+			}
+			return secret, nil
+
+		case "vault":
+			vaultRef, ok := value.(map[string]any)
+			if !ok {
+				return "", fmt.Errorf("secretRef vault requires path and key")
+			}
+			path, _ := vaultRef["path"].(string)
+			key, _ := vaultRef["key"].(string)
+			if path == "" || key == "" {
+				return "", fmt.Errorf("secretRef vault requires path and key")
+			}
+			return r.readVaultSecret(path, key)
+		}
+	}
+
+	return "", fmt.Errorf("unknown secretRef source (supported: file, env, vault)")
+}
+
+// readVaultSecret reads a key from a Vault KV secret (v1 or v2)
+// The server address and token come from VAULT_ADDR and VAULT_TOKEN
+func (r *secretResolver) readVaultSecret(path, key string) (string, error) {
+	addr, _ := r.lookupEnv("VAULT_ADDR")
+	token, _ := r.lookupEnv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		return "", fmt.Errorf("vault secrets require VAULT_ADDR and VAULT_TOKEN")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	url := strings.TrimRight(addr, "/") + "/v1/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace, ok := r.lookupEnv("VAULT_NAMESPACE"); ok && namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault secret %s: %w", path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to read vault secret %s: status %d", path, resp.StatusCode)
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+
+	// KV v2 nests the secret under data.data
+	data := body.Data
+	if nested, ok := data["data"].(map[string]any); ok {
+		data = nested
+	}
+
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no key %s", path, key)
+	}
+	secret, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %s key %s is not a string", path, key)
+	}
+	return secret, nil
+}
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
@@ -0,0 +1,244 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestSecretResolver(env map[string]string) *secretResolver {
+	r := newSecretResolver()
+	r.lookupEnv = func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	return r
+}
+
+func TestSecretResolver_Interpolation(t *testing.T) {
+	r := newTestSecretResolver(map[string]string{"HOST": "idp.example.com", "EMPTY": ""})
+
+	tests := []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		{in: "https://${HOST}/jwks", want: "https://idp.example.com/jwks"},
+		{in: "${MISSING:-fallback}", want: "fallback"},
+		{in: "${EMPTY:-fallback}", want: ""},
+		{in: "${MISSING:-}", want: ""},
+		{in: "literal $${HOST} and ^/orders$", want: "literal ${HOST} and ^/orders$"},
+		{in: "${MISSING}-${ALSO_MISSING}", wantErr: "undefined environment variable(s): MISSING, ALSO_MISSING"},
+	}
+
+	for _, tt := range tests {
+		got, err := r.interpolate(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("interpolate(%q): expected error %q, got %v", tt.in, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("interpolate(%q): unexpected error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("interpolate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSecretResolver_LiteralKeys(t *testing.T) {
+	r := newTestSecretResolver(map[string]string{"HOST": "idp.example.com"})
+
+	raw := map[string]any{
+		"jwks_url": "https://${HOST}/jwks",
+		"mappers": []any{
+			map[string]any{"type": "lua", "script": `return {greeting = "${name}"}`},
+		},
+		"transforms": []any{
+			map[string]any{"type": "cel", "expression": `"${" + claims.sub + "}"`},
+		},
+		"request_context_schemas": []any{
+			map[string]any{"schema": map[string]any{"properties": map[string]any{
+				"path": map[string]any{"pattern": `^/api/\${HOST}$`},
+			}}},
+		},
+	}
+
+	resolved, _, err := r.resolve(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resolved["jwks_url"] != "https://idp.example.com/jwks" {
+		t.Errorf("expected other values expanded, got %v", resolved["jwks_url"])
+	}
+	if got := resolved["mappers"].([]any)[0].(map[string]any)["script"]; got != raw["mappers"].([]any)[0].(map[string]any)["script"] {
+		t.Errorf("expected script kept verbatim, got %v", got)
+	}
+	if got := resolved["transforms"].([]any)[0].(map[string]any)["expression"]; got != `"${" + claims.sub + "}"` {
+		t.Errorf("expected expression kept verbatim, got %v", got)
+	}
+	schema := resolved["request_context_schemas"].([]any)[0].(map[string]any)["schema"].(map[string]any)
+	pattern := schema["properties"].(map[string]any)["path"].(map[string]any)["pattern"]
+	if pattern != `^/api/\${HOST}$` {
+		t.Errorf("expected schema pattern kept verbatim, got %v", pattern)
+	}
+}
+
+func TestSecretResolver_SecretRefs(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if req.URL.Path != "/v1/secret/data/parsec" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"data": {"client_secret": "from-vault"}}}`))
+	}))
+	defer vault.Close()
+
+	r := newTestSecretResolver(map[string]string{
+		"API_KEY":     "from-env",
+		"VAULT_ADDR":  vault.URL,
+		"VAULT_TOKEN": "vault-token",
+	})
+
+	raw := map[string]any{
+		"trust_domain": "prod.example.com",
+		"data_sources": []any{
+			map[string]any{
+				"name": "roles",
+				"headers": map[string]any{
+					"Authorization": "Bearer inline",
+					"X-Api-Key":     map[string]any{"secretRef": map[string]any{"env": "API_KEY"}},
+				},
+			},
+		},
+		"file_secret":  map[string]any{"secretRef": map[string]any{"file": secretFile}},
+		"vault_secret": map[string]any{"secretRef": map[string]any{"vault": map[string]any{"path": "secret/data/parsec", "key": "client_secret"}}},
+	}
+
+	resolved, redacted, err := r.resolve(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	headers := resolved["data_sources"].([]any)[0].(map[string]any)["headers"].(map[string]any)
+	if headers["X-Api-Key"] != "from-env" {
+		t.Errorf("expected env secret, got %v", headers["X-Api-Key"])
+	}
+	if resolved["file_secret"] != "from-file" {
+		t.Errorf("expected file secret with trailing newline trimmed, got %q", resolved["file_secret"])
+	}
+	if resolved["vault_secret"] != "from-vault" {
+		t.Errorf("expected vault secret, got %v", resolved["vault_secret"])
+	}
+
+	redactedHeaders := redacted["data_sources"].([]any)[0].(map[string]any)["headers"].(map[string]any)
+	for name, value := range map[string]any{
+		"X-Api-Key":     redactedHeaders["X-Api-Key"],
+		"Authorization": redactedHeaders["Authorization"],
+		"file_secret":   redacted["file_secret"],
+		"vault_secret":  redacted["vault_secret"],
+	} {
+		if value != RedactedValue {
+			t.Errorf("expected %s redacted, got %v", name, value)
+		}
+	}
+	if redacted["trust_domain"] != "prod.example.com" {
+		t.Errorf("expected non-secret values kept, got %v", redacted["trust_domain"])
+	}
+}
+
+func TestSecretResolver_SecretRefErrors(t *testing.T) {
+	r := newTestSecretResolver(nil)
+
+	tests := []struct {
+		name    string
+		ref     any
+		wantErr string
+	}{
+		{name: "unset env", ref: map[string]any{"env": "NOPE"}, wantErr: "secret environment variable NOPE is not set"},
+		{name: "multiple sources", ref: map[string]any{"env": "A", "file": "/b"}, wantErr: "exactly one of file, env, or vault"},
+		{name: "unknown source", ref: map[string]any{"kms": "x"}, wantErr: "unknown secretRef source"},
+		{name: "vault without address", ref: map[string]any{"vault": map[string]any{"path": "p", "key": "k"}}, wantErr: "VAULT_ADDR and VAULT_TOKEN"},
+	}
+
+	for _, tt := range tests {
+		_, _, err := r.resolve(map[string]any{"issuers": []any{map[string]any{"salt": map[string]any{"secretRef": tt.ref}}}})
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), "issuers[0].salt") {
+			t.Errorf("%s: expected error to name the config path, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestNewLoader_ResolvesReferences(t *testing.T) {
+	t.Setenv("PARSEC_TEST_DOMAIN", "interpolated.example.com")
+	t.Setenv("PARSEC_TEST_SALT", "pepper")
+	t.Setenv("PARSEC_TEST_NODE_ID", "7")
+
+	configPath := filepath.Join(t.TempDir(), "parsec.yaml")
+	content := `
+trust_domain: ${PARSEC_TEST_DOMAIN}
+issuers:
+  - token_type: "urn:ietf:params:oauth:token-type:txn_token"
+    type: stub
+    transaction_id_node_id: ${PARSEC_TEST_NODE_ID}
+    request_context:
+      - type: request_context
+        transforms:
+          - type: hash
+            field: ip_address
+            salt:
+              secretRef:
+                env: PARSEC_TEST_SALT
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	loader, err := NewLoader(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg, err := loader.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.TrustDomain != "interpolated.example.com" {
+		t.Errorf("expected interpolated trust domain, got %s", cfg.TrustDomain)
+	}
+	if id := cfg.Issuers[0].TransactionIDNodeID; id == nil || *id != 7 {
+		t.Errorf("expected node ID 7 from the environment, got %v", id)
+	}
+	if salt := cfg.Issuers[0].RequestContextMappers[0].Transforms[0].Salt; salt != "pepper" {
+		t.Errorf("expected salt from secretRef, got %q", salt)
+	}
+
+	dump := loader.Redacted()
+	transform := dump["issuers"].([]any)[0].(map[string]any)["request_context"].([]any)[0].(map[string]any)["transforms"].([]any)[0].(map[string]any)
+	if transform["salt"] != RedactedValue {
+		t.Errorf("expected salt redacted in dump, got %v", transform["salt"])
+	}
+	if dump["trust_domain"] != "interpolated.example.com" {
+		t.Errorf("expected trust domain in dump, got %v", dump["trust_domain"])
+	}
+}
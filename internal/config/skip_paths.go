@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/project-kessel/parsec/internal/server"
+)
+
+// NewSkipPaths creates the request paths ext_authz allows without validation
+func NewSkipPaths(cfgs []SkipPathConfig) ([]server.SkipPath, error) {
+	paths := make([]server.SkipPath, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("skip path %d requires a path", i)
+		}
+		switch cfg.Type {
+		case "exact":
+			paths = append(paths, server.SkipPath{Exact: cfg.Path})
+		case "prefix":
+			paths = append(paths, server.SkipPath{Prefix: cfg.Path})
+		case "regex":
+			re, err := regexp.Compile(cfg.Path)
+			if err != nil {
+				return nil, fmt.Errorf("invalid skip path regex %q: %w", cfg.Path, err)
+			}
+			paths = append(paths, server.SkipPath{Regex: re})
+		default:
+			return nil, fmt.Errorf("unknown skip path type: %s (supported: exact, prefix, regex)", cfg.Type)
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestNewSkipPaths(t *testing.T) {
+	paths, err := NewSkipPaths([]SkipPathConfig{
+		{Type: "exact", Path: "/healthz"},
+		{Type: "prefix", Path: "/static/"},
+		{Type: "regex", Path: `^/public/[a-z]+\.css$`},
+	})
+	if err != nil {
+		t.Fatalf("NewSkipPaths failed: %v", err)
+	}
+	if len(paths) != 3 || paths[0].Exact != "/healthz" || paths[1].Prefix != "/static/" || paths[2].Regex == nil {
+		t.Errorf("unexpected paths %+v", paths)
+	}
+
+	for name, cfgs := range map[string][]SkipPathConfig{
+		"no path":       {{Type: "exact"}},
+		"unknown type":  {{Type: "glob", Path: "/static/*"}},
+		"invalid regex": {{Type: "regex", Path: "/public/("}},
+	} {
+		if _, err := NewSkipPaths(cfgs); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/project-kessel/parsec/internal/server"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// NewSubjectCredentialExtractor creates the ext_authz subject credential
+// extractor chain
+// Returns the default (Bearer token in the Authorization header) if no sources
+// are configured.
+func NewSubjectCredentialExtractor(cfgs []SubjectCredentialSourceConfig) (server.SubjectCredentialExtractor, error) {
+	if len(cfgs) == 0 {
+		return server.DefaultSubjectCredentialExtractor(), nil
+	}
+
+	chain := make(server.SubjectCredentialExtractorChain, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		extractor, err := newSubjectCredentialSource(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subject credential source %d: %w", i, err)
+		}
+		chain = append(chain, extractor)
+	}
+	return chain, nil
+}
+
+func newSubjectCredentialSource(cfg SubjectCredentialSourceConfig) (server.SubjectCredentialExtractor, error) {
+	source := server.SubjectCredentialSource{
+		Location:       server.SubjectCredentialLocation(cfg.Type),
+		Name:           cfg.Name,
+		Scheme:         cfg.Scheme,
+		CredentialType: trust.CredentialType(cfg.CredentialType),
+	}
+
+	switch cfg.Type {
+	case "header", "cookie", "query":
+	case "rh_identity":
+		// x-rh-identity carries a base64 encoded JSON identity document
+		source.Location = server.SubjectCredentialLocationHeader
+		if source.Name == "" {
+			source.Name = "x-rh-identity"
+		}
+		source.Base64 = true
+		if source.CredentialType == "" {
+			source.CredentialType = trust.CredentialTypeJSON
+		}
+	default:
+		return nil, fmt.Errorf("unknown subject credential source type: %s (supported: header, cookie, query, rh_identity)", cfg.Type)
+	}
+	if source.CredentialType == "" {
+		source.CredentialType = trust.CredentialTypeBearer
+	}
+
+	return server.NewSubjectCredentialSource(source)
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/server"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestNewSubjectCredentialExtractor(t *testing.T) {
+	extractor, err := NewSubjectCredentialExtractor([]SubjectCredentialSourceConfig{
+		{Type: "cookie", Name: "session", CredentialType: "jwt"},
+		{Type: "rh_identity"},
+		{Type: "header", Name: "Authorization", Scheme: "Bearer"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	chain, ok := extractor.(server.SubjectCredentialExtractorChain)
+	if !ok || len(chain) != 3 {
+		t.Fatalf("expected chain of 3 extractors, got %#v", extractor)
+	}
+	rhIdentity, ok := chain[1].(server.SubjectCredentialSource)
+	if !ok || rhIdentity.Name != "x-rh-identity" || !rhIdentity.Base64 || rhIdentity.CredentialType != trust.CredentialTypeJSON {
+		t.Errorf("expected x-rh-identity JSON source, got %#v", chain[1])
+	}
+	header, ok := chain[2].(server.SubjectCredentialSource)
+	if !ok || header.Name != "authorization" || header.CredentialType != trust.CredentialTypeBearer {
+		t.Errorf("expected lowercase authorization bearer source, got %#v", chain[2])
+	}
+
+	for _, cfg := range []SubjectCredentialSourceConfig{
+		{Type: "header"},
+		{Type: "body", Name: "token"},
+		{Type: "cookie", Name: "session", Scheme: "Bearer"},
+		{Type: "query", Name: "token", CredentialType: "mtls"},
+	} {
+		if _, err := NewSubjectCredentialExtractor([]SubjectCredentialSourceConfig{cfg}); err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/project-kessel/parsec/internal/telemetry"
+)
+
+// defaultMetricsPath is where metrics are served for scraping by default
+const defaultMetricsPath = "/metrics"
+
+// NewTelemetry creates the metrics exporters
+// Returns nil if cfg is nil or enables no exporter. Resources are identified
+// by trustDomain along with the configured service name and instance.
+func NewTelemetry(ctx context.Context, cfg *TelemetryConfig, trustDomain string) (*telemetry.Telemetry, error) {
+	if cfg == nil || cfg.Metrics == nil {
+		return nil, nil
+	}
+
+	telemetryCfg := telemetry.Config{
+		ServiceName: cfg.ServiceName,
+		Instance:    cfg.Instance,
+		TrustDomain: trustDomain,
+		Attributes:  cfg.ResourceAttributes,
+		Prometheus:  cfg.Metrics.Prometheus != nil && cfg.Metrics.Prometheus.Enabled,
+	}
+	if telemetryCfg.ServiceName == "" {
+		telemetryCfg.ServiceName = "parsec"
+	}
+	if telemetryCfg.Instance == "" {
+		// Without a hostname, instances are told apart by the other attributes
+		telemetryCfg.Instance, _ = os.Hostname()
+	}
+
+	if otlp := cfg.Metrics.OTLP; otlp != nil {
+		interval, err := parseOptionalDuration(otlp.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid otlp interval: %w", err)
+		}
+		timeout, err := parseOptionalDuration(otlp.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid otlp timeout: %w", err)
+		}
+		telemetryCfg.OTLP = &telemetry.OTLPConfig{
+			Protocol: otlp.Protocol,
+			Endpoint: otlp.Endpoint,
+			Insecure: otlp.Insecure,
+			Headers:  otlp.Headers,
+			Interval: interval,
+			Timeout:  timeout,
+		}
+	}
+
+	if !telemetryCfg.Prometheus && telemetryCfg.OTLP == nil {
+		return nil, nil
+	}
+	return telemetry.New(ctx, telemetryCfg)
+}
+
+// MetricsPath returns the HTTP path metrics are served at for scraping
+func MetricsPath(cfg *TelemetryConfig) string {
+	if cfg != nil && cfg.Metrics != nil && cfg.Metrics.Prometheus != nil && cfg.Metrics.Prometheus.Path != "" {
+		return cfg.Metrics.Prometheus.Path
+	}
+	return defaultMetricsPath
+}
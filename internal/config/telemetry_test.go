@@ -0,0 +1,79 @@
+package config
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewTelemetry(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("prometheus with defaults", func(t *testing.T) {
+		cfg := &TelemetryConfig{
+			ResourceAttributes: map[string]string{"region": "eu-west-1"},
+			Metrics:            &MetricsConfig{Prometheus: &PrometheusMetricsConfig{Enabled: true}},
+		}
+		telemetry, err := NewTelemetry(ctx, cfg, "trust.example.com")
+		if err != nil {
+			t.Fatalf("NewTelemetry failed: %v", err)
+		}
+		defer func() { _ = telemetry.Shutdown(ctx) }()
+
+		rec := httptest.NewRecorder()
+		telemetry.Handler().ServeHTTP(rec, httptest.NewRequest("GET", MetricsPath(cfg), nil))
+		body, _ := io.ReadAll(rec.Body)
+		for _, want := range []string{`service_name="parsec"`, `parsec_trust_domain="trust.example.com"`, `region="eu-west-1"`} {
+			if !strings.Contains(string(body), want) {
+				t.Errorf("expected %s in the scrape, got:\n%s", want, body)
+			}
+		}
+		if MetricsPath(cfg) != "/metrics" {
+			t.Errorf("expected default metrics path, got %s", MetricsPath(cfg))
+		}
+	})
+
+	t.Run("otlp", func(t *testing.T) {
+		telemetry, err := NewTelemetry(ctx, &TelemetryConfig{
+			ServiceName: "parsec-edge",
+			Metrics: &MetricsConfig{OTLP: &OTLPMetricsConfig{
+				Protocol: "http",
+				Endpoint: "collector:4318",
+				Interval: "15s",
+				Timeout:  "5s",
+			}},
+		}, "trust.example.com")
+		if err != nil {
+			t.Fatalf("NewTelemetry failed: %v", err)
+		}
+		if telemetry.Handler() != nil {
+			t.Error("expected no scrape handler without prometheus")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		for _, cfg := range []*TelemetryConfig{
+			nil,
+			{ServiceName: "parsec"},
+			{Metrics: &MetricsConfig{Prometheus: &PrometheusMetricsConfig{Path: "/stats"}}},
+		} {
+			if telemetry, err := NewTelemetry(ctx, cfg, "trust.example.com"); telemetry != nil || err != nil {
+				t.Errorf("expected no telemetry for %+v, got %v (%v)", cfg, telemetry, err)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, otlp := range []*OTLPMetricsConfig{
+			{Protocol: "thrift"},
+			{Interval: "often"},
+			{Timeout: "soon"},
+		} {
+			if _, err := NewTelemetry(ctx, &TelemetryConfig{Metrics: &MetricsConfig{OTLP: otlp}}, ""); err == nil {
+				t.Errorf("expected error for %+v", otlp)
+			}
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/project-kessel/parsec/internal/tokenwebhook"
+)
+
+// NewTokenWebhook creates the token webhook notifier
+// Returns nil if cfg is nil (issued tokens are not reported). The notifier
+// reports dropped summaries to logger.
+func NewTokenWebhook(cfg *TokenWebhookConfig, transport http.RoundTripper, logger *slog.Logger) (*tokenwebhook.Notifier, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	notifierCfg, err := tokenWebhookConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	notifierCfg.Transport = transport
+	notifierCfg.Logger = logger
+	return tokenwebhook.NewNotifier(notifierCfg)
+}
+
+// tokenWebhookConfig converts and checks cfg without starting a notifier
+func tokenWebhookConfig(cfg *TokenWebhookConfig) (tokenwebhook.Config, error) {
+	if cfg.URL == "" {
+		return tokenwebhook.Config{}, fmt.Errorf("token webhook requires a url")
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return tokenwebhook.Config{}, fmt.Errorf("invalid token webhook url %q: must be an absolute http or https url", cfg.URL)
+	}
+	if cfg.Secret == "" {
+		return tokenwebhook.Config{}, fmt.Errorf("token webhook requires a secret")
+	}
+	timeout, err := parseOptionalDuration(cfg.Timeout)
+	if err != nil {
+		return tokenwebhook.Config{}, fmt.Errorf("invalid timeout: %w", err)
+	}
+	return tokenwebhook.Config{
+		URL:        cfg.URL,
+		Secret:     cfg.Secret,
+		Timeout:    timeout,
+		BufferSize: cfg.BufferSize,
+		BatchSize:  cfg.BatchSize,
+		Retries:    cfg.Retries,
+	}, nil
+}
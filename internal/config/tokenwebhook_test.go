@@ -0,0 +1,40 @@
+package config
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewTokenWebhook(t *testing.T) {
+	notifier, err := NewTokenWebhook(&TokenWebhookConfig{URL: "https://hooks.example.com/tokens", Secret: "s3cret", Timeout: "2s"}, nil, nil)
+	if err != nil {
+		t.Fatalf("NewTokenWebhook failed: %v", err)
+	}
+	if err := notifier.Close(context.Background()); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+
+	if notifier, err := NewTokenWebhook(nil, nil, nil); notifier != nil || err != nil {
+		t.Errorf("expected no token webhook without config, got %v (%v)", notifier, err)
+	}
+}
+
+func TestNewTokenWebhook_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *TokenWebhookConfig
+	}{
+		{"without url", &TokenWebhookConfig{Secret: "s3cret"}},
+		{"relative url", &TokenWebhookConfig{URL: "/tokens", Secret: "s3cret"}},
+		{"unsupported scheme", &TokenWebhookConfig{URL: "ftp://hooks.example.com", Secret: "s3cret"}},
+		{"without secret", &TokenWebhookConfig{URL: "https://hooks.example.com"}},
+		{"invalid timeout", &TokenWebhookConfig{URL: "https://hooks.example.com", Secret: "s3cret", Timeout: "soon"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTokenWebhook(tt.cfg, nil, nil); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// NewTrustedProxies parses trusted proxy CIDRs. A bare address is treated as
+// a single-address prefix.
+func NewTrustedProxies(cidrs []string) ([]netip.Prefix, error) {
+	return parsePrefixes("trusted proxy", cidrs)
+}
+
+// parsePrefixes parses CIDRs, treating a bare address as a single-address
+// prefix; errors name the entry as kind and its index
+func parsePrefixes(kind string, cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for i, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(cidr)
+			if addrErr != nil {
+				return nil, fmt.Errorf("%s %d: invalid CIDR %q: %w", kind, i, cidr, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
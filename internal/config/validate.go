@@ -0,0 +1,374 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/project-kessel/parsec/internal/clients"
+	"github.com/project-kessel/parsec/internal/issuer"
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+	"github.com/project-kessel/parsec/internal/trustdomain"
+	"github.com/project-kessel/parsec/pkg/plugin"
+)
+
+// ValidationError is a configuration error at a config path
+type ValidationError struct {
+	// Path locates the offending configuration, e.g. "issuers[1].ttl_policy"
+	Path string
+
+	// Err describes the problem
+	Err error
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, strings.TrimSpace(e.Err.Error()))
+}
+
+func (e ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors collects every error found while validating a configuration
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return fmt.Sprintf("%d configuration error(s):\n  %s", len(e), strings.Join(lines, "\n  "))
+}
+
+// validator accumulates validation errors
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) check(path string, err error) bool {
+	if err != nil {
+		v.errs = append(v.errs, ValidationError{Path: path, Err: err})
+		return false
+	}
+	return true
+}
+
+// Validate builds every component described by the configuration (trust store
+// validators, data sources, key providers, signers, issuers, claim mappers,
+// exchange and authz server policies, observability) without starting signers
+// or listeners, compiling all CEL and Lua scripts along the way.
+//
+// Unlike the Provider, which stops at the first failure, Validate keeps going
+// and returns every error as ValidationErrors with the config path of each.
+// The transport is used for any HTTP fetches during construction (e.g. JWKS).
+func Validate(cfg *Config, transport http.RoundTripper) error {
+	v := &validator{}
+
+	if cfg.TrustDomain == "" {
+		v.check("trust_domain", fmt.Errorf("trust domain is required"))
+	}
+
+	v.validateClients(cfg)
+	v.validateTrustStore(cfg.TrustStore, transport, newIssuerKeySource(func() (service.Registry, error) {
+		return NewIssuerRegistry(*cfg, transport, nil, nil)
+	}), cfg.Clients != nil)
+	plugins := v.validatePlugins(cfg.Plugins)
+	v.validateDataSources(cfg.DataSources, plugins, transport)
+	v.validateDistributedCache(cfg.DistributedCache)
+	v.check("key_slot_store", checkKeySlotStore(cfg.KeySlotStore))
+	v.validateIssuers(cfg, plugins, transport)
+
+	_, err := parseIssuanceTimeout(cfg.IssuanceTimeout)
+	v.check("issuance_timeout", err)
+	_, err = NewIssuanceLimiter(cfg.Quotas, nil)
+	v.check("quotas", err)
+	_, err = NewRiskEvaluators(cfg.Risk, transport, nil)
+	v.check("risk", err)
+	_, err = NewAudiencePolicy(cfg.AudiencePolicy, cfg.TrustDomain)
+	v.check("audience_policy", err)
+	if cfg.TokenWebhook != nil {
+		_, err = tokenWebhookConfig(cfg.TokenWebhook)
+		v.check("token_webhook", err)
+	}
+	_, err = NewTokenLedger(cfg.TokenLedger, nil)
+	v.check("token_ledger", err)
+	v.validateExchangeServer(cfg.ExchangeServer)
+	if cfg.IntrospectionServer != nil && cfg.IntrospectionServer.Enabled {
+		_, err := newAllowedCallers(cfg.IntrospectionServer.AllowUnauthenticated, cfg.IntrospectionServer.AllowedCallers)
+		v.check("introspection_server.allowed_callers", err)
+	}
+	if cfg.AdminServer != nil && cfg.AdminServer.Enabled {
+		_, err := newAllowedCallers(cfg.AdminServer.AllowUnauthenticated, cfg.AdminServer.AllowedCallers)
+		v.check("admin_server.allowed_callers", err)
+	}
+
+	_, err = NewActorCredentialExtractor(cfg.Server.ActorCredentials)
+	v.check("server.actor_credentials", err)
+
+	_, err = NewTrustedProxies(cfg.Server.TrustedProxies)
+	v.check("server.trusted_proxies", err)
+
+	if _, err := NewProvider(cfg).AuthzServerTokenTypes(); err != nil {
+		v.check("authz_server.token_types", err)
+	}
+	if cfg.AuthzServer != nil {
+		_, err := NewSubjectCredentialExtractor(cfg.AuthzServer.SubjectCredentials)
+		v.check("authz_server.subject_credentials", err)
+		if cfg.AuthzServer.HeaderSizeLimit != nil {
+			_, err := NewHeaderSizeLimit(cfg.AuthzServer.HeaderSizeLimit, cfg.Issuers)
+			v.check("authz_server.header_size_limit", err)
+		}
+		_, err = NewResponseHeaders(cfg.AuthzServer.ResponseHeaders)
+		v.check("authz_server.response_headers", err)
+		_, err = NewDenyCache(cfg.AuthzServer.DenyCache)
+		v.check("authz_server.deny_cache", err)
+		_, err = NewSkipPaths(cfg.AuthzServer.SkipPaths)
+		v.check("authz_server.skip_paths", err)
+		_, err = NewIPPolicy(cfg.AuthzServer.IPPolicy)
+		v.check("authz_server.ip_policy", err)
+		_, err = NewBodyHash(cfg.AuthzServer.BodyHash)
+		v.check("authz_server.body_hash", err)
+		_, err = NewRequestAttributeSources(cfg.AuthzServer.RequestAttributes)
+		v.check("authz_server.request_attributes", err)
+		_, err = NewDynamicMetadata(cfg.AuthzServer.DynamicMetadata)
+		v.check("authz_server.dynamic_metadata", err)
+	}
+
+	if _, err := NewObserver(cfg.Observability); err != nil {
+		v.check("observability", err)
+	}
+
+	if _, err := BuildHTTPFixtureProvider(cfg.Fixtures, nil); err != nil {
+		v.check("fixtures", err)
+	}
+
+	if _, err := BuildFixtureClock(cfg.Fixtures); err != nil {
+		v.check("fixtures", err)
+	}
+
+	if _, err := BuildHTTPRecorder(cfg.Fixtures); err != nil {
+		v.check("fixtures", err)
+	}
+
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+func (v *validator) validateTrustStore(cfg TrustStoreConfig, transport http.RoundTripper, keys trust.KeySource, hasClients bool) {
+	// Validators are only built, so any registry will do as long as clients are configured
+	var clientRegistry clients.Registry
+	if hasClients {
+		clientRegistry = &clients.StaticRegistry{}
+	}
+
+	switch cfg.Type {
+	case "stub_store", "filtered_store":
+	default:
+		v.check("trust_store.type", fmt.Errorf("unknown trust store type: %s (supported: stub_store, filtered_store)", cfg.Type))
+	}
+
+	names := make(map[string]bool)
+	for i, validatorCfg := range cfg.Validators {
+		path := fmt.Sprintf("trust_store.validators[%d]", i)
+		if cfg.Type == "filtered_store" {
+			if validatorCfg.Name == "" {
+				v.check(path+".name", fmt.Errorf("validator name is required for filtered store"))
+			} else if names[validatorCfg.Name] {
+				v.check(path+".name", fmt.Errorf("duplicate validator name: %s", validatorCfg.Name))
+			}
+			names[validatorCfg.Name] = true
+		}
+		_, err := newValidator(validatorCfg.ValidatorConfig, transport, nil, keys, clientRegistry)
+		v.check(path, err)
+	}
+
+	if cfg.Filter != nil {
+		_, err := newValidatorFilter(*cfg.Filter)
+		v.check("trust_store.filter", err)
+	}
+
+	_, err := newResultCache(cfg.ResultCache, nil)
+	v.check("trust_store.result_cache", err)
+
+	_, err = newWarmupConfig(cfg.Warmup)
+	v.check("trust_store.warmup", err)
+}
+
+func (v *validator) validateClients(cfg *Config) {
+	if cfg.Clients == nil {
+		return
+	}
+	_, err := NewClientRegistry(cfg.Clients, nil)
+	v.check("clients", err)
+	if name := cfg.Clients.DataSource; name != "" && !slices.ContainsFunc(cfg.DataSources, func(ds DataSourceConfig) bool { return ds.Name == name }) {
+		v.check("clients.data_source", fmt.Errorf("unknown data source: %s", name))
+	}
+}
+
+func (v *validator) validateDataSources(cfgs []DataSourceConfig, plugins *Plugins, transport http.RoundTripper) {
+	names := make(map[string]bool)
+	for i, dsCfg := range cfgs {
+		path := fmt.Sprintf("data_sources[%d]", i)
+		if dsCfg.Name != "" && names[dsCfg.Name] {
+			v.check(path+".name", fmt.Errorf("duplicate data source name: %s", dsCfg.Name))
+		}
+		names[dsCfg.Name] = true
+
+		_, err := newDataSource(dsCfg, plugins, transport, nil)
+		v.check(path, err)
+	}
+}
+
+func (v *validator) validateDistributedCache(cfg *DistributedCacheConfig) {
+	if cfg == nil {
+		return
+	}
+	// The peer pool is process-wide and discovery may need the cluster, so
+	// both are checked rather than created
+	if cfg.Self == "" {
+		v.check("distributed_cache.self", fmt.Errorf("self URL is required for groupcache peers"))
+	}
+	if cfg.Port <= 0 || cfg.Port > 65535 {
+		v.check("distributed_cache.port", fmt.Errorf("a port between 1 and 65535 is required for groupcache peers"))
+	}
+	if cfg.Secret == "" {
+		v.check("distributed_cache.secret", fmt.Errorf("secret is required for groupcache peers"))
+	}
+	_, err := parseCachePeerNetworks(cfg.AllowedCIDRs)
+	v.check("distributed_cache.allowed_cidrs", err)
+	v.check("distributed_cache.discovery", validatePeerDiscovery(cfg.Discovery))
+	if isPeerDiscovery(cfg.Discovery) {
+		if len(cfg.Peers) > 0 {
+			v.check("distributed_cache.peers", fmt.Errorf("peers cannot be listed with %s peer discovery", cfg.Discovery.Type))
+		}
+	} else if cfg.Self != "" && !slices.Contains(cfg.Peers, cfg.Self) {
+		v.check("distributed_cache.peers", fmt.Errorf("groupcache peers must include self (%s)", cfg.Self))
+	}
+}
+
+// validatePlugins validates plugins one at a time so each error names its
+// entry, returning the valid ones; plugins are never started
+func (v *validator) validatePlugins(cfgs []PluginConfig) *Plugins {
+	plugins := &Plugins{clients: make(map[string]*plugin.Client)}
+	for i, pluginCfg := range cfgs {
+		path := fmt.Sprintf("plugins[%d]", i)
+		if _, exists := plugins.clients[pluginCfg.Name]; exists {
+			v.check(path+".name", fmt.Errorf("duplicate plugin name: %s", pluginCfg.Name))
+			continue
+		}
+		built, err := NewPlugins([]PluginConfig{pluginCfg})
+		if v.check(path, err) {
+			plugins.clients[pluginCfg.Name] = built.clients[pluginCfg.Name]
+		}
+	}
+	return plugins
+}
+
+func (v *validator) validateIssuers(cfg *Config, plugins *Plugins, transport http.RoundTripper) {
+	// Key providers are validated one at a time so each error names its entry;
+	// only valid providers are made available to signers
+	providers := make(map[string]keys.KeyProvider)
+	for i, providerCfg := range cfg.KeyProviders {
+		path := fmt.Sprintf("key_providers[%d]", i)
+		if _, exists := providers[providerCfg.ID]; exists {
+			v.check(path+".id", fmt.Errorf("duplicate key provider id: %s", providerCfg.ID))
+			continue
+		}
+		registry, err := buildKeyProviderRegistry([]KeyProviderConfig{providerCfg})
+		if v.check(path, err) {
+			providers[providerCfg.ID] = registry[providerCfg.ID]
+		}
+	}
+
+	// Signers are registered but not started, so no keys are generated
+	signerRegistry := keys.NewSignerRegistry()
+	slotStore := keys.NewInMemoryKeySlotStore()
+	for i, signerCfg := range cfg.Signers {
+		path := fmt.Sprintf("signers[%d]", i)
+		if signerCfg.KeyProviderID != "" {
+			if _, ok := providers[signerCfg.KeyProviderID]; !ok && hasKeyProvider(cfg.KeyProviders, signerCfg.KeyProviderID) {
+				// The provider itself is invalid and already reported
+				continue
+			}
+		}
+		registry, err := buildSignerRegistry([]SignerConfig{signerCfg}, cfg.TrustDomain, providers, slotStore, nil)
+		if !v.check(path, err) {
+			continue
+		}
+		signer, err := registry.Get(signerCfg.ID)
+		if v.check(path, err) {
+			v.check(path+".id", signerRegistry.Register(signerCfg.ID, signer))
+		}
+	}
+
+	tokenTypes := make(map[string]bool)
+	for i, issuerCfg := range cfg.Issuers {
+		path := fmt.Sprintf("issuers[%d]", i)
+		if issuerCfg.TokenType == "" {
+			v.check(path+".token_type", fmt.Errorf("token_type is required for issuer"))
+		} else if tokenTypes[issuerCfg.TokenType] {
+			v.check(path+".token_type", fmt.Errorf("duplicate issuer for token type %s", issuerCfg.TokenType))
+		}
+		tokenTypes[issuerCfg.TokenType] = true
+
+		var encryption *issuer.EncryptionConfig
+		if issuerCfg.Encryption != nil {
+			if issuerCfg.Type == "reference_token" {
+				v.check(path+".encryption", fmt.Errorf("encryption is not supported for reference_token issuers"))
+				continue
+			}
+			var err error
+			encryption, err = newEncryptionConfig(issuerCfg.Encryption, transport)
+			if !v.check(path+".encryption", err) {
+				continue
+			}
+		}
+
+		if _, err := newIssuer(issuerCfg, signerRegistry, plugins, encryption, nil); !v.check(path, err) {
+			continue
+		}
+
+		_, err := newBulkhead("issuer:"+issuerCfg.TokenType, issuerCfg.Bulkhead, nil, nil)
+		v.check(path+".bulkhead", err)
+	}
+}
+
+func (v *validator) validateExchangeServer(cfg *ExchangeServerConfig) {
+	if cfg == nil {
+		return
+	}
+
+	_, err := NewClaimsFilterRegistry(cfg.ClaimsFilter)
+	v.check("exchange_server.claims_filter", err)
+
+	_, err = NewDelegationPolicy(cfg.Delegation)
+	v.check("exchange_server.delegation", err)
+
+	_, err = NewSelfIssuancePolicy(cfg.ClientCredentials)
+	v.check("exchange_server.client_credentials", err)
+
+	for i, schemaCfg := range cfg.RequestContextSchemas {
+		path := fmt.Sprintf("exchange_server.request_context_schemas[%d]", i)
+		_, err := loadRequestContextSchema(schemaCfg)
+		if v.check(path, err) {
+			v.check(path, trustdomain.ValidatePattern(schemaCfg.TrustDomain))
+		}
+	}
+
+	v.check("exchange_server.allowed_audiences", trustdomain.ValidatePatterns(cfg.AllowedAudiences))
+}
+
+// hasKeyProvider reports whether a key provider with the ID is configured
+func hasKeyProvider(cfgs []KeyProviderConfig, id string) bool {
+	for _, cfg := range cfgs {
+		if cfg.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,304 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestValidate_ValidConfig(t *testing.T) {
+	cfg := &Config{
+		TrustDomain: "parsec.test",
+		TrustStore: TrustStoreConfig{
+			Type: "stub_store",
+			Validators: []NamedValidatorConfig{
+				{ValidatorConfig: ValidatorConfig{Type: "stub_validator"}},
+			},
+		},
+		KeyProviders: []KeyProviderConfig{
+			{ID: "mem", Type: "memory", KeyType: "ec-p256"},
+		},
+		Signers: []SignerConfig{
+			{ID: "main", KeyProviderID: "mem"},
+		},
+		Issuers: []IssuerConfig{
+			{
+				TokenType: "urn:ietf:params:oauth:token-type:txn_token",
+				Type:      "transaction_token",
+				IssuerURL: "https://parsec.test",
+				TTL:       "5m",
+				SignerID:  "main",
+				TransactionContextMappers: []ClaimMapperConfig{
+					{Type: "cel", Script: `{"sub": subject.subject}`},
+				},
+			},
+		},
+	}
+
+	if err := Validate(cfg, nil); err != nil {
+		t.Fatalf("expected valid config, got: %v", err)
+	}
+}
+
+func TestValidate_JWTSVIDSignerAlgorithm(t *testing.T) {
+	newConfig := func(algorithm string) *Config {
+		return &Config{
+			TrustDomain:  "parsec.test",
+			TrustStore:   TrustStoreConfig{Type: "stub_store"},
+			KeyProviders: []KeyProviderConfig{{ID: "mem", Type: "memory", KeyType: "EC-P256", Algorithm: algorithm}},
+			Signers:      []SignerConfig{{ID: "svid", KeyProviderID: "mem"}},
+			Issuers: []IssuerConfig{{
+				TokenType:         "urn:ietf:params:oauth:token-type:jwt",
+				Type:              "jwt_svid",
+				SPIFFETrustDomain: "mesh.example.org",
+				SignerID:          "svid",
+				ClaimMappers:      []ClaimMapperConfig{{Type: "cel", Script: `{"sub": "/workload"}`}},
+			}},
+		}
+	}
+
+	if err := Validate(newConfig(""), nil); err != nil {
+		t.Errorf("expected default ES256 signer to be valid, got: %v", err)
+	}
+
+	err := Validate(newConfig("HS256"), nil)
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) || len(validationErrs) != 1 || validationErrs[0].Path != "issuers[0]" {
+		t.Fatalf("expected an error at issuers[0], got %v", err)
+	}
+}
+
+func TestValidate_AccessTokenClientID(t *testing.T) {
+	newConfig := func(clientID string) *Config {
+		return &Config{
+			TrustDomain:  "parsec.test",
+			TrustStore:   TrustStoreConfig{Type: "stub_store"},
+			KeyProviders: []KeyProviderConfig{{ID: "mem", Type: "memory", KeyType: "EC-P256"}},
+			Signers:      []SignerConfig{{ID: "at", KeyProviderID: "mem"}},
+			Issuers: []IssuerConfig{{
+				TokenType: "urn:ietf:params:oauth:token-type:access_token",
+				Type:      "jwt_access_token",
+				IssuerURL: "https://parsec.test",
+				SignerID:  "at",
+				ClientID:  clientID,
+			}},
+		}
+	}
+
+	if err := Validate(newConfig("parsec"), nil); err != nil {
+		t.Errorf("expected access token issuer with a client_id to be valid, got: %v", err)
+	}
+
+	err := Validate(newConfig(""), nil)
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) || len(validationErrs) != 1 || validationErrs[0].Path != "issuers[0]" {
+		t.Fatalf("expected an error at issuers[0], got %v", err)
+	}
+}
+
+func TestValidate_SizeBudgetIssuerTypes(t *testing.T) {
+	cfg := &Config{
+		TrustDomain: "parsec.test",
+		TrustStore:  TrustStoreConfig{Type: "stub_store"},
+		Issuers: []IssuerConfig{{
+			TokenType:  "urn:ietf:params:oauth:token-type:jwt",
+			Type:       "unsigned",
+			SizeBudget: &SizeBudgetConfig{MaxBytes: 1024},
+		}},
+	}
+
+	err := Validate(cfg, nil)
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) || len(validationErrs) != 1 || validationErrs[0].Path != "issuers[0]" {
+		t.Fatalf("expected an error at issuers[0], got %v", err)
+	}
+}
+
+func TestValidate_DistributedCacheDiscovery(t *testing.T) {
+	newConfig := func(cache *DistributedCacheConfig) *Config {
+		return &Config{
+			TrustDomain:      "parsec.test",
+			TrustStore:       TrustStoreConfig{Type: "stub_store"},
+			DistributedCache: cache,
+		}
+	}
+
+	// Discovery is checked without contacting the cluster
+	valid := &DistributedCacheConfig{
+		Port:      8081,
+		Secret:    "peer-secret",
+		Self:      "http://10.0.0.1:8080",
+		Discovery: &PeerDiscoveryConfig{Type: "kubernetes", Service: "parsec", RefreshInterval: "10s"},
+	}
+	if err := Validate(newConfig(valid), nil); err != nil {
+		t.Errorf("expected kubernetes discovery to be valid, got: %v", err)
+	}
+
+	invalid := &DistributedCacheConfig{
+		AllowedCIDRs: []string{"10.0.0.0"},
+		Self:         "http://10.0.0.1:8080",
+		Peers:        []string{"http://10.0.0.1:8080"},
+		Discovery:    &PeerDiscoveryConfig{Type: "dns_srv"},
+	}
+	err := Validate(newConfig(invalid), nil)
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	var paths []string
+	for _, e := range validationErrs {
+		paths = append(paths, e.Path)
+	}
+	want := []string{
+		"distributed_cache.port", "distributed_cache.secret", "distributed_cache.allowed_cidrs",
+		"distributed_cache.discovery", "distributed_cache.peers",
+	}
+	if !slices.Equal(paths, want) {
+		t.Errorf("expected errors at %v, got %v", want, validationErrs)
+	}
+}
+
+func TestValidate_Clients(t *testing.T) {
+	newConfig := func(clients *ClientsConfig) *Config {
+		return &Config{
+			TrustDomain: "parsec.test",
+			TrustStore: TrustStoreConfig{Type: "stub_store", Validators: []NamedValidatorConfig{
+				{ValidatorConfig: ValidatorConfig{Type: "client_secret_validator", TrustDomain: "clients.parsec.test"}},
+			}},
+			DataSources: []DataSourceConfig{{Name: "clients", Type: "lua", Script: "function fetch(input) return nil end"}},
+			Clients:     clients,
+		}
+	}
+
+	if err := Validate(newConfig(&ClientsConfig{DataSource: "clients"}), nil); err != nil {
+		t.Errorf("expected clients looked up in a data source to be valid, got: %v", err)
+	}
+
+	err := Validate(newConfig(nil), nil)
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) || len(validationErrs) != 1 || validationErrs[0].Path != "trust_store.validators[0]" {
+		t.Errorf("expected the validator to require clients, got %v", err)
+	}
+
+	err = Validate(newConfig(&ClientsConfig{DataSource: "accounts"}), nil)
+	if !errors.As(err, &validationErrs) || len(validationErrs) != 1 || validationErrs[0].Path != "clients.data_source" {
+		t.Errorf("expected an unknown data source error, got %v", err)
+	}
+}
+
+func TestValidate_TrustDomainPatterns(t *testing.T) {
+	cfg := &Config{
+		TrustDomain: "parsec.test",
+		TrustStore:  TrustStoreConfig{Type: "stub_store"},
+		ExchangeServer: &ExchangeServerConfig{
+			AllowedAudiences: []string{"*.parsec.test", "orders.*.parsec.test"},
+		},
+	}
+	err := Validate(cfg, nil)
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) || len(validationErrs) != 1 || validationErrs[0].Path != "exchange_server.allowed_audiences" {
+		t.Errorf("expected the misplaced wildcard to be rejected, got %v", err)
+	}
+
+	cfg.ExchangeServer.AllowedAudiences = []string{"*.parsec.test", "https://billing.parsec.test/*"}
+	if err := Validate(cfg, nil); err != nil {
+		t.Errorf("expected the patterns to be valid, got %v", err)
+	}
+}
+
+func TestValidate_ReportsAllErrors(t *testing.T) {
+	cfg := &Config{
+		TrustDomain: "parsec.test",
+		TrustStore: TrustStoreConfig{
+			Type: "filtered_store",
+			Validators: []NamedValidatorConfig{
+				{ValidatorConfig: ValidatorConfig{Type: "stub_validator"}},
+				{Name: "idp", ValidatorConfig: ValidatorConfig{
+					Type:        "jwt_validator",
+					Issuer:      "https://idp.test",
+					JWKSURL:     "https://idp.test/jwks.json",
+					TrustDomain: "idp.test",
+					Leeway:      "-5s",
+				}},
+			},
+			Filter:      &ValidatorFilterConfig{Type: "cel", Script: "actor.trust_domain =="},
+			ResultCache: &ResultCacheConfig{TTL: "soon"},
+			Warmup:      &WarmupConfig{Backoff: "later"},
+		},
+		DataSources: []DataSourceConfig{
+			{Name: "roles", Type: "lua", Script: "function fetch( end"},
+		},
+		KeyProviders: []KeyProviderConfig{
+			{ID: "mem", Type: "memory", KeyType: "ec-p256"},
+			{ID: "broken", Type: "disk", KeyType: "ec-p256"},
+		},
+		Signers: []SignerConfig{
+			{ID: "main", KeyProviderID: "mem", KeyTTL: "forever"},
+			{ID: "other", KeyProviderID: "broken"},
+		},
+		Issuers: []IssuerConfig{
+			{
+				TokenType: "urn:ietf:params:oauth:token-type:txn_token",
+				Type:      "stub",
+				IssuerURL: "https://parsec.test",
+				TransactionContextMappers: []ClaimMapperConfig{
+					{Type: "cel", Script: `{"sub": `},
+				},
+			},
+			{TokenType: "urn:ietf:params:oauth:token-type:txn_token", Type: "unsigned"},
+			{
+				TokenType: "urn:ietf:params:oauth:token-type:jwt",
+				Type:      "unsigned",
+				Bulkhead:  &BulkheadConfig{MaxConcurrent: 0},
+			},
+		},
+		IssuanceTimeout:     "soon",
+		Server:              ServerConfig{TrustedProxies: []string{"10.0.0.0/8", "proxy.internal"}},
+		IntrospectionServer: &IntrospectionServerConfig{Enabled: true},
+		AdminServer:         &AdminServerConfig{Enabled: true},
+		ExchangeServer: &ExchangeServerConfig{
+			Delegation: &DelegationConfig{Type: "cel"},
+		},
+	}
+
+	err := Validate(cfg, nil)
+
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+
+	wantPaths := []string{
+		"trust_store.validators[0].name",
+		"trust_store.validators[1]",
+		"trust_store.filter",
+		"trust_store.result_cache",
+		"trust_store.warmup",
+		"data_sources[0]",
+		"key_providers[1]",
+		"signers[0]",
+		"issuers[0]",
+		"issuers[1].token_type",
+		"issuers[2].bulkhead",
+		"issuance_timeout",
+		"server.trusted_proxies",
+		"introspection_server.allowed_callers",
+		"admin_server.allowed_callers",
+		"exchange_server.delegation",
+	}
+
+	gotPaths := make(map[string]bool)
+	for _, validationErr := range validationErrs {
+		gotPaths[validationErr.Path] = true
+	}
+	for _, path := range wantPaths {
+		if !gotPaths[path] {
+			t.Errorf("expected an error at %s, got: %v", path, validationErrs)
+		}
+	}
+
+	// signers[1] references an invalid key provider that is already reported
+	if gotPaths["signers[1]"] {
+		t.Errorf("expected no cascading error for signers[1], got: %v", validationErrs)
+	}
+}
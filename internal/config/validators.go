@@ -3,31 +3,94 @@ package config
 import (
 	"fmt"
 	"net/http"
+	"os"
+	"slices"
 	"time"
 
+	"github.com/project-kessel/parsec/internal/clients"
+	"github.com/project-kessel/parsec/internal/clock"
+	luaservices "github.com/project-kessel/parsec/internal/lua"
 	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/rhidentity"
 	"github.com/project-kessel/parsec/internal/trust"
+	"github.com/project-kessel/parsec/internal/trustdomain"
 )
 
 // NewTrustStore creates a trust store from configuration
-func NewTrustStore(cfg TrustStoreConfig, transport http.RoundTripper) (trust.Store, error) {
+// The clock is the time source for token validation (nil uses the system clock).
+// Keys are parsec's own issuer keys, used by txn_token_validator.
+func NewTrustStore(cfg TrustStoreConfig, transport http.RoundTripper, clk clock.Clock, keys trust.KeySource, clientRegistry clients.Registry) (trust.Store, error) {
+	resultCache, err := newResultCache(cfg.ResultCache, clk)
+	if err != nil {
+		return nil, err
+	}
+
 	switch cfg.Type {
 	case "stub_store":
-		return newStubStore(cfg, transport)
+		return newStubStore(cfg, transport, clk, keys, clientRegistry, resultCache)
 	case "filtered_store":
-		return newFilteredStore(cfg, transport)
+		return newFilteredStore(cfg, transport, clk, keys, clientRegistry, resultCache)
 	default:
 		return nil, fmt.Errorf("unknown trust store type: %s (supported: stub_store, filtered_store)", cfg.Type)
 	}
 }
 
+// newResultCache creates the trust store's validation result cache, or nil if
+// it isn't configured
+func newResultCache(cfg *ResultCacheConfig, clk clock.Clock) (*trust.ResultCache, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.MaxEntries < 0 {
+		return nil, fmt.Errorf("result_cache.max_entries must not be negative")
+	}
+	ttl, err := parseOptionalDuration(cfg.TTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid result_cache.ttl: %w", err)
+	}
+	if ttl < 0 {
+		return nil, fmt.Errorf("result_cache.ttl must not be negative")
+	}
+	return trust.NewResultCache(trust.ResultCacheConfig{
+		MaxEntries: cfg.MaxEntries,
+		TTL:        ttl,
+		Clock:      clk,
+	}), nil
+}
+
+// newWarmupConfig converts the trust store warmup configuration
+func newWarmupConfig(cfg *WarmupConfig) (trust.WarmupConfig, error) {
+	if cfg == nil {
+		return trust.WarmupConfig{}, nil
+	}
+	if cfg.Attempts < 0 {
+		return trust.WarmupConfig{}, fmt.Errorf("warmup.attempts must not be negative")
+	}
+	backoff, err := parseOptionalDuration(cfg.Backoff)
+	if err != nil {
+		return trust.WarmupConfig{}, fmt.Errorf("invalid warmup.backoff: %w", err)
+	}
+	attemptTimeout, err := parseOptionalDuration(cfg.AttemptTimeout)
+	if err != nil {
+		return trust.WarmupConfig{}, fmt.Errorf("invalid warmup.attempt_timeout: %w", err)
+	}
+	return trust.WarmupConfig{
+		Attempts:       cfg.Attempts,
+		Backoff:        backoff,
+		AttemptTimeout: attemptTimeout,
+	}, nil
+}
+
 // newStubStore creates a stub trust store (no filtering)
-func newStubStore(cfg TrustStoreConfig, transport http.RoundTripper) (trust.Store, error) {
+func newStubStore(cfg TrustStoreConfig, transport http.RoundTripper, clk clock.Clock, keys trust.KeySource, clientRegistry clients.Registry, resultCache *trust.ResultCache) (trust.Store, error) {
 	store := trust.NewStubStore()
+	if resultCache != nil {
+		store.WithResultCache(resultCache)
+	}
 
 	// Add validators
 	for _, validatorCfg := range cfg.Validators {
-		validator, err := newValidator(validatorCfg.ValidatorConfig, transport)
+		validator, err := newValidator(validatorCfg.ValidatorConfig, transport, clk, keys, clientRegistry)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create validator: %w", err)
 		}
@@ -38,12 +101,17 @@ func newStubStore(cfg TrustStoreConfig, transport http.RoundTripper) (trust.Stor
 }
 
 // newFilteredStore creates a filtered trust store with validator filtering
-func newFilteredStore(cfg TrustStoreConfig, transport http.RoundTripper) (trust.Store, error) {
+func newFilteredStore(cfg TrustStoreConfig, transport http.RoundTripper, clk clock.Clock, keys trust.KeySource, clientRegistry clients.Registry, resultCache *trust.ResultCache) (trust.Store, error) {
 	var opts []trust.FilteredStoreOption
+	if resultCache != nil {
+		opts = append(opts, trust.WithResultCache(resultCache))
+	}
 
 	// Add validator filter if configured
+	var filter trust.ValidatorFilter
 	if cfg.Filter != nil {
-		filter, err := newValidatorFilter(*cfg.Filter)
+		var err error
+		filter, err = newValidatorFilter(*cfg.Filter)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create validator filter: %w", err)
 		}
@@ -61,7 +129,7 @@ func newFilteredStore(cfg TrustStoreConfig, transport http.RoundTripper) (trust.
 			return nil, fmt.Errorf("validator name is required for filtered store")
 		}
 
-		validator, err := newValidator(validatorCfg.ValidatorConfig, transport)
+		validator, err := newValidator(validatorCfg.ValidatorConfig, transport, clk, keys, clientRegistry)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create validator %s: %w", validatorCfg.Name, err)
 		}
@@ -69,25 +137,114 @@ func newFilteredStore(cfg TrustStoreConfig, transport http.RoundTripper) (trust.
 		store.AddValidator(validatorCfg.Name, validator)
 	}
 
+	// A static policy naming a validator that does not exist is a typo, not a
+	// deliberate denial
+	if static, ok := filter.(*trust.StaticValidatorFilter); ok {
+		for _, name := range static.ValidatorNames() {
+			if !slices.ContainsFunc(cfg.Validators, func(v NamedValidatorConfig) bool { return v.Name == name }) {
+				return nil, fmt.Errorf("static filter refers to unknown validator %s", name)
+			}
+		}
+	}
+
 	return store, nil
 }
 
-// newValidator creates a validator from configuration
-func newValidator(cfg ValidatorConfig, transport http.RoundTripper) (trust.Validator, error) {
+// newValidator creates a validator from configuration, with its result hooks
+func newValidator(cfg ValidatorConfig, transport http.RoundTripper, clk clock.Clock, keys trust.KeySource, clientRegistry clients.Registry) (trust.Validator, error) {
+	validator, err := newBaseValidator(cfg, transport, clk, keys, clientRegistry)
+	if err != nil || len(cfg.Hooks) == 0 {
+		return validator, err
+	}
+
+	hooks := make([]trust.ResultHook, len(cfg.Hooks))
+	for i, hookCfg := range cfg.Hooks {
+		hooks[i], err = newResultHook(hookCfg)
+		if err != nil {
+			return nil, fmt.Errorf("hooks[%d]: %w", i, err)
+		}
+	}
+	return trust.NewHookedValidator(validator, hooks...), nil
+}
+
+// newResultHook creates a validator result hook from configuration
+func newResultHook(cfg ResultHookConfig) (trust.ResultHook, error) {
+	script := cfg.Script
+	switch {
+	case script != "" && cfg.ScriptFile != "":
+		return nil, fmt.Errorf("%s hook accepts only one of script or script_file", cfg.Type)
+	case cfg.ScriptFile != "":
+		data, err := os.ReadFile(cfg.ScriptFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read hook script file: %w", err)
+		}
+		script = string(data)
+	}
+
+	switch cfg.Type {
+	case "cel":
+		return trust.NewCelResultHook(script)
+	case "lua":
+		var configSource luaservices.ConfigSource
+		if cfg.Config != nil {
+			configSource = luaservices.NewMapConfigSource(cfg.Config)
+		}
+		return trust.NewLuaResultHook(script, configSource)
+	default:
+		return nil, fmt.Errorf("unknown result hook type: %s (supported: cel, lua)", cfg.Type)
+	}
+}
+
+// newBaseValidator creates the validator of a configuration, without hooks
+func newBaseValidator(cfg ValidatorConfig, transport http.RoundTripper, clk clock.Clock, keys trust.KeySource, clientRegistry clients.Registry) (trust.Validator, error) {
 	switch cfg.Type {
 	case "jwt_validator":
-		return newJWTValidator(cfg, transport)
+		return newJWTValidator(cfg, transport, clk)
+	case "introspection_validator":
+		return newIntrospectionValidator(cfg, transport)
+	case "spiffe_validator":
+		return newSPIFFEValidator(cfg, transport, clk)
 	case "json_validator":
 		return newJSONValidator(cfg)
+	case "rh_identity_validator":
+		return newRHIdentityValidator(cfg)
+	case "client_secret_validator":
+		return newClientSecretValidator(cfg, clientRegistry)
+	case "txn_token_validator":
+		return newTransactionTokenValidator(cfg, clk, keys)
 	case "stub_validator":
 		return newStubValidator(cfg)
 	default:
-		return nil, fmt.Errorf("unknown validator type: %s (supported: jwt_validator, json_validator, stub_validator)", cfg.Type)
+		return nil, fmt.Errorf("unknown validator type: %s (supported: jwt_validator, introspection_validator, spiffe_validator, json_validator, rh_identity_validator, client_secret_validator, txn_token_validator, stub_validator)", cfg.Type)
+	}
+}
+
+// parseLeeway parses the clock skew leeway of a validator or issuer
+func parseLeeway(s string) (time.Duration, error) {
+	leeway, err := parseOptionalDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid leeway: %w", err)
+	}
+	if leeway < 0 {
+		return 0, fmt.Errorf("invalid leeway: must not be negative")
 	}
+	return leeway, nil
+}
+
+// parseNotBeforeSkew parses the window an issuer backdates nbf by
+func parseNotBeforeSkew(s string) (time.Duration, error) {
+	skew, err := parseOptionalDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid not_before_skew: %w", err)
+	}
+	if skew < 0 {
+		return 0, fmt.Errorf("invalid not_before_skew: must not be negative")
+	}
+	return skew, nil
 }
 
 // newJWTValidator creates a JWT validator
-func newJWTValidator(cfg ValidatorConfig, transport http.RoundTripper) (trust.Validator, error) {
+func newJWTValidator(cfg ValidatorConfig, transport http.RoundTripper, clk clock.Clock) (trust.Validator, error) {
 	if cfg.Issuer == "" {
 		return nil, fmt.Errorf("jwt_validator requires issuer")
 	}
@@ -95,10 +252,20 @@ func newJWTValidator(cfg ValidatorConfig, transport http.RoundTripper) (trust.Va
 		return nil, fmt.Errorf("jwt_validator requires trust_domain")
 	}
 
+	leeway, err := parseLeeway(cfg.Leeway)
+	if err != nil {
+		return nil, err
+	}
+
+	// The JWKS of all validators are fetched in parallel by the trust store warmup
 	validatorCfg := trust.JWTValidatorConfig{
-		Issuer:      cfg.Issuer,
-		JWKSURL:     cfg.JWKSURL,
-		TrustDomain: cfg.TrustDomain,
+		Issuer:            cfg.Issuer,
+		JWKSURL:           cfg.JWKSURL,
+		TrustDomain:       cfg.TrustDomain,
+		Clock:             clk,
+		Leeway:            leeway,
+		ClientIDs:         cfg.ClientIDs,
+		DeferInitialFetch: true,
 	}
 
 	// Parse refresh interval if provided
@@ -110,6 +277,14 @@ func newJWTValidator(cfg ValidatorConfig, transport http.RoundTripper) (trust.Va
 		validatorCfg.RefreshInterval = duration
 	}
 
+	if cfg.JWKSCacheDir != "" {
+		store, err := trust.NewFileJWKSStore(cfg.JWKSCacheDir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwks_cache_dir: %w", err)
+		}
+		validatorCfg.JWKSStore = store
+	}
+
 	// Use provided transport if available
 	if transport != nil {
 		validatorCfg.HTTPClient = &http.Client{
@@ -120,6 +295,109 @@ func newJWTValidator(cfg ValidatorConfig, transport http.RoundTripper) (trust.Va
 	return trust.NewJWTValidator(validatorCfg)
 }
 
+// newTransactionTokenValidator creates a validator for parsec's own transaction tokens
+func newTransactionTokenValidator(cfg ValidatorConfig, clk clock.Clock, keys trust.KeySource) (trust.Validator, error) {
+	if cfg.Issuer == "" {
+		return nil, fmt.Errorf("txn_token_validator requires issuer")
+	}
+	if cfg.TrustDomain == "" {
+		return nil, fmt.Errorf("txn_token_validator requires trust_domain")
+	}
+	if len(cfg.Audiences) == 0 {
+		return nil, fmt.Errorf("txn_token_validator requires audiences")
+	}
+	if err := trustdomain.ValidatePatterns(cfg.Audiences); err != nil {
+		return nil, fmt.Errorf("txn_token_validator audiences: %w", err)
+	}
+	if keys == nil {
+		return nil, fmt.Errorf("txn_token_validator requires parsec's issuer keys")
+	}
+	leeway, err := parseLeeway(cfg.Leeway)
+	if err != nil {
+		return nil, err
+	}
+
+	return trust.NewTransactionTokenValidator(trust.TransactionTokenValidatorConfig{
+		Issuer:      cfg.Issuer,
+		TrustDomain: cfg.TrustDomain,
+		Audiences:   cfg.Audiences,
+		Keys:        keys,
+		Clock:       clk,
+		Leeway:      leeway,
+	})
+}
+
+// newIntrospectionValidator creates an OAuth 2.0 token introspection validator
+func newIntrospectionValidator(cfg ValidatorConfig, transport http.RoundTripper) (trust.Validator, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("introspection_validator requires endpoint")
+	}
+	if cfg.TrustDomain == "" {
+		return nil, fmt.Errorf("introspection_validator requires trust_domain")
+	}
+
+	validatorCfg := trust.IntrospectionValidatorConfig{
+		Endpoint:     cfg.Endpoint,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		Issuer:       cfg.Issuer,
+		TrustDomain:  cfg.TrustDomain,
+	}
+
+	// Use provided transport if available
+	if transport != nil {
+		validatorCfg.HTTPClient = &http.Client{
+			Transport: transport,
+			Timeout:   10 * time.Second,
+		}
+	}
+
+	return trust.NewIntrospectionValidator(validatorCfg)
+}
+
+// newSPIFFEValidator creates a SPIFFE JWT-SVID validator
+func newSPIFFEValidator(cfg ValidatorConfig, transport http.RoundTripper, clk clock.Clock) (trust.Validator, error) {
+	if cfg.TrustDomain == "" {
+		return nil, fmt.Errorf("spiffe_validator requires trust_domain")
+	}
+	if cfg.BundleURL == "" {
+		return nil, fmt.Errorf("spiffe_validator requires bundle_url")
+	}
+	if err := trustdomain.ValidatePatterns(cfg.Audiences); err != nil {
+		return nil, fmt.Errorf("spiffe_validator audiences: %w", err)
+	}
+
+	leeway, err := parseLeeway(cfg.Leeway)
+	if err != nil {
+		return nil, err
+	}
+
+	validatorCfg := trust.SPIFFEValidatorConfig{
+		TrustDomain: cfg.TrustDomain,
+		BundleURL:   cfg.BundleURL,
+		Audiences:   cfg.Audiences,
+		Clock:       clk,
+		Leeway:      leeway,
+	}
+
+	if cfg.RefreshInterval != "" {
+		duration, err := time.ParseDuration(cfg.RefreshInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid refresh_interval: %w", err)
+		}
+		validatorCfg.RefreshInterval = duration
+	}
+
+	// Use provided transport if available
+	if transport != nil {
+		validatorCfg.HTTPClient = &http.Client{
+			Transport: transport,
+		}
+	}
+
+	return trust.NewSPIFFEValidator(validatorCfg)
+}
+
 // newJSONValidator creates a JSON validator
 func newJSONValidator(cfg ValidatorConfig) (trust.Validator, error) {
 	if cfg.TrustDomain == "" {
@@ -128,9 +406,48 @@ func newJSONValidator(cfg ValidatorConfig) (trust.Validator, error) {
 
 	return trust.NewJSONValidator(
 		trust.WithTrustDomain(cfg.TrustDomain),
+		trust.WithRequireIssuer(cfg.RequireIssuer),
 	), nil
 }
 
+// newRHIdentityValidator creates an x-rh-identity validator
+func newRHIdentityValidator(cfg ValidatorConfig) (trust.Validator, error) {
+	if cfg.TrustDomain == "" {
+		return nil, fmt.Errorf("rh_identity_validator requires trust_domain")
+	}
+
+	identityTypes := make([]rhidentity.Type, 0, len(cfg.IdentityTypes))
+	for _, t := range cfg.IdentityTypes {
+		switch identityType := rhidentity.Type(t); identityType {
+		case rhidentity.TypeUser, rhidentity.TypeServiceAccount, rhidentity.TypeSystem, rhidentity.TypeAssociate, rhidentity.TypeX509:
+			identityTypes = append(identityTypes, identityType)
+		default:
+			return nil, fmt.Errorf("unknown identity type: %s (supported: User, ServiceAccount, System, Associate, X509)", t)
+		}
+	}
+
+	return trust.NewRHIdentityValidator(trust.RHIdentityValidatorConfig{
+		TrustDomain:   cfg.TrustDomain,
+		Issuer:        cfg.Issuer,
+		IdentityTypes: identityTypes,
+	})
+}
+
+// newClientSecretValidator creates a validator of client secret credentials
+// against the client registry
+func newClientSecretValidator(cfg ValidatorConfig, clientRegistry clients.Registry) (trust.Validator, error) {
+	if cfg.TrustDomain == "" {
+		return nil, fmt.Errorf("client_secret_validator requires trust_domain")
+	}
+	if clientRegistry == nil {
+		return nil, fmt.Errorf("client_secret_validator requires clients to be configured")
+	}
+	return clients.NewValidator(clients.ValidatorConfig{
+		Registry:    clientRegistry,
+		TrustDomain: cfg.TrustDomain,
+	})
+}
+
 // newStubValidator creates a stub validator
 func newStubValidator(cfg ValidatorConfig) (trust.Validator, error) {
 	// Convert credential type strings to CredentialType
@@ -155,10 +472,36 @@ func newStubValidator(cfg ValidatorConfig) (trust.Validator, error) {
 func newValidatorFilter(cfg ValidatorFilterConfig) (trust.ValidatorFilter, error) {
 	switch cfg.Type {
 	case "cel":
-		if cfg.Script == "" {
-			return nil, fmt.Errorf("cel filter requires script")
+		script, err := loadValidatorFilterScript(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return trust.NewCelValidatorFilter(script)
+	case "rego":
+		policy, err := loadValidatorFilterScript(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return trust.NewRegoValidatorFilter(policy, cfg.Query)
+	case "static":
+		if len(cfg.Mappings) == 0 {
+			return nil, fmt.Errorf("static filter requires at least one mapping")
+		}
+		mappings := make([]trust.ValidatorMapping, len(cfg.Mappings))
+		for i, m := range cfg.Mappings {
+			if m.TrustDomain == "" && m.Subject == "" {
+				return nil, fmt.Errorf("static filter mapping %d requires trust_domain or subject", i)
+			}
+			if err := trustdomain.ValidatePattern(m.TrustDomain); err != nil {
+				return nil, fmt.Errorf("static filter mapping %d: %w", i, err)
+			}
+			mappings[i] = trust.ValidatorMapping{
+				TrustDomain: m.TrustDomain,
+				Subject:     m.Subject,
+				Validators:  m.Validators,
+			}
 		}
-		return trust.NewCelValidatorFilter(cfg.Script)
+		return trust.NewStaticValidatorFilter(mappings, cfg.DefaultValidators), nil
 	case "any":
 		// Composite filter - allows if any sub-filter allows
 		if len(cfg.Filters) == 0 {
@@ -180,8 +523,28 @@ func newValidatorFilter(cfg ValidatorFilterConfig) (trust.ValidatorFilter, error
 		// Passthrough filter - allows all validators
 		return &passthroughValidatorFilter{}, nil
 	default:
-		return nil, fmt.Errorf("unknown validator filter type: %s (supported: cel, any, passthrough)", cfg.Type)
+		return nil, fmt.Errorf("unknown validator filter type: %s (supported: cel, rego, static, any, passthrough)", cfg.Type)
+	}
+}
+
+// loadValidatorFilterScript returns the inline or file script of a cel or
+// rego validator filter
+func loadValidatorFilterScript(cfg ValidatorFilterConfig) (string, error) {
+	script := cfg.Script
+	switch {
+	case script != "" && cfg.ScriptFile != "":
+		return "", fmt.Errorf("%s filter accepts only one of script or script_file", cfg.Type)
+	case cfg.ScriptFile != "":
+		data, err := os.ReadFile(cfg.ScriptFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read filter script file: %w", err)
+		}
+		script = string(data)
+	}
+	if script == "" {
+		return "", fmt.Errorf("%s filter requires script or script_file", cfg.Type)
 	}
+	return script, nil
 }
 
 // passthroughValidatorFilter allows all validators (no filtering)
@@ -202,7 +565,9 @@ func parseCredentialType(s string) (trust.CredentialType, error) {
 		return trust.CredentialTypeJSON, nil
 	case "mtls":
 		return trust.CredentialTypeMTLS, nil
+	case "client_secret":
+		return trust.CredentialTypeClientSecret, nil
 	default:
-		return "", fmt.Errorf("unknown credential type: %s (supported: bearer, jwt, json, mtls)", s)
+		return "", fmt.Errorf("unknown credential type: %s (supported: bearer, jwt, json, mtls, client_secret)", s)
 	}
 }
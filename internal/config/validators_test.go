@@ -0,0 +1,332 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/httpfixture"
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestNewTrustStore_FromYAML(t *testing.T) {
+	ctx := context.Background()
+
+	introspection := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]any{"active": false}
+		if r.PostFormValue("token") == "opaque-token" {
+			body = map[string]any{"active": true, "sub": "alice", "iss": "https://idp.example.com"}
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	defer introspection.Close()
+
+	bundle, err := httpfixture.NewJWKSFixture(httpfixture.JWKSFixtureConfig{
+		Issuer:  "https://spire.example.org",
+		JWKSURL: "https://spire.example.org/keys",
+	})
+	if err != nil {
+		t.Fatalf("failed to create JWKS fixture: %v", err)
+	}
+	transport := httpfixture.NewTransport(httpfixture.TransportConfig{
+		Provider: bundle,
+		Fallback: http.DefaultTransport,
+	})
+
+	dir := t.TempDir()
+	filterPath := filepath.Join(dir, "filter.cel")
+	if err := os.WriteFile(filterPath, []byte(`validator_name == "mesh" || actor.trust_domain == "example.org"`), 0o600); err != nil {
+		t.Fatalf("failed to write filter script: %v", err)
+	}
+
+	configPath := filepath.Join(dir, "parsec.yaml")
+	yaml := fmt.Sprintf(`
+trust_store:
+  type: filtered_store
+  validators:
+    - name: mesh
+      type: spiffe_validator
+      trust_domain: example.org
+      bundle_url: https://spire.example.org/keys
+      audiences: [parsec]
+    - name: idp
+      type: introspection_validator
+      endpoint: %s
+      issuer: https://idp.example.com
+      trust_domain: example.com
+  filter:
+    type: cel
+    script_file: %s
+`, introspection.URL, filterPath)
+	if err := os.WriteFile(configPath, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	loader, err := NewLoader(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg, err := loader.Get()
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+
+	store, err := NewTrustStore(cfg.TrustStore, transport, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create trust store: %v", err)
+	}
+
+	svid, err := bundle.CreateAndSignToken(map[string]any{
+		"sub": "spiffe://example.org/ns/default/sa/gateway",
+		"aud": []string{"parsec"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create SVID: %v", err)
+	}
+
+	actor, err := store.Validate(ctx, &trust.BearerCredential{Token: svid})
+	if err != nil {
+		t.Fatalf("expected SVID to validate: %v", err)
+	}
+	if actor.Subject != "spiffe://example.org/ns/default/sa/gateway" {
+		t.Errorf("expected SPIFFE ID subject, got %q", actor.Subject)
+	}
+
+	subject, err := store.Validate(ctx, &trust.BearerCredential{Token: "opaque-token"})
+	if err != nil {
+		t.Fatalf("expected opaque token to validate by introspection: %v", err)
+	}
+	if subject.Subject != "alice" || subject.TrustDomain != "example.com" {
+		t.Errorf("expected alice in example.com, got %q in %q", subject.Subject, subject.TrustDomain)
+	}
+
+	t.Run("filter allows mesh actors every validator", func(t *testing.T) {
+		scoped, err := store.ForActor(ctx, actor, &request.RequestAttributes{})
+		if err != nil {
+			t.Fatalf("ForActor failed: %v", err)
+		}
+		if _, err := scoped.Validate(ctx, &trust.BearerCredential{Token: "opaque-token"}); err != nil {
+			t.Errorf("expected introspection validator to be available: %v", err)
+		}
+	})
+
+	t.Run("filter restricts other actors to the mesh validator", func(t *testing.T) {
+		other := &trust.Result{Subject: "svc", TrustDomain: "other.org"}
+		scoped, err := store.ForActor(ctx, other, &request.RequestAttributes{})
+		if err != nil {
+			t.Fatalf("ForActor failed: %v", err)
+		}
+		if _, err := scoped.Validate(ctx, &trust.BearerCredential{Token: "opaque-token"}); err == nil {
+			t.Error("expected introspection validator to be filtered out")
+		}
+		if _, err := scoped.Validate(ctx, &trust.BearerCredential{Token: svid}); err != nil {
+			t.Errorf("expected mesh validator to remain available: %v", err)
+		}
+	})
+}
+
+func TestNewValidator_RequiredFields(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ValidatorConfig
+	}{
+		{"introspection without endpoint", ValidatorConfig{Type: "introspection_validator", TrustDomain: "example.com"}},
+		{"introspection without trust domain", ValidatorConfig{Type: "introspection_validator", Endpoint: "https://idp.example.com/introspect"}},
+		{"spiffe without bundle", ValidatorConfig{Type: "spiffe_validator", TrustDomain: "example.org"}},
+		{"spiffe without trust domain", ValidatorConfig{Type: "spiffe_validator", BundleURL: "https://spire.example.org/keys"}},
+		{"txn token without issuer", ValidatorConfig{Type: "txn_token_validator", TrustDomain: "parsec.test"}},
+		{"txn token without audiences", ValidatorConfig{Type: "txn_token_validator", Issuer: "https://parsec.test", TrustDomain: "parsec.test"}},
+		{"txn token without issuer keys", ValidatorConfig{Type: "txn_token_validator", Issuer: "https://parsec.test", TrustDomain: "parsec.test", Audiences: []string{"parsec.test"}}},
+		{"rh identity without trust domain", ValidatorConfig{Type: "rh_identity_validator"}},
+		{"rh identity with unknown identity type", ValidatorConfig{Type: "rh_identity_validator", TrustDomain: "console.redhat.com", IdentityTypes: []string{"Robot"}}},
+		{"client secret without trust domain", ValidatorConfig{Type: "client_secret_validator"}},
+		{"client secret without clients", ValidatorConfig{Type: "client_secret_validator", TrustDomain: "clients.example.com"}},
+		{"unknown type", ValidatorConfig{Type: "kerberos_validator"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newValidator(tt.cfg, nil, nil, nil, nil); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestNewValidator_RHIdentity(t *testing.T) {
+	validator, err := newValidator(ValidatorConfig{
+		Type:          "rh_identity_validator",
+		TrustDomain:   "console.redhat.com",
+		IdentityTypes: []string{"User"},
+	}, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("newValidator failed: %v", err)
+	}
+
+	result, err := validator.Validate(context.Background(), &trust.JSONCredential{RawJSON: []byte(`{"identity": {
+		"org_id": "12345", "type": "User", "user": {"username": "alice"}
+	}}`)})
+	if err != nil || result.Subject != "alice" || result.TrustDomain != "console.redhat.com" {
+		t.Errorf("expected alice of console.redhat.com, got %v, %v", result, err)
+	}
+}
+
+func TestNewValidatorFilter_ScriptSources(t *testing.T) {
+	if _, err := newValidatorFilter(ValidatorFilterConfig{Type: "cel"}); err == nil {
+		t.Error("expected error without script")
+	}
+	if _, err := newValidatorFilter(ValidatorFilterConfig{Type: "cel", Script: "true", ScriptFile: "filter.cel"}); err == nil {
+		t.Error("expected error with both script and script_file")
+	}
+	if _, err := newValidatorFilter(ValidatorFilterConfig{Type: "cel", ScriptFile: filepath.Join(t.TempDir(), "missing.cel")}); err == nil {
+		t.Error("expected error for missing script file")
+	}
+}
+
+func TestNewValidatorFilter_Rego(t *testing.T) {
+	policyFile := filepath.Join(t.TempDir(), "filter.rego")
+	policy := "package mesh\n\nallowed if input.actor.trust_domain == \"prod\"\n"
+	if err := os.WriteFile(policyFile, []byte(policy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	filter, err := newValidatorFilter(ValidatorFilterConfig{Type: "rego", ScriptFile: policyFile, Query: "data.mesh.allowed"})
+	if err != nil {
+		t.Fatalf("newValidatorFilter failed: %v", err)
+	}
+	if allowed, err := filter.IsAllowed(&trust.Result{TrustDomain: "prod"}, "any", nil); err != nil || !allowed {
+		t.Errorf("expected prod actor to be allowed, got %v, %v", allowed, err)
+	}
+	if allowed, err := filter.IsAllowed(&trust.Result{TrustDomain: "dev"}, "any", nil); err != nil || allowed {
+		t.Errorf("expected dev actor to be denied, got %v, %v", allowed, err)
+	}
+
+	if _, err := newValidatorFilter(ValidatorFilterConfig{Type: "rego"}); err == nil {
+		t.Error("expected error without policy")
+	}
+}
+
+func TestNewTrustStore_StaticFilter(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := TrustStoreConfig{
+		Type: "filtered_store",
+		Validators: []NamedValidatorConfig{
+			{Name: "customer-idp", ValidatorConfig: ValidatorConfig{Type: "stub_validator"}},
+			{Name: "internal-idp", ValidatorConfig: ValidatorConfig{Type: "stub_validator"}},
+		},
+		Filter: &ValidatorFilterConfig{
+			Type: "static",
+			Mappings: []ValidatorMappingConfig{
+				{TrustDomain: "prod", Subject: "gateway-*", Validators: []string{"customer-idp", "internal-idp"}},
+			},
+			DefaultValidators: []string{"internal-idp"},
+		},
+	}
+
+	store, err := NewTrustStore(cfg, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create trust store: %v", err)
+	}
+	scoped, err := store.ForActor(ctx, &trust.Result{Subject: "gateway-eu", TrustDomain: "prod"}, &request.RequestAttributes{})
+	if err != nil {
+		t.Fatalf("ForActor failed: %v", err)
+	}
+	if _, err := scoped.Validate(ctx, &trust.BearerCredential{Token: "token"}); err != nil {
+		t.Errorf("expected mapped actor to have validators: %v", err)
+	}
+
+	t.Run("unknown validator", func(t *testing.T) {
+		cfg := cfg
+		cfg.Filter = &ValidatorFilterConfig{
+			Type:     "static",
+			Mappings: []ValidatorMappingConfig{{TrustDomain: "prod", Validators: []string{"custmer-idp"}}},
+		}
+		if _, err := NewTrustStore(cfg, nil, nil, nil, nil); err == nil {
+			t.Error("expected error for unknown validator")
+		}
+	})
+
+	t.Run("mapping matching every actor", func(t *testing.T) {
+		cfg := cfg
+		cfg.Filter = &ValidatorFilterConfig{
+			Type:     "static",
+			Mappings: []ValidatorMappingConfig{{Validators: []string{"customer-idp"}}},
+		}
+		if _, err := NewTrustStore(cfg, nil, nil, nil, nil); err == nil {
+			t.Error("expected error for mapping without trust_domain or subject")
+		}
+	})
+}
+
+func TestNewTrustStore_ResultHooks(t *testing.T) {
+	dir := t.TempDir()
+	luaPath := filepath.Join(dir, "tenant.lua")
+	lua := `
+function enrich(result)
+  return {claims = {tenant = config.get("tenant")}}
+end
+`
+	if err := os.WriteFile(luaPath, []byte(lua), 0o600); err != nil {
+		t.Fatalf("failed to write hook script: %v", err)
+	}
+
+	configPath := filepath.Join(dir, "parsec.yaml")
+	yaml := fmt.Sprintf(`
+trust_store:
+  type: filtered_store
+  validators:
+    - name: idp
+      type: stub_validator
+      hooks:
+        - type: cel
+          script: '{"subject": result.subject.upperAscii(), "trust_domain": "idp.example.com"}'
+        - type: lua
+          script_file: %s
+          config:
+            tenant: acme
+`, luaPath)
+	if err := os.WriteFile(configPath, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	loader, err := NewLoader(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg, err := loader.Get()
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+
+	store, err := NewTrustStore(cfg.TrustStore, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create trust store: %v", err)
+	}
+	result, err := store.Validate(context.Background(), &trust.BearerCredential{Token: "token"})
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if result.Subject != "TEST-SUBJECT" || result.TrustDomain != "idp.example.com" || result.Claims["tenant"] != "acme" {
+		t.Errorf("expected hooks to change the result, got %+v", result)
+	}
+
+	t.Run("invalid hooks", func(t *testing.T) {
+		for _, hook := range []ResultHookConfig{
+			{Type: "wasm", Script: "enrich"},
+			{Type: "cel"},
+			{Type: "cel", Script: "{}", ScriptFile: luaPath},
+			{Type: "lua", Script: "function fetch(input) end"},
+		} {
+			if _, err := newValidator(ValidatorConfig{Type: "stub_validator", Hooks: []ResultHookConfig{hook}}, nil, nil, nil, nil); err == nil {
+				t.Errorf("expected error for %+v", hook)
+			}
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package datasource
+
+import (
+	"context"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// BulkheadDataSource limits how many fetches run against a data source at
+// once, so a slow backend cannot hold every server goroutine
+// Wrap the source before caching so cache hits never wait for a slot.
+type BulkheadDataSource struct {
+	source   service.DataSource
+	bulkhead *service.Bulkhead
+}
+
+// NewBulkheadDataSource wraps a data source with a bulkhead
+// The result implements Cacheable if the source does.
+func NewBulkheadDataSource(source service.DataSource, bulkhead *service.Bulkhead) service.DataSource {
+	ds := &BulkheadDataSource{
+		source:   source,
+		bulkhead: bulkhead,
+	}
+	if cacheable, ok := source.(service.Cacheable); ok {
+		return &cacheableBulkheadDataSource{BulkheadDataSource: ds, cacheable: cacheable}
+	}
+	return ds
+}
+
+// Name forwards to the underlying data source
+func (b *BulkheadDataSource) Name() string {
+	return b.source.Name()
+}
+
+// Fetch fetches from the underlying data source once a slot is free
+func (b *BulkheadDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	var result *service.DataSourceResult
+	err := b.bulkhead.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = b.source.Fetch(ctx, input)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// cacheableBulkheadDataSource is a BulkheadDataSource over a Cacheable source
+type cacheableBulkheadDataSource struct {
+	*BulkheadDataSource
+	cacheable service.Cacheable
+}
+
+// CacheKey forwards to the underlying data source
+func (c *cacheableBulkheadDataSource) CacheKey(input *service.DataSourceInput) service.DataSourceInput {
+	return c.cacheable.CacheKey(input)
+}
+
+// CacheTTL forwards to the underlying data source
+func (c *cacheableBulkheadDataSource) CacheTTL() time.Duration {
+	return c.cacheable.CacheTTL()
+}
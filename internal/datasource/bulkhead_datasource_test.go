@@ -0,0 +1,76 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// gatedDataSource blocks fetches until released
+type gatedDataSource struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (g *gatedDataSource) Name() string {
+	return "gated"
+}
+
+func (g *gatedDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	close(g.started)
+	<-g.release
+	return &service.DataSourceResult{Data: []byte(`{}`), ContentType: service.ContentTypeJSON}, nil
+}
+
+func TestBulkheadDataSource(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("rejects fetches beyond the limit", func(t *testing.T) {
+		bulkhead, err := service.NewBulkhead(service.BulkheadConfig{Name: "data_source:gated", MaxConcurrent: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		source := &gatedDataSource{started: make(chan struct{}), release: make(chan struct{})}
+		ds := NewBulkheadDataSource(source, bulkhead)
+
+		if ds.Name() != "gated" {
+			t.Errorf("expected name to be forwarded, got %s", ds.Name())
+		}
+		if _, ok := ds.(service.Cacheable); ok {
+			t.Error("expected non-cacheable source to stay non-cacheable")
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := ds.Fetch(ctx, &service.DataSourceInput{})
+			done <- err
+		}()
+		<-source.started
+
+		if _, err := ds.Fetch(ctx, &service.DataSourceInput{}); !errors.Is(err, service.ErrBulkheadFull) {
+			t.Errorf("expected ErrBulkheadFull, got %v", err)
+		}
+
+		close(source.release)
+		if err := <-done; err != nil {
+			t.Errorf("expected first fetch to succeed, got %v", err)
+		}
+	})
+
+	t.Run("cacheable sources stay cacheable", func(t *testing.T) {
+		bulkhead, _ := service.NewBulkhead(service.BulkheadConfig{MaxConcurrent: 1})
+		source := NewCacheableDataSource(&gatedDataSource{}, time.Minute, "subject.subject")
+		ds := NewBulkheadDataSource(source, bulkhead)
+
+		cacheable, ok := ds.(service.Cacheable)
+		if !ok {
+			t.Fatal("expected cacheable source to stay cacheable")
+		}
+		if cacheable.CacheTTL() != time.Minute {
+			t.Errorf("expected TTL to be forwarded, got %s", cacheable.CacheTTL())
+		}
+	})
+}
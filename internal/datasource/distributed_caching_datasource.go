@@ -2,13 +2,22 @@ package datasource
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang/groupcache"
 
+	"github.com/project-kessel/parsec/internal/clock"
 	"github.com/project-kessel/parsec/internal/service"
 )
 
@@ -18,6 +27,7 @@ type DistributedCachingDataSource struct {
 	source    service.DataSource
 	cacheable service.Cacheable
 	group     *groupcache.Group
+	current   *groupSource
 }
 
 // DistributedCachingConfig configures the distributed caching data source
@@ -31,11 +41,99 @@ type DistributedCachingConfig struct {
 	CacheSizeBytes int64
 }
 
+// groupSources tracks the data source each groupcache group fetches from.
+// groupcache groups live for the lifetime of the process, so rebuilding a data
+// source (e.g. after a configuration reload) reuses its group and only swaps
+// the source behind it.
+var (
+	groupSourcesMu sync.Mutex
+	groupSources   = map[string]*groupSource{}
+)
+
+type groupSource struct {
+	group  *groupcache.Group
+	mu     sync.RWMutex
+	source service.DataSource
+
+	// invalidated records when entries were last invalidated, which is part
+	// of their cache keys so invalidated entries are never requested again
+	invalidated invalidationMarks
+}
+
+// invalidationMarks records when the entries of a group were invalidated:
+// all of them, those of a subject, or those with a key
+type invalidationMarks struct {
+	all      time.Time
+	subjects map[string]time.Time
+	keys     map[string]time.Time
+}
+
+// latest returns when the entry with the given subject and key was last
+// invalidated, or the zero time if it never was
+func (m *invalidationMarks) latest(subject, keyJSON string) time.Time {
+	latest := m.all
+	if at := m.subjects[subject]; subject != "" && at.After(latest) {
+		latest = at
+	}
+	if at := m.keys[keyJSON]; at.After(latest) {
+		latest = at
+	}
+	return latest
+}
+
+// mark records an invalidation at time at
+// Marks older than ttl no longer match any live entry and are dropped.
+func (m *invalidationMarks) mark(invalidation service.CacheInvalidation, at time.Time, ttl time.Duration) {
+	if ttl > 0 {
+		expired := at.Add(-ttl)
+		for subject, marked := range m.subjects {
+			if marked.Before(expired) {
+				delete(m.subjects, subject)
+			}
+		}
+		for key, marked := range m.keys {
+			if marked.Before(expired) {
+				delete(m.keys, key)
+			}
+		}
+	}
+
+	switch {
+	case invalidation.Key != "":
+		// The key determines the subject, so only the key is marked; a key
+		// of another subject selects nothing
+		if invalidation.Subject != "" {
+			key, err := DeserializeInputFromJSON(invalidation.Key)
+			if err != nil || service.CacheKeySubject(key) != invalidation.Subject {
+				return
+			}
+		}
+		if m.keys == nil {
+			m.keys = make(map[string]time.Time)
+		}
+		m.keys[invalidation.Key] = at
+	case invalidation.Subject != "":
+		if m.subjects == nil {
+			m.subjects = make(map[string]time.Time)
+		}
+		m.subjects[invalidation.Subject] = at
+	default:
+		m.all = at
+	}
+}
+
+func (g *groupSource) get() service.DataSource {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.source
+}
+
 // NewDistributedCachingDataSource wraps a data source with distributed caching using groupcache
 // Returns the original source if it doesn't implement Cacheable
 //
-// Note: groupcache requires that you set up the peer pool before creating caching data sources
-// See groupcache documentation for details on setting up peers
+// Without a peer pool (see SetGroupcachePeers) the cache is local to this process.
+// Creating a data source with the name of an existing group reuses that group:
+// its cached entries are kept, and misses are fetched from the new source.
 func NewDistributedCachingDataSource(source service.DataSource, config DistributedCachingConfig) service.DataSource {
 	cacheable, ok := source.(service.Cacheable)
 	if !ok {
@@ -51,12 +149,29 @@ func NewDistributedCachingDataSource(source service.DataSource, config Distribut
 		config.CacheSizeBytes = 64 << 20 // 64MB default
 	}
 
+	groupSourcesMu.Lock()
+	defer groupSourcesMu.Unlock()
+
+	if existing, ok := groupSources[config.GroupName]; ok {
+		existing.mu.Lock()
+		existing.source = source
+		existing.mu.Unlock()
+		return &DistributedCachingDataSource{
+			source:    source,
+			cacheable: cacheable,
+			group:     existing.group,
+			current:   existing,
+		}
+	}
+
+	current := &groupSource{source: source}
+
 	// Create the getter function that will be called on cache miss
 	// This may be called on a different server in the groupcache peer pool
 	getter := groupcache.GetterFunc(func(ctx context.Context, key string, dest groupcache.Sink) error {
-		// Strip TTL timestamp suffix if present (format: "...json...:ttl:timestamp")
-		// The cache key may include a TTL-based timestamp for expiration
-		inputJSON := stripTTLSuffix(key)
+		// Strip TTL timestamp and invalidation suffixes if present
+		// (format: "...json...:ttl:timestamp:inv:timestamp")
+		inputJSON := stripTTLSuffix(stripInvalidationSuffix(key))
 
 		// Deserialize the cache key back into the masked input
 		maskedInput, err := DeserializeInputFromJSON(inputJSON)
@@ -66,7 +181,7 @@ func NewDistributedCachingDataSource(source service.DataSource, config Distribut
 
 		// Fetch using the masked input
 		// The masked input is sufficient for fetching by design of Cacheable interface
-		result, err := source.Fetch(ctx, maskedInput)
+		result, err := current.get().Fetch(ctx, maskedInput)
 		if err != nil {
 			return fmt.Errorf("data source fetch failed: %w", err)
 		}
@@ -95,11 +210,14 @@ func NewDistributedCachingDataSource(source service.DataSource, config Distribut
 
 	// Create the groupcache group
 	group := groupcache.NewGroup(config.GroupName, config.CacheSizeBytes, getter)
+	current.group = group
+	groupSources[config.GroupName] = current
 
 	return &DistributedCachingDataSource{
 		source:    source,
 		cacheable: cacheable,
 		group:     group,
+		current:   current,
 	}
 }
 
@@ -115,17 +233,20 @@ func (c *DistributedCachingDataSource) Name() string {
 }
 
 // Fetch checks the distributed cache first, then fetches from source on miss
+// Groupcache already loads each key once at a time, across the peers, so
+// concurrent misses for the same cache key result in one fetch from source.
 func (c *DistributedCachingDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
 	// Get the cache key (which is the masked input with only relevant fields)
 	maskedInput := c.cacheable.CacheKey(input)
 
 	// Serialize the masked input into a cache key string
 	// This must be reversible (JSON) for distributed caching
-	cacheKeyStr, err := SerializeInputToJSON(&maskedInput)
+	keyJSON, err := SerializeInputToJSON(&maskedInput)
 	if err != nil {
 		// If serialization fails, fall back to direct fetch
 		return c.source.Fetch(ctx, input)
 	}
+	cacheKeyStr := keyJSON
 
 	// Add TTL-based timestamp to cache key for effective expiration
 	// This rounds the timestamp to the nearest TTL interval so that
@@ -136,6 +257,14 @@ func (c *DistributedCachingDataSource) Fetch(ctx context.Context, input *service
 		cacheKeyStr = fmt.Sprintf("%s:ttl:%d", cacheKeyStr, roundedTimestamp.Unix())
 	}
 
+	// Entries fetched after an invalidation are cached under a new key
+	c.current.mu.RLock()
+	invalidatedAt := c.current.invalidated.latest(service.CacheKeySubject(&maskedInput), keyJSON)
+	c.current.mu.RUnlock()
+	if !invalidatedAt.IsZero() {
+		cacheKeyStr = fmt.Sprintf("%s:inv:%d", cacheKeyStr, invalidatedAt.UnixNano())
+	}
+
 	// Fetch from groupcache (will hit cache or call getter)
 	var cachedBytes []byte
 	err = c.group.Get(ctx, cacheKeyStr, groupcache.AllocatingByteSliceSink(&cachedBytes))
@@ -155,6 +284,17 @@ func (c *DistributedCachingDataSource) Fetch(ctx context.Context, input *service
 	}, nil
 }
 
+// InvalidateCache stops the selected entries from being served by this
+// instance. Entries cannot be removed from groupcache, so later fetches use
+// new keys instead; other instances must be invalidated too, as each computes
+// its own keys.
+func (c *DistributedCachingDataSource) InvalidateCache(ctx context.Context, invalidation service.CacheInvalidation) error {
+	c.current.mu.Lock()
+	defer c.current.mu.Unlock()
+	c.current.invalidated.mark(invalidation, time.Now(), c.cacheable.CacheTTL())
+	return nil
+}
+
 // roundTimestampToInterval rounds a timestamp to the nearest interval boundary.
 // This is used to create cache keys that naturally expire as time intervals change.
 // For example, with a 5-minute TTL:
@@ -181,6 +321,15 @@ func stripTTLSuffix(key string) string {
 	return key
 }
 
+// stripInvalidationSuffix removes the ":inv:timestamp" suffix from a cache key
+// if present
+func stripInvalidationSuffix(key string) string {
+	if idx := strings.LastIndex(key, ":inv:"); idx >= 0 {
+		return key[:idx]
+	}
+	return key
+}
+
 // SerializeInputToJSON serializes a DataSourceInput to JSON (reversible)
 // This is used for distributed caching where the key must be deserializable
 func SerializeInputToJSON(input *service.DataSourceInput) (string, error) {
@@ -200,3 +349,264 @@ func DeserializeInputFromJSON(key string) (*service.DataSourceInput, error) {
 	}
 	return &input, nil
 }
+
+// PeerDiscovery finds the base URLs of the instances in a groupcache peer pool
+type PeerDiscovery interface {
+	Peers(ctx context.Context) ([]string, error)
+}
+
+// GroupcachePeersConfig configures the groupcache peer pool shared by all
+// distributed caching data sources in the process
+type GroupcachePeersConfig struct {
+	// Self is this instance's base URL as reachable by its peers,
+	// e.g. "http://10.0.0.1:8080"
+	Self string
+
+	// Peers are the base URLs of all instances in the pool, including Self
+	// Unused with Discovery.
+	Peers []string
+
+	// Discovery finds the peers in place of a static Peers list (optional)
+	// Self is always part of the pool, whether or not it is discovered.
+	Discovery PeerDiscovery
+
+	// RefreshInterval is how often Discovery is asked for the peers
+	// (default: 30s)
+	RefreshInterval time.Duration
+
+	// BasePath is the HTTP path prefix serving peer requests (default: "/_groupcache/")
+	BasePath string
+
+	// Secret authenticates peers to each other: requests to peers carry it as
+	// a bearer token, and requests without it are refused
+	// Peer requests fetch data source results for any subject, so they must
+	// never be served unauthenticated.
+	Secret string
+
+	// AllowedNetworks restrict the addresses peer requests are served to
+	// (optional; any address when empty)
+	AllowedNetworks []netip.Prefix
+
+	// Transport sends requests to peers (default: http.DefaultTransport)
+	Transport http.RoundTripper
+
+	// Clock schedules peer refreshes (defaults to system clock)
+	Clock clock.Clock
+
+	// Logger reports failed peer refreshes (optional)
+	Logger *slog.Logger
+}
+
+var (
+	peerPoolMu   sync.Mutex
+	peerPool     *groupcache.HTTPPool
+	peerPoolSelf string
+
+	// peerTransport authenticates the pool's requests with the current
+	// secret; it is replaced when the peers are set again, e.g. on reload
+	peerTransport atomic.Pointer[peerAuthTransport]
+)
+
+// peerAuthTransport adds the peer secret to requests to peers
+type peerAuthTransport struct {
+	secret string
+	base   http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *peerAuthTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+t.secret)
+	return t.base.RoundTrip(r)
+}
+
+// GroupcachePeers serves peer requests for the process-wide groupcache pool
+// and, with discovery, keeps its list of peers current between Start and Stop
+type GroupcachePeers struct {
+	pool      *groupcache.HTTPPool
+	self      string
+	secret    string
+	networks  []netip.Prefix
+	discovery PeerDiscovery
+	interval  time.Duration
+	clock     clock.Clock
+	logger    *slog.Logger
+
+	mu     sync.Mutex
+	peers  []string
+	ticker clock.Ticker
+}
+
+// SetGroupcachePeers creates the process-wide groupcache peer pool (or updates
+// its peers) and returns the peers, whose handler must be served at BasePath.
+//
+// groupcache allows a single pool per process, so later calls may only change
+// the peers. With Discovery the peers are set once the returned peers are
+// started.
+func SetGroupcachePeers(config GroupcachePeersConfig) (*GroupcachePeers, error) {
+	if config.Self == "" {
+		return nil, fmt.Errorf("self URL is required for groupcache peers")
+	}
+	if config.Discovery == nil && !slices.Contains(config.Peers, config.Self) {
+		return nil, fmt.Errorf("groupcache peers must include self (%s)", config.Self)
+	}
+	if config.Secret == "" {
+		return nil, fmt.Errorf("secret is required for groupcache peers")
+	}
+	if config.Transport == nil {
+		config.Transport = http.DefaultTransport
+	}
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = 30 * time.Second
+	}
+	if config.Clock == nil {
+		config.Clock = clock.NewSystemClock()
+	}
+	if config.Logger == nil {
+		config.Logger = slog.New(slog.DiscardHandler)
+	}
+
+	peerPoolMu.Lock()
+	defer peerPoolMu.Unlock()
+
+	if peerPool == nil {
+		peerPool = groupcache.NewHTTPPoolOpts(config.Self, &groupcache.HTTPPoolOptions{
+			BasePath: config.BasePath,
+		})
+		peerPool.Transport = func(context.Context) http.RoundTripper {
+			return peerTransport.Load()
+		}
+		peerPoolSelf = config.Self
+	} else if peerPoolSelf != config.Self {
+		return nil, fmt.Errorf("groupcache peer pool already created for %s", peerPoolSelf)
+	}
+	peerTransport.Store(&peerAuthTransport{secret: config.Secret, base: config.Transport})
+
+	peers := &GroupcachePeers{
+		pool:      peerPool,
+		self:      config.Self,
+		secret:    config.Secret,
+		networks:  config.AllowedNetworks,
+		discovery: config.Discovery,
+		interval:  config.RefreshInterval,
+		clock:     config.Clock,
+		logger:    config.Logger,
+	}
+	if config.Discovery == nil {
+		peerPool.Set(config.Peers...)
+		peers.peers = slices.Sorted(slices.Values(config.Peers))
+	}
+	return peers, nil
+}
+
+// Self returns this instance's base URL as reachable by its peers
+func (p *GroupcachePeers) Self() string {
+	return p.self
+}
+
+// ServeHTTP serves requests from other peers for entries this instance owns
+// Requests from outside the allowed networks, or without the peer secret, are
+// refused.
+func (p *GroupcachePeers) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !p.allowedAddress(r.RemoteAddr) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(p.secret)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	p.pool.ServeHTTP(w, r)
+}
+
+// allowedAddress reports whether a peer request from remoteAddr may be served
+func (p *GroupcachePeers) allowedAddress(remoteAddr string) bool {
+	if len(p.networks) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, network := range p.networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Start discovers the peers and keeps rediscovering them in the background
+// It does nothing for a static list of peers.
+func (p *GroupcachePeers) Start(ctx context.Context) error {
+	if p.discovery == nil {
+		return nil
+	}
+
+	if err := p.Refresh(ctx); err != nil {
+		p.logger.Warn("initial peer discovery failed, will retry", "error", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ticker = p.clock.Ticker(p.interval)
+	return p.ticker.Start(func(ctx context.Context) {
+		if err := p.Refresh(ctx); err != nil {
+			p.logger.Warn("peer discovery failed, keeping previous peers", "error", err)
+		}
+	})
+}
+
+// Stop stops rediscovering the peers
+func (p *GroupcachePeers) Stop() {
+	p.mu.Lock()
+	ticker := p.ticker
+	p.ticker = nil
+	p.mu.Unlock()
+
+	if ticker != nil {
+		ticker.Stop()
+	}
+}
+
+// Refresh asks discovery for the peers and sets them on the pool
+// The previous peers are kept if discovery fails.
+func (p *GroupcachePeers) Refresh(ctx context.Context) error {
+	if p.discovery == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.interval)
+	defer cancel()
+	peers, err := p.discovery.Peers(ctx)
+	if err != nil {
+		return err
+	}
+	peers = append(peers, p.self)
+	slices.Sort(peers)
+	peers = slices.Compact(peers)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !slices.Equal(peers, p.peers) {
+		p.logger.Info("groupcache peers changed", "peers", peers)
+		p.peers = peers
+	}
+	p.pool.Set(peers...)
+	return nil
+}
+
+// Peers returns the base URLs of the instances in the pool, including Self:
+// the static list, or the peers last discovered (nil before discovery)
+func (p *GroupcachePeers) Peers() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.peers)
+}
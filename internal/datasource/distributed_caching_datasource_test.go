@@ -2,9 +2,15 @@ package datasource
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
 	"testing"
 	"time"
 
+	"github.com/project-kessel/parsec/internal/clock"
 	"github.com/project-kessel/parsec/internal/request"
 	"github.com/project-kessel/parsec/internal/service"
 	"github.com/project-kessel/parsec/internal/trust"
@@ -57,6 +63,33 @@ func TestDistributedCachingDataSource(t *testing.T) {
 		}
 	})
 
+	t.Run("recreating a data source reuses its group", func(t *testing.T) {
+		config := DistributedCachingConfig{GroupName: "test-group-rebuild", CacheSizeBytes: 1 << 20}
+		input := func(subject string) *service.DataSourceInput {
+			return &service.DataSourceInput{Subject: &trust.Result{Subject: subject}}
+		}
+
+		oldSource := &mockCacheableDataSource{name: "test-distributed", ttl: time.Hour}
+		if _, err := NewDistributedCachingDataSource(oldSource, config).Fetch(ctx, input("alice")); err != nil {
+			t.Fatalf("fetch failed: %v", err)
+		}
+
+		// Must not panic on the duplicate group name
+		newSource := &mockCacheableDataSource{name: "test-distributed", ttl: time.Hour}
+		rebuilt := NewDistributedCachingDataSource(newSource, config)
+
+		if _, err := rebuilt.Fetch(ctx, input("alice")); err != nil {
+			t.Fatalf("fetch failed: %v", err)
+		}
+		if _, err := rebuilt.Fetch(ctx, input("bob")); err != nil {
+			t.Fatalf("fetch failed: %v", err)
+		}
+		if oldSource.fetchCount != 1 || newSource.fetchCount != 1 {
+			t.Errorf("expected cached entry kept and misses sent to the new source, got old=%d new=%d",
+				oldSource.fetchCount, newSource.fetchCount)
+		}
+	})
+
 	t.Run("different cache keys result in different cache entries", func(t *testing.T) {
 		source := &mockCacheableDataSource{
 			name: "test-distributed",
@@ -218,6 +251,31 @@ func TestDistributedCachingDataSource(t *testing.T) {
 	})
 }
 
+func TestDistributedCachingDataSource_InvalidateCache(t *testing.T) {
+	source := &mockCacheableDataSource{name: "roles", ttl: time.Hour}
+	ds := NewDistributedCachingDataSource(source, DistributedCachingConfig{GroupName: "test-group-invalidate", CacheSizeBytes: 1 << 20})
+	testInvalidateCache(t, ds, source)
+
+	// Invalidations outlive rebuilding the data source
+	rebuilt := NewDistributedCachingDataSource(source, DistributedCachingConfig{GroupName: "test-group-invalidate"})
+	if _, err := rebuilt.Fetch(context.Background(), &service.DataSourceInput{Subject: &trust.Result{Subject: "alice"}}); err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if source.fetchCount != 6 {
+		t.Errorf("expected the rebuilt data source to hit the cache, got %d fetches", source.fetchCount)
+	}
+}
+
+func TestStripInvalidationSuffix(t *testing.T) {
+	key := `{"subject":{"subject":"alice"}}:ttl:1700000000:inv:1700000123000000000`
+	if got := stripTTLSuffix(stripInvalidationSuffix(key)); got != `{"subject":{"subject":"alice"}}` {
+		t.Errorf("expected the input JSON, got %s", got)
+	}
+	if got := stripInvalidationSuffix(`{"subject":{"subject":"alice"}}`); got != `{"subject":{"subject":"alice"}}` {
+		t.Errorf("expected a key without suffix to be unchanged, got %s", got)
+	}
+}
+
 func TestRoundTimestampToInterval(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -441,3 +499,104 @@ func TestSerializeDeserializeInputJSON(t *testing.T) {
 		}
 	})
 }
+
+// peerList is a PeerDiscovery returning fixed peers or an error
+type peerList struct {
+	peers []string
+	err   error
+}
+
+func (l *peerList) Peers(ctx context.Context) ([]string, error) {
+	return l.peers, l.err
+}
+
+func TestGroupcachePeers_Discovery(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Now())
+	discovery := &peerList{peers: []string{"http://10.0.0.2:8080", "http://10.0.0.3:8080"}}
+
+	peers, err := SetGroupcachePeers(GroupcachePeersConfig{
+		Self:            "http://10.0.0.1:8080",
+		Discovery:       discovery,
+		RefreshInterval: 10 * time.Second,
+		Secret:          "peer-secret",
+		Clock:           clk,
+	})
+	if err != nil {
+		t.Fatalf("failed to set peers: %v", err)
+	}
+	if err := peers.Start(ctx); err != nil {
+		t.Fatalf("failed to start peers: %v", err)
+	}
+	defer peers.Stop()
+
+	// The pool is process-wide; leave it without unreachable peers
+	t.Cleanup(func() {
+		_, _ = SetGroupcachePeers(GroupcachePeersConfig{Self: "http://10.0.0.1:8080", Peers: []string{"http://10.0.0.1:8080"}, Secret: "peer-secret"})
+	})
+
+	// Self is a peer even when it is not discovered
+	want := []string{"http://10.0.0.1:8080", "http://10.0.0.2:8080", "http://10.0.0.3:8080"}
+	if got := peers.Peers(); !slices.Equal(got, want) {
+		t.Errorf("expected %v after start, got %v", want, got)
+	}
+
+	discovery.peers = []string{"http://10.0.0.1:8080", "http://10.0.0.4:8080"}
+	clk.Advance(10 * time.Second)
+	want = []string{"http://10.0.0.1:8080", "http://10.0.0.4:8080"}
+	if got := peers.Peers(); !slices.Equal(got, want) {
+		t.Errorf("expected %v after refresh, got %v", want, got)
+	}
+
+	// A failed discovery keeps the previous peers
+	discovery.err = errors.New("dns unavailable")
+	clk.Advance(10 * time.Second)
+	if got := peers.Peers(); !slices.Equal(got, want) {
+		t.Errorf("expected %v to be kept, got %v", want, got)
+	}
+
+	// groupcache has a single pool per process
+	if _, err := SetGroupcachePeers(GroupcachePeersConfig{Self: "http://10.0.0.9:8080", Discovery: discovery, Secret: "peer-secret"}); err == nil {
+		t.Error("expected a different self to be rejected")
+	}
+}
+
+func TestGroupcachePeers_Authentication(t *testing.T) {
+	if _, err := SetGroupcachePeers(GroupcachePeersConfig{Self: "http://10.0.0.1:8080", Peers: []string{"http://10.0.0.1:8080"}}); err == nil {
+		t.Error("expected peers without a secret to be rejected")
+	}
+
+	peers, err := SetGroupcachePeers(GroupcachePeersConfig{
+		Self:            "http://10.0.0.1:8080",
+		Peers:           []string{"http://10.0.0.1:8080"},
+		Secret:          "peer-secret",
+		AllowedNetworks: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/16")},
+	})
+	if err != nil {
+		t.Fatalf("failed to set peers: %v", err)
+	}
+
+	serve := func(remoteAddr, authorization string) int {
+		r := httptest.NewRequest(http.MethodGet, "/_groupcache/test-group-1/key", nil)
+		r.RemoteAddr = remoteAddr
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		peers.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	if code := serve("10.0.0.2:4000", ""); code != http.StatusUnauthorized {
+		t.Errorf("expected an unauthenticated request to be refused with 401, got %d", code)
+	}
+	if code := serve("10.0.0.2:4000", "Bearer wrong-secret"); code != http.StatusUnauthorized {
+		t.Errorf("expected a wrong secret to be refused with 401, got %d", code)
+	}
+	if code := serve("192.0.2.1:4000", "Bearer peer-secret"); code != http.StatusForbidden {
+		t.Errorf("expected a request from outside the allowed networks to be refused with 403, got %d", code)
+	}
+	if code := serve("10.0.0.2:4000", "Bearer peer-secret"); code == http.StatusUnauthorized || code == http.StatusForbidden {
+		t.Errorf("expected an authenticated peer request to be served, got %d", code)
+	}
+}
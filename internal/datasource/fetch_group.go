@@ -0,0 +1,95 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// errFetchPanicked is returned to the callers sharing a fetch that panicked
+var errFetchPanicked = errors.New("data source fetch panicked")
+
+// fetchGroup collapses concurrent fetches of the same cache key into one
+// The zero value is ready to use.
+//
+// Callers joining a fetch in flight wait for its result, or until their own
+// context is done. A fetch that fails because the context of the caller
+// running it was cancelled or timed out is retried by a caller whose context
+// is still live, so one caller's deadline does not fail the others.
+type fetchGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+	waiting int
+}
+
+// flight is a fetch in progress
+type flight struct {
+	done   chan struct{}
+	result *service.DataSourceResult
+	err    error
+}
+
+// do runs fetch for key, unless a fetch for key is already in flight, in
+// which case it returns that fetch's result
+func (g *fetchGroup) do(ctx context.Context, key string, fetch func() (*service.DataSourceResult, error)) (*service.DataSourceResult, error) {
+	for {
+		g.mu.Lock()
+		if f, ok := g.flights[key]; ok {
+			g.waiting++
+			g.mu.Unlock()
+
+			select {
+			case <-f.done:
+			case <-ctx.Done():
+			}
+			g.mu.Lock()
+			g.waiting--
+			g.mu.Unlock()
+
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if isContextError(f.err) {
+				continue
+			}
+			return f.result, f.err
+		}
+
+		f := &flight{done: make(chan struct{})}
+		if g.flights == nil {
+			g.flights = make(map[string]*flight)
+		}
+		g.flights[key] = f
+		g.mu.Unlock()
+
+		g.run(key, f, fetch)
+		return f.result, f.err
+	}
+}
+
+// run runs fetch as flight f, releasing the callers waiting on it even if
+// fetch panics
+func (g *fetchGroup) run(key string, f *flight, fetch func() (*service.DataSourceResult, error)) {
+	defer func() {
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+
+	f.err = errFetchPanicked
+	f.result, f.err = fetch()
+}
+
+// pending returns the number of callers waiting on another caller's fetch
+func (g *fetchGroup) pending() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.waiting
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,269 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// blockingCacheableDataSource is a cacheable data source whose fetches wait
+// for release
+type blockingCacheableDataSource struct {
+	fetches atomic.Int32
+	release chan struct{}
+}
+
+func (b *blockingCacheableDataSource) Name() string { return "blocking" }
+
+func (b *blockingCacheableDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	b.fetches.Add(1)
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &service.DataSourceResult{
+		Data:        []byte(`{"subject":"` + input.Subject.Subject + `"}`),
+		ContentType: service.ContentTypeJSON,
+	}, nil
+}
+
+func (b *blockingCacheableDataSource) CacheKey(input *service.DataSourceInput) service.DataSourceInput {
+	return service.DataSourceInput{Subject: &trust.Result{Subject: input.Subject.Subject}}
+}
+
+func (b *blockingCacheableDataSource) CacheTTL() time.Duration { return time.Minute }
+
+// waitForPending waits until n callers wait on another caller's fetch
+func waitForPending(t *testing.T, g *fetchGroup, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for g.pending() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d waiting callers, got %d", n, g.pending())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFetchGroup(t *testing.T) {
+	result := &service.DataSourceResult{Data: []byte(`{}`)}
+
+	t.Run("concurrent callers share one fetch", func(t *testing.T) {
+		var g fetchGroup
+		var fetches atomic.Int32
+		release := make(chan struct{})
+		fetch := func() (*service.DataSourceResult, error) {
+			fetches.Add(1)
+			<-release
+			return result, nil
+		}
+
+		const callers = 10
+		results := make(chan *service.DataSourceResult, callers)
+		var wg sync.WaitGroup
+		for range callers {
+			wg.Go(func() {
+				got, err := g.do(context.Background(), "alice", fetch)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				results <- got
+			})
+		}
+		waitForPending(t, &g, callers-1)
+		close(release)
+		wg.Wait()
+		close(results)
+
+		if n := fetches.Load(); n != 1 {
+			t.Errorf("expected 1 fetch, got %d", n)
+		}
+		for got := range results {
+			if got != result {
+				t.Errorf("expected the shared result, got %v", got)
+			}
+		}
+	})
+
+	t.Run("different keys fetch separately", func(t *testing.T) {
+		var g fetchGroup
+		var fetches atomic.Int32
+		fetch := func() (*service.DataSourceResult, error) {
+			fetches.Add(1)
+			return result, nil
+		}
+
+		for _, key := range []string{"alice", "bob", "alice"} {
+			if _, err := g.do(context.Background(), key, fetch); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if n := fetches.Load(); n != 3 {
+			t.Errorf("expected 3 fetches, got %d", n)
+		}
+	})
+
+	t.Run("errors are shared", func(t *testing.T) {
+		var g fetchGroup
+		release := make(chan struct{})
+		errBackend := errors.New("backend down")
+		fetch := func() (*service.DataSourceResult, error) {
+			<-release
+			return nil, errBackend
+		}
+
+		errs := make(chan error, 2)
+		for range 2 {
+			go func() {
+				_, err := g.do(context.Background(), "alice", fetch)
+				errs <- err
+			}()
+		}
+		waitForPending(t, &g, 1)
+		close(release)
+		for range 2 {
+			if err := <-errs; !errors.Is(err, errBackend) {
+				t.Errorf("expected backend error, got %v", err)
+			}
+		}
+	})
+
+	t.Run("waiting caller gives up with its own context", func(t *testing.T) {
+		var g fetchGroup
+		release := make(chan struct{})
+		defer close(release)
+		go func() {
+			_, _ = g.do(context.Background(), "alice", func() (*service.DataSourceResult, error) {
+				<-release
+				return result, nil
+			})
+		}()
+		waitForFlight(t, &g, "alice")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			_, err := g.do(ctx, "alice", nil)
+			done <- err
+		}()
+		waitForPending(t, &g, 1)
+		cancel()
+
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("cancelled fetch is retried by a live caller", func(t *testing.T) {
+		var g fetchGroup
+		leaderCtx, cancelLeader := context.WithCancel(context.Background())
+		var fetches atomic.Int32
+		fetchWith := func(ctx context.Context) func() (*service.DataSourceResult, error) {
+			return func() (*service.DataSourceResult, error) {
+				fetches.Add(1)
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+		}
+
+		go func() {
+			_, _ = g.do(leaderCtx, "alice", fetchWith(leaderCtx))
+		}()
+		waitForFlight(t, &g, "alice")
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := g.do(context.Background(), "alice", func() (*service.DataSourceResult, error) {
+				fetches.Add(1)
+				return result, nil
+			})
+			done <- err
+		}()
+		waitForPending(t, &g, 1)
+		cancelLeader()
+
+		if err := <-done; err != nil {
+			t.Errorf("expected the retried fetch to succeed, got %v", err)
+		}
+		if n := fetches.Load(); n != 2 {
+			t.Errorf("expected 2 fetches, got %d", n)
+		}
+	})
+
+	t.Run("panicking fetch releases waiting callers", func(t *testing.T) {
+		var g fetchGroup
+		release := make(chan struct{})
+		go func() {
+			defer func() { _ = recover() }()
+			_, _ = g.do(context.Background(), "alice", func() (*service.DataSourceResult, error) {
+				<-release
+				panic("boom")
+			})
+		}()
+		waitForFlight(t, &g, "alice")
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := g.do(context.Background(), "alice", nil)
+			done <- err
+		}()
+		waitForPending(t, &g, 1)
+		close(release)
+
+		if err := <-done; !errors.Is(err, errFetchPanicked) {
+			t.Errorf("expected errFetchPanicked, got %v", err)
+		}
+	})
+}
+
+// waitForFlight waits until a fetch for key is in flight
+func waitForFlight(t *testing.T, g *fetchGroup, key string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		g.mu.Lock()
+		_, ok := g.flights[key]
+		g.mu.Unlock()
+		if ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a fetch for %s in flight", key)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestInMemoryCachingDataSource_CollapsesConcurrentFetches(t *testing.T) {
+	source := &blockingCacheableDataSource{release: make(chan struct{})}
+	ds := NewInMemoryCachingDataSource(source).(*InMemoryCachingDataSource)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Go(func() {
+			input := &service.DataSourceInput{Subject: &trust.Result{Subject: "alice", TrustDomain: "example.com"}}
+			result, err := ds.Fetch(context.Background(), input)
+			if err != nil || result == nil || string(result.Data) != `{"subject":"alice"}` {
+				t.Errorf("unexpected fetch result %v, %v", result, err)
+			}
+		})
+	}
+	waitForPending(t, &ds.flights, callers-1)
+	close(source.release)
+	wg.Wait()
+
+	if n := source.fetches.Load(); n != 1 {
+		t.Errorf("expected 1 upstream fetch for %d concurrent issuances, got %d", callers, n)
+	}
+	if ds.Size() != 1 {
+		t.Errorf("expected 1 cache entry, got %d", ds.Size())
+	}
+}
@@ -0,0 +1,215 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/mmdb"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// GeoIPDataSource looks up IP addresses in a MaxMind DB file (GeoIP2,
+// GeoLite2, or a compatible database such as DB-IP or IPinfo) and returns the
+// record of the address as JSON, e.g.
+//
+//	{"country": {"iso_code": "AU", ...}}                              (Country)
+//	{"autonomous_system_number": 64496, "autonomous_system_organization": "..."} (ASN)
+//
+// The file is checked for changes at most every reload interval and
+// reloaded when its modification time or size changed, so the database can
+// be updated in place (e.g. by geoipupdate or a mounted ConfigMap). A file
+// that fails to load keeps the previous database in use.
+type GeoIPDataSource struct {
+	name           string
+	path           string
+	ip             *InputTemplate
+	reloadInterval time.Duration
+	clock          clock.Clock
+
+	mu      sync.RWMutex
+	reader  *mmdb.Reader
+	modTime time.Time
+	size    int64
+	checkAt time.Time
+}
+
+// GeoIPDataSourceConfig configures a GeoIP data source
+type GeoIPDataSourceConfig struct {
+	// Name identifies this data source
+	Name string
+
+	// Path is the path of the .mmdb file
+	Path string
+
+	// IP is a template of the address looked up
+	// (default: "{{ .request_attributes.ip_address }}")
+	IP string
+
+	// ReloadInterval is how often the file is checked for changes
+	// (default: 1m; negative disables reloading)
+	ReloadInterval time.Duration
+
+	// Clock is the time source of reload checks (defaults to system clock)
+	Clock clock.Clock
+}
+
+// NewGeoIPDataSource creates a GeoIP data source, loading its database
+func NewGeoIPDataSource(cfg GeoIPDataSourceConfig) (*GeoIPDataSource, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("data source name is required")
+	}
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	ipText := cfg.IP
+	if ipText == "" {
+		ipText = "{{ .request_attributes.ip_address }}"
+	}
+	ip, err := ParseInputTemplate("ip", ipText, EscapeText)
+	if err != nil {
+		return nil, err
+	}
+
+	reloadInterval := cfg.ReloadInterval
+	if reloadInterval == 0 {
+		reloadInterval = time.Minute
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	ds := &GeoIPDataSource{
+		name:           cfg.Name,
+		path:           cfg.Path,
+		ip:             ip,
+		reloadInterval: reloadInterval,
+		clock:          clk,
+	}
+	info, err := os.Stat(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat GeoIP database: %w", err)
+	}
+	if err := ds.load(info); err != nil {
+		return nil, err
+	}
+	ds.checkAt = clk.Now().Add(reloadInterval)
+	return ds, nil
+}
+
+// Name returns the data source name
+func (ds *GeoIPDataSource) Name() string {
+	return ds.name
+}
+
+// Fetch looks up the rendered IP address
+// Inputs without a valid address, and addresses not in the database, have
+// nothing to contribute.
+func (ds *GeoIPDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	text, err := ds.ip.Render(input)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(text))
+	if err != nil {
+		return nil, nil
+	}
+
+	record, err := ds.database().Lookup(addr.WithZone(""))
+	if err != nil {
+		return nil, fmt.Errorf("GeoIP lookup of %s failed: %w", addr, err)
+	}
+	if record == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(jsonRecord(record))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode GeoIP record: %w", err)
+	}
+	return &service.DataSourceResult{
+		Data:        data,
+		ContentType: service.ContentTypeJSON,
+	}, nil
+}
+
+// database returns the current database, reloading it first if the file
+// changed and a check is due
+func (ds *GeoIPDataSource) database() *mmdb.Reader {
+	ds.mu.RLock()
+	reader, due := ds.reader, ds.reloadInterval > 0 && !ds.clock.Now().Before(ds.checkAt)
+	ds.mu.RUnlock()
+	if !due {
+		return reader
+	}
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	now := ds.clock.Now()
+	if now.Before(ds.checkAt) {
+		// Another fetch checked in the meantime
+		return ds.reader
+	}
+	ds.checkAt = now.Add(ds.reloadInterval)
+
+	// A file that is missing or fails to load keeps the previous database
+	info, err := os.Stat(ds.path)
+	if err == nil && (!info.ModTime().Equal(ds.modTime) || info.Size() != ds.size) {
+		_ = ds.load(info)
+	}
+	return ds.reader
+}
+
+// load reads and parses the database file described by info
+// Callers other than the constructor hold mu.
+func (ds *GeoIPDataSource) load(info os.FileInfo) error {
+	buf, err := os.ReadFile(ds.path)
+	if err != nil {
+		return fmt.Errorf("failed to read GeoIP database: %w", err)
+	}
+	reader, err := mmdb.Open(buf)
+	if err != nil {
+		return fmt.Errorf("failed to load GeoIP database %s: %w", ds.path, err)
+	}
+	ds.reader = reader
+	ds.modTime = info.ModTime()
+	ds.size = info.Size()
+	return nil
+}
+
+// jsonRecord converts a record to values encoding/json encodes faithfully:
+// byte strings become arrays of numbers rather than base64, and 128-bit
+// integers become decimal strings
+func jsonRecord(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = jsonRecord(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = jsonRecord(item)
+		}
+		return v
+	case []byte:
+		a := make([]any, len(v))
+		for i, b := range v {
+			a[i] = int(b)
+		}
+		return a
+	case *big.Int:
+		return v.String()
+	default:
+		return v
+	}
+}
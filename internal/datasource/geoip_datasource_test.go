@@ -0,0 +1,171 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/mmdb/mmdbtest"
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// writeGeoIPDatabase writes a database mapping each network to a country
+func writeGeoIPDatabase(t *testing.T, path string, countries map[string]string, modTime time.Time) {
+	t.Helper()
+	var networks []mmdbtest.Network
+	for prefix, isoCode := range countries {
+		networks = append(networks, mmdbtest.Network{
+			Prefix: netip.MustParsePrefix(prefix),
+			Record: map[string]any{
+				"country": map[string]any{"iso_code": isoCode},
+				"asn":     uint32(64496),
+			},
+		})
+	}
+	buf, err := mmdbtest.Build(mmdbtest.Database{DatabaseType: "Test-Country", Networks: networks})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if err := os.WriteFile(path, buf, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+}
+
+func fetchCountry(t *testing.T, ds *GeoIPDataSource, ip string) any {
+	t.Helper()
+	result, err := ds.Fetch(context.Background(), &service.DataSourceInput{
+		RequestAttributes: &request.RequestAttributes{IPAddress: ip},
+	})
+	if err != nil {
+		t.Fatalf("Fetch(%s) failed: %v", ip, err)
+	}
+	if result == nil {
+		return nil
+	}
+	var data map[string]any
+	if err := json.Unmarshal(result.Data, &data); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	return data["country"].(map[string]any)["iso_code"]
+}
+
+func TestGeoIPDataSource_Fetch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "country.mmdb")
+	writeGeoIPDatabase(t, path, map[string]string{"203.0.113.0/24": "AU", "2001:db8::/32": "DE"}, time.Now())
+
+	ds, err := NewGeoIPDataSource(GeoIPDataSourceConfig{Name: "geo", Path: path})
+	if err != nil {
+		t.Fatalf("NewGeoIPDataSource failed: %v", err)
+	}
+
+	for ip, want := range map[string]any{
+		"203.0.113.7":  "AU",
+		"2001:db8::1":  "DE",
+		"198.51.100.1": nil,
+		"":             nil,
+		"not-an-ip":    nil,
+	} {
+		if got := fetchCountry(t, ds, ip); got != want {
+			t.Errorf("Fetch(%q) = %v, want %v", ip, got, want)
+		}
+	}
+
+	result, err := ds.Fetch(context.Background(), &service.DataSourceInput{
+		RequestAttributes: &request.RequestAttributes{IPAddress: "203.0.113.7"},
+	})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	var data map[string]any
+	if err := json.Unmarshal(result.Data, &data); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	want := map[string]any{"country": map[string]any{"iso_code": "AU"}, "asn": float64(64496)}
+	if !reflect.DeepEqual(data, want) || result.ContentType != service.ContentTypeJSON {
+		t.Errorf("unexpected result: %s (%s)", result.Data, result.ContentType)
+	}
+}
+
+func TestGeoIPDataSource_IPTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "country.mmdb")
+	writeGeoIPDatabase(t, path, map[string]string{"203.0.113.0/24": "AU"}, time.Now())
+
+	ds, err := NewGeoIPDataSource(GeoIPDataSourceConfig{
+		Name: "geo",
+		Path: path,
+		IP:   `{{ index .request_attributes.headers "x-client-ip" }}`,
+	})
+	if err != nil {
+		t.Fatalf("NewGeoIPDataSource failed: %v", err)
+	}
+
+	result, err := ds.Fetch(context.Background(), &service.DataSourceInput{
+		RequestAttributes: &request.RequestAttributes{
+			IPAddress: "192.0.2.1",
+			Headers:   map[string]string{"x-client-ip": "203.0.113.9"},
+		},
+	})
+	if err != nil || result == nil {
+		t.Fatalf("expected the header address to be looked up, got %v, %v", result, err)
+	}
+}
+
+func TestGeoIPDataSource_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "country.mmdb")
+	modTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeGeoIPDatabase(t, path, map[string]string{"203.0.113.0/24": "AU"}, modTime)
+
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	ds, err := NewGeoIPDataSource(GeoIPDataSourceConfig{Name: "geo", Path: path, ReloadInterval: time.Minute, Clock: clk})
+	if err != nil {
+		t.Fatalf("NewGeoIPDataSource failed: %v", err)
+	}
+
+	writeGeoIPDatabase(t, path, map[string]string{"203.0.113.0/24": "NZ"}, modTime.Add(time.Hour))
+	if got := fetchCountry(t, ds, "203.0.113.7"); got != "AU" {
+		t.Errorf("expected the old database before the reload interval, got %v", got)
+	}
+
+	clk.Advance(time.Minute)
+	if got := fetchCountry(t, ds, "203.0.113.7"); got != "NZ" {
+		t.Errorf("expected the new database after the reload interval, got %v", got)
+	}
+
+	// A corrupt replacement keeps the database in use
+	if err := os.WriteFile(path, []byte("truncated"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	clk.Advance(time.Minute)
+	if got := fetchCountry(t, ds, "203.0.113.7"); got != "NZ" {
+		t.Errorf("expected the previous database to stay in use, got %v", got)
+	}
+}
+
+func TestNewGeoIPDataSource_Errors(t *testing.T) {
+	invalid := filepath.Join(t.TempDir(), "invalid.mmdb")
+	if err := os.WriteFile(invalid, []byte("not a database"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	for name, cfg := range map[string]GeoIPDataSourceConfig{
+		"no name":          {Path: invalid},
+		"no path":          {Name: "geo"},
+		"missing file":     {Name: "geo", Path: filepath.Join(t.TempDir(), "missing.mmdb")},
+		"invalid database": {Name: "geo", Path: invalid},
+		"invalid template": {Name: "geo", Path: invalid, IP: "{{ .unclosed"},
+	} {
+		if _, err := NewGeoIPDataSource(cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// GroupBackend looks up the groups a subject is a member of
+type GroupBackend interface {
+	// Groups returns the names of the groups of the subject in input
+	Groups(ctx context.Context, input *service.DataSourceInput) ([]string, error)
+}
+
+// GroupsDataSource aggregates group membership from several backends
+//
+// Backends are queried concurrently and their groups merged into a single,
+// sorted and deduplicated roles array:
+//
+//	{"roles": ["admins", "engineering"]}
+//
+// A failing backend fails the fetch, so tokens are never issued with a
+// partial set of roles. Inputs without a subject have nothing to contribute.
+type GroupsDataSource struct {
+	name     string
+	backends []GroupBackend
+}
+
+// GroupsDataSourceConfig configures a groups data source
+type GroupsDataSourceConfig struct {
+	// Name identifies this data source
+	Name string
+
+	// Backends are the group backends to aggregate (at least one)
+	Backends []GroupBackend
+}
+
+// NewGroupsDataSource creates a new groups data source
+func NewGroupsDataSource(cfg GroupsDataSourceConfig) (*GroupsDataSource, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("data source name is required")
+	}
+	if len(cfg.Backends) == 0 {
+		return nil, fmt.Errorf("at least one group backend is required")
+	}
+	return &GroupsDataSource{
+		name:     cfg.Name,
+		backends: cfg.Backends,
+	}, nil
+}
+
+// Name returns the data source name
+func (ds *GroupsDataSource) Name() string {
+	return ds.name
+}
+
+// Fetch returns the merged groups of the input's subject
+func (ds *GroupsDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	if input == nil || input.Subject == nil {
+		return nil, nil
+	}
+
+	groups := make([][]string, len(ds.backends))
+	errs := make([]error, len(ds.backends))
+	var wg sync.WaitGroup
+	for i, backend := range ds.backends {
+		wg.Go(func() {
+			groups[i], errs[i] = backend.Groups(ctx, input)
+		})
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("group backend %d: %w", i, err)
+		}
+	}
+
+	roles := slices.Concat(groups...)
+	slices.Sort(roles)
+	roles = slices.Compact(roles)
+	if roles == nil {
+		roles = []string{}
+	}
+
+	data, err := json.Marshal(map[string]any{"roles": roles})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode roles: %w", err)
+	}
+	return &service.DataSourceResult{
+		Data:        data,
+		ContentType: service.ContentTypeJSON,
+	}, nil
+}
+
+// StaticGroupBackend resolves groups from a fixed table of group members
+// Members are matched against the subject's subject.
+type StaticGroupBackend struct {
+	groups map[string][]string
+}
+
+// NewStaticGroupBackend creates a group backend from a map of group names to
+// their members
+func NewStaticGroupBackend(members map[string][]string) *StaticGroupBackend {
+	groups := make(map[string][]string)
+	for group, subjects := range members {
+		for _, subject := range subjects {
+			groups[subject] = append(groups[subject], group)
+		}
+	}
+	return &StaticGroupBackend{groups: groups}
+}
+
+// Groups implements GroupBackend
+func (b *StaticGroupBackend) Groups(ctx context.Context, input *service.DataSourceInput) ([]string, error) {
+	return b.groups[input.Subject.Subject], nil
+}
@@ -0,0 +1,234 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// newSCIMServer serves the groups of each member in pages of at most two
+// groups, whatever count is requested
+func newSCIMServer(t *testing.T, members map[string][]string, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/scim/v2/Groups" || r.Header.Get("Authorization") != "Bearer scim-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var member string
+		if _, err := fmt.Sscanf(r.URL.Query().Get("filter"), "members.value eq %q", &member); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		groups := members[member]
+		start, _ := strconv.Atoi(r.URL.Query().Get("startIndex"))
+		page := groups[min(start-1, len(groups)):min(start+1, len(groups))]
+
+		resources := []map[string]string{}
+		for _, group := range page {
+			resources = append(resources, map[string]string{"displayName": group})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"schemas":      []string{"urn:ietf:params:scim:api:messages:2.0:ListResponse"},
+			"totalResults": len(groups),
+			"startIndex":   start,
+			"itemsPerPage": len(page),
+			"Resources":    resources,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// fakeLDAPConn answers searches from a table of filters to entries
+type fakeLDAPConn struct {
+	entries  map[string][]*ldap.Entry
+	bound    string
+	pageSize uint32
+	closed   bool
+}
+
+func (c *fakeLDAPConn) Bind(username, password string) error {
+	if password != "secret" {
+		return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+	}
+	c.bound = username
+	return nil
+}
+
+func (c *fakeLDAPConn) SearchWithPaging(req *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error) {
+	c.pageSize = pagingSize
+	return &ldap.SearchResult{Entries: c.entries[req.Filter]}, nil
+}
+
+func (c *fakeLDAPConn) SetTimeout(time.Duration) {}
+
+func (c *fakeLDAPConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func fetchRoles(t *testing.T, ds service.DataSource, subject string) []string {
+	t.Helper()
+	result, err := ds.Fetch(context.Background(), &service.DataSourceInput{
+		Subject: &trust.Result{Subject: subject, TrustDomain: "example.com"},
+	})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	var data struct {
+		Roles []string `json:"roles"`
+	}
+	if err := json.Unmarshal(result.Data, &data); err != nil {
+		t.Fatalf("invalid result: %v", err)
+	}
+	return data.Roles
+}
+
+func TestGroupsDataSource_MergesBackends(t *testing.T) {
+	var requests atomic.Int32
+	server := newSCIMServer(t, map[string][]string{
+		"alice": {"engineering", "oncall", "admins", "release-managers", "admins"},
+	}, &requests)
+
+	scim, err := NewSCIMGroupBackend(SCIMGroupBackendConfig{
+		URL:     server.URL + "/scim/v2/",
+		Headers: map[string]string{"Authorization": "Bearer scim-token"},
+	})
+	if err != nil {
+		t.Fatalf("NewSCIMGroupBackend failed: %v", err)
+	}
+
+	conn := &fakeLDAPConn{entries: map[string][]*ldap.Entry{
+		"(&(objectClass=groupOfNames)(member=uid=alice,ou=people,dc=example,dc=com))": {
+			ldap.NewEntry("cn=engineering,ou=groups,dc=example,dc=com", map[string][]string{"cn": {"engineering"}}),
+			ldap.NewEntry("cn=vpn-users,ou=groups,dc=example,dc=com", map[string][]string{"cn": {"vpn-users"}}),
+		},
+	}}
+	directory, err := NewLDAPGroupBackend(LDAPGroupBackendConfig{
+		URL:          "ldap://ldap.example.com",
+		BindDN:       "cn=parsec,dc=example,dc=com",
+		BindPassword: "secret",
+		BaseDN:       "ou=groups,dc=example,dc=com",
+		Filter:       "(&(objectClass=groupOfNames)(member=uid={{ .subject.subject }},ou=people,dc=example,dc=com))",
+		PageSize:     50,
+	})
+	if err != nil {
+		t.Fatalf("NewLDAPGroupBackend failed: %v", err)
+	}
+	directory.dial = func(string) (ldapConn, error) { return conn, nil }
+
+	ds, err := NewGroupsDataSource(GroupsDataSourceConfig{
+		Name: "groups",
+		Backends: []GroupBackend{
+			directory,
+			scim,
+			NewStaticGroupBackend(map[string][]string{
+				"employees": {"alice", "bob"},
+				"oncall":    {"alice"},
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGroupsDataSource failed: %v", err)
+	}
+
+	want := []string{"admins", "employees", "engineering", "oncall", "release-managers", "vpn-users"}
+	if got := fetchRoles(t, ds, "alice"); !slices.Equal(got, want) {
+		t.Errorf("got roles %v, want %v", got, want)
+	}
+	if requests.Load() != 3 {
+		t.Errorf("expected the SCIM groups to be read in 3 pages, got %d requests", requests.Load())
+	}
+	if conn.bound != "cn=parsec,dc=example,dc=com" || conn.pageSize != 50 || !conn.closed {
+		t.Errorf("expected a bound, paged search on a closed connection, got %+v", conn)
+	}
+
+	if got := fetchRoles(t, ds, "bob"); !slices.Equal(got, []string{"employees"}) {
+		t.Errorf("got roles %v, want [employees]", got)
+	}
+	if got := fetchRoles(t, ds, "mallory"); got == nil || len(got) != 0 {
+		t.Errorf("expected an empty roles array, got %v", got)
+	}
+}
+
+func TestGroupsDataSource_Errors(t *testing.T) {
+	ctx := context.Background()
+	input := &service.DataSourceInput{Subject: &trust.Result{Subject: "alice"}}
+
+	t.Run("failing backend fails the fetch", func(t *testing.T) {
+		var requests atomic.Int32
+		server := newSCIMServer(t, nil, &requests)
+		scim, _ := NewSCIMGroupBackend(SCIMGroupBackendConfig{URL: server.URL + "/scim/v2"})
+		ds, _ := NewGroupsDataSource(GroupsDataSourceConfig{
+			Name:     "groups",
+			Backends: []GroupBackend{NewStaticGroupBackend(map[string][]string{"employees": {"alice"}}), scim},
+		})
+		if _, err := ds.Fetch(ctx, input); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("too many pages", func(t *testing.T) {
+		var requests atomic.Int32
+		server := newSCIMServer(t, map[string][]string{"alice": {"a", "b", "c", "d", "e"}}, &requests)
+		scim, _ := NewSCIMGroupBackend(SCIMGroupBackendConfig{
+			URL:      server.URL + "/scim/v2",
+			Headers:  map[string]string{"Authorization": "Bearer scim-token"},
+			MaxPages: 2,
+		})
+		if _, err := scim.Groups(ctx, input); err == nil {
+			t.Error("expected error for groups beyond the page limit")
+		}
+	})
+
+	t.Run("LDAP bind failure", func(t *testing.T) {
+		directory, _ := NewLDAPGroupBackend(LDAPGroupBackendConfig{
+			URL:          "ldap://ldap.example.com",
+			BindDN:       "cn=parsec,dc=example,dc=com",
+			BindPassword: "wrong",
+			BaseDN:       "dc=example,dc=com",
+			Filter:       "(memberUid={{ .subject.subject }})",
+		})
+		directory.dial = func(string) (ldapConn, error) { return &fakeLDAPConn{}, nil }
+		if _, err := directory.Groups(ctx, input); !ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			t.Errorf("expected bind error, got %v", err)
+		}
+	})
+
+	t.Run("no subject", func(t *testing.T) {
+		ds, _ := NewGroupsDataSource(GroupsDataSourceConfig{
+			Name:     "groups",
+			Backends: []GroupBackend{NewStaticGroupBackend(nil)},
+		})
+		if result, err := ds.Fetch(ctx, &service.DataSourceInput{}); result != nil || err != nil {
+			t.Errorf("expected no result, got %v (%v)", result, err)
+		}
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		if _, err := NewGroupsDataSource(GroupsDataSourceConfig{Name: "groups"}); err == nil {
+			t.Error("expected error without backends")
+		}
+		if _, err := NewLDAPGroupBackend(LDAPGroupBackendConfig{URL: "ldap://ldap.example.com", BaseDN: "dc=example,dc=com"}); err == nil {
+			t.Error("expected error without filter")
+		}
+		if _, err := NewSCIMGroupBackend(SCIMGroupBackendConfig{}); err == nil {
+			t.Error("expected error without url")
+		}
+	})
+}
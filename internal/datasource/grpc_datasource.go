@@ -0,0 +1,165 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// GRPCDataSource calls a unary gRPC method and returns the response as JSON
+//
+// Messages are built dynamically from protobuf descriptors, so any service can be
+// called without generated code. The request is an input template producing the
+// protobuf JSON form of the request message; values render as JSON literals.
+type GRPCDataSource struct {
+	name    string
+	conn    grpc.ClientConnInterface
+	method  string
+	input   protoreflect.MessageDescriptor
+	output  protoreflect.MessageDescriptor
+	request *InputTemplate
+	timeout time.Duration
+}
+
+// GRPCDataSourceConfig configures a gRPC data source
+type GRPCDataSourceConfig struct {
+	// Name identifies this data source
+	Name string
+
+	// Conn is the client connection to the service
+	Conn grpc.ClientConnInterface
+
+	// Method is the full method name, e.g. "/users.v1.UserService/GetUser"
+	Method string
+
+	// Files resolves the service descriptor
+	// If nil, the global registry (services linked into the binary) is used
+	Files *protoregistry.Files
+
+	// Request is the request message template in protobuf JSON form, e.g.
+	// {"user_id": "{{ .subject.subject }}"}
+	// If empty, an empty request message is sent.
+	Request string
+
+	// Timeout bounds each call (default: 5s)
+	Timeout time.Duration
+}
+
+// NewGRPCDataSource creates a new gRPC data source
+func NewGRPCDataSource(cfg GRPCDataSourceConfig) (*GRPCDataSource, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("data source name is required")
+	}
+	if cfg.Conn == nil {
+		return nil, fmt.Errorf("connection is required")
+	}
+
+	files := cfg.Files
+	if files == nil {
+		files = protoregistry.GlobalFiles
+	}
+	method, err := findMethod(files, cfg.Method)
+	if err != nil {
+		return nil, err
+	}
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return nil, fmt.Errorf("method %s is streaming; only unary methods are supported", cfg.Method)
+	}
+
+	var request *InputTemplate
+	if cfg.Request != "" {
+		request, err = ParseInputTemplate("request", cfg.Request, EscapeJSON)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	return &GRPCDataSource{
+		name:    cfg.Name,
+		conn:    cfg.Conn,
+		method:  cfg.Method,
+		input:   method.Input(),
+		output:  method.Output(),
+		request: request,
+		timeout: timeout,
+	}, nil
+}
+
+// findMethod resolves a "/package.Service/Method" name to its descriptor
+func findMethod(files *protoregistry.Files, fullMethod string) (protoreflect.MethodDescriptor, error) {
+	serviceName, methodName, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok || serviceName == "" || methodName == "" {
+		return nil, fmt.Errorf("invalid method %q: expected /package.Service/Method", fullMethod)
+	}
+
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found: %w", serviceName, err)
+	}
+	svc, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", serviceName)
+	}
+	method := svc.Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		return nil, fmt.Errorf("method %s not found in service %s", methodName, serviceName)
+	}
+	return method, nil
+}
+
+// Name returns the data source name
+func (ds *GRPCDataSource) Name() string {
+	return ds.name
+}
+
+// Fetch calls the method with the templated request
+// A NotFound status means the data source has nothing to contribute.
+func (ds *GRPCDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	req := dynamicpb.NewMessage(ds.input)
+	if ds.request != nil {
+		rendered, err := ds.request.Render(input)
+		if err != nil {
+			return nil, err
+		}
+		if err := protojson.Unmarshal([]byte(rendered), req); err != nil {
+			return nil, fmt.Errorf("failed to build request message: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ds.timeout)
+	defer cancel()
+
+	resp := dynamicpb.NewMessage(ds.output)
+	if err := ds.conn.Invoke(ctx, ds.method, req, resp); err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("call to %s failed: %w", ds.method, err)
+	}
+
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response: %w", err)
+	}
+
+	return &service.DataSourceResult{
+		Data:        data,
+		ContentType: service.ContentTypeJSON,
+	}, nil
+}
@@ -0,0 +1,71 @@
+package datasource
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestGRPCDataSource(t *testing.T) {
+	ctx := context.Background()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("alice", healthpb.HealthCheckResponse_SERVING)
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	ds, err := NewGRPCDataSource(GRPCDataSourceConfig{
+		Name:    "health",
+		Conn:    conn,
+		Method:  "/grpc.health.v1.Health/Check",
+		Request: `{"service": {{ json .subject.subject }}}`,
+	})
+	if err != nil {
+		t.Fatalf("failed to create data source: %v", err)
+	}
+
+	t.Run("returns response as JSON", func(t *testing.T) {
+		result, err := ds.Fetch(ctx, &service.DataSourceInput{Subject: &trust.Result{Subject: "alice"}})
+		if err != nil {
+			t.Fatalf("fetch failed: %v", err)
+		}
+		if string(result.Data) != `{"status":"SERVING"}` {
+			t.Errorf("unexpected data: %s", result.Data)
+		}
+	})
+
+	t.Run("not found contributes nothing", func(t *testing.T) {
+		result, err := ds.Fetch(ctx, &service.DataSourceInput{Subject: &trust.Result{Subject: "bob"}})
+		if err != nil || result != nil {
+			t.Errorf("expected nil result and error, got %v, %v", result, err)
+		}
+	})
+
+	t.Run("rejects unknown and streaming methods", func(t *testing.T) {
+		for _, method := range []string{"/grpc.health.v1.Health/Nope", "/no.such.Service/Call", "Check", "/grpc.health.v1.Health/Watch"} {
+			if _, err := NewGRPCDataSource(GRPCDataSourceConfig{Name: "bad", Conn: conn, Method: method}); err == nil {
+				t.Errorf("expected error for method %s", method)
+			}
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// maxHTTPResponseBytes bounds the size of HTTP data source responses
+const maxHTTPResponseBytes = 10 << 20
+
+// HTTPDataSource fetches JSON from an HTTP endpoint
+// The URL, headers, and body are input templates (see InputTemplate). Values
+// are query-escaped in the URL and rendered as JSON literals in the body.
+type HTTPDataSource struct {
+	name    string
+	method  string
+	url     *InputTemplate
+	headers map[string]*InputTemplate
+	body    *InputTemplate
+	client  *http.Client
+}
+
+// HTTPDataSourceConfig configures an HTTP data source
+type HTTPDataSourceConfig struct {
+	// Name identifies this data source
+	Name string
+
+	// URL is the request URL template, e.g.
+	// "https://users.example.com/v1/users/{{ .subject.subject }}"
+	URL string
+
+	// Method is the HTTP method (default: GET)
+	Method string
+
+	// Headers are request header templates
+	Headers map[string]string
+
+	// Body is an optional JSON request body template, e.g.
+	// `{"user": {{ .subject.subject }}}`
+	Body string
+
+	// Timeout bounds each request (default: 30s)
+	Timeout time.Duration
+
+	// Transport is an optional HTTP transport (e.g. for fixtures)
+	Transport http.RoundTripper
+}
+
+// NewHTTPDataSource creates a new HTTP data source
+func NewHTTPDataSource(cfg HTTPDataSourceConfig) (*HTTPDataSource, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("data source name is required")
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+
+	urlTemplate, err := ParseInputTemplate("url", cfg.URL, EscapeURL)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]*InputTemplate, len(cfg.Headers))
+	for name, value := range cfg.Headers {
+		headers[name], err = ParseInputTemplate("header "+name, value, EscapeText)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var body *InputTemplate
+	if cfg.Body != "" {
+		body, err = ParseInputTemplate("body", cfg.Body, EscapeJSON)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	method := strings.ToUpper(cfg.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &HTTPDataSource{
+		name:    cfg.Name,
+		method:  method,
+		url:     urlTemplate,
+		headers: headers,
+		body:    body,
+		client: &http.Client{
+			Transport: cfg.Transport,
+			Timeout:   timeout,
+		},
+	}, nil
+}
+
+// Name returns the data source name
+func (ds *HTTPDataSource) Name() string {
+	return ds.name
+}
+
+// Fetch sends the templated request and returns the JSON response body
+// A 404 response means the data source has nothing to contribute.
+func (ds *HTTPDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	url, err := ds.url.Render(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	if ds.body != nil {
+		rendered, err := ds.body.Render(input)
+		if err != nil {
+			return nil, err
+		}
+		body = strings.NewReader(rendered)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, ds.method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, tmpl := range ds.headers {
+		value, err := tmpl.Render(input)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(name, value)
+	}
+
+	resp, err := ds.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("request failed: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("response is not valid JSON")
+	}
+
+	return &service.DataSourceResult{
+		Data:        data,
+		ContentType: service.ContentTypeJSON,
+	}, nil
+}
@@ -0,0 +1,137 @@
+package datasource
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestHTTPDataSource(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/alice@example.com":
+			if r.Header.Get("X-Tenant") != "acme" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"roles":["admin"]}`))
+		case "/lookup":
+			body, _ := io.ReadAll(r.Body)
+			_, _ = w.Write(body)
+		case "/broken":
+			_, _ = w.Write([]byte("not json"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	input := &service.DataSourceInput{
+		Subject: &trust.Result{Subject: "alice@example.com"},
+		RequestAttributes: &request.RequestAttributes{
+			Headers: map[string]string{"x-tenant": "acme"},
+		},
+	}
+
+	t.Run("renders URL and headers from input", func(t *testing.T) {
+		ds, err := NewHTTPDataSource(HTTPDataSourceConfig{
+			Name:    "roles",
+			URL:     server.URL + "/users/{{ .subject.subject | urlquery }}",
+			Headers: map[string]string{"X-Tenant": `{{ index .request_attributes.headers "x-tenant" }}`},
+		})
+		if err != nil {
+			t.Fatalf("failed to create data source: %v", err)
+		}
+
+		result, err := ds.Fetch(ctx, input)
+		if err != nil {
+			t.Fatalf("fetch failed: %v", err)
+		}
+		if string(result.Data) != `{"roles":["admin"]}` {
+			t.Errorf("unexpected data: %s", result.Data)
+		}
+		if result.ContentType != service.ContentTypeJSON {
+			t.Errorf("expected JSON content type, got %s", result.ContentType)
+		}
+	})
+
+	t.Run("sends templated body", func(t *testing.T) {
+		ds, err := NewHTTPDataSource(HTTPDataSourceConfig{
+			Name:   "lookup",
+			URL:    server.URL + "/lookup",
+			Method: "post",
+			Body:   `{"user": {{ json .subject.subject }}}`,
+		})
+		if err != nil {
+			t.Fatalf("failed to create data source: %v", err)
+		}
+
+		result, err := ds.Fetch(ctx, input)
+		if err != nil {
+			t.Fatalf("fetch failed: %v", err)
+		}
+		if string(result.Data) != `{"user": "alice@example.com"}` {
+			t.Errorf("unexpected data: %s", result.Data)
+		}
+	})
+
+	t.Run("not found contributes nothing", func(t *testing.T) {
+		ds, err := NewHTTPDataSource(HTTPDataSourceConfig{Name: "missing", URL: server.URL + "/users/{{ with .actor }}{{ .subject }}{{ end }}"})
+		if err != nil {
+			t.Fatalf("failed to create data source: %v", err)
+		}
+		result, err := ds.Fetch(ctx, input)
+		if err != nil || result != nil {
+			t.Errorf("expected nil result and error, got %v, %v", result, err)
+		}
+	})
+
+	t.Run("rejects non-JSON responses", func(t *testing.T) {
+		ds, err := NewHTTPDataSource(HTTPDataSourceConfig{Name: "broken", URL: server.URL + "/broken"})
+		if err != nil {
+			t.Fatalf("failed to create data source: %v", err)
+		}
+		if _, err := ds.Fetch(ctx, input); err == nil {
+			t.Error("expected error for non-JSON response")
+		}
+	})
+
+	t.Run("rejects invalid templates", func(t *testing.T) {
+		if _, err := NewHTTPDataSource(HTTPDataSourceConfig{Name: "bad", URL: "{{ .subject"}); err == nil {
+			t.Error("expected template parse error")
+		}
+	})
+}
+
+func TestCacheableDataSource_MasksInput(t *testing.T) {
+	source := &mockCacheableDataSource{name: "src"}
+	ds := NewCacheableDataSource(source, 0, "subject.subject", "request_attributes.headers.x-tenant")
+
+	key := ds.CacheKey(&service.DataSourceInput{
+		Subject: &trust.Result{Subject: "alice", Issuer: "https://idp.example.com"},
+		Actor:   &trust.Result{Subject: "gateway"},
+		RequestAttributes: &request.RequestAttributes{
+			Path:    "/orders/1",
+			Headers: map[string]string{"x-tenant": "acme", "x-request-id": "123"},
+		},
+	})
+
+	if key.Subject == nil || key.Subject.Subject != "alice" || key.Subject.Issuer != "" {
+		t.Errorf("expected only subject.subject to be kept, got %+v", key.Subject)
+	}
+	if key.Actor != nil {
+		t.Errorf("expected actor to be masked, got %+v", key.Actor)
+	}
+	if key.RequestAttributes == nil || key.RequestAttributes.Path != "" ||
+		len(key.RequestAttributes.Headers) != 1 || key.RequestAttributes.Headers["x-tenant"] != "acme" {
+		t.Errorf("expected only the x-tenant header to be kept, got %+v", key.RequestAttributes)
+	}
+}
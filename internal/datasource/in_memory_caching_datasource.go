@@ -20,12 +20,16 @@ type InMemoryCachingDataSource struct {
 	clock     clock.Clock
 	mu        sync.RWMutex
 	entries   map[string]*cacheEntry
+	flights   fetchGroup
 }
 
 // cacheEntry stores cached data with expiration
+// The subject and serialized key select the entry for invalidation.
 type cacheEntry struct {
 	result    *service.DataSourceResult
 	expiresAt time.Time
+	subject   string
+	keyJSON   string
 }
 
 // InMemoryCachingDataSourceOption is a functional option for configuring InMemoryCachingDataSource
@@ -68,34 +72,56 @@ func (c *InMemoryCachingDataSource) Name() string {
 }
 
 // Fetch checks the cache first, then fetches from source on miss
+// Concurrent misses for the same cache key result in one fetch from source.
 func (c *InMemoryCachingDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
 	// Get the cache key (which is the masked input with only relevant fields)
 	maskedInput := c.cacheable.CacheKey(input)
 
 	// Serialize the masked input into a cache key string
-	cacheKeyStr, err := serializeInput(&maskedInput)
+	keyJSON, err := SerializeInputToJSON(&maskedInput)
 	if err != nil {
 		// If serialization fails, skip caching and fetch directly
 		return c.source.Fetch(ctx, input)
 	}
+	cacheKeyStr := hashKey(keyJSON)
 
-	// Check cache
+	if result, found := c.lookup(cacheKeyStr); found {
+		return result, nil
+	}
+
+	// Cache miss - concurrent misses for the same key share one fetch
+	return c.flights.do(ctx, cacheKeyStr, func() (*service.DataSourceResult, error) {
+		// The fetch this one waited behind may have just filled the cache
+		if result, found := c.lookup(cacheKeyStr); found {
+			return result, nil
+		}
+		return c.fetchAndStore(ctx, input, &maskedInput, cacheKeyStr, keyJSON)
+	})
+}
+
+// lookup returns the unexpired cache entry for key, removing an expired one
+func (c *InMemoryCachingDataSource) lookup(key string) (*service.DataSourceResult, bool) {
 	c.mu.RLock()
-	entry, found := c.entries[cacheKeyStr]
+	entry, found := c.entries[key]
 	c.mu.RUnlock()
 
-	if found {
-		// Check if entry has expired
-		if entry.expiresAt.IsZero() || c.clock.Now().Before(entry.expiresAt) {
-			return entry.result, nil
-		}
-		// Entry expired, remove it
-		c.mu.Lock()
-		delete(c.entries, cacheKeyStr)
-		c.mu.Unlock()
+	if !found {
+		return nil, false
+	}
+	// Check if entry has expired
+	if entry.expiresAt.IsZero() || c.clock.Now().Before(entry.expiresAt) {
+		return entry.result, true
 	}
+	// Entry expired, remove it
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+	return nil, false
+}
 
-	// Cache miss - fetch from source using the original (full) input
+// fetchAndStore fetches from source using the original (full) input and
+// caches the result
+func (c *InMemoryCachingDataSource) fetchAndStore(ctx context.Context, input, maskedInput *service.DataSourceInput, cacheKeyStr, keyJSON string) (*service.DataSourceResult, error) {
 	result, err := c.source.Fetch(ctx, input)
 	if err != nil {
 		return nil, err
@@ -113,6 +139,8 @@ func (c *InMemoryCachingDataSource) Fetch(ctx context.Context, input *service.Da
 		c.entries[cacheKeyStr] = &cacheEntry{
 			result:    result,
 			expiresAt: expiresAt,
+			subject:   service.CacheKeySubject(maskedInput),
+			keyJSON:   keyJSON,
 		}
 		c.mu.Unlock()
 	}
@@ -120,6 +148,19 @@ func (c *InMemoryCachingDataSource) Fetch(ctx context.Context, input *service.Da
 	return result, nil
 }
 
+// InvalidateCache removes the selected entries from the cache
+func (c *InMemoryCachingDataSource) InvalidateCache(ctx context.Context, invalidation service.CacheInvalidation) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		if invalidation.Matches(entry.subject, entry.keyJSON) {
+			delete(c.entries, key)
+		}
+	}
+	return nil
+}
+
 // Cleanup removes expired entries from the cache
 // This should be called periodically to prevent memory leaks
 func (c *InMemoryCachingDataSource) Cleanup() {
@@ -149,8 +190,11 @@ func serializeInput(input *service.DataSourceInput) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to serialize input: %w", err)
 	}
+	return hashKey(string(keyBytes)), nil
+}
 
-	// Hash the serialized form to get a fixed-size key
-	hash := sha256.Sum256(keyBytes)
-	return fmt.Sprintf("%x", hash), nil
+// hashKey hashes a serialized cache key to get a fixed-size key
+func hashKey(keyJSON string) string {
+	hash := sha256.Sum256([]byte(keyJSON))
+	return fmt.Sprintf("%x", hash)
 }
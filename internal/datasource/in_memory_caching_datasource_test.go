@@ -230,3 +230,65 @@ func TestInMemoryCachingDataSource(t *testing.T) {
 		}
 	})
 }
+
+func TestInMemoryCachingDataSource_InvalidateCache(t *testing.T) {
+	source := &mockCacheableDataSource{name: "roles", ttl: time.Hour}
+	testInvalidateCache(t, NewInMemoryCachingDataSource(source), source)
+}
+
+// testInvalidateCache checks that invalidating a caching data source wrapping
+// source drops the selected entries only
+func testInvalidateCache(t *testing.T, ds service.DataSource, source *mockCacheableDataSource) {
+	t.Helper()
+	ctx := context.Background()
+	invalidator, ok := ds.(service.CacheInvalidator)
+	if !ok {
+		t.Fatalf("expected %T to be a cache invalidator", ds)
+	}
+
+	alice := &service.DataSourceInput{Subject: &trust.Result{Subject: "alice"}}
+	bob := &service.DataSourceInput{Subject: &trust.Result{Subject: "bob"}}
+	fetch := func(inputs ...*service.DataSourceInput) {
+		t.Helper()
+		for _, input := range inputs {
+			if _, err := ds.Fetch(ctx, input); err != nil {
+				t.Fatalf("fetch failed: %v", err)
+			}
+		}
+	}
+	invalidate := func(invalidation service.CacheInvalidation) {
+		t.Helper()
+		if err := invalidator.InvalidateCache(ctx, invalidation); err != nil {
+			t.Fatalf("invalidation failed: %v", err)
+		}
+	}
+	expectFetches := func(want int, after string) {
+		t.Helper()
+		if source.fetchCount != want {
+			t.Errorf("expected %d source fetches %s, got %d", want, after, source.fetchCount)
+		}
+	}
+
+	fetch(alice, bob, alice, bob)
+	expectFetches(2, "before invalidation")
+
+	invalidate(service.CacheInvalidation{Subject: "alice"})
+	fetch(alice, bob)
+	expectFetches(3, "after invalidating a subject")
+
+	bobKey := source.CacheKey(bob)
+	bobKeyJSON, err := SerializeInputToJSON(&bobKey)
+	if err != nil {
+		t.Fatalf("failed to serialize key: %v", err)
+	}
+	invalidate(service.CacheInvalidation{Subject: "alice", Key: bobKeyJSON})
+	fetch(bob)
+	expectFetches(3, "after invalidating a key of another subject")
+	invalidate(service.CacheInvalidation{Key: bobKeyJSON})
+	fetch(alice, bob)
+	expectFetches(4, "after invalidating a key")
+
+	invalidate(service.CacheInvalidation{})
+	fetch(alice, bob)
+	expectFetches(6, "after invalidating every entry")
+}
@@ -0,0 +1,277 @@
+package datasource
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// InputEscaping selects how an input template escapes the values it renders
+type InputEscaping int
+
+const (
+	// EscapeText renders values as plain text, for contexts that are not
+	// parsed further (HTTP headers, SQL query parameters)
+	EscapeText InputEscaping = iota
+
+	// EscapeURL query-escapes values, so they cannot add path segments, query
+	// parameters or fragments to a URL
+	EscapeURL
+
+	// EscapeJSON renders values as JSON literals, so they cannot change the
+	// structure of a JSON document. Strings render with their quotes.
+	EscapeJSON
+
+	// EscapeLDAPFilter escapes values as LDAP filter assertion values
+	// (RFC 4515), so they cannot add filter components or wildcards
+	EscapeLDAPFilter
+)
+
+// escapeFunc is the template function applied to the output of every action
+const escapeFunc = "_escape"
+
+// escapingFuncs are the functions whose output is already escaped; actions
+// ending in one of them are rendered as is
+var escapingFuncs = map[string]bool{
+	"json":     true,
+	"urlquery": true,
+	"raw":      true,
+}
+
+// InputTemplate renders a string from a data source input
+//
+// Templates use Go text/template syntax over the JSON form of the input, so
+// fields are referenced by their JSON names:
+//
+//	https://users.example.com/v1/users/{{ .subject.subject }}
+//	{"service": {{ .request_attributes.path }}}
+//
+// Every value is escaped for the template's InputEscaping unless the action
+// ends in json, urlquery or raw (which opts out of escaping). Missing fields
+// render as empty strings (null with EscapeJSON), but fields of an absent
+// object (such as .actor.subject without an actor) are an error; guard them
+// with {{ with }}.
+type InputTemplate struct {
+	tmpl *template.Template
+}
+
+// ParseInputTemplate parses an input template that escapes values as given
+func ParseInputTemplate(name, text string, escaping InputEscaping) (*InputTemplate, error) {
+	tmpl, err := template.New(name).Option("missingkey=zero").Funcs(template.FuncMap{
+		"json":     jsonValue,
+		"urlquery": func(v any) string { return url.QueryEscape(textValue(v)) },
+		"raw":      textValue,
+		escapeFunc: escaperFor(escaping),
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s template: %w", name, err)
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			escapeActions(t.Tree.Root)
+		}
+	}
+	return &InputTemplate{tmpl: tmpl}, nil
+}
+
+// Render executes the template against the input
+func (t *InputTemplate) Render(input *service.DataSourceInput) (string, error) {
+	data, err := inputData(input)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", t.tmpl.Name(), err)
+	}
+	return b.String(), nil
+}
+
+// escaperFor returns the escape function for an escaping mode
+func escaperFor(escaping InputEscaping) any {
+	switch escaping {
+	case EscapeURL:
+		return func(v any) string { return url.QueryEscape(textValue(v)) }
+	case EscapeJSON:
+		return jsonValue
+	case EscapeLDAPFilter:
+		return func(v any) string { return ldap.EscapeFilter(textValue(v)) }
+	default:
+		return textValue
+	}
+}
+
+// textValue renders a value as text; missing values render as ""
+func textValue(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// jsonValue renders a value as a JSON literal; missing values render as null
+func jsonValue(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// escapeActions appends the escape function to every action that prints a
+// value and is not already escaped
+func escapeActions(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			escapeActions(child)
+		}
+	case *parse.ActionNode:
+		// Variable declarations print nothing
+		if len(n.Pipe.Decl) > 0 || isEscaped(n.Pipe) {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{parse.NewIdentifier(escapeFunc).SetPos(n.Pos)},
+		})
+	case *parse.IfNode:
+		escapeActions(n.List)
+		escapeActions(n.ElseList)
+	case *parse.RangeNode:
+		escapeActions(n.List)
+		escapeActions(n.ElseList)
+	case *parse.WithNode:
+		escapeActions(n.List)
+		escapeActions(n.ElseList)
+	}
+}
+
+// isEscaped reports whether a pipeline ends in an escaping function
+func isEscaped(pipe *parse.PipeNode) bool {
+	if len(pipe.Cmds) == 0 {
+		return false
+	}
+	last := pipe.Cmds[len(pipe.Cmds)-1]
+	if ident, ok := last.Args[0].(*parse.IdentifierNode); ok {
+		return escapingFuncs[ident.Ident]
+	}
+	return false
+}
+
+// inputData converts an input to the generic map form seen by templates
+func inputData(input *service.DataSourceInput) (map[string]any, error) {
+	data := map[string]any{}
+	if input == nil {
+		return data, nil
+	}
+	b, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode data source input: %w", err)
+	}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode data source input: %w", err)
+	}
+	return data, nil
+}
+
+// CacheableDataSource makes any data source cacheable by keying the cache on a
+// fixed set of input fields
+type CacheableDataSource struct {
+	service.DataSource
+	keyFields []string
+	ttl       time.Duration
+}
+
+// NewCacheableDataSource wraps a data source so caching layers can cache its results.
+//
+// keyFields are dotted JSON paths into the input (e.g. "subject.subject" or
+// "request_attributes.headers.x-tenant") that determine the result; all other
+// fields are masked out of the cache key. If keyFields is empty, the whole input
+// is the cache key.
+func NewCacheableDataSource(source service.DataSource, ttl time.Duration, keyFields ...string) *CacheableDataSource {
+	return &CacheableDataSource{
+		DataSource: source,
+		keyFields:  keyFields,
+		ttl:        ttl,
+	}
+}
+
+// CacheKey implements the Cacheable interface
+func (c *CacheableDataSource) CacheKey(input *service.DataSourceInput) service.DataSourceInput {
+	if len(c.keyFields) == 0 {
+		return *input
+	}
+	masked, err := MaskInput(input, c.keyFields)
+	if err != nil {
+		// On error, return full input
+		return *input
+	}
+	return masked
+}
+
+// CacheTTL implements the Cacheable interface
+func (c *CacheableDataSource) CacheTTL() time.Duration {
+	return c.ttl
+}
+
+// MaskInput returns a copy of the input with only the fields at the given
+// dotted JSON paths set
+func MaskInput(input *service.DataSourceInput, fields []string) (service.DataSourceInput, error) {
+	data, err := inputData(input)
+	if err != nil {
+		return service.DataSourceInput{}, err
+	}
+
+	masked := map[string]any{}
+	for _, field := range fields {
+		if value, ok := lookupPath(data, strings.Split(field, ".")); ok {
+			setPath(masked, strings.Split(field, "."), value)
+		}
+	}
+
+	b, err := json.Marshal(masked)
+	if err != nil {
+		return service.DataSourceInput{}, fmt.Errorf("failed to encode masked input: %w", err)
+	}
+	var result service.DataSourceInput
+	if err := json.Unmarshal(b, &result); err != nil {
+		return service.DataSourceInput{}, fmt.Errorf("failed to decode masked input: %w", err)
+	}
+	return result, nil
+}
+
+func lookupPath(data map[string]any, path []string) (any, bool) {
+	value, ok := data[path[0]]
+	if !ok || len(path) == 1 {
+		return value, ok
+	}
+	child, ok := value.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	return lookupPath(child, path[1:])
+}
+
+func setPath(data map[string]any, path []string, value any) {
+	if len(path) == 1 {
+		data[path[0]] = value
+		return
+	}
+	child, ok := data[path[0]].(map[string]any)
+	if !ok {
+		child = map[string]any{}
+		data[path[0]] = child
+	}
+	setPath(child, path[1:], value)
+}
@@ -0,0 +1,103 @@
+package datasource
+
+import (
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestInputTemplate_Escaping(t *testing.T) {
+	input := &service.DataSourceInput{
+		Subject: &trust.Result{Subject: "alice"},
+		RequestAttributes: &request.RequestAttributes{
+			Path:    `/orders", "admin": true, "x": "`,
+			Headers: map[string]string{"x-tenant": "acme&role=admin", "x-note": "<no value>"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		escaping InputEscaping
+		want     string
+	}{
+		{
+			name:     "JSON values cannot change the document",
+			text:     `{"path": {{ .request_attributes.path }}}`,
+			escaping: EscapeJSON,
+			want:     `{"path": "/orders\", \"admin\": true, \"x\": \""}`,
+		},
+		{
+			name:     "explicit json is not escaped twice",
+			text:     `{"user": {{ json .subject.subject }}}`,
+			escaping: EscapeJSON,
+			want:     `{"user": "alice"}`,
+		},
+		{
+			name:     "missing JSON values are null",
+			text:     `{"tenant": {{ .request_attributes.headers.x_region }}}`,
+			escaping: EscapeJSON,
+			want:     `{"tenant": null}`,
+		},
+		{
+			name:     "URL values cannot add query parameters",
+			text:     `https://users.example.com/{{ .subject.subject }}?tenant={{ index .request_attributes.headers "x-tenant" }}`,
+			escaping: EscapeURL,
+			want:     `https://users.example.com/alice?tenant=acme%26role%3Dadmin`,
+		},
+		{
+			name:     "explicit urlquery is not escaped twice",
+			text:     `/users/{{ .subject.subject | urlquery }}`,
+			escaping: EscapeURL,
+			want:     `/users/alice`,
+		},
+		{
+			name:     "raw opts out of escaping",
+			text:     `/{{ raw (index .request_attributes.headers "x-tenant") }}`,
+			escaping: EscapeURL,
+			want:     `/acme&role=admin`,
+		},
+		{
+			name:     "LDAP filter values cannot add filter components",
+			text:     `(&(objectClass=groupOfNames)(memberUid={{ "*)(uid=*" }}))`,
+			escaping: EscapeLDAPFilter,
+			want:     `(&(objectClass=groupOfNames)(memberUid=\2a\29\28uid=\2a))`,
+		},
+		{
+			name:     "missing text values are empty",
+			text:     `[{{ .request_attributes.headers.x_region }}]`,
+			escaping: EscapeText,
+			want:     `[]`,
+		},
+		{
+			name:     "values that look like a missing value are kept",
+			text:     `{{ index .request_attributes.headers "x-note" }}`,
+			escaping: EscapeText,
+			want:     `<no value>`,
+		},
+		{
+			name:     "actions inside control structures are escaped",
+			text:     `{{ with .subject }}{{ .subject }}{{ end }}{{ range $k, $v := .request_attributes.headers }}&{{ $k }}={{ $v }}{{ end }}`,
+			escaping: EscapeURL,
+			want:     `alice&x-note=%3Cno+value%3E&x-tenant=acme%26role%3Dadmin`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseInputTemplate("test", tt.text, tt.escaping)
+			if err != nil {
+				t.Fatalf("failed to parse template: %v", err)
+			}
+			got, err := tmpl.Render(input)
+			if err != nil {
+				t.Fatalf("failed to render template: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// LDAPGroupBackend resolves groups with an LDAP search
+//
+// Each lookup connects to the directory, binds (if a bind DN is configured)
+// and searches under the base DN with a filter template, reading all results
+// with the simple paged results control (RFC 2696). Each matching entry
+// contributes the values of the group attribute.
+type LDAPGroupBackend struct {
+	url          string
+	bindDN       string
+	bindPassword string
+	baseDN       string
+	filter       *InputTemplate
+	attribute    string
+	pageSize     uint32
+	timeout      time.Duration
+
+	// dial connects to the directory; replaced in tests
+	dial func(url string) (ldapConn, error)
+}
+
+// ldapConn is the part of an LDAP connection used by LDAPGroupBackend
+type ldapConn interface {
+	Bind(username, password string) error
+	SearchWithPaging(searchRequest *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error)
+	SetTimeout(timeout time.Duration)
+	Close() error
+}
+
+// LDAPGroupBackendConfig configures an LDAP group backend
+type LDAPGroupBackendConfig struct {
+	// URL is the directory URL, e.g. "ldaps://ldap.example.com:636"
+	URL string
+
+	// BindDN and BindPassword authenticate the search (anonymous if empty)
+	BindDN       string
+	BindPassword string
+
+	// BaseDN is the search base, e.g. "ou=groups,dc=example,dc=com"
+	BaseDN string
+
+	// Filter is the search filter template. Values are escaped for LDAP
+	// filters, e.g. "(&(objectClass=groupOfNames)(member=uid={{ .subject.subject }},ou=people,dc=example,dc=com))"
+	Filter string
+
+	// Attribute is the attribute holding the group name (default: cn)
+	Attribute string
+
+	// PageSize is the number of entries requested per page (default: 100)
+	PageSize uint32
+
+	// Timeout bounds each lookup (default: 5s)
+	Timeout time.Duration
+}
+
+// NewLDAPGroupBackend creates a new LDAP group backend
+// No connection is made until the first lookup.
+func NewLDAPGroupBackend(cfg LDAPGroupBackendConfig) (*LDAPGroupBackend, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	if cfg.BaseDN == "" {
+		return nil, fmt.Errorf("base_dn is required")
+	}
+	if cfg.Filter == "" {
+		return nil, fmt.Errorf("filter is required")
+	}
+
+	filter, err := ParseInputTemplate("filter", cfg.Filter, EscapeLDAPFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	attribute := cfg.Attribute
+	if attribute == "" {
+		attribute = "cn"
+	}
+	pageSize := cfg.PageSize
+	if pageSize == 0 {
+		pageSize = 100
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	return &LDAPGroupBackend{
+		url:          cfg.URL,
+		bindDN:       cfg.BindDN,
+		bindPassword: cfg.BindPassword,
+		baseDN:       cfg.BaseDN,
+		filter:       filter,
+		attribute:    attribute,
+		pageSize:     pageSize,
+		timeout:      timeout,
+		dial: func(url string) (ldapConn, error) {
+			return ldap.DialURL(url, ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+		},
+	}, nil
+}
+
+// Groups implements GroupBackend
+func (b *LDAPGroupBackend) Groups(ctx context.Context, input *service.DataSourceInput) ([]string, error) {
+	filter, err := b.filter.Render(input)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	conn, err := b.dial(b.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// The client has no context support: bound each operation by the
+	// deadline, and close the connection if the context ends first
+	deadline, _ := ctx.Deadline()
+	conn.SetTimeout(time.Until(deadline))
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	if b.bindDN != "" {
+		if err := conn.Bind(b.bindDN, b.bindPassword); err != nil {
+			return nil, ldapError(ctx, "bind failed", err)
+		}
+	}
+
+	result, err := conn.SearchWithPaging(ldap.NewSearchRequest(
+		b.baseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		0,
+		int(b.timeout.Seconds()),
+		false,
+		filter,
+		[]string{b.attribute},
+		nil,
+	), b.pageSize)
+	if err != nil {
+		return nil, ldapError(ctx, "search failed", err)
+	}
+
+	var groups []string
+	for _, entry := range result.Entries {
+		groups = append(groups, entry.GetAttributeValues(b.attribute)...)
+	}
+	return groups, nil
+}
+
+// ldapError reports a failed operation, preferring the context's error if
+// the connection was closed because the context ended
+func ldapError(ctx context.Context, op string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%s: %w", op, ctxErr)
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
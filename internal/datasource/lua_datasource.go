@@ -103,8 +103,10 @@ func (ds *LuaDataSource) Name() string {
 // Fetch executes the Lua script to fetch data
 func (ds *LuaDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
 	// Create a new Lua state for this request
+	// The script and its HTTP calls are interrupted when ctx is done
 	L := lua.NewState()
 	defer L.Close()
+	L.SetContext(ctx)
 
 	// Register services
 	httpService := luaservices.NewHTTPServiceWithConfig(ds.httpConfig)
@@ -202,6 +204,21 @@ func (ds *LuaDataSource) inputToLuaTable(L *lua.LState, input *service.DataSourc
 		if input.RequestAttributes.UserAgent != "" {
 			L.SetField(reqTbl, "user_agent", lua.LString(input.RequestAttributes.UserAgent))
 		}
+		if input.RequestAttributes.Scheme != "" {
+			L.SetField(reqTbl, "scheme", lua.LString(input.RequestAttributes.Scheme))
+		}
+		if input.RequestAttributes.Port != 0 {
+			L.SetField(reqTbl, "port", lua.LNumber(input.RequestAttributes.Port))
+		}
+		if input.RequestAttributes.BodySHA256 != "" {
+			L.SetField(reqTbl, "body_sha256", lua.LString(input.RequestAttributes.BodySHA256))
+		}
+		if query := input.RequestAttributes.QueryMap(); query != nil {
+			L.SetField(reqTbl, "query", luaservices.GoToLua(L, query))
+		}
+		if tls := input.RequestAttributes.TLS.Map(); tls != nil {
+			L.SetField(reqTbl, "tls", luaservices.GoToLua(L, tls))
+		}
 
 		if len(input.RequestAttributes.Headers) > 0 {
 			headersTbl := L.NewTable()
@@ -290,10 +307,37 @@ func (ds *LuaDataSource) luaTableToInput(tbl *lua.LTable) service.DataSourceInpu
 	if reqLV := tbl.RawGetString("request_attributes"); reqLV.Type() == lua.LTTable {
 		reqTbl := reqLV.(*lua.LTable)
 		reqAttrs := &request.RequestAttributes{
-			Method:    lua.LVAsString(reqTbl.RawGetString("method")),
-			Path:      lua.LVAsString(reqTbl.RawGetString("path")),
-			IPAddress: lua.LVAsString(reqTbl.RawGetString("ip_address")),
-			UserAgent: lua.LVAsString(reqTbl.RawGetString("user_agent")),
+			Method:     lua.LVAsString(reqTbl.RawGetString("method")),
+			Path:       lua.LVAsString(reqTbl.RawGetString("path")),
+			IPAddress:  lua.LVAsString(reqTbl.RawGetString("ip_address")),
+			UserAgent:  lua.LVAsString(reqTbl.RawGetString("user_agent")),
+			Scheme:     lua.LVAsString(reqTbl.RawGetString("scheme")),
+			Port:       int(lua.LVAsNumber(reqTbl.RawGetString("port"))),
+			BodySHA256: lua.LVAsString(reqTbl.RawGetString("body_sha256")),
+		}
+
+		if queryLV := reqTbl.RawGetString("query"); queryLV.Type() == lua.LTTable {
+			query := make(map[string][]string)
+			queryLV.(*lua.LTable).ForEach(func(k, v lua.LValue) {
+				if k.Type() != lua.LTString || v.Type() != lua.LTTable {
+					return
+				}
+				v.(*lua.LTable).ForEach(func(_, value lua.LValue) {
+					if value.Type() == lua.LTString {
+						query[k.String()] = append(query[k.String()], value.String())
+					}
+				})
+			})
+			reqAttrs.Query = query
+		}
+
+		if tlsLV := reqTbl.RawGetString("tls"); tlsLV.Type() == lua.LTTable {
+			tlsTbl := tlsLV.(*lua.LTable)
+			reqAttrs.TLS = &request.TLSInfo{
+				SNI:         lua.LVAsString(tlsTbl.RawGetString("sni")),
+				Version:     lua.LVAsString(tlsTbl.RawGetString("version")),
+				CipherSuite: lua.LVAsString(tlsTbl.RawGetString("cipher_suite")),
+			}
 		}
 
 		if headersLV := reqTbl.RawGetString("headers"); headersLV.Type() == lua.LTTable {
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -219,6 +220,68 @@ end
 	}
 }
 
+func TestLuaDataSource_Fetch_StructuredRequestAttributes(t *testing.T) {
+	script := `
+function fetch(input)
+	local req = input.request_attributes
+	return {
+		data = '{"tag":"' .. req.query.tag[2] .. '","scheme":"' .. req.scheme .. '","port":' .. req.port .. ',"sni":"' .. req.tls.sni .. '"}',
+		content_type = "application/json"
+	}
+end
+
+function cache_key(input)
+	return {request_attributes = {query = input.request_attributes.query, tls = input.request_attributes.tls}}
+end
+`
+
+	ds, err := NewCacheableLuaDataSource(CacheableLuaDataSourceConfig{
+		Name:         "test",
+		Script:       script,
+		CacheKeyFunc: "cache_key",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	input := &service.DataSourceInput{
+		RequestAttributes: &request.RequestAttributes{
+			Path:   "/api?tag=a&tag=b",
+			Query:  map[string][]string{"tag": {"a", "b"}},
+			Scheme: "https",
+			Port:   8443,
+			TLS:    &request.TLSInfo{SNI: "api.example.com", Version: "TLSv1.3"},
+		},
+	}
+
+	result, err := ds.Fetch(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	var data map[string]any
+	if err := json.Unmarshal(result.Data, &data); err != nil {
+		t.Fatalf("failed to unmarshal result: %v", err)
+	}
+	want := map[string]any{"tag": "b", "scheme": "https", "port": float64(8443), "sni": "api.example.com"}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("data = %v, want %v", data, want)
+	}
+
+	masked := ds.CacheKey(input)
+	if masked.RequestAttributes == nil {
+		t.Fatal("expected request attributes in the cache key")
+	}
+	if !reflect.DeepEqual(masked.RequestAttributes.Query, input.RequestAttributes.Query) {
+		t.Errorf("cache key query = %v, want %v", masked.RequestAttributes.Query, input.RequestAttributes.Query)
+	}
+	if masked.RequestAttributes.TLS == nil || *masked.RequestAttributes.TLS != *input.RequestAttributes.TLS {
+		t.Errorf("cache key TLS = %+v, want %+v", masked.RequestAttributes.TLS, input.RequestAttributes.TLS)
+	}
+	if masked.RequestAttributes.Scheme != "" {
+		t.Errorf("cache key scheme should be empty, got %q", masked.RequestAttributes.Scheme)
+	}
+}
+
 func TestLuaDataSource_Fetch_JSONService(t *testing.T) {
 	script := `
 function fetch(input)
@@ -326,6 +389,47 @@ end
 	}
 }
 
+func TestLuaDataSource_Fetch_ContextDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	script := `
+function fetch(input)
+	local response, err = http.get("` + server.URL + `/slow")
+	if err ~= nil then
+		error(err)
+	end
+	return {data = response.body, content_type = "application/json"}
+end
+`
+
+	ds, err := NewLuaDataSource(LuaDataSourceConfig{
+		Name:   "test",
+		Script: script,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := ds.Fetch(ctx, &service.DataSourceInput{}); err == nil {
+		t.Fatal("expected error when the context deadline passes")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected fetch to stop at the deadline, took %s", elapsed)
+	}
+}
+
 func TestLuaDataSource_Fetch_HTTPService(t *testing.T) {
 	// Create a test HTTP server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -742,3 +846,32 @@ end
 		t.Errorf("CacheTTL() = %v, want %v", ds.CacheTTL(), 10*time.Minute)
 	}
 }
+
+func BenchmarkLuaDataSource_Fetch(b *testing.B) {
+	ds, err := NewLuaDataSource(LuaDataSourceConfig{
+		Name: "entitlements",
+		Script: `
+function fetch(input)
+  return {
+    data = json.encode({roles = {"developer", "admin"}, org_id = "o-" .. input.subject.subject}),
+    content_type = "application/json"
+  }
+end
+`,
+	})
+	if err != nil {
+		b.Fatalf("failed to create data source: %v", err)
+	}
+
+	input := &service.DataSourceInput{
+		Subject:           &trust.Result{Subject: "alice", TrustDomain: "example.com"},
+		RequestAttributes: &request.RequestAttributes{Method: "GET", Path: "/api/resource"},
+	}
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := ds.Fetch(ctx, input); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/pkg/plugin"
+)
+
+// PluginDataSource fetches data from an out-of-process plugin, for enrichment
+// sources written in other languages
+//
+// The plugin is asked for the data source by name, so one plugin can serve
+// several data sources.
+type PluginDataSource struct {
+	name    string
+	plugin  *plugin.Client
+	timeout time.Duration
+}
+
+// PluginDataSourceConfig configures a plugin data source
+type PluginDataSourceConfig struct {
+	// Name identifies this data source, and is passed to the plugin
+	Name string
+
+	// Plugin is the plugin that fetches the data
+	Plugin *plugin.Client
+
+	// Timeout bounds each fetch (default: 5s)
+	Timeout time.Duration
+}
+
+// NewPluginDataSource creates a new plugin data source
+func NewPluginDataSource(cfg PluginDataSourceConfig) (*PluginDataSource, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("data source name is required")
+	}
+	if cfg.Plugin == nil {
+		return nil, fmt.Errorf("plugin is required")
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &PluginDataSource{name: cfg.Name, plugin: cfg.Plugin, timeout: timeout}, nil
+}
+
+// Name implements service.DataSource
+func (ds *PluginDataSource) Name() string {
+	return ds.name
+}
+
+// Fetch implements service.DataSource
+func (ds *PluginDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	// The plugin request has the JSON form of the input
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode input: %w", err)
+	}
+	var req plugin.FetchRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("failed to encode input: %w", err)
+	}
+	req.DataSource = ds.name
+
+	ctx, cancel := context.WithTimeout(ctx, ds.timeout)
+	defer cancel()
+
+	resp, err := ds.plugin.Fetch(ctx, &req)
+	if err != nil {
+		return nil, fmt.Errorf("plugin fetch failed: %w", err)
+	}
+	if len(resp.Data) == 0 {
+		return nil, nil
+	}
+
+	return &service.DataSourceResult{
+		Data:        resp.Data,
+		ContentType: service.ContentTypeJSON,
+	}, nil
+}
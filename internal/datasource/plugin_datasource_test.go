@@ -0,0 +1,135 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+	"github.com/project-kessel/parsec/pkg/plugin"
+)
+
+// rolesPlugin returns the roles of subjects in its table, recording the last request
+type rolesPlugin struct {
+	roles map[string][]string
+	delay time.Duration
+
+	mu   sync.Mutex
+	last *plugin.FetchRequest
+}
+
+func (p *rolesPlugin) Fetch(ctx context.Context, req *plugin.FetchRequest) (*plugin.FetchResponse, error) {
+	p.mu.Lock()
+	p.last = req
+	p.mu.Unlock()
+	if p.delay > 0 {
+		select {
+		case <-time.After(p.delay):
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+	roles, ok := p.roles[req.Subject.Subject]
+	if !ok {
+		return &plugin.FetchResponse{}, nil
+	}
+	data, err := json.Marshal(map[string]any{"roles": roles})
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &plugin.FetchResponse{Data: data}, nil
+}
+
+// servePluginDataSource serves impl as a plugin and returns a client for it
+func servePluginDataSource(t *testing.T, impl plugin.DataSource) *plugin.Client {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	plugin.Register(srv, plugin.ServeConfig{DataSource: impl})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	client, err := plugin.NewClient(plugin.ClientConfig{Address: lis.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestPluginDataSource_Fetch(t *testing.T) {
+	impl := &rolesPlugin{roles: map[string][]string{"alice": {"admin", "viewer"}}}
+	ds, err := NewPluginDataSource(PluginDataSourceConfig{Name: "roles", Plugin: servePluginDataSource(t, impl)})
+	if err != nil {
+		t.Fatalf("NewPluginDataSource failed: %v", err)
+	}
+	if ds.Name() != "roles" {
+		t.Errorf("expected name roles, got %s", ds.Name())
+	}
+	ctx := context.Background()
+
+	result, err := ds.Fetch(ctx, &service.DataSourceInput{
+		Subject:           &trust.Result{Subject: "alice", TrustDomain: "idp.example.com", Claims: claims.Claims{"email": "alice@example.com"}},
+		RequestAttributes: &request.RequestAttributes{Method: "GET", Path: "/orders"},
+	})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	var data struct{ Roles []string }
+	if err := json.Unmarshal(result.Data, &data); err != nil || result.ContentType != service.ContentTypeJSON || !slices.Equal(data.Roles, []string{"admin", "viewer"}) {
+		t.Errorf("unexpected result %s (%s)", result.Data, result.ContentType)
+	}
+	impl.mu.Lock()
+	req := impl.last
+	impl.mu.Unlock()
+	if req.DataSource != "roles" || req.Subject.TrustDomain != "idp.example.com" || req.Subject.Claims["email"] != "alice@example.com" {
+		t.Errorf("unexpected request %+v", req)
+	}
+	if req.RequestAttributes == nil || req.RequestAttributes.Path != "/orders" || req.Actor != nil {
+		t.Errorf("unexpected request attributes %+v, actor %+v", req.RequestAttributes, req.Actor)
+	}
+
+	result, err = ds.Fetch(ctx, &service.DataSourceInput{Subject: &trust.Result{Subject: "bob"}})
+	if err != nil || result != nil {
+		t.Errorf("expected no result for bob, got %v (%v)", result, err)
+	}
+}
+
+func TestPluginDataSource_Timeout(t *testing.T) {
+	impl := &rolesPlugin{delay: time.Second}
+	ds, err := NewPluginDataSource(PluginDataSourceConfig{
+		Name:    "roles",
+		Plugin:  servePluginDataSource(t, impl),
+		Timeout: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ds.Fetch(context.Background(), &service.DataSourceInput{Subject: &trust.Result{Subject: "alice"}})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestNewPluginDataSource_Validation(t *testing.T) {
+	if _, err := NewPluginDataSource(PluginDataSourceConfig{Plugin: &plugin.Client{}}); err == nil {
+		t.Error("expected error without name")
+	}
+	if _, err := NewPluginDataSource(PluginDataSourceConfig{Name: "roles"}); err == nil {
+		t.Error("expected error without plugin")
+	}
+}
@@ -0,0 +1,431 @@
+package datasource
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// RedisCachingDataSource wraps a cacheable data source with a shared Redis cache
+//
+// Unlike groupcache, entries live in Redis with a native TTL, so every parsec
+// instance sees the same entries and expiry is exact. The cache is best effort:
+// if Redis is unavailable, results are fetched from the source directly.
+type RedisCachingDataSource struct {
+	source    service.DataSource
+	cacheable service.Cacheable
+	client    *redisClient
+	keyPrefix string
+	flights   fetchGroup
+}
+
+// RedisCachingConfig configures the Redis caching data source
+type RedisCachingConfig struct {
+	// Address is the Redis server address (host:port)
+	Address string
+
+	// Password authenticates with AUTH (optional)
+	Password string
+
+	// DB selects the Redis database (default: 0)
+	DB int
+
+	// KeyPrefix namespaces cache keys (default: "parsec:datasource:<name>:")
+	KeyPrefix string
+
+	// Timeout bounds connecting and each command (default: 1s)
+	Timeout time.Duration
+
+	// MaxIdleConns is the number of idle connections kept open (default: 4)
+	MaxIdleConns int
+}
+
+// NewRedisCachingDataSource wraps a data source with Redis caching
+// Returns the original source if it doesn't implement Cacheable
+func NewRedisCachingDataSource(source service.DataSource, config RedisCachingConfig) (service.DataSource, error) {
+	if config.Address == "" {
+		return nil, fmt.Errorf("redis address is required")
+	}
+
+	cacheable, ok := source.(service.Cacheable)
+	if !ok {
+		return source, nil
+	}
+
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = "parsec:datasource:" + source.Name() + ":"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = time.Second
+	}
+	if config.MaxIdleConns == 0 {
+		config.MaxIdleConns = 4
+	}
+
+	return &RedisCachingDataSource{
+		source:    source,
+		cacheable: cacheable,
+		keyPrefix: config.KeyPrefix,
+		client: &redisClient{
+			address:  config.Address,
+			password: config.Password,
+			db:       config.DB,
+			timeout:  config.Timeout,
+			idle:     make(chan *redisConn, config.MaxIdleConns),
+		},
+	}, nil
+}
+
+// Name forwards to the underlying data source
+func (c *RedisCachingDataSource) Name() string {
+	return c.source.Name()
+}
+
+// Fetch checks Redis first, then fetches from source on miss
+// Concurrent misses on this instance for the same cache key result in one
+// fetch from source.
+func (c *RedisCachingDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	maskedInput := c.cacheable.CacheKey(input)
+	keyJSON, err := SerializeInputToJSON(&maskedInput)
+	if err != nil {
+		return c.source.Fetch(ctx, input)
+	}
+	key := c.keyPrefix + hashKey(keyJSON)
+
+	if result, found := c.lookup(ctx, key); found {
+		return result, nil
+	}
+
+	// Concurrent misses on this instance for the same key share one fetch
+	return c.flights.do(ctx, key, func() (*service.DataSourceResult, error) {
+		return c.fetchAndStore(ctx, input, &maskedInput, key)
+	})
+}
+
+// lookup returns the entry cached under key, if Redis has a readable one
+func (c *RedisCachingDataSource) lookup(ctx context.Context, key string) (*service.DataSourceResult, bool) {
+	cached, err := c.client.get(ctx, key)
+	if err != nil || cached == nil {
+		return nil, false
+	}
+	var entry cachedEntry
+	if err := json.Unmarshal(cached, &entry); err != nil {
+		return nil, false
+	}
+	return &service.DataSourceResult{
+		Data:        entry.Data,
+		ContentType: entry.ContentType,
+	}, true
+}
+
+// fetchAndStore fetches from source and writes the result to Redis
+func (c *RedisCachingDataSource) fetchAndStore(ctx context.Context, input, maskedInput *service.DataSourceInput, key string) (*service.DataSourceResult, error) {
+	result, err := c.source.Fetch(ctx, input)
+	if err != nil || result == nil {
+		return result, err
+	}
+
+	entryBytes, err := json.Marshal(cachedEntry{
+		Data:        result.Data,
+		ContentType: result.ContentType,
+	})
+	if err == nil {
+		// Best effort: a failed write only costs a future cache miss
+		ttl := c.cacheable.CacheTTL()
+		if c.client.set(ctx, key, entryBytes, ttl) == nil {
+			if subject := service.CacheKeySubject(maskedInput); subject != "" {
+				_ = c.indexSubject(ctx, subject, key, ttl)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// subjectIndex is the key of the set of cache keys held for a subject
+func (c *RedisCachingDataSource) subjectIndex(subject string) string {
+	return c.keyPrefix + "subject:" + subject
+}
+
+// indexSubject records key in the subject's index, which lives as long as its
+// newest entry
+func (c *RedisCachingDataSource) indexSubject(ctx context.Context, subject, key string, ttl time.Duration) error {
+	index := c.subjectIndex(subject)
+	if _, err := c.client.do(ctx, "SADD", index, key); err != nil {
+		return err
+	}
+	if ttl > 0 {
+		_, err := c.client.do(ctx, "PEXPIRE", index, strconv.FormatInt(ttl.Milliseconds(), 10))
+		return err
+	}
+	return nil
+}
+
+// InvalidateCache deletes the selected entries from Redis
+// Entries are found by key, through the subject's index, or by scanning the
+// key prefix when every entry is selected.
+func (c *RedisCachingDataSource) InvalidateCache(ctx context.Context, invalidation service.CacheInvalidation) error {
+	switch {
+	case invalidation.Key != "":
+		if invalidation.Subject != "" {
+			key, err := DeserializeInputFromJSON(invalidation.Key)
+			if err != nil {
+				return err
+			}
+			if service.CacheKeySubject(key) != invalidation.Subject {
+				return nil
+			}
+		}
+		return c.client.del(ctx, c.keyPrefix+hashKey(invalidation.Key))
+
+	case invalidation.Subject != "":
+		index := c.subjectIndex(invalidation.Subject)
+		reply, err := c.client.do(ctx, "SMEMBERS", index)
+		if err != nil {
+			return fmt.Errorf("failed to read subject index: %w", err)
+		}
+		keys, err := redisStrings(reply)
+		if err != nil {
+			return err
+		}
+		return c.client.del(ctx, append(keys, index)...)
+
+	default:
+		pattern := redisGlobEscaper.Replace(c.keyPrefix) + "*"
+		cursor := "0"
+		for {
+			reply, err := c.client.do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", "100")
+			if err != nil {
+				return fmt.Errorf("failed to scan cache keys: %w", err)
+			}
+			page, ok := reply.([]any)
+			if !ok || len(page) != 2 {
+				return fmt.Errorf("unexpected redis reply to SCAN: %v", reply)
+			}
+			next, err := redisStrings([]any{page[0]})
+			if err != nil {
+				return err
+			}
+			keys, err := redisStrings(page[1])
+			if err != nil {
+				return err
+			}
+			if err := c.client.del(ctx, keys...); err != nil {
+				return err
+			}
+			if cursor = next[0]; cursor == "0" {
+				return nil
+			}
+		}
+	}
+}
+
+// redisGlobEscaper escapes the characters special in SCAN MATCH patterns
+var redisGlobEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// redisStrings converts an array reply of bulk strings
+func redisStrings(reply any) ([]string, error) {
+	items, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected redis reply: %v", reply)
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		value, ok := item.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("unexpected redis array item: %v", item)
+		}
+		values = append(values, string(value))
+	}
+	return values, nil
+}
+
+// redisClient is a minimal RESP client supporting the commands the cache needs
+type redisClient struct {
+	address  string
+	password string
+	db       int
+	timeout  time.Duration
+	idle     chan *redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// get returns the value of key, or nil if it does not exist
+func (r *redisClient) get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := r.do(ctx, "GET", key)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, nil
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected redis reply to GET: %v", reply)
+	}
+	return value, nil
+}
+
+// set stores value at key, expiring after ttl (never if ttl is 0)
+func (r *redisClient) set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+// del deletes keys, if any
+func (r *redisClient) del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if _, err := r.do(ctx, append([]string{"DEL"}, keys...)...); err != nil {
+		return fmt.Errorf("failed to delete cache entries: %w", err)
+	}
+	return nil
+}
+
+// do runs a command on a pooled connection
+func (r *redisClient) do(ctx context.Context, args ...string) (any, error) {
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.command(r.deadline(ctx), args...)
+	if err != nil {
+		// The connection state is unknown after an I/O or protocol error
+		_ = conn.conn.Close()
+		return nil, err
+	}
+
+	select {
+	case r.idle <- conn:
+	default:
+		_ = conn.conn.Close()
+	}
+	return reply, nil
+}
+
+// conn returns an idle connection or dials a new one
+func (r *redisClient) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-r.idle:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: r.timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", r.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if r.password != "" {
+		if _, err := conn.command(r.deadline(ctx), "AUTH", r.password); err != nil {
+			_ = netConn.Close()
+			return nil, fmt.Errorf("redis AUTH failed: %w", err)
+		}
+	}
+	if r.db != 0 {
+		if _, err := conn.command(r.deadline(ctx), "SELECT", strconv.Itoa(r.db)); err != nil {
+			_ = netConn.Close()
+			return nil, fmt.Errorf("redis SELECT failed: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+func (r *redisClient) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(r.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		return ctxDeadline
+	}
+	return deadline
+}
+
+// command writes a RESP array command and reads its reply
+func (c *redisConn) command(deadline time.Time, args ...string) (any, error) {
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		return nil, fmt.Errorf("failed to write redis command: %w", err)
+	}
+
+	return c.readReply()
+}
+
+// readReply reads a RESP reply: simple strings and integers are returned as
+// strings and int64s, bulk strings as []byte, arrays as []any, and a null
+// bulk string or array as nil
+func (c *redisConn) readReply() (any, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redis reply: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("redis error: %s", line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis bulk length: %w", err)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, buf); err != nil {
+			return nil, fmt.Errorf("failed to read redis bulk string: %w", err)
+		}
+		return buf[:size], nil
+	case '*':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis array length: %w", err)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		items := make([]any, size)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unsupported redis reply: %q", line)
+	}
+}
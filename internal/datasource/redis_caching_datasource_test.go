@@ -0,0 +1,291 @@
+package datasource
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// fakeRedis is a minimal in-memory Redis speaking enough RESP for the cache
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu      sync.Mutex
+	values  map[string]string
+	ttls    map[string]string
+	sets    map[string]map[string]bool
+	authOK  bool
+	selects []string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	r := &fakeRedis{
+		listener: listener,
+		password: password,
+		values:   make(map[string]string),
+		ttls:     make(map[string]string),
+		sets:     make(map[string]map[string]bool),
+	}
+	go r.serve()
+	t.Cleanup(func() { _ = listener.Close() })
+	return r
+}
+
+func (r *fakeRedis) serve() {
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+		go r.handle(conn)
+	}
+}
+
+func (r *fakeRedis) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		r.mu.Lock()
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			r.authOK = args[1] == r.password
+			reply = "+OK\r\n"
+			if !r.authOK {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case "SELECT":
+			r.selects = append(r.selects, args[1])
+			reply = "+OK\r\n"
+		case "GET":
+			if value, ok := r.values[args[1]]; ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+			} else {
+				reply = "$-1\r\n"
+			}
+		case "SET":
+			r.values[args[1]] = args[2]
+			if len(args) == 5 {
+				r.ttls[args[1]] = args[4]
+			}
+			reply = "+OK\r\n"
+		case "SADD":
+			if r.sets[args[1]] == nil {
+				r.sets[args[1]] = make(map[string]bool)
+			}
+			r.sets[args[1]][args[2]] = true
+			reply = ":1\r\n"
+		case "PEXPIRE":
+			reply = ":1\r\n"
+		case "SMEMBERS":
+			var members []string
+			for member := range r.sets[args[1]] {
+				members = append(members, member)
+			}
+			reply = respArray(members)
+		case "DEL":
+			for _, key := range args[1:] {
+				delete(r.values, key)
+				delete(r.ttls, key)
+				delete(r.sets, key)
+			}
+			reply = fmt.Sprintf(":%d\r\n", len(args)-1)
+		case "SCAN":
+			// A single page of the keys matching "<prefix>*"
+			prefix := strings.TrimSuffix(args[3], "*")
+			var keys []string
+			for key := range r.values {
+				if strings.HasPrefix(key, prefix) {
+					keys = append(keys, key)
+				}
+			}
+			for key := range r.sets {
+				if strings.HasPrefix(key, prefix) {
+					keys = append(keys, key)
+				}
+			}
+			reply = "*2\r\n$1\r\n0\r\n" + respArray(keys)
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		r.mu.Unlock()
+
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func respArray(items []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(items))
+	for _, item := range items {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(item), item)
+	}
+	return b.String()
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		if _, err := reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func TestRedisCachingDataSource(t *testing.T) {
+	ctx := context.Background()
+	redis := newFakeRedis(t, "s3cret")
+
+	source := &mockCacheableDataSource{name: "roles", ttl: 5 * time.Minute}
+	ds, err := NewRedisCachingDataSource(source, RedisCachingConfig{
+		Address:  redis.listener.Addr().String(),
+		Password: "s3cret",
+		DB:       2,
+	})
+	if err != nil {
+		t.Fatalf("failed to create caching data source: %v", err)
+	}
+
+	input := &service.DataSourceInput{Subject: &trust.Result{Subject: "alice"}}
+
+	first, err := ds.Fetch(ctx, input)
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	second, err := ds.Fetch(ctx, input)
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+
+	if source.fetchCount != 1 {
+		t.Errorf("expected 1 source fetch, got %d", source.fetchCount)
+	}
+	if string(first.Data) != string(second.Data) || second.ContentType != service.ContentTypeJSON {
+		t.Errorf("expected cached result %s, got %s (%s)", first.Data, second.Data, second.ContentType)
+	}
+
+	redis.mu.Lock()
+	defer redis.mu.Unlock()
+	if !redis.authOK {
+		t.Error("expected client to authenticate")
+	}
+	if len(redis.selects) == 0 || redis.selects[0] != "2" {
+		t.Errorf("expected SELECT 2, got %v", redis.selects)
+	}
+	for key, ttl := range redis.ttls {
+		if !strings.HasPrefix(key, "parsec:datasource:roles:") {
+			t.Errorf("unexpected key %s", key)
+		}
+		if ttl != "300000" {
+			t.Errorf("expected PX 300000, got %s", ttl)
+		}
+	}
+	if len(redis.ttls) != 1 {
+		t.Errorf("expected 1 cached entry, got %d", len(redis.ttls))
+	}
+}
+
+func TestRedisCachingDataSource_InvalidateCache(t *testing.T) {
+	redis := newFakeRedis(t, "")
+	source := &mockCacheableDataSource{name: "roles", ttl: 5 * time.Minute}
+	ds, err := NewRedisCachingDataSource(source, RedisCachingConfig{Address: redis.listener.Addr().String()})
+	if err != nil {
+		t.Fatalf("failed to create caching data source: %v", err)
+	}
+
+	testInvalidateCache(t, ds, source)
+
+	redis.mu.Lock()
+	defer redis.mu.Unlock()
+	if len(redis.values) != 2 {
+		t.Errorf("expected only the refetched entries to remain, got %d", len(redis.values))
+	}
+}
+
+func TestRedisCachingDataSource_CollapsesConcurrentFetches(t *testing.T) {
+	redis := newFakeRedis(t, "")
+	source := &blockingCacheableDataSource{release: make(chan struct{})}
+	cached, err := NewRedisCachingDataSource(source, RedisCachingConfig{Address: redis.listener.Addr().String()})
+	if err != nil {
+		t.Fatalf("failed to create caching data source: %v", err)
+	}
+	ds := cached.(*RedisCachingDataSource)
+
+	const callers = 5
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Go(func() {
+			input := &service.DataSourceInput{Subject: &trust.Result{Subject: "alice"}}
+			if _, err := ds.Fetch(context.Background(), input); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+	waitForPending(t, &ds.flights, callers-1)
+	close(source.release)
+	wg.Wait()
+
+	if n := source.fetches.Load(); n != 1 {
+		t.Errorf("expected 1 upstream fetch, got %d", n)
+	}
+}
+
+func TestRedisCachingDataSource_FallsBackWhenUnavailable(t *testing.T) {
+	// Reserve an address with nothing listening
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	address := listener.Addr().String()
+	_ = listener.Close()
+
+	source := &mockCacheableDataSource{name: "roles"}
+	ds, err := NewRedisCachingDataSource(source, RedisCachingConfig{Address: address})
+	if err != nil {
+		t.Fatalf("failed to create caching data source: %v", err)
+	}
+
+	if _, err := ds.Fetch(context.Background(), &service.DataSourceInput{Subject: &trust.Result{Subject: "alice"}}); err != nil {
+		t.Fatalf("expected fetch to fall back to the source: %v", err)
+	}
+	if source.fetchCount != 1 {
+		t.Errorf("expected 1 source fetch, got %d", source.fetchCount)
+	}
+}
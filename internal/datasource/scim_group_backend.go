@@ -0,0 +1,189 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// SCIMGroupBackend resolves groups from a SCIM 2.0 service provider (RFC 7644)
+//
+// Groups are found by filtering the Groups endpoint on their members, e.g.
+// GET /Groups?filter=members.value eq "2819c223", and paged through with
+// startIndex and count until every result has been read. Each group
+// contributes its displayName.
+type SCIMGroupBackend struct {
+	url      string
+	member   *InputTemplate
+	headers  map[string]*InputTemplate
+	pageSize int
+	maxPages int
+	client   *http.Client
+}
+
+// SCIMGroupBackendConfig configures a SCIM group backend
+type SCIMGroupBackendConfig struct {
+	// URL is the base URL of the SCIM service, e.g. "https://idp.example.com/scim/v2"
+	URL string
+
+	// Member is a template of the member value the groups are filtered on
+	// (default: "{{ .subject.subject }}")
+	Member string
+
+	// Headers are request header templates, e.g. for authorization
+	Headers map[string]string
+
+	// PageSize is the number of groups requested per page (default: 100)
+	PageSize int
+
+	// MaxPages bounds the pages read per lookup (default: 50). Subjects with
+	// more groups fail the lookup rather than resolve to a partial list.
+	MaxPages int
+
+	// Timeout bounds each request (default: 30s)
+	Timeout time.Duration
+
+	// Transport is an optional HTTP transport (e.g. for fixtures)
+	Transport http.RoundTripper
+}
+
+// scimListResponse is a page of a SCIM list response
+type scimListResponse struct {
+	TotalResults int `json:"totalResults"`
+	Resources    []struct {
+		DisplayName string `json:"displayName"`
+	} `json:"Resources"`
+}
+
+// NewSCIMGroupBackend creates a new SCIM group backend
+func NewSCIMGroupBackend(cfg SCIMGroupBackendConfig) (*SCIMGroupBackend, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+
+	memberText := cfg.Member
+	if memberText == "" {
+		memberText = "{{ .subject.subject }}"
+	}
+	member, err := ParseInputTemplate("member", memberText, EscapeText)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]*InputTemplate, len(cfg.Headers))
+	for name, value := range cfg.Headers {
+		headers[name], err = ParseInputTemplate("header "+name, value, EscapeText)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pageSize := cfg.PageSize
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+	maxPages := cfg.MaxPages
+	if maxPages <= 0 {
+		maxPages = 50
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &SCIMGroupBackend{
+		url:      strings.TrimSuffix(cfg.URL, "/") + "/Groups",
+		member:   member,
+		headers:  headers,
+		pageSize: pageSize,
+		maxPages: maxPages,
+		client: &http.Client{
+			Transport: cfg.Transport,
+			Timeout:   timeout,
+		},
+	}, nil
+}
+
+// Groups implements GroupBackend
+func (b *SCIMGroupBackend) Groups(ctx context.Context, input *service.DataSourceInput) ([]string, error) {
+	member, err := b.member.Render(input)
+	if err != nil {
+		return nil, err
+	}
+	if member == "" {
+		return nil, nil
+	}
+	// SCIM filter strings are JSON string literals
+	literal, err := json.Marshal(member)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode member: %w", err)
+	}
+
+	headers := make(http.Header, len(b.headers))
+	for name, tmpl := range b.headers {
+		value, err := tmpl.Render(input)
+		if err != nil {
+			return nil, err
+		}
+		headers.Set(name, value)
+	}
+
+	var groups []string
+	startIndex := 1
+	for range b.maxPages {
+		page, err := b.page(ctx, headers, "members.value eq "+string(literal), startIndex)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range page.Resources {
+			if group.DisplayName != "" {
+				groups = append(groups, group.DisplayName)
+			}
+		}
+		startIndex += len(page.Resources)
+		if len(page.Resources) == 0 || startIndex > page.TotalResults {
+			return groups, nil
+		}
+	}
+	return nil, fmt.Errorf("member %s has groups beyond %d pages", member, b.maxPages)
+}
+
+// page requests one page of groups matching filter
+func (b *SCIMGroupBackend) page(ctx context.Context, headers http.Header, filter string, startIndex int) (*scimListResponse, error) {
+	query := url.Values{
+		"filter":     {filter},
+		"attributes": {"displayName"},
+		"startIndex": {strconv.Itoa(startIndex)},
+		"count":      {strconv.Itoa(b.pageSize)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.url+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header = headers.Clone()
+	req.Header.Set("Accept", "application/scim+json, application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("request failed: status %d", resp.StatusCode)
+	}
+
+	var page scimListResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxHTTPResponseBytes)).Decode(&page); err != nil {
+		return nil, fmt.Errorf("invalid SCIM list response: %w", err)
+	}
+	return &page, nil
+}
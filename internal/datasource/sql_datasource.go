@@ -0,0 +1,163 @@
+package datasource
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// SQLDataSource runs a parameterized query and returns the rows as JSON
+//
+// Query arguments are input templates (see InputTemplate) bound as parameters,
+// never interpolated into the query text. Rows are returned as an array of
+// objects keyed by column name, or as a single object when SingleRow is set.
+type SQLDataSource struct {
+	name      string
+	db        *sql.DB
+	query     string
+	args      []*InputTemplate
+	singleRow bool
+	timeout   time.Duration
+}
+
+// SQLDataSourceConfig configures a SQL data source
+type SQLDataSourceConfig struct {
+	// Name identifies this data source
+	Name string
+
+	// DB is the database handle. The driver must be registered with database/sql.
+	DB *sql.DB
+
+	// Query is the SQL query, using the driver's placeholder syntax
+	// e.g. "SELECT role FROM user_roles WHERE user_id = $1"
+	Query string
+
+	// Args are templates for the query parameters, e.g. ["{{ .subject.subject }}"]
+	Args []string
+
+	// SingleRow returns the first row as an object instead of an array of rows.
+	// No rows means the data source has nothing to contribute.
+	SingleRow bool
+
+	// Timeout bounds each query (default: 5s)
+	Timeout time.Duration
+}
+
+// NewSQLDataSource creates a new SQL data source
+func NewSQLDataSource(cfg SQLDataSourceConfig) (*SQLDataSource, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("data source name is required")
+	}
+	if cfg.DB == nil {
+		return nil, fmt.Errorf("database is required")
+	}
+	if cfg.Query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+
+	args := make([]*InputTemplate, len(cfg.Args))
+	for i, arg := range cfg.Args {
+		tmpl, err := ParseInputTemplate(fmt.Sprintf("arg %d", i), arg, EscapeText)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = tmpl
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	return &SQLDataSource{
+		name:      cfg.Name,
+		db:        cfg.DB,
+		query:     cfg.Query,
+		args:      args,
+		singleRow: cfg.SingleRow,
+		timeout:   timeout,
+	}, nil
+}
+
+// Name returns the data source name
+func (ds *SQLDataSource) Name() string {
+	return ds.name
+}
+
+// Fetch runs the query with the templated arguments
+func (ds *SQLDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	args := make([]any, len(ds.args))
+	for i, tmpl := range ds.args {
+		arg, err := tmpl.Render(input)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = arg
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ds.timeout)
+	defer cancel()
+
+	rows, err := ds.db.QueryContext(ctx, ds.query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+
+	results := []map[string]any{}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			// Drivers commonly return text columns as []byte
+			if b, ok := values[i].([]byte); ok {
+				row[column] = string(b)
+			} else {
+				row[column] = values[i]
+			}
+		}
+		results = append(results, row)
+
+		if ds.singleRow {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	var result any = results
+	if ds.singleRow {
+		if len(results) == 0 {
+			return nil, nil
+		}
+		result = results[0]
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode rows: %w", err)
+	}
+
+	return &service.DataSourceResult{
+		Data:        data,
+		ContentType: service.ContentTypeJSON,
+	}, nil
+}
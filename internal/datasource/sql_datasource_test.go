@@ -0,0 +1,137 @@
+package datasource
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// fakeRolesDriver answers any query with the roles of the user given as the
+// single argument
+type fakeRolesDriver struct {
+	roles map[string][]string
+}
+
+func (d *fakeRolesDriver) Open(string) (driver.Conn, error) { return &fakeRolesConn{d}, nil }
+
+type fakeRolesConn struct{ driver *fakeRolesDriver }
+
+func (c *fakeRolesConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeRolesStmt{c.driver}, nil
+}
+func (c *fakeRolesConn) Close() error              { return nil }
+func (c *fakeRolesConn) Begin() (driver.Tx, error) { return nil, fmt.Errorf("not supported") }
+
+type fakeRolesStmt struct{ driver *fakeRolesDriver }
+
+func (s *fakeRolesStmt) Close() error  { return nil }
+func (s *fakeRolesStmt) NumInput() int { return 1 }
+func (s *fakeRolesStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("not supported")
+}
+func (s *fakeRolesStmt) Query(args []driver.Value) (driver.Rows, error) {
+	user, _ := args[0].(string)
+	return &fakeRolesRows{user: user, roles: s.driver.roles[user]}, nil
+}
+
+type fakeRolesRows struct {
+	user  string
+	roles []string
+}
+
+func (r *fakeRolesRows) Columns() []string { return []string{"user_id", "role"} }
+func (r *fakeRolesRows) Close() error      { return nil }
+func (r *fakeRolesRows) Next(dest []driver.Value) error {
+	if len(r.roles) == 0 {
+		return io.EOF
+	}
+	dest[0] = r.user
+	dest[1] = []byte(r.roles[0])
+	r.roles = r.roles[1:]
+	return nil
+}
+
+func init() {
+	sql.Register("fake-roles", &fakeRolesDriver{roles: map[string][]string{
+		"alice": {"admin", "viewer"},
+	}})
+}
+
+func TestSQLDataSource(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("fake-roles", "")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	input := func(subject string) *service.DataSourceInput {
+		return &service.DataSourceInput{Subject: &trust.Result{Subject: subject}}
+	}
+
+	t.Run("returns rows as objects", func(t *testing.T) {
+		ds, err := NewSQLDataSource(SQLDataSourceConfig{
+			Name:  "roles",
+			DB:    db,
+			Query: "SELECT user_id, role FROM user_roles WHERE user_id = $1",
+			Args:  []string{"{{ .subject.subject }}"},
+		})
+		if err != nil {
+			t.Fatalf("failed to create data source: %v", err)
+		}
+
+		result, err := ds.Fetch(ctx, input("alice"))
+		if err != nil {
+			t.Fatalf("fetch failed: %v", err)
+		}
+		var rows []map[string]any
+		if err := json.Unmarshal(result.Data, &rows); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if len(rows) != 2 || rows[0]["role"] != "admin" || rows[1]["role"] != "viewer" {
+			t.Errorf("unexpected rows: %v", rows)
+		}
+
+		result, err = ds.Fetch(ctx, input("bob"))
+		if err != nil {
+			t.Fatalf("fetch failed: %v", err)
+		}
+		if string(result.Data) != "[]" {
+			t.Errorf("expected empty array for no rows, got %s", result.Data)
+		}
+	})
+
+	t.Run("single row returns an object or nothing", func(t *testing.T) {
+		ds, err := NewSQLDataSource(SQLDataSourceConfig{
+			Name:      "primary_role",
+			DB:        db,
+			Query:     "SELECT user_id, role FROM user_roles WHERE user_id = $1",
+			Args:      []string{"{{ .subject.subject }}"},
+			SingleRow: true,
+		})
+		if err != nil {
+			t.Fatalf("failed to create data source: %v", err)
+		}
+
+		result, err := ds.Fetch(ctx, input("alice"))
+		if err != nil {
+			t.Fatalf("fetch failed: %v", err)
+		}
+		if string(result.Data) != `{"role":"admin","user_id":"alice"}` {
+			t.Errorf("unexpected data: %s", result.Data)
+		}
+
+		result, err = ds.Fetch(ctx, input("bob"))
+		if err != nil || result != nil {
+			t.Errorf("expected nil result and error, got %v, %v", result, err)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+// Package decisionlog records an audit event for every token issuance
+// decision and delivers the events to sinks such as Kafka, a file or stdout,
+// for security teams to stream into their SIEM.
+package decisionlog
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// SchemaVersion identifies the schema of the events. Fields may be added
+// within a version; removing or changing the meaning of a field requires a
+// new version.
+const SchemaVersion = "parsec.decision/v1"
+
+// EventTypeTokenIssuance is the type of events recording a token issuance
+const EventTypeTokenIssuance = "token_issuance"
+
+// Decisions recorded by token issuance events
+const (
+	// DecisionIssued means every requested token was issued
+	DecisionIssued = "issued"
+
+	// DecisionFailed means issuance failed; the event's error says why
+	DecisionFailed = "failed"
+)
+
+// Event is a decision event
+// Token values are never recorded, only their types and lifetimes.
+type Event struct {
+	SchemaVersion string    `json:"schema_version"`
+	ID            string    `json:"id"`
+	Time          time.Time `json:"time"`
+	Type          string    `json:"type"`
+	Decision      string    `json:"decision"`
+
+	// Subject and Actor are the identities tokens were requested for and by
+	Subject *Principal `json:"subject,omitempty"`
+	Actor   *Principal `json:"actor,omitempty"`
+
+	// Scope and TokenTypes are what was requested
+	Scope      string   `json:"scope,omitempty"`
+	TokenTypes []string `json:"token_types"`
+
+	// Tokens are the tokens issued
+	Tokens []IssuedToken `json:"tokens,omitempty"`
+
+	// Error is why issuance failed
+	Error *EventError `json:"error,omitempty"`
+}
+
+// Principal identifies a validated identity
+type Principal struct {
+	Subject     string `json:"subject"`
+	Issuer      string `json:"issuer,omitempty"`
+	TrustDomain string `json:"trust_domain,omitempty"`
+}
+
+// IssuedToken describes an issued token
+type IssuedToken struct {
+	Type      string    `json:"type"`
+	IssuedAt  time.Time `json:"issued_at,omitzero"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	Compacted bool      `json:"compacted,omitempty"`
+
+	// Provenance records where the token's claims came from, for issuers
+	// configured to record it
+	Provenance []ClaimSource `json:"provenance,omitempty"`
+}
+
+// ClaimSource records the configured claim mapper, and the data sources it
+// fetched, that produced a claim
+type ClaimSource struct {
+	Claim       string   `json:"claim"`
+	Mapper      string   `json:"mapper"`
+	MapperType  string   `json:"mapper_type,omitempty"`
+	DataSources []string `json:"data_sources,omitempty"`
+}
+
+// EventError describes a failed decision
+type EventError struct {
+	// Code is the parsec error code, e.g. "invalid_subject_token"
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// newEvent creates an event of the given type, stamped with a time-ordered ID
+func newEvent(eventType string, now time.Time) Event {
+	event := Event{
+		SchemaVersion: SchemaVersion,
+		Time:          now.UTC(),
+		Type:          eventType,
+	}
+	if id, err := uuid.NewV7(); err == nil {
+		event.ID = id.String()
+	} else {
+		event.ID = uuid.NewString()
+	}
+	return event
+}
+
+// principal returns the principal of a validated identity (nil if none)
+func principal(result *trust.Result) *Principal {
+	if result == nil {
+		return nil
+	}
+	return &Principal{
+		Subject:     result.Subject,
+		Issuer:      result.Issuer,
+		TrustDomain: result.TrustDomain,
+	}
+}
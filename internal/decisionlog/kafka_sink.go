@@ -0,0 +1,129 @@
+package decisionlog
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+)
+
+// KafkaSink produces events to a Kafka topic
+//
+// Records are keyed by the subject's trust domain and subject, so a
+// subject's events stay in order within a partition, and carry the schema
+// version in a header. Writes wait for every in-sync replica to acknowledge
+// (acks=all) with the idempotent producer, so retried batches are not
+// duplicated within a producer session.
+type KafkaSink struct {
+	client kafkaProducer
+}
+
+// kafkaProducer is the part of the Kafka client used by KafkaSink
+type kafkaProducer interface {
+	ProduceSync(ctx context.Context, rs ...*kgo.Record) kgo.ProduceResults
+	Close()
+}
+
+// KafkaSinkConfig configures a Kafka sink
+type KafkaSinkConfig struct {
+	// Brokers are the seed brokers, e.g. ["kafka-0.kafka:9092"]
+	Brokers []string
+
+	// Topic is the topic events are produced to
+	Topic string
+
+	// ClientID identifies parsec to the brokers (default: parsec)
+	ClientID string
+
+	// TLS enables TLS to the brokers (plaintext if nil)
+	TLS *tls.Config
+
+	// SASL authenticates to the brokers (optional)
+	SASL sasl.Mechanism
+
+	// DeliveryTimeout bounds how long a record is retried before the write
+	// fails (default: 30s)
+	DeliveryTimeout time.Duration
+}
+
+// NewKafkaSink creates a Kafka sink
+// No connection is made until the first write.
+func NewKafkaSink(cfg KafkaSinkConfig) (*KafkaSink, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka sink requires brokers")
+	}
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("kafka sink requires a topic")
+	}
+
+	clientID := cfg.ClientID
+	if clientID == "" {
+		clientID = "parsec"
+	}
+	deliveryTimeout := cfg.DeliveryTimeout
+	if deliveryTimeout == 0 {
+		deliveryTimeout = 30 * time.Second
+	}
+
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(cfg.Brokers...),
+		kgo.ClientID(clientID),
+		kgo.DefaultProduceTopic(cfg.Topic),
+		kgo.RequiredAcks(kgo.AllISRAcks()),
+		kgo.RecordDeliveryTimeout(deliveryTimeout),
+	}
+	if cfg.TLS != nil {
+		opts = append(opts, kgo.DialTLSConfig(cfg.TLS))
+	}
+	if cfg.SASL != nil {
+		opts = append(opts, kgo.SASL(cfg.SASL))
+	}
+
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client: %w", err)
+	}
+	return &KafkaSink{client: client}, nil
+}
+
+// Write implements Sink
+func (s *KafkaSink) Write(ctx context.Context, events []Event) error {
+	records := make([]*kgo.Record, len(events))
+	for i, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode decision event: %w", err)
+		}
+		records[i] = &kgo.Record{
+			Key:   recordKey(event),
+			Value: value,
+			Headers: []kgo.RecordHeader{
+				{Key: "schema_version", Value: []byte(SchemaVersion)},
+				{Key: "content-type", Value: []byte("application/json")},
+			},
+		}
+	}
+
+	if err := s.client.ProduceSync(ctx, records...).FirstErr(); err != nil {
+		return fmt.Errorf("failed to produce decision events: %w", err)
+	}
+	return nil
+}
+
+// Close implements Sink
+func (s *KafkaSink) Close() error {
+	s.client.Close()
+	return nil
+}
+
+// recordKey returns the partitioning key of an event (nil without a subject)
+func recordKey(event Event) []byte {
+	if event.Subject == nil {
+		return nil
+	}
+	return []byte(event.Subject.TrustDomain + "/" + event.Subject.Subject)
+}
@@ -0,0 +1,80 @@
+package decisionlog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// fakeProducer records produced records, failing with err if set
+type fakeProducer struct {
+	records []*kgo.Record
+	err     error
+	closed  bool
+}
+
+func (p *fakeProducer) ProduceSync(ctx context.Context, rs ...*kgo.Record) kgo.ProduceResults {
+	results := make(kgo.ProduceResults, len(rs))
+	for i, r := range rs {
+		results[i] = kgo.ProduceResult{Record: r, Err: p.err}
+		if p.err == nil {
+			p.records = append(p.records, r)
+		}
+	}
+	return results
+}
+
+func (p *fakeProducer) Close() { p.closed = true }
+
+func TestKafkaSink_Write(t *testing.T) {
+	producer := &fakeProducer{}
+	sink := &KafkaSink{client: producer}
+
+	events := testEvents(2)
+	events[0].Subject = &Principal{Subject: "alice", TrustDomain: "corp.example.com"}
+	if err := sink.Write(context.Background(), events); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if len(producer.records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(producer.records))
+	}
+	record := producer.records[0]
+	if string(record.Key) != "corp.example.com/alice" {
+		t.Errorf("expected records keyed by subject, got %q", record.Key)
+	}
+	if producer.records[1].Key != nil {
+		t.Errorf("expected no key without a subject, got %q", producer.records[1].Key)
+	}
+	if len(record.Headers) == 0 || record.Headers[0].Key != "schema_version" || string(record.Headers[0].Value) != SchemaVersion {
+		t.Errorf("expected schema version header, got %v", record.Headers)
+	}
+	var event Event
+	if err := json.Unmarshal(record.Value, &event); err != nil || event.ID != events[0].ID {
+		t.Errorf("expected the event as JSON, got %s (%v)", record.Value, err)
+	}
+
+	_ = sink.Close()
+	if !producer.closed {
+		t.Error("expected the client to be closed")
+	}
+}
+
+func TestKafkaSink_WriteError(t *testing.T) {
+	sink := &KafkaSink{client: &fakeProducer{err: errors.New("not enough replicas")}}
+	if err := sink.Write(context.Background(), testEvents(1)); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestNewKafkaSink_Errors(t *testing.T) {
+	if _, err := NewKafkaSink(KafkaSinkConfig{Topic: "decisions"}); err == nil {
+		t.Error("expected error without brokers")
+	}
+	if _, err := NewKafkaSink(KafkaSinkConfig{Brokers: []string{"localhost:9092"}}); err == nil {
+		t.Error("expected error without topic")
+	}
+}
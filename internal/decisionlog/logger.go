@@ -0,0 +1,256 @@
+package decisionlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Delivery selects when events are delivered relative to the decision
+type Delivery string
+
+const (
+	// DeliveryAsync buffers events and delivers them in the background, in
+	// batches. Decisions are not delayed by the sink; events still buffered
+	// when the process dies are lost.
+	DeliveryAsync Delivery = "async"
+
+	// DeliverySync delivers each event before the decision is returned to
+	// the caller, so every decision made is recorded, at the cost of latency.
+	DeliverySync Delivery = "sync"
+)
+
+// Logger delivers decision events to a sink
+//
+// Delivery is at least once: a batch the sink fails to accept is retried,
+// then written to the fallback sink (e.g. a local file or stdout) so events
+// are not silently dropped when the primary sink is unavailable. Events
+// recorded while the buffer is full also go to the fallback sink. Events
+// neither sink accepts are counted as lost and logged.
+type Logger struct {
+	sink         Sink
+	fallback     Sink
+	delivery     Delivery
+	batchSize    int
+	retries      int
+	retryBackoff time.Duration
+	logger       *slog.Logger
+
+	mu     sync.RWMutex
+	closed bool
+	events chan Event
+	done   chan struct{}
+	lost   atomic.Uint64
+}
+
+// LoggerConfig configures a decision logger
+type LoggerConfig struct {
+	// Sink is the primary sink
+	Sink Sink
+
+	// Fallback receives the events the primary sink does not accept (optional)
+	Fallback Sink
+
+	// Delivery selects synchronous or asynchronous delivery (default: async)
+	Delivery Delivery
+
+	// BufferSize is the number of events buffered for asynchronous delivery
+	// (default: 1024)
+	BufferSize int
+
+	// BatchSize is the maximum number of events written at once (default: 100)
+	BatchSize int
+
+	// Retries is the number of times a failed write is retried before events
+	// go to the fallback sink (default: 3, negative disables retries)
+	Retries int
+
+	// RetryBackoff is the delay before the first retry, doubling with each
+	// retry (default: 100ms)
+	RetryBackoff time.Duration
+
+	// Logger reports delivery failures (default: slog.Default())
+	Logger *slog.Logger
+}
+
+// NewLogger creates a decision logger
+// Asynchronous loggers deliver events in the background until closed.
+func NewLogger(cfg LoggerConfig) (*Logger, error) {
+	if cfg.Sink == nil {
+		return nil, fmt.Errorf("decision log requires a sink")
+	}
+	delivery := cfg.Delivery
+	if delivery == "" {
+		delivery = DeliveryAsync
+	}
+	if delivery != DeliveryAsync && delivery != DeliverySync {
+		return nil, fmt.Errorf("unknown decision log delivery: %s (supported: async, sync)", delivery)
+	}
+
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = 1024
+	}
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	retries := cfg.Retries
+	if retries < 0 {
+		retries = 0
+	} else if retries == 0 {
+		retries = 3
+	}
+	retryBackoff := cfg.RetryBackoff
+	if retryBackoff <= 0 {
+		retryBackoff = 100 * time.Millisecond
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	l := &Logger{
+		sink:         cfg.Sink,
+		fallback:     cfg.Fallback,
+		delivery:     delivery,
+		batchSize:    batchSize,
+		retries:      retries,
+		retryBackoff: retryBackoff,
+		logger:       logger.With("event", "decision_log"),
+		done:         make(chan struct{}),
+	}
+	if delivery == DeliveryAsync {
+		l.events = make(chan Event, bufferSize)
+		go l.run()
+	} else {
+		close(l.done)
+	}
+	return l, nil
+}
+
+// Record delivers an event, or buffers it for delivery
+func (l *Logger) Record(ctx context.Context, event Event) {
+	// Delivery outlives the request that made the decision
+	ctx = context.WithoutCancel(ctx)
+
+	l.mu.RLock()
+	if l.delivery == DeliveryAsync && !l.closed {
+		select {
+		case l.events <- event:
+			l.mu.RUnlock()
+			return
+		default:
+		}
+	}
+	l.mu.RUnlock()
+
+	if l.delivery == DeliverySync {
+		l.deliver(ctx, []Event{event})
+		return
+	}
+	// The buffer is full, or the logger closed: bypass the primary sink
+	l.writeFallback(ctx, []Event{event}, errors.New("decision log buffer is full or closed"))
+}
+
+// Lost returns the number of events neither sink accepted
+func (l *Logger) Lost() uint64 {
+	return l.lost.Load()
+}
+
+// Close delivers the buffered events and closes the sinks
+// If ctx ends first, the remaining events are abandoned.
+func (l *Logger) Close(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	if l.events != nil {
+		close(l.events)
+	}
+	l.mu.Unlock()
+
+	var err error
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		err = fmt.Errorf("decision log not flushed: %w", ctx.Err())
+	}
+
+	err = errors.Join(err, l.sink.Close())
+	if l.fallback != nil {
+		err = errors.Join(err, l.fallback.Close())
+	}
+	return err
+}
+
+// run delivers buffered events in batches until the buffer is closed
+func (l *Logger) run() {
+	defer close(l.done)
+	ctx := context.Background()
+
+	for event := range l.events {
+		batch := []Event{event}
+	fill:
+		for len(batch) < l.batchSize {
+			select {
+			case next, ok := <-l.events:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, next)
+			default:
+				break fill
+			}
+		}
+		l.deliver(ctx, batch)
+	}
+}
+
+// deliver writes a batch to the sink, retrying with backoff, then to the
+// fallback sink
+func (l *Logger) deliver(ctx context.Context, batch []Event) {
+	backoff := l.retryBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = l.sink.Write(ctx, batch); err == nil {
+			return
+		}
+		if attempt == l.retries {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	l.writeFallback(ctx, batch, err)
+}
+
+// writeFallback writes a batch the primary sink did not take to the fallback
+// sink, counting the events as lost if that fails too
+func (l *Logger) writeFallback(ctx context.Context, batch []Event, cause error) {
+	if l.fallback != nil {
+		err := l.fallback.Write(ctx, batch)
+		if err == nil {
+			l.logger.WarnContext(ctx, "Decision events written to fallback sink",
+				slog.Int("events", len(batch)), slog.String("error", cause.Error()))
+			return
+		}
+		cause = errors.Join(cause, err)
+	}
+
+	lost := l.lost.Add(uint64(len(batch)))
+	l.logger.ErrorContext(ctx, "Decision events lost",
+		slog.Int("events", len(batch)),
+		slog.Uint64("lost_total", lost),
+		slog.String("error", cause.Error()))
+}
@@ -0,0 +1,197 @@
+package decisionlog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink records the events written to it, failing the first
+// failures writes
+type recordingSink struct {
+	mu       sync.Mutex
+	events   []Event
+	batches  int
+	failures int
+	closed   bool
+}
+
+func (s *recordingSink) Write(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures != 0 {
+		s.failures--
+		return errors.New("broker unavailable")
+	}
+	s.events = append(s.events, events...)
+	s.batches++
+	return nil
+}
+
+func (s *recordingSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *recordingSink) ids() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, len(s.events))
+	for i, event := range s.events {
+		ids[i] = event.ID
+	}
+	return ids
+}
+
+func testEvents(n int) []Event {
+	events := make([]Event, n)
+	for i := range events {
+		events[i] = newEvent(EventTypeTokenIssuance, time.Now())
+	}
+	return events
+}
+
+func TestLogger_AsyncDeliversEveryEvent(t *testing.T) {
+	sink := &recordingSink{failures: 1}
+	logger, err := NewLogger(LoggerConfig{
+		Sink:         sink,
+		BatchSize:    10,
+		RetryBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+
+	events := testEvents(50)
+	for _, event := range events {
+		logger.Record(context.Background(), event)
+	}
+	if err := logger.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	ids := sink.ids()
+	if len(ids) != len(events) {
+		t.Fatalf("expected %d events delivered, got %d", len(events), len(ids))
+	}
+	for i, event := range events {
+		if ids[i] != event.ID {
+			t.Fatalf("expected events in order, event %d is %s, want %s", i, ids[i], event.ID)
+		}
+	}
+	if sink.batches > len(events) || !sink.closed {
+		t.Errorf("expected batched delivery and a closed sink, got %d batches (closed: %v)", sink.batches, sink.closed)
+	}
+}
+
+func TestLogger_Fallback(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unavailable sink falls back", func(t *testing.T) {
+		sink := &recordingSink{failures: 100}
+		fallback := &recordingSink{}
+		logger, err := NewLogger(LoggerConfig{
+			Sink:         sink,
+			Fallback:     fallback,
+			Delivery:     DeliverySync,
+			Retries:      2,
+			RetryBackoff: time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("NewLogger failed: %v", err)
+		}
+
+		event := testEvents(1)[0]
+		logger.Record(ctx, event)
+		if ids := fallback.ids(); len(ids) != 1 || ids[0] != event.ID {
+			t.Errorf("expected the event in the fallback sink, got %v", ids)
+		}
+		if sink.failures != 97 {
+			t.Errorf("expected 3 attempts on the sink, got %d", 100-sink.failures)
+		}
+		if logger.Lost() != 0 {
+			t.Errorf("expected no lost events, got %d", logger.Lost())
+		}
+	})
+
+	t.Run("events are lost without a fallback", func(t *testing.T) {
+		logger, err := NewLogger(LoggerConfig{
+			Sink:     &recordingSink{failures: 100},
+			Delivery: DeliverySync,
+			Retries:  -1,
+		})
+		if err != nil {
+			t.Fatalf("NewLogger failed: %v", err)
+		}
+		logger.Record(ctx, testEvents(1)[0])
+		if logger.Lost() != 1 {
+			t.Errorf("expected 1 lost event, got %d", logger.Lost())
+		}
+	})
+
+	t.Run("events recorded after close go to the fallback", func(t *testing.T) {
+		fallback := &recordingSink{}
+		logger, err := NewLogger(LoggerConfig{Sink: &recordingSink{}, Fallback: fallback})
+		if err != nil {
+			t.Fatalf("NewLogger failed: %v", err)
+		}
+		_ = logger.Close(ctx)
+		logger.Record(ctx, testEvents(1)[0])
+		if len(fallback.ids()) != 1 {
+			t.Errorf("expected the event in the fallback sink")
+		}
+	})
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("NewFileSink failed: %v", err)
+	}
+	events := testEvents(2)
+	if err := sink.Write(context.Background(), events); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	var lines int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid JSON line: %v", err)
+		}
+		if event.SchemaVersion != SchemaVersion || event.ID != events[lines].ID {
+			t.Errorf("unexpected event %+v", event)
+		}
+		lines++
+	}
+	if lines != 2 {
+		t.Errorf("expected 2 lines, got %d", lines)
+	}
+}
+
+func TestNewLogger_Errors(t *testing.T) {
+	if _, err := NewLogger(LoggerConfig{}); err == nil {
+		t.Error("expected error without sink")
+	}
+	if _, err := NewLogger(LoggerConfig{Sink: &recordingSink{}, Delivery: "eventually"}); err == nil {
+		t.Error("expected error for unknown delivery")
+	}
+}
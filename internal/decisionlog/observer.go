@@ -0,0 +1,113 @@
+package decisionlog
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// observer records a decision event for each token issuance
+type observer struct {
+	service.NoOpApplicationObserver
+	logger *Logger
+	now    func() time.Time
+}
+
+// NewObserver creates an application observer recording a decision event
+// with logger for every token issuance, whether it succeeds or fails
+func NewObserver(logger *Logger) service.ApplicationObserver {
+	return &observer{logger: logger, now: time.Now}
+}
+
+// TokenIssuanceStarted implements service.TokenServiceObserver
+func (o *observer) TokenIssuanceStarted(
+	ctx context.Context,
+	subject *trust.Result,
+	actor *trust.Result,
+	scope string,
+	tokenTypes []service.TokenType,
+) (context.Context, service.TokenIssuanceProbe) {
+	event := newEvent(EventTypeTokenIssuance, o.now())
+	event.Subject = principal(subject)
+	event.Actor = principal(actor)
+	event.Scope = scope
+	event.TokenTypes = make([]string, len(tokenTypes))
+	for i, tokenType := range tokenTypes {
+		event.TokenTypes[i] = string(tokenType)
+	}
+
+	return ctx, &issuanceProbe{
+		ctx:    ctx,
+		logger: o.logger,
+		event:  event,
+	}
+}
+
+// issuanceProbe builds the decision event of one token issuance
+// Token types may be issued concurrently, so the event is guarded.
+type issuanceProbe struct {
+	service.NoOpTokenIssuanceProbe
+	ctx    context.Context
+	logger *Logger
+
+	mu    sync.Mutex
+	event Event
+	err   error
+}
+
+func (p *issuanceProbe) TokenTypeIssuanceSucceeded(tokenType service.TokenType, token *service.Token) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	issued := IssuedToken{Type: string(tokenType)}
+	if token != nil {
+		issued.IssuedAt = token.IssuedAt.UTC()
+		issued.ExpiresAt = token.ExpiresAt.UTC()
+		issued.Compacted = token.Compaction != nil
+		for _, source := range token.Provenance {
+			issued.Provenance = append(issued.Provenance, ClaimSource(source))
+		}
+	}
+	p.event.Tokens = append(p.event.Tokens, issued)
+}
+
+func (p *issuanceProbe) TokenTypeIssuanceFailed(tokenType service.TokenType, err error) {
+	p.failed(err)
+}
+
+func (p *issuanceProbe) IssuerNotFound(tokenType service.TokenType, err error) {
+	p.failed(err)
+}
+
+func (p *issuanceProbe) IssuanceRefused(err error) {
+	p.failed(err)
+}
+
+// failed keeps the first error of the issuance
+func (p *issuanceProbe) failed(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+func (p *issuanceProbe) End() {
+	p.mu.Lock()
+	event := p.event
+	if p.err != nil {
+		event.Decision = DecisionFailed
+		event.Error = &EventError{
+			Code:    string(perr.CodeOf(p.err)),
+			Message: p.err.Error(),
+		}
+	} else {
+		event.Decision = DecisionIssued
+	}
+	p.mu.Unlock()
+
+	p.logger.Record(p.ctx, event)
+}
@@ -0,0 +1,114 @@
+package decisionlog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// stubIssuer issues a fixed token with provenance, or fails with err
+type stubIssuer struct {
+	err        error
+	provenance []service.ClaimSource
+}
+
+func (i *stubIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	if i.err != nil {
+		return nil, i.err
+	}
+	now := time.Now()
+	return &service.Token{Value: "secret-token", IssuedAt: now, ExpiresAt: now.Add(5 * time.Minute), Provenance: i.provenance}, nil
+}
+
+func (i *stubIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	return nil, nil
+}
+
+func TestObserver_RecordsIssuanceDecisions(t *testing.T) {
+	sink := &recordingSink{}
+	logger, err := NewLogger(LoggerConfig{Sink: sink, Delivery: DeliverySync})
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+
+	registry := service.NewSimpleRegistry()
+	registry.Register(service.TokenTypeTransactionToken, &stubIssuer{})
+	registry.Register(service.TokenTypeAccessToken, &stubIssuer{err: perr.New(perr.ErrCodeIssuerUnavailable, "signer unavailable")})
+	tokenService := service.NewTokenService("trust.example.com", nil, registry, NewObserver(logger))
+
+	subject := &trust.Result{Subject: "alice", Issuer: "https://idp.example.com", TrustDomain: "idp.example.com"}
+	actor := &trust.Result{Subject: "spiffe://example.com/gateway", TrustDomain: "example.com"}
+
+	if _, err := tokenService.IssueTokens(context.Background(), &service.IssueRequest{
+		Subject:    subject,
+		Actor:      actor,
+		Scope:      "orders:read",
+		TokenTypes: []service.TokenType{service.TokenTypeTransactionToken},
+	}); err != nil {
+		t.Fatalf("IssueTokens failed: %v", err)
+	}
+	_, _ = tokenService.IssueTokens(context.Background(), &service.IssueRequest{
+		Subject:    subject,
+		TokenTypes: []service.TokenType{service.TokenTypeAccessToken},
+	})
+
+	if len(sink.events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(sink.events))
+	}
+
+	issued := sink.events[0]
+	if issued.Decision != DecisionIssued || issued.Type != EventTypeTokenIssuance || issued.SchemaVersion != SchemaVersion {
+		t.Errorf("unexpected event %+v", issued)
+	}
+	if issued.Subject.Subject != "alice" || issued.Subject.Issuer != "https://idp.example.com" || issued.Actor.Subject != "spiffe://example.com/gateway" {
+		t.Errorf("expected subject and actor, got %+v and %+v", issued.Subject, issued.Actor)
+	}
+	if issued.Scope != "orders:read" || len(issued.Tokens) != 1 || issued.Tokens[0].Type != string(service.TokenTypeTransactionToken) {
+		t.Errorf("expected the issued token, got %+v", issued)
+	}
+	if issued.Tokens[0].ExpiresAt.IsZero() || issued.ID == "" {
+		t.Errorf("expected token lifetime and event ID, got %+v", issued)
+	}
+
+	failed := sink.events[1]
+	if failed.Decision != DecisionFailed || failed.Error == nil || failed.Error.Code != string(perr.ErrCodeIssuerUnavailable) {
+		t.Errorf("expected a failed decision with its error code, got %+v", failed)
+	}
+	if failed.ID == issued.ID {
+		t.Error("expected distinct event IDs")
+	}
+}
+
+func TestObserver_RecordsClaimProvenance(t *testing.T) {
+	sink := &recordingSink{}
+	logger, err := NewLogger(LoggerConfig{Sink: sink, Delivery: DeliverySync})
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+
+	registry := service.NewSimpleRegistry()
+	registry.Register(service.TokenTypeTransactionToken, &stubIssuer{provenance: []service.ClaimSource{
+		{Claim: "tctx.roles", Mapper: "transaction_context[0]", MapperType: "cel", DataSources: []string{"roles"}},
+	}})
+	tokenService := service.NewTokenService("trust.example.com", nil, registry, NewObserver(logger))
+
+	if _, err := tokenService.IssueTokens(context.Background(), &service.IssueRequest{
+		Subject:    &trust.Result{Subject: "alice"},
+		TokenTypes: []service.TokenType{service.TokenTypeTransactionToken},
+	}); err != nil {
+		t.Fatalf("IssueTokens failed: %v", err)
+	}
+
+	if len(sink.events) != 1 || len(sink.events[0].Tokens) != 1 {
+		t.Fatalf("expected one event with one token, got %+v", sink.events)
+	}
+	provenance := sink.events[0].Tokens[0].Provenance
+	if len(provenance) != 1 || provenance[0].Claim != "tctx.roles" || provenance[0].Mapper != "transaction_context[0]" ||
+		provenance[0].MapperType != "cel" || len(provenance[0].DataSources) != 1 || provenance[0].DataSources[0] != "roles" {
+		t.Errorf("expected the claim provenance, got %+v", provenance)
+	}
+}
@@ -0,0 +1,80 @@
+package decisionlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Sink delivers decision events
+type Sink interface {
+	// Write delivers a batch of events, returning only once they are durably
+	// accepted by the destination. On error, the whole batch may be retried.
+	Write(ctx context.Context, events []Event) error
+
+	// Close releases the sink's resources
+	Close() error
+}
+
+// WriterSink writes events as JSON lines
+type WriterSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	sync   func() error
+}
+
+// NewStdoutSink creates a sink writing events to stdout
+func NewStdoutSink() *WriterSink {
+	return NewWriterSink(os.Stdout)
+}
+
+// NewWriterSink creates a sink writing events to w
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// NewFileSink creates a sink appending events to a file, which is created
+// if needed. Each batch is synced to disk before Write returns.
+func NewFileSink(path string) (*WriterSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open decision log file: %w", err)
+	}
+	return &WriterSink{w: f, closer: f, sync: f.Sync}, nil
+}
+
+// Write implements Sink
+func (s *WriterSink) Write(ctx context.Context, events []Event) error {
+	var buf []byte
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode decision event: %w", err)
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(buf); err != nil {
+		return fmt.Errorf("failed to write decision events: %w", err)
+	}
+	if s.sync != nil {
+		if err := s.sync(); err != nil {
+			return fmt.Errorf("failed to sync decision events: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close implements Sink
+func (s *WriterSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
@@ -0,0 +1,107 @@
+package envoytest
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net/url"
+	"testing"
+)
+
+func TestCheckRequestBuilder(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		req := NewCheckRequest().Build()
+
+		httpReq := req.GetAttributes().GetRequest().GetHttp()
+		if httpReq.GetMethod() != "GET" || httpReq.GetPath() != "/" || httpReq.GetProtocol() != "HTTP/1.1" {
+			t.Errorf("unexpected defaults: %v", httpReq)
+		}
+		if httpReq.GetHeaders() == nil {
+			t.Error("expected an empty header map, as Envoy always sends one")
+		}
+		if req.GetAttributes().GetSource() != nil {
+			t.Error("expected no source unless one is set")
+		}
+	})
+
+	t.Run("request attributes", func(t *testing.T) {
+		req := NewCheckRequest().
+			Method("POST").
+			Path("/api/resource?x=1").
+			Host("api.example.com").
+			Bearer("token").
+			Header("X-Request-Id", "abc").
+			Body(`{"a":1}`).
+			SourceAddress("10.0.0.1", 51234).
+			DestinationAddress("10.0.0.2", 8080).
+			SNI("api.example.com").
+			ContextExtension("tenant_id", "tenant-123").
+			Build()
+
+		attrs := req.GetAttributes()
+		httpReq := attrs.GetRequest().GetHttp()
+		if httpReq.GetMethod() != "POST" || httpReq.GetPath() != "/api/resource?x=1" || httpReq.GetHost() != "api.example.com" {
+			t.Errorf("unexpected request line: %v", httpReq)
+		}
+		if httpReq.GetHeaders()["authorization"] != "Bearer token" {
+			t.Errorf("expected a bearer authorization header, got %v", httpReq.GetHeaders())
+		}
+		if httpReq.GetHeaders()["x-request-id"] != "abc" {
+			t.Errorf("expected header names to be lowercased, got %v", httpReq.GetHeaders())
+		}
+		if httpReq.GetBody() != `{"a":1}` || httpReq.GetSize() != 7 {
+			t.Errorf("expected the body and its size, got %q (%d)", httpReq.GetBody(), httpReq.GetSize())
+		}
+		source := attrs.GetSource().GetAddress().GetSocketAddress()
+		if source.GetAddress() != "10.0.0.1" || source.GetPortValue() != 51234 {
+			t.Errorf("unexpected source: %v", source)
+		}
+		if attrs.GetDestination().GetAddress().GetSocketAddress().GetPortValue() != 8080 {
+			t.Errorf("unexpected destination: %v", attrs.GetDestination())
+		}
+		if attrs.GetTlsSession().GetSni() != "api.example.com" {
+			t.Errorf("unexpected TLS session: %v", attrs.GetTlsSession())
+		}
+		if attrs.GetContextExtensions()["tenant_id"] != "tenant-123" {
+			t.Errorf("unexpected context extensions: %v", attrs.GetContextExtensions())
+		}
+	})
+
+	t.Run("peer certificate", func(t *testing.T) {
+		cert := NewPeerCertificate(t, "workload", "spiffe://example.org/ns/default/sa/api")
+		req := NewCheckRequest().PeerCertificate(cert).Build()
+
+		source := req.GetAttributes().GetSource()
+		if source.GetPrincipal() != "spiffe://example.org/ns/default/sa/api" {
+			t.Errorf("expected the URI SAN as principal, got %q", source.GetPrincipal())
+		}
+
+		decoded, err := url.QueryUnescape(source.GetCertificate())
+		if err != nil {
+			t.Fatalf("expected a URL-encoded certificate: %v", err)
+		}
+		block, _ := pem.Decode([]byte(decoded))
+		if block == nil {
+			t.Fatal("expected a PEM certificate")
+		}
+		parsed, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatalf("failed to parse certificate: %v", err)
+		}
+		if parsed.Subject.CommonName != "workload" {
+			t.Errorf("unexpected certificate subject: %v", parsed.Subject)
+		}
+	})
+
+	t.Run("builds independent requests", func(t *testing.T) {
+		template := NewCheckRequest().Bearer("token")
+		first := template.Build()
+		second := template.Header("x-extra", "1").Build()
+
+		if _, ok := first.GetAttributes().GetRequest().GetHttp().GetHeaders()["x-extra"]; ok {
+			t.Error("expected later changes to the builder not to affect built requests")
+		}
+		if second.GetAttributes().GetRequest().GetHttp().GetHeaders()["authorization"] != "Bearer token" {
+			t.Error("expected the template's headers in every request")
+		}
+	})
+}
@@ -21,6 +21,9 @@ type FileSystem interface {
 	// For in-memory filesystems, this can be a direct write
 	WriteFileAtomic(name string, data []byte, perm fs.FileMode) error
 
+	// Mode returns the permission bits of a file
+	Mode(name string) (fs.FileMode, error)
+
 	// IsNotExist returns true if the error indicates a file doesn't exist
 	IsNotExist(err error) bool
 }
@@ -95,6 +98,15 @@ func (f *OSFileSystem) WriteFileAtomic(name string, data []byte, perm fs.FileMod
 	return nil
 }
 
+// Mode returns the permission bits of a file
+func (f *OSFileSystem) Mode(name string) (fs.FileMode, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return 0, err
+	}
+	return info.Mode().Perm(), nil
+}
+
 // IsNotExist returns true if the error indicates a file doesn't exist
 func (f *OSFileSystem) IsNotExist(err error) bool {
 	return os.IsNotExist(err)
@@ -16,14 +16,16 @@ var (
 // MemFileSystem is an in-memory filesystem for testing
 type MemFileSystem struct {
 	mu    sync.RWMutex
-	files map[string][]byte // path -> content
-	dirs  map[string]bool   // path -> exists
+	files map[string][]byte      // path -> content
+	modes map[string]fs.FileMode // path -> permission bits
+	dirs  map[string]bool        // path -> exists
 }
 
 // NewMemFileSystem creates a new in-memory filesystem
 func NewMemFileSystem() *MemFileSystem {
 	return &MemFileSystem{
 		files: make(map[string][]byte),
+		modes: make(map[string]fs.FileMode),
 		dirs:  make(map[string]bool),
 	}
 }
@@ -76,9 +78,21 @@ func (f *MemFileSystem) WriteFileAtomic(name string, data []byte, perm fs.FileMo
 	dataCopy := make([]byte, len(data))
 	copy(dataCopy, data)
 	f.files[name] = dataCopy
+	f.modes[name] = perm.Perm()
 	return nil
 }
 
+// Mode returns the permission bits of a file
+func (f *MemFileSystem) Mode(name string) (fs.FileMode, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if _, ok := f.files[name]; !ok {
+		return 0, ErrNotExist
+	}
+	return f.modes[name], nil
+}
+
 // IsNotExist returns true if the error indicates a file doesn't exist
 func (f *MemFileSystem) IsNotExist(err error) bool {
 	return errors.Is(err, ErrNotExist)
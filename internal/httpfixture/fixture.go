@@ -11,6 +11,37 @@ type Fixture struct {
 	Headers    map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
 	Body       string            `json:"body" yaml:"body"`
 	Delay      *time.Duration    `json:"delay,omitempty" yaml:"delay,omitempty"`
+
+	// Fault fails the request without a response, after Delay
+	Fault Fault `json:"fault,omitempty" yaml:"fault,omitempty"`
+
+	// ErrorRate is the fraction of requests (0 to 1) answered with ErrorStatus
+	// and an empty body instead of this response
+	ErrorRate float64 `json:"error_rate,omitempty" yaml:"error_rate,omitempty"`
+
+	// ErrorStatus is the status of requests failed by ErrorRate (default: 503)
+	ErrorStatus int `json:"error_status,omitempty" yaml:"error_status,omitempty"`
+}
+
+// Fault is a transport failure injected instead of a response
+type Fault string
+
+const (
+	// FaultConnectionReset fails the request as if the server reset the connection
+	FaultConnectionReset Fault = "connection_reset"
+
+	// FaultTimeout blocks the request until its context is done, as if the
+	// server never answered
+	FaultTimeout Fault = "timeout"
+)
+
+// Valid reports whether f is a known fault (or no fault)
+func (f Fault) Valid() bool {
+	switch f {
+	case "", FaultConnectionReset, FaultTimeout:
+		return true
+	}
+	return false
 }
 
 // FixtureProvider returns a fixture for a request, or nil if no fixture applies
@@ -22,6 +53,10 @@ type FixtureProvider interface {
 type HTTPFixtureRule struct {
 	Request  FixtureRequest `json:"request" yaml:"request"`
 	Response Fixture        `json:"response" yaml:"response"`
+
+	// Sequence, if set, replaces Response with responses for consecutive
+	// matching requests (e.g. a 500 and then a 200); the last one repeats
+	Sequence []Fixture `json:"sequence,omitempty" yaml:"sequence,omitempty"`
 }
 
 // FixtureRequest defines request matching criteria (for file-based fixtures)
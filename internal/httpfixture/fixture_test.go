@@ -1,10 +1,13 @@
 package httpfixture
 
 import (
+	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -432,3 +435,74 @@ func TestTransport_WithDelay(t *testing.T) {
 		t.Errorf("expected delay of at least %v, got %v", delay, elapsed)
 	}
 }
+
+func TestRuleBasedProvider_Sequence(t *testing.T) {
+	provider := NewRuleBasedProvider([]HTTPFixtureRule{
+		{
+			Request: FixtureRequest{Method: "GET", URL: "https://api.example.com/flaky"},
+			Sequence: []Fixture{
+				{StatusCode: 500, Body: "first"},
+				{StatusCode: 200, Body: "second"},
+			},
+		},
+	})
+
+	req := httptest.NewRequest("GET", "https://api.example.com/flaky", nil)
+	for i, want := range []int{500, 200, 200} {
+		if got := provider.GetFixture(req).StatusCode; got != want {
+			t.Errorf("call %d: StatusCode = %d, want %d", i+1, got, want)
+		}
+	}
+}
+
+func TestTransport_Faults(t *testing.T) {
+	provider := NewMapProvider(map[string]*Fixture{
+		"GET https://api.example.com/reset": {Fault: FaultConnectionReset},
+		"GET https://api.example.com/hang":  {Fault: FaultTimeout},
+	})
+	client := &http.Client{
+		Transport: NewTransport(TransportConfig{Provider: provider, Strict: true}),
+		Timeout:   50 * time.Millisecond,
+	}
+
+	if _, err := client.Get("https://api.example.com/reset"); !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("expected a connection reset, got %v", err)
+	}
+
+	start := time.Now()
+	_, err := client.Get("https://api.example.com/hang")
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the request to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the request to end at the client timeout, took %v", elapsed)
+	}
+}
+
+func TestTransport_ErrorRate(t *testing.T) {
+	provider := NewMapProvider(map[string]*Fixture{
+		"GET https://api.example.com/data": {StatusCode: 200, Body: "ok", ErrorRate: 0.5, ErrorStatus: 502},
+	})
+	rolls := []float64{0.1, 0.9}
+	transport := NewTransport(TransportConfig{
+		Provider: provider,
+		Strict:   true,
+		Random: func() float64 {
+			r := rolls[0]
+			rolls = rolls[1:]
+			return r
+		},
+	})
+	client := &http.Client{Transport: transport}
+
+	for _, want := range []int{502, 200} {
+		resp, err := client.Get("https://api.example.com/data")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("StatusCode = %d, want %d", resp.StatusCode, want)
+		}
+	}
+}
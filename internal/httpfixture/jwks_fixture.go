@@ -44,12 +44,16 @@ type JWKSFixtureConfig struct {
 	// If zero value, defaults to RS256
 	Algorithm jwa.SignatureAlgorithm
 
+	// PrivateKey is the RSA signing key
+	// If nil, a new 2048-bit key is generated
+	PrivateKey *rsa.PrivateKey
+
 	// Clock is the time source for token timestamps
 	// If nil, uses system clock
 	Clock clock.Clock
 }
 
-// NewJWKSFixture creates a new JWKS fixture with a generated RSA key pair
+// NewJWKSFixture creates a new JWKS fixture with a generated or provided RSA key pair
 func NewJWKSFixture(cfg JWKSFixtureConfig) (*JWKSFixture, error) {
 	if cfg.Issuer == "" {
 		return nil, fmt.Errorf("issuer is required")
@@ -69,10 +73,14 @@ func NewJWKSFixture(cfg JWKSFixtureConfig) (*JWKSFixture, error) {
 		algorithm = jwa.RS256()
 	}
 
-	// Generate RSA key pair
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate RSA key: %w", err)
+	// Generate RSA key pair unless one was provided
+	privateKey := cfg.PrivateKey
+	if privateKey == nil {
+		var err error
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate RSA key: %w", err)
+		}
 	}
 
 	// Create JWK from public key
@@ -3,24 +3,38 @@ package httpfixture
 import (
 	"net/http"
 	"regexp"
+	"sync"
 )
 
 // RuleBasedProvider matches requests against a set of rules
 type RuleBasedProvider struct {
 	rules []HTTPFixtureRule
+
+	// calls counts the requests matched by each rule, to step through sequences
+	mu    sync.Mutex
+	calls []int
 }
 
 // NewRuleBasedProvider creates a new rule-based fixture provider
 func NewRuleBasedProvider(rules []HTTPFixtureRule) *RuleBasedProvider {
-	return &RuleBasedProvider{rules: rules}
+	return &RuleBasedProvider{rules: rules, calls: make([]int, len(rules))}
 }
 
 // GetFixture returns a fixture for the given request if any rule matches
 func (p *RuleBasedProvider) GetFixture(req *http.Request) *Fixture {
-	for _, rule := range p.rules {
-		if p.matches(req, rule.Request) {
+	for i, rule := range p.rules {
+		if !p.matches(req, rule.Request) {
+			continue
+		}
+		if len(rule.Sequence) == 0 {
 			return &rule.Response
 		}
+
+		p.mu.Lock()
+		n := min(p.calls[i], len(rule.Sequence)-1)
+		p.calls[i]++
+		p.mu.Unlock()
+		return &rule.Sequence[n]
 	}
 	return nil
 }
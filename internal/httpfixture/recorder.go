@@ -0,0 +1,173 @@
+package httpfixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Recorder is an http.RoundTripper that sends requests through a real
+// transport and records each interaction as a fixture rule, so traffic captured
+// from a live environment can be replayed later with LoadFixturesFromFile
+//
+// Rules match the exact method and URL of the recorded request. A request
+// recorded again replaces the earlier response. Failed round trips (no
+// response at all) are not recorded.
+type Recorder struct {
+	transport       http.RoundTripper
+	path            string
+	responseHeaders []string
+
+	mu    sync.Mutex
+	rules []HTTPFixtureRule
+}
+
+// RecorderConfig configures a Recorder
+type RecorderConfig struct {
+	// Transport sends the recorded requests (default: http.DefaultTransport)
+	Transport http.RoundTripper
+
+	// Path is the fixture file the rules are saved to after each recorded
+	// interaction (optional). Files ending in .yaml or .yml are written as
+	// YAML, others as JSON.
+	Path string
+
+	// ResponseHeaders are the response headers recorded (default: Content-Type)
+	// Headers like Date or Set-Cookie would make fixtures differ on every run.
+	ResponseHeaders []string
+}
+
+// NewRecorder creates a recording transport
+func NewRecorder(cfg RecorderConfig) *Recorder {
+	transport := cfg.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	headers := cfg.ResponseHeaders
+	if len(headers) == 0 {
+		headers = []string{"Content-Type"}
+	}
+	return &Recorder{
+		transport:       transport,
+		path:            cfg.Path,
+		responseHeaders: headers,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The caller still reads the body, so it is buffered and replaced
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to record response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	rule := HTTPFixtureRule{
+		Request: FixtureRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+		},
+		Response: Fixture{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+		},
+	}
+	for _, name := range r.responseHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			if rule.Response.Headers == nil {
+				rule.Response.Headers = make(map[string]string)
+			}
+			rule.Response.Headers[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+
+	if err := r.record(rule); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// record adds or replaces the rule for the request and saves the rules
+func (r *Recorder) record(rule HTTPFixtureRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := slices.IndexFunc(r.rules, func(existing HTTPFixtureRule) bool {
+		return existing.Request.Method == rule.Request.Method && existing.Request.URL == rule.Request.URL
+	})
+	if i >= 0 {
+		r.rules[i] = rule
+	} else {
+		r.rules = append(r.rules, rule)
+	}
+
+	if r.path == "" {
+		return nil
+	}
+	return saveFixtures(r.path, r.rules)
+}
+
+// Rules returns the recorded rules in the order they were first recorded
+func (r *Recorder) Rules() []HTTPFixtureRule {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.rules)
+}
+
+// Save writes the recorded rules to a fixture file
+// Files ending in .yaml or .yml are written as YAML, others as JSON.
+func (r *Recorder) Save(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return saveFixtures(path, r.rules)
+}
+
+// saveFixtures writes rules as a fixture file, replacing it atomically
+func saveFixtures(path string, rules []HTTPFixtureRule) error {
+	set := FixtureSet{Rules: rules}
+
+	var data []byte
+	var err error
+	if strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml") {
+		data, err = yaml.Marshal(set)
+	} else {
+		data, err = json.MarshalIndent(set, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode fixtures: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".fixtures-*")
+	if err != nil {
+		return fmt.Errorf("failed to save fixtures: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to save fixtures: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save fixtures: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save fixtures: %w", err)
+	}
+	return nil
+}
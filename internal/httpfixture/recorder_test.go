@@ -0,0 +1,74 @@
+package httpfixture
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecorder_RecordAndReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "changes-every-time")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": "not_found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"keys": []}`))
+	}))
+	defer server.Close()
+
+	for _, name := range []string{"fixtures.json", "fixtures.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			recorder := NewRecorder(RecorderConfig{Path: path})
+			client := &http.Client{Transport: recorder}
+
+			for _, url := range []string{server.URL + "/jwks", server.URL + "/missing", server.URL + "/jwks"} {
+				resp, err := client.Get(url)
+				if err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+				if len(body) == 0 {
+					t.Errorf("expected the caller to still read the body of %s", url)
+				}
+			}
+			if rules := recorder.Rules(); len(rules) != 2 {
+				t.Fatalf("expected a rule per distinct request, got %d", len(rules))
+			}
+
+			provider, err := LoadFixturesFromFile(path)
+			if err != nil {
+				t.Fatalf("failed to load recorded fixtures: %v", err)
+			}
+			replay := &http.Client{Transport: NewTransport(TransportConfig{Provider: provider, Strict: true})}
+
+			before := calls
+			resp, err := replay.Get(server.URL + "/missing")
+			if err != nil {
+				t.Fatalf("replay failed: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if calls != before {
+				t.Error("expected replay not to reach the server")
+			}
+			if resp.StatusCode != http.StatusNotFound || string(body) != `{"error": "not_found"}` {
+				t.Errorf("expected the recorded response, got %d %s", resp.StatusCode, body)
+			}
+			if resp.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("expected Content-Type to be recorded, got %v", resp.Header)
+			}
+			if resp.Header.Get("X-Request-Id") != "" {
+				t.Errorf("expected unlisted headers not to be recorded, got %v", resp.Header)
+			}
+		})
+	}
+}
@@ -3,8 +3,12 @@ package httpfixture
 import (
 	"fmt"
 	"io"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"os"
 	"strings"
+	"syscall"
 
 	"github.com/project-kessel/parsec/internal/clock"
 )
@@ -15,6 +19,7 @@ type Transport struct {
 	fallback http.RoundTripper // optional fallback to real HTTP
 	strict   bool              // if true, error when no fixture provided
 	clock    clock.Clock       // clock for simulating delays
+	random   func() float64    // decides which requests fail by error rate
 }
 
 // TransportConfig configures the fixture transport
@@ -23,6 +28,7 @@ type TransportConfig struct {
 	Fallback http.RoundTripper // optional fallback transport
 	Strict   bool              // if true, error when provider returns nil
 	Clock    clock.Clock       // optional clock for delays (defaults to system clock)
+	Random   func() float64    // optional source in [0, 1) for error rates (defaults to math/rand)
 }
 
 // NewTransport creates a new fixture transport
@@ -31,11 +37,16 @@ func NewTransport(config TransportConfig) *Transport {
 	if clk == nil {
 		clk = clock.NewSystemClock()
 	}
+	random := config.Random
+	if random == nil {
+		random = rand.Float64
+	}
 	return &Transport{
 		provider: config.Provider,
 		fallback: config.Fallback,
 		strict:   config.Strict,
 		clock:    clk,
+		random:   random,
 	}
 }
 
@@ -48,6 +59,25 @@ func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		// Apply delay if specified
 		if fixture.Delay != nil {
 			t.clock.Sleep(*fixture.Delay)
+			if err := req.Context().Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		switch fixture.Fault {
+		case FaultConnectionReset:
+			return nil, &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+		case FaultTimeout:
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+
+		if fixture.ErrorRate > 0 && t.random() < fixture.ErrorRate {
+			status := fixture.ErrorStatus
+			if status == 0 {
+				status = http.StatusServiceUnavailable
+			}
+			return createResponse(&Fixture{StatusCode: status}, req), nil
 		}
 
 		// Create response from fixture
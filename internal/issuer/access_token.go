@@ -0,0 +1,256 @@
+package issuer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// AccessTokenJWTType is the JWS "typ" header value for JWT access tokens (RFC 9068 section 2.1)
+const AccessTokenJWTType = "at+jwt"
+
+// accessTokenReservedClaims are set by the issuer and cannot be overridden by claim mappers
+var accessTokenReservedClaims = map[string]bool{
+	jwt.IssuerKey:     true,
+	jwt.SubjectKey:    true,
+	jwt.AudienceKey:   true,
+	jwt.ExpirationKey: true,
+	jwt.IssuedAtKey:   true,
+	jwt.NotBeforeKey:  true,
+	jwt.JwtIDKey:      true,
+	"client_id":       true,
+	"scope":           true,
+}
+
+// AccessTokenIssuerConfig is the configuration for creating a JWT access token issuer
+type AccessTokenIssuerConfig struct {
+	// IssuerURL is the issuer URL (iss claim)
+	IssuerURL string
+
+	// TTL is the time-to-live for tokens
+	TTL time.Duration
+
+	// Signer handles key rotation and signing (also provides the signing algorithm)
+	Signer keys.RotatingSigner
+
+	// AudienceSigners sign the tokens of specific audiences instead of Signer (optional)
+	AudienceSigners AudienceSigners
+
+	// Audience is the aud claim. If empty, the audience from the issue context is used.
+	Audience string
+
+	// ClientID is the client_id claim used when no actor is present.
+	// When an actor is present, its subject identifies the client. Tokens
+	// are not issued without a client_id (RFC 9068 requires it).
+	ClientID string
+
+	// ClaimMappers build additional top-level claims (e.g., roles, groups)
+	ClaimMappers []service.ClaimMapper
+
+	// SizeBudget bounds the encoded token size, compacting claims when exceeded (optional)
+	SizeBudget *SizeBudget
+
+	// Encrypter encrypts signed tokens as compact JWEs (optional)
+	// The size budget applies to the encrypted token.
+	Encrypter *TokenEncrypter
+
+	// Clock is an optional clock for testing (defaults to system clock)
+	Clock clock.Clock
+
+	// Leeway backdates the token's iat so that clients whose clocks run behind
+	// don't see a token issued in the future
+	Leeway time.Duration
+
+	// NotBeforeSkew adds an nbf claim backdated by this window (optional)
+	// When zero, access tokens carry no nbf claim.
+	NotBeforeSkew time.Duration
+}
+
+// AccessTokenIssuer issues signed JWT access tokens per RFC 9068.
+// It uses a RotatingSigner for key rotation and signing operations.
+type AccessTokenIssuer struct {
+	issuerURL       string
+	ttl             time.Duration
+	signer          keys.RotatingSigner
+	audienceSigners AudienceSigners
+	audience        string
+	clientID        string
+	claimMappers    []service.ClaimMapper
+	sizeBudget      *SizeBudget
+	encrypter       *TokenEncrypter
+	clock           clock.Clock
+	leeway          time.Duration
+	notBeforeSkew   time.Duration
+}
+
+// NewAccessTokenIssuer creates a new JWT access token issuer
+func NewAccessTokenIssuer(cfg AccessTokenIssuerConfig) *AccessTokenIssuer {
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	return &AccessTokenIssuer{
+		issuerURL:       cfg.IssuerURL,
+		ttl:             cfg.TTL,
+		signer:          cfg.Signer,
+		audienceSigners: cfg.AudienceSigners,
+		audience:        cfg.Audience,
+		clientID:        cfg.ClientID,
+		claimMappers:    cfg.ClaimMappers,
+		sizeBudget:      cfg.SizeBudget,
+		encrypter:       cfg.Encrypter,
+		clock:           clk,
+		leeway:          cfg.Leeway,
+		notBeforeSkew:   cfg.NotBeforeSkew,
+	}
+}
+
+// Issue implements the Issuer interface
+// Issues a signed JWT access token per RFC 9068
+func (i *AccessTokenIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	// Apply claim mappers
+	mappedClaims, err := issueCtx.ToClaims(ctx, i.claimMappers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map claims: %w", err)
+	}
+
+	now := issueCtx.IssueTime(i.clock)
+	expiresAt := now.Add(issueCtx.CapTTL(i.ttl))
+
+	audience := issueCtx.TokenAudiences()
+	if i.audience != "" {
+		audience = []string{i.audience}
+	}
+
+	token := jwt.New()
+
+	// Mapped claims first, so the required claims below always win
+	for key, value := range mappedClaims {
+		if accessTokenReservedClaims[key] {
+			continue
+		}
+		if err := token.Set(key, value); err != nil {
+			return nil, fmt.Errorf("failed to set claim %s: %w", key, err)
+		}
+	}
+
+	// Required claims (RFC 9068 section 2.2)
+	if err := token.Set(jwt.IssuerKey, i.issuerURL); err != nil {
+		return nil, fmt.Errorf("failed to set issuer: %w", err)
+	}
+	if err := token.Set(jwt.SubjectKey, issueCtx.Subject.Subject); err != nil {
+		return nil, fmt.Errorf("failed to set subject: %w", err)
+	}
+	if err := token.Set(jwt.AudienceKey, audience); err != nil {
+		return nil, fmt.Errorf("failed to set audience: %w", err)
+	}
+	if err := token.Set(jwt.IssuedAtKey, now.Add(-i.leeway).Unix()); err != nil {
+		return nil, fmt.Errorf("failed to set issued at: %w", err)
+	}
+	if err := token.Set(jwt.ExpirationKey, expiresAt.Unix()); err != nil {
+		return nil, fmt.Errorf("failed to set expiration: %w", err)
+	}
+	if i.notBeforeSkew > 0 {
+		if err := token.Set(jwt.NotBeforeKey, now.Add(-i.notBeforeSkew).Unix()); err != nil {
+			return nil, fmt.Errorf("failed to set not before: %w", err)
+		}
+	}
+	jti := uuid.NewString()
+	if err := token.Set(jwt.JwtIDKey, jti); err != nil {
+		return nil, fmt.Errorf("failed to set JWT ID: %w", err)
+	}
+	clientID := i.resolveClientID(issueCtx)
+	if clientID == "" {
+		return nil, fmt.Errorf("access token has no client_id: no actor and no configured client ID")
+	}
+	if err := token.Set("client_id", clientID); err != nil {
+		return nil, fmt.Errorf("failed to set client ID: %w", err)
+	}
+
+	// Scope (if provided)
+	if issueCtx.Scope != "" {
+		if err := token.Set("scope", issueCtx.Scope); err != nil {
+			return nil, fmt.Errorf("failed to set scope: %w", err)
+		}
+	}
+
+	// Actor (act) - records that the actor was permitted to act for the subject
+	if act := issueCtx.Delegation.ActClaim(); act != nil {
+		if err := token.Set("act", act); err != nil {
+			return nil, fmt.Errorf("failed to set actor: %w", err)
+		}
+	}
+
+	// Get the current signer, key ID, and algorithm from the signer for the token's audiences
+	rotatingSigner, err := i.audienceSigners.signerFor(audience, i.signer)
+	if err != nil {
+		return nil, err
+	}
+	signer, keyID, algorithm, err := rotatingSigner.GetCurrentSigner(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current signer: %w", err)
+	}
+	signAlg, ok := jwa.LookupSignatureAlgorithm(string(algorithm))
+	if !ok {
+		return nil, fmt.Errorf("unsupported signature algorithm: %s", algorithm)
+	}
+
+	// Build JWS headers with the key ID and the access token type
+	headers := jws.NewHeaders()
+	if err := headers.Set(jws.KeyIDKey, string(keyID)); err != nil {
+		return nil, fmt.Errorf("failed to set key ID header: %w", err)
+	}
+	if err := headers.Set(jws.TypeKey, AccessTokenJWTType); err != nil {
+		return nil, fmt.Errorf("failed to set type header: %w", err)
+	}
+
+	// Sign the token with the current key, compacting claims if it is over budget
+	signedToken, compaction, err := i.sizeBudget.Enforce(token, func(t jwt.Token) ([]byte, error) {
+		signed, err := jwt.Sign(t, jwt.WithKey(signAlg, signer, jws.WithProtectedHeaders(headers)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign token: %w", err)
+		}
+		if i.encrypter != nil {
+			return i.encrypter.Encrypt(ctx, signed)
+		}
+		return signed, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &service.Token{
+		Value:      string(signedToken),
+		Type:       string(service.TokenTypeAccessToken),
+		ExpiresAt:  expiresAt,
+		IssuedAt:   now,
+		ID:         jti,
+		Audience:   audience,
+		Compaction: compaction,
+	}, nil
+}
+
+// resolveClientID determines the client_id claim
+// The authenticated actor is the client; the configured client ID is the fallback
+func (i *AccessTokenIssuer) resolveClientID(issueCtx *service.IssueContext) string {
+	if issueCtx.Actor != nil && issueCtx.Actor.Subject != "" {
+		return issueCtx.Actor.Subject
+	}
+	return i.clientID
+}
+
+// PublicKeys implements the Issuer interface
+// Returns all non-expired public keys from the rotating signer and the audience signers
+func (i *AccessTokenIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	return i.audienceSigners.publicKeys(ctx, i.signer)
+}
@@ -0,0 +1,153 @@
+package issuer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jws"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestAccessTokenIssuer(t *testing.T) {
+	ctx := context.Background()
+	signer := newTestSigner(t)
+
+	iss := NewAccessTokenIssuer(AccessTokenIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       10 * time.Minute,
+		Signer:    signer,
+		Audience:  "https://api.example.com",
+		ClientID:  "default-client",
+		ClaimMappers: []service.ClaimMapper{
+			service.NewStubClaimMapper(claims.Claims{"roles": []any{"admin"}, "iss": "https://spoofed"}),
+		},
+	})
+
+	issueCtx := &service.IssueContext{
+		Subject:            &trust.Result{Subject: "alice"},
+		Audience:           "parsec.test",
+		Scope:              "orders.read",
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	}
+
+	t.Run("emits RFC 9068 claims", func(t *testing.T) {
+		token, err := iss.Issue(ctx, issueCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if token.Type != string(service.TokenTypeAccessToken) {
+			t.Errorf("expected access token type, got %s", token.Type)
+		}
+		if got := token.ExpiresAt.Sub(token.IssuedAt); got != 10*time.Minute {
+			t.Errorf("expected 10m lifetime, got %v", got)
+		}
+
+		parsed := parseUnverified(t, token.Value)
+
+		if iss, _ := parsed.Issuer(); iss != "https://parsec.test" {
+			t.Errorf("expected issuer not to be overridden by mappers, got %s", iss)
+		}
+		if aud, _ := parsed.Audience(); len(aud) != 1 || aud[0] != "https://api.example.com" {
+			t.Errorf("expected configured audience, got %v", aud)
+		}
+		if jti, _ := parsed.JwtID(); jti == "" || jti != token.ID {
+			t.Errorf("expected jti %q to be reported as the token ID, got %q", jti, token.ID)
+		}
+		if len(token.Audience) != 1 || token.Audience[0] != "https://api.example.com" {
+			t.Errorf("expected the token audience to be reported, got %v", token.Audience)
+		}
+
+		var clientID, scope string
+		if err := parsed.Get("client_id", &clientID); err != nil || clientID != "default-client" {
+			t.Errorf("expected client_id default-client, got %q (%v)", clientID, err)
+		}
+		if err := parsed.Get("scope", &scope); err != nil || scope != "orders.read" {
+			t.Errorf("expected scope orders.read, got %q (%v)", scope, err)
+		}
+
+		var roles []any
+		if err := parsed.Get("roles", &roles); err != nil || len(roles) != 1 {
+			t.Errorf("expected mapped roles claim, got %v (%v)", roles, err)
+		}
+
+		msg, err := jws.Parse([]byte(token.Value))
+		if err != nil {
+			t.Fatalf("failed to parse JWS: %v", err)
+		}
+		if typ, _ := msg.Signatures()[0].ProtectedHeaders().Type(); typ != AccessTokenJWTType {
+			t.Errorf("expected typ %s, got %s", AccessTokenJWTType, typ)
+		}
+	})
+
+	t.Run("actor is the client", func(t *testing.T) {
+		ic := *issueCtx
+		ic.Actor = &trust.Result{Subject: "gateway-client"}
+
+		token, err := iss.Issue(ctx, &ic)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var clientID string
+		if err := parseUnverified(t, token.Value).Get("client_id", &clientID); err != nil {
+			t.Fatalf("expected client_id: %v", err)
+		}
+		if clientID != "gateway-client" {
+			t.Errorf("expected client_id gateway-client, got %s", clientID)
+		}
+	})
+
+	t.Run("refuses tokens without a client_id", func(t *testing.T) {
+		noClient := NewAccessTokenIssuer(AccessTokenIssuerConfig{
+			IssuerURL: "https://parsec.test",
+			TTL:       10 * time.Minute,
+			Signer:    signer,
+		})
+		if _, err := noClient.Issue(ctx, issueCtx); err == nil {
+			t.Fatal("expected an error without an actor or a configured client ID")
+		}
+
+		ic := *issueCtx
+		ic.Actor = &trust.Result{Subject: "gateway-client"}
+		if _, err := noClient.Issue(ctx, &ic); err != nil {
+			t.Errorf("expected the actor to be the client: %v", err)
+		}
+	})
+
+	t.Run("nbf only with a not-before skew", func(t *testing.T) {
+		issuedAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		ic := *issueCtx
+		ic.IssuedAt = issuedAt
+
+		token, err := iss.Issue(ctx, &ic)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if nbf, ok := parseUnverified(t, token.Value).NotBefore(); ok {
+			t.Errorf("expected no nbf without a skew, got %v", nbf)
+		}
+
+		skewed := NewAccessTokenIssuer(AccessTokenIssuerConfig{
+			IssuerURL:     "https://parsec.test",
+			TTL:           10 * time.Minute,
+			Signer:        signer,
+			ClientID:      "default-client",
+			NotBeforeSkew: 10 * time.Second,
+		})
+		token, err = skewed.Issue(ctx, &ic)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		parsed := parseUnverified(t, token.Value)
+		nbf, _ := parsed.NotBefore()
+		iat, _ := parsed.IssuedAt()
+		if !nbf.Equal(issuedAt.Add(-10*time.Second)) || !iat.Equal(issuedAt) {
+			t.Errorf("expected nbf backdated 10s and iat unchanged, got %v and %v", nbf, iat)
+		}
+	})
+}
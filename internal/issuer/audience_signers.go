@@ -0,0 +1,88 @@
+package issuer
+
+import (
+	"context"
+	"slices"
+
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trustdomain"
+)
+
+// AudienceSigners maps audiences to the signers their tokens are signed with,
+// e.g. so that a legacy consumer receives RS256 tokens while the mesh
+// receives ES256 ones
+// Audiences may be patterns such as "*.mesh.example.com" (see
+// trustdomain.Match); an audience listed exactly takes precedence, then the
+// longest matching pattern.
+type AudienceSigners map[string]keys.RotatingSigner
+
+// lookup returns the signer of an audience
+func (s AudienceSigners) lookup(audience string) (keys.RotatingSigner, bool) {
+	if signer, ok := s[audience]; ok {
+		return signer, true
+	}
+	var match string
+	for pattern := range s {
+		if trustdomain.IsPattern(pattern) && trustdomain.Match(pattern, audience) &&
+			(len(pattern) > len(match) || len(pattern) == len(match) && pattern < match) {
+			match = pattern
+		}
+	}
+	if match == "" {
+		return nil, false
+	}
+	return s[match], true
+}
+
+// signerFor returns the signer of a token for audiences: the signer of the
+// audiences that have one, or fallback if none do
+// A token has a single signature, so its audiences must not map to
+// different signers.
+func (s AudienceSigners) signerFor(audiences []string, fallback keys.RotatingSigner) (keys.RotatingSigner, error) {
+	var (
+		signer   keys.RotatingSigner
+		selected string
+	)
+	for _, audience := range audiences {
+		audienceSigner, ok := s.lookup(audience)
+		if !ok {
+			continue
+		}
+		if signer != nil && audienceSigner != signer {
+			return nil, perr.Errorf(perr.ErrCodeInvalidAudience, "audiences %q and %q are signed with different signers", selected, audience)
+		}
+		signer, selected = audienceSigner, audience
+	}
+	if signer == nil {
+		return fallback, nil
+	}
+	return signer, nil
+}
+
+// publicKeys returns the public keys of fallback and of every audience
+// signer, so tokens signed for any audience can be verified
+func (s AudienceSigners) publicKeys(ctx context.Context, fallback keys.RotatingSigner) ([]service.PublicKey, error) {
+	signers := []keys.RotatingSigner{fallback}
+	audiences := make([]string, 0, len(s))
+	for audience := range s {
+		audiences = append(audiences, audience)
+	}
+	slices.Sort(audiences)
+	for _, audience := range audiences {
+		if !slices.Contains(signers, s[audience]) {
+			signers = append(signers, s[audience])
+		}
+	}
+
+	var publicKeys []service.PublicKey
+	for _, signer := range signers {
+		signerKeys, err := signer.PublicKeys(ctx)
+		if err != nil {
+			return nil, err
+		}
+		publicKeys = append(publicKeys, signerKeys...)
+	}
+	return publicKeys, nil
+}
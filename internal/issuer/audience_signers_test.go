@@ -0,0 +1,117 @@
+package issuer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jws"
+
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// newRSATestSigner creates a started in-memory RS256 rotating signer
+func newRSATestSigner(t testing.TB) keys.RotatingSigner {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	signer := keys.NewDualSlotRotatingSigner(keys.DualSlotRotatingSignerConfig{
+		Namespace:     "legacy",
+		KeyProviderID: "rsa-provider",
+		KeyProviderRegistry: map[string]keys.KeyProvider{
+			"rsa-provider": keys.NewInMemoryKeyProvider(keys.KeyTypeRSA2048, "RS256"),
+		},
+		SlotStore: keys.NewInMemoryKeySlotStore(),
+	})
+	if err := signer.Start(ctx); err != nil {
+		t.Fatalf("failed to start signer: %v", err)
+	}
+	t.Cleanup(signer.Stop)
+	return signer
+}
+
+func TestAudienceSigners(t *testing.T) {
+	ctx := context.Background()
+	mesh := newTestSigner(t)
+	legacy := newRSATestSigner(t)
+
+	iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+		IssuerURL:       "https://parsec.test",
+		TTL:             time.Minute,
+		Signer:          mesh,
+		AudienceSigners: AudienceSigners{"legacy.parsec.test": legacy},
+	})
+	issue := func(audiences ...string) (*service.Token, error) {
+		return iss.Issue(ctx, &service.IssueContext{
+			Subject:            &trust.Result{Subject: "alice"},
+			Audience:           audiences[0],
+			Audiences:          audiences,
+			DataSourceRegistry: service.NewDataSourceRegistry(),
+		})
+	}
+	algorithm := func(token *service.Token) string {
+		t.Helper()
+		msg, err := jws.Parse([]byte(token.Value))
+		if err != nil {
+			t.Fatalf("failed to parse JWS: %v", err)
+		}
+		alg, _ := msg.Signatures()[0].ProtectedHeaders().Algorithm()
+		return alg.String()
+	}
+
+	t.Run("signs each audience with its signer", func(t *testing.T) {
+		token, err := issue("parsec.test")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if alg := algorithm(token); alg != "ES256" {
+			t.Errorf("expected the default signer's ES256, got %s", alg)
+		}
+
+		token, err = issue("parsec.test", "legacy.parsec.test")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if alg := algorithm(token); alg != "RS256" {
+			t.Errorf("expected the legacy audience's RS256, got %s", alg)
+		}
+	})
+
+	t.Run("rejects audiences with different signers", func(t *testing.T) {
+		conflicting := AudienceSigners{"legacy.parsec.test": legacy, "orders.parsec.test": mesh}
+		_, err := conflicting.signerFor([]string{"legacy.parsec.test", "orders.parsec.test"}, mesh)
+		if !perr.HasCode(err, perr.ErrCodeInvalidAudience) {
+			t.Errorf("expected invalid_audience, got %v", err)
+		}
+	})
+
+	t.Run("matches audiences by pattern", func(t *testing.T) {
+		patterned := AudienceSigners{"*.parsec.test": legacy, "orders.parsec.test": mesh}
+		signer, err := patterned.signerFor([]string{"eu.billing.parsec.test"}, mesh)
+		if err != nil || signer != legacy {
+			t.Errorf("expected the pattern's signer, got %v (%v)", signer, err)
+		}
+		signer, err = patterned.signerFor([]string{"orders.parsec.test"}, legacy)
+		if err != nil || signer != mesh {
+			t.Errorf("expected the exact audience's signer over the pattern's, got %v (%v)", signer, err)
+		}
+	})
+
+	t.Run("publishes the keys of every signer", func(t *testing.T) {
+		publicKeys, err := iss.PublicKeys(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		algorithms := map[string]bool{}
+		for _, key := range publicKeys {
+			algorithms[key.Algorithm] = true
+		}
+		if !algorithms["ES256"] || !algorithms["RS256"] {
+			t.Errorf("expected ES256 and RS256 keys, got %+v", publicKeys)
+		}
+	})
+}
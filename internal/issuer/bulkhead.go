@@ -0,0 +1,52 @@
+package issuer
+
+import (
+	"context"
+
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// BulkheadIssuer limits how many tokens an issuer mints at once, so a slow
+// issuer (e.g. one waiting on a KMS) cannot hold every server goroutine and
+// starve other token types
+type BulkheadIssuer struct {
+	inner    service.Issuer
+	bulkhead *service.Bulkhead
+}
+
+// NewBulkheadIssuer creates an issuer that issues tokens of inner through a bulkhead
+func NewBulkheadIssuer(inner service.Issuer, bulkhead *service.Bulkhead) *BulkheadIssuer {
+	return &BulkheadIssuer{
+		inner:    inner,
+		bulkhead: bulkhead,
+	}
+}
+
+// Issue implements the Issuer interface
+func (b *BulkheadIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	var token *service.Token
+	err := b.bulkhead.Do(ctx, func(ctx context.Context) error {
+		var err error
+		token, err = b.inner.Issue(ctx, issueCtx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// PublicKeys implements the Issuer interface
+func (b *BulkheadIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	return b.inner.PublicKeys(ctx)
+}
+
+// Introspect implements service.TokenIntrospector for issuers that support it
+// Introspection does not take a slot; tokens of other issuers are inactive.
+func (b *BulkheadIssuer) Introspect(ctx context.Context, token string) (*service.IntrospectionResult, error) {
+	introspector, ok := b.inner.(service.TokenIntrospector)
+	if !ok {
+		return service.InactiveIntrospectionResult(), nil
+	}
+	return introspector.Introspect(ctx, token)
+}
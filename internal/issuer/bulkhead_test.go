@@ -0,0 +1,98 @@
+package issuer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// blockingIssuer blocks issuance until released
+type blockingIssuer struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (i *blockingIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	close(i.started)
+	<-i.release
+	return &service.Token{Value: "slow"}, nil
+}
+
+func (i *blockingIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	return nil, nil
+}
+
+func TestBulkheadIssuer(t *testing.T) {
+	ctx := context.Background()
+	issueCtx := &service.IssueContext{
+		Subject:            &trust.Result{Subject: "alice"},
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	}
+
+	t.Run("rejects issuance beyond the limit", func(t *testing.T) {
+		bulkhead, err := service.NewBulkhead(service.BulkheadConfig{Name: "issuer:slow", MaxConcurrent: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		inner := &blockingIssuer{started: make(chan struct{}), release: make(chan struct{})}
+		iss := NewBulkheadIssuer(inner, bulkhead)
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := iss.Issue(ctx, issueCtx)
+			done <- err
+		}()
+		<-inner.started
+
+		if _, err := iss.Issue(ctx, issueCtx); !errors.Is(err, service.ErrBulkheadFull) {
+			t.Errorf("expected ErrBulkheadFull, got %v", err)
+		}
+
+		close(inner.release)
+		if err := <-done; err != nil {
+			t.Errorf("expected first issuance to succeed, got %v", err)
+		}
+	})
+
+	t.Run("introspection is forwarded", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		bulkhead, _ := service.NewBulkhead(service.BulkheadConfig{MaxConcurrent: 1})
+		iss := NewBulkheadIssuer(NewReferenceTokenIssuer(ReferenceTokenIssuerConfig{
+			TokenType: string(service.TokenTypeAccessToken),
+			IssuerURL: "https://parsec.test",
+			TTL:       time.Minute,
+			Store:     NewInMemoryReferenceTokenStore(InMemoryReferenceTokenStoreConfig{Clock: clk}),
+			Clock:     clk,
+		}), bulkhead)
+
+		token, err := iss.Issue(ctx, issueCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		result, err := iss.Introspect(ctx, token.Value)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.Active {
+			t.Error("expected issued reference token to be active")
+		}
+	})
+
+	t.Run("issuers without introspection report inactive tokens", func(t *testing.T) {
+		bulkhead, _ := service.NewBulkhead(service.BulkheadConfig{MaxConcurrent: 1})
+		iss := NewBulkheadIssuer(NewStubIssuer(StubIssuerConfig{IssuerURL: "https://parsec.test", TTL: time.Minute}), bulkhead)
+
+		result, err := iss.Introspect(ctx, "anything")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Active {
+			t.Error("expected inactive result")
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package issuer
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/perr"
+)
+
+// ClaimsContract declares what consumers of a claim, such as a transaction
+// token's "tctx", rely on: defaults for members the mappers leave unset, and
+// members every token must carry
+//
+// Enforcing the contract at issuance makes a mapper or data source that stops
+// producing a member visible where it happens, rather than in every consumer.
+type ClaimsContract struct {
+	// Defaults are the values of members the mappers leave unset or null
+	Defaults claims.Claims
+
+	// Required are the members every token must carry, after defaults
+	Required []string
+}
+
+// ApplyDefaults sets the defaults c lacks
+func (contract *ClaimsContract) ApplyDefaults(c claims.Claims) {
+	if contract == nil {
+		return
+	}
+	for name, value := range contract.Defaults {
+		if c[name] == nil {
+			c[name] = value
+		}
+	}
+}
+
+// Check returns an error with an ErrCodeMissingClaims code listing the
+// required members c lacks, naming them as members of claim
+func (contract *ClaimsContract) Check(claim string, c claims.Claims) error {
+	if contract == nil {
+		return nil
+	}
+	var missing []string
+	for _, name := range contract.Required {
+		if c[name] == nil {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return perr.Errorf(perr.ErrCodeMissingClaims, "%s is missing required claims: %s", claim, strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package issuer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestTransactionTokenIssuer_ClaimsContract(t *testing.T) {
+	ctx := context.Background()
+	signer := newTestSigner(t)
+	contract := &ClaimsContract{
+		Defaults: claims.Claims{"region": "us-east-1", "tier": "standard"},
+		Required: []string{"tenant", "region", "org_id"},
+	}
+	issue := func(mapped claims.Claims) (*service.Token, error) {
+		iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL:                  "https://parsec.test",
+			TTL:                        time.Minute,
+			Signer:                     signer,
+			TransactionContextMappers:  []service.ClaimMapper{service.NewStubClaimMapper(mapped)},
+			TransactionContextContract: contract,
+		})
+		return iss.Issue(ctx, &service.IssueContext{
+			Subject:            &trust.Result{Subject: "alice"},
+			Audience:           "parsec.test",
+			DataSourceRegistry: service.NewDataSourceRegistry(),
+		})
+	}
+
+	t.Run("fills in defaults", func(t *testing.T) {
+		token, err := issue(claims.Claims{"tenant": "acme", "org_id": "42", "tier": nil, "region": "eu-west-1"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var tctx map[string]any
+		if err := parseUnverified(t, token.Value).Get("tctx", &tctx); err != nil {
+			t.Fatalf("expected tctx claim: %v", err)
+		}
+		if tctx["region"] != "eu-west-1" || tctx["tier"] != "standard" {
+			t.Errorf("expected mapped values to win over defaults and null ones to be defaulted, got %v", tctx)
+		}
+	})
+
+	t.Run("lists missing required claims", func(t *testing.T) {
+		_, err := issue(claims.Claims{"org_id": nil})
+		if !perr.HasCode(err, perr.ErrCodeMissingClaims) {
+			t.Fatalf("expected missing_claims, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "tctx is missing required claims: org_id, tenant") {
+			t.Errorf("expected the missing claims to be listed, got %v", err)
+		}
+	})
+}
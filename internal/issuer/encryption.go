@@ -0,0 +1,304 @@
+package issuer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lestrrat-go/httprc/v3"
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwe"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// EncryptionKeySource provides the recipient's public key for token encryption
+type EncryptionKeySource interface {
+	// EncryptionKey returns the current recipient key
+	EncryptionKey(ctx context.Context) (jwk.Key, error)
+}
+
+// StaticEncryptionKeySource always returns the same recipient key
+type StaticEncryptionKeySource struct {
+	key jwk.Key
+}
+
+// NewStaticEncryptionKeySource creates a key source for a fixed recipient key
+func NewStaticEncryptionKeySource(key jwk.Key) *StaticEncryptionKeySource {
+	return &StaticEncryptionKeySource{key: key}
+}
+
+// EncryptionKey implements EncryptionKeySource
+func (s *StaticEncryptionKeySource) EncryptionKey(ctx context.Context) (jwk.Key, error) {
+	return s.key, nil
+}
+
+// ParseEncryptionKey parses a recipient public key from a JWK (JSON) or PEM document
+func ParseEncryptionKey(data []byte) (jwk.Key, error) {
+	key, err := jwk.ParseKey(data)
+	if err == nil {
+		return key, nil
+	}
+
+	key, pemErr := jwk.ParseKey(data, jwk.WithPEM(true))
+	if pemErr != nil {
+		return nil, fmt.Errorf("failed to parse encryption key as JWK (%v) or PEM: %w", err, pemErr)
+	}
+	return key, nil
+}
+
+// JWKSEncryptionKeySource fetches the recipient key from a JWKS URL
+// The key set is cached and refreshed in the background
+type JWKSEncryptionKeySource struct {
+	url   string
+	keyID string
+	cache *jwk.Cache
+}
+
+// JWKSEncryptionKeySourceConfig configures a JWKS-backed encryption key source
+type JWKSEncryptionKeySourceConfig struct {
+	// URL is the recipient's JWKS URL
+	URL string
+
+	// KeyID selects a key by "kid" (optional)
+	// If empty, the first key intended for encryption is used
+	KeyID string
+
+	// RefreshInterval for the JWKS cache (default: 15 minutes)
+	RefreshInterval time.Duration
+
+	// HTTPClient is an optional HTTP client for JWKS fetching
+	HTTPClient *http.Client
+}
+
+// NewJWKSEncryptionKeySource creates a key source that reads the recipient key from a JWKS URL
+func NewJWKSEncryptionKeySource(ctx context.Context, cfg JWKSEncryptionKeySourceConfig) (*JWKSEncryptionKeySource, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("JWKS URL is required")
+	}
+
+	refreshInterval := cfg.RefreshInterval
+	if refreshInterval == 0 {
+		refreshInterval = 15 * time.Minute
+	}
+
+	cache, err := jwk.NewCache(ctx, httprc.NewClient())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS cache: %w", err)
+	}
+
+	registerOpts := []jwk.RegisterOption{jwk.WithMinInterval(refreshInterval)}
+	if cfg.HTTPClient != nil {
+		registerOpts = append(registerOpts, jwk.WithHTTPClient(cfg.HTTPClient))
+	}
+	if err := cache.Register(ctx, cfg.URL, registerOpts...); err != nil {
+		return nil, fmt.Errorf("failed to register JWKS URL: %w", err)
+	}
+
+	// Pre-fetch the JWKS so misconfiguration surfaces at startup
+	fetchCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if _, err := cache.Refresh(fetchCtx, cfg.URL); err != nil {
+		return nil, fmt.Errorf("failed to fetch initial JWKS: %w", err)
+	}
+
+	return &JWKSEncryptionKeySource{
+		url:   cfg.URL,
+		keyID: cfg.KeyID,
+		cache: cache,
+	}, nil
+}
+
+// EncryptionKey implements EncryptionKeySource
+func (s *JWKSEncryptionKeySource) EncryptionKey(ctx context.Context) (jwk.Key, error) {
+	set, err := s.cache.Lookup(ctx, s.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	for i := 0; i < set.Len(); i++ {
+		key, ok := set.Key(i)
+		if !ok {
+			continue
+		}
+
+		if s.keyID != "" {
+			if kid, _ := key.KeyID(); kid == s.keyID {
+				return key, nil
+			}
+			continue
+		}
+
+		// Skip keys explicitly reserved for signatures
+		if use, ok := key.KeyUsage(); ok && use != string(jwk.ForEncryption) {
+			continue
+		}
+		return key, nil
+	}
+
+	if s.keyID != "" {
+		return nil, fmt.Errorf("no key with kid %q in JWKS %s", s.keyID, s.url)
+	}
+	return nil, fmt.Errorf("no encryption key in JWKS %s", s.url)
+}
+
+// EncryptionConfig configures JWE encryption of issued tokens
+type EncryptionConfig struct {
+	// KeySource provides the recipient's public key (required)
+	KeySource EncryptionKeySource
+
+	// KeyAlgorithm is the key management algorithm ("alg")
+	// If empty, uses the key's "alg" or a default for its key type
+	KeyAlgorithm string
+
+	// ContentEncryption is the content encryption algorithm ("enc")
+	// If empty, defaults to A256GCM
+	ContentEncryption string
+}
+
+// TokenEncrypter encrypts token values as compact JWEs for one recipient
+type TokenEncrypter struct {
+	keySource         EncryptionKeySource
+	keyAlgorithm      jwa.KeyEncryptionAlgorithm
+	hasKeyAlgorithm   bool
+	contentEncryption jwa.ContentEncryptionAlgorithm
+}
+
+// NewTokenEncrypter creates a token encrypter
+func NewTokenEncrypter(cfg EncryptionConfig) (*TokenEncrypter, error) {
+	if cfg.KeySource == nil {
+		return nil, fmt.Errorf("encryption key source is required")
+	}
+
+	e := &TokenEncrypter{
+		keySource:         cfg.KeySource,
+		contentEncryption: jwa.A256GCM(),
+	}
+
+	if cfg.KeyAlgorithm != "" {
+		alg, ok := jwa.LookupKeyEncryptionAlgorithm(cfg.KeyAlgorithm)
+		if !ok {
+			return nil, fmt.Errorf("unsupported key encryption algorithm: %s", cfg.KeyAlgorithm)
+		}
+		e.keyAlgorithm = alg
+		e.hasKeyAlgorithm = true
+	}
+
+	if cfg.ContentEncryption != "" {
+		enc, ok := jwa.LookupContentEncryptionAlgorithm(cfg.ContentEncryption)
+		if !ok {
+			return nil, fmt.Errorf("unsupported content encryption algorithm: %s", cfg.ContentEncryption)
+		}
+		e.contentEncryption = enc
+	}
+
+	return e, nil
+}
+
+// Encrypt encrypts a token value. Signed JWTs are nested (JWS inside JWE,
+// cty "JWT"); other token formats are encrypted as-is.
+func (e *TokenEncrypter) Encrypt(ctx context.Context, value []byte) ([]byte, error) {
+	key, err := e.keySource.EncryptionKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encryption key: %w", err)
+	}
+
+	alg, err := e.algorithmFor(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mark nested JWTs (RFC 7519 section 5.2)
+	protected := jwe.NewHeaders()
+	if bytes.Count(value, []byte(".")) == 2 {
+		if err := protected.Set(jwe.ContentTypeKey, "JWT"); err != nil {
+			return nil, fmt.Errorf("failed to set content type header: %w", err)
+		}
+	}
+
+	recipient := jwe.NewHeaders()
+	if kid, ok := key.KeyID(); ok {
+		if err := recipient.Set(jwe.KeyIDKey, kid); err != nil {
+			return nil, fmt.Errorf("failed to set key ID header: %w", err)
+		}
+	}
+
+	encrypted, err := jwe.Encrypt(value,
+		jwe.WithKey(alg, key, jwe.WithPerRecipientHeaders(recipient)),
+		jwe.WithContentEncryption(e.contentEncryption),
+		jwe.WithProtectedHeaders(protected),
+		jwe.WithCompact(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt token: %w", err)
+	}
+	return encrypted, nil
+}
+
+// algorithmFor selects the key management algorithm for a recipient key
+func (e *TokenEncrypter) algorithmFor(key jwk.Key) (jwa.KeyEncryptionAlgorithm, error) {
+	if e.hasKeyAlgorithm {
+		return e.keyAlgorithm, nil
+	}
+
+	if keyAlg, ok := key.Algorithm(); ok {
+		if alg, ok := jwa.LookupKeyEncryptionAlgorithm(keyAlg.String()); ok {
+			return alg, nil
+		}
+	}
+
+	switch key.KeyType() {
+	case jwa.RSA():
+		return jwa.RSA_OAEP_256(), nil
+	case jwa.EC(), jwa.OKP():
+		return jwa.ECDH_ES_A256KW(), nil
+	case jwa.OctetSeq():
+		return jwa.A256KW(), nil
+	default:
+		return jwa.KeyEncryptionAlgorithm{}, fmt.Errorf("unsupported encryption key type: %s", key.KeyType())
+	}
+}
+
+// EncryptingIssuer wraps an issuer and encrypts the tokens it issues as compact JWEs.
+// Intermediate hops can no longer read claims; only the holder of the recipient
+// private key can decrypt. Signed JWTs are nested (JWS inside JWE, cty "JWT").
+type EncryptingIssuer struct {
+	inner     service.Issuer
+	encrypter *TokenEncrypter
+}
+
+// NewEncryptingIssuer creates an issuer that encrypts the tokens of inner
+func NewEncryptingIssuer(inner service.Issuer, cfg EncryptionConfig) (*EncryptingIssuer, error) {
+	encrypter, err := NewTokenEncrypter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptingIssuer{inner: inner, encrypter: encrypter}, nil
+}
+
+// Issue implements the Issuer interface
+// Issues a token with the wrapped issuer and encrypts its value
+func (e *EncryptingIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	token, err := e.inner.Issue(ctx, issueCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := e.encrypter.Encrypt(ctx, []byte(token.Value))
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedToken := *token
+	encryptedToken.Value = string(encrypted)
+	return &encryptedToken, nil
+}
+
+// PublicKeys implements the Issuer interface
+// Returns the wrapped issuer's signing keys
+func (e *EncryptingIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	return e.inner.PublicKeys(ctx)
+}
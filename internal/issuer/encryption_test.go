@@ -0,0 +1,139 @@
+package issuer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwe"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestEncryptingIssuer(t *testing.T) {
+	ctx := context.Background()
+	signer := newTestSigner(t)
+
+	inner := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       time.Minute,
+		Signer:    signer,
+	})
+
+	issueCtx := &service.IssueContext{
+		Subject:            &trust.Result{Subject: "alice"},
+		Audience:           "parsec.test",
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	}
+
+	t.Run("static RSA recipient key", func(t *testing.T) {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		publicKey, err := jwk.Import(&privateKey.PublicKey)
+		if err != nil {
+			t.Fatalf("failed to import key: %v", err)
+		}
+
+		iss, err := NewEncryptingIssuer(inner, EncryptionConfig{
+			KeySource: NewStaticEncryptionKeySource(publicKey),
+		})
+		if err != nil {
+			t.Fatalf("failed to create issuer: %v", err)
+		}
+
+		token, err := iss.Issue(ctx, issueCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Count(token.Value, ".") != 4 {
+			t.Fatalf("expected compact JWE with 5 parts, got %q", token.Value)
+		}
+
+		msg, err := jwe.Parse([]byte(token.Value))
+		if err != nil {
+			t.Fatalf("failed to parse JWE: %v", err)
+		}
+		if cty, _ := msg.ProtectedHeaders().ContentType(); cty != "JWT" {
+			t.Errorf("expected cty JWT, got %q", cty)
+		}
+
+		payload, err := jwe.Decrypt([]byte(token.Value), jwe.WithKey(jwa.RSA_OAEP_256(), privateKey))
+		if err != nil {
+			t.Fatalf("failed to decrypt: %v", err)
+		}
+		if sub, _ := parseUnverified(t, string(payload)).Subject(); sub != "alice" {
+			t.Errorf("expected sub alice in nested token, got %q", sub)
+		}
+	})
+
+	t.Run("recipient key from JWKS URL", func(t *testing.T) {
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		publicKey, err := jwk.Import(&privateKey.PublicKey)
+		if err != nil {
+			t.Fatalf("failed to import key: %v", err)
+		}
+		_ = publicKey.Set(jwk.KeyIDKey, "enc-1")
+		_ = publicKey.Set(jwk.KeyUsageKey, jwk.ForEncryption)
+
+		set := jwk.NewSet()
+		_ = set.AddKey(publicKey)
+		jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(set)
+		}))
+		defer jwksServer.Close()
+
+		keySource, err := NewJWKSEncryptionKeySource(ctx, JWKSEncryptionKeySourceConfig{URL: jwksServer.URL})
+		if err != nil {
+			t.Fatalf("failed to create key source: %v", err)
+		}
+
+		iss, err := NewEncryptingIssuer(inner, EncryptionConfig{KeySource: keySource})
+		if err != nil {
+			t.Fatalf("failed to create issuer: %v", err)
+		}
+
+		token, err := iss.Issue(ctx, issueCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		msg, err := jwe.Parse([]byte(token.Value))
+		if err != nil {
+			t.Fatalf("failed to parse JWE: %v", err)
+		}
+		if kid, _ := msg.ProtectedHeaders().KeyID(); kid != "enc-1" {
+			t.Errorf("expected kid enc-1, got %q", kid)
+		}
+
+		if _, err := jwe.Decrypt([]byte(token.Value), jwe.WithKey(jwa.ECDH_ES_A256KW(), privateKey)); err != nil {
+			t.Fatalf("failed to decrypt: %v", err)
+		}
+	})
+
+	t.Run("rejects unknown algorithms", func(t *testing.T) {
+		_, err := NewEncryptingIssuer(inner, EncryptionConfig{
+			KeySource:    NewStaticEncryptionKeySource(nil),
+			KeyAlgorithm: "ROT13",
+		})
+		if err == nil {
+			t.Error("expected error for unknown key algorithm")
+		}
+	})
+}
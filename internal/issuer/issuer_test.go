@@ -88,6 +88,29 @@ func TestStubIssuer(t *testing.T) {
 		}
 	})
 
+	t.Run("token lifetime starts at the issue time of the context", func(t *testing.T) {
+		issuedAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		issuer := NewStubIssuer(StubIssuerConfig{
+			IssuerURL:                 "https://parsec.example.com",
+			TTL:                       5 * time.Minute,
+			TransactionContextMappers: []service.ClaimMapper{service.NewPassthroughSubjectMapper()},
+			// The issuer's own clock only applies when the context has no issue time
+			Clock: clock.NewFixtureClock(issuedAt.Add(time.Hour)),
+		})
+
+		token, err := issuer.Issue(ctx, &service.IssueContext{
+			Subject:            &trust.Result{Subject: "test-user"},
+			DataSourceRegistry: service.NewDataSourceRegistry(),
+			IssuedAt:           issuedAt,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !token.IssuedAt.Equal(issuedAt) || !token.ExpiresAt.Equal(issuedAt.Add(5*time.Minute)) {
+			t.Errorf("expected token issued at %v for 5m, got %v to %v", issuedAt, token.IssuedAt, token.ExpiresAt)
+		}
+	})
+
 	t.Run("returns empty public keys for unsigned tokens", func(t *testing.T) {
 		issuerURL := "https://parsec.example.com"
 		txnMappers := []service.ClaimMapper{service.NewPassthroughSubjectMapper()}
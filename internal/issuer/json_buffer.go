@@ -0,0 +1,52 @@
+package issuer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"sync"
+)
+
+// maxPooledBufferSize bounds the buffers kept for reuse, so one oversized
+// token does not pin its buffer
+const maxPooledBufferSize = 64 << 10
+
+// bufferPool holds the buffers tokens are encoded into
+var bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// getBuffer returns an empty buffer from the pool
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer returns a buffer to the pool; its contents must no longer be used
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBufferSize {
+		bufferPool.Put(buf)
+	}
+}
+
+// appendJSON appends the JSON encoding of v to buf, as json.Marshal would
+// encode it
+func appendJSON(buf *bytes.Buffer, v any) error {
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		return err
+	}
+	// Encode terminates the value with a newline
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// appendBase64JSON appends the standard base64 encoding of v's JSON to buf
+func appendBase64JSON(buf *bytes.Buffer, v any) error {
+	encoded := getBuffer()
+	defer putBuffer(encoded)
+	if err := appendJSON(encoded, v); err != nil {
+		return err
+	}
+	buf.Grow(base64.StdEncoding.EncodedLen(encoded.Len()))
+	buf.Write(base64.StdEncoding.AppendEncode(buf.AvailableBuffer(), encoded.Bytes()))
+	return nil
+}
@@ -0,0 +1,58 @@
+package issuer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAppendJSON(t *testing.T) {
+	values := []any{
+		map[string]any{"path": "/api?a=1&b=<2>", "roles": []string{"admin"}, "n": 1.5},
+		map[string]any{},
+		nil,
+		"plain",
+	}
+	for _, v := range values {
+		want, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %v", v, err)
+		}
+
+		buf := getBuffer()
+		buf.WriteString("prefix.")
+		if err := appendJSON(buf, v); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := buf.String(); got != "prefix."+string(want) {
+			t.Errorf("expected %s, got %s", "prefix."+string(want), got)
+		}
+
+		buf.Reset()
+		if err := appendBase64JSON(buf, v); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := buf.String(); got != base64.StdEncoding.EncodeToString(want) {
+			t.Errorf("expected base64 of %s, got %s", want, got)
+		}
+		putBuffer(buf)
+	}
+
+	t.Run("unencodable value", func(t *testing.T) {
+		buf := getBuffer()
+		defer putBuffer(buf)
+		if err := appendJSON(buf, map[string]any{"c": make(chan int)}); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("oversized buffers are not pooled", func(t *testing.T) {
+		buf := getBuffer()
+		buf.WriteString(strings.Repeat("x", maxPooledBufferSize+1))
+		putBuffer(buf)
+		if got := getBuffer(); got == buf {
+			t.Error("expected the oversized buffer to be dropped")
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package issuer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+	"github.com/project-kessel/parsec/pkg/plugin"
+)
+
+// PluginIssuerConfig is the configuration for creating a plugin issuer
+type PluginIssuerConfig struct {
+	// TokenType is the token type to issue
+	TokenType string
+
+	// Plugin is the plugin that issues the tokens
+	Plugin *plugin.Client
+
+	// ClaimMappers produce the claims handed to the plugin (optional)
+	ClaimMappers []service.ClaimMapper
+
+	// Clock is the time source for token timestamps
+	// If nil, uses system clock
+	Clock clock.Clock
+}
+
+// PluginIssuer issues tokens through an out-of-process plugin, for token
+// formats parsec doesn't support itself
+// Claim mapping happens in parsec; the plugin encodes and signs the claims.
+type PluginIssuer struct {
+	tokenType    string
+	plugin       *plugin.Client
+	claimMappers []service.ClaimMapper
+	clock        clock.Clock
+}
+
+// NewPluginIssuer creates a new plugin issuer
+func NewPluginIssuer(cfg PluginIssuerConfig) *PluginIssuer {
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	return &PluginIssuer{
+		tokenType:    cfg.TokenType,
+		plugin:       cfg.Plugin,
+		claimMappers: cfg.ClaimMappers,
+		clock:        clk,
+	}
+}
+
+// Issue implements the Issuer interface
+func (i *PluginIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	mappedClaims, err := issueCtx.ToClaims(ctx, i.claimMappers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map claims: %w", err)
+	}
+
+	issuedAt := issueCtx.IssueTime(i.clock)
+	resp, err := i.plugin.Issue(ctx, &plugin.IssueRequest{
+		TokenType:         i.tokenType,
+		Subject:           pluginIdentity(issueCtx.Subject),
+		Actor:             pluginIdentity(issueCtx.Actor),
+		RequestAttributes: pluginRequestAttributes(issueCtx.RequestAttributes),
+		Audience:          issueCtx.Audience,
+		Audiences:         issueCtx.TokenAudiences(),
+		Scope:             issueCtx.Scope,
+		Purpose:           issueCtx.Purpose,
+		Claims:            mappedClaims,
+		IssuedAt:          issuedAt,
+	})
+	if err != nil {
+		return nil, pluginError(err)
+	}
+	if resp.Token == "" {
+		return nil, fmt.Errorf("plugin returned an empty token")
+	}
+	if !resp.IssuedAt.IsZero() {
+		issuedAt = resp.IssuedAt
+	}
+
+	return &service.Token{
+		Value:         resp.Token,
+		Type:          i.tokenType,
+		ExpiresAt:     resp.ExpiresAt,
+		IssuedAt:      issuedAt,
+		TransactionID: resp.TransactionID,
+	}, nil
+}
+
+// PublicKeys implements the Issuer interface
+// The plugin's JWKs are converted to the keys parsec publishes.
+func (i *PluginIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	resp, err := i.plugin.PublicKeys(ctx)
+	if err != nil {
+		return nil, pluginError(err)
+	}
+
+	publicKeys := make([]service.PublicKey, 0, len(resp.Keys))
+	for _, data := range resp.Keys {
+		key, err := jwk.ParseKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("plugin returned an invalid public key: %w", err)
+		}
+		kid, ok := key.KeyID()
+		if !ok {
+			return nil, fmt.Errorf("plugin returned a public key without kid")
+		}
+		if private, _ := jwk.IsPrivateKey(key); private {
+			return nil, fmt.Errorf("plugin returned private key %s as a public key", kid)
+		}
+		var raw any
+		if err := jwk.Export(key, &raw); err != nil {
+			return nil, fmt.Errorf("plugin returned an invalid public key %s: %w", kid, err)
+		}
+		publicKey := service.PublicKey{KeyID: kid, Key: raw, Use: "sig"}
+		if alg, ok := key.Algorithm(); ok {
+			publicKey.Algorithm = alg.String()
+		}
+		if use, ok := key.KeyUsage(); ok {
+			publicKey.Use = use
+		}
+		publicKeys = append(publicKeys, publicKey)
+	}
+	return publicKeys, nil
+}
+
+// pluginError describes a failed plugin call, marking an unreachable plugin
+// as unavailable
+func pluginError(err error) error {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted:
+		return perr.Errorf(perr.ErrCodeIssuerUnavailable, "issuer plugin unavailable: %v", err)
+	case codes.DeadlineExceeded:
+		return perr.Errorf(perr.ErrCodeDeadlineExceeded, "issuer plugin timed out: %v", err)
+	}
+	return fmt.Errorf("issuer plugin failed: %w", err)
+}
+
+func pluginIdentity(result *trust.Result) *plugin.Identity {
+	if result == nil {
+		return nil
+	}
+	return &plugin.Identity{
+		Subject:     result.Subject,
+		Issuer:      result.Issuer,
+		TrustDomain: result.TrustDomain,
+		Claims:      result.Claims,
+		ExpiresAt:   result.ExpiresAt,
+		IssuedAt:    result.IssuedAt,
+		Audience:    result.Audience,
+		Scope:       result.Scope,
+	}
+}
+
+func pluginRequestAttributes(attrs *request.RequestAttributes) *plugin.RequestAttributes {
+	if attrs == nil {
+		return nil
+	}
+	return &plugin.RequestAttributes{
+		Method:     attrs.Method,
+		Path:       attrs.Path,
+		IPAddress:  attrs.IPAddress,
+		UserAgent:  attrs.UserAgent,
+		Headers:    attrs.Headers,
+		Additional: attrs.Additional,
+	}
+}
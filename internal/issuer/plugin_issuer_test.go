@@ -0,0 +1,192 @@
+package issuer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+	"github.com/project-kessel/parsec/pkg/plugin"
+)
+
+// recordingPluginIssuer is a plugin issuer that records its last request
+type recordingPluginIssuer struct {
+	err  error
+	keys []json.RawMessage
+
+	mu   sync.Mutex
+	last *plugin.IssueRequest
+}
+
+func (p *recordingPluginIssuer) Issue(ctx context.Context, req *plugin.IssueRequest) (*plugin.IssueResponse, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.mu.Lock()
+	p.last = req
+	p.mu.Unlock()
+	return &plugin.IssueResponse{Token: "plugin-token", ExpiresAt: req.IssuedAt.Add(time.Hour), TransactionID: "txn-1"}, nil
+}
+
+func (p *recordingPluginIssuer) PublicKeys(ctx context.Context) (*plugin.PublicKeysResponse, error) {
+	return &plugin.PublicKeysResponse{Keys: p.keys}, nil
+}
+
+// servePluginIssuer serves impl as a plugin and returns a client for it
+func servePluginIssuer(t *testing.T, impl plugin.Issuer) *plugin.Client {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	plugin.Register(srv, plugin.ServeConfig{Issuer: impl})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	client, err := plugin.NewClient(plugin.ClientConfig{Address: lis.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestPluginIssuer_Issue(t *testing.T) {
+	impl := &recordingPluginIssuer{}
+	iss := NewPluginIssuer(PluginIssuerConfig{
+		TokenType:    "urn:example:token-type:custom",
+		Plugin:       servePluginIssuer(t, impl),
+		ClaimMappers: []service.ClaimMapper{service.NewStubClaimMapper(claims.Claims{"org_id": "42"})},
+	})
+
+	issuedAt := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	token, err := iss.Issue(context.Background(), &service.IssueContext{
+		Subject:            &trust.Result{Subject: "alice", TrustDomain: "idp.example.com"},
+		RequestAttributes:  &request.RequestAttributes{Method: "GET", Path: "/orders"},
+		Audience:           "parsec.test",
+		Scope:              "orders",
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+		IssuedAt:           issuedAt,
+	})
+	if err != nil {
+		t.Fatalf("Issue() failed: %v", err)
+	}
+
+	if token.Value != "plugin-token" || token.Type != "urn:example:token-type:custom" || token.TransactionID != "txn-1" {
+		t.Errorf("unexpected token: %+v", token)
+	}
+	if !token.IssuedAt.Equal(issuedAt) || !token.ExpiresAt.Equal(issuedAt.Add(time.Hour)) {
+		t.Errorf("unexpected token times: issued %v, expires %v", token.IssuedAt, token.ExpiresAt)
+	}
+
+	impl.mu.Lock()
+	req := impl.last
+	impl.mu.Unlock()
+	if req.Subject.Subject != "alice" || req.Subject.TrustDomain != "idp.example.com" || req.Actor != nil {
+		t.Errorf("unexpected identities: subject %+v, actor %+v", req.Subject, req.Actor)
+	}
+	if req.RequestAttributes.Path != "/orders" || req.Audience != "parsec.test" || req.Scope != "orders" {
+		t.Errorf("unexpected request: %+v", req)
+	}
+	if req.Claims["org_id"] != "42" {
+		t.Errorf("expected the mapped claims, got %v", req.Claims)
+	}
+}
+
+func TestPluginIssuer_IssueErrors(t *testing.T) {
+	t.Run("plugin error", func(t *testing.T) {
+		iss := NewPluginIssuer(PluginIssuerConfig{
+			Plugin: servePluginIssuer(t, &recordingPluginIssuer{err: status.Error(codes.PermissionDenied, "no")}),
+		})
+		_, err := iss.Issue(context.Background(), &service.IssueContext{Subject: &trust.Result{Subject: "alice"}})
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected the plugin's error, got %v", err)
+		}
+	})
+
+	t.Run("plugin unreachable", func(t *testing.T) {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		address := lis.Addr().String()
+		_ = lis.Close()
+		client, err := plugin.NewClient(plugin.ClientConfig{Address: address})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = client.Close() }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err = NewPluginIssuer(PluginIssuerConfig{Plugin: client}).Issue(ctx, &service.IssueContext{})
+		if !perr.HasCode(err, perr.ErrCodeIssuerUnavailable) {
+			t.Errorf("expected issuer_unavailable, got %v", err)
+		}
+	})
+}
+
+func TestPluginIssuer_PublicKeys(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwkOf := func(raw any, kid string) json.RawMessage {
+		key, err := jwk.Import(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kid != "" {
+			_ = key.Set(jwk.KeyIDKey, kid)
+		}
+		_ = key.Set(jwk.AlgorithmKey, "ES256")
+		data, err := json.Marshal(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	t.Run("converts JWKs", func(t *testing.T) {
+		impl := &recordingPluginIssuer{keys: []json.RawMessage{jwkOf(ecKey.Public(), "key-1")}}
+		keys, err := NewPluginIssuer(PluginIssuerConfig{Plugin: servePluginIssuer(t, impl)}).PublicKeys(context.Background())
+		if err != nil {
+			t.Fatalf("PublicKeys() failed: %v", err)
+		}
+		if len(keys) != 1 || keys[0].KeyID != "key-1" || keys[0].Algorithm != "ES256" || keys[0].Use != "sig" {
+			t.Fatalf("unexpected keys: %+v", keys)
+		}
+		if pub, ok := keys[0].Key.(*ecdsa.PublicKey); !ok || !pub.Equal(ecKey.Public()) {
+			t.Errorf("expected the plugin's public key, got %T", keys[0].Key)
+		}
+	})
+
+	for name, key := range map[string]json.RawMessage{
+		"missing kid": jwkOf(ecKey.Public(), ""),
+		"private key": jwkOf(ecKey, "key-1"),
+		"not a JWK":   json.RawMessage(`{"kty":"nope"}`),
+	} {
+		t.Run(name, func(t *testing.T) {
+			impl := &recordingPluginIssuer{keys: []json.RawMessage{key}}
+			if _, err := NewPluginIssuer(PluginIssuerConfig{Plugin: servePluginIssuer(t, impl)}).PublicKeys(context.Background()); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
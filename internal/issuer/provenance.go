@@ -0,0 +1,49 @@
+package issuer
+
+import (
+	"context"
+
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// ProvenanceIssuer records where the claims of issued tokens came from, so
+// disputes about token contents can be traced to the configured mapper and
+// data sources that produced them
+//
+// Only the claims of labeled mappers (see service.LabelClaimMapper) are
+// recorded. The sources are returned in Token.Provenance for audit events;
+// the token itself is unchanged.
+type ProvenanceIssuer struct {
+	inner service.Issuer
+}
+
+// NewProvenanceIssuer creates an issuer recording the claim provenance of the
+// tokens of inner
+func NewProvenanceIssuer(inner service.Issuer) *ProvenanceIssuer {
+	return &ProvenanceIssuer{inner: inner}
+}
+
+// Issue implements the Issuer interface
+func (p *ProvenanceIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	ctx, provenance := service.WithClaimProvenance(ctx)
+	token, err := p.inner.Issue(ctx, issueCtx)
+	if err != nil {
+		return nil, err
+	}
+	token.Provenance = provenance.Sources()
+	return token, nil
+}
+
+// PublicKeys implements the Issuer interface
+func (p *ProvenanceIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	return p.inner.PublicKeys(ctx)
+}
+
+// Introspect implements service.TokenIntrospector for issuers that support it
+func (p *ProvenanceIssuer) Introspect(ctx context.Context, token string) (*service.IntrospectionResult, error) {
+	introspector, ok := p.inner.(service.TokenIntrospector)
+	if !ok {
+		return service.InactiveIntrospectionResult(), nil
+	}
+	return introspector.Introspect(ctx, token)
+}
@@ -0,0 +1,333 @@
+package issuer
+
+import (
+	"container/heap"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// ErrReferenceNotFound is returned by a ReferenceTokenStore when a reference is unknown
+var ErrReferenceNotFound = perr.New(perr.ErrCodeNotFound, "reference token not found")
+
+// referenceTokenBytes is the amount of randomness in a reference token
+const referenceTokenBytes = 32
+
+// ReferenceTokenRecord is the server-side state behind an opaque reference token
+type ReferenceTokenRecord struct {
+	// Claims are the token claims that are hidden from the token holder
+	Claims claims.Claims
+
+	// TokenType is the type of the issued token
+	TokenType string
+
+	// IssuedAt is when the token was issued
+	IssuedAt time.Time
+
+	// ExpiresAt is when the token (and its record) expires
+	ExpiresAt time.Time
+}
+
+// ReferenceTokenStore persists the claims behind opaque reference tokens
+type ReferenceTokenStore interface {
+	// Put stores a record under the given reference
+	Put(ctx context.Context, reference string, record *ReferenceTokenRecord) error
+
+	// Get returns the record for a reference, or ErrReferenceNotFound
+	Get(ctx context.Context, reference string) (*ReferenceTokenRecord, error)
+
+	// Delete removes a record (e.g., on revocation)
+	Delete(ctx context.Context, reference string) error
+}
+
+// DefaultReferenceTokenStoreMaxEntries bounds an InMemoryReferenceTokenStore
+// without a configured limit
+const DefaultReferenceTokenStoreMaxEntries = 100000
+
+// ErrReferenceTokenStoreFull is returned by Put when an
+// InMemoryReferenceTokenStore holds its maximum number of unexpired records
+var ErrReferenceTokenStoreFull = perr.New(perr.ErrCodeIssuerUnavailable, "reference token store is full")
+
+// InMemoryReferenceTokenStore is a ReferenceTokenStore backed by a map
+// Expired records are evicted lazily on lookup and, in order of expiry, when
+// new records are stored. Once MaxEntries unexpired records are held, new
+// records are refused rather than evicting tokens that are still valid.
+// Records are lost on restart and are not shared between replicas
+type InMemoryReferenceTokenStore struct {
+	mu         sync.Mutex
+	records    map[string]*ReferenceTokenRecord
+	expiries   referenceExpiryHeap
+	maxEntries int
+	clock      clock.Clock
+}
+
+// InMemoryReferenceTokenStoreConfig configures an InMemoryReferenceTokenStore
+type InMemoryReferenceTokenStoreConfig struct {
+	// MaxEntries bounds the records held (default: DefaultReferenceTokenStoreMaxEntries)
+	MaxEntries int
+
+	// Clock is the time source for expiry
+	// If nil, uses system clock
+	Clock clock.Clock
+}
+
+// NewInMemoryReferenceTokenStore creates a new in-memory reference token store
+func NewInMemoryReferenceTokenStore(cfg InMemoryReferenceTokenStoreConfig) *InMemoryReferenceTokenStore {
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+	maxEntries := cfg.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = DefaultReferenceTokenStoreMaxEntries
+	}
+	return &InMemoryReferenceTokenStore{
+		records:    make(map[string]*ReferenceTokenRecord),
+		maxEntries: maxEntries,
+		clock:      clk,
+	}
+}
+
+// Put implements ReferenceTokenStore
+func (s *InMemoryReferenceTokenStore) Put(ctx context.Context, reference string, record *ReferenceTokenRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evictExpired(s.clock.Now())
+	if _, replaced := s.records[reference]; !replaced && len(s.records) >= s.maxEntries {
+		return ErrReferenceTokenStoreFull
+	}
+
+	s.records[reference] = record
+	heap.Push(&s.expiries, referenceExpiry{reference: reference, record: record})
+	if len(s.expiries) > 2*s.maxEntries {
+		s.compactExpiries()
+	}
+	return nil
+}
+
+// compactExpiries drops schedule entries for records that were deleted or
+// replaced before expiring; s.mu must be held
+func (s *InMemoryReferenceTokenStore) compactExpiries() {
+	live := s.expiries[:0]
+	for _, e := range s.expiries {
+		if s.records[e.reference] == e.record {
+			live = append(live, e)
+		}
+	}
+	clear(s.expiries[len(live):])
+	s.expiries = live
+	heap.Init(&s.expiries)
+}
+
+// evictExpired removes the records expired at now, soonest first; s.mu must
+// be held
+func (s *InMemoryReferenceTokenStore) evictExpired(now time.Time) {
+	for len(s.expiries) > 0 && !now.Before(s.expiries[0].record.ExpiresAt) {
+		expired := heap.Pop(&s.expiries).(referenceExpiry)
+		// The reference may have been deleted or stored again since
+		if s.records[expired.reference] == expired.record {
+			delete(s.records, expired.reference)
+		}
+	}
+}
+
+// referenceExpiry schedules the eviction of a stored record
+type referenceExpiry struct {
+	reference string
+	record    *ReferenceTokenRecord
+}
+
+// referenceExpiryHeap orders records by expiry, soonest first
+type referenceExpiryHeap []referenceExpiry
+
+func (h referenceExpiryHeap) Len() int { return len(h) }
+func (h referenceExpiryHeap) Less(i, j int) bool {
+	return h[i].record.ExpiresAt.Before(h[j].record.ExpiresAt)
+}
+func (h referenceExpiryHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *referenceExpiryHeap) Push(x any)   { *h = append(*h, x.(referenceExpiry)) }
+func (h *referenceExpiryHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = referenceExpiry{}
+	*h = old[:n-1]
+	return x
+}
+
+// Get implements ReferenceTokenStore
+func (s *InMemoryReferenceTokenStore) Get(ctx context.Context, reference string) (*ReferenceTokenRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[reference]
+	if !ok {
+		return nil, ErrReferenceNotFound
+	}
+
+	if !s.clock.Now().Before(record.ExpiresAt) {
+		delete(s.records, reference)
+		return nil, ErrReferenceNotFound
+	}
+
+	return record, nil
+}
+
+// Delete implements ReferenceTokenStore
+func (s *InMemoryReferenceTokenStore) Delete(ctx context.Context, reference string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, reference)
+	return nil
+}
+
+// ReferenceTokenIssuerConfig is the configuration for creating a reference token issuer
+type ReferenceTokenIssuerConfig struct {
+	// TokenType is the token type to issue
+	TokenType string
+
+	// IssuerURL is the issuer URL (iss claim in the stored claims)
+	IssuerURL string
+
+	// TTL is the time-to-live for tokens
+	TTL time.Duration
+
+	// ClaimMappers build the stored claims
+	ClaimMappers []service.ClaimMapper
+
+	// Store persists the claims behind each reference (required)
+	Store ReferenceTokenStore
+
+	// Clock is the time source for token timestamps
+	// If nil, uses system clock
+	Clock clock.Clock
+}
+
+// ReferenceTokenIssuer issues opaque reference tokens.
+// The token value is a random handle; the claims are kept server-side in a
+// ReferenceTokenStore and can only be read via introspection.
+type ReferenceTokenIssuer struct {
+	tokenType    string
+	issuerURL    string
+	ttl          time.Duration
+	claimMappers []service.ClaimMapper
+	store        ReferenceTokenStore
+	clock        clock.Clock
+}
+
+// NewReferenceTokenIssuer creates a new reference token issuer
+func NewReferenceTokenIssuer(cfg ReferenceTokenIssuerConfig) *ReferenceTokenIssuer {
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	return &ReferenceTokenIssuer{
+		tokenType:    cfg.TokenType,
+		issuerURL:    cfg.IssuerURL,
+		ttl:          cfg.TTL,
+		claimMappers: cfg.ClaimMappers,
+		store:        cfg.Store,
+		clock:        clk,
+	}
+}
+
+// Issue implements the Issuer interface
+// Stores the mapped claims and returns a random opaque reference
+func (i *ReferenceTokenIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	mappedClaims, err := issueCtx.ToClaims(ctx, i.claimMappers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map claims: %w", err)
+	}
+
+	now := issueCtx.IssueTime(i.clock)
+	expiresAt := now.Add(issueCtx.CapTTL(i.ttl))
+
+	// Standard claims always reflect the issuance, regardless of mapper output
+	stored := mappedClaims.Copy()
+	stored["iss"] = i.issuerURL
+	stored["sub"] = issueCtx.Subject.Subject
+	audience := issueCtx.TokenAudiences()
+	jti := uuid.NewString()
+	stored["aud"] = audience
+	stored["iat"] = now.Unix()
+	stored["exp"] = expiresAt.Unix()
+	stored["jti"] = jti
+	if issueCtx.Scope != "" {
+		stored["scope"] = issueCtx.Scope
+	}
+	if act := issueCtx.Delegation.ActClaim(); act != nil {
+		stored["act"] = act
+	}
+
+	reference, err := newReference()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate reference: %w", err)
+	}
+
+	if err := i.store.Put(ctx, reference, &ReferenceTokenRecord{
+		Claims:    stored,
+		TokenType: i.tokenType,
+		IssuedAt:  now,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to store reference token: %w", err)
+	}
+
+	return &service.Token{
+		Value:     reference,
+		Type:      i.tokenType,
+		ExpiresAt: expiresAt,
+		IssuedAt:  now,
+		ID:        jti,
+		Audience:  audience,
+	}, nil
+}
+
+// Introspect implements service.TokenIntrospector
+func (i *ReferenceTokenIssuer) Introspect(ctx context.Context, token string) (*service.IntrospectionResult, error) {
+	record, err := i.store.Get(ctx, token)
+	if errors.Is(err, ErrReferenceNotFound) {
+		return service.InactiveIntrospectionResult(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up reference token: %w", err)
+	}
+
+	if !i.clock.Now().Before(record.ExpiresAt) {
+		return service.InactiveIntrospectionResult(), nil
+	}
+
+	return &service.IntrospectionResult{
+		Active:    true,
+		TokenType: record.TokenType,
+		Claims:    record.Claims.Copy(),
+	}, nil
+}
+
+// PublicKeys implements the Issuer interface
+// Reference tokens are not signed, so there are no public keys
+func (i *ReferenceTokenIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	return []service.PublicKey{}, nil
+}
+
+// newReference generates a random, URL-safe token handle
+func newReference() (string, error) {
+	b := make([]byte, referenceTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,177 @@
+package issuer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestReferenceTokenIssuer(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	newIssuer := func() *ReferenceTokenIssuer {
+		return NewReferenceTokenIssuer(ReferenceTokenIssuerConfig{
+			TokenType: string(service.TokenTypeAccessToken),
+			IssuerURL: "https://parsec.test",
+			TTL:       time.Minute,
+			ClaimMappers: []service.ClaimMapper{
+				service.NewStubClaimMapper(claims.Claims{"roles": []any{"admin"}, "sub": "spoofed"}),
+			},
+			Store: NewInMemoryReferenceTokenStore(InMemoryReferenceTokenStoreConfig{Clock: clk}),
+			Clock: clk,
+		})
+	}
+
+	issueCtx := &service.IssueContext{
+		Subject:            &trust.Result{Subject: "alice"},
+		Audience:           "parsec.test",
+		Scope:              "orders.read",
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	}
+
+	t.Run("token value does not reveal claims", func(t *testing.T) {
+		iss := newIssuer()
+		token, err := iss.Issue(ctx, issueCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if strings.Count(token.Value, ".") != 0 {
+			t.Errorf("expected opaque token, got %q", token.Value)
+		}
+		if strings.Contains(token.Value, "alice") {
+			t.Errorf("token value leaks subject: %q", token.Value)
+		}
+		if !token.ExpiresAt.Equal(clk.Now().Add(time.Minute)) {
+			t.Errorf("unexpected expiry %v", token.ExpiresAt)
+		}
+	})
+
+	t.Run("introspection resolves stored claims", func(t *testing.T) {
+		iss := newIssuer()
+		token, err := iss.Issue(ctx, issueCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		result, err := iss.Introspect(ctx, token.Value)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.Active {
+			t.Fatal("expected active token")
+		}
+		if result.Claims.GetString("sub") != "alice" {
+			t.Errorf("expected sub alice, got %v", result.Claims["sub"])
+		}
+		if result.Claims.GetString("scope") != "orders.read" {
+			t.Errorf("expected scope, got %v", result.Claims["scope"])
+		}
+		if _, ok := result.Claims["roles"]; !ok {
+			t.Error("expected mapped roles claim")
+		}
+		if result.TokenType != string(service.TokenTypeAccessToken) {
+			t.Errorf("unexpected token type %q", result.TokenType)
+		}
+	})
+
+	t.Run("unknown and expired tokens are inactive", func(t *testing.T) {
+		iss := newIssuer()
+		token, err := iss.Issue(ctx, issueCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		result, err := iss.Introspect(ctx, "not-a-reference")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Active {
+			t.Error("expected unknown token to be inactive")
+		}
+
+		clk.Advance(2 * time.Minute)
+		result, err = iss.Introspect(ctx, token.Value)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Active {
+			t.Error("expected expired token to be inactive")
+		}
+	})
+}
+
+func TestInMemoryReferenceTokenStore(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	record := func(ttl time.Duration) *ReferenceTokenRecord {
+		return &ReferenceTokenRecord{IssuedAt: clk.Now(), ExpiresAt: clk.Now().Add(ttl)}
+	}
+
+	t.Run("refuses records once full", func(t *testing.T) {
+		store := NewInMemoryReferenceTokenStore(InMemoryReferenceTokenStoreConfig{MaxEntries: 2, Clock: clk})
+		for _, ref := range []string{"a", "b"} {
+			if err := store.Put(ctx, ref, record(time.Minute)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		if err := store.Put(ctx, "c", record(time.Minute)); !errors.Is(err, ErrReferenceTokenStoreFull) {
+			t.Fatalf("expected store full error, got %v", err)
+		}
+		if err := store.Put(ctx, "a", record(time.Hour)); err != nil {
+			t.Errorf("replacing a stored reference should not be refused: %v", err)
+		}
+		if _, err := store.Get(ctx, "b"); err != nil {
+			t.Errorf("existing record should be kept: %v", err)
+		}
+	})
+
+	t.Run("expired records free space", func(t *testing.T) {
+		store := NewInMemoryReferenceTokenStore(InMemoryReferenceTokenStoreConfig{MaxEntries: 2, Clock: clk})
+		if err := store.Put(ctx, "short", record(time.Second)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := store.Put(ctx, "long", record(time.Hour)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		clk.Advance(time.Minute)
+		if err := store.Put(ctx, "new", record(time.Hour)); err != nil {
+			t.Fatalf("expected expired record to be evicted, got %v", err)
+		}
+		if _, err := store.Get(ctx, "short"); !errors.Is(err, ErrReferenceNotFound) {
+			t.Errorf("expected expired record to be gone, got %v", err)
+		}
+		if _, err := store.Get(ctx, "long"); err != nil {
+			t.Errorf("unexpired record should be kept: %v", err)
+		}
+	})
+
+	t.Run("replaced records are not evicted by their old expiry", func(t *testing.T) {
+		store := NewInMemoryReferenceTokenStore(InMemoryReferenceTokenStoreConfig{Clock: clk})
+		if err := store.Put(ctx, "ref", record(time.Second)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := store.Put(ctx, "ref", record(time.Hour)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		clk.Advance(time.Minute)
+		if err := store.Put(ctx, "other", record(time.Hour)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := store.Get(ctx, "ref"); err != nil {
+			t.Errorf("replaced record should be kept: %v", err)
+		}
+	})
+}
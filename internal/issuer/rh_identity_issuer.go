@@ -2,12 +2,11 @@ package issuer
 
 import (
 	"context"
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/rhidentity"
 	"github.com/project-kessel/parsec/internal/service"
 )
 
@@ -25,7 +24,8 @@ type RHIdentityIssuerConfig struct {
 }
 
 // RHIdentityIssuer issues Red Hat identity tokens in the x-rh-identity format
-// The token is the base64-encoded JSON representation wrapped in {"identity": {...}}
+// The token is the base64-encoded JSON document {"identity": {...}, "entitlements": {...}},
+// validated against the x-rh-identity schema so it is directly usable as the header value
 type RHIdentityIssuer struct {
 	tokenType    string
 	claimMappers []service.ClaimMapper
@@ -55,20 +55,19 @@ func (i *RHIdentityIssuer) Issue(ctx context.Context, issueCtx *service.IssueCon
 		return nil, fmt.Errorf("failed to map claims: %w", err)
 	}
 
-	// Wrap mapped claims in "identity" wrapper
-	// This matches the format expected by Red Hat services
-	identityWrapper := map[string]any{
-		"identity": mappedClaims,
+	// Build and validate the identity document expected by Red Hat services
+	identity, err := rhidentity.FromClaims(mappedClaims)
+	if err != nil {
+		return nil, err
 	}
 
-	// Serialize to JSON
-	identityJSON, err := json.Marshal(identityWrapper)
-	if err != nil {
+	// Serialize to base64-encoded JSON
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := appendBase64JSON(buf, identity); err != nil {
 		return nil, fmt.Errorf("failed to marshal RH identity: %w", err)
 	}
-
-	// Base64-encode the JSON
-	encodedToken := base64.StdEncoding.EncodeToString(identityJSON)
+	encodedToken := buf.String()
 
 	// RH identity tokens don't have a real expiration
 	// Use a far-future time to indicate effectively never expires
@@ -78,7 +77,7 @@ func (i *RHIdentityIssuer) Issue(ctx context.Context, issueCtx *service.IssueCon
 		Value:     encodedToken,
 		Type:      i.tokenType,
 		ExpiresAt: neverExpires,
-		IssuedAt:  i.clock.Now(),
+		IssuedAt:  issueCtx.IssueTime(i.clock),
 	}, nil
 }
 
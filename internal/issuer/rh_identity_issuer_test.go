@@ -0,0 +1,106 @@
+package issuer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestRHIdentityIssuer(t *testing.T) {
+	ctx := context.Background()
+
+	issue := func(t *testing.T, mapped claims.Claims) (map[string]any, error) {
+		t.Helper()
+		iss := NewRHIdentityIssuer(RHIdentityIssuerConfig{
+			TokenType:    string(service.TokenTypeRHIdentity),
+			ClaimMappers: []service.ClaimMapper{service.NewStubClaimMapper(mapped)},
+		})
+		token, err := iss.Issue(ctx, &service.IssueContext{
+			Subject:            &trust.Result{Subject: "alice"},
+			DataSourceRegistry: service.NewDataSourceRegistry(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(token.Value)
+		if err != nil {
+			t.Fatalf("header value is not base64: %v", err)
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(decoded, &doc); err != nil {
+			t.Fatalf("header value is not JSON: %v", err)
+		}
+		return doc, nil
+	}
+
+	t.Run("user identity with entitlements", func(t *testing.T) {
+		doc, err := issue(t, claims.Claims{
+			"org_id":    "12345",
+			"type":      "User",
+			"auth_type": "jwt-auth",
+			"user":      map[string]any{"username": "alice", "is_org_admin": true},
+			"internal":  map[string]any{"org_id": "12345", "auth_time": 1700000000},
+			"entitlements": map[string]any{
+				"insights": map[string]any{"is_entitled": true, "is_trial": false},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		identity := doc["identity"].(map[string]any)
+		if identity["type"] != "User" || identity["org_id"] != "12345" {
+			t.Errorf("unexpected identity: %v", identity)
+		}
+		if _, ok := identity["entitlements"]; ok {
+			t.Error("entitlements must be a top-level section")
+		}
+		insights := doc["entitlements"].(map[string]any)["insights"].(map[string]any)
+		if insights["is_entitled"] != true {
+			t.Errorf("unexpected entitlements: %v", doc["entitlements"])
+		}
+	})
+
+	t.Run("complete document is accepted", func(t *testing.T) {
+		doc, err := issue(t, claims.Claims{
+			"identity": map[string]any{
+				"type":      "Associate",
+				"associate": map[string]any{"email": "alice@redhat.com", "Role": []any{"admin"}},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if doc["identity"].(map[string]any)["type"] != "Associate" {
+			t.Errorf("unexpected document: %v", doc)
+		}
+	})
+
+	errorCases := []struct {
+		name   string
+		mapped claims.Claims
+		want   string
+	}{
+		{name: "missing type", mapped: claims.Claims{"org_id": "1"}, want: "identity.type is required"},
+		{name: "unknown type", mapped: claims.Claims{"type": "Robot", "org_id": "1"}, want: "unknown identity.type"},
+		{name: "missing section", mapped: claims.Claims{"type": "System", "org_id": "1"}, want: "requires its system section"},
+		{name: "missing org", mapped: claims.Claims{"type": "ServiceAccount", "service_account": map[string]any{"client_id": "c", "username": "u"}}, want: "requires org_id"},
+		{name: "unknown field", mapped: claims.Claims{"type": "X509", "x509": map[string]any{"subject_dn": "CN=a", "issuer_dn": "CN=b"}, "colour": "blue"}, want: "schema"},
+		{name: "mapper error", mapped: claims.Claims{"error": "unsupported_token_type"}, want: "unsupported_token_type"},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := issue(t, tt.mapped)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package issuer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v3/jwt"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// ErrTokenTooLarge is returned when a token exceeds its size budget after all compaction steps
+var ErrTokenTooLarge = perr.New(perr.ErrCodeTokenTooLarge, "token exceeds size budget")
+
+// CompactionAction is what a compaction step does to its claim
+type CompactionAction string
+
+const (
+	// CompactionActionDrop removes the claim (for optional claim groups)
+	CompactionActionDrop CompactionAction = "drop"
+
+	// CompactionActionReference replaces an array claim with a pointer to the
+	// data source that downstream services can query for the full value
+	CompactionActionReference CompactionAction = "reference"
+)
+
+// CompactionStep is a single compaction applied when a token is over budget
+type CompactionStep struct {
+	// Claim is the claim path, using "." to address nested claims (e.g., "tctx.groups")
+	Claim string
+
+	// Action is the compaction to apply
+	Action CompactionAction
+
+	// DataSource names the data source holding the full value (reference action)
+	DataSource string
+}
+
+// String describes the step for reporting
+func (s CompactionStep) String() string {
+	if s.Action == CompactionActionReference {
+		return fmt.Sprintf("%s:%s->%s", s.Action, s.Claim, s.DataSource)
+	}
+	return fmt.Sprintf("%s:%s", s.Action, s.Claim)
+}
+
+// SizeBudget bounds the encoded size of issued tokens, e.g. to stay under proxy header limits.
+// When a token is over budget, steps are applied in order, cumulatively, until it fits.
+type SizeBudget struct {
+	// MaxBytes is the maximum encoded token size (0 disables the budget)
+	MaxBytes int
+
+	// Steps are the compaction steps, in the order they are tried
+	Steps []CompactionStep
+}
+
+// Validate checks that the budget is well-formed
+func (b *SizeBudget) Validate() error {
+	if b == nil {
+		return nil
+	}
+	if b.MaxBytes < 0 {
+		return fmt.Errorf("max size cannot be negative")
+	}
+	for i, step := range b.Steps {
+		if step.Claim == "" {
+			return fmt.Errorf("compaction step %d requires a claim", i)
+		}
+		switch step.Action {
+		case CompactionActionDrop:
+		case CompactionActionReference:
+			if step.DataSource == "" {
+				return fmt.Errorf("compaction step %d (reference %s) requires a data source", i, step.Claim)
+			}
+		default:
+			return fmt.Errorf("compaction step %d has unknown action %q (supported: drop, reference)", i, step.Action)
+		}
+	}
+	return nil
+}
+
+// Enforce signs the token and, if it is over budget, compacts and re-signs it until it fits.
+// Returns the encoded token and a compaction report (nil if no compaction was needed).
+func (b *SizeBudget) Enforce(token jwt.Token, sign func(jwt.Token) ([]byte, error)) ([]byte, *service.CompactionReport, error) {
+	encoded, err := sign(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b == nil || b.MaxBytes == 0 || len(encoded) <= b.MaxBytes {
+		return encoded, nil, nil
+	}
+
+	report := &service.CompactionReport{
+		MaxSize:      b.MaxBytes,
+		OriginalSize: len(encoded),
+	}
+
+	compacted, err := token.Clone()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to clone token: %w", err)
+	}
+
+	for _, step := range b.Steps {
+		applied, err := applyCompactionStep(compacted, step)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !applied {
+			continue
+		}
+		report.Steps = append(report.Steps, step.String())
+
+		encoded, err = sign(compacted)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(encoded) <= b.MaxBytes {
+			report.FinalSize = len(encoded)
+			return encoded, report, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("%w: %d bytes exceeds %d after %d compaction steps",
+		ErrTokenTooLarge, len(encoded), b.MaxBytes, len(report.Steps))
+}
+
+// applyCompactionStep applies a step to the token
+// Returns false if the claim is absent (or not an array, for references)
+func applyCompactionStep(token jwt.Token, step CompactionStep) (bool, error) {
+	top, rest, nested := strings.Cut(step.Claim, ".")
+
+	if !token.Has(top) {
+		return false, nil
+	}
+
+	if !nested {
+		switch step.Action {
+		case CompactionActionDrop:
+			if err := token.Remove(top); err != nil {
+				return false, fmt.Errorf("failed to remove claim %s: %w", top, err)
+			}
+			return true, nil
+		default:
+			var value any
+			if err := token.Get(top, &value); err != nil {
+				return false, fmt.Errorf("failed to get claim %s: %w", top, err)
+			}
+			replacement, ok := referenceFor(value, step.DataSource)
+			if !ok {
+				return false, nil
+			}
+			if err := token.Set(top, replacement); err != nil {
+				return false, fmt.Errorf("failed to set claim %s: %w", top, err)
+			}
+			return true, nil
+		}
+	}
+
+	var value any
+	if err := token.Get(top, &value); err != nil {
+		return false, fmt.Errorf("failed to get claim %s: %w", top, err)
+	}
+	updated, applied := compactNested(value, strings.Split(rest, "."), step)
+	if !applied {
+		return false, nil
+	}
+	if err := token.Set(top, updated); err != nil {
+		return false, fmt.Errorf("failed to set claim %s: %w", top, err)
+	}
+	return true, nil
+}
+
+// compactNested applies a step at path within value, copying maps along the path
+// so claim maps shared with the issue context are never mutated
+func compactNested(value any, path []string, step CompactionStep) (any, bool) {
+	m, ok := asMap(value)
+	if !ok {
+		return value, false
+	}
+
+	child, ok := m[path[0]]
+	if !ok {
+		return value, false
+	}
+
+	copied := make(map[string]any, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+
+	if len(path) > 1 {
+		updated, applied := compactNested(child, path[1:], step)
+		if !applied {
+			return value, false
+		}
+		copied[path[0]] = updated
+		return copied, true
+	}
+
+	switch step.Action {
+	case CompactionActionDrop:
+		delete(copied, path[0])
+	default:
+		replacement, ok := referenceFor(child, step.DataSource)
+		if !ok {
+			return value, false
+		}
+		copied[path[0]] = replacement
+	}
+	return copied, true
+}
+
+// referenceFor builds the pointer that replaces an array claim
+// Returns false if value is not an array
+func referenceFor(value any, dataSource string) (map[string]any, bool) {
+	if value == nil {
+		return nil, false
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	return map[string]any{
+		"datasource": dataSource,
+		"count":      rv.Len(),
+	}, true
+}
+
+// asMap returns value as a map if it is a claims map
+func asMap(value any) (map[string]any, bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		return v, true
+	case claims.Claims:
+		return v, true
+	default:
+		return nil, false
+	}
+}
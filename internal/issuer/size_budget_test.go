@@ -0,0 +1,177 @@
+package issuer
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwe"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestTransactionTokenIssuer_SizeBudget(t *testing.T) {
+	ctx := context.Background()
+	signer := newTestSigner(t)
+
+	groups := make([]any, 200)
+	for i := range groups {
+		groups[i] = fmt.Sprintf("group-%03d", i)
+	}
+
+	subjectClaims := claims.Claims{"groups": groups}
+	issueCtx := &service.IssueContext{
+		Subject:            &trust.Result{Subject: "alice", Claims: subjectClaims},
+		Audience:           "parsec.test",
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	}
+
+	newIssuer := func(budget *SizeBudget) *TransactionTokenIssuer {
+		return NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL: "https://parsec.test",
+			TTL:       time.Minute,
+			Signer:    signer,
+			TransactionContextMappers: []service.ClaimMapper{
+				service.NewStubClaimMapper(claims.Claims{"groups": groups, "tenant": "acme"}),
+			},
+			RequestContextMappers: []service.ClaimMapper{
+				service.NewStubClaimMapper(claims.Claims{"user_agent": "curl/8.0"}),
+			},
+			SizeBudget: budget,
+		})
+	}
+
+	t.Run("under budget is not compacted", func(t *testing.T) {
+		token, err := newIssuer(&SizeBudget{MaxBytes: 64 * 1024}).Issue(ctx, issueCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if token.Compaction != nil {
+			t.Errorf("expected no compaction, got %+v", token.Compaction)
+		}
+	})
+
+	t.Run("steps applied in order until the token fits", func(t *testing.T) {
+		budget := &SizeBudget{
+			MaxBytes: 1024,
+			Steps: []CompactionStep{
+				{Claim: "req_ctx", Action: CompactionActionDrop},
+				{Claim: "tctx.groups", Action: CompactionActionReference, DataSource: "user_groups"},
+				{Claim: "tctx", Action: CompactionActionDrop},
+			},
+		}
+
+		token, err := newIssuer(budget).Issue(ctx, issueCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(token.Value) > budget.MaxBytes {
+			t.Errorf("token of %d bytes exceeds budget", len(token.Value))
+		}
+
+		report := token.Compaction
+		if report == nil {
+			t.Fatal("expected compaction report")
+		}
+		if len(report.Steps) != 2 {
+			t.Errorf("expected 2 steps applied, got %v", report.Steps)
+		}
+		if report.OriginalSize <= budget.MaxBytes || report.FinalSize != len(token.Value) {
+			t.Errorf("unexpected sizes in report: %+v", report)
+		}
+
+		parsed := parseUnverified(t, token.Value)
+		if parsed.Has("req_ctx") {
+			t.Error("expected req_ctx to be dropped")
+		}
+		var tctx map[string]any
+		if err := parsed.Get("tctx", &tctx); err != nil {
+			t.Fatalf("expected tctx to be kept: %v", err)
+		}
+		ref, ok := tctx["groups"].(map[string]any)
+		if !ok || ref["datasource"] != "user_groups" {
+			t.Errorf("expected groups reference, got %v", tctx["groups"])
+		}
+		if tctx["tenant"] != "acme" {
+			t.Errorf("expected other tctx claims to be kept, got %v", tctx)
+		}
+
+		if _, ok := subjectClaims["groups"].([]any); !ok {
+			t.Error("compaction must not mutate input claims")
+		}
+	})
+
+	t.Run("fails when still over budget", func(t *testing.T) {
+		_, err := newIssuer(&SizeBudget{MaxBytes: 100}).Issue(ctx, issueCtx)
+		if !errors.Is(err, ErrTokenTooLarge) {
+			t.Errorf("expected ErrTokenTooLarge, got %v", err)
+		}
+	})
+}
+
+func TestTransactionTokenIssuer_SizeBudgetMeasuresEncryptedToken(t *testing.T) {
+	ctx := context.Background()
+	signer := newTestSigner(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	publicKey, err := jwk.Import(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to import key: %v", err)
+	}
+	encrypter, err := NewTokenEncrypter(EncryptionConfig{KeySource: NewStaticEncryptionKeySource(publicKey)})
+	if err != nil {
+		t.Fatalf("failed to create encrypter: %v", err)
+	}
+
+	issueCtx := &service.IssueContext{
+		Subject:            &trust.Result{Subject: "alice"},
+		Audience:           "parsec.test",
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	}
+
+	newIssuer := func(budget *SizeBudget, encrypter *TokenEncrypter) *TransactionTokenIssuer {
+		return NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL:  "https://parsec.test",
+			TTL:        time.Minute,
+			Signer:     signer,
+			SizeBudget: budget,
+			Encrypter:  encrypter,
+		})
+	}
+
+	signed, err := newIssuer(nil, nil).Issue(ctx, issueCtx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The signed token fits, but the encrypted one does not
+	budget := &SizeBudget{MaxBytes: len(signed.Value) + 16}
+
+	if _, err := newIssuer(budget, nil).Issue(ctx, issueCtx); err != nil {
+		t.Fatalf("expected signed token to fit the budget, got %v", err)
+	}
+
+	_, err = newIssuer(budget, encrypter).Issue(ctx, issueCtx)
+	if !errors.Is(err, ErrTokenTooLarge) {
+		t.Errorf("expected ErrTokenTooLarge for the encrypted token, got %v", err)
+	}
+
+	token, err := newIssuer(&SizeBudget{MaxBytes: 64 * 1024}, encrypter).Issue(ctx, issueCtx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := jwe.Decrypt([]byte(token.Value), jwe.WithKey(jwa.RSA_OAEP_256(), privateKey)); err != nil {
+		t.Errorf("expected a decryptable JWE, got %v", err)
+	}
+}
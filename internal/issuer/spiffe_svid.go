@@ -0,0 +1,302 @@
+package issuer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// spiffeScheme is the URI scheme of SPIFFE IDs
+const spiffeScheme = "spiffe://"
+
+// jwtSVIDAlgorithms are the signing algorithms JWT-SVIDs may use (JWT-SVID section 3)
+var jwtSVIDAlgorithms = map[keys.Algorithm]bool{
+	"RS256": true, "RS384": true, "RS512": true,
+	"ES256": true, "ES384": true, "ES512": true,
+	"PS256": true, "PS384": true, "PS512": true,
+}
+
+// JWTSVIDIssuerConfig is the configuration for creating a JWT-SVID issuer
+type JWTSVIDIssuerConfig struct {
+	// TokenType is the token type to issue
+	TokenType string
+
+	// TrustDomain is the SPIFFE trust domain SVIDs are issued in (e.g., "example.org")
+	TrustDomain string
+
+	// TTL is the time-to-live for SVIDs
+	TTL time.Duration
+
+	// Signer handles key rotation and signing (also provides the signing algorithm)
+	Signer keys.RotatingSigner
+
+	// AudienceSigners sign the tokens of specific audiences instead of Signer (optional)
+	AudienceSigners AudienceSigners
+
+	// Audience is the aud claim. If empty, the audience from the issue context is used.
+	Audience []string
+
+	// ClaimMappers build the SVID claims
+	// The mapped "sub" claim is required and is either a full SPIFFE ID in the
+	// trust domain or a path (starting with "/") within it. Other mapped claims
+	// are included as-is, except for sub, aud, exp, and iat.
+	ClaimMappers []service.ClaimMapper
+
+	// Clock is an optional clock for testing (defaults to system clock)
+	Clock clock.Clock
+
+	// Leeway backdates the token's iat so that clients whose clocks run behind
+	// don't see a token issued in the future
+	Leeway time.Duration
+
+	// NotBeforeSkew adds an nbf claim backdated by this window (optional)
+	// When zero, JWT-SVIDs carry no nbf claim.
+	NotBeforeSkew time.Duration
+}
+
+// JWTSVIDIssuer issues SPIFFE JWT-SVIDs.
+// This lets parsec bridge identities from external IdPs into mesh-native SVIDs.
+type JWTSVIDIssuer struct {
+	tokenType       string
+	trustDomain     string
+	ttl             time.Duration
+	signer          keys.RotatingSigner
+	audienceSigners AudienceSigners
+	audience        []string
+	claimMappers    []service.ClaimMapper
+	clock           clock.Clock
+	leeway          time.Duration
+	notBeforeSkew   time.Duration
+}
+
+// NewJWTSVIDIssuer creates a new JWT-SVID issuer
+// Returns an error if the trust domain is not a valid SPIFFE trust domain, or
+// if the signer or an audience signer reports an algorithm JWT-SVIDs do not
+// allow (see keys.AlgorithmReporter). Issue checks the algorithm of every key
+// it signs with.
+func NewJWTSVIDIssuer(cfg JWTSVIDIssuerConfig) (*JWTSVIDIssuer, error) {
+	if err := validateSPIFFETrustDomain(cfg.TrustDomain); err != nil {
+		return nil, err
+	}
+
+	signers := []keys.RotatingSigner{cfg.Signer}
+	for _, signer := range cfg.AudienceSigners {
+		signers = append(signers, signer)
+	}
+	for _, signer := range signers {
+		if reporter, ok := signer.(keys.AlgorithmReporter); ok && reporter.Algorithm() != "" {
+			if err := validateJWTSVIDAlgorithm(reporter.Algorithm()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	return &JWTSVIDIssuer{
+		tokenType:       cfg.TokenType,
+		trustDomain:     cfg.TrustDomain,
+		ttl:             cfg.TTL,
+		signer:          cfg.Signer,
+		audienceSigners: cfg.AudienceSigners,
+		audience:        cfg.Audience,
+		claimMappers:    cfg.ClaimMappers,
+		clock:           clk,
+		leeway:          cfg.Leeway,
+		notBeforeSkew:   cfg.NotBeforeSkew,
+	}, nil
+}
+
+// Issue implements the Issuer interface
+// Issues a signed JWT-SVID whose subject is the mapped SPIFFE ID
+func (i *JWTSVIDIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	mappedClaims, err := issueCtx.ToClaims(ctx, i.claimMappers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map claims: %w", err)
+	}
+
+	spiffeID, err := i.spiffeID(mappedClaims.GetString(jwt.SubjectKey))
+	if err != nil {
+		return nil, err
+	}
+
+	audience := i.audience
+	if len(audience) == 0 {
+		audience = issueCtx.TokenAudiences()
+	}
+
+	now := issueCtx.IssueTime(i.clock)
+	expiresAt := now.Add(issueCtx.CapTTL(i.ttl))
+
+	token := jwt.New()
+
+	// Mapped claims first, so the required claims below always win
+	for key, value := range mappedClaims {
+		switch key {
+		case jwt.SubjectKey, jwt.AudienceKey, jwt.ExpirationKey, jwt.IssuedAtKey:
+			continue
+		}
+		if err := token.Set(key, value); err != nil {
+			return nil, fmt.Errorf("failed to set claim %s: %w", key, err)
+		}
+	}
+
+	// Required claims (JWT-SVID section 3)
+	if err := token.Set(jwt.SubjectKey, spiffeID); err != nil {
+		return nil, fmt.Errorf("failed to set subject: %w", err)
+	}
+	if err := token.Set(jwt.AudienceKey, audience); err != nil {
+		return nil, fmt.Errorf("failed to set audience: %w", err)
+	}
+	if err := token.Set(jwt.ExpirationKey, expiresAt.Unix()); err != nil {
+		return nil, fmt.Errorf("failed to set expiration: %w", err)
+	}
+	if err := token.Set(jwt.IssuedAtKey, now.Add(-i.leeway).Unix()); err != nil {
+		return nil, fmt.Errorf("failed to set issued at: %w", err)
+	}
+	if i.notBeforeSkew > 0 {
+		if err := token.Set(jwt.NotBeforeKey, now.Add(-i.notBeforeSkew).Unix()); err != nil {
+			return nil, fmt.Errorf("failed to set not before: %w", err)
+		}
+	}
+
+	rotatingSigner, err := i.audienceSigners.signerFor(audience, i.signer)
+	if err != nil {
+		return nil, err
+	}
+	signer, keyID, algorithm, err := rotatingSigner.GetCurrentSigner(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current signer: %w", err)
+	}
+	if err := validateJWTSVIDAlgorithm(algorithm); err != nil {
+		return nil, err
+	}
+	signAlg, ok := jwa.LookupSignatureAlgorithm(string(algorithm))
+	if !ok {
+		return nil, fmt.Errorf("unsupported signature algorithm: %s", algorithm)
+	}
+
+	// JWT-SVIDs must carry a key ID so validators can select the bundle key
+	headers := jws.NewHeaders()
+	if err := headers.Set(jws.KeyIDKey, string(keyID)); err != nil {
+		return nil, fmt.Errorf("failed to set key ID header: %w", err)
+	}
+	if err := headers.Set(jws.TypeKey, "JWT"); err != nil {
+		return nil, fmt.Errorf("failed to set type header: %w", err)
+	}
+
+	signedToken, err := jwt.Sign(token,
+		jwt.WithKey(signAlg, signer, jws.WithProtectedHeaders(headers)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return &service.Token{
+		Value:     string(signedToken),
+		Type:      i.tokenType,
+		ExpiresAt: expiresAt,
+		IssuedAt:  now,
+		Audience:  audience,
+	}, nil
+}
+
+// validateJWTSVIDAlgorithm checks that an algorithm may sign JWT-SVIDs
+func validateJWTSVIDAlgorithm(algorithm keys.Algorithm) error {
+	if !jwtSVIDAlgorithms[algorithm] {
+		return fmt.Errorf("signing algorithm %q is not allowed for JWT-SVIDs (allowed: RS, ES or PS with SHA-256, SHA-384 or SHA-512)", algorithm)
+	}
+	return nil
+}
+
+// spiffeID resolves the mapped subject into a SPIFFE ID in the issuer's trust domain
+func (i *JWTSVIDIssuer) spiffeID(mapped string) (string, error) {
+	if mapped == "" {
+		return "", fmt.Errorf("claim mappers did not produce a sub claim for the SPIFFE ID")
+	}
+
+	id := mapped
+	if strings.HasPrefix(mapped, "/") {
+		id = spiffeScheme + i.trustDomain + mapped
+	}
+
+	trustDomain, path, err := parseSPIFFEID(id)
+	if err != nil {
+		return "", err
+	}
+	if trustDomain != i.trustDomain {
+		return "", fmt.Errorf("SPIFFE ID %q is not in trust domain %q", id, i.trustDomain)
+	}
+	if path == "" {
+		return "", fmt.Errorf("SPIFFE ID %q has no path; JWT-SVIDs must identify a workload", id)
+	}
+
+	return id, nil
+}
+
+// PublicKeys implements the Issuer interface
+// Returns all non-expired public keys from the rotating signer and the audience
+// signers (the JWT bundle)
+func (i *JWTSVIDIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	return i.audienceSigners.publicKeys(ctx, i.signer)
+}
+
+// parseSPIFFEID splits a SPIFFE ID into its trust domain and path,
+// enforcing the character rules of the SPIFFE ID specification
+func parseSPIFFEID(id string) (trustDomain, path string, err error) {
+	rest, ok := strings.CutPrefix(id, spiffeScheme)
+	if !ok {
+		return "", "", fmt.Errorf("invalid SPIFFE ID %q: scheme must be spiffe", id)
+	}
+
+	trustDomain, path, _ = strings.Cut(rest, "/")
+	if err := validateSPIFFETrustDomain(trustDomain); err != nil {
+		return "", "", fmt.Errorf("invalid SPIFFE ID %q: %w", id, err)
+	}
+
+	if path == "" {
+		return trustDomain, "", nil
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", "", fmt.Errorf("invalid SPIFFE ID %q: empty or relative path segment", id)
+		}
+		for _, c := range segment {
+			if !isSPIFFEPathChar(c) {
+				return "", "", fmt.Errorf("invalid SPIFFE ID %q: invalid path character %q", id, c)
+			}
+		}
+	}
+
+	return trustDomain, "/" + path, nil
+}
+
+// validateSPIFFETrustDomain checks a trust domain name (lowercase letters, digits, ".", "-", "_")
+func validateSPIFFETrustDomain(trustDomain string) error {
+	if trustDomain == "" {
+		return fmt.Errorf("SPIFFE trust domain is required")
+	}
+	for _, c := range trustDomain {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+			return fmt.Errorf("invalid SPIFFE trust domain %q: invalid character %q", trustDomain, c)
+		}
+	}
+	return nil
+}
+
+// isSPIFFEPathChar reports whether c is allowed in a SPIFFE ID path segment
+func isSPIFFEPathChar(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '.' || c == '-' || c == '_'
+}
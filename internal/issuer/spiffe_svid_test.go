@@ -0,0 +1,107 @@
+package issuer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestJWTSVIDIssuer(t *testing.T) {
+	ctx := context.Background()
+	signer := newTestSigner(t)
+
+	issueCtx := &service.IssueContext{
+		Subject:            &trust.Result{Subject: "alice"},
+		Audience:           "parsec.test",
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	}
+
+	newIssuer := func(t *testing.T, sub string) *JWTSVIDIssuer {
+		t.Helper()
+		iss, err := NewJWTSVIDIssuer(JWTSVIDIssuerConfig{
+			TokenType:   string(service.TokenTypeJWT),
+			TrustDomain: "mesh.example.org",
+			TTL:         time.Minute,
+			Signer:      signer,
+			Audience:    []string{"payments"},
+			ClaimMappers: []service.ClaimMapper{
+				service.NewStubClaimMapper(claims.Claims{"sub": sub, "tenant": "acme"}),
+			},
+		})
+		if err != nil {
+			t.Fatalf("failed to create issuer: %v", err)
+		}
+		return iss
+	}
+
+	tests := []struct {
+		name    string
+		sub     string
+		want    string
+		wantErr bool
+	}{
+		{name: "path is joined to trust domain", sub: "/tenant/acme/user/alice", want: "spiffe://mesh.example.org/tenant/acme/user/alice"},
+		{name: "full SPIFFE ID is accepted", sub: "spiffe://mesh.example.org/gw", want: "spiffe://mesh.example.org/gw"},
+		{name: "foreign trust domain is rejected", sub: "spiffe://other.example.org/gw", wantErr: true},
+		{name: "missing path is rejected", sub: "spiffe://mesh.example.org", wantErr: true},
+		{name: "relative segment is rejected", sub: "/tenant/../admin", wantErr: true},
+		{name: "invalid character is rejected", sub: "/user/alice@example.com", wantErr: true},
+		{name: "empty sub is rejected", sub: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := newIssuer(t, tt.sub).Issue(ctx, issueCtx)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			parsed := parseUnverified(t, token.Value)
+			if sub, _ := parsed.Subject(); sub != tt.want {
+				t.Errorf("expected sub %q, got %q", tt.want, sub)
+			}
+			if aud, _ := parsed.Audience(); len(aud) != 1 || aud[0] != "payments" {
+				t.Errorf("expected aud [payments], got %v", aud)
+			}
+			var tenant string
+			if err := parsed.Get("tenant", &tenant); err != nil || tenant != "acme" {
+				t.Errorf("expected tenant claim acme, got %q (%v)", tenant, err)
+			}
+		})
+	}
+
+	t.Run("disallowed signing algorithm", func(t *testing.T) {
+		hmacSigner := keys.NewDualSlotRotatingSigner(keys.DualSlotRotatingSignerConfig{
+			Namespace:     "svid",
+			KeyProviderID: "hmac",
+			KeyProviderRegistry: map[string]keys.KeyProvider{
+				"hmac": keys.NewInMemoryKeyProvider(keys.KeyTypeECP256, "HS256"),
+			},
+			SlotStore: keys.NewInMemoryKeySlotStore(),
+		})
+
+		_, err := NewJWTSVIDIssuer(JWTSVIDIssuerConfig{TrustDomain: "mesh.example.org", Signer: hmacSigner})
+		if err == nil || !strings.Contains(err.Error(), `"HS256" is not allowed`) {
+			t.Errorf("expected disallowed algorithm error, got %v", err)
+		}
+	})
+
+	t.Run("invalid trust domain", func(t *testing.T) {
+		_, err := NewJWTSVIDIssuer(JWTSVIDIssuerConfig{TrustDomain: "Mesh.Example.org", Signer: signer})
+		if err == nil {
+			t.Error("expected error for uppercase trust domain")
+		}
+	})
+}
@@ -2,7 +2,6 @@ package issuer
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 
@@ -69,8 +68,8 @@ func (i *StubIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext)
 		return nil, fmt.Errorf("failed to map request context: %w", err)
 	}
 
-	now := i.clock.Now()
-	expiresAt := now.Add(i.ttl)
+	now := issueCtx.IssueTime(i.clock)
+	expiresAt := now.Add(issueCtx.CapTTL(i.ttl))
 
 	// Generate a simple token ID with microsecond precision for uniqueness
 	txnID := fmt.Sprintf("txn-%d", now.UnixNano()/1000)
@@ -78,14 +77,19 @@ func (i *StubIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext)
 	// Include subject from the issue context
 	subject := issueCtx.Subject.Subject
 
-	// Encode the request context as JSON so tests can verify filtering
-	requestContextJSON, err := json.Marshal(requestContext)
-	if err != nil {
+	// Format: stub-txn-token.{subject}.{txnID}.{requestContextJSON}
+	// The request context is encoded as JSON so tests can verify filtering
+	buf := getBuffer()
+	defer putBuffer(buf)
+	buf.WriteString("stub-txn-token.")
+	buf.WriteString(subject)
+	buf.WriteByte('.')
+	buf.WriteString(txnID)
+	buf.WriteByte('.')
+	if err := appendJSON(buf, requestContext); err != nil {
 		return nil, fmt.Errorf("failed to marshal request context: %w", err)
 	}
-
-	// Format: stub-txn-token.{subject}.{txnID}.{requestContextJSON}
-	tokenValue := fmt.Sprintf("stub-txn-token.%s.%s.%s", subject, txnID, string(requestContextJSON))
+	tokenValue := buf.String()
 
 	return &service.Token{
 		Value:     tokenValue,
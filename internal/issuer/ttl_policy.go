@@ -0,0 +1,172 @@
+package issuer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+	"github.com/project-kessel/parsec/internal/trustdomain"
+)
+
+// TTLPolicy computes the lifetime of a token at issuance time
+type TTLPolicy interface {
+	// TTL returns the time-to-live for the token being issued
+	TTL(ctx context.Context, issueCtx *service.IssueContext) (time.Duration, error)
+}
+
+// TTLPolicyLibrary creates a CEL library for TTL policies.
+//
+// This provides compile-time declarations for:
+//   - subject - the subject's Result object as a map
+//   - actor - the actor's Result object as a map
+//   - request - the request attributes as a map (method, path, ip_address, user_agent, headers, additional)
+//   - scope - the requested scope
+//   - audience - the token audience
+//
+// The expression evaluates to a duration or to an integer number of seconds.
+//
+// Example expressions:
+//   - request.method == "GET" ? duration("5m") : duration("30s")
+//   - actor.trust_domain == "internal" ? 600 : 60
+//   - "admin" in subject.claims.roles ? duration("1m") : duration("10m")
+func TTLPolicyLibrary() cel.EnvOption {
+	return cel.Lib(&ttlPolicyLib{})
+}
+
+type ttlPolicyLib struct{}
+
+func (lib *ttlPolicyLib) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		// trustDomainMatches(value, pattern)
+		trustdomain.CELLibrary(),
+		cel.Variable("subject", cel.DynType),
+		cel.Variable("actor", cel.DynType),
+		cel.Variable("request", cel.DynType),
+		cel.Variable("scope", cel.StringType),
+		cel.Variable("audience", cel.StringType),
+	}
+}
+
+func (lib *ttlPolicyLib) ProgramOptions() []cel.ProgramOption {
+	return []cel.ProgramOption{}
+}
+
+// CelTTLPolicy computes token TTL with a CEL expression, bounded by a minimum and maximum
+type CelTTLPolicy struct {
+	program cel.Program
+	script  string
+	minTTL  time.Duration
+	maxTTL  time.Duration
+}
+
+// NewCelTTLPolicy creates a new CEL-based TTL policy
+// Results are clamped to [minTTL, maxTTL]; a zero maxTTL means no upper bound.
+// A result of zero or less is an error rather than being clamped, since it
+// would mint a token that is already expired.
+func NewCelTTLPolicy(script string, minTTL, maxTTL time.Duration) (*CelTTLPolicy, error) {
+	if script == "" {
+		return nil, fmt.Errorf("CEL TTL script cannot be empty")
+	}
+	if minTTL < 0 {
+		return nil, fmt.Errorf("minimum TTL cannot be negative")
+	}
+	if maxTTL != 0 && maxTTL < minTTL {
+		return nil, fmt.Errorf("maximum TTL %s is less than minimum TTL %s", maxTTL, minTTL)
+	}
+
+	env, err := cel.NewEnv(TTLPolicyLibrary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(script)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("failed to compile CEL TTL script: %w", issues.Err())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL program: %w", err)
+	}
+
+	return &CelTTLPolicy{
+		program: program,
+		script:  script,
+		minTTL:  minTTL,
+		maxTTL:  maxTTL,
+	}, nil
+}
+
+// TTL implements TTLPolicy
+func (p *CelTTLPolicy) TTL(ctx context.Context, issueCtx *service.IssueContext) (time.Duration, error) {
+	subjectMap, err := trust.ConvertResultToMap(issueCtx.Subject)
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert subject: %w", err)
+	}
+
+	actorMap, err := trust.ConvertResultToMap(issueCtx.Actor)
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert actor: %w", err)
+	}
+
+	requestMap, err := trust.ConvertRequestAttributesToMap(issueCtx.RequestAttributes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert request attributes: %w", err)
+	}
+
+	// Absent identities and attributes are empty maps so policies can use has() checks
+	result, _, err := p.program.ContextEval(ctx, map[string]any{
+		"subject":  orEmpty(subjectMap),
+		"actor":    orEmpty(actorMap),
+		"request":  orEmpty(requestMap),
+		"scope":    issueCtx.Scope,
+		"audience": issueCtx.Audience,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to evaluate TTL policy: %w", err)
+	}
+
+	var ttl time.Duration
+	switch v := result.(type) {
+	case types.Duration:
+		ttl = v.Duration
+	case types.Int:
+		ttl = time.Duration(v) * time.Second
+	default:
+		return 0, fmt.Errorf("TTL policy must evaluate to a duration or integer seconds, got %s", result.Type().TypeName())
+	}
+	if ttl <= 0 {
+		return 0, fmt.Errorf("TTL policy must evaluate to a positive TTL, got %s", ttl)
+	}
+
+	return p.clamp(ttl), nil
+}
+
+// clamp bounds a TTL to the configured minimum and maximum
+func (p *CelTTLPolicy) clamp(ttl time.Duration) time.Duration {
+	if ttl < p.minTTL {
+		ttl = p.minTTL
+	}
+	if p.maxTTL != 0 && ttl > p.maxTTL {
+		ttl = p.maxTTL
+	}
+	return ttl
+}
+
+// orEmpty returns m, or an empty map if m is nil
+func orEmpty(m map[string]any) map[string]any {
+	if m == nil {
+		return map[string]any{}
+	}
+	return m
+}
+
+// Script returns the CEL script used by this policy
+func (p *CelTTLPolicy) Script() string {
+	return p.script
+}
@@ -0,0 +1,111 @@
+package issuer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestCelTTLPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	policy, err := NewCelTTLPolicy(
+		`request.method == "GET" ? 600 : (has(actor.trust_domain) && actor.trust_domain == "internal" ? 3600 : 5)`,
+		30*time.Second, 15*time.Minute,
+	)
+	if err != nil {
+		t.Fatalf("failed to create policy: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		actor  *trust.Result
+		want   time.Duration
+	}{
+		{name: "integer seconds", method: "GET", want: 10 * time.Minute},
+		{name: "integer seconds clamped to max", method: "POST", actor: &trust.Result{TrustDomain: "internal"}, want: 15 * time.Minute},
+		{name: "integer seconds clamped to min", method: "DELETE", want: 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := policy.TTL(ctx, &service.IssueContext{
+				Subject:           &trust.Result{Subject: "alice"},
+				Actor:             tt.actor,
+				RequestAttributes: &request.RequestAttributes{Method: tt.method},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("rejects non-duration results", func(t *testing.T) {
+		p, err := NewCelTTLPolicy(`"five minutes"`, 0, 0)
+		if err != nil {
+			t.Fatalf("failed to create policy: %v", err)
+		}
+		if _, err := p.TTL(ctx, &service.IssueContext{}); err == nil {
+			t.Error("expected error for string result")
+		}
+	})
+
+	t.Run("rejects non-positive results", func(t *testing.T) {
+		for _, script := range []string{`0`, `-60`, `duration("-1m")`} {
+			p, err := NewCelTTLPolicy(script, 0, 0)
+			if err != nil {
+				t.Fatalf("failed to create policy: %v", err)
+			}
+			if ttl, err := p.TTL(ctx, &service.IssueContext{}); err == nil {
+				t.Errorf("%s: expected error for non-positive TTL, got %s", script, ttl)
+			}
+		}
+	})
+
+	t.Run("rejects inverted bounds", func(t *testing.T) {
+		if _, err := NewCelTTLPolicy(`60`, time.Minute, time.Second); err == nil {
+			t.Error("expected error when max is less than min")
+		}
+	})
+}
+
+func TestTransactionTokenIssuer_TTLPolicy(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	policy, err := NewCelTTLPolicy(`scope == "payments.write" ? duration("30s") : duration("5m")`, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to create policy: %v", err)
+	}
+
+	iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       time.Hour,
+		TTLPolicy: policy,
+		Signer:    newTestSigner(t),
+		Clock:     clk,
+	})
+
+	token, err := iss.Issue(ctx, &service.IssueContext{
+		Subject:            &trust.Result{Subject: "alice"},
+		Audience:           "parsec.test",
+		Scope:              "payments.write",
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := clk.Now().Add(30 * time.Second); !token.ExpiresAt.Equal(want) {
+		t.Errorf("expected expiry %v, got %v", want, token.ExpiresAt)
+	}
+}
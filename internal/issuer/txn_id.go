@@ -0,0 +1,227 @@
+package issuer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// TxnIDGenerator generates the "txn" claim of a transaction token
+type TxnIDGenerator interface {
+	// NewTxnID returns a transaction ID for the token being issued
+	NewTxnID(ctx context.Context, issueCtx *service.IssueContext) (string, error)
+}
+
+// NewTxnIDGenerator returns the generator for a format with default settings:
+// Snowflake IDs use node 0 and trace IDs fall back to UUIDv7
+func NewTxnIDGenerator(format TransactionIDFormat, clk clock.Clock) (TxnIDGenerator, error) {
+	switch format {
+	case TransactionIDFormatUUIDv7, "":
+		return UUIDv7TxnIDGenerator{}, nil
+	case TransactionIDFormatUUIDv4:
+		return UUIDv4TxnIDGenerator{}, nil
+	case TransactionIDFormatULID:
+		return &ULIDTxnIDGenerator{Clock: clk}, nil
+	case TransactionIDFormatSnowflake:
+		return NewSnowflakeTxnIDGenerator(0, clk)
+	case TransactionIDFormatTraceID:
+		return &TraceTxnIDGenerator{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported transaction ID format: %s", format)
+	}
+}
+
+// UUIDv7TxnIDGenerator generates time-ordered UUIDv7 transaction IDs
+type UUIDv7TxnIDGenerator struct{}
+
+// NewTxnID implements TxnIDGenerator
+func (UUIDv7TxnIDGenerator) NewTxnID(ctx context.Context, issueCtx *service.IssueContext) (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// UUIDv4TxnIDGenerator generates random UUIDv4 transaction IDs
+type UUIDv4TxnIDGenerator struct{}
+
+// NewTxnID implements TxnIDGenerator
+func (UUIDv4TxnIDGenerator) NewTxnID(ctx context.Context, issueCtx *service.IssueContext) (string, error) {
+	return uuid.NewString(), nil
+}
+
+// crockfordBase32 is the ULID alphabet
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDTxnIDGenerator generates ULIDs: a 48-bit millisecond timestamp and 80
+// random bits, as 26 Crockford base32 characters that sort by time
+type ULIDTxnIDGenerator struct {
+	// Clock is the time source (defaults to system clock)
+	Clock clock.Clock
+}
+
+// NewTxnID implements TxnIDGenerator
+func (g *ULIDTxnIDGenerator) NewTxnID(ctx context.Context, issueCtx *service.IssueContext) (string, error) {
+	clk := g.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	var id [16]byte
+	ms := uint64(clk.Now().UnixMilli())
+	for i := 0; i < 6; i++ {
+		id[i] = byte(ms >> (40 - 8*i))
+	}
+	if _, err := rand.Read(id[6:]); err != nil {
+		return "", err
+	}
+
+	// Encode the 128 bits 5 at a time from the least significant end
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockfordBase32[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:]), nil
+}
+
+const (
+	// snowflakeEpoch is the start of Snowflake timestamps (2024-01-01T00:00:00Z)
+	snowflakeEpoch = 1704067200000
+
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+
+	// MaxSnowflakeNodeID is the largest Snowflake node ID
+	MaxSnowflakeNodeID = 1<<snowflakeNodeBits - 1
+)
+
+// SnowflakeTxnIDGenerator generates 63-bit Snowflake IDs in decimal: a 41-bit
+// millisecond timestamp, a 10-bit node ID and a 12-bit per-millisecond sequence
+//
+// IDs are unique across replicas only if each replica has its own node ID.
+// When the sequence is exhausted, or the clock moves backwards, the timestamp
+// advances logically rather than blocking.
+type SnowflakeTxnIDGenerator struct {
+	nodeID int64
+	clock  clock.Clock
+
+	mu       sync.Mutex
+	lastMS   int64
+	sequence int64
+}
+
+// NewSnowflakeTxnIDGenerator creates a Snowflake generator for a node
+func NewSnowflakeTxnIDGenerator(nodeID int64, clk clock.Clock) (*SnowflakeTxnIDGenerator, error) {
+	if nodeID < 0 || nodeID > MaxSnowflakeNodeID {
+		return nil, fmt.Errorf("snowflake node ID must be between 0 and %d, got %d", MaxSnowflakeNodeID, nodeID)
+	}
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+	return &SnowflakeTxnIDGenerator{nodeID: nodeID, clock: clk}, nil
+}
+
+// NewTxnID implements TxnIDGenerator
+func (g *SnowflakeTxnIDGenerator) NewTxnID(ctx context.Context, issueCtx *service.IssueContext) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := g.clock.Now().UnixMilli() - snowflakeEpoch
+	if ms < 0 {
+		return "", fmt.Errorf("clock is before the snowflake epoch")
+	}
+	switch {
+	case ms > g.lastMS:
+		g.lastMS = ms
+		g.sequence = 0
+	default:
+		g.sequence++
+		if g.sequence >= 1<<snowflakeSequenceBits {
+			g.lastMS++
+			g.sequence = 0
+		}
+	}
+
+	id := g.lastMS<<(snowflakeNodeBits+snowflakeSequenceBits) | g.nodeID<<snowflakeSequenceBits | g.sequence
+	return strconv.FormatInt(id, 10), nil
+}
+
+// TraceTxnIDGenerator uses the trace ID of the incoming request as the
+// transaction ID, so "txn" matches the trace in existing tracing systems
+//
+// The trace ID is read from the W3C traceparent request header, then from the
+// B3 x-b3-traceid header, as 32 lowercase hex characters. Requests without a
+// valid trace ID get an ID from Fallback.
+//
+// "txn" is only as trustworthy as the trace headers: anyone who can set them
+// chooses the transaction ID, and can reuse another transaction's. Exchange
+// takes them from the caller's metadata over request_context, but callers
+// themselves, and the clients behind an ext_authz proxy that does not
+// overwrite trace headers, can still set them.
+type TraceTxnIDGenerator struct {
+	// Fallback generates IDs for requests without a trace ID (defaults to UUIDv7)
+	Fallback TxnIDGenerator
+}
+
+// NewTxnID implements TxnIDGenerator
+func (g *TraceTxnIDGenerator) NewTxnID(ctx context.Context, issueCtx *service.IssueContext) (string, error) {
+	if traceID := incomingTraceID(issueCtx); traceID != "" {
+		return traceID, nil
+	}
+	fallback := g.Fallback
+	if fallback == nil {
+		fallback = UUIDv7TxnIDGenerator{}
+	}
+	return fallback.NewTxnID(ctx, issueCtx)
+}
+
+// incomingTraceID returns the trace ID of the request being authorized, or ""
+func incomingTraceID(issueCtx *service.IssueContext) string {
+	if issueCtx == nil || issueCtx.RequestAttributes == nil {
+		return ""
+	}
+	headers := issueCtx.RequestAttributes.Headers
+
+	// traceparent: version-traceid-parentid-flags
+	if parts := strings.Split(headers["traceparent"], "-"); len(parts) >= 4 {
+		if traceID := parts[1]; validTraceID(traceID) {
+			return traceID
+		}
+	}
+
+	// B3 trace IDs may be 64-bit; pad them to 128 bits as B3 propagation does
+	if traceID := strings.ToLower(headers["x-b3-traceid"]); len(traceID) == 16 || len(traceID) == 32 {
+		traceID = strings.Repeat("0", 32-len(traceID)) + traceID
+		if validTraceID(traceID) {
+			return traceID
+		}
+	}
+	return ""
+}
+
+// validTraceID reports whether id is 32 lowercase hex characters and not all zeros
+func validTraceID(id string) bool {
+	if len(id) != 32 || id == strings.Repeat("0", 32) {
+		return false
+	}
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,210 @@
+package issuer
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+func TestNewTxnIDGenerator(t *testing.T) {
+	for _, format := range []TransactionIDFormat{"", TransactionIDFormatUUIDv7, TransactionIDFormatUUIDv4, TransactionIDFormatULID, TransactionIDFormatSnowflake, TransactionIDFormatTraceID} {
+		t.Run(string(format), func(t *testing.T) {
+			gen, err := NewTxnIDGenerator(format, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			id, err := gen.NewTxnID(context.Background(), &service.IssueContext{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if id == "" {
+				t.Error("expected a transaction ID")
+			}
+		})
+	}
+
+	t.Run("unsupported format", func(t *testing.T) {
+		if _, err := NewTxnIDGenerator("sequential", nil); err == nil {
+			t.Error("expected error for unsupported format")
+		}
+	})
+}
+
+func TestULIDTxnIDGenerator(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	gen := &ULIDTxnIDGenerator{Clock: clk}
+
+	first, err := gen.NewTxnID(ctx, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(first) != 26 {
+		t.Fatalf("expected 26 characters, got %q", first)
+	}
+	for _, c := range first {
+		if !strings.ContainsRune(crockfordBase32, c) {
+			t.Fatalf("unexpected character %q in %q", c, first)
+		}
+	}
+
+	clk.Set(clk.Now().Add(time.Millisecond))
+	second, err := gen.NewTxnID(ctx, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second <= first {
+		t.Errorf("expected later ULID to sort after %q, got %q", first, second)
+	}
+	// The first 10 characters encode the timestamp; a millisecond only changes the last
+	if first[:9] != second[:9] {
+		t.Errorf("expected shared timestamp prefix, got %q and %q", first, second)
+	}
+}
+
+func TestSnowflakeTxnIDGenerator(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("node ID bounds", func(t *testing.T) {
+		if _, err := NewSnowflakeTxnIDGenerator(-1, nil); err == nil {
+			t.Error("expected error for negative node ID")
+		}
+		if _, err := NewSnowflakeTxnIDGenerator(MaxSnowflakeNodeID+1, nil); err == nil {
+			t.Error("expected error for node ID above the maximum")
+		}
+		if _, err := NewSnowflakeTxnIDGenerator(MaxSnowflakeNodeID, nil); err != nil {
+			t.Errorf("unexpected error for maximum node ID: %v", err)
+		}
+	})
+
+	t.Run("IDs increase within a millisecond and across sequence overflow", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+		gen, err := NewSnowflakeTxnIDGenerator(7, clk)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var last int64
+		for i := 0; i < 1<<snowflakeSequenceBits+10; i++ {
+			id, err := gen.NewTxnID(ctx, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			n, err := strconv.ParseInt(id, 10, 64)
+			if err != nil {
+				t.Fatalf("ID is not decimal: %q", id)
+			}
+			if n <= last {
+				t.Fatalf("ID %d did not increase after %d", n, last)
+			}
+			if node := n >> snowflakeSequenceBits & MaxSnowflakeNodeID; node != 7 {
+				t.Fatalf("expected node 7, got %d", node)
+			}
+			last = n
+		}
+	})
+
+	t.Run("clock moving backwards keeps IDs increasing", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+		gen, _ := NewSnowflakeTxnIDGenerator(0, clk)
+
+		first, _ := gen.NewTxnID(ctx, nil)
+		clk.Set(clk.Now().Add(-time.Second))
+		second, _ := gen.NewTxnID(ctx, nil)
+
+		a, _ := strconv.ParseInt(first, 10, 64)
+		b, _ := strconv.ParseInt(second, 10, 64)
+		if b <= a {
+			t.Errorf("expected %d to be greater than %d", b, a)
+		}
+	})
+}
+
+func TestTraceTxnIDGenerator(t *testing.T) {
+	ctx := context.Background()
+	issueCtx := func(headers map[string]string) *service.IssueContext {
+		return &service.IssueContext{RequestAttributes: &request.RequestAttributes{Headers: headers}}
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "traceparent",
+			headers: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			want:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name: "traceparent preferred over B3",
+			headers: map[string]string{
+				"traceparent":  "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				"x-b3-traceid": "80f198ee56343ba864fe8b2a57d3eff7",
+			},
+			want: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:    "128-bit B3 trace ID",
+			headers: map[string]string{"x-b3-traceid": "80F198EE56343BA864FE8B2A57D3EFF7"},
+			want:    "80f198ee56343ba864fe8b2a57d3eff7",
+		},
+		{
+			name:    "64-bit B3 trace ID is padded",
+			headers: map[string]string{"x-b3-traceid": "a3ce929d0e0e4736"},
+			want:    "0000000000000000a3ce929d0e0e4736",
+		},
+		{
+			name: "invalid traceparent falls through to B3",
+			headers: map[string]string{
+				"traceparent":  "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+				"x-b3-traceid": "a3ce929d0e0e4736",
+			},
+			want: "0000000000000000a3ce929d0e0e4736",
+		},
+	}
+
+	gen := &TraceTxnIDGenerator{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gen.NewTxnID(ctx, issueCtx(tt.headers))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("falls back without a trace ID", func(t *testing.T) {
+		for _, ic := range []*service.IssueContext{nil, {}, issueCtx(map[string]string{"traceparent": "garbage"})} {
+			got, err := gen.NewTxnID(ctx, ic)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if id, err := uuid.Parse(got); err != nil || id.Version() != 7 {
+				t.Errorf("expected UUIDv7 fallback, got %q", got)
+			}
+		}
+	})
+
+	t.Run("custom fallback", func(t *testing.T) {
+		gen := &TraceTxnIDGenerator{Fallback: UUIDv4TxnIDGenerator{}}
+		got, err := gen.NewTxnID(ctx, issueCtx(nil))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if uuid.MustParse(got).Version() != 4 {
+			t.Errorf("expected UUIDv4 fallback, got %q", got)
+		}
+	})
+}
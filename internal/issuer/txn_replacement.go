@@ -0,0 +1,67 @@
+package issuer
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v3/jwt"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/perr"
+)
+
+// replacementRefreshedClaims are set afresh on every replacement token, so
+// they are never compared with the replaced token's
+// The audience may only narrow, which parentTransaction enforces.
+var replacementRefreshedClaims = []string{"iat", "nbf", "exp", "jti", "aud"}
+
+// ReplacementPolicy restricts which claims a replacement transaction token may
+// change relative to the token it replaces (draft-ietf-oauth-transaction-tokens
+// token replacement)
+//
+// The replacement always keeps the replaced token's "txn", subject and
+// "tctx" values; the policy gates everything else, e.g. a new "req_ctx"
+// for the next hop or a narrower "purp".
+type ReplacementPolicy struct {
+	// MutableClaims are the top-level claims that may differ from the
+	// replaced token's, e.g. "req_ctx" or "purp"
+	MutableClaims []string
+}
+
+// Check returns an error with an ErrCodeReplacementDenied code if the
+// replacement token changes a claim of the replaced token the policy does not
+// allow to change
+func (p *ReplacementPolicy) Check(replaced claims.Claims, replacement jwt.Token) error {
+	serialized, err := json.Marshal(replacement)
+	if err != nil {
+		return fmt.Errorf("failed to serialize replacement claims: %w", err)
+	}
+	var replacementClaims claims.Claims
+	if err := json.Unmarshal(serialized, &replacementClaims); err != nil {
+		return fmt.Errorf("failed to parse replacement claims: %w", err)
+	}
+
+	var changed []string
+	check := func(name string) {
+		if slices.Contains(replacementRefreshedClaims, name) || slices.Contains(p.MutableClaims, name) || slices.Contains(changed, name) {
+			return
+		}
+		if !reflect.DeepEqual(replaced[name], replacementClaims[name]) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range replaced {
+		check(name)
+	}
+	for name := range replacementClaims {
+		check(name)
+	}
+	if len(changed) > 0 {
+		slices.Sort(changed)
+		return perr.Errorf(perr.ErrCodeReplacementDenied, "replacement transaction token may not change claims: %s", strings.Join(changed, ", "))
+	}
+	return nil
+}
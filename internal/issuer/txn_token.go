@@ -3,6 +3,7 @@ package issuer
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,11 +11,36 @@ import (
 	"github.com/lestrrat-go/jwx/v3/jws"
 	"github.com/lestrrat-go/jwx/v3/jwt"
 
+	"github.com/project-kessel/parsec/internal/claims"
 	"github.com/project-kessel/parsec/internal/clock"
 	"github.com/project-kessel/parsec/internal/keys"
 	"github.com/project-kessel/parsec/internal/service"
 )
 
+// TransactionTokenJWTType is the JWS "typ" header value for transaction tokens
+// (draft-ietf-oauth-transaction-tokens)
+const TransactionTokenJWTType = "txntoken+jwt"
+
+// TransactionIDFormat selects how the "txn" claim is generated
+type TransactionIDFormat string
+
+const (
+	// TransactionIDFormatUUIDv7 generates time-ordered UUIDv7 transaction IDs (default)
+	TransactionIDFormatUUIDv7 TransactionIDFormat = "uuidv7"
+
+	// TransactionIDFormatUUIDv4 generates random UUIDv4 transaction IDs
+	TransactionIDFormatUUIDv4 TransactionIDFormat = "uuidv4"
+
+	// TransactionIDFormatULID generates time-ordered ULIDs
+	TransactionIDFormatULID TransactionIDFormat = "ulid"
+
+	// TransactionIDFormatSnowflake generates time-ordered Snowflake IDs
+	TransactionIDFormatSnowflake TransactionIDFormat = "snowflake"
+
+	// TransactionIDFormatTraceID uses the trace ID of the incoming request
+	TransactionIDFormatTraceID TransactionIDFormat = "trace_id"
+)
+
 // TransactionTokenIssuerConfig is the configuration for creating a transaction token issuer
 type TransactionTokenIssuerConfig struct {
 	// IssuerURL is the issuer URL (iss claim)
@@ -23,28 +49,91 @@ type TransactionTokenIssuerConfig struct {
 	// TTL is the time-to-live for tokens
 	TTL time.Duration
 
+	// TTLPolicy computes the time-to-live per token (optional)
+	// When set, it takes precedence over TTL
+	TTLPolicy TTLPolicy
+
 	// Signer handles key rotation and signing (also provides the signing algorithm)
 	Signer keys.RotatingSigner
 
+	// AudienceSigners sign the tokens of specific audiences instead of Signer (optional)
+	AudienceSigners AudienceSigners
+
 	// TransactionContextMappers build the "tctx" claim
 	TransactionContextMappers []service.ClaimMapper
 
+	// TransactionContextContract declares defaults and required members of
+	// the "tctx" claim (optional)
+	TransactionContextContract *ClaimsContract
+
 	// RequestContextMappers build the "req_ctx" claim
 	RequestContextMappers []service.ClaimMapper
 
+	// AuthorizationDetailsMappers build the "azd" claim
+	AuthorizationDetailsMappers []service.ClaimMapper
+
+	// TransactionIDFormat selects how the "txn" claim is generated (defaults to UUIDv7)
+	TransactionIDFormat TransactionIDFormat
+
+	// TxnIDGenerator generates the "txn" claim (optional)
+	// When set, it takes precedence over TransactionIDFormat
+	TxnIDGenerator TxnIDGenerator
+
+	// JTIGenerator generates the "jti" claim (optional; defaults to random UUIDs)
+	JTIGenerator func() string
+
+	// Purpose is the default "purp" claim when no purpose is requested
+	Purpose string
+
+	// PurposeFromScope uses the requested scope as "purp" when no purpose is requested
+	PurposeFromScope bool
+
+	// SizeBudget bounds the encoded token size, compacting claims when exceeded (optional)
+	SizeBudget *SizeBudget
+
+	// Encrypter encrypts signed tokens as compact JWEs (optional)
+	// The size budget applies to the encrypted token.
+	Encrypter *TokenEncrypter
+
 	// Clock is an optional clock for testing (defaults to system clock)
 	Clock clock.Clock
+
+	// Leeway backdates the token's iat and nbf so that clients whose clocks
+	// run behind don't see a token that is not yet valid
+	Leeway time.Duration
+
+	// NotBeforeSkew backdates nbf by a different window than iat (optional)
+	// When zero, nbf is backdated by Leeway.
+	NotBeforeSkew time.Duration
+
+	// ReplacementPolicy restricts the claims a replacement for one of this
+	// issuer's own tokens may change (optional)
+	// When nil, replacements may change any claim except "txn" and "tctx" values.
+	ReplacementPolicy *ReplacementPolicy
 }
 
 // TransactionTokenIssuer issues signed transaction tokens per draft-ietf-oauth-transaction-tokens.
 // It uses a RotatingSigner for key rotation and signing operations.
 type TransactionTokenIssuer struct {
-	issuerURL                 string
-	ttl                       time.Duration
-	signer                    keys.RotatingSigner
-	transactionContextMappers []service.ClaimMapper
-	requestContextMappers     []service.ClaimMapper
-	clock                     clock.Clock
+	issuerURL                   string
+	ttl                         time.Duration
+	ttlPolicy                   TTLPolicy
+	signer                      keys.RotatingSigner
+	audienceSigners             AudienceSigners
+	transactionContextMappers   []service.ClaimMapper
+	transactionContextContract  *ClaimsContract
+	requestContextMappers       []service.ClaimMapper
+	authorizationDetailsMappers []service.ClaimMapper
+	txnIDGenerator              TxnIDGenerator
+	jtiGenerator                func() string
+	purpose                     string
+	purposeFromScope            bool
+	sizeBudget                  *SizeBudget
+	encrypter                   *TokenEncrypter
+	clock                       clock.Clock
+	leeway                      time.Duration
+	notBeforeSkew               time.Duration
+	replacementPolicy           *ReplacementPolicy
 }
 
 // NewTransactionTokenIssuer creates a new transaction token issuer
@@ -54,13 +143,46 @@ func NewTransactionTokenIssuer(cfg TransactionTokenIssuerConfig) *TransactionTok
 		clk = clock.NewSystemClock()
 	}
 
+	txnIDGenerator := cfg.TxnIDGenerator
+	if txnIDGenerator == nil {
+		generator, err := NewTxnIDGenerator(cfg.TransactionIDFormat, clk)
+		if err != nil {
+			// Surface the misconfiguration when a token is issued
+			generator = failingTxnIDGenerator{err: err}
+		}
+		txnIDGenerator = generator
+	}
+
+	jtiGenerator := cfg.JTIGenerator
+	if jtiGenerator == nil {
+		jtiGenerator = uuid.NewString
+	}
+
+	notBeforeSkew := cfg.NotBeforeSkew
+	if notBeforeSkew == 0 {
+		notBeforeSkew = cfg.Leeway
+	}
+
 	return &TransactionTokenIssuer{
-		issuerURL:                 cfg.IssuerURL,
-		ttl:                       cfg.TTL,
-		signer:                    cfg.Signer,
-		transactionContextMappers: cfg.TransactionContextMappers,
-		requestContextMappers:     cfg.RequestContextMappers,
-		clock:                     clk,
+		issuerURL:                   cfg.IssuerURL,
+		ttl:                         cfg.TTL,
+		ttlPolicy:                   cfg.TTLPolicy,
+		signer:                      cfg.Signer,
+		audienceSigners:             cfg.AudienceSigners,
+		transactionContextMappers:   cfg.TransactionContextMappers,
+		transactionContextContract:  cfg.TransactionContextContract,
+		requestContextMappers:       cfg.RequestContextMappers,
+		authorizationDetailsMappers: cfg.AuthorizationDetailsMappers,
+		txnIDGenerator:              txnIDGenerator,
+		jtiGenerator:                jtiGenerator,
+		purpose:                     cfg.Purpose,
+		purposeFromScope:            cfg.PurposeFromScope,
+		sizeBudget:                  cfg.SizeBudget,
+		encrypter:                   cfg.Encrypter,
+		clock:                       clk,
+		leeway:                      cfg.Leeway,
+		notBeforeSkew:               notBeforeSkew,
+		replacementPolicy:           cfg.ReplacementPolicy,
 	}
 }
 
@@ -79,11 +201,40 @@ func (i *TransactionTokenIssuer) Issue(ctx context.Context, issueCtx *service.Is
 		return nil, fmt.Errorf("failed to map request context: %w", err)
 	}
 
-	now := i.clock.Now()
-	expiresAt := now.Add(i.ttl)
+	// Apply authorization details mappers
+	authorizationDetails, err := issueCtx.ToClaims(ctx, i.authorizationDetailsMappers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map authorization details: %w", err)
+	}
+
+	ttl, err := i.resolveTTL(ctx, issueCtx)
+	if err != nil {
+		return nil, err
+	}
 
-	// Generate UUIDv7 for transaction ID (provides temporal ordering)
-	txnID := uuid.NewString()
+	now := issueCtx.IssueTime(i.clock)
+	expiresAt := now.Add(ttl)
+
+	// A subject validated from one of this issuer's own transaction tokens
+	// continues that transaction: the replacement keeps its "txn", and its
+	// "tctx" values take precedence over freshly mapped ones
+	txnID, parentContext, err := i.parentTransaction(issueCtx)
+	if err != nil {
+		return nil, err
+	}
+	replacing := txnID != ""
+	if txnID == "" {
+		// Generate transaction ID (UUIDv7 by default, which provides temporal ordering)
+		txnID, err = i.txnIDGenerator.NewTxnID(ctx, issueCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate transaction ID: %w", err)
+		}
+	}
+	i.transactionContextContract.ApplyDefaults(transactionContext)
+	transactionContext.Merge(parentContext)
+	if err := i.transactionContextContract.Check("tctx", transactionContext); err != nil {
+		return nil, err
+	}
 
 	// Build JWT token per draft-ietf-oauth-transaction-tokens
 	token := jwt.New()
@@ -95,19 +246,20 @@ func (i *TransactionTokenIssuer) Issue(ctx context.Context, issueCtx *service.Is
 	if err := token.Set(jwt.SubjectKey, issueCtx.Subject.Subject); err != nil {
 		return nil, fmt.Errorf("failed to set subject: %w", err)
 	}
-	if err := token.Set(jwt.AudienceKey, []string{issueCtx.Audience}); err != nil {
+	if err := token.Set(jwt.AudienceKey, issueCtx.TokenAudiences()); err != nil {
 		return nil, fmt.Errorf("failed to set audience: %w", err)
 	}
-	if err := token.Set(jwt.IssuedAtKey, now.Unix()); err != nil {
+	if err := token.Set(jwt.IssuedAtKey, now.Add(-i.leeway).Unix()); err != nil {
 		return nil, fmt.Errorf("failed to set issued at: %w", err)
 	}
 	if err := token.Set(jwt.ExpirationKey, expiresAt.Unix()); err != nil {
 		return nil, fmt.Errorf("failed to set expiration: %w", err)
 	}
-	if err := token.Set(jwt.NotBeforeKey, now.Unix()); err != nil {
+	if err := token.Set(jwt.NotBeforeKey, now.Add(-i.notBeforeSkew).Unix()); err != nil {
 		return nil, fmt.Errorf("failed to set not before: %w", err)
 	}
-	if err := token.Set(jwt.JwtIDKey, uuid.NewString()); err != nil {
+	jti := i.jtiGenerator()
+	if err := token.Set(jwt.JwtIDKey, jti); err != nil {
 		return nil, fmt.Errorf("failed to set JWT ID: %w", err)
 	}
 
@@ -130,6 +282,27 @@ func (i *TransactionTokenIssuer) Issue(ctx context.Context, issueCtx *service.Is
 		}
 	}
 
+	// Authorization details (azd) - fine-grained authorization data for the transaction
+	if len(authorizationDetails) > 0 {
+		if err := token.Set("azd", authorizationDetails); err != nil {
+			return nil, fmt.Errorf("failed to set authorization details: %w", err)
+		}
+	}
+
+	// Purpose (purp) - the intended purpose of the transaction
+	if purpose := i.resolvePurpose(issueCtx); purpose != "" {
+		if err := token.Set("purp", purpose); err != nil {
+			return nil, fmt.Errorf("failed to set purpose: %w", err)
+		}
+	}
+
+	// Actor (act) - records that the actor was permitted to act for the subject
+	if act := issueCtx.Delegation.ActClaim(); act != nil {
+		if err := token.Set("act", act); err != nil {
+			return nil, fmt.Errorf("failed to set actor: %w", err)
+		}
+	}
+
 	// Scope (if provided)
 	if issueCtx.Scope != "" {
 		if err := token.Set("scope", issueCtx.Scope); err != nil {
@@ -137,8 +310,19 @@ func (i *TransactionTokenIssuer) Issue(ctx context.Context, issueCtx *service.Is
 		}
 	}
 
-	// Get the current signer, key ID, and algorithm from the signer
-	signer, keyID, algorithm, err := i.signer.GetCurrentSigner(ctx)
+	// A replacement may only change the claims the replacement policy allows
+	if replacing && i.replacementPolicy != nil {
+		if err := i.replacementPolicy.Check(issueCtx.Subject.Claims, token); err != nil {
+			return nil, err
+		}
+	}
+
+	// Get the current signer, key ID, and algorithm from the signer for the token's audiences
+	rotatingSigner, err := i.audienceSigners.signerFor(issueCtx.TokenAudiences(), i.signer)
+	if err != nil {
+		return nil, err
+	}
+	signer, keyID, algorithm, err := rotatingSigner.GetCurrentSigner(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current signer: %w", err)
 	}
@@ -147,30 +331,99 @@ func (i *TransactionTokenIssuer) Issue(ctx context.Context, issueCtx *service.Is
 		return nil, fmt.Errorf("unsupported signature algorithm: %s", algorithm)
 	}
 
-	// Build JWS headers with the key ID
+	// Build JWS headers with the key ID and the transaction token type
 	headers := jws.NewHeaders()
 	if err := headers.Set(jws.KeyIDKey, string(keyID)); err != nil {
 		return nil, fmt.Errorf("failed to set key ID header: %w", err)
 	}
+	if err := headers.Set(jws.TypeKey, TransactionTokenJWTType); err != nil {
+		return nil, fmt.Errorf("failed to set type header: %w", err)
+	}
 
-	// Sign the token with the current key
-	signedToken, err := jwt.Sign(token,
-		jwt.WithKey(signAlg, signer, jws.WithProtectedHeaders(headers)))
+	// Sign the token with the current key, compacting claims if it is over budget
+	signedToken, compaction, err := i.sizeBudget.Enforce(token, func(t jwt.Token) ([]byte, error) {
+		signed, err := jwt.Sign(t, jwt.WithKey(signAlg, signer, jws.WithProtectedHeaders(headers)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign token: %w", err)
+		}
+		if i.encrypter != nil {
+			return i.encrypter.Encrypt(ctx, signed)
+		}
+		return signed, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to sign token: %w", err)
+		return nil, err
 	}
 
 	return &service.Token{
-		Value:     string(signedToken),
-		Type:      "urn:ietf:params:oauth:token-type:txn_token",
-		ExpiresAt: expiresAt,
-		IssuedAt:  now,
+		Value:         string(signedToken),
+		Type:          "urn:ietf:params:oauth:token-type:txn_token",
+		ExpiresAt:     expiresAt,
+		IssuedAt:      now,
+		ID:            jti,
+		Audience:      issueCtx.TokenAudiences(),
+		Compaction:    compaction,
+		TransactionID: txnID,
 	}, nil
 }
 
+// parentTransaction returns the "txn" and "tctx" of the transaction token the
+// subject was validated from, if a TransactionTokenValidator validated it as
+// one of this issuer's tokens. The replacement token may narrow the parent's
+// audiences but not widen them.
+func (i *TransactionTokenIssuer) parentTransaction(issueCtx *service.IssueContext) (string, claims.Claims, error) {
+	subject := issueCtx.Subject
+	if subject == nil || subject.Transaction == nil || subject.Issuer != i.issuerURL {
+		return "", nil, nil
+	}
+	for _, audience := range issueCtx.TokenAudiences() {
+		if !slices.Contains(subject.Audience, audience) {
+			return "", nil, fmt.Errorf("audience %q is not an audience of the parent transaction token %v", audience, subject.Audience)
+		}
+	}
+	return subject.Transaction.TxnID, subject.Transaction.Context, nil
+}
+
+// failingTxnIDGenerator reports a transaction ID generator that could not be created
+type failingTxnIDGenerator struct {
+	err error
+}
+
+// NewTxnID implements TxnIDGenerator
+func (g failingTxnIDGenerator) NewTxnID(ctx context.Context, issueCtx *service.IssueContext) (string, error) {
+	return "", g.err
+}
+
+// resolvePurpose determines the "purp" claim
+// An explicitly requested purpose wins, then the scope (if enabled), then the configured default
+func (i *TransactionTokenIssuer) resolvePurpose(issueCtx *service.IssueContext) string {
+	if issueCtx.Purpose != "" {
+		return issueCtx.Purpose
+	}
+	if i.purposeFromScope && issueCtx.Scope != "" {
+		return issueCtx.Scope
+	}
+	return i.purpose
+}
+
+// resolveTTL determines the token lifetime
+// A TTL policy, if configured, overrides the static TTL; either is capped by
+// the issuance's risk assessment
+func (i *TransactionTokenIssuer) resolveTTL(ctx context.Context, issueCtx *service.IssueContext) (time.Duration, error) {
+	if i.ttlPolicy == nil {
+		return issueCtx.CapTTL(i.ttl), nil
+	}
+
+	ttl, err := i.ttlPolicy.TTL(ctx, issueCtx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute TTL: %w", err)
+	}
+	return issueCtx.CapTTL(ttl), nil
+}
+
 // PublicKeys implements the Issuer interface
-// Returns all non-expired public keys from the rotating signer
+// Returns all non-expired public keys from the rotating signer and the audience signers
 func (i *TransactionTokenIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
-	// Get all public keys from the rotating signer (already in service.PublicKey format)
-	return i.signer.PublicKeys(ctx)
+	// Get all public keys from the rotating signers (already in service.PublicKey format)
+	return i.audienceSigners.publicKeys(ctx, i.signer)
 }
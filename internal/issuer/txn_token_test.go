@@ -0,0 +1,600 @@
+package issuer
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// newTestSigner creates a started in-memory rotating signer
+func newTestSigner(t testing.TB) keys.RotatingSigner {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	signer := keys.NewDualSlotRotatingSigner(keys.DualSlotRotatingSignerConfig{
+		Namespace:     string(service.TokenTypeTransactionToken),
+		KeyProviderID: "test-provider",
+		KeyProviderRegistry: map[string]keys.KeyProvider{
+			"test-provider": keys.NewInMemoryKeyProvider(keys.KeyTypeECP256, "ES256"),
+		},
+		SlotStore: keys.NewInMemoryKeySlotStore(),
+	})
+	if err := signer.Start(ctx); err != nil {
+		t.Fatalf("failed to start signer: %v", err)
+	}
+	t.Cleanup(signer.Stop)
+	return signer
+}
+
+// parseUnverified parses a signed JWT without verifying its signature
+func parseUnverified(t *testing.T, value string) jwt.Token {
+	t.Helper()
+	token, err := jwt.ParseInsecure([]byte(value))
+	if err != nil {
+		t.Fatalf("failed to parse token: %v", err)
+	}
+	return token
+}
+
+func TestTransactionTokenIssuer_StandardClaims(t *testing.T) {
+	ctx := context.Background()
+	signer := newTestSigner(t)
+
+	issueCtx := &service.IssueContext{
+		Subject:            &trust.Result{Subject: "alice"},
+		Audience:           "parsec.test",
+		Scope:              "orders.read",
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	}
+
+	t.Run("txn is a UUIDv7 by default", func(t *testing.T) {
+		iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL: "https://parsec.test",
+			TTL:       time.Minute,
+			Signer:    signer,
+		})
+
+		token, err := iss.Issue(ctx, issueCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var txn string
+		if err := parseUnverified(t, token.Value).Get("txn", &txn); err != nil {
+			t.Fatalf("expected txn claim: %v", err)
+		}
+		id, err := uuid.Parse(txn)
+		if err != nil {
+			t.Fatalf("txn is not a UUID: %v", err)
+		}
+		if id.Version() != 7 {
+			t.Errorf("expected UUIDv7, got version %d", id.Version())
+		}
+		if token.TransactionID != txn {
+			t.Errorf("expected the token to report txn %s, got %q", txn, token.TransactionID)
+		}
+	})
+
+	t.Run("typ header marks a transaction token", func(t *testing.T) {
+		iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL: "https://parsec.test",
+			TTL:       time.Minute,
+			Signer:    signer,
+		})
+
+		token, err := iss.Issue(ctx, issueCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		msg, err := jws.Parse([]byte(token.Value))
+		if err != nil {
+			t.Fatalf("failed to parse JWS: %v", err)
+		}
+		if typ, _ := msg.Signatures()[0].ProtectedHeaders().Type(); typ != TransactionTokenJWTType {
+			t.Errorf("expected typ %s, got %s", TransactionTokenJWTType, typ)
+		}
+	})
+
+	t.Run("leeway backdates iat and nbf but not exp", func(t *testing.T) {
+		iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL: "https://parsec.test",
+			TTL:       time.Minute,
+			Signer:    signer,
+			Leeway:    30 * time.Second,
+		})
+
+		issuedAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		ic := *issueCtx
+		ic.IssuedAt = issuedAt
+		token, err := iss.Issue(ctx, &ic)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		parsed := parseUnverified(t, token.Value)
+		iat, _ := parsed.IssuedAt()
+		nbf, _ := parsed.NotBefore()
+		exp, _ := parsed.Expiration()
+		backdated := issuedAt.Add(-30 * time.Second)
+		if !iat.Equal(backdated) || !nbf.Equal(backdated) {
+			t.Errorf("expected iat and nbf %v, got %v and %v", backdated, iat, nbf)
+		}
+		if !exp.Equal(issuedAt.Add(time.Minute)) {
+			t.Errorf("expected exp one TTL after issuance, got %v", exp)
+		}
+	})
+
+	t.Run("nbf can be backdated by its own skew", func(t *testing.T) {
+		iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL:     "https://parsec.test",
+			TTL:           time.Minute,
+			Signer:        signer,
+			Leeway:        5 * time.Second,
+			NotBeforeSkew: 30 * time.Second,
+		})
+
+		issuedAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		ic := *issueCtx
+		ic.IssuedAt = issuedAt
+		token, err := iss.Issue(ctx, &ic)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		parsed := parseUnverified(t, token.Value)
+		iat, _ := parsed.IssuedAt()
+		nbf, _ := parsed.NotBefore()
+		if !iat.Equal(issuedAt.Add(-5*time.Second)) || !nbf.Equal(issuedAt.Add(-30*time.Second)) {
+			t.Errorf("expected iat backdated 5s and nbf 30s, got %v and %v", iat, nbf)
+		}
+	})
+
+	t.Run("txn can be a UUIDv4", func(t *testing.T) {
+		iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL:           "https://parsec.test",
+			TTL:                 time.Minute,
+			Signer:              signer,
+			TransactionIDFormat: TransactionIDFormatUUIDv4,
+		})
+
+		token, err := iss.Issue(ctx, issueCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var txn string
+		if err := parseUnverified(t, token.Value).Get("txn", &txn); err != nil {
+			t.Fatalf("expected txn claim: %v", err)
+		}
+		if uuid.MustParse(txn).Version() != 4 {
+			t.Errorf("expected UUIDv4, got %s", txn)
+		}
+	})
+
+	t.Run("txn from a configured generator", func(t *testing.T) {
+		iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL:      "https://parsec.test",
+			TTL:            time.Minute,
+			Signer:         signer,
+			TxnIDGenerator: &TraceTxnIDGenerator{},
+		})
+
+		ic := *issueCtx
+		ic.RequestAttributes = &request.RequestAttributes{
+			Headers: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		}
+		token, err := iss.Issue(ctx, &ic)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var txn string
+		if err := parseUnverified(t, token.Value).Get("txn", &txn); err != nil {
+			t.Fatalf("expected txn claim: %v", err)
+		}
+		if txn != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("expected trace ID as txn, got %s", txn)
+		}
+	})
+
+	t.Run("unsupported txn format fails issuance", func(t *testing.T) {
+		iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL:           "https://parsec.test",
+			TTL:                 time.Minute,
+			Signer:              signer,
+			TransactionIDFormat: "sequential",
+		})
+
+		if _, err := iss.Issue(ctx, issueCtx); err == nil {
+			t.Error("expected error for unsupported transaction ID format")
+		}
+	})
+
+	t.Run("purp precedence", func(t *testing.T) {
+		iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL:        "https://parsec.test",
+			TTL:              time.Minute,
+			Signer:           signer,
+			Purpose:          "default-purpose",
+			PurposeFromScope: true,
+		})
+
+		tests := []struct {
+			name    string
+			purpose string
+			scope   string
+			want    string
+		}{
+			{name: "requested purpose wins", purpose: "checkout", scope: "orders.read", want: "checkout"},
+			{name: "scope used when no purpose requested", scope: "orders.read", want: "orders.read"},
+			{name: "default used otherwise", want: "default-purpose"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ic := *issueCtx
+				ic.Purpose = tt.purpose
+				ic.Scope = tt.scope
+
+				token, err := iss.Issue(ctx, &ic)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				var purp string
+				if err := parseUnverified(t, token.Value).Get("purp", &purp); err != nil {
+					t.Fatalf("expected purp claim: %v", err)
+				}
+				if purp != tt.want {
+					t.Errorf("expected purp %q, got %q", tt.want, purp)
+				}
+			})
+		}
+	})
+
+	t.Run("azd built from authorization details mappers", func(t *testing.T) {
+		iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL: "https://parsec.test",
+			TTL:       time.Minute,
+			Signer:    signer,
+			AuthorizationDetailsMappers: []service.ClaimMapper{
+				service.NewStubClaimMapper(claims.Claims{"type": "order", "actions": []any{"read"}}),
+			},
+		})
+
+		token, err := iss.Issue(ctx, issueCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var azd map[string]any
+		if err := parseUnverified(t, token.Value).Get("azd", &azd); err != nil {
+			t.Fatalf("expected azd claim: %v", err)
+		}
+		if azd["type"] != "order" {
+			t.Errorf("expected azd.type order, got %v", azd["type"])
+		}
+	})
+
+	t.Run("act claim reflects delegation", func(t *testing.T) {
+		iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL: "https://parsec.test",
+			TTL:       time.Minute,
+			Signer:    signer,
+		})
+
+		ic := *issueCtx
+		ic.Delegation = &trust.Delegation{Actor: &trust.Result{Subject: "gateway"}}
+
+		token, err := iss.Issue(ctx, &ic)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var act map[string]any
+		if err := parseUnverified(t, token.Value).Get("act", &act); err != nil {
+			t.Fatalf("expected act claim: %v", err)
+		}
+		if act["sub"] != "gateway" {
+			t.Errorf("expected act.sub gateway, got %v", act["sub"])
+		}
+	})
+	t.Run("chained exchange keeps txn and tctx", func(t *testing.T) {
+		iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL: "https://parsec.test",
+			TTL:       time.Minute,
+			Signer:    signer,
+			TransactionContextMappers: []service.ClaimMapper{
+				service.NewStubClaimMapper(claims.Claims{"tenant": "acme"}),
+			},
+		})
+
+		original, err := iss.Issue(ctx, issueCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		validator, err := trust.NewTransactionTokenValidator(trust.TransactionTokenValidatorConfig{
+			Issuer:      "https://parsec.test",
+			TrustDomain: "parsec.test",
+			Audiences:   []string{"parsec.test"},
+			Keys:        signerKeySource{signer},
+		})
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+		subject, err := validator.Validate(ctx, &trust.BearerCredential{Token: original.Value})
+		if err != nil {
+			t.Fatalf("expected parsec's own token to validate: %v", err)
+		}
+
+		// Freshly mapped context must not override the original transaction's
+		chained := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL: "https://parsec.test",
+			TTL:       time.Minute,
+			Signer:    signer,
+			TransactionContextMappers: []service.ClaimMapper{
+				service.NewStubClaimMapper(claims.Claims{"tenant": "other", "hop": "orders"}),
+			},
+		})
+		ic := *issueCtx
+		ic.Subject = subject
+		replacement, err := chained.Issue(ctx, &ic)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var originalTxn, replacementTxn string
+		_ = parseUnverified(t, original.Value).Get("txn", &originalTxn)
+		_ = parseUnverified(t, replacement.Value).Get("txn", &replacementTxn)
+		if replacementTxn != originalTxn {
+			t.Errorf("expected txn %s to be preserved, got %s", originalTxn, replacementTxn)
+		}
+
+		var tctx map[string]any
+		if err := parseUnverified(t, replacement.Value).Get("tctx", &tctx); err != nil {
+			t.Fatalf("expected tctx claim: %v", err)
+		}
+		if tctx["tenant"] != "acme" || tctx["hop"] != "orders" {
+			t.Errorf("expected original tenant and new hop in tctx, got %v", tctx)
+		}
+		if sub, _ := parseUnverified(t, replacement.Value).Subject(); sub != "alice" {
+			t.Errorf("expected subject alice, got %q", sub)
+		}
+	})
+
+	t.Run("chained exchange cannot widen the audience", func(t *testing.T) {
+		iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL: "https://parsec.test",
+			TTL:       time.Minute,
+			Signer:    signer,
+		})
+
+		ic := *issueCtx
+		ic.Subject = &trust.Result{
+			Subject:     "alice",
+			Issuer:      "https://parsec.test",
+			Audience:    []string{"orders.parsec.test"},
+			Transaction: &trust.TransactionLineage{TxnID: "parent"},
+		}
+		ic.Audience = "billing.parsec.test"
+		if _, err := iss.Issue(ctx, &ic); err == nil {
+			t.Error("expected a replacement for another audience to be rejected")
+		}
+
+		ic.Audience = "orders.parsec.test"
+		if _, err := iss.Issue(ctx, &ic); err != nil {
+			t.Errorf("expected a replacement for the parent's audience, got %v", err)
+		}
+	})
+
+	t.Run("replacement policy gates changed claims", func(t *testing.T) {
+		original, err := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL: "https://parsec.test",
+			TTL:       time.Minute,
+			Signer:    signer,
+			Purpose:   "checkout",
+			RequestContextMappers: []service.ClaimMapper{
+				service.NewStubClaimMapper(claims.Claims{"hop": "gateway"}),
+			},
+		}).Issue(ctx, issueCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		validator, err := trust.NewTransactionTokenValidator(trust.TransactionTokenValidatorConfig{
+			Issuer:      "https://parsec.test",
+			TrustDomain: "parsec.test",
+			Audiences:   []string{"parsec.test"},
+			Keys:        signerKeySource{signer},
+		})
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+		subject, err := validator.Validate(ctx, &trust.BearerCredential{Token: original.Value})
+		if err != nil {
+			t.Fatalf("expected parsec's own token to validate: %v", err)
+		}
+
+		replace := func(purpose string, mutable ...string) (*service.Token, error) {
+			iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+				IssuerURL: "https://parsec.test",
+				TTL:       time.Minute,
+				Signer:    signer,
+				Purpose:   purpose,
+				RequestContextMappers: []service.ClaimMapper{
+					service.NewStubClaimMapper(claims.Claims{"hop": "orders"}),
+				},
+				ReplacementPolicy: &ReplacementPolicy{MutableClaims: mutable},
+			})
+			ic := *issueCtx
+			ic.Subject = subject
+			return iss.Issue(ctx, &ic)
+		}
+
+		replacement, err := replace("checkout", "req_ctx")
+		if err != nil {
+			t.Fatalf("expected the request context to be replaceable: %v", err)
+		}
+		var reqCtx map[string]any
+		if err := parseUnverified(t, replacement.Value).Get("req_ctx", &reqCtx); err != nil || reqCtx["hop"] != "orders" {
+			t.Errorf("expected the updated request context, got %v (%v)", reqCtx, err)
+		}
+		if replacement.TransactionID != original.TransactionID {
+			t.Errorf("expected txn %s to be preserved, got %s", original.TransactionID, replacement.TransactionID)
+		}
+
+		if _, err := replace("checkout"); !perr.HasCode(err, perr.ErrCodeReplacementDenied) || !strings.Contains(err.Error(), "req_ctx") {
+			t.Errorf("expected a changed req_ctx to be denied, got %v", err)
+		}
+		if _, err := replace("refund", "req_ctx"); !perr.HasCode(err, perr.ErrCodeReplacementDenied) || !strings.Contains(err.Error(), "purp") {
+			t.Errorf("expected a changed purp to be denied, got %v", err)
+		}
+	})
+
+	t.Run("aud lists every requested audience", func(t *testing.T) {
+		iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL: "https://parsec.test",
+			TTL:       time.Minute,
+			Signer:    signer,
+		})
+
+		ic := *issueCtx
+		ic.Audiences = []string{"orders.parsec.test", "billing.parsec.test"}
+		token, err := iss.Issue(ctx, &ic)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if aud, _ := parseUnverified(t, token.Value).Audience(); !slices.Equal(aud, ic.Audiences) {
+			t.Errorf("expected aud %v, got %v", ic.Audiences, aud)
+		}
+
+		// A replacement may keep only audiences of its parent
+		ic.Subject = &trust.Result{
+			Subject:     "alice",
+			Issuer:      "https://parsec.test",
+			Audience:    []string{"orders.parsec.test"},
+			Transaction: &trust.TransactionLineage{TxnID: "parent"},
+		}
+		if _, err := iss.Issue(ctx, &ic); err == nil || !strings.Contains(err.Error(), "billing.parsec.test") {
+			t.Errorf("expected the audience outside the parent's to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("lineage requires the transaction token validator", func(t *testing.T) {
+		iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL: "https://parsec.test",
+			TTL:       time.Minute,
+			Signer:    signer,
+		})
+
+		// Another validator reporting parsec's issuer URL is not trusted for lineage
+		ic := *issueCtx
+		ic.Subject = &trust.Result{
+			Subject: "alice",
+			Issuer:  "https://parsec.test",
+			Claims:  claims.Claims{"txn": "forged", "tctx": map[string]any{"tenant": "forged"}},
+		}
+		token, err := iss.Issue(ctx, &ic)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var txn string
+		_ = parseUnverified(t, token.Value).Get("txn", &txn)
+		if txn == "forged" {
+			t.Error("expected a new txn for a subject not validated as a transaction token")
+		}
+	})
+
+	t.Run("tokens from other issuers start a new transaction", func(t *testing.T) {
+		iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL: "https://parsec.test",
+			TTL:       time.Minute,
+			Signer:    signer,
+		})
+
+		ic := *issueCtx
+		ic.Subject = &trust.Result{
+			Subject: "alice",
+			Issuer:  "https://idp.example.com",
+			Claims:  claims.Claims{"txn": "not-ours"},
+		}
+		token, err := iss.Issue(ctx, &ic)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var txn string
+		_ = parseUnverified(t, token.Value).Get("txn", &txn)
+		if txn == "not-ours" {
+			t.Error("expected a new txn for a foreign token")
+		}
+	})
+}
+
+// signerKeySource serves a rotating signer's public keys as a JWK set
+type signerKeySource struct {
+	signer keys.RotatingSigner
+}
+
+func (s signerKeySource) KeySet(ctx context.Context) (jwk.Set, error) {
+	publicKeys, err := s.signer.PublicKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	set := jwk.NewSet()
+	for _, pk := range publicKeys {
+		key, err := jwk.Import(pk.Key)
+		if err != nil {
+			return nil, err
+		}
+		_ = key.Set(jwk.KeyIDKey, pk.KeyID)
+		alg, _ := jwa.LookupSignatureAlgorithm(pk.Algorithm)
+		_ = key.Set(jwk.AlgorithmKey, alg)
+		if err := set.AddKey(key); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+func BenchmarkTransactionTokenIssuer_Issue(b *testing.B) {
+	iss := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+		IssuerURL:                 "https://parsec.test",
+		TTL:                       5 * time.Minute,
+		Signer:                    newTestSigner(b),
+		TransactionContextMappers: []service.ClaimMapper{service.NewPassthroughSubjectMapper()},
+		RequestContextMappers:     []service.ClaimMapper{service.NewRequestAttributesMapper()},
+	})
+	issueCtx := &service.IssueContext{
+		Subject:            &trust.Result{Subject: "alice", TrustDomain: "example.com"},
+		Audience:           "parsec.test",
+		RequestAttributes:  &request.RequestAttributes{Method: "GET", Path: "/api/resource"},
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	}
+
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := iss.Issue(ctx, issueCtx); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Package txnvectors pins the exact encoding of transaction tokens.
+//
+// A vector is the input of a transaction token (subject, audiences, scope,
+// purpose and a transaction context mapper) and the compact JWT that
+// TransactionTokenIssuer produces for it. Every vector is issued with the
+// same Ed25519 key, clock, txn and jti, and Ed25519 signatures are
+// deterministic, so a vector's token is reproduced byte for byte: any change
+// to claim names, claim values, header fields or their order shows up as a
+// mismatch.
+//
+// After an intentional change to the tokens, regenerate the vectors with
+//
+//	go test ./internal/issuer/txnvectors -update
+//
+// or by calling Regenerate, and review the diff.
+package txnvectors
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/issuer"
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/mapper"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+const (
+	// KeyID is the kid of the key signing every vector
+	KeyID = "parsec-test-vectors"
+
+	// IssuerURL is the iss of every vector
+	IssuerURL = "https://parsec.example.com"
+
+	// TTL is the lifetime of every vector
+	TTL = 5 * time.Minute
+)
+
+// Now is the time every vector is issued at
+var Now = time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+
+// Vector is the input of a transaction token and the token issued for it
+type Vector struct {
+	Description   string        `json:"description"`
+	Subject       string        `json:"subject"`
+	TrustDomain   string        `json:"trust_domain,omitempty"`
+	SubjectClaims claims.Claims `json:"subject_claims,omitempty"`
+	Audiences     []string      `json:"audiences"`
+	Scope         string        `json:"scope,omitempty"`
+	Purpose       string        `json:"purpose,omitempty"`
+	TxnID         string        `json:"txn"`
+	JTI           string        `json:"jti"`
+
+	// TransactionContext is a CEL script building the tctx claim (optional)
+	TransactionContext string `json:"transaction_context,omitempty"`
+
+	// Token is the compact JWT issued for the vector
+	Token string `json:"token"`
+}
+
+// Key returns the Ed25519 key signing every vector
+// It is derived from a fixed seed: never trust tokens it signs.
+func Key() ed25519.PrivateKey {
+	seed := sha256.Sum256([]byte("parsec transaction token test vectors"))
+	return ed25519.NewKeyFromSeed(seed[:])
+}
+
+// Issue issues the transaction token of a vector
+func Issue(ctx context.Context, v Vector) (string, error) {
+	cfg := issuer.TransactionTokenIssuerConfig{
+		IssuerURL:      IssuerURL,
+		TTL:            TTL,
+		Signer:         fixedSigner{key: Key()},
+		TxnIDGenerator: fixedTxnID(v.TxnID),
+		JTIGenerator:   func() string { return v.JTI },
+		Clock:          clock.NewFixtureClock(Now),
+	}
+	if v.TransactionContext != "" {
+		m, err := mapper.NewCELMapper(v.TransactionContext)
+		if err != nil {
+			return "", fmt.Errorf("invalid transaction_context: %w", err)
+		}
+		cfg.TransactionContextMappers = []service.ClaimMapper{m}
+	}
+
+	audience := ""
+	if len(v.Audiences) > 0 {
+		audience = v.Audiences[0]
+	}
+	token, err := issuer.NewTransactionTokenIssuer(cfg).Issue(ctx, &service.IssueContext{
+		Subject: &trust.Result{
+			Subject:     v.Subject,
+			TrustDomain: v.TrustDomain,
+			Claims:      v.SubjectClaims,
+		},
+		Audience:           audience,
+		Audiences:          v.Audiences,
+		Scope:              v.Scope,
+		Purpose:            v.Purpose,
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token.Value, nil
+}
+
+// Load reads the vectors of the JSON files in dir, by file name without
+// extension
+func Load(dir string) (map[string]Vector, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	vectors := make(map[string]Vector, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var v Vector
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("invalid vector %s: %w", path, err)
+		}
+		vectors[strings.TrimSuffix(filepath.Base(path), ".json")] = v
+	}
+	return vectors, nil
+}
+
+// Regenerate reissues the vectors in dir and rewrites their tokens
+func Regenerate(ctx context.Context, dir string) error {
+	vectors, err := Load(dir)
+	if err != nil {
+		return err
+	}
+	for name, v := range vectors {
+		if v.Token, err = Issue(ctx, v); err != nil {
+			return fmt.Errorf("failed to issue vector %s: %w", name, err)
+		}
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name+".json"), append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fixedSigner signs with one key that never rotates
+type fixedSigner struct {
+	key ed25519.PrivateKey
+}
+
+func (s fixedSigner) GetCurrentSigner(ctx context.Context) (crypto.Signer, keys.KeyID, keys.Algorithm, error) {
+	return s.key, KeyID, "EdDSA", nil
+}
+
+func (s fixedSigner) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	return []service.PublicKey{{KeyID: KeyID, Algorithm: "EdDSA", Key: s.key.Public(), Use: "sig"}}, nil
+}
+
+func (s fixedSigner) Start(ctx context.Context) error { return nil }
+func (s fixedSigner) Stop()                           {}
+
+// fixedTxnID generates the same transaction ID for every token
+type fixedTxnID string
+
+func (id fixedTxnID) NewTxnID(ctx context.Context, issueCtx *service.IssueContext) (string, error) {
+	return string(id), nil
+}
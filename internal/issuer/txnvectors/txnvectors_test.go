@@ -0,0 +1,56 @@
+package txnvectors
+
+import (
+	"context"
+	"flag"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jws"
+)
+
+var update = flag.Bool("update", false, "regenerate the vectors in testdata")
+
+func TestVectors(t *testing.T) {
+	ctx := context.Background()
+	if *update {
+		if err := Regenerate(ctx, "testdata"); err != nil {
+			t.Fatalf("Regenerate failed: %v", err)
+		}
+	}
+
+	vectors, err := Load("testdata")
+	if err != nil || len(vectors) == 0 {
+		t.Fatalf("no vectors found: %v", err)
+	}
+	for name, v := range vectors {
+		t.Run(name, func(t *testing.T) {
+			token, err := Issue(ctx, v)
+			if err != nil {
+				t.Fatalf("Issue failed: %v", err)
+			}
+			if token != v.Token {
+				t.Errorf("token differs from the vector (regenerate with -update if intended)\n got: %s\nwant: %s", token, v.Token)
+			}
+			if _, err := jws.Verify([]byte(v.Token), jws.WithKey(jwa.EdDSA(), Key().Public())); err != nil {
+				t.Errorf("vector token does not verify with the vector key: %v", err)
+			}
+		})
+	}
+
+	t.Run("issuing is deterministic", func(t *testing.T) {
+		for _, v := range vectors {
+			first, err := Issue(ctx, v)
+			if err != nil {
+				t.Fatalf("Issue failed: %v", err)
+			}
+			second, err := Issue(ctx, v)
+			if err != nil {
+				t.Fatalf("Issue failed: %v", err)
+			}
+			if first != second {
+				t.Errorf("tokens differ between issuances:\n%s\n%s", first, second)
+			}
+		}
+	})
+}
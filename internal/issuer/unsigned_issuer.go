@@ -2,8 +2,6 @@ package issuer
 
 import (
 	"context"
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"time"
 
@@ -57,14 +55,13 @@ func (i *UnsignedIssuer) Issue(ctx context.Context, issueCtx *service.IssueConte
 		return nil, fmt.Errorf("failed to map claims: %w", err)
 	}
 
-	// Serialize mapped claims to JSON
-	claimsJSON, err := json.Marshal(mappedClaims)
-	if err != nil {
+	// Serialize mapped claims to base64-encoded JSON
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := appendBase64JSON(buf, mappedClaims); err != nil {
 		return nil, fmt.Errorf("failed to marshal claims: %w", err)
 	}
-
-	// Base64-encode the JSON
-	encodedToken := base64.StdEncoding.EncodeToString(claimsJSON)
+	encodedToken := buf.String()
 
 	// Use a far-future expiration time to indicate the token never expires
 	neverExpires := never
@@ -73,7 +70,7 @@ func (i *UnsignedIssuer) Issue(ctx context.Context, issueCtx *service.IssueConte
 		Value:     encodedToken,
 		Type:      i.tokenType,
 		ExpiresAt: neverExpires,
-		IssuedAt:  i.clock.Now(),
+		IssuedAt:  issueCtx.IssueTime(i.clock),
 	}, nil
 }
 
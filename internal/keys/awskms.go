@@ -77,6 +77,11 @@ func NewAWSKMSKeyProvider(ctx context.Context, cfg AWSKMSConfig) (*AWSKMSKeyProv
 	}, nil
 }
 
+// Algorithm implements AlgorithmReporter
+func (m *AWSKMSKeyProvider) Algorithm() Algorithm {
+	return Algorithm(m.algorithm)
+}
+
 func (m *AWSKMSKeyProvider) GetKeyHandle(ctx context.Context, trustDomain, namespace, keyName string) (KeyHandle, error) {
 	return &awsKeyHandle{
 		manager:     m,
@@ -5,13 +5,16 @@ import (
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
+	"crypto/hkdf"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -22,12 +25,26 @@ import (
 
 // DiskKeyProvider is a KeyProvider that stores keys on disk as JSON files.
 // It's suitable for single-pod Kubernetes deployments with ReadWriteOnce persistent volumes.
+//
+// Every rotation is listed in a human-readable manifest (manifest.json) in the
+// keys directory. With a seed, keys are derived from the seed and the key's
+// trust domain, namespace, name and generation rather than generated randomly,
+// so local environments get the same keys and kids after their keys directory
+// is wiped, without KMS access.
+//
+// With an encryption key, private keys are encrypted at rest with AES-256-GCM
+// and key files are authenticated with an HMAC keyed by it. Key files that are
+// not encrypted carry a checksum instead, and key files readable by group or
+// others are refused.
 type DiskKeyProvider struct {
 	mu        sync.RWMutex
 	keyType   KeyType       // The key type this provider creates
 	algorithm string        // The signing algorithm to use
 	keysPath  string        // Directory path for storing key files
 	fs        fs.FileSystem // Filesystem abstraction for operations
+	seed      []byte        // Seed keys are derived from (optional)
+	kek       []byte        // Key encrypting private keys at rest (optional)
+	migrate   bool          // Rewrite outdated key files when they are loaded
 }
 
 // DiskKeyProviderConfig configures the disk key provider
@@ -43,6 +60,22 @@ type DiskKeyProviderConfig struct {
 
 	// FileSystem is an optional filesystem abstraction (defaults to OSFileSystem)
 	FileSystem fs.FileSystem
+
+	// Seed derives keys deterministically instead of generating them randomly
+	// (optional; EC key types only). Keys derived from a seed are only as
+	// secret as the seed: use it for local development, never in production.
+	Seed string
+
+	// EncryptionKey encrypts private keys at rest (optional; 32 bytes for
+	// AES-256-GCM). Once it is set, key files that are not encrypted are
+	// refused unless MigrateKeyFiles is set.
+	EncryptionKey []byte
+
+	// MigrateKeyFiles rewrites key files written by earlier versions when they
+	// are loaded: unencrypted key files are encrypted with EncryptionKey, and
+	// key files without a checksum get one. Set it for one deployment to
+	// migrate a keys directory, then unset it.
+	MigrateKeyFiles bool
 }
 
 // keyFileData represents the JSON structure stored on disk
@@ -50,8 +83,11 @@ type keyFileData struct {
 	ID         string    `json:"id"`
 	Algorithm  string    `json:"algorithm"`
 	KeyType    string    `json:"key_type"`
-	PrivateKey string    `json:"private_key"` // Base64-encoded DER format
+	PrivateKey string    `json:"private_key"` // Base64-encoded DER format, or nonce and ciphertext when encrypted
 	CreatedAt  time.Time `json:"created_at"`
+	Generation int       `json:"generation,omitempty"` // Rotations of the key so far
+	Encryption string    `json:"encryption,omitempty"` // Encryption of the private key ("" if not encrypted)
+	Checksum   string    `json:"checksum,omitempty"`   // HMAC of an encrypted key file, or SHA-256 of a key that is not encrypted
 }
 
 // NewDiskKeyProvider creates a new disk-based key provider
@@ -85,6 +121,14 @@ func NewDiskKeyProvider(cfg DiskKeyProviderConfig) (*DiskKeyProvider, error) {
 		}
 	}
 
+	if cfg.Seed != "" && cfg.KeyType != KeyTypeECP256 && cfg.KeyType != KeyTypeECP384 {
+		return nil, fmt.Errorf("seed requires an EC key type, got %s", cfg.KeyType)
+	}
+
+	if cfg.EncryptionKey != nil && len(cfg.EncryptionKey) != encryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", encryptionKeySize, len(cfg.EncryptionKey))
+	}
+
 	// Default to OS filesystem if not provided
 	filesystem := cfg.FileSystem
 	if filesystem == nil {
@@ -101,9 +145,17 @@ func NewDiskKeyProvider(cfg DiskKeyProviderConfig) (*DiskKeyProvider, error) {
 		algorithm: algorithm,
 		keysPath:  cfg.KeysPath,
 		fs:        filesystem,
+		seed:      []byte(cfg.Seed),
+		kek:       cfg.EncryptionKey,
+		migrate:   cfg.MigrateKeyFiles,
 	}, nil
 }
 
+// Algorithm implements AlgorithmReporter
+func (m *DiskKeyProvider) Algorithm() Algorithm {
+	return Algorithm(m.algorithm)
+}
+
 func (m *DiskKeyProvider) GetKeyHandle(ctx context.Context, trustDomain, namespace, keyName string) (KeyHandle, error) {
 	return &diskKeyHandle{
 		manager:     m,
@@ -117,45 +169,42 @@ func (m *DiskKeyProvider) rotateKey(trustDomain, namespace, keyName string) erro
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Generate new key based on configured keyType
+	// The generation continues from the key being replaced, if any
+	generation := 1
+	if previous, err := m.readKeyFile(trustDomain, namespace, keyName); err == nil {
+		generation = previous.Generation + 1
+	}
+
 	var signer crypto.Signer
+	var kid string
 	var err error
-
-	switch m.keyType {
-	case KeyTypeECP256:
-		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
-	case KeyTypeECP384:
-		signer, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
-	case KeyTypeRSA2048:
-		signer, err = rsa.GenerateKey(rand.Reader, 2048)
-	case KeyTypeRSA4096:
-		signer, err = rsa.GenerateKey(rand.Reader, 4096)
-	default:
-		return fmt.Errorf("unsupported key type: %s", m.keyType)
+	if len(m.seed) > 0 {
+		signer, kid, err = m.deriveKey(trustDomain, namespace, keyName, generation)
+	} else {
+		signer, err = m.generateKey()
+		// Generate a unique kid using UUID
+		kid = uuid.New().String()
 	}
 	if err != nil {
 		return fmt.Errorf("failed to generate key: %w", err)
 	}
 
-	// Generate a unique kid using UUID
-	kid := uuid.New().String()
-
 	// Marshal private key to PKCS8 DER format
 	privateKeyDER, err := x509.MarshalPKCS8PrivateKey(signer)
 	if err != nil {
 		return fmt.Errorf("failed to marshal private key: %w", err)
 	}
 
-	// Encode to base64
-	privateKeyB64 := base64.StdEncoding.EncodeToString(privateKeyDER)
-
 	// Create key file data
 	data := keyFileData{
 		ID:         kid,
 		Algorithm:  m.algorithm,
 		KeyType:    string(m.keyType),
-		PrivateKey: privateKeyB64,
 		CreatedAt:  time.Now().UTC(),
+		Generation: generation,
+	}
+	if err := m.sealPrivateKey(trustDomain, namespace, keyName, &data, privateKeyDER); err != nil {
+		return fmt.Errorf("failed to seal private key: %w", err)
 	}
 
 	// Write to disk atomically
@@ -163,10 +212,101 @@ func (m *DiskKeyProvider) rotateKey(trustDomain, namespace, keyName string) erro
 		return fmt.Errorf("failed to write key file: %w", err)
 	}
 
+	if err := m.recordInManifest(trustDomain, namespace, keyName, &data); err != nil {
+		return fmt.Errorf("failed to update key manifest: %w", err)
+	}
+
 	return nil
 }
 
+// generateKey generates a random key of the configured keyType
+func (m *DiskKeyProvider) generateKey() (crypto.Signer, error) {
+	switch m.keyType {
+	case KeyTypeECP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeECP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyTypeRSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case KeyTypeRSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", m.keyType)
+	}
+}
+
+// deriveKey derives the EC key of a key generation from the seed
+// The kid is the key's RFC 7638 thumbprint, so equal kids mean equal keys
+// even across environments using different seeds.
+func (m *DiskKeyProvider) deriveKey(trustDomain, namespace, keyName string, generation int) (crypto.Signer, string, error) {
+	var curve elliptic.Curve
+	switch m.keyType {
+	case KeyTypeECP256:
+		curve = elliptic.P256()
+	case KeyTypeECP384:
+		curve = elliptic.P384()
+	default:
+		return nil, "", fmt.Errorf("seed requires an EC key type, got %s", m.keyType)
+	}
+	size := (curve.Params().BitSize + 7) / 8
+
+	// A derived scalar outside the curve's order is rejected; the next
+	// counter derives another, which is needed with negligible probability
+	for counter := 0; counter < 100; counter++ {
+		info := fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%d\x00%d", m.keyType, trustDomain, namespace, keyName, generation, counter)
+		scalar, err := hkdf.Key(sha256.New, m.seed, []byte("parsec disk key provider"), info, size)
+		if err != nil {
+			return nil, "", err
+		}
+		key, err := ecdsa.ParseRawPrivateKey(curve, scalar)
+		if err != nil {
+			continue
+		}
+		kid, err := ComputeThumbprint(key.Public())
+		if err != nil {
+			return nil, "", err
+		}
+		return key, kid, nil
+	}
+	return nil, "", fmt.Errorf("failed to derive a valid key")
+}
+
 func (m *DiskKeyProvider) loadKey(trustDomain, namespace, keyName string) (crypto.Signer, string, string, error) {
+	signer, id, algorithm, err := m.readKey(trustDomain, namespace, keyName)
+	if errors.Is(err, errOutdatedKeyFile) && m.migrate {
+		if err := m.migrateKeyFile(trustDomain, namespace, keyName); err != nil {
+			return nil, "", "", fmt.Errorf("failed to migrate key file: %w", err)
+		}
+		return m.readKey(trustDomain, namespace, keyName)
+	}
+	return signer, id, algorithm, err
+}
+
+// migrateKeyFile rewrites a key file written by an earlier version in the
+// current format
+func (m *DiskKeyProvider) migrateKeyFile(trustDomain, namespace, keyName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := m.readKeyFile(trustDomain, namespace, keyName)
+	if err != nil {
+		return err
+	}
+	if data.Encryption != "" {
+		// Migrated by another caller in the meantime
+		return nil
+	}
+	privateKeyDER, err := openOutdatedPrivateKey(trustDomain, namespace, keyName, data)
+	if err != nil {
+		return err
+	}
+	if err := m.sealPrivateKey(trustDomain, namespace, keyName, data, privateKeyDER); err != nil {
+		return fmt.Errorf("failed to seal private key: %w", err)
+	}
+	return m.writeKeyFile(trustDomain, namespace, keyName, data)
+}
+
+func (m *DiskKeyProvider) readKey(trustDomain, namespace, keyName string) (crypto.Signer, string, string, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -184,10 +324,9 @@ func (m *DiskKeyProvider) loadKey(trustDomain, namespace, keyName string) (crypt
 		return nil, "", "", fmt.Errorf("algorithm mismatch: expected %s, found %s", m.algorithm, data.Algorithm)
 	}
 
-	// Decode base64 private key
-	privateKeyDER, err := base64.StdEncoding.DecodeString(data.PrivateKey)
+	privateKeyDER, err := m.openPrivateKey(trustDomain, namespace, keyName, data)
 	if err != nil {
-		return nil, "", "", fmt.Errorf("failed to decode private key: %w", err)
+		return nil, "", "", err
 	}
 
 	// Parse PKCS8 private key
@@ -242,6 +381,15 @@ func (m *DiskKeyProvider) readKeyFile(trustDomain, namespace, keyName string) (*
 		return nil, fmt.Errorf("failed to read key file: %w", err)
 	}
 
+	// A key others can read may already be compromised
+	mode, err := m.fs.Mode(keyFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat key file: %w", err)
+	}
+	if mode&0077 != 0 {
+		return nil, fmt.Errorf("key file %s is accessible by group or others (mode %04o): restrict it to 0600", keyFilePath, mode)
+	}
+
 	// Unmarshal JSON
 	var data keyFileData
 	if err := json.Unmarshal(jsonData, &data); err != nil {
@@ -251,6 +399,72 @@ func (m *DiskKeyProvider) readKeyFile(trustDomain, namespace, keyName string) (*
 	return &data, nil
 }
 
+// manifestFile is the name of the key manifest in the keys directory
+const manifestFile = "manifest.json"
+
+// keyManifest lists the keys of a DiskKeyProvider for people to read
+// Key files remain the source of truth; the manifest is rewritten on rotation.
+type keyManifest struct {
+	Keys []keyManifestEntry `json:"keys"`
+}
+
+// keyManifestEntry describes the current key of a key name
+type keyManifestEntry struct {
+	TrustDomain string    `json:"trust_domain,omitempty"`
+	Namespace   string    `json:"namespace,omitempty"`
+	KeyName     string    `json:"key_name"`
+	ID          string    `json:"id"`
+	Algorithm   string    `json:"algorithm"`
+	KeyType     string    `json:"key_type"`
+	Generation  int       `json:"generation"`
+	Derived     bool      `json:"derived,omitempty"` // Derived from the seed
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// recordInManifest replaces the manifest entry of a key name with data;
+// m.mu must be held
+func (m *DiskKeyProvider) recordInManifest(trustDomain, namespace, keyName string, data *keyFileData) error {
+	manifestPath := filepath.Join(m.keysPath, manifestFile)
+
+	var manifest keyManifest
+	existing, err := m.fs.ReadFile(manifestPath)
+	if err != nil && !m.fs.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		// A corrupted manifest is rebuilt from this rotation on
+		if err := json.Unmarshal(existing, &manifest); err != nil {
+			manifest = keyManifest{}
+		}
+	}
+
+	entry := keyManifestEntry{
+		TrustDomain: trustDomain,
+		Namespace:   namespace,
+		KeyName:     keyName,
+		ID:          data.ID,
+		Algorithm:   data.Algorithm,
+		KeyType:     data.KeyType,
+		Generation:  data.Generation,
+		Derived:     len(m.seed) > 0,
+		CreatedAt:   data.CreatedAt,
+	}
+	i := slices.IndexFunc(manifest.Keys, func(e keyManifestEntry) bool {
+		return e.TrustDomain == trustDomain && e.Namespace == namespace && e.KeyName == keyName
+	})
+	if i >= 0 {
+		manifest.Keys[i] = entry
+	} else {
+		manifest.Keys = append(manifest.Keys, entry)
+	}
+
+	jsonData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return m.fs.WriteFileAtomic(manifestPath, jsonData, 0600)
+}
+
 // keyFilePath returns the full path to a key file for a given trust domain, namespace, and keyName
 func (m *DiskKeyProvider) keyFilePath(trustDomain, namespace, keyName string) string {
 	// Build path components separately and sanitize each
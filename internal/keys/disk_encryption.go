@@ -0,0 +1,173 @@
+package keys
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// encryptionKeySize is the size of a DiskKeyProvider encryption key (AES-256)
+const encryptionKeySize = 32
+
+// encryptionAES256GCM marks a private key encrypted with AES-256-GCM
+const encryptionAES256GCM = "A256GCM"
+
+// errOutdatedKeyFile marks a key file written by an earlier version, which is
+// only loaded once migrated
+var errOutdatedKeyFile = errors.New("outdated key file")
+
+// keyFileBinding identifies a key file's key: the encrypted private key is
+// bound to it, so a key file copied to another key name or edited fails to
+// load instead of signing under the wrong identity
+func keyFileBinding(trustDomain, namespace, keyName string, data *keyFileData) []byte {
+	return []byte(strings.Join([]string{
+		trustDomain, namespace, keyName,
+		data.ID, data.Algorithm, data.KeyType, strconv.Itoa(data.Generation),
+	}, "\x00"))
+}
+
+// sealPrivateKey stores the DER private key in data, encrypted and
+// authenticated with the provider's encryption key, or checksummed when it has
+// none
+func (m *DiskKeyProvider) sealPrivateKey(trustDomain, namespace, keyName string, data *keyFileData, privateKeyDER []byte) error {
+	binding := keyFileBinding(trustDomain, namespace, keyName, data)
+	if m.kek == nil {
+		data.PrivateKey = base64.StdEncoding.EncodeToString(privateKeyDER)
+		data.Encryption = ""
+		data.Checksum = keyFileChecksum(binding, privateKeyDER)
+		return nil
+	}
+
+	gcm, err := m.keyFileCipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := gcm.Seal(nonce, nonce, privateKeyDER, binding)
+	data.PrivateKey = base64.StdEncoding.EncodeToString(sealed)
+	data.Encryption = encryptionAES256GCM
+	data.Checksum, err = m.keyFileMAC(binding, data)
+	return err
+}
+
+// openPrivateKey returns the DER private key of data, verifying its integrity
+// and decrypting it
+//
+// Once an encryption key is configured, key files that are not encrypted are
+// refused, as is a key file without a checksum: both fail with
+// errOutdatedKeyFile until migrated.
+func (m *DiskKeyProvider) openPrivateKey(trustDomain, namespace, keyName string, data *keyFileData) ([]byte, error) {
+	stored, err := base64.StdEncoding.DecodeString(data.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode private key: %w", err)
+	}
+	binding := keyFileBinding(trustDomain, namespace, keyName, data)
+
+	switch data.Encryption {
+	case "":
+		if m.kek != nil {
+			return nil, fmt.Errorf("%w: key file is not encrypted but an encryption key is configured; "+
+				"set migrate_key_files to encrypt it", errOutdatedKeyFile)
+		}
+		if data.Checksum == "" {
+			return nil, fmt.Errorf("%w: key file has no checksum; set migrate_key_files to add one", errOutdatedKeyFile)
+		}
+		if subtle.ConstantTimeCompare([]byte(data.Checksum), []byte(keyFileChecksum(binding, stored))) != 1 {
+			return nil, fmt.Errorf("key file checksum mismatch (corrupted or modified?)")
+		}
+		return stored, nil
+	case encryptionAES256GCM:
+		if m.kek == nil {
+			return nil, fmt.Errorf("key file is encrypted but no encryption key is configured")
+		}
+		mac, err := m.keyFileMAC(binding, data)
+		if err != nil {
+			return nil, err
+		}
+		if subtle.ConstantTimeCompare([]byte(data.Checksum), []byte(mac)) != 1 {
+			return nil, fmt.Errorf("key file checksum mismatch (wrong encryption key, or file modified?)")
+		}
+		gcm, err := m.keyFileCipher()
+		if err != nil {
+			return nil, err
+		}
+		if len(stored) < gcm.NonceSize() {
+			return nil, fmt.Errorf("encrypted private key is truncated")
+		}
+		nonce, ciphertext := stored[:gcm.NonceSize()], stored[gcm.NonceSize():]
+		privateKeyDER, err := gcm.Open(nil, nonce, ciphertext, binding)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt private key (wrong encryption key, or file modified?)")
+		}
+		return privateKeyDER, nil
+	default:
+		return nil, fmt.Errorf("unsupported key file encryption: %s", data.Encryption)
+	}
+}
+
+// openOutdatedPrivateKey returns the DER private key of a key file written by
+// an earlier version, verifying its checksum if it has one
+func openOutdatedPrivateKey(trustDomain, namespace, keyName string, data *keyFileData) ([]byte, error) {
+	if data.Encryption != "" {
+		return nil, fmt.Errorf("key file is not outdated")
+	}
+	stored, err := base64.StdEncoding.DecodeString(data.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode private key: %w", err)
+	}
+	binding := keyFileBinding(trustDomain, namespace, keyName, data)
+	if data.Checksum != "" && subtle.ConstantTimeCompare([]byte(data.Checksum), []byte(keyFileChecksum(binding, stored))) != 1 {
+		return nil, fmt.Errorf("key file checksum mismatch (corrupted or modified?)")
+	}
+	return stored, nil
+}
+
+func (m *DiskKeyProvider) keyFileCipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(m.kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// keyFileMAC authenticates an encrypted key file as a whole with a key
+// derived from the encryption key, so a key file cannot be edited, even where
+// the ciphertext does not cover it, without the encryption key
+func (m *DiskKeyProvider) keyFileMAC(binding []byte, data *keyFileData) (string, error) {
+	macKey, err := hkdf.Key(sha256.New, m.kek, nil, "parsec disk key file mac", sha256.Size)
+	if err != nil {
+		return "", err
+	}
+	h := hmac.New(sha256.New, macKey)
+	h.Write(binding)
+	for _, field := range []string{data.CreatedAt.UTC().Format(time.RFC3339Nano), data.Encryption, data.PrivateKey} {
+		h.Write([]byte{0})
+		h.Write([]byte(field))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// keyFileChecksum detects corruption of a key file that is not encrypted
+// Without an encryption key there is no secret to key it with, so it does not
+// stop deliberate edits; an encryption key does.
+func keyFileChecksum(binding, privateKeyDER []byte) string {
+	h := sha256.New()
+	h.Write(binding)
+	h.Write([]byte{0})
+	h.Write(privateKeyDER)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,179 @@
+package keys
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/project-kessel/parsec/internal/fs"
+)
+
+const testKeyFile = "/keys/test.example.com/test-ns/key-a.json"
+
+func newEncryptionTestProvider(t *testing.T, memFS *fs.MemFileSystem, kek []byte) *DiskKeyProvider {
+	t.Helper()
+	return newMigratingTestProvider(t, memFS, kek, false)
+}
+
+func newMigratingTestProvider(t *testing.T, memFS *fs.MemFileSystem, kek []byte, migrate bool) *DiskKeyProvider {
+	t.Helper()
+	kp, err := NewDiskKeyProvider(DiskKeyProviderConfig{
+		KeyType:         KeyTypeECP256,
+		KeysPath:        "/keys",
+		FileSystem:      memFS,
+		EncryptionKey:   kek,
+		MigrateKeyFiles: migrate,
+	})
+	require.NoError(t, err)
+	return kp
+}
+
+// readKeyFile returns the stored key file
+func readKeyFile(t *testing.T, memFS *fs.MemFileSystem) keyFileData {
+	t.Helper()
+	raw, err := memFS.ReadFile(testKeyFile)
+	require.NoError(t, err)
+	var data keyFileData
+	require.NoError(t, json.Unmarshal(raw, &data))
+	return data
+}
+
+// rewriteKeyFile applies edit to the stored key file
+func rewriteKeyFile(t *testing.T, memFS *fs.MemFileSystem, edit func(data *keyFileData)) {
+	t.Helper()
+	data := readKeyFile(t, memFS)
+	edit(&data)
+	raw, err := json.Marshal(data)
+	require.NoError(t, err)
+	require.NoError(t, memFS.WriteFileAtomic(testKeyFile, raw, 0600))
+}
+
+func TestDiskKeyProvider_Encryption(t *testing.T) {
+	ctx := context.Background()
+	kek := bytes.Repeat([]byte{7}, 32)
+
+	rotate := func(t *testing.T, kp *DiskKeyProvider) KeyHandle {
+		t.Helper()
+		handle, err := kp.GetKeyHandle(ctx, "test.example.com", "test-ns", "key-a")
+		require.NoError(t, err)
+		require.NoError(t, handle.Rotate(ctx))
+		return handle
+	}
+	metadata := func(kp *DiskKeyProvider) error {
+		handle, _ := kp.GetKeyHandle(ctx, "test.example.com", "test-ns", "key-a")
+		_, _, err := handle.Metadata(ctx)
+		return err
+	}
+
+	t.Run("private keys are encrypted at rest", func(t *testing.T) {
+		memFS := fs.NewMemFileSystem()
+		handle := rotate(t, newEncryptionTestProvider(t, memFS, kek))
+		id, _, err := handle.Metadata(ctx)
+		require.NoError(t, err)
+
+		data := readKeyFile(t, memFS)
+		assert.Equal(t, "A256GCM", data.Encryption)
+		assert.NotEmpty(t, data.Checksum)
+
+		// Another instance with the same key loads it; without it, or with another, it doesn't
+		h2, _ := newEncryptionTestProvider(t, memFS, kek).GetKeyHandle(ctx, "test.example.com", "test-ns", "key-a")
+		id2, _, err := h2.Metadata(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, id, id2)
+		assert.ErrorContains(t, metadata(newEncryptionTestProvider(t, memFS, nil)), "no encryption key")
+		assert.ErrorContains(t, metadata(newEncryptionTestProvider(t, memFS, bytes.Repeat([]byte{8}, 32))), "checksum mismatch")
+	})
+
+	t.Run("encrypted key file is authenticated as a whole", func(t *testing.T) {
+		memFS := fs.NewMemFileSystem()
+		kp := newEncryptionTestProvider(t, memFS, kek)
+		rotate(t, kp)
+		original := readKeyFile(t, memFS)
+
+		rewriteKeyFile(t, memFS, func(data *keyFileData) { data.ID = "forged" })
+		assert.ErrorContains(t, metadata(kp), "checksum mismatch")
+
+		rewriteKeyFile(t, memFS, func(data *keyFileData) { *data = original; data.CreatedAt = data.CreatedAt.Add(-time.Hour) })
+		assert.ErrorContains(t, metadata(kp), "checksum mismatch")
+
+		// A checksum cannot be recomputed without the encryption key
+		rewriteKeyFile(t, memFS, func(data *keyFileData) { *data = original; data.Checksum = "" })
+		assert.ErrorContains(t, metadata(kp), "checksum mismatch")
+	})
+
+	t.Run("unencrypted keys are checksummed", func(t *testing.T) {
+		memFS := fs.NewMemFileSystem()
+		kp := newEncryptionTestProvider(t, memFS, nil)
+		rotate(t, kp)
+		require.NoError(t, metadata(kp))
+
+		rewriteKeyFile(t, memFS, func(data *keyFileData) { data.Generation++ })
+		assert.ErrorContains(t, metadata(kp), "checksum mismatch")
+	})
+
+	t.Run("unencrypted keys are refused once encryption is enabled", func(t *testing.T) {
+		memFS := fs.NewMemFileSystem()
+		rotate(t, newEncryptionTestProvider(t, memFS, nil))
+		handle, _ := newEncryptionTestProvider(t, memFS, nil).GetKeyHandle(ctx, "test.example.com", "test-ns", "key-a")
+		id, _, err := handle.Metadata(ctx)
+		require.NoError(t, err)
+
+		err = metadata(newEncryptionTestProvider(t, memFS, kek))
+		assert.ErrorContains(t, err, "not encrypted")
+		assert.ErrorContains(t, err, "migrate_key_files")
+		assert.Empty(t, readKeyFile(t, memFS).Encryption)
+
+		// Migration encrypts the key file in place, keeping the key
+		h2, _ := newMigratingTestProvider(t, memFS, kek, true).GetKeyHandle(ctx, "test.example.com", "test-ns", "key-a")
+		id2, _, err := h2.Metadata(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, id, id2)
+		assert.Equal(t, "A256GCM", readKeyFile(t, memFS).Encryption)
+		require.NoError(t, metadata(newEncryptionTestProvider(t, memFS, kek)))
+	})
+
+	t.Run("key files without a checksum are refused until migrated", func(t *testing.T) {
+		memFS := fs.NewMemFileSystem()
+		kp := newEncryptionTestProvider(t, memFS, nil)
+		rotate(t, kp)
+		rewriteKeyFile(t, memFS, func(data *keyFileData) { data.Checksum = "" })
+		assert.ErrorContains(t, metadata(kp), "no checksum")
+
+		require.NoError(t, metadata(newMigratingTestProvider(t, memFS, nil, true)))
+		assert.NotEmpty(t, readKeyFile(t, memFS).Checksum)
+		require.NoError(t, metadata(kp))
+	})
+
+	t.Run("migration still verifies checksums", func(t *testing.T) {
+		memFS := fs.NewMemFileSystem()
+		rotate(t, newEncryptionTestProvider(t, memFS, nil))
+		rewriteKeyFile(t, memFS, func(data *keyFileData) { data.Generation++ })
+		assert.ErrorContains(t, metadata(newMigratingTestProvider(t, memFS, kek, true)), "checksum mismatch")
+		assert.Empty(t, readKeyFile(t, memFS).Encryption)
+	})
+
+	t.Run("key files readable by others are refused", func(t *testing.T) {
+		memFS := fs.NewMemFileSystem()
+		kp := newEncryptionTestProvider(t, memFS, nil)
+		rotate(t, kp)
+		raw, err := memFS.ReadFile(testKeyFile)
+		require.NoError(t, err)
+		require.NoError(t, memFS.WriteFileAtomic(testKeyFile, raw, 0644))
+		assert.ErrorContains(t, metadata(kp), "accessible by group or others")
+	})
+
+	t.Run("encryption key must be 32 bytes", func(t *testing.T) {
+		_, err := NewDiskKeyProvider(DiskKeyProviderConfig{
+			KeyType:       KeyTypeECP256,
+			KeysPath:      "/keys",
+			FileSystem:    fs.NewMemFileSystem(),
+			EncryptionKey: []byte("short"),
+		})
+		assert.ErrorContains(t, err, "encryption key must be 32 bytes")
+	})
+}
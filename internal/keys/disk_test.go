@@ -347,3 +347,87 @@ func TestDiskKeyProvider_ExplicitAlgorithm(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "RS512", alg)
 }
+
+func TestDiskKeyProvider_Seed(t *testing.T) {
+	ctx := context.Background()
+
+	// rotate rotates a key of a fresh keys directory the given number of times
+	rotate := func(t *testing.T, seed, keyName string, times int) (KeyHandle, *fs.MemFileSystem) {
+		t.Helper()
+		memFS := fs.NewMemFileSystem()
+		kp, err := NewDiskKeyProvider(DiskKeyProviderConfig{
+			KeyType:    KeyTypeECP256,
+			KeysPath:   "/keys",
+			FileSystem: memFS,
+			Seed:       seed,
+		})
+		require.NoError(t, err)
+		handle, err := kp.GetKeyHandle(ctx, "example.com", "txn-token", keyName)
+		require.NoError(t, err)
+		for range times {
+			require.NoError(t, handle.Rotate(ctx))
+		}
+		return handle, memFS
+	}
+	kid := func(t *testing.T, handle KeyHandle) string {
+		t.Helper()
+		id, _, err := handle.Metadata(ctx)
+		require.NoError(t, err)
+		return id
+	}
+
+	t.Run("derives the same keys in fresh directories", func(t *testing.T) {
+		first, _ := rotate(t, "local-dev", "key-a", 2)
+		second, _ := rotate(t, "local-dev", "key-a", 2)
+		assert.Equal(t, kid(t, first), kid(t, second))
+
+		firstPub, err := first.Public(ctx)
+		require.NoError(t, err)
+		secondPub, err := second.Public(ctx)
+		require.NoError(t, err)
+		assert.True(t, firstPub.(interface{ Equal(crypto.PublicKey) bool }).Equal(secondPub))
+
+		thumbprint, err := ComputeThumbprint(firstPub)
+		require.NoError(t, err)
+		assert.Equal(t, thumbprint, kid(t, first), "expected the kid to be the key's thumbprint")
+	})
+
+	t.Run("derives different keys per generation, name and seed", func(t *testing.T) {
+		kids := map[string]bool{}
+		for _, handle := range []KeyHandle{
+			func() KeyHandle { h, _ := rotate(t, "local-dev", "key-a", 1); return h }(),
+			func() KeyHandle { h, _ := rotate(t, "local-dev", "key-a", 2); return h }(),
+			func() KeyHandle { h, _ := rotate(t, "local-dev", "key-b", 1); return h }(),
+			func() KeyHandle { h, _ := rotate(t, "other-dev", "key-a", 1); return h }(),
+		} {
+			kids[kid(t, handle)] = true
+		}
+		assert.Len(t, kids, 4)
+	})
+
+	t.Run("records keys in the manifest", func(t *testing.T) {
+		handle, memFS := rotate(t, "local-dev", "key-a", 2)
+
+		data, err := memFS.ReadFile("/keys/manifest.json")
+		require.NoError(t, err)
+		var manifest keyManifest
+		require.NoError(t, json.Unmarshal(data, &manifest))
+		require.Len(t, manifest.Keys, 1)
+		entry := manifest.Keys[0]
+		assert.Equal(t, "txn-token", entry.Namespace)
+		assert.Equal(t, "key-a", entry.KeyName)
+		assert.Equal(t, kid(t, handle), entry.ID)
+		assert.Equal(t, 2, entry.Generation)
+		assert.True(t, entry.Derived)
+	})
+
+	t.Run("requires an EC key type", func(t *testing.T) {
+		_, err := NewDiskKeyProvider(DiskKeyProviderConfig{
+			KeyType:    KeyTypeRSA2048,
+			KeysPath:   "/keys",
+			FileSystem: fs.NewMemFileSystem(),
+			Seed:       "local-dev",
+		})
+		assert.Error(t, err)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"slices"
 	"sync"
 	"time"
 
@@ -58,6 +59,17 @@ type DualSlotRotatingSigner struct {
 	activeThumbprint KeyID               // Public key ID (JWK Thumbprint)
 	activeAlg        Algorithm           // JWT Algorithm
 	publicKeys       []service.PublicKey // All non-expired public keys
+	nextRotation     time.Time           // When the newest key is due to be rotated
+
+	// Rotation state reported by Health
+	running       bool
+	lastCheck     time.Time
+	lastError     error
+	rotations     int64
+	checkFailures int64
+
+	// Signatures per key, reported by KeyUsage
+	usage keyUsage
 
 	clock  clock.Clock
 	ticker clock.Ticker
@@ -153,6 +165,10 @@ func (r *DualSlotRotatingSigner) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to start rotation ticker: %w", err)
 	}
 
+	r.mu.Lock()
+	r.running = true
+	r.mu.Unlock()
+
 	return nil
 }
 
@@ -161,24 +177,61 @@ func (r *DualSlotRotatingSigner) Stop() {
 	if r.ticker != nil {
 		r.ticker.Stop()
 	}
+	r.mu.Lock()
+	r.running = false
+	r.mu.Unlock()
+}
+
+// Health implements HealthReporter
+func (r *DualSlotRotatingSigner) Health() SignerHealth {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	h := SignerHealth{
+		ActiveKeyID:   string(r.activeThumbprint),
+		Algorithm:     string(r.activeAlg),
+		Running:       r.running,
+		NextRotation:  r.nextRotation,
+		LastCheck:     r.lastCheck,
+		Rotations:     r.rotations,
+		CheckFailures: r.checkFailures,
+	}
+	if r.lastError != nil {
+		h.LastError = r.lastError.Error()
+	}
+	return h
 }
 
 // doRotationCheck is called periodically by the ticker to check for rotation needs
 func (r *DualSlotRotatingSigner) doRotationCheck(ctx context.Context) {
-	if err := r.checkAndRotate(ctx); err != nil {
-		log.Printf("Error during key rotation check: %v", err)
+	checkErr := r.checkAndRotate(ctx)
+	if checkErr != nil {
+		log.Printf("Error during key rotation check: %v", checkErr)
 	}
 	// Update active key cache after each check (whether rotation happened or not)
-	if err := r.updateActiveKeyCache(ctx); err != nil {
-		log.Printf("Error updating active key cache: %v", err)
+	cacheErr := r.updateActiveKeyCache(ctx)
+	if cacheErr != nil {
+		log.Printf("Error updating active key cache: %v", cacheErr)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastCheck = r.clock.Now()
+	r.lastError = errors.Join(checkErr, cacheErr)
+	if r.lastError != nil {
+		r.checkFailures++
 	}
 }
 
 // contextSigner wraps a KeyHandle to implement crypto.Signer with context and mismatch detection
+// Successful signatures are recorded against keyID in usage.
 type contextSigner struct {
 	handle     KeyHandle
 	ctx        context.Context
 	expectedID string
+	keyID      KeyID
+	usage      *keyUsage
+	clock      clock.Clock
 }
 
 func (s *contextSigner) Public() crypto.PublicKey {
@@ -194,6 +247,7 @@ func (s *contextSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOp
 	if usedID != s.expectedID {
 		return nil, ErrKeyMismatch
 	}
+	s.usage.record(s.keyID, s.clock.Now())
 	return sig, nil
 }
 
@@ -214,11 +268,28 @@ func (r *DualSlotRotatingSigner) GetCurrentSigner(ctx context.Context) (crypto.S
 		handle:     handle,
 		ctx:        ctx,
 		expectedID: internalID,
+		keyID:      thumbprint,
+		usage:      &r.usage,
+		clock:      r.clock,
 	}
 
 	return signer, thumbprint, alg, nil
 }
 
+// KeyUsage implements KeyUsageReporter
+func (r *DualSlotRotatingSigner) KeyUsage() []KeyUsage {
+	return r.usage.snapshot()
+}
+
+// Algorithm implements AlgorithmReporter
+// Returns "" if the key provider does not report its algorithm.
+func (r *DualSlotRotatingSigner) Algorithm() Algorithm {
+	if reporter, ok := r.keyProviderRegistry[r.keyProviderID].(AlgorithmReporter); ok {
+		return reporter.Algorithm()
+	}
+	return ""
+}
+
 // PublicKeys returns all non-expired public keys from cache
 func (r *DualSlotRotatingSigner) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
 	r.mu.RLock()
@@ -280,31 +351,19 @@ func (r *DualSlotRotatingSigner) ensureInitialKey(ctx context.Context) error {
 		return fmt.Errorf("failed to save slot A: %w", err)
 	}
 
+	r.mu.Lock()
+	r.rotations++
+	r.mu.Unlock()
+
 	return nil
 }
 
 // checkAndRotate checks if rotation is needed and performs it using two-phase rotation
 func (r *DualSlotRotatingSigner) checkAndRotate(ctx context.Context) error {
-	// 1. Read all slots and store version
-	slots, storeVersion, err := r.slotStore.ListSlots(ctx)
+	// 1. Read this signer's slots and the store version
+	slotA, slotB, storeVersion, err := r.listSlotPair(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to list slots: %w", err)
-	}
-
-	// Filter slots to find slotA and slotB for this token type
-	var slotA, slotB *KeySlot
-	for _, slot := range slots {
-		if slot.Namespace != r.namespace || slot.KeyProviderID != r.keyProviderID {
-			continue
-		}
-		switch slot.Position {
-		case SlotPositionA:
-			slotA = slot
-		case SlotPositionB:
-			slotB = slot
-		default:
-			return fmt.Errorf("unexpected slot position for namespace %s: %s", r.namespace, slot.Position)
-		}
+		return err
 	}
 
 	// 2. Determine which slot needs rotation and which slot to rotate TO
@@ -324,49 +383,89 @@ func (r *DualSlotRotatingSigner) checkAndRotate(ctx context.Context) error {
 		// else: timed out, proceed to generate key
 	}
 
+	generated, err := r.generateKey(ctx, targetSlot, storeVersion)
+	if err != nil || !generated {
+		return err
+	}
+	log.Printf("Completed rotation for slot %s", targetSlot.Position)
+	return nil
+}
+
+// generateKey generates a new key in the target slot using two-phase rotation:
+// the slot is marked as preparing, the key is generated, and the slot is saved
+// as rotated. Returns false if another process changed the store first.
+func (r *DualSlotRotatingSigner) generateKey(ctx context.Context, targetSlot *KeySlot, storeVersion StoreVersion) (bool, error) {
+	now := r.clock.Now()
 	targetSlot.PreparingAt = &now
 	// Use current KeyProvider for new key
 	targetSlot.KeyProviderID = r.keyProviderID
-	storeVersion, err = r.slotStore.SaveSlot(ctx, targetSlot, storeVersion)
+	storeVersion, err := r.slotStore.SaveSlot(ctx, targetSlot, storeVersion)
 	if errors.Is(err, ErrVersionMismatch) {
-		return nil // Another process won, that's fine
+		return false, nil // Another process won, that's fine
 	}
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	// 4. Generate key and complete rotation using current KeyProvider
+	// Generate key using current KeyProvider
 	provider, ok := r.keyProviderRegistry[r.keyProviderID]
 	if !ok {
-		return fmt.Errorf("key provider not found: %s", r.keyProviderID)
+		return false, fmt.Errorf("key provider not found: %s", r.keyProviderID)
 	}
 
 	keyName := r.keyName(targetSlot.Position)
 	handle, err := provider.GetKeyHandle(ctx, r.trustDomain, r.namespace, keyName)
 	if err != nil {
-		return fmt.Errorf("failed to get key handle: %w", err)
+		return false, fmt.Errorf("failed to get key handle: %w", err)
 	}
 
 	if err := handle.Rotate(ctx); err != nil {
-		return fmt.Errorf("failed to rotate key: %w", err)
+		return false, fmt.Errorf("failed to rotate key: %w", err)
 	}
 
-	// 5. Update slot with rotation completed, clear preparing state
+	// Update slot with rotation completed, clear preparing state
 	targetSlot.PreparingAt = nil
 	targetSlot.RotationCompletedAt = &now
 
 	_, err = r.slotStore.SaveSlot(ctx, targetSlot, storeVersion)
 	if errors.Is(err, ErrVersionMismatch) {
 		log.Printf("Another process completed rotation for slot %s, skipping", targetSlot.Position)
-		return nil
+		return false, nil
 	}
 	if err != nil {
-		return fmt.Errorf("failed to save slot: %w", err)
+		return false, fmt.Errorf("failed to save slot: %w", err)
 	}
 
-	log.Printf("Completed rotation for slot %s", targetSlot.Position)
+	r.mu.Lock()
+	r.rotations++
+	r.mu.Unlock()
 
-	return nil
+	return true, nil
+}
+
+// listSlotPair returns this signer's slots A and B (nil if not created yet)
+// and the store version
+func (r *DualSlotRotatingSigner) listSlotPair(ctx context.Context) (*KeySlot, *KeySlot, StoreVersion, error) {
+	slots, storeVersion, err := r.slotStore.ListSlots(ctx)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to list slots: %w", err)
+	}
+
+	var slotA, slotB *KeySlot
+	for _, slot := range slots {
+		if slot.Namespace != r.namespace || slot.KeyProviderID != r.keyProviderID {
+			continue
+		}
+		switch slot.Position {
+		case SlotPositionA:
+			slotA = slot
+		case SlotPositionB:
+			slotB = slot
+		default:
+			return nil, nil, "", fmt.Errorf("unexpected slot position for namespace %s: %s", r.namespace, slot.Position)
+		}
+	}
+	return slotA, slotB, storeVersion, nil
 }
 
 // selectSlotsForRotation determines which slot needs rotation and which slot to rotate to
@@ -590,14 +689,23 @@ func (r *DualSlotRotatingSigner) updateActiveKeyCache(ctx context.Context) error
 	}
 	alg := Algorithm(algStr)
 
+	// The newest key is the next to be rotated once it nears its TTL
+	var nextRotation time.Time
+	if newest := findNewestSlot(slices.Concat(preferredSlots, fallbackSlots)); newest != nil && newest.RotationCompletedAt != nil {
+		nextRotation = newest.RotationCompletedAt.Add(r.keyTTL - r.rotationThreshold)
+	}
+
 	r.mu.Lock()
 	r.activeHandle = activeHandle
 	r.activeInternalID = internalID
 	r.activeThumbprint = thumbprints[activeSlot]
 	r.activeAlg = alg
 	r.publicKeys = publicKeys
+	r.nextRotation = nextRotation
 	r.mu.Unlock()
 
+	r.usage.retain(publicKeys)
+
 	return nil
 }
 
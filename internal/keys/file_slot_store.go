@@ -0,0 +1,138 @@
+package keys
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/fs"
+)
+
+// FileKeySlotStore is a KeySlotStore persisting key slots in a JSON file, so
+// signers keep their keys across restarts
+//
+// Every call reads the file, and saves are written atomically, so instances
+// sharing the file on a single node see each other's slots. Like the
+// DiskKeyProvider, it suits single-pod deployments with ReadWriteOnce
+// persistent volumes.
+type FileKeySlotStore struct {
+	mu   sync.Mutex
+	path string
+	fs   fs.FileSystem
+}
+
+// slotStoreFile is the JSON structure of a FileKeySlotStore
+type slotStoreFile struct {
+	Version int              `json:"version"`
+	Slots   []slotStoreEntry `json:"slots"`
+}
+
+type slotStoreEntry struct {
+	Position            SlotPosition `json:"position"`
+	Namespace           string       `json:"namespace"`
+	KeyProviderID       string       `json:"key_provider_id"`
+	PreparingAt         *time.Time   `json:"preparing_at,omitempty"`
+	RotationCompletedAt *time.Time   `json:"rotation_completed_at,omitempty"`
+}
+
+// NewFileKeySlotStore creates a key slot store persisted at path
+// The filesystem is optional (defaults to OSFileSystem).
+func NewFileKeySlotStore(path string, filesystem fs.FileSystem) (*FileKeySlotStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if filesystem == nil {
+		filesystem = fs.NewOSFileSystem()
+	}
+	if err := filesystem.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create slot store directory: %w", err)
+	}
+	return &FileKeySlotStore{path: path, fs: filesystem}, nil
+}
+
+// ListSlots returns all slots and the current store version
+func (s *FileKeySlotStore) ListSlots(ctx context.Context) ([]*KeySlot, StoreVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.read()
+	if err != nil {
+		return nil, "", err
+	}
+	slots := make([]*KeySlot, 0, len(file.Slots))
+	for _, entry := range file.Slots {
+		slots = append(slots, &KeySlot{
+			Position:            entry.Position,
+			Namespace:           entry.Namespace,
+			KeyProviderID:       entry.KeyProviderID,
+			PreparingAt:         entry.PreparingAt,
+			RotationCompletedAt: entry.RotationCompletedAt,
+		})
+	}
+	return slots, StoreVersion(strconv.Itoa(file.Version)), nil
+}
+
+// SaveSlot saves a slot atomically with optimistic locking
+func (s *FileKeySlotStore) SaveSlot(ctx context.Context, slot *KeySlot, expectedVersion StoreVersion) (StoreVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.read()
+	if err != nil {
+		return "", err
+	}
+	if expectedVersion != StoreVersion(strconv.Itoa(file.Version)) {
+		return "", ErrVersionMismatch
+	}
+
+	entry := slotStoreEntry{
+		Position:            slot.Position,
+		Namespace:           slot.Namespace,
+		KeyProviderID:       slot.KeyProviderID,
+		PreparingAt:         slot.PreparingAt,
+		RotationCompletedAt: slot.RotationCompletedAt,
+	}
+	i := slices.IndexFunc(file.Slots, func(e slotStoreEntry) bool {
+		return e.Namespace == slot.Namespace && e.KeyProviderID == slot.KeyProviderID && e.Position == slot.Position
+	})
+	if i >= 0 {
+		file.Slots[i] = entry
+	} else {
+		file.Slots = append(file.Slots, entry)
+	}
+	slices.SortFunc(file.Slots, func(a, b slotStoreEntry) int {
+		return strings.Compare(a.Namespace+"|"+a.KeyProviderID+":"+string(a.Position), b.Namespace+"|"+b.KeyProviderID+":"+string(b.Position))
+	})
+	file.Version++
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := s.fs.WriteFileAtomic(s.path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write slot store: %w", err)
+	}
+	return StoreVersion(strconv.Itoa(file.Version)), nil
+}
+
+// read reads the store file; a missing file is an empty store
+func (s *FileKeySlotStore) read() (*slotStoreFile, error) {
+	data, err := s.fs.ReadFile(s.path)
+	if err != nil {
+		if s.fs.IsNotExist(err) {
+			return &slotStoreFile{}, nil
+		}
+		return nil, fmt.Errorf("failed to read slot store: %w", err)
+	}
+	var file slotStoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse slot store %s (corrupted?): %w", s.path, err)
+	}
+	return &file, nil
+}
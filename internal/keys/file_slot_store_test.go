@@ -0,0 +1,55 @@
+package keys
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/project-kessel/parsec/internal/fs"
+)
+
+func TestFileKeySlotStore(t *testing.T) {
+	ctx := context.Background()
+	memFS := fs.NewMemFileSystem()
+	store, err := NewFileKeySlotStore("/state/slots.json", memFS)
+	require.NoError(t, err)
+
+	slots, version, err := store.ListSlots(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, slots)
+
+	completed := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	slot := &KeySlot{Position: SlotPositionA, Namespace: "txn", KeyProviderID: "disk", RotationCompletedAt: &completed}
+	version, err = store.SaveSlot(ctx, slot, version)
+	require.NoError(t, err)
+
+	_, err = store.SaveSlot(ctx, slot, "0")
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+
+	// Another store on the same file sees the slot and its version
+	reopened, err := NewFileKeySlotStore("/state/slots.json", memFS)
+	require.NoError(t, err)
+	slots, reopenedVersion, err := reopened.ListSlots(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, version, reopenedVersion)
+	require.Len(t, slots, 1)
+	assert.Equal(t, "disk", slots[0].KeyProviderID)
+	assert.True(t, slots[0].RotationCompletedAt.Equal(completed))
+
+	// Saving a slot again replaces it
+	preparing := completed.Add(time.Hour)
+	slot.PreparingAt = &preparing
+	_, err = reopened.SaveSlot(ctx, slot, reopenedVersion)
+	require.NoError(t, err)
+	slots, _, err = store.ListSlots(ctx)
+	require.NoError(t, err)
+	require.Len(t, slots, 1)
+	assert.True(t, slots[0].PreparingAt.Equal(preparing))
+
+	require.NoError(t, memFS.WriteFileAtomic("/state/slots.json", []byte("{"), 0600))
+	_, _, err = store.ListSlots(ctx)
+	assert.ErrorContains(t, err, "corrupted")
+}
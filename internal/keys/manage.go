@@ -0,0 +1,271 @@
+package keys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrKeyNotFound is returned when no slot holds the key to revoke
+	ErrKeyNotFound = errors.New("key not found")
+
+	// ErrRotationPending is returned when a rotation is requested while a new
+	// key is still being generated or is in its grace period
+	ErrRotationPending = errors.New("a rotation is already pending")
+)
+
+// SlotState describes what a slot's key is used for
+type SlotState string
+
+const (
+	// SlotStateEmpty slots have never held a key
+	SlotStateEmpty SlotState = "empty"
+
+	// SlotStatePreparing slots are having a key generated
+	SlotStatePreparing SlotState = "preparing"
+
+	// SlotStatePending keys are published but not yet used for signing
+	SlotStatePending SlotState = "pending"
+
+	// SlotStateActive keys sign new tokens
+	SlotStateActive SlotState = "active"
+
+	// SlotStateRetiring keys no longer sign but are still published, so tokens
+	// they signed can be verified
+	SlotStateRetiring SlotState = "retiring"
+
+	// SlotStateExpired keys are past their TTL and no longer published
+	SlotStateExpired SlotState = "expired"
+)
+
+// SlotStatus is a snapshot of a key slot
+type SlotStatus struct {
+	Position      SlotPosition `json:"position"`
+	Namespace     string       `json:"namespace"`
+	KeyProviderID string       `json:"key_provider_id,omitempty"`
+	State         SlotState    `json:"state"`
+
+	// KeyID and Algorithm of the slot's key ("" if it has none)
+	KeyID     string `json:"key_id,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+
+	// GeneratedAt is when the key was generated
+	GeneratedAt time.Time `json:"generated_at,omitzero"`
+
+	// UsableAt is when the key's grace period ends
+	UsableAt time.Time `json:"usable_at,omitzero"`
+
+	// RotatesAt is when the key is due to be replaced by a new one
+	RotatesAt time.Time `json:"rotates_at,omitzero"`
+
+	// ExpiresAt is when the key stops being published
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+
+	// PreparingSince is when key generation started, while preparing
+	PreparingSince time.Time `json:"preparing_since,omitzero"`
+
+	// Signatures made with the key by this process, and when it last signed
+	Signatures int64     `json:"signatures,omitempty"`
+	LastUsedAt time.Time `json:"last_used_at,omitzero"`
+}
+
+// KeyManager is implemented by signers whose keys operators can inspect and
+// replace outside the rotation schedule
+type KeyManager interface {
+	// Namespace returns the namespace of the signer's keys
+	Namespace() string
+
+	// Slots returns the state of each of the signer's key slots
+	Slots(ctx context.Context) ([]SlotStatus, error)
+
+	// Rotate generates a new key now instead of when the rotation schedule
+	// would. The key is published immediately and signs once its grace period
+	// ends. Returns ErrRotationPending while the key it would replace is still
+	// signing because the newest key is in its grace period.
+	Rotate(ctx context.Context) (SlotStatus, error)
+
+	// Revoke replaces the key with the given ID by a new key, so that it is no
+	// longer published or used for signing. Tokens it signed stop verifying.
+	// Returns ErrKeyNotFound if no slot holds the key.
+	Revoke(ctx context.Context, keyID KeyID) (SlotStatus, error)
+}
+
+// Namespace implements KeyManager
+func (r *DualSlotRotatingSigner) Namespace() string {
+	return r.namespace
+}
+
+// Slots implements KeyManager
+func (r *DualSlotRotatingSigner) Slots(ctx context.Context) ([]SlotStatus, error) {
+	slotA, slotB, _, err := r.listSlotPair(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []SlotStatus{
+		r.slotStatus(ctx, SlotPositionA, slotA),
+		r.slotStatus(ctx, SlotPositionB, slotB),
+	}, nil
+}
+
+// Rotate implements KeyManager
+// The new key goes in the slot not holding the newest key, replacing the key
+// that signed before the newest one.
+func (r *DualSlotRotatingSigner) Rotate(ctx context.Context) (SlotStatus, error) {
+	slotA, slotB, storeVersion, err := r.listSlotPair(ctx)
+	if err != nil {
+		return SlotStatus{}, err
+	}
+
+	var newest, target *KeySlot
+	switch {
+	case slotA == nil && slotB == nil:
+		return SlotStatus{}, fmt.Errorf("signer for namespace %s has no keys yet", r.namespace)
+	case slotB == nil:
+		newest = slotA
+		target = &KeySlot{Position: SlotPositionB, Namespace: r.namespace}
+	case slotA == nil:
+		newest = slotB
+		target = &KeySlot{Position: SlotPositionA, Namespace: r.namespace}
+	default:
+		newest = findNewestSlot([]*KeySlot{slotA, slotB})
+		target = slotA
+		if newest == slotA {
+			target = slotB
+		}
+	}
+
+	// Replacing the signing key before the newest key may sign would leave
+	// no usable key
+	if target.RotationCompletedAt != nil {
+		r.mu.RLock()
+		active := r.activeThumbprint
+		r.mu.RUnlock()
+		if keyID, _, err := r.slotKey(ctx, target); err == nil && keyID == active {
+			return SlotStatus{}, fmt.Errorf("%w: key in slot %s signs until slot %s's key is usable at %s", ErrRotationPending,
+				target.Position, newest.Position, newest.RotationCompletedAt.Add(r.gracePeriod).Format(time.RFC3339))
+		}
+	}
+	return r.replaceKey(ctx, target, storeVersion)
+}
+
+// Revoke implements KeyManager
+// The new key takes the revoked key's slot. If the revoked key was signing,
+// the other slot's key signs instead when it can; otherwise the new key signs
+// immediately, without waiting for its grace period.
+func (r *DualSlotRotatingSigner) Revoke(ctx context.Context, keyID KeyID) (SlotStatus, error) {
+	slotA, slotB, storeVersion, err := r.listSlotPair(ctx)
+	if err != nil {
+		return SlotStatus{}, err
+	}
+
+	var target *KeySlot
+	for _, slot := range []*KeySlot{slotA, slotB} {
+		if slot == nil || slot.RotationCompletedAt == nil {
+			continue
+		}
+		if id, _, err := r.slotKey(ctx, slot); err == nil && id == keyID {
+			target = slot
+			break
+		}
+	}
+	if target == nil {
+		return SlotStatus{}, fmt.Errorf("%w: %s in namespace %s", ErrKeyNotFound, keyID, r.namespace)
+	}
+	return r.replaceKey(ctx, target, storeVersion)
+}
+
+// replaceKey generates a new key in the target slot and switches this
+// instance to it. Other instances pick up the change at their next check.
+func (r *DualSlotRotatingSigner) replaceKey(ctx context.Context, target *KeySlot, storeVersion StoreVersion) (SlotStatus, error) {
+	if target.PreparingAt != nil && r.clock.Now().Sub(*target.PreparingAt) < r.prepareTimeout {
+		return SlotStatus{}, fmt.Errorf("%w: slot %s is being prepared", ErrRotationPending, target.Position)
+	}
+
+	generated, err := r.generateKey(ctx, target, storeVersion)
+	if err != nil {
+		return SlotStatus{}, err
+	}
+	if !generated {
+		return SlotStatus{}, ErrVersionMismatch
+	}
+	if err := r.updateActiveKeyCache(ctx); err != nil {
+		return SlotStatus{}, fmt.Errorf("generated a new key in slot %s but failed to use it: %w", target.Position, err)
+	}
+	return r.slotStatus(ctx, target.Position, target), nil
+}
+
+// slotKey returns the key ID (JWK thumbprint) and algorithm of a slot's key
+func (r *DualSlotRotatingSigner) slotKey(ctx context.Context, slot *KeySlot) (KeyID, Algorithm, error) {
+	provider, ok := r.keyProviderRegistry[slot.KeyProviderID]
+	if !ok {
+		return "", "", fmt.Errorf("key provider not found: %s", slot.KeyProviderID)
+	}
+	handle, err := provider.GetKeyHandle(ctx, r.trustDomain, r.namespace, r.keyName(slot.Position))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get key handle: %w", err)
+	}
+	pub, err := handle.Public(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get public key: %w", err)
+	}
+	thumbprint, err := ComputeThumbprint(pub)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to compute thumbprint: %w", err)
+	}
+	_, alg, err := handle.Metadata(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get metadata: %w", err)
+	}
+	return KeyID(thumbprint), Algorithm(alg), nil
+}
+
+// slotStatus describes a slot (nil if it was never created)
+func (r *DualSlotRotatingSigner) slotStatus(ctx context.Context, position SlotPosition, slot *KeySlot) SlotStatus {
+	status := SlotStatus{Position: position, Namespace: r.namespace, State: SlotStateEmpty}
+	if slot == nil {
+		return status
+	}
+	status.KeyProviderID = slot.KeyProviderID
+
+	now := r.clock.Now()
+	if slot.PreparingAt != nil {
+		status.State = SlotStatePreparing
+		status.PreparingSince = *slot.PreparingAt
+	}
+	if slot.RotationCompletedAt == nil {
+		return status
+	}
+
+	generatedAt := *slot.RotationCompletedAt
+	status.GeneratedAt = generatedAt
+	status.UsableAt = generatedAt.Add(r.gracePeriod)
+	status.RotatesAt = generatedAt.Add(r.keyTTL - r.rotationThreshold)
+	status.ExpiresAt = generatedAt.Add(r.keyTTL)
+	if keyID, alg, err := r.slotKey(ctx, slot); err == nil {
+		status.KeyID = string(keyID)
+		status.Algorithm = string(alg)
+		usage := r.usage.get(keyID)
+		status.Signatures, status.LastUsedAt = usage.Signatures, usage.LastUsedAt
+	}
+	if status.State == SlotStatePreparing {
+		return status
+	}
+
+	r.mu.RLock()
+	active := r.activeThumbprint
+	r.mu.RUnlock()
+
+	switch {
+	case !now.Before(status.ExpiresAt):
+		status.State = SlotStateExpired
+	case status.KeyID != "" && KeyID(status.KeyID) == active:
+		status.State = SlotStateActive
+	case now.Before(status.UsableAt):
+		status.State = SlotStatePending
+	default:
+		status.State = SlotStateRetiring
+	}
+	return status
+}
@@ -0,0 +1,165 @@
+package keys
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/project-kessel/parsec/internal/clock"
+)
+
+func TestDualSlotRotatingSigner_Slots(t *testing.T) {
+	clk := clock.NewFixtureClock(time.Time{})
+	rs, _ := newTestDualSlotRotatingSigner(t, clk, nil, nil)
+	ctx := context.Background()
+	require.NoError(t, rs.Start(ctx))
+	defer rs.Stop()
+
+	_, activeID, _, err := rs.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+
+	slots, err := rs.Slots(ctx)
+	require.NoError(t, err)
+	require.Len(t, slots, 2)
+	assert.Equal(t, SlotStateActive, slots[0].State)
+	assert.Equal(t, string(activeID), slots[0].KeyID)
+	assert.Equal(t, "ES256", slots[0].Algorithm)
+	assert.Equal(t, slots[0].GeneratedAt.Add(22*time.Minute), slots[0].RotatesAt)
+	assert.Equal(t, slots[0].GeneratedAt.Add(30*time.Minute), slots[0].ExpiresAt)
+	assert.Equal(t, SlotStateEmpty, slots[1].State)
+
+	// Scheduled rotation generates a pending key in slot B
+	clk.Advance(23 * time.Minute)
+	slots, err = rs.Slots(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, SlotStateActive, slots[0].State)
+	assert.Equal(t, SlotStatePending, slots[1].State)
+
+	clk.Advance(3 * time.Minute)
+	slots, err = rs.Slots(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, SlotStateRetiring, slots[0].State)
+	assert.Equal(t, SlotStateActive, slots[1].State)
+
+	clk.Advance(5 * time.Minute)
+	slots, err = rs.Slots(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, SlotStateExpired, slots[0].State)
+}
+
+func TestDualSlotRotatingSigner_Rotate(t *testing.T) {
+	clk := clock.NewFixtureClock(time.Time{})
+	rs, _ := newTestDualSlotRotatingSigner(t, clk, nil, nil)
+	ctx := context.Background()
+	require.NoError(t, rs.Start(ctx))
+	defer rs.Stop()
+
+	clk.Advance(3 * time.Minute)
+	_, keyID1, _, err := rs.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+
+	status, err := rs.Rotate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, SlotPositionB, status.Position)
+	assert.Equal(t, SlotStatePending, status.State)
+	assert.NotEqual(t, string(keyID1), status.KeyID)
+
+	// The new key is published at once but signs only after its grace period
+	publicKeys, err := rs.PublicKeys(ctx)
+	require.NoError(t, err)
+	assert.Len(t, publicKeys, 2)
+	_, keyID2, _, err := rs.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, keyID1, keyID2)
+
+	_, err = rs.Rotate(ctx)
+	require.ErrorIs(t, err, ErrRotationPending, "rotating again would replace the signing key")
+	_, keyID2, _, err = rs.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, keyID1, keyID2)
+
+	clk.Advance(3 * time.Minute)
+	_, keyID3, _, err := rs.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, status.KeyID, string(keyID3))
+
+	// The next rotation replaces the oldest key, in slot A
+	status, err = rs.Rotate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, SlotPositionA, status.Position)
+}
+
+func TestDualSlotRotatingSigner_RotateInitialKey(t *testing.T) {
+	clk := clock.NewFixtureClock(time.Time{})
+	rs, _ := newTestDualSlotRotatingSigner(t, clk, nil, nil)
+	ctx := context.Background()
+	require.NoError(t, rs.Start(ctx))
+	defer rs.Stop()
+
+	// The initial key signs during its grace period; a new key in the empty
+	// slot does not replace it
+	_, keyID1, _, err := rs.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	clk.Advance(10 * time.Second)
+	status, err := rs.Rotate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, SlotPositionB, status.Position)
+
+	_, keyID2, _, err := rs.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, keyID1, keyID2, "the oldest key in its grace period keeps signing")
+
+	_, err = rs.Rotate(ctx)
+	require.ErrorIs(t, err, ErrRotationPending)
+}
+
+func TestDualSlotRotatingSigner_Revoke(t *testing.T) {
+	clk := clock.NewFixtureClock(time.Time{})
+	rs, _ := newTestDualSlotRotatingSigner(t, clk, nil, nil)
+	ctx := context.Background()
+	require.NoError(t, rs.Start(ctx))
+	defer rs.Stop()
+
+	_, err := rs.Revoke(ctx, "unknown")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	// Rotate so that slot B holds the signing key and slot A the previous one
+	clk.Advance(3 * time.Minute)
+	_, oldID, _, err := rs.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	_, err = rs.Rotate(ctx)
+	require.NoError(t, err)
+	clk.Advance(3 * time.Minute)
+	_, activeID, _, err := rs.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	require.NotEqual(t, oldID, activeID)
+
+	// Revoking the signing key falls back to the previous key
+	status, err := rs.Revoke(ctx, activeID)
+	require.NoError(t, err)
+	assert.Equal(t, SlotPositionB, status.Position)
+	assert.NotEqual(t, string(activeID), status.KeyID)
+
+	_, signingID, _, err := rs.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, oldID, signingID)
+
+	publicKeys, err := rs.PublicKeys(ctx)
+	require.NoError(t, err)
+	for _, key := range publicKeys {
+		assert.NotEqual(t, string(activeID), key.KeyID, "revoked key must not be published")
+	}
+}
+
+func TestSignerRegistry_KeyManagers(t *testing.T) {
+	rs, _ := newTestDualSlotRotatingSigner(t, clock.NewFixtureClock(time.Time{}), nil, nil)
+	registry := NewSignerRegistry()
+	require.NoError(t, registry.Register("txn", rs))
+
+	managers := registry.KeyManagers()
+	require.Contains(t, managers, "txn")
+	assert.Equal(t, testTokenType, managers["txn"].Namespace())
+}
@@ -51,6 +51,11 @@ func NewInMemoryKeyProvider(keyType KeyType, algorithm string) *InMemoryKeyProvi
 	}
 }
 
+// Algorithm implements AlgorithmReporter
+func (m *InMemoryKeyProvider) Algorithm() Algorithm {
+	return Algorithm(m.algorithm)
+}
+
 // GetKeyHandle returns a handle for a specific trust domain, namespace, and key name.
 func (m *InMemoryKeyProvider) GetKeyHandle(ctx context.Context, trustDomain, namespace, keyName string) (KeyHandle, error) {
 	return &memoryKeyHandle{
@@ -0,0 +1,111 @@
+package keys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrSlotConflict is returned by MigrateSlots when the destination store holds
+// different state for a slot than the source
+var ErrSlotConflict = errors.New("destination slot differs from source")
+
+// MigrateSlotsOptions configures MigrateSlots
+type MigrateSlotsOptions struct {
+	// DryRun reports the slots that would be copied without writing them
+	DryRun bool
+}
+
+// SlotMigration reports the outcome of MigrateSlots
+type SlotMigration struct {
+	// Copied are the slots written to the destination (or that would be, on a dry run)
+	Copied []*KeySlot
+
+	// Unchanged are the slots the destination already holds as they are
+	Unchanged []*KeySlot
+}
+
+// MigrateSlots copies the key slots of one KeySlotStore to another, so a
+// signer can move to a new store without regenerating its keys
+//
+// Slots are matched by namespace, key provider and position. Nothing is
+// written when the destination holds different state for any slot: it is
+// likely in use, and overwriting it could unpublish keys that still sign.
+// After copying, the destination is read back to verify every slot. Signers
+// should be stopped while their slots are migrated.
+func MigrateSlots(ctx context.Context, from, to KeySlotStore, opts MigrateSlotsOptions) (SlotMigration, error) {
+	source, _, err := from.ListSlots(ctx)
+	if err != nil {
+		return SlotMigration{}, fmt.Errorf("failed to list source slots: %w", err)
+	}
+	existing, version, err := to.ListSlots(ctx)
+	if err != nil {
+		return SlotMigration{}, fmt.Errorf("failed to list destination slots: %w", err)
+	}
+	slices.SortFunc(source, func(a, b *KeySlot) int {
+		return strings.Compare(slotKey(a), slotKey(b))
+	})
+
+	var migration SlotMigration
+	for _, slot := range source {
+		current := findSlotByKey(existing, slotKey(slot))
+		switch {
+		case current == nil:
+			migration.Copied = append(migration.Copied, slot)
+		case slotsEqual(current, slot):
+			migration.Unchanged = append(migration.Unchanged, slot)
+		default:
+			return SlotMigration{}, fmt.Errorf("%w: %s", ErrSlotConflict, slotKey(slot))
+		}
+	}
+	if opts.DryRun {
+		return migration, nil
+	}
+
+	for _, slot := range migration.Copied {
+		if version, err = to.SaveSlot(ctx, slot, version); err != nil {
+			return SlotMigration{}, fmt.Errorf("failed to save slot %s: %w", slotKey(slot), err)
+		}
+	}
+
+	migrated, _, err := to.ListSlots(ctx)
+	if err != nil {
+		return SlotMigration{}, fmt.Errorf("failed to verify destination slots: %w", err)
+	}
+	for _, slot := range source {
+		if current := findSlotByKey(migrated, slotKey(slot)); current == nil || !slotsEqual(current, slot) {
+			return SlotMigration{}, fmt.Errorf("failed to verify slot %s: destination does not match source", slotKey(slot))
+		}
+	}
+	return migration, nil
+}
+
+// slotKey identifies a slot across stores
+func slotKey(slot *KeySlot) string {
+	return slot.Namespace + "|" + slot.KeyProviderID + ":" + string(slot.Position)
+}
+
+func findSlotByKey(slots []*KeySlot, key string) *KeySlot {
+	for _, slot := range slots {
+		if slotKey(slot) == key {
+			return slot
+		}
+	}
+	return nil
+}
+
+func slotsEqual(a, b *KeySlot) bool {
+	return slotKey(a) == slotKey(b) &&
+		timesEqual(a.PreparingAt, b.PreparingAt) &&
+		timesEqual(a.RotationCompletedAt, b.RotationCompletedAt)
+}
+
+func timesEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
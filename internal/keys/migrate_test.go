@@ -0,0 +1,73 @@
+package keys
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/project-kessel/parsec/internal/clock"
+)
+
+func TestMigrateSlots(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Time{})
+	from := NewInMemoryKeySlotStore()
+	rs, provider := newTestDualSlotRotatingSigner(t, clk, from, nil)
+	require.NoError(t, rs.Start(ctx))
+	_, keyID, _, err := rs.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	rs.Stop()
+
+	t.Run("dry run writes nothing", func(t *testing.T) {
+		to := NewInMemoryKeySlotStore()
+		migration, err := MigrateSlots(ctx, from, to, MigrateSlotsOptions{DryRun: true})
+		require.NoError(t, err)
+		assert.Len(t, migration.Copied, 1)
+
+		slots, _, err := to.ListSlots(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, slots)
+	})
+
+	t.Run("signers keep their keys in the new store", func(t *testing.T) {
+		to := NewInMemoryKeySlotStore()
+		migration, err := MigrateSlots(ctx, from, to, MigrateSlotsOptions{})
+		require.NoError(t, err)
+		assert.Len(t, migration.Copied, 1)
+
+		migrated, _ := newTestDualSlotRotatingSigner(t, clk, to, provider)
+		require.NoError(t, migrated.Start(ctx))
+		defer migrated.Stop()
+		_, migratedID, _, err := migrated.GetCurrentSigner(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, keyID, migratedID)
+
+		// Migrating again finds nothing to copy
+		migration, err = MigrateSlots(ctx, from, to, MigrateSlotsOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, migration.Copied)
+		assert.Len(t, migration.Unchanged, 1)
+	})
+
+	t.Run("conflicting destination is left alone", func(t *testing.T) {
+		to := NewInMemoryKeySlotStore()
+		slots, _, err := from.ListSlots(ctx)
+		require.NoError(t, err)
+		other := *slots[0]
+		later := other.RotationCompletedAt.Add(time.Hour)
+		other.RotationCompletedAt = &later
+		_, err = to.SaveSlot(ctx, &other, "0")
+		require.NoError(t, err)
+
+		_, err = MigrateSlots(ctx, from, to, MigrateSlotsOptions{})
+		assert.ErrorIs(t, err, ErrSlotConflict)
+
+		current, _, err := to.ListSlots(ctx)
+		require.NoError(t, err)
+		require.Len(t, current, 1)
+		assert.True(t, current[0].RotationCompletedAt.Equal(later))
+	})
+}
@@ -0,0 +1,262 @@
+// Package providertest checks that a keys.KeyProvider behaves the way
+// DualSlotRotatingSigner relies on.
+//
+// Run it from the provider's own tests, with a constructor returning a
+// provider that holds no keys yet:
+//
+//	func TestMyKeyProvider(t *testing.T) {
+//		providertest.Run(t, func(t *testing.T) keys.KeyProvider {
+//			return newMyKeyProvider(t)
+//		})
+//	}
+//
+// The suite checks that a handle has no key before its first rotation, that
+// signatures verify against the public key and report the key ID of
+// Metadata, that rotation replaces the key, that thumbprints are stable
+// across calls and handles, and that keys are isolated by trust domain,
+// namespace and key name.
+package providertest
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/keys"
+)
+
+// NewProvider returns a provider holding no keys, for one subtest
+type NewProvider func(t *testing.T) keys.KeyProvider
+
+const (
+	trustDomain = "conformance.example.com"
+	namespace   = "conformance"
+	keyName     = "key-a"
+)
+
+// Run runs the conformance suite against the providers of newProvider
+func Run(t *testing.T, newProvider NewProvider) {
+	t.Run("no key before the first rotation", func(t *testing.T) {
+		handle := getHandle(t, newProvider(t), trustDomain, namespace, keyName)
+		ctx := context.Background()
+
+		if _, _, err := handle.Metadata(ctx); err == nil {
+			t.Error("Metadata succeeded before the first rotation")
+		}
+		if _, err := handle.Public(ctx); err == nil {
+			t.Error("Public succeeded before the first rotation")
+		}
+		if _, _, err := handle.Sign(ctx, make([]byte, 32), crypto.SHA256); err == nil {
+			t.Error("Sign succeeded before the first rotation")
+		}
+	})
+
+	t.Run("signatures verify with the public key", func(t *testing.T) {
+		handle := getHandle(t, newProvider(t), trustDomain, namespace, keyName)
+		rotate(t, handle)
+		keyID, alg := metadata(t, handle)
+		if keyID == "" || alg == "" {
+			t.Fatalf("Metadata returned key ID %q and algorithm %q, want both set", keyID, alg)
+		}
+
+		usedKeyID := signAndVerify(t, handle, alg)
+		if usedKeyID != keyID {
+			t.Errorf("Sign used key %q, Metadata reports %q", usedKeyID, keyID)
+		}
+	})
+
+	t.Run("rotation replaces the key", func(t *testing.T) {
+		handle := getHandle(t, newProvider(t), trustDomain, namespace, keyName)
+		rotate(t, handle)
+		oldKeyID, _ := metadata(t, handle)
+		oldThumbprint := thumbprint(t, handle)
+
+		rotate(t, handle)
+		newKeyID, alg := metadata(t, handle)
+		if newKeyID == oldKeyID {
+			t.Errorf("key ID %q unchanged by rotation", newKeyID)
+		}
+		if thumbprint(t, handle) == oldThumbprint {
+			t.Error("public key unchanged by rotation")
+		}
+		if usedKeyID := signAndVerify(t, handle, alg); usedKeyID != newKeyID {
+			t.Errorf("Sign used key %q after rotation, want %q", usedKeyID, newKeyID)
+		}
+	})
+
+	t.Run("handles for a key name share its key", func(t *testing.T) {
+		provider := newProvider(t)
+		first := getHandle(t, provider, trustDomain, namespace, keyName)
+		rotate(t, first)
+
+		second := getHandle(t, provider, trustDomain, namespace, keyName)
+		if thumbprint(t, first) != thumbprint(t, second) {
+			t.Error("handles for the same key name see different keys")
+		}
+		if thumbprint(t, first) != thumbprint(t, first) {
+			t.Error("thumbprint differs between calls")
+		}
+
+		rotate(t, second)
+		if thumbprint(t, first) != thumbprint(t, second) {
+			t.Error("a rotation through one handle is not seen through another")
+		}
+		firstKeyID, _ := metadata(t, first)
+		secondKeyID, _ := metadata(t, second)
+		if firstKeyID != secondKeyID {
+			t.Errorf("handles report key IDs %q and %q for the same key", firstKeyID, secondKeyID)
+		}
+	})
+
+	t.Run("keys are isolated by trust domain, namespace and name", func(t *testing.T) {
+		provider := newProvider(t)
+		names := [][3]string{
+			{trustDomain, namespace, keyName},
+			{"other." + trustDomain, namespace, keyName},
+			{trustDomain, "other-" + namespace, keyName},
+			{trustDomain, namespace, "key-b"},
+		}
+
+		handles := make([]keys.KeyHandle, len(names))
+		thumbprints := make(map[string]string, len(names))
+		for i, name := range names {
+			handles[i] = getHandle(t, provider, name[0], name[1], name[2])
+			rotate(t, handles[i])
+			tp := thumbprint(t, handles[i])
+			if other, ok := thumbprints[tp]; ok {
+				t.Errorf("%s shares its key with %s", strings.Join(name[:], "/"), other)
+			}
+			thumbprints[tp] = strings.Join(name[:], "/")
+		}
+
+		// Rotating one key leaves the others alone
+		before := thumbprint(t, handles[1])
+		rotate(t, handles[0])
+		if thumbprint(t, handles[1]) != before {
+			t.Error("rotating a key changed the key of another trust domain")
+		}
+
+		unrotated := getHandle(t, provider, trustDomain, namespace, "key-c")
+		if _, _, err := unrotated.Metadata(context.Background()); err == nil {
+			t.Error("a key name that was never rotated has a key")
+		}
+	})
+}
+
+func getHandle(t *testing.T, provider keys.KeyProvider, trustDomain, namespace, keyName string) keys.KeyHandle {
+	t.Helper()
+	handle, err := provider.GetKeyHandle(context.Background(), trustDomain, namespace, keyName)
+	if err != nil {
+		t.Fatalf("GetKeyHandle(%q, %q, %q) failed: %v", trustDomain, namespace, keyName, err)
+	}
+	return handle
+}
+
+func rotate(t *testing.T, handle keys.KeyHandle) {
+	t.Helper()
+	if err := handle.Rotate(context.Background()); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+}
+
+func metadata(t *testing.T, handle keys.KeyHandle) (keyID, alg string) {
+	t.Helper()
+	keyID, alg, err := handle.Metadata(context.Background())
+	if err != nil {
+		t.Fatalf("Metadata failed: %v", err)
+	}
+	return keyID, alg
+}
+
+func thumbprint(t *testing.T, handle keys.KeyHandle) string {
+	t.Helper()
+	pub, err := handle.Public(context.Background())
+	if err != nil {
+		t.Fatalf("Public failed: %v", err)
+	}
+	tp, err := keys.ComputeThumbprint(pub)
+	if err != nil {
+		t.Fatalf("ComputeThumbprint failed: %v", err)
+	}
+	return tp
+}
+
+// signAndVerify signs a digest with the handle's key and verifies the
+// signature, returning the key ID Sign reported
+func signAndVerify(t *testing.T, handle keys.KeyHandle, alg string) string {
+	t.Helper()
+	ctx := context.Background()
+
+	hash, opts, err := signerOpts(alg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := []byte("parsec key provider conformance")
+	if hash != 0 {
+		h := hash.New()
+		h.Write(digest)
+		digest = h.Sum(nil)
+	}
+
+	signature, usedKeyID, err := handle.Sign(ctx, digest, opts)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	pub, err := handle.Public(ctx)
+	if err != nil {
+		t.Fatalf("Public failed: %v", err)
+	}
+	if err := verify(pub, alg, digest, signature, hash); err != nil {
+		t.Errorf("signature does not verify with the public key: %v", err)
+	}
+	return usedKeyID
+}
+
+// signerOpts returns the hash and signer options of a JWS algorithm
+func signerOpts(alg string) (crypto.Hash, crypto.SignerOpts, error) {
+	if alg == "EdDSA" {
+		return 0, crypto.Hash(0), nil
+	}
+	var hash crypto.Hash
+	switch {
+	case strings.HasSuffix(alg, "256"):
+		hash = crypto.SHA256
+	case strings.HasSuffix(alg, "384"):
+		hash = crypto.SHA384
+	case strings.HasSuffix(alg, "512"):
+		hash = crypto.SHA512
+	default:
+		return 0, nil, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	if strings.HasPrefix(alg, "PS") {
+		return hash, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash}, nil
+	}
+	return hash, hash, nil
+}
+
+func verify(pub crypto.PublicKey, alg string, digest, signature []byte, hash crypto.Hash) error {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest, signature) {
+			return fmt.Errorf("invalid %s signature", alg)
+		}
+		return nil
+	case *rsa.PublicKey:
+		if strings.HasPrefix(alg, "PS") {
+			return rsa.VerifyPSS(key, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.VerifyPKCS1v15(key, hash, digest, signature)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, digest, signature) {
+			return fmt.Errorf("invalid %s signature", alg)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
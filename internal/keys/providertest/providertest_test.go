@@ -0,0 +1,39 @@
+package providertest_test
+
+import (
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/fs"
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/keys/providertest"
+)
+
+func TestInMemoryKeyProvider(t *testing.T) {
+	for _, keyType := range []keys.KeyType{keys.KeyTypeECP256, keys.KeyTypeRSA2048} {
+		t.Run(string(keyType), func(t *testing.T) {
+			providertest.Run(t, func(t *testing.T) keys.KeyProvider {
+				return keys.NewInMemoryKeyProvider(keyType, "")
+			})
+		})
+	}
+}
+
+func TestDiskKeyProvider(t *testing.T) {
+	configs := map[string]keys.DiskKeyProviderConfig{
+		"random": {KeyType: keys.KeyTypeECP384},
+		"seeded": {KeyType: keys.KeyTypeECP256, Seed: "conformance"},
+	}
+	for name, cfg := range configs {
+		t.Run(name, func(t *testing.T) {
+			providertest.Run(t, func(t *testing.T) keys.KeyProvider {
+				cfg.KeysPath = "/keys"
+				cfg.FileSystem = fs.NewMemFileSystem()
+				provider, err := keys.NewDiskKeyProvider(cfg)
+				if err != nil {
+					t.Fatalf("NewDiskKeyProvider failed: %v", err)
+				}
+				return provider
+			})
+		})
+	}
+}
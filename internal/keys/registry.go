@@ -3,7 +3,10 @@ package keys
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
 	"sync"
+	"time"
 )
 
 // SignerRegistry manages a collection of named RotatingSigners
@@ -59,12 +62,116 @@ func (r *SignerRegistry) Start(ctx context.Context) error {
 	return nil
 }
 
-// Stop stops all registered signers
-func (r *SignerRegistry) Stop() {
+// Stop stops the rotation of all registered signers, returning once they have
+// stopped or ctx is done
+func (r *SignerRegistry) Stop(ctx context.Context) error {
+	r.mu.RLock()
+	signers := slices.Collect(maps.Values(r.signers))
+	r.mu.RUnlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		for _, signer := range signers {
+			wg.Go(signer.Stop)
+		}
+		wg.Wait()
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to stop signers: %w", ctx.Err())
+	}
+}
+
+// KeyManagers returns the signers that implement KeyManager by ID
+func (r *SignerRegistry) KeyManagers() map[string]KeyManager {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	for _, signer := range r.signers {
-		signer.Stop()
+	managers := make(map[string]KeyManager, len(r.signers))
+	for id, signer := range r.signers {
+		if manager, ok := signer.(KeyManager); ok {
+			managers[id] = manager
+		}
+	}
+	return managers
+}
+
+// SignerHealth is a snapshot of a signer's state
+type SignerHealth struct {
+	// ActiveKeyID is the kid tokens are signed with ("" if there is no active key)
+	ActiveKeyID string `json:"active_key_id"`
+
+	// Algorithm of the active key
+	Algorithm string `json:"algorithm,omitempty"`
+
+	// Running reports whether background rotation is running
+	Running bool `json:"running"`
+
+	// NextRotation is when the active key is due to be rotated (zero if unknown)
+	NextRotation time.Time `json:"next_rotation,omitzero"`
+
+	// LastCheck is when rotation was last checked (zero before the first check)
+	LastCheck time.Time `json:"last_check,omitzero"`
+
+	// LastError is the error of the last rotation check, if it failed
+	LastError string `json:"last_error,omitempty"`
+
+	// Rotations counts the keys this signer generated since it started
+	Rotations int64 `json:"rotations"`
+
+	// CheckFailures counts the rotation checks that failed since it started
+	CheckFailures int64 `json:"check_failures"`
+}
+
+// Healthy reports whether the signer can sign tokens
+func (h SignerHealth) Healthy() bool {
+	return h.ActiveKeyID != ""
+}
+
+// HealthReporter is implemented by signers that report their rotation state
+type HealthReporter interface {
+	Health() SignerHealth
+}
+
+// Health returns a snapshot of each registered signer by ID
+// Signers that don't report their health are described by their current key.
+func (r *SignerRegistry) Health(ctx context.Context) map[string]SignerHealth {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	health := make(map[string]SignerHealth, len(r.signers))
+	for id, signer := range r.signers {
+		if reporter, ok := signer.(HealthReporter); ok {
+			health[id] = reporter.Health()
+			continue
+		}
+		var h SignerHealth
+		if _, keyID, alg, err := signer.GetCurrentSigner(ctx); err != nil {
+			h.LastError = err.Error()
+		} else {
+			h.ActiveKeyID, h.Algorithm = string(keyID), string(alg)
+		}
+		health[id] = h
+	}
+	return health
+}
+
+// KeyUsage returns the signatures per key of each registered signer by ID
+// Signers that don't count their signatures are left out.
+func (r *SignerRegistry) KeyUsage() map[string][]KeyUsage {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	usage := make(map[string][]KeyUsage, len(r.signers))
+	for id, signer := range r.signers {
+		if reporter, ok := signer.(KeyUsageReporter); ok {
+			usage[id] = reporter.KeyUsage()
+		}
 	}
+	return usage
 }
@@ -0,0 +1,57 @@
+package keys
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/project-kessel/parsec/internal/clock"
+)
+
+func TestSignerRegistry_HealthAndStop(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFixtureClock(start)
+
+	rs, _ := newTestDualSlotRotatingSigner(t, clk, nil, nil)
+	registry := NewSignerRegistry()
+	require.NoError(t, registry.Register("txn", rs))
+	require.NoError(t, registry.Start(ctx))
+
+	health := registry.Health(ctx)
+	require.Contains(t, health, "txn")
+	h := health["txn"]
+	assert.True(t, h.Healthy())
+	assert.True(t, h.Running)
+	assert.Equal(t, "ES256", h.Algorithm)
+	assert.Equal(t, int64(1), h.Rotations)
+	// The key is due for rotation when RotationThreshold of its KeyTTL remains
+	assert.Equal(t, start.Add(30*time.Minute-8*time.Minute), h.NextRotation)
+
+	require.NoError(t, registry.Stop(ctx))
+	h = registry.Health(ctx)["txn"]
+	assert.False(t, h.Running)
+	assert.True(t, h.Healthy(), "a stopped signer still signs with its active key")
+}
+
+func TestSignerRegistry_StopHonorsContext(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	registry := NewSignerRegistry()
+	require.NoError(t, registry.Register("blocked", blockingSigner{release: release}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, registry.Stop(ctx), context.Canceled)
+}
+
+// blockingSigner doesn't finish stopping until release is closed
+type blockingSigner struct {
+	RotatingSigner
+	release chan struct{}
+}
+
+func (s blockingSigner) Stop() { <-s.release }
@@ -69,6 +69,13 @@ type KeyProvider interface {
 	GetKeyHandle(ctx context.Context, trustDomain, namespace, keyName string) (KeyHandle, error)
 }
 
+// AlgorithmReporter is implemented by key providers and signers that know
+// their signing algorithm without generating or loading a key
+type AlgorithmReporter interface {
+	// Algorithm returns the signing algorithm of the keys
+	Algorithm() Algorithm
+}
+
 // KeyType represents the cryptographic key type
 type KeyType string
 
@@ -0,0 +1,83 @@
+package keys
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// KeyUsage counts the signatures made with one key
+// Counts are kept in memory and start over when the process restarts.
+type KeyUsage struct {
+	KeyID      string    `json:"key_id"`
+	Signatures int64     `json:"signatures"`
+	LastUsedAt time.Time `json:"last_used_at,omitzero"`
+}
+
+// KeyUsageReporter is implemented by signers that count signatures per key
+type KeyUsageReporter interface {
+	KeyUsage() []KeyUsage
+}
+
+// keyUsage records signatures per key ID
+// The zero value is ready to use.
+type keyUsage struct {
+	mu   sync.Mutex
+	keys map[KeyID]*KeyUsage
+}
+
+func (u *keyUsage) record(keyID KeyID, at time.Time) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.keys == nil {
+		u.keys = make(map[KeyID]*KeyUsage)
+	}
+	usage, ok := u.keys[keyID]
+	if !ok {
+		usage = &KeyUsage{KeyID: string(keyID)}
+		u.keys[keyID] = usage
+	}
+	usage.Signatures++
+	usage.LastUsedAt = at
+}
+
+func (u *keyUsage) get(keyID KeyID) KeyUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if usage, ok := u.keys[keyID]; ok {
+		return *usage
+	}
+	return KeyUsage{KeyID: string(keyID)}
+}
+
+// snapshot returns the usage of every key, ordered by key ID
+func (u *keyUsage) snapshot() []KeyUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	usages := make([]KeyUsage, 0, len(u.keys))
+	for _, usage := range u.keys {
+		usages = append(usages, *usage)
+	}
+	slices.SortFunc(usages, func(a, b KeyUsage) int {
+		return strings.Compare(a.KeyID, b.KeyID)
+	})
+	return usages
+}
+
+// retain forgets the usage of keys that are no longer published
+func (u *keyUsage) retain(published []service.PublicKey) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for keyID := range u.keys {
+		if !slices.ContainsFunc(published, func(pk service.PublicKey) bool { return pk.KeyID == string(keyID) }) {
+			delete(u.keys, keyID)
+		}
+	}
+}
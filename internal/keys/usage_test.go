@@ -0,0 +1,84 @@
+package keys
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/project-kessel/parsec/internal/clock"
+)
+
+func TestDualSlotRotatingSigner_KeyUsage(t *testing.T) {
+	clk := clock.NewFixtureClock(time.Time{})
+	rs, _ := newTestDualSlotRotatingSigner(t, clk, nil, nil)
+	ctx := context.Background()
+	require.NoError(t, rs.Start(ctx))
+	defer rs.Stop()
+
+	digest := sha256.Sum256([]byte("test message"))
+	sign := func() KeyID {
+		t.Helper()
+		signer, keyID, _, err := rs.GetCurrentSigner(ctx)
+		require.NoError(t, err)
+		_, err = signer.Sign(nil, digest[:], crypto.SHA256)
+		require.NoError(t, err)
+		return keyID
+	}
+
+	assert.Empty(t, rs.KeyUsage(), "no key has signed yet")
+
+	oldID := sign()
+	sign()
+	oldLastUsed := clk.Now()
+	usage := rs.KeyUsage()
+	require.Len(t, usage, 1)
+	assert.Equal(t, KeyUsage{KeyID: string(oldID), Signatures: 2, LastUsedAt: oldLastUsed}, usage[0])
+
+	// Signing shifts to the new key once its grace period ends
+	clk.Advance(23 * time.Minute)
+	clk.Advance(3 * time.Minute)
+	newID := sign()
+	require.NotEqual(t, oldID, newID)
+
+	slots, err := rs.Slots(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, string(oldID), slots[0].KeyID)
+	assert.Equal(t, int64(2), slots[0].Signatures)
+	assert.Equal(t, oldLastUsed, slots[0].LastUsedAt)
+	assert.Equal(t, string(newID), slots[1].KeyID)
+	assert.Equal(t, int64(1), slots[1].Signatures)
+	assert.Equal(t, clk.Now(), slots[1].LastUsedAt)
+
+	// The old key's usage is forgotten once it is no longer published
+	clk.Advance(5 * time.Minute)
+	usage = rs.KeyUsage()
+	require.Len(t, usage, 1)
+	assert.Equal(t, string(newID), usage[0].KeyID)
+}
+
+func TestSignerRegistry_KeyUsage(t *testing.T) {
+	clk := clock.NewFixtureClock(time.Time{})
+	rs, _ := newTestDualSlotRotatingSigner(t, clk, nil, nil)
+	ctx := context.Background()
+	require.NoError(t, rs.Start(ctx))
+	defer rs.Stop()
+
+	registry := NewSignerRegistry()
+	require.NoError(t, registry.Register("txn", rs))
+
+	signer, keyID, _, err := rs.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("test message"))
+	_, err = signer.Sign(nil, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+
+	usage := registry.KeyUsage()
+	require.Len(t, usage["txn"], 1)
+	assert.Equal(t, string(keyID), usage["txn"][0].KeyID)
+	assert.Equal(t, int64(1), usage["txn"][0].Signatures)
+}
@@ -0,0 +1,171 @@
+// Package ledger keeps a bounded record of recently issued tokens in memory,
+// so operators investigating an incident can ask which tokens a subject or
+// actor was issued, or who a token was issued to, without searching logs.
+package ledger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+)
+
+// Principal identifies a validated identity
+type Principal struct {
+	Subject     string
+	Issuer      string
+	TrustDomain string
+}
+
+// Entry records an issued token
+// Token values are never recorded.
+type Entry struct {
+	// Type is the token type
+	Type string
+
+	// JTI is the token's "jti" claim, empty for token types without one
+	JTI string
+
+	// Subject and Actor are the identities the token was issued for and to
+	// Actor is nil for anonymous requests.
+	Subject *Principal
+	Actor   *Principal
+
+	Audience      []string
+	Scope         string
+	IssuedAt      time.Time
+	ExpiresAt     time.Time
+	TransactionID string
+}
+
+// Query selects entries
+// Empty fields match every entry.
+type Query struct {
+	// Subject matches the subject of the token exactly
+	Subject string
+
+	// Actor matches the subject of the actor exactly
+	Actor string
+
+	// JTI matches the token's "jti" claim exactly
+	JTI string
+
+	// Since excludes tokens issued before it
+	Since time.Time
+
+	// Limit caps the number of entries returned (0: no limit)
+	Limit int
+}
+
+// matches reports whether entry is selected by q
+func (q Query) matches(entry *Entry) bool {
+	switch {
+	case q.Subject != "" && (entry.Subject == nil || entry.Subject.Subject != q.Subject):
+		return false
+	case q.Actor != "" && (entry.Actor == nil || entry.Actor.Subject != q.Actor):
+		return false
+	case q.JTI != "" && entry.JTI != q.JTI:
+		return false
+	case !q.Since.IsZero() && entry.IssuedAt.Before(q.Since):
+		return false
+	}
+	return true
+}
+
+// Ledger records issued tokens in a ring of bounded size
+//
+// Once full, each new entry replaces the oldest. Entries older than the
+// retention are not returned either, so the ledger covers the last retention
+// at most. It lives in process memory: each replica records the tokens it
+// issued, and the record is lost on restart.
+type Ledger struct {
+	retention time.Duration
+	clock     clock.Clock
+
+	mu      sync.RWMutex
+	entries []Entry
+	next    int
+	full    bool
+}
+
+// Config configures a ledger
+type Config struct {
+	// MaxEntries is the number of entries kept (default: 10000)
+	MaxEntries int
+
+	// Retention is how long entries are kept (default: 24h)
+	Retention time.Duration
+
+	// Clock tells the age of entries (default: the system clock)
+	Clock clock.Clock
+}
+
+// New creates a ledger
+func New(cfg Config) (*Ledger, error) {
+	maxEntries := cfg.MaxEntries
+	if maxEntries < 0 {
+		return nil, fmt.Errorf("max entries must not be negative: %d", maxEntries)
+	} else if maxEntries == 0 {
+		maxEntries = 10000
+	}
+	retention := cfg.Retention
+	if retention < 0 {
+		return nil, fmt.Errorf("retention must not be negative: %s", retention)
+	} else if retention == 0 {
+		retention = 24 * time.Hour
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+	return &Ledger{
+		retention: retention,
+		clock:     clk,
+		entries:   make([]Entry, maxEntries),
+	}, nil
+}
+
+// Record adds an entry, replacing the oldest if the ledger is full
+func (l *Ledger) Record(entry Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Find returns the entries selected by q, most recently recorded first
+func (l *Ledger) Find(q Query) []Entry {
+	cutoff := l.clock.Now().Add(-l.retention)
+	if q.Since.Before(cutoff) {
+		q.Since = cutoff
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	count := l.next
+	if l.full {
+		count = len(l.entries)
+	}
+
+	found := []Entry{}
+	for i := 1; i <= count; i++ {
+		entry := &l.entries[(l.next-i+len(l.entries))%len(l.entries)]
+		if !q.matches(entry) {
+			continue
+		}
+		found = append(found, *entry)
+		if q.Limit > 0 && len(found) == q.Limit {
+			break
+		}
+	}
+	return found
+}
+
+// Retention returns how long entries are kept
+func (l *Ledger) Retention() time.Duration {
+	return l.retention
+}
@@ -0,0 +1,90 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+)
+
+func TestLedger_Find(t *testing.T) {
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	l, err := New(Config{MaxEntries: 3, Retention: time.Hour, Clock: clk})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	record := func(jti, subject, actor string) {
+		entry := Entry{JTI: jti, Subject: &Principal{Subject: subject}, IssuedAt: clk.Now()}
+		if actor != "" {
+			entry.Actor = &Principal{Subject: actor}
+		}
+		l.Record(entry)
+	}
+	jtis := func(entries []Entry) []string {
+		var out []string
+		for _, entry := range entries {
+			out = append(out, entry.JTI)
+		}
+		return out
+	}
+
+	record("a", "alice", "gateway")
+	clk.Advance(10 * time.Minute)
+	record("b", "bob", "gateway")
+	record("c", "alice", "")
+
+	t.Run("newest first", func(t *testing.T) {
+		if got := jtis(l.Find(Query{})); len(got) != 3 || got[0] != "c" || got[2] != "a" {
+			t.Errorf("expected [c b a], got %v", got)
+		}
+		if got := jtis(l.Find(Query{Limit: 1})); len(got) != 1 || got[0] != "c" {
+			t.Errorf("expected [c], got %v", got)
+		}
+	})
+
+	t.Run("by subject, actor and jti", func(t *testing.T) {
+		if got := jtis(l.Find(Query{Subject: "alice"})); len(got) != 2 || got[0] != "c" || got[1] != "a" {
+			t.Errorf("expected [c a], got %v", got)
+		}
+		if got := jtis(l.Find(Query{Actor: "gateway"})); len(got) != 2 || got[0] != "b" {
+			t.Errorf("expected [b a], got %v", got)
+		}
+		if got := jtis(l.Find(Query{JTI: "b"})); len(got) != 1 || got[0] != "b" {
+			t.Errorf("expected [b], got %v", got)
+		}
+		if got := l.Find(Query{Subject: "carol"}); got == nil || len(got) != 0 {
+			t.Errorf("expected no entries, got %v", got)
+		}
+	})
+
+	t.Run("since", func(t *testing.T) {
+		if got := jtis(l.Find(Query{Since: clk.Now().Add(-5 * time.Minute)})); len(got) != 2 {
+			t.Errorf("expected [c b], got %v", got)
+		}
+	})
+
+	t.Run("bounded size", func(t *testing.T) {
+		clk.Advance(10 * time.Minute)
+		record("d", "dave", "")
+		if got := jtis(l.Find(Query{})); len(got) != 3 || got[0] != "d" || got[2] != "b" {
+			t.Errorf("expected the oldest entry to be replaced, got %v", got)
+		}
+	})
+
+	t.Run("retention", func(t *testing.T) {
+		clk.Advance(time.Hour)
+		if got := jtis(l.Find(Query{})); len(got) != 1 || got[0] != "d" {
+			t.Errorf("expected entries older than the retention to be left out, got %v", got)
+		}
+	})
+}
+
+func TestNew_Errors(t *testing.T) {
+	if _, err := New(Config{MaxEntries: -1}); err == nil {
+		t.Error("expected an error for negative max entries")
+	}
+	if _, err := New(Config{Retention: -time.Hour}); err == nil {
+		t.Error("expected an error for a negative retention")
+	}
+}
@@ -0,0 +1,113 @@
+package ledger
+
+import (
+	"context"
+	"sync"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// observer records the tokens of each successful issuance
+type observer struct {
+	service.NoOpApplicationObserver
+	ledger *Ledger
+}
+
+// NewObserver creates an application observer recording every token issued
+// in ledger
+// Tokens of an issuance that fails as a whole are not returned to the caller,
+// so they are left out.
+func NewObserver(ledger *Ledger) service.ApplicationObserver {
+	return &observer{ledger: ledger}
+}
+
+// TokenIssuanceStarted implements service.TokenServiceObserver
+func (o *observer) TokenIssuanceStarted(
+	ctx context.Context,
+	subject *trust.Result,
+	actor *trust.Result,
+	scope string,
+	tokenTypes []service.TokenType,
+) (context.Context, service.TokenIssuanceProbe) {
+	return ctx, &issuanceProbe{
+		ledger:  o.ledger,
+		subject: principal(subject),
+		actor:   principal(actor),
+		scope:   scope,
+	}
+}
+
+// issuanceProbe collects the tokens of one issuance
+// Token types may be issued concurrently, so the entries are guarded.
+type issuanceProbe struct {
+	service.NoOpTokenIssuanceProbe
+	ledger  *Ledger
+	subject *Principal
+	actor   *Principal
+	scope   string
+
+	mu      sync.Mutex
+	entries []Entry
+	failed  bool
+}
+
+func (p *issuanceProbe) TokenTypeIssuanceSucceeded(tokenType service.TokenType, token *service.Token) {
+	if token == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.entries = append(p.entries, Entry{
+		Type:          string(tokenType),
+		JTI:           token.ID,
+		Subject:       p.subject,
+		Actor:         p.actor,
+		Audience:      token.Audience,
+		Scope:         p.scope,
+		IssuedAt:      token.IssuedAt.UTC(),
+		ExpiresAt:     token.ExpiresAt.UTC(),
+		TransactionID: token.TransactionID,
+	})
+}
+
+func (p *issuanceProbe) TokenTypeIssuanceFailed(tokenType service.TokenType, err error) {
+	p.fail()
+}
+
+func (p *issuanceProbe) IssuerNotFound(tokenType service.TokenType, err error) {
+	p.fail()
+}
+
+func (p *issuanceProbe) IssuanceRefused(err error) {
+	p.fail()
+}
+
+func (p *issuanceProbe) fail() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failed = true
+}
+
+func (p *issuanceProbe) End() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failed {
+		return
+	}
+	for _, entry := range p.entries {
+		p.ledger.Record(entry)
+	}
+}
+
+// principal returns the principal of a validated identity (nil if none)
+func principal(result *trust.Result) *Principal {
+	if result == nil {
+		return nil
+	}
+	return &Principal{
+		Subject:     result.Subject,
+		Issuer:      result.Issuer,
+		TrustDomain: result.TrustDomain,
+	}
+}
@@ -0,0 +1,78 @@
+package ledger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// stubIssuer issues a fixed token, or fails with err
+type stubIssuer struct {
+	err error
+}
+
+func (i *stubIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	if i.err != nil {
+		return nil, i.err
+	}
+	now := time.Now()
+	return &service.Token{
+		Value:     "secret-token",
+		IssuedAt:  now,
+		ExpiresAt: now.Add(5 * time.Minute),
+		ID:        "jti-1",
+		Audience:  issueCtx.TokenAudiences(),
+	}, nil
+}
+
+func (i *stubIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	return nil, nil
+}
+
+func TestObserver_RecordsIssuedTokens(t *testing.T) {
+	l, err := New(Config{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	registry := service.NewSimpleRegistry()
+	registry.Register(service.TokenTypeTransactionToken, &stubIssuer{})
+	registry.Register(service.TokenTypeAccessToken, &stubIssuer{err: perr.New(perr.ErrCodeIssuerUnavailable, "signer unavailable")})
+	tokenService := service.NewTokenService("trust.example.com", nil, registry, NewObserver(l))
+
+	subject := &trust.Result{Subject: "alice", Issuer: "https://idp.example.com", TrustDomain: "idp.example.com"}
+	actor := &trust.Result{Subject: "spiffe://example.com/gateway", TrustDomain: "example.com"}
+
+	if _, err := tokenService.IssueTokens(context.Background(), &service.IssueRequest{
+		Subject:    subject,
+		Actor:      actor,
+		Scope:      "orders:read",
+		TokenTypes: []service.TokenType{service.TokenTypeTransactionToken},
+	}); err != nil {
+		t.Fatalf("IssueTokens failed: %v", err)
+	}
+	// The transaction token of a failed issuance is not returned, so not recorded
+	_, _ = tokenService.IssueTokens(context.Background(), &service.IssueRequest{
+		Subject:    subject,
+		TokenTypes: []service.TokenType{service.TokenTypeTransactionToken, service.TokenTypeAccessToken},
+	})
+
+	entries := l.Find(Query{Actor: "spiffe://example.com/gateway"})
+	if len(entries) != 1 || len(l.Find(Query{})) != 1 {
+		t.Fatalf("expected 1 entry, got %+v", l.Find(Query{}))
+	}
+	entry := entries[0]
+	if entry.JTI != "jti-1" || entry.Type != string(service.TokenTypeTransactionToken) || entry.Scope != "orders:read" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.Subject.Subject != "alice" || entry.Subject.Issuer != "https://idp.example.com" || entry.Actor.TrustDomain != "example.com" {
+		t.Errorf("expected subject and actor, got %+v and %+v", entry.Subject, entry.Actor)
+	}
+	if len(entry.Audience) != 1 || entry.Audience[0] != "trust.example.com" || entry.ExpiresAt.IsZero() {
+		t.Errorf("expected audience and expiry, got %+v", entry)
+	}
+}
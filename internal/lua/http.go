@@ -90,7 +90,7 @@ func (s *HTTPService) luaHTTPGet(L *lua.LState) int {
 	url := L.CheckString(1)
 	headers := s.parseHeaders(L, 2)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(requestContext(L), "GET", url, nil)
 
 	if err != nil {
 		L.Push(lua.LNil)
@@ -131,7 +131,7 @@ func (s *HTTPService) luaHTTPPost(L *lua.LState) int {
 	body := L.CheckString(2)
 	headers := s.parseHeaders(L, 3)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBufferString(body))
+	req, err := http.NewRequestWithContext(requestContext(L), "POST", url, bytes.NewBufferString(body))
 	if err != nil {
 		L.Push(lua.LNil)
 		L.Push(lua.LString(fmt.Sprintf("failed to create request: %v", err)))
@@ -178,7 +178,7 @@ func (s *HTTPService) luaHTTPRequest(L *lua.LState) int {
 
 	headers := s.parseHeaders(L, 4)
 
-	req, err := http.NewRequest(method, url, body)
+	req, err := http.NewRequestWithContext(requestContext(L), method, url, body)
 	if err != nil {
 		L.Push(lua.LNil)
 		L.Push(lua.LString(fmt.Sprintf("failed to create request: %v", err)))
@@ -210,6 +210,15 @@ func (s *HTTPService) luaHTTPRequest(L *lua.LState) int {
 	return 1
 }
 
+// requestContext returns the context of the Lua state, so requests are
+// canceled with the script
+func requestContext(L *lua.LState) context.Context {
+	if ctx := L.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
 // parseHeaders converts a Lua table to Go map of headers
 func (s *HTTPService) parseHeaders(L *lua.LState, arg int) map[string]string {
 	headers := make(map[string]string)
@@ -10,6 +10,7 @@ import (
 	"github.com/project-kessel/parsec/internal/claims"
 	"github.com/project-kessel/parsec/internal/service"
 	"github.com/project-kessel/parsec/internal/trust"
+	"github.com/project-kessel/parsec/internal/trustdomain"
 )
 
 // CELMapper is a ClaimMapper that uses CEL (Common Expression Language) expressions
@@ -46,6 +47,10 @@ type CELMapper struct {
 	ast    *cel.Ast // Pre-compiled AST
 }
 
+// celInterruptCheckFrequency is how many comprehension iterations run between
+// checks for cancellation of the mapping context
+const celInterruptCheckFrequency = 100
+
 // NewCELMapper creates a new CEL-based claim mapper
 // The script should be a CEL expression that evaluates to a map of claims
 func NewCELMapper(script string) (*CELMapper, error) {
@@ -58,6 +63,7 @@ func NewCELMapper(script string) (*CELMapper, error) {
 	env, err := cel.NewEnv(
 		celhelpers.MapperInputLibrary(context.Background(), nil, nil),
 		celhelpers.RedHatHelpersLibrary(),
+		trustdomain.CELLibrary(),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
@@ -87,6 +93,7 @@ func (m *CELMapper) Map(ctx context.Context, input *service.MapperInput) (claims
 	env, err := cel.NewEnv(
 		celhelpers.MapperInputLibrary(ctx, input.DataSourceRegistry, input.DataSourceInput),
 		celhelpers.RedHatHelpersLibrary(),
+		trustdomain.CELLibrary(),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
@@ -94,7 +101,8 @@ func (m *CELMapper) Map(ctx context.Context, input *service.MapperInput) (claims
 
 	// Create program from the pre-compiled AST with the runtime environment
 	// This allows us to inject different datasources per invocation
-	program, err := env.Program(m.ast)
+	// Comprehensions check for cancellation so evaluation stops at the issuance deadline
+	program, err := env.Program(m.ast, cel.InterruptCheckFrequency(celInterruptCheckFrequency))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CEL program: %w", err)
 	}
@@ -103,7 +111,7 @@ func (m *CELMapper) Map(ctx context.Context, input *service.MapperInput) (claims
 	activation := m.createActivation(ctx, input)
 
 	// Evaluate the program with the activation
-	result, _, err := program.Eval(activation)
+	result, _, err := program.ContextEval(ctx, activation)
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate CEL expression: %w", err)
 	}
@@ -152,14 +160,39 @@ func (m *CELMapper) createActivation(ctx context.Context, input *service.MapperI
 				return nil
 			}
 
-			return map[string]any{
-				"method":     input.RequestAttributes.Method,
-				"path":       input.RequestAttributes.Path,
-				"ip_address": input.RequestAttributes.IPAddress,
-				"user_agent": input.RequestAttributes.UserAgent,
-				"headers":    input.RequestAttributes.Headers,
-				"additional": input.RequestAttributes.Additional,
+			req := map[string]any{
+				"method":      input.RequestAttributes.Method,
+				"path":        input.RequestAttributes.Path,
+				"scheme":      input.RequestAttributes.Scheme,
+				"port":        input.RequestAttributes.Port,
+				"body_sha256": input.RequestAttributes.BodySHA256,
+				"ip_address":  input.RequestAttributes.IPAddress,
+				"user_agent":  input.RequestAttributes.UserAgent,
+				"headers":     input.RequestAttributes.Headers,
+				"additional":  input.RequestAttributes.Additional,
+			}
+			// Like in validator filters, has(request.query) and has(request.tls)
+			// are false when the request has none
+			if query := input.RequestAttributes.QueryMap(); query != nil {
+				req["query"] = query
+			}
+			if tls := input.RequestAttributes.TLS.Map(); tls != nil {
+				req["tls"] = tls
+			}
+			// Empty without baggage, so has(request.baggage.tenant) works
+			req["baggage"] = map[string]string{}
+			if baggage := input.RequestAttributes.Baggage(); baggage != nil {
+				req["baggage"] = baggage
+			}
+			return req
+		}(),
+
+		// Unassessed issuances have an empty risk, so has(risk.level) is false
+		"risk": func() any {
+			if input.Risk == nil {
+				return map[string]any{}
 			}
+			return input.Risk.Map()
 		}(),
 	}
 
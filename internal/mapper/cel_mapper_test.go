@@ -273,6 +273,129 @@ func TestCELMapper_Map(t *testing.T) {
 		}
 	})
 
+	t.Run("access structured request attributes", func(t *testing.T) {
+		mapper, err := NewCELMapper(`{
+			"status": request.query.status[0],
+			"secure": request.scheme == "https" && has(request.tls) && request.tls.version == "TLSv1.3",
+			"port": request.port
+		}`)
+		if err != nil {
+			t.Fatalf("failed to create mapper: %v", err)
+		}
+
+		input := &service.MapperInput{
+			RequestAttributes: &request.RequestAttributes{
+				Path:   "/api/orders?status=open",
+				Query:  map[string][]string{"status": {"open"}},
+				Scheme: "https",
+				Port:   8443,
+				TLS:    &request.TLSInfo{SNI: "api.example.com", Version: "TLSv1.3"},
+			},
+		}
+
+		result, err := mapper.Map(ctx, input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result["status"] != "open" || result["secure"] != true || result["port"] != int64(8443) {
+			t.Errorf("unexpected result: %v", result)
+		}
+
+		// Without TLS, has(request.tls) is false
+		input.RequestAttributes.TLS = nil
+		result, err = mapper.Map(ctx, input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result["secure"] != false {
+			t.Errorf("expected secure=false without TLS, got %v", result["secure"])
+		}
+	})
+
+	t.Run("select baggage entries", func(t *testing.T) {
+		mapper, err := NewCELMapper(`has(request.baggage.tenant) ? {"tenant": request.baggage.tenant} : {}`)
+		if err != nil {
+			t.Fatalf("failed to create mapper: %v", err)
+		}
+
+		input := &service.MapperInput{
+			RequestAttributes: &request.RequestAttributes{
+				Headers: map[string]string{"baggage": "tenant=acme%20corp;ttl=60, experiment=blue"},
+			},
+		}
+		result, err := mapper.Map(ctx, input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(result) != 1 || result["tenant"] != "acme corp" {
+			t.Errorf("expected only the decoded tenant entry, got %v", result)
+		}
+
+		// Without baggage, or with malformed baggage, there are no entries
+		for _, header := range []string{"", "tenant"} {
+			input.RequestAttributes.Headers = map[string]string{"baggage": header}
+			result, err = mapper.Map(ctx, input)
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %v", header, err)
+			}
+			if len(result) != 0 {
+				t.Errorf("expected no claims for %q, got %v", header, result)
+			}
+		}
+	})
+
+	t.Run("bind to the request body digest", func(t *testing.T) {
+		mapper, err := NewCELMapper(`request.body_sha256 != "" ? {"body_sha256": request.body_sha256} : {}`)
+		if err != nil {
+			t.Fatalf("failed to create mapper: %v", err)
+		}
+
+		input := &service.MapperInput{
+			RequestAttributes: &request.RequestAttributes{Method: "POST", BodySHA256: "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"},
+		}
+		result, err := mapper.Map(ctx, input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result["body_sha256"] != input.RequestAttributes.BodySHA256 {
+			t.Errorf("unexpected result: %v", result)
+		}
+
+		input.RequestAttributes.BodySHA256 = ""
+		result, err = mapper.Map(ctx, input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(result) != 0 {
+			t.Errorf("expected no claims without a digest, got %v", result)
+		}
+	})
+
+	t.Run("tag with the risk level", func(t *testing.T) {
+		mapper, err := NewCELMapper(`has(risk.level) && risk.level == "high" ? {"step_up": true} : {}`)
+		if err != nil {
+			t.Fatalf("failed to create mapper: %v", err)
+		}
+
+		result, err := mapper.Map(ctx, &service.MapperInput{
+			Risk: &service.RiskAssessment{Level: service.RiskLevelHigh, Reasons: []string{"velocity"}},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result["step_up"] != true {
+			t.Errorf("unexpected result: %v", result)
+		}
+
+		result, err = mapper.Map(ctx, &service.MapperInput{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(result) != 0 {
+			t.Errorf("expected no claims without an assessment, got %v", result)
+		}
+	})
+
 	t.Run("access datasource", func(t *testing.T) {
 		mapper, err := NewCELMapper(`{
 			"roles": datasource("user_roles").roles,
@@ -564,3 +687,51 @@ func TestCELMapper_Map(t *testing.T) {
 		}
 	})
 }
+
+func TestCELMapper_Map_CanceledContext(t *testing.T) {
+	// A comprehension long enough to reach an interrupt check
+	m, err := NewCELMapper(`{"n": [0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19].map(x,
+		[0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19].map(y, x * y).size()).size()}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := m.Map(ctx, &service.MapperInput{}); err == nil {
+		t.Error("expected evaluation to stop when the context is canceled")
+	}
+}
+
+func BenchmarkCELMapper_Map(b *testing.B) {
+	celMapper, err := NewCELMapper(`{
+		"sub": subject.subject,
+		"email": subject.claims.email,
+		"roles": datasource("user_roles").roles,
+		"path": request.path
+	}`)
+	if err != nil {
+		b.Fatalf("failed to create mapper: %v", err)
+	}
+
+	registry := service.NewDataSourceRegistry()
+	registry.Register(&mockDataSource{name: "user_roles", data: map[string]any{"roles": []string{"admin", "developer"}}})
+	input := &service.MapperInput{
+		Subject: &trust.Result{
+			Subject: "alice",
+			Claims:  claims.Claims{"email": "alice@example.com"},
+		},
+		RequestAttributes:  &request.RequestAttributes{Method: "GET", Path: "/api/resource"},
+		DataSourceRegistry: registry,
+		DataSourceInput:    &service.DataSourceInput{},
+	}
+
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := celMapper.Map(ctx, input); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package mapper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+
+	celhelpers "github.com/project-kessel/parsec/internal/cel"
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// RequestContextTransform is a single step of a request context pipeline
+// It receives a copy of the claims built so far and returns the claims for the next step.
+// Any claims.ClaimsTransformer (e.g. redaction or hashing) can be used as a step.
+type RequestContextTransform = claims.ClaimsTransformer
+
+// RequestContextTransformFunc adapts a function to a RequestContextTransform
+type RequestContextTransformFunc func(c claims.Claims) (claims.Claims, error)
+
+// Transform implements RequestContextTransform
+func (f RequestContextTransformFunc) Transform(c claims.Claims) (claims.Claims, error) {
+	return f(c)
+}
+
+// RequestContextMapper is a ClaimMapper that normalizes the (filtered) request context
+// before it is embedded in a token.
+//
+// It starts from the same claims as the request_attributes mapper (method, path,
+// ip_address, user_agent and any additional request_context claims) and applies
+// each transform in order.
+type RequestContextMapper struct {
+	transforms []RequestContextTransform
+}
+
+// NewRequestContextMapper creates a request context mapper applying the transforms in order
+func NewRequestContextMapper(transforms ...RequestContextTransform) *RequestContextMapper {
+	return &RequestContextMapper{
+		transforms: transforms,
+	}
+}
+
+// Map implements the ClaimMapper interface
+func (m *RequestContextMapper) Map(ctx context.Context, input *service.MapperInput) (claims.Claims, error) {
+	result, err := service.NewRequestAttributesMapper().Map(ctx, input)
+	if err != nil || result == nil {
+		return result, err
+	}
+
+	for i, transform := range m.transforms {
+		result, err = transform.Transform(result.Copy())
+		if err != nil {
+			return nil, fmt.Errorf("request context transform %d failed: %w", i, err)
+		}
+	}
+
+	return result, nil
+}
+
+// RenameField moves a claim to a new name, replacing any existing claim with that name
+func RenameField(from, to string) RequestContextTransform {
+	return RequestContextTransformFunc(func(c claims.Claims) (claims.Claims, error) {
+		if value, ok := c[from]; ok {
+			delete(c, from)
+			c[to] = value
+		}
+		return c, nil
+	})
+}
+
+// DropField removes a claim
+func DropField(field string) RequestContextTransform {
+	return RequestContextTransformFunc(func(c claims.Claims) (claims.Claims, error) {
+		delete(c, field)
+		return c, nil
+	})
+}
+
+// LowercaseField lowercases a string claim; other values are left untouched
+func LowercaseField(field string) RequestContextTransform {
+	return mapStringField(field, strings.ToLower)
+}
+
+// UppercaseField uppercases a string claim; other values are left untouched
+func UppercaseField(field string) RequestContextTransform {
+	return mapStringField(field, strings.ToUpper)
+}
+
+func mapStringField(field string, fn func(string) string) RequestContextTransform {
+	return RequestContextTransformFunc(func(c claims.Claims) (claims.Claims, error) {
+		if s, ok := c[field].(string); ok {
+			c[field] = fn(s)
+		}
+		return c, nil
+	})
+}
+
+// ParseUserAgent parses the user agent string in field into a {"family", "version"}
+// map stored in target. Unparseable user agents produce no target claim.
+func ParseUserAgent(field, target string) RequestContextTransform {
+	return RequestContextTransformFunc(func(c claims.Claims) (claims.Claims, error) {
+		ua, ok := c[field].(string)
+		if !ok {
+			return c, nil
+		}
+		if family, version := parseUserAgent(ua); family != "" {
+			c[target] = map[string]any{
+				"family":  family,
+				"version": version,
+			}
+		}
+		return c, nil
+	})
+}
+
+// userAgentFamilies maps product tokens to browser families, most specific first
+// (e.g. Edge and Opera user agents also advertise Chrome and Safari)
+var userAgentFamilies = []struct {
+	token  string
+	family string
+}{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"Version/", "Safari"},
+}
+
+// parseUserAgent extracts the client family and version from a user agent string
+// Known browsers are detected by product token; anything else falls back to the
+// leading product (e.g. "curl/8.4.0" or "grpc-go/1.60.0")
+func parseUserAgent(ua string) (family, version string) {
+	for _, known := range userAgentFamilies {
+		if idx := strings.Index(ua, known.token); idx >= 0 {
+			return known.family, productVersion(ua[idx+len(known.token):])
+		}
+	}
+
+	product, _, _ := strings.Cut(strings.TrimSpace(ua), " ")
+	name, ver, ok := strings.Cut(product, "/")
+	if !ok || name == "" {
+		return "", ""
+	}
+	return name, ver
+}
+
+// productVersion returns the version following a product token
+func productVersion(s string) string {
+	if end := strings.IndexAny(s, " ;)"); end >= 0 {
+		return s[:end]
+	}
+	return s
+}
+
+// celRequestContextTransform derives a claim from a CEL expression
+type celRequestContextTransform struct {
+	field   string
+	program cel.Program
+}
+
+// NewCELTransform creates a transform that sets field to the result of a CEL expression.
+// The expression sees the claims built so far as request_context, e.g.
+//
+//	request_context.method + " " + request_context.path
+//
+// A null result removes the field.
+func NewCELTransform(field, expression string) (RequestContextTransform, error) {
+	if expression == "" {
+		return nil, fmt.Errorf("CEL expression cannot be empty")
+	}
+
+	env, err := cel.NewEnv(
+		cel.Variable("request_context", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("failed to compile CEL expression: %w", issues.Err())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL program: %w", err)
+	}
+
+	return &celRequestContextTransform{
+		field:   field,
+		program: program,
+	}, nil
+}
+
+// Transform implements RequestContextTransform
+func (t *celRequestContextTransform) Transform(c claims.Claims) (claims.Claims, error) {
+	result, _, err := t.program.Eval(map[string]any{
+		"request_context": map[string]any(c),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate CEL expression for %s: %w", t.field, err)
+	}
+
+	if result == types.NullValue {
+		delete(c, t.field)
+		return c, nil
+	}
+	c[t.field] = celhelpers.ConvertCELValue(result)
+	return c, nil
+}
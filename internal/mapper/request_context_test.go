@@ -0,0 +1,111 @@
+package mapper
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+func TestRequestContextMapper(t *testing.T) {
+	deriveRoute, err := NewCELTransform("route", `request_context.verb + " " + request_context.path`)
+	if err != nil {
+		t.Fatalf("failed to create CEL transform: %v", err)
+	}
+
+	m := NewRequestContextMapper(
+		LowercaseField("method"),
+		RenameField("method", "verb"),
+		ParseUserAgent("user_agent", "client"),
+		DropField("user_agent"),
+		deriveRoute,
+	)
+
+	input := &service.MapperInput{
+		RequestAttributes: &request.RequestAttributes{
+			Method:    "GET",
+			Path:      "/orders",
+			UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.109 Safari/537.36",
+			Additional: map[string]any{
+				"tenant": "acme",
+			},
+		},
+	}
+
+	got, err := m.Map(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]any{
+		"verb":   "get",
+		"path":   "/orders",
+		"tenant": "acme",
+		"route":  "get /orders",
+		"client": map[string]any{"family": "Chrome", "version": "120.0.6099.109"},
+	}
+	if !reflect.DeepEqual(map[string]any(got), want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// The input request attributes must not be modified
+	if input.RequestAttributes.Method != "GET" || input.RequestAttributes.Additional["tenant"] != "acme" {
+		t.Errorf("input request attributes were modified: %+v", input.RequestAttributes)
+	}
+}
+
+func TestRequestContextMapper_NoRequestAttributes(t *testing.T) {
+	m := NewRequestContextMapper(DropField("path"))
+
+	got, err := m.Map(context.Background(), &service.MapperInput{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != nil {
+		t.Errorf("expected nil claims, got %v", got)
+	}
+}
+
+func TestNewCELTransform_Errors(t *testing.T) {
+	if _, err := NewCELTransform("x", ""); err == nil {
+		t.Error("expected error for empty expression")
+	}
+	if _, err := NewCELTransform("x", "request_context."); err == nil {
+		t.Error("expected compile error")
+	}
+
+	transform, err := NewCELTransform("x", `request_context.missing`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := NewRequestContextMapper(transform)
+	_, err = m.Map(context.Background(), &service.MapperInput{RequestAttributes: request.FromClaims(nil)})
+	if err == nil || !strings.Contains(err.Error(), "request context transform 0 failed") {
+		t.Errorf("expected evaluation error, got %v", err)
+	}
+}
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		ua      string
+		family  string
+		version string
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91", "Edge", "120.0.2210.91"},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0", "Firefox", "121.0"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1", "Safari", "17.2"},
+		{"curl/8.4.0", "curl", "8.4.0"},
+		{"grpc-go/1.60.0", "grpc-go", "1.60.0"},
+		{"unknown", "", ""},
+	}
+
+	for _, tt := range tests {
+		family, version := parseUserAgent(tt.ua)
+		if family != tt.family || version != tt.version {
+			t.Errorf("parseUserAgent(%q) = %q, %q; want %q, %q", tt.ua, family, version, tt.family, tt.version)
+		}
+	}
+}
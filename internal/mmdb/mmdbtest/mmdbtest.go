@@ -0,0 +1,344 @@
+// Package mmdbtest writes small MaxMind DB files for tests, since the real
+// databases cannot be redistributed.
+//
+//	buf, err := mmdbtest.Build(mmdbtest.Database{
+//		Networks: []mmdbtest.Network{{
+//			Prefix: netip.MustParsePrefix("203.0.113.0/24"),
+//			Record: map[string]any{"country": map[string]any{"iso_code": "AU"}},
+//		}},
+//	})
+//
+// Repeated strings are written once and referenced through pointers, as in
+// real databases.
+package mmdbtest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"net/netip"
+	"slices"
+)
+
+// Database describes a database to write
+type Database struct {
+	// DatabaseType is the type in the metadata (default: "Test")
+	DatabaseType string
+
+	// IPVersion is 4 or 6 (default: 6). IPv4 networks of IPv6 databases are
+	// written under ::/96.
+	IPVersion int
+
+	// RecordSize is 24, 28 or 32 bits (default: 24)
+	RecordSize int
+
+	// Networks are the records of the database; they must not overlap
+	Networks []Network
+}
+
+// Network is the record of a network
+type Network struct {
+	Prefix netip.Prefix
+
+	// Record is a map, slice, string, bool, float32, float64, int, int32,
+	// uint16, uint32, uint64, []byte or *big.Int value, or a composite of
+	// them
+	Record any
+}
+
+// node is a search tree node; each side holds a child node, a record, or
+// neither
+type node struct {
+	child  [2]int // index+1 of the child node, or 0
+	record [2]int // index+1 of the record, or 0
+}
+
+// Build returns the database as an .mmdb file
+func Build(db Database) ([]byte, error) {
+	if db.IPVersion == 0 {
+		db.IPVersion = 6
+	}
+	if db.RecordSize == 0 {
+		db.RecordSize = 24
+	}
+	if db.DatabaseType == "" {
+		db.DatabaseType = "Test"
+	}
+
+	nodes := []node{{}}
+	for i, network := range db.Networks {
+		bits, length, err := prefixBits(network.Prefix, db.IPVersion)
+		if err != nil {
+			return nil, err
+		}
+		if length == 0 {
+			return nil, fmt.Errorf("network %s: a database cannot hold a single network of every address", network.Prefix)
+		}
+		n := 0
+		for depth := range length - 1 {
+			bit := bits[depth]
+			if nodes[n].record[bit] != 0 {
+				return nil, fmt.Errorf("network %s overlaps another network", network.Prefix)
+			}
+			if nodes[n].child[bit] == 0 {
+				nodes = append(nodes, node{})
+				nodes[n].child[bit] = len(nodes)
+			}
+			n = nodes[n].child[bit] - 1
+		}
+		bit := bits[length-1]
+		if nodes[n].child[bit] != 0 || nodes[n].record[bit] != 0 {
+			return nil, fmt.Errorf("network %s overlaps another network", network.Prefix)
+		}
+		nodes[n].record[bit] = i + 1
+	}
+
+	data := newEncoder(true)
+	offsets := make([]int, len(db.Networks))
+	for i, network := range db.Networks {
+		offsets[i] = data.buf.Len()
+		if err := data.encode(network.Record); err != nil {
+			return nil, fmt.Errorf("network %s: %w", network.Prefix, err)
+		}
+	}
+
+	var out bytes.Buffer
+	nodeCount := len(nodes)
+	for _, n := range nodes {
+		var records [2]uint64
+		for bit := range 2 {
+			switch {
+			case n.child[bit] != 0:
+				records[bit] = uint64(n.child[bit] - 1)
+			case n.record[bit] != 0:
+				records[bit] = uint64(nodeCount + 16 + offsets[n.record[bit]-1])
+			default:
+				records[bit] = uint64(nodeCount)
+			}
+			if records[bit] >= 1<<db.RecordSize {
+				return nil, fmt.Errorf("database too large for %d-bit records", db.RecordSize)
+			}
+		}
+		writeNode(&out, db.RecordSize, records)
+	}
+	out.Write(make([]byte, 16))
+	out.Write(data.buf.Bytes())
+
+	out.WriteString("\xab\xcd\xefMaxMind.com")
+	metadata := newEncoder(false)
+	if err := metadata.encode(map[string]any{
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(db.RecordSize),
+		"ip_version":                  uint16(db.IPVersion),
+		"database_type":               db.DatabaseType,
+		"languages":                   []any{"en"},
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1700000000),
+		"description":                 map[string]any{"en": "Test database"},
+	}); err != nil {
+		return nil, err
+	}
+	out.Write(metadata.buf.Bytes())
+	return out.Bytes(), nil
+}
+
+// prefixBits returns the bits of prefix as stored in the search tree
+func prefixBits(prefix netip.Prefix, ipVersion int) ([]byte, int, error) {
+	addr := prefix.Addr()
+	length := prefix.Bits()
+	switch {
+	case !prefix.IsValid():
+		return nil, 0, fmt.Errorf("invalid network %s", prefix)
+	case ipVersion == 4 && !addr.Is4():
+		return nil, 0, fmt.Errorf("network %s is not IPv4", prefix)
+	case ipVersion == 6 && addr.Is4():
+		// As16 maps to ::ffff:a.b.c.d; IPv4 networks live under ::/96
+		b := addr.As16()
+		b[10], b[11] = 0, 0
+		addr = netip.AddrFrom16(b)
+		length += 96
+	}
+
+	raw := addr.AsSlice()
+	bits := make([]byte, len(raw)*8)
+	for i := range bits {
+		bits[i] = (raw[i/8] >> (7 - i%8)) & 1
+	}
+	return bits, length, nil
+}
+
+// writeNode writes a search tree node
+func writeNode(out *bytes.Buffer, recordSize int, records [2]uint64) {
+	left, right := records[0], records[1]
+	switch recordSize {
+	case 24:
+		out.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left), byte(right >> 16), byte(right >> 8), byte(right)})
+	case 28:
+		out.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left),
+			byte(left>>20)&0xf0 | byte(right>>24)&0x0f,
+			byte(right >> 16), byte(right >> 8), byte(right)})
+	default:
+		out.Write(binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, uint32(left)), uint32(right)))
+	}
+}
+
+// encoder writes data section values
+type encoder struct {
+	buf     bytes.Buffer
+	strings map[string]int // offsets of written strings, if deduplicated
+}
+
+func newEncoder(dedupe bool) *encoder {
+	e := &encoder{}
+	if dedupe {
+		e.strings = make(map[string]int)
+	}
+	return e
+}
+
+// Data section types
+const (
+	typePointer = 1
+	typeString  = 2
+	typeDouble  = 3
+	typeBytes   = 4
+	typeUint16  = 5
+	typeUint32  = 6
+	typeMap     = 7
+	typeInt32   = 8
+	typeUint64  = 9
+	typeUint128 = 10
+	typeArray   = 11
+	typeBool    = 14
+	typeFloat   = 15
+)
+
+func (e *encoder) encode(value any) error {
+	switch v := value.(type) {
+	case map[string]any:
+		e.control(typeMap, len(v))
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			e.string(key)
+			if err := e.encode(v[key]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		e.control(typeArray, len(v))
+		for _, item := range v {
+			if err := e.encode(item); err != nil {
+				return err
+			}
+		}
+	case string:
+		e.string(v)
+	case bool:
+		size := 0
+		if v {
+			size = 1
+		}
+		e.control(typeBool, size)
+	case float64:
+		e.control(typeDouble, 8)
+		e.buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(v)))
+	case float32:
+		e.control(typeFloat, 4)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, math.Float32bits(v)))
+	case []byte:
+		e.control(typeBytes, len(v))
+		e.buf.Write(v)
+	case int:
+		return e.encode(int32(v))
+	case int32:
+		e.control(typeInt32, 4)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(v)))
+	case uint16:
+		e.uint(typeUint16, uint64(v))
+	case uint32:
+		e.uint(typeUint32, uint64(v))
+	case uint64:
+		e.uint(typeUint64, v)
+	case *big.Int:
+		b := v.Bytes()
+		e.control(typeUint128, len(b))
+		e.buf.Write(b)
+	default:
+		return fmt.Errorf("unsupported record value %T", value)
+	}
+	return nil
+}
+
+// string writes s, or a pointer to where it was written before
+func (e *encoder) string(s string) {
+	if offset, ok := e.strings[s]; ok {
+		e.pointer(offset)
+		return
+	}
+	if e.strings != nil {
+		e.strings[s] = e.buf.Len()
+	}
+	e.control(typeString, len(s))
+	e.buf.WriteString(s)
+}
+
+// uint writes v in as few bytes as it takes
+func (e *encoder) uint(kind int, v uint64) {
+	b := binary.BigEndian.AppendUint64(nil, v)
+	b = bytes.TrimLeft(b, "\x00")
+	e.control(kind, len(b))
+	e.buf.Write(b)
+}
+
+// pointer writes a pointer to offset
+func (e *encoder) pointer(offset int) {
+	switch {
+	case offset < 1<<11:
+		e.buf.Write([]byte{typePointer<<5 | byte(offset>>8), byte(offset)})
+	case offset < 526336:
+		p := offset - 2048
+		e.buf.Write([]byte{typePointer<<5 | 1<<3 | byte(p>>16)&0x7, byte(p >> 8), byte(p)})
+	case offset < 134744064:
+		p := offset - 526336
+		e.buf.Write([]byte{typePointer<<5 | 2<<3 | byte(p>>24)&0x7, byte(p >> 16), byte(p >> 8), byte(p)})
+	default:
+		e.buf.Write(binary.BigEndian.AppendUint32([]byte{typePointer<<5 | 3<<3}, uint32(offset)))
+	}
+}
+
+// control writes the control byte(s) of a value
+func (e *encoder) control(kind, size int) {
+	var ctrl byte
+	var extended []byte
+	if kind <= 7 {
+		ctrl = byte(kind) << 5
+	} else {
+		extended = []byte{byte(kind - 7)}
+	}
+
+	var sizeBytes []byte
+	switch {
+	case size < 29:
+		ctrl |= byte(size)
+	case size < 285:
+		ctrl |= 29
+		sizeBytes = []byte{byte(size - 29)}
+	case size < 65821:
+		ctrl |= 30
+		sizeBytes = binary.BigEndian.AppendUint16(nil, uint16(size-285))
+	default:
+		ctrl |= 31
+		s := size - 65821
+		sizeBytes = []byte{byte(s >> 16), byte(s >> 8), byte(s)}
+	}
+	e.buf.WriteByte(ctrl)
+	e.buf.Write(extended)
+	e.buf.Write(sizeBytes)
+}
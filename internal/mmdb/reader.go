@@ -0,0 +1,405 @@
+// Package mmdb reads MaxMind DB files (.mmdb), the binary format of GeoIP2,
+// GeoLite2 and compatible IP databases such as DB-IP and IPinfo.
+//
+// The format is a binary search tree over the bits of IP addresses, whose
+// leaves point into a data section of self-describing records, followed by
+// a metadata map. See https://maxmind.github.io/MaxMind-DB/.
+//
+// A Reader holds the whole database in memory and is safe for concurrent use.
+package mmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"net/netip"
+)
+
+// metadataMarker precedes the metadata map at the end of the file
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// maxMetadataSize bounds the search for the metadata marker
+const maxMetadataSize = 128 << 10
+
+// dataSectionSeparator is the size of the zeroes between the search tree and
+// the data section
+const dataSectionSeparator = 16
+
+// maxDepth bounds the nesting of decoded records
+const maxDepth = 64
+
+// Metadata describes a database
+type Metadata struct {
+	// DatabaseType is the kind of database, e.g. "GeoLite2-Country"
+	DatabaseType string
+
+	// IPVersion is 4 for IPv4-only databases and 6 for databases holding
+	// IPv6 (and usually IPv4) networks
+	IPVersion int
+
+	// NodeCount is the number of nodes of the search tree
+	NodeCount uint
+
+	// RecordSize is the size of the search tree records in bits
+	RecordSize int
+
+	// BuildEpoch is when the database was built, in seconds since the epoch
+	BuildEpoch uint64
+}
+
+// Reader looks up IP addresses in a database
+type Reader struct {
+	metadata  Metadata
+	tree      []byte
+	data      []byte
+	nodeBytes uint
+	ipv4Start uint
+}
+
+// Open parses a database held in buf
+func Open(buf []byte) (*Reader, error) {
+	start := bytes.LastIndex(buf[max(0, len(buf)-maxMetadataSize):], metadataMarker)
+	if start < 0 {
+		return nil, fmt.Errorf("invalid MaxMind DB: metadata not found")
+	}
+	start += max(0, len(buf)-maxMetadataSize) + len(metadataMarker)
+
+	raw, _, err := (&decoder{buf: buf[start:]}).decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MaxMind DB metadata: %w", err)
+	}
+	fields, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid MaxMind DB metadata: not a map")
+	}
+	metadata := Metadata{
+		NodeCount:  uint(metadataUint(fields, "node_count")),
+		RecordSize: int(metadataUint(fields, "record_size")),
+		IPVersion:  int(metadataUint(fields, "ip_version")),
+		BuildEpoch: metadataUint(fields, "build_epoch"),
+	}
+	metadata.DatabaseType, _ = fields["database_type"].(string)
+
+	if major := metadataUint(fields, "binary_format_major_version"); major != 2 {
+		return nil, fmt.Errorf("unsupported MaxMind DB format version %d", major)
+	}
+	switch metadata.RecordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("unsupported MaxMind DB record size %d", metadata.RecordSize)
+	}
+	if metadata.IPVersion != 4 && metadata.IPVersion != 6 {
+		return nil, fmt.Errorf("unsupported MaxMind DB IP version %d", metadata.IPVersion)
+	}
+
+	nodeBytes := uint(metadata.RecordSize) / 4
+	treeSize := metadata.NodeCount * nodeBytes
+	dataStart := treeSize + dataSectionSeparator
+	metadataStart := uint(start - len(metadataMarker))
+	if metadata.NodeCount == 0 || dataStart > metadataStart {
+		return nil, fmt.Errorf("invalid MaxMind DB: search tree of %d nodes does not fit", metadata.NodeCount)
+	}
+
+	r := &Reader{
+		metadata:  metadata,
+		tree:      buf[:treeSize],
+		data:      buf[dataStart:metadataStart],
+		nodeBytes: nodeBytes,
+	}
+	if metadata.IPVersion == 6 {
+		// IPv4 addresses are looked up under ::/96
+		node := uint(0)
+		for i := 0; i < 96 && node < metadata.NodeCount; i++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// Metadata returns the metadata of the database
+func (r *Reader) Metadata() Metadata {
+	return r.metadata
+}
+
+// Lookup returns the record of the network holding addr, or nil if the
+// database has none
+// Records are decoded into maps, slices, strings, bools, float64, int32,
+// uint64 and []byte values; 128-bit integers are decoded as *big.Int.
+func (r *Reader) Lookup(addr netip.Addr) (any, error) {
+	addr = addr.Unmap()
+	node := uint(0)
+	bits := 128
+	if addr.Is4() {
+		bits = 32
+		node = r.ipv4Start
+	} else if r.metadata.IPVersion == 4 {
+		return nil, fmt.Errorf("cannot look up IPv6 address %s in an IPv4 database", addr)
+	}
+
+	ip := addr.AsSlice()
+	for i := 0; i < bits && node < r.metadata.NodeCount; i++ {
+		bit := (ip[i/8] >> (7 - uint(i%8))) & 1
+		node = r.record(node, uint(bit))
+	}
+
+	switch {
+	case node == r.metadata.NodeCount:
+		return nil, nil
+	case node < r.metadata.NodeCount:
+		return nil, fmt.Errorf("invalid MaxMind DB: search tree deeper than %d bits", bits)
+	}
+	offset := node - r.metadata.NodeCount - dataSectionSeparator
+	if offset >= uint(len(r.data)) {
+		return nil, fmt.Errorf("invalid MaxMind DB: record pointer %d out of range", node)
+	}
+	value, _, err := (&decoder{buf: r.data}).decode(offset, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MaxMind DB record: %w", err)
+	}
+	return value, nil
+}
+
+// record returns the left (bit 0) or right (bit 1) record of node
+func (r *Reader) record(node, bit uint) uint {
+	b := r.tree[node*r.nodeBytes : (node+1)*r.nodeBytes]
+	switch r.metadata.RecordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// metadataUint returns the unsigned integer metadata field key (0 if absent)
+func metadataUint(fields map[string]any, key string) uint64 {
+	switch v := fields[key].(type) {
+	case uint64:
+		return v
+	case int32:
+		return uint64(max(v, 0))
+	}
+	return 0
+}
+
+// Data section types
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// decoder decodes values of a data section
+type decoder struct {
+	buf []byte
+}
+
+// decode decodes the value at offset, returning it and the offset after it
+func (d *decoder) decode(offset uint, depth int) (any, uint, error) {
+	if depth > maxDepth {
+		return nil, 0, fmt.Errorf("values nested deeper than %d", maxDepth)
+	}
+	kind, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if kind == typePointer {
+		target, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		// A pointer's value is the value it points to, which cannot be
+		// another pointer; decoding resumes after the pointer itself
+		if k, _, _, err := d.control(target); err != nil || k == typePointer {
+			return nil, 0, fmt.Errorf("invalid pointer at offset %d", offset)
+		}
+		value, _, err := d.decode(target, depth+1)
+		return value, next, err
+	}
+
+	switch kind {
+	case typeMap:
+		m := make(map[string]any, min(size, 64))
+		for range size {
+			var key, value any
+			key, offset, err = d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("map key is a %T, not a string", key)
+			}
+			value, offset, err = d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[name] = value
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, 0, min(size, 64))
+		for range size {
+			var value any
+			value, offset, err = d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+		}
+		return a, offset, nil
+	case typeBool:
+		if size > 1 {
+			return nil, 0, fmt.Errorf("invalid boolean size %d", size)
+		}
+		return size == 1, offset, nil
+	}
+
+	end := offset + size
+	if end > uint(len(d.buf)) || end < offset {
+		return nil, 0, fmt.Errorf("value at offset %d overruns the data section", offset)
+	}
+	b := d.buf[offset:end]
+	switch kind {
+	case typeString:
+		return string(b), end, nil
+	case typeBytes:
+		return bytes.Clone(b), end, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("invalid double size %d", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), end, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("invalid float size %d", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), end, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > uintSize(kind) {
+			return nil, 0, fmt.Errorf("invalid unsigned integer size %d", size)
+		}
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, end, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("invalid int32 size %d", size)
+		}
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		return int32(v), end, nil
+	case typeUint128:
+		if size > 16 {
+			return nil, 0, fmt.Errorf("invalid uint128 size %d", size)
+		}
+		return new(big.Int).SetBytes(b), end, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported data type %d at offset %d", kind, offset)
+	}
+}
+
+// uintSize returns the maximum size in bytes of an unsigned integer type
+func uintSize(kind uint) uint {
+	switch kind {
+	case typeUint16:
+		return 2
+	case typeUint32:
+		return 4
+	default:
+		return 8
+	}
+}
+
+// control decodes the control byte(s) at offset, returning the type and
+// size of the value and the offset of its payload
+// For pointers, size is the raw size bits of the control byte.
+func (d *decoder) control(offset uint) (kind, size, next uint, err error) {
+	if offset >= uint(len(d.buf)) {
+		return 0, 0, 0, fmt.Errorf("offset %d out of range", offset)
+	}
+	ctrl := d.buf[offset]
+	offset++
+	kind = uint(ctrl >> 5)
+	if kind == typeExtended {
+		if offset >= uint(len(d.buf)) {
+			return 0, 0, 0, fmt.Errorf("offset %d out of range", offset)
+		}
+		kind = 7 + uint(d.buf[offset])
+		offset++
+	}
+	if kind == typePointer {
+		return kind, uint(ctrl & 0x1f), offset, nil
+	}
+
+	size = uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(d.buf)) {
+			return 0, 0, 0, fmt.Errorf("offset %d out of range", offset)
+		}
+		var extra uint
+		for _, c := range d.buf[offset : offset+n] {
+			extra = extra<<8 | uint(c)
+		}
+		offset += n
+		switch n {
+		case 1:
+			size = 29 + extra
+		case 2:
+			size = 285 + extra
+		default:
+			size = 65821 + extra
+		}
+	}
+	return kind, size, offset, nil
+}
+
+// pointer decodes a pointer whose control byte held bits, with its
+// remaining bytes at offset, returning its target and the offset after it
+func (d *decoder) pointer(bits, offset uint) (target, next uint, err error) {
+	n := (bits>>3)&0x3 + 1
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, fmt.Errorf("offset %d out of range", offset)
+	}
+	var p uint
+	if n < 4 {
+		p = bits & 0x7
+	}
+	for _, c := range d.buf[offset : offset+n] {
+		p = p<<8 | uint(c)
+	}
+	switch n {
+	case 2:
+		p += 2048
+	case 3:
+		p += 526336
+	}
+	return p, offset + n, nil
+}
@@ -0,0 +1,147 @@
+package mmdb_test
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/mmdb"
+	"github.com/project-kessel/parsec/internal/mmdb/mmdbtest"
+)
+
+func mustOpen(t *testing.T, db mmdbtest.Database) *mmdb.Reader {
+	t.Helper()
+	buf, err := mmdbtest.Build(db)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	reader, err := mmdb.Open(buf)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	return reader
+}
+
+func country(isoCode string) map[string]any {
+	return map[string]any{
+		"country": map[string]any{
+			"iso_code": isoCode,
+			"names":    map[string]any{"en": isoCode},
+		},
+	}
+}
+
+func TestReader_Lookup(t *testing.T) {
+	for _, recordSize := range []int{24, 28, 32} {
+		t.Run(fmt.Sprintf("%d-bit records", recordSize), func(t *testing.T) {
+			reader := mustOpen(t, mmdbtest.Database{
+				DatabaseType: "GeoLite2-Country",
+				RecordSize:   recordSize,
+				Networks: []mmdbtest.Network{
+					{Prefix: netip.MustParsePrefix("203.0.113.0/24"), Record: country("AU")},
+					{Prefix: netip.MustParsePrefix("198.51.100.128/25"), Record: country("US")},
+					{Prefix: netip.MustParsePrefix("2001:db8::/32"), Record: country("DE")},
+				},
+			})
+			if md := reader.Metadata(); md.DatabaseType != "GeoLite2-Country" || md.IPVersion != 6 || md.RecordSize != recordSize {
+				t.Errorf("unexpected metadata: %+v", md)
+			}
+
+			for addr, want := range map[string]any{
+				"203.0.113.7":          country("AU"),
+				"::ffff:203.0.113.255": country("AU"),
+				"198.51.100.200":       country("US"),
+				"198.51.100.1":         nil,
+				"2001:db8::1":          country("DE"),
+				"2001:db9::1":          nil,
+				"192.0.2.1":            nil,
+			} {
+				got, err := reader.Lookup(netip.MustParseAddr(addr))
+				if err != nil {
+					t.Fatalf("Lookup(%s) failed: %v", addr, err)
+				}
+				if want == nil && got != nil || want != nil && !reflect.DeepEqual(got, want) {
+					t.Errorf("Lookup(%s) = %v, want %v", addr, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReader_Lookup_IPv4Database(t *testing.T) {
+	reader := mustOpen(t, mmdbtest.Database{
+		IPVersion: 4,
+		Networks:  []mmdbtest.Network{{Prefix: netip.MustParsePrefix("10.0.0.0/8"), Record: "private"}},
+	})
+
+	got, err := reader.Lookup(netip.MustParseAddr("10.1.2.3"))
+	if err != nil || got != "private" {
+		t.Errorf("expected the IPv4 record, got %v, %v", got, err)
+	}
+	if _, err := reader.Lookup(netip.MustParseAddr("2001:db8::1")); err == nil {
+		t.Error("expected an error looking up an IPv6 address")
+	}
+}
+
+func TestReader_Lookup_Types(t *testing.T) {
+	record := map[string]any{
+		"asn":      uint32(64496),
+		"small":    uint16(7),
+		"big":      uint64(1) << 40,
+		"huge":     new(big.Int).Lsh(big.NewInt(1), 100),
+		"negative": int32(-12),
+		"ratio":    0.25,
+		"float":    float32(1.5),
+		"flag":     true,
+		"off":      false,
+		"raw":      []byte{0, 1, 2},
+		"list":     []any{"a", "b", "a"},
+		"long":     string(make([]byte, 300)),
+	}
+	reader := mustOpen(t, mmdbtest.Database{
+		Networks: []mmdbtest.Network{{Prefix: netip.MustParsePrefix("192.0.2.0/24"), Record: record}},
+	})
+
+	got, err := reader.Lookup(netip.MustParseAddr("192.0.2.1"))
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	want := map[string]any{
+		"asn":      uint64(64496),
+		"small":    uint64(7),
+		"big":      uint64(1) << 40,
+		"huge":     new(big.Int).Lsh(big.NewInt(1), 100),
+		"negative": int32(-12),
+		"ratio":    0.25,
+		"float":    1.5,
+		"flag":     true,
+		"off":      false,
+		"raw":      []byte{0, 1, 2},
+		"list":     []any{"a", "b", "a"},
+		"long":     string(make([]byte, 300)),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lookup = %#v, want %#v", got, want)
+	}
+}
+
+func TestOpen_Invalid(t *testing.T) {
+	valid, err := mmdbtest.Build(mmdbtest.Database{
+		Networks: []mmdbtest.Network{{Prefix: netip.MustParsePrefix("192.0.2.0/24"), Record: "x"}},
+	})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	for name, buf := range map[string][]byte{
+		"empty":          nil,
+		"no metadata":    []byte("not a database"),
+		"truncated tree": valid[len(valid)/2:],
+	} {
+		if _, err := mmdb.Open(buf); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
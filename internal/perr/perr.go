@@ -0,0 +1,321 @@
+// Package perr defines parsec's error taxonomy.
+//
+// Errors that callers need to tell apart carry a Code. Codes survive wrapping
+// with fmt.Errorf("...: %w", err), so a package can attach a code where the
+// failure is understood and let callers add context freely. The outermost code
+// in a chain wins, which lets a caller reclassify an error with more context
+// (an invalid token becomes an invalid subject token or an invalid actor).
+//
+// Codes map consistently to gRPC status codes, HTTP statuses, OAuth error
+// values and metric or log labels, so transports never inspect error strings.
+package perr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Domain is the ErrorInfo domain of parsec error details
+const Domain = "parsec.kessel.project"
+
+// Code classifies a parsec error
+// Codes are stable and safe to use as metric and log labels.
+type Code string
+
+const (
+	// ErrCodeInternal is an unexpected failure; it is the code of uncoded errors
+	ErrCodeInternal Code = "internal"
+
+	// ErrCodeInvalidRequest is a malformed or incomplete request
+	ErrCodeInvalidRequest Code = "invalid_request"
+
+	// ErrCodeUnsupportedGrantType is a token exchange with an unknown grant_type
+	ErrCodeUnsupportedGrantType Code = "unsupported_grant_type"
+
+	// ErrCodeUnsupportedTokenType is a request for a token type no issuer handles
+	ErrCodeUnsupportedTokenType Code = "unsupported_token_type"
+
+	// ErrCodeInvalidAudience is a requested audience parsec cannot issue for
+	ErrCodeInvalidAudience Code = "invalid_audience"
+
+	// ErrCodeInvalidRequestContext is request_context that cannot be decoded
+	// or does not satisfy its schema
+	ErrCodeInvalidRequestContext Code = "invalid_request_context"
+
+	// ErrCodeMissingCredential is a request without a usable credential
+	ErrCodeMissingCredential Code = "missing_credential"
+
+	// ErrCodeInvalidToken is a credential that failed validation
+	ErrCodeInvalidToken Code = "invalid_token"
+
+	// ErrCodeExpiredToken is a credential that has expired
+	ErrCodeExpiredToken Code = "expired_token"
+
+	// ErrCodeInvalidSubjectToken is a subject credential that failed validation
+	ErrCodeInvalidSubjectToken Code = "invalid_subject_token"
+
+	// ErrCodeInvalidActor is an actor credential that failed validation
+	ErrCodeInvalidActor Code = "invalid_actor"
+
+	// ErrCodeActorDenied is an authenticated actor that is not allowed the request
+	ErrCodeActorDenied Code = "actor_denied"
+
+	// ErrCodeDelegationDenied is an actor that may not act for the subject
+	ErrCodeDelegationDenied Code = "delegation_denied"
+
+	// ErrCodeClientDenied is a request from a client address that is not allowed
+	ErrCodeClientDenied Code = "client_denied"
+
+	// ErrCodeRiskDenied is an issuance a risk evaluator judged too risky
+	ErrCodeRiskDenied Code = "risk_denied"
+
+	// ErrCodeReplacementDenied is a transaction token replacement that changes
+	// claims its issuer's replacement policy does not allow to change
+	ErrCodeReplacementDenied Code = "replacement_denied"
+
+	// ErrCodeTokenTooLarge is a token over its size budget after compaction
+	ErrCodeTokenTooLarge Code = "token_too_large"
+
+	// ErrCodeMissingClaims is a token lacking claims its issuer requires,
+	// e.g. because the request or subject did not provide them
+	ErrCodeMissingClaims Code = "missing_claims"
+
+	// ErrCodeIssuerUnavailable is an issuer that could not issue a token, e.g.
+	// because its signer or a data source failed
+	ErrCodeIssuerUnavailable Code = "issuer_unavailable"
+
+	// ErrCodeDeadlineExceeded is an issuance that did not finish within its
+	// deadline, e.g. because a data source or KMS was slow to respond
+	ErrCodeDeadlineExceeded Code = "deadline_exceeded"
+
+	// ErrCodeCanceled is a request abandoned by its caller before it finished
+	ErrCodeCanceled Code = "canceled"
+
+	// ErrCodeRateLimited is a request over a quota; retry after the error's
+	// RetryAfter
+	ErrCodeRateLimited Code = "rate_limited"
+
+	// ErrCodeNotFound is a lookup of something that does not exist
+	ErrCodeNotFound Code = "not_found"
+)
+
+// GRPCCode returns the gRPC status code for the code
+func (c Code) GRPCCode() codes.Code {
+	switch c {
+	case ErrCodeInvalidRequest, ErrCodeUnsupportedGrantType, ErrCodeUnsupportedTokenType,
+		ErrCodeInvalidAudience, ErrCodeInvalidRequestContext:
+		return codes.InvalidArgument
+	case ErrCodeMissingCredential, ErrCodeInvalidToken, ErrCodeExpiredToken,
+		ErrCodeInvalidSubjectToken, ErrCodeInvalidActor:
+		return codes.Unauthenticated
+	case ErrCodeActorDenied, ErrCodeDelegationDenied, ErrCodeClientDenied, ErrCodeReplacementDenied,
+		ErrCodeRiskDenied:
+		return codes.PermissionDenied
+	case ErrCodeTokenTooLarge, ErrCodeMissingClaims:
+		return codes.FailedPrecondition
+	case ErrCodeIssuerUnavailable:
+		return codes.Unavailable
+	case ErrCodeDeadlineExceeded:
+		return codes.DeadlineExceeded
+	case ErrCodeCanceled:
+		return codes.Canceled
+	case ErrCodeRateLimited:
+		return codes.ResourceExhausted
+	case ErrCodeNotFound:
+		return codes.NotFound
+	default:
+		return codes.Internal
+	}
+}
+
+// HTTPStatus returns the HTTP status code for the code
+func (c Code) HTTPStatus() int {
+	switch c.GRPCCode() {
+	case codes.InvalidArgument, codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.Canceled:
+		// Client Closed Request, as used by grpc-gateway
+		return 499
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// OAuthError returns the OAuth 2.0 error value for the code
+// (RFC 6749 section 5.2, RFC 8693 section 2.2.2)
+func (c Code) OAuthError() string {
+	switch c {
+	case ErrCodeUnsupportedGrantType:
+		return "unsupported_grant_type"
+	case ErrCodeInvalidAudience:
+		return "invalid_target"
+	case ErrCodeInvalidActor:
+		return "invalid_client"
+	case ErrCodeActorDenied, ErrCodeDelegationDenied:
+		return "unauthorized_client"
+	case ErrCodeIssuerUnavailable, ErrCodeDeadlineExceeded, ErrCodeRateLimited:
+		return "temporarily_unavailable"
+	case ErrCodeInternal:
+		return "server_error"
+	default:
+		return "invalid_request"
+	}
+}
+
+// Error is an error with a Code
+type Error struct {
+	// Code classifies the error
+	Code Code
+
+	// Err is the underlying error, which provides the message
+	Err error
+
+	// RetryAfter is how long the caller should wait before retrying (zero if
+	// unknown)
+	RetryAfter time.Duration
+}
+
+// New returns an error with a code and a fixed message
+// Use it for sentinel errors that are compared with errors.Is.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Err: errors.New(message)}
+}
+
+// Errorf returns an error with a code and a message formatted as fmt.Errorf,
+// so %w wraps the error it formats
+func Errorf(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// WithRetryAfter sets how long the caller should wait before retrying
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	e.RetryAfter = d
+	return e
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is a code matcher for the error's code (see HasCode)
+func (e *Error) Is(target error) bool {
+	c, ok := target.(codeMatcher)
+	return ok && Code(c) == e.Code
+}
+
+// GRPCStatus returns the gRPC status for the error, with the code attached as
+// an ErrorInfo detail so it survives a gRPC hop (see CodeFromStatus), and the
+// retry delay of the chain as a RetryInfo detail (see RetryAfterFromStatus)
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(e.Code.GRPCCode(), e.Error())
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: string(e.Code), Domain: Domain}}
+	if retryAfter := RetryAfterOf(e); retryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	}
+	if detailed, err := st.WithDetails(details...); err == nil {
+		return detailed
+	}
+	return st
+}
+
+// codeMatcher is an errors.Is target matching any Error with its code
+type codeMatcher Code
+
+func (c codeMatcher) Error() string {
+	return string(c)
+}
+
+// CodeOf returns the outermost code in err's chain, ErrCodeInternal if the
+// chain has no code, or "" if err is nil
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ErrCodeInternal
+}
+
+// HasCode reports whether any error in err's chain has the code
+func HasCode(err error, code Code) bool {
+	return errors.Is(err, codeMatcher(code))
+}
+
+// IsCoded reports whether any error in err's chain has a code
+func IsCoded(err error) bool {
+	var e *Error
+	return errors.As(err, &e)
+}
+
+// RetryAfterOf returns the first retry delay set in err's chain, or zero if
+// none is
+func RetryAfterOf(err error) time.Duration {
+	for err != nil {
+		if e, ok := err.(*Error); ok && e.RetryAfter > 0 {
+			return e.RetryAfter
+		}
+		switch unwrapped := err.(type) {
+		case interface{ Unwrap() error }:
+			err = unwrapped.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, inner := range unwrapped.Unwrap() {
+				if d := RetryAfterOf(inner); d > 0 {
+					return d
+				}
+			}
+			return 0
+		default:
+			return 0
+		}
+	}
+	return 0
+}
+
+// RetryAfterFromStatus returns the retry delay attached to a gRPC status by
+// GRPCStatus
+// Returns false if the status carries none.
+func RetryAfterFromStatus(st *status.Status) (time.Duration, bool) {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.GetRetryDelay() != nil {
+			return info.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+// CodeFromStatus returns the code attached to a gRPC status by GRPCStatus
+// Returns false if the status carries no parsec code.
+func CodeFromStatus(st *status.Status) (Code, bool) {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetDomain() == Domain {
+			return Code(info.GetReason()), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,129 @@
+package perr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCodeOf(t *testing.T) {
+	sentinel := New(ErrCodeInvalidToken, "invalid token")
+	inner := fmt.Errorf("%w: signature mismatch", sentinel)
+	outer := fmt.Errorf("exchange failed: %w", Errorf(ErrCodeInvalidSubjectToken, "token validation failed: %w", inner))
+
+	tests := []struct {
+		name string
+		err  error
+		want Code
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "uncoded", err: errors.New("boom"), want: ErrCodeInternal},
+		{name: "sentinel", err: sentinel, want: ErrCodeInvalidToken},
+		{name: "wrapped sentinel", err: inner, want: ErrCodeInvalidToken},
+		{name: "outermost code wins", err: outer, want: ErrCodeInvalidSubjectToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CodeOf(tt.err); got != tt.want {
+				t.Errorf("CodeOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if !HasCode(outer, ErrCodeInvalidToken) || !HasCode(outer, ErrCodeInvalidSubjectToken) {
+		t.Error("expected HasCode to find every code in the chain")
+	}
+	if HasCode(outer, ErrCodeActorDenied) {
+		t.Error("expected HasCode to not find codes outside the chain")
+	}
+	if !errors.Is(outer, sentinel) {
+		t.Error("expected errors.Is to find the sentinel")
+	}
+	if outer.Error() != "exchange failed: token validation failed: invalid token: signature mismatch" {
+		t.Errorf("unexpected message: %s", outer.Error())
+	}
+}
+
+func TestGRPCStatus(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", Errorf(ErrCodeDelegationDenied, "actor %q denied", "svc"))
+
+	st := status.Convert(err)
+	if st.Code() != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied, got %s", st.Code())
+	}
+
+	// The code travels in the status details across a gRPC hop
+	code, ok := CodeFromStatus(st)
+	if !ok || code != ErrCodeDelegationDenied {
+		t.Errorf("expected delegation_denied from status, got %q (%v)", code, ok)
+	}
+	if _, ok := CodeFromStatus(status.New(codes.InvalidArgument, "bad")); ok {
+		t.Error("expected no code on a plain status")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	limited := Errorf(ErrCodeRateLimited, "quota exceeded").WithRetryAfter(30 * time.Second)
+
+	// A reclassifying code keeps the retry delay of the error it wraps
+	err := fmt.Errorf("issuance: %w", Errorf(ErrCodeIssuerUnavailable, "wrapped: %w", limited))
+	if got := RetryAfterOf(err); got != 30*time.Second {
+		t.Errorf("RetryAfterOf() = %s, want 30s", got)
+	}
+	if got := RetryAfterOf(errors.Join(errors.New("other"), limited)); got != 30*time.Second {
+		t.Errorf("RetryAfterOf() of joined errors = %s, want 30s", got)
+	}
+	if got := RetryAfterOf(New(ErrCodeRateLimited, "no hint")); got != 0 {
+		t.Errorf("RetryAfterOf() = %s, want 0", got)
+	}
+
+	retryAfter, ok := RetryAfterFromStatus(status.Convert(err))
+	if !ok || retryAfter != 30*time.Second {
+		t.Errorf("expected 30s from status, got %s (%v)", retryAfter, ok)
+	}
+	if _, ok := RetryAfterFromStatus(status.Convert(New(ErrCodeRateLimited, "no hint"))); ok {
+		t.Error("expected no retry delay on a status without one")
+	}
+}
+
+func TestCodeMappings(t *testing.T) {
+	tests := []struct {
+		code  Code
+		grpc  codes.Code
+		http  int
+		oauth string
+	}{
+		{ErrCodeUnsupportedGrantType, codes.InvalidArgument, http.StatusBadRequest, "unsupported_grant_type"},
+		{ErrCodeInvalidAudience, codes.InvalidArgument, http.StatusBadRequest, "invalid_target"},
+		{ErrCodeInvalidSubjectToken, codes.Unauthenticated, http.StatusUnauthorized, "invalid_request"},
+		{ErrCodeInvalidActor, codes.Unauthenticated, http.StatusUnauthorized, "invalid_client"},
+		{ErrCodeActorDenied, codes.PermissionDenied, http.StatusForbidden, "unauthorized_client"},
+		{ErrCodeClientDenied, codes.PermissionDenied, http.StatusForbidden, "invalid_request"},
+		{ErrCodeReplacementDenied, codes.PermissionDenied, http.StatusForbidden, "invalid_request"},
+		{ErrCodeRiskDenied, codes.PermissionDenied, http.StatusForbidden, "invalid_request"},
+		{ErrCodeMissingClaims, codes.FailedPrecondition, http.StatusBadRequest, "invalid_request"},
+		{ErrCodeIssuerUnavailable, codes.Unavailable, http.StatusServiceUnavailable, "temporarily_unavailable"},
+		{ErrCodeDeadlineExceeded, codes.DeadlineExceeded, http.StatusGatewayTimeout, "temporarily_unavailable"},
+		{ErrCodeCanceled, codes.Canceled, 499, "invalid_request"},
+		{ErrCodeRateLimited, codes.ResourceExhausted, http.StatusTooManyRequests, "temporarily_unavailable"},
+		{ErrCodeInternal, codes.Internal, http.StatusInternalServerError, "server_error"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.code), func(t *testing.T) {
+			if got := tt.code.GRPCCode(); got != tt.grpc {
+				t.Errorf("GRPCCode() = %s, want %s", got, tt.grpc)
+			}
+			if got := tt.code.HTTPStatus(); got != tt.http {
+				t.Errorf("HTTPStatus() = %d, want %d", got, tt.http)
+			}
+			if got := tt.code.OAuthError(); got != tt.oauth {
+				t.Errorf("OAuthError() = %s, want %s", got, tt.oauth)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 
+	"github.com/project-kessel/parsec/internal/perr"
 	"github.com/project-kessel/parsec/internal/request"
 	"github.com/project-kessel/parsec/internal/service"
 	"github.com/project-kessel/parsec/internal/trust"
@@ -116,6 +117,7 @@ func (p *loggingTokenIssuanceProbe) TokenTypeIssuanceFailed(tokenType service.To
 		"Token issuance failed",
 		slog.String("token_type", string(tokenType)),
 		slog.String("error", err.Error()),
+		slog.String("error_code", string(perr.CodeOf(err))),
 	)
 }
 
@@ -124,6 +126,35 @@ func (p *loggingTokenIssuanceProbe) IssuerNotFound(tokenType service.TokenType,
 		"No issuer found for token type",
 		slog.String("token_type", string(tokenType)),
 		slog.String("error", err.Error()),
+		slog.String("error_code", string(perr.CodeOf(err))),
+	)
+}
+
+func (p *loggingTokenIssuanceProbe) TokenCompacted(tokenType service.TokenType, report *service.CompactionReport) {
+	p.logger.LogAttrs(p.ctx, slog.LevelWarn,
+		"Token compacted to fit size budget",
+		slog.String("token_type", string(tokenType)),
+		slog.Int("max_size", report.MaxSize),
+		slog.Int("original_size", report.OriginalSize),
+		slog.Int("final_size", report.FinalSize),
+		slog.Any("steps", report.Steps),
+	)
+}
+
+func (p *loggingTokenIssuanceProbe) IssuanceRefused(err error) {
+	p.logger.LogAttrs(p.ctx, slog.LevelWarn,
+		"Token issuance refused",
+		slog.String("error", err.Error()),
+		slog.String("error_code", string(perr.CodeOf(err))),
+	)
+}
+
+func (p *loggingTokenIssuanceProbe) OptionalMapperFailed(mapper string, err error) {
+	p.logger.LogAttrs(p.ctx, slog.LevelWarn,
+		"Optional claim mapper failed, omitting its claims",
+		slog.String("mapper", mapper),
+		slog.String("error", err.Error()),
+		slog.String("error_code", string(perr.CodeOf(err))),
 	)
 }
 
@@ -178,6 +209,7 @@ func (p *loggingTokenExchangeProbe) ActorValidationFailed(err error) {
 	p.logger.LogAttrs(p.ctx, slog.LevelError,
 		"Actor validation failed",
 		slog.String("error", err.Error()),
+		slog.String("error_code", string(perr.CodeOf(err))),
 	)
 }
 
@@ -189,6 +221,14 @@ func (p *loggingTokenExchangeProbe) RequestContextParseFailed(err error) {
 	p.logger.LogAttrs(p.ctx, slog.LevelError,
 		"Request context parse failed",
 		slog.String("error", err.Error()),
+		slog.String("error_code", string(perr.CodeOf(err))),
+	)
+}
+
+func (p *loggingTokenExchangeProbe) RequestContextClaimsRejected(names []string) {
+	p.logger.LogAttrs(p.ctx, slog.LevelWarn,
+		"Request context claims rejected by claims filter",
+		slog.Any("claims", names),
 	)
 }
 
@@ -207,6 +247,15 @@ func (p *loggingTokenExchangeProbe) SubjectTokenValidationFailed(err error) {
 	p.logger.LogAttrs(p.ctx, slog.LevelError,
 		"Subject token validation failed",
 		slog.String("error", err.Error()),
+		slog.String("error_code", string(perr.CodeOf(err))),
+	)
+}
+
+func (p *loggingTokenExchangeProbe) DelegationDenied(err error) {
+	p.logger.LogAttrs(p.ctx, slog.LevelWarn,
+		"Delegation denied",
+		slog.String("error", err.Error()),
+		slog.String("error_code", string(perr.CodeOf(err))),
 	)
 }
 
@@ -247,6 +296,25 @@ func (p *loggingAuthzCheckProbe) RequestAttributesParsed(attrs *request.RequestA
 	p.logger.LogAttrs(p.ctx, slog.LevelDebug, "Request attributes parsed", logAttrs...)
 }
 
+func (p *loggingAuthzCheckProbe) RequestAttributeExtractionFailed(err error) {
+	p.logger.LogAttrs(p.ctx, slog.LevelWarn,
+		"Request attribute extraction failed",
+		slog.String("error", err.Error()),
+	)
+}
+
+func (p *loggingAuthzCheckProbe) ClientAddressDenied(address string, err error) {
+	p.logger.LogAttrs(p.ctx, slog.LevelWarn,
+		"Client address denied",
+		slog.String("ip_address", address),
+		slog.String("error", err.Error()),
+	)
+}
+
+func (p *loggingAuthzCheckProbe) CheckSkipped(path string) {
+	p.logger.LogAttrs(p.ctx, slog.LevelDebug, "Authorization check skipped", slog.String("path", path))
+}
+
 func (p *loggingAuthzCheckProbe) ActorValidationSucceeded(actor *trust.Result) {
 	attrs := []slog.Attr{}
 	if actor != nil {
@@ -262,6 +330,7 @@ func (p *loggingAuthzCheckProbe) ActorValidationFailed(err error) {
 	p.logger.LogAttrs(p.ctx, slog.LevelError,
 		"Actor validation failed",
 		slog.String("error", err.Error()),
+		slog.String("error_code", string(perr.CodeOf(err))),
 	)
 }
 
@@ -276,6 +345,7 @@ func (p *loggingAuthzCheckProbe) SubjectCredentialExtractionFailed(err error) {
 	p.logger.LogAttrs(p.ctx, slog.LevelError,
 		"Subject credential extraction failed",
 		slog.String("error", err.Error()),
+		slog.String("error_code", string(perr.CodeOf(err))),
 	)
 }
 
@@ -294,9 +364,75 @@ func (p *loggingAuthzCheckProbe) SubjectValidationFailed(err error) {
 	p.logger.LogAttrs(p.ctx, slog.LevelError,
 		"Subject validation failed",
 		slog.String("error", err.Error()),
+		slog.String("error_code", string(perr.CodeOf(err))),
+	)
+}
+
+func (p *loggingAuthzCheckProbe) TokensIssued(tokenTypes []service.TokenType) {
+	p.logger.LogAttrs(p.ctx, slog.LevelDebug,
+		"Tokens issued for authorization check",
+		slog.Any("token_types", tokenTypes),
+	)
+}
+
+func (p *loggingAuthzCheckProbe) TokenIssuanceFailed(err error) {
+	p.logger.LogAttrs(p.ctx, slog.LevelError,
+		"Token issuance for authorization check failed",
+		slog.String("error", err.Error()),
+		slog.String("error_code", string(perr.CodeOf(err))),
+	)
+}
+
+func (p *loggingAuthzCheckProbe) ResponseHeaderTooLarge(header string, size int, limit int) {
+	p.logger.LogAttrs(p.ctx, slog.LevelWarn,
+		"Token over response header size limit",
+		slog.String("header", header),
+		slog.Int("size", size),
+		slog.Int("limit", limit),
+	)
+}
+
+func (p *loggingAuthzCheckProbe) ResponseHeadersEmitted(added []string, removed []string) {
+	p.logger.LogAttrs(p.ctx, slog.LevelDebug,
+		"Response headers emitted",
+		slog.Any("headers_added", added),
+		slog.Any("headers_removed", removed),
 	)
 }
 
 func (p *loggingAuthzCheckProbe) End() {
 	p.logger.LogAttrs(p.ctx, slog.LevelDebug, "Authorization check completed")
 }
+
+func (o *loggingObserver) BulkheadCallStarted(
+	ctx context.Context,
+	bulkhead string,
+) (context.Context, service.BulkheadProbe) {
+	// Calls through a bulkhead are frequent; only queueing and rejection are logged
+	return ctx, &loggingBulkheadProbe{
+		ctx:    ctx,
+		logger: o.logger.With("event", "bulkhead", "bulkhead", bulkhead),
+	}
+}
+
+// loggingBulkheadProbe is a call-scoped probe that logs bulkhead saturation
+type loggingBulkheadProbe struct {
+	service.NoOpBulkheadProbe
+	ctx    context.Context
+	logger *slog.Logger
+}
+
+func (p *loggingBulkheadProbe) BulkheadQueued(queued int) {
+	p.logger.LogAttrs(p.ctx, slog.LevelDebug,
+		"Waiting for bulkhead slot",
+		slog.Int("queued", queued),
+	)
+}
+
+func (p *loggingBulkheadProbe) BulkheadRejected(err error) {
+	p.logger.LogAttrs(p.ctx, slog.LevelWarn,
+		"Bulkhead rejected call",
+		slog.String("error", err.Error()),
+		slog.String("error_code", string(perr.CodeOf(err))),
+	)
+}
@@ -0,0 +1,82 @@
+package probe
+
+import (
+	"context"
+	"math/rand/v2"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// SamplingRates are the fractions of operations observed by a sampled
+// observer, from 0 (none) to 1 (all), per probe
+type SamplingRates struct {
+	TokenIssuance float64
+	TokenExchange float64
+	AuthzCheck    float64
+	Bulkhead      float64
+}
+
+// samplingObserver passes a sample of operations to an expensive observer
+type samplingObserver struct {
+	next   service.ApplicationObserver
+	rates  SamplingRates
+	random func() float64
+}
+
+// NewSamplingObserver creates an observer passing only a sample of operations
+// to next, at the rate configured for each probe. An operation is sampled when
+// it starts, so either all or none of its events reach next; operations not
+// sampled get a no-op probe.
+//
+// Use it for observers whose cost grows with traffic, such as detailed logs or
+// span attributes, and compose cheap observers like metrics counters alongside
+// it so they still see every operation.
+func NewSamplingObserver(next service.ApplicationObserver, rates SamplingRates) service.ApplicationObserver {
+	return &samplingObserver{next: next, rates: rates, random: rand.Float64}
+}
+
+// sampled reports whether to observe an operation sampled at rate
+func (o *samplingObserver) sampled(rate float64) bool {
+	return rate >= 1 || (rate > 0 && o.random() < rate)
+}
+
+func (o *samplingObserver) TokenIssuanceStarted(
+	ctx context.Context,
+	subject *trust.Result,
+	actor *trust.Result,
+	scope string,
+	tokenTypes []service.TokenType,
+) (context.Context, service.TokenIssuanceProbe) {
+	if !o.sampled(o.rates.TokenIssuance) {
+		return ctx, &service.NoOpTokenIssuanceProbe{}
+	}
+	return o.next.TokenIssuanceStarted(ctx, subject, actor, scope, tokenTypes)
+}
+
+func (o *samplingObserver) TokenExchangeStarted(
+	ctx context.Context,
+	grantType string,
+	requestedTokenType string,
+	audience string,
+	scope string,
+) (context.Context, service.TokenExchangeProbe) {
+	if !o.sampled(o.rates.TokenExchange) {
+		return ctx, &service.NoOpTokenExchangeProbe{}
+	}
+	return o.next.TokenExchangeStarted(ctx, grantType, requestedTokenType, audience, scope)
+}
+
+func (o *samplingObserver) AuthzCheckStarted(ctx context.Context) (context.Context, service.AuthzCheckProbe) {
+	if !o.sampled(o.rates.AuthzCheck) {
+		return ctx, &service.NoOpAuthzCheckProbe{}
+	}
+	return o.next.AuthzCheckStarted(ctx)
+}
+
+func (o *samplingObserver) BulkheadCallStarted(ctx context.Context, bulkhead string) (context.Context, service.BulkheadProbe) {
+	if !o.sampled(o.rates.Bulkhead) {
+		return ctx, &service.NoOpBulkheadProbe{}
+	}
+	return o.next.BulkheadCallStarted(ctx, bulkhead)
+}
@@ -0,0 +1,52 @@
+package probe
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// countingObserver counts the operations it observes
+type countingObserver struct {
+	service.NoOpApplicationObserver
+	issuances, exchanges int
+}
+
+func (o *countingObserver) TokenIssuanceStarted(ctx context.Context, subject, actor *trust.Result, scope string, tokenTypes []service.TokenType) (context.Context, service.TokenIssuanceProbe) {
+	o.issuances++
+	return ctx, &service.NoOpTokenIssuanceProbe{}
+}
+
+func (o *countingObserver) TokenExchangeStarted(ctx context.Context, grantType, requestedTokenType, audience, scope string) (context.Context, service.TokenExchangeProbe) {
+	o.exchanges++
+	return ctx, &service.NoOpTokenExchangeProbe{}
+}
+
+func TestSamplingObserver(t *testing.T) {
+	next := &countingObserver{}
+	observer := NewSamplingObserver(next, SamplingRates{TokenIssuance: 0.25, TokenExchange: 1})
+
+	// A deterministic sequence samples exactly the draws below the rate
+	draws := []float64{0.1, 0.5, 0.9, 0.2, 0.3, 0.24, 0.99, 0.7}
+	observer.(*samplingObserver).random = func() float64 {
+		draw := draws[0]
+		draws = draws[1:]
+		return draw
+	}
+
+	ctx := context.Background()
+	for range 8 {
+		observer.TokenIssuanceStarted(ctx, nil, nil, "", nil)
+		observer.TokenExchangeStarted(ctx, "", "", "", "")
+		observer.AuthzCheckStarted(ctx)
+	}
+
+	if next.issuances != 3 {
+		t.Errorf("expected 3 sampled issuances, got %d", next.issuances)
+	}
+	if next.exchanges != 8 {
+		t.Errorf("expected every exchange observed at rate 1, got %d", next.exchanges)
+	}
+}
@@ -0,0 +1,151 @@
+// Package quota limits the tokens issued per subject, actor or any other key
+// selected from an issuance, per fixed time window.
+//
+// A Limiter holds rules. Each rule selects a key from the issuance with a
+// Selector (typically a CEL expression such as actor.subject), and counts the
+// tokens issued for that key in a CounterStore. An issuance that takes a key
+// over its rule's limit is refused with perr.ErrCodeRateLimited and the time
+// left in the window as its retry delay, so a compromised actor can only mint
+// so many tokens before it is cut off.
+//
+// Refused issuances count too: a client retrying before the window ends stays
+// refused rather than being admitted on every other attempt.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// Rule caps the tokens issued for each key its selector selects
+type Rule struct {
+	// Name identifies the rule in errors and counter keys
+	Name string
+
+	// Selector selects the key an issuance counts against
+	// Issuances for which it selects no key are not counted.
+	Selector Selector
+
+	// Limit is the number of tokens that may be issued per key and window
+	Limit int64
+
+	// Window is the length of the fixed windows tokens are counted in
+	Window time.Duration
+
+	// TokenTypes are the token types the rule counts; empty counts all
+	TokenTypes []service.TokenType
+}
+
+// Config configures a Limiter
+type Config struct {
+	// Rules are the quotas, all of which an issuance must fit in
+	Rules []Rule
+
+	// Store holds the counters (defaults to a MemoryStore, which counts per
+	// replica)
+	Store CounterStore
+
+	// FailOpen admits issuances when the store fails, rather than refusing
+	// them
+	FailOpen bool
+
+	// Clock is the time source of the windows (defaults to system clock)
+	Clock clock.Clock
+}
+
+// Limiter refuses issuances over their quotas
+// It implements service.IssuanceLimiter.
+type Limiter struct {
+	rules    []Rule
+	store    CounterStore
+	failOpen bool
+	clock    clock.Clock
+}
+
+var _ service.IssuanceLimiter = (*Limiter)(nil)
+
+// NewLimiter creates a limiter
+func NewLimiter(cfg Config) (*Limiter, error) {
+	names := make(map[string]bool, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		switch {
+		case rule.Name == "":
+			return nil, fmt.Errorf("quota %d: name is required", i)
+		case names[rule.Name]:
+			return nil, fmt.Errorf("quota %q: duplicate name", rule.Name)
+		case rule.Selector == nil:
+			return nil, fmt.Errorf("quota %q: selector is required", rule.Name)
+		case rule.Limit <= 0:
+			return nil, fmt.Errorf("quota %q: limit must be positive, got %d", rule.Name, rule.Limit)
+		case rule.Window <= 0:
+			return nil, fmt.Errorf("quota %q: window must be positive, got %s", rule.Name, rule.Window)
+		}
+		names[rule.Name] = true
+	}
+
+	store := cfg.Store
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+	return &Limiter{
+		rules:    slices.Clone(cfg.Rules),
+		store:    store,
+		failOpen: cfg.FailOpen,
+		clock:    clk,
+	}, nil
+}
+
+// Admit implements service.IssuanceLimiter
+func (l *Limiter) Admit(ctx context.Context, issueCtx *service.IssueContext, tokenTypes []service.TokenType) error {
+	now := l.clock.Now()
+	for _, rule := range l.rules {
+		n := rule.count(tokenTypes)
+		if n == 0 {
+			continue
+		}
+		key, err := rule.Selector.Select(ctx, issueCtx)
+		if err != nil {
+			return fmt.Errorf("quota %q: %w", rule.Name, err)
+		}
+		if key == "" {
+			continue
+		}
+
+		count, windowEnd, err := l.store.Add(ctx, rule.Name+"\x00"+key, n, rule.Window, now)
+		if err != nil {
+			if l.failOpen {
+				continue
+			}
+			return fmt.Errorf("quota %q: failed to count tokens: %w", rule.Name, err)
+		}
+		if count > rule.Limit {
+			return perr.Errorf(perr.ErrCodeRateLimited, "quota %q exceeded: at most %d tokens per %s", rule.Name, rule.Limit, rule.Window).
+				WithRetryAfter(windowEnd.Sub(now))
+		}
+	}
+	return nil
+}
+
+// count returns the number of tokenTypes the rule counts
+func (r *Rule) count(tokenTypes []service.TokenType) int64 {
+	if len(r.TokenTypes) == 0 {
+		return int64(len(tokenTypes))
+	}
+	var n int64
+	for _, tokenType := range tokenTypes {
+		if slices.Contains(r.TokenTypes, tokenType) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,164 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// failingStore fails every Add
+type failingStore struct{}
+
+func (failingStore) Add(ctx context.Context, key string, n int64, window time.Duration, now time.Time) (int64, time.Time, error) {
+	return 0, time.Time{}, errors.New("store unavailable")
+}
+
+func mustSelector(t *testing.T, expression string) Selector {
+	t.Helper()
+	selector, err := NewCELSelector(expression)
+	if err != nil {
+		t.Fatalf("NewCELSelector failed: %v", err)
+	}
+	return selector
+}
+
+func issueCtx(subject, actor string) *service.IssueContext {
+	return &service.IssueContext{
+		Subject: &trust.Result{Subject: subject, TrustDomain: "example.com"},
+		Actor:   &trust.Result{Subject: actor, TrustDomain: "example.com"},
+	}
+}
+
+func TestLimiter_Admit(t *testing.T) {
+	ctx := context.Background()
+	txn := []service.TokenType{service.TokenTypeTransactionToken}
+
+	t.Run("refuses tokens over the limit until the window ends", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 12, 0, 15, 0, time.UTC))
+		limiter, err := NewLimiter(Config{
+			Rules: []Rule{{Name: "per-actor", Selector: mustSelector(t, "actor.subject"), Limit: 2, Window: time.Minute}},
+			Clock: clk,
+		})
+		if err != nil {
+			t.Fatalf("NewLimiter failed: %v", err)
+		}
+
+		for i := range 2 {
+			if err := limiter.Admit(ctx, issueCtx("alice", "gateway"), txn); err != nil {
+				t.Fatalf("issuance %d refused: %v", i, err)
+			}
+		}
+		err = limiter.Admit(ctx, issueCtx("bob", "gateway"), txn)
+		if !perr.HasCode(err, perr.ErrCodeRateLimited) {
+			t.Fatalf("expected rate_limited, got %v", err)
+		}
+		if got := perr.RetryAfterOf(err); got != 45*time.Second {
+			t.Errorf("RetryAfter = %s, want 45s", got)
+		}
+
+		// Other actors have their own counters
+		if err := limiter.Admit(ctx, issueCtx("alice", "batch"), txn); err != nil {
+			t.Errorf("other actor refused: %v", err)
+		}
+
+		clk.Advance(45 * time.Second)
+		if err := limiter.Admit(ctx, issueCtx("alice", "gateway"), txn); err != nil {
+			t.Errorf("issuance refused in the next window: %v", err)
+		}
+	})
+
+	t.Run("counts each token type issued", func(t *testing.T) {
+		limiter, err := NewLimiter(Config{
+			Rules: []Rule{{Name: "per-subject", Selector: mustSelector(t, "subject.subject"), Limit: 3, Window: time.Hour}},
+		})
+		if err != nil {
+			t.Fatalf("NewLimiter failed: %v", err)
+		}
+		two := []service.TokenType{service.TokenTypeTransactionToken, service.TokenTypeAccessToken}
+		if err := limiter.Admit(ctx, issueCtx("alice", "gateway"), two); err != nil {
+			t.Fatalf("first issuance refused: %v", err)
+		}
+		if err := limiter.Admit(ctx, issueCtx("alice", "gateway"), two); !perr.HasCode(err, perr.ErrCodeRateLimited) {
+			t.Errorf("expected rate_limited for the fourth token, got %v", err)
+		}
+	})
+
+	t.Run("rules only count their token types", func(t *testing.T) {
+		limiter, err := NewLimiter(Config{
+			Rules: []Rule{{
+				Name:       "access-tokens",
+				Selector:   mustSelector(t, "subject.subject"),
+				Limit:      1,
+				Window:     time.Hour,
+				TokenTypes: []service.TokenType{service.TokenTypeAccessToken},
+			}},
+		})
+		if err != nil {
+			t.Fatalf("NewLimiter failed: %v", err)
+		}
+		for range 3 {
+			if err := limiter.Admit(ctx, issueCtx("alice", "gateway"), txn); err != nil {
+				t.Fatalf("uncounted token type refused: %v", err)
+			}
+		}
+	})
+
+	t.Run("issuances without a key are not counted", func(t *testing.T) {
+		limiter, err := NewLimiter(Config{
+			Rules: []Rule{{Name: "external", Selector: mustSelector(t, `actor.subject == "trusted" ? null : actor.subject`), Limit: 1, Window: time.Hour}},
+		})
+		if err != nil {
+			t.Fatalf("NewLimiter failed: %v", err)
+		}
+		for range 3 {
+			if err := limiter.Admit(ctx, issueCtx("alice", "trusted"), txn); err != nil {
+				t.Fatalf("exempt issuance refused: %v", err)
+			}
+		}
+	})
+
+	t.Run("store failures refuse unless failing open", func(t *testing.T) {
+		rules := []Rule{{Name: "per-actor", Selector: mustSelector(t, "actor.subject"), Limit: 1, Window: time.Minute}}
+
+		closed, err := NewLimiter(Config{Rules: rules, Store: failingStore{}})
+		if err != nil {
+			t.Fatalf("NewLimiter failed: %v", err)
+		}
+		if err := closed.Admit(ctx, issueCtx("alice", "gateway"), txn); err == nil || perr.IsCoded(err) {
+			t.Errorf("expected an uncoded store error, got %v", err)
+		}
+
+		open, err := NewLimiter(Config{Rules: rules, Store: failingStore{}, FailOpen: true})
+		if err != nil {
+			t.Fatalf("NewLimiter failed: %v", err)
+		}
+		if err := open.Admit(ctx, issueCtx("alice", "gateway"), txn); err != nil {
+			t.Errorf("expected fail open to admit, got %v", err)
+		}
+	})
+}
+
+func TestNewLimiter_InvalidRules(t *testing.T) {
+	selector := mustSelector(t, "actor.subject")
+	for name, rule := range map[string]Rule{
+		"no name":     {Selector: selector, Limit: 1, Window: time.Minute},
+		"no selector": {Name: "q", Limit: 1, Window: time.Minute},
+		"no limit":    {Name: "q", Selector: selector, Window: time.Minute},
+		"no window":   {Name: "q", Selector: selector, Limit: 1},
+	} {
+		if _, err := NewLimiter(Config{Rules: []Rule{rule}}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	rule := Rule{Name: "q", Selector: selector, Limit: 1, Window: time.Minute}
+	if _, err := NewLimiter(Config{Rules: []Rule{rule, rule}}); err == nil {
+		t.Error("duplicate names: expected error")
+	}
+}
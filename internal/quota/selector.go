@@ -0,0 +1,139 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+	"github.com/project-kessel/parsec/internal/trustdomain"
+)
+
+// Selector selects the key an issuance counts against
+type Selector interface {
+	// Select returns the key of the issuance, or "" if it is not counted
+	Select(ctx context.Context, issueCtx *service.IssueContext) (string, error)
+}
+
+// SelectorLibrary creates a CEL library for quota selectors.
+//
+// This provides compile-time declarations for:
+//   - subject - the subject's Result object as a map
+//   - actor - the actor's Result object as a map
+//   - request - the request attributes as a map
+//   - scope - the requested scope
+//   - audience - the token audience
+//
+// The expression evaluates to a string key, or to null or "" for issuances
+// that are not counted.
+//
+// Example expressions:
+//   - actor.subject
+//   - subject.trust_domain + "/" + subject.subject
+//   - actor.trust_domain == "internal" ? null : actor.subject
+func SelectorLibrary() cel.EnvOption {
+	return cel.Lib(&selectorLib{})
+}
+
+type selectorLib struct{}
+
+func (lib *selectorLib) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		// trustDomainMatches(value, pattern)
+		trustdomain.CELLibrary(),
+		cel.Variable("subject", cel.DynType),
+		cel.Variable("actor", cel.DynType),
+		cel.Variable("request", cel.DynType),
+		cel.Variable("scope", cel.StringType),
+		cel.Variable("audience", cel.StringType),
+	}
+}
+
+func (lib *selectorLib) ProgramOptions() []cel.ProgramOption {
+	return []cel.ProgramOption{}
+}
+
+// CELSelector selects keys with a CEL expression
+type CELSelector struct {
+	program    cel.Program
+	expression string
+}
+
+// NewCELSelector creates a selector from a CEL expression
+func NewCELSelector(expression string) (*CELSelector, error) {
+	if expression == "" {
+		return nil, fmt.Errorf("CEL selector cannot be empty")
+	}
+
+	env, err := cel.NewEnv(SelectorLibrary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("failed to compile CEL selector: %w", issues.Err())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL program: %w", err)
+	}
+
+	return &CELSelector{program: program, expression: expression}, nil
+}
+
+// Select implements Selector
+func (s *CELSelector) Select(ctx context.Context, issueCtx *service.IssueContext) (string, error) {
+	subjectMap, err := trust.ConvertResultToMap(issueCtx.Subject)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert subject: %w", err)
+	}
+
+	actorMap, err := trust.ConvertResultToMap(issueCtx.Actor)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert actor: %w", err)
+	}
+
+	requestMap, err := trust.ConvertRequestAttributesToMap(issueCtx.RequestAttributes)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert request attributes: %w", err)
+	}
+
+	// Absent identities and attributes are empty maps so selectors can use has() checks
+	result, _, err := s.program.ContextEval(ctx, map[string]any{
+		"subject":  orEmpty(subjectMap),
+		"actor":    orEmpty(actorMap),
+		"request":  orEmpty(requestMap),
+		"scope":    issueCtx.Scope,
+		"audience": issueCtx.Audience,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to evaluate selector: %w", err)
+	}
+
+	switch v := result.(type) {
+	case types.String:
+		return string(v), nil
+	case types.Null:
+		return "", nil
+	default:
+		return "", fmt.Errorf("selector must evaluate to a string or null, got %s", result.Type().TypeName())
+	}
+}
+
+// Expression returns the CEL expression of the selector
+func (s *CELSelector) Expression() string {
+	return s.expression
+}
+
+// orEmpty returns m, or an empty map if m is nil
+func orEmpty(m map[string]any) map[string]any {
+	if m == nil {
+		return map[string]any{}
+	}
+	return m
+}
@@ -0,0 +1,50 @@
+package quota
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestCELSelector(t *testing.T) {
+	ctx := context.Background()
+	issue := &service.IssueContext{
+		Subject:           &trust.Result{Subject: "alice", TrustDomain: "example.com"},
+		RequestAttributes: &request.RequestAttributes{IPAddress: "192.0.2.1"},
+		Audience:          "api.example.com",
+	}
+
+	tests := []struct {
+		expression string
+		want       string
+		wantErr    bool
+	}{
+		{`subject.trust_domain + "/" + subject.subject`, "example.com/alice", false},
+		{`request.ip_address`, "192.0.2.1", false},
+		{`has(actor.subject) ? actor.subject : null`, "", false},
+		{`audience == "api.example.com" ? "" : audience`, "", false},
+		{`42`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			selector, err := NewCELSelector(tt.expression)
+			if err != nil {
+				t.Fatalf("NewCELSelector failed: %v", err)
+			}
+			got, err := selector.Select(ctx, issue)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Select() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Select() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewCELSelector("subject."); err == nil {
+		t.Error("expected a compile error")
+	}
+}
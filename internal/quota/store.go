@@ -0,0 +1,75 @@
+package quota
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CounterStore counts tokens per key in fixed windows
+// Implementations backed by a shared store (e.g. Redis) enforce quotas across
+// replicas; the MemoryStore counts per replica.
+type CounterStore interface {
+	// Add adds n to the counter of key in the window of length window that
+	// contains now, returning the count after adding and the end of the
+	// window. Windows are aligned to the Unix epoch.
+	Add(ctx context.Context, key string, n int64, window time.Duration, now time.Time) (count int64, windowEnd time.Time, err error)
+}
+
+// sweepInterval is how often a MemoryStore drops the counters of past windows
+const sweepInterval = time.Minute
+
+// MemoryStore is a CounterStore in process memory
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]*counter
+	sweepAt  time.Time
+}
+
+// counter is the count of a key in the window ending at windowEnd
+type counter struct {
+	windowEnd time.Time
+	count     int64
+}
+
+// NewMemoryStore creates an empty in-memory counter store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: make(map[string]*counter)}
+}
+
+// Add implements CounterStore
+func (s *MemoryStore) Add(ctx context.Context, key string, n int64, window time.Duration, now time.Time) (int64, time.Time, error) {
+	windowEnd := now.Truncate(window).Add(window)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	c, ok := s.counters[key]
+	if !ok || !c.windowEnd.Equal(windowEnd) {
+		c = &counter{windowEnd: windowEnd}
+		s.counters[key] = c
+	}
+	c.count += n
+	return c.count, windowEnd, nil
+}
+
+// Len returns the number of counters held
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.counters)
+}
+
+// sweep drops the counters of windows that ended, at most every sweepInterval
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Before(s.sweepAt) {
+		return
+	}
+	for key, c := range s.counters {
+		if !now.Before(c.windowEnd) {
+			delete(s.counters, key)
+		}
+	}
+	s.sweepAt = now.Add(sweepInterval)
+}
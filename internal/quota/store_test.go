@@ -0,0 +1,33 @@
+package quota
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_Add(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Date(2025, 1, 1, 12, 0, 30, 0, time.UTC)
+
+	count, windowEnd, err := store.Add(ctx, "a", 2, time.Minute, now)
+	if err != nil || count != 2 || !windowEnd.Equal(now.Truncate(time.Minute).Add(time.Minute)) {
+		t.Fatalf("Add() = %d, %s, %v", count, windowEnd, err)
+	}
+	if count, _, _ = store.Add(ctx, "a", 1, time.Minute, now.Add(20*time.Second)); count != 3 {
+		t.Errorf("count in the same window = %d, want 3", count)
+	}
+	if count, _, _ = store.Add(ctx, "b", 1, time.Minute, now); count != 1 {
+		t.Errorf("count of another key = %d, want 1", count)
+	}
+	if count, _, _ = store.Add(ctx, "a", 1, time.Minute, now.Add(30*time.Second)); count != 1 {
+		t.Errorf("count in the next window = %d, want 1", count)
+	}
+
+	// Counters of past windows are dropped
+	store.Add(ctx, "c", 1, time.Minute, now.Add(5*time.Minute))
+	if store.Len() != 1 {
+		t.Errorf("expected only the current counter to be kept, got %d", store.Len())
+	}
+}
@@ -4,7 +4,23 @@
 // which are used by multiple packages including trust validation and token issuance.
 package request
 
-import "github.com/project-kessel/parsec/internal/claims"
+import (
+	"maps"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel/baggage"
+
+	"github.com/project-kessel/parsec/internal/claims"
+)
+
+// CallerAttributesKey is the Additional key under which servers record what
+// they themselves know about the caller, as opposed to what the client claimed
+const CallerAttributesKey = "caller"
+
+// BaggageHeader is the W3C Baggage header, propagating application-defined
+// entries such as a tenant or experiment flags along a request's path
+const BaggageHeader = "baggage"
 
 // RequestAttributes contains attributes about the incoming request
 // This is used for both token issuance context and validator filtering decisions
@@ -13,15 +29,31 @@ type RequestAttributes struct {
 	// Method is the HTTP method or RPC method name
 	Method string `json:"method,omitempty"`
 
-	// Path is the request path/resource being accessed
+	// Path is the request path/resource being accessed, including any query
 	Path string `json:"path,omitempty"`
 
+	// Query contains the parsed query parameters of Path
+	Query map[string][]string `json:"query,omitempty"`
+
+	// Scheme is the request scheme ("http" or "https")
+	Scheme string `json:"scheme,omitempty"`
+
+	// Port is the port the request was received on (0 if unknown)
+	Port int `json:"port,omitempty"`
+
+	// TLS describes the client's TLS session (nil without TLS, or if unknown)
+	TLS *TLSInfo `json:"tls,omitempty"`
+
 	// IPAddress is the client IP address
 	IPAddress string `json:"ip_address,omitempty"`
 
 	// UserAgent is the client user agent
 	UserAgent string `json:"user_agent,omitempty"`
 
+	// BodySHA256 is the hex SHA-256 digest of the request body, when body
+	// hashing is enabled and the complete body was available
+	BodySHA256 string `json:"body_sha256,omitempty"`
+
 	// Headers contains relevant HTTP headers
 	Headers map[string]string `json:"headers,omitempty"`
 
@@ -35,6 +67,108 @@ type RequestAttributes struct {
 	Additional map[string]any `json:"additional"`
 }
 
+// TLSInfo describes the TLS session of a request
+type TLSInfo struct {
+	// SNI is the server name the client requested
+	SNI string `json:"sni,omitempty"`
+
+	// Version is the TLS version (e.g. "TLSv1.3")
+	Version string `json:"version,omitempty"`
+
+	// CipherSuite is the negotiated cipher suite (e.g. "TLS_AES_128_GCM_SHA256")
+	CipherSuite string `json:"cipher_suite,omitempty"`
+}
+
+// Map returns the TLS session as a map with the JSON field names, for
+// expression languages (nil for a nil session)
+func (t *TLSInfo) Map() map[string]any {
+	if t == nil {
+		return nil
+	}
+	return map[string]any{
+		"sni":          t.SNI,
+		"version":      t.Version,
+		"cipher_suite": t.CipherSuite,
+	}
+}
+
+// ParseQuery returns the query parameters of a request path, or nil if it has
+// none. Malformed pairs are skipped.
+func ParseQuery(path string) map[string][]string {
+	_, rawQuery, ok := strings.Cut(path, "?")
+	if !ok || rawQuery == "" {
+		return nil
+	}
+	rawQuery, _, _ = strings.Cut(rawQuery, "#")
+	query, _ := url.ParseQuery(rawQuery)
+	if len(query) == 0 {
+		return nil
+	}
+	return query
+}
+
+// QueryMap returns the query parameters as a map of lists of any, the shape
+// expression languages expect (nil without a query)
+func (r *RequestAttributes) QueryMap() map[string]any {
+	if len(r.Query) == 0 {
+		return nil
+	}
+	m := make(map[string]any, len(r.Query))
+	for key, values := range r.Query {
+		list := make([]any, len(values))
+		for i, value := range values {
+			list[i] = value
+		}
+		m[key] = list
+	}
+	return m
+}
+
+// Baggage returns the entries of the request's W3C Baggage header, with
+// percent-decoded values and without their properties (nil without the
+// header)
+// Baggage is set by the client or any hop before parsec, so it is only as
+// trustworthy as the proxies in front of parsec. A malformed header, or one
+// over the W3C limits, is ignored as a whole.
+func (r *RequestAttributes) Baggage() map[string]string {
+	header := r.Headers[BaggageHeader]
+	if header == "" {
+		return nil
+	}
+	parsed, err := baggage.Parse(header)
+	if err != nil || parsed.Len() == 0 {
+		return nil
+	}
+	entries := make(map[string]string, parsed.Len())
+	for _, member := range parsed.Members() {
+		entries[member.Key()] = member.Value()
+	}
+	return entries
+}
+
+// Clone returns a copy of the attributes whose Headers, Query, TLS and
+// Additional can be changed without affecting the original. Values in
+// Additional are shared.
+func (r *RequestAttributes) Clone() *RequestAttributes {
+	if r == nil {
+		return nil
+	}
+	clone := *r
+	clone.Headers = maps.Clone(r.Headers)
+	if r.Query != nil {
+		clone.Query = make(map[string][]string, len(r.Query))
+		for key, values := range r.Query {
+			clone.Query[key] = append([]string(nil), values...)
+		}
+	}
+	if r.TLS != nil {
+		tls := *r.TLS
+		clone.TLS = &tls
+	}
+	clone.Additional = maps.Clone(r.Additional)
+	return &clone
+}
+
 // FromClaims constructs RequestAttributes from filtered claims
 // This is used when the client provides request_context claims that have been filtered
 // The function maps well-known claim names to RequestAttributes fields
@@ -60,6 +194,21 @@ func FromClaims(filteredClaims claims.Claims) *RequestAttributes {
 	if userAgent := filteredClaims.GetString("user_agent"); userAgent != "" {
 		attrs.UserAgent = userAgent
 	}
+	if scheme := filteredClaims.GetString("scheme"); scheme != "" {
+		attrs.Scheme = scheme
+	}
+	if bodySHA256 := filteredClaims.GetString("body_sha256"); bodySHA256 != "" {
+		attrs.BodySHA256 = bodySHA256
+	}
+	switch port := filteredClaims["port"].(type) {
+	case float64:
+		attrs.Port = int(port)
+	case int:
+		attrs.Port = port
+	case int64:
+		attrs.Port = int(port)
+	}
+	attrs.Query = ParseQuery(attrs.Path)
 
 	// Handle headers if present
 	if headersRaw, ok := filteredClaims["headers"]; ok {
@@ -75,11 +224,14 @@ func FromClaims(filteredClaims claims.Claims) *RequestAttributes {
 
 	// Add all other claims to Additional
 	knownFields := map[string]bool{
-		"method":     true,
-		"path":       true,
-		"ip_address": true,
-		"user_agent": true,
-		"headers":    true,
+		"method":      true,
+		"path":        true,
+		"ip_address":  true,
+		"user_agent":  true,
+		"headers":     true,
+		"scheme":      true,
+		"port":        true,
+		"body_sha256": true,
 	}
 
 	for key, value := range filteredClaims {
@@ -0,0 +1,236 @@
+// Package rhidentity implements the x-rh-identity document, the base64
+// encoded JSON identity console.redhat.com services receive from their
+// gateway.
+package rhidentity
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+
+	"github.com/project-kessel/parsec/internal/claims"
+)
+
+// Type is the identity.type of an x-rh-identity
+type Type string
+
+const (
+	TypeUser           Type = "User"
+	TypeServiceAccount Type = "ServiceAccount"
+	TypeSystem         Type = "System"
+	TypeAssociate      Type = "Associate"
+	TypeX509           Type = "X509"
+)
+
+// authTypes are the accepted identity.auth_type values
+var authTypes = map[string]bool{
+	"basic-auth": true,
+	"cert-auth":  true,
+	"jwt-auth":   true,
+	"uhc-auth":   true,
+	"saml-auth":  true,
+}
+
+// Document is the complete x-rh-identity document
+type Document struct {
+	Identity     Identity               `json:"identity"`
+	Entitlements map[string]Entitlement `json:"entitlements,omitempty"`
+}
+
+// Identity is the "identity" section of an x-rh-identity
+type Identity struct {
+	AccountNumber         string `json:"account_number,omitempty"`
+	EmployeeAccountNumber string `json:"employee_account_number,omitempty"`
+	OrgID                 string `json:"org_id,omitempty"`
+	Type                  Type   `json:"type"`
+	AuthType              string `json:"auth_type,omitempty"`
+
+	Internal       *Internal       `json:"internal,omitempty"`
+	User           *User           `json:"user,omitempty"`
+	ServiceAccount *ServiceAccount `json:"service_account,omitempty"`
+	System         *System         `json:"system,omitempty"`
+	Associate      *Associate      `json:"associate,omitempty"`
+	X509           *X509           `json:"x509,omitempty"`
+}
+
+// Internal is internal org data carried in the identity
+type Internal struct {
+	OrgID       string  `json:"org_id,omitempty"`
+	AuthTime    float64 `json:"auth_time,omitempty"`
+	CrossAccess bool    `json:"cross_access"`
+}
+
+// User describes a User identity
+type User struct {
+	Username   string `json:"username"`
+	Email      string `json:"email,omitempty"`
+	FirstName  string `json:"first_name,omitempty"`
+	LastName   string `json:"last_name,omitempty"`
+	IsActive   bool   `json:"is_active"`
+	IsOrgAdmin bool   `json:"is_org_admin"`
+	IsInternal bool   `json:"is_internal"`
+	Locale     string `json:"locale,omitempty"`
+	UserID     string `json:"user_id,omitempty"`
+}
+
+// ServiceAccount describes a ServiceAccount identity
+type ServiceAccount struct {
+	ClientID string `json:"client_id"`
+	Username string `json:"username"`
+	UserID   string `json:"user_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+}
+
+// System describes a System (certificate-authenticated host) identity
+type System struct {
+	CommonName string `json:"cn"`
+	CertType   string `json:"cert_type,omitempty"`
+	ClusterID  string `json:"cluster_id,omitempty"`
+}
+
+// Associate describes an Associate (Red Hat employee, SAML) identity
+type Associate struct {
+	Role      []string `json:"Role,omitempty"`
+	Email     string   `json:"email"`
+	GivenName string   `json:"givenName,omitempty"`
+	RHatUUID  string   `json:"rhatUUID,omitempty"`
+	Surname   string   `json:"surname,omitempty"`
+}
+
+// X509 describes an X509 identity
+type X509 struct {
+	SubjectDN string `json:"subject_dn"`
+	IssuerDN  string `json:"issuer_dn"`
+}
+
+// Entitlement is a single entry of the "entitlements" section
+type Entitlement struct {
+	IsEntitled bool `json:"is_entitled"`
+	IsTrial    bool `json:"is_trial"`
+}
+
+// FromClaims builds an x-rh-identity from mapper output.
+//
+// The claims are either the identity section itself (with an optional
+// "entitlements" key that is moved to the top level), or a complete document
+// with "identity" and "entitlements" keys. Unknown fields are rejected.
+func FromClaims(c claims.Claims) (*Document, error) {
+	if msg := c.GetString("error"); msg != "" {
+		return nil, fmt.Errorf("identity mapper reported error: %s", msg)
+	}
+
+	doc := map[string]any(c)
+	if !c.Has("identity") {
+		identity := c.Copy()
+		delete(identity, "entitlements")
+		doc = map[string]any{"identity": identity}
+		if entitlements, ok := c["entitlements"]; ok {
+			doc["entitlements"] = entitlements
+		}
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal identity claims: %w", err)
+	}
+
+	var id Document
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&id); err != nil {
+		return nil, fmt.Errorf("identity does not match x-rh-identity schema: %w", err)
+	}
+
+	if err := id.Validate(); err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// Parse decodes and validates an x-rh-identity document
+// Unlike FromClaims, it ignores unknown fields, which gateways add over time.
+func Parse(raw []byte) (*Document, error) {
+	var id Document
+	if err := json.Unmarshal(raw, &id); err != nil {
+		return nil, fmt.Errorf("identity does not match x-rh-identity schema: %w", err)
+	}
+	if err := id.Validate(); err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// Principal returns the identifier of the identity's principal: the user ID
+// (or username) of users, the client ID of service accounts, the common name
+// of systems, the rhatUUID (or email) of associates, and the subject DN of
+// X509 identities
+// It is empty for identities that have not been validated.
+func (id *Document) Principal() string {
+	ident := id.Identity
+	switch {
+	case ident.Type == TypeUser && ident.User != nil:
+		return cmp.Or(ident.User.UserID, ident.User.Username)
+	case ident.Type == TypeServiceAccount && ident.ServiceAccount != nil:
+		return ident.ServiceAccount.ClientID
+	case ident.Type == TypeSystem && ident.System != nil:
+		return ident.System.CommonName
+	case ident.Type == TypeAssociate && ident.Associate != nil:
+		return cmp.Or(ident.Associate.RHatUUID, ident.Associate.Email)
+	case ident.Type == TypeX509 && ident.X509 != nil:
+		return ident.X509.SubjectDN
+	}
+	return ""
+}
+
+// Validate checks that the identity is consistent with its type
+func (id *Document) Validate() error {
+	ident := id.Identity
+
+	if ident.AuthType != "" && !authTypes[ident.AuthType] {
+		return fmt.Errorf("invalid identity.auth_type %q", ident.AuthType)
+	}
+
+	var sectionPresent bool
+	switch ident.Type {
+	case TypeUser:
+		sectionPresent = ident.User != nil
+	case TypeServiceAccount:
+		sectionPresent = ident.ServiceAccount != nil
+	case TypeSystem:
+		sectionPresent = ident.System != nil
+	case TypeAssociate:
+		sectionPresent = ident.Associate != nil
+	case TypeX509:
+		sectionPresent = ident.X509 != nil
+	case "":
+		return fmt.Errorf("identity.type is required")
+	default:
+		return fmt.Errorf("unknown identity.type %q (supported: User, ServiceAccount, System, Associate, X509)", ident.Type)
+	}
+	if !sectionPresent {
+		return fmt.Errorf("identity of type %s requires its %s section", ident.Type, section(ident.Type))
+	}
+
+	// Tenant-scoped identities must name their organization
+	switch ident.Type {
+	case TypeUser, TypeServiceAccount, TypeSystem:
+		if ident.OrgID == "" {
+			return fmt.Errorf("identity of type %s requires org_id", ident.Type)
+		}
+	}
+
+	return nil
+}
+
+// section returns the section name for an identity type
+func section(t Type) string {
+	switch t {
+	case TypeServiceAccount:
+		return "service_account"
+	case TypeX509:
+		return "x509"
+	default:
+		return string(bytes.ToLower([]byte(t)))
+	}
+}
@@ -0,0 +1,159 @@
+package risk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// maxResponseBytes bounds the risk engine responses read
+const maxResponseBytes = 1 << 20
+
+// HTTP delegates risk evaluation to an external risk engine
+//
+// Each issuance is POSTed as JSON:
+//
+//	{"subject": {...}, "actor": {...}, "request_attributes": {...},
+//	 "audience": "...", "scope": "..."}
+//
+// and the engine answers with its assessment, every field of which is
+// optional:
+//
+//	{"deny": false, "level": "medium", "reasons": ["new_device"], "max_ttl": "5m"}
+//
+// An empty object, or a 204 No Content response, means no opinion.
+type HTTP struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// HTTPConfig configures an HTTP risk evaluator
+type HTTPConfig struct {
+	// URL is the endpoint of the risk engine
+	URL string
+
+	// Headers are sent with every request, e.g. for authorization
+	Headers map[string]string
+
+	// Timeout bounds each request (default: 5s)
+	Timeout time.Duration
+
+	// Transport is an optional HTTP transport (e.g. for fixtures)
+	Transport http.RoundTripper
+}
+
+// httpRequest is the issuance as sent to the risk engine
+type httpRequest struct {
+	Subject           *trust.Result              `json:"subject,omitempty"`
+	Actor             *trust.Result              `json:"actor,omitempty"`
+	RequestAttributes *request.RequestAttributes `json:"request_attributes,omitempty"`
+	Audience          string                     `json:"audience,omitempty"`
+	Scope             string                     `json:"scope,omitempty"`
+}
+
+// httpResponse is the assessment of the risk engine
+type httpResponse struct {
+	Deny    bool     `json:"deny"`
+	Level   string   `json:"level"`
+	Reasons []string `json:"reasons"`
+	MaxTTL  string   `json:"max_ttl"`
+}
+
+var _ service.RiskEvaluator = (*HTTP)(nil)
+
+// NewHTTP creates an HTTP risk evaluator
+func NewHTTP(cfg HTTPConfig) (*HTTP, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	return &HTTP{
+		url:     cfg.URL,
+		headers: cfg.Headers,
+		client: &http.Client{
+			Transport: cfg.Transport,
+			Timeout:   timeout,
+		},
+	}, nil
+}
+
+// Evaluate implements service.RiskEvaluator
+func (h *HTTP) Evaluate(ctx context.Context, issueCtx *service.IssueContext) (*service.RiskAssessment, error) {
+	body, err := json.Marshal(httpRequest{
+		Subject:           issueCtx.Subject,
+		Actor:             issueCtx.Actor,
+		RequestAttributes: issueCtx.RequestAttributes,
+		Audience:          issueCtx.Audience,
+		Scope:             issueCtx.Scope,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode risk request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, value := range h.headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("risk request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("risk request failed: status %d", resp.StatusCode)
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+
+	var answer httpResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&answer); err != nil {
+		return nil, fmt.Errorf("invalid risk response: %w", err)
+	}
+	return answer.assessment()
+}
+
+// assessment converts the response to an assessment, or nil for no opinion
+func (r *httpResponse) assessment() (*service.RiskAssessment, error) {
+	assessment := &service.RiskAssessment{
+		Deny:    r.Deny,
+		Reasons: r.Reasons,
+	}
+	if r.Level != "" {
+		level, err := service.ParseRiskLevel(r.Level)
+		if err != nil {
+			return nil, fmt.Errorf("invalid risk response: %w", err)
+		}
+		assessment.Level = level
+	}
+	if r.MaxTTL != "" {
+		maxTTL, err := time.ParseDuration(r.MaxTTL)
+		if err != nil || maxTTL <= 0 {
+			return nil, fmt.Errorf("invalid risk response: max_ttl must be a positive duration, got %q", r.MaxTTL)
+		}
+		assessment.MaxTTL = maxTTL
+	}
+	if !assessment.Deny && assessment.Level == "" && assessment.MaxTTL == 0 && len(assessment.Reasons) == 0 {
+		return nil, nil
+	}
+	return assessment, nil
+}
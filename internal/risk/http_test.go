@@ -0,0 +1,112 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+func TestHTTP_Evaluate(t *testing.T) {
+	ctx := context.Background()
+
+	serve := func(t *testing.T, status int, body string) (*HTTP, *map[string]any) {
+		t.Helper()
+		received := map[string]any{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer engine-token" {
+				t.Errorf("unexpected request: %s %v", r.Method, r.Header)
+			}
+			if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+				t.Errorf("invalid request body: %v", err)
+			}
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+		}))
+		t.Cleanup(server.Close)
+
+		evaluator, err := NewHTTP(HTTPConfig{
+			URL:     server.URL,
+			Headers: map[string]string{"Authorization": "Bearer engine-token"},
+		})
+		if err != nil {
+			t.Fatalf("NewHTTP failed: %v", err)
+		}
+		return evaluator, &received
+	}
+
+	t.Run("returns the engine's assessment", func(t *testing.T) {
+		evaluator, received := serve(t, http.StatusOK, `{"level": "medium", "reasons": ["new_device"], "max_ttl": "5m"}`)
+		input := issueCtx("alice")
+		input.Audience = "api.example.com"
+		input.RequestAttributes = &request.RequestAttributes{Method: "POST", IPAddress: "203.0.113.7"}
+
+		assessment, err := evaluator.Evaluate(ctx, input)
+		if err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+		want := &service.RiskAssessment{Level: service.RiskLevelMedium, Reasons: []string{"new_device"}, MaxTTL: 5 * time.Minute}
+		if !reflect.DeepEqual(assessment, want) {
+			t.Errorf("expected %+v, got %+v", want, assessment)
+		}
+
+		subject, _ := (*received)["subject"].(map[string]any)
+		attrs, _ := (*received)["request_attributes"].(map[string]any)
+		if subject["subject"] != "alice" || attrs["ip_address"] != "203.0.113.7" || (*received)["audience"] != "api.example.com" {
+			t.Errorf("unexpected request: %v", *received)
+		}
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		evaluator, _ := serve(t, http.StatusOK, `{"deny": true, "reasons": ["impossible_travel"]}`)
+		assessment, err := evaluator.Evaluate(ctx, issueCtx("alice"))
+		if err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+		if assessment == nil || !assessment.Deny {
+			t.Errorf("expected a denial, got %+v", assessment)
+		}
+	})
+
+	t.Run("no opinion", func(t *testing.T) {
+		for _, tc := range []struct {
+			status int
+			body   string
+		}{{http.StatusOK, `{}`}, {http.StatusNoContent, ``}} {
+			evaluator, _ := serve(t, tc.status, tc.body)
+			assessment, err := evaluator.Evaluate(ctx, issueCtx("alice"))
+			if err != nil || assessment != nil {
+				t.Errorf("status %d: expected no opinion, got %+v, %v", tc.status, assessment, err)
+			}
+		}
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		for _, tc := range []struct {
+			status int
+			body   string
+		}{
+			{http.StatusInternalServerError, `{}`},
+			{http.StatusOK, `not json`},
+			{http.StatusOK, `{"level": "severe"}`},
+			{http.StatusOK, `{"max_ttl": "forever"}`},
+		} {
+			evaluator, _ := serve(t, tc.status, tc.body)
+			if _, err := evaluator.Evaluate(ctx, issueCtx("alice")); err == nil {
+				t.Errorf("status %d, body %s: expected an error", tc.status, tc.body)
+			}
+		}
+	})
+
+	t.Run("url is required", func(t *testing.T) {
+		if _, err := NewHTTP(HTTPConfig{}); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
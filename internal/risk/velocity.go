@@ -0,0 +1,134 @@
+// Package risk provides service.RiskEvaluator implementations: a built-in
+// velocity check, and an evaluator that delegates to an external risk engine
+// over HTTP.
+package risk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/quota"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// Velocity flags keys (e.g. subjects or actors) that are issued tokens more
+// often than usual
+//
+// Each issuance counts once against the key its selector selects, in fixed
+// windows. Issuances past the threshold of their window are assessed at the
+// configured level, and may have their lifetime capped or be denied outright.
+// Unlike a quota, velocity never refuses with a retry delay: it is meant to
+// grade issuances, not to throttle clients.
+type Velocity struct {
+	name      string
+	selector  quota.Selector
+	window    time.Duration
+	threshold int64
+	verdict   service.RiskAssessment
+	store     quota.CounterStore
+	clock     clock.Clock
+}
+
+// VelocityConfig configures a velocity evaluator
+type VelocityConfig struct {
+	// Name identifies the evaluator in counter keys, and is the reason given
+	// for its assessments (default: "velocity")
+	Name string
+
+	// Selector selects the key issuances count against
+	Selector quota.Selector
+
+	// Window is the length of the fixed windows issuances are counted in
+	Window time.Duration
+
+	// Threshold is the number of issuances per key and window past which
+	// issuances are assessed
+	Threshold int64
+
+	// Level is the risk level of assessed issuances (default: high)
+	Level service.RiskLevel
+
+	// MaxTTL caps the lifetime of the tokens of assessed issuances (optional)
+	MaxTTL time.Duration
+
+	// Deny denies assessed issuances
+	Deny bool
+
+	// Store holds the counters (defaults to a quota.MemoryStore)
+	Store quota.CounterStore
+
+	// Clock is the time source of the windows (defaults to system clock)
+	Clock clock.Clock
+}
+
+var _ service.RiskEvaluator = (*Velocity)(nil)
+
+// NewVelocity creates a velocity evaluator
+func NewVelocity(cfg VelocityConfig) (*Velocity, error) {
+	switch {
+	case cfg.Selector == nil:
+		return nil, fmt.Errorf("selector is required")
+	case cfg.Window <= 0:
+		return nil, fmt.Errorf("window must be positive, got %s", cfg.Window)
+	case cfg.Threshold <= 0:
+		return nil, fmt.Errorf("threshold must be positive, got %d", cfg.Threshold)
+	case cfg.MaxTTL < 0:
+		return nil, fmt.Errorf("max_ttl must not be negative, got %s", cfg.MaxTTL)
+	}
+
+	name := cfg.Name
+	if name == "" {
+		name = "velocity"
+	}
+	level := cfg.Level
+	if level == "" {
+		level = service.RiskLevelHigh
+	}
+	store := cfg.Store
+	if store == nil {
+		store = quota.NewMemoryStore()
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	return &Velocity{
+		name:      name,
+		selector:  cfg.Selector,
+		window:    cfg.Window,
+		threshold: cfg.Threshold,
+		verdict: service.RiskAssessment{
+			Deny:   cfg.Deny,
+			Level:  level,
+			MaxTTL: cfg.MaxTTL,
+		},
+		store: store,
+		clock: clk,
+	}, nil
+}
+
+// Evaluate implements service.RiskEvaluator
+func (v *Velocity) Evaluate(ctx context.Context, issueCtx *service.IssueContext) (*service.RiskAssessment, error) {
+	key, err := v.selector.Select(ctx, issueCtx)
+	if err != nil {
+		return nil, fmt.Errorf("velocity %q: %w", v.name, err)
+	}
+	if key == "" {
+		return nil, nil
+	}
+
+	count, _, err := v.store.Add(ctx, "risk\x00"+v.name+"\x00"+key, 1, v.window, v.clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("velocity %q: failed to count issuances: %w", v.name, err)
+	}
+	if count <= v.threshold {
+		return nil, nil
+	}
+
+	assessment := v.verdict
+	assessment.Reasons = []string{v.name}
+	return &assessment, nil
+}
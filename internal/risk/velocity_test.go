@@ -0,0 +1,104 @@
+package risk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/quota"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func mustSelector(t *testing.T, expression string) quota.Selector {
+	t.Helper()
+	selector, err := quota.NewCELSelector(expression)
+	if err != nil {
+		t.Fatalf("NewCELSelector failed: %v", err)
+	}
+	return selector
+}
+
+func issueCtx(subject string) *service.IssueContext {
+	return &service.IssueContext{
+		Subject: &trust.Result{Subject: subject, TrustDomain: "example.com"},
+	}
+}
+
+func TestVelocity_Evaluate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("assesses issuances past the threshold until the window ends", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+		velocity, err := NewVelocity(VelocityConfig{
+			Selector:  mustSelector(t, "subject.subject"),
+			Window:    time.Minute,
+			Threshold: 2,
+			MaxTTL:    time.Minute,
+			Clock:     clk,
+		})
+		if err != nil {
+			t.Fatalf("NewVelocity failed: %v", err)
+		}
+
+		for i := range 2 {
+			assessment, err := velocity.Evaluate(ctx, issueCtx("alice"))
+			if err != nil || assessment != nil {
+				t.Fatalf("issuance %d: expected no opinion, got %+v, %v", i, assessment, err)
+			}
+		}
+		assessment, err := velocity.Evaluate(ctx, issueCtx("alice"))
+		if err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+		if assessment == nil || assessment.Level != service.RiskLevelHigh || assessment.MaxTTL != time.Minute ||
+			assessment.Deny || len(assessment.Reasons) != 1 || assessment.Reasons[0] != "velocity" {
+			t.Errorf("unexpected assessment: %+v", assessment)
+		}
+
+		// Other subjects count separately
+		if assessment, _ := velocity.Evaluate(ctx, issueCtx("bob")); assessment != nil {
+			t.Errorf("expected no opinion for another subject, got %+v", assessment)
+		}
+
+		clk.Advance(time.Minute)
+		if assessment, _ := velocity.Evaluate(ctx, issueCtx("alice")); assessment != nil {
+			t.Errorf("expected no opinion in the next window, got %+v", assessment)
+		}
+	})
+
+	t.Run("unselected issuances are not counted", func(t *testing.T) {
+		velocity, err := NewVelocity(VelocityConfig{
+			Name:      "actor-velocity",
+			Selector:  mustSelector(t, `has(actor.subject) ? actor.subject : null`),
+			Window:    time.Minute,
+			Threshold: 1,
+			Level:     service.RiskLevelMedium,
+			Deny:      true,
+		})
+		if err != nil {
+			t.Fatalf("NewVelocity failed: %v", err)
+		}
+
+		for range 3 {
+			if assessment, err := velocity.Evaluate(ctx, issueCtx("alice")); err != nil || assessment != nil {
+				t.Fatalf("expected no opinion, got %+v, %v", assessment, err)
+			}
+		}
+	})
+
+	t.Run("rejects invalid configurations", func(t *testing.T) {
+		selector := mustSelector(t, "subject.subject")
+		for name, cfg := range map[string]VelocityConfig{
+			"no selector":      {Window: time.Minute, Threshold: 1},
+			"no window":        {Selector: selector, Threshold: 1},
+			"no threshold":     {Selector: selector, Window: time.Minute},
+			"negative max ttl": {Selector: selector, Window: time.Minute, Threshold: 1, MaxTTL: -time.Second},
+		} {
+			if _, err := NewVelocity(cfg); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+	})
+}
@@ -3,8 +3,10 @@ package server
 import (
 	"context"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"google.golang.org/grpc/credentials"
@@ -14,47 +16,24 @@ import (
 	"github.com/project-kessel/parsec/internal/trust"
 )
 
-// extractActorCredential extracts credentials from the gRPC context
-// This identifies the calling actor (e.g., gateway making the request to parsec)
-// Returns nil credential and nil error if no actor authentication is present
-func extractActorCredential(ctx context.Context) (trust.Credential, error) {
-	// 1. Try to extract mTLS certificate from peer
-	if p, ok := peer.FromContext(ctx); ok {
-		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
-			// Check if client certificate is present
-			if len(tlsInfo.State.PeerCertificates) > 0 {
-				clientCert := tlsInfo.State.PeerCertificates[0]
-
-				// Convert certificate to DER encoding
-				certDER := clientCert.Raw
-
-				// Build certificate chain
-				chain := make([][]byte, len(tlsInfo.State.PeerCertificates)-1)
-				for i, cert := range tlsInfo.State.PeerCertificates[1:] {
-					chain[i] = cert.Raw
-				}
-
-				// Extract issuer identity from certificate
-				issuerIdentity := extractIssuerFromCert(clientCert)
-
-				return &trust.MTLSCredential{
-					Certificate:    certDER,
-					Chain:          chain,
-					IssuerIdentity: issuerIdentity,
-				}, nil
-			}
-		}
-	}
+// ActorCredentialExtractor extracts the credential of the calling actor (e.g.,
+// the gateway making the request to parsec) from the gRPC context
+type ActorCredentialExtractor interface {
+	// ExtractActorCredential returns the actor's credential
+	// Returns nil credential and nil error if this source holds no credential
+	ExtractActorCredential(ctx context.Context) (trust.Credential, error)
+}
 
-	// 2. Try to extract bearer token from gRPC metadata as fallback
-	if md, ok := metadata.FromIncomingContext(ctx); ok {
-		if authHeaders := md.Get("authorization"); len(authHeaders) > 0 {
-			authHeader := authHeaders[0]
-			if token, ok := strings.CutPrefix(authHeader, "Bearer "); ok {
-				return &trust.BearerCredential{
-					Token: token,
-				}, nil
-			}
+// ActorCredentialExtractorChain tries extractors in order and returns the first
+// credential found
+type ActorCredentialExtractorChain []ActorCredentialExtractor
+
+// ExtractActorCredential implements ActorCredentialExtractor
+func (c ActorCredentialExtractorChain) ExtractActorCredential(ctx context.Context) (trust.Credential, error) {
+	for _, extractor := range c {
+		cred, err := extractor.ExtractActorCredential(ctx)
+		if err != nil || cred != nil {
+			return cred, err
 		}
 	}
 
@@ -62,6 +41,130 @@ func extractActorCredential(ctx context.Context) (trust.Credential, error) {
 	return nil, nil
 }
 
+// DefaultActorCredentialExtractor returns the extractor used when none is
+// configured: the mTLS peer certificate, then a bearer token in the
+// "authorization" metadata
+func DefaultActorCredentialExtractor() ActorCredentialExtractor {
+	return ActorCredentialExtractorChain{
+		MTLSActorCredentialExtractor{},
+		BearerActorCredentialExtractor{},
+	}
+}
+
+// MTLSActorCredentialExtractor extracts the client certificate of the mTLS peer
+type MTLSActorCredentialExtractor struct{}
+
+// ExtractActorCredential implements ActorCredentialExtractor
+func (MTLSActorCredentialExtractor) ExtractActorCredential(ctx context.Context) (trust.Credential, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return nil, nil
+	}
+
+	clientCert := tlsInfo.State.PeerCertificates[0]
+
+	// Build certificate chain
+	chain := make([][]byte, len(tlsInfo.State.PeerCertificates)-1)
+	for i, cert := range tlsInfo.State.PeerCertificates[1:] {
+		chain[i] = cert.Raw
+	}
+
+	return &trust.MTLSCredential{
+		Certificate:    clientCert.Raw,
+		Chain:          chain,
+		IssuerIdentity: extractIssuerFromCert(clientCert),
+	}, nil
+}
+
+// BearerActorCredentialExtractor extracts a "Bearer" token from a gRPC metadata key
+// Values without the Bearer scheme are ignored.
+type BearerActorCredentialExtractor struct {
+	// Key is the metadata key (default: "authorization")
+	Key string
+}
+
+// ExtractActorCredential implements ActorCredentialExtractor
+func (e BearerActorCredentialExtractor) ExtractActorCredential(ctx context.Context) (trust.Credential, error) {
+	key := e.Key
+	if key == "" {
+		key = "authorization"
+	}
+	value := firstIncomingMetadata(ctx, key)
+	if token, ok := strings.CutPrefix(value, "Bearer "); ok {
+		return &trust.BearerCredential{Token: token}, nil
+	}
+	return nil, nil
+}
+
+// HeaderActorCredentialExtractor extracts a bearer credential whose token is the
+// whole value of a gRPC metadata key, e.g. "x-actor-token"
+//
+// Over HTTP, grpc-gateway forwards custom headers only when they are sent with
+// the Grpc-Metadata- prefix (Grpc-Metadata-X-Actor-Token).
+type HeaderActorCredentialExtractor struct {
+	// Key is the metadata key
+	Key string
+}
+
+// ExtractActorCredential implements ActorCredentialExtractor
+func (e HeaderActorCredentialExtractor) ExtractActorCredential(ctx context.Context) (trust.Credential, error) {
+	if value := firstIncomingMetadata(ctx, e.Key); value != "" {
+		return &trust.BearerCredential{Token: value}, nil
+	}
+	return nil, nil
+}
+
+// ClientSecretActorCredentialExtractor extracts a client ID and secret, for
+// clients that can't easily mint tokens to call parsec
+// It reads HTTP basic authentication in the "authorization" metadata, with
+// the ID and secret form-urlencoded as RFC 6749 (section 2.3.1) requires, or
+// else the "client_id" and "client_secret" metadata keys (sent over HTTP as
+// Grpc-Metadata-Client-Id and Grpc-Metadata-Client-Secret).
+type ClientSecretActorCredentialExtractor struct{}
+
+// ExtractActorCredential implements ActorCredentialExtractor
+func (ClientSecretActorCredentialExtractor) ExtractActorCredential(ctx context.Context) (trust.Credential, error) {
+	if value, ok := strings.CutPrefix(firstIncomingMetadata(ctx, "authorization"), "Basic "); ok {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid basic authorization: %w", err)
+		}
+		id, secret, ok := strings.Cut(string(raw), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid basic authorization: missing client secret")
+		}
+		if id, err = url.QueryUnescape(id); err != nil {
+			return nil, fmt.Errorf("invalid basic authorization client ID: %w", err)
+		}
+		if secret, err = url.QueryUnescape(secret); err != nil {
+			return nil, fmt.Errorf("invalid basic authorization client secret: %w", err)
+		}
+		return &trust.ClientSecretCredential{ClientID: id, ClientSecret: secret}, nil
+	}
+
+	id := firstIncomingMetadata(ctx, "client_id")
+	if id == "" {
+		return nil, nil
+	}
+	return &trust.ClientSecretCredential{ClientID: id, ClientSecret: firstIncomingMetadata(ctx, "client_secret")}, nil
+}
+
+// firstIncomingMetadata returns the first value of key in the incoming metadata
+func firstIncomingMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
 // extractIssuerFromCert extracts an issuer identity from a certificate
 // This uses the certificate's issuer DN as the identity
 func extractIssuerFromCert(cert *x509.Certificate) string {
@@ -0,0 +1,317 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/ledger"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+const (
+	// cacheInvalidatePath is where AdminServer serves cache invalidation
+	cacheInvalidatePath = "/v1/admin/cache/invalidate"
+
+	// adminKeysPath is where AdminServer lists signing keys; keys are rotated
+	// and revoked under adminKeysPath/{signer}/rotate and /revoke
+	adminKeysPath = "/v1/admin/keys"
+
+	// adminIssuersPath and adminDataSourcesPath are where AdminServer lists
+	// the registered issuers and data sources; each is described under
+	// its token type or name below the path
+	adminIssuersPath     = "/v1/admin/issuers"
+	adminDataSourcesPath = "/v1/admin/data_sources"
+
+	// adminTokensPath is where AdminServer queries the token ledger
+	adminTokensPath = "/v1/admin/tokens"
+)
+
+// PeerList lists the instances sharing data source caches
+type PeerList interface {
+	// Self returns this instance's base URL as listed among the peers
+	Self() string
+
+	// Peers returns the base URLs of all instances, including Self
+	Peers() []string
+}
+
+// AdminServer serves administrative endpoints over HTTP
+//
+// POST /v1/admin/cache/invalidate invalidates cached data source entries on
+// this instance and, with peers, on every other instance, so changes behind a
+// data source can take effect before its cache TTL.
+//
+// GET /v1/admin/keys lists the key slots of every signer. POST
+// /v1/admin/keys/{signer}/rotate generates a new key for a signer ahead of
+// schedule, and POST /v1/admin/keys/{signer}/revoke replaces the key named by
+// the body's key_id.
+//
+// GET /v1/admin/issuers and /v1/admin/data_sources list the registered issuers
+// and data sources with their types, TTLs and caching, and GET
+// /v1/admin/issuers/{token_type} and /v1/admin/data_sources/{name} describe one.
+//
+// GET /v1/admin/tokens lists the tokens recorded in the token ledger, most
+// recent first, by subject, actor or jti and issuance time.
+type AdminServer struct {
+	dataSources *service.DataSourceRegistry
+	issuers     service.Registry
+	signers     *keys.SignerRegistry
+	jwks        *JWKSServer
+	ledger      *ledger.Ledger
+	peers       PeerList
+	client      *http.Client
+	callers     callerAuthorizer
+	logger      *slog.Logger
+}
+
+// AdminServerConfig configures the admin server
+type AdminServerConfig struct {
+	// DataSourceRegistry holds the data sources whose caches are invalidated
+	DataSourceRegistry *service.DataSourceRegistry
+
+	// IssuerRegistry holds the issuers described under /v1/admin/issuers (optional)
+	IssuerRegistry service.Registry
+
+	// Signers hold the keys managed under /v1/admin/keys (optional)
+	Signers *keys.SignerRegistry
+
+	// JWKSServer is refreshed after keys change, so the change is published
+	// without waiting for its refresh interval (optional)
+	JWKSServer *JWKSServer
+
+	// Ledger records the tokens listed under /v1/admin/tokens (optional)
+	Ledger *ledger.Ledger
+
+	// Peers are the other instances to forward invalidations to (optional)
+	// Peers serve the admin endpoints on the HTTP port at their base URL.
+	Peers PeerList
+
+	// HTTPClient forwards invalidations to peers (defaults to http.DefaultClient)
+	HTTPClient *http.Client
+
+	// TrustStore authenticates callers (optional)
+	// When set, callers must present a bearer credential accepted by the store
+	// When nil, the endpoints are unauthenticated and must be protected by other means
+	TrustStore trust.Store
+
+	// AllowedCallers are the operators allowed to call the admin endpoints
+	// An authenticated caller matching none is refused.
+	AllowedCallers []AllowedCaller
+
+	// Logger is the structured logger to use (defaults to slog.Default())
+	Logger *slog.Logger
+}
+
+// NewAdminServer creates a new admin server
+func NewAdminServer(cfg AdminServerConfig) *AdminServer {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &AdminServer{
+		dataSources: cfg.DataSourceRegistry,
+		issuers:     cfg.IssuerRegistry,
+		signers:     cfg.Signers,
+		jwks:        cfg.JWKSServer,
+		ledger:      cfg.Ledger,
+		peers:       cfg.Peers,
+		client:      cfg.HTTPClient,
+		callers: callerAuthorizer{
+			trustStore:     cfg.TrustStore,
+			allowedCallers: cfg.AllowedCallers,
+		},
+		logger: cfg.Logger,
+	}
+}
+
+// cacheInvalidateRequest is the body of a cache invalidation request
+type cacheInvalidateRequest struct {
+	DataSource string `json:"data_source"`
+	service.CacheInvalidation
+}
+
+// cacheInvalidateResponse reports the peers an invalidation could not reach
+type cacheInvalidateResponse struct {
+	DataSource  string   `json:"data_source"`
+	FailedPeers []string `json:"failed_peers,omitempty"`
+}
+
+// ServeHTTP implements http.Handler
+func (s *AdminServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler, method := s.route(r.URL.Path)
+	if handler == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeOAuthError(w, http.StatusMethodNotAllowed, "invalid_request", r.URL.Path+" requires "+method)
+		return
+	}
+
+	if s.callers.trustStore != nil {
+		caller, err := s.callers.authenticate(r)
+		if err != nil {
+			s.logger.Warn("admin caller authentication failed", "error", err)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "caller authentication failed")
+			return
+		}
+		if !s.callers.authorized(caller) {
+			s.logger.Warn("admin caller not allowed",
+				"subject", caller.Subject, "trust_domain", caller.TrustDomain)
+			writeOAuthError(w, http.StatusForbidden, "access_denied", "caller may not use admin endpoints")
+			return
+		}
+	}
+
+	handler(w, r)
+}
+
+// route returns the handler for an admin path and the method it accepts
+func (s *AdminServer) route(path string) (http.HandlerFunc, string) {
+	if path == cacheInvalidatePath {
+		return s.invalidateCache, http.MethodPost
+	}
+	if path == adminKeysPath {
+		return s.listKeys, http.MethodGet
+	}
+	if path == adminIssuersPath {
+		return s.listIssuers, http.MethodGet
+	}
+	if tokenType, ok := strings.CutPrefix(path, adminIssuersPath+"/"); ok && tokenType != "" {
+		return func(w http.ResponseWriter, r *http.Request) { s.describeIssuer(w, r, tokenType) }, http.MethodGet
+	}
+	if path == adminTokensPath {
+		return s.listTokens, http.MethodGet
+	}
+	if path == adminDataSourcesPath {
+		return s.listDataSources, http.MethodGet
+	}
+	if name, ok := strings.CutPrefix(path, adminDataSourcesPath+"/"); ok && name != "" {
+		return func(w http.ResponseWriter, r *http.Request) { s.describeDataSource(w, r, name) }, http.MethodGet
+	}
+	rest, ok := strings.CutPrefix(path, adminKeysPath+"/")
+	if !ok {
+		return nil, ""
+	}
+	signer, action, ok := strings.Cut(rest, "/")
+	if !ok || signer == "" {
+		return nil, ""
+	}
+	switch action {
+	case "rotate":
+		return func(w http.ResponseWriter, r *http.Request) { s.rotateKey(w, r, signer) }, http.MethodPost
+	case "revoke":
+		return func(w http.ResponseWriter, r *http.Request) { s.revokeKey(w, r, signer) }, http.MethodPost
+	}
+	return nil, ""
+}
+
+// invalidateCache expects a JSON body naming the data source and, optionally,
+// the subject or key to invalidate. Requests forwarded by a peer carry
+// ?local=true and are not forwarded again.
+func (s *AdminServer) invalidateCache(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "failed to read request body")
+		return
+	}
+	var req cacheInvalidateRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "malformed JSON body")
+		return
+	}
+	if req.DataSource == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "missing data_source")
+		return
+	}
+
+	if err := s.dataSources.InvalidateCache(r.Context(), req.DataSource, req.CacheInvalidation); err != nil {
+		s.logger.Warn("cache invalidation failed", "data_source", req.DataSource, "error", err)
+		switch perr.CodeOf(err) {
+		case perr.ErrCodeNotFound:
+			writeOAuthError(w, http.StatusNotFound, "not_found", err.Error())
+		case perr.ErrCodeInvalidRequest:
+			writeOAuthError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		default:
+			writeOAuthError(w, http.StatusInternalServerError, "server_error", "cache invalidation failed")
+		}
+		return
+	}
+	s.logger.Info("cache invalidated", "data_source", req.DataSource,
+		"subject", req.Subject, "key", req.Key != "", "forwarded", r.URL.Query().Get("local") == "true")
+
+	resp := cacheInvalidateResponse{DataSource: req.DataSource}
+	if r.URL.Query().Get("local") != "true" {
+		resp.FailedPeers = s.forward(r.Context(), body, r.Header.Get("Authorization"))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// forward sends an invalidation to every peer but this instance, with the
+// caller's credential, and returns the peers that failed
+func (s *AdminServer) forward(ctx context.Context, body []byte, authorization string) []string {
+	if s.peers == nil {
+		return nil
+	}
+	self := s.peers.Self()
+
+	var (
+		mu     sync.Mutex
+		failed []string
+		wg     sync.WaitGroup
+	)
+	for _, peer := range s.peers.Peers() {
+		if peer == self {
+			continue
+		}
+		wg.Go(func() {
+			if err := s.forwardTo(ctx, peer, body, authorization); err != nil {
+				s.logger.Warn("failed to forward cache invalidation", "peer", peer, "error", err)
+				mu.Lock()
+				failed = append(failed, peer)
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	slices.Sort(failed)
+	return failed
+}
+
+func (s *AdminServer) forwardTo(ctx context.Context, peer string, body []byte, authorization string) error {
+	url := strings.TrimSuffix(peer, "/") + cacheInvalidatePath + "?local=true"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
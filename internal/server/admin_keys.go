@@ -0,0 +1,140 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/project-kessel/parsec/internal/keys"
+)
+
+// signerKeys are the key slots of one signer
+type signerKeys struct {
+	Signer    string            `json:"signer"`
+	Namespace string            `json:"namespace"`
+	Slots     []keys.SlotStatus `json:"slots"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// listKeysResponse lists the key slots of every signer, ordered by signer
+type listKeysResponse struct {
+	Signers []signerKeys `json:"signers"`
+}
+
+// keyChangeResponse reports the slot a rotation or revocation changed
+type keyChangeResponse struct {
+	Signer string          `json:"signer"`
+	Slot   keys.SlotStatus `json:"slot"`
+}
+
+// revokeKeyRequest is the body of a key revocation request
+type revokeKeyRequest struct {
+	KeyID string `json:"key_id"`
+}
+
+// listKeys reports the slots of every signer
+// A signer whose slots cannot be read is listed with its error.
+func (s *AdminServer) listKeys(w http.ResponseWriter, r *http.Request) {
+	resp := listKeysResponse{Signers: []signerKeys{}}
+	if s.signers != nil {
+		for id, manager := range s.signers.KeyManagers() {
+			entry := signerKeys{Signer: id, Namespace: manager.Namespace()}
+			slots, err := manager.Slots(r.Context())
+			if err != nil {
+				s.logger.Warn("failed to list key slots", "signer", id, "error", err)
+				entry.Error = err.Error()
+			}
+			entry.Slots = slots
+			resp.Signers = append(resp.Signers, entry)
+		}
+	}
+	slices.SortFunc(resp.Signers, func(a, b signerKeys) int {
+		return cmp.Or(strings.Compare(a.Namespace, b.Namespace), strings.Compare(a.Signer, b.Signer))
+	})
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// rotateKey generates a new key for a signer ahead of schedule
+func (s *AdminServer) rotateKey(w http.ResponseWriter, r *http.Request, signer string) {
+	manager, ok := s.keyManager(w, signer)
+	if !ok {
+		return
+	}
+	slot, err := manager.Rotate(r.Context())
+	if err != nil {
+		s.writeKeyError(w, signer, "rotation", err)
+		return
+	}
+	s.logger.Info("key rotated", "signer", signer, "slot", slot.Position, "kid", slot.KeyID)
+	s.keysChanged(r)
+	writeJSON(w, http.StatusOK, keyChangeResponse{Signer: signer, Slot: slot})
+}
+
+// revokeKey replaces a signer's key named by the request body
+func (s *AdminServer) revokeKey(w http.ResponseWriter, r *http.Request, signer string) {
+	manager, ok := s.keyManager(w, signer)
+	if !ok {
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "failed to read request body")
+		return
+	}
+	var req revokeKeyRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "malformed JSON body")
+		return
+	}
+	if req.KeyID == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "missing key_id")
+		return
+	}
+
+	slot, err := manager.Revoke(r.Context(), keys.KeyID(req.KeyID))
+	if err != nil {
+		s.writeKeyError(w, signer, "revocation", err)
+		return
+	}
+	s.logger.Warn("key revoked", "signer", signer, "revoked_kid", req.KeyID, "slot", slot.Position, "kid", slot.KeyID)
+	s.keysChanged(r)
+	writeJSON(w, http.StatusOK, keyChangeResponse{Signer: signer, Slot: slot})
+}
+
+// keyManager finds a signer's key manager, answering 404 if there is none
+func (s *AdminServer) keyManager(w http.ResponseWriter, signer string) (keys.KeyManager, bool) {
+	if s.signers != nil {
+		if manager, ok := s.signers.KeyManagers()[signer]; ok {
+			return manager, true
+		}
+	}
+	writeOAuthError(w, http.StatusNotFound, "not_found", "no signer with managed keys: "+signer)
+	return nil, false
+}
+
+// writeKeyError answers a failed rotation or revocation
+func (s *AdminServer) writeKeyError(w http.ResponseWriter, signer, operation string, err error) {
+	s.logger.Warn("key "+operation+" failed", "signer", signer, "error", err)
+	switch {
+	case errors.Is(err, keys.ErrKeyNotFound):
+		writeOAuthError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, keys.ErrRotationPending), errors.Is(err, keys.ErrVersionMismatch):
+		writeOAuthError(w, http.StatusConflict, "conflict", err.Error())
+	default:
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "key "+operation+" failed")
+	}
+}
+
+// keysChanged republishes the JWKS after keys changed
+func (s *AdminServer) keysChanged(r *http.Request) {
+	if s.jwks == nil {
+		return
+	}
+	if err := s.jwks.refreshCache(r.Context()); err != nil {
+		s.logger.Warn("failed to refresh JWKS after key change", "error", err)
+	}
+}
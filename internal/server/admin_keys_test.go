@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/keys"
+)
+
+func TestAdminServer_Keys(t *testing.T) {
+	clk := clock.NewFixtureClock(time.Time{})
+	signer := keys.NewDualSlotRotatingSigner(keys.DualSlotRotatingSignerConfig{
+		Namespace:           "txn-tokens",
+		TrustDomain:         "parsec.test",
+		KeyProviderID:       "memory",
+		KeyProviderRegistry: map[string]keys.KeyProvider{"memory": keys.NewInMemoryKeyProvider(keys.KeyTypeECP256, "ES256")},
+		SlotStore:           keys.NewInMemoryKeySlotStore(),
+		Clock:               clk,
+		KeyTTL:              time.Hour,
+		RotationThreshold:   10 * time.Minute,
+		GracePeriod:         time.Minute,
+	})
+	registry := keys.NewSignerRegistry()
+	if err := registry.Register("txn", signer); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = registry.Stop(context.Background()) }()
+	clk.Advance(2 * time.Minute)
+
+	srv := NewAdminServer(AdminServerConfig{Signers: registry})
+	call := func(method, target, body string) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid JSON response %q: %v", rec.Body.String(), err)
+		}
+		return rec, resp
+	}
+
+	rec, resp := call(http.MethodGet, "/v1/admin/keys", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %v", rec.Code, resp)
+	}
+	signers := resp["signers"].([]any)
+	if len(signers) != 1 {
+		t.Fatalf("expected one signer, got %v", signers)
+	}
+	entry := signers[0].(map[string]any)
+	slots := entry["slots"].([]any)
+	if entry["signer"] != "txn" || entry["namespace"] != "txn-tokens" || len(slots) != 2 {
+		t.Fatalf("unexpected signer entry: %v", entry)
+	}
+	activeKeyID := slots[0].(map[string]any)["key_id"].(string)
+	if slots[0].(map[string]any)["state"] != "active" || slots[1].(map[string]any)["state"] != "empty" {
+		t.Errorf("unexpected slot states: %v", slots)
+	}
+
+	rec, resp = call(http.MethodPost, "/v1/admin/keys/txn/rotate", "")
+	slot, _ := resp["slot"].(map[string]any)
+	if rec.Code != http.StatusOK || slot["position"] != "B" || slot["state"] != "pending" {
+		t.Fatalf("expected a pending key in slot B, got %d: %v", rec.Code, resp)
+	}
+
+	rec, resp = call(http.MethodPost, "/v1/admin/keys/txn/rotate", "")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 while a rotation is pending, got %d: %v", rec.Code, resp)
+	}
+
+	rec, resp = call(http.MethodPost, "/v1/admin/keys/txn/revoke", `{"key_id":"`+activeKeyID+`"}`)
+	slot, _ = resp["slot"].(map[string]any)
+	if rec.Code != http.StatusOK || slot["position"] != "A" || slot["key_id"] == activeKeyID {
+		t.Fatalf("expected slot A to get a new key, got %d: %v", rec.Code, resp)
+	}
+	publicKeys, err := signer.PublicKeys(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range publicKeys {
+		if key.KeyID == activeKeyID {
+			t.Error("expected the revoked key to no longer be published")
+		}
+	}
+
+	for _, tc := range []struct {
+		name, method, target, body string
+		status                     int
+	}{
+		{"unknown key", http.MethodPost, "/v1/admin/keys/txn/revoke", `{"key_id":"nope"}`, http.StatusNotFound},
+		{"missing key id", http.MethodPost, "/v1/admin/keys/txn/revoke", `{}`, http.StatusBadRequest},
+		{"unknown signer", http.MethodPost, "/v1/admin/keys/other/rotate", "", http.StatusNotFound},
+		{"wrong method", http.MethodPost, "/v1/admin/keys", "", http.StatusMethodNotAllowed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if rec, resp := call(tc.method, tc.target, tc.body); rec.Code != tc.status {
+				t.Errorf("expected %d, got %d: %v", tc.status, rec.Code, resp)
+			}
+		})
+	}
+}
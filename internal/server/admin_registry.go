@@ -0,0 +1,117 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// issuerEntry describes a registered issuer
+type issuerEntry struct {
+	TokenType  string `json:"token_type"`
+	Type       string `json:"type,omitempty"`
+	TTL        string `json:"ttl,omitempty"`
+	TTLPolicy  bool   `json:"ttl_policy,omitempty"`
+	SignerID   string `json:"signer_id,omitempty"`
+	Plugin     string `json:"plugin,omitempty"`
+	Encrypted  bool   `json:"encrypted,omitempty"`
+	Provenance bool   `json:"provenance,omitempty"`
+}
+
+// listIssuersResponse describes every registered issuer, ordered by token type
+type listIssuersResponse struct {
+	Issuers []issuerEntry `json:"issuers"`
+}
+
+// dataSourceEntry describes a registered data source
+type dataSourceEntry struct {
+	Name      string `json:"name"`
+	Type      string `json:"type,omitempty"`
+	Cacheable bool   `json:"cacheable"`
+	Cache     string `json:"cache,omitempty"`
+	CacheTTL  string `json:"cache_ttl,omitempty"`
+}
+
+// listDataSourcesResponse describes every registered data source, ordered by name
+type listDataSourcesResponse struct {
+	DataSources []dataSourceEntry `json:"data_sources"`
+}
+
+func newIssuerEntry(info service.IssuerInfo) issuerEntry {
+	entry := issuerEntry{
+		TokenType:  string(info.TokenType),
+		Type:       info.Type,
+		TTLPolicy:  info.TTLPolicy,
+		SignerID:   info.SignerID,
+		Plugin:     info.Plugin,
+		Encrypted:  info.Encrypted,
+		Provenance: info.Provenance,
+	}
+	if info.TTL > 0 {
+		entry.TTL = info.TTL.String()
+	}
+	return entry
+}
+
+func newDataSourceEntry(info service.DataSourceInfo) dataSourceEntry {
+	entry := dataSourceEntry{
+		Name:      info.Name,
+		Type:      info.Type,
+		Cacheable: info.Cacheable,
+		Cache:     info.Cache,
+	}
+	if info.CacheTTL > 0 {
+		entry.CacheTTL = info.CacheTTL.String()
+	}
+	return entry
+}
+
+// listIssuers describes every registered issuer
+func (s *AdminServer) listIssuers(w http.ResponseWriter, r *http.Request) {
+	resp := listIssuersResponse{Issuers: []issuerEntry{}}
+	if s.issuers != nil {
+		for _, info := range s.issuers.List() {
+			resp.Issuers = append(resp.Issuers, newIssuerEntry(info))
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// describeIssuer describes the issuer registered for a token type
+func (s *AdminServer) describeIssuer(w http.ResponseWriter, r *http.Request, tokenType string) {
+	if s.issuers == nil {
+		writeOAuthError(w, http.StatusNotFound, "not_found", "no issuer registered for token type: "+tokenType)
+		return
+	}
+	info, err := s.issuers.Describe(service.TokenType(tokenType))
+	if err != nil {
+		writeOAuthError(w, http.StatusNotFound, "not_found", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, newIssuerEntry(info))
+}
+
+// listDataSources describes every registered data source
+func (s *AdminServer) listDataSources(w http.ResponseWriter, r *http.Request) {
+	resp := listDataSourcesResponse{DataSources: []dataSourceEntry{}}
+	if s.dataSources != nil {
+		for _, info := range s.dataSources.List() {
+			resp.DataSources = append(resp.DataSources, newDataSourceEntry(info))
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// describeDataSource describes the named data source
+func (s *AdminServer) describeDataSource(w http.ResponseWriter, r *http.Request, name string) {
+	if s.dataSources == nil {
+		writeOAuthError(w, http.StatusNotFound, "not_found", "no data source named "+name)
+		return
+	}
+	info, err := s.dataSources.Describe(name)
+	if err != nil {
+		writeOAuthError(w, http.StatusNotFound, "not_found", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, newDataSourceEntry(info))
+}
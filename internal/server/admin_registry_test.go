@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/issuer"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+func TestAdminServer_Registry(t *testing.T) {
+	issuers := service.NewSimpleRegistry()
+	issuers.RegisterWithInfo(service.IssuerInfo{
+		TokenType: service.TokenTypeTransactionToken,
+		Type:      "transaction_token",
+		TTL:       5 * time.Minute,
+		SignerID:  "txn",
+	}, issuer.NewStubIssuer(issuer.StubIssuerConfig{}))
+	dataSources := service.NewDataSourceRegistry()
+	dataSources.RegisterWithInfo(service.DataSourceInfo{
+		Type:      "http",
+		Cacheable: true,
+		Cache:     "redis",
+		CacheTTL:  10 * time.Minute,
+	}, &invalidatingDataSource{})
+	dataSources.Register(uncachedDataSource{})
+
+	srv := NewAdminServer(AdminServerConfig{IssuerRegistry: issuers, DataSourceRegistry: dataSources})
+	get := func(target string) (*httptest.ResponseRecorder, map[string]any) {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid JSON response %q: %v", rec.Body.String(), err)
+		}
+		return rec, resp
+	}
+
+	rec, resp := get("/v1/admin/issuers")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %v", rec.Code, resp)
+	}
+	list := resp["issuers"].([]any)
+	if len(list) != 1 {
+		t.Fatalf("expected one issuer, got %v", list)
+	}
+	entry := list[0].(map[string]any)
+	if entry["token_type"] != string(service.TokenTypeTransactionToken) || entry["type"] != "transaction_token" ||
+		entry["ttl"] != "5m0s" || entry["signer_id"] != "txn" {
+		t.Errorf("unexpected issuer %v", entry)
+	}
+
+	rec, resp = get("/v1/admin/issuers/" + string(service.TokenTypeTransactionToken))
+	if rec.Code != http.StatusOK || resp["type"] != "transaction_token" {
+		t.Errorf("expected the issuer, got %d: %v", rec.Code, resp)
+	}
+	if rec, resp := get("/v1/admin/issuers/urn:example:unknown"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown token type, got %d: %v", rec.Code, resp)
+	}
+
+	rec, resp = get("/v1/admin/data_sources")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %v", rec.Code, resp)
+	}
+	list = resp["data_sources"].([]any)
+	if len(list) != 2 {
+		t.Fatalf("expected two data sources, got %v", list)
+	}
+	if first := list[0].(map[string]any); first["name"] != "uncached" || first["cacheable"] != false {
+		t.Errorf("expected the uncached data source first, got %v", first)
+	}
+
+	rec, resp = get("/v1/admin/data_sources/user-info")
+	if rec.Code != http.StatusOK || resp["type"] != "http" || resp["cache"] != "redis" || resp["cache_ttl"] != "10m0s" || resp["cacheable"] != true {
+		t.Errorf("expected the cached data source, got %d: %v", rec.Code, resp)
+	}
+	if rec, resp := get("/v1/admin/data_sources/unknown"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown data source, got %d: %v", rec.Code, resp)
+	}
+
+	t.Run("without registries", func(t *testing.T) {
+		srv := NewAdminServer(AdminServerConfig{})
+		for _, target := range []string{"/v1/admin/issuers", "/v1/admin/data_sources"} {
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("%s: expected 200, got %d", target, rec.Code)
+			}
+		}
+	})
+}
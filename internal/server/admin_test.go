@@ -0,0 +1,240 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// invalidatingDataSource records the cache invalidations it receives
+type invalidatingDataSource struct {
+	mu            sync.Mutex
+	invalidations []service.CacheInvalidation
+}
+
+func (d *invalidatingDataSource) Name() string { return "user-info" }
+
+func (d *invalidatingDataSource) Fetch(context.Context, *service.DataSourceInput) (*service.DataSourceResult, error) {
+	return nil, nil
+}
+
+func (d *invalidatingDataSource) InvalidateCache(_ context.Context, inv service.CacheInvalidation) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.invalidations = append(d.invalidations, inv)
+	return nil
+}
+
+func (d *invalidatingDataSource) received() []service.CacheInvalidation {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]service.CacheInvalidation(nil), d.invalidations...)
+}
+
+// uncachedDataSource has no cache to invalidate
+type uncachedDataSource struct{}
+
+func (uncachedDataSource) Name() string { return "uncached" }
+
+func (uncachedDataSource) Fetch(context.Context, *service.DataSourceInput) (*service.DataSourceResult, error) {
+	return nil, nil
+}
+
+// staticPeers lists fixed peers
+type staticPeers struct {
+	self  string
+	peers []string
+}
+
+func (p staticPeers) Self() string    { return p.self }
+func (p staticPeers) Peers() []string { return p.peers }
+
+func TestAdminServer_InvalidateCache(t *testing.T) {
+	newRegistry := func() (*service.DataSourceRegistry, *invalidatingDataSource) {
+		source := &invalidatingDataSource{}
+		registry := service.NewDataSourceRegistry()
+		registry.Register(source)
+		registry.Register(uncachedDataSource{})
+		return registry, source
+	}
+
+	invalidate := func(srv http.Handler, target, body, bearer string) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid JSON response: %v", err)
+		}
+		return rec, resp
+	}
+
+	t.Run("invalidates by subject", func(t *testing.T) {
+		registry, source := newRegistry()
+		srv := NewAdminServer(AdminServerConfig{DataSourceRegistry: registry})
+
+		rec, resp := invalidate(srv, cacheInvalidatePath, `{"data_source":"user-info","subject":"alice"}`, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d %v", rec.Code, resp)
+		}
+		if resp["data_source"] != "user-info" {
+			t.Errorf("expected data_source in response, got %v", resp)
+		}
+		got := source.received()
+		if len(got) != 1 || got[0] != (service.CacheInvalidation{Subject: "alice"}) {
+			t.Errorf("expected invalidation of alice, got %v", got)
+		}
+	})
+
+	t.Run("key is canonicalized", func(t *testing.T) {
+		registry, source := newRegistry()
+		srv := NewAdminServer(AdminServerConfig{DataSourceRegistry: registry})
+
+		body := `{"data_source":"user-info","key":"{ \"subject\": { \"subject\": \"alice\" } }"}`
+		rec, resp := invalidate(srv, cacheInvalidatePath, body, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d %v", rec.Code, resp)
+		}
+
+		want, err := json.Marshal(&service.DataSourceInput{Subject: &trust.Result{Subject: "alice"}})
+		if err != nil {
+			t.Fatalf("failed to marshal key: %v", err)
+		}
+		got := source.received()
+		if len(got) != 1 || got[0].Key != string(want) {
+			t.Errorf("expected key %s, got %v", want, got)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		registry, _ := newRegistry()
+		srv := NewAdminServer(AdminServerConfig{DataSourceRegistry: registry})
+
+		tests := []struct {
+			name   string
+			body   string
+			status int
+			error  string
+		}{
+			{"unknown data source", `{"data_source":"missing"}`, http.StatusNotFound, "not_found"},
+			{"data source without cache", `{"data_source":"uncached"}`, http.StatusBadRequest, "invalid_request"},
+			{"missing data source", `{"subject":"alice"}`, http.StatusBadRequest, "invalid_request"},
+			{"malformed body", `{`, http.StatusBadRequest, "invalid_request"},
+			{"malformed key", `{"data_source":"user-info","key":"nope"}`, http.StatusBadRequest, "invalid_request"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rec, resp := invalidate(srv, cacheInvalidatePath, tt.body, "")
+				if rec.Code != tt.status || resp["error"] != tt.error {
+					t.Errorf("expected %d %s, got %d %v", tt.status, tt.error, rec.Code, resp)
+				}
+			})
+		}
+	})
+
+	t.Run("caller authentication", func(t *testing.T) {
+		registry, source := newRegistry()
+		validator := trust.NewStubValidator(trust.CredentialTypeBearer)
+		validator.WithResult(&trust.Result{Subject: "alice", TrustDomain: "users.example.com"})
+		store := trust.NewStubStore().AddValidator(validator)
+		srv := NewAdminServer(AdminServerConfig{
+			DataSourceRegistry: registry,
+			TrustStore:         store,
+			AllowedCallers:     []AllowedCaller{{TrustDomain: "ops.internal"}},
+		})
+
+		rec, resp := invalidate(srv, cacheInvalidatePath, `{"data_source":"user-info"}`, "")
+		if rec.Code != http.StatusUnauthorized || resp["error"] != "invalid_client" {
+			t.Errorf("expected invalid_client, got %d %v", rec.Code, resp)
+		}
+
+		rec, resp = invalidate(srv, cacheInvalidatePath, `{"data_source":"user-info"}`, "user-token")
+		if rec.Code != http.StatusForbidden || resp["error"] != "access_denied" {
+			t.Errorf("expected access_denied, got %d %v", rec.Code, resp)
+		}
+		if got := source.received(); len(got) != 0 {
+			t.Errorf("expected no invalidation, got %v", got)
+		}
+	})
+
+	t.Run("forwards to peers", func(t *testing.T) {
+		peerRegistry, peerSource := newRegistry()
+		peerAdmin := NewAdminServer(AdminServerConfig{DataSourceRegistry: peerRegistry})
+		var forwarded *http.Request
+		peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			forwarded = r
+			peerAdmin.ServeHTTP(w, r)
+		}))
+		defer peer.Close()
+
+		down := httptest.NewServer(http.NotFoundHandler())
+		down.Close()
+
+		registry, source := newRegistry()
+		srv := NewAdminServer(AdminServerConfig{
+			DataSourceRegistry: registry,
+			Peers: staticPeers{
+				self:  "http://self:8080",
+				peers: []string{"http://self:8080", peer.URL, down.URL},
+			},
+		})
+
+		rec, resp := invalidate(srv, cacheInvalidatePath, `{"data_source":"user-info","subject":"alice"}`, "operator-token")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d %v", rec.Code, resp)
+		}
+		if failed, _ := resp["failed_peers"].([]any); len(failed) != 1 || failed[0] != down.URL {
+			t.Errorf("expected failed peer %s, got %v", down.URL, resp["failed_peers"])
+		}
+
+		if got := source.received(); len(got) != 1 {
+			t.Errorf("expected local invalidation, got %v", got)
+		}
+		if got := peerSource.received(); len(got) != 1 || got[0].Subject != "alice" {
+			t.Errorf("expected peer invalidation of alice, got %v", got)
+		}
+		if forwarded == nil {
+			t.Fatal("expected a forwarded request")
+		}
+		if forwarded.URL.Query().Get("local") != "true" {
+			t.Errorf("expected forwarded request to be local, got %s", forwarded.URL)
+		}
+		if got := forwarded.Header.Get("Authorization"); got != "Bearer operator-token" {
+			t.Errorf("expected caller credential to be forwarded, got %q", got)
+		}
+	})
+
+	t.Run("local requests are not forwarded", func(t *testing.T) {
+		forwarded := false
+		peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			forwarded = true
+		}))
+		defer peer.Close()
+
+		registry, _ := newRegistry()
+		srv := NewAdminServer(AdminServerConfig{
+			DataSourceRegistry: registry,
+			Peers:              staticPeers{self: "http://self:8080", peers: []string{peer.URL}},
+		})
+
+		rec, resp := invalidate(srv, cacheInvalidatePath+"?local=true", `{"data_source":"user-info"}`, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d %v", rec.Code, resp)
+		}
+		if forwarded {
+			t.Error("expected a local request not to be forwarded")
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/ledger"
+)
+
+const (
+	// defaultTokenQueryLimit and maxTokenQueryLimit bound the number of
+	// ledger entries returned by one query
+	defaultTokenQueryLimit = 100
+	maxTokenQueryLimit     = 1000
+)
+
+// tokenPrincipal describes the subject or actor of a ledger entry
+type tokenPrincipal struct {
+	Subject     string `json:"subject"`
+	Issuer      string `json:"issuer,omitempty"`
+	TrustDomain string `json:"trust_domain,omitempty"`
+}
+
+// tokenEntry describes an issued token recorded in the ledger
+type tokenEntry struct {
+	Type          string          `json:"type"`
+	JTI           string          `json:"jti,omitempty"`
+	Subject       *tokenPrincipal `json:"subject,omitempty"`
+	Actor         *tokenPrincipal `json:"actor,omitempty"`
+	Audience      []string        `json:"aud,omitempty"`
+	Scope         string          `json:"scope,omitempty"`
+	IssuedAt      time.Time       `json:"issued_at,omitzero"`
+	ExpiresAt     time.Time       `json:"expires_at,omitzero"`
+	TransactionID string          `json:"txn,omitempty"`
+}
+
+// listTokensResponse lists the issued tokens matching a query, most recent
+// first
+type listTokensResponse struct {
+	Tokens []tokenEntry `json:"tokens"`
+}
+
+func newTokenPrincipal(p *ledger.Principal) *tokenPrincipal {
+	if p == nil {
+		return nil
+	}
+	return &tokenPrincipal{Subject: p.Subject, Issuer: p.Issuer, TrustDomain: p.TrustDomain}
+}
+
+func newTokenEntry(entry ledger.Entry) tokenEntry {
+	return tokenEntry{
+		Type:          entry.Type,
+		JTI:           entry.JTI,
+		Subject:       newTokenPrincipal(entry.Subject),
+		Actor:         newTokenPrincipal(entry.Actor),
+		Audience:      entry.Audience,
+		Scope:         entry.Scope,
+		IssuedAt:      entry.IssuedAt,
+		ExpiresAt:     entry.ExpiresAt,
+		TransactionID: entry.TransactionID,
+	}
+}
+
+// listTokens queries the token ledger by the subject, actor and jti query
+// parameters. since is a duration ("1h") or an RFC 3339 time, and limit caps
+// the number of tokens returned.
+func (s *AdminServer) listTokens(w http.ResponseWriter, r *http.Request) {
+	if s.ledger == nil {
+		writeOAuthError(w, http.StatusNotFound, "not_found", "token ledger is not enabled")
+		return
+	}
+
+	params := r.URL.Query()
+	q := ledger.Query{
+		Subject: params.Get("subject"),
+		Actor:   params.Get("actor"),
+		JTI:     params.Get("jti"),
+		Limit:   defaultTokenQueryLimit,
+	}
+	if since := params.Get("since"); since != "" {
+		if d, err := time.ParseDuration(since); err == nil && d > 0 {
+			q.Since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+			q.Since = t
+		} else {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_request", "since must be a positive duration or an RFC 3339 time")
+			return
+		}
+	}
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 || n > maxTokenQueryLimit {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_request",
+				"limit must be between 1 and "+strconv.Itoa(maxTokenQueryLimit))
+			return
+		}
+		q.Limit = n
+	}
+
+	resp := listTokensResponse{Tokens: []tokenEntry{}}
+	for _, entry := range s.ledger.Find(q) {
+		resp.Tokens = append(resp.Tokens, newTokenEntry(entry))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/ledger"
+)
+
+func TestAdminServer_Tokens(t *testing.T) {
+	l, err := ledger.New(ledger.Config{})
+	if err != nil {
+		t.Fatalf("ledger.New failed: %v", err)
+	}
+	now := time.Now().UTC()
+	gateway := &ledger.Principal{Subject: "spiffe://example.com/gateway", TrustDomain: "example.com"}
+	l.Record(ledger.Entry{Type: "access_token", JTI: "old", Subject: &ledger.Principal{Subject: "alice"}, Actor: gateway, IssuedAt: now.Add(-2 * time.Hour)})
+	l.Record(ledger.Entry{Type: "access_token", JTI: "a", Subject: &ledger.Principal{Subject: "alice"}, Actor: gateway, IssuedAt: now, Audience: []string{"orders"}})
+	l.Record(ledger.Entry{Type: "access_token", JTI: "b", Subject: &ledger.Principal{Subject: "bob"}, IssuedAt: now})
+
+	srv := NewAdminServer(AdminServerConfig{Ledger: l})
+	get := func(target string) (int, map[string]any) {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid JSON response %q: %v", rec.Body.String(), err)
+		}
+		return rec.Code, resp
+	}
+	jtis := func(resp map[string]any) []string {
+		var out []string
+		for _, token := range resp["tokens"].([]any) {
+			out = append(out, token.(map[string]any)["jti"].(string))
+		}
+		return out
+	}
+
+	code, resp := get("/v1/admin/tokens?actor=spiffe://example.com/gateway&since=1h")
+	if got := jtis(resp); code != http.StatusOK || len(got) != 1 || got[0] != "a" {
+		t.Fatalf("expected the actor's token of the last hour, got %d: %v", code, resp)
+	}
+	token := resp["tokens"].([]any)[0].(map[string]any)
+	if token["subject"].(map[string]any)["subject"] != "alice" || token["aud"].([]any)[0] != "orders" {
+		t.Errorf("unexpected token %v", token)
+	}
+
+	if _, resp := get("/v1/admin/tokens?subject=alice"); len(jtis(resp)) != 2 {
+		t.Errorf("expected both of alice's tokens, got %v", resp)
+	}
+	if _, resp := get("/v1/admin/tokens?jti=b"); len(jtis(resp)) != 1 {
+		t.Errorf("expected the token by jti, got %v", resp)
+	}
+	if _, resp := get("/v1/admin/tokens?limit=1"); len(jtis(resp)) != 1 || jtis(resp)[0] != "b" {
+		t.Errorf("expected the most recent token, got %v", resp)
+	}
+	if _, resp := get("/v1/admin/tokens?since=" + now.Add(-time.Minute).Format(time.RFC3339)); len(jtis(resp)) != 2 {
+		t.Errorf("expected the tokens since the time, got %v", resp)
+	}
+
+	for _, query := range []string{"since=yesterday", "since=-1h", "limit=0", "limit=5000"} {
+		if code, resp := get("/v1/admin/tokens?" + query); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %v", query, code, resp)
+		}
+	}
+
+	srv = NewAdminServer(AdminServerConfig{})
+	if code, resp := get("/v1/admin/tokens"); code != http.StatusNotFound {
+		t.Errorf("expected 404 without a ledger, got %d: %v", code, resp)
+	}
+}
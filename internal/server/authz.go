@@ -2,14 +2,19 @@ package server
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
-	"strings"
+	"maps"
 
 	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/structpb"
 
+	"github.com/project-kessel/parsec/internal/perr"
 	"github.com/project-kessel/parsec/internal/request"
 	"github.com/project-kessel/parsec/internal/service"
 	"github.com/project-kessel/parsec/internal/trust"
@@ -29,17 +34,158 @@ type TokenTypeSpec struct {
 type AuthzServer struct {
 	authv3.UnimplementedAuthorizationServer
 
-	trustStore   trust.Store
-	tokenService *service.TokenService
-	observer     service.AuthzCheckObserver
+	trustStore         trust.Store
+	tokenService       *service.TokenService
+	observer           service.AuthzCheckObserver
+	actorCredentials   ActorCredentialExtractor
+	subjectCredentials SubjectCredentialExtractor
 
 	// TokenTypesToIssue specifies which token types to issue and their headers
 	// This could come from configuration in the future
 	TokenTypesToIssue []TokenTypeSpec
+
+	headerSizeLimit *HeaderSizeLimit
+	responseHeaders []ResponseHeaderSpec
+	denyCache       *DenyCache
+	skipPaths       []SkipPath
+	ipPolicy        *IPPolicy
+	bodyHash        *BodyHash
+
+	attributeSources []RequestAttributeSource
+	dynamicMetadata  *DynamicMetadata
+	clientPolicy     *ClientPolicy
+}
+
+// ResponseHeaderSpec specifies a header emitted alongside the tokens with a
+// value derived by a claim mapper, e.g. an x-rh-identity or x-request-id for
+// sidecars that need more than one identity format
+type ResponseHeaderSpec struct {
+	// HeaderName is the HTTP header to emit
+	HeaderName string
+
+	// Mapper derives the value from the subject, actor and request, like the
+	// claim mappers of an issuer
+	Mapper service.ClaimMapper
+
+	// Claim is the mapped claim holding the value; strings are emitted as is
+	// and other values as JSON. If empty, all mapped claims are emitted as a
+	// JSON object. The header is omitted if the value is missing.
+	Claim string
+
+	// Base64 encodes the value with standard base64, as x-rh-identity expects
+	Base64 bool
+}
+
+// HeaderSizeLimit bounds the size of the tokens emitted in response headers,
+// which Envoy or downstream services would otherwise reject with an opaque
+// 431 Request Header Fields Too Large
+type HeaderSizeLimit struct {
+	// MaxTokenSize is the largest token value emitted in a header, in bytes
+	MaxTokenSize int
+
+	// ReferenceTokenType is issued in place of tokens over MaxTokenSize, e.g. an
+	// opaque reference token resolved by introspection. If empty, checks with
+	// tokens over MaxTokenSize are denied.
+	ReferenceTokenType service.TokenType
+}
+
+// AuthzServerOption is a functional option for configuring an AuthzServer
+type AuthzServerOption func(*AuthzServer)
+
+// WithAuthzActorCredentialExtractor sets where the calling actor's credential
+// is read from. Defaults to DefaultActorCredentialExtractor.
+func WithAuthzActorCredentialExtractor(extractor ActorCredentialExtractor) AuthzServerOption {
+	return func(s *AuthzServer) {
+		if extractor != nil {
+			s.actorCredentials = extractor
+		}
+	}
+}
+
+// WithSubjectCredentialExtractor sets where the subject's credential is read
+// from in the checked request. Defaults to DefaultSubjectCredentialExtractor.
+func WithSubjectCredentialExtractor(extractor SubjectCredentialExtractor) AuthzServerOption {
+	return func(s *AuthzServer) {
+		if extractor != nil {
+			s.subjectCredentials = extractor
+		}
+	}
+}
+
+// WithHeaderSizeLimit bounds the size of the tokens emitted in response headers
+func WithHeaderSizeLimit(limit *HeaderSizeLimit) AuthzServerOption {
+	return func(s *AuthzServer) {
+		s.headerSizeLimit = limit
+	}
+}
+
+// WithResponseHeaders emits headers derived by claim mappers alongside the tokens
+func WithResponseHeaders(specs ...ResponseHeaderSpec) AuthzServerOption {
+	return func(s *AuthzServer) {
+		s.responseHeaders = append(s.responseHeaders, specs...)
+	}
+}
+
+// WithDenyCache caches subject credentials that failed validation, so they are
+// denied again without being validated again until the entry expires
+func WithDenyCache(cache *DenyCache) AuthzServerOption {
+	return func(s *AuthzServer) {
+		s.denyCache = cache
+	}
+}
+
+// WithSkipPaths allows requests to the matching paths without validation or
+// token issuance, before any credential is extracted
+func WithSkipPaths(paths ...SkipPath) AuthzServerOption {
+	return func(s *AuthzServer) {
+		s.skipPaths = append(s.skipPaths, paths...)
+	}
+}
+
+// WithIPPolicy allows or denies checks by the client address
+// The client address is then derived from x-forwarded-for entries added by
+// the policy's trusted proxies, and recorded in the request attributes.
+func WithIPPolicy(policy *IPPolicy) AuthzServerOption {
+	return func(s *AuthzServer) {
+		s.ipPolicy = policy
+	}
+}
+
+// WithBodyHash records the digest of forwarded request bodies in the request
+// attributes
+func WithBodyHash(hash *BodyHash) AuthzServerOption {
+	return func(s *AuthzServer) {
+		s.bodyHash = hash
+	}
+}
+
+// WithRequestAttributeSources records attributes derived by extractors in the
+// request attributes, after the built-in ones
+func WithRequestAttributeSources(sources ...RequestAttributeSource) AuthzServerOption {
+	return func(s *AuthzServer) {
+		s.attributeSources = append(s.attributeSources, sources...)
+	}
+}
+
+// WithDynamicMetadata sets identity attributes in the dynamic metadata of
+// allowed checks
+func WithDynamicMetadata(metadata *DynamicMetadata) AuthzServerOption {
+	return func(s *AuthzServer) {
+		s.dynamicMetadata = metadata
+	}
+}
+
+// WithAuthzClientPolicy applies the policies of registered clients to the
+// checks of their actors: only the token types a client may be issued are
+// issued, and count against its quota
+func WithAuthzClientPolicy(policy *ClientPolicy) AuthzServerOption {
+	return func(s *AuthzServer) {
+		s.clientPolicy = policy
+	}
 }
 
 // NewAuthzServer creates a new ext_authz server
-func NewAuthzServer(trustStore trust.Store, tokenService *service.TokenService, tokenTypes []TokenTypeSpec, observer service.AuthzCheckObserver) *AuthzServer {
+func NewAuthzServer(trustStore trust.Store, tokenService *service.TokenService, tokenTypes []TokenTypeSpec, observer service.AuthzCheckObserver, opts ...AuthzServerOption) *AuthzServer {
 	// Default to transaction tokens if none specified
 	if len(tokenTypes) == 0 {
 		tokenTypes = []TokenTypeSpec{
@@ -55,12 +201,18 @@ func NewAuthzServer(trustStore trust.Store, tokenService *service.TokenService,
 		observer = service.NoOpAuthzCheckObserver()
 	}
 
-	return &AuthzServer{
-		trustStore:        trustStore,
-		tokenService:      tokenService,
-		TokenTypesToIssue: tokenTypes,
-		observer:          observer,
+	s := &AuthzServer{
+		trustStore:         trustStore,
+		tokenService:       tokenService,
+		TokenTypesToIssue:  tokenTypes,
+		observer:           observer,
+		actorCredentials:   DefaultActorCredentialExtractor(),
+		subjectCredentials: DefaultSubjectCredentialExtractor(),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 // Check implements the ext_authz check endpoint
@@ -71,13 +223,39 @@ func (s *AuthzServer) Check(ctx context.Context, req *authv3.CheckRequest) (*aut
 
 	// 1. Build request attributes
 	reqAttrs := s.buildRequestAttributes(req)
+	if s.bodyHash != nil {
+		if err := s.bodyHash.apply(req.GetAttributes().GetRequest().GetHttp(), reqAttrs); err != nil {
+			probe.RequestAttributeExtractionFailed(err)
+			return s.denyResponse(err), nil
+		}
+	}
+	if err := applyRequestAttributeSources(req, reqAttrs, s.attributeSources); err != nil {
+		probe.RequestAttributeExtractionFailed(err)
+		return s.denyResponse(perr.Errorf(perr.ErrCodeInvalidRequest, "failed to extract request attributes: %w", err)), nil
+	}
 	probe.RequestAttributesParsed(reqAttrs)
 
+	// Clients from networks the IP policy rejects are denied before anything else
+	if s.ipPolicy != nil {
+		if err := s.ipPolicy.Evaluate(reqAttrs.IPAddress); err != nil {
+			probe.ClientAddressDenied(reqAttrs.IPAddress, err)
+			return s.denyResponse(err), nil
+		}
+	}
+
+	// Skipped paths are allowed as they are, like health checks and public assets
+	if s.skipped(reqAttrs.Path) {
+		probe.CheckSkipped(reqAttrs.Path)
+		return &authv3.CheckResponse{
+			Status:       &status.Status{Code: int32(codes.OK)},
+			HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: &authv3.OkHttpResponse{}},
+		}, nil
+	}
+
 	// 2. Extract actor credential from gRPC context
-	actorCred, err := extractActorCredential(ctx)
+	actorCred, err := s.actorCredentials.ExtractActorCredential(ctx)
 	if err != nil {
-		return s.denyResponse(codes.Internal,
-			fmt.Sprintf("failed to extract actor credential: %v", err)), nil
+		return s.denyResponse(perr.Errorf(perr.ErrCodeInvalidActor, "failed to extract actor credential: %w", err)), nil
 	}
 
 	var actor *trust.Result
@@ -86,8 +264,7 @@ func (s *AuthzServer) Check(ctx context.Context, req *authv3.CheckRequest) (*aut
 		actor, validationErr = s.trustStore.Validate(ctx, actorCred)
 		if validationErr != nil {
 			probe.ActorValidationFailed(validationErr)
-			return s.denyResponse(codes.Unauthenticated,
-				fmt.Sprintf("actor validation failed: %v", validationErr)), nil
+			return s.denyResponse(perr.Errorf(perr.ErrCodeInvalidActor, "actor validation failed: %w", validationErr)), nil
 		}
 		probe.ActorValidationSucceeded(actor)
 	} else {
@@ -95,35 +272,51 @@ func (s *AuthzServer) Check(ctx context.Context, req *authv3.CheckRequest) (*aut
 		probe.ActorValidationSucceeded(actor)
 	}
 
+	// The actor's registered client, if any, has its own policies
+	client, err := s.clientPolicy.client(ctx, actor)
+	if err != nil {
+		return s.denyResponse(err), nil
+	}
+
 	// 3. Filter trust store based on actor permissions
 	filteredStore, err := s.trustStore.ForActor(ctx, actor, reqAttrs)
 	if err != nil {
-		return s.denyResponse(codes.PermissionDenied,
-			fmt.Sprintf("failed to filter trust store: %v", err)), nil
+		return s.denyResponse(perr.Errorf(perr.ErrCodeActorDenied, "failed to filter trust store: %w", err)), nil
 	}
 
 	// 4. Extract subject credentials from request
-	// The extraction layer returns both the credential and which headers were used
-	cred, headersUsed, err := s.extractCredential(req)
+	// The extraction layer returns both the credential and where it was found
+	extracted, err := s.extractCredential(req)
 	if err != nil {
 		probe.SubjectCredentialExtractionFailed(err)
-		return s.denyResponse(codes.Unauthenticated, fmt.Sprintf("failed to extract credentials: %v", err)), nil
+		return s.denyResponse(perr.Errorf(perr.ErrCodeMissingCredential, "failed to extract credentials: %w", err)), nil
 	}
-	probe.SubjectCredentialExtracted(cred, headersUsed)
+	cred := extracted.Credential
+	probe.SubjectCredentialExtracted(cred, extracted.HeadersUsed)
 
 	// 5. Validate subject credentials against filtered trust store
 	// The filtered store only includes validators the actor is allowed to use
-	result, err := filteredStore.Validate(ctx, cred)
+	result, err := s.validateSubject(ctx, filteredStore, cred, actor, reqAttrs)
 	if err != nil {
 		probe.SubjectValidationFailed(err)
-		return s.denyResponse(codes.Unauthenticated, fmt.Sprintf("validation failed: %v", err)), nil
+		return s.denyResponse(perr.Errorf(perr.ErrCodeInvalidSubjectToken, "validation failed: %w", err)), nil
 	}
 	probe.SubjectValidationSucceeded(result)
 
 	// 6. Issue tokens via TokenService
-	tokenTypes := make([]service.TokenType, len(s.TokenTypesToIssue))
-	for i, spec := range s.TokenTypesToIssue {
-		tokenTypes[i] = spec.Type
+	// A registered client is only issued the token types it is allowed
+	tokenTypes := make([]service.TokenType, 0, len(s.TokenTypesToIssue))
+	for _, spec := range s.TokenTypesToIssue {
+		if client == nil || client.AllowsTokenType(spec.Type) {
+			tokenTypes = append(tokenTypes, spec.Type)
+		}
+	}
+	if len(tokenTypes) == 0 {
+		return s.denyResponse(perr.Errorf(perr.ErrCodeActorDenied, "client %s may not be issued any of the configured token types", client.ID)), nil
+	}
+	if err := s.clientPolicy.admit(ctx, client, len(tokenTypes)); err != nil {
+		probe.TokenIssuanceFailed(err)
+		return s.denyResponse(err), nil
 	}
 
 	issuedTokens, err := s.tokenService.IssueTokens(ctx, &service.IssueRequest{
@@ -135,11 +328,34 @@ func (s *AuthzServer) Check(ctx context.Context, req *authv3.CheckRequest) (*aut
 		Scope: "",
 	})
 	if err != nil {
-		return s.denyResponse(codes.Internal, fmt.Sprintf("failed to issue tokens: %v", err)), nil
+		probe.TokenIssuanceFailed(err)
+		return s.denyResponse(fmt.Errorf("failed to issue tokens: %w", err)), nil
+	}
+	probe.TokensIssued(tokenTypes)
+
+	// Metadata is built before oversized tokens are replaced, which would drop their txn
+	var dynamicMetadata *structpb.Struct
+	if s.dynamicMetadata != nil {
+		dynamicMetadata, err = s.dynamicMetadata.build(result, actor, issuedTokens)
+		if err != nil {
+			probe.TokenIssuanceFailed(err)
+			return s.denyResponse(err), nil
+		}
+	}
+
+	issuedTokens, err = s.enforceHeaderSizeLimit(ctx, probe, issuedTokens, &service.IssueRequest{
+		Subject:           result,
+		Actor:             actor,
+		RequestAttributes: reqAttrs,
+	})
+	if err != nil {
+		probe.TokenIssuanceFailed(err)
+		return s.denyResponse(err), nil
 	}
 
 	// 7. Build response headers from issued tokens
 	responseHeaders := make([]*corev3.HeaderValueOption, 0, len(issuedTokens))
+	headerNames := make([]string, 0, len(issuedTokens))
 	for _, spec := range s.TokenTypesToIssue {
 		if token, ok := issuedTokens[spec.Type]; ok {
 			responseHeaders = append(responseHeaders, &corev3.HeaderValueOption{
@@ -148,60 +364,189 @@ func (s *AuthzServer) Check(ctx context.Context, req *authv3.CheckRequest) (*aut
 					Value: token.Value,
 				},
 			})
+			headerNames = append(headerNames, spec.HeaderName)
 		}
 	}
 
+	// Derived headers are computed for the same request as the tokens
+	for _, spec := range s.responseHeaders {
+		value, ok, err := s.deriveHeader(ctx, spec, &service.IssueRequest{
+			Subject:           result,
+			Actor:             actor,
+			RequestAttributes: reqAttrs,
+		})
+		if err != nil {
+			probe.TokenIssuanceFailed(err)
+			return s.denyResponse(err), nil
+		}
+		if !ok {
+			continue
+		}
+		responseHeaders = append(responseHeaders, &corev3.HeaderValueOption{
+			Header: &corev3.HeaderValue{
+				Key:   spec.HeaderName,
+				Value: value,
+			},
+		})
+		headerNames = append(headerNames, spec.HeaderName)
+	}
+	probe.ResponseHeadersEmitted(headerNames, extracted.HeadersUsed)
+
 	// 8. Return OK with issued tokens in headers
-	// Remove the external credential headers so they don't leak to backend
+	// Remove the external credential headers and query parameters so they don't leak to backend
 	// This creates a security boundary - external credentials stay outside
 	return &authv3.CheckResponse{
 		Status: &status.Status{
 			Code: int32(codes.OK),
 		},
+		DynamicMetadata: dynamicMetadata,
 		HttpResponse: &authv3.CheckResponse_OkResponse{
 			OkResponse: &authv3.OkHttpResponse{
 				Headers: responseHeaders,
 				// Remove external credential headers - security boundary
-				HeadersToRemove: headersUsed,
+				HeadersToRemove:         extracted.HeadersUsed,
+				QueryParametersToRemove: extracted.QueryParametersUsed,
 			},
 		},
 	}, nil
 }
 
-// extractCredential extracts credentials from the Envoy request
-// Returns the credential and the list of headers that were used to extract it
-func (s *AuthzServer) extractCredential(req *authv3.CheckRequest) (trust.Credential, []string, error) {
-	httpReq := req.GetAttributes().GetRequest().GetHttp()
-	// TODO: mtls e.g. cert := req.GetAttributes().GetSource().GetCertificate()
+// enforceHeaderSizeLimit replaces the issued tokens over the header size limit
+// with a reference token, or fails with ErrCodeTokenTooLarge if none is
+// configured. Returns issuedTokens unchanged without a limit.
+func (s *AuthzServer) enforceHeaderSizeLimit(
+	ctx context.Context,
+	probe service.AuthzCheckProbe,
+	issuedTokens map[service.TokenType]*service.Token,
+	req *service.IssueRequest,
+) (map[service.TokenType]*service.Token, error) {
+	limit := s.headerSizeLimit
+	if limit == nil || limit.MaxTokenSize <= 0 {
+		return issuedTokens, nil
+	}
 
-	if httpReq == nil {
-		return nil, nil, fmt.Errorf("no HTTP request attributes")
+	var oversized []TokenTypeSpec
+	for _, spec := range s.TokenTypesToIssue {
+		if token, ok := issuedTokens[spec.Type]; ok && len(token.Value) > limit.MaxTokenSize {
+			probe.ResponseHeaderTooLarge(spec.HeaderName, len(token.Value), limit.MaxTokenSize)
+			oversized = append(oversized, spec)
+		}
+	}
+	if len(oversized) == 0 {
+		return issuedTokens, nil
 	}
 
-	// Look for Authorization header
-	authHeader := httpReq.GetHeaders()["authorization"]
-	if authHeader == "" {
-		return nil, nil, fmt.Errorf("no authorization header")
+	spec := oversized[0]
+	if limit.ReferenceTokenType == "" {
+		return nil, perr.Errorf(perr.ErrCodeTokenTooLarge, "%s token for header %s is %d bytes, over the limit of %d bytes",
+			spec.Type, spec.HeaderName, len(issuedTokens[spec.Type].Value), limit.MaxTokenSize)
 	}
 
-	// Extract bearer token
-	if token, ok := strings.CutPrefix(authHeader, "Bearer "); ok {
-		// For bearer tokens, the trust store determines which validator to use
-		// based on its configuration (e.g., default validator, token introspection)
-		cred := &trust.BearerCredential{
-			Token: token,
+	// One reference token stands in for every oversized token
+	req.TokenTypes = []service.TokenType{limit.ReferenceTokenType}
+	references, err := s.tokenService.IssueTokens(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue reference token in place of oversized %s token: %w", spec.Type, err)
+	}
+	reference, ok := references[limit.ReferenceTokenType]
+	if !ok {
+		return nil, perr.Errorf(perr.ErrCodeIssuerUnavailable, "no %s reference token issued", limit.ReferenceTokenType)
+	}
+	if len(reference.Value) > limit.MaxTokenSize {
+		return nil, perr.Errorf(perr.ErrCodeTokenTooLarge, "%s reference token is %d bytes, over the limit of %d bytes",
+			limit.ReferenceTokenType, len(reference.Value), limit.MaxTokenSize)
+	}
+	probe.TokensIssued(req.TokenTypes)
+
+	replaced := maps.Clone(issuedTokens)
+	for _, spec := range oversized {
+		replaced[spec.Type] = reference
+	}
+	return replaced, nil
+}
+
+// skipped reports whether the request path is on the skip list
+func (s *AuthzServer) skipped(path string) bool {
+	if path == "" {
+		return false
+	}
+	for _, skip := range s.skipPaths {
+		if skip.Matches(path) {
+			return true
 		}
-		// Return the credential and the headers that were used
-		headersUsed := []string{"authorization"}
-		return cred, headersUsed, nil
 	}
+	return false
+}
 
-	// Future: Handle other authentication schemes
-	// - Basic auth: would use "authorization" header
-	// - API key in custom header: would track that header name
-	// - Cookie-based auth: would track cookie names
+// validateSubject validates the subject credential, denying credentials that
+// recently failed validation from the deny cache
+func (s *AuthzServer) validateSubject(ctx context.Context, store trust.Store, cred trust.Credential, actor *trust.Result, reqAttrs *request.RequestAttributes) (*trust.Result, error) {
+	if s.denyCache == nil {
+		return store.Validate(ctx, cred)
+	}
+	if err := s.denyCache.Get(cred, actor, reqAttrs); err != nil {
+		return nil, err
+	}
+	result, err := store.Validate(ctx, cred)
+	if err != nil {
+		s.denyCache.Put(cred, actor, reqAttrs, err)
+	}
+	return result, err
+}
 
-	return nil, nil, fmt.Errorf("unsupported authorization scheme")
+// deriveHeader computes the value of a derived header
+// Reports false if the mapper produced no value.
+func (s *AuthzServer) deriveHeader(ctx context.Context, spec ResponseHeaderSpec, req *service.IssueRequest) (string, bool, error) {
+	mapped, err := s.tokenService.MapClaims(ctx, req, []service.ClaimMapper{spec.Mapper})
+	if err != nil {
+		return "", false, fmt.Errorf("failed to derive header %s: %w", spec.HeaderName, err)
+	}
+
+	var value any = map[string]any(mapped)
+	if spec.Claim != "" {
+		value = mapped[spec.Claim]
+	}
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		text = v
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to encode header %s: %w", spec.HeaderName, err)
+		}
+		text = string(data)
+	}
+	if text == "" {
+		return "", false, nil
+	}
+
+	if spec.Base64 {
+		text = base64.StdEncoding.EncodeToString([]byte(text))
+	}
+	return text, true, nil
+}
+
+// extractCredential extracts the subject credential from the Envoy request
+// Returns the credential and the headers and query parameters it was read from
+func (s *AuthzServer) extractCredential(req *authv3.CheckRequest) (*SubjectCredential, error) {
+	httpReq := req.GetAttributes().GetRequest().GetHttp()
+	// TODO: mtls e.g. cert := req.GetAttributes().GetSource().GetCertificate()
+
+	if httpReq == nil {
+		return nil, fmt.Errorf("no HTTP request attributes")
+	}
+
+	extracted, err := s.subjectCredentials.ExtractSubjectCredential(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if extracted == nil {
+		return nil, fmt.Errorf("no subject credential found")
+	}
+	return extracted, nil
 }
 
 // buildRequestAttributes extracts request attributes from the Envoy request
@@ -222,26 +567,89 @@ func (s *AuthzServer) buildRequestAttributes(req *authv3.CheckRequest) *request.
 		additional["context_extensions"] = contextExtensions
 	}
 
+	// With an IP policy, the client may be behind proxies in front of Envoy
+	ipAddress := req.GetAttributes().GetSource().GetAddress().GetSocketAddress().GetAddress()
+	if s.ipPolicy != nil {
+		var forwardedFor []string
+		if value := httpReq.GetHeaders()["x-forwarded-for"]; value != "" {
+			forwardedFor = []string{value}
+		}
+		ipAddress = clientAddress(ipAddress, forwardedFor, s.ipPolicy.TrustedProxies)
+	}
+
 	return &request.RequestAttributes{
 		Method:     httpReq.GetMethod(),
 		Path:       httpReq.GetPath(),
-		IPAddress:  req.GetAttributes().GetSource().GetAddress().GetSocketAddress().GetAddress(),
+		Query:      envoyQuery(httpReq),
+		Scheme:     httpReq.GetScheme(),
+		Port:       int(req.GetAttributes().GetDestination().GetAddress().GetSocketAddress().GetPortValue()),
+		TLS:        envoyTLS(req.GetAttributes(), httpReq.GetHeaders()),
+		IPAddress:  ipAddress,
 		UserAgent:  httpReq.GetHeaders()["user-agent"],
 		Headers:    httpReq.GetHeaders(),
 		Additional: additional,
 	}
 }
 
+// TLS headers carry the downstream TLS version and cipher suite, which Envoy
+// does not include in ext_authz attributes. A header_mutation filter ahead of
+// ext_authz sets them from %DOWNSTREAM_TLS_VERSION% and %DOWNSTREAM_TLS_CIPHER%,
+// overwriting any the client sent.
+const (
+	tlsVersionHeader = "x-forwarded-tls-version"
+	tlsCipherHeader  = "x-forwarded-tls-cipher"
+)
+
+// envoyQuery returns the query parameters of the request
+// Envoy sends the query as part of the path, but may set it separately.
+func envoyQuery(httpReq *authv3.AttributeContext_HttpRequest) map[string][]string {
+	if query := httpReq.GetQuery(); query != "" {
+		return request.ParseQuery("?" + query)
+	}
+	return request.ParseQuery(httpReq.GetPath())
+}
+
+// envoyTLS returns the downstream TLS session, or nil if the request was not
+// received over TLS
+func envoyTLS(attrs *authv3.AttributeContext, headers map[string]string) *request.TLSInfo {
+	info := request.TLSInfo{
+		SNI:         attrs.GetTlsSession().GetSni(),
+		Version:     headers[tlsVersionHeader],
+		CipherSuite: headers[tlsCipherHeader],
+	}
+	// Envoy formats the version and cipher as "-" without TLS
+	if info.Version == "-" {
+		info.Version = ""
+	}
+	if info.CipherSuite == "-" {
+		info.CipherSuite = ""
+	}
+	if attrs.GetTlsSession() == nil && info.Version == "" && info.CipherSuite == "" {
+		return nil
+	}
+	return &info
+}
+
 // denyResponse creates a denial response
-func (s *AuthzServer) denyResponse(code codes.Code, message string) *authv3.CheckResponse {
+// The gRPC and HTTP statuses follow the error's code (see perr).
+func (s *AuthzServer) denyResponse(err error) *authv3.CheckResponse {
+	code := perr.CodeOf(err)
+	var headers []*corev3.HeaderValueOption
+	if retryAfter := perr.RetryAfterOf(err); retryAfter > 0 {
+		headers = append(headers, &corev3.HeaderValueOption{
+			Header: &corev3.HeaderValue{Key: "retry-after", Value: retryAfterSeconds(retryAfter)},
+		})
+	}
 	return &authv3.CheckResponse{
 		Status: &status.Status{
-			Code:    int32(code),
-			Message: message,
+			Code:    int32(code.GRPCCode()),
+			Message: err.Error(),
 		},
 		HttpResponse: &authv3.CheckResponse_DeniedResponse{
 			DeniedResponse: &authv3.DeniedHttpResponse{
-				Body: message,
+				Status:  &typev3.HttpStatus{Code: typev3.StatusCode(code.HTTPStatus())},
+				Headers: headers,
+				Body:    err.Error(),
 			},
 		},
 	}
@@ -2,15 +2,23 @@ package server
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
-	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
-	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/envoytest"
 	"github.com/project-kessel/parsec/internal/issuer"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/request"
 	"github.com/project-kessel/parsec/internal/service"
 	"github.com/project-kessel/parsec/internal/trust"
 )
@@ -45,28 +53,11 @@ func TestAuthzServer_Check(t *testing.T) {
 	authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
 
 	t.Run("successful authorization", func(t *testing.T) {
-		req := &authv3.CheckRequest{
-			Attributes: &authv3.AttributeContext{
-				Request: &authv3.AttributeContext_Request{
-					Http: &authv3.AttributeContext_HttpRequest{
-						Method: "GET",
-						Path:   "/api/resource",
-						Headers: map[string]string{
-							"authorization": "Bearer test-token-123",
-						},
-					},
-				},
-				Source: &authv3.AttributeContext_Peer{
-					Address: &corev3.Address{
-						Address: &corev3.Address_SocketAddress{
-							SocketAddress: &corev3.SocketAddress{
-								Address: "192.168.1.1",
-							},
-						},
-					},
-				},
-			},
-		}
+		req := envoytest.NewCheckRequest().
+			Path("/api/resource").
+			Bearer("test-token-123").
+			SourceIP("192.168.1.1").
+			Build()
 
 		resp, err := authzServer.Check(ctx, req)
 		if err != nil {
@@ -115,17 +106,9 @@ func TestAuthzServer_Check(t *testing.T) {
 	})
 
 	t.Run("missing authorization header", func(t *testing.T) {
-		req := &authv3.CheckRequest{
-			Attributes: &authv3.AttributeContext{
-				Request: &authv3.AttributeContext_Request{
-					Http: &authv3.AttributeContext_HttpRequest{
-						Method:  "GET",
-						Path:    "/api/resource",
-						Headers: map[string]string{},
-					},
-				},
-			},
-		}
+		req := envoytest.NewCheckRequest().
+			Path("/api/resource").
+			Build()
 
 		resp, err := authzServer.Check(ctx, req)
 		if err != nil {
@@ -147,19 +130,10 @@ func TestAuthzServer_Check(t *testing.T) {
 		// Configure validator to reject
 		stubValidator.WithError(trust.ErrInvalidToken)
 
-		req := &authv3.CheckRequest{
-			Attributes: &authv3.AttributeContext{
-				Request: &authv3.AttributeContext_Request{
-					Http: &authv3.AttributeContext_HttpRequest{
-						Method: "GET",
-						Path:   "/api/resource",
-						Headers: map[string]string{
-							"authorization": "Bearer invalid-token",
-						},
-					},
-				},
-			},
-		}
+		req := envoytest.NewCheckRequest().
+			Path("/api/resource").
+			Bearer("invalid-token").
+			Build()
 
 		resp, err := authzServer.Check(ctx, req)
 		if err != nil {
@@ -176,36 +150,16 @@ func TestAuthzServer_Check(t *testing.T) {
 	})
 
 	t.Run("successful authorization with context extensions", func(t *testing.T) {
-		req := &authv3.CheckRequest{
-			Attributes: &authv3.AttributeContext{
-				Request: &authv3.AttributeContext_Request{
-					Http: &authv3.AttributeContext_HttpRequest{
-						Method: "GET",
-						Path:   "/api/resource",
-						Host:   "api.example.com",
-						Headers: map[string]string{
-							"authorization": "Bearer test-token-123",
-						},
-					},
-				},
-				Source: &authv3.AttributeContext_Peer{
-					Address: &corev3.Address{
-						Address: &corev3.Address_SocketAddress{
-							SocketAddress: &corev3.SocketAddress{
-								Address: "192.168.1.1",
-							},
-						},
-					},
-				},
-				// Envoy context extensions
-				ContextExtensions: map[string]string{
-					"env":       "production",
-					"region":    "us-west-2",
-					"namespace": "default",
-					"cluster":   "prod-cluster-1",
-				},
-			},
-		}
+		req := envoytest.NewCheckRequest().
+			Path("/api/resource").
+			Host("api.example.com").
+			Bearer("test-token-123").
+			SourceIP("192.168.1.1").
+			ContextExtension("env", "production").
+			ContextExtension("region", "us-west-2").
+			ContextExtension("namespace", "default").
+			ContextExtension("cluster", "prod-cluster-1").
+			Build()
 
 		resp, err := authzServer.Check(ctx, req)
 		if err != nil {
@@ -238,34 +192,16 @@ func TestAuthzServer_Check(t *testing.T) {
 	})
 
 	t.Run("buildRequestAttributes extracts context extensions", func(t *testing.T) {
-		req := &authv3.CheckRequest{
-			Attributes: &authv3.AttributeContext{
-				Request: &authv3.AttributeContext_Request{
-					Http: &authv3.AttributeContext_HttpRequest{
-						Method: "POST",
-						Path:   "/api/users",
-						Host:   "api.example.com",
-						Headers: map[string]string{
-							"content-type": "application/json",
-						},
-					},
-				},
-				Source: &authv3.AttributeContext_Peer{
-					Address: &corev3.Address{
-						Address: &corev3.Address_SocketAddress{
-							SocketAddress: &corev3.SocketAddress{
-								Address: "10.0.1.5",
-							},
-						},
-					},
-				},
-				ContextExtensions: map[string]string{
-					"env":       "staging",
-					"tenant_id": "tenant-123",
-					"app":       "myapp",
-				},
-			},
-		}
+		req := envoytest.NewCheckRequest().
+			Method("POST").
+			Path("/api/users").
+			Host("api.example.com").
+			Header("content-type", "application/json").
+			SourceIP("10.0.1.5").
+			ContextExtension("env", "staging").
+			ContextExtension("tenant_id", "tenant-123").
+			ContextExtension("app", "myapp").
+			Build()
 
 		attrs := authzServer.buildRequestAttributes(req)
 
@@ -308,27 +244,11 @@ func TestAuthzServer_Check(t *testing.T) {
 	})
 
 	t.Run("buildRequestAttributes handles missing context extensions", func(t *testing.T) {
-		req := &authv3.CheckRequest{
-			Attributes: &authv3.AttributeContext{
-				Request: &authv3.AttributeContext_Request{
-					Http: &authv3.AttributeContext_HttpRequest{
-						Method: "GET",
-						Path:   "/health",
-						Host:   "api.example.com",
-					},
-				},
-				Source: &authv3.AttributeContext_Peer{
-					Address: &corev3.Address{
-						Address: &corev3.Address_SocketAddress{
-							SocketAddress: &corev3.SocketAddress{
-								Address: "127.0.0.1",
-							},
-						},
-					},
-				},
-				// No context extensions
-			},
-		}
+		req := envoytest.NewCheckRequest().
+			Path("/health").
+			Host("api.example.com").
+			SourceIP("127.0.0.1").
+			Build()
 
 		attrs := authzServer.buildRequestAttributes(req)
 
@@ -343,29 +263,52 @@ func TestAuthzServer_Check(t *testing.T) {
 		}
 	})
 
-	t.Run("buildRequestAttributes with empty context extensions", func(t *testing.T) {
-		req := &authv3.CheckRequest{
-			Attributes: &authv3.AttributeContext{
-				Request: &authv3.AttributeContext_Request{
-					Http: &authv3.AttributeContext_HttpRequest{
-						Method: "GET",
-						Path:   "/api",
-						Host:   "api.example.com",
-					},
-				},
-				Source: &authv3.AttributeContext_Peer{
-					Address: &corev3.Address{
-						Address: &corev3.Address_SocketAddress{
-							SocketAddress: &corev3.SocketAddress{
-								Address: "192.168.1.1",
-							},
-						},
-					},
-				},
-				// Empty context extensions map
-				ContextExtensions: map[string]string{},
-			},
+	t.Run("buildRequestAttributes extracts query, scheme, port and TLS", func(t *testing.T) {
+		req := envoytest.NewCheckRequest().
+			Path("/api/orders?status=open&tag=a&tag=b").
+			Scheme("https").
+			DestinationAddress("10.0.0.1", 8443).
+			SNI("api.example.com").
+			Header("x-forwarded-tls-version", "TLSv1.3").
+			Header("x-forwarded-tls-cipher", "TLS_AES_128_GCM_SHA256").
+			Build()
+
+		attrs := authzServer.buildRequestAttributes(req)
+
+		if !reflect.DeepEqual(attrs.Query, map[string][]string{"status": {"open"}, "tag": {"a", "b"}}) {
+			t.Errorf("unexpected query: %v", attrs.Query)
+		}
+		if attrs.Scheme != "https" || attrs.Port != 8443 {
+			t.Errorf("expected https on port 8443, got %s on %d", attrs.Scheme, attrs.Port)
 		}
+		want := &request.TLSInfo{SNI: "api.example.com", Version: "TLSv1.3", CipherSuite: "TLS_AES_128_GCM_SHA256"}
+		if !reflect.DeepEqual(attrs.TLS, want) {
+			t.Errorf("expected TLS %+v, got %+v", want, attrs.TLS)
+		}
+	})
+
+	t.Run("buildRequestAttributes without TLS", func(t *testing.T) {
+		req := envoytest.NewCheckRequest().
+			Path("/api").
+			Header("x-forwarded-tls-version", "-").
+			Build()
+
+		attrs := authzServer.buildRequestAttributes(req)
+
+		if attrs.TLS != nil {
+			t.Errorf("expected no TLS session, got %+v", attrs.TLS)
+		}
+		if attrs.Query != nil {
+			t.Errorf("expected no query, got %v", attrs.Query)
+		}
+	})
+
+	t.Run("buildRequestAttributes with empty context extensions", func(t *testing.T) {
+		req := envoytest.NewCheckRequest().
+			Path("/api").
+			Host("api.example.com").
+			SourceIP("192.168.1.1").
+			Build()
 
 		attrs := authzServer.buildRequestAttributes(req)
 
@@ -427,28 +370,11 @@ func TestAuthzServer_WithActorFiltering(t *testing.T) {
 	t.Run("anonymous actor gets filtered store - no validators match", func(t *testing.T) {
 		// No actor credentials in context, so ForActor will be called with AnonymousResult
 		// The CEL filter requires trust_domain == "gateway.example.com", which won't match empty actor
-		req := &authv3.CheckRequest{
-			Attributes: &authv3.AttributeContext{
-				Request: &authv3.AttributeContext_Request{
-					Http: &authv3.AttributeContext_HttpRequest{
-						Method: "GET",
-						Path:   "/api/resource",
-						Headers: map[string]string{
-							"authorization": "Bearer external-token",
-						},
-					},
-				},
-				Source: &authv3.AttributeContext_Peer{
-					Address: &corev3.Address{
-						Address: &corev3.Address_SocketAddress{
-							SocketAddress: &corev3.SocketAddress{
-								Address: "192.168.1.1",
-							},
-						},
-					},
-				},
-			},
-		}
+		req := envoytest.NewCheckRequest().
+			Path("/api/resource").
+			Bearer("external-token").
+			SourceIP("192.168.1.1").
+			Build()
 
 		resp, err := authzServer.Check(ctx, req)
 		if err != nil {
@@ -491,28 +417,11 @@ func TestAuthzServer_WithActorFiltering(t *testing.T) {
 
 		authzServerWithGateway := NewAuthzServer(storeWithGateway, tokenService, nil, nil)
 
-		req := &authv3.CheckRequest{
-			Attributes: &authv3.AttributeContext{
-				Request: &authv3.AttributeContext_Request{
-					Http: &authv3.AttributeContext_HttpRequest{
-						Method: "GET",
-						Path:   "/api/resource",
-						Headers: map[string]string{
-							"authorization": "Bearer external-token",
-						},
-					},
-				},
-				Source: &authv3.AttributeContext_Peer{
-					Address: &corev3.Address{
-						Address: &corev3.Address_SocketAddress{
-							SocketAddress: &corev3.SocketAddress{
-								Address: "192.168.1.1",
-							},
-						},
-					},
-				},
-			},
-		}
+		req := envoytest.NewCheckRequest().
+			Path("/api/resource").
+			Bearer("external-token").
+			SourceIP("192.168.1.1").
+			Build()
 
 		resp, err := authzServerWithGateway.Check(actorCtx, req)
 		if err != nil {
@@ -553,28 +462,11 @@ func TestAuthzServer_WithActorFiltering(t *testing.T) {
 		})
 		actorCtx := metadata.NewIncomingContext(ctx, md)
 
-		req := &authv3.CheckRequest{
-			Attributes: &authv3.AttributeContext{
-				Request: &authv3.AttributeContext_Request{
-					Http: &authv3.AttributeContext_HttpRequest{
-						Method: "GET",
-						Path:   "/api/resource",
-						Headers: map[string]string{
-							"authorization": "Bearer subject-token",
-						},
-					},
-				},
-				Source: &authv3.AttributeContext_Peer{
-					Address: &corev3.Address{
-						Address: &corev3.Address_SocketAddress{
-							SocketAddress: &corev3.SocketAddress{
-								Address: "192.168.1.1",
-							},
-						},
-					},
-				},
-			},
-		}
+		req := envoytest.NewCheckRequest().
+			Path("/api/resource").
+			Bearer("subject-token").
+			SourceIP("192.168.1.1").
+			Build()
 
 		resp, err := authzServerFailing.Check(actorCtx, req)
 		if err != nil {
@@ -643,28 +535,11 @@ func TestAuthzServer_WithActorFilteringByRequestPath(t *testing.T) {
 	authzServer := NewAuthzServer(filteredStore, tokenService, nil, nil)
 
 	t.Run("admin path allows admin validator", func(t *testing.T) {
-		req := &authv3.CheckRequest{
-			Attributes: &authv3.AttributeContext{
-				Request: &authv3.AttributeContext_Request{
-					Http: &authv3.AttributeContext_HttpRequest{
-						Method: "GET",
-						Path:   "/admin/dashboard",
-						Headers: map[string]string{
-							"authorization": "Bearer admin-token",
-						},
-					},
-				},
-				Source: &authv3.AttributeContext_Peer{
-					Address: &corev3.Address{
-						Address: &corev3.Address_SocketAddress{
-							SocketAddress: &corev3.SocketAddress{
-								Address: "192.168.1.1",
-							},
-						},
-					},
-				},
-			},
-		}
+		req := envoytest.NewCheckRequest().
+			Path("/admin/dashboard").
+			Bearer("admin-token").
+			SourceIP("192.168.1.1").
+			Build()
 
 		resp, err := authzServer.Check(ctx, req)
 		if err != nil {
@@ -678,28 +553,11 @@ func TestAuthzServer_WithActorFilteringByRequestPath(t *testing.T) {
 	})
 
 	t.Run("api path allows user validator", func(t *testing.T) {
-		req := &authv3.CheckRequest{
-			Attributes: &authv3.AttributeContext{
-				Request: &authv3.AttributeContext_Request{
-					Http: &authv3.AttributeContext_HttpRequest{
-						Method: "GET",
-						Path:   "/api/users",
-						Headers: map[string]string{
-							"authorization": "Bearer user-token",
-						},
-					},
-				},
-				Source: &authv3.AttributeContext_Peer{
-					Address: &corev3.Address{
-						Address: &corev3.Address_SocketAddress{
-							SocketAddress: &corev3.SocketAddress{
-								Address: "192.168.1.1",
-							},
-						},
-					},
-				},
-			},
-		}
+		req := envoytest.NewCheckRequest().
+			Path("/api/users").
+			Bearer("user-token").
+			SourceIP("192.168.1.1").
+			Build()
 
 		resp, err := authzServer.Check(ctx, req)
 		if err != nil {
@@ -713,28 +571,11 @@ func TestAuthzServer_WithActorFilteringByRequestPath(t *testing.T) {
 	})
 
 	t.Run("wrong path denies access", func(t *testing.T) {
-		req := &authv3.CheckRequest{
-			Attributes: &authv3.AttributeContext{
-				Request: &authv3.AttributeContext_Request{
-					Http: &authv3.AttributeContext_HttpRequest{
-						Method: "GET",
-						Path:   "/other/resource",
-						Headers: map[string]string{
-							"authorization": "Bearer user-token",
-						},
-					},
-				},
-				Source: &authv3.AttributeContext_Peer{
-					Address: &corev3.Address{
-						Address: &corev3.Address_SocketAddress{
-							SocketAddress: &corev3.SocketAddress{
-								Address: "192.168.1.1",
-							},
-						},
-					},
-				},
-			},
-		}
+		req := envoytest.NewCheckRequest().
+			Path("/other/resource").
+			Bearer("user-token").
+			SourceIP("192.168.1.1").
+			Build()
 
 		resp, err := authzServer.Check(ctx, req)
 		if err != nil {
@@ -783,19 +624,10 @@ func TestAuthzServer_Check_Observability(t *testing.T) {
 			TrustDomain: trustDomain,
 		})
 
-		req := &authv3.CheckRequest{
-			Attributes: &authv3.AttributeContext{
-				Request: &authv3.AttributeContext_Request{
-					Http: &authv3.AttributeContext_HttpRequest{
-						Method: "GET",
-						Path:   "/api/resource",
-						Headers: map[string]string{
-							"authorization": "Bearer valid-token",
-						},
-					},
-				},
-			},
-		}
+		req := envoytest.NewCheckRequest().
+			Path("/api/resource").
+			Bearer("valid-token").
+			Build()
 
 		_, err := authzServer.Check(ctx, req)
 		if err != nil {
@@ -809,10 +641,57 @@ func TestAuthzServer_Check_Observability(t *testing.T) {
 			"ActorValidationSucceeded",
 			"SubjectCredentialExtracted",
 			"SubjectValidationSucceeded",
+			service.ProbeCall("TokensIssued", service.AnyArgument()),
+			"ResponseHeadersEmitted",
 			"End",
 		)
 	})
 
+	t.Run("token issuance is observed within the check", func(t *testing.T) {
+		fakeObs := service.NewFakeObserver(t)
+
+		trustStore := trust.NewStubStore()
+		stubValidator := trust.NewStubValidator(trust.CredentialTypeBearer)
+		stubValidator.WithResult(&trust.Result{Subject: "user-123", TrustDomain: "parsec.test"})
+		trustStore.AddValidator(stubValidator)
+
+		issuerRegistry := service.NewSimpleRegistry()
+		issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+			IssuerURL: "https://parsec.test",
+			TTL:       5 * time.Minute,
+		}))
+		tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, fakeObs)
+
+		authzServer := NewAuthzServer(trustStore, tokenService, nil, fakeObs)
+
+		req := envoytest.NewCheckRequest().
+			Path("/api/resource").
+			Bearer("valid-token").
+			Build()
+		if _, err := authzServer.Check(ctx, req); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+
+		fakeObs.AssertCallsInOrder(
+			service.InProbe("AuthzCheckStarted", service.ProbeCall("SubjectValidationSucceeded", service.ResultWithSubject("user-123"))),
+			service.InProbe("TokenIssuanceStarted", "TokenIssuanceStarted"),
+			service.InProbe("TokenIssuanceStarted", service.ProbeCall("TokenTypeIssuanceSucceeded", service.TokenTypeTransactionToken, service.AnyArgument())),
+			service.InProbe("TokenIssuanceStarted", "End"),
+			service.InProbe("AuthzCheckStarted", "TokensIssued"),
+			service.InProbe("AuthzCheckStarted", "ResponseHeadersEmitted"),
+			service.InProbe("AuthzCheckStarted", "End"),
+		)
+
+		check := fakeObs.ProbesStartedWith("AuthzCheckStarted")[0]
+		emitted := check.CallArgs("ResponseHeadersEmitted")
+		if added := emitted[0][0].([]string); len(added) != 1 || added[0] != "Transaction-Token" {
+			t.Errorf("expected the transaction token header, got %v", added)
+		}
+		if removed := emitted[0][1].([]string); len(removed) != 1 || removed[0] != "authorization" {
+			t.Errorf("expected the credential header removed, got %v", removed)
+		}
+	})
+
 	t.Run("authorization failure calls probe correctly", func(t *testing.T) {
 		// Setup
 		fakeObs := service.NewFakeObserver(t)
@@ -829,19 +708,10 @@ func TestAuthzServer_Check_Observability(t *testing.T) {
 		authzServer := NewAuthzServer(trustStore, tokenService, nil, fakeObs)
 
 		// Create request with invalid token (not added to stubValidator)
-		req := &authv3.CheckRequest{
-			Attributes: &authv3.AttributeContext{
-				Request: &authv3.AttributeContext_Request{
-					Http: &authv3.AttributeContext_HttpRequest{
-						Method: "GET",
-						Path:   "/api/resource",
-						Headers: map[string]string{
-							"authorization": "Bearer invalid-token",
-						},
-					},
-				},
-			},
-		}
+		req := envoytest.NewCheckRequest().
+			Path("/api/resource").
+			Bearer("invalid-token").
+			Build()
 
 		_, err := authzServer.Check(ctx, req)
 		if err != nil {
@@ -854,7 +724,8 @@ func TestAuthzServer_Check_Observability(t *testing.T) {
 			"RequestAttributesParsed",
 			"ActorValidationSucceeded",
 			"SubjectCredentialExtracted",
-			"SubjectValidationSucceeded", // Still succeeds even for invalid token with StubValidator
+			"SubjectValidationSucceeded",                                 // Still succeeds even for invalid token with StubValidator
+			service.ProbeCall("TokenIssuanceFailed", service.AnyError()), // No issuer is registered
 			"End",
 		)
 	})
@@ -872,17 +743,9 @@ func TestAuthzServer_Check_Observability(t *testing.T) {
 		authzServer := NewAuthzServer(trustStore, tokenService, nil, fakeObs)
 
 		// Create request with no authorization header
-		req := &authv3.CheckRequest{
-			Attributes: &authv3.AttributeContext{
-				Request: &authv3.AttributeContext_Request{
-					Http: &authv3.AttributeContext_HttpRequest{
-						Method:  "GET",
-						Path:    "/api/resource",
-						Headers: map[string]string{},
-					},
-				},
-			},
-		}
+		req := envoytest.NewCheckRequest().
+			Path("/api/resource").
+			Build()
 
 		_, err := authzServer.Check(ctx, req)
 		if err != nil {
@@ -899,3 +762,241 @@ func TestAuthzServer_Check_Observability(t *testing.T) {
 		)
 	})
 }
+
+// sizedIssuer issues tokens of a fixed size
+type sizedIssuer struct {
+	size int
+}
+
+func (i *sizedIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	now := time.Now()
+	return &service.Token{
+		Value:     strings.Repeat("x", i.size),
+		IssuedAt:  now,
+		ExpiresAt: now.Add(time.Minute),
+	}, nil
+}
+
+func (i *sizedIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	return nil, nil
+}
+
+func TestAuthzServer_Check_HeaderSizeLimit(t *testing.T) {
+	ctx := context.Background()
+	referenceType := service.TokenType("urn:parsec:token-type:reference")
+
+	newServer := func(t *testing.T, observer service.AuthzCheckObserver, referenceSize int, limit *HeaderSizeLimit) *AuthzServer {
+		trustStore := trust.NewStubStore()
+		trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+		issuerRegistry := service.NewSimpleRegistry()
+		issuerRegistry.Register(service.TokenTypeTransactionToken, &sizedIssuer{size: 9000})
+		issuerRegistry.Register(referenceType, &sizedIssuer{size: referenceSize})
+		tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+		return NewAuthzServer(trustStore, tokenService, nil, observer, WithHeaderSizeLimit(limit))
+	}
+	req := envoytest.NewCheckRequest().Path("/api/resource").Bearer("valid-token").Build()
+
+	t.Run("oversized token is denied", func(t *testing.T) {
+		fakeObs := service.NewFakeObserver(t)
+		authzServer := newServer(t, fakeObs, 32, &HeaderSizeLimit{MaxTokenSize: 8192})
+
+		resp, err := authzServer.Check(ctx, req)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if resp.GetDeniedResponse() == nil || resp.Status.Code != int32(codes.FailedPrecondition) {
+			t.Fatalf("expected a denial, got %v", resp)
+		}
+		if !strings.Contains(resp.Status.Message, "Transaction-Token is 9000 bytes, over the limit of 8192 bytes") {
+			t.Errorf("expected a clear status message, got %q", resp.Status.Message)
+		}
+		fakeObs.AssertSingleProbe("AuthzCheckStarted", nil).AssertProbeSequence(
+			"RequestAttributesParsed",
+			"ActorValidationSucceeded",
+			"SubjectCredentialExtracted",
+			"SubjectValidationSucceeded",
+			"TokensIssued",
+			service.ProbeCall("ResponseHeaderTooLarge", "Transaction-Token", 9000, 8192),
+			service.ProbeCall("TokenIssuanceFailed", service.ErrorWithCode(perr.ErrCodeTokenTooLarge)),
+			"End",
+		)
+	})
+
+	t.Run("oversized token is replaced by a reference token", func(t *testing.T) {
+		authzServer := newServer(t, nil, 32, &HeaderSizeLimit{MaxTokenSize: 8192, ReferenceTokenType: referenceType})
+
+		resp, err := authzServer.Check(ctx, req)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		okResp := resp.GetOkResponse()
+		if okResp == nil || len(okResp.Headers) != 1 {
+			t.Fatalf("expected the check to be allowed with one header, got %v", resp)
+		}
+		if header := okResp.Headers[0].Header; header.Key != "Transaction-Token" || len(header.Value) != 32 {
+			t.Errorf("expected the reference token in Transaction-Token, got %s of %d bytes", header.Key, len(header.Value))
+		}
+	})
+
+	t.Run("oversized reference token is denied", func(t *testing.T) {
+		authzServer := newServer(t, nil, 9000, &HeaderSizeLimit{MaxTokenSize: 8192, ReferenceTokenType: referenceType})
+
+		resp, err := authzServer.Check(ctx, req)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if resp.GetDeniedResponse() == nil || resp.Status.Code != int32(codes.FailedPrecondition) {
+			t.Errorf("expected a denial, got %v", resp)
+		}
+	})
+
+	t.Run("tokens within the limit are emitted", func(t *testing.T) {
+		authzServer := newServer(t, nil, 32, &HeaderSizeLimit{MaxTokenSize: 16384})
+
+		resp, err := authzServer.Check(ctx, req)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if okResp := resp.GetOkResponse(); okResp == nil || len(okResp.Headers[0].Header.Value) != 9000 {
+			t.Errorf("expected the issued token, got %v", resp)
+		}
+	})
+}
+
+// failingMapper fails every mapping
+type failingMapper struct{}
+
+func (failingMapper) Map(ctx context.Context, input *service.MapperInput) (claims.Claims, error) {
+	return nil, perr.New(perr.ErrCodeIssuerUnavailable, "identity lookup unavailable")
+}
+
+func TestAuthzServer_Check_ResponseHeaders(t *testing.T) {
+	ctx := context.Background()
+
+	newServer := func(t *testing.T, observer service.AuthzCheckObserver, specs ...ResponseHeaderSpec) *AuthzServer {
+		trustStore := trust.NewStubStore()
+		trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+		issuerRegistry := service.NewSimpleRegistry()
+		issuerRegistry.Register(service.TokenTypeTransactionToken, &sizedIssuer{size: 32})
+		tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+		return NewAuthzServer(trustStore, tokenService, nil, observer, WithResponseHeaders(specs...))
+	}
+	req := envoytest.NewCheckRequest().Path("/api/resource").Bearer("valid-token").Build()
+
+	headerValues := func(t *testing.T, authzServer *AuthzServer) map[string]string {
+		t.Helper()
+		resp, err := authzServer.Check(ctx, req)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		okResp := resp.GetOkResponse()
+		if okResp == nil {
+			t.Fatalf("expected the check to be allowed, got %v", resp)
+		}
+		values := make(map[string]string)
+		for _, header := range okResp.Headers {
+			values[header.Header.Key] = header.Header.Value
+		}
+		return values
+	}
+
+	t.Run("claim is emitted alongside the token", func(t *testing.T) {
+		fakeObs := service.NewFakeObserver(t)
+		authzServer := newServer(t, fakeObs, ResponseHeaderSpec{
+			HeaderName: "x-request-id",
+			Mapper:     service.NewStubClaimMapper(claims.Claims{"request_id": "req-123"}),
+			Claim:      "request_id",
+		})
+
+		values := headerValues(t, authzServer)
+		if values["x-request-id"] != "req-123" || len(values["Transaction-Token"]) != 32 {
+			t.Errorf("expected the token and the derived header, got %v", values)
+		}
+		probe := fakeObs.AssertSingleProbe("AuthzCheckStarted", nil)
+		probe.AssertProbeSequence(
+			"RequestAttributesParsed",
+			"ActorValidationSucceeded",
+			"SubjectCredentialExtracted",
+			"SubjectValidationSucceeded",
+			"TokensIssued",
+			"ResponseHeadersEmitted",
+			"End",
+		)
+		if args := probe.CallArgs("ResponseHeadersEmitted"); len(args) != 1 || !slices.Equal(args[0][0].([]string), []string{"Transaction-Token", "x-request-id"}) {
+			t.Errorf("expected both headers to be reported, got %v", args)
+		}
+	})
+
+	t.Run("claims are emitted as base64 JSON", func(t *testing.T) {
+		authzServer := newServer(t, nil, ResponseHeaderSpec{
+			HeaderName: "x-rh-identity",
+			Mapper:     service.NewStubClaimMapper(claims.Claims{"identity": map[string]any{"org_id": "42"}}),
+			Base64:     true,
+		})
+
+		values := headerValues(t, authzServer)
+		data, err := base64.StdEncoding.DecodeString(values["x-rh-identity"])
+		if err != nil {
+			t.Fatalf("expected base64, got %q: %v", values["x-rh-identity"], err)
+		}
+		if string(data) != `{"identity":{"org_id":"42"}}` {
+			t.Errorf("expected the mapped claims, got %s", data)
+		}
+	})
+
+	t.Run("missing claim omits the header", func(t *testing.T) {
+		authzServer := newServer(t, nil, ResponseHeaderSpec{
+			HeaderName: "x-request-id",
+			Mapper:     service.NewStubClaimMapper(claims.Claims{"other": "value"}),
+			Claim:      "request_id",
+		})
+
+		values := headerValues(t, authzServer)
+		if _, ok := values["x-request-id"]; ok || len(values) != 1 {
+			t.Errorf("expected only the token header, got %v", values)
+		}
+	})
+
+	t.Run("mapper failure denies the check", func(t *testing.T) {
+		fakeObs := service.NewFakeObserver(t)
+		authzServer := newServer(t, fakeObs, ResponseHeaderSpec{HeaderName: "x-rh-identity", Mapper: failingMapper{}})
+
+		resp, err := authzServer.Check(ctx, req)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if resp.GetDeniedResponse() == nil {
+			t.Fatalf("expected a denial, got %v", resp)
+		}
+		if !strings.Contains(resp.Status.Message, "failed to derive header x-rh-identity") {
+			t.Errorf("expected the header in the status message, got %q", resp.Status.Message)
+		}
+		fakeObs.AssertSingleProbe("AuthzCheckStarted", nil).AssertProbeSequence(
+			"RequestAttributesParsed",
+			"ActorValidationSucceeded",
+			"SubjectCredentialExtracted",
+			"SubjectValidationSucceeded",
+			"TokensIssued",
+			service.ProbeCall("TokenIssuanceFailed", service.ErrorWithCode(perr.ErrCodeIssuerUnavailable)),
+			"End",
+		)
+	})
+}
+
+func TestAuthzServer_DenyResponse_RetryAfter(t *testing.T) {
+	authzServer := NewAuthzServer(trust.NewStubStore(), nil, nil, nil)
+
+	err := fmt.Errorf("failed to issue tokens: %w", perr.Errorf(perr.ErrCodeRateLimited, "quota exceeded").WithRetryAfter(90*time.Second))
+	denied := authzServer.denyResponse(err).GetDeniedResponse()
+	if denied.GetStatus().GetCode() != typev3.StatusCode_TooManyRequests {
+		t.Errorf("expected 429, got %v", denied.GetStatus().GetCode())
+	}
+	headers := denied.GetHeaders()
+	if len(headers) != 1 || headers[0].GetHeader().GetKey() != "retry-after" || headers[0].GetHeader().GetValue() != "90" {
+		t.Errorf("expected retry-after: 90, got %v", headers)
+	}
+
+	if headers := authzServer.denyResponse(perr.New(perr.ErrCodeInvalidRequest, "bad")).GetDeniedResponse().GetHeaders(); len(headers) != 0 {
+		t.Errorf("expected no headers without a retry delay, got %v", headers)
+	}
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	parsecv1 "github.com/project-kessel/parsec/api/gen/parsec/v1"
+	"github.com/project-kessel/parsec/internal/datasource"
+	"github.com/project-kessel/parsec/internal/envoytest"
+	"github.com/project-kessel/parsec/internal/issuer"
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/mapper"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// benchmarkLuaScript stands in for a data source calling an entitlements API
+const benchmarkLuaScript = `
+function fetch(input)
+  return {
+    data = json.encode({roles = {"developer", "admin"}, org_id = "o-" .. input.subject.subject}),
+    content_type = "application/json"
+  }
+end
+
+function cache_key(input)
+  return {subject = {subject = input.subject.subject}}
+end
+`
+
+// benchmarkCELScript maps subject, data source and request attributes
+const benchmarkCELScript = `{
+  "sub": subject.subject,
+  "email": subject.claims.email,
+  "roles": datasource("entitlements").roles,
+  "org_id": datasource("entitlements").org_id,
+  "path": request.path
+}`
+
+// newBenchmarkTokenService builds a token service issuing transaction tokens
+// With real, tokens are signed with ES256 and enriched by a CEL mapper
+// reading a cached Lua data source; otherwise stub issuers and mappers are used.
+func newBenchmarkTokenService(b *testing.B, real bool) *service.TokenService {
+	b.Helper()
+
+	dataSources := service.NewDataSourceRegistry()
+	issuers := service.NewSimpleRegistry()
+
+	if !real {
+		issuers.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+			IssuerURL:                 "https://parsec.test",
+			TTL:                       5 * time.Minute,
+			TransactionContextMappers: []service.ClaimMapper{service.NewPassthroughSubjectMapper()},
+			RequestContextMappers:     []service.ClaimMapper{service.NewRequestAttributesMapper()},
+		}))
+		return service.NewTokenService("parsec.test", dataSources, issuers, nil)
+	}
+
+	lua, err := datasource.NewCacheableLuaDataSource(datasource.CacheableLuaDataSourceConfig{
+		Name:         "entitlements",
+		Script:       benchmarkLuaScript,
+		CacheKeyFunc: "cache_key",
+		CacheTTL:     time.Minute,
+	})
+	if err != nil {
+		b.Fatalf("failed to create Lua data source: %v", err)
+	}
+	dataSources.Register(datasource.NewInMemoryCachingDataSource(lua))
+
+	celMapper, err := mapper.NewCELMapper(benchmarkCELScript)
+	if err != nil {
+		b.Fatalf("failed to create CEL mapper: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.Cleanup(cancel)
+	signer := keys.NewDualSlotRotatingSigner(keys.DualSlotRotatingSignerConfig{
+		Namespace:     string(service.TokenTypeTransactionToken),
+		KeyProviderID: "memory",
+		KeyProviderRegistry: map[string]keys.KeyProvider{
+			"memory": keys.NewInMemoryKeyProvider(keys.KeyTypeECP256, "ES256"),
+		},
+		SlotStore: keys.NewInMemoryKeySlotStore(),
+	})
+	if err := signer.Start(ctx); err != nil {
+		b.Fatalf("failed to start signer: %v", err)
+	}
+	b.Cleanup(signer.Stop)
+
+	issuers.Register(service.TokenTypeTransactionToken, issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL:                 "https://parsec.test",
+		TTL:                       5 * time.Minute,
+		Signer:                    signer,
+		TransactionContextMappers: []service.ClaimMapper{celMapper},
+		RequestContextMappers:     []service.ClaimMapper{service.NewRequestAttributesMapper()},
+	}))
+	return service.NewTokenService("parsec.test", dataSources, issuers, nil)
+}
+
+// benchmarkModes are the token service setups each hot path is measured with
+var benchmarkModes = []struct {
+	name string
+	real bool
+}{
+	{"stub", false},
+	{"signed_cel_lua", true},
+}
+
+func BenchmarkAuthzServer_Check(b *testing.B) {
+	for _, mode := range benchmarkModes {
+		b.Run(mode.name, func(b *testing.B) {
+			store := trust.NewStubStore().AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+			authzServer := NewAuthzServer(store, newBenchmarkTokenService(b, mode.real), nil, nil)
+
+			req := envoytest.NewCheckRequest().
+				Path("/api/resource").
+				Host("api.example.com").
+				Bearer("user-token").
+				Header("user-agent", "bench").
+				Build()
+
+			ctx := context.Background()
+			b.ReportAllocs()
+			for b.Loop() {
+				resp, err := authzServer.Check(ctx, req)
+				if err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+				if resp.GetOkResponse() == nil {
+					b.Fatalf("expected OK response, got %v", resp.GetStatus())
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkExchangeServer_Exchange(b *testing.B) {
+	for _, mode := range benchmarkModes {
+		b.Run(mode.name, func(b *testing.B) {
+			store := trust.NewStubStore().AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+			exchangeServer := NewExchangeServer(store, newBenchmarkTokenService(b, mode.real), NewStubClaimsFilterRegistry(), nil)
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.New(map[string]string{
+				"authorization": "Bearer client-token",
+			}))
+			req := &parsecv1.ExchangeRequest{
+				GrantType:          "urn:ietf:params:oauth:grant-type:token-exchange",
+				SubjectToken:       "user-token",
+				SubjectTokenType:   "urn:ietf:params:oauth:token-type:access_token",
+				RequestedTokenType: string(service.TokenTypeTransactionToken),
+				Audience:           "parsec.test",
+			}
+
+			b.ReportAllocs()
+			for b.Loop() {
+				resp, err := exchangeServer.Exchange(ctx, req)
+				if err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+				if resp.AccessToken == "" {
+					b.Fatal("expected access token")
+				}
+			}
+		})
+	}
+}
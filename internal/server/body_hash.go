@@ -0,0 +1,68 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/request"
+)
+
+// partialBodyHeader is set by Envoy when it forwarded only the first
+// max_request_bytes of the body (allow_partial_message)
+const partialBodyHeader = "x-envoy-auth-partial-body"
+
+// BodyHash records the SHA-256 digest of the bodies Envoy forwards to
+// ext_authz (with_request_body) as the body_sha256 request attribute, so
+// issued tokens can bind to the exact payload of a request
+//
+// Only complete bodies are hashed: a body Envoy truncated, or did not
+// forward, has no digest.
+type BodyHash struct {
+	// Methods are the HTTP methods whose bodies are hashed; empty hashes all
+	Methods []string
+
+	// Required denies checks of those methods whose complete body is not
+	// available
+	Required bool
+}
+
+// apply records the digest of the request body in attrs
+func (h *BodyHash) apply(httpReq *authv3.AttributeContext_HttpRequest, attrs *request.RequestAttributes) error {
+	if httpReq == nil {
+		return nil
+	}
+	if len(h.Methods) > 0 && !slices.Contains(h.Methods, httpReq.GetMethod()) {
+		return nil
+	}
+
+	body, ok := envoyBody(httpReq)
+	if !ok {
+		if h.Required {
+			return perr.Errorf(perr.ErrCodeInvalidRequest, "the complete request body is required but was not forwarded")
+		}
+		return nil
+	}
+	digest := sha256.Sum256(body)
+	attrs.BodySHA256 = hex.EncodeToString(digest[:])
+	return nil
+}
+
+// envoyBody returns the request body, and whether it is the complete body
+func envoyBody(httpReq *authv3.AttributeContext_HttpRequest) ([]byte, bool) {
+	if httpReq.GetHeaders()[partialBodyHeader] == "true" {
+		return nil, false
+	}
+	if raw := httpReq.GetRawBody(); len(raw) > 0 {
+		return raw, true
+	}
+	if body := httpReq.GetBody(); body != "" {
+		return []byte(body), true
+	}
+	// An empty body is only known to be complete when the request declared
+	// it empty; otherwise Envoy may not have forwarded it
+	return nil, httpReq.GetSize() == 0
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/project-kessel/parsec/internal/envoytest"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestBodyHash_Apply(t *testing.T) {
+	digest := func(body string) string {
+		sum := sha256.Sum256([]byte(body))
+		return hex.EncodeToString(sum[:])
+	}
+	hash := &BodyHash{Methods: []string{"POST", "PUT"}}
+
+	tests := []struct {
+		name string
+		req  *envoytest.CheckRequestBuilder
+		want string
+	}{
+		{"body", envoytest.NewCheckRequest().Method("POST").Body(`{"amount":10}`), digest(`{"amount":10}`)},
+		{"raw body", envoytest.NewCheckRequest().Method("PUT").RawBody([]byte{0, 1, 2}), digest("\x00\x01\x02")},
+		{"declared empty body", envoytest.NewCheckRequest().Method("POST").Body(""), digest("")},
+		{"method not hashed", envoytest.NewCheckRequest().Method("GET").Body("ignored"), ""},
+		{"partial body", envoytest.NewCheckRequest().Method("POST").Body("trunc").Header(partialBodyHeader, "true"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := &request.RequestAttributes{}
+			if err := hash.apply(tt.req.Build().GetAttributes().GetRequest().GetHttp(), attrs); err != nil {
+				t.Fatalf("apply failed: %v", err)
+			}
+			if attrs.BodySHA256 != tt.want {
+				t.Errorf("BodySHA256 = %q, want %q", attrs.BodySHA256, tt.want)
+			}
+		})
+	}
+
+	t.Run("body not forwarded", func(t *testing.T) {
+		httpReq := envoytest.NewCheckRequest().Method("POST").Build().GetAttributes().GetRequest().GetHttp()
+		httpReq.Size = 42
+
+		attrs := &request.RequestAttributes{}
+		if err := hash.apply(httpReq, attrs); err != nil || attrs.BodySHA256 != "" {
+			t.Errorf("expected no digest and no error, got %q and %v", attrs.BodySHA256, err)
+		}
+		required := &BodyHash{Required: true}
+		if err := required.apply(httpReq, attrs); !perr.HasCode(err, perr.ErrCodeInvalidRequest) {
+			t.Errorf("expected an invalid request error, got %v", err)
+		}
+	})
+}
+
+func TestAuthzServer_Check_BodyHash(t *testing.T) {
+	ctx := context.Background()
+
+	trustStore := trust.NewStubStore()
+	trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, &sizedIssuer{size: 32})
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+	authzServer := NewAuthzServer(trustStore, tokenService, nil, nil, WithBodyHash(&BodyHash{Methods: []string{"POST"}, Required: true}))
+
+	t.Run("truncated body is denied", func(t *testing.T) {
+		req := envoytest.NewCheckRequest().Method("POST").Body("trunc").
+			Header(partialBodyHeader, "true").Bearer("valid-token").Build()
+		resp, err := authzServer.Check(ctx, req)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if resp.GetDeniedResponse() == nil || resp.Status.Code != int32(codes.InvalidArgument) {
+			t.Errorf("expected an invalid argument response, got %v", resp)
+		}
+	})
+
+	t.Run("complete body is allowed", func(t *testing.T) {
+		req := envoytest.NewCheckRequest().Method("POST").Body(`{}`).Bearer("valid-token").Build()
+		resp, err := authzServer.Check(ctx, req)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if resp.GetOkResponse() == nil {
+			t.Errorf("expected the check to be allowed, got %v", resp)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/project-kessel/parsec/internal/trust"
+	"github.com/project-kessel/parsec/internal/trustdomain"
+)
+
+// AllowedCaller identifies authenticated actors allowed to call an endpoint
+type AllowedCaller struct {
+	// TrustDomain matches the caller's trust domain, exactly or by a pattern
+	// such as "*.example.com" (see trustdomain.Match; empty matches any)
+	TrustDomain string
+
+	// Subject matches the caller's subject exactly, or by prefix when it ends
+	// in "*" (empty matches any)
+	Subject string
+}
+
+// matches reports whether the caller is allowed by this entry
+func (c *AllowedCaller) matches(caller *trust.Result) bool {
+	if !trustdomain.Match(c.TrustDomain, caller.TrustDomain) {
+		return false
+	}
+	return matchPattern(c.Subject, caller.Subject)
+}
+
+// callerAuthorizer admits callers of an HTTP endpoint presenting a bearer
+// credential accepted by the trust store and matching an allowed caller
+// Without a trust store, every caller is admitted.
+type callerAuthorizer struct {
+	trustStore     trust.Store
+	allowedCallers []AllowedCaller
+}
+
+// authenticate validates the caller's bearer credential against the trust store
+func (a *callerAuthorizer) authenticate(r *http.Request) (*trust.Result, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, fmt.Errorf("missing bearer credential")
+	}
+
+	caller, err := a.trustStore.Validate(r.Context(), &trust.BearerCredential{Token: token})
+	if err != nil {
+		return nil, fmt.Errorf("invalid bearer credential: %w", err)
+	}
+	return caller, nil
+}
+
+// authorized reports whether an authenticated caller is allowed
+// Any credential the trust store accepts authenticates, including end-user
+// tokens, so only the configured callers are allowed.
+func (a *callerAuthorizer) authorized(caller *trust.Result) bool {
+	for i := range a.allowedCallers {
+		if a.allowedCallers[i].matches(caller) {
+			return true
+		}
+	}
+	return false
+}
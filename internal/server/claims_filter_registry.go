@@ -1,8 +1,11 @@
 package server
 
 import (
+	"fmt"
+
 	"github.com/project-kessel/parsec/internal/claims"
 	"github.com/project-kessel/parsec/internal/trust"
+	"github.com/project-kessel/parsec/internal/trustdomain"
 )
 
 // ClaimsFilterRegistry determines which request_context claims an actor is allowed to provide
@@ -37,5 +40,78 @@ func NewStubClaimsFilterRegistryWithFilter(filter claims.ClaimsFilter) *StubClai
 
 // GetFilter implements ClaimsFilterRegistry
 func (r *StubClaimsFilterRegistry) GetFilter(actor *trust.Result) (claims.ClaimsFilter, error) {
-	return r.filter, nil
+	return bindActor(r.filter, actor)
+}
+
+// ClaimsFilterRule selects a claims filter for matching actors
+type ClaimsFilterRule struct {
+	// TrustDomain matches the actor's trust domain, exactly or by a pattern such
+	// as "*.example.com" (see trustdomain.Match; empty matches any)
+	TrustDomain string
+
+	// Subject matches the actor's subject exactly, or by prefix when it ends in "*"
+	// (empty matches any)
+	Subject string
+
+	// Filter is applied to request_context claims from matching actors
+	Filter claims.ClaimsFilter
+}
+
+// matches reports whether the rule applies to the actor
+func (r *ClaimsFilterRule) matches(actor *trust.Result) bool {
+	if !trustdomain.Match(r.TrustDomain, actor.TrustDomain) {
+		return false
+	}
+	return matchPattern(r.Subject, actor.Subject)
+}
+
+// RuleClaimsFilterRegistry selects a claims filter by the actor's trust domain and subject.
+// Rules are evaluated in order and the first match wins. Actors matching no rule get
+// the default filter, which drops all claims unless configured otherwise.
+type RuleClaimsFilterRegistry struct {
+	rules         []ClaimsFilterRule
+	defaultFilter claims.ClaimsFilter
+}
+
+// NewRuleClaimsFilterRegistry creates a rule-based registry
+// If defaultFilter is nil, actors matching no rule may not provide any claims
+func NewRuleClaimsFilterRegistry(rules []ClaimsFilterRule, defaultFilter claims.ClaimsFilter) *RuleClaimsFilterRegistry {
+	if defaultFilter == nil {
+		defaultFilter = claims.NewAllowListClaimsFilter(nil)
+	}
+	return &RuleClaimsFilterRegistry{
+		rules:         rules,
+		defaultFilter: defaultFilter,
+	}
+}
+
+// GetFilter implements ClaimsFilterRegistry
+func (r *RuleClaimsFilterRegistry) GetFilter(actor *trust.Result) (claims.ClaimsFilter, error) {
+	if actor == nil {
+		actor = trust.AnonymousResult()
+	}
+
+	filter := r.defaultFilter
+	for i := range r.rules {
+		if r.rules[i].matches(actor) {
+			filter = r.rules[i].Filter
+			break
+		}
+	}
+
+	return bindActor(filter, actor)
+}
+
+// bindActor binds the actor to filters whose decisions depend on it
+func bindActor(filter claims.ClaimsFilter, actor *trust.Result) (claims.ClaimsFilter, error) {
+	aware, ok := filter.(claims.ActorAwareClaimsFilter)
+	if !ok {
+		return filter, nil
+	}
+
+	actorMap, err := trust.ConvertResultToMap(actor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert actor: %w", err)
+	}
+	return aware.WithActor(actorMap), nil
 }
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestRuleClaimsFilterRegistry(t *testing.T) {
+	celFilter, err := claims.NewCelClaimsFilter(`name == "method" || (actor.trust_domain == "gateways" && name == "path")`)
+	if err != nil {
+		t.Fatalf("failed to create CEL filter: %v", err)
+	}
+
+	registry := NewRuleClaimsFilterRegistry([]ClaimsFilterRule{
+		{TrustDomain: "internal", Subject: "admin-*", Filter: &claims.PassthroughClaimsFilter{}},
+		{TrustDomain: "internal", Filter: claims.NewAllowListClaimsFilter([]string{"ip_address"})},
+		{TrustDomain: "gateways", Filter: celFilter},
+		{TrustDomain: "*.partners.test", Filter: claims.NewAllowListClaimsFilter([]string{"method"})},
+	}, nil)
+
+	input := claims.Claims{
+		"method":     "GET",
+		"path":       "/orders",
+		"ip_address": "10.0.0.1",
+	}
+
+	tests := []struct {
+		name  string
+		actor *trust.Result
+		want  []string
+	}{
+		{name: "subject prefix rule", actor: &trust.Result{TrustDomain: "internal", Subject: "admin-tool"}, want: []string{"ip_address", "method", "path"}},
+		{name: "first matching rule wins", actor: &trust.Result{TrustDomain: "internal", Subject: "batch"}, want: []string{"ip_address"}},
+		{name: "CEL filter sees actor", actor: &trust.Result{TrustDomain: "gateways", Subject: "edge"}, want: []string{"method", "path"}},
+		{name: "trust domain pattern rule", actor: &trust.Result{TrustDomain: "acme.partners.test", Subject: "acme"}, want: []string{"method"}},
+		{name: "unmatched actor gets nothing", actor: &trust.Result{TrustDomain: "partners", Subject: "acme"}, want: nil},
+		{name: "nil actor gets nothing", actor: nil, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := registry.GetFilter(tt.actor)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := filter.Filter(input)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected claims %v, got %v", tt.want, got)
+			}
+			for _, name := range tt.want {
+				if !got.Has(name) {
+					t.Errorf("expected claim %q, got %v", name, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCelClaimsFilter_EvaluationErrorDropsClaim(t *testing.T) {
+	filter, err := claims.NewCelClaimsFilter(`value.startsWith("/")`)
+	if err != nil {
+		t.Fatalf("failed to create CEL filter: %v", err)
+	}
+
+	got := filter.Filter(claims.Claims{"path": "/orders", "port": 8080})
+	if !got.Has("path") || got.Has("port") {
+		t.Errorf("expected only path to survive, got %v", got)
+	}
+}
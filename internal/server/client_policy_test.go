@@ -0,0 +1,193 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	parsecv1 "github.com/project-kessel/parsec/api/gen/parsec/v1"
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/clients"
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/envoytest"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// newClientPolicyStore returns a store authenticating the clients of registry
+// as actors of clients.parsec.test, and accepting any bearer subject token
+func newClientPolicyStore(t *testing.T, registry clients.Registry) trust.Store {
+	t.Helper()
+	validator, err := clients.NewValidator(clients.ValidatorConfig{Registry: registry, TrustDomain: "clients.parsec.test"})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	store := trust.NewStubStore()
+	store.AddValidator(validator)
+	store.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+	return store
+}
+
+func clientContext(id string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("client_id", id, "client_secret", "s3cret"))
+}
+
+func TestExchangeServer_ClientPolicy(t *testing.T) {
+	secret := []clients.SecretHash{clients.HashSecret("s3cret")}
+	registry, err := clients.NewStaticRegistry([]clients.Client{
+		{
+			ID:           "billing",
+			SecretHashes: secret,
+			Audiences:    []string{"billing.parsec.test"},
+			TokenTypes:   []service.TokenType{service.TokenTypeTransactionToken},
+			ClaimsFilter: claims.NewAllowListClaimsFilter([]string{"purpose"}),
+			Quota:        &clients.Quota{Limit: 3, Window: time.Minute},
+		},
+		{ID: "reports", SecretHashes: secret},
+		{ID: "elsewhere", TrustDomain: "other.parsec.test", SecretHashes: secret, TokenTypes: []service.TokenType{}},
+	})
+	if err != nil {
+		t.Fatalf("NewStaticRegistry failed: %v", err)
+	}
+
+	capturing := &capturingIssuer{}
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, capturing)
+	issuerRegistry.Register(service.TokenTypeAccessToken, capturing)
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	exchangeServer := NewExchangeServer(newClientPolicyStore(t, registry), tokenService, NewStubClaimsFilterRegistry(), nil,
+		WithActorCredentialExtractor(ClientSecretActorCredentialExtractor{}),
+		WithAllowedAudiences([]string{"orders.parsec.test"}),
+		WithClientPolicy(NewClientPolicy(registry, clk)),
+	)
+	exchange := func(client string, edit func(*parsecv1.ExchangeRequest)) error {
+		req := &parsecv1.ExchangeRequest{
+			GrantType:        GrantTypeTokenExchange,
+			SubjectToken:     "user-token",
+			SubjectTokenType: string(service.TokenTypeAccessToken),
+		}
+		if edit != nil {
+			edit(req)
+		}
+		_, err := exchangeServer.Exchange(clientContext(client), req)
+		return err
+	}
+
+	t.Run("client audiences replace the allowed audiences", func(t *testing.T) {
+		if err := exchange("billing", func(req *parsecv1.ExchangeRequest) { req.Audience = "billing.parsec.test" }); err != nil {
+			t.Errorf("expected the client's audience to be allowed: %v", err)
+		}
+		err := exchange("billing", func(req *parsecv1.ExchangeRequest) { req.Audience = "orders.parsec.test" })
+		if perr.CodeOf(err) != perr.ErrCodeInvalidAudience {
+			t.Errorf("expected invalid_audience, got %v", err)
+		}
+		if err := exchange("reports", func(req *parsecv1.ExchangeRequest) { req.Audience = "orders.parsec.test" }); err != nil {
+			t.Errorf("expected clients without audiences to keep the allowed audiences: %v", err)
+		}
+	})
+
+	t.Run("client token types", func(t *testing.T) {
+		err := exchange("billing", func(req *parsecv1.ExchangeRequest) { req.RequestedTokenType = string(service.TokenTypeAccessToken) })
+		if perr.CodeOf(err) != perr.ErrCodeActorDenied {
+			t.Errorf("expected actor_denied, got %v", err)
+		}
+		if err := exchange("reports", func(req *parsecv1.ExchangeRequest) { req.RequestedTokenType = string(service.TokenTypeAccessToken) }); err != nil {
+			t.Errorf("expected clients without token types to be allowed any: %v", err)
+		}
+	})
+
+	t.Run("client claims filter", func(t *testing.T) {
+		requestContext := base64.StdEncoding.EncodeToString([]byte(`{"purpose": "invoicing", "tenant": "acme"}`))
+		if err := exchange("billing", func(req *parsecv1.ExchangeRequest) { req.RequestContext = requestContext }); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		additional := capturing.last.RequestAttributes.Additional
+		if additional["purpose"] != "invoicing" || additional["tenant"] != nil {
+			t.Errorf("expected only purpose to pass the client's filter, got %v", additional)
+		}
+		if err := exchange("reports", func(req *parsecv1.ExchangeRequest) { req.RequestContext = requestContext }); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if capturing.last.RequestAttributes.Additional["tenant"] != "acme" {
+			t.Error("expected clients without a filter to get the registry's")
+		}
+	})
+
+	t.Run("client trust domain", func(t *testing.T) {
+		// The client is of another trust domain, so its policy doesn't apply
+		if err := exchange("elsewhere", nil); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("client quota", func(t *testing.T) {
+		clk.Advance(time.Minute)
+		for i := 0; i < 3; i++ {
+			if err := exchange("billing", nil); err != nil {
+				t.Fatalf("exchange %d: unexpected error: %v", i, err)
+			}
+		}
+		err := exchange("billing", nil)
+		if retryAfter := perr.RetryAfterOf(err); perr.CodeOf(err) != perr.ErrCodeRateLimited || retryAfter != time.Minute {
+			t.Errorf("expected rate_limited for a minute, got %v (%s)", err, retryAfter)
+		}
+		if err := exchange("reports", nil); err != nil {
+			t.Errorf("expected other clients to be unaffected: %v", err)
+		}
+	})
+}
+
+func TestAuthzServer_ClientPolicy(t *testing.T) {
+	secret := []clients.SecretHash{clients.HashSecret("s3cret")}
+	registry, err := clients.NewStaticRegistry([]clients.Client{
+		{
+			ID:           "gateway",
+			SecretHashes: secret,
+			TokenTypes:   []service.TokenType{service.TokenTypeTransactionToken},
+			Quota:        &clients.Quota{Limit: 1, Window: time.Minute},
+		},
+		{ID: "sidecar", SecretHashes: secret, TokenTypes: []service.TokenType{service.TokenTypeJWT}},
+	})
+	if err != nil {
+		t.Fatalf("NewStaticRegistry failed: %v", err)
+	}
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, &capturingIssuer{})
+	issuerRegistry.Register(service.TokenTypeAccessToken, &capturingIssuer{})
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	authzServer := NewAuthzServer(newClientPolicyStore(t, registry), tokenService, []TokenTypeSpec{
+		{Type: service.TokenTypeTransactionToken, HeaderName: "Transaction-Token"},
+		{Type: service.TokenTypeAccessToken, HeaderName: "Authorization"},
+	}, nil,
+		WithAuthzActorCredentialExtractor(ClientSecretActorCredentialExtractor{}),
+		WithAuthzClientPolicy(NewClientPolicy(registry, clock.NewFixtureClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)))),
+	)
+	check := envoytest.NewCheckRequest().Path("/api").Bearer("user-token").Build()
+
+	resp, err := authzServer.Check(clientContext("gateway"), check)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	headers := resp.GetOkResponse().GetHeaders()
+	if len(headers) != 1 || headers[0].GetHeader().GetKey() != "Transaction-Token" {
+		t.Errorf("expected only the client's token type to be issued, got %v", headers)
+	}
+
+	resp, _ = authzServer.Check(clientContext("gateway"), check)
+	if resp.GetDeniedResponse() == nil || resp.GetDeniedResponse().GetHeaders()[0].GetHeader().GetKey() != "retry-after" {
+		t.Errorf("expected the check over the client's quota to be denied, got %v", resp)
+	}
+
+	resp, _ = authzServer.Check(clientContext("sidecar"), check)
+	if resp.GetDeniedResponse() == nil {
+		t.Errorf("expected a client allowed none of the token types to be denied, got %v", resp)
+	}
+}
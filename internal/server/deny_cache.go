@@ -0,0 +1,158 @@
+package server
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// DenyCache remembers subject credentials that failed validation, by a hash of
+// the credential, so a client retrying with the same expired or forged token
+// is denied without repeating JWKS lookups or introspection calls. Entries
+// live for a short TTL and the least recently used are evicted first.
+//
+// Only failures with an invalid_token or expired_token code are cached;
+// transient failures such as an unreachable introspection endpoint are not.
+// The actor and request path are part of the key, since they select the
+// validators a credential is checked against.
+type DenyCache struct {
+	maxEntries int
+	ttl        time.Duration
+	clock      clock.Clock
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	lru     *list.List
+}
+
+// DenyCacheConfig configures a DenyCache
+type DenyCacheConfig struct {
+	// MaxEntries bounds the number of cached failures (default: 10000)
+	MaxEntries int
+
+	// TTL bounds how long a failure is cached (default: 10 seconds)
+	TTL time.Duration
+
+	// Clock is the time source for expiry (default: system clock)
+	Clock clock.Clock
+}
+
+// denyCacheEntry is a cached validation failure
+type denyCacheEntry struct {
+	key       [sha256.Size]byte
+	err       error
+	expiresAt time.Time
+}
+
+// NewDenyCache creates a validation failure cache
+func NewDenyCache(cfg DenyCacheConfig) *DenyCache {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 10000
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 10 * time.Second
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.NewSystemClock()
+	}
+	return &DenyCache{
+		maxEntries: cfg.MaxEntries,
+		ttl:        cfg.TTL,
+		clock:      cfg.Clock,
+		entries:    make(map[[sha256.Size]byte]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// denyCacheKey hashes the credential with the actor and request path,
+// reporting false if the credential isn't cacheable
+func denyCacheKey(credential trust.Credential, actor *trust.Result, attrs *request.RequestAttributes) ([sha256.Size]byte, bool) {
+	var token string
+	switch cred := credential.(type) {
+	case *trust.BearerCredential:
+		token = cred.Token
+	case *trust.JWTCredential:
+		token = cred.Token
+	case *trust.OIDCCredential:
+		token = cred.Token
+	default:
+		return [sha256.Size]byte{}, false
+	}
+	if token == "" {
+		return [sha256.Size]byte{}, false
+	}
+
+	h := sha256.New()
+	for _, part := range []string{string(credential.Type()), token, actor.TrustDomain, actor.Subject, attrs.Path} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key, true
+}
+
+// Get returns the unexpired failure cached for the credential, or nil
+func (c *DenyCache) Get(credential trust.Credential, actor *trust.Result, attrs *request.RequestAttributes) error {
+	key, ok := denyCacheKey(credential, actor, attrs)
+	if !ok {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*denyCacheEntry)
+	if !c.clock.Now().Before(entry.expiresAt) {
+		c.remove(elem)
+		return nil
+	}
+	c.lru.MoveToFront(elem)
+	return entry.err
+}
+
+// Put caches the validation failure of the credential, unless it is transient
+func (c *DenyCache) Put(credential trust.Credential, actor *trust.Result, attrs *request.RequestAttributes, err error) {
+	if !perr.HasCode(err, perr.ErrCodeInvalidToken) && !perr.HasCode(err, perr.ErrCodeExpiredToken) {
+		return
+	}
+	key, ok := denyCacheKey(credential, actor, attrs)
+	if !ok {
+		return
+	}
+	entry := &denyCacheEntry{key: key, err: err, expiresAt: c.clock.Now().Add(c.ttl)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove drops an entry; c.mu must be held
+func (c *DenyCache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*denyCacheEntry).key)
+}
+
+// Len returns the number of cached failures, including expired ones not yet evicted
+func (c *DenyCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
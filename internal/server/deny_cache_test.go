@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/envoytest"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// countingValidator counts validations
+type countingValidator struct {
+	*trust.StubValidator
+	calls int
+}
+
+func (v *countingValidator) Validate(ctx context.Context, credential trust.Credential) (*trust.Result, error) {
+	v.calls++
+	return v.StubValidator.Validate(ctx, credential)
+}
+
+func TestAuthzServer_Check_DenyCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	newServer := func(validationErr error, cache *DenyCache) (*AuthzServer, *countingValidator) {
+		validator := &countingValidator{StubValidator: trust.NewStubValidator(trust.CredentialTypeBearer).WithError(validationErr)}
+		trustStore := trust.NewStubStore()
+		trustStore.AddValidator(validator)
+		issuerRegistry := service.NewSimpleRegistry()
+		issuerRegistry.Register(service.TokenTypeTransactionToken, &sizedIssuer{size: 32})
+		tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+		return NewAuthzServer(trustStore, tokenService, nil, nil, WithDenyCache(cache)), validator
+	}
+	check := func(t *testing.T, authzServer *AuthzServer, path string) {
+		t.Helper()
+		resp, err := authzServer.Check(ctx, envoytest.NewCheckRequest().Path(path).Bearer("expired-token").Build())
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if resp.GetDeniedResponse() == nil || resp.Status.Code != int32(codes.Unauthenticated) {
+			t.Fatalf("expected an unauthenticated denial, got %v", resp)
+		}
+	}
+
+	t.Run("repeated invalid token is validated once until the TTL", func(t *testing.T) {
+		clk := clock.NewFixtureClock(now)
+		authzServer, validator := newServer(trust.ErrExpiredToken, NewDenyCache(DenyCacheConfig{TTL: 10 * time.Second, Clock: clk}))
+
+		for range 3 {
+			check(t, authzServer, "/api/resource")
+		}
+		if validator.calls != 1 {
+			t.Errorf("expected 1 validation, got %d", validator.calls)
+		}
+
+		check(t, authzServer, "/api/other")
+		if validator.calls != 2 {
+			t.Errorf("expected another path to be validated, got %d validations", validator.calls)
+		}
+
+		clk.Advance(10 * time.Second)
+		check(t, authzServer, "/api/resource")
+		if validator.calls != 3 {
+			t.Errorf("expected the expired entry to be validated again, got %d validations", validator.calls)
+		}
+	})
+
+	t.Run("transient failures are not cached", func(t *testing.T) {
+		cache := NewDenyCache(DenyCacheConfig{})
+		authzServer, validator := newServer(perr.New(perr.ErrCodeIssuerUnavailable, "introspection unavailable"), cache)
+
+		resp, err := authzServer.Check(ctx, envoytest.NewCheckRequest().Path("/api/resource").Bearer("token").Build())
+		if err != nil || resp.GetDeniedResponse() == nil {
+			t.Fatalf("expected a denial, got %v, %v", resp, err)
+		}
+		_, _ = authzServer.Check(ctx, envoytest.NewCheckRequest().Path("/api/resource").Bearer("token").Build())
+		if validator.calls != 2 || cache.Len() != 0 {
+			t.Errorf("expected 2 validations and nothing cached, got %d and %d", validator.calls, cache.Len())
+		}
+	})
+}
+
+func TestDenyCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewDenyCache(DenyCacheConfig{MaxEntries: 2})
+	actor := trust.AnonymousResult()
+	attrs := &request.RequestAttributes{Path: "/"}
+	tokens := []*trust.BearerCredential{{Token: "a"}, {Token: "b"}, {Token: "c"}}
+
+	cache.Put(tokens[0], actor, attrs, trust.ErrInvalidToken)
+	cache.Put(tokens[1], actor, attrs, trust.ErrInvalidToken)
+	_ = cache.Get(tokens[0], actor, attrs)
+	cache.Put(tokens[2], actor, attrs, trust.ErrInvalidToken)
+
+	if cache.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", cache.Len())
+	}
+	if cache.Get(tokens[1], actor, attrs) != nil {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+	if cache.Get(tokens[0], actor, attrs) == nil || cache.Get(tokens[2], actor, attrs) == nil {
+		t.Error("expected the recently used entries to be kept")
+	}
+}
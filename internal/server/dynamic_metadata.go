@@ -0,0 +1,101 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// DynamicMetadataField is an identity attribute the AuthzServer sets in Envoy
+// dynamic metadata
+type DynamicMetadataField string
+
+const (
+	// DynamicMetadataSubject is the validated subject
+	DynamicMetadataSubject DynamicMetadataField = "subject"
+
+	// DynamicMetadataIssuer is the issuer of the subject's credential
+	DynamicMetadataIssuer DynamicMetadataField = "issuer"
+
+	// DynamicMetadataTrustDomain is the subject's trust domain
+	DynamicMetadataTrustDomain DynamicMetadataField = "trust_domain"
+
+	// DynamicMetadataActor is the calling actor's subject
+	DynamicMetadataActor DynamicMetadataField = "actor"
+
+	// DynamicMetadataActorTrustDomain is the calling actor's trust domain
+	DynamicMetadataActorTrustDomain DynamicMetadataField = "actor_trust_domain"
+
+	// DynamicMetadataTransactionID is the "txn" of the issued transaction token
+	DynamicMetadataTransactionID DynamicMetadataField = "txn"
+)
+
+// DynamicMetadata selects the identity attributes set in the dynamic metadata
+// of allowed checks, so downstream Envoy filters (RBAC, rate limits, access
+// logs) can key on identity without parsing tokens. Envoy records them under
+// the ext_authz filter's namespace, "envoy.filters.http.ext_authz".
+type DynamicMetadata struct {
+	// Fields are the identity attributes to set
+	Fields []DynamicMetadataField
+
+	// Claims are subject claims to set under "claims"
+	Claims []string
+}
+
+// build returns the metadata for an allowed check, or nil if it is empty
+// Attributes without a value are left out.
+func (m *DynamicMetadata) build(subject, actor *trust.Result, tokens map[service.TokenType]*service.Token) (*structpb.Struct, error) {
+	values := make(map[string]any)
+	for _, field := range m.Fields {
+		var value string
+		switch field {
+		case DynamicMetadataSubject:
+			value = subject.Subject
+		case DynamicMetadataIssuer:
+			value = subject.Issuer
+		case DynamicMetadataTrustDomain:
+			value = subject.TrustDomain
+		case DynamicMetadataActor:
+			value = actor.Subject
+		case DynamicMetadataActorTrustDomain:
+			value = actor.TrustDomain
+		case DynamicMetadataTransactionID:
+			if token := tokens[service.TokenTypeTransactionToken]; token != nil {
+				value = token.TransactionID
+			}
+		}
+		if value != "" {
+			values[string(field)] = value
+		}
+	}
+
+	claims := make(map[string]any)
+	for _, name := range m.Claims {
+		if value, ok := subject.Claims[name]; ok && value != nil {
+			claims[name] = value
+		}
+	}
+	if len(claims) > 0 {
+		values["claims"] = claims
+	}
+
+	if len(values) == 0 {
+		return nil, nil
+	}
+	// Claims may hold any JSON value, such as typed slices set by result hooks,
+	// which structpb only accepts in their generic JSON form
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode dynamic metadata: %w", err)
+	}
+	metadata := &structpb.Struct{}
+	if err := protojson.Unmarshal(data, metadata); err != nil {
+		return nil, fmt.Errorf("failed to encode dynamic metadata: %w", err)
+	}
+	return metadata, nil
+}
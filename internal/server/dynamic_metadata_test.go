@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/envoytest"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// txnIssuer issues transaction tokens with a fixed txn
+type txnIssuer struct {
+	txn string
+}
+
+func (i *txnIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	now := time.Now()
+	return &service.Token{Value: "txn-token", IssuedAt: now, ExpiresAt: now.Add(time.Minute), TransactionID: i.txn}, nil
+}
+
+func (i *txnIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	return nil, nil
+}
+
+func TestAuthzServer_Check_DynamicMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	newServer := func(metadata *DynamicMetadata) *AuthzServer {
+		trustStore := trust.NewStubStore()
+		trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer).WithResult(&trust.Result{
+			Subject:     "alice",
+			Issuer:      "https://idp.example.com",
+			TrustDomain: "idp.example.com",
+			Claims:      claims.Claims{"org_id": "42", "groups": []string{"admins"}},
+		}))
+		issuerRegistry := service.NewSimpleRegistry()
+		issuerRegistry.Register(service.TokenTypeTransactionToken, &txnIssuer{txn: "txn-123"})
+		tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+		return NewAuthzServer(trustStore, tokenService, nil, nil, WithDynamicMetadata(metadata))
+	}
+	req := envoytest.NewCheckRequest().Bearer("valid-token").Build()
+
+	t.Run("identity attributes are set", func(t *testing.T) {
+		authzServer := newServer(&DynamicMetadata{
+			Fields: []DynamicMetadataField{DynamicMetadataSubject, DynamicMetadataTrustDomain, DynamicMetadataActor, DynamicMetadataTransactionID},
+			Claims: []string{"org_id", "groups", "missing"},
+		})
+
+		resp, err := authzServer.Check(ctx, req)
+		if err != nil || resp.GetOkResponse() == nil {
+			t.Fatalf("expected the check to be allowed, got %v, %v", resp, err)
+		}
+		want := map[string]any{
+			"subject":      "alice",
+			"trust_domain": "idp.example.com",
+			"txn":          "txn-123",
+			"claims":       map[string]any{"org_id": "42", "groups": []any{"admins"}},
+		}
+		if got := resp.GetDynamicMetadata().AsMap(); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("no metadata without configuration", func(t *testing.T) {
+		resp, err := newServer(nil).Check(ctx, req)
+		if err != nil || resp.GetOkResponse() == nil {
+			t.Fatalf("expected the check to be allowed, got %v, %v", resp, err)
+		}
+		if resp.GetDynamicMetadata() != nil {
+			t.Errorf("expected no dynamic metadata, got %v", resp.GetDynamicMetadata())
+		}
+	})
+}
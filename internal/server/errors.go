@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/status"
+
+	"github.com/project-kessel/parsec/internal/perr"
+)
+
+// oauthErrorResponse is an OAuth 2.0 error response (RFC 6749 section 5.2)
+type oauthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// oauthErrorHandler writes gateway errors that carry a parsec error code as
+// OAuth 2.0 error responses, with the HTTP status of the code, as RFC 8693
+// (section 2.2.2) requires for token exchange. Other errors, such as malformed
+// requests rejected by the gateway itself, use the default handler.
+func oauthErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	st := status.Convert(err)
+	code, ok := perr.CodeFromStatus(st)
+	if !ok {
+		runtime.DefaultHTTPErrorHandler(ctx, mux, marshaler, w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if retryAfter, ok := perr.RetryAfterFromStatus(st); ok {
+		w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+	}
+	w.WriteHeader(code.HTTPStatus())
+	_ = json.NewEncoder(w).Encode(oauthErrorResponse{
+		Error:            code.OAuthError(),
+		ErrorDescription: st.Message(),
+	})
+}
+
+// retryAfterSeconds formats a retry delay as a Retry-After value, in whole
+// seconds rounded up so clients never retry early
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
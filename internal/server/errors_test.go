@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/project-kessel/parsec/internal/perr"
+)
+
+func TestOAuthErrorHandler(t *testing.T) {
+	mux := runtime.NewServeMux()
+	marshaler := &runtime.JSONPb{}
+
+	t.Run("parsec errors are OAuth error responses", func(t *testing.T) {
+		// Simulate the gRPC hop: the gateway sees the status, not the error
+		err := status.Convert(perr.Errorf(perr.ErrCodeInvalidAudience, "requested audience %q does not match", "other")).Err()
+
+		w := httptest.NewRecorder()
+		oauthErrorHandler(context.Background(), mux, marshaler, w, httptest.NewRequest(http.MethodPost, "/v1/token", nil), err)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", w.Code)
+		}
+		var body oauthErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid response body: %v", err)
+		}
+		if body.Error != "invalid_target" || body.ErrorDescription != `requested audience "other" does not match` {
+			t.Errorf("unexpected response: %+v", body)
+		}
+	})
+
+	t.Run("rate limited errors carry Retry-After", func(t *testing.T) {
+		err := status.Convert(perr.Errorf(perr.ErrCodeRateLimited, "quota exceeded").WithRetryAfter(1500 * time.Millisecond)).Err()
+
+		w := httptest.NewRecorder()
+		oauthErrorHandler(context.Background(), mux, marshaler, w, httptest.NewRequest(http.MethodPost, "/v1/token", nil), err)
+
+		if w.Code != http.StatusTooManyRequests {
+			t.Errorf("expected 429, got %d", w.Code)
+		}
+		if got := w.Header().Get("Retry-After"); got != "2" {
+			t.Errorf("expected Retry-After 2, got %q", got)
+		}
+	})
+
+	t.Run("other errors use the default handler", func(t *testing.T) {
+		err := status.Error(codes.NotFound, "no route")
+
+		w := httptest.NewRecorder()
+		oauthErrorHandler(context.Background(), mux, marshaler, w, httptest.NewRequest(http.MethodGet, "/nope", nil), err)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", w.Code)
+		}
+	})
+}
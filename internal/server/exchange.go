@@ -4,37 +4,160 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 
 	parsecv1 "github.com/project-kessel/parsec/api/gen/parsec/v1"
 	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/clients"
+	"github.com/project-kessel/parsec/internal/perr"
 	"github.com/project-kessel/parsec/internal/request"
 	"github.com/project-kessel/parsec/internal/service"
 	"github.com/project-kessel/parsec/internal/trust"
+	"github.com/project-kessel/parsec/internal/trustdomain"
 )
 
 // ExchangeServer implements the TokenExchange gRPC service
 type ExchangeServer struct {
 	parsecv1.UnimplementedTokenExchangeServiceServer
 
-	trustStore           trust.Store
-	tokenService         *service.TokenService
-	claimsFilterRegistry ClaimsFilterRegistry
-	observer             service.TokenExchangeObserver
+	trustStore            trust.Store
+	tokenService          *service.TokenService
+	claimsFilterRegistry  ClaimsFilterRegistry
+	observer              service.TokenExchangeObserver
+	delegationPolicy      trust.DelegationPolicy
+	rejectedClaimsWarning bool
+	schemaRegistry        RequestContextSchemaRegistry
+	actorCredentials      ActorCredentialExtractor
+	selfIssuancePolicy    trust.SelfIssuancePolicy
+	trustedProxies        []netip.Prefix
+	allowedAudiences      []string
+	clientPolicy          *ClientPolicy
+}
+
+const (
+	// GrantTypeTokenExchange exchanges a subject token for a new token (RFC 8693)
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	// GrantTypeClientCredentials issues the authenticated actor a token
+	// representing itself, with no subject token (RFC 6749 section 4.4)
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// RejectedClaimsHeader is the response metadata key listing request_context
+// claims dropped by the claims filter. Over HTTP, grpc-gateway forwards it as
+// the Grpc-Metadata-Parsec-Rejected-Claims header. The same names are returned
+// in the rejected_claims response field; the header is kept for clients that
+// read it.
+const RejectedClaimsHeader = "parsec-rejected-claims"
+
+// NonceHeader is the request metadata carrying the nonce an id_token
+// subject_token must have been issued with, sent over HTTP as the
+// Grpc-Metadata-Parsec-Nonce header. The nonce is not checked without it.
+const NonceHeader = "parsec-nonce"
+
+// ExchangeServerOption is a functional option for configuring an ExchangeServer
+type ExchangeServerOption func(*ExchangeServer)
+
+// WithDelegationPolicy sets the policy deciding whether the calling actor
+// may act on behalf of the subject. Defaults to allowing all delegations.
+func WithDelegationPolicy(policy trust.DelegationPolicy) ExchangeServerOption {
+	return func(s *ExchangeServer) {
+		if policy != nil {
+			s.delegationPolicy = policy
+		}
+	}
+}
+
+// WithRejectedClaimsWarning reports the names of request_context claims
+// dropped by the claims filter back to the caller in the rejected_claims
+// response field and the RejectedClaimsHeader response metadata. Disabled by
+// default.
+func WithRejectedClaimsWarning(enabled bool) ExchangeServerOption {
+	return func(s *ExchangeServer) {
+		s.rejectedClaimsWarning = enabled
+	}
+}
+
+// WithRequestContextSchemas enforces a schema on filtered request_context claims.
+// Exchanges whose request_context does not satisfy the selected schema are rejected.
+func WithRequestContextSchemas(registry RequestContextSchemaRegistry) ExchangeServerOption {
+	return func(s *ExchangeServer) {
+		s.schemaRegistry = registry
+	}
+}
+
+// WithActorCredentialExtractor sets where the calling actor's credential is
+// read from. Defaults to DefaultActorCredentialExtractor.
+func WithActorCredentialExtractor(extractor ActorCredentialExtractor) ExchangeServerOption {
+	return func(s *ExchangeServer) {
+		if extractor != nil {
+			s.actorCredentials = extractor
+		}
+	}
+}
+
+// WithSelfIssuancePolicy enables the client_credentials grant, issuing an
+// authenticated actor a token representing itself when the policy allows it.
+// The grant is unsupported by default.
+func WithSelfIssuancePolicy(policy trust.SelfIssuancePolicy) ExchangeServerOption {
+	return func(s *ExchangeServer) {
+		s.selfIssuancePolicy = policy
+	}
+}
+
+// WithTrustedProxies sets the proxies whose x-forwarded-for entries are
+// trusted when deriving the caller's address. Loopback is always trusted.
+func WithTrustedProxies(prefixes []netip.Prefix) ExchangeServerOption {
+	return func(s *ExchangeServer) {
+		s.trustedProxies = prefixes
+	}
+}
+
+// WithAllowedAudiences sets the audiences tokens may be requested for, in
+// addition to those the token service's audience policy allows (by default
+// the trust domain). Audiences may be patterns, e.g. "*.example.com" for any
+// audience under example.com.
+func WithAllowedAudiences(audiences []string) ExchangeServerOption {
+	return func(s *ExchangeServer) {
+		s.allowedAudiences = audiences
+	}
+}
+
+// WithClientPolicy applies the policies of registered clients to the exchanges
+// of their actors: the audiences and token types they may request, the
+// filter of the request_context claims they provide, and their quota
+func WithClientPolicy(policy *ClientPolicy) ExchangeServerOption {
+	return func(s *ExchangeServer) {
+		s.clientPolicy = policy
+	}
 }
 
 // NewExchangeServer creates a new token exchange server
-func NewExchangeServer(trustStore trust.Store, tokenService *service.TokenService, claimsFilterRegistry ClaimsFilterRegistry, observer service.TokenExchangeObserver) *ExchangeServer {
+func NewExchangeServer(trustStore trust.Store, tokenService *service.TokenService, claimsFilterRegistry ClaimsFilterRegistry, observer service.TokenExchangeObserver, opts ...ExchangeServerOption) *ExchangeServer {
 	// Use null object pattern - default to no-op observer if none provided
 	if observer == nil {
 		observer = service.NoOpTokenExchangeObserver()
 	}
-	return &ExchangeServer{
+	s := &ExchangeServer{
 		trustStore:           trustStore,
 		tokenService:         tokenService,
 		claimsFilterRegistry: claimsFilterRegistry,
 		observer:             observer,
+		delegationPolicy:     &trust.AllowAllDelegationPolicy{},
+		actorCredentials:     DefaultActorCredentialExtractor(),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 // Exchange implements the token exchange endpoint (RFC 8693)
@@ -44,14 +167,32 @@ func (s *ExchangeServer) Exchange(ctx context.Context, req *parsecv1.ExchangeReq
 	defer probe.End()
 
 	// 1. Validate the grant type
-	if req.GrantType != "urn:ietf:params:oauth:grant-type:token-exchange" {
-		return nil, fmt.Errorf("unsupported grant_type: %s", req.GrantType)
+	selfIssuance := req.GrantType == GrantTypeClientCredentials && s.selfIssuancePolicy != nil
+	if req.GrantType != GrantTypeTokenExchange && !selfIssuance {
+		return nil, perr.Errorf(perr.ErrCodeUnsupportedGrantType, "unsupported grant_type: %s", req.GrantType)
+	}
+
+	// Tokens must be of their declared types, so each is only tried against
+	// the validators handling that type
+	var cred trust.Credential
+	if !selfIssuance {
+		var err error
+		if cred, err = tokenCredential("subject_token", req.SubjectTokenType, req.SubjectToken); err != nil {
+			return nil, err
+		}
+		if idToken, ok := cred.(*trust.OIDCCredential); ok {
+			md, _ := metadata.FromIncomingContext(ctx)
+			idToken.Nonce = firstMetadata(md, NonceHeader)
+		}
+	}
+	if err := checkActorTokenType(req); err != nil {
+		return nil, err
 	}
 
 	// 2. Extract actor credential from gRPC context
-	actorCred, err := extractActorCredential(ctx)
+	actorCred, err := s.actorCredentials.ExtractActorCredential(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract actor credential: %w", err)
+		return nil, perr.Errorf(perr.ErrCodeInvalidActor, "failed to extract actor credential: %w", err)
 	}
 
 	var actor *trust.Result
@@ -60,7 +201,7 @@ func (s *ExchangeServer) Exchange(ctx context.Context, req *parsecv1.ExchangeReq
 		actor, validationErr = s.trustStore.Validate(ctx, actorCred)
 		if validationErr != nil {
 			probe.ActorValidationFailed(validationErr)
-			return nil, fmt.Errorf("actor validation failed: %w", validationErr)
+			return nil, perr.Errorf(perr.ErrCodeInvalidActor, "actor validation failed: %w", validationErr)
 		}
 		probe.ActorValidationSucceeded(actor)
 	} else {
@@ -68,42 +209,76 @@ func (s *ExchangeServer) Exchange(ctx context.Context, req *parsecv1.ExchangeReq
 		probe.ActorValidationSucceeded(actor)
 	}
 
+	// The actor's registered client, if any, has its own policies
+	client, err := s.clientPolicy.client(ctx, actor)
+	if err != nil {
+		return nil, err
+	}
+
 	// 3. Parse and filter client-provided request_context claims
-	var reqAttrs *request.RequestAttributes
+	filteredClaims := claims.Claims{}
+	var rejectedClaims []string
 	if req.RequestContext != "" {
 		// Decode base64-encoded request_context (per transaction token spec)
 		decodedJSON, err := base64.StdEncoding.DecodeString(req.RequestContext)
 		if err != nil {
 			probe.RequestContextParseFailed(err)
-			return nil, fmt.Errorf("failed to decode request_context base64: %w", err)
+			return nil, perr.Errorf(perr.ErrCodeInvalidRequestContext, "failed to decode request_context base64: %w", err)
 		}
 
 		// Parse request_context JSON
 		var requestContextClaims claims.Claims
 		if err := json.Unmarshal(decodedJSON, &requestContextClaims); err != nil {
 			probe.RequestContextParseFailed(err)
-			return nil, fmt.Errorf("failed to parse request_context JSON: %w", err)
+			return nil, perr.Errorf(perr.ErrCodeInvalidRequestContext, "failed to parse request_context JSON: %w", err)
 		}
 
-		// Get the claims filter for this actor
-		claimsFilter, err := s.claimsFilterRegistry.GetFilter(actor)
+		// Get the claims filter for this actor, the client's if it has one
+		claimsFilter, err := claimsFilterOf(client, actor)
+		if claimsFilter == nil && err == nil {
+			claimsFilter, err = s.claimsFilterRegistry.GetFilter(actor)
+		}
 		if err != nil {
 			probe.RequestContextParseFailed(err)
 			return nil, fmt.Errorf("failed to get claims filter for actor: %w", err)
 		}
 
 		// Filter the claims based on actor permissions
-		filteredClaims := claimsFilter.Filter(requestContextClaims)
+		filteredClaims = claimsFilter.Filter(requestContextClaims)
+		if filteredClaims == nil {
+			filteredClaims = claims.Claims{}
+		}
 
-		// Convert filtered claims to RequestAttributes
-		reqAttrs = request.FromClaims(filteredClaims)
-		probe.RequestContextParsed(reqAttrs)
-	} else {
-		// No request_context provided, use empty attributes
-		reqAttrs = request.FromClaims(nil)
-		probe.RequestContextParsed(reqAttrs)
+		// Report claims the filter dropped (names only, never values)
+		if rejected := rejectedClaimNames(requestContextClaims, filteredClaims); len(rejected) > 0 {
+			probe.RequestContextClaimsRejected(rejected)
+			if s.rejectedClaimsWarning {
+				rejectedClaims = rejected
+				// Best effort: the warning must not fail the exchange
+				_ = grpc.SetHeader(ctx, metadata.Pairs(RejectedClaimsHeader, strings.Join(rejected, ",")))
+			}
+		}
 	}
 
+	// Reject request contexts that do not satisfy the actor's schema, including
+	// an omitted request_context, which is checked as an empty one
+	if s.schemaRegistry != nil {
+		if schema := s.schemaRegistry.GetSchema(actor); schema != nil {
+			if err := schema.Validate(filteredClaims); err != nil {
+				probe.RequestContextParseFailed(err)
+				return nil, perr.Errorf(perr.ErrCodeInvalidRequestContext, "invalid request_context: %w", err)
+			}
+		}
+	}
+
+	// Convert filtered claims to RequestAttributes
+	reqAttrs := request.FromClaims(filteredClaims)
+
+	// Add what parsec itself knows about the caller from gRPC metadata and
+	// peer info; unlike request_context, clients cannot supply these
+	applyCallerAttributes(ctx, reqAttrs, s.trustedProxies)
+	probe.RequestContextParsed(reqAttrs)
+
 	// Add metadata from the token exchange request itself to Additional
 	// These are not client-provided claims but server-side request metadata
 	if req.Audience != "" {
@@ -113,29 +288,53 @@ func (s *ExchangeServer) Exchange(ctx context.Context, req *parsecv1.ExchangeReq
 		reqAttrs.Additional["requested_scope"] = req.Scope
 	}
 
-	// 4. Filter trust store based on actor permissions
-	filteredStore, err := s.trustStore.ForActor(ctx, actor, reqAttrs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to filter trust store: %w", err)
-	}
+	var result *trust.Result
+	var delegation *trust.Delegation
+	if selfIssuance {
+		// 4-5. The actor is the subject; there is no delegation to check
+		if actorCred == nil {
+			return nil, perr.Errorf(perr.ErrCodeMissingCredential, "client_credentials grant requires an authenticated actor")
+		}
+		if req.SubjectToken != "" {
+			return nil, perr.Errorf(perr.ErrCodeInvalidRequest, "client_credentials grant does not accept a subject_token")
+		}
+		if err := s.selfIssuancePolicy.CheckSelfIssuance(ctx, actor, reqAttrs); err != nil {
+			return nil, fmt.Errorf("self-issuance denied: %w", err)
+		}
+		result = actor
+	} else {
+		// 4. Filter trust store based on actor permissions
+		filteredStore, err := s.trustStore.ForActor(ctx, actor, reqAttrs)
+		if err != nil {
+			return nil, perr.Errorf(perr.ErrCodeActorDenied, "failed to filter trust store: %w", err)
+		}
 
-	// 5. Validate subject_token
-	// Create strongly-typed credential based on token type
-	// In production, we'd parse the token_type to determine the specific credential type
-	// For now, we'll treat all as bearer tokens
-	// TODO: Parse subject_token_type to determine specific credential type (JWT, OIDC, etc.)
-	cred := &trust.BearerCredential{
-		Token: req.SubjectToken,
-	}
+		// 5. Validate subject_token
+		// The filtered store only includes validators the actor is allowed to
+		// use, and only tries those handling the declared token type
+		result, err = filteredStore.Validate(ctx, cred)
+		if errors.Is(err, trust.ErrNoValidator) {
+			probe.SubjectTokenValidationFailed(err)
+			return nil, perr.Errorf(perr.ErrCodeInvalidRequest, "subject_token_type %q is not accepted from this client", req.SubjectTokenType)
+		}
+		if err != nil {
+			probe.SubjectTokenValidationFailed(err)
+			return nil, perr.Errorf(perr.ErrCodeInvalidSubjectToken, "token validation failed: %w", err)
+		}
+		probe.SubjectTokenValidationSucceeded(result)
 
-	// Validate subject credential against filtered trust store
-	// The filtered store only includes validators the actor is allowed to use
-	result, err := filteredStore.Validate(ctx, cred)
-	if err != nil {
-		probe.SubjectTokenValidationFailed(err)
-		return nil, fmt.Errorf("token validation failed: %w", err)
+		// Enforce the delegation policy, for anonymous callers too, so a
+		// subject token naming its actors in may_act, or a policy requiring
+		// may_act, cannot be exchanged by omitting the actor credential
+		if err := s.delegationPolicy.CheckDelegation(ctx, result, actor); err != nil {
+			probe.DelegationDenied(err)
+			return nil, fmt.Errorf("delegation denied: %w", err)
+		}
+		// Only an authenticated actor acts for the subject
+		if actorCred != nil {
+			delegation = &trust.Delegation{Actor: actor}
+		}
 	}
-	probe.SubjectTokenValidationSucceeded(result)
 
 	// 6. Determine which token type to issue
 	// RFC 8693: If requested_token_type is not specified, default to access_token
@@ -144,12 +343,20 @@ func (s *ExchangeServer) Exchange(ctx context.Context, req *parsecv1.ExchangeReq
 	if req.RequestedTokenType != "" {
 		requestedTokenType = service.TokenType(req.RequestedTokenType)
 	}
+	if err := checkTokenType(client, requestedTokenType); err != nil {
+		return nil, err
+	}
 
-	// 7. Validate audience matches trust domain (per transaction token spec)
-	// The audience for transaction tokens is always the trust domain
-	if req.Audience != "" && req.Audience != s.tokenService.TrustDomain() {
-		return nil, fmt.Errorf("requested audience %q does not match trust domain %q",
-			req.Audience, s.tokenService.TrustDomain())
+	// 7. Validate the requested audiences against the audience policy
+	// Without audience or resource parameters, the audience is the trust domain
+	// (per transaction token spec)
+	audiences, err := s.requestedAudiences(ctx, req, result, actor, client)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.clientPolicy.admit(ctx, client, 1); err != nil {
+		return nil, err
 	}
 
 	// 8. Issue the token via TokenService
@@ -159,6 +366,9 @@ func (s *ExchangeServer) Exchange(ctx context.Context, req *parsecv1.ExchangeReq
 		RequestAttributes: reqAttrs,
 		TokenTypes:        []service.TokenType{requestedTokenType},
 		Scope:             req.Scope,
+		Audiences:         audiences,
+		Purpose:           requestedPurpose(reqAttrs),
+		Delegation:        delegation,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to issue token: %w", err)
@@ -176,5 +386,60 @@ func (s *ExchangeServer) Exchange(ctx context.Context, req *parsecv1.ExchangeReq
 		TokenType:       "Bearer",
 		ExpiresIn:       int64(token.ExpiresAt.Sub(token.IssuedAt).Seconds()),
 		Scope:           req.Scope,
+		RejectedClaims:  rejectedClaims,
 	}, nil
 }
+
+// requestedAudiences returns the audiences named by the request's audience and
+// resource parameters, in order and without duplicates
+// Each parameter may list several, space-separated, as the form marshaler
+// joins repeated parameters. Every audience must be allowed by the token
+// service's audience policy or match one of the allowed audiences, the
+// client's if it has its own, which may be patterns such as "*.example.com"
+// (see trustdomain.Match).
+func (s *ExchangeServer) requestedAudiences(ctx context.Context, req *parsecv1.ExchangeRequest, subject, actor *trust.Result, client *clients.Client) ([]string, error) {
+	allowed := s.allowedAudiences
+	if client != nil && client.Audiences != nil {
+		allowed = client.Audiences
+	}
+
+	var audiences []string
+	for _, audience := range append(strings.Fields(req.Audience), strings.Fields(req.Resource)...) {
+		if !trustdomain.MatchAny(allowed, audience) {
+			ok, err := s.tokenService.AllowsAudience(ctx, audience, subject, actor)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check audience %q: %w", audience, err)
+			}
+			if !ok {
+				return nil, perr.Errorf(perr.ErrCodeInvalidAudience, "requested audience %q is not allowed by the audience policy or an allowed audience", audience)
+			}
+		}
+		if !slices.Contains(audiences, audience) {
+			audiences = append(audiences, audience)
+		}
+	}
+	return audiences, nil
+}
+
+// requestedPurpose returns the transaction purpose the client asked for via the
+// (already filtered) "purpose" request_context claim, if any
+func requestedPurpose(attrs *request.RequestAttributes) string {
+	if attrs == nil {
+		return ""
+	}
+	purpose, _ := attrs.Additional["purpose"].(string)
+	return purpose
+}
+
+// rejectedClaimNames returns the sorted names of claims present in original
+// but absent from filtered
+func rejectedClaimNames(original, filtered claims.Claims) []string {
+	var rejected []string
+	for name := range original {
+		if _, ok := filtered[name]; !ok {
+			rejected = append(rejected, name)
+		}
+	}
+	sort.Strings(rejected)
+	return rejected
+}
@@ -5,11 +5,20 @@ import (
 	"fmt"
 	"io"
 	"net/url"
+	"strings"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"google.golang.org/protobuf/encoding/protojson"
 )
 
+// multiValuedFormParams are the token exchange parameters that may be repeated
+// (RFC 8693 section 2.1); their values are joined with spaces, as they map to
+// single string fields
+var multiValuedFormParams = map[string]bool{
+	"audience": true,
+	"resource": true,
+}
+
 // FormMarshaler implements runtime.Marshaler for application/x-www-form-urlencoded
 // This is needed for RFC 8693 OAuth 2.0 Token Exchange compatibility
 type FormMarshaler struct {
@@ -57,6 +66,8 @@ func (m *FormMarshaler) Unmarshal(data []byte, v any) error {
 	for key, vals := range values {
 		if len(vals) == 1 {
 			dataMap[key] = vals[0]
+		} else if multiValuedFormParams[key] {
+			dataMap[key] = strings.Join(vals, " ")
 		} else if len(vals) > 1 {
 			dataMap[key] = vals
 		}
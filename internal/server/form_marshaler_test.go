@@ -46,6 +46,18 @@ func TestFormMarshaler_Unmarshal(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "repeated audience and resource",
+			data: "grant_type=urn%3Aietf%3Aparams%3Aoauth%3Agrant-type%3Atoken-exchange" +
+				"&audience=orders&audience=billing" +
+				"&resource=https%3A%2F%2Fa.example.com&resource=https%3A%2F%2Fb.example.com",
+			want: &parsecv1.ExchangeRequest{
+				GrantType: "urn:ietf:params:oauth:grant-type:token-exchange",
+				Audience:  "orders billing",
+				Resource:  "https://a.example.com https://b.example.com",
+			},
+			wantErr: false,
+		},
 		{
 			name:    "invalid form data",
 			data:    "%ZZ%invalid",
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// IntrospectionServer implements the token introspection endpoint (RFC 7662)
+// It resolves tokens issued by issuers implementing service.TokenIntrospector,
+// such as opaque reference tokens whose claims are only held server-side
+type IntrospectionServer struct {
+	issuerRegistry service.Registry
+	callers        callerAuthorizer
+	logger         *slog.Logger
+}
+
+// IntrospectionServerConfig configures the introspection server
+type IntrospectionServerConfig struct {
+	// IssuerRegistry provides access to all issuers
+	IssuerRegistry service.Registry
+
+	// TrustStore authenticates callers (optional)
+	// When set, callers must present a bearer credential accepted by the store
+	// When nil, the endpoint is unauthenticated and must be protected by other means
+	TrustStore trust.Store
+
+	// AllowedCallers are the protected resources allowed to introspect tokens
+	// (RFC 7662 section 4). An authenticated caller matching none is refused,
+	// so with a TrustStore and no AllowedCallers every caller is refused.
+	AllowedCallers []AllowedCaller
+
+	// Logger is the structured logger to use (defaults to slog.Default())
+	Logger *slog.Logger
+}
+
+// NewIntrospectionServer creates a new introspection server
+func NewIntrospectionServer(cfg IntrospectionServerConfig) *IntrospectionServer {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &IntrospectionServer{
+		issuerRegistry: cfg.IssuerRegistry,
+		callers: callerAuthorizer{
+			trustStore:     cfg.TrustStore,
+			allowedCallers: cfg.AllowedCallers,
+		},
+		logger: cfg.Logger,
+	}
+}
+
+// ServeHTTP implements http.Handler
+// Expects a POST with an application/x-www-form-urlencoded "token" parameter
+func (s *IntrospectionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeOAuthError(w, http.StatusMethodNotAllowed, "invalid_request", "introspection requires POST")
+		return
+	}
+
+	if s.callers.trustStore != nil {
+		caller, err := s.callers.authenticate(r)
+		if err != nil {
+			s.logger.Warn("introspection caller authentication failed", "error", err)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "caller authentication failed")
+			return
+		}
+		if !s.callers.authorized(caller) {
+			s.logger.Warn("introspection caller not allowed",
+				"subject", caller.Subject, "trust_domain", caller.TrustDomain)
+			writeOAuthError(w, http.StatusForbidden, "access_denied", "caller may not introspect tokens")
+			return
+		}
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "malformed form body")
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "missing token parameter")
+		return
+	}
+
+	result, err := s.Introspect(r.Context(), token)
+	if err != nil {
+		s.logger.Error("token introspection failed", "error", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "introspection failed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, introspectionResponse(result))
+}
+
+// Introspect asks each introspecting issuer to resolve the token
+// The first active result wins; unknown tokens yield an inactive result
+func (s *IntrospectionServer) Introspect(ctx context.Context, token string) (*service.IntrospectionResult, error) {
+	for _, tokenType := range s.issuerRegistry.ListTokenTypes() {
+		iss, err := s.issuerRegistry.GetIssuer(tokenType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get issuer for %s: %w", tokenType, err)
+		}
+
+		introspector, ok := iss.(service.TokenIntrospector)
+		if !ok {
+			continue
+		}
+
+		result, err := introspector.Introspect(ctx, token)
+		if err != nil {
+			return nil, fmt.Errorf("issuer for %s failed to introspect token: %w", tokenType, err)
+		}
+		if result.Active {
+			return result, nil
+		}
+	}
+
+	return service.InactiveIntrospectionResult(), nil
+}
+
+// introspectionResponse builds the RFC 7662 response body
+// Inactive tokens reveal nothing beyond "active": false (RFC 7662 section 2.2)
+func introspectionResponse(result *service.IntrospectionResult) map[string]any {
+	if !result.Active {
+		return map[string]any{"active": false}
+	}
+
+	resp := make(map[string]any, len(result.Claims)+2)
+	for k, v := range result.Claims {
+		resp[k] = v
+	}
+	resp["active"] = true
+	if result.TokenType != "" {
+		resp["token_type"] = result.TokenType
+	}
+	return resp
+}
+
+// writeOAuthError writes an OAuth 2.0 error response
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+// writeJSON writes a JSON response that must not be cached
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
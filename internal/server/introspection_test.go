@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/issuer"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestIntrospectionServer(t *testing.T) {
+	ctx := context.Background()
+
+	refIssuer := issuer.NewReferenceTokenIssuer(issuer.ReferenceTokenIssuerConfig{
+		TokenType: string(service.TokenTypeAccessToken),
+		IssuerURL: "https://parsec.test",
+		TTL:       time.Minute,
+		Store:     issuer.NewInMemoryReferenceTokenStore(issuer.InMemoryReferenceTokenStoreConfig{}),
+	})
+	registry := service.NewSimpleRegistry().
+		Register(service.TokenTypeAccessToken, refIssuer).
+		Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+			IssuerURL: "https://parsec.test",
+			TTL:       time.Minute,
+		}))
+
+	token, err := refIssuer.Issue(ctx, &service.IssueContext{
+		Subject:            &trust.Result{Subject: "alice"},
+		Audience:           "parsec.test",
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	})
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	introspect := func(srv *IntrospectionServer, token, bearer string) (*httptest.ResponseRecorder, map[string]any) {
+		form := url.Values{"token": {token}}
+		req := httptest.NewRequest(http.MethodPost, "/v1/introspect", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid JSON response: %v", err)
+		}
+		return rec, body
+	}
+
+	t.Run("active reference token", func(t *testing.T) {
+		srv := NewIntrospectionServer(IntrospectionServerConfig{IssuerRegistry: registry})
+
+		rec, body := introspect(srv, token.Value, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		if body["active"] != true {
+			t.Errorf("expected active, got %v", body)
+		}
+		if body["sub"] != "alice" {
+			t.Errorf("expected sub alice, got %v", body["sub"])
+		}
+	})
+
+	t.Run("unknown token is inactive with no other fields", func(t *testing.T) {
+		srv := NewIntrospectionServer(IntrospectionServerConfig{IssuerRegistry: registry})
+
+		_, body := introspect(srv, "unknown", "")
+		if body["active"] != false || len(body) != 1 {
+			t.Errorf("expected only active=false, got %v", body)
+		}
+	})
+
+	t.Run("missing token parameter", func(t *testing.T) {
+		srv := NewIntrospectionServer(IntrospectionServerConfig{IssuerRegistry: registry})
+
+		rec, body := introspect(srv, "", "")
+		if rec.Code != http.StatusBadRequest || body["error"] != "invalid_request" {
+			t.Errorf("expected invalid_request, got %d %v", rec.Code, body)
+		}
+	})
+
+	t.Run("caller authentication", func(t *testing.T) {
+		validator := trust.NewStubValidator(trust.CredentialTypeBearer)
+		validator.WithResult(&trust.Result{Subject: "orders-api", TrustDomain: "services.internal"})
+		store := trust.NewStubStore().AddValidator(validator)
+		srv := NewIntrospectionServer(IntrospectionServerConfig{
+			IssuerRegistry: registry,
+			TrustStore:     store,
+			AllowedCallers: []AllowedCaller{{TrustDomain: "services.internal", Subject: "orders-*"}},
+		})
+
+		rec, body := introspect(srv, token.Value, "")
+		if rec.Code != http.StatusUnauthorized || body["error"] != "invalid_client" {
+			t.Errorf("expected invalid_client, got %d %v", rec.Code, body)
+		}
+
+		rec, body = introspect(srv, token.Value, "caller-token")
+		if rec.Code != http.StatusOK || body["active"] != true {
+			t.Errorf("expected active token for authenticated caller, got %d %v", rec.Code, body)
+		}
+	})
+
+	t.Run("authenticated caller not in allowed callers", func(t *testing.T) {
+		// e.g. an end user presenting their own bearer token
+		validator := trust.NewStubValidator(trust.CredentialTypeBearer)
+		validator.WithResult(&trust.Result{Subject: "alice", TrustDomain: "users.example.com"})
+		store := trust.NewStubStore().AddValidator(validator)
+		srv := NewIntrospectionServer(IntrospectionServerConfig{
+			IssuerRegistry: registry,
+			TrustStore:     store,
+			AllowedCallers: []AllowedCaller{{TrustDomain: "services.internal"}},
+		})
+
+		rec, body := introspect(srv, token.Value, "user-token")
+		if rec.Code != http.StatusForbidden || body["error"] != "access_denied" {
+			t.Errorf("expected access_denied, got %d %v", rec.Code, body)
+		}
+		if _, ok := body["sub"]; ok {
+			t.Errorf("expected no token claims to be revealed, got %v", body)
+		}
+
+		// No allowed callers refuses everyone
+		srv = NewIntrospectionServer(IntrospectionServerConfig{IssuerRegistry: registry, TrustStore: store})
+		if rec, _ := introspect(srv, token.Value, "user-token"); rec.Code != http.StatusForbidden {
+			t.Errorf("expected 403 with no allowed callers, got %d", rec.Code)
+		}
+	})
+}
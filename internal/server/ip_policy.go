@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/netip"
+
+	"github.com/project-kessel/parsec/internal/perr"
+)
+
+// IPPolicy allows or denies ext_authz checks by the client address, before
+// any credential is validated
+//
+// A client in Deny is denied. Otherwise, if Allow is not empty, a client that
+// is not in Allow is denied, as is a client whose address is unknown.
+type IPPolicy struct {
+	// Allow are the client networks allowed; empty allows all that are not denied
+	Allow []netip.Prefix
+
+	// Deny are the client networks denied, even if allowed
+	Deny []netip.Prefix
+
+	// TrustedProxies are the proxies in front of Envoy whose x-forwarded-for
+	// entries are trusted when deriving the client address (loopback is
+	// always trusted)
+	TrustedProxies []netip.Prefix
+}
+
+// Evaluate returns an error with an ErrCodeClientDenied code if the client
+// address is not allowed
+func (p *IPPolicy) Evaluate(clientAddr string) error {
+	addr, err := netip.ParseAddr(clientAddr)
+	if err != nil {
+		if len(p.Allow) > 0 {
+			return perr.Errorf(perr.ErrCodeClientDenied, "client address %q is not allowed", clientAddr)
+		}
+		return nil
+	}
+	addr = addr.Unmap()
+
+	if containsAddr(p.Deny, addr) {
+		return perr.Errorf(perr.ErrCodeClientDenied, "client address %s is denied", addr)
+	}
+	if len(p.Allow) > 0 && !containsAddr(p.Allow, addr) {
+		return perr.Errorf(perr.ErrCodeClientDenied, "client address %s is not allowed", addr)
+	}
+	return nil
+}
+
+// containsAddr reports whether addr is in any of prefixes
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/project-kessel/parsec/internal/envoytest"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestIPPolicy_Evaluate(t *testing.T) {
+	prefixes := func(cidrs ...string) []netip.Prefix {
+		var out []netip.Prefix
+		for _, cidr := range cidrs {
+			out = append(out, netip.MustParsePrefix(cidr))
+		}
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		policy  IPPolicy
+		address string
+		allowed bool
+	}{
+		{"allowed network", IPPolicy{Allow: prefixes("10.0.0.0/8")}, "10.2.3.4", true},
+		{"outside allowed networks", IPPolicy{Allow: prefixes("10.0.0.0/8")}, "192.0.2.1", false},
+		{"deny wins over allow", IPPolicy{Allow: prefixes("10.0.0.0/8"), Deny: prefixes("10.1.0.0/16")}, "10.1.2.3", false},
+		{"deny only", IPPolicy{Deny: prefixes("192.0.2.0/24")}, "198.51.100.1", true},
+		{"denied network", IPPolicy{Deny: prefixes("192.0.2.0/24")}, "192.0.2.1", false},
+		{"mapped IPv4", IPPolicy{Allow: prefixes("10.0.0.0/8")}, "::ffff:10.0.0.1", true},
+		{"unknown address with allow list", IPPolicy{Allow: prefixes("10.0.0.0/8")}, "", false},
+		{"unknown address with deny list", IPPolicy{Deny: prefixes("10.0.0.0/8")}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Evaluate(tt.address)
+			if tt.allowed && err != nil {
+				t.Errorf("expected %q to be allowed, got %v", tt.address, err)
+			}
+			if !tt.allowed && !perr.HasCode(err, perr.ErrCodeClientDenied) {
+				t.Errorf("expected %q to be denied, got %v", tt.address, err)
+			}
+		})
+	}
+}
+
+func TestAuthzServer_Check_IPPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	trustStore := trust.NewStubStore()
+	trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, &sizedIssuer{size: 32})
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	newServer := func(observer service.AuthzCheckObserver) *AuthzServer {
+		return NewAuthzServer(trustStore, tokenService, nil, observer, WithIPPolicy(&IPPolicy{
+			Allow:          []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+			TrustedProxies: []netip.Prefix{netip.MustParsePrefix("172.16.0.0/12")},
+		}))
+	}
+
+	t.Run("denied client is rejected before validation", func(t *testing.T) {
+		fakeObs := service.NewFakeObserver(t)
+		resp, err := newServer(fakeObs).Check(ctx, envoytest.NewCheckRequest().SourceIP("192.0.2.1").Bearer("valid-token").Build())
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if resp.GetDeniedResponse() == nil || resp.Status.Code != int32(codes.PermissionDenied) {
+			t.Fatalf("expected a permission denied response, got %v", resp)
+		}
+		fakeObs.AssertSingleProbe("AuthzCheckStarted", nil).AssertProbeSequence(
+			"RequestAttributesParsed",
+			service.ProbeCall("ClientAddressDenied", "192.0.2.1", service.ErrorWithCode(perr.ErrCodeClientDenied)),
+			"End",
+		)
+	})
+
+	t.Run("allowed client is checked", func(t *testing.T) {
+		resp, err := newServer(nil).Check(ctx, envoytest.NewCheckRequest().SourceIP("10.0.0.5").Bearer("valid-token").Build())
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if resp.GetOkResponse() == nil {
+			t.Errorf("expected the check to be allowed, got %v", resp)
+		}
+	})
+
+	t.Run("client behind a trusted proxy is taken from x-forwarded-for", func(t *testing.T) {
+		req := envoytest.NewCheckRequest().SourceIP("172.16.0.2").
+			Header("x-forwarded-for", "192.0.2.1, 10.0.0.5").Bearer("valid-token").Build()
+		resp, err := newServer(nil).Check(ctx, req)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if resp.GetOkResponse() == nil {
+			t.Errorf("expected the forwarded client to be allowed, got %v", resp)
+		}
+	})
+
+	t.Run("x-forwarded-for from an untrusted peer is ignored", func(t *testing.T) {
+		req := envoytest.NewCheckRequest().SourceIP("192.0.2.1").
+			Header("x-forwarded-for", "10.0.0.5").Bearer("valid-token").Build()
+		resp, err := newServer(nil).Check(ctx, req)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if resp.GetDeniedResponse() == nil {
+			t.Errorf("expected the peer address to be denied, got %v", resp)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// readinessPath is where the server reports whether it is ready to serve
+const readinessPath = "/readyz"
+
+// readinessResponse reports readiness, the warmup of each validator and the
+// health of each signer
+type readinessResponse struct {
+	Ready      bool                          `json:"ready"`
+	Validators map[string]trust.WarmupStatus `json:"validators,omitempty"`
+	Signers    map[string]keys.SignerHealth  `json:"signers,omitempty"`
+}
+
+// serveReadiness responds 200 once every validator is warmed and every signer
+// has an active key, and 503 otherwise
+func serveReadiness(ctx context.Context, w http.ResponseWriter, warmup *trust.Warmup, signers *keys.SignerRegistry) {
+	resp := readinessResponse{Ready: true}
+	if warmup != nil {
+		resp.Ready = warmup.Ready()
+		resp.Validators = warmup.Status()
+	}
+	if signers != nil {
+		resp.Signers = signers.Health(ctx)
+		for _, health := range resp.Signers {
+			if !health.Healthy() {
+				resp.Ready = false
+			}
+		}
+	}
+
+	status := http.StatusOK
+	if !resp.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, resp)
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// stubWarmer warms with err
+type stubWarmer struct{ err error }
+
+func (w stubWarmer) Warm(context.Context) error { return w.err }
+
+func TestServeReadiness(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		warmup     *trust.Warmup
+		wantStatus int
+	}{
+		{"no warmup", nil, http.StatusOK},
+		{"warmed", trust.StartWarmup(ctx, trust.WarmupConfig{}, map[string]trust.Warmer{"idp": stubWarmer{}}), http.StatusOK},
+		{"failed", trust.StartWarmup(ctx, trust.WarmupConfig{Attempts: 1}, map[string]trust.Warmer{
+			"idp": stubWarmer{errors.New("connection refused")},
+		}), http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.warmup != nil {
+				_ = tt.warmup.Wait(ctx)
+			}
+			rec := httptest.NewRecorder()
+			serveReadiness(ctx, rec, tt.warmup, nil)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d", tt.wantStatus, rec.Code)
+			}
+			var resp readinessResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Ready != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("expected ready %v, got %+v", tt.wantStatus == http.StatusOK, resp)
+			}
+			if tt.warmup != nil && resp.Validators["idp"].State == "" {
+				t.Errorf("expected the validator's status, got %+v", resp)
+			}
+		})
+	}
+}
+
+// keylessSigner has no active key
+type keylessSigner struct {
+	keys.RotatingSigner
+}
+
+func (keylessSigner) GetCurrentSigner(context.Context) (crypto.Signer, keys.KeyID, keys.Algorithm, error) {
+	return nil, "", "", errors.New("no active key")
+}
+
+func (keylessSigner) Stop() {}
+
+func TestServeReadiness_Signers(t *testing.T) {
+	ctx := context.Background()
+
+	signer := keys.NewDualSlotRotatingSigner(keys.DualSlotRotatingSignerConfig{
+		Namespace:     "txn",
+		KeyProviderID: "memory",
+		KeyProviderRegistry: map[string]keys.KeyProvider{
+			"memory": keys.NewInMemoryKeyProvider(keys.KeyTypeECP256, "ES256"),
+		},
+		SlotStore: keys.NewInMemoryKeySlotStore(),
+	})
+	signers := keys.NewSignerRegistry()
+	if err := signers.Register("txn", signer); err != nil {
+		t.Fatalf("failed to register signer: %v", err)
+	}
+	if err := signers.Start(ctx); err != nil {
+		t.Fatalf("failed to start signers: %v", err)
+	}
+	t.Cleanup(func() { _ = signers.Stop(ctx) })
+
+	rec := httptest.NewRecorder()
+	serveReadiness(ctx, rec, nil, signers)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp readinessResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if h := resp.Signers["txn"]; h.ActiveKeyID == "" || !h.Running || h.NextRotation.IsZero() {
+		t.Errorf("expected the signer's health, got %+v", h)
+	}
+
+	if err := signers.Register("keyless", keylessSigner{}); err != nil {
+		t.Fatalf("failed to register signer: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	serveReadiness(ctx, rec, nil, signers)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 with a signer without a key, got %d: %s", rec.Code, rec.Body)
+	}
+}
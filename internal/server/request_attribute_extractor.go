@@ -0,0 +1,163 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+
+	"github.com/project-kessel/parsec/internal/request"
+)
+
+// RequestAttributeExtractor derives extra attributes from the request Envoy is
+// checking, so trust store filters and claim mappers see more of the request
+// than the built-in attributes
+type RequestAttributeExtractor interface {
+	// ExtractRequestAttributes returns the value recorded under the
+	// extractor's key in RequestAttributes.Additional
+	// Returns nil and nil error if the request holds nothing to extract.
+	ExtractRequestAttributes(req *authv3.CheckRequest) (any, error)
+}
+
+// RequestAttributeSource records the attributes of an extractor under Key
+type RequestAttributeSource struct {
+	// Key is the RequestAttributes.Additional key of the attributes
+	Key string
+
+	// Extractor derives the attributes
+	Extractor RequestAttributeExtractor
+}
+
+// reservedAttributeKeys are the Additional keys set by the AuthzServer itself,
+// which extractors may not replace
+var reservedAttributeKeys = []string{"host", "context_extensions", request.CallerAttributesKey}
+
+// IsReservedAttributeKey reports whether key is set by the AuthzServer itself
+func IsReservedAttributeKey(key string) bool {
+	return slices.Contains(reservedAttributeKeys, key)
+}
+
+// applyRequestAttributeSources records the attributes of each source in attrs
+func applyRequestAttributeSources(req *authv3.CheckRequest, attrs *request.RequestAttributes, sources []RequestAttributeSource) error {
+	for _, source := range sources {
+		value, err := source.Extractor.ExtractRequestAttributes(req)
+		if err != nil {
+			return fmt.Errorf("request attribute %s: %w", source.Key, err)
+		}
+		if value == nil {
+			continue
+		}
+		if attrs.Additional == nil {
+			attrs.Additional = make(map[string]any)
+		}
+		attrs.Additional[source.Key] = value
+	}
+	return nil
+}
+
+// JWTClaimsExtractor decodes the claims of a JWT carried in a request header,
+// e.g. a token from an upstream gateway that identifies the tenant
+//
+// The signature is NOT verified: the claims are request context like any
+// header, and must not be trusted as an identity. Validate credentials with
+// the trust store instead.
+type JWTClaimsExtractor struct {
+	// Header is the request header carrying the JWT
+	Header string
+
+	// Scheme is an authorization scheme the header value must start with,
+	// e.g. "Bearer" (optional)
+	Scheme string
+}
+
+// ExtractRequestAttributes implements RequestAttributeExtractor
+func (e JWTClaimsExtractor) ExtractRequestAttributes(req *authv3.CheckRequest) (any, error) {
+	value := req.GetAttributes().GetRequest().GetHttp().GetHeaders()[strings.ToLower(e.Header)]
+	if value == "" {
+		return nil, nil
+	}
+	if e.Scheme != "" {
+		scheme, token, ok := strings.Cut(value, " ")
+		if !ok || !strings.EqualFold(scheme, e.Scheme) {
+			return nil, nil
+		}
+		value = strings.TrimSpace(token)
+	}
+
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("header %s is not a JWT", e.Header)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("header %s has an invalid JWT payload: %w", e.Header, err)
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("header %s has an invalid JWT payload: %w", e.Header, err)
+	}
+	return claims, nil
+}
+
+// FilterMetadataExtractor reads the dynamic metadata an earlier Envoy filter
+// set in a namespace, e.g. "envoy.filters.http.jwt_authn"
+type FilterMetadataExtractor struct {
+	// Namespace is the filter metadata namespace
+	Namespace string
+}
+
+// ExtractRequestAttributes implements RequestAttributeExtractor
+func (e FilterMetadataExtractor) ExtractRequestAttributes(req *authv3.CheckRequest) (any, error) {
+	metadata := req.GetAttributes().GetMetadataContext().GetFilterMetadata()[e.Namespace]
+	if metadata == nil {
+		return nil, nil
+	}
+	return metadata.AsMap(), nil
+}
+
+// QueryParametersExtractor reads query parameters of the request path
+// Parameters with one value are recorded as a string, others as a list.
+type QueryParametersExtractor struct {
+	// Names are the parameters to read; empty reads all of them
+	Names []string
+}
+
+// ExtractRequestAttributes implements RequestAttributeExtractor
+func (e QueryParametersExtractor) ExtractRequestAttributes(req *authv3.CheckRequest) (any, error) {
+	httpReq := req.GetAttributes().GetRequest().GetHttp()
+	rawQuery := httpReq.GetQuery()
+	if rawQuery == "" {
+		_, rawQuery, _ = strings.Cut(httpReq.GetPath(), "?")
+	}
+	if rawQuery == "" {
+		return nil, nil
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query string: %w", err)
+	}
+
+	params := make(map[string]any)
+	for name, values := range query {
+		if len(e.Names) > 0 && !slices.Contains(e.Names, name) {
+			continue
+		}
+		if len(values) == 1 {
+			params[name] = values[0]
+		} else {
+			list := make([]any, len(values))
+			for i, value := range values {
+				list[i] = value
+			}
+			params[name] = list
+		}
+	}
+	if len(params) == 0 {
+		return nil, nil
+	}
+	return params, nil
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/project-kessel/parsec/internal/envoytest"
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// unsignedJWT returns a JWT with the payload and an empty signature
+func unsignedJWT(payload string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	return header + "." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + "."
+}
+
+func TestJWTClaimsExtractor(t *testing.T) {
+	extractor := JWTClaimsExtractor{Header: "X-Gateway-Token", Scheme: "Bearer"}
+
+	t.Run("decodes the claims", func(t *testing.T) {
+		req := envoytest.NewCheckRequest().Header("x-gateway-token", "Bearer "+unsignedJWT(`{"tenant":"acme","tier":2}`)).Build()
+		got, err := extractor.ExtractRequestAttributes(req)
+		if err != nil {
+			t.Fatalf("ExtractRequestAttributes failed: %v", err)
+		}
+		if want := map[string]any{"tenant": "acme", "tier": float64(2)}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("missing header or other scheme extracts nothing", func(t *testing.T) {
+		for _, req := range []*envoytest.CheckRequestBuilder{
+			envoytest.NewCheckRequest(),
+			envoytest.NewCheckRequest().Header("x-gateway-token", "Basic dXNlcjpwYXNz"),
+		} {
+			if got, err := extractor.ExtractRequestAttributes(req.Build()); got != nil || err != nil {
+				t.Errorf("expected nothing, got %v, %v", got, err)
+			}
+		}
+	})
+
+	t.Run("malformed token fails", func(t *testing.T) {
+		req := envoytest.NewCheckRequest().Header("x-gateway-token", "Bearer not-a-jwt").Build()
+		if _, err := extractor.ExtractRequestAttributes(req); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestFilterMetadataExtractor(t *testing.T) {
+	metadata, err := structpb.NewStruct(map[string]any{"payload": map[string]any{"sub": "alice"}})
+	if err != nil {
+		t.Fatalf("NewStruct failed: %v", err)
+	}
+	req := envoytest.NewCheckRequest().Build()
+	req.Attributes.MetadataContext = &corev3.Metadata{
+		FilterMetadata: map[string]*structpb.Struct{"envoy.filters.http.jwt_authn": metadata},
+	}
+
+	got, err := FilterMetadataExtractor{Namespace: "envoy.filters.http.jwt_authn"}.ExtractRequestAttributes(req)
+	if err != nil {
+		t.Fatalf("ExtractRequestAttributes failed: %v", err)
+	}
+	if want := map[string]any{"payload": map[string]any{"sub": "alice"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got, _ := (FilterMetadataExtractor{Namespace: "other"}).ExtractRequestAttributes(req); got != nil {
+		t.Errorf("expected nothing for another namespace, got %v", got)
+	}
+}
+
+func TestQueryParametersExtractor(t *testing.T) {
+	req := envoytest.NewCheckRequest().Path("/api/orders?tenant=acme&tag=a&tag=b&debug=1").Build()
+
+	got, err := QueryParametersExtractor{Names: []string{"tenant", "tag"}}.ExtractRequestAttributes(req)
+	if err != nil {
+		t.Fatalf("ExtractRequestAttributes failed: %v", err)
+	}
+	if want := map[string]any{"tenant": "acme", "tag": []any{"a", "b"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got, _ := (QueryParametersExtractor{}).ExtractRequestAttributes(envoytest.NewCheckRequest().Path("/api").Build()); got != nil {
+		t.Errorf("expected nothing without a query, got %v", got)
+	}
+}
+
+func TestAuthzServer_Check_RequestAttributeSources(t *testing.T) {
+	ctx := context.Background()
+
+	trustStore := trust.NewStubStore()
+	trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, &sizedIssuer{size: 32})
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	newServer := func(observer service.AuthzCheckObserver) *AuthzServer {
+		return NewAuthzServer(trustStore, tokenService, nil, observer, WithRequestAttributeSources(
+			RequestAttributeSource{Key: "gateway", Extractor: JWTClaimsExtractor{Header: "x-gateway-token"}},
+			RequestAttributeSource{Key: "query", Extractor: QueryParametersExtractor{}},
+		))
+	}
+
+	t.Run("attributes are recorded under their keys", func(t *testing.T) {
+		fakeObs := service.NewFakeObserver(t)
+		req := envoytest.NewCheckRequest().Path("/api?tenant=acme").Bearer("valid-token").
+			Header("x-gateway-token", unsignedJWT(`{"org":"42"}`)).Build()
+		resp, err := newServer(fakeObs).Check(ctx, req)
+		if err != nil || resp.GetOkResponse() == nil {
+			t.Fatalf("expected the check to be allowed, got %v, %v", resp, err)
+		}
+
+		args := fakeObs.AssertSingleProbe("AuthzCheckStarted", nil).CallArgs("RequestAttributesParsed")
+		attrs := args[0][0].(*request.RequestAttributes)
+		if !reflect.DeepEqual(attrs.Additional["gateway"], map[string]any{"org": "42"}) {
+			t.Errorf("expected the gateway claims, got %v", attrs.Additional["gateway"])
+		}
+		if !reflect.DeepEqual(attrs.Additional["query"], map[string]any{"tenant": "acme"}) {
+			t.Errorf("expected the query parameters, got %v", attrs.Additional["query"])
+		}
+	})
+
+	t.Run("extraction failure denies the check", func(t *testing.T) {
+		fakeObs := service.NewFakeObserver(t)
+		req := envoytest.NewCheckRequest().Bearer("valid-token").Header("x-gateway-token", "garbage").Build()
+		resp, err := newServer(fakeObs).Check(ctx, req)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if resp.GetDeniedResponse() == nil || resp.Status.Code != int32(codes.InvalidArgument) {
+			t.Fatalf("expected an invalid request denial, got %v", resp)
+		}
+		fakeObs.AssertSingleProbe("AuthzCheckStarted", nil).AssertProbeSequence(
+			service.ProbeCall("RequestAttributeExtractionFailed", service.ErrorContaining("x-gateway-token is not a JWT")),
+			"End",
+		)
+	})
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/project-kessel/parsec/internal/request"
+)
+
+// CallerAttributesKey is the RequestAttributes.Additional key holding the
+// attributes of the Exchange caller, derived by parsec from gRPC metadata and
+// peer info rather than supplied by the client
+//
+// The value is a map with string entries (empty when unknown):
+//   - "ip_address": the client address (see clientAddress)
+//   - "user_agent": the HTTP user agent forwarded by the gateway, else the gRPC user agent
+//   - "authority": the x-forwarded-host forwarded by the gateway, else :authority
+//   - "method": the full gRPC method name
+//
+// The caller attributes are for trust decisions; claim mappers do not copy
+// them into issued tokens.
+const CallerAttributesKey = request.CallerAttributesKey
+
+// loopbackProxies are always trusted to report the client address, because
+// the HTTP gateway calls the gRPC server over loopback
+var loopbackProxies = []netip.Prefix{
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("::1/128"),
+}
+
+// traceHeaders are the trace context and baggage headers copied from Exchange
+// metadata into the request attributes, so issuers can correlate tokens with
+// the trace and mappers can read the baggage (see
+// request.RequestAttributes.Baggage)
+var traceHeaders = []string{"traceparent", "tracestate", "x-b3-traceid", request.BaggageHeader}
+
+// traceHeaderMatcher forwards trace context and baggage headers through
+// grpc-gateway in addition to the headers it forwards by default
+func traceHeaderMatcher(key string) (string, bool) {
+	if slices.Contains(traceHeaders, strings.ToLower(key)) {
+		return strings.ToLower(key), true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// callerAttributes derives the Exchange caller's attributes from the gRPC context
+func callerAttributes(ctx context.Context, trustedProxies []netip.Prefix) map[string]any {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var peerAddr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		peerAddr = p.Addr.String()
+	}
+
+	// grpc-gateway forwards the HTTP user agent and host under its own keys
+	userAgent := firstMetadata(md, "grpcgateway-user-agent", "user-agent")
+	authority := firstMetadata(md, "x-forwarded-host", ":authority")
+
+	method, _ := grpc.Method(ctx)
+
+	return map[string]any{
+		"ip_address": clientAddress(peerAddr, md.Get("x-forwarded-for"), trustedProxies),
+		"user_agent": userAgent,
+		"authority":  authority,
+		"method":     method,
+	}
+}
+
+// clientAddress returns the address of the client that sent the request
+//
+// The peer address is used unless the peer is a trusted proxy (loopback or one
+// of trustedProxies). Then x-forwarded-for is walked from the right, skipping
+// trusted proxies, and the first untrusted hop is the client: entries to its
+// left were supplied by the client and could be anything.
+func clientAddress(peerAddr string, forwardedFor []string, trustedProxies []netip.Prefix) string {
+	if host, _, err := net.SplitHostPort(peerAddr); err == nil {
+		peerAddr = host
+	}
+	client := peerAddr
+
+	var hops []string
+	for _, value := range forwardedFor {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+
+	for i := len(hops) - 1; i >= 0 && isTrustedProxy(client, trustedProxies); i-- {
+		if hops[i] == "" {
+			break
+		}
+		client = hops[i]
+	}
+	return client
+}
+
+// isTrustedProxy reports whether addr is loopback or in trustedProxies
+func isTrustedProxy(addr string, trustedProxies []netip.Prefix) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, prefix := range slices.Concat(loopbackProxies, trustedProxies) {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyCallerAttributes records the caller's attributes in attrs. The
+// server-derived client IP, user agent, TLS session and trace context headers
+// replace any the request_context supplied, so filters and issuers never see
+// client claims where parsec knows better.
+func applyCallerAttributes(ctx context.Context, attrs *request.RequestAttributes, trustedProxies []netip.Prefix) {
+	caller := callerAttributes(ctx, trustedProxies)
+	attrs.Additional[CallerAttributesKey] = caller
+
+	if ip := caller["ip_address"].(string); ip != "" {
+		attrs.IPAddress = ip
+	}
+	if userAgent := caller["user_agent"].(string); userAgent != "" {
+		attrs.UserAgent = userAgent
+	}
+
+	attrs.TLS = peerTLS(ctx)
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, key := range traceHeaders {
+		value := firstMetadata(md, key)
+		if value == "" {
+			continue
+		}
+		if attrs.Headers == nil {
+			attrs.Headers = make(map[string]string)
+		}
+		attrs.Headers[key] = value
+	}
+}
+
+// peerTLS returns the TLS session of a gRPC caller connected over TLS, else nil
+// Callers of the HTTP gateway reach the gRPC server over loopback and are
+// reported without TLS.
+func peerTLS(ctx context.Context) *request.TLSInfo {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+	return &request.TLSInfo{
+		SNI:         info.State.ServerName,
+		Version:     tlsVersionName(info.State.Version),
+		CipherSuite: tls.CipherSuiteName(info.State.CipherSuite),
+	}
+}
+
+// tlsVersionName names a TLS version the way Envoy's %DOWNSTREAM_TLS_VERSION% does
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLSv1"
+	case tls.VersionTLS11:
+		return "TLSv1.1"
+	case tls.VersionTLS12:
+		return "TLSv1.2"
+	case tls.VersionTLS13:
+		return "TLSv1.3"
+	default:
+		return tls.VersionName(version)
+	}
+}
+
+// firstMetadata returns the first value of the first key present in md
+func firstMetadata(md metadata.MD, keys ...string) string {
+	for _, key := range keys {
+		if values := md.Get(key); len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	return ""
+}
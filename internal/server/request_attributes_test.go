@@ -0,0 +1,242 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	parsecv1 "github.com/project-kessel/parsec/api/gen/parsec/v1"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/project-kessel/parsec/internal/issuer"
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestCallerAttributes(t *testing.T) {
+	peerCtx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 52000},
+	})
+
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")}
+
+	tests := []struct {
+		name    string
+		peer    string
+		md      metadata.MD
+		trusted []netip.Prefix
+		want    map[string]any
+	}{
+		{
+			name: "direct gRPC call uses peer address",
+			peer: "10.0.0.5",
+			md:   metadata.Pairs("user-agent", "grpc-go/1.70.0", ":authority", "parsec:9090"),
+			want: map[string]any{"ip_address": "10.0.0.5", "user_agent": "grpc-go/1.70.0", "authority": "parsec:9090", "method": ""},
+		},
+		{
+			name: "x-forwarded-for from an untrusted peer is ignored",
+			peer: "10.0.0.5",
+			md:   metadata.Pairs("x-forwarded-for", "203.0.113.7"),
+			want: map[string]any{"ip_address": "10.0.0.5"},
+		},
+		{
+			name: "gateway call uses forwarded values",
+			peer: "127.0.0.1",
+			md: metadata.Pairs(
+				"x-forwarded-for", "203.0.113.7",
+				"x-forwarded-host", "auth.example.com",
+				"grpcgateway-user-agent", "curl/8.5.0",
+				"user-agent", "grpc-go/1.70.0",
+				":authority", "localhost:9090",
+			),
+			want: map[string]any{"ip_address": "203.0.113.7", "user_agent": "curl/8.5.0", "authority": "auth.example.com", "method": ""},
+		},
+		{
+			name:    "trusted proxies are skipped from the right",
+			peer:    "127.0.0.1",
+			md:      metadata.Pairs("x-forwarded-for", "198.51.100.9, 203.0.113.7, 10.0.0.1"),
+			trusted: trusted,
+			want:    map[string]any{"ip_address": "203.0.113.7"},
+		},
+		{
+			name: "spoofed leftmost entries are not the client",
+			peer: "127.0.0.1",
+			md:   metadata.Pairs("x-forwarded-for", "10.0.0.9, 203.0.113.7"),
+			want: map[string]any{"ip_address": "203.0.113.7"},
+		},
+		{
+			name:    "all hops trusted uses the leftmost",
+			peer:    "10.0.0.5",
+			md:      metadata.Pairs("x-forwarded-for", "10.0.0.2, 10.0.0.1"),
+			trusted: trusted,
+			want:    map[string]any{"ip_address": "10.0.0.2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peer.NewContext(context.Background(), &peer.Peer{
+				Addr: &net.TCPAddr{IP: net.ParseIP(tt.peer), Port: 52000},
+			})
+			got := callerAttributes(metadata.NewIncomingContext(ctx, tt.md), tt.trusted)
+			for key, want := range tt.want {
+				if got[key] != want {
+					t.Errorf("%s = %q, want %q", key, got[key], want)
+				}
+			}
+		})
+	}
+
+	t.Run("server-derived values replace request_context values", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(peerCtx, metadata.Pairs("user-agent", "grpc-go/1.70.0"))
+		attrs := &request.RequestAttributes{IPAddress: "198.51.100.1", UserAgent: "spoofed", Additional: map[string]any{}}
+		applyCallerAttributes(ctx, attrs, nil)
+
+		if attrs.IPAddress != "10.0.0.5" {
+			t.Errorf("expected peer IP to replace request_context IP, got %s", attrs.IPAddress)
+		}
+		if attrs.UserAgent != "grpc-go/1.70.0" {
+			t.Errorf("expected caller user agent to replace request_context user agent, got %s", attrs.UserAgent)
+		}
+		caller := attrs.Additional[CallerAttributesKey].(map[string]any)
+		if caller["ip_address"] != "10.0.0.5" {
+			t.Errorf("expected caller IP 10.0.0.5, got %v", caller["ip_address"])
+		}
+	})
+
+	t.Run("trace headers from metadata replace request_context headers", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(peerCtx, metadata.Pairs(
+			"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"baggage", "tenant=acme",
+		))
+		attrs := &request.RequestAttributes{
+			Headers: map[string]string{
+				"traceparent":  "00-11111111111111111111111111111111-00f067aa0ba902b7-01",
+				"x-b3-traceid": "80f198ee56343ba864fe8b2a57d3eff7",
+			},
+			Additional: map[string]any{},
+		}
+		applyCallerAttributes(ctx, attrs, nil)
+
+		if attrs.Headers["traceparent"] != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+			t.Errorf("expected metadata traceparent to win, got %q", attrs.Headers["traceparent"])
+		}
+		if attrs.Headers["x-b3-traceid"] != "80f198ee56343ba864fe8b2a57d3eff7" {
+			t.Errorf("expected request_context header without metadata counterpart to be kept, got %q", attrs.Headers["x-b3-traceid"])
+		}
+		if attrs.Baggage()["tenant"] != "acme" {
+			t.Errorf("expected baggage from metadata, got %v", attrs.Baggage())
+		}
+	})
+
+	t.Run("TLS session comes from the peer", func(t *testing.T) {
+		tlsCtx := peer.NewContext(context.Background(), &peer.Peer{
+			Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 52000},
+			AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+				Version:     tls.VersionTLS13,
+				CipherSuite: tls.TLS_AES_128_GCM_SHA256,
+				ServerName:  "parsec.example.com",
+			}},
+		})
+		attrs := &request.RequestAttributes{Additional: map[string]any{}}
+		applyCallerAttributes(tlsCtx, attrs, nil)
+
+		want := request.TLSInfo{SNI: "parsec.example.com", Version: "TLSv1.3", CipherSuite: "TLS_AES_128_GCM_SHA256"}
+		if attrs.TLS == nil || *attrs.TLS != want {
+			t.Errorf("expected TLS %+v, got %+v", want, attrs.TLS)
+		}
+
+		attrs = &request.RequestAttributes{TLS: &request.TLSInfo{Version: "spoofed"}, Additional: map[string]any{}}
+		applyCallerAttributes(peerCtx, attrs, nil)
+		if attrs.TLS != nil {
+			t.Errorf("expected no TLS session for a plaintext peer, got %+v", attrs.TLS)
+		}
+	})
+}
+
+func TestTraceHeaderMatcher(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    string
+		forward bool
+	}{
+		{header: "Traceparent", want: "traceparent", forward: true},
+		{header: "X-B3-TraceId", want: "x-b3-traceid", forward: true},
+		{header: "Baggage", want: "baggage", forward: true},
+		{header: "Authorization", want: "grpcgateway-Authorization", forward: true},
+		{header: "X-Custom", forward: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, ok := traceHeaderMatcher(tt.header)
+			if ok != tt.forward {
+				t.Fatalf("forward = %v, want %v", ok, tt.forward)
+			}
+			if ok && got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExchangeServer_CallerAttributesInTrustFilter(t *testing.T) {
+	// Only callers forwarded from the internal network may use the validator
+	store, err := trust.NewFilteredStore(
+		trust.WithCELFilter(`request.additional.caller.ip_address.startsWith("203.0.113.")`),
+	)
+	if err != nil {
+		t.Fatalf("failed to create filtered store: %v", err)
+	}
+	validator := trust.NewStubValidator(trust.CredentialTypeBearer)
+	validator.WithResult(&trust.Result{Subject: "user", Issuer: "https://idp.example.com", TrustDomain: "external"})
+	store.AddValidator("idp", validator)
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL:                 "https://parsec.test",
+		TTL:                       5 * time.Minute,
+		TransactionContextMappers: []service.ClaimMapper{service.NewPassthroughSubjectMapper()},
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+	exchangeServer := NewExchangeServer(store, tokenService, NewStubClaimsFilterRegistry(), nil)
+
+	req := &parsecv1.ExchangeRequest{
+		GrantType:        "urn:ietf:params:oauth:grant-type:token-exchange",
+		SubjectToken:     "token",
+		SubjectTokenType: "urn:ietf:params:oauth:token-type:access_token",
+	}
+
+	// A client-supplied request_context cannot stand in for the caller
+	spoofed := &parsecv1.ExchangeRequest{
+		GrantType:        req.GrantType,
+		SubjectToken:     req.SubjectToken,
+		SubjectTokenType: req.SubjectTokenType,
+		RequestContext:   "eyJjYWxsZXIiOnsiaXBfYWRkcmVzcyI6IjIwMy4wLjExMy45In19", // {"caller":{"ip_address":"203.0.113.9"}}
+	}
+	if _, err := exchangeServer.Exchange(context.Background(), spoofed); err == nil {
+		t.Error("expected spoofed caller in request_context to be ignored")
+	}
+
+	// Forwarded by the HTTP gateway over loopback
+	gatewayCtx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 52000},
+	})
+	ctx := metadata.NewIncomingContext(gatewayCtx, metadata.Pairs("x-forwarded-for", "203.0.113.7"))
+	if _, err := exchangeServer.Exchange(ctx, req); err != nil {
+		t.Errorf("expected forwarded caller to be allowed, got %v", err)
+	}
+
+	// The same header straight from an untrusted peer is ignored
+	directCtx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("198.51.100.4"), Port: 52000},
+	})
+	ctx = metadata.NewIncomingContext(directCtx, metadata.Pairs("x-forwarded-for", "203.0.113.7"))
+	if _, err := exchangeServer.Exchange(ctx, req); err == nil {
+		t.Error("expected x-forwarded-for from an untrusted peer to be ignored")
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/trust"
+	"github.com/project-kessel/parsec/internal/trustdomain"
+)
+
+// RequestContextSchemaRegistry selects the schema that filtered request_context
+// claims must satisfy
+type RequestContextSchemaRegistry interface {
+	// GetSchema returns the schema for the actor
+	// Returns nil if the actor's request_context is not subject to a schema
+	//
+	// Selection uses only what parsec has verified about the caller; claims in
+	// the request_context are the client's to choose and must be constrained by
+	// the schema itself.
+	GetSchema(actor *trust.Result) *claims.Schema
+}
+
+// RequestContextSchemaRule selects a schema for matching actors
+type RequestContextSchemaRule struct {
+	// TrustDomain matches the actor's trust domain, exactly or by a pattern such
+	// as "*.example.com" (see trustdomain.Match; empty matches any)
+	TrustDomain string
+
+	// Subject matches the actor's subject exactly, or by prefix when it ends in "*"
+	// (empty matches any)
+	Subject string
+
+	// Schema is enforced on request_context claims matching this rule
+	Schema *claims.Schema
+}
+
+// matches reports whether the rule applies to the actor
+func (r *RequestContextSchemaRule) matches(actor *trust.Result) bool {
+	if !trustdomain.Match(r.TrustDomain, actor.TrustDomain) {
+		return false
+	}
+	return matchPattern(r.Subject, actor.Subject)
+}
+
+// matchPattern matches a value exactly, or by prefix when the pattern ends in "*"
+// An empty pattern matches any value
+func matchPattern(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(value, prefix)
+	}
+	return pattern == value
+}
+
+// RuleRequestContextSchemaRegistry selects a schema by actor.
+// Rules are evaluated in order and the first match wins. Requests matching no rule
+// are not subject to a schema.
+type RuleRequestContextSchemaRegistry struct {
+	rules []RequestContextSchemaRule
+}
+
+// NewRuleRequestContextSchemaRegistry creates a rule-based schema registry
+func NewRuleRequestContextSchemaRegistry(rules []RequestContextSchemaRule) *RuleRequestContextSchemaRegistry {
+	return &RuleRequestContextSchemaRegistry{
+		rules: rules,
+	}
+}
+
+// GetSchema implements RequestContextSchemaRegistry
+func (r *RuleRequestContextSchemaRegistry) GetSchema(actor *trust.Result) *claims.Schema {
+	if actor == nil {
+		actor = trust.AnonymousResult()
+	}
+	for i := range r.rules {
+		if r.rules[i].matches(actor) {
+			return r.rules[i].Schema
+		}
+	}
+	return nil
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
@@ -13,19 +16,58 @@ import (
 	"google.golang.org/grpc/reflection"
 
 	parsecv1 "github.com/project-kessel/parsec/api/gen/parsec/v1"
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/trust"
 )
 
 // Server manages the gRPC and HTTP servers
+//
+// The listeners are bound to the ports given to New for the server's lifetime;
+// the handlers behind them can be replaced while serving with SetHandlers.
+// Distributed cache peers run from Start to Stop, or while they are the
+// current handlers. Peer requests are served on their own port, apart from
+// the public HTTP port.
 type Server struct {
-	grpcServer *grpc.Server
-	httpServer *http.Server
+	grpcServer  *grpc.Server
+	httpServer  *http.Server
+	cacheServer *http.Server
 
-	grpcPort int
-	httpPort int
+	grpcPort  int
+	httpPort  int
+	cachePort int
 
+	handlers atomic.Pointer[handlers]
+
+	// lifecycleMu orders starting and stopping cache peers with replacing them
+	lifecycleMu sync.Mutex
+	started     bool
+}
+
+// handlers are the request handlers built from one configuration
+type handlers struct {
 	authzServer    *AuthzServer
 	exchangeServer *ExchangeServer
 	jwksServer     *JWKSServer
+
+	introspectionServer *IntrospectionServer
+	adminServer         *AdminServer
+
+	warmup  *trust.Warmup
+	signers *keys.SignerRegistry
+
+	distributedCache     CachePeers
+	distributedCachePath string
+
+	metrics     http.Handler
+	metricsPath string
+}
+
+// CachePeers serves data source cache peer requests and keeps the list of
+// peers current while started
+type CachePeers interface {
+	http.Handler
+	Start(ctx context.Context) error
+	Stop()
 }
 
 // Config contains server configuration
@@ -33,31 +75,102 @@ type Config struct {
 	GRPCPort int
 	HTTPPort int
 
+	// DistributedCachePort serves cache peer requests (optional; no peer
+	// requests are served when 0)
+	DistributedCachePort int
+
 	AuthzServer    *AuthzServer
 	ExchangeServer *ExchangeServer
 	JWKSServer     *JWKSServer
+
+	// IntrospectionServer serves /v1/introspect over HTTP (optional)
+	IntrospectionServer *IntrospectionServer
+
+	// AdminServer serves /v1/admin/* over HTTP (optional)
+	AdminServer *AdminServer
+
+	// Warmup and the health of Signers are reported at /readyz over HTTP
+	// (optional; ready when nil)
+	Warmup  *trust.Warmup
+	Signers *keys.SignerRegistry
+
+	// DistributedCache serves data source cache peer requests under
+	// DistributedCachePath on DistributedCachePort (optional)
+	DistributedCache     CachePeers
+	DistributedCachePath string
+
+	// Metrics serves metrics for scraping at MetricsPath over HTTP (optional)
+	Metrics     http.Handler
+	MetricsPath string
 }
 
 // New creates a new server with the given configuration
 func New(cfg Config) *Server {
-	return &Server{
-		grpcPort:       cfg.GRPCPort,
-		httpPort:       cfg.HTTPPort,
+	s := &Server{
+		grpcPort:  cfg.GRPCPort,
+		httpPort:  cfg.HTTPPort,
+		cachePort: cfg.DistributedCachePort,
+	}
+	s.handlers.Store(newHandlers(cfg))
+	return s
+}
+
+func newHandlers(cfg Config) *handlers {
+	return &handlers{
 		authzServer:    cfg.AuthzServer,
 		exchangeServer: cfg.ExchangeServer,
 		jwksServer:     cfg.JWKSServer,
+
+		introspectionServer: cfg.IntrospectionServer,
+		adminServer:         cfg.AdminServer,
+
+		warmup:  cfg.Warmup,
+		signers: cfg.Signers,
+
+		distributedCache:     cfg.DistributedCache,
+		distributedCachePath: cfg.DistributedCachePath,
+
+		metrics:     cfg.Metrics,
+		metricsPath: cfg.MetricsPath,
 	}
 }
 
+// SetHandlers replaces the handlers of a running server. Requests already in
+// progress finish on the handlers they started with; the listeners keep
+// serving throughout. The ports cannot be changed without a restart.
+//
+// On a started server the new cache peers are started before they replace the
+// previous ones, which are then stopped.
+func (s *Server) SetHandlers(cfg Config) error {
+	if cfg.GRPCPort != s.grpcPort || cfg.HTTPPort != s.httpPort || cfg.DistributedCachePort != s.cachePort {
+		return fmt.Errorf("changing server ports requires a restart")
+	}
+
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+
+	next := newHandlers(cfg)
+	if s.started && next.distributedCache != nil {
+		if err := next.distributedCache.Start(context.Background()); err != nil {
+			return fmt.Errorf("failed to start distributed cache peers: %w", err)
+		}
+	}
+	previous := s.handlers.Swap(next)
+	if s.started && previous.distributedCache != nil && previous.distributedCache != next.distributedCache {
+		previous.distributedCache.Stop()
+	}
+	return nil
+}
+
 // Start starts both the gRPC and HTTP servers
 func (s *Server) Start(ctx context.Context) error {
 	// Create gRPC server
 	s.grpcServer = grpc.NewServer()
 
-	// Register services
-	authv3.RegisterAuthorizationServer(s.grpcServer, s.authzServer)
-	parsecv1.RegisterTokenExchangeServiceServer(s.grpcServer, s.exchangeServer)
-	parsecv1.RegisterJWKSServiceServer(s.grpcServer, s.jwksServer)
+	// Register services; each call is dispatched to the current handlers
+	authv3.RegisterAuthorizationServer(s.grpcServer, authzService{s})
+	parsecv1.RegisterTokenExchangeServiceServer(s.grpcServer, exchangeService{server: s})
+	parsecv1.RegisterJWKSServiceServer(s.grpcServer, jwksService{server: s})
 
 	// Register reflection service for grpcurl and other tools
 	reflection.Register(s.grpcServer)
@@ -77,8 +190,12 @@ func (s *Server) Start(ctx context.Context) error {
 
 	// Create HTTP server with grpc-gateway
 	// Register custom marshaler for application/x-www-form-urlencoded (RFC 8693 compliance)
+	// Errors are written as OAuth 2.0 error responses
+	// Trace context headers are forwarded so issuers can derive "txn" from them
 	mux := runtime.NewServeMux(
 		runtime.WithMarshalerOption("application/x-www-form-urlencoded", NewFormMarshaler()),
+		runtime.WithErrorHandler(oauthErrorHandler),
+		runtime.WithIncomingHeaderMatcher(traceHeaderMatcher),
 	)
 	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
 
@@ -91,10 +208,58 @@ func (s *Server) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to register JWKS handler: %w", err)
 	}
 
+	// Introspection is plain HTTP (RFC 7662), not transcoded from gRPC
+	if err := mux.HandlePath(http.MethodPost, "/v1/introspect", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		introspectionServer := s.handlers.Load().introspectionServer
+		if introspectionServer == nil {
+			http.NotFound(w, r)
+			return
+		}
+		introspectionServer.ServeHTTP(w, r)
+	}); err != nil {
+		return fmt.Errorf("failed to register introspection handler: %w", err)
+	}
+
+	serveAdmin := func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		adminServer := s.handlers.Load().adminServer
+		if adminServer == nil {
+			http.NotFound(w, r)
+			return
+		}
+		adminServer.ServeHTTP(w, r)
+	}
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, cacheInvalidatePath},
+		{http.MethodGet, adminKeysPath},
+		{http.MethodPost, adminKeysPath + "/{signer}/{action}"},
+	} {
+		if err := mux.HandlePath(route.method, route.path, serveAdmin); err != nil {
+			return fmt.Errorf("failed to register admin handler: %w", err)
+		}
+	}
+
+	if err := mux.HandlePath(http.MethodGet, readinessPath, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		h := s.handlers.Load()
+		serveReadiness(r.Context(), w, h.warmup, h.signers)
+	}); err != nil {
+		return fmt.Errorf("failed to register readiness handler: %w", err)
+	}
+
+	// The metrics scrape is routed outside the gateway, as its path may change
+	// on reload
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := s.handlers.Load()
+		if h.metrics != nil && r.Method == http.MethodGet && r.URL.Path == h.metricsPath {
+			h.metrics.ServeHTTP(w, r)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+
 	// Start HTTP server
 	s.httpServer = &http.Server{
 		Addr:    fmt.Sprintf(":%d", s.httpPort),
-		Handler: mux,
+		Handler: handler,
 	}
 
 	go func() {
@@ -104,18 +269,102 @@ func (s *Server) Start(ctx context.Context) error {
 		}
 	}()
 
+	if s.cachePort != 0 {
+		s.cacheServer = &http.Server{
+			Addr:    fmt.Sprintf(":%d", s.cachePort),
+			Handler: s.cachePeersHandler(),
+		}
+		go func() {
+			fmt.Printf("Distributed cache peers listening on :%d\n", s.cachePort)
+			if err := s.cacheServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fmt.Printf("Distributed cache server error: %v\n", err)
+			}
+		}()
+	}
+
+	// Discover cache peers once peer requests can be served
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+	s.started = true
+	if peers := s.handlers.Load().distributedCache; peers != nil {
+		if err := peers.Start(ctx); err != nil {
+			return fmt.Errorf("failed to start distributed cache peers: %w", err)
+		}
+	}
+
 	return nil
 }
 
-// Stop gracefully stops both servers
+// Stop gracefully stops both servers and the cache peers
 func (s *Server) Stop(ctx context.Context) error {
+	s.lifecycleMu.Lock()
+	if s.started {
+		if peers := s.handlers.Load().distributedCache; peers != nil {
+			peers.Stop()
+		}
+		s.started = false
+	}
+	s.lifecycleMu.Unlock()
+
 	if s.grpcServer != nil {
 		s.grpcServer.GracefulStop()
 	}
 
+	if s.cacheServer != nil {
+		if err := s.cacheServer.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+
 	if s.httpServer != nil {
 		return s.httpServer.Shutdown(ctx)
 	}
 
 	return nil
 }
+
+// cachePeersHandler serves the cache peer port: peer requests, addressed by
+// URL path prefix, and cache invalidations forwarded by peers, which the
+// admin server authorizes as on the HTTP port
+func (s *Server) cachePeersHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := s.handlers.Load()
+		switch {
+		case h.distributedCache != nil && strings.HasPrefix(r.URL.Path, h.distributedCachePath):
+			h.distributedCache.ServeHTTP(w, r)
+		case h.adminServer != nil && r.Method == http.MethodPost && r.URL.Path == cacheInvalidatePath:
+			h.adminServer.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// authzService dispatches ext_authz checks to the server's current handlers
+type authzService struct {
+	server *Server
+}
+
+func (a authzService) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	return a.server.handlers.Load().authzServer.Check(ctx, req)
+}
+
+// exchangeService dispatches token exchanges to the server's current handlers
+type exchangeService struct {
+	parsecv1.UnimplementedTokenExchangeServiceServer
+	server *Server
+}
+
+func (e exchangeService) Exchange(ctx context.Context, req *parsecv1.ExchangeRequest) (*parsecv1.ExchangeResponse, error) {
+	return e.server.handlers.Load().exchangeServer.Exchange(ctx, req)
+}
+
+// jwksService dispatches JWKS requests to the server's current handlers
+type jwksService struct {
+	parsecv1.UnimplementedJWKSServiceServer
+	server *Server
+}
+
+func (j jwksService) GetJWKS(ctx context.Context, req *parsecv1.GetJWKSRequest) (*parsecv1.GetJWKSResponse, error) {
+	return j.server.handlers.Load().jwksServer.GetJWKS(ctx, req)
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// SkipPath matches request paths the AuthzServer allows without validation or
+// token issuance, such as health checks and public assets. Set one of Exact,
+// Prefix or Regex. Paths are matched without their query string, after they
+// are decoded and cleaned; paths with dot-segments or encoded slashes are
+// never skipped, since the upstream may resolve them to another path.
+type SkipPath struct {
+	// Exact matches the path exactly
+	Exact string
+
+	// Prefix matches the path it names and the paths below it: "/health"
+	// matches "/health" and "/health/live", not "/healthz"
+	Prefix string
+
+	// Regex matches paths it matches; anchor it to match the whole path
+	Regex *regexp.Regexp
+}
+
+// Matches reports whether the path, without its query string, is skipped
+func (p SkipPath) Matches(rawPath string) bool {
+	cleaned, ok := normalizeSkipPath(rawPath)
+	if !ok {
+		return false
+	}
+	switch {
+	case p.Exact != "":
+		return cleaned == p.Exact
+	case p.Prefix != "":
+		dir := strings.TrimSuffix(p.Prefix, "/")
+		return cleaned == dir || strings.HasPrefix(cleaned, dir+"/")
+	case p.Regex != nil:
+		return p.Regex.MatchString(cleaned)
+	default:
+		return false
+	}
+}
+
+// normalizeSkipPath decodes and cleans a request path for matching, keeping a
+// trailing slash. It fails for paths that are not absolute, do not decode, or
+// hold dot-segments or encoded slashes.
+func normalizeSkipPath(rawPath string) (string, bool) {
+	rawPath, _, _ = strings.Cut(rawPath, "?")
+	lower := strings.ToLower(rawPath)
+	if strings.Contains(lower, "%2f") || strings.Contains(lower, "%5c") {
+		return "", false
+	}
+	decoded, err := url.PathUnescape(rawPath)
+	if err != nil || !strings.HasPrefix(decoded, "/") || strings.Contains(decoded, "\\") {
+		return "", false
+	}
+	for _, segment := range strings.Split(decoded, "/") {
+		if segment == "." || segment == ".." {
+			return "", false
+		}
+	}
+	cleaned := path.Clean(decoded)
+	if strings.HasSuffix(decoded, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned, true
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/project-kessel/parsec/internal/envoytest"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestSkipPath_Matches(t *testing.T) {
+	tests := []struct {
+		name string
+		skip SkipPath
+		path string
+		want bool
+	}{
+		{"exact", SkipPath{Exact: "/healthz"}, "/healthz", true},
+		{"exact ignores query", SkipPath{Exact: "/healthz"}, "/healthz?verbose=1", true},
+		{"exact is not a prefix", SkipPath{Exact: "/healthz"}, "/healthz/deep", false},
+		{"prefix", SkipPath{Prefix: "/static/"}, "/static/app.js", true},
+		{"prefix mismatch", SkipPath{Prefix: "/static/"}, "/api/static/", false},
+		{"regex", SkipPath{Regex: regexp.MustCompile(`^/public/[a-z]+\.css$`)}, "/public/site.css", true},
+		{"regex mismatch", SkipPath{Regex: regexp.MustCompile(`^/public/[a-z]+\.css$`)}, "/public/site.js", false},
+		{"empty matches nothing", SkipPath{}, "/", false},
+		{"prefix on a segment boundary", SkipPath{Prefix: "/health"}, "/health/live", true},
+		{"prefix names its own path", SkipPath{Prefix: "/health"}, "/health", true},
+		{"prefix does not match a longer segment", SkipPath{Prefix: "/health"}, "/healthz-internal", false},
+		{"dot-segments are not skipped", SkipPath{Prefix: "/public/"}, "/public/../admin", false},
+		{"encoded dot-segments are not skipped", SkipPath{Prefix: "/public/"}, "/public/%2e%2e/admin", false},
+		{"encoded slashes are not skipped", SkipPath{Prefix: "/public/"}, "/public/..%2Fadmin", false},
+		{"encoded backslashes are not skipped", SkipPath{Prefix: "/public/"}, "/public/..%5cadmin", false},
+		{"decoded before matching", SkipPath{Exact: "/healthz"}, "/health%7a", true},
+		{"cleaned before matching", SkipPath{Prefix: "/static/"}, "//static//app.js", true},
+		{"invalid encoding is not skipped", SkipPath{Prefix: "/static/"}, "/static/%zz", false},
+		{"regex sees the decoded path", SkipPath{Regex: regexp.MustCompile(`^/public/[a-z]+\.css$`)}, "/public/%2e%2e/site.css", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.skip.Matches(tt.path); got != tt.want {
+				t.Errorf("Matches(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthzServer_Check_SkipPaths(t *testing.T) {
+	ctx := context.Background()
+
+	validator := &countingValidator{StubValidator: trust.NewStubValidator(trust.CredentialTypeBearer)}
+	trustStore := trust.NewStubStore()
+	trustStore.AddValidator(validator)
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, &sizedIssuer{size: 32})
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	fakeObs := service.NewFakeObserver(t)
+	authzServer := NewAuthzServer(trustStore, tokenService, nil, fakeObs,
+		WithSkipPaths(SkipPath{Exact: "/healthz"}, SkipPath{Prefix: "/static/"}))
+
+	t.Run("skipped path is allowed without a credential", func(t *testing.T) {
+		resp, err := authzServer.Check(ctx, envoytest.NewCheckRequest().Path("/static/app.js").Build())
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		okResp := resp.GetOkResponse()
+		if resp.Status.Code != int32(codes.OK) || okResp == nil || len(okResp.Headers) != 0 {
+			t.Fatalf("expected an allowed check without tokens, got %v", resp)
+		}
+		if validator.calls != 0 {
+			t.Errorf("expected no validation, got %d", validator.calls)
+		}
+		fakeObs.AssertSingleProbe("AuthzCheckStarted", nil).AssertProbeSequence(
+			"RequestAttributesParsed",
+			service.ProbeCall("CheckSkipped", "/static/app.js"),
+			"End",
+		)
+	})
+
+	t.Run("other paths are checked", func(t *testing.T) {
+		resp, err := authzServer.Check(ctx, envoytest.NewCheckRequest().Path("/api/resource").Build())
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if resp.GetDeniedResponse() == nil {
+			t.Errorf("expected a request without a credential to be denied, got %v", resp)
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// SubjectCredential is a subject credential and where in the request it was found
+type SubjectCredential struct {
+	// Credential is the extracted credential
+	Credential trust.Credential
+
+	// HeadersUsed are the headers that carried the credential; ext_authz removes
+	// them from the request forwarded to the backend
+	HeadersUsed []string
+
+	// QueryParametersUsed are the query parameters that carried the credential;
+	// ext_authz removes them from the request forwarded to the backend
+	QueryParametersUsed []string
+}
+
+// SubjectCredentialExtractor extracts the subject's credential from the HTTP
+// request Envoy is checking
+type SubjectCredentialExtractor interface {
+	// ExtractSubjectCredential returns the subject's credential
+	// Returns nil and nil error if this source holds no credential
+	ExtractSubjectCredential(req *authv3.AttributeContext_HttpRequest) (*SubjectCredential, error)
+}
+
+// SubjectCredentialExtractorChain tries extractors in order and returns the
+// first credential found
+type SubjectCredentialExtractorChain []SubjectCredentialExtractor
+
+// ExtractSubjectCredential implements SubjectCredentialExtractor
+func (c SubjectCredentialExtractorChain) ExtractSubjectCredential(req *authv3.AttributeContext_HttpRequest) (*SubjectCredential, error) {
+	for _, extractor := range c {
+		cred, err := extractor.ExtractSubjectCredential(req)
+		if err != nil || cred != nil {
+			return cred, err
+		}
+	}
+	return nil, nil
+}
+
+// DefaultSubjectCredentialExtractor returns the extractor used when none is
+// configured: a bearer token in the Authorization header
+func DefaultSubjectCredentialExtractor() SubjectCredentialExtractor {
+	return SubjectCredentialSource{
+		Location:       SubjectCredentialLocationHeader,
+		Name:           "authorization",
+		Scheme:         "Bearer",
+		CredentialType: trust.CredentialTypeBearer,
+	}
+}
+
+// SubjectCredentialLocation is the part of the request a credential is read from
+type SubjectCredentialLocation string
+
+const (
+	// SubjectCredentialLocationHeader reads a request header
+	SubjectCredentialLocationHeader SubjectCredentialLocation = "header"
+
+	// SubjectCredentialLocationCookie reads a cookie; the whole Cookie header is
+	// reported as used, since ext_authz cannot remove a single cookie
+	SubjectCredentialLocationCookie SubjectCredentialLocation = "cookie"
+
+	// SubjectCredentialLocationQuery reads a query parameter
+	SubjectCredentialLocationQuery SubjectCredentialLocation = "query"
+)
+
+// SubjectCredentialSource reads a credential of a configured type from one
+// header, cookie, or query parameter
+type SubjectCredentialSource struct {
+	// Location is the part of the request holding the credential
+	Location SubjectCredentialLocation
+
+	// Name is the header, cookie, or query parameter name
+	Name string
+
+	// Scheme is an authorization scheme the value must start with, e.g. "Bearer"
+	// Values with another scheme are ignored. Empty uses the whole value.
+	Scheme string
+
+	// Base64 decodes the value as standard base64, e.g. for x-rh-identity
+	Base64 bool
+
+	// CredentialType selects the credential built from the value
+	// Supported: bearer, jwt, json
+	CredentialType trust.CredentialType
+}
+
+// NewSubjectCredentialSource validates a source and returns it
+func NewSubjectCredentialSource(source SubjectCredentialSource) (SubjectCredentialSource, error) {
+	switch source.Location {
+	case SubjectCredentialLocationHeader, SubjectCredentialLocationCookie, SubjectCredentialLocationQuery:
+	default:
+		return source, fmt.Errorf("unknown subject credential location: %s (supported: header, cookie, query)", source.Location)
+	}
+	if source.Name == "" {
+		return source, fmt.Errorf("name is required for %s subject credentials", source.Location)
+	}
+	if source.Scheme != "" && source.Location != SubjectCredentialLocationHeader {
+		return source, fmt.Errorf("scheme is only supported for header subject credentials")
+	}
+	switch source.CredentialType {
+	case trust.CredentialTypeBearer, trust.CredentialTypeJWT, trust.CredentialTypeJSON:
+	default:
+		return source, fmt.Errorf("unsupported subject credential type: %s (supported: bearer, jwt, json)", source.CredentialType)
+	}
+	if source.Location == SubjectCredentialLocationHeader {
+		source.Name = strings.ToLower(source.Name)
+	}
+	return source, nil
+}
+
+// ExtractSubjectCredential implements SubjectCredentialExtractor
+func (s SubjectCredentialSource) ExtractSubjectCredential(req *authv3.AttributeContext_HttpRequest) (*SubjectCredential, error) {
+	value, extracted := s.lookup(req)
+	if value == "" {
+		return nil, nil
+	}
+
+	if s.Scheme != "" {
+		token, ok := cutScheme(value, s.Scheme)
+		if !ok {
+			return nil, nil
+		}
+		value = token
+	}
+
+	raw := []byte(value)
+	if s.Base64 {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 in %s %s: %w", s.Location, s.Name, err)
+		}
+		raw = decoded
+	}
+
+	var cred trust.Credential
+	switch s.CredentialType {
+	case trust.CredentialTypeJWT:
+		cred = &trust.JWTCredential{BearerCredential: trust.BearerCredential{Token: string(raw)}}
+	case trust.CredentialTypeJSON:
+		cred = &trust.JSONCredential{RawJSON: raw}
+	default:
+		// For bearer tokens, the trust store determines which validator to use
+		// based on its configuration (e.g., default validator, token introspection)
+		cred = &trust.BearerCredential{Token: string(raw)}
+	}
+
+	extracted.Credential = cred
+	return extracted, nil
+}
+
+// lookup returns the raw value of the source and where it was found
+func (s SubjectCredentialSource) lookup(req *authv3.AttributeContext_HttpRequest) (string, *SubjectCredential) {
+	switch s.Location {
+	case SubjectCredentialLocationCookie:
+		cookies, err := http.ParseCookie(req.GetHeaders()["cookie"])
+		if err != nil {
+			return "", nil
+		}
+		for _, cookie := range cookies {
+			if cookie.Name == s.Name {
+				return cookie.Value, &SubjectCredential{HeadersUsed: []string{"cookie"}}
+			}
+		}
+		return "", nil
+	case SubjectCredentialLocationQuery:
+		// Envoy includes the query in the path; the query field is rarely set
+		query := req.GetQuery()
+		if _, pathQuery, ok := strings.Cut(req.GetPath(), "?"); ok {
+			query = pathQuery
+		}
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			return "", nil
+		}
+		return values.Get(s.Name), &SubjectCredential{QueryParametersUsed: []string{s.Name}}
+	default:
+		return req.GetHeaders()[s.Name], &SubjectCredential{HeadersUsed: []string{s.Name}}
+	}
+}
+
+// cutScheme strips an authorization scheme, matched case-insensitively, and
+// the space following it
+func cutScheme(value, scheme string) (string, bool) {
+	if len(value) <= len(scheme) || !strings.EqualFold(value[:len(scheme)], scheme) || value[len(scheme)] != ' ' {
+		return "", false
+	}
+	return value[len(scheme)+1:], true
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"encoding/base64"
+	"slices"
+	"testing"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestSubjectCredentialExtractors(t *testing.T) {
+	identity := `{"identity":{"user":{"user_id":"123"}}}`
+
+	tests := []struct {
+		name        string
+		extractor   SubjectCredentialExtractor
+		req         *authv3.AttributeContext_HttpRequest
+		wantType    trust.CredentialType
+		wantValue   string
+		wantHeaders []string
+		wantQuery   []string
+		wantNone    bool
+		wantErr     bool
+	}{
+		{
+			name:        "default reads authorization bearer",
+			extractor:   DefaultSubjectCredentialExtractor(),
+			req:         &authv3.AttributeContext_HttpRequest{Headers: map[string]string{"authorization": "Bearer subject-token"}},
+			wantType:    trust.CredentialTypeBearer,
+			wantValue:   "subject-token",
+			wantHeaders: []string{"authorization"},
+		},
+		{
+			name:      "default ignores other schemes",
+			extractor: DefaultSubjectCredentialExtractor(),
+			req:       &authv3.AttributeContext_HttpRequest{Headers: map[string]string{"authorization": "Basic dXNlcjpwYXNz"}},
+			wantNone:  true,
+		},
+		{
+			name: "session cookie",
+			extractor: SubjectCredentialSource{
+				Location: SubjectCredentialLocationCookie, Name: "session", CredentialType: trust.CredentialTypeJWT,
+			},
+			req:         &authv3.AttributeContext_HttpRequest{Headers: map[string]string{"cookie": "theme=dark; session=cookie-token"}},
+			wantType:    trust.CredentialTypeJWT,
+			wantValue:   "cookie-token",
+			wantHeaders: []string{"cookie"},
+		},
+		{
+			name: "query parameter from path",
+			extractor: SubjectCredentialSource{
+				Location: SubjectCredentialLocationQuery, Name: "access_token", CredentialType: trust.CredentialTypeBearer,
+			},
+			req:       &authv3.AttributeContext_HttpRequest{Path: "/ws?access_token=query-token&x=1"},
+			wantType:  trust.CredentialTypeBearer,
+			wantValue: "query-token",
+			wantQuery: []string{"access_token"},
+		},
+		{
+			name: "base64 JSON identity header",
+			extractor: SubjectCredentialSource{
+				Location: SubjectCredentialLocationHeader, Name: "x-rh-identity", Base64: true, CredentialType: trust.CredentialTypeJSON,
+			},
+			req: &authv3.AttributeContext_HttpRequest{Headers: map[string]string{
+				"x-rh-identity": base64.StdEncoding.EncodeToString([]byte(identity)),
+			}},
+			wantType:    trust.CredentialTypeJSON,
+			wantValue:   identity,
+			wantHeaders: []string{"x-rh-identity"},
+		},
+		{
+			name: "invalid base64 is an error",
+			extractor: SubjectCredentialSource{
+				Location: SubjectCredentialLocationHeader, Name: "x-rh-identity", Base64: true, CredentialType: trust.CredentialTypeJSON,
+			},
+			req:     &authv3.AttributeContext_HttpRequest{Headers: map[string]string{"x-rh-identity": "not base64!"}},
+			wantErr: true,
+		},
+		{
+			name: "chain tries sources in order",
+			extractor: SubjectCredentialExtractorChain{
+				SubjectCredentialSource{Location: SubjectCredentialLocationCookie, Name: "session", CredentialType: trust.CredentialTypeBearer},
+				DefaultSubjectCredentialExtractor(),
+			},
+			req:         &authv3.AttributeContext_HttpRequest{Headers: map[string]string{"authorization": "bearer from-header"}},
+			wantType:    trust.CredentialTypeBearer,
+			wantValue:   "from-header",
+			wantHeaders: []string{"authorization"},
+		},
+		{
+			name:      "no credential is not an error",
+			extractor: DefaultSubjectCredentialExtractor(),
+			req:       &authv3.AttributeContext_HttpRequest{},
+			wantNone:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.extractor.ExtractSubjectCredential(tt.req)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantNone {
+				if got != nil {
+					t.Fatalf("expected no credential, got %#v", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("expected a credential")
+			}
+			if got.Credential.Type() != tt.wantType {
+				t.Errorf("expected credential type %s, got %s", tt.wantType, got.Credential.Type())
+			}
+			var value string
+			switch cred := got.Credential.(type) {
+			case *trust.BearerCredential:
+				value = cred.Token
+			case *trust.JWTCredential:
+				value = cred.Token
+			case *trust.JSONCredential:
+				value = string(cred.RawJSON)
+			}
+			if value != tt.wantValue {
+				t.Errorf("expected value %q, got %q", tt.wantValue, value)
+			}
+			if !slices.Equal(got.HeadersUsed, tt.wantHeaders) {
+				t.Errorf("expected headers used %v, got %v", tt.wantHeaders, got.HeadersUsed)
+			}
+			if !slices.Equal(got.QueryParametersUsed, tt.wantQuery) {
+				t.Errorf("expected query parameters used %v, got %v", tt.wantQuery, got.QueryParametersUsed)
+			}
+		})
+	}
+}
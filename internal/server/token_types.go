@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	parsecv1 "github.com/project-kessel/parsec/api/gen/parsec/v1"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// tokenCredentialTypes are the credential types of the token type URNs
+// accepted as subject and actor tokens. The trust store only tries the
+// validators handling a token's credential type, so a jwt token is never
+// sent to token introspection, nor an access token to JSON validators.
+var tokenCredentialTypes = map[service.TokenType]trust.CredentialType{
+	// Access tokens may be JWTs or opaque, so any bearer validator may accept them
+	service.TokenTypeAccessToken:      trust.CredentialTypeBearer,
+	service.TokenTypeJWT:              trust.CredentialTypeJWT,
+	service.TokenTypeIDToken:          trust.CredentialTypeOIDC,
+	service.TokenTypeTransactionToken: trust.CredentialTypeJWT,
+	service.TokenTypeRHIdentity:       trust.CredentialTypeJSON,
+}
+
+// tokenCredential returns the credential of a token of the given type URN,
+// named param in errors ("subject_token" or "actor_token")
+// Unknown types, and tokens that are not of their declared type (such as an
+// opaque token declared a jwt), are rejected with invalid_request.
+func tokenCredential(param, tokenType, token string) (trust.Credential, error) {
+	if tokenType == "" {
+		return nil, perr.Errorf(perr.ErrCodeInvalidRequest, "%s_type is required", param)
+	}
+	credType, ok := tokenCredentialTypes[service.TokenType(tokenType)]
+	if !ok {
+		return nil, perr.Errorf(perr.ErrCodeInvalidRequest, "unsupported %s_type %q", param, tokenType)
+	}
+
+	switch credType {
+	case trust.CredentialTypeJWT, trust.CredentialTypeOIDC:
+		cred, ok := parseJWTCredential(token)
+		if !ok {
+			return nil, perr.Errorf(perr.ErrCodeInvalidRequest, "%s is not a JWT as declared by %s_type %q", param, param, tokenType)
+		}
+		if credType == trust.CredentialTypeOIDC {
+			// ID tokens are only accepted by validators configured with the
+			// client IDs they may be issued to
+			return &trust.OIDCCredential{Token: token, IssuerIdentity: cred.IssuerIdentity}, nil
+		}
+		return cred, nil
+	case trust.CredentialTypeJSON:
+		raw, err := base64.StdEncoding.DecodeString(token)
+		if err != nil || !json.Valid(raw) {
+			return nil, perr.Errorf(perr.ErrCodeInvalidRequest, "%s is not base64-encoded JSON as declared by %s_type %q", param, param, tokenType)
+		}
+		return &trust.JSONCredential{RawJSON: raw}, nil
+	default:
+		return &trust.BearerCredential{Token: token}, nil
+	}
+}
+
+// parseJWTCredential returns token as a JWT credential, with the algorithm and
+// key ID of its header and the issuer of its (unverified) claims, if it is a
+// compact-serialized JWS
+func parseJWTCredential(token string) (*trust.JWTCredential, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, false
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if !decodeJWTSegment(parts[0], &header) || header.Algorithm == "" {
+		return nil, false
+	}
+	var payload struct {
+		Issuer string `json:"iss"`
+	}
+	if !decodeJWTSegment(parts[1], &payload) {
+		return nil, false
+	}
+
+	return &trust.JWTCredential{
+		BearerCredential: trust.BearerCredential{Token: token},
+		Algorithm:        header.Algorithm,
+		KeyID:            header.KeyID,
+		IssuerIdentity:   payload.Issuer,
+	}, true
+}
+
+// decodeJWTSegment decodes a base64url JSON object segment of a JWT into v
+func decodeJWTSegment(segment string, v any) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(raw, v) == nil
+}
+
+// checkActorTokenType checks that an actor_token is of its declared type
+// Actors authenticate with the credentials of their calls (see
+// ActorCredentialExtractor), so the actor_token itself is not validated, but
+// its type must still be declared and match, as for the subject_token.
+func checkActorTokenType(req *parsecv1.ExchangeRequest) error {
+	if req.ActorToken == "" {
+		if req.ActorTokenType != "" {
+			return perr.Errorf(perr.ErrCodeInvalidRequest, "actor_token_type is only allowed with an actor_token")
+		}
+		return nil
+	}
+	_, err := tokenCredential("actor_token", req.ActorTokenType, req.ActorToken)
+	return err
+}
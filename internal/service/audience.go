@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+
+	"github.com/project-kessel/parsec/internal/trust"
+	"github.com/project-kessel/parsec/internal/trustdomain"
+)
+
+// AudiencePolicy decides which audiences tokens may be requested for, so one
+// token service can front several APIs
+// The trust domain is the audience of tokens requested for none; a policy
+// only decides the audiences requested explicitly.
+type AudiencePolicy interface {
+	// AllowsAudience reports whether the subject's tokens may be issued for
+	// audience at the actor's request. actor is nil for anonymous requests.
+	AllowsAudience(ctx context.Context, audience string, subject, actor *trust.Result) (bool, error)
+}
+
+// WithAudiencePolicy sets the policy deciding the audiences tokens may be
+// requested for (default: an ExactAudiencePolicy of the trust domain)
+func WithAudiencePolicy(policy AudiencePolicy) TokenServiceOption {
+	return func(ts *TokenService) {
+		if policy != nil {
+			ts.audiencePolicy = policy
+		}
+	}
+}
+
+// AllowsAudience reports whether the token service's audience policy allows
+// tokens for audience (see AudiencePolicy)
+func (ts *TokenService) AllowsAudience(ctx context.Context, audience string, subject, actor *trust.Result) (bool, error) {
+	return ts.audiencePolicy.AllowsAudience(ctx, audience, subject, actor)
+}
+
+// ExactAudiencePolicy allows a single audience, by default the trust domain
+type ExactAudiencePolicy struct {
+	Audience string
+}
+
+// AllowsAudience implements AudiencePolicy
+func (p *ExactAudiencePolicy) AllowsAudience(ctx context.Context, audience string, subject, actor *trust.Result) (bool, error) {
+	return audience == p.Audience, nil
+}
+
+// AllowlistAudiencePolicy allows a set of audiences, each exact or a pattern
+// such as "*.example.com" (see trustdomain.Match)
+type AllowlistAudiencePolicy struct {
+	Audiences []string
+}
+
+// AllowsAudience implements AudiencePolicy
+func (p *AllowlistAudiencePolicy) AllowsAudience(ctx context.Context, audience string, subject, actor *trust.Result) (bool, error) {
+	return trustdomain.MatchAny(p.Audiences, audience), nil
+}
+
+// AudiencePolicyLibrary creates a CEL library for audience policies.
+//
+// This provides compile-time declarations for:
+//   - audience - the requested audience
+//   - trust_domain - parsec's trust domain
+//   - subject - the subject's Result object as a map (subject, issuer, trust_domain, claims, etc.)
+//   - actor - the actor's Result object as a map, empty for anonymous requests
+//
+// Example expressions:
+//   - audience == trust_domain || trustDomainMatches(audience, "*.apis.example.com")
+//   - audience.startsWith("https://" + subject.claims.tenant + ".example.com/")
+func AudiencePolicyLibrary() cel.EnvOption {
+	return cel.Lib(&audiencePolicyLib{})
+}
+
+type audiencePolicyLib struct{}
+
+func (lib *audiencePolicyLib) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		// trustDomainMatches(value, pattern)
+		trustdomain.CELLibrary(),
+		cel.Variable("audience", cel.StringType),
+		cel.Variable("trust_domain", cel.StringType),
+		cel.Variable("subject", cel.DynType),
+		cel.Variable("actor", cel.DynType),
+	}
+}
+
+func (lib *audiencePolicyLib) ProgramOptions() []cel.ProgramOption {
+	return []cel.ProgramOption{}
+}
+
+// CelAudiencePolicy uses a CEL expression to decide whether tokens may be
+// requested for an audience
+type CelAudiencePolicy struct {
+	program     cel.Program
+	script      string
+	trustDomain string
+}
+
+// NewCelAudiencePolicy creates a new CEL-based audience policy
+// The script should be a CEL expression that evaluates to a boolean.
+func NewCelAudiencePolicy(script, trustDomain string) (*CelAudiencePolicy, error) {
+	if script == "" {
+		return nil, fmt.Errorf("CEL audience script cannot be empty")
+	}
+
+	env, err := cel.NewEnv(AudiencePolicyLibrary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(script)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("failed to compile CEL audience script: %w", issues.Err())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL program: %w", err)
+	}
+
+	return &CelAudiencePolicy{
+		program:     program,
+		script:      script,
+		trustDomain: trustDomain,
+	}, nil
+}
+
+// AllowsAudience implements AudiencePolicy
+func (p *CelAudiencePolicy) AllowsAudience(ctx context.Context, audience string, subject, actor *trust.Result) (bool, error) {
+	subjectMap := map[string]any{}
+	if subject != nil {
+		var err error
+		if subjectMap, err = trust.ConvertResultToMap(subject); err != nil {
+			return false, fmt.Errorf("failed to convert subject: %w", err)
+		}
+	}
+	actorMap := map[string]any{}
+	if actor != nil {
+		var err error
+		if actorMap, err = trust.ConvertResultToMap(actor); err != nil {
+			return false, fmt.Errorf("failed to convert actor: %w", err)
+		}
+	}
+
+	result, _, err := p.program.ContextEval(ctx, map[string]any{
+		"audience":     audience,
+		"trust_domain": p.trustDomain,
+		"subject":      subjectMap,
+		"actor":        actorMap,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate audience policy: %w", err)
+	}
+	return result.Type() == types.BoolType && result.Value().(bool), nil
+}
+
+// Script returns the CEL script used by this policy
+func (p *CelAudiencePolicy) Script() string {
+	return p.script
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestTokenService_AllowsAudience(t *testing.T) {
+	ctx := context.Background()
+	subject := &trust.Result{Subject: "alice", TrustDomain: "idp.parsec.test"}
+
+	ts := NewTokenService("parsec.test", NewDataSourceRegistry(), NewSimpleRegistry(), nil)
+	if ok, _ := ts.AllowsAudience(ctx, "parsec.test", subject, nil); !ok {
+		t.Error("expected the trust domain to be allowed by default")
+	}
+	if ok, _ := ts.AllowsAudience(ctx, "orders.parsec.test", subject, nil); ok {
+		t.Error("expected other audiences to be denied by default")
+	}
+
+	ts = NewTokenService("parsec.test", NewDataSourceRegistry(), NewSimpleRegistry(), nil,
+		WithAudiencePolicy(&AllowlistAudiencePolicy{Audiences: []string{"*.parsec.test"}}))
+	if ok, _ := ts.AllowsAudience(ctx, "orders.parsec.test", subject, nil); !ok {
+		t.Error("expected the allowlist to allow a matching audience")
+	}
+	if ok, _ := ts.AllowsAudience(ctx, "parsec.test", subject, nil); ok {
+		t.Error("expected the allowlist to replace the trust domain")
+	}
+}
+
+func TestCelAudiencePolicy(t *testing.T) {
+	policy, err := NewCelAudiencePolicy(`trustDomainMatches(audience, "*." + trust_domain) && actor.trust_domain == "gateways.parsec.test"`, "parsec.test")
+	if err != nil {
+		t.Fatalf("NewCelAudiencePolicy failed: %v", err)
+	}
+	ctx := context.Background()
+	subject := &trust.Result{Subject: "alice"}
+	gateway := &trust.Result{Subject: "edge", TrustDomain: "gateways.parsec.test"}
+
+	if ok, err := policy.AllowsAudience(ctx, "orders.parsec.test", subject, gateway); !ok || err != nil {
+		t.Errorf("expected the gateway's audience to be allowed, got %v (%v)", ok, err)
+	}
+	if ok, err := policy.AllowsAudience(ctx, "orders.example.com", subject, gateway); ok || err != nil {
+		t.Errorf("expected an audience outside the trust domain to be denied, got %v (%v)", ok, err)
+	}
+	// Anonymous requests see an empty actor, so the field is missing
+	if ok, err := policy.AllowsAudience(ctx, "orders.parsec.test", subject, nil); ok || err == nil {
+		t.Errorf("expected an anonymous request to fail evaluation, got %v (%v)", ok, err)
+	}
+
+	if _, err := NewCelAudiencePolicy("", "parsec.test"); err == nil {
+		t.Error("expected an empty script to be rejected")
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/perr"
+)
+
+// ErrBulkheadFull is returned when a bulkhead has no free slot and no room in
+// its queue
+var ErrBulkheadFull = perr.New(perr.ErrCodeIssuerUnavailable, "too many concurrent requests")
+
+// BulkheadConfig configures a Bulkhead
+type BulkheadConfig struct {
+	// Name identifies the bulkhead in observations, e.g. "issuer:<token type>"
+	Name string
+
+	// MaxConcurrent is the number of calls that may run at once
+	MaxConcurrent int
+
+	// MaxQueued is the number of calls that may wait for a slot
+	// Calls beyond it are rejected with ErrBulkheadFull. Waiting calls give up
+	// when their context ends.
+	MaxQueued int
+
+	// Observer observes calls through the bulkhead (optional)
+	Observer BulkheadObserver
+
+	// Clock times waits in the queue (defaults to system clock)
+	Clock clock.Clock
+}
+
+// Bulkhead bounds the concurrent calls to one issuer or data source, so a slow
+// token type or backend holds at most its own slots and queue rather than every
+// server goroutine
+type Bulkhead struct {
+	name      string
+	slots     chan struct{}
+	maxQueued int64
+	queued    atomic.Int64
+	observer  BulkheadObserver
+	clock     clock.Clock
+}
+
+// NewBulkhead creates a bulkhead
+func NewBulkhead(cfg BulkheadConfig) (*Bulkhead, error) {
+	if cfg.MaxConcurrent <= 0 {
+		return nil, fmt.Errorf("bulkhead max concurrent calls must be positive, got %d", cfg.MaxConcurrent)
+	}
+	if cfg.MaxQueued < 0 {
+		return nil, fmt.Errorf("bulkhead max queued calls must not be negative, got %d", cfg.MaxQueued)
+	}
+
+	observer := cfg.Observer
+	if observer == nil {
+		observer = NoOpBulkheadObserver()
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	return &Bulkhead{
+		name:      cfg.Name,
+		slots:     make(chan struct{}, cfg.MaxConcurrent),
+		maxQueued: int64(cfg.MaxQueued),
+		observer:  observer,
+		clock:     clk,
+	}, nil
+}
+
+// Name returns the name of the bulkhead
+func (b *Bulkhead) Name() string {
+	return b.name
+}
+
+// InFlight returns the number of calls holding a slot
+func (b *Bulkhead) InFlight() int {
+	return len(b.slots)
+}
+
+// Queued returns the number of calls waiting for a slot
+func (b *Bulkhead) Queued() int {
+	return int(b.queued.Load())
+}
+
+// Do runs fn once a slot is free, waiting in the queue if necessary
+// Returns ErrBulkheadFull if the queue is full, or the context's error if it
+// ends while waiting; fn's error otherwise.
+func (b *Bulkhead) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, probe := b.observer.BulkheadCallStarted(ctx, b.name)
+	defer probe.End()
+
+	if err := b.acquire(ctx, probe); err != nil {
+		probe.BulkheadRejected(err)
+		return err
+	}
+	defer func() { <-b.slots }()
+
+	return fn(ctx)
+}
+
+// acquire takes a slot, waiting for one if the queue has room
+func (b *Bulkhead) acquire(ctx context.Context, probe BulkheadProbe) error {
+	select {
+	case b.slots <- struct{}{}:
+		probe.BulkheadAdmitted(len(b.slots), 0)
+		return nil
+	default:
+	}
+
+	start := b.clock.Now()
+	queued := b.queued.Add(1)
+	defer b.queued.Add(-1)
+	if queued > b.maxQueued {
+		return fmt.Errorf("%s: %w", b.name, ErrBulkheadFull)
+	}
+	probe.BulkheadQueued(int(queued))
+
+	select {
+	case b.slots <- struct{}{}:
+		probe.BulkheadAdmitted(len(b.slots), b.clock.Now().Sub(start))
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s: gave up waiting for a slot: %w", b.name, ctx.Err())
+	}
+}
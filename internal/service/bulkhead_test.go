@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/perr"
+)
+
+func TestNewBulkhead(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     BulkheadConfig
+		wantErr bool
+	}{
+		{name: "valid", cfg: BulkheadConfig{MaxConcurrent: 2, MaxQueued: 4}},
+		{name: "no queue", cfg: BulkheadConfig{MaxConcurrent: 1}},
+		{name: "zero concurrency", cfg: BulkheadConfig{MaxConcurrent: 0}, wantErr: true},
+		{name: "negative queue", cfg: BulkheadConfig{MaxConcurrent: 1, MaxQueued: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewBulkhead(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewBulkhead() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBulkhead_Do(t *testing.T) {
+	ctx := context.Background()
+
+	// occupy holds a slot of b until the returned function is called
+	occupy := func(t *testing.T, b *Bulkhead) func() {
+		t.Helper()
+		started := make(chan struct{})
+		release := make(chan struct{})
+		go func() {
+			_ = b.Do(ctx, func(ctx context.Context) error {
+				close(started)
+				<-release
+				return nil
+			})
+		}()
+		<-started
+		return func() { close(release) }
+	}
+
+	t.Run("runs calls within the limit", func(t *testing.T) {
+		fakeObs := NewFakeObserver(t)
+		b, _ := NewBulkhead(BulkheadConfig{Name: "issuer:test", MaxConcurrent: 1, Observer: fakeObs})
+
+		callErr := errors.New("issue failed")
+		err := b.Do(ctx, func(ctx context.Context) error { return callErr })
+		if !errors.Is(err, callErr) {
+			t.Errorf("expected the call's error, got %v", err)
+		}
+		if b.InFlight() != 0 {
+			t.Errorf("expected slot to be released, %d in flight", b.InFlight())
+		}
+
+		p := fakeObs.AssertSingleProbe("BulkheadCallStarted", map[string]any{"bulkhead": "issuer:test"})
+		p.AssertProbeSequence(
+			ProbeCall("BulkheadAdmitted", 1, time.Duration(0)),
+			"End",
+		)
+	})
+
+	t.Run("rejects calls when the queue is full", func(t *testing.T) {
+		fakeObs := NewFakeObserver(t)
+		b, _ := NewBulkhead(BulkheadConfig{Name: "data_source:slow", MaxConcurrent: 1})
+		release := occupy(t, b)
+		defer release()
+
+		// Observe only the rejected call
+		b.observer = fakeObs
+		called := false
+		err := b.Do(ctx, func(ctx context.Context) error {
+			called = true
+			return nil
+		})
+		if !errors.Is(err, ErrBulkheadFull) || perr.CodeOf(err) != perr.ErrCodeIssuerUnavailable {
+			t.Errorf("expected ErrBulkheadFull, got %v", err)
+		}
+		if called {
+			t.Error("rejected call must not run")
+		}
+
+		p := fakeObs.AssertSingleProbe("BulkheadCallStarted", nil)
+		p.AssertProbeSequence(
+			ProbeCall("BulkheadRejected", ErrorWithCode(perr.ErrCodeIssuerUnavailable)),
+			"End",
+		)
+	})
+
+	t.Run("queued calls run when a slot frees", func(t *testing.T) {
+		b, _ := NewBulkhead(BulkheadConfig{MaxConcurrent: 1, MaxQueued: 1})
+		release := occupy(t, b)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- b.Do(ctx, func(ctx context.Context) error { return nil })
+		}()
+
+		deadline := time.Now().Add(time.Second)
+		for b.Queued() != 1 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if b.Queued() != 1 {
+			t.Fatalf("expected 1 queued call, got %d", b.Queued())
+		}
+
+		// The queue is full, so a third call is rejected
+		if err := b.Do(ctx, func(ctx context.Context) error { return nil }); !errors.Is(err, ErrBulkheadFull) {
+			t.Errorf("expected ErrBulkheadFull, got %v", err)
+		}
+
+		release()
+		if err := <-done; err != nil {
+			t.Errorf("expected queued call to succeed, got %v", err)
+		}
+		if b.Queued() != 0 || b.InFlight() != 0 {
+			t.Errorf("expected empty bulkhead, got %d queued and %d in flight", b.Queued(), b.InFlight())
+		}
+	})
+
+	t.Run("queue wait is timed by the clock", func(t *testing.T) {
+		fakeObs := NewFakeObserver(t)
+		clk := clock.NewFixtureClock(time.Now())
+		b, _ := NewBulkhead(BulkheadConfig{MaxConcurrent: 1, MaxQueued: 1, Clock: clk})
+		release := occupy(t, b)
+
+		// Observe only the queued call
+		b.observer = fakeObs
+		done := make(chan error, 1)
+		go func() {
+			done <- b.Do(ctx, func(ctx context.Context) error { return nil })
+		}()
+
+		deadline := time.Now().Add(time.Second)
+		for b.Queued() != 1 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		clk.Advance(3 * time.Second)
+		release()
+		if err := <-done; err != nil {
+			t.Fatalf("expected queued call to succeed, got %v", err)
+		}
+
+		p := fakeObs.AssertSingleProbe("BulkheadCallStarted", nil)
+		p.AssertProbeSequence(
+			ProbeCall("BulkheadQueued", 1),
+			ProbeCall("BulkheadAdmitted", 1, 3*time.Second),
+			"End",
+		)
+	})
+
+	t.Run("queued calls give up when their context ends", func(t *testing.T) {
+		b, _ := NewBulkhead(BulkheadConfig{MaxConcurrent: 1, MaxQueued: 1})
+		release := occupy(t, b)
+		defer release()
+
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		err := b.Do(ctx, func(ctx context.Context) error { return nil })
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+		if b.Queued() != 0 {
+			t.Errorf("expected call to leave the queue, %d queued", b.Queued())
+		}
+	})
+}
@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/project-kessel/parsec/internal/perr"
 	"github.com/project-kessel/parsec/internal/request"
 	"github.com/project-kessel/parsec/internal/trust"
 )
@@ -49,6 +53,43 @@ type Cacheable interface {
 	CacheTTL() time.Duration
 }
 
+// CacheInvalidation selects cached data source entries to drop before they
+// expire. An empty invalidation selects every entry of the data source.
+type CacheInvalidation struct {
+	// Subject selects the entries cached for this subject, i.e. whose cache key
+	// has this Subject.Subject
+	Subject string `json:"subject,omitempty"`
+
+	// Key selects the entry with this cache key: the JSON serialization of the
+	// masked input returned by Cacheable.CacheKey
+	Key string `json:"key,omitempty"`
+}
+
+// Matches reports whether the invalidation selects the entry cached under the
+// serialized cache key keyJSON for subject (empty if the key has no subject)
+func (inv CacheInvalidation) Matches(subject, keyJSON string) bool {
+	if inv.Key != "" && inv.Key != keyJSON {
+		return false
+	}
+	return inv.Subject == "" || inv.Subject == subject
+}
+
+// CacheKeySubject returns the subject a cache key was masked to, if any
+func CacheKeySubject(key *DataSourceInput) string {
+	if key.Subject == nil {
+		return ""
+	}
+	return key.Subject.Subject
+}
+
+// CacheInvalidator is implemented by caching data sources whose entries can be
+// invalidated before they expire
+type CacheInvalidator interface {
+	// InvalidateCache drops the selected entries, so that they are fetched
+	// from the underlying data source when next needed
+	InvalidateCache(ctx context.Context, invalidation CacheInvalidation) error
+}
+
 // DataSourceContentType identifies the serialization format of data source results
 type DataSourceContentType string
 
@@ -93,27 +134,128 @@ type DataSourceInput struct {
 	RequestAttributes *request.RequestAttributes `json:"request_attributes,omitempty"`
 }
 
+// DataSourceInfo describes a registered data source, so operators can see
+// what is configured without reading the configuration and code side by side
+type DataSourceInfo struct {
+	// Name is the name of the data source
+	Name string
+
+	// Type is the data source implementation, e.g. "http" ("" if unknown)
+	Type string
+
+	// Cacheable is whether results are cached
+	Cacheable bool
+
+	// Cache is where results are cached, e.g. "redis" ("" if unknown)
+	Cache string
+
+	// CacheTTL is how long results are cached (0 if they don't expire)
+	CacheTTL time.Duration
+}
+
 // DataSourceRegistry is a simple registry that stores data sources by name
 type DataSourceRegistry struct {
 	sources map[string]DataSource
+	infos   map[string]DataSourceInfo
+
+	// onGet is called with the name of every data source looked up (optional)
+	onGet func(name string)
 }
 
 // NewDataSourceRegistry creates a new data source registry
 func NewDataSourceRegistry() *DataSourceRegistry {
 	return &DataSourceRegistry{
 		sources: make(map[string]DataSource),
+		infos:   make(map[string]DataSourceInfo),
 	}
 }
 
 // Register adds a data source to the registry
+// Data sources implementing Cacheable are described as cacheable with their TTL.
 func (r *DataSourceRegistry) Register(source DataSource) {
-	r.sources[source.Name()] = source
+	info := DataSourceInfo{Name: source.Name()}
+	if cacheable, ok := source.(Cacheable); ok {
+		info.Cacheable = true
+		info.CacheTTL = cacheable.CacheTTL()
+	}
+	r.RegisterWithInfo(info, source)
+}
+
+// RegisterWithInfo adds a data source to the registry, described by info
+// The name of the data source overrides info.Name.
+func (r *DataSourceRegistry) RegisterWithInfo(info DataSourceInfo, source DataSource) {
+	info.Name = source.Name()
+	r.sources[info.Name] = source
+	r.infos[info.Name] = info
 }
 
 // Get retrieves a data source by name
 // Returns nil if the data source is not found
 func (r *DataSourceRegistry) Get(name string) DataSource {
-	return r.sources[name]
+	source, ok := r.sources[name]
+	if ok && r.onGet != nil {
+		r.onGet(name)
+	}
+	return source
+}
+
+// observed returns a view of the registry that calls onGet with the name of
+// every data source looked up through it (nil for a nil registry)
+func (r *DataSourceRegistry) observed(onGet func(name string)) *DataSourceRegistry {
+	if r == nil {
+		return nil
+	}
+	return &DataSourceRegistry{sources: r.sources, infos: r.infos, onGet: onGet}
+}
+
+// InvalidateCache drops the selected cache entries of the named data source
+// Fails with not_found for an unknown data source and invalid_request for one
+// without a cache.
+func (r *DataSourceRegistry) InvalidateCache(ctx context.Context, name string, invalidation CacheInvalidation) error {
+	source, ok := r.sources[name]
+	if !ok {
+		return perr.Errorf(perr.ErrCodeNotFound, "no data source named %s", name)
+	}
+	invalidator, ok := source.(CacheInvalidator)
+	if !ok {
+		return perr.Errorf(perr.ErrCodeInvalidRequest, "data source %s is not cached", name)
+	}
+
+	// Caches compare keys as serialized, so the key is brought into that form
+	if invalidation.Key != "" {
+		var key DataSourceInput
+		if err := json.Unmarshal([]byte(invalidation.Key), &key); err != nil {
+			return perr.Errorf(perr.ErrCodeInvalidRequest, "invalid cache key: %v", err)
+		}
+		canonical, err := json.Marshal(&key)
+		if err != nil {
+			return perr.Errorf(perr.ErrCodeInvalidRequest, "invalid cache key: %v", err)
+		}
+		invalidation.Key = string(canonical)
+	}
+	return invalidator.InvalidateCache(ctx, invalidation)
+}
+
+// List describes all registered data sources, ordered by name
+func (r *DataSourceRegistry) List() []DataSourceInfo {
+	infos := make([]DataSourceInfo, 0, len(r.infos))
+	for _, info := range r.infos {
+		infos = append(infos, info)
+	}
+	slices.SortFunc(infos, func(a, b DataSourceInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return infos
+}
+
+// Describe describes the named data source
+// Fails with not_found for an unknown data source.
+func (r *DataSourceRegistry) Describe(name string) (DataSourceInfo, error) {
+	info, ok := r.infos[name]
+	if !ok {
+		return DataSourceInfo{}, perr.Errorf(perr.ErrCodeNotFound, "no data source named %s", name)
+	}
+	return info, nil
 }
 
 // Names returns the names of all registered data sources
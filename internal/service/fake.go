@@ -3,19 +3,33 @@ package service
 import (
 	"context"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/project-kessel/parsec/internal/perr"
 	"github.com/project-kessel/parsec/internal/request"
 	"github.com/project-kessel/parsec/internal/trust"
 )
 
 // FakeObserver is a test double that implements ApplicationObserver.
 // It records all probe creations for later assertion in tests.
+// Probes may be created and called concurrently; every call is also recorded
+// on a timeline shared by all probes, for ordering assertions across them.
 type FakeObserver struct {
 	t *testing.T
 
 	// All probes created across all observer methods
 	Probes []*FakeProbe
+
+	mu       sync.Mutex
+	timeline []timelineEntry
+}
+
+// timelineEntry is a probe start or call in the order it happened
+type timelineEntry struct {
+	probe *FakeProbe
+	call  probeCall
 }
 
 // NewFakeObserver creates a new fake observer for testing
@@ -31,19 +45,12 @@ func (o *FakeObserver) TokenIssuanceStarted(
 	scope string,
 	tokenTypes []TokenType,
 ) (context.Context, TokenIssuanceProbe) {
-	probe := &FakeProbe{
-		t:           o.t,
-		StartMethod: "TokenIssuanceStarted",
-		StartArgs: map[string]any{
-			"subject":    subject,
-			"actor":      actor,
-			"scope":      scope,
-			"tokenTypes": tokenTypes,
-		},
-		calls: []probeCall{},
-	}
-	o.Probes = append(o.Probes, probe)
-	return ctx, probe
+	return ctx, o.startProbe("TokenIssuanceStarted", map[string]any{
+		"subject":    subject,
+		"actor":      actor,
+		"scope":      scope,
+		"tokenTypes": tokenTypes,
+	})
 }
 
 // TokenExchangeStarted implements TokenExchangeObserver
@@ -54,33 +61,45 @@ func (o *FakeObserver) TokenExchangeStarted(
 	audience string,
 	scope string,
 ) (context.Context, TokenExchangeProbe) {
-	probe := &FakeProbe{
-		t:           o.t,
-		StartMethod: "TokenExchangeStarted",
-		StartArgs: map[string]any{
-			"grantType":          grantType,
-			"requestedTokenType": requestedTokenType,
-			"audience":           audience,
-			"scope":              scope,
-		},
-		calls: []probeCall{},
-	}
-	o.Probes = append(o.Probes, probe)
-	return ctx, probe
+	return ctx, o.startProbe("TokenExchangeStarted", map[string]any{
+		"grantType":          grantType,
+		"requestedTokenType": requestedTokenType,
+		"audience":           audience,
+		"scope":              scope,
+	})
 }
 
 // AuthzCheckStarted implements AuthzCheckObserver
 func (o *FakeObserver) AuthzCheckStarted(
 	ctx context.Context,
 ) (context.Context, AuthzCheckProbe) {
+	return ctx, o.startProbe("AuthzCheckStarted", map[string]any{})
+}
+
+// BulkheadCallStarted implements BulkheadObserver
+func (o *FakeObserver) BulkheadCallStarted(
+	ctx context.Context,
+	bulkhead string,
+) (context.Context, BulkheadProbe) {
+	return ctx, o.startProbe("BulkheadCallStarted", map[string]any{
+		"bulkhead": bulkhead,
+	})
+}
+
+// startProbe creates and records a probe started with startMethod
+func (o *FakeObserver) startProbe(startMethod string, args map[string]any) *FakeProbe {
 	probe := &FakeProbe{
 		t:           o.t,
-		StartMethod: "AuthzCheckStarted",
-		StartArgs:   map[string]any{},
+		observer:    o,
+		StartMethod: startMethod,
+		StartArgs:   args,
 		calls:       []probeCall{},
 	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
 	o.Probes = append(o.Probes, probe)
-	return ctx, probe
+	o.timeline = append(o.timeline, timelineEntry{probe: probe, call: probeCall{methodName: startMethod}})
+	return probe
 }
 
 // AssertProbeCount verifies the expected number of probes were created
@@ -136,9 +155,74 @@ func (o *FakeObserver) AssertSingleProbe(startMethod string, args map[string]any
 	return probe
 }
 
+// ProbesStartedWith returns the probes started with startMethod, in start order
+func (o *FakeObserver) ProbesStartedWith(startMethod string) []*FakeProbe {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var probes []*FakeProbe
+	for _, probe := range o.Probes {
+		if probe.StartMethod == startMethod {
+			probes = append(probes, probe)
+		}
+	}
+	return probes
+}
+
+// TimelineCall is an expected call on the observer's timeline: Call (a
+// method name or ProbeMatcher) made on a probe started with StartMethod. The
+// start of a probe is matched by its start method, e.g.
+// InProbe("AuthzCheckStarted", "AuthzCheckStarted").
+type TimelineCall struct {
+	StartMethod string
+	Call        any
+}
+
+// InProbe creates a TimelineCall for call made on a probe started with startMethod
+func InProbe(startMethod string, call any) TimelineCall {
+	return TimelineCall{StartMethod: startMethod, Call: call}
+}
+
+// AssertCallsInOrder verifies that the expected calls happened in this order
+// across all probes. Other calls may happen in between, so concurrent probes
+// can be checked for the ordering that matters only.
+func (o *FakeObserver) AssertCallsInOrder(expected ...TimelineCall) {
+	o.t.Helper()
+	o.mu.Lock()
+	timeline := append([]timelineEntry(nil), o.timeline...)
+	o.mu.Unlock()
+
+	next := 0
+	for _, entry := range timeline {
+		if next == len(expected) {
+			break
+		}
+		if entry.probe.StartMethod == expected[next].StartMethod && matches(entry.call, expected[next].Call) {
+			next++
+		}
+	}
+	if next < len(expected) {
+		o.t.Errorf("expected call %d (%s in %s) after the previous ones, but it did not happen",
+			next, callName(expected[next].Call), expected[next].StartMethod)
+		names := make([]string, len(timeline))
+		for i, entry := range timeline {
+			names[i] = entry.probe.StartMethod + "/" + entry.call.method()
+		}
+		o.t.Logf("actual timeline: %v", names)
+	}
+}
+
+// callName describes an expected call for failure messages
+func callName(call any) string {
+	if name, ok := call.(string); ok {
+		return name
+	}
+	return "matcher"
+}
+
 // FakeProbe implements all probe interfaces and records method calls
 type FakeProbe struct {
-	t *testing.T
+	t        *testing.T
+	observer *FakeObserver
 
 	// Captured at probe creation (exported for test assertions)
 	StartMethod string
@@ -161,12 +245,23 @@ func (p *probeCall) arguments() []any {
 	return p.args
 }
 
-// recordCall records a method call
+// recordCall records a method call on the probe and the observer's timeline
 func (p *FakeProbe) recordCall(method string, args ...any) {
-	p.calls = append(p.calls, probeCall{
+	call := probeCall{
 		methodName: method,
 		args:       args,
-	})
+	}
+	p.observer.mu.Lock()
+	defer p.observer.mu.Unlock()
+	p.calls = append(p.calls, call)
+	p.observer.timeline = append(p.observer.timeline, timelineEntry{probe: p, call: call})
+}
+
+// recordedCalls returns a copy of the calls recorded so far
+func (p *FakeProbe) recordedCalls() []probeCall {
+	p.observer.mu.Lock()
+	defer p.observer.mu.Unlock()
+	return append([]probeCall(nil), p.calls...)
 }
 
 // TokenIssuanceProbe methods
@@ -186,6 +281,18 @@ func (p *FakeProbe) IssuerNotFound(tokenType TokenType, err error) {
 	p.recordCall("IssuerNotFound", tokenType, err)
 }
 
+func (p *FakeProbe) TokenCompacted(tokenType TokenType, report *CompactionReport) {
+	p.recordCall("TokenCompacted", tokenType, report)
+}
+
+func (p *FakeProbe) IssuanceRefused(err error) {
+	p.recordCall("IssuanceRefused", err)
+}
+
+func (p *FakeProbe) OptionalMapperFailed(mapper string, err error) {
+	p.recordCall("OptionalMapperFailed", mapper, err)
+}
+
 // TokenExchangeProbe methods
 func (p *FakeProbe) ActorValidationSucceeded(actor *trust.Result) {
 	p.recordCall("ActorValidationSucceeded", actor)
@@ -203,6 +310,10 @@ func (p *FakeProbe) RequestContextParseFailed(err error) {
 	p.recordCall("RequestContextParseFailed", err)
 }
 
+func (p *FakeProbe) RequestContextClaimsRejected(names []string) {
+	p.recordCall("RequestContextClaimsRejected", names)
+}
+
 func (p *FakeProbe) SubjectTokenValidationSucceeded(subject *trust.Result) {
 	p.recordCall("SubjectTokenValidationSucceeded", subject)
 }
@@ -211,11 +322,27 @@ func (p *FakeProbe) SubjectTokenValidationFailed(err error) {
 	p.recordCall("SubjectTokenValidationFailed", err)
 }
 
+func (p *FakeProbe) DelegationDenied(err error) {
+	p.recordCall("DelegationDenied", err)
+}
+
 // AuthzCheckProbe methods
 func (p *FakeProbe) RequestAttributesParsed(attrs *request.RequestAttributes) {
 	p.recordCall("RequestAttributesParsed", attrs)
 }
 
+func (p *FakeProbe) RequestAttributeExtractionFailed(err error) {
+	p.recordCall("RequestAttributeExtractionFailed", err)
+}
+
+func (p *FakeProbe) ClientAddressDenied(address string, err error) {
+	p.recordCall("ClientAddressDenied", address, err)
+}
+
+func (p *FakeProbe) CheckSkipped(path string) {
+	p.recordCall("CheckSkipped", path)
+}
+
 func (p *FakeProbe) SubjectCredentialExtracted(cred trust.Credential, headersUsed []string) {
 	p.recordCall("SubjectCredentialExtracted", cred, headersUsed)
 }
@@ -232,6 +359,35 @@ func (p *FakeProbe) SubjectValidationFailed(err error) {
 	p.recordCall("SubjectValidationFailed", err)
 }
 
+func (p *FakeProbe) TokensIssued(tokenTypes []TokenType) {
+	p.recordCall("TokensIssued", tokenTypes)
+}
+
+func (p *FakeProbe) TokenIssuanceFailed(err error) {
+	p.recordCall("TokenIssuanceFailed", err)
+}
+
+func (p *FakeProbe) ResponseHeaderTooLarge(header string, size int, limit int) {
+	p.recordCall("ResponseHeaderTooLarge", header, size, limit)
+}
+
+func (p *FakeProbe) ResponseHeadersEmitted(added []string, removed []string) {
+	p.recordCall("ResponseHeadersEmitted", added, removed)
+}
+
+// BulkheadProbe methods
+func (p *FakeProbe) BulkheadQueued(queued int) {
+	p.recordCall("BulkheadQueued", queued)
+}
+
+func (p *FakeProbe) BulkheadAdmitted(inFlight int, waited time.Duration) {
+	p.recordCall("BulkheadAdmitted", inFlight, waited)
+}
+
+func (p *FakeProbe) BulkheadRejected(err error) {
+	p.recordCall("BulkheadRejected", err)
+}
+
 // End is common to all probes
 func (p *FakeProbe) End() {
 	p.recordCall("End")
@@ -241,13 +397,14 @@ func (p *FakeProbe) End() {
 // Accepts either strings (method names) or ProbeMatcher functions.
 func (p *FakeProbe) AssertProbeSequence(expected ...any) {
 	p.t.Helper()
-	if len(p.calls) != len(expected) {
-		p.t.Errorf("expected %d probe calls, got %d", len(expected), len(p.calls))
+	calls := p.recordedCalls()
+	if len(calls) != len(expected) {
+		p.t.Errorf("expected %d probe calls, got %d", len(expected), len(calls))
 		p.t.Logf("actual probe calls: %v", p.methodNames())
 		return
 	}
 	for i, exp := range expected {
-		call := p.calls[i]
+		call := calls[i]
 		switch e := exp.(type) {
 		case string:
 			// Simple method name matching
@@ -266,13 +423,50 @@ func (p *FakeProbe) AssertProbeSequence(expected ...any) {
 }
 
 func (p *FakeProbe) methodNames() []string {
-	names := make([]string, len(p.calls))
-	for i, call := range p.calls {
+	calls := p.recordedCalls()
+	names := make([]string, len(calls))
+	for i, call := range calls {
 		names[i] = call.method()
 	}
 	return names
 }
 
+// CallArgs returns the arguments of each call of method, in call order
+func (p *FakeProbe) CallArgs(method string) [][]any {
+	var args [][]any
+	for _, call := range p.recordedCalls() {
+		if call.method() == method {
+			args = append(args, call.arguments())
+		}
+	}
+	return args
+}
+
+// Errors returns the errors reported to the probe, in call order
+func (p *FakeProbe) Errors() []error {
+	var errs []error
+	for _, call := range p.recordedCalls() {
+		for _, arg := range call.arguments() {
+			if err, ok := arg.(error); ok && err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// matches reports whether call matches an expected method name or ProbeMatcher
+func matches(call probeCall, expected any) bool {
+	switch e := expected.(type) {
+	case string:
+		return call.method() == e
+	case ProbeMatcher:
+		return e(call)
+	default:
+		return false
+	}
+}
+
 // ProbeMatcher is a function that matches against a probe call
 type ProbeMatcher func(probeCall) bool
 
@@ -323,6 +517,37 @@ func (e ErrorContaining) Matches(actual any) bool {
 	return strings.Contains(err.Error(), string(e))
 }
 
+// ErrorWithCode creates a matcher that checks if an error's chain has a parsec error code
+type ErrorWithCode perr.Code
+
+func (e ErrorWithCode) Matches(actual any) bool {
+	err, ok := actual.(error)
+	if !ok || err == nil {
+		return false
+	}
+	return perr.HasCode(err, perr.Code(e))
+}
+
+// ResultWithSubject creates a matcher that checks a *trust.Result's subject
+type ResultWithSubject string
+
+func (r ResultWithSubject) Matches(actual any) bool {
+	result, ok := actual.(*trust.Result)
+	return ok && result != nil && result.Subject == string(r)
+}
+
+// anyArgumentMatcher matches any argument
+type anyArgumentMatcher struct{}
+
+// AnyArgument returns a matcher that matches any argument, to skip checking it
+func AnyArgument() ArgumentMatcher {
+	return anyArgumentMatcher{}
+}
+
+func (anyArgumentMatcher) Matches(actual any) bool {
+	return true
+}
+
 // AnyError matches any non-nil error
 type anyErrorMatcher struct{}
 
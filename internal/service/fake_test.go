@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+func TestFakeObserver_ConcurrentProbes(t *testing.T) {
+	observer := NewFakeObserver(t)
+	ctx := context.Background()
+
+	_, check := observer.AuthzCheckStarted(ctx)
+	check.SubjectValidationSucceeded(&trust.Result{Subject: "alice"})
+
+	var wg sync.WaitGroup
+	for _, tokenType := range []TokenType{TokenTypeTransactionToken, TokenTypeAccessToken} {
+		wg.Go(func() {
+			_, probe := observer.TokenIssuanceStarted(ctx, &trust.Result{Subject: "alice"}, nil, "", []TokenType{tokenType})
+			if tokenType == TokenTypeAccessToken {
+				probe.TokenTypeIssuanceFailed(tokenType, perr.New(perr.ErrCodeIssuerUnavailable, "signer unavailable"))
+			} else {
+				probe.TokenTypeIssuanceSucceeded(tokenType, &Token{})
+			}
+			probe.End()
+		})
+	}
+	wg.Wait()
+	check.End()
+
+	issuances := observer.ProbesStartedWith("TokenIssuanceStarted")
+	if len(issuances) != 2 {
+		t.Fatalf("expected 2 issuance probes, got %d", len(issuances))
+	}
+	var failures int
+	for _, probe := range issuances {
+		for _, err := range probe.Errors() {
+			if !perr.HasCode(err, perr.ErrCodeIssuerUnavailable) {
+				t.Errorf("unexpected error %v", err)
+			}
+			failures++
+		}
+		if args := probe.CallArgs("End"); len(args) != 1 {
+			t.Errorf("expected one End call, got %d", len(args))
+		}
+	}
+	if failures != 1 {
+		t.Errorf("expected 1 captured failure, got %d", failures)
+	}
+
+	observer.AssertCallsInOrder(
+		InProbe("AuthzCheckStarted", ProbeCall("SubjectValidationSucceeded", ResultWithSubject("alice"))),
+		InProbe("TokenIssuanceStarted", ProbeCall("TokenTypeIssuanceFailed", TokenTypeAccessToken, ErrorWithCode(perr.ErrCodeIssuerUnavailable))),
+		InProbe("AuthzCheckStarted", "End"),
+	)
+}
+
+func TestFakeObserver_AssertCallsInOrderFails(t *testing.T) {
+	inner := &testing.T{}
+	observer := NewFakeObserver(inner)
+	ctx := context.Background()
+
+	_, exchange := observer.TokenExchangeStarted(ctx, "", "", "", "")
+	exchange.End()
+	exchange.SubjectTokenValidationFailed(errors.New("expired"))
+
+	observer.AssertCallsInOrder(
+		InProbe("TokenExchangeStarted", "SubjectTokenValidationFailed"),
+		InProbe("TokenExchangeStarted", "End"),
+	)
+	if !inner.Failed() {
+		t.Error("expected calls out of order to fail the assertion")
+	}
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// ExternalSubjectClaim is the subject claim keeping the original subject of a
+// subject mapped to a principal
+const ExternalSubjectClaim = "external_sub"
+
+// IdentityMapper translates the subjects of external credentials, which each
+// identity provider assigns differently, into canonical principal IDs, so the
+// same person gets the same subject in tokens whichever IdP they signed in with
+type IdentityMapper interface {
+	// MapIdentity returns the principal ID of subject, or "" if it is not mapped
+	MapIdentity(ctx context.Context, subject *trust.Result) (string, error)
+}
+
+// ErrUnmappedSubject is returned when identity mapping is required and the
+// subject is not mapped to a principal
+var ErrUnmappedSubject = perr.New(perr.ErrCodeInvalidSubjectToken, "subject is not mapped to a principal")
+
+// WithIdentityMapper maps the subject of each issuance to its principal ID
+// before claims are mapped and tokens issued. A mapped subject's Subject is
+// the principal ID and its original subject is kept in the ExternalSubjectClaim
+// claim. Unmapped subjects keep their subject, unless required is set, in which
+// case issuance fails with ErrUnmappedSubject.
+func WithIdentityMapper(mapper IdentityMapper, required bool) TokenServiceOption {
+	return func(ts *TokenService) {
+		ts.identityMapper = mapper
+		ts.identityRequired = required
+	}
+}
+
+// mapIdentity returns subject as the principal it is mapped to
+func (ts *TokenService) mapIdentity(ctx context.Context, subject *trust.Result) (*trust.Result, error) {
+	if ts.identityMapper == nil || subject == nil {
+		return subject, nil
+	}
+
+	principal, err := ts.identityMapper.MapIdentity(ctx, subject)
+	if err != nil {
+		if !perr.IsCoded(err) {
+			err = perr.Errorf(perr.ErrCodeIssuerUnavailable, "%w", err)
+		}
+		return nil, fmt.Errorf("failed to map subject identity: %w", err)
+	}
+	if principal == "" {
+		if ts.identityRequired {
+			return nil, fmt.Errorf("%w: %s in trust domain %s", ErrUnmappedSubject, subject.Subject, subject.TrustDomain)
+		}
+		return subject, nil
+	}
+
+	mapped := *subject
+	mapped.Claims = maps.Clone(subject.Claims)
+	if mapped.Claims == nil {
+		mapped.Claims = make(claims.Claims, 1)
+	}
+	mapped.Claims[ExternalSubjectClaim] = subject.Subject
+	mapped.Subject = principal
+	return &mapped, nil
+}
+
+// IdentityMapping maps one external subject to a principal
+type IdentityMapping struct {
+	// Principal is the canonical principal ID
+	Principal string
+
+	// TrustDomain and Subject identify the external subject
+	TrustDomain string
+	Subject     string
+}
+
+// identityKey identifies an external subject
+type identityKey struct {
+	trustDomain string
+	subject     string
+}
+
+// StaticIdentityMapper maps subjects with a fixed table
+type StaticIdentityMapper struct {
+	principals map[identityKey]string
+}
+
+// NewStaticIdentityMapper creates an identity mapper from a table of mappings
+// A subject may be mapped to only one principal.
+func NewStaticIdentityMapper(mappings []IdentityMapping) (*StaticIdentityMapper, error) {
+	principals := make(map[identityKey]string, len(mappings))
+	for _, m := range mappings {
+		if m.Principal == "" || m.TrustDomain == "" || m.Subject == "" {
+			return nil, fmt.Errorf("identity mapping requires principal, trust domain and subject")
+		}
+		key := identityKey{trustDomain: m.TrustDomain, subject: m.Subject}
+		if existing, ok := principals[key]; ok && existing != m.Principal {
+			return nil, fmt.Errorf("subject %s in trust domain %s is mapped to both %s and %s",
+				m.Subject, m.TrustDomain, existing, m.Principal)
+		}
+		principals[key] = m.Principal
+	}
+	return &StaticIdentityMapper{principals: principals}, nil
+}
+
+// MapIdentity implements IdentityMapper
+func (m *StaticIdentityMapper) MapIdentity(ctx context.Context, subject *trust.Result) (string, error) {
+	return m.principals[identityKey{trustDomain: subject.TrustDomain, subject: subject.Subject}], nil
+}
+
+// DataSourceIdentityMapper looks subjects up with a data source, e.g. a
+// directory of linked accounts. The data source is fetched with the subject
+// and returns a JSON object whose field holds the principal ID; a nil result,
+// or an object without the field, leaves the subject unmapped. Data source
+// caching applies.
+type DataSourceIdentityMapper struct {
+	dataSources *DataSourceRegistry
+	name        string
+	field       string
+}
+
+// DefaultIdentityField is the field of data source results holding the
+// principal ID unless another one is configured
+const DefaultIdentityField = "principal"
+
+// NewDataSourceIdentityMapper creates an identity mapper backed by the named
+// data source. field is the result field holding the principal ID
+// (DefaultIdentityField if empty).
+func NewDataSourceIdentityMapper(dataSources *DataSourceRegistry, name, field string) (*DataSourceIdentityMapper, error) {
+	if dataSources == nil || dataSources.Get(name) == nil {
+		return nil, fmt.Errorf("data source %s not found", name)
+	}
+	if field == "" {
+		field = DefaultIdentityField
+	}
+	return &DataSourceIdentityMapper{
+		dataSources: dataSources,
+		name:        name,
+		field:       field,
+	}, nil
+}
+
+// MapIdentity implements IdentityMapper
+func (m *DataSourceIdentityMapper) MapIdentity(ctx context.Context, subject *trust.Result) (string, error) {
+	result, err := m.dataSources.Get(m.name).Fetch(ctx, &DataSourceInput{Subject: subject})
+	if err != nil {
+		return "", fmt.Errorf("data source %s: %w", m.name, err)
+	}
+	if result == nil {
+		return "", nil
+	}
+	if result.ContentType != ContentTypeJSON {
+		return "", fmt.Errorf("data source %s returned unsupported content type %s", m.name, result.ContentType)
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal(result.Data, &data); err != nil {
+		return "", fmt.Errorf("data source %s returned invalid JSON: %w", m.name, err)
+	}
+	switch principal := data[m.field].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return principal, nil
+	default:
+		return "", fmt.Errorf("data source %s field %s must be a string, got %T", m.name, m.field, principal)
+	}
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// subjectRecorder records the subject it was asked to issue for
+type subjectRecorder struct {
+	subject *trust.Result
+}
+
+func (i *subjectRecorder) Issue(ctx context.Context, issueCtx *IssueContext) (*Token, error) {
+	i.subject = issueCtx.Subject
+	return &Token{Value: "token"}, nil
+}
+
+func (i *subjectRecorder) PublicKeys(ctx context.Context) ([]PublicKey, error) {
+	return nil, nil
+}
+
+// directoryDataSource returns a fixed JSON document per subject
+type directoryDataSource struct {
+	entries map[string]string
+	err     error
+}
+
+func (d *directoryDataSource) Name() string { return "directory" }
+
+func (d *directoryDataSource) Fetch(ctx context.Context, input *DataSourceInput) (*DataSourceResult, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	entry, ok := d.entries[input.Subject.TrustDomain+"/"+input.Subject.Subject]
+	if !ok {
+		return nil, nil
+	}
+	return &DataSourceResult{Data: []byte(entry), ContentType: ContentTypeJSON}, nil
+}
+
+func TestTokenService_IdentityMapping(t *testing.T) {
+	mapper, err := NewStaticIdentityMapper([]IdentityMapping{
+		{Principal: "p-alice", TrustDomain: "corp.example.com", Subject: "alice"},
+		{Principal: "p-alice", TrustDomain: "github.com", Subject: "1234567"},
+		{Principal: "p-alice", TrustDomain: "customers.example.com", Subject: "alice@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("NewStaticIdentityMapper failed: %v", err)
+	}
+
+	issue := func(t *testing.T, required bool, subject *trust.Result) (*trust.Result, error) {
+		t.Helper()
+		recorder := &subjectRecorder{}
+		registry := NewSimpleRegistry()
+		registry.Register(TokenTypeTransactionToken, recorder)
+		service := NewTokenService("trust.example.com", nil, registry, nil, WithIdentityMapper(mapper, required))
+		_, err := service.IssueTokens(context.Background(), &IssueRequest{
+			Subject:    subject,
+			TokenTypes: []TokenType{TokenTypeTransactionToken},
+		})
+		return recorder.subject, err
+	}
+
+	t.Run("same principal from every identity provider", func(t *testing.T) {
+		for _, subject := range []*trust.Result{
+			{Subject: "alice", TrustDomain: "corp.example.com"},
+			{Subject: "1234567", TrustDomain: "github.com", Claims: claims.Claims{"login": "alice"}},
+			{Subject: "alice@example.com", TrustDomain: "customers.example.com"},
+		} {
+			mapped, err := issue(t, false, subject)
+			if err != nil {
+				t.Fatalf("IssueTokens failed: %v", err)
+			}
+			if mapped.Subject != "p-alice" || mapped.TrustDomain != subject.TrustDomain {
+				t.Errorf("expected p-alice in %s, got %s in %s", subject.TrustDomain, mapped.Subject, mapped.TrustDomain)
+			}
+			if mapped.Claims[ExternalSubjectClaim] != subject.Subject {
+				t.Errorf("expected external subject %s, got %v", subject.Subject, mapped.Claims[ExternalSubjectClaim])
+			}
+			if _, ok := subject.Claims[ExternalSubjectClaim]; ok {
+				t.Error("expected the validated subject to be unchanged")
+			}
+		}
+	})
+
+	t.Run("subject of another trust domain is not mapped", func(t *testing.T) {
+		subject := &trust.Result{Subject: "alice", TrustDomain: "partner.example.com"}
+		mapped, err := issue(t, false, subject)
+		if err != nil {
+			t.Fatalf("IssueTokens failed: %v", err)
+		}
+		if mapped != subject {
+			t.Errorf("expected unmapped subject to be kept, got %+v", mapped)
+		}
+	})
+
+	t.Run("unmapped subject is rejected when required", func(t *testing.T) {
+		_, err := issue(t, true, &trust.Result{Subject: "mallory", TrustDomain: "corp.example.com"})
+		if !errors.Is(err, ErrUnmappedSubject) {
+			t.Fatalf("expected ErrUnmappedSubject, got %v", err)
+		}
+		if perr.CodeOf(err) != perr.ErrCodeInvalidSubjectToken {
+			t.Errorf("expected invalid_subject_token, got %s", perr.CodeOf(err))
+		}
+	})
+}
+
+func TestNewStaticIdentityMapper_Conflicts(t *testing.T) {
+	_, err := NewStaticIdentityMapper([]IdentityMapping{
+		{Principal: "p-alice", TrustDomain: "corp.example.com", Subject: "alice"},
+		{Principal: "p-bob", TrustDomain: "corp.example.com", Subject: "alice"},
+	})
+	if err == nil {
+		t.Error("expected error for a subject mapped to two principals")
+	}
+
+	if _, err := NewStaticIdentityMapper([]IdentityMapping{{Principal: "p-alice", Subject: "alice"}}); err == nil {
+		t.Error("expected error without trust domain")
+	}
+}
+
+func TestDataSourceIdentityMapper(t *testing.T) {
+	ctx := context.Background()
+	directory := &directoryDataSource{entries: map[string]string{
+		"github.com/1234567":     `{"principal": "p-alice", "display_name": "Alice"}`,
+		"github.com/7654321":     `{"display_name": "Unlinked"}`,
+		"corp.example.com/bob":   `{"person_id": "p-bob"}`,
+		"corp.example.com/carol": `{"principal": 42}`,
+	}}
+	dataSources := NewDataSourceRegistry()
+	dataSources.Register(directory)
+
+	mapper, err := NewDataSourceIdentityMapper(dataSources, "directory", "")
+	if err != nil {
+		t.Fatalf("NewDataSourceIdentityMapper failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		subject *trust.Result
+		want    string
+		wantErr bool
+	}{
+		{"linked account", &trust.Result{Subject: "1234567", TrustDomain: "github.com"}, "p-alice", false},
+		{"entry without principal", &trust.Result{Subject: "7654321", TrustDomain: "github.com"}, "", false},
+		{"no entry", &trust.Result{Subject: "dave", TrustDomain: "corp.example.com"}, "", false},
+		{"principal not a string", &trust.Result{Subject: "carol", TrustDomain: "corp.example.com"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mapper.MapIdentity(ctx, tt.subject)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MapIdentity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("MapIdentity() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("custom field", func(t *testing.T) {
+		mapper, err := NewDataSourceIdentityMapper(dataSources, "directory", "person_id")
+		if err != nil {
+			t.Fatalf("NewDataSourceIdentityMapper failed: %v", err)
+		}
+		if got, _ := mapper.MapIdentity(ctx, &trust.Result{Subject: "bob", TrustDomain: "corp.example.com"}); got != "p-bob" {
+			t.Errorf("expected p-bob, got %q", got)
+		}
+	})
+
+	t.Run("data source failure fails issuance", func(t *testing.T) {
+		failing := NewDataSourceRegistry()
+		failing.Register(&directoryDataSource{err: errors.New("directory unavailable")})
+		mapper, err := NewDataSourceIdentityMapper(failing, "directory", "")
+		if err != nil {
+			t.Fatalf("NewDataSourceIdentityMapper failed: %v", err)
+		}
+		registry := NewSimpleRegistry()
+		registry.Register(TokenTypeTransactionToken, &subjectRecorder{})
+		service := NewTokenService("trust.example.com", failing, registry, nil, WithIdentityMapper(mapper, false))
+		_, err = service.IssueTokens(ctx, &IssueRequest{
+			Subject:    &trust.Result{Subject: "alice", TrustDomain: "corp.example.com"},
+			TokenTypes: []TokenType{TokenTypeTransactionToken},
+		})
+		if perr.CodeOf(err) != perr.ErrCodeIssuerUnavailable {
+			t.Errorf("expected issuer_unavailable, got %v", err)
+		}
+	})
+
+	if _, err := NewDataSourceIdentityMapper(dataSources, "accounts", ""); err == nil {
+		t.Error("expected error for unknown data source")
+	}
+}
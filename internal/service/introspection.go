@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+
+	"github.com/project-kessel/parsec/internal/claims"
+)
+
+// TokenIntrospector is implemented by issuers that can resolve their own tokens back to claims
+// This is used by the introspection endpoint (RFC 7662) for tokens whose claims
+// are not readable by the holder, such as opaque reference tokens
+type TokenIntrospector interface {
+	// Introspect resolves a token value issued by this issuer.
+	// Returns an inactive result (not an error) if the token is unknown or expired.
+	Introspect(ctx context.Context, token string) (*IntrospectionResult, error)
+}
+
+// IntrospectionResult describes the state of an introspected token
+type IntrospectionResult struct {
+	// Active indicates whether the token is currently valid
+	Active bool
+
+	// TokenType is the type of the introspected token (e.g., a token type URN)
+	TokenType string
+
+	// Claims are the claims associated with the token (only set when active)
+	Claims claims.Claims
+}
+
+// InactiveIntrospectionResult returns a result for an unknown, expired, or revoked token
+func InactiveIntrospectionResult() *IntrospectionResult {
+	return &IntrospectionResult{Active: false}
+}
@@ -3,9 +3,13 @@ package service
 import (
 	"context"
 	"crypto"
+	"maps"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/clock"
 	"github.com/project-kessel/parsec/internal/request"
 	"github.com/project-kessel/parsec/internal/trust"
 )
@@ -23,43 +27,153 @@ type IssueContext struct {
 	RequestAttributes *request.RequestAttributes
 
 	// Audience for the token (aud claim) - typically the trust domain
+	// When the token is for several audiences, it is the first of Audiences.
 	Audience string
 
+	// Audiences are all the audiences the token is for (optional)
+	// If empty, the token is for Audience alone; see TokenAudiences.
+	Audiences []string
+
 	// Scope for the token (scope claim)
 	Scope string
 
+	// Purpose is the requested purpose of the transaction (purp claim)
+	Purpose string
+
+	// Delegation records an approved delegation of the subject to the actor
+	// Issuers that support it reflect this in the "act" claim
+	Delegation *trust.Delegation
+
 	// DataSourceRegistry provides access to data sources for lazy fetching
 	DataSourceRegistry *DataSourceRegistry
+
+	// IssuedAt is when the tokens are issued, from which issuers compute iat
+	// and expiry. If zero, issuers use their own clock.
+	IssuedAt time.Time
+
+	// Risk is the combined assessment of the risk evaluators (nil without
+	// evaluators, or if none had an opinion)
+	// Issuers cap token lifetimes with CapTTL.
+	Risk *RiskAssessment
+}
+
+// IssueTime returns when the token is issued: ic.IssuedAt, or clk's current
+// time if it's not set
+func (ic *IssueContext) IssueTime(clk clock.Clock) time.Time {
+	if ic != nil && !ic.IssuedAt.IsZero() {
+		return ic.IssuedAt
+	}
+	return clk.Now()
+}
+
+// TokenAudiences returns the audiences of the token (aud claim): Audiences,
+// or Audience alone if none are set
+func (ic *IssueContext) TokenAudiences() []string {
+	if len(ic.Audiences) > 0 {
+		return ic.Audiences
+	}
+	return []string{ic.Audience}
+}
+
+// clone returns a copy of the context that does not share the request
+// attributes or identity claims with ic, so it can be handed to work that
+// may outlive the caller
+func (ic *IssueContext) clone() *IssueContext {
+	c := *ic
+	c.Subject = cloneResult(ic.Subject)
+	c.Actor = cloneResult(ic.Actor)
+	c.Audiences = slices.Clone(ic.Audiences)
+	if ic.Risk != nil {
+		risk := *ic.Risk
+		risk.Reasons = slices.Clone(ic.Risk.Reasons)
+		c.Risk = &risk
+	}
+	if ic.RequestAttributes != nil {
+		c.RequestAttributes = ic.RequestAttributes.Clone()
+	}
+	return &c
+}
+
+func cloneResult(r *trust.Result) *trust.Result {
+	if r == nil {
+		return nil
+	}
+	c := *r
+	c.Claims = maps.Clone(r.Claims)
+	c.Audience = slices.Clone(r.Audience)
+	return &c
+}
+
+// mapperInputs holds the inputs of one ToClaims call, pooled so that mapping
+// does not allocate them per call
+type mapperInputs struct {
+	mapper     MapperInput
+	dataSource DataSourceInput
 }
 
+var mapperInputsPool = sync.Pool{New: func() any { return new(mapperInputs) }}
+
 // ToClaims applies a set of claim mappers to produce claims
 // This is a convenience method to reduce duplication in issuer implementations
+//
+// The MapperInput and DataSourceInput handed to the mappers are reused once
+// ToClaims returns, so mappers and data sources must not retain them.
 func (ic *IssueContext) ToClaims(ctx context.Context, mappers []ClaimMapper) (claims.Claims, error) {
+	if len(mappers) == 0 {
+		return claims.Claims{}, nil
+	}
+
+	inputs := mapperInputsPool.Get().(*mapperInputs)
+	defer func() {
+		*inputs = mapperInputs{}
+		mapperInputsPool.Put(inputs)
+	}()
+
 	// Build data source input
-	dataSourceInput := &DataSourceInput{
+	inputs.dataSource = DataSourceInput{
 		Subject:           ic.Subject,
 		Actor:             ic.Actor,
 		RequestAttributes: ic.RequestAttributes,
 	}
 
 	// Build mapper input
-	mapperInput := &MapperInput{
+	inputs.mapper = MapperInput{
 		Subject:            ic.Subject,
 		Actor:              ic.Actor,
 		RequestAttributes:  ic.RequestAttributes,
 		DataSourceRegistry: ic.DataSourceRegistry,
-		DataSourceInput:    dataSourceInput,
+		DataSourceInput:    &inputs.dataSource,
+		Risk:               ic.Risk,
 	}
 
+	// Labeled mappers record the sources of their claims if the issuance
+	// records claim provenance
+	provenance := claimProvenanceFrom(ctx)
+
 	// Apply mappers
-	result := make(claims.Claims)
+	// The first mapper's claims are cloned rather than merged into an empty
+	// map, so the result is allocated once at its size
+	var result claims.Claims
 	for _, mapper := range mappers {
-		mapperClaims, err := mapper.Map(ctx, mapperInput)
+		var mapperClaims claims.Claims
+		var err error
+		if labeled, ok := mapper.(*labeledClaimMapper); ok && provenance != nil {
+			mapperClaims, err = provenance.mapClaims(ctx, labeled, &inputs.mapper)
+		} else {
+			mapperClaims, err = mapper.Map(ctx, &inputs.mapper)
+		}
 		if err != nil {
 			return nil, err
 		}
+		if result == nil {
+			result = maps.Clone(mapperClaims)
+			continue
+		}
 		result.Merge(mapperClaims)
 	}
+	if result == nil {
+		result = claims.Claims{}
+	}
 
 	return result, nil
 }
@@ -106,6 +220,39 @@ type Token struct {
 
 	// IssuedAt is when the token was issued
 	IssuedAt time.Time
+
+	// ID is the "jti" claim of the token (empty if the issuer sets none)
+	ID string
+
+	// Audience is the "aud" claim of the token (nil if the issuer sets none)
+	Audience []string
+
+	// Compaction describes how the token was compacted to fit its size budget
+	// (nil if no compaction was needed)
+	Compaction *CompactionReport
+
+	// TransactionID is the "txn" claim of a transaction token (empty for
+	// other token types)
+	TransactionID string
+
+	// Provenance records where the token's claims came from, for audit
+	// events rather than the token (nil unless the issuer records it)
+	Provenance []ClaimSource
+}
+
+// CompactionReport describes compaction applied to an over-budget token
+type CompactionReport struct {
+	// MaxSize is the size budget in bytes
+	MaxSize int
+
+	// OriginalSize is the encoded size before compaction
+	OriginalSize int
+
+	// FinalSize is the encoded size after compaction
+	FinalSize int
+
+	// Steps describes the compaction steps that were applied, in order
+	Steps []string
 }
 
 // TokenClaims represents the claims in a transaction token
@@ -130,6 +277,9 @@ type TokenClaims struct {
 	// Purpose of the token
 	Purpose string `json:"purp,omitempty"`
 
+	// Authorization details - fine-grained authorization data for the transaction
+	AuthorizationDetails claims.Claims `json:"azd,omitempty"`
+
 	// Request context - information about the request being authorized
 	RequestContext claims.Claims `json:"req_ctx,omitempty"`
 
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// inputRecordingMapper records copies of the inputs it is given, which are
+// reused once mapping returns
+type inputRecordingMapper struct {
+	inputs           []MapperInput
+	dataSourceInputs []DataSourceInput
+}
+
+func (m *inputRecordingMapper) Map(ctx context.Context, input *MapperInput) (claims.Claims, error) {
+	m.inputs = append(m.inputs, *input)
+	m.dataSourceInputs = append(m.dataSourceInputs, *input.DataSourceInput)
+	return nil, nil
+}
+
+func TestIssueContext_ToClaims(t *testing.T) {
+	ctx := context.Background()
+	issueCtx := &IssueContext{
+		Subject:            &trust.Result{Subject: "alice"},
+		Actor:              &trust.Result{Subject: "gateway"},
+		RequestAttributes:  &request.RequestAttributes{Method: "GET", Path: "/api"},
+		DataSourceRegistry: NewDataSourceRegistry(),
+	}
+
+	t.Run("later mappers override earlier ones", func(t *testing.T) {
+		first := NewStubClaimMapper(claims.Claims{"a": 1, "b": 1})
+		second := NewStubClaimMapper(claims.Claims{"b": 2})
+
+		got, err := issueCtx.ToClaims(ctx, []ClaimMapper{first, second})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got["a"] != 1 || got["b"] != 2 {
+			t.Errorf("expected a=1 b=2, got %v", got)
+		}
+	})
+
+	t.Run("mapper claims are not modified", func(t *testing.T) {
+		static := claims.Claims{"a": 1}
+		got, err := issueCtx.ToClaims(ctx, []ClaimMapper{NewStubClaimMapper(static), NewStubClaimMapper(claims.Claims{"b": 2})})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got["c"] = 3
+		if len(static) != 1 {
+			t.Errorf("expected the first mapper's claims to be left alone, got %v", static)
+		}
+	})
+
+	t.Run("no mappers or no claims give empty claims", func(t *testing.T) {
+		for _, mappers := range [][]ClaimMapper{nil, {NewStubClaimMapper(nil)}} {
+			got, err := issueCtx.ToClaims(ctx, mappers)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got == nil || len(got) != 0 {
+				t.Errorf("expected empty claims, got %#v", got)
+			}
+		}
+	})
+
+	t.Run("mappers see the issue context", func(t *testing.T) {
+		recorder := &inputRecordingMapper{}
+		for range 2 {
+			if _, err := issueCtx.ToClaims(ctx, []ClaimMapper{recorder}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		for i, input := range recorder.inputs {
+			if input.Subject != issueCtx.Subject || input.Actor != issueCtx.Actor ||
+				input.RequestAttributes != issueCtx.RequestAttributes ||
+				input.DataSourceRegistry != issueCtx.DataSourceRegistry {
+				t.Errorf("unexpected mapper input %+v", input)
+			}
+			if dsInput := recorder.dataSourceInputs[i]; dsInput.Subject != issueCtx.Subject ||
+				dsInput.Actor != issueCtx.Actor || dsInput.RequestAttributes != issueCtx.RequestAttributes {
+				t.Errorf("unexpected data source input %+v", dsInput)
+			}
+		}
+	})
+}
+
+func BenchmarkIssueContext_ToClaims(b *testing.B) {
+	issueCtx := &IssueContext{
+		Subject:            &trust.Result{Subject: "alice", Claims: claims.Claims{"email": "alice@example.com"}},
+		RequestAttributes:  &request.RequestAttributes{Method: "GET", Path: "/api"},
+		DataSourceRegistry: NewDataSourceRegistry(),
+	}
+	mappers := []ClaimMapper{NewPassthroughSubjectMapper(), NewRequestAttributesMapper()}
+
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := issueCtx.ToClaims(ctx, mappers); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
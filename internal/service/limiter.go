@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/project-kessel/parsec/internal/perr"
+)
+
+// IssuanceLimiter admits or refuses token issuance before any token is issued,
+// e.g. to cap the tokens issued to a subject or actor per time window
+type IssuanceLimiter interface {
+	// Admit counts the issuance of tokenTypes in issueCtx, returning an error
+	// if it is refused. Refusals over a quota have code perr.ErrCodeRateLimited
+	// and, when known, the delay before retrying (see perr.RetryAfterOf).
+	Admit(ctx context.Context, issueCtx *IssueContext, tokenTypes []TokenType) error
+}
+
+// WithIssuanceLimiter admits each issuance through limiter once the subject is
+// mapped, before claims are mapped and tokens issued
+func WithIssuanceLimiter(limiter IssuanceLimiter) TokenServiceOption {
+	return func(ts *TokenService) {
+		ts.limiter = limiter
+	}
+}
+
+// admit admits an issuance through the limiter, if there is one
+func (ts *TokenService) admit(ctx context.Context, issueCtx *IssueContext, tokenTypes []TokenType) error {
+	if ts.limiter == nil || len(tokenTypes) == 0 {
+		return nil
+	}
+	if err := ts.limiter.Admit(ctx, issueCtx, tokenTypes); err != nil {
+		// Failures the limiter did not classify mean it could not decide
+		if !perr.IsCoded(err) {
+			err = perr.Errorf(perr.ErrCodeIssuerUnavailable, "%w", err)
+		}
+		return fmt.Errorf("issuance not admitted: %w", err)
+	}
+	return nil
+}
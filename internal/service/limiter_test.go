@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// refusingLimiter refuses every issuance with err, recording the token types
+type refusingLimiter struct {
+	err        error
+	tokenTypes []TokenType
+}
+
+func (l *refusingLimiter) Admit(ctx context.Context, issueCtx *IssueContext, tokenTypes []TokenType) error {
+	l.tokenTypes = tokenTypes
+	return l.err
+}
+
+func TestTokenService_IssuanceLimiter(t *testing.T) {
+	issue := func(t *testing.T, limiter IssuanceLimiter, observer TokenServiceObserver) (*subjectRecorder, error) {
+		t.Helper()
+		recorder := &subjectRecorder{}
+		registry := NewSimpleRegistry()
+		registry.Register(TokenTypeTransactionToken, recorder)
+		service := NewTokenService("trust.example.com", nil, registry, observer, WithIssuanceLimiter(limiter))
+		_, err := service.IssueTokens(context.Background(), &IssueRequest{
+			Subject:    &trust.Result{Subject: "alice", TrustDomain: "example.com"},
+			TokenTypes: []TokenType{TokenTypeTransactionToken},
+		})
+		return recorder, err
+	}
+
+	t.Run("admitted issuance issues tokens", func(t *testing.T) {
+		limiter := &refusingLimiter{}
+		recorder, err := issue(t, limiter, nil)
+		if err != nil {
+			t.Fatalf("IssueTokens failed: %v", err)
+		}
+		if recorder.subject == nil || len(limiter.tokenTypes) != 1 {
+			t.Errorf("expected the token to be issued and counted, got %v and %v", recorder.subject, limiter.tokenTypes)
+		}
+	})
+
+	t.Run("refused issuance issues nothing", func(t *testing.T) {
+		fakeObs := NewFakeObserver(t)
+		limited := perr.Errorf(perr.ErrCodeRateLimited, "quota exceeded").WithRetryAfter(time.Minute)
+		recorder, err := issue(t, &refusingLimiter{err: limited}, fakeObs)
+		if !perr.HasCode(err, perr.ErrCodeRateLimited) || perr.RetryAfterOf(err) != time.Minute {
+			t.Fatalf("expected rate_limited with a retry delay, got %v", err)
+		}
+		if recorder.subject != nil {
+			t.Error("expected no token to be issued")
+		}
+		fakeObs.AssertSingleProbe("TokenIssuanceStarted", nil).AssertProbeSequence(
+			ProbeCall("IssuanceRefused", ErrorWithCode(perr.ErrCodeRateLimited)),
+			"End",
+		)
+	})
+
+	t.Run("limiter failures are issuer_unavailable", func(t *testing.T) {
+		_, err := issue(t, &refusingLimiter{err: errors.New("store down")}, nil)
+		if !perr.HasCode(err, perr.ErrCodeIssuerUnavailable) {
+			t.Errorf("expected issuer_unavailable, got %v", err)
+		}
+	})
+}
@@ -17,6 +17,8 @@ type ClaimMapper interface {
 }
 
 // MapperInput contains all inputs available to a claim mapper
+// Inputs are reused across issuances, so mappers must not retain them (or
+// their DataSourceInput) beyond Map.
 type MapperInput struct {
 	// Subject identity (attested claims from validated credential)
 	Subject *trust.Result
@@ -33,4 +35,7 @@ type MapperInput struct {
 
 	// DataSourceInput is the input to use when fetching from data sources
 	DataSourceInput *DataSourceInput
+
+	// Risk is the risk assessment of the issuance (nil if there is none)
+	Risk *RiskAssessment
 }
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/project-kessel/parsec/internal/request"
 	"github.com/project-kessel/parsec/internal/trust"
@@ -39,6 +40,17 @@ type TokenIssuanceProbe interface {
 	// IssuerNotFound is called when no issuer is registered for a requested token type.
 	IssuerNotFound(tokenType TokenType, err error)
 
+	// TokenCompacted is called when a token was compacted to fit its size budget.
+	TokenCompacted(tokenType TokenType, report *CompactionReport)
+
+	// IssuanceRefused is called when the issuance limiter or risk evaluation
+	// refuses the issuance, before any token is issued.
+	IssuanceRefused(err error)
+
+	// OptionalMapperFailed is called when an optional claim mapper fails, so
+	// its claims are omitted from the tokens rather than failing the issuance.
+	OptionalMapperFailed(mapper string, err error)
+
 	// End terminates the observation. Should be deferred to ensure cleanup.
 	// The probe determines success/failure based on methods called before End().
 	End()
@@ -66,12 +78,19 @@ type TokenExchangeProbe interface {
 	// RequestContextParseFailed is called when request_context parsing fails.
 	RequestContextParseFailed(err error)
 
+	// RequestContextClaimsRejected is called when the claims filter drops request_context claims.
+	// Only claim names are reported, never values.
+	RequestContextClaimsRejected(names []string)
+
 	// SubjectTokenValidationSucceeded is called when subject token validation succeeds.
 	SubjectTokenValidationSucceeded(subject *trust.Result)
 
 	// SubjectTokenValidationFailed is called when subject token validation fails.
 	SubjectTokenValidationFailed(err error)
 
+	// DelegationDenied is called when the actor is not permitted to act for the subject.
+	DelegationDenied(err error)
+
 	// End terminates the observation. Should be deferred to ensure cleanup.
 	End()
 }
@@ -89,6 +108,17 @@ type AuthzCheckProbe interface {
 	// RequestAttributesParsed is called when request attributes are built from the incoming request.
 	RequestAttributesParsed(attrs *request.RequestAttributes)
 
+	// RequestAttributeExtractionFailed is called when a configured request
+	// attribute extractor fails, before the check is denied.
+	RequestAttributeExtractionFailed(err error)
+
+	// ClientAddressDenied is called when the IP policy denies the client address.
+	ClientAddressDenied(address string, err error)
+
+	// CheckSkipped is called when the request path is on the skip list, so the
+	// request is allowed without validation or token issuance.
+	CheckSkipped(path string)
+
 	// ActorValidationSucceeded is called when actor credential validation succeeds.
 	ActorValidationSucceeded(actor *trust.Result)
 
@@ -107,17 +137,64 @@ type AuthzCheckProbe interface {
 	// SubjectValidationFailed is called when subject credential validation fails.
 	SubjectValidationFailed(err error)
 
+	// TokensIssued is called when the tokens for the check are issued.
+	// The issuance itself is observed by a TokenIssuanceProbe started with the
+	// context returned by AuthzCheckStarted, so a trace started there spans
+	// validation, claim mapping and signing.
+	TokensIssued(tokenTypes []TokenType)
+
+	// TokenIssuanceFailed is called when the tokens for the check cannot be issued.
+	TokenIssuanceFailed(err error)
+
+	// ResponseHeaderTooLarge is called when the token for a response header is
+	// over the configured size limit, before it is replaced or the check denied.
+	ResponseHeaderTooLarge(header string, size int, limit int)
+
+	// ResponseHeadersEmitted is called when the check allows the request, with the
+	// names of the headers added to and removed from it. Values are never reported.
+	ResponseHeadersEmitted(added []string, removed []string)
+
 	// End terminates the observation. Should be deferred to ensure cleanup.
 	End()
 }
 
+// BulkheadObserver creates call-scoped observability probes for bulkheads, which
+// bound the concurrent calls to an issuer or data source.
+// Follows the same pattern as TokenServiceObserver.
+type BulkheadObserver interface {
+	// BulkheadCallStarted creates a new probe for a call through the named bulkhead.
+	// Returns an instrumented context and a probe scoped to this call.
+	BulkheadCallStarted(ctx context.Context, bulkhead string) (context.Context, BulkheadProbe)
+}
+
+// BulkheadProbe provides observability for a single call through a bulkhead.
+// Together, the queue depths and in-flight counts it reports describe the
+// saturation of each issuer and data source.
+type BulkheadProbe interface {
+	// BulkheadQueued is called when the call waits for a slot, with the number
+	// of calls waiting including this one.
+	BulkheadQueued(queued int)
+
+	// BulkheadAdmitted is called when the call takes a slot, with the number of
+	// calls now in flight and how long the call waited.
+	BulkheadAdmitted(inFlight int, waited time.Duration)
+
+	// BulkheadRejected is called when the call is turned away because the queue
+	// is full, or gives up waiting because its context ended.
+	BulkheadRejected(err error)
+
+	// End terminates the observation when the call releases its slot or is rejected.
+	End()
+}
+
 // ApplicationObserver provides a unified interface for all observability concerns in the application.
-// Concrete implementations can implement all three interfaces in a single type.
+// Concrete implementations can implement all of these interfaces in a single type.
 // Implementations can embed the NoOp* types to get default behavior for methods they don't care about.
 type ApplicationObserver interface {
 	TokenServiceObserver
 	TokenExchangeObserver
 	AuthzCheckObserver
+	BulkheadObserver
 }
 
 // compositeObserver delegates to multiple observers in order.
@@ -170,6 +247,17 @@ func (c *compositeObserver) AuthzCheckStarted(
 	return ctx, &compositeAuthzCheckProbe{probes: probes}
 }
 
+func (c *compositeObserver) BulkheadCallStarted(
+	ctx context.Context,
+	bulkhead string,
+) (context.Context, BulkheadProbe) {
+	probes := make([]BulkheadProbe, len(c.observers))
+	for i, obs := range c.observers {
+		ctx, probes[i] = obs.BulkheadCallStarted(ctx, bulkhead)
+	}
+	return ctx, &compositeBulkheadProbe{probes: probes}
+}
+
 // compositeTokenIssuanceProbe delegates to multiple probes in order.
 type compositeTokenIssuanceProbe struct {
 	probes []TokenIssuanceProbe
@@ -199,6 +287,24 @@ func (c *compositeTokenIssuanceProbe) IssuerNotFound(tokenType TokenType, err er
 	}
 }
 
+func (c *compositeTokenIssuanceProbe) TokenCompacted(tokenType TokenType, report *CompactionReport) {
+	for _, probe := range c.probes {
+		probe.TokenCompacted(tokenType, report)
+	}
+}
+
+func (c *compositeTokenIssuanceProbe) IssuanceRefused(err error) {
+	for _, probe := range c.probes {
+		probe.IssuanceRefused(err)
+	}
+}
+
+func (c *compositeTokenIssuanceProbe) OptionalMapperFailed(mapper string, err error) {
+	for _, probe := range c.probes {
+		probe.OptionalMapperFailed(mapper, err)
+	}
+}
+
 func (c *compositeTokenIssuanceProbe) End() {
 	for _, probe := range c.probes {
 		probe.End()
@@ -234,6 +340,12 @@ func (c *compositeTokenExchangeProbe) RequestContextParseFailed(err error) {
 	}
 }
 
+func (c *compositeTokenExchangeProbe) RequestContextClaimsRejected(names []string) {
+	for _, probe := range c.probes {
+		probe.RequestContextClaimsRejected(names)
+	}
+}
+
 func (c *compositeTokenExchangeProbe) SubjectTokenValidationSucceeded(subject *trust.Result) {
 	for _, probe := range c.probes {
 		probe.SubjectTokenValidationSucceeded(subject)
@@ -246,6 +358,12 @@ func (c *compositeTokenExchangeProbe) SubjectTokenValidationFailed(err error) {
 	}
 }
 
+func (c *compositeTokenExchangeProbe) DelegationDenied(err error) {
+	for _, probe := range c.probes {
+		probe.DelegationDenied(err)
+	}
+}
+
 func (c *compositeTokenExchangeProbe) End() {
 	for _, probe := range c.probes {
 		probe.End()
@@ -263,6 +381,24 @@ func (c *compositeAuthzCheckProbe) RequestAttributesParsed(attrs *request.Reques
 	}
 }
 
+func (c *compositeAuthzCheckProbe) RequestAttributeExtractionFailed(err error) {
+	for _, probe := range c.probes {
+		probe.RequestAttributeExtractionFailed(err)
+	}
+}
+
+func (c *compositeAuthzCheckProbe) ClientAddressDenied(address string, err error) {
+	for _, probe := range c.probes {
+		probe.ClientAddressDenied(address, err)
+	}
+}
+
+func (c *compositeAuthzCheckProbe) CheckSkipped(path string) {
+	for _, probe := range c.probes {
+		probe.CheckSkipped(path)
+	}
+}
+
 func (c *compositeAuthzCheckProbe) ActorValidationSucceeded(actor *trust.Result) {
 	for _, probe := range c.probes {
 		probe.ActorValidationSucceeded(actor)
@@ -299,6 +435,30 @@ func (c *compositeAuthzCheckProbe) SubjectValidationFailed(err error) {
 	}
 }
 
+func (c *compositeAuthzCheckProbe) TokensIssued(tokenTypes []TokenType) {
+	for _, probe := range c.probes {
+		probe.TokensIssued(tokenTypes)
+	}
+}
+
+func (c *compositeAuthzCheckProbe) TokenIssuanceFailed(err error) {
+	for _, probe := range c.probes {
+		probe.TokenIssuanceFailed(err)
+	}
+}
+
+func (c *compositeAuthzCheckProbe) ResponseHeaderTooLarge(header string, size int, limit int) {
+	for _, probe := range c.probes {
+		probe.ResponseHeaderTooLarge(header, size, limit)
+	}
+}
+
+func (c *compositeAuthzCheckProbe) ResponseHeadersEmitted(added []string, removed []string) {
+	for _, probe := range c.probes {
+		probe.ResponseHeadersEmitted(added, removed)
+	}
+}
+
 func (c *compositeAuthzCheckProbe) End() {
 	for _, probe := range c.probes {
 		probe.End()
@@ -314,6 +474,9 @@ func (n *NoOpTokenIssuanceProbe) TokenTypeIssuanceStarted(tokenType TokenType)
 func (n *NoOpTokenIssuanceProbe) TokenTypeIssuanceSucceeded(tokenType TokenType, token *Token) {}
 func (n *NoOpTokenIssuanceProbe) TokenTypeIssuanceFailed(tokenType TokenType, err error)       {}
 func (n *NoOpTokenIssuanceProbe) IssuerNotFound(tokenType TokenType, err error)                {}
+func (n *NoOpTokenIssuanceProbe) TokenCompacted(tokenType TokenType, report *CompactionReport) {}
+func (n *NoOpTokenIssuanceProbe) IssuanceRefused(err error)                                    {}
+func (n *NoOpTokenIssuanceProbe) OptionalMapperFailed(mapper string, err error)                {}
 func (n *NoOpTokenIssuanceProbe) End()                                                         {}
 
 // NoOpTokenExchangeProbe is an exported null object implementation of TokenExchangeProbe.
@@ -324,8 +487,10 @@ func (n *NoOpTokenExchangeProbe) ActorValidationSucceeded(actor *trust.Result)
 func (n *NoOpTokenExchangeProbe) ActorValidationFailed(err error)                       {}
 func (n *NoOpTokenExchangeProbe) RequestContextParsed(attrs *request.RequestAttributes) {}
 func (n *NoOpTokenExchangeProbe) RequestContextParseFailed(err error)                   {}
+func (n *NoOpTokenExchangeProbe) RequestContextClaimsRejected(names []string)           {}
 func (n *NoOpTokenExchangeProbe) SubjectTokenValidationSucceeded(subject *trust.Result) {}
 func (n *NoOpTokenExchangeProbe) SubjectTokenValidationFailed(err error)                {}
+func (n *NoOpTokenExchangeProbe) DelegationDenied(err error)                            {}
 func (n *NoOpTokenExchangeProbe) End()                                                  {}
 
 // NoOpAuthzCheckProbe is an exported null object implementation of AuthzCheckProbe.
@@ -333,14 +498,58 @@ func (n *NoOpTokenExchangeProbe) End()
 type NoOpAuthzCheckProbe struct{}
 
 func (n *NoOpAuthzCheckProbe) RequestAttributesParsed(attrs *request.RequestAttributes) {}
+func (n *NoOpAuthzCheckProbe) RequestAttributeExtractionFailed(err error)               {}
+func (n *NoOpAuthzCheckProbe) ClientAddressDenied(address string, err error)            {}
+func (n *NoOpAuthzCheckProbe) CheckSkipped(path string)                                 {}
 func (n *NoOpAuthzCheckProbe) ActorValidationSucceeded(actor *trust.Result)             {}
 func (n *NoOpAuthzCheckProbe) ActorValidationFailed(err error)                          {}
 func (n *NoOpAuthzCheckProbe) SubjectCredentialExtracted(cred trust.Credential, headersUsed []string) {
 }
-func (n *NoOpAuthzCheckProbe) SubjectCredentialExtractionFailed(err error)      {}
-func (n *NoOpAuthzCheckProbe) SubjectValidationSucceeded(subject *trust.Result) {}
-func (n *NoOpAuthzCheckProbe) SubjectValidationFailed(err error)                {}
-func (n *NoOpAuthzCheckProbe) End()                                             {}
+func (n *NoOpAuthzCheckProbe) SubjectCredentialExtractionFailed(err error)           {}
+func (n *NoOpAuthzCheckProbe) SubjectValidationSucceeded(subject *trust.Result)      {}
+func (n *NoOpAuthzCheckProbe) SubjectValidationFailed(err error)                     {}
+func (n *NoOpAuthzCheckProbe) TokensIssued(tokenTypes []TokenType)                   {}
+func (n *NoOpAuthzCheckProbe) TokenIssuanceFailed(err error)                         {}
+func (n *NoOpAuthzCheckProbe) ResponseHeaderTooLarge(header string, size, limit int) {}
+func (n *NoOpAuthzCheckProbe) ResponseHeadersEmitted(added, removed []string)        {}
+func (n *NoOpAuthzCheckProbe) End()                                                  {}
+
+// compositeBulkheadProbe delegates to multiple probes in order.
+type compositeBulkheadProbe struct {
+	probes []BulkheadProbe
+}
+
+func (c *compositeBulkheadProbe) BulkheadQueued(queued int) {
+	for _, p := range c.probes {
+		p.BulkheadQueued(queued)
+	}
+}
+
+func (c *compositeBulkheadProbe) BulkheadAdmitted(inFlight int, waited time.Duration) {
+	for _, p := range c.probes {
+		p.BulkheadAdmitted(inFlight, waited)
+	}
+}
+
+func (c *compositeBulkheadProbe) BulkheadRejected(err error) {
+	for _, p := range c.probes {
+		p.BulkheadRejected(err)
+	}
+}
+
+func (c *compositeBulkheadProbe) End() {
+	for _, p := range c.probes {
+		p.End()
+	}
+}
+
+// NoOpBulkheadProbe is a no-op implementation of BulkheadProbe.
+type NoOpBulkheadProbe struct{}
+
+func (n *NoOpBulkheadProbe) BulkheadQueued(queued int)                           {}
+func (n *NoOpBulkheadProbe) BulkheadAdmitted(inFlight int, waited time.Duration) {}
+func (n *NoOpBulkheadProbe) BulkheadRejected(err error)                          {}
+func (n *NoOpBulkheadProbe) End()                                                {}
 
 // NoOpApplicationObserver implements ApplicationObserver with no-op behavior.
 // Use this as a default when no observability is needed.
@@ -362,6 +571,11 @@ func NoOpAuthzCheckObserver() AuthzCheckObserver {
 	return &NoOpApplicationObserver{}
 }
 
+// NoOpBulkheadObserver returns an observer that does nothing.
+func NoOpBulkheadObserver() BulkheadObserver {
+	return &NoOpApplicationObserver{}
+}
+
 // NoOpObserver returns an application observer that does nothing.
 func NoOpObserver() ApplicationObserver {
 	return &NoOpApplicationObserver{}
@@ -378,3 +592,7 @@ func (n *NoOpApplicationObserver) TokenExchangeStarted(ctx context.Context, gran
 func (n *NoOpApplicationObserver) AuthzCheckStarted(ctx context.Context) (context.Context, AuthzCheckProbe) {
 	return ctx, &NoOpAuthzCheckProbe{}
 }
+
+func (n *NoOpApplicationObserver) BulkheadCallStarted(ctx context.Context, bulkhead string) (context.Context, BulkheadProbe) {
+	return ctx, &NoOpBulkheadProbe{}
+}
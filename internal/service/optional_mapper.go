@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+
+	"github.com/project-kessel/parsec/internal/claims"
+)
+
+// optionalClaimMapper is a claim mapper whose failures omit its claims from
+// the tokens rather than failing the issuance, for mappers enriching tokens
+// with claims consumers can do without
+type optionalClaimMapper struct {
+	ClaimMapper
+	name string
+}
+
+// OptionalClaimMapper makes a claim mapper optional: when it fails, the
+// failure is reported to the issuance's probe (see
+// TokenIssuanceProbe.OptionalMapperFailed) under name, and the mapper
+// contributes no claims
+// Failures once the issuance is canceled or past its deadline still fail it.
+func OptionalClaimMapper(mapper ClaimMapper, name string) ClaimMapper {
+	return &optionalClaimMapper{ClaimMapper: mapper, name: name}
+}
+
+// Map implements ClaimMapper
+func (m *optionalClaimMapper) Map(ctx context.Context, input *MapperInput) (claims.Claims, error) {
+	mapped, err := m.ClaimMapper.Map(ctx, input)
+	if err == nil || ctx.Err() != nil {
+		return mapped, err
+	}
+	issuanceProbeFrom(ctx).OptionalMapperFailed(m.name, err)
+	return nil, nil
+}
+
+type issuanceProbeKey struct{}
+
+// withIssuanceProbe returns a context in which optional claim mappers report
+// their failures to probe
+func withIssuanceProbe(ctx context.Context, probe TokenIssuanceProbe) context.Context {
+	return context.WithValue(ctx, issuanceProbeKey{}, probe)
+}
+
+// issuanceProbeFrom returns the issuance probe of ctx, or a no-op probe
+// outside of an issuance, e.g. when claims are mapped for response headers
+func issuanceProbeFrom(ctx context.Context) TokenIssuanceProbe {
+	if probe, ok := ctx.Value(issuanceProbeKey{}).(TokenIssuanceProbe); ok {
+		return probe
+	}
+	return &NoOpTokenIssuanceProbe{}
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// failingClaimMapper fails every mapping with err
+type failingClaimMapper struct {
+	err error
+}
+
+func (m *failingClaimMapper) Map(ctx context.Context, input *MapperInput) (claims.Claims, error) {
+	return nil, m.err
+}
+
+// claimsRecorder records the claims its mappers produce for the issuance
+type claimsRecorder struct {
+	mappers []ClaimMapper
+	claims  claims.Claims
+}
+
+func (r *claimsRecorder) Issue(ctx context.Context, issueCtx *IssueContext) (*Token, error) {
+	mapped, err := issueCtx.ToClaims(ctx, r.mappers)
+	if err != nil {
+		return nil, err
+	}
+	r.claims = mapped
+	return &Token{Value: "token"}, nil
+}
+
+func (r *claimsRecorder) PublicKeys(ctx context.Context) ([]PublicKey, error) {
+	return nil, nil
+}
+
+func TestOptionalClaimMapper(t *testing.T) {
+	errDown := errors.New("enrichment data source down")
+	issue := func(t *testing.T, mappers ...ClaimMapper) (*claimsRecorder, *FakeObserver, error) {
+		t.Helper()
+		recorder := &claimsRecorder{mappers: mappers}
+		registry := NewSimpleRegistry()
+		registry.Register(TokenTypeTransactionToken, recorder)
+		observer := NewFakeObserver(t)
+		ts := NewTokenService("parsec.test", NewDataSourceRegistry(), registry, observer)
+		_, err := ts.IssueTokens(context.Background(), &IssueRequest{
+			Subject:    &trust.Result{Subject: "alice"},
+			TokenTypes: []TokenType{TokenTypeTransactionToken},
+		})
+		return recorder, observer, err
+	}
+
+	t.Run("omits the claims of a failing optional mapper", func(t *testing.T) {
+		recorder, observer, err := issue(t,
+			NewStubClaimMapper(claims.Claims{"tenant": "acme"}),
+			OptionalClaimMapper(&failingClaimMapper{err: errDown}, "enrichment"),
+		)
+		if err != nil {
+			t.Fatalf("expected the issuance to succeed, got %v", err)
+		}
+		if len(recorder.claims) != 1 || recorder.claims["tenant"] != "acme" {
+			t.Errorf("expected only the other mapper's claims, got %v", recorder.claims)
+		}
+		args := observer.GetProbe(0).CallArgs("OptionalMapperFailed")
+		if len(args) != 1 || args[0][0] != "enrichment" || !errors.Is(args[0][1].(error), errDown) {
+			t.Errorf("expected the failure to be probed, got %v", args)
+		}
+	})
+
+	t.Run("fails on a failing required mapper", func(t *testing.T) {
+		_, _, err := issue(t,
+			OptionalClaimMapper(NewStubClaimMapper(claims.Claims{"tenant": "acme"}), "tenant"),
+			&failingClaimMapper{err: errDown},
+		)
+		if !errors.Is(err, errDown) {
+			t.Errorf("expected the mapper's error, got %v", err)
+		}
+	})
+
+	t.Run("fails once the issuance is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		mapper := OptionalClaimMapper(&failingClaimMapper{err: context.Canceled}, "enrichment")
+		if _, err := mapper.Map(ctx, &MapperInput{}); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the cancellation to fail the mapping, got %v", err)
+		}
+	})
+}
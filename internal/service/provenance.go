@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/project-kessel/parsec/internal/claims"
+)
+
+// ClaimSource records where an issued claim came from: the configured claim
+// mapper that produced it and the data sources the mapper fetched
+type ClaimSource struct {
+	// Claim is the path of the claim, e.g. "tctx.org_id"
+	Claim string
+
+	// Mapper is the configuration element of the mapper, e.g. "transaction_context[1]"
+	Mapper string
+
+	// MapperType is the type of the mapper, e.g. "cel"
+	MapperType string
+
+	// DataSources are the data sources the mapper fetched, sorted by name
+	DataSources []string
+}
+
+// ClaimMapperLabel identifies a configured claim mapper for claim provenance
+type ClaimMapperLabel struct {
+	// Name is the configuration element of the mapper, e.g. "transaction_context[1]"
+	Name string
+
+	// Type is the type of the mapper, e.g. "cel"
+	Type string
+
+	// Claim is the claim the mapper's claims are nested in, e.g. "tctx"
+	// ("" for top-level claims)
+	Claim string
+}
+
+// labeledClaimMapper is a claim mapper whose claims are recorded in the claim
+// provenance of an issuance
+type labeledClaimMapper struct {
+	ClaimMapper
+	label ClaimMapperLabel
+}
+
+// LabelClaimMapper labels a claim mapper, so ToClaims records the claims it
+// produces when the issuance records claim provenance
+func LabelClaimMapper(mapper ClaimMapper, label ClaimMapperLabel) ClaimMapper {
+	return &labeledClaimMapper{ClaimMapper: mapper, label: label}
+}
+
+// ClaimProvenance collects the sources of the claims of one issuance
+// Claims may be mapped concurrently, so it is safe for concurrent use.
+type ClaimProvenance struct {
+	mu      sync.Mutex
+	sources map[string]ClaimSource
+}
+
+type claimProvenanceKey struct{}
+
+// WithClaimProvenance returns a context in which ToClaims records the
+// sources of the claims produced by labeled mappers, and the provenance they
+// are recorded in
+func WithClaimProvenance(ctx context.Context) (context.Context, *ClaimProvenance) {
+	provenance := &ClaimProvenance{sources: make(map[string]ClaimSource)}
+	return context.WithValue(ctx, claimProvenanceKey{}, provenance), provenance
+}
+
+// claimProvenanceFrom returns the claim provenance recorded in ctx, if any
+func claimProvenanceFrom(ctx context.Context) *ClaimProvenance {
+	provenance, _ := ctx.Value(claimProvenanceKey{}).(*ClaimProvenance)
+	return provenance
+}
+
+// Sources returns the recorded claim sources, sorted by claim
+func (p *ClaimProvenance) Sources() []ClaimSource {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.sources) == 0 {
+		return nil
+	}
+	sources := make([]ClaimSource, 0, len(p.sources))
+	for _, source := range p.sources {
+		sources = append(sources, source)
+	}
+	slices.SortFunc(sources, func(a, b ClaimSource) int {
+		return strings.Compare(a.Claim, b.Claim)
+	})
+	return sources
+}
+
+// mapClaims applies a labeled mapper, recording the claims it produces and
+// the data sources it fetched
+// Later mappers override the claims of earlier ones, and so their sources.
+func (p *ClaimProvenance) mapClaims(ctx context.Context, mapper *labeledClaimMapper, input *MapperInput) (claims.Claims, error) {
+	var (
+		mu          sync.Mutex
+		dataSources []string
+	)
+	observed := *input
+	observed.DataSourceRegistry = input.DataSourceRegistry.observed(func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !slices.Contains(dataSources, name) {
+			dataSources = append(dataSources, name)
+		}
+	})
+
+	mapperClaims, err := mapper.Map(ctx, &observed)
+	if err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	slices.Sort(dataSources)
+	mu.Unlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key := range mapperClaims {
+		claim := key
+		if mapper.label.Claim != "" {
+			claim = mapper.label.Claim + "." + key
+		}
+		p.sources[claim] = ClaimSource{
+			Claim:       claim,
+			Mapper:      mapper.label.Name,
+			MapperType:  mapper.label.Type,
+			DataSources: dataSources,
+		}
+	}
+	return mapperClaims, nil
+}
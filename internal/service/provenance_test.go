@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// fetchingMapper looks up data sources by name and maps the given claims
+type fetchingMapper struct {
+	dataSources []string
+	claims      claims.Claims
+}
+
+func (m *fetchingMapper) Map(ctx context.Context, input *MapperInput) (claims.Claims, error) {
+	for _, name := range m.dataSources {
+		input.DataSourceRegistry.Get(name)
+	}
+	return m.claims, nil
+}
+
+func TestIssueContext_ToClaimsProvenance(t *testing.T) {
+	registry := NewDataSourceRegistry()
+	registry.Register(cacheableTestDataSource{name: "roles"})
+	registry.Register(cacheableTestDataSource{name: "profile"})
+	issueCtx := &IssueContext{Subject: &trust.Result{Subject: "alice"}, DataSourceRegistry: registry}
+
+	mappers := []ClaimMapper{
+		LabelClaimMapper(&fetchingMapper{dataSources: []string{"roles", "profile", "roles", "unknown"}, claims: claims.Claims{"roles": "admin", "email": "a@example.com"}},
+			ClaimMapperLabel{Name: "transaction_context[0]", Type: "cel", Claim: "tctx"}),
+		LabelClaimMapper(NewStubClaimMapper(claims.Claims{"email": "alice@example.com"}),
+			ClaimMapperLabel{Name: "transaction_context[1]", Type: "stub", Claim: "tctx"}),
+		NewStubClaimMapper(claims.Claims{"unlabeled": true}),
+	}
+
+	t.Run("records the sources of labeled mappers' claims", func(t *testing.T) {
+		ctx, provenance := WithClaimProvenance(context.Background())
+		got, err := issueCtx.ToClaims(ctx, mappers)
+		if err != nil {
+			t.Fatalf("ToClaims failed: %v", err)
+		}
+		if got["email"] != "alice@example.com" || got["unlabeled"] != true {
+			t.Errorf("unexpected claims %v", got)
+		}
+
+		sources := provenance.Sources()
+		if len(sources) != 2 {
+			t.Fatalf("expected two claim sources, got %+v", sources)
+		}
+		if email := sources[0]; email.Claim != "tctx.email" || email.Mapper != "transaction_context[1]" || email.MapperType != "stub" || len(email.DataSources) != 0 {
+			t.Errorf("expected the overriding mapper as the source of tctx.email, got %+v", email)
+		}
+		if roles := sources[1]; roles.Claim != "tctx.roles" || roles.Mapper != "transaction_context[0]" ||
+			!slices.Equal(roles.DataSources, []string{"profile", "roles"}) {
+			t.Errorf("unexpected source of tctx.roles: %+v", roles)
+		}
+	})
+
+	t.Run("labeled mappers map as usual without provenance", func(t *testing.T) {
+		got, err := issueCtx.ToClaims(context.Background(), mappers)
+		if err != nil {
+			t.Fatalf("ToClaims failed: %v", err)
+		}
+		if got["email"] != "alice@example.com" || got["roles"] != "admin" || got["unlabeled"] != true {
+			t.Errorf("unexpected claims %v", got)
+		}
+	})
+}
@@ -1,30 +1,71 @@
 package service
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/perr"
 )
 
+// IssuerInfo describes a registered issuer, so operators can see what is
+// configured without reading the configuration and code side by side
+type IssuerInfo struct {
+	// TokenType is the token type the issuer is registered for
+	TokenType TokenType
+
+	// Type is the issuer implementation, e.g. "transaction_token" ("" if unknown)
+	Type string
+
+	// TTL is the lifetime of issued tokens (0 if the issuer decides it)
+	TTL time.Duration
+
+	// TTLPolicy is whether the lifetime is computed per request, bounded by TTL
+	TTLPolicy bool
+
+	// SignerID is the signer of issued tokens, if any
+	SignerID string
+
+	// Plugin is the plugin issuing the tokens, if any
+	Plugin string
+
+	// Encrypted is whether issued tokens are wrapped in a JWE
+	Encrypted bool
+
+	// Provenance is whether the sources of issued claims are recorded
+	Provenance bool
+}
+
 // SimpleRegistry is a simple in-memory registry of issuers by token type
 type SimpleRegistry struct {
 	mu      sync.RWMutex
 	issuers map[TokenType]Issuer
+	infos   map[TokenType]IssuerInfo
 }
 
 // NewSimpleRegistry creates a new simple issuer registry
 func NewSimpleRegistry() *SimpleRegistry {
 	return &SimpleRegistry{
 		issuers: make(map[TokenType]Issuer),
+		infos:   make(map[TokenType]IssuerInfo),
 	}
 }
 
 // Register registers an issuer for a token type
 func (r *SimpleRegistry) Register(tokenType TokenType, issuer Issuer) *SimpleRegistry {
+	return r.RegisterWithInfo(IssuerInfo{TokenType: tokenType}, issuer)
+}
+
+// RegisterWithInfo registers an issuer for info.TokenType, described by info
+func (r *SimpleRegistry) RegisterWithInfo(info IssuerInfo, issuer Issuer) *SimpleRegistry {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.issuers[tokenType] = issuer
+	r.issuers[info.TokenType] = issuer
+	r.infos[info.TokenType] = info
 	return r
 }
 
@@ -35,7 +76,7 @@ func (r *SimpleRegistry) GetIssuer(tokenType TokenType) (Issuer, error) {
 
 	issuer, ok := r.issuers[tokenType]
 	if !ok {
-		return nil, fmt.Errorf("no issuer registered for token type: %s", tokenType)
+		return nil, perr.Errorf(perr.ErrCodeUnsupportedTokenType, "no issuer registered for token type: %s", tokenType)
 	}
 
 	return issuer, nil
@@ -54,6 +95,33 @@ func (r *SimpleRegistry) ListTokenTypes() []TokenType {
 	return types
 }
 
+// List describes all registered issuers, ordered by token type
+func (r *SimpleRegistry) List() []IssuerInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	infos := make([]IssuerInfo, 0, len(r.infos))
+	for _, info := range r.infos {
+		infos = append(infos, info)
+	}
+	slices.SortFunc(infos, func(a, b IssuerInfo) int {
+		return cmp.Compare(a.TokenType, b.TokenType)
+	})
+	return infos
+}
+
+// Describe describes the issuer registered for the token type
+func (r *SimpleRegistry) Describe(tokenType TokenType) (IssuerInfo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	info, ok := r.infos[tokenType]
+	if !ok {
+		return IssuerInfo{}, perr.Errorf(perr.ErrCodeUnsupportedTokenType, "no issuer registered for token type: %s", tokenType)
+	}
+	return info, nil
+}
+
 // GetAllPublicKeys returns all public keys from all registered issuers.
 // It collects keys from all issuers, aggregating any errors that occur.
 // Returns the collected keys along with any errors encountered.
@@ -7,6 +7,9 @@ import (
 	"crypto/rand"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/perr"
 )
 
 func TestSimpleRegistry_GetAllPublicKeys(t *testing.T) {
@@ -235,6 +238,79 @@ func TestSimpleRegistry_GetAllPublicKeys(t *testing.T) {
 	})
 }
 
+func TestSimpleRegistry_Describe(t *testing.T) {
+	registry := NewSimpleRegistry()
+	registry.RegisterWithInfo(IssuerInfo{
+		TokenType: TokenTypeTransactionToken,
+		Type:      "transaction_token",
+		TTL:       5 * time.Minute,
+		SignerID:  "txn",
+	}, &testIssuerWithKeys{})
+	registry.Register(TokenTypeAccessToken, &testIssuerWithKeys{})
+
+	infos := registry.List()
+	if len(infos) != 2 || infos[0].TokenType != TokenTypeAccessToken || infos[1].TokenType != TokenTypeTransactionToken {
+		t.Fatalf("expected issuers ordered by token type, got %+v", infos)
+	}
+	if infos[0].Type != "" {
+		t.Errorf("expected no metadata for a plain registration, got %+v", infos[0])
+	}
+
+	info, err := registry.Describe(TokenTypeTransactionToken)
+	if err != nil {
+		t.Fatalf("Describe failed: %v", err)
+	}
+	if info.Type != "transaction_token" || info.TTL != 5*time.Minute || info.SignerID != "txn" {
+		t.Errorf("unexpected info: %+v", info)
+	}
+
+	if _, err := registry.Describe("urn:example:unknown"); !perr.HasCode(err, perr.ErrCodeUnsupportedTokenType) {
+		t.Errorf("expected unsupported_token_type, got %v", err)
+	}
+}
+
+// cacheableTestDataSource is a data source caching its results for ttl
+type cacheableTestDataSource struct {
+	name string
+	ttl  time.Duration
+}
+
+func (d cacheableTestDataSource) Name() string { return d.name }
+
+func (d cacheableTestDataSource) Fetch(context.Context, *DataSourceInput) (*DataSourceResult, error) {
+	return nil, nil
+}
+
+func (d cacheableTestDataSource) CacheKey(input *DataSourceInput) DataSourceInput { return *input }
+
+func (d cacheableTestDataSource) CacheTTL() time.Duration { return d.ttl }
+
+func TestDataSourceRegistry_Describe(t *testing.T) {
+	registry := NewDataSourceRegistry()
+	registry.Register(cacheableTestDataSource{name: "roles", ttl: time.Minute})
+	registry.RegisterWithInfo(DataSourceInfo{Name: "ignored", Type: "http"}, cacheableTestDataSource{name: "profile"})
+
+	infos := registry.List()
+	if len(infos) != 2 || infos[0].Name != "profile" || infos[1].Name != "roles" {
+		t.Fatalf("expected data sources ordered by name, got %+v", infos)
+	}
+	if infos[0].Type != "http" || infos[0].Cacheable {
+		t.Errorf("expected the registered info, got %+v", infos[0])
+	}
+
+	info, err := registry.Describe("roles")
+	if err != nil {
+		t.Fatalf("Describe failed: %v", err)
+	}
+	if !info.Cacheable || info.CacheTTL != time.Minute {
+		t.Errorf("expected a cacheable data source with its TTL, got %+v", info)
+	}
+
+	if _, err := registry.Describe("unknown"); !perr.HasCode(err, perr.ErrCodeNotFound) {
+		t.Errorf("expected not_found, got %v", err)
+	}
+}
+
 // testIssuerWithKeys is a test issuer that returns a predefined set of public keys
 type testIssuerWithKeys struct {
 	publicKeys []PublicKey
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/perr"
+)
+
+// RiskLevel grades the risk of an issuance
+type RiskLevel string
+
+const (
+	RiskLevelLow    RiskLevel = "low"
+	RiskLevelMedium RiskLevel = "medium"
+	RiskLevelHigh   RiskLevel = "high"
+)
+
+// riskLevels are the risk levels from lowest to highest
+var riskLevels = []RiskLevel{RiskLevelLow, RiskLevelMedium, RiskLevelHigh}
+
+// ParseRiskLevel returns the risk level named s
+func ParseRiskLevel(s string) (RiskLevel, error) {
+	if level := RiskLevel(s); slices.Contains(riskLevels, level) {
+		return level, nil
+	}
+	return "", fmt.Errorf("unknown risk level %q (supported: low, medium, high)", s)
+}
+
+// RiskAssessment is a verdict on the risk of an issuance
+type RiskAssessment struct {
+	// Deny refuses the issuance
+	Deny bool
+
+	// Level tags the tokens with the risk claim (see NewRiskMapper); empty
+	// leaves them untagged
+	Level RiskLevel
+
+	// Reasons explain the verdict, e.g. "velocity"
+	Reasons []string
+
+	// MaxTTL caps the lifetime of the tokens; zero leaves it as configured
+	MaxTTL time.Duration
+}
+
+// RiskEvaluator assesses the risk of an issuance before any token is issued,
+// e.g. from the velocity of a subject's issuances or an external risk engine
+type RiskEvaluator interface {
+	// Evaluate returns the assessment of the issuance, or nil if the evaluator
+	// has no opinion on it
+	Evaluate(ctx context.Context, issueCtx *IssueContext) (*RiskAssessment, error)
+}
+
+// ErrRiskDenied is returned when a risk evaluator denies an issuance
+var ErrRiskDenied = perr.New(perr.ErrCodeRiskDenied, "issuance denied by risk evaluation")
+
+// WithRiskEvaluators assesses each issuance with evaluators, after the
+// issuance limiter and before claims are mapped
+//
+// The assessments are combined: the issuance is denied with ErrRiskDenied if
+// any evaluator denies it, tokens carry the highest level and every reason,
+// and their lifetime is capped by the lowest MaxTTL. An evaluator that fails
+// fails the issuance, unless failOpen is set, in which case it is ignored.
+func WithRiskEvaluators(failOpen bool, evaluators ...RiskEvaluator) TokenServiceOption {
+	return func(ts *TokenService) {
+		ts.riskEvaluators = append(ts.riskEvaluators, evaluators...)
+		ts.riskFailOpen = failOpen
+	}
+}
+
+// assessRisk evaluates the issuance with every risk evaluator, recording the
+// combined assessment in issueCtx
+func (ts *TokenService) assessRisk(ctx context.Context, issueCtx *IssueContext) error {
+	if len(ts.riskEvaluators) == 0 {
+		return nil
+	}
+
+	var combined *RiskAssessment
+	for _, evaluator := range ts.riskEvaluators {
+		assessment, err := evaluator.Evaluate(ctx, issueCtx)
+		if err != nil {
+			if ts.riskFailOpen {
+				continue
+			}
+			if !perr.IsCoded(err) {
+				err = perr.Errorf(perr.ErrCodeIssuerUnavailable, "%w", err)
+			}
+			return fmt.Errorf("risk evaluation failed: %w", err)
+		}
+		combined = combined.merge(assessment)
+	}
+	if combined == nil {
+		return nil
+	}
+	if combined.Deny {
+		if len(combined.Reasons) > 0 {
+			return fmt.Errorf("%w: %v", ErrRiskDenied, combined.Reasons)
+		}
+		return ErrRiskDenied
+	}
+	issueCtx.Risk = combined
+	return nil
+}
+
+// merge returns the combination of a and b, either of which may be nil
+func (a *RiskAssessment) merge(b *RiskAssessment) *RiskAssessment {
+	if b == nil {
+		return a
+	}
+	if a == nil {
+		c := *b
+		c.Reasons = slices.Clone(b.Reasons)
+		return &c
+	}
+	a.Deny = a.Deny || b.Deny
+	if slices.Index(riskLevels, b.Level) > slices.Index(riskLevels, a.Level) {
+		a.Level = b.Level
+	}
+	for _, reason := range b.Reasons {
+		if !slices.Contains(a.Reasons, reason) {
+			a.Reasons = append(a.Reasons, reason)
+		}
+	}
+	if b.MaxTTL > 0 && (a.MaxTTL == 0 || b.MaxTTL < a.MaxTTL) {
+		a.MaxTTL = b.MaxTTL
+	}
+	return a
+}
+
+// Map returns the assessment as a map, for expression languages and the risk
+// claim (nil for a nil assessment)
+func (a *RiskAssessment) Map() map[string]any {
+	if a == nil {
+		return nil
+	}
+	m := map[string]any{"level": string(a.Level)}
+	if len(a.Reasons) > 0 {
+		reasons := make([]any, len(a.Reasons))
+		for i, reason := range a.Reasons {
+			reasons[i] = reason
+		}
+		m["reasons"] = reasons
+	}
+	return m
+}
+
+// RiskClaim is the claim a RiskMapper tags tokens with
+const RiskClaim = "risk"
+
+// RiskMapper tags tokens with the risk level and reasons of the issuance, as
+// {"risk": {"level": "high", "reasons": ["velocity"]}}
+// Issuances without a graded risk are not tagged.
+type RiskMapper struct{}
+
+// NewRiskMapper creates a mapper tagging tokens with the issuance's risk
+func NewRiskMapper() *RiskMapper {
+	return &RiskMapper{}
+}
+
+// Map implements ClaimMapper
+func (m *RiskMapper) Map(ctx context.Context, input *MapperInput) (claims.Claims, error) {
+	if input.Risk == nil || input.Risk.Level == "" {
+		return nil, nil
+	}
+	return claims.Claims{RiskClaim: input.Risk.Map()}, nil
+}
+
+// CapTTL returns ttl, capped by the MaxTTL of the issuance's risk assessment
+func (ic *IssueContext) CapTTL(ttl time.Duration) time.Duration {
+	if ic != nil && ic.Risk != nil && ic.Risk.MaxTTL > 0 && ic.Risk.MaxTTL < ttl {
+		return ic.Risk.MaxTTL
+	}
+	return ttl
+}
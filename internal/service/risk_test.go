@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// fixedRiskEvaluator returns the same assessment or error for every issuance
+type fixedRiskEvaluator struct {
+	assessment *RiskAssessment
+	err        error
+}
+
+func (e *fixedRiskEvaluator) Evaluate(ctx context.Context, issueCtx *IssueContext) (*RiskAssessment, error) {
+	return e.assessment, e.err
+}
+
+// riskRecorder records the risk assessment and capped TTL of the issuance
+type riskRecorder struct {
+	issued bool
+	risk   *RiskAssessment
+	ttl    time.Duration
+}
+
+func (r *riskRecorder) Issue(ctx context.Context, issueCtx *IssueContext) (*Token, error) {
+	r.issued = true
+	r.risk = issueCtx.Risk
+	r.ttl = issueCtx.CapTTL(time.Hour)
+	return &Token{Value: "token"}, nil
+}
+
+func (r *riskRecorder) PublicKeys(ctx context.Context) ([]PublicKey, error) {
+	return nil, nil
+}
+
+func TestTokenService_RiskEvaluators(t *testing.T) {
+	issue := func(t *testing.T, failOpen bool, observer TokenServiceObserver, evaluators ...RiskEvaluator) (*riskRecorder, error) {
+		t.Helper()
+		recorder := &riskRecorder{}
+		registry := NewSimpleRegistry()
+		registry.Register(TokenTypeTransactionToken, recorder)
+		service := NewTokenService("trust.example.com", nil, registry, observer, WithRiskEvaluators(failOpen, evaluators...))
+		_, err := service.IssueTokens(context.Background(), &IssueRequest{
+			Subject:    &trust.Result{Subject: "alice", TrustDomain: "example.com"},
+			TokenTypes: []TokenType{TokenTypeTransactionToken},
+		})
+		return recorder, err
+	}
+
+	t.Run("assessments are combined", func(t *testing.T) {
+		recorder, err := issue(t, false, nil,
+			&fixedRiskEvaluator{assessment: &RiskAssessment{Level: RiskLevelMedium, Reasons: []string{"new_device"}, MaxTTL: 30 * time.Minute}},
+			&fixedRiskEvaluator{},
+			&fixedRiskEvaluator{assessment: &RiskAssessment{Level: RiskLevelHigh, Reasons: []string{"velocity"}, MaxTTL: 5 * time.Minute}},
+			&fixedRiskEvaluator{assessment: &RiskAssessment{Level: RiskLevelLow, Reasons: []string{"velocity"}}},
+		)
+		if err != nil {
+			t.Fatalf("IssueTokens failed: %v", err)
+		}
+		want := &RiskAssessment{Level: RiskLevelHigh, Reasons: []string{"new_device", "velocity"}, MaxTTL: 5 * time.Minute}
+		if !reflect.DeepEqual(recorder.risk, want) {
+			t.Errorf("expected %+v, got %+v", want, recorder.risk)
+		}
+		if recorder.ttl != 5*time.Minute {
+			t.Errorf("expected the TTL to be capped to 5m, got %s", recorder.ttl)
+		}
+	})
+
+	t.Run("no opinion leaves the issuance unassessed", func(t *testing.T) {
+		recorder, err := issue(t, false, nil, &fixedRiskEvaluator{})
+		if err != nil {
+			t.Fatalf("IssueTokens failed: %v", err)
+		}
+		if recorder.risk != nil || recorder.ttl != time.Hour {
+			t.Errorf("expected no assessment and an uncapped TTL, got %+v and %s", recorder.risk, recorder.ttl)
+		}
+	})
+
+	t.Run("denied issuance issues nothing", func(t *testing.T) {
+		fakeObs := NewFakeObserver(t)
+		recorder, err := issue(t, false, fakeObs,
+			&fixedRiskEvaluator{assessment: &RiskAssessment{Level: RiskLevelLow}},
+			&fixedRiskEvaluator{assessment: &RiskAssessment{Deny: true, Reasons: []string{"impossible_travel"}}},
+		)
+		if !errors.Is(err, ErrRiskDenied) || !perr.HasCode(err, perr.ErrCodeRiskDenied) {
+			t.Fatalf("expected risk_denied, got %v", err)
+		}
+		if recorder.issued {
+			t.Error("expected no token to be issued")
+		}
+		fakeObs.AssertSingleProbe("TokenIssuanceStarted", nil).AssertProbeSequence(
+			ProbeCall("IssuanceRefused", ErrorWithCode(perr.ErrCodeRiskDenied)),
+			"End",
+		)
+	})
+
+	t.Run("evaluator failures are issuer_unavailable", func(t *testing.T) {
+		_, err := issue(t, false, nil, &fixedRiskEvaluator{err: errors.New("risk engine down")})
+		if !perr.HasCode(err, perr.ErrCodeIssuerUnavailable) {
+			t.Errorf("expected issuer_unavailable, got %v", err)
+		}
+	})
+
+	t.Run("fail open ignores failed evaluators", func(t *testing.T) {
+		recorder, err := issue(t, true, nil,
+			&fixedRiskEvaluator{err: errors.New("risk engine down")},
+			&fixedRiskEvaluator{assessment: &RiskAssessment{Level: RiskLevelMedium}},
+		)
+		if err != nil {
+			t.Fatalf("IssueTokens failed: %v", err)
+		}
+		if recorder.risk == nil || recorder.risk.Level != RiskLevelMedium {
+			t.Errorf("expected the remaining assessment, got %+v", recorder.risk)
+		}
+	})
+}
+
+func TestRiskMapper(t *testing.T) {
+	mapper := NewRiskMapper()
+
+	result, err := mapper.Map(context.Background(), &MapperInput{
+		Risk: &RiskAssessment{Level: RiskLevelHigh, Reasons: []string{"velocity"}},
+	})
+	if err != nil {
+		t.Fatalf("Map failed: %v", err)
+	}
+	want := map[string]any{"level": "high", "reasons": []any{"velocity"}}
+	if !reflect.DeepEqual(result[RiskClaim], want) {
+		t.Errorf("expected %v, got %v", want, result)
+	}
+
+	for _, input := range []*MapperInput{{}, {Risk: &RiskAssessment{MaxTTL: time.Minute}}} {
+		result, err := mapper.Map(context.Background(), input)
+		if err != nil || result != nil {
+			t.Errorf("expected no claims without a risk level, got %v, %v", result, err)
+		}
+	}
+}
@@ -2,8 +2,16 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/perr"
 	"github.com/project-kessel/parsec/internal/request"
 	"github.com/project-kessel/parsec/internal/trust"
 )
@@ -16,6 +24,67 @@ type TokenService struct {
 	dataSources    *DataSourceRegistry
 	issuerRegistry Registry
 	observer       TokenServiceObserver
+	timeout        time.Duration
+	clock          clock.Clock
+
+	// identityMapper maps subjects to principals (optional)
+	identityMapper   IdentityMapper
+	identityRequired bool
+
+	// limiter admits or refuses each issuance (optional)
+	limiter IssuanceLimiter
+
+	// audiencePolicy decides the audiences tokens may be requested for
+	audiencePolicy AudiencePolicy
+
+	// riskEvaluators assess each issuance (optional)
+	riskEvaluators []RiskEvaluator
+	riskFailOpen   bool
+
+	// abandoned counts issuer calls still running after their issuance
+	// stopped at its deadline; new issuance is refused at maxAbandoned
+	abandoned    atomic.Int64
+	maxAbandoned int64
+}
+
+// DefaultMaxAbandonedIssuances is the default number of issuer calls that may
+// still be running after their issuance stopped at its deadline
+const DefaultMaxAbandonedIssuances = 100
+
+// ErrTooManyAbandonedIssuances is returned while too many issuer calls are
+// still running after their issuance stopped at its deadline
+var ErrTooManyAbandonedIssuances = perr.New(perr.ErrCodeIssuerUnavailable, "too many abandoned issuances still running")
+
+// TokenServiceOption is a functional option for configuring a TokenService
+type TokenServiceOption func(*TokenService)
+
+// WithIssuanceTimeout bounds each IssueTokens call, including claim mapping,
+// data source fetches and signing for every requested token type
+// The caller's own deadline still applies if it is sooner.
+func WithIssuanceTimeout(timeout time.Duration) TokenServiceOption {
+	return func(ts *TokenService) {
+		ts.timeout = timeout
+	}
+}
+
+// WithMaxAbandonedIssuances bounds the issuer calls that may still be running
+// after their issuance stopped at its deadline (default
+// DefaultMaxAbandonedIssuances). While that many are running, new issuance
+// fails with ErrTooManyAbandonedIssuances rather than piling up more goroutines
+// on an unresponsive dependency.
+func WithMaxAbandonedIssuances(n int) TokenServiceOption {
+	return func(ts *TokenService) {
+		ts.maxAbandoned = int64(n)
+	}
+}
+
+// WithClock sets the time source tokens are issued at (default: system clock)
+func WithClock(clk clock.Clock) TokenServiceOption {
+	return func(ts *TokenService) {
+		if clk != nil {
+			ts.clock = clk
+		}
+	}
 }
 
 // NewTokenService creates a new token service
@@ -24,21 +93,31 @@ func NewTokenService(
 	dataSources *DataSourceRegistry,
 	issuerRegistry Registry,
 	observer TokenServiceObserver,
+	opts ...TokenServiceOption,
 ) *TokenService {
 	// Use null object pattern - default to no-op observer if none provided
 	if observer == nil {
 		observer = NoOpTokenServiceObserver()
 	}
-	return &TokenService{
+	ts := &TokenService{
 		trustDomain:    trustDomain,
 		dataSources:    dataSources,
 		issuerRegistry: issuerRegistry,
 		observer:       observer,
+		maxAbandoned:   DefaultMaxAbandonedIssuances,
+		clock:          clock.NewSystemClock(),
+		audiencePolicy: &ExactAudiencePolicy{Audience: trustDomain},
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(ts)
+		}
 	}
+	return ts
 }
 
 // TrustDomain returns the trust domain for this token service
-// The trust domain is used as the audience for all issued tokens
+// The trust domain is the audience of tokens requested for none
 func (ts *TokenService) TrustDomain() string {
 	return ts.trustDomain
 }
@@ -60,24 +139,52 @@ type IssueRequest struct {
 
 	// Scope for the tokens
 	Scope string
+
+	// Audiences are the audiences the tokens are for (optional)
+	// Defaults to the trust domain. Callers check them against their audience
+	// policy; the token service issues for any audience.
+	Audiences []string
+
+	// Purpose is the requested purpose of the transaction
+	Purpose string
+
+	// Delegation records that the actor was permitted to act on behalf of the subject
+	// May be nil if no delegation took place
+	Delegation *trust.Delegation
 }
 
 // IssueTokens orchestrates the complete token issuance process
 // Returns a map of token type to issued token
+//
+// Issuance stops when ctx is done or the issuance timeout elapses, even if an
+// issuer is blocked on a dependency that ignores ctx. The error then has code
+// deadline_exceeded (or canceled) and names the token types already issued.
 func (ts *TokenService) IssueTokens(ctx context.Context, req *IssueRequest) (map[TokenType]*Token, error) {
 	// Create request-scoped probe that captures execution context
 	ctx, probe := ts.observer.TokenIssuanceStarted(ctx, req.Subject, req.Actor, req.Scope, req.TokenTypes)
 	defer probe.End()
+	ctx = withIssuanceProbe(ctx, probe)
 
-	// Build issue context with base information needed for all issuers
-	// Audience is always the trust domain per transaction token spec
-	issueCtx := &IssueContext{
-		Subject:            req.Subject,
-		Actor:              req.Actor,
-		RequestAttributes:  req.RequestAttributes,
-		Audience:           ts.trustDomain,
-		Scope:              req.Scope,
-		DataSourceRegistry: ts.dataSources,
+	if ts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ts.timeout)
+		defer cancel()
+	}
+
+	subject, err := ts.mapIdentity(ctx, req.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	// All tokens of one issuance share an issue context, so their lifetimes line up
+	issueCtx := ts.newIssueContext(subject, req)
+	if err := ts.admit(ctx, issueCtx, req.TokenTypes); err != nil {
+		probe.IssuanceRefused(err)
+		return nil, err
+	}
+	if err := ts.assessRisk(ctx, issueCtx); err != nil {
+		probe.IssuanceRefused(err)
+		return nil, err
 	}
 
 	// Issue tokens for each requested type
@@ -91,15 +198,136 @@ func (ts *TokenService) IssueTokens(ctx context.Context, req *IssueRequest) (map
 			return nil, fmt.Errorf("no issuer for token type %s: %w", tokenType, err)
 		}
 
-		token, err := iss.Issue(ctx, issueCtx)
+		token, err := ts.issueWithContext(ctx, iss, issueCtx)
 		if err != nil {
 			probe.TokenTypeIssuanceFailed(tokenType, err)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, interruptedIssuanceError(ctxErr, tokenType, tokens, err)
+			}
+			// Failures the issuer did not classify mean it could not issue at all
+			if !perr.IsCoded(err) {
+				err = perr.Errorf(perr.ErrCodeIssuerUnavailable, "%w", err)
+			}
 			return nil, fmt.Errorf("failed to issue %s: %w", tokenType, err)
 		}
 
+		if token.Compaction != nil {
+			probe.TokenCompacted(tokenType, token.Compaction)
+		}
 		probe.TokenTypeIssuanceSucceeded(tokenType, token)
 		tokens[tokenType] = token
 	}
 
 	return tokens, nil
 }
+
+// MapClaims runs mappers for req as they would run for an issuance, with the
+// subject mapped to its canonical identity, and merges their claims
+// req.TokenTypes is ignored. Use it to derive values delivered alongside
+// tokens rather than in them.
+func (ts *TokenService) MapClaims(ctx context.Context, req *IssueRequest, mappers []ClaimMapper) (claims.Claims, error) {
+	if ts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ts.timeout)
+		defer cancel()
+	}
+
+	subject, err := ts.mapIdentity(ctx, req.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.newIssueContext(subject, req).ToClaims(ctx, mappers)
+}
+
+// newIssueContext builds the issue context of req for the mapped subject
+// The audience is the trust domain per transaction token spec, unless req
+// names its audiences.
+func (ts *TokenService) newIssueContext(subject *trust.Result, req *IssueRequest) *IssueContext {
+	audience := ts.trustDomain
+	if len(req.Audiences) > 0 {
+		audience = req.Audiences[0]
+	}
+	return &IssueContext{
+		IssuedAt:           ts.clock.Now(),
+		Subject:            subject,
+		Actor:              req.Actor,
+		RequestAttributes:  req.RequestAttributes,
+		Audience:           audience,
+		Audiences:          slices.Clone(req.Audiences),
+		Scope:              req.Scope,
+		Purpose:            req.Purpose,
+		Delegation:         req.Delegation,
+		DataSourceRegistry: ts.dataSources,
+	}
+}
+
+// issueWithContext issues a token, returning ctx's error as soon as ctx is done
+// An issuer still running at that point finishes in the background, counted as
+// abandoned until it returns. It works on its own copy of issueCtx, since the
+// caller goes on without it.
+func (ts *TokenService) issueWithContext(ctx context.Context, iss Issuer, issueCtx *IssueContext) (*Token, error) {
+	if ctx.Done() == nil {
+		return iss.Issue(ctx, issueCtx)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ts.abandoned.Load() >= ts.maxAbandoned {
+		return nil, ErrTooManyAbandonedIssuances
+	}
+
+	// Set by whichever of the issuer and the caller finishes first
+	const (
+		running int32 = iota
+		finished
+		abandoned
+	)
+	var state atomic.Int32
+
+	type result struct {
+		token *Token
+		err   error
+	}
+	done := make(chan result, 1)
+	ownCtx := issueCtx.clone()
+	go func() {
+		token, err := iss.Issue(ctx, ownCtx)
+		if !state.CompareAndSwap(running, finished) {
+			ts.abandoned.Add(-1)
+		}
+		done <- result{token: token, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.token, r.err
+	case <-ctx.Done():
+		ts.abandoned.Add(1)
+		if !state.CompareAndSwap(running, abandoned) {
+			// The issuer returned just as ctx ended
+			ts.abandoned.Add(-1)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// interruptedIssuanceError classifies an issuance stopped by its context,
+// naming the token types that were issued before it stopped
+func interruptedIssuanceError(ctxErr error, tokenType TokenType, issued map[TokenType]*Token, err error) error {
+	code := perr.ErrCodeDeadlineExceeded
+	if errors.Is(ctxErr, context.Canceled) {
+		code = perr.ErrCodeCanceled
+	}
+
+	var done []string
+	for t := range issued {
+		done = append(done, string(t))
+	}
+	slices.Sort(done)
+
+	if len(done) == 0 {
+		return perr.Errorf(code, "failed to issue %s: %w", tokenType, err)
+	}
+	return perr.Errorf(code, "failed to issue %s (issued: %s): %w", tokenType, strings.Join(done, ", "), err)
+}
@@ -3,9 +3,12 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/perr"
 	"github.com/project-kessel/parsec/internal/trust"
 )
 
@@ -85,7 +88,7 @@ func TestTokenService_IssueTokens_Observability(t *testing.T) {
 		p := fakeObs.AssertSingleProbe("TokenIssuanceStarted", nil)
 		p.AssertProbeSequence(
 			ProbeCall("TokenTypeIssuanceStarted", TokenTypeTransactionToken),
-			ProbeCall("IssuerNotFound", TokenTypeTransactionToken, ErrorContaining("no issuer")),
+			ProbeCall("IssuerNotFound", TokenTypeTransactionToken, ErrorWithCode(perr.ErrCodeUnsupportedTokenType)),
 			"End",
 		)
 	})
@@ -111,6 +114,9 @@ func TestTokenService_IssueTokens_Observability(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected error when token issuance fails")
 		}
+		if !errors.Is(err, issueErr) || perr.CodeOf(err) != perr.ErrCodeIssuerUnavailable {
+			t.Errorf("expected issuer_unavailable wrapping the issuer error, got %q (%s)", err, perr.CodeOf(err))
+		}
 
 		// Verify observer saw probe with correct method sequence including error
 		p := fakeObs.AssertSingleProbe("TokenIssuanceStarted", nil)
@@ -196,6 +202,146 @@ func TestTokenService_IssueTokens_Observability(t *testing.T) {
 	})
 }
 
+func TestTokenService_IssueTokens_Deadline(t *testing.T) {
+	req := &IssueRequest{
+		Subject:    &trust.Result{Subject: "user-123"},
+		TokenTypes: []TokenType{TokenTypeAccessToken, TokenTypeTransactionToken},
+	}
+
+	newRegistry := func(blocked chan struct{}) Registry {
+		registry := NewSimpleRegistry()
+		registry.Register(TokenTypeAccessToken, &testIssuerStub{token: &Token{Value: "at"}})
+		registry.Register(TokenTypeTransactionToken, &blockingIssuer{release: blocked})
+		return registry
+	}
+
+	t.Run("slow issuer fails with deadline_exceeded", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		service := NewTokenService("trust.example.com", nil, newRegistry(release), nil, WithIssuanceTimeout(20*time.Millisecond))
+
+		start := time.Now()
+		_, err := service.IssueTokens(context.Background(), req)
+		if err == nil {
+			t.Fatal("expected error when issuance exceeds its deadline")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("expected issuance to stop at the deadline, took %s", elapsed)
+		}
+		if perr.CodeOf(err) != perr.ErrCodeDeadlineExceeded || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline_exceeded, got %q (%s)", err, perr.CodeOf(err))
+		}
+		if !strings.Contains(err.Error(), "issued: "+string(TokenTypeAccessToken)) {
+			t.Errorf("expected error to name the issued token types, got %q", err)
+		}
+	})
+
+	t.Run("caller cancellation fails with canceled", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		service := NewTokenService("trust.example.com", nil, newRegistry(release), nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		_, err := service.IssueTokens(ctx, req)
+		if perr.CodeOf(err) != perr.ErrCodeCanceled {
+			t.Errorf("expected canceled, got %q (%s)", err, perr.CodeOf(err))
+		}
+	})
+
+	t.Run("abandoned issuances are bounded", func(t *testing.T) {
+		release := make(chan struct{})
+		service := NewTokenService("trust.example.com", nil, newRegistry(release), nil,
+			WithIssuanceTimeout(20*time.Millisecond), WithMaxAbandonedIssuances(1))
+
+		if _, err := service.IssueTokens(context.Background(), req); perr.CodeOf(err) != perr.ErrCodeDeadlineExceeded {
+			t.Fatalf("expected deadline_exceeded, got %q (%s)", err, perr.CodeOf(err))
+		}
+		_, err := service.IssueTokens(context.Background(), req)
+		if !errors.Is(err, ErrTooManyAbandonedIssuances) || perr.CodeOf(err) != perr.ErrCodeIssuerUnavailable {
+			t.Fatalf("expected issuance to be refused while an abandoned issuer is running, got %q (%s)", err, perr.CodeOf(err))
+		}
+
+		close(release)
+		for deadline := time.Now().Add(time.Second); service.abandoned.Load() > 0; {
+			if time.Now().After(deadline) {
+				t.Fatal("abandoned issuer was never released")
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if tokens, err := service.IssueTokens(context.Background(), req); err != nil || tokens[TokenTypeTransactionToken].Value != "late" {
+			t.Errorf("expected issuance once the abandoned issuer returned, got %v, %v", tokens, err)
+		}
+	})
+
+	t.Run("issuance within the deadline succeeds", func(t *testing.T) {
+		registry := NewSimpleRegistry()
+		registry.Register(TokenTypeTransactionToken, &testIssuerStub{token: &Token{Value: "txn"}})
+		service := NewTokenService("trust.example.com", nil, registry, nil, WithIssuanceTimeout(time.Second))
+
+		tokens, err := service.IssueTokens(context.Background(), &IssueRequest{TokenTypes: []TokenType{TokenTypeTransactionToken}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if tokens[TokenTypeTransactionToken].Value != "txn" {
+			t.Errorf("expected issued token, got %v", tokens)
+		}
+	})
+}
+
+func TestTokenService_IssueTokens_Clock(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	access := &issueTimeRecorder{}
+	txn := &issueTimeRecorder{}
+	registry := NewSimpleRegistry()
+	registry.Register(TokenTypeAccessToken, access)
+	registry.Register(TokenTypeTransactionToken, txn)
+
+	service := NewTokenService("trust.example.com", nil, registry, nil, WithClock(clock.NewFixtureClock(now)))
+	_, err := service.IssueTokens(context.Background(), &IssueRequest{
+		Subject:    &trust.Result{Subject: "user-123"},
+		TokenTypes: []TokenType{TokenTypeAccessToken, TokenTypeTransactionToken},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !access.issuedAt.Equal(now) || !txn.issuedAt.Equal(now) {
+		t.Errorf("expected every token type issued at %v, got %v and %v", now, access.issuedAt, txn.issuedAt)
+	}
+}
+
+// issueTimeRecorder records the issue time it was asked to issue at
+type issueTimeRecorder struct {
+	issuedAt time.Time
+}
+
+func (i *issueTimeRecorder) Issue(ctx context.Context, issueCtx *IssueContext) (*Token, error) {
+	i.issuedAt = issueCtx.IssuedAt
+	return &Token{Value: "token", IssuedAt: issueCtx.IssuedAt}, nil
+}
+
+func (i *issueTimeRecorder) PublicKeys(ctx context.Context) ([]PublicKey, error) {
+	return nil, nil
+}
+
+// blockingIssuer ignores its context and blocks until released, like an
+// issuer stuck on an unresponsive dependency
+type blockingIssuer struct {
+	release chan struct{}
+}
+
+func (i *blockingIssuer) Issue(ctx context.Context, issueCtx *IssueContext) (*Token, error) {
+	<-i.release
+	return &Token{Value: "late"}, nil
+}
+
+func (i *blockingIssuer) PublicKeys(ctx context.Context) ([]PublicKey, error) {
+	return nil, nil
+}
+
 // testIssuerStub is a simple stub issuer for testing
 type testIssuerStub struct {
 	token *Token
@@ -5,6 +5,7 @@ import (
 	"maps"
 
 	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/request"
 )
 
 // StubClaimMapper is a simple stub claim mapper for testing
@@ -67,9 +68,23 @@ func (r *RequestAttributesMapper) Map(ctx context.Context, input *MapperInput) (
 	if input.RequestAttributes.UserAgent != "" {
 		result["user_agent"] = input.RequestAttributes.UserAgent
 	}
+	if input.RequestAttributes.Scheme != "" {
+		result["scheme"] = input.RequestAttributes.Scheme
+	}
+	if input.RequestAttributes.Port != 0 {
+		result["port"] = input.RequestAttributes.Port
+	}
+	if query := input.RequestAttributes.QueryMap(); query != nil {
+		result["query"] = query
+	}
+	if input.RequestAttributes.BodySHA256 != "" {
+		result["body_sha256"] = input.RequestAttributes.BodySHA256
+	}
 
-	// Include all items from Additional map
+	// Include all items from Additional map except the server-derived caller
+	// attributes, which are for trust decisions and not for issued tokens
 	maps.Copy(result, input.RequestAttributes.Additional)
+	delete(result, request.CallerAttributesKey)
 
 	return result, nil
 }
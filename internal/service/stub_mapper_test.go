@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/request"
+)
+
+func TestRequestAttributesMapper_ExcludesCallerAttributes(t *testing.T) {
+	attrs := request.FromClaims(nil)
+	attrs.Path = "/api/orders"
+	attrs.Additional["tenant"] = "acme"
+	attrs.Additional[request.CallerAttributesKey] = map[string]any{"ip_address": "203.0.113.7"}
+
+	got, err := NewRequestAttributesMapper().Map(context.Background(), &MapperInput{RequestAttributes: attrs})
+	if err != nil {
+		t.Fatalf("Map failed: %v", err)
+	}
+
+	if got["path"] != "/api/orders" || got["tenant"] != "acme" {
+		t.Errorf("expected request attributes to be mapped, got %v", got)
+	}
+	if _, ok := got[request.CallerAttributesKey]; ok {
+		t.Errorf("expected caller attributes to be excluded, got %v", got[request.CallerAttributesKey])
+	}
+}
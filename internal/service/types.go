@@ -15,6 +15,9 @@ const (
 	// TokenTypeJWT is a JWT token (generic)
 	TokenTypeJWT TokenType = "urn:ietf:params:oauth:token-type:jwt"
 
+	// TokenTypeIDToken is an OpenID Connect ID token
+	TokenTypeIDToken TokenType = "urn:ietf:params:oauth:token-type:id_token"
+
 	// TokenTypeRHIdentity is a Red Hat identity token (x-rh-identity format)
 	TokenTypeRHIdentity TokenType = "urn:redhat:params:oauth:token-type:rh-identity"
 )
@@ -27,6 +30,12 @@ type Registry interface {
 	// ListTokenTypes returns all registered token types
 	ListTokenTypes() []TokenType
 
+	// List describes all registered issuers, ordered by token type
+	List() []IssuerInfo
+
+	// Describe describes the issuer registered for the token type
+	Describe(tokenType TokenType) (IssuerInfo, error)
+
 	// GetAllPublicKeys returns all public keys from all registered issuers
 	// This is useful for JWKS endpoints that need to serve all public keys at once
 	GetAllPublicKeys(ctx context.Context) ([]PublicKey, error)
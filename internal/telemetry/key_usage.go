@@ -0,0 +1,51 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/project-kessel/parsec/internal/keys"
+)
+
+// KeyUsageSource reports the signatures per key of each signer by ID
+// keys.SignerRegistry implements it.
+type KeyUsageSource interface {
+	KeyUsage() map[string][]keys.KeyUsage
+}
+
+// RegisterKeyUsageMetrics reports the signatures made with each key of source
+// whenever metrics are collected
+//
+// A key whose signatures stop growing after a rotation is no longer signing;
+// one that keeps growing after its successor became active points to a stale
+// replica still signing with it.
+func RegisterKeyUsageMetrics(meter metric.Meter, source KeyUsageSource) error {
+	signatures, err := meter.Int64ObservableCounter("parsec.key.signatures",
+		metric.WithDescription("Signatures made with a key, by signer and key ID"),
+		metric.WithUnit("{signature}"))
+	if err != nil {
+		return err
+	}
+	lastUsed, err := meter.Int64ObservableGauge("parsec.key.last_used",
+		metric.WithDescription("Unix time a key last signed, by signer and key ID"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for signerID, usages := range source.KeyUsage() {
+			for _, usage := range usages {
+				attrs := metric.WithAttributes(
+					attribute.String("signer", signerID),
+					attribute.String("kid", usage.KeyID))
+				o.ObserveInt64(signatures, usage.Signatures, attrs)
+				o.ObserveInt64(lastUsed, usage.LastUsedAt.Unix(), attrs)
+			}
+		}
+		return nil
+	}, signatures, lastUsed)
+	return err
+}
@@ -0,0 +1,54 @@
+package telemetry
+
+import (
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/project-kessel/parsec/internal/keys"
+)
+
+type staticKeyUsage map[string][]keys.KeyUsage
+
+func (s staticKeyUsage) KeyUsage() map[string][]keys.KeyUsage { return s }
+
+func TestRegisterKeyUsageMetrics(t *testing.T) {
+	lastUsed := time.Unix(1700000000, 0)
+	source := staticKeyUsage{
+		"txn": {
+			{KeyID: "old", Signatures: 7, LastUsedAt: lastUsed.Add(-time.Hour)},
+			{KeyID: "new", Signatures: 3, LastUsedAt: lastUsed},
+		},
+	}
+
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	if err := RegisterKeyUsageMetrics(provider.Meter("test"), source); err != nil {
+		t.Fatalf("RegisterKeyUsageMetrics failed: %v", err)
+	}
+
+	metrics := collect(t, reader)
+	attrs := func(kid string) []attribute.KeyValue {
+		return []attribute.KeyValue{attribute.String("signer", "txn"), attribute.String("kid", kid)}
+	}
+	if n := sumOf(t, metrics["parsec.key.signatures"], attrs("old")...); n != 7 {
+		t.Errorf("expected 7 signatures with the old key, got %d", n)
+	}
+	if n := sumOf(t, metrics["parsec.key.signatures"], attrs("new")...); n != 3 {
+		t.Errorf("expected 3 signatures with the new key, got %d", n)
+	}
+
+	gauge, ok := metrics["parsec.key.last_used"].(metricdata.Gauge[int64])
+	if !ok {
+		t.Fatalf("expected an int64 gauge, got %T", metrics["parsec.key.last_used"])
+	}
+	want := attribute.NewSet(attrs("new")...)
+	for _, point := range gauge.DataPoints {
+		if point.Attributes.Equals(&want) && point.Value != lastUsed.Unix() {
+			t.Errorf("expected the new key last used at %d, got %d", lastUsed.Unix(), point.Value)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// instrumentationName names the meter of parsec's metrics
+const instrumentationName = "github.com/project-kessel/parsec"
+
+// Outcomes recorded in the outcome attribute
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+)
+
+// observer records metrics for token issuance, token exchange, authorization
+// checks and bulkheads
+type observer struct {
+	service.NoOpApplicationObserver
+	now func() time.Time
+
+	issuances        metric.Int64Counter
+	issuanceDuration metric.Float64Histogram
+	mapperFailures   metric.Int64Counter
+	exchanges        metric.Int64Counter
+	exchangeDuration metric.Float64Histogram
+	authzChecks      metric.Int64Counter
+	authzDuration    metric.Float64Histogram
+
+	bulkheadInFlight metric.Int64Gauge
+	bulkheadQueued   metric.Int64Gauge
+	bulkheadWait     metric.Float64Histogram
+	bulkheadRejected metric.Int64Counter
+}
+
+// NewObserver creates an application observer recording metrics with meter
+func NewObserver(meter metric.Meter) (service.ApplicationObserver, error) {
+	o := &observer{now: time.Now}
+
+	var err error
+	if o.issuances, err = meter.Int64Counter("parsec.token.issuances",
+		metric.WithDescription("Tokens issued or failed, by token type and outcome"),
+		metric.WithUnit("{token}")); err != nil {
+		return nil, err
+	}
+	if o.issuanceDuration, err = meter.Float64Histogram("parsec.token.issuance.duration",
+		metric.WithDescription("Duration of token issuance requests"),
+		metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if o.mapperFailures, err = meter.Int64Counter("parsec.mapper.optional_failures",
+		metric.WithDescription("Failures of optional claim mappers whose claims were omitted, by mapper"),
+		metric.WithUnit("{failure}")); err != nil {
+		return nil, err
+	}
+	if o.exchanges, err = meter.Int64Counter("parsec.token.exchanges",
+		metric.WithDescription("Token exchange requests, by grant type and outcome"),
+		metric.WithUnit("{request}")); err != nil {
+		return nil, err
+	}
+	if o.exchangeDuration, err = meter.Float64Histogram("parsec.token.exchange.duration",
+		metric.WithDescription("Duration of token exchange requests"),
+		metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if o.authzChecks, err = meter.Int64Counter("parsec.authz.checks",
+		metric.WithDescription("Authorization checks, by outcome"),
+		metric.WithUnit("{request}")); err != nil {
+		return nil, err
+	}
+	if o.authzDuration, err = meter.Float64Histogram("parsec.authz.check.duration",
+		metric.WithDescription("Duration of authorization checks"),
+		metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if o.bulkheadInFlight, err = meter.Int64Gauge("parsec.bulkhead.in_flight",
+		metric.WithDescription("Calls in flight through a bulkhead when a call was admitted"),
+		metric.WithUnit("{call}")); err != nil {
+		return nil, err
+	}
+	if o.bulkheadQueued, err = meter.Int64Gauge("parsec.bulkhead.queued",
+		metric.WithDescription("Calls waiting for a bulkhead slot when a call was queued"),
+		metric.WithUnit("{call}")); err != nil {
+		return nil, err
+	}
+	if o.bulkheadWait, err = meter.Float64Histogram("parsec.bulkhead.wait.duration",
+		metric.WithDescription("Time calls waited for a bulkhead slot"),
+		metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if o.bulkheadRejected, err = meter.Int64Counter("parsec.bulkhead.rejections",
+		metric.WithDescription("Calls turned away by a bulkhead"),
+		metric.WithUnit("{call}")); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// outcomeAttributes describes the outcome of an operation failing with err (nil on success)
+func outcomeAttributes(err error, attrs ...attribute.KeyValue) metric.MeasurementOption {
+	if err != nil {
+		attrs = append(attrs,
+			attribute.String("outcome", OutcomeFailed),
+			attribute.String("error.code", string(perr.CodeOf(err))))
+	} else {
+		attrs = append(attrs, attribute.String("outcome", OutcomeSucceeded))
+	}
+	return metric.WithAttributes(attrs...)
+}
+
+// failure keeps the first error of an operation
+type failure struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (f *failure) set(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+func (f *failure) get() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+// TokenIssuanceStarted implements service.TokenServiceObserver
+func (o *observer) TokenIssuanceStarted(
+	ctx context.Context,
+	subject *trust.Result,
+	actor *trust.Result,
+	scope string,
+	tokenTypes []service.TokenType,
+) (context.Context, service.TokenIssuanceProbe) {
+	return ctx, &issuanceProbe{ctx: ctx, observer: o, started: o.now()}
+}
+
+// issuanceProbe counts the tokens of one issuance and times it
+// Token types may be issued concurrently.
+type issuanceProbe struct {
+	service.NoOpTokenIssuanceProbe
+	ctx      context.Context
+	observer *observer
+	started  time.Time
+	failure  failure
+}
+
+func (p *issuanceProbe) TokenTypeIssuanceSucceeded(tokenType service.TokenType, token *service.Token) {
+	p.observer.issuances.Add(p.ctx, 1, outcomeAttributes(nil, attribute.String("token_type", string(tokenType))))
+}
+
+func (p *issuanceProbe) TokenTypeIssuanceFailed(tokenType service.TokenType, err error) {
+	p.failed(tokenType, err)
+}
+
+func (p *issuanceProbe) IssuerNotFound(tokenType service.TokenType, err error) {
+	p.failed(tokenType, err)
+}
+
+func (p *issuanceProbe) IssuanceRefused(err error) {
+	p.failure.set(err)
+}
+
+func (p *issuanceProbe) OptionalMapperFailed(mapper string, err error) {
+	p.observer.mapperFailures.Add(p.ctx, 1, metric.WithAttributes(
+		attribute.String("mapper", mapper),
+		attribute.String("error.code", string(perr.CodeOf(err)))))
+}
+
+func (p *issuanceProbe) failed(tokenType service.TokenType, err error) {
+	p.failure.set(err)
+	p.observer.issuances.Add(p.ctx, 1, outcomeAttributes(err, attribute.String("token_type", string(tokenType))))
+}
+
+func (p *issuanceProbe) End() {
+	elapsed := p.observer.now().Sub(p.started).Seconds()
+	p.observer.issuanceDuration.Record(p.ctx, elapsed, outcomeAttributes(p.failure.get()))
+}
+
+// TokenExchangeStarted implements service.TokenExchangeObserver
+func (o *observer) TokenExchangeStarted(
+	ctx context.Context,
+	grantType string,
+	requestedTokenType string,
+	audience string,
+	scope string,
+) (context.Context, service.TokenExchangeProbe) {
+	return ctx, &exchangeProbe{ctx: ctx, observer: o, grantType: grantType, started: o.now()}
+}
+
+// exchangeProbe counts and times one token exchange request
+type exchangeProbe struct {
+	service.NoOpTokenExchangeProbe
+	ctx       context.Context
+	observer  *observer
+	grantType string
+	started   time.Time
+	failure   failure
+}
+
+func (p *exchangeProbe) ActorValidationFailed(err error)        { p.failure.set(err) }
+func (p *exchangeProbe) RequestContextParseFailed(err error)    { p.failure.set(err) }
+func (p *exchangeProbe) SubjectTokenValidationFailed(err error) { p.failure.set(err) }
+func (p *exchangeProbe) DelegationDenied(err error)             { p.failure.set(err) }
+
+func (p *exchangeProbe) End() {
+	attrs := outcomeAttributes(p.failure.get(), attribute.String("grant_type", p.grantType))
+	p.observer.exchanges.Add(p.ctx, 1, attrs)
+	p.observer.exchangeDuration.Record(p.ctx, p.observer.now().Sub(p.started).Seconds(), attrs)
+}
+
+// AuthzCheckStarted implements service.AuthzCheckObserver
+func (o *observer) AuthzCheckStarted(ctx context.Context) (context.Context, service.AuthzCheckProbe) {
+	return ctx, &authzProbe{ctx: ctx, observer: o, started: o.now()}
+}
+
+// authzProbe counts and times one authorization check
+type authzProbe struct {
+	service.NoOpAuthzCheckProbe
+	ctx      context.Context
+	observer *observer
+	started  time.Time
+	failure  failure
+}
+
+func (p *authzProbe) RequestAttributeExtractionFailed(err error)    { p.failure.set(err) }
+func (p *authzProbe) ClientAddressDenied(address string, err error) { p.failure.set(err) }
+func (p *authzProbe) ActorValidationFailed(err error)               { p.failure.set(err) }
+func (p *authzProbe) SubjectCredentialExtractionFailed(err error)   { p.failure.set(err) }
+func (p *authzProbe) SubjectValidationFailed(err error)             { p.failure.set(err) }
+func (p *authzProbe) TokenIssuanceFailed(err error)                 { p.failure.set(err) }
+
+func (p *authzProbe) End() {
+	attrs := outcomeAttributes(p.failure.get())
+	p.observer.authzChecks.Add(p.ctx, 1, attrs)
+	p.observer.authzDuration.Record(p.ctx, p.observer.now().Sub(p.started).Seconds(), attrs)
+}
+
+// BulkheadCallStarted implements service.BulkheadObserver
+func (o *observer) BulkheadCallStarted(ctx context.Context, bulkhead string) (context.Context, service.BulkheadProbe) {
+	return ctx, &bulkheadProbe{ctx: ctx, observer: o, attrs: metric.WithAttributes(attribute.String("bulkhead", bulkhead))}
+}
+
+// bulkheadProbe records the saturation of a bulkhead seen by one call
+type bulkheadProbe struct {
+	service.NoOpBulkheadProbe
+	ctx      context.Context
+	observer *observer
+	attrs    metric.MeasurementOption
+}
+
+func (p *bulkheadProbe) BulkheadQueued(queued int) {
+	p.observer.bulkheadQueued.Record(p.ctx, int64(queued), p.attrs)
+}
+
+func (p *bulkheadProbe) BulkheadAdmitted(inFlight int, waited time.Duration) {
+	p.observer.bulkheadInFlight.Record(p.ctx, int64(inFlight), p.attrs)
+	p.observer.bulkheadWait.Record(p.ctx, waited.Seconds(), p.attrs)
+}
+
+func (p *bulkheadProbe) BulkheadRejected(err error) {
+	p.observer.bulkheadRejected.Add(p.ctx, 1, p.attrs)
+}
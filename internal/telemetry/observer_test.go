@@ -0,0 +1,144 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+)
+
+// collect reads the metrics recorded so far, by name
+func collect(t *testing.T, reader sdkmetric.Reader) map[string]metricdata.Aggregation {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	metrics := make(map[string]metricdata.Aggregation)
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+	return metrics
+}
+
+// sumOf returns the value of the sum data point with attrs
+func sumOf(t *testing.T, data metricdata.Aggregation, attrs ...attribute.KeyValue) int64 {
+	t.Helper()
+	sum, ok := data.(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("expected an int64 sum, got %T", data)
+	}
+	want := attribute.NewSet(attrs...)
+	for _, point := range sum.DataPoints {
+		if point.Attributes.Equals(&want) {
+			return point.Value
+		}
+	}
+	return 0
+}
+
+func newTestObserver(t *testing.T) (service.ApplicationObserver, sdkmetric.Reader) {
+	t.Helper()
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	observer, err := NewObserver(provider.Meter("test"))
+	if err != nil {
+		t.Fatalf("NewObserver failed: %v", err)
+	}
+	return observer, reader
+}
+
+func TestObserver_TokenIssuance(t *testing.T) {
+	observer, reader := newTestObserver(t)
+
+	_, probe := observer.TokenIssuanceStarted(context.Background(), nil, nil, "", nil)
+	probe.TokenTypeIssuanceSucceeded(service.TokenTypeTransactionToken, &service.Token{})
+	probe.TokenTypeIssuanceFailed(service.TokenTypeAccessToken, perr.New(perr.ErrCodeIssuerUnavailable, "signer unavailable"))
+	probe.End()
+
+	metrics := collect(t, reader)
+	if n := sumOf(t, metrics["parsec.token.issuances"],
+		attribute.String("token_type", string(service.TokenTypeTransactionToken)),
+		attribute.String("outcome", OutcomeSucceeded)); n != 1 {
+		t.Errorf("expected 1 issued transaction token, got %d", n)
+	}
+	if n := sumOf(t, metrics["parsec.token.issuances"],
+		attribute.String("token_type", string(service.TokenTypeAccessToken)),
+		attribute.String("outcome", OutcomeFailed),
+		attribute.String("error.code", string(perr.ErrCodeIssuerUnavailable))); n != 1 {
+		t.Errorf("expected 1 failed access token, got %d", n)
+	}
+
+	duration, ok := metrics["parsec.token.issuance.duration"].(metricdata.Histogram[float64])
+	if !ok || len(duration.DataPoints) != 1 || duration.DataPoints[0].Count != 1 {
+		t.Fatalf("expected one issuance duration, got %+v", metrics["parsec.token.issuance.duration"])
+	}
+	if outcome, _ := duration.DataPoints[0].Attributes.Value("outcome"); outcome.AsString() != OutcomeFailed {
+		t.Errorf("expected the issuance to have failed, got %q", outcome.AsString())
+	}
+}
+
+func TestObserver_ExchangeAndAuthz(t *testing.T) {
+	observer, reader := newTestObserver(t)
+	ctx := context.Background()
+
+	_, exchange := observer.TokenExchangeStarted(ctx, "urn:ietf:params:oauth:grant-type:token-exchange", "", "", "")
+	exchange.End()
+	_, exchange = observer.TokenExchangeStarted(ctx, "urn:ietf:params:oauth:grant-type:token-exchange", "", "", "")
+	exchange.SubjectTokenValidationFailed(errors.New("expired"))
+	exchange.End()
+
+	_, authz := observer.AuthzCheckStarted(ctx)
+	authz.SubjectCredentialExtractionFailed(perr.New(perr.ErrCodeMissingCredential, "no credential"))
+	authz.End()
+
+	metrics := collect(t, reader)
+	grantType := attribute.String("grant_type", "urn:ietf:params:oauth:grant-type:token-exchange")
+	if n := sumOf(t, metrics["parsec.token.exchanges"], grantType, attribute.String("outcome", OutcomeSucceeded)); n != 1 {
+		t.Errorf("expected 1 successful exchange, got %d", n)
+	}
+	if n := sumOf(t, metrics["parsec.token.exchanges"], grantType,
+		attribute.String("outcome", OutcomeFailed), attribute.String("error.code", string(perr.ErrCodeInternal))); n != 1 {
+		t.Errorf("expected 1 failed exchange, got %d", n)
+	}
+	if n := sumOf(t, metrics["parsec.authz.checks"],
+		attribute.String("outcome", OutcomeFailed), attribute.String("error.code", string(perr.ErrCodeMissingCredential))); n != 1 {
+		t.Errorf("expected 1 failed authz check, got %d", n)
+	}
+}
+
+func TestObserver_Bulkhead(t *testing.T) {
+	observer, reader := newTestObserver(t)
+	ctx := context.Background()
+
+	_, probe := observer.BulkheadCallStarted(ctx, "issuer:jwt")
+	probe.BulkheadQueued(1)
+	probe.BulkheadAdmitted(4, 20*time.Millisecond)
+	probe.End()
+	_, probe = observer.BulkheadCallStarted(ctx, "issuer:jwt")
+	probe.BulkheadRejected(errors.New("queue full"))
+	probe.End()
+
+	metrics := collect(t, reader)
+	bulkhead := attribute.String("bulkhead", "issuer:jwt")
+	if n := sumOf(t, metrics["parsec.bulkhead.rejections"], bulkhead); n != 1 {
+		t.Errorf("expected 1 rejection, got %d", n)
+	}
+	inFlight, ok := metrics["parsec.bulkhead.in_flight"].(metricdata.Gauge[int64])
+	if !ok || len(inFlight.DataPoints) != 1 || inFlight.DataPoints[0].Value != 4 {
+		t.Errorf("expected 4 calls in flight, got %+v", metrics["parsec.bulkhead.in_flight"])
+	}
+	wait, ok := metrics["parsec.bulkhead.wait.duration"].(metricdata.Histogram[float64])
+	if !ok || len(wait.DataPoints) != 1 || wait.DataPoints[0].Sum != 0.02 {
+		t.Errorf("expected a 20ms wait, got %+v", metrics["parsec.bulkhead.wait.duration"])
+	}
+}
@@ -0,0 +1,206 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// Resource attribute keys set from the configuration
+const (
+	AttrServiceName       = "service.name"
+	AttrServiceInstanceID = "service.instance.id"
+	AttrTrustDomain       = "parsec.trust_domain"
+)
+
+// OTLP protocols
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// Config configures metrics export
+type Config struct {
+	// ServiceName, Instance and TrustDomain identify this parsec in the
+	// resource attached to every metric; Attributes are added to them
+	ServiceName string
+	Instance    string
+	TrustDomain string
+	Attributes  map[string]string
+
+	// Prometheus serves the metrics for scraping from Handler
+	Prometheus bool
+
+	// OTLP pushes the metrics to a collector (optional)
+	OTLP *OTLPConfig
+}
+
+// OTLPConfig configures pushing metrics with OTLP
+type OTLPConfig struct {
+	// Protocol is ProtocolGRPC (default) or ProtocolHTTP
+	Protocol string
+
+	// Endpoint is the collector's host:port (default: the exporter's,
+	// localhost:4317 for gRPC and localhost:4318 for HTTP)
+	Endpoint string
+
+	// Insecure disables TLS to the collector
+	Insecure bool
+
+	// Headers are sent with every export, e.g. for authentication
+	Headers map[string]string
+
+	// Interval between exports (default: 60s) and Timeout of each export
+	// (default: 30s)
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+// Telemetry owns the meter provider metrics are recorded with and the
+// exporters they are read by
+type Telemetry struct {
+	provider *sdkmetric.MeterProvider
+	handler  http.Handler
+}
+
+// New creates the meter provider and its exporters
+// The OTLP exporter connects lazily, so an unreachable collector does not
+// fail New; exports are retried on the next interval.
+func New(ctx context.Context, cfg Config) (*Telemetry, error) {
+	if cfg.ServiceName == "" {
+		return nil, fmt.Errorf("telemetry requires a service name")
+	}
+	if !cfg.Prometheus && cfg.OTLP == nil {
+		return nil, fmt.Errorf("telemetry requires prometheus or otlp metrics")
+	}
+
+	opts := []sdkmetric.Option{sdkmetric.WithResource(newResource(cfg))}
+	t := &Telemetry{}
+
+	if cfg.Prometheus {
+		// A dedicated registry keeps the process-wide default one untouched
+		registry := prometheus.NewRegistry()
+		exporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create prometheus exporter: %w", err)
+		}
+		opts = append(opts, sdkmetric.WithReader(exporter))
+		t.handler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	}
+
+	if cfg.OTLP != nil {
+		exporter, err := newOTLPExporter(ctx, cfg.OTLP)
+		if err != nil {
+			return nil, err
+		}
+		var readerOpts []sdkmetric.PeriodicReaderOption
+		if cfg.OTLP.Interval > 0 {
+			readerOpts = append(readerOpts, sdkmetric.WithInterval(cfg.OTLP.Interval))
+		}
+		if cfg.OTLP.Timeout > 0 {
+			readerOpts = append(readerOpts, sdkmetric.WithTimeout(cfg.OTLP.Timeout))
+		}
+		opts = append(opts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, readerOpts...)))
+	}
+
+	t.provider = sdkmetric.NewMeterProvider(opts...)
+	return t, nil
+}
+
+// newResource describes this parsec to the metrics backend
+func newResource(cfg Config) *resource.Resource {
+	attrs := make([]attribute.KeyValue, 0, len(cfg.Attributes)+3)
+	for key, value := range cfg.Attributes {
+		attrs = append(attrs, attribute.String(key, value))
+	}
+	// The configured identity takes precedence over the free-form attributes
+	attrs = append(attrs, attribute.String(AttrServiceName, cfg.ServiceName))
+	if cfg.Instance != "" {
+		attrs = append(attrs, attribute.String(AttrServiceInstanceID, cfg.Instance))
+	}
+	if cfg.TrustDomain != "" {
+		attrs = append(attrs, attribute.String(AttrTrustDomain, cfg.TrustDomain))
+	}
+	return resource.NewSchemaless(attrs...)
+}
+
+// newOTLPExporter creates the OTLP metric exporter for cfg.Protocol
+func newOTLPExporter(ctx context.Context, cfg *OTLPConfig) (sdkmetric.Exporter, error) {
+	switch cfg.Protocol {
+	case "", ProtocolGRPC:
+		var opts []otlpmetricgrpc.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlpmetricgrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlpmetricgrpc.WithHeaders(cfg.Headers))
+		}
+		if cfg.Timeout > 0 {
+			opts = append(opts, otlpmetricgrpc.WithTimeout(cfg.Timeout))
+		}
+		exporter, err := otlpmetricgrpc.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create otlp grpc exporter: %w", err)
+		}
+		return exporter, nil
+	case ProtocolHTTP:
+		var opts []otlpmetrichttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlpmetrichttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlpmetrichttp.WithHeaders(cfg.Headers))
+		}
+		if cfg.Timeout > 0 {
+			opts = append(opts, otlpmetrichttp.WithTimeout(cfg.Timeout))
+		}
+		exporter, err := otlpmetrichttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create otlp http exporter: %w", err)
+		}
+		return exporter, nil
+	default:
+		return nil, fmt.Errorf("unknown otlp protocol: %s (supported: grpc, http)", cfg.Protocol)
+	}
+}
+
+// Meter returns the meter parsec records its metrics with
+func (t *Telemetry) Meter() metric.Meter {
+	return t.provider.Meter(instrumentationName)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+// Returns nil if Prometheus is not enabled.
+func (t *Telemetry) Handler() http.Handler {
+	return t.handler
+}
+
+// Shutdown pushes the pending metrics and stops the exporters
+// It is safe to call on a nil Telemetry.
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	if err := t.provider.Shutdown(ctx); err != nil && !errors.Is(err, sdkmetric.ErrReaderShutdown) {
+		return fmt.Errorf("failed to shut down telemetry: %w", err)
+	}
+	return nil
+}
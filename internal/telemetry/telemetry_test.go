@@ -0,0 +1,87 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTelemetry_PrometheusHandler(t *testing.T) {
+	telemetry, err := New(context.Background(), Config{
+		ServiceName: "parsec",
+		Instance:    "parsec-0",
+		TrustDomain: "trust.example.com",
+		Attributes:  map[string]string{"deployment.environment": "test", "service.name": "ignored"},
+		Prometheus:  true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer func() { _ = telemetry.Shutdown(context.Background()) }()
+
+	observer, err := NewObserver(telemetry.Meter())
+	if err != nil {
+		t.Fatalf("NewObserver failed: %v", err)
+	}
+	_, probe := observer.AuthzCheckStarted(context.Background())
+	probe.End()
+
+	rec := httptest.NewRecorder()
+	telemetry.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	for _, want := range []string{
+		`parsec_authz_checks_total{`,
+		`outcome="succeeded"`,
+		`service_name="parsec"`,
+		`service_instance_id="parsec-0"`,
+		`parsec_trust_domain="trust.example.com"`,
+		`deployment_environment="test"`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected %s in the scrape, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestTelemetry_OTLP(t *testing.T) {
+	for _, protocol := range []string{ProtocolGRPC, ProtocolHTTP} {
+		t.Run(protocol, func(t *testing.T) {
+			telemetry, err := New(context.Background(), Config{
+				ServiceName: "parsec",
+				OTLP:        &OTLPConfig{Protocol: protocol, Endpoint: "localhost:1", Insecure: true},
+			})
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			if telemetry.Handler() != nil {
+				t.Error("expected no scrape handler without prometheus")
+			}
+			// The unreachable collector fails the final push, not the shutdown of the exporters
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			_ = telemetry.Shutdown(ctx)
+		})
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	if _, err := New(context.Background(), Config{Prometheus: true}); err == nil {
+		t.Error("expected error without service name")
+	}
+	if _, err := New(context.Background(), Config{ServiceName: "parsec"}); err == nil {
+		t.Error("expected error without exporters")
+	}
+	if _, err := New(context.Background(), Config{ServiceName: "parsec", OTLP: &OTLPConfig{Protocol: "thrift"}}); err == nil {
+		t.Error("expected error for unknown protocol")
+	}
+}
+
+func TestTelemetry_ShutdownNil(t *testing.T) {
+	var telemetry *Telemetry
+	if err := telemetry.Shutdown(context.Background()); err != nil {
+		t.Errorf("expected nil-safe shutdown, got %v", err)
+	}
+}
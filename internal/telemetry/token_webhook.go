@@ -0,0 +1,33 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+// TokenWebhookSource reports the token summaries a webhook dropped
+// tokenwebhook.Notifier implements it.
+type TokenWebhookSource interface {
+	Dropped() uint64
+}
+
+// RegisterTokenWebhookMetrics reports the summaries source dropped whenever
+// metrics are collected
+//
+// Drops are only logged periodically, so alert on this counter rather than
+// on the logs.
+func RegisterTokenWebhookMetrics(meter metric.Meter, source TokenWebhookSource) error {
+	dropped, err := meter.Int64ObservableCounter("parsec.token_webhook.dropped",
+		metric.WithDescription("Token summaries not delivered to the token webhook"),
+		metric.WithUnit("{summary}"))
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveInt64(dropped, int64(source.Dropped()))
+		return nil
+	}, dropped)
+	return err
+}
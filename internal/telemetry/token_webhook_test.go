@@ -0,0 +1,24 @@
+package telemetry
+
+import (
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+type staticDrops uint64
+
+func (s staticDrops) Dropped() uint64 { return uint64(s) }
+
+func TestRegisterTokenWebhookMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	if err := RegisterTokenWebhookMetrics(provider.Meter("test"), staticDrops(5)); err != nil {
+		t.Fatalf("RegisterTokenWebhookMetrics failed: %v", err)
+	}
+
+	metrics := collect(t, reader)
+	if n := sumOf(t, metrics["parsec.token_webhook.dropped"]); n != 5 {
+		t.Errorf("expected 5 dropped summaries, got %d", n)
+	}
+}
@@ -0,0 +1,105 @@
+package tokenwebhook
+
+import (
+	"context"
+	"sync"
+
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// observer notifies the webhook of the tokens of each successful issuance
+type observer struct {
+	service.NoOpApplicationObserver
+	notifier *Notifier
+}
+
+// NewObserver creates an application observer notifying notifier of every
+// token issued
+// Tokens of an issuance that fails as a whole are not returned to the caller,
+// so they are left out.
+func NewObserver(notifier *Notifier) service.ApplicationObserver {
+	return &observer{notifier: notifier}
+}
+
+// TokenIssuanceStarted implements service.TokenServiceObserver
+func (o *observer) TokenIssuanceStarted(
+	ctx context.Context,
+	subject *trust.Result,
+	actor *trust.Result,
+	scope string,
+	tokenTypes []service.TokenType,
+) (context.Context, service.TokenIssuanceProbe) {
+	return ctx, &issuanceProbe{
+		notifier: o.notifier,
+		subject:  subject,
+		actor:    actor,
+	}
+}
+
+// issuanceProbe collects the tokens of one issuance
+// Token types may be issued concurrently, so the tokens are guarded.
+type issuanceProbe struct {
+	service.NoOpTokenIssuanceProbe
+	notifier *Notifier
+	subject  *trust.Result
+	actor    *trust.Result
+
+	mu     sync.Mutex
+	tokens []Summary
+	failed bool
+}
+
+func (p *issuanceProbe) TokenTypeIssuanceSucceeded(tokenType service.TokenType, token *service.Token) {
+	if token == nil {
+		return
+	}
+	summary := Summary{
+		Type:          string(tokenType),
+		JTI:           token.ID,
+		Audience:      token.Audience,
+		IssuedAt:      token.IssuedAt.UTC(),
+		ExpiresAt:     token.ExpiresAt.UTC(),
+		TransactionID: token.TransactionID,
+	}
+	if p.subject != nil {
+		summary.SubjectHash = p.notifier.HashSubject(p.subject.Subject)
+		summary.SubjectTrustDomain = p.subject.TrustDomain
+	}
+	if p.actor != nil {
+		summary.Actor = &Actor{Subject: p.actor.Subject, TrustDomain: p.actor.TrustDomain}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tokens = append(p.tokens, summary)
+}
+
+func (p *issuanceProbe) TokenTypeIssuanceFailed(tokenType service.TokenType, err error) {
+	p.fail()
+}
+
+func (p *issuanceProbe) IssuerNotFound(tokenType service.TokenType, err error) {
+	p.fail()
+}
+
+func (p *issuanceProbe) IssuanceRefused(err error) {
+	p.fail()
+}
+
+func (p *issuanceProbe) fail() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failed = true
+}
+
+func (p *issuanceProbe) End() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failed {
+		return
+	}
+	for _, summary := range p.tokens {
+		p.notifier.Notify(summary)
+	}
+}
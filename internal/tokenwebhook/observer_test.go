@@ -0,0 +1,89 @@
+package tokenwebhook
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// stubIssuer issues a fixed token, or fails with err
+type stubIssuer struct {
+	err error
+}
+
+func (i *stubIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	if i.err != nil {
+		return nil, i.err
+	}
+	now := time.Now()
+	return &service.Token{
+		Value:         "secret-token",
+		IssuedAt:      now,
+		ExpiresAt:     now.Add(5 * time.Minute),
+		ID:            "jti-1",
+		Audience:      issueCtx.TokenAudiences(),
+		TransactionID: "txn-1",
+	}, nil
+}
+
+func (i *stubIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	return nil, nil
+}
+
+func TestObserver_NotifiesIssuedTokens(t *testing.T) {
+	recv := &receiver{t: t, secret: "s3cret"}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	notifier, err := NewNotifier(Config{URL: srv.URL, Secret: "s3cret"})
+	if err != nil {
+		t.Fatalf("NewNotifier failed: %v", err)
+	}
+
+	registry := service.NewSimpleRegistry()
+	registry.Register(service.TokenTypeTransactionToken, &stubIssuer{})
+	registry.Register(service.TokenTypeAccessToken, &stubIssuer{err: perr.New(perr.ErrCodeIssuerUnavailable, "signer unavailable")})
+	tokenService := service.NewTokenService("trust.example.com", nil, registry, NewObserver(notifier))
+
+	subject := &trust.Result{Subject: "alice", TrustDomain: "idp.example.com"}
+	actor := &trust.Result{Subject: "spiffe://example.com/gateway", TrustDomain: "example.com"}
+
+	if _, err := tokenService.IssueTokens(context.Background(), &service.IssueRequest{
+		Subject:    subject,
+		Actor:      actor,
+		TokenTypes: []service.TokenType{service.TokenTypeTransactionToken},
+	}); err != nil {
+		t.Fatalf("IssueTokens failed: %v", err)
+	}
+	// The transaction token of a failed issuance is not returned, so not notified
+	_, _ = tokenService.IssueTokens(context.Background(), &service.IssueRequest{
+		Subject:    subject,
+		TokenTypes: []service.TokenType{service.TokenTypeTransactionToken, service.TokenTypeAccessToken},
+	})
+	if err := notifier.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	tokens := recv.received()
+	if len(tokens) != 1 {
+		t.Fatalf("expected 1 summary, got %+v", tokens)
+	}
+	summary := tokens[0]
+	if summary.JTI != "jti-1" || summary.TransactionID != "txn-1" || summary.Type != string(service.TokenTypeTransactionToken) {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if len(summary.Audience) != 1 || summary.Audience[0] != "trust.example.com" || summary.ExpiresAt.IsZero() {
+		t.Errorf("expected audience and expiry, got %+v", summary)
+	}
+	if summary.SubjectHash != Sign([]byte("alice"), "s3cret") || summary.SubjectTrustDomain != "idp.example.com" {
+		t.Errorf("expected the keyed subject hash, got %+v", summary)
+	}
+	if summary.Actor == nil || summary.Actor.Subject != "spiffe://example.com/gateway" {
+		t.Errorf("expected the actor, got %+v", summary.Actor)
+	}
+}
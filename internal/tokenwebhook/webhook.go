@@ -0,0 +1,340 @@
+// Package tokenwebhook POSTs a summary of every issued token to a webhook,
+// for teams keeping a near-real-time inventory of the tokens in circulation
+// without scraping logs.
+package tokenwebhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SchemaVersion identifies the schema of the summaries. Fields may be added
+// within a version; removing or changing the meaning of a field requires a
+// new version.
+const SchemaVersion = "parsec.token/v1"
+
+// SignatureHeader carries the signature of a webhook request, as
+// "t=<unix time>,v1=<hex HMAC-SHA256 of the time, a '.' and the body>"
+// Receivers should recompute the HMAC with the shared secret and reject
+// requests whose time is too far from their own, so requests cannot be
+// replayed.
+const SignatureHeader = "Parsec-Signature"
+
+// Summary describes an issued token
+// Token values are never sent, and the subject only as a keyed hash.
+type Summary struct {
+	SchemaVersion string `json:"schema_version"`
+	Type          string `json:"type"`
+
+	// JTI is the token's "jti" claim, empty for token types without one
+	JTI string `json:"jti,omitempty"`
+
+	// SubjectHash is the hex HMAC-SHA256 of the subject keyed with the
+	// webhook secret (see HashSubject), so receivers can look up known
+	// subjects without the webhook disclosing them
+	SubjectHash        string `json:"sub_hash"`
+	SubjectTrustDomain string `json:"sub_trust_domain,omitempty"`
+
+	Audience  []string  `json:"aud,omitempty"`
+	IssuedAt  time.Time `json:"iat,omitzero"`
+	ExpiresAt time.Time `json:"exp,omitzero"`
+
+	// TransactionID is the "txn" claim of transaction tokens
+	TransactionID string `json:"txn,omitempty"`
+
+	// Actor is the workload the token was requested by (nil if anonymous)
+	Actor *Actor `json:"actor,omitempty"`
+}
+
+// Actor identifies the workload a token was requested by
+type Actor struct {
+	Subject     string `json:"sub"`
+	TrustDomain string `json:"trust_domain,omitempty"`
+}
+
+// payload is the body of a webhook request
+type payload struct {
+	Tokens []Summary `json:"tokens"`
+}
+
+// Notifier delivers token summaries to a webhook
+//
+// Summaries are buffered and POSTed in the background, in batches, so
+// issuance is never delayed by the webhook. A batch the webhook does not
+// accept with a 2xx status is retried with backoff, then dropped. Summaries
+// recorded while the buffer is full, or still buffered when the process dies,
+// are dropped too. Drops are counted (see Dropped) and logged at most once per
+// DropLogInterval: the webhook is an inventory feed, not an audit trail (see
+// the decision log for that).
+type Notifier struct {
+	url          string
+	secret       []byte
+	client       *http.Client
+	batchSize    int
+	retries      int
+	retryBackoff time.Duration
+	now          func() time.Time
+	logger       *slog.Logger
+
+	mu      sync.RWMutex
+	closed  bool
+	pending chan Summary
+	done    chan struct{}
+	dropped atomic.Uint64
+
+	// dropLogMu guards the drop log rate limit
+	dropLogMu     sync.Mutex
+	lastDropLog   time.Time
+	droppedLogged uint64
+}
+
+// DropLogInterval is the minimum time between logs of dropped summaries
+// Drops in between are reported together by the next log.
+const DropLogInterval = time.Minute
+
+// Config configures a token webhook notifier
+type Config struct {
+	// URL receives the summaries
+	URL string
+
+	// Secret signs requests (see SignatureHeader) and keys subject hashes
+	Secret string
+
+	// Timeout bounds each request (default: 5s)
+	Timeout time.Duration
+
+	// Transport is the HTTP transport (default: http.DefaultTransport)
+	Transport http.RoundTripper
+
+	// BufferSize is the number of summaries buffered (default: 1024)
+	BufferSize int
+
+	// BatchSize is the maximum number of summaries POSTed at once (default: 100)
+	BatchSize int
+
+	// Retries is the number of times a failed request is retried before its
+	// summaries are dropped (default: 3, negative disables retries)
+	Retries int
+
+	// RetryBackoff is the delay before the first retry, doubling with each
+	// retry (default: 100ms)
+	RetryBackoff time.Duration
+
+	// Logger reports dropped summaries (default: slog.Default())
+	Logger *slog.Logger
+}
+
+// NewNotifier creates a notifier, which delivers summaries in the background
+// until closed
+func NewNotifier(cfg Config) (*Notifier, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("token webhook requires a url")
+	}
+	if cfg.Secret == "" {
+		return nil, fmt.Errorf("token webhook requires a secret")
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = 1024
+	}
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	retries := cfg.Retries
+	if retries < 0 {
+		retries = 0
+	} else if retries == 0 {
+		retries = 3
+	}
+	retryBackoff := cfg.RetryBackoff
+	if retryBackoff <= 0 {
+		retryBackoff = 100 * time.Millisecond
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	n := &Notifier{
+		url:          cfg.URL,
+		secret:       []byte(cfg.Secret),
+		client:       &http.Client{Transport: cfg.Transport, Timeout: timeout},
+		batchSize:    batchSize,
+		retries:      retries,
+		retryBackoff: retryBackoff,
+		now:          time.Now,
+		logger:       logger.With("event", "token_webhook"),
+		pending:      make(chan Summary, bufferSize),
+		done:         make(chan struct{}),
+	}
+	go n.run()
+	return n, nil
+}
+
+// HashSubject returns the subject hash of summaries: the hex HMAC-SHA256 of
+// subject keyed with the webhook secret
+func (n *Notifier) HashSubject(subject string) string {
+	return Sign([]byte(subject), string(n.secret))
+}
+
+// Notify buffers a summary for delivery, dropping it if the buffer is full
+// or the notifier closed
+func (n *Notifier) Notify(summary Summary) {
+	summary.SchemaVersion = SchemaVersion
+
+	n.mu.RLock()
+	if !n.closed {
+		select {
+		case n.pending <- summary:
+			n.mu.RUnlock()
+			return
+		default:
+		}
+	}
+	n.mu.RUnlock()
+
+	n.drop(1, errors.New("token webhook buffer is full or closed"))
+}
+
+// Dropped returns the number of summaries that were not delivered
+func (n *Notifier) Dropped() uint64 {
+	return n.dropped.Load()
+}
+
+// Close delivers the buffered summaries
+// If ctx ends first, the remaining summaries are abandoned.
+func (n *Notifier) Close(ctx context.Context) error {
+	if n == nil {
+		return nil
+	}
+
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return nil
+	}
+	n.closed = true
+	close(n.pending)
+	n.mu.Unlock()
+
+	select {
+	case <-n.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("token webhook not flushed: %w", ctx.Err())
+	}
+}
+
+// run delivers buffered summaries in batches until the buffer is closed
+func (n *Notifier) run() {
+	defer close(n.done)
+	ctx := context.Background()
+
+	for summary := range n.pending {
+		batch := []Summary{summary}
+	fill:
+		for len(batch) < n.batchSize {
+			select {
+			case next, ok := <-n.pending:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, next)
+			default:
+				break fill
+			}
+		}
+		n.deliver(ctx, batch)
+	}
+}
+
+// deliver POSTs a batch, retrying with backoff, then drops it
+func (n *Notifier) deliver(ctx context.Context, batch []Summary) {
+	body, err := json.Marshal(payload{Tokens: batch})
+	if err != nil {
+		n.drop(len(batch), fmt.Errorf("failed to encode token summaries: %w", err))
+		return
+	}
+
+	backoff := n.retryBackoff
+	for attempt := 0; ; attempt++ {
+		if err = n.post(ctx, body); err == nil {
+			return
+		}
+		if attempt == n.retries {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	n.drop(len(batch), err)
+}
+
+// post sends one signed request
+func (n *Notifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create token webhook request: %w", err)
+	}
+	timestamp := strconv.FormatInt(n.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, "t="+timestamp+",v1="+Sign(append([]byte(timestamp+"."), body...), string(n.secret)))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("token webhook request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("token webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// drop counts summaries that were not delivered, logging the drops since the
+// last log if DropLogInterval has passed
+func (n *Notifier) drop(count int, cause error) {
+	dropped := n.dropped.Add(uint64(count))
+
+	n.dropLogMu.Lock()
+	now := n.now()
+	if !n.lastDropLog.IsZero() && now.Sub(n.lastDropLog) < DropLogInterval {
+		n.dropLogMu.Unlock()
+		return
+	}
+	since := dropped - n.droppedLogged
+	n.lastDropLog, n.droppedLogged = now, dropped
+	n.dropLogMu.Unlock()
+
+	n.logger.Error("Token summaries dropped",
+		slog.Uint64("summaries", since),
+		slog.Uint64("dropped_total", dropped),
+		slog.String("error", cause.Error()))
+}
+
+// Sign returns the hex HMAC-SHA256 of data keyed with secret
+func Sign(data []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,183 @@
+package tokenwebhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver is a webhook receiving summaries, failing the first failures
+// requests with a 503
+type receiver struct {
+	t        *testing.T
+	secret   string
+	failures int
+
+	mu       sync.Mutex
+	requests int
+	tokens   []Summary
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests++
+	if r.requests <= r.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	timestamp, signature, ok := strings.Cut(strings.TrimPrefix(req.Header.Get(SignatureHeader), "t="), ",v1=")
+	if !ok || signature != Sign([]byte(timestamp+"."+string(body)), r.secret) {
+		r.t.Errorf("unexpected signature %q", req.Header.Get(SignatureHeader))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var p payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		r.t.Errorf("failed to decode payload: %v", err)
+	}
+	r.tokens = append(r.tokens, p.Tokens...)
+}
+
+func (r *receiver) received() []Summary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tokens
+}
+
+func TestNotifier_DeliversSignedSummaries(t *testing.T) {
+	recv := &receiver{t: t, secret: "s3cret"}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	notifier, err := NewNotifier(Config{URL: srv.URL, Secret: "s3cret", BatchSize: 2})
+	if err != nil {
+		t.Fatalf("NewNotifier failed: %v", err)
+	}
+	for _, jti := range []string{"a", "b", "c"} {
+		notifier.Notify(Summary{Type: "access_token", JTI: jti})
+	}
+	if err := notifier.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	tokens := recv.received()
+	if len(tokens) != 3 || tokens[0].JTI != "a" || tokens[2].JTI != "c" {
+		t.Fatalf("expected the 3 summaries in order, got %+v", tokens)
+	}
+	if tokens[0].SchemaVersion != SchemaVersion {
+		t.Errorf("expected schema version %s, got %q", SchemaVersion, tokens[0].SchemaVersion)
+	}
+	if notifier.Dropped() != 0 {
+		t.Errorf("expected no drops, got %d", notifier.Dropped())
+	}
+}
+
+func TestNotifier_RetriesThenDrops(t *testing.T) {
+	t.Run("retried batch is delivered", func(t *testing.T) {
+		recv := &receiver{t: t, secret: "s3cret", failures: 2}
+		srv := httptest.NewServer(recv)
+		defer srv.Close()
+
+		notifier, err := NewNotifier(Config{URL: srv.URL, Secret: "s3cret", RetryBackoff: time.Millisecond})
+		if err != nil {
+			t.Fatalf("NewNotifier failed: %v", err)
+		}
+		notifier.Notify(Summary{JTI: "a"})
+		_ = notifier.Close(context.Background())
+
+		if len(recv.received()) != 1 || notifier.Dropped() != 0 {
+			t.Errorf("expected the summary after retries, got %+v (%d dropped)", recv.received(), notifier.Dropped())
+		}
+	})
+
+	t.Run("batch is dropped once retries are exhausted", func(t *testing.T) {
+		recv := &receiver{t: t, secret: "s3cret", failures: 10}
+		srv := httptest.NewServer(recv)
+		defer srv.Close()
+
+		notifier, err := NewNotifier(Config{URL: srv.URL, Secret: "s3cret", Retries: 1, RetryBackoff: time.Millisecond})
+		if err != nil {
+			t.Fatalf("NewNotifier failed: %v", err)
+		}
+		notifier.Notify(Summary{JTI: "a"})
+		_ = notifier.Close(context.Background())
+
+		if recv.requests != 2 || notifier.Dropped() != 1 {
+			t.Errorf("expected 2 attempts and 1 drop, got %d and %d", recv.requests, notifier.Dropped())
+		}
+	})
+
+	t.Run("summaries after close are dropped", func(t *testing.T) {
+		notifier, err := NewNotifier(Config{URL: "http://127.0.0.1:0", Secret: "s3cret"})
+		if err != nil {
+			t.Fatalf("NewNotifier failed: %v", err)
+		}
+		_ = notifier.Close(context.Background())
+		notifier.Notify(Summary{JTI: "a"})
+		if notifier.Dropped() != 1 {
+			t.Errorf("expected 1 drop, got %d", notifier.Dropped())
+		}
+	})
+}
+
+func TestNotifier_RateLimitsDropLogs(t *testing.T) {
+	var logs bytes.Buffer
+	notifier, err := NewNotifier(Config{
+		URL:    "http://127.0.0.1:0",
+		Secret: "s3cret",
+		Logger: slog.New(slog.NewJSONHandler(&logs, nil)),
+	})
+	if err != nil {
+		t.Fatalf("NewNotifier failed: %v", err)
+	}
+	_ = notifier.Close(context.Background())
+	now := time.Unix(1700000000, 0)
+	notifier.now = func() time.Time { return now }
+
+	for range 5 {
+		notifier.Notify(Summary{JTI: "a"})
+	}
+	now = now.Add(DropLogInterval)
+	notifier.Notify(Summary{JTI: "b"})
+
+	if notifier.Dropped() != 6 {
+		t.Errorf("expected 6 drops, got %d", notifier.Dropped())
+	}
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("failed to decode log line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 drop logs, got %d: %s", len(entries), logs.String())
+	}
+	if entries[0]["summaries"] != float64(1) || entries[1]["summaries"] != float64(5) {
+		t.Errorf("expected 1 then 5 summaries per log, got %v and %v", entries[0]["summaries"], entries[1]["summaries"])
+	}
+	if entries[1]["dropped_total"] != float64(6) {
+		t.Errorf("expected 6 dropped in total, got %v", entries[1]["dropped_total"])
+	}
+}
+
+func TestNewNotifier_Errors(t *testing.T) {
+	if _, err := NewNotifier(Config{Secret: "s3cret"}); err == nil {
+		t.Error("expected an error without a url")
+	}
+	if _, err := NewNotifier(Config{URL: "https://hooks.example.com"}); err == nil {
+		t.Error("expected an error without a secret")
+	}
+}
@@ -0,0 +1,103 @@
+package trust
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/project-kessel/parsec/internal/trustdomain"
+)
+
+// CelResultHook changes validation results with a CEL expression
+//
+// The expression has access to result, the validator's Result as a map
+// (subject, issuer, trust_domain, claims, audience, scope, etc.), and
+// evaluates to a map of the fields to change: subject, issuer, trust_domain,
+// audience and scope replace the result's, while claims are merged into the
+// result's claims (a null claim removes it). An empty map or null changes
+// nothing. The CEL strings extension (lowerAscii, replace, split, etc.) is
+// available.
+//
+// Example expressions:
+//
+//	// Canonicalize the subject
+//	{"subject": result.subject.lowerAscii()}
+//
+//	// Derive the trust domain from the issuer
+//	{"trust_domain": result.issuer.startsWith("https://sso.redhat.com") ? "redhat.com" : "external"}
+//
+//	// Augment the claims
+//	{"claims": {"idp": result.issuer, "groups": null}}
+type CelResultHook struct {
+	program cel.Program
+	script  string
+}
+
+// NewCelResultHook creates a CEL result hook from an expression
+func NewCelResultHook(script string) (*CelResultHook, error) {
+	if script == "" {
+		return nil, fmt.Errorf("CEL result hook script cannot be empty")
+	}
+
+	env, err := cel.NewEnv(
+		cel.Variable("result", cel.DynType),
+		// String functions such as lowerAscii, replace and split, for normalizing
+		ext.Strings(),
+		// trustDomainMatches(value, pattern)
+		trustdomain.CELLibrary(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(script)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("failed to compile CEL result hook: %w", issues.Err())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL program: %w", err)
+	}
+
+	return &CelResultHook{
+		program: program,
+		script:  script,
+	}, nil
+}
+
+// Apply implements the ResultHook interface
+func (h *CelResultHook) Apply(ctx context.Context, result *Result) (*Result, error) {
+	resultMap, err := ConvertResultToMap(result)
+	if err != nil {
+		return nil, err
+	}
+
+	out, _, err := h.program.ContextEval(ctx, map[string]any{"result": resultMap})
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate CEL result hook: %w", err)
+	}
+
+	// Converting through structpb yields JSON-like Go values
+	native, err := out.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return nil, fmt.Errorf("CEL result hook must evaluate to a map: %w", err)
+	}
+	switch changes := native.(*structpb.Value).AsInterface().(type) {
+	case nil:
+		return result, nil
+	case map[string]any:
+		return applyResultChanges(result, changes)
+	default:
+		return nil, fmt.Errorf("CEL result hook must evaluate to a map, got %T", changes)
+	}
+}
+
+// Script returns the CEL script used by this hook
+func (h *CelResultHook) Script() string {
+	return h.script
+}
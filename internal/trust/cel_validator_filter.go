@@ -8,6 +8,7 @@ import (
 	"github.com/google/cel-go/common/types"
 
 	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/trustdomain"
 )
 
 // ValidatorFilterLibrary creates a CEL library for filtering validators based on actor context.
@@ -33,6 +34,8 @@ type validatorFilterLib struct{}
 
 func (lib *validatorFilterLib) CompileOptions() []cel.EnvOption {
 	return []cel.EnvOption{
+		// trustDomainMatches(value, pattern)
+		trustdomain.CELLibrary(),
 		// Declare actor as a dynamic type (will be a map)
 		cel.Variable("actor", cel.DynType),
 		// Declare validator_name as a string
@@ -0,0 +1,236 @@
+package trust
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/trustdomain"
+)
+
+// ErrDelegationNotPermitted is returned when an actor is not allowed to act on behalf of a subject
+var ErrDelegationNotPermitted = perr.New(perr.ErrCodeDelegationDenied, "actor is not permitted to act for subject")
+
+// MayActClaim is the claim name used by RFC 8693 (section 4.4) to express
+// which parties are authorized to act on behalf of the subject
+const MayActClaim = "may_act"
+
+// DelegationPolicy decides whether an actor may act on behalf of a subject.
+// It is evaluated during token exchange after both identities have been validated.
+type DelegationPolicy interface {
+	// CheckDelegation returns nil if the actor may act for the subject.
+	// Returns an error wrapping ErrDelegationNotPermitted if the delegation is denied,
+	// or another error if the policy could not be evaluated.
+	CheckDelegation(ctx context.Context, subject *Result, actor *Result) error
+}
+
+// Delegation records an approved delegation of a subject to an actor.
+// It is passed to issuers so the delegation can be reflected in issued tokens.
+type Delegation struct {
+	// Actor is the party acting on behalf of the subject
+	Actor *Result
+}
+
+// ActClaim returns the RFC 8693 "act" claim describing the acting party
+func (d *Delegation) ActClaim() claims.Claims {
+	if d == nil || d.Actor == nil {
+		return nil
+	}
+
+	act := claims.Claims{
+		"sub": d.Actor.Subject,
+	}
+	if d.Actor.Issuer != "" {
+		act["iss"] = d.Actor.Issuer
+	}
+	return act
+}
+
+// AllowAllDelegationPolicy permits every delegation
+// This preserves the behavior of deployments that do not configure a delegation policy
+type AllowAllDelegationPolicy struct{}
+
+// CheckDelegation implements DelegationPolicy
+func (p *AllowAllDelegationPolicy) CheckDelegation(ctx context.Context, subject *Result, actor *Result) error {
+	return nil
+}
+
+// MayActDelegationPolicy enforces the subject's may_act claim (RFC 8693 section 4.4).
+// The claim is a JSON object identifying the authorized actor by "sub" and,
+// optionally, "iss". A list of such objects is also accepted.
+type MayActDelegationPolicy struct {
+	// RequireMayAct denies delegation when the subject carries no may_act claim.
+	// When false, subjects without the claim may be acted for by any actor.
+	RequireMayAct bool
+}
+
+// NewMayActDelegationPolicy creates a may_act delegation policy
+func NewMayActDelegationPolicy(requireMayAct bool) *MayActDelegationPolicy {
+	return &MayActDelegationPolicy{
+		RequireMayAct: requireMayAct,
+	}
+}
+
+// CheckDelegation implements DelegationPolicy
+func (p *MayActDelegationPolicy) CheckDelegation(ctx context.Context, subject *Result, actor *Result) error {
+	if subject == nil || actor == nil {
+		return fmt.Errorf("%w: subject and actor are required", ErrDelegationNotPermitted)
+	}
+
+	raw, ok := subject.Claims[MayActClaim]
+	if !ok {
+		if p.RequireMayAct {
+			return fmt.Errorf("%w: subject %q has no %s claim", ErrDelegationNotPermitted, subject.Subject, MayActClaim)
+		}
+		return nil
+	}
+
+	var entries []any
+	switch v := raw.(type) {
+	case []any:
+		entries = v
+	default:
+		entries = []any{v}
+	}
+
+	for _, entry := range entries {
+		permitted, err := mayActMatches(entry, actor)
+		if err != nil {
+			return err
+		}
+		if permitted {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: actor %q is not listed in %s of subject %q",
+		ErrDelegationNotPermitted, actor.Subject, MayActClaim, subject.Subject)
+}
+
+// mayActMatches reports whether a single may_act entry identifies the actor
+func mayActMatches(entry any, actor *Result) (bool, error) {
+	var m map[string]any
+	switch v := entry.(type) {
+	case map[string]any:
+		m = v
+	case claims.Claims:
+		m = v
+	default:
+		return false, fmt.Errorf("invalid %s claim: expected object, got %T", MayActClaim, entry)
+	}
+
+	sub, _ := m["sub"].(string)
+	if sub == "" || sub != actor.Subject {
+		return false, nil
+	}
+
+	if iss, ok := m["iss"].(string); ok && iss != "" && iss != actor.Issuer {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// DelegationPolicyLibrary creates a CEL library for delegation policies.
+//
+// This provides compile-time declarations for:
+//   - subject - the subject's Result object as a map (subject, issuer, trust_domain, claims, etc.)
+//   - actor - the actor's Result object as a map
+//
+// Example expressions:
+//   - actor.trust_domain == "gateways.example.com"
+//   - has(subject.claims.may_act) && subject.claims.may_act.sub == actor.subject
+//   - actor.claims.role == "impersonator" && subject.trust_domain != "admins"
+func DelegationPolicyLibrary() cel.EnvOption {
+	return cel.Lib(&delegationPolicyLib{})
+}
+
+type delegationPolicyLib struct{}
+
+func (lib *delegationPolicyLib) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		// trustDomainMatches(value, pattern)
+		trustdomain.CELLibrary(),
+		cel.Variable("subject", cel.DynType),
+		cel.Variable("actor", cel.DynType),
+	}
+}
+
+func (lib *delegationPolicyLib) ProgramOptions() []cel.ProgramOption {
+	return []cel.ProgramOption{}
+}
+
+// CelDelegationPolicy uses a CEL expression to decide whether an actor may act for a subject
+type CelDelegationPolicy struct {
+	program cel.Program
+	script  string
+}
+
+// NewCelDelegationPolicy creates a new CEL-based delegation policy
+// The script should be a CEL expression that evaluates to a boolean
+func NewCelDelegationPolicy(script string) (*CelDelegationPolicy, error) {
+	if script == "" {
+		return nil, fmt.Errorf("CEL delegation script cannot be empty")
+	}
+
+	env, err := cel.NewEnv(DelegationPolicyLibrary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(script)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("failed to compile CEL delegation script: %w", issues.Err())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL program: %w", err)
+	}
+
+	return &CelDelegationPolicy{
+		program: program,
+		script:  script,
+	}, nil
+}
+
+// CheckDelegation implements DelegationPolicy
+func (p *CelDelegationPolicy) CheckDelegation(ctx context.Context, subject *Result, actor *Result) error {
+	if subject == nil || actor == nil {
+		return fmt.Errorf("%w: subject and actor are required", ErrDelegationNotPermitted)
+	}
+
+	subjectMap, err := ConvertResultToMap(subject)
+	if err != nil {
+		return fmt.Errorf("failed to convert subject: %w", err)
+	}
+
+	actorMap, err := ConvertResultToMap(actor)
+	if err != nil {
+		return fmt.Errorf("failed to convert actor: %w", err)
+	}
+
+	result, _, err := p.program.ContextEval(ctx, map[string]any{
+		"subject": subjectMap,
+		"actor":   actorMap,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to evaluate delegation policy: %w", err)
+	}
+
+	if result.Type() == types.BoolType && result.Value().(bool) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: delegation policy denied actor %q for subject %q",
+		ErrDelegationNotPermitted, actor.Subject, subject.Subject)
+}
+
+// Script returns the CEL script used by this policy
+func (p *CelDelegationPolicy) Script() string {
+	return p.script
+}
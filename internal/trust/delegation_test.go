@@ -0,0 +1,154 @@
+package trust
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/claims"
+)
+
+func TestMayActDelegationPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	actor := &Result{
+		Subject: "gateway",
+		Issuer:  "https://gateways.example.com",
+	}
+
+	tests := []struct {
+		name          string
+		requireMayAct bool
+		subjectClaims claims.Claims
+		wantDenied    bool
+		wantErr       bool
+	}{
+		{
+			name:          "no may_act claim is allowed by default",
+			subjectClaims: claims.Claims{},
+		},
+		{
+			name:          "no may_act claim is denied when required",
+			requireMayAct: true,
+			subjectClaims: claims.Claims{},
+			wantDenied:    true,
+		},
+		{
+			name: "matching sub is allowed",
+			subjectClaims: claims.Claims{
+				"may_act": map[string]any{"sub": "gateway"},
+			},
+		},
+		{
+			name: "matching sub and iss is allowed",
+			subjectClaims: claims.Claims{
+				"may_act": map[string]any{"sub": "gateway", "iss": "https://gateways.example.com"},
+			},
+		},
+		{
+			name: "mismatched iss is denied",
+			subjectClaims: claims.Claims{
+				"may_act": map[string]any{"sub": "gateway", "iss": "https://other.example.com"},
+			},
+			wantDenied: true,
+		},
+		{
+			name: "mismatched sub is denied",
+			subjectClaims: claims.Claims{
+				"may_act": map[string]any{"sub": "someone-else"},
+			},
+			wantDenied: true,
+		},
+		{
+			name: "list of entries allows any match",
+			subjectClaims: claims.Claims{
+				"may_act": []any{
+					map[string]any{"sub": "someone-else"},
+					map[string]any{"sub": "gateway"},
+				},
+			},
+		},
+		{
+			name: "malformed claim is an error",
+			subjectClaims: claims.Claims{
+				"may_act": "gateway",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := NewMayActDelegationPolicy(tt.requireMayAct)
+			subject := &Result{Subject: "user-1", Claims: tt.subjectClaims}
+
+			err := policy.CheckDelegation(ctx, subject, actor)
+
+			switch {
+			case tt.wantDenied:
+				if !errors.Is(err, ErrDelegationNotPermitted) {
+					t.Errorf("expected ErrDelegationNotPermitted, got %v", err)
+				}
+			case tt.wantErr:
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+			default:
+				if err != nil {
+					t.Errorf("expected delegation to be allowed, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCelDelegationPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	policy, err := NewCelDelegationPolicy(`actor.trust_domain == "gateways" && subject.trust_domain == "users"`)
+	if err != nil {
+		t.Fatalf("failed to create policy: %v", err)
+	}
+
+	t.Run("allows matching delegation", func(t *testing.T) {
+		err := policy.CheckDelegation(ctx,
+			&Result{Subject: "alice", TrustDomain: "users"},
+			&Result{Subject: "gw", TrustDomain: "gateways"},
+		)
+		if err != nil {
+			t.Errorf("expected delegation to be allowed, got %v", err)
+		}
+	})
+
+	t.Run("denies non-matching delegation", func(t *testing.T) {
+		err := policy.CheckDelegation(ctx,
+			&Result{Subject: "root", TrustDomain: "admins"},
+			&Result{Subject: "gw", TrustDomain: "gateways"},
+		)
+		if !errors.Is(err, ErrDelegationNotPermitted) {
+			t.Errorf("expected ErrDelegationNotPermitted, got %v", err)
+		}
+	})
+
+	t.Run("rejects empty script", func(t *testing.T) {
+		if _, err := NewCelDelegationPolicy(""); err == nil {
+			t.Error("expected error for empty script")
+		}
+	})
+}
+
+func TestDelegation_ActClaim(t *testing.T) {
+	var nilDelegation *Delegation
+	if nilDelegation.ActClaim() != nil {
+		t.Error("expected nil act claim for nil delegation")
+	}
+
+	d := &Delegation{Actor: &Result{Subject: "gateway", Issuer: "https://gateways.example.com"}}
+	act := d.ActClaim()
+	if act.GetString("sub") != "gateway" {
+		t.Errorf("expected sub gateway, got %v", act["sub"])
+	}
+	if act.GetString("iss") != "https://gateways.example.com" {
+		t.Errorf("expected iss, got %v", act["iss"])
+	}
+}
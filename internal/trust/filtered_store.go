@@ -3,6 +3,7 @@ package trust
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/project-kessel/parsec/internal/request"
 )
@@ -31,6 +32,11 @@ type FilteredStore struct {
 	validators []NamedValidator
 	// Filter for determining validator access
 	filter ValidatorFilter
+	// Cache of successful validations, shared with stores filtered for actors (optional)
+	resultCache *ResultCache
+	// Validators of the store this one was filtered from, by credential type
+	// (nil if unfiltered)
+	unfilteredByType map[CredentialType][]NamedValidator
 }
 
 // FilteredStoreOption is a functional option for configuring a FilteredStore
@@ -60,6 +66,31 @@ func WithCELFilter(script string) FilteredStoreOption {
 	}
 }
 
+// WithRegoFilter sets a Rego policy filter for the store
+// query selects the rule deciding whether a validator is allowed
+// (DefaultRegoFilterQuery if empty). Its input has the same fields as the
+// CEL filter's variables: input.actor, input.validator_name and input.request.
+func WithRegoFilter(policy, query string) FilteredStoreOption {
+	return func(s *FilteredStore) error {
+		filter, err := NewRegoValidatorFilter(policy, query)
+		if err != nil {
+			return err
+		}
+		s.filter = filter
+		return nil
+	}
+}
+
+// WithResultCache caches the store's successful validations in cache
+// Stores returned by ForActor share the cache, but only use results of the
+// validators the actor is allowed to use.
+func WithResultCache(cache *ResultCache) FilteredStoreOption {
+	return func(s *FilteredStore) error {
+		s.resultCache = cache
+		return nil
+	}
+}
+
 // NewFilteredStore creates a new filtered store
 func NewFilteredStore(opts ...FilteredStoreOption) (*FilteredStore, error) {
 	s := &FilteredStore{
@@ -99,7 +130,20 @@ func (s *FilteredStore) Validate(ctx context.Context, credential Credential) (*R
 	// Look up validators for this credential type
 	validators, ok := s.validatorsByType[credType]
 	if !ok || len(validators) == 0 {
-		return nil, fmt.Errorf("no validator found for credential type %s", credType)
+		// Credentials the store validates, but not for this actor, are
+		// invalid rather than unsupported
+		if s.unfilteredByType != nil && len(s.unfilteredByType[credType]) > 0 {
+			return nil, fmt.Errorf("%w: no validator allowed for credential type %s", ErrInvalidToken, credType)
+		}
+		return nil, fmt.Errorf("%w %s", ErrNoValidator, credType)
+	}
+
+	if s.resultCache != nil {
+		if result, ok := s.resultCache.get(credential, func(v Validator) bool {
+			return slices.ContainsFunc(validators, func(nv NamedValidator) bool { return nv.Validator == v })
+		}); ok {
+			return result, nil
+		}
 	}
 
 	// Try validators in order until one succeeds
@@ -107,6 +151,9 @@ func (s *FilteredStore) Validate(ctx context.Context, credential Credential) (*R
 	for _, nv := range validators {
 		result, err := nv.Validator.Validate(ctx, credential)
 		if err == nil {
+			if s.resultCache != nil {
+				s.resultCache.put(credential, nv.Validator, result)
+			}
 			return result, nil
 		}
 
@@ -136,6 +183,11 @@ func (s *FilteredStore) ForActor(ctx context.Context, actor *Result, requestAttr
 		validatorsByType: make(map[CredentialType][]NamedValidator),
 		validators:       make([]NamedValidator, 0),
 		filter:           s.filter,
+		resultCache:      s.resultCache,
+		unfilteredByType: s.unfilteredByType,
+	}
+	if filtered.unfilteredByType == nil {
+		filtered.unfilteredByType = s.validatorsByType
 	}
 
 	// Evaluate the filter for each validator
@@ -3,6 +3,7 @@ package trust
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
@@ -369,3 +370,31 @@ func TestJSONCredentialType(t *testing.T) {
 		t.Errorf("expected CredentialTypeJSON, got %s", cred.Type())
 	}
 }
+
+func TestFilteredStore_Validate_NoValidator(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewFilteredStore(WithCELFilter(`actor.trust_domain == "admin.example.com"`))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	store.AddValidator("bearer", NewStubValidator(CredentialTypeBearer))
+
+	// Credential types without validators are unsupported
+	if _, err := store.Validate(ctx, &JSONCredential{RawJSON: []byte("{}")}); !errors.Is(err, ErrNoValidator) {
+		t.Errorf("expected ErrNoValidator, got %v", err)
+	}
+
+	// Types with validators the actor may not use are merely invalid
+	filtered, err := store.ForActor(ctx, &Result{TrustDomain: "other.example.com"}, nil)
+	if err != nil {
+		t.Fatalf("ForActor failed: %v", err)
+	}
+	_, err = filtered.Validate(ctx, &BearerCredential{Token: "token"})
+	if !errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrNoValidator) {
+		t.Errorf("expected ErrInvalidToken, got %v", err)
+	}
+	if _, err := filtered.Validate(ctx, &JSONCredential{RawJSON: []byte("{}")}); !errors.Is(err, ErrNoValidator) {
+		t.Errorf("expected ErrNoValidator, got %v", err)
+	}
+}
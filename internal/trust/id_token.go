@@ -0,0 +1,67 @@
+package trust
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v3/jwt"
+
+	"github.com/project-kessel/parsec/internal/claims"
+)
+
+// checkIDToken checks the OIDC ID token rules a JWT validator does not
+// otherwise apply (OpenID Connect Core 1.0, section 3.1.3.7): the token must
+// be issued to one of clientIDs, by its azp if it has several audiences, and
+// carry nonce if one is given
+func checkIDToken(token jwt.Token, clientIDs []string, nonce string) error {
+	audiences, _ := token.Audience()
+	if !slices.ContainsFunc(audiences, func(aud string) bool { return slices.Contains(clientIDs, aud) }) {
+		return fmt.Errorf("%w: ID token audience %v is not an accepted client ID", ErrInvalidToken, audiences)
+	}
+
+	var azp string
+	if err := token.Get("azp", &azp); err == nil && azp != "" {
+		if !slices.Contains(clientIDs, azp) {
+			return fmt.Errorf("%w: ID token authorized party %q is not an accepted client ID", ErrInvalidToken, azp)
+		}
+	} else if len(audiences) > 1 {
+		return fmt.Errorf("%w: ID token with several audiences has no authorized party", ErrInvalidToken)
+	}
+
+	if nonce != "" {
+		var got string
+		if err := token.Get("nonce", &got); err != nil || subtle.ConstantTimeCompare([]byte(got), []byte(nonce)) != 1 {
+			return fmt.Errorf("%w: ID token nonce does not match", ErrInvalidToken)
+		}
+	}
+	return nil
+}
+
+// normalizeOIDCClaims gives the standard OIDC profile claims of an ID token
+// the same types whatever the provider: email and preferred_username are
+// strings (dropped otherwise), and email_verified is a bool, false unless
+// the provider says otherwise ("true" strings included)
+func normalizeOIDCClaims(c claims.Claims) {
+	for _, name := range []string{"email", "preferred_username"} {
+		if value, ok := c[name]; ok {
+			if s, ok := value.(string); !ok || s == "" {
+				delete(c, name)
+			}
+		}
+	}
+
+	verified := false
+	switch v := c["email_verified"].(type) {
+	case bool:
+		verified = v
+	case string:
+		verified = strings.EqualFold(v, "true")
+	}
+	if _, hasEmail := c["email"]; hasEmail {
+		c["email_verified"] = verified
+	} else {
+		delete(c, "email_verified")
+	}
+}
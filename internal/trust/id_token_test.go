@@ -0,0 +1,118 @@
+package trust
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/httpfixture"
+)
+
+func TestJWTValidator_IDTokens(t *testing.T) {
+	ctx := context.Background()
+	fixture := setupTestJWKSFixture(t)
+
+	validator, err := NewJWTValidator(JWTValidatorConfig{
+		Issuer:      fixture.Issuer(),
+		JWKSURL:     fixture.JWKSURL(),
+		TrustDomain: "test-domain",
+		HTTPClient: &http.Client{Transport: httpfixture.NewTransport(httpfixture.TransportConfig{
+			Provider: fixture,
+			Strict:   true,
+		})},
+		Clock:     fixture.Clock(),
+		ClientIDs: []string{"console", "cli"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+	if !slices.Contains(validator.CredentialTypes(), CredentialTypeOIDC) {
+		t.Errorf("expected a validator with client IDs to accept OIDC credentials, got %v", validator.CredentialTypes())
+	}
+
+	idToken := func(claims map[string]interface{}) string {
+		t.Helper()
+		claims["sub"] = "alice"
+		token, err := fixture.CreateAndSignToken(claims)
+		if err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+		return token
+	}
+
+	t.Run("accepts ID tokens of its clients", func(t *testing.T) {
+		result, err := validator.Validate(ctx, &OIDCCredential{Token: idToken(map[string]interface{}{
+			"aud":                "console",
+			"nonce":              "n-0S6_WzA2Mj",
+			"email":              "alice@example.com",
+			"email_verified":     "true",
+			"preferred_username": "alice",
+		}), Nonce: "n-0S6_WzA2Mj"})
+		if err != nil {
+			t.Fatalf("validation failed: %v", err)
+		}
+		for name, want := range map[string]any{"email": "alice@example.com", "email_verified": true, "preferred_username": "alice"} {
+			if got := result.Claims[name]; !reflect.DeepEqual(got, want) {
+				t.Errorf("expected %s %v, got %#v", name, want, got)
+			}
+		}
+	})
+
+	t.Run("normalizes profile claims", func(t *testing.T) {
+		result, err := validator.Validate(ctx, &OIDCCredential{Token: idToken(map[string]interface{}{
+			"aud":                "cli",
+			"email":              "alice@example.com",
+			"preferred_username": 42,
+		})})
+		if err != nil {
+			t.Fatalf("validation failed: %v", err)
+		}
+		if result.Claims["email_verified"] != false {
+			t.Errorf("expected an unverified email, got %#v", result.Claims["email_verified"])
+		}
+		if _, ok := result.Claims["preferred_username"]; ok {
+			t.Errorf("expected a non-string preferred_username to be dropped, got %#v", result.Claims["preferred_username"])
+		}
+	})
+
+	for name, tc := range map[string]struct {
+		claims map[string]interface{}
+		nonce  string
+	}{
+		"other client":              {claims: map[string]interface{}{"aud": "other"}},
+		"no audience":               {claims: map[string]interface{}{}},
+		"several audiences, no azp": {claims: map[string]interface{}{"aud": []string{"console", "api"}}},
+		"azp of another client":     {claims: map[string]interface{}{"aud": []string{"console", "api"}, "azp": "api"}},
+		"missing nonce":             {claims: map[string]interface{}{"aud": "console"}, nonce: "n-0S6_WzA2Mj"},
+		"nonce of another request":  {claims: map[string]interface{}{"aud": "console", "nonce": "replayed"}, nonce: "n-0S6_WzA2Mj"},
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			_, err := validator.Validate(ctx, &OIDCCredential{Token: idToken(tc.claims), Nonce: tc.nonce})
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	t.Run("accepts several audiences authorized by a client", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &OIDCCredential{Token: idToken(map[string]interface{}{
+			"aud": []string{"console", "api"},
+			"azp": "console",
+		})})
+		if err != nil {
+			t.Errorf("validation failed: %v", err)
+		}
+	})
+
+	t.Run("ID tokens need client IDs", func(t *testing.T) {
+		plain := createValidatorWithFixture(t, fixture)
+		if slices.Contains(plain.CredentialTypes(), CredentialTypeOIDC) {
+			t.Error("expected a validator without client IDs not to accept OIDC credentials")
+		}
+		if _, err := plain.Validate(ctx, &OIDCCredential{Token: idToken(map[string]interface{}{"aud": "console"})}); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
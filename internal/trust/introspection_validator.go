@@ -0,0 +1,175 @@
+package trust
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/claims"
+)
+
+// IntrospectionValidator validates opaque bearer tokens using OAuth 2.0
+// Token Introspection (RFC 7662)
+type IntrospectionValidator struct {
+	endpoint     string
+	clientID     string
+	clientSecret string
+	issuer       string
+	trustDomain  string
+	httpClient   *http.Client
+}
+
+// IntrospectionValidatorConfig contains configuration for token introspection
+type IntrospectionValidatorConfig struct {
+	// Endpoint is the introspection endpoint URL
+	Endpoint string
+
+	// ClientID and ClientSecret authenticate parsec to the introspection endpoint
+	// using HTTP Basic authentication. If ClientID is empty, no credentials are sent.
+	ClientID     string
+	ClientSecret string
+
+	// Issuer is the issuer reported for tokens that have no iss in the introspection response
+	// If set, responses with a different iss are rejected
+	Issuer string
+
+	// TrustDomain is the trust domain this issuer belongs to
+	TrustDomain string
+
+	// HTTPClient is an optional HTTP client for introspection requests
+	// If nil, a client with a 10 second timeout is used
+	HTTPClient *http.Client
+}
+
+// NewIntrospectionValidator creates a new token introspection validator
+func NewIntrospectionValidator(cfg IntrospectionValidatorConfig) (*IntrospectionValidator, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("endpoint is required")
+	}
+	if _, err := url.ParseRequestURI(cfg.Endpoint); err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &IntrospectionValidator{
+		endpoint:     cfg.Endpoint,
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		issuer:       cfg.Issuer,
+		trustDomain:  cfg.TrustDomain,
+		httpClient:   httpClient,
+	}, nil
+}
+
+// CredentialTypes returns the credential types this validator can handle
+func (v *IntrospectionValidator) CredentialTypes() []CredentialType {
+	return []CredentialType{CredentialTypeBearer}
+}
+
+// Validate introspects a bearer token and returns the subject it was issued to
+func (v *IntrospectionValidator) Validate(ctx context.Context, credential Credential) (*Result, error) {
+	var token string
+	switch cred := credential.(type) {
+	case *BearerCredential:
+		token = cred.Token
+	case *JWTCredential:
+		token = cred.Token
+	default:
+		return nil, fmt.Errorf("unsupported credential type for introspection validator: %T", credential)
+	}
+	if token == "" {
+		return nil, fmt.Errorf("%w: empty token", ErrInvalidToken)
+	}
+
+	form := url.Values{}
+	form.Set("token", token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create introspection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if v.clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(v.clientID), url.QueryEscape(v.clientSecret))
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("introspection request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection request failed: status %d", resp.StatusCode)
+	}
+
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode introspection response: %w", err)
+	}
+
+	return v.result(body)
+}
+
+// result converts an introspection response into a validation result
+func (v *IntrospectionValidator) result(body map[string]any) (*Result, error) {
+	if active, _ := body["active"].(bool); !active {
+		return nil, fmt.Errorf("%w: token is not active", ErrInvalidToken)
+	}
+
+	subject, _ := body["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing subject claim", ErrInvalidToken)
+	}
+
+	issuer, _ := body["iss"].(string)
+	switch {
+	case issuer == "":
+		issuer = v.issuer
+	case v.issuer != "" && issuer != v.issuer:
+		return nil, fmt.Errorf("%w: unexpected issuer %s", ErrInvalidToken, issuer)
+	}
+
+	result := &Result{
+		Subject:     subject,
+		Issuer:      issuer,
+		TrustDomain: v.trustDomain,
+		Claims:      make(claims.Claims, len(body)),
+		ExpiresAt:   numericDate(body["exp"]),
+		IssuedAt:    numericDate(body["iat"]),
+	}
+	maps.Copy(result.Claims, body)
+	delete(result.Claims, "active")
+
+	switch aud := body["aud"].(type) {
+	case string:
+		result.Audience = []string{aud}
+	case []any:
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				result.Audience = append(result.Audience, s)
+			}
+		}
+	}
+	result.Scope, _ = body["scope"].(string)
+
+	return result, nil
+}
+
+// numericDate converts a JSON NumericDate (seconds since the epoch) to a time
+func numericDate(v any) time.Time {
+	seconds, ok := v.(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(seconds), 0)
+}
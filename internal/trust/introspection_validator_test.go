@@ -0,0 +1,109 @@
+package trust
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIntrospectionValidator(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "parsec" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var body map[string]any
+		switch r.PostForm.Get("token") {
+		case "active-token":
+			body = map[string]any{
+				"active": true,
+				"sub":    "alice",
+				"iss":    "https://idp.example.com",
+				"aud":    "parsec",
+				"scope":  "read write",
+				"exp":    2000000000,
+				"email":  "alice@example.com",
+			}
+		case "foreign-token":
+			body = map[string]any{"active": true, "sub": "bob", "iss": "https://other.example.com"}
+		default:
+			body = map[string]any{"active": false}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	defer server.Close()
+
+	validator, err := NewIntrospectionValidator(IntrospectionValidatorConfig{
+		Endpoint:     server.URL,
+		ClientID:     "parsec",
+		ClientSecret: "s3cret",
+		Issuer:       "https://idp.example.com",
+		TrustDomain:  "example.com",
+	})
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	t.Run("accepts active token", func(t *testing.T) {
+		result, err := validator.Validate(ctx, &BearerCredential{Token: "active-token"})
+		if err != nil {
+			t.Fatalf("validation failed: %v", err)
+		}
+		if result.Subject != "alice" {
+			t.Errorf("expected subject alice, got %q", result.Subject)
+		}
+		if result.TrustDomain != "example.com" {
+			t.Errorf("expected trust domain example.com, got %q", result.TrustDomain)
+		}
+		if len(result.Audience) != 1 || result.Audience[0] != "parsec" {
+			t.Errorf("expected audience [parsec], got %v", result.Audience)
+		}
+		if result.Scope != "read write" {
+			t.Errorf("expected scope 'read write', got %q", result.Scope)
+		}
+		if result.ExpiresAt.Unix() != 2000000000 {
+			t.Errorf("expected exp 2000000000, got %v", result.ExpiresAt.Unix())
+		}
+		if result.Claims["email"] != "alice@example.com" {
+			t.Errorf("expected email claim, got %v", result.Claims["email"])
+		}
+		if _, ok := result.Claims["active"]; ok {
+			t.Error("expected active to be omitted from claims")
+		}
+	})
+
+	t.Run("rejects inactive token", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &BearerCredential{Token: "revoked"})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects token from another issuer", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &BearerCredential{Token: "foreign-token"})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("fails when client authentication is rejected", func(t *testing.T) {
+		unauthenticated, err := NewIntrospectionValidator(IntrospectionValidatorConfig{Endpoint: server.URL})
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+		if _, err := unauthenticated.Validate(ctx, &BearerCredential{Token: "active-token"}); err == nil {
+			t.Error("expected error for unauthorized introspection request")
+		}
+	})
+}
@@ -64,27 +64,27 @@ func (v *JSONValidator) Validate(ctx context.Context, credential Credential) (*R
 	}
 
 	if len(jsonCred.RawJSON) == 0 {
-		return nil, fmt.Errorf("empty JSON credential")
+		return nil, fmt.Errorf("%w: empty JSON credential", ErrInvalidToken)
 	}
 
 	// Parse the JSON into a Result structure
 	var result Result
 	if err := json.Unmarshal(jsonCred.RawJSON, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON credential: %w", err)
+		return nil, fmt.Errorf("%w: failed to parse JSON credential: %v", ErrInvalidToken, err)
 	}
 
 	// Validate required fields
 	if result.Subject == "" {
-		return nil, fmt.Errorf("subject is required")
+		return nil, fmt.Errorf("%w: subject is required", ErrInvalidToken)
 	}
 
 	if v.requireIssuer && result.Issuer == "" {
-		return nil, fmt.Errorf("issuer is required")
+		return nil, fmt.Errorf("%w: issuer is required", ErrInvalidToken)
 	}
 
 	// Validate trust domain if configured
 	if v.trustDomain != "" && result.TrustDomain != v.trustDomain {
-		return nil, fmt.Errorf("trust domain mismatch: expected %s, got %s", v.trustDomain, result.TrustDomain)
+		return nil, fmt.Errorf("%w: trust domain mismatch: expected %s, got %s", ErrInvalidToken, v.trustDomain, result.TrustDomain)
 	}
 
 	// Filter claims
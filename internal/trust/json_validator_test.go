@@ -3,6 +3,7 @@ package trust
 import (
 	"context"
 	"encoding/json"
+	"reflect"
 	"testing"
 	"time"
 
@@ -241,10 +242,9 @@ func TestClaimsFilters(t *testing.T) {
 			t.Errorf("expected %d claims, got %d", len(testClaims), len(result))
 		}
 
-		// Ensure it's a copy, not the same map
-		result["new"] = "value"
-		if testClaims.Has("new") {
-			t.Errorf("filter should return a copy, not the same map")
+		// Passing every claim through needs no copy
+		if reflect.ValueOf(result).UnsafePointer() != reflect.ValueOf(testClaims).UnsafePointer() {
+			t.Errorf("filter should return the claims without copying them")
 		}
 	})
 
@@ -0,0 +1,80 @@
+package trust
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// JWKSStore persists the last-known-good JWKS of validators, so they can
+// start validating tokens while their JWKS endpoint is unreachable
+type JWKSStore interface {
+	// Load returns the JWKS last saved for jwksURL, or nil if there is none
+	Load(ctx context.Context, jwksURL string) ([]byte, error)
+
+	// Save replaces the JWKS stored for jwksURL
+	Save(ctx context.Context, jwksURL string, jwks []byte) error
+}
+
+// FileJWKSStore stores each JWKS in its own file under a directory, which
+// may be a volume shared between replicas
+type FileJWKSStore struct {
+	dir string
+}
+
+// NewFileJWKSStore creates a store writing to dir, creating it if needed
+func NewFileJWKSStore(dir string) (*FileJWKSStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create JWKS cache directory: %w", err)
+	}
+	return &FileJWKSStore{dir: dir}, nil
+}
+
+// path names the file of a JWKS URL by its hash, so any URL makes a valid file name
+func (s *FileJWKSStore) path(jwksURL string) string {
+	sum := sha256.Sum256([]byte(jwksURL))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Load implements JWKSStore
+func (s *FileJWKSStore) Load(_ context.Context, jwksURL string) ([]byte, error) {
+	data, err := os.ReadFile(s.path(jwksURL))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored JWKS: %w", err)
+	}
+	return data, nil
+}
+
+// Save implements JWKSStore
+// The JWKS is written to a temporary file and renamed into place, so readers
+// never see a partial file.
+func (s *FileJWKSStore) Save(_ context.Context, jwksURL string, jwks []byte) error {
+	tmp, err := os.CreateTemp(s.dir, ".jwks-*")
+	if err != nil {
+		return fmt.Errorf("failed to store JWKS: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(jwks); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to store JWKS: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to store JWKS: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(jwksURL)); err != nil {
+		return fmt.Errorf("failed to store JWKS: %w", err)
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"maps"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/lestrrat-go/httprc/v3"
@@ -24,6 +25,16 @@ type JWTValidator struct {
 	cache       *jwk.Cache
 	trustDomain string
 	clock       clock.Clock
+	leeway      time.Duration
+	clientIDs   []string
+
+	// store persists the last fetched JWKS (optional)
+	store JWKSStore
+
+	mu sync.Mutex
+	// persisted is the JWKS last loaded from or saved to the store, used
+	// while the cache has not fetched one
+	persisted jwk.Set
 }
 
 // JWTValidatorConfig contains configuration for JWT validation
@@ -50,6 +61,23 @@ type JWTValidatorConfig struct {
 	// If nil, uses system clock
 	// This is useful for testing time-dependent behavior
 	Clock clock.Clock
+
+	// Leeway is the clock skew tolerated when checking exp, nbf and iat
+	Leeway time.Duration
+
+	// ClientIDs are the OIDC clients whose ID tokens are accepted as OIDC
+	// credentials (ID tokens are not accepted if empty)
+	ClientIDs []string
+
+	// JWKSStore persists the last fetched JWKS (optional)
+	// If the JWKS cannot be fetched at startup, the stored one is used until
+	// a refresh succeeds.
+	JWKSStore JWKSStore
+
+	// DeferInitialFetch leaves the initial JWKS fetch to Warm, e.g. so the
+	// JWKS of many validators can be fetched in parallel
+	// The JWKS is still fetched in the background once the validator is created.
+	DeferInitialFetch bool
 }
 
 // NewJWTValidator creates a new JWT validator with JWKS support
@@ -76,7 +104,8 @@ func NewJWTValidator(cfg JWTValidatorConfig) (*JWTValidator, error) {
 	}
 
 	// Register the JWKS URL with the cache
-	registerOpts := []jwk.RegisterOption{jwk.WithMinInterval(refreshInterval)}
+	// Registration doesn't wait for the first fetch; the refresh below does.
+	registerOpts := []jwk.RegisterOption{jwk.WithMinInterval(refreshInterval), jwk.WithWaitReady(false)}
 	if cfg.HTTPClient != nil {
 		registerOpts = append(registerOpts, jwk.WithHTTPClient(cfg.HTTPClient))
 	}
@@ -84,32 +113,115 @@ func NewJWTValidator(cfg JWTValidatorConfig) (*JWTValidator, error) {
 		return nil, fmt.Errorf("failed to register JWKS URL: %w", err)
 	}
 
-	// Pre-fetch the JWKS
-	// TODO: could make this lazy as opposed to eager fetch on creation
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	if _, err := cache.Refresh(ctx, jwksURL); err != nil {
-		return nil, fmt.Errorf("failed to fetch initial JWKS: %w", err)
-	}
-
 	// Use provided clock or default to system clock
 	clk := cfg.Clock
 	if clk == nil {
 		clk = clock.NewSystemClock()
 	}
 
-	return &JWTValidator{
+	v := &JWTValidator{
 		issuer:      cfg.Issuer,
 		jwksURL:     jwksURL,
 		cache:       cache,
 		trustDomain: cfg.TrustDomain,
 		clock:       clk,
-	}, nil
+		leeway:      cfg.Leeway,
+		clientIDs:   cfg.ClientIDs,
+		store:       cfg.JWKSStore,
+	}
+
+	if cfg.DeferInitialFetch {
+		return v, nil
+	}
+
+	// Pre-fetch the JWKS
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := v.Warm(ctx); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// Warm fetches the JWKS, so validations don't wait for it
+// If the JWKS can't be fetched, the one persisted in the JWKS store is used
+// until a background refresh succeeds; without one, Warm fails.
+func (v *JWTValidator) Warm(ctx context.Context) error {
+	jwks, err := v.cache.Refresh(ctx, v.jwksURL)
+	if err != nil {
+		if !v.loadPersisted(ctx) {
+			return fmt.Errorf("failed to fetch initial JWKS: %w", err)
+		}
+		return nil
+	}
+	v.persist(ctx, jwks)
+	return nil
+}
+
+// loadPersisted loads the JWKS from the store, reporting whether there was one
+func (v *JWTValidator) loadPersisted(ctx context.Context) bool {
+	if v.store == nil {
+		return false
+	}
+	data, err := v.store.Load(ctx, v.jwksURL)
+	if err != nil || data == nil {
+		return false
+	}
+	jwks, err := jwk.Parse(data)
+	if err != nil {
+		return false
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.persisted = jwks
+	return true
+}
+
+// persist saves jwks to the store, unless it was already saved
+// Refreshes replace the cached set, so a new set is saved once per refresh.
+func (v *JWTValidator) persist(ctx context.Context, jwks jwk.Set) {
+	if v.store == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.persisted == jwks {
+		return
+	}
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		return
+	}
+	// Failing to persist only loses the fallback for the next restart, so
+	// the set is recorded either way to avoid retrying on every token.
+	_ = v.store.Save(context.WithoutCancel(ctx), v.jwksURL, data)
+	v.persisted = jwks
+}
+
+// keySet returns the cached JWKS, or the persisted one until the cache has fetched one
+func (v *JWTValidator) keySet(ctx context.Context) (jwk.Set, error) {
+	jwks, err := v.cache.Lookup(ctx, v.jwksURL)
+	if err != nil {
+		v.mu.Lock()
+		persisted := v.persisted
+		v.mu.Unlock()
+		if persisted != nil {
+			return persisted, nil
+		}
+		return nil, err
+	}
+	v.persist(ctx, jwks)
+	return jwks, nil
 }
 
 // CredentialTypes returns the credential types this validator can handle
-// JWT validator can handle both JWT and Bearer credentials (since Bearer tokens might be JWTs)
+// JWT validator can handle both JWT and Bearer credentials (since Bearer tokens might be JWTs),
+// and OIDC credentials (ID tokens) if it has client IDs
 func (v *JWTValidator) CredentialTypes() []CredentialType {
+	if len(v.clientIDs) > 0 {
+		return []CredentialType{CredentialTypeJWT, CredentialTypeBearer, CredentialTypeOIDC}
+	}
 	return []CredentialType{CredentialTypeJWT, CredentialTypeBearer}
 }
 
@@ -117,18 +229,25 @@ func (v *JWTValidator) CredentialTypes() []CredentialType {
 func (v *JWTValidator) Validate(ctx context.Context, credential Credential) (*Result, error) {
 	// Type assertion to JWTCredential or BearerCredential
 	var tokenString string
+	var idToken *OIDCCredential
 	switch cred := credential.(type) {
 	case *JWTCredential:
 		tokenString = cred.Token
 	case *BearerCredential:
 		// Bearer credentials can also be JWTs
 		tokenString = cred.Token
+	case *OIDCCredential:
+		if len(v.clientIDs) == 0 {
+			return nil, fmt.Errorf("%w: ID tokens are not accepted from %s", ErrInvalidToken, v.issuer)
+		}
+		tokenString = cred.Token
+		idToken = cred
 	default:
 		return nil, fmt.Errorf("unsupported credential type for JWT validator: %T", credential)
 	}
 
 	// Fetch the current JWKS
-	jwks, err := v.cache.Lookup(ctx, v.jwksURL)
+	jwks, err := v.keySet(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
 	}
@@ -142,6 +261,7 @@ func (v *JWTValidator) Validate(ctx context.Context, credential Credential) (*Re
 		jwt.WithClock(jwt.ClockFunc(func() time.Time {
 			return v.clock.Now()
 		})),
+		jwt.WithAcceptableSkew(v.leeway),
 		// TODO: validate aud
 	)
 	if err != nil {
@@ -150,6 +270,11 @@ func (v *JWTValidator) Validate(ctx context.Context, credential Credential) (*Re
 		}
 		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
 	}
+	if idToken != nil {
+		if err := checkIDToken(token, v.clientIDs, idToken.Nonce); err != nil {
+			return nil, err
+		}
+	}
 
 	// Ensure there is a subject
 	subject, ok := token.Subject()
@@ -170,6 +295,9 @@ func (v *JWTValidator) Validate(ctx context.Context, credential Credential) (*Re
 	// TODO: Probably should add a ClaimsFilter to validator config so we can configure trust on a per-claim basis
 	claimsMap := make(claims.Claims)
 	maps.Copy(claimsMap, allClaims)
+	if idToken != nil {
+		normalizeOIDCClaims(claimsMap)
+	}
 
 	// Extract audience
 	audiences, _ := token.Audience()
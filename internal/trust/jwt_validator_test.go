@@ -2,7 +2,9 @@ package trust
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -196,6 +198,62 @@ func TestJWTValidator(t *testing.T) {
 		}
 	})
 
+	t.Run("tolerates clock skew within the leeway", func(t *testing.T) {
+		fixedTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+		clk := clock.NewFixtureClock(fixedTime)
+		fixtureWithClock, err := httpfixture.NewJWKSFixture(httpfixture.JWKSFixtureConfig{
+			Issuer:  "https://test-issuer.example.com",
+			JWKSURL: "https://test-issuer.example.com/.well-known/jwks.json",
+			Clock:   clk,
+		})
+		if err != nil {
+			t.Fatalf("failed to create fixture: %v", err)
+		}
+
+		strict := createValidatorWithFixture(t, fixtureWithClock)
+		lenient, err := NewJWTValidator(JWTValidatorConfig{
+			Issuer:      fixtureWithClock.Issuer(),
+			JWKSURL:     fixtureWithClock.JWKSURL(),
+			TrustDomain: "test-domain",
+			HTTPClient: &http.Client{Transport: httpfixture.NewTransport(httpfixture.TransportConfig{
+				Provider: fixtureWithClock,
+				Strict:   true,
+			})},
+			Clock:  clk,
+			Leeway: 30 * time.Second,
+		})
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+
+		// Issued by an issuer whose clock runs 20 seconds ahead
+		tokenString, err := fixtureWithClock.CreateAndSignToken(map[string]interface{}{
+			"sub": "user@example.com",
+			"iat": fixedTime.Add(20 * time.Second).Unix(),
+			"nbf": fixedTime.Add(20 * time.Second).Unix(),
+		})
+		if err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+		cred := &JWTCredential{BearerCredential: BearerCredential{Token: tokenString}}
+
+		if _, err := strict.Validate(ctx, cred); err == nil {
+			t.Error("expected a token that is not yet valid to be rejected without leeway")
+		}
+		if _, err := lenient.Validate(ctx, cred); err != nil {
+			t.Errorf("expected token within the leeway to be valid, got %v", err)
+		}
+
+		// Expired 10 seconds ago
+		clk.Advance(time.Hour + 10*time.Second)
+		if _, err := strict.Validate(ctx, cred); err != ErrExpiredToken {
+			t.Errorf("expected ErrExpiredToken without leeway, got %v", err)
+		}
+		if _, err := lenient.Validate(ctx, cred); err != nil {
+			t.Errorf("expected token expired within the leeway to be valid, got %v", err)
+		}
+	})
+
 	t.Run("rejects JWT with wrong issuer", func(t *testing.T) {
 		validator := createValidatorWithFixture(t, fixture)
 
@@ -375,3 +433,114 @@ func TestJWTValidatorConfig(t *testing.T) {
 		}
 	})
 }
+
+// unreachableTransport fails every request, like a JWKS endpoint that is down
+type unreachableTransport struct{}
+
+func (unreachableTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestJWTValidator_JWKSStore(t *testing.T) {
+	ctx := context.Background()
+	fixture := setupTestJWKSFixture(t)
+
+	store, err := NewFileJWKSStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	newValidator := func(transport http.RoundTripper) (*JWTValidator, error) {
+		return NewJWTValidator(JWTValidatorConfig{
+			Issuer:      fixture.Issuer(),
+			JWKSURL:     fixture.JWKSURL(),
+			TrustDomain: "test-domain",
+			HTTPClient:  &http.Client{Transport: transport},
+			Clock:       fixture.Clock(),
+			JWKSStore:   store,
+		})
+	}
+
+	t.Run("fails to start when the JWKS is unreachable and none is stored", func(t *testing.T) {
+		if _, err := newValidator(unreachableTransport{}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("saves the fetched JWKS", func(t *testing.T) {
+		_, err := newValidator(httpfixture.NewTransport(httpfixture.TransportConfig{
+			Provider: fixture,
+			Strict:   true,
+		}))
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+
+		stored, err := store.Load(ctx, fixture.JWKSURL())
+		if err != nil {
+			t.Fatalf("failed to load stored JWKS: %v", err)
+		}
+		if !strings.Contains(string(stored), `"keys"`) {
+			t.Errorf("expected a stored JWKS, got %q", stored)
+		}
+	})
+
+	t.Run("starts from the stored JWKS when the JWKS is unreachable", func(t *testing.T) {
+		validator, err := newValidator(unreachableTransport{})
+		if err != nil {
+			t.Fatalf("expected the stored JWKS to be used, got %v", err)
+		}
+
+		tokenString, err := fixture.CreateAndSignToken(map[string]interface{}{"sub": "user@example.com"})
+		if err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+		result, err := validator.Validate(ctx, &JWTCredential{BearerCredential: BearerCredential{Token: tokenString}})
+		if err != nil {
+			t.Fatalf("failed to validate with the stored JWKS: %v", err)
+		}
+		if result.Subject != "user@example.com" {
+			t.Errorf("expected subject user@example.com, got %q", result.Subject)
+		}
+	})
+}
+
+func TestFileJWKSStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileJWKSStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	if data, err := store.Load(ctx, "https://idp.example.com/jwks"); err != nil || data != nil {
+		t.Errorf("expected nothing stored, got %q, %v", data, err)
+	}
+
+	if err := store.Save(ctx, "https://idp.example.com/jwks", []byte(`{"keys":[]}`)); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	if err := store.Save(ctx, "https://other.example.com/jwks", []byte(`{"keys":[{}]}`)); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	if data, _ := store.Load(ctx, "https://idp.example.com/jwks"); string(data) != `{"keys":[]}` {
+		t.Errorf("expected the saved JWKS, got %q", data)
+	}
+}
+
+func TestJWTValidator_Warm(t *testing.T) {
+	fixture := setupTestJWKSFixture(t)
+
+	validator, err := NewJWTValidator(JWTValidatorConfig{
+		Issuer:            fixture.Issuer(),
+		JWKSURL:           fixture.JWKSURL(),
+		TrustDomain:       "test-domain",
+		HTTPClient:        &http.Client{Transport: unreachableTransport{}},
+		DeferInitialFetch: true,
+	})
+	if err != nil {
+		t.Fatalf("expected creation not to fetch the JWKS, got %v", err)
+	}
+	if err := validator.Warm(context.Background()); err == nil || !strings.Contains(err.Error(), "failed to fetch initial JWKS") {
+		t.Errorf("expected the fetch to fail, got %v", err)
+	}
+}
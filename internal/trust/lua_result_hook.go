@@ -0,0 +1,96 @@
+package trust
+
+import (
+	"context"
+	"fmt"
+
+	lua "github.com/yuin/gopher-lua"
+
+	luaservices "github.com/project-kessel/parsec/internal/lua"
+)
+
+// LuaResultHook changes validation results with a Lua script
+//
+// The script defines a function called 'enrich' that takes the validator's
+// Result as a table (subject, issuer, trust_domain, claims, audience, scope,
+// etc.) and returns a table of the fields to change, like CelResultHook
+// expects, or nil to change nothing. The script has access to the config
+// and json services.
+//
+// Example:
+//
+//	function enrich(result)
+//	  local tenant = string.match(result.issuer, "^https://([^.]+)%.idp%.example%.com")
+//	  if tenant == nil then
+//	    return nil
+//	  end
+//	  return {trust_domain = tenant .. ".example.com", claims = {tenant = tenant}}
+//	end
+type LuaResultHook struct {
+	script       string
+	configSource luaservices.ConfigSource
+}
+
+// NewLuaResultHook creates a Lua result hook
+// configSource provides the values available to the script via config.get()
+// (none if nil).
+func NewLuaResultHook(script string, configSource luaservices.ConfigSource) (*LuaResultHook, error) {
+	if script == "" {
+		return nil, fmt.Errorf("lua result hook script cannot be empty")
+	}
+	if configSource == nil {
+		configSource = luaservices.NewMapConfigSource(nil)
+	}
+
+	// Validate that the script has an enrich function
+	L := lua.NewState()
+	defer L.Close()
+	if err := L.DoString(script); err != nil {
+		return nil, fmt.Errorf("failed to load script: %w", err)
+	}
+	if L.GetGlobal("enrich").Type() != lua.LTFunction {
+		return nil, fmt.Errorf("script must define an 'enrich' function")
+	}
+
+	return &LuaResultHook{
+		script:       script,
+		configSource: configSource,
+	}, nil
+}
+
+// Apply implements the ResultHook interface
+func (h *LuaResultHook) Apply(ctx context.Context, result *Result) (*Result, error) {
+	resultMap, err := ConvertResultToMap(result)
+	if err != nil {
+		return nil, err
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(ctx)
+
+	luaservices.NewConfigService(h.configSource).Register(L)
+	luaservices.NewJSONService().Register(L)
+
+	if err := L.DoString(h.script); err != nil {
+		return nil, fmt.Errorf("failed to execute script: %w", err)
+	}
+	if err := L.CallByParam(lua.P{
+		Fn:      L.GetGlobal("enrich"),
+		NRet:    1,
+		Protect: true,
+	}, luaservices.GoToLua(L, resultMap)); err != nil {
+		return nil, fmt.Errorf("failed to call enrich function: %w", err)
+	}
+
+	ret := L.Get(-1)
+	L.Pop(1)
+	switch changes := luaservices.LuaToGo(ret).(type) {
+	case nil:
+		return result, nil
+	case map[string]any:
+		return applyResultChanges(result, changes)
+	default:
+		return nil, fmt.Errorf("enrich must return a table of fields or nil, got %s", ret.Type())
+	}
+}
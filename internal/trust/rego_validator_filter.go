@@ -0,0 +1,80 @@
+package trust
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+
+	"github.com/project-kessel/parsec/internal/request"
+)
+
+// DefaultRegoFilterQuery is the query a RegoValidatorFilter evaluates unless
+// another one is given: the allow rule of package parsec
+const DefaultRegoFilterQuery = "data.parsec.allow"
+
+// RegoValidatorFilter uses an OPA Rego policy to filter validators based on
+// actor context
+type RegoValidatorFilter struct {
+	query  rego.PreparedEvalQuery
+	policy string
+}
+
+// NewRegoValidatorFilter creates a new Rego-based validator filter
+// The policy is a Rego module; query selects the rule deciding whether a
+// validator is allowed (DefaultRegoFilterQuery if empty). A validator is
+// allowed only if the query evaluates to true; undefined means denied.
+//
+// The policy's input has the same fields as the CEL filter's variables:
+//   - input.actor: the actor's Result object (subject, issuer, trust_domain, claims, etc.)
+//   - input.validator_name: the name of the validator being checked
+//   - input.request: the request attributes (method, path, headers, additional, etc.)
+//
+// Example policy:
+//
+//	package parsec
+//
+//	default allow := false
+//
+//	allow if input.actor.trust_domain == "prod"
+//	allow if input.validator_name in {"public-validator"}
+func NewRegoValidatorFilter(policy, query string) (*RegoValidatorFilter, error) {
+	if policy == "" {
+		return nil, fmt.Errorf("rego filter policy cannot be empty")
+	}
+	if query == "" {
+		query = DefaultRegoFilterQuery
+	}
+
+	prepared, err := rego.New(
+		rego.Query(query),
+		rego.Module("validator_filter.rego", policy),
+	).PrepareForEval(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile Rego filter policy: %w", err)
+	}
+
+	return &RegoValidatorFilter{
+		query:  prepared,
+		policy: policy,
+	}, nil
+}
+
+// IsAllowed implements the ValidatorFilter interface
+func (f *RegoValidatorFilter) IsAllowed(actor *Result, validatorName string, requestAttrs *request.RequestAttributes) (bool, error) {
+	input, err := CreateValidatorFilterActivation(actor, validatorName, requestAttrs)
+	if err != nil {
+		return false, err
+	}
+
+	results, err := f.query.Eval(context.Background(), rego.EvalInput(input))
+	if err != nil {
+		return false, err
+	}
+	return results.Allowed(), nil
+}
+
+// Policy returns the Rego policy used by this filter
+func (f *RegoValidatorFilter) Policy() string {
+	return f.policy
+}
@@ -0,0 +1,147 @@
+package trust
+
+import (
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/request"
+)
+
+func TestRegoValidatorFilter_IsAllowed(t *testing.T) {
+	policy := `package parsec
+
+default allow := false
+
+allow if input.actor.trust_domain == "prod"
+
+allow if input.validator_name in {"public-validator"}
+
+allow if input.actor.claims.role == "admin"
+
+allow if {
+	startswith(input.request.path, "/api/internal")
+	input.request.method == "GET"
+}
+`
+	filter, err := NewRegoValidatorFilter(policy, "")
+	if err != nil {
+		t.Fatalf("NewRegoValidatorFilter failed: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		actor         *Result
+		validatorName string
+		requestAttrs  *request.RequestAttributes
+		wantAllowed   bool
+	}{
+		{
+			name:          "allow by trust domain",
+			actor:         &Result{Subject: "prod-service", TrustDomain: "prod"},
+			validatorName: "any-validator",
+			wantAllowed:   true,
+		},
+		{
+			name:          "allow by validator name",
+			actor:         &Result{Subject: "dev-service", TrustDomain: "dev"},
+			validatorName: "public-validator",
+			wantAllowed:   true,
+		},
+		{
+			name:          "allow by claim",
+			actor:         &Result{Subject: "dev-service", TrustDomain: "dev", Claims: claims.Claims{"role": "admin"}},
+			validatorName: "any-validator",
+			wantAllowed:   true,
+		},
+		{
+			name:          "allow by request",
+			actor:         &Result{Subject: "dev-service", TrustDomain: "dev"},
+			validatorName: "any-validator",
+			requestAttrs:  &request.RequestAttributes{Method: "GET", Path: "/api/internal/users"},
+			wantAllowed:   true,
+		},
+		{
+			name:          "deny by default",
+			actor:         &Result{Subject: "dev-service", TrustDomain: "dev", Claims: claims.Claims{"role": "user"}},
+			validatorName: "any-validator",
+			requestAttrs:  &request.RequestAttributes{Method: "POST", Path: "/api/internal/users"},
+			wantAllowed:   false,
+		},
+		{
+			name:          "deny without actor",
+			validatorName: "any-validator",
+			wantAllowed:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, err := filter.IsAllowed(tt.actor, tt.validatorName, tt.requestAttrs)
+			if err != nil {
+				t.Fatalf("IsAllowed failed: %v", err)
+			}
+			if allowed != tt.wantAllowed {
+				t.Errorf("IsAllowed() = %v, want %v", allowed, tt.wantAllowed)
+			}
+		})
+	}
+}
+
+func TestRegoValidatorFilter_Query(t *testing.T) {
+	policy := `package mesh.filters
+
+validators := {"prod": {"prod-validator"}, "dev": {"dev-validator", "public-validator"}}
+
+permit if input.validator_name in validators[input.actor.trust_domain]
+`
+	filter, err := NewRegoValidatorFilter(policy, "data.mesh.filters.permit")
+	if err != nil {
+		t.Fatalf("NewRegoValidatorFilter failed: %v", err)
+	}
+
+	actor := &Result{Subject: "svc", TrustDomain: "dev"}
+	for name, want := range map[string]bool{"dev-validator": true, "public-validator": true, "prod-validator": false} {
+		allowed, err := filter.IsAllowed(actor, name, nil)
+		if err != nil {
+			t.Fatalf("IsAllowed(%s) failed: %v", name, err)
+		}
+		if allowed != want {
+			t.Errorf("IsAllowed(%s) = %v, want %v", name, allowed, want)
+		}
+	}
+}
+
+func TestRegoValidatorFilter_NonBooleanResult(t *testing.T) {
+	filter, err := NewRegoValidatorFilter("package parsec\n\nallow := input.validator_name\n", "")
+	if err != nil {
+		t.Fatalf("NewRegoValidatorFilter failed: %v", err)
+	}
+
+	allowed, err := filter.IsAllowed(&Result{Subject: "svc"}, "any-validator", nil)
+	if err != nil {
+		t.Fatalf("IsAllowed failed: %v", err)
+	}
+	if allowed {
+		t.Error("expected a non-boolean result to deny")
+	}
+}
+
+func TestNewRegoValidatorFilter_InvalidPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		query  string
+	}{
+		{name: "empty policy", policy: ""},
+		{name: "syntax error", policy: "package parsec\n\nallow if {"},
+		{name: "invalid query", policy: "package parsec\n\nallow := true\n", query: "data.parsec.allow ==="},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRegoValidatorFilter(tt.policy, tt.query); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package trust
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/clock"
+)
+
+// ResultCache caches successful validation results by a hash of the credential,
+// so a token presented again is not validated again until the cache entry
+// expires. Entries live for at most the configured TTL and never past the
+// token's own expiry. The least recently used entries are evicted first.
+//
+// Only bearer-like credentials (bearer, JWT and OIDC tokens) are cached.
+type ResultCache struct {
+	maxEntries int
+	ttl        time.Duration
+	clock      clock.Clock
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	lru     *list.List
+}
+
+// ResultCacheConfig configures a ResultCache
+type ResultCacheConfig struct {
+	// MaxEntries bounds the number of cached results (default: 10000)
+	MaxEntries int
+
+	// TTL bounds how long a result is cached (default: 1 minute)
+	TTL time.Duration
+
+	// Clock is the time source for expiry (default: system clock)
+	Clock clock.Clock
+}
+
+// resultCacheEntry is a cached result and the validator that produced it
+type resultCacheEntry struct {
+	key       [sha256.Size]byte
+	validator Validator
+	result    *Result
+	expiresAt time.Time
+}
+
+// NewResultCache creates a validation result cache
+func NewResultCache(cfg ResultCacheConfig) *ResultCache {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 10000
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = time.Minute
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.NewSystemClock()
+	}
+	return &ResultCache{
+		maxEntries: cfg.MaxEntries,
+		ttl:        cfg.TTL,
+		clock:      cfg.Clock,
+		entries:    make(map[[sha256.Size]byte]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// resultCacheKey hashes the credential, reporting false if it isn't cacheable
+// The credential type is part of the key, since stores pick validators by it.
+func resultCacheKey(credential Credential) ([sha256.Size]byte, bool) {
+	var token, nonce string
+	switch cred := credential.(type) {
+	case *BearerCredential:
+		token = cred.Token
+	case *JWTCredential:
+		token = cred.Token
+	case *OIDCCredential:
+		// A result is only valid for the nonce it was checked against
+		token, nonce = cred.Token, cred.Nonce
+	default:
+		return [sha256.Size]byte{}, false
+	}
+	if token == "" {
+		return [sha256.Size]byte{}, false
+	}
+	h := sha256.New()
+	h.Write([]byte(credential.Type()))
+	h.Write([]byte{0})
+	h.Write([]byte(token))
+	h.Write([]byte{0})
+	h.Write([]byte(nonce))
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key, true
+}
+
+// get returns a copy of the unexpired result cached for the credential, if
+// allowed reports that the validator which produced it may be used
+// A store filtered for an actor may not allow every validator.
+func (c *ResultCache) get(credential Credential, allowed func(Validator) bool) (*Result, bool) {
+	key, ok := resultCacheKey(credential)
+	if !ok {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*resultCacheEntry)
+	if !c.clock.Now().Before(entry.expiresAt) {
+		c.remove(elem)
+		return nil, false
+	}
+	if !allowed(entry.validator) {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+
+	// Callers own the result they get, so the cached one is not shared
+	result := *entry.result
+	result.Claims = maps.Clone(entry.result.Claims)
+	return &result, true
+}
+
+// put caches the result validator produced for the credential
+func (c *ResultCache) put(credential Credential, validator Validator, result *Result) {
+	key, ok := resultCacheKey(credential)
+	if !ok {
+		return
+	}
+	now := c.clock.Now()
+	expiresAt := now.Add(c.ttl)
+	if !result.ExpiresAt.IsZero() && result.ExpiresAt.Before(expiresAt) {
+		expiresAt = result.ExpiresAt
+	}
+	if !now.Before(expiresAt) {
+		return
+	}
+
+	cached := *result
+	cached.Claims = maps.Clone(result.Claims)
+	entry := &resultCacheEntry{key: key, validator: validator, result: &cached, expiresAt: expiresAt}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove drops an entry; c.mu must be held
+func (c *ResultCache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*resultCacheEntry).key)
+}
+
+// Len returns the number of cached results, including expired ones not yet evicted
+func (c *ResultCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
@@ -0,0 +1,146 @@
+package trust
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/clock"
+)
+
+// countingValidator counts validations, accepting every token
+type countingValidator struct {
+	*StubValidator
+	calls int
+}
+
+func (v *countingValidator) Validate(ctx context.Context, credential Credential) (*Result, error) {
+	v.calls++
+	return v.StubValidator.Validate(ctx, credential)
+}
+
+func newCountingValidator(result *Result) *countingValidator {
+	return &countingValidator{StubValidator: NewStubValidator(CredentialTypeBearer).WithResult(result)}
+}
+
+func TestResultCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	token := &BearerCredential{Token: "user-token"}
+
+	t.Run("validates a token once until the TTL", func(t *testing.T) {
+		clk := clock.NewFixtureClock(now)
+		validator := newCountingValidator(&Result{Subject: "alice", ExpiresAt: now.Add(time.Hour)})
+		store := NewStubStore().AddValidator(validator).
+			WithResultCache(NewResultCache(ResultCacheConfig{TTL: time.Minute, Clock: clk}))
+
+		for range 3 {
+			result, err := store.Validate(ctx, token)
+			if err != nil || result.Subject != "alice" {
+				t.Fatalf("expected alice, got %v, %v", result, err)
+			}
+		}
+		if validator.calls != 1 {
+			t.Errorf("expected 1 validation, got %d", validator.calls)
+		}
+
+		clk.Advance(time.Minute)
+		if _, err := store.Validate(ctx, token); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if validator.calls != 2 {
+			t.Errorf("expected the expired result to be validated again, got %d validations", validator.calls)
+		}
+	})
+
+	t.Run("never caches past the token's expiry", func(t *testing.T) {
+		clk := clock.NewFixtureClock(now)
+		validator := newCountingValidator(&Result{Subject: "alice", ExpiresAt: now.Add(10 * time.Second)})
+		store := NewStubStore().AddValidator(validator).
+			WithResultCache(NewResultCache(ResultCacheConfig{TTL: time.Minute, Clock: clk}))
+
+		_, _ = store.Validate(ctx, token)
+		clk.Advance(10 * time.Second)
+		_, _ = store.Validate(ctx, token)
+		if validator.calls != 2 {
+			t.Errorf("expected 2 validations, got %d", validator.calls)
+		}
+	})
+
+	t.Run("doesn't cache failures", func(t *testing.T) {
+		validator := &countingValidator{StubValidator: NewStubValidator(CredentialTypeBearer).WithError(ErrInvalidToken)}
+		cache := NewResultCache(ResultCacheConfig{})
+		store := NewStubStore().AddValidator(validator).WithResultCache(cache)
+
+		_, _ = store.Validate(ctx, token)
+		_, _ = store.Validate(ctx, token)
+		if validator.calls != 2 || cache.Len() != 0 {
+			t.Errorf("expected 2 validations and nothing cached, got %d and %d", validator.calls, cache.Len())
+		}
+	})
+
+	t.Run("evicts the least recently used result", func(t *testing.T) {
+		validator := newCountingValidator(&Result{Subject: "alice"})
+		cache := NewResultCache(ResultCacheConfig{MaxEntries: 2})
+		store := NewStubStore().AddValidator(validator).WithResultCache(cache)
+
+		for _, tok := range []string{"a", "b", "a", "c", "a", "b"} {
+			_, _ = store.Validate(ctx, &BearerCredential{Token: tok})
+		}
+		// a, b, c miss; a hits; b was evicted by c and misses again
+		if validator.calls != 4 {
+			t.Errorf("expected 4 validations, got %d", validator.calls)
+		}
+		if cache.Len() != 2 {
+			t.Errorf("expected 2 cached results, got %d", cache.Len())
+		}
+	})
+
+	t.Run("ID token results are cached per nonce", func(t *testing.T) {
+		validator := &countingValidator{StubValidator: NewStubValidator(CredentialTypeOIDC).WithResult(&Result{Subject: "alice"})}
+		store := NewStubStore().AddValidator(validator).WithResultCache(NewResultCache(ResultCacheConfig{}))
+
+		for _, nonce := range []string{"a", "b", "a"} {
+			_, _ = store.Validate(ctx, &OIDCCredential{Token: "id-token", Nonce: nonce})
+		}
+		if validator.calls != 2 {
+			t.Errorf("expected a validation per nonce, got %d", validator.calls)
+		}
+	})
+
+	t.Run("callers can't modify cached results", func(t *testing.T) {
+		validator := newCountingValidator(&Result{Subject: "alice", Claims: claims.Claims{"role": "admin"}})
+		store := NewStubStore().AddValidator(validator).WithResultCache(NewResultCache(ResultCacheConfig{}))
+
+		first, _ := store.Validate(ctx, token)
+		first.Claims["role"] = "root"
+		second, _ := store.Validate(ctx, token)
+		if second.Claims["role"] != "admin" {
+			t.Errorf("expected the cached claims, got %v", second.Claims)
+		}
+	})
+
+	t.Run("stores filtered for an actor only use their validators' results", func(t *testing.T) {
+		internal := newCountingValidator(&Result{Subject: "alice"})
+		cache := NewResultCache(ResultCacheConfig{})
+		store, err := NewFilteredStore(WithResultCache(cache), WithCELFilter(`validator_name != "internal"`))
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		store.AddValidator("internal", internal)
+		store.AddValidator("external", NewStubValidator(CredentialTypeBearer).WithError(ErrInvalidToken))
+
+		if _, err := store.Validate(ctx, token); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		restricted, err := store.ForActor(ctx, &Result{Subject: "svc"}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := restricted.Validate(ctx, token); err == nil {
+			t.Error("expected the cached result of a disallowed validator to be ignored")
+		}
+	})
+}
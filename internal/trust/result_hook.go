@@ -0,0 +1,121 @@
+package trust
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ResultHook normalizes or augments the result of a validator before it
+// reaches the token service, e.g. to canonicalize the subject format or to
+// derive the trust domain from the issuer
+type ResultHook interface {
+	// Apply returns the changed result
+	// It must not modify result, which may be shared with a result cache.
+	Apply(ctx context.Context, result *Result) (*Result, error)
+}
+
+// HookedValidator applies result hooks to the results of a validator
+// Hooks are applied in order; credentials the validator rejects are not
+// passed to them.
+type HookedValidator struct {
+	validator Validator
+	hooks     []ResultHook
+}
+
+// NewHookedValidator wraps validator so hooks are applied to its results
+func NewHookedValidator(validator Validator, hooks ...ResultHook) *HookedValidator {
+	return &HookedValidator{
+		validator: validator,
+		hooks:     hooks,
+	}
+}
+
+// Validate implements the Validator interface
+func (v *HookedValidator) Validate(ctx context.Context, credential Credential) (*Result, error) {
+	result, err := v.validator.Validate(ctx, credential)
+	if err != nil {
+		return nil, err
+	}
+	for i, hook := range v.hooks {
+		result, err = hook.Apply(ctx, result)
+		if err != nil {
+			return nil, fmt.Errorf("result hook %d failed: %w", i, err)
+		}
+	}
+	return result, nil
+}
+
+// CredentialTypes implements the Validator interface
+func (v *HookedValidator) CredentialTypes() []CredentialType {
+	return v.validator.CredentialTypes()
+}
+
+// Unwrap returns the wrapped validator
+func (v *HookedValidator) Unwrap() Validator {
+	return v.validator
+}
+
+// resultHookFields are the Result fields a hook may change, by JSON name
+var resultHookFields = []string{"subject", "issuer", "trust_domain", "claims", "audience", "scope"}
+
+// applyResultChanges returns a copy of result with the changes a hook made
+// Fields in changes replace those of result, except that claims are merged
+// into the result's claims; a null claim removes it.
+func applyResultChanges(result *Result, changes map[string]any) (*Result, error) {
+	changed := *result
+	changed.Claims = maps.Clone(result.Claims)
+	changed.Audience = slices.Clone(result.Audience)
+
+	for field, value := range changes {
+		var ok bool
+		switch field {
+		case "subject":
+			changed.Subject, ok = value.(string)
+		case "issuer":
+			changed.Issuer, ok = value.(string)
+		case "trust_domain":
+			changed.TrustDomain, ok = value.(string)
+		case "scope":
+			changed.Scope, ok = value.(string)
+		case "audience":
+			changed.Audience, ok = toStringSlice(value)
+		case "claims":
+			var claims map[string]any
+			if claims, ok = value.(map[string]any); ok {
+				if changed.Claims == nil && len(claims) > 0 {
+					changed.Claims = make(map[string]any, len(claims))
+				}
+				for name, claim := range claims {
+					if claim == nil {
+						delete(changed.Claims, name)
+						continue
+					}
+					changed.Claims[name] = claim
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unknown result field %q (supported: %v)", field, resultHookFields)
+		}
+		if !ok {
+			return nil, fmt.Errorf("result field %q has unsupported type %T", field, value)
+		}
+	}
+	return &changed, nil
+}
+
+// toStringSlice converts a list of strings decoded from CEL or Lua
+func toStringSlice(value any) ([]string, bool) {
+	list, ok := value.([]any)
+	if !ok {
+		return nil, false
+	}
+	strs := make([]string, len(list))
+	for i, item := range list {
+		if strs[i], ok = item.(string); !ok {
+			return nil, false
+		}
+	}
+	return strs, true
+}
@@ -0,0 +1,164 @@
+package trust
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	luaservices "github.com/project-kessel/parsec/internal/lua"
+)
+
+func newHookTestValidator() *StubValidator {
+	return NewStubValidator(CredentialTypeBearer).WithResult(&Result{
+		Subject:     "Alice@Example.COM",
+		Issuer:      "https://acme.idp.example.com",
+		TrustDomain: "idp.example.com",
+		Claims:      claims.Claims{"email": "alice@example.com", "groups": []any{"eng"}},
+		Audience:    []string{"parsec"},
+	})
+}
+
+func TestHookedValidator_CelHooks(t *testing.T) {
+	ctx := context.Background()
+
+	canonicalize, err := NewCelResultHook(`{"subject": result.subject.lowerAscii()}`)
+	if err != nil {
+		t.Fatalf("NewCelResultHook failed: %v", err)
+	}
+	trustDomain, err := NewCelResultHook(`
+		result.issuer.startsWith("https://acme.") ?
+			{"trust_domain": "acme.example.com", "claims": {"tenant": "acme", "groups": null}} :
+			{}
+	`)
+	if err != nil {
+		t.Fatalf("NewCelResultHook failed: %v", err)
+	}
+
+	stub := newHookTestValidator()
+	validator := NewHookedValidator(stub, canonicalize, trustDomain)
+
+	result, err := validator.Validate(ctx, &BearerCredential{Token: "token"})
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if result.Subject != "alice@example.com" {
+		t.Errorf("expected canonical subject, got %q", result.Subject)
+	}
+	if result.TrustDomain != "acme.example.com" {
+		t.Errorf("expected trust domain derived from issuer, got %q", result.TrustDomain)
+	}
+	if result.Claims["tenant"] != "acme" || result.Claims["email"] != "alice@example.com" {
+		t.Errorf("expected claims to be augmented, got %v", result.Claims)
+	}
+	if _, ok := result.Claims["groups"]; ok {
+		t.Errorf("expected null claim to be removed, got %v", result.Claims)
+	}
+	if !slices.Equal(result.Audience, []string{"parsec"}) || result.Issuer != "https://acme.idp.example.com" {
+		t.Errorf("expected other fields to be kept, got %+v", result)
+	}
+
+	// The validator's own result is not modified
+	original, _ := stub.Validate(ctx, &BearerCredential{Token: "token"})
+	if original.Subject != "Alice@Example.COM" || original.Claims["groups"] == nil || original.Claims["tenant"] != nil {
+		t.Errorf("expected the validator's result to be unchanged, got %+v", original)
+	}
+}
+
+func TestHookedValidator_LuaHook(t *testing.T) {
+	hook, err := NewLuaResultHook(`
+		function enrich(result)
+			local tenant = string.match(result.issuer, "^https://([^.]+)%.idp%.example%.com")
+			if tenant == nil then
+				return nil
+			end
+			return {
+				trust_domain = tenant .. "." .. config.get("domain"),
+				audience = {"parsec", tenant},
+				claims = {tenant = tenant},
+			}
+		end
+	`, luaservices.NewMapConfigSource(map[string]any{"domain": "example.com"}))
+	if err != nil {
+		t.Fatalf("NewLuaResultHook failed: %v", err)
+	}
+
+	validator := NewHookedValidator(newHookTestValidator(), hook)
+	result, err := validator.Validate(context.Background(), &BearerCredential{Token: "token"})
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if result.TrustDomain != "acme.example.com" || result.Claims["tenant"] != "acme" {
+		t.Errorf("expected Lua changes to apply, got %+v", result)
+	}
+	if !slices.Equal(result.Audience, []string{"parsec", "acme"}) {
+		t.Errorf("expected audience to be replaced, got %v", result.Audience)
+	}
+
+	unchanged := NewHookedValidator(NewStubValidator(CredentialTypeBearer), hook)
+	result, err = unchanged.Validate(context.Background(), &BearerCredential{Token: "token"})
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if result.TrustDomain != "test-domain" {
+		t.Errorf("expected nil to change nothing, got %q", result.TrustDomain)
+	}
+}
+
+func TestHookedValidator_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("validation errors skip hooks", func(t *testing.T) {
+		hook, _ := NewCelResultHook(`{"subject": "x"}`)
+		validator := NewHookedValidator(NewStubValidator().WithError(ErrInvalidToken), hook)
+		if _, err := validator.Validate(ctx, &BearerCredential{Token: "token"}); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected validation error, got %v", err)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{"unknown field", `{"expires_at": "never"}`},
+		{"wrong field type", `{"subject": 42}`},
+		{"not a map", `result.subject`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook, err := NewCelResultHook(tt.script)
+			if err != nil {
+				t.Fatalf("NewCelResultHook failed: %v", err)
+			}
+			validator := NewHookedValidator(newHookTestValidator(), hook)
+			if _, err := validator.Validate(ctx, &BearerCredential{Token: "token"}); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	t.Run("invalid scripts", func(t *testing.T) {
+		if _, err := NewCelResultHook(`{"subject": `); err == nil {
+			t.Error("expected CEL compile error")
+		}
+		if _, err := NewLuaResultHook(`function fetch(input) end`, nil); err == nil {
+			t.Error("expected error without enrich function")
+		}
+	})
+}
+
+func TestWarmers_HookedValidator(t *testing.T) {
+	hook, _ := NewCelResultHook(`{}`)
+	store, err := NewFilteredStore()
+	if err != nil {
+		t.Fatalf("NewFilteredStore failed: %v", err)
+	}
+	store.AddValidator("warmable", NewHookedValidator(newFlakyWarmer(0), hook))
+	store.AddValidator("cold", NewHookedValidator(NewStubValidator(), hook))
+
+	warmers := Warmers(store)
+	if _, ok := warmers["warmable"]; !ok || len(warmers) != 1 {
+		t.Errorf("expected only the wrapped warmer, got %v", warmers)
+	}
+}
@@ -0,0 +1,110 @@
+package trust
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/rhidentity"
+)
+
+// RHIdentityValidator validates x-rh-identity documents, as set by the
+// console.redhat.com gateway, so that traffic authenticated there can be
+// exchanged for transaction tokens
+//
+// The identity is not signed: it must only be accepted from callers that
+// strip the header from external requests, such as the gateway itself.
+//
+// The subject is the identity's principal (see rhidentity.Document.Principal),
+// and the claims are its identity section, e.g.
+//
+//	{"org_id": "12345", "account_number": "67890", "type": "User",
+//	 "user": {"username": "alice", "is_org_admin": true, ...},
+//	 "entitlements": {"insights": {"is_entitled": true, ...}}}
+//
+// with the entitlements of the document, if any. This is the form the
+// rh_identity issuer accepts, so identities without fields unknown to the
+// schema pass through unchanged.
+type RHIdentityValidator struct {
+	trustDomain   string
+	issuer        string
+	identityTypes []rhidentity.Type
+}
+
+// RHIdentityValidatorConfig configures an x-rh-identity validator
+type RHIdentityValidatorConfig struct {
+	// TrustDomain is the trust domain of the identities
+	TrustDomain string
+
+	// Issuer is the issuer of results (optional), e.g. the gateway URL
+	Issuer string
+
+	// IdentityTypes are the accepted identity types (all if empty)
+	IdentityTypes []rhidentity.Type
+}
+
+// NewRHIdentityValidator creates an x-rh-identity validator
+func NewRHIdentityValidator(cfg RHIdentityValidatorConfig) (*RHIdentityValidator, error) {
+	if cfg.TrustDomain == "" {
+		return nil, fmt.Errorf("trust domain is required")
+	}
+	return &RHIdentityValidator{
+		trustDomain:   cfg.TrustDomain,
+		issuer:        cfg.Issuer,
+		identityTypes: cfg.IdentityTypes,
+	}, nil
+}
+
+// CredentialTypes implements the Validator interface
+// x-rh-identity documents are JSON credentials, like those of JSONValidator
+func (v *RHIdentityValidator) CredentialTypes() []CredentialType {
+	return []CredentialType{CredentialTypeJSON}
+}
+
+// Validate implements the Validator interface
+func (v *RHIdentityValidator) Validate(ctx context.Context, credential Credential) (*Result, error) {
+	jsonCred, ok := credential.(*JSONCredential)
+	if !ok {
+		return nil, fmt.Errorf("expected JSONCredential, got %T", credential)
+	}
+
+	doc, err := rhidentity.Parse(jsonCred.RawJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if len(v.identityTypes) > 0 && !slices.Contains(v.identityTypes, doc.Identity.Type) {
+		return nil, fmt.Errorf("%w: identity type %s is not accepted", ErrInvalidToken, doc.Identity.Type)
+	}
+	subject := doc.Principal()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: identity of type %s has no principal", ErrInvalidToken, doc.Identity.Type)
+	}
+
+	// The claims keep fields the schema doesn't know about
+	var raw struct {
+		Identity     claims.Claims  `json:"identity"`
+		Entitlements map[string]any `json:"entitlements"`
+	}
+	if err := json.Unmarshal(jsonCred.RawJSON, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if raw.Entitlements != nil {
+		raw.Identity["entitlements"] = raw.Entitlements
+	}
+
+	result := &Result{
+		Subject:     subject,
+		Issuer:      v.issuer,
+		TrustDomain: v.trustDomain,
+		Claims:      raw.Identity,
+	}
+	if internal := doc.Identity.Internal; internal != nil && internal.AuthTime > 0 {
+		sec, frac := math.Modf(internal.AuthTime)
+		result.IssuedAt = time.Unix(int64(sec), int64(frac*1e9)).UTC()
+	}
+	return result, nil
+}
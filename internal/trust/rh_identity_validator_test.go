@@ -0,0 +1,101 @@
+package trust
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/rhidentity"
+)
+
+func TestRHIdentityValidator(t *testing.T) {
+	ctx := context.Background()
+	validator, err := NewRHIdentityValidator(RHIdentityValidatorConfig{
+		TrustDomain: "console.redhat.com",
+		Issuer:      "https://console.redhat.com",
+	})
+	if err != nil {
+		t.Fatalf("NewRHIdentityValidator failed: %v", err)
+	}
+
+	t.Run("maps a user identity", func(t *testing.T) {
+		result, err := validator.Validate(ctx, &JSONCredential{RawJSON: []byte(`{
+			"identity": {
+				"account_number": "67890",
+				"org_id": "12345",
+				"type": "User",
+				"auth_type": "jwt-auth",
+				"internal": {"org_id": "12345", "auth_time": 1700000000.5},
+				"user": {"username": "alice", "user_id": "u-1", "email": "alice@example.com", "is_org_admin": true},
+				"gateway_field": "kept"
+			},
+			"entitlements": {"insights": {"is_entitled": true, "is_trial": false}}
+		}`)})
+		if err != nil {
+			t.Fatalf("Validate failed: %v", err)
+		}
+		if result.Subject != "u-1" || result.TrustDomain != "console.redhat.com" || result.Issuer != "https://console.redhat.com" {
+			t.Errorf("unexpected result: %+v", result)
+		}
+		if result.Claims.GetString("org_id") != "12345" || result.Claims.GetString("account_number") != "67890" {
+			t.Errorf("expected org_id and account_number claims, got %v", result.Claims)
+		}
+		if user, _ := result.Claims["user"].(map[string]any); user["username"] != "alice" || user["is_org_admin"] != true {
+			t.Errorf("expected the user section, got %v", result.Claims["user"])
+		}
+		if result.Claims.GetString("gateway_field") != "kept" || !result.Claims.Has("entitlements") {
+			t.Errorf("expected unknown fields and entitlements to be kept, got %v", result.Claims)
+		}
+		if want := time.Unix(1700000000, 5e8).UTC(); !result.IssuedAt.Equal(want) {
+			t.Errorf("expected the auth time as IssuedAt, got %v", result.IssuedAt)
+		}
+	})
+
+	t.Run("maps service accounts by client ID", func(t *testing.T) {
+		result, err := validator.Validate(ctx, &JSONCredential{RawJSON: []byte(`{"identity": {
+			"org_id": "12345", "type": "ServiceAccount",
+			"service_account": {"client_id": "b69eaf9e", "username": "service-account-b69eaf9e"}
+		}}`)})
+		if err != nil || result.Subject != "b69eaf9e" {
+			t.Errorf("expected the client ID as subject, got %v, %v", result, err)
+		}
+	})
+
+	for name, doc := range map[string]string{
+		"not JSON":                 `x-rh-identity`,
+		"no type":                  `{"identity": {"org_id": "12345", "user": {"username": "alice"}}}`,
+		"missing type section":     `{"identity": {"org_id": "12345", "type": "User"}}`,
+		"missing org_id":           `{"identity": {"type": "User", "user": {"username": "alice"}}}`,
+		"no principal":             `{"identity": {"org_id": "12345", "type": "User", "user": {}}}`,
+		"unknown auth_type":        `{"identity": {"org_id": "12345", "type": "User", "auth_type": "magic", "user": {"username": "alice"}}}`,
+		"mistyped identity fields": `{"identity": {"org_id": 12345, "type": "User", "user": {"username": "alice"}}}`,
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			_, err := validator.Validate(ctx, &JSONCredential{RawJSON: []byte(doc)})
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+
+	t.Run("only accepts configured identity types", func(t *testing.T) {
+		users, err := NewRHIdentityValidator(RHIdentityValidatorConfig{
+			TrustDomain:   "console.redhat.com",
+			IdentityTypes: []rhidentity.Type{rhidentity.TypeUser},
+		})
+		if err != nil {
+			t.Fatalf("NewRHIdentityValidator failed: %v", err)
+		}
+		_, err = users.Validate(ctx, &JSONCredential{RawJSON: []byte(`{"identity": {
+			"org_id": "12345", "type": "System", "system": {"cn": "host-1"}
+		}}`)})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	if _, err := NewRHIdentityValidator(RHIdentityValidatorConfig{}); err == nil {
+		t.Error("expected an error without a trust domain")
+	}
+}
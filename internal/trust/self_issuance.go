@@ -0,0 +1,128 @@
+package trust
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+
+	"github.com/project-kessel/parsec/internal/perr"
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/trustdomain"
+)
+
+// ErrSelfIssuanceNotPermitted is returned when an actor is not allowed to obtain
+// a token representing itself
+var ErrSelfIssuanceNotPermitted = perr.New(perr.ErrCodeActorDenied, "actor is not permitted to obtain tokens for itself")
+
+// SelfIssuancePolicy decides whether an authenticated actor may obtain a token
+// representing itself, with no subject token (the client_credentials grant).
+// This covers workloads such as scheduled jobs that initiate transactions
+// without an end user.
+type SelfIssuancePolicy interface {
+	// CheckSelfIssuance returns nil if the actor may obtain a token for itself.
+	// Returns an error wrapping ErrSelfIssuanceNotPermitted if it is denied,
+	// or another error if the policy could not be evaluated.
+	CheckSelfIssuance(ctx context.Context, actor *Result, requestAttrs *request.RequestAttributes) error
+}
+
+// SelfIssuancePolicyLibrary creates a CEL library for self-issuance policies.
+//
+// This provides compile-time declarations for:
+//   - actor - the actor's Result object as a map (subject, issuer, trust_domain, claims, etc.)
+//   - request - the request attributes as a map (path, method, additional, etc.)
+//
+// Example expressions:
+//   - actor.trust_domain == "jobs.example.com"
+//   - actor.subject.startsWith("spiffe://example.org/ns/batch/")
+//   - actor.claims.role == "scheduler" && request.additional.purpose == "nightly-sync"
+func SelfIssuancePolicyLibrary() cel.EnvOption {
+	return cel.Lib(&selfIssuancePolicyLib{})
+}
+
+type selfIssuancePolicyLib struct{}
+
+func (lib *selfIssuancePolicyLib) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		// trustDomainMatches(value, pattern)
+		trustdomain.CELLibrary(),
+		cel.Variable("actor", cel.DynType),
+		cel.Variable("request", cel.DynType),
+	}
+}
+
+func (lib *selfIssuancePolicyLib) ProgramOptions() []cel.ProgramOption {
+	return []cel.ProgramOption{}
+}
+
+// CelSelfIssuancePolicy uses a CEL expression to decide whether an actor may
+// obtain a token for itself
+type CelSelfIssuancePolicy struct {
+	program cel.Program
+	script  string
+}
+
+// NewCelSelfIssuancePolicy creates a new CEL-based self-issuance policy
+// The script should be a CEL expression that evaluates to a boolean
+func NewCelSelfIssuancePolicy(script string) (*CelSelfIssuancePolicy, error) {
+	if script == "" {
+		return nil, fmt.Errorf("CEL self-issuance script cannot be empty")
+	}
+
+	env, err := cel.NewEnv(SelfIssuancePolicyLibrary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(script)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("failed to compile CEL self-issuance script: %w", issues.Err())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL program: %w", err)
+	}
+
+	return &CelSelfIssuancePolicy{
+		program: program,
+		script:  script,
+	}, nil
+}
+
+// CheckSelfIssuance implements SelfIssuancePolicy
+func (p *CelSelfIssuancePolicy) CheckSelfIssuance(ctx context.Context, actor *Result, requestAttrs *request.RequestAttributes) error {
+	if actor == nil || actor.Subject == "" {
+		return fmt.Errorf("%w: an authenticated actor is required", ErrSelfIssuanceNotPermitted)
+	}
+
+	actorMap, err := ConvertResultToMap(actor)
+	if err != nil {
+		return fmt.Errorf("failed to convert actor: %w", err)
+	}
+
+	requestMap, err := ConvertRequestAttributesToMap(requestAttrs)
+	if err != nil {
+		return fmt.Errorf("failed to convert request attributes: %w", err)
+	}
+
+	result, _, err := p.program.ContextEval(ctx, map[string]any{
+		"actor":   actorMap,
+		"request": requestMap,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to evaluate self-issuance policy: %w", err)
+	}
+
+	if result.Type() == types.BoolType && result.Value().(bool) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: self-issuance policy denied actor %q", ErrSelfIssuanceNotPermitted, actor.Subject)
+}
+
+// Script returns the CEL script used by this policy
+func (p *CelSelfIssuancePolicy) Script() string {
+	return p.script
+}
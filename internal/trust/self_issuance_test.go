@@ -0,0 +1,45 @@
+package trust
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/request"
+)
+
+func TestCelSelfIssuancePolicy(t *testing.T) {
+	ctx := context.Background()
+
+	policy, err := NewCelSelfIssuancePolicy(`actor.trust_domain == "jobs" && request.path == "/sync"`)
+	if err != nil {
+		t.Fatalf("failed to create policy: %v", err)
+	}
+
+	t.Run("allows matching actor", func(t *testing.T) {
+		err := policy.CheckSelfIssuance(ctx, &Result{Subject: "nightly-sync", TrustDomain: "jobs"}, &request.RequestAttributes{Path: "/sync"})
+		if err != nil {
+			t.Errorf("expected self-issuance to be allowed, got %v", err)
+		}
+	})
+
+	t.Run("denies non-matching actor", func(t *testing.T) {
+		err := policy.CheckSelfIssuance(ctx, &Result{Subject: "gw", TrustDomain: "gateways"}, &request.RequestAttributes{Path: "/sync"})
+		if !errors.Is(err, ErrSelfIssuanceNotPermitted) {
+			t.Errorf("expected ErrSelfIssuanceNotPermitted, got %v", err)
+		}
+	})
+
+	t.Run("denies anonymous actor", func(t *testing.T) {
+		err := policy.CheckSelfIssuance(ctx, AnonymousResult(), &request.RequestAttributes{Path: "/sync"})
+		if !errors.Is(err, ErrSelfIssuanceNotPermitted) {
+			t.Errorf("expected ErrSelfIssuanceNotPermitted, got %v", err)
+		}
+	})
+
+	t.Run("rejects empty script", func(t *testing.T) {
+		if _, err := NewCelSelfIssuancePolicy(""); err == nil {
+			t.Error("expected error for empty script")
+		}
+	})
+}
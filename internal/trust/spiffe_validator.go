@@ -0,0 +1,212 @@
+package trust
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/httprc/v3"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/trustdomain"
+)
+
+// spiffeScheme is the URI scheme of SPIFFE IDs
+const spiffeScheme = "spiffe://"
+
+// SPIFFEValidator validates SPIFFE JWT-SVIDs against a trust domain's JWT bundle
+type SPIFFEValidator struct {
+	trustDomain string
+	bundleURL   string
+	audiences   []string
+	cache       *jwk.Cache
+	clock       clock.Clock
+	leeway      time.Duration
+}
+
+// SPIFFEValidatorConfig contains configuration for JWT-SVID validation
+type SPIFFEValidatorConfig struct {
+	// TrustDomain is the SPIFFE trust domain (e.g., "example.org")
+	// Only SVIDs whose subject is a SPIFFE ID in this trust domain are accepted
+	TrustDomain string
+
+	// BundleURL is the URL of the trust domain's JWT bundle (a JWK Set),
+	// e.g. a SPIFFE bundle endpoint or a SPIRE OIDC discovery provider keys URL
+	BundleURL string
+
+	// Audiences are the accepted audiences, or patterns such as
+	// "*.example.com". JWT-SVIDs always carry an audience, and at least one
+	// must match. If empty, any audience is accepted.
+	Audiences []string
+
+	// RefreshInterval for the bundle cache (default: 5 minutes)
+	RefreshInterval time.Duration
+
+	// HTTPClient is an optional HTTP client for bundle fetching
+	HTTPClient *http.Client
+
+	// Clock is the time source for token validation
+	// If nil, uses system clock
+	Clock clock.Clock
+
+	// Leeway is the clock skew tolerated when checking exp, nbf and iat
+	Leeway time.Duration
+}
+
+// NewSPIFFEValidator creates a new JWT-SVID validator
+func NewSPIFFEValidator(cfg SPIFFEValidatorConfig) (*SPIFFEValidator, error) {
+	if cfg.TrustDomain == "" {
+		return nil, fmt.Errorf("trust domain is required")
+	}
+	if strings.ContainsAny(cfg.TrustDomain, "/:") || cfg.TrustDomain != strings.ToLower(cfg.TrustDomain) {
+		return nil, fmt.Errorf("invalid SPIFFE trust domain %q", cfg.TrustDomain)
+	}
+	if cfg.BundleURL == "" {
+		return nil, fmt.Errorf("bundle URL is required")
+	}
+
+	refreshInterval := cfg.RefreshInterval
+	if refreshInterval == 0 {
+		refreshInterval = 5 * time.Minute
+	}
+
+	cache, err := jwk.NewCache(context.Background(), httprc.NewClient())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bundle cache: %w", err)
+	}
+
+	registerOpts := []jwk.RegisterOption{jwk.WithMinInterval(refreshInterval)}
+	if cfg.HTTPClient != nil {
+		registerOpts = append(registerOpts, jwk.WithHTTPClient(cfg.HTTPClient))
+	}
+	if err := cache.Register(context.Background(), cfg.BundleURL, registerOpts...); err != nil {
+		return nil, fmt.Errorf("failed to register bundle URL: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := cache.Refresh(ctx, cfg.BundleURL); err != nil {
+		return nil, fmt.Errorf("failed to fetch initial bundle: %w", err)
+	}
+
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	return &SPIFFEValidator{
+		trustDomain: cfg.TrustDomain,
+		bundleURL:   cfg.BundleURL,
+		audiences:   cfg.Audiences,
+		cache:       cache,
+		clock:       clk,
+		leeway:      cfg.Leeway,
+	}, nil
+}
+
+// CredentialTypes returns the credential types this validator can handle
+func (v *SPIFFEValidator) CredentialTypes() []CredentialType {
+	return []CredentialType{CredentialTypeJWT, CredentialTypeBearer}
+}
+
+// Validate validates a JWT-SVID
+// The subject of the result is the SPIFFE ID and the issuer is the trust domain's
+// SPIFFE ID (spiffe://<trust-domain>), since JWT-SVIDs need not carry an iss claim
+func (v *SPIFFEValidator) Validate(ctx context.Context, credential Credential) (*Result, error) {
+	var tokenString string
+	switch cred := credential.(type) {
+	case *JWTCredential:
+		tokenString = cred.Token
+	case *BearerCredential:
+		tokenString = cred.Token
+	default:
+		return nil, fmt.Errorf("unsupported credential type for SPIFFE validator: %T", credential)
+	}
+
+	bundle, err := v.cache.Lookup(ctx, v.bundleURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bundle: %w", err)
+	}
+
+	token, err := jwt.Parse(
+		[]byte(tokenString),
+		jwt.WithKeySet(bundle),
+		jwt.WithValidate(true),
+		jwt.WithClock(jwt.ClockFunc(func() time.Time {
+			return v.clock.Now()
+		})),
+		jwt.WithAcceptableSkew(v.leeway),
+	)
+	if err != nil {
+		if errors.Is(err, jwt.TokenExpiredError()) {
+			return nil, ErrExpiredToken
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	// JWT-SVIDs must carry sub, aud and exp (JWT-SVID section 3)
+	subject, _ := token.Subject()
+	if err := v.checkSPIFFEID(subject); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	audiences, _ := token.Audience()
+	if len(audiences) == 0 {
+		return nil, fmt.Errorf("%w: missing audience claim", ErrInvalidToken)
+	}
+	if len(v.audiences) > 0 && !slices.ContainsFunc(audiences, func(aud string) bool {
+		return trustdomain.MatchAny(v.audiences, aud)
+	}) {
+		return nil, fmt.Errorf("%w: audience %v not accepted", ErrInvalidToken, audiences)
+	}
+	expiresAt, ok := token.Expiration()
+	if !ok {
+		return nil, fmt.Errorf("%w: missing expiration claim", ErrInvalidToken)
+	}
+	issuedAt, _ := token.IssuedAt()
+
+	claimsMap := make(claims.Claims)
+	serialized, err := json.Marshal(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize token claims: %w", err)
+	}
+	if err := json.Unmarshal(serialized, &claimsMap); err != nil {
+		return nil, fmt.Errorf("failed to parse token claims: %w", err)
+	}
+
+	return &Result{
+		Subject:     subject,
+		Issuer:      spiffeScheme + v.trustDomain,
+		TrustDomain: v.trustDomain,
+		Claims:      claimsMap,
+		ExpiresAt:   expiresAt,
+		IssuedAt:    issuedAt,
+		Audience:    audiences,
+	}, nil
+}
+
+// checkSPIFFEID checks that id is a workload SPIFFE ID in the validator's trust domain
+func (v *SPIFFEValidator) checkSPIFFEID(id string) error {
+	rest, ok := strings.CutPrefix(id, spiffeScheme)
+	if !ok {
+		return fmt.Errorf("subject %q is not a SPIFFE ID", id)
+	}
+	trustDomain, path, _ := strings.Cut(rest, "/")
+	if trustDomain != v.trustDomain {
+		return fmt.Errorf("SPIFFE ID %q is not in trust domain %q", id, v.trustDomain)
+	}
+	if path == "" {
+		return fmt.Errorf("SPIFFE ID %q has no path", id)
+	}
+	if strings.ContainsAny(path, "?#") {
+		return fmt.Errorf("SPIFFE ID %q must not have a query or fragment", id)
+	}
+	return nil
+}
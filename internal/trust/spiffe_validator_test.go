@@ -0,0 +1,94 @@
+package trust
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/httpfixture"
+)
+
+func TestSPIFFEValidator(t *testing.T) {
+	ctx := context.Background()
+
+	fixture, err := httpfixture.NewJWKSFixture(httpfixture.JWKSFixtureConfig{
+		Issuer:  "https://spire.example.org",
+		JWKSURL: "https://spire.example.org/keys",
+	})
+	if err != nil {
+		t.Fatalf("failed to create JWKS fixture: %v", err)
+	}
+
+	validator, err := NewSPIFFEValidator(SPIFFEValidatorConfig{
+		TrustDomain: "example.org",
+		BundleURL:   fixture.JWKSURL(),
+		Audiences:   []string{"parsec"},
+		HTTPClient: &http.Client{
+			Transport: httpfixture.NewTransport(httpfixture.TransportConfig{
+				Provider: fixture,
+				Strict:   true,
+			}),
+		},
+		Clock: fixture.Clock(),
+	})
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	sign := func(t *testing.T, claims map[string]any) Credential {
+		t.Helper()
+		token, err := fixture.CreateAndSignToken(claims)
+		if err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+		return &JWTCredential{BearerCredential: BearerCredential{Token: token}}
+	}
+
+	t.Run("accepts SVID in trust domain", func(t *testing.T) {
+		result, err := validator.Validate(ctx, sign(t, map[string]any{
+			"sub": "spiffe://example.org/ns/default/sa/web",
+			"aud": []string{"parsec"},
+		}))
+		if err != nil {
+			t.Fatalf("validation failed: %v", err)
+		}
+		if result.Subject != "spiffe://example.org/ns/default/sa/web" {
+			t.Errorf("expected SPIFFE ID subject, got %q", result.Subject)
+		}
+		if result.Issuer != "spiffe://example.org" {
+			t.Errorf("expected issuer spiffe://example.org, got %q", result.Issuer)
+		}
+		if result.TrustDomain != "example.org" {
+			t.Errorf("expected trust domain example.org, got %q", result.TrustDomain)
+		}
+	})
+
+	rejected := []struct {
+		name   string
+		claims map[string]any
+	}{
+		{"other trust domain", map[string]any{"sub": "spiffe://other.org/web", "aud": []string{"parsec"}}},
+		{"non-SPIFFE subject", map[string]any{"sub": "alice", "aud": []string{"parsec"}}},
+		{"no path", map[string]any{"sub": "spiffe://example.org", "aud": []string{"parsec"}}},
+		{"missing audience", map[string]any{"sub": "spiffe://example.org/web"}},
+		{"wrong audience", map[string]any{"sub": "spiffe://example.org/web", "aud": []string{"other"}}},
+	}
+	for _, tt := range rejected {
+		t.Run("rejects "+tt.name, func(t *testing.T) {
+			_, err := validator.Validate(ctx, sign(t, tt.claims))
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+
+	t.Run("requires trust domain and bundle URL", func(t *testing.T) {
+		if _, err := NewSPIFFEValidator(SPIFFEValidatorConfig{BundleURL: "https://spire.example.org/keys"}); err == nil {
+			t.Error("expected error without trust domain")
+		}
+		if _, err := NewSPIFFEValidator(SPIFFEValidatorConfig{TrustDomain: "example.org"}); err == nil {
+			t.Error("expected error without bundle URL")
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package trust
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/trustdomain"
+)
+
+// ValidatorMapping allows matching actors to use a fixed list of validators
+type ValidatorMapping struct {
+	// TrustDomain matches the actor's trust domain, exactly or by a pattern such
+	// as "*.example.com" (see trustdomain.Match; empty matches any)
+	TrustDomain string
+
+	// Subject matches the actor's subject exactly, or by prefix when it ends in "*"
+	// (empty matches any)
+	Subject string
+
+	// Validators are the names of the validators matching actors may use
+	Validators []string
+}
+
+// matches reports whether the mapping applies to the actor
+func (m *ValidatorMapping) matches(actor *Result) bool {
+	if !trustdomain.Match(m.TrustDomain, actor.TrustDomain) {
+		return false
+	}
+	if m.Subject == "" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(m.Subject, "*"); ok {
+		return strings.HasPrefix(actor.Subject, prefix)
+	}
+	return m.Subject == actor.Subject
+}
+
+// StaticValidatorFilter maps actors directly to the validators they may use,
+// for deployments that want a static, auditable policy instead of expressions.
+// Mappings are evaluated in order and the first match wins. Actors matching no
+// mapping may use the default validators.
+type StaticValidatorFilter struct {
+	mappings          []ValidatorMapping
+	defaultValidators []string
+}
+
+// NewStaticValidatorFilter creates a static mapping filter
+// If defaultValidators is empty, actors matching no mapping may not use any
+// validator.
+func NewStaticValidatorFilter(mappings []ValidatorMapping, defaultValidators []string) *StaticValidatorFilter {
+	return &StaticValidatorFilter{
+		mappings:          mappings,
+		defaultValidators: defaultValidators,
+	}
+}
+
+// IsAllowed implements the ValidatorFilter interface
+func (f *StaticValidatorFilter) IsAllowed(actor *Result, validatorName string, requestAttrs *request.RequestAttributes) (bool, error) {
+	return slices.Contains(f.validatorsFor(actor), validatorName), nil
+}
+
+// validatorsFor returns the validators the actor may use
+func (f *StaticValidatorFilter) validatorsFor(actor *Result) []string {
+	if actor == nil {
+		actor = AnonymousResult()
+	}
+	for i := range f.mappings {
+		if f.mappings[i].matches(actor) {
+			return f.mappings[i].Validators
+		}
+	}
+	return f.defaultValidators
+}
+
+// ValidatorNames returns the names of all validators the filter refers to,
+// sorted and without duplicates
+func (f *StaticValidatorFilter) ValidatorNames() []string {
+	names := slices.Clone(f.defaultValidators)
+	for _, m := range f.mappings {
+		names = append(names, m.Validators...)
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
@@ -0,0 +1,116 @@
+package trust
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/project-kessel/parsec/internal/request"
+)
+
+func TestStaticValidatorFilter_IsAllowed(t *testing.T) {
+	filter := NewStaticValidatorFilter([]ValidatorMapping{
+		{TrustDomain: "prod", Subject: "spiffe://prod/ns/payments/*", Validators: []string{"payments-idp", "customer-idp"}},
+		{TrustDomain: "prod", Validators: []string{"customer-idp"}},
+		{Subject: "admin", Validators: []string{"admin-idp"}},
+	}, []string{"public-idp"})
+
+	tests := []struct {
+		name          string
+		actor         *Result
+		validatorName string
+		wantAllowed   bool
+	}{
+		{
+			name:          "subject prefix match",
+			actor:         &Result{Subject: "spiffe://prod/ns/payments/sa/api", TrustDomain: "prod"},
+			validatorName: "payments-idp",
+			wantAllowed:   true,
+		},
+		{
+			name:          "first match wins",
+			actor:         &Result{Subject: "spiffe://prod/ns/payments/sa/api", TrustDomain: "prod"},
+			validatorName: "public-idp",
+			wantAllowed:   false,
+		},
+		{
+			name:          "trust domain match",
+			actor:         &Result{Subject: "spiffe://prod/ns/orders/sa/api", TrustDomain: "prod"},
+			validatorName: "customer-idp",
+			wantAllowed:   true,
+		},
+		{
+			name:          "trust domain match denies other validators",
+			actor:         &Result{Subject: "spiffe://prod/ns/orders/sa/api", TrustDomain: "prod"},
+			validatorName: "payments-idp",
+			wantAllowed:   false,
+		},
+		{
+			name:          "exact subject match in any trust domain",
+			actor:         &Result{Subject: "admin", TrustDomain: "corp"},
+			validatorName: "admin-idp",
+			wantAllowed:   true,
+		},
+		{
+			name:          "exact subject does not match prefix",
+			actor:         &Result{Subject: "administrator", TrustDomain: "corp"},
+			validatorName: "admin-idp",
+			wantAllowed:   false,
+		},
+		{
+			name:          "default validators",
+			actor:         &Result{Subject: "svc", TrustDomain: "dev"},
+			validatorName: "public-idp",
+			wantAllowed:   true,
+		},
+		{
+			name:          "anonymous actor gets default validators",
+			validatorName: "public-idp",
+			wantAllowed:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, err := filter.IsAllowed(tt.actor, tt.validatorName, nil)
+			if err != nil {
+				t.Fatalf("IsAllowed failed: %v", err)
+			}
+			if allowed != tt.wantAllowed {
+				t.Errorf("IsAllowed() = %v, want %v", allowed, tt.wantAllowed)
+			}
+		})
+	}
+}
+
+func TestStaticValidatorFilter_NoDefault(t *testing.T) {
+	filter := NewStaticValidatorFilter([]ValidatorMapping{
+		{TrustDomain: "prod", Validators: []string{"customer-idp"}},
+	}, nil)
+
+	store, err := NewFilteredStore(WithValidatorFilter(filter))
+	if err != nil {
+		t.Fatalf("NewFilteredStore failed: %v", err)
+	}
+	store.AddValidator("customer-idp", NewStubValidator(CredentialTypeBearer))
+
+	scoped, err := store.ForActor(context.Background(), &Result{Subject: "svc", TrustDomain: "dev"}, &request.RequestAttributes{})
+	if err != nil {
+		t.Fatalf("ForActor failed: %v", err)
+	}
+	if _, err := scoped.Validate(context.Background(), &BearerCredential{Token: "token"}); err == nil {
+		t.Error("expected actors matching no mapping to have no validators")
+	}
+}
+
+func TestStaticValidatorFilter_ValidatorNames(t *testing.T) {
+	filter := NewStaticValidatorFilter([]ValidatorMapping{
+		{TrustDomain: "prod", Validators: []string{"customer-idp", "payments-idp"}},
+		{Subject: "admin", Validators: []string{"admin-idp", "customer-idp"}},
+	}, []string{"public-idp"})
+
+	want := []string{"admin-idp", "customer-idp", "payments-idp", "public-idp"}
+	if got := filter.ValidatorNames(); !slices.Equal(got, want) {
+		t.Errorf("ValidatorNames() = %v, want %v", got, want)
+	}
+}
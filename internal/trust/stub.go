@@ -14,6 +14,11 @@ import (
 type StubStore struct {
 	// Index validators by credential type for fast lookup
 	validatorsByType map[CredentialType][]Validator
+	// All validators in order
+	validators []Validator
+
+	// resultCache caches successful validations (optional)
+	resultCache *ResultCache
 }
 
 // NewStubStore creates a new stub trust store
@@ -29,6 +34,18 @@ func (s *StubStore) AddValidator(v Validator) *StubStore {
 	for _, credType := range v.CredentialTypes() {
 		s.validatorsByType[credType] = append(s.validatorsByType[credType], v)
 	}
+	s.validators = append(s.validators, v)
+	return s
+}
+
+// Validators returns all validators in the store
+func (s *StubStore) Validators() []Validator {
+	return s.validators
+}
+
+// WithResultCache caches the store's successful validations in cache
+func (s *StubStore) WithResultCache(cache *ResultCache) *StubStore {
+	s.resultCache = cache
 	return s
 }
 
@@ -40,7 +57,15 @@ func (s *StubStore) Validate(ctx context.Context, credential Credential) (*Resul
 	// Look up validators for this credential type
 	validators, ok := s.validatorsByType[credType]
 	if !ok || len(validators) == 0 {
-		return nil, fmt.Errorf("no validator found for credential type %s", credType)
+		return nil, fmt.Errorf("%w %s", ErrNoValidator, credType)
+	}
+
+	if s.resultCache != nil {
+		if result, ok := s.resultCache.get(credential, func(v Validator) bool {
+			return slices.Contains(validators, v)
+		}); ok {
+			return result, nil
+		}
 	}
 
 	// Try validators in order until one succeeds
@@ -48,6 +73,9 @@ func (s *StubStore) Validate(ctx context.Context, credential Credential) (*Resul
 	for _, v := range validators {
 		result, err := v.Validate(ctx, credential)
 		if err == nil {
+			if s.resultCache != nil {
+				s.resultCache.put(credential, v, result)
+			}
 			return result, nil
 		}
 
@@ -120,15 +148,15 @@ func (v *StubValidator) Validate(ctx context.Context, credential Credential) (*R
 	switch cred := credential.(type) {
 	case *BearerCredential:
 		if cred.Token == "" {
-			return nil, fmt.Errorf("empty token")
+			return nil, fmt.Errorf("%w: empty token", ErrInvalidToken)
 		}
 	case *JWTCredential:
 		if cred.Token == "" {
-			return nil, fmt.Errorf("empty token")
+			return nil, fmt.Errorf("%w: empty token", ErrInvalidToken)
 		}
 	case *OIDCCredential:
 		if cred.Token == "" {
-			return nil, fmt.Errorf("empty token")
+			return nil, fmt.Errorf("%w: empty token", ErrInvalidToken)
 		}
 	default:
 		// For other credential types, just validate the type is supported
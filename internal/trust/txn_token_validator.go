@@ -0,0 +1,178 @@
+package trust
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+
+	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/trustdomain"
+)
+
+// KeySource provides the public keys a locally issued token is verified with
+type KeySource interface {
+	// KeySet returns the current verification keys
+	// Keys must carry a key ID and algorithm.
+	KeySet(ctx context.Context) (jwk.Set, error)
+}
+
+// TransactionTokenValidator validates transaction tokens issued by parsec itself,
+// so a service can exchange a transaction token it received for a new one
+//
+// Signatures are verified against parsec's own signing keys rather than a
+// fetched JWKS. The result's claims carry the original "txn" and "tctx", which
+// the transaction token issuer preserves in the replacement token.
+type TransactionTokenValidator struct {
+	issuer      string
+	trustDomain string
+	audiences   []string
+	keys        KeySource
+	clock       clock.Clock
+	leeway      time.Duration
+}
+
+// TransactionTokenValidatorConfig contains configuration for transaction token validation
+type TransactionTokenValidatorConfig struct {
+	// Issuer is the issuer URL of parsec's transaction token issuer (iss claim)
+	Issuer string
+
+	// TrustDomain is the trust domain of the validated subjects
+	TrustDomain string
+
+	// Audiences are the accepted audiences, or patterns such as
+	// "*.example.com" (see trustdomain.Match); at least one must match
+	// At least one audience is required.
+	Audiences []string
+
+	// Keys provides parsec's public signing keys
+	Keys KeySource
+
+	// Clock is the time source for token validation
+	// If nil, uses system clock
+	Clock clock.Clock
+
+	// Leeway is the clock skew tolerated when checking exp, nbf and iat
+	Leeway time.Duration
+}
+
+// NewTransactionTokenValidator creates a new transaction token validator
+func NewTransactionTokenValidator(cfg TransactionTokenValidatorConfig) (*TransactionTokenValidator, error) {
+	if cfg.Issuer == "" {
+		return nil, fmt.Errorf("issuer is required")
+	}
+	if cfg.Keys == nil {
+		return nil, fmt.Errorf("key source is required")
+	}
+	if len(cfg.Audiences) == 0 {
+		return nil, fmt.Errorf("at least one audience is required")
+	}
+
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	return &TransactionTokenValidator{
+		issuer:      cfg.Issuer,
+		trustDomain: cfg.TrustDomain,
+		audiences:   cfg.Audiences,
+		keys:        cfg.Keys,
+		clock:       clk,
+		leeway:      cfg.Leeway,
+	}, nil
+}
+
+// CredentialTypes returns the credential types this validator can handle
+func (v *TransactionTokenValidator) CredentialTypes() []CredentialType {
+	return []CredentialType{CredentialTypeJWT, CredentialTypeBearer}
+}
+
+// Validate validates a transaction token issued by parsec
+func (v *TransactionTokenValidator) Validate(ctx context.Context, credential Credential) (*Result, error) {
+	var tokenString string
+	switch cred := credential.(type) {
+	case *JWTCredential:
+		tokenString = cred.Token
+	case *BearerCredential:
+		tokenString = cred.Token
+	default:
+		return nil, fmt.Errorf("unsupported credential type for transaction token validator: %T", credential)
+	}
+
+	keySet, err := v.keys.KeySet(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signing keys: %w", err)
+	}
+
+	token, err := jwt.Parse(
+		[]byte(tokenString),
+		jwt.WithKeySet(keySet),
+		jwt.WithValidate(true),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithClock(jwt.ClockFunc(func() time.Time {
+			return v.clock.Now()
+		})),
+		jwt.WithAcceptableSkew(v.leeway),
+	)
+	if err != nil {
+		if errors.Is(err, jwt.TokenExpiredError()) {
+			return nil, ErrExpiredToken
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	subject, ok := token.Subject()
+	if !ok || subject == "" {
+		return nil, fmt.Errorf("%w: missing subject claim", ErrInvalidToken)
+	}
+	var txn string
+	if err := token.Get("txn", &txn); err != nil || txn == "" {
+		return nil, fmt.Errorf("%w: missing txn claim", ErrInvalidToken)
+	}
+	audiences, _ := token.Audience()
+	if !slices.ContainsFunc(audiences, func(aud string) bool {
+		return trustdomain.MatchAny(v.audiences, aud)
+	}) {
+		return nil, fmt.Errorf("%w: audience %v not accepted", ErrInvalidToken, audiences)
+	}
+
+	claimsMap := make(claims.Claims)
+	serialized, err := json.Marshal(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize token claims: %w", err)
+	}
+	if err := json.Unmarshal(serialized, &claimsMap); err != nil {
+		return nil, fmt.Errorf("failed to parse token claims: %w", err)
+	}
+
+	scope := ""
+	if err := token.Get("scope", &scope); err != nil {
+		scope = ""
+	}
+
+	expiresAt, _ := token.Expiration()
+	issuedAt, _ := token.IssuedAt()
+	transactionContext, _ := claimsMap.Get("tctx").(map[string]any)
+
+	return &Result{
+		Subject:     subject,
+		Issuer:      v.issuer,
+		TrustDomain: v.trustDomain,
+		Claims:      claimsMap,
+		ExpiresAt:   expiresAt,
+		IssuedAt:    issuedAt,
+		Audience:    audiences,
+		Scope:       scope,
+		Transaction: &TransactionLineage{
+			TxnID:   txn,
+			Context: transactionContext,
+		},
+	}, nil
+}
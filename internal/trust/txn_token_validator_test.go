@@ -0,0 +1,158 @@
+package trust
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+
+	"github.com/project-kessel/parsec/internal/perr"
+)
+
+// staticKeySource serves a fixed JWK set
+type staticKeySource struct {
+	set jwk.Set
+}
+
+func (s staticKeySource) KeySet(context.Context) (jwk.Set, error) {
+	return s.set, nil
+}
+
+func TestTransactionTokenValidator_Validate(t *testing.T) {
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signingKey, err := jwk.Import(privateKey)
+	if err != nil {
+		t.Fatalf("failed to import key: %v", err)
+	}
+	_ = signingKey.Set(jwk.KeyIDKey, "parsec-1")
+	publicKey, err := signingKey.PublicKey()
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	_ = publicKey.Set(jwk.AlgorithmKey, jwa.ES256())
+	set := jwk.NewSet()
+	_ = set.AddKey(publicKey)
+
+	sign := func(t *testing.T, claims map[string]any) string {
+		t.Helper()
+		token := jwt.New()
+		for k, v := range claims {
+			if err := token.Set(k, v); err != nil {
+				t.Fatalf("failed to set %s: %v", k, err)
+			}
+		}
+		signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256(), signingKey))
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return string(signed)
+	}
+
+	validator, err := NewTransactionTokenValidator(TransactionTokenValidatorConfig{
+		Issuer:      "https://parsec.test",
+		TrustDomain: "parsec.test",
+		Audiences:   []string{"parsec.test"},
+		Keys:        staticKeySource{set},
+	})
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	valid := func() map[string]any {
+		return map[string]any{
+			"iss":  "https://parsec.test",
+			"sub":  "alice",
+			"aud":  []string{"parsec.test"},
+			"exp":  time.Now().Add(time.Minute).Unix(),
+			"txn":  "0190c6f4-0000-7000-8000-000000000000",
+			"tctx": map[string]any{"tenant": "acme"},
+		}
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		result, err := validator.Validate(ctx, &BearerCredential{Token: sign(t, valid())})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Subject != "alice" || result.Issuer != "https://parsec.test" || result.TrustDomain != "parsec.test" {
+			t.Errorf("unexpected result: %+v", result)
+		}
+		if result.Claims.GetString("txn") == "" {
+			t.Error("expected txn claim in result")
+		}
+		if tctx, _ := result.Claims.Get("tctx").(map[string]any); tctx["tenant"] != "acme" {
+			t.Errorf("expected tctx in result, got %v", result.Claims.Get("tctx"))
+		}
+		if result.Transaction == nil || result.Transaction.TxnID != "0190c6f4-0000-7000-8000-000000000000" || result.Transaction.Context["tenant"] != "acme" {
+			t.Errorf("expected transaction lineage in result, got %+v", result.Transaction)
+		}
+	})
+
+	t.Run("audiences are required", func(t *testing.T) {
+		_, err := NewTransactionTokenValidator(TransactionTokenValidatorConfig{
+			Issuer:      "https://parsec.test",
+			TrustDomain: "parsec.test",
+			Keys:        staticKeySource{set},
+		})
+		if err == nil {
+			t.Error("expected error without audiences")
+		}
+	})
+
+	tests := []struct {
+		name   string
+		mutate func(claims map[string]any)
+	}{
+		{name: "other issuer", mutate: func(c map[string]any) { c["iss"] = "https://idp.example.com" }},
+		{name: "missing txn", mutate: func(c map[string]any) { delete(c, "txn") }},
+		{name: "other audience", mutate: func(c map[string]any) { c["aud"] = []string{"elsewhere"} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.mutate(claims)
+			_, err := validator.Validate(ctx, &BearerCredential{Token: sign(t, claims)})
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+
+	t.Run("expired token", func(t *testing.T) {
+		claims := valid()
+		claims["exp"] = time.Now().Add(-time.Minute).Unix()
+		_, err := validator.Validate(ctx, &BearerCredential{Token: sign(t, claims)})
+		if !perr.HasCode(err, perr.ErrCodeExpiredToken) {
+			t.Errorf("expected expired token, got %v", err)
+		}
+	})
+
+	t.Run("token signed by another key", func(t *testing.T) {
+		otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		other, _ := jwk.Import(otherKey)
+		_ = other.Set(jwk.KeyIDKey, "parsec-1")
+		token := jwt.New()
+		for k, v := range valid() {
+			_ = token.Set(k, v)
+		}
+		signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256(), other))
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		if _, err := validator.Validate(ctx, &BearerCredential{Token: string(signed)}); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+}
@@ -2,16 +2,20 @@ package trust
 
 import (
 	"context"
-	"errors"
 	"time"
 
 	"github.com/project-kessel/parsec/internal/claims"
+	"github.com/project-kessel/parsec/internal/perr"
 )
 
 // Common validation errors
 var (
-	ErrInvalidToken = errors.New("invalid token")
-	ErrExpiredToken = errors.New("token expired")
+	ErrInvalidToken = perr.New(perr.ErrCodeInvalidToken, "invalid token")
+	ErrExpiredToken = perr.New(perr.ErrCodeExpiredToken, "token expired")
+
+	// ErrNoValidator is returned by stores without a validator for the
+	// credential's type
+	ErrNoValidator = perr.New(perr.ErrCodeInvalidToken, "invalid token: no validator for the credential type")
 )
 
 // Validator validates external credentials and returns claims about the authenticated subject
@@ -53,6 +57,20 @@ type Result struct {
 
 	// Scope is the OAuth2 scope if applicable
 	Scope string `json:"scope,omitempty"`
+
+	// Transaction is the lineage of a validated parsec transaction token.
+	// Only TransactionTokenValidator sets it, so the transaction token issuer
+	// can tell its own tokens from others carrying the same claims.
+	Transaction *TransactionLineage `json:"-"`
+}
+
+// TransactionLineage is the transaction a parsec transaction token belongs to
+type TransactionLineage struct {
+	// TxnID is the "txn" claim of the transaction token
+	TxnID string
+
+	// Context is the "tctx" claim of the transaction token
+	Context claims.Claims
 }
 
 // AnonymousResult returns a Result representing an anonymous/unauthenticated actor
@@ -71,6 +89,8 @@ const (
 	CredentialTypeMTLS   CredentialType = "mtls"
 	CredentialTypeOAuth2 CredentialType = "oauth2"
 	CredentialTypeJSON   CredentialType = "json"
+
+	CredentialTypeClientSecret CredentialType = "client_secret"
 )
 
 // Credential is the interface for all credential types
@@ -108,6 +128,9 @@ type OIDCCredential struct {
 	Token          string
 	IssuerIdentity string // Parsed from JWT "iss" claim
 	ClientID       string
+
+	// Nonce is the nonce the ID token must carry (not checked if empty)
+	Nonce string
 }
 
 func (c *OIDCCredential) Type() CredentialType {
@@ -135,6 +158,17 @@ func (c *MTLSCredential) Type() CredentialType {
 	return CredentialTypeMTLS
 }
 
+// ClientSecretCredential represents a client authenticating with its ID and
+// secret, e.g. with HTTP basic authentication (RFC 6749, section 2.3.1)
+type ClientSecretCredential struct {
+	ClientID     string
+	ClientSecret string
+}
+
+func (c *ClientSecretCredential) Type() CredentialType {
+	return CredentialTypeClientSecret
+}
+
 // JSONCredential represents an unsigned JSON credential with a well-defined structure
 // This is used for pre-validated or self-asserted credentials where the structure
 // follows the Result format
@@ -0,0 +1,191 @@
+package trust
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Warmer is a validator that fetches what it validates with (e.g. a JWKS)
+// ahead of the first validation
+type Warmer interface {
+	Warm(ctx context.Context) error
+}
+
+// Warmers returns the validators of a store that can be warmed, by name
+// The validators of a StubStore are unnamed, so they are named by position.
+func Warmers(store Store) map[string]Warmer {
+	warmers := make(map[string]Warmer)
+	switch s := store.(type) {
+	case *FilteredStore:
+		for _, nv := range s.Validators() {
+			if warmer, ok := asWarmer(nv.Validator); ok {
+				warmers[nv.Name] = warmer
+			}
+		}
+	case *StubStore:
+		for i, v := range s.Validators() {
+			if warmer, ok := asWarmer(v); ok {
+				warmers[fmt.Sprintf("validators[%d]", i)] = warmer
+			}
+		}
+	}
+	return warmers
+}
+
+// asWarmer returns the validator, or the validator a HookedValidator wraps,
+// if it can be warmed
+func asWarmer(v Validator) (Warmer, bool) {
+	if hooked, ok := v.(*HookedValidator); ok {
+		v = hooked.Unwrap()
+	}
+	warmer, ok := v.(Warmer)
+	return warmer, ok
+}
+
+// WarmupConfig configures how validators are warmed
+type WarmupConfig struct {
+	// Attempts bounds how often each validator is warmed (default: 3)
+	Attempts int
+
+	// Backoff is the wait before the second attempt, doubling for each
+	// further attempt (default: 1 second)
+	Backoff time.Duration
+
+	// AttemptTimeout bounds each attempt (default: 10 seconds)
+	AttemptTimeout time.Duration
+}
+
+// WarmupState is the state of a validator being warmed
+type WarmupState string
+
+const (
+	WarmupStateWarming WarmupState = "warming"
+	WarmupStateReady   WarmupState = "ready"
+	WarmupStateFailed  WarmupState = "failed"
+)
+
+// WarmupStatus is the status of one validator being warmed
+type WarmupStatus struct {
+	State    WarmupState `json:"state"`
+	Attempts int         `json:"attempts"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// Warmup warms validators in parallel, retrying failures, and reports their status
+type Warmup struct {
+	mu     sync.Mutex
+	status map[string]WarmupStatus
+	errs   map[string]error
+	done   chan struct{}
+}
+
+// StartWarmup starts warming the named validators in the background
+func StartWarmup(ctx context.Context, cfg WarmupConfig, warmers map[string]Warmer) *Warmup {
+	if cfg.Attempts <= 0 {
+		cfg.Attempts = 3
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.AttemptTimeout <= 0 {
+		cfg.AttemptTimeout = 10 * time.Second
+	}
+
+	w := &Warmup{
+		status: make(map[string]WarmupStatus, len(warmers)),
+		errs:   make(map[string]error),
+		done:   make(chan struct{}),
+	}
+	for name := range warmers {
+		w.status[name] = WarmupStatus{State: WarmupStateWarming}
+	}
+
+	var wg sync.WaitGroup
+	for name, warmer := range warmers {
+		wg.Go(func() { w.warm(ctx, cfg, name, warmer) })
+	}
+	go func() {
+		wg.Wait()
+		close(w.done)
+	}()
+	return w
+}
+
+// warm warms one validator until it succeeds or runs out of attempts
+func (w *Warmup) warm(ctx context.Context, cfg WarmupConfig, name string, warmer Warmer) {
+	backoff := cfg.Backoff
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, cfg.AttemptTimeout)
+		err := warmer.Warm(attemptCtx)
+		cancel()
+
+		if err == nil {
+			w.record(name, WarmupStatus{State: WarmupStateReady, Attempts: attempt}, nil)
+			return
+		}
+		if attempt == cfg.Attempts || ctx.Err() != nil {
+			w.record(name, WarmupStatus{State: WarmupStateFailed, Attempts: attempt, Error: err.Error()}, err)
+			return
+		}
+		w.record(name, WarmupStatus{State: WarmupStateWarming, Attempts: attempt, Error: err.Error()}, nil)
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			w.record(name, WarmupStatus{State: WarmupStateFailed, Attempts: attempt, Error: err.Error()}, err)
+			return
+		}
+	}
+}
+
+func (w *Warmup) record(name string, status WarmupStatus, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status[name] = status
+	if err != nil {
+		w.errs[name] = err
+	}
+}
+
+// Wait waits until every validator is warmed or has failed, returning the
+// failures, or until ctx is done
+func (w *Warmup) Wait(ctx context.Context) error {
+	select {
+	case <-w.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(w.errs)) {
+		errs = append(errs, fmt.Errorf("validator %s: %w", name, w.errs[name]))
+	}
+	return errors.Join(errs...)
+}
+
+// Ready reports whether every validator is warmed
+func (w *Warmup) Ready() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, status := range w.status {
+		if status.State != WarmupStateReady {
+			return false
+		}
+	}
+	return true
+}
+
+// Status returns the status of each validator by name
+func (w *Warmup) Status() map[string]WarmupStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return maps.Clone(w.status)
+}
@@ -0,0 +1,128 @@
+package trust
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyWarmer fails its first warms, optionally blocking each until released
+type flakyWarmer struct {
+	*StubValidator
+	failures int32
+	calls    atomic.Int32
+	started  chan struct{}
+	release  chan struct{}
+}
+
+func (w *flakyWarmer) Warm(ctx context.Context) error {
+	n := w.calls.Add(1)
+	if w.started != nil {
+		w.started <- struct{}{}
+		<-w.release
+	}
+	if n <= w.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func newFlakyWarmer(failures int32) *flakyWarmer {
+	return &flakyWarmer{StubValidator: NewStubValidator(CredentialTypeBearer), failures: failures}
+}
+
+func TestWarmup(t *testing.T) {
+	ctx := context.Background()
+	cfg := WarmupConfig{Attempts: 3, Backoff: time.Millisecond}
+
+	t.Run("retries until warmed", func(t *testing.T) {
+		warmer := newFlakyWarmer(2)
+		warmup := StartWarmup(ctx, cfg, map[string]Warmer{"idp": warmer})
+
+		if err := warmup.Wait(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !warmup.Ready() {
+			t.Error("expected ready")
+		}
+		if status := warmup.Status()["idp"]; status.State != WarmupStateReady || status.Attempts != 3 {
+			t.Errorf("expected ready after 3 attempts, got %+v", status)
+		}
+	})
+
+	t.Run("fails after the last attempt", func(t *testing.T) {
+		warmup := StartWarmup(ctx, cfg, map[string]Warmer{
+			"down": newFlakyWarmer(3),
+			"up":   newFlakyWarmer(0),
+		})
+
+		err := warmup.Wait(ctx)
+		if err == nil || !strings.Contains(err.Error(), "validator down") {
+			t.Fatalf("expected the failed validator, got %v", err)
+		}
+		if warmup.Ready() {
+			t.Error("expected not ready")
+		}
+		status := warmup.Status()
+		if status["down"].State != WarmupStateFailed || status["down"].Error == "" {
+			t.Errorf("expected down to have failed, got %+v", status["down"])
+		}
+		if status["up"].State != WarmupStateReady {
+			t.Errorf("expected up to be ready, got %+v", status["up"])
+		}
+	})
+
+	t.Run("warms validators in parallel", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		first, second := newFlakyWarmer(0), newFlakyWarmer(0)
+		for _, w := range []*flakyWarmer{first, second} {
+			w.started, w.release = started, release
+		}
+		warmup := StartWarmup(ctx, cfg, map[string]Warmer{"first": first, "second": second})
+
+		// Both warms start before either finishes
+		for range 2 {
+			select {
+			case <-started:
+			case <-time.After(5 * time.Second):
+				t.Fatal("expected both validators to be warming")
+			}
+		}
+		if warmup.Ready() || warmup.Status()["first"].State != WarmupStateWarming {
+			t.Errorf("expected warming, got %+v", warmup.Status())
+		}
+		close(release)
+		if err := warmup.Wait(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("nothing to warm is ready", func(t *testing.T) {
+		warmup := StartWarmup(ctx, cfg, nil)
+		if err := warmup.Wait(ctx); err != nil || !warmup.Ready() {
+			t.Errorf("expected ready, got %v", err)
+		}
+	})
+}
+
+func TestWarmers(t *testing.T) {
+	warmer := newFlakyWarmer(0)
+
+	stub := NewStubStore().AddValidator(NewStubValidator(CredentialTypeBearer)).AddValidator(warmer)
+	if warmers := Warmers(stub); len(warmers) != 1 || warmers["validators[1]"] != warmer {
+		t.Errorf("expected validators[1], got %v", warmers)
+	}
+
+	filtered, err := NewFilteredStore()
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	filtered.AddValidator("stub", NewStubValidator(CredentialTypeBearer)).AddValidator("idp", warmer)
+	if warmers := Warmers(filtered); len(warmers) != 1 || warmers["idp"] != warmer {
+		t.Errorf("expected idp, got %v", warmers)
+	}
+}
@@ -0,0 +1,114 @@
+// Package trustdomain matches trust domains and audiences against patterns,
+// so one rule can cover a family of trust domains rather than each being
+// listed.
+//
+// A pattern is either:
+//
+//   - a trust domain, matching it exactly, e.g. "example.com"
+//   - "*." followed by a trust domain, matching any trust domain under it at
+//     any depth, but not the trust domain itself, e.g. "*.example.com"
+//     matches "orders.example.com" and "eu.orders.example.com"
+//   - a prefix followed by "*", matching values starting with the prefix,
+//     e.g. "https://billing.example.com/*"
+//   - "*", matching any non-empty value
+//
+// Trust domains are DNS names, so matching ignores case.
+package trustdomain
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// Match reports whether value matches pattern
+// An empty pattern matches any value, as rules leaving the trust domain unset
+// apply to every trust domain.
+func Match(pattern, value string) bool {
+	switch {
+	case pattern == "":
+		return true
+	case value == "":
+		return false
+	case pattern == "*":
+		return true
+	}
+	pattern, value = strings.ToLower(pattern), strings.ToLower(value)
+	if parent, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(value, "."+parent)
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(value, prefix)
+	}
+	return pattern == value
+}
+
+// MatchAny reports whether value matches any of patterns
+// Unlike Match, no patterns match no value.
+func MatchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if pattern != "" && Match(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPattern reports whether pattern matches more than one value
+func IsPattern(pattern string) bool {
+	return strings.Contains(pattern, "*")
+}
+
+// ValidatePattern checks that pattern only has a wildcard where Match honors
+// one, so that e.g. "api.*.example.com" is rejected rather than matched
+// literally
+func ValidatePattern(pattern string) error {
+	rest := strings.TrimPrefix(pattern, "*.")
+	rest = strings.TrimSuffix(rest, "*")
+	if strings.Contains(rest, "*") || pattern == "*." {
+		return fmt.Errorf("invalid pattern %q: a wildcard may only lead as \"*.\" or trail", pattern)
+	}
+	return nil
+}
+
+// ValidatePatterns checks each of patterns with ValidatePattern
+func ValidatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if err := ValidatePattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CELLibrary provides trust domain matching to CEL expressions:
+//
+//   - trustDomainMatches(value, pattern) - whether value matches pattern, e.g.
+//     trustDomainMatches(actor.trust_domain, "*.example.com")
+func CELLibrary() cel.EnvOption {
+	return cel.Lib(&celLib{})
+}
+
+type celLib struct{}
+
+func (lib *celLib) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Function("trustDomainMatches",
+			cel.Overload("trustDomainMatches_string_string",
+				[]*cel.Type{cel.StringType, cel.StringType},
+				cel.BoolType,
+				cel.BinaryBinding(func(value, pattern ref.Val) ref.Val {
+					// An empty pattern matches nothing here, unlike in rules
+					return types.Bool(MatchAny([]string{string(pattern.(types.String))}, string(value.(types.String))))
+				}),
+			),
+		),
+	}
+}
+
+func (lib *celLib) ProgramOptions() []cel.ProgramOption {
+	return []cel.ProgramOption{}
+}
@@ -0,0 +1,82 @@
+package trustdomain
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{"", "example.com", true},
+		{"example.com", "example.com", true},
+		{"example.com", "EXAMPLE.com", true},
+		{"example.com", "orders.example.com", false},
+		{"*.example.com", "orders.example.com", true},
+		{"*.example.com", "eu.orders.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "badexample.com", false},
+		{"https://billing.example.com/*", "https://billing.example.com/invoices", true},
+		{"https://billing.example.com/*", "https://orders.example.com/", false},
+		{"*", "example.com", true},
+		{"*", "", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.value); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	if MatchAny(nil, "example.com") {
+		t.Error("expected no patterns to match nothing")
+	}
+	if MatchAny([]string{""}, "example.com") {
+		t.Error("expected an empty pattern in a list to match nothing")
+	}
+	if !MatchAny([]string{"parsec.test", "*.example.com"}, "orders.example.com") {
+		t.Error("expected the wildcard pattern to match")
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	for _, pattern := range []string{"", "example.com", "*.example.com", "https://example.com/*", "*"} {
+		if err := ValidatePattern(pattern); err != nil {
+			t.Errorf("ValidatePattern(%q) unexpected error: %v", pattern, err)
+		}
+	}
+	for _, pattern := range []string{"api.*.example.com", "*example.com", "*.", "**.example.com"} {
+		if err := ValidatePattern(pattern); err == nil {
+			t.Errorf("ValidatePattern(%q) expected an error", pattern)
+		}
+	}
+}
+
+func TestCELLibrary(t *testing.T) {
+	env, err := cel.NewEnv(CELLibrary(), cel.Variable("trust_domain", cel.StringType))
+	if err != nil {
+		t.Fatalf("failed to create CEL environment: %v", err)
+	}
+	ast, issues := env.Compile(`trustDomainMatches(trust_domain, "*.example.com") && !trustDomainMatches(trust_domain, "")`)
+	if issues.Err() != nil {
+		t.Fatalf("failed to compile: %v", issues.Err())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		t.Fatalf("failed to create program: %v", err)
+	}
+	for value, want := range map[string]bool{"orders.example.com": true, "example.com": false} {
+		out, _, err := program.Eval(map[string]any{"trust_domain": value})
+		if err != nil {
+			t.Fatalf("failed to evaluate: %v", err)
+		}
+		if out.Value() != want {
+			t.Errorf("trustDomainMatches(%q) = %v, want %v", value, out.Value(), want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ActorCredentials provides the credential a client authenticates with
+type ActorCredentials interface {
+	// Token returns the current credential
+	Token(ctx context.Context) (string, error)
+}
+
+// ActorCredentialsFunc adapts a function to ActorCredentials
+type ActorCredentialsFunc func(ctx context.Context) (string, error)
+
+// Token implements ActorCredentials
+func (f ActorCredentialsFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// StaticToken is a credential that never changes
+type StaticToken string
+
+// Token implements ActorCredentials
+func (t StaticToken) Token(ctx context.Context) (string, error) {
+	if t == "" {
+		return "", fmt.Errorf("static token is empty")
+	}
+	return string(t), nil
+}
+
+// TokenFile reads the credential from a file on every request, so tokens
+// rotated in place (such as Kubernetes projected service account tokens)
+// are picked up without restarting
+func TokenFile(path string) ActorCredentials {
+	return ActorCredentialsFunc(func(ctx context.Context) (string, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token file: %w", err)
+		}
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("token file %s is empty", path)
+		}
+		return token, nil
+	})
+}
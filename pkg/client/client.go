@@ -0,0 +1,370 @@
+// Package client is a Go client for parsec's token exchange API.
+//
+// A Client exchanges credentials for parsec-issued tokens over HTTP or gRPC,
+// attaching the caller's actor credential to every request and retrying
+// transient failures with backoff:
+//
+//	c, err := client.New(client.Config{
+//		URL:              "https://parsec.example.com",
+//		ActorCredentials: client.TokenFile("/var/run/secrets/tokens/parsec"),
+//	})
+//	token, err := c.Exchange(ctx, client.ExchangeRequest{
+//		SubjectToken: userToken,
+//		Audience:     "parsec.example.com",
+//	})
+//	claims, err := token.Claims()
+//
+// Services receiving parsec tokens verify them against parsec's published keys
+// with Client.Verify, or with package verify when they only receive tokens.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	parsecv1 "github.com/project-kessel/parsec/api/gen/parsec/v1"
+	"github.com/project-kessel/parsec/pkg/verify"
+)
+
+const (
+	// GrantTypeTokenExchange is the OAuth 2.0 token exchange grant (RFC 8693)
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	// TokenTypeTransactionToken is a transaction token
+	TokenTypeTransactionToken = "urn:ietf:params:oauth:token-type:txn_token"
+
+	// TokenTypeAccessToken is an OAuth 2.0 access token
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+
+	// TokenTypeJWT is a JWT
+	TokenTypeJWT = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// Config configures a Client
+type Config struct {
+	// URL is parsec's HTTP base URL, e.g. https://parsec.example.com
+	// Required unless GRPCConn is set.
+	URL string
+
+	// GRPCConn sends requests over gRPC instead of HTTP (optional)
+	// The connection is owned by the caller.
+	GRPCConn grpc.ClientConnInterface
+
+	// HTTPClient sends HTTP requests (default: http.DefaultClient)
+	HTTPClient *http.Client
+
+	// ActorCredentials authenticates the caller as the actor of every
+	// exchange (optional). The credential is sent as a bearer Authorization.
+	ActorCredentials ActorCredentials
+
+	// Retry controls retries of transient failures (default: DefaultRetryPolicy)
+	Retry *RetryPolicy
+
+	// TrustDomain is the audience Verify requires
+	// Verify is unavailable without it.
+	TrustDomain string
+
+	// Leeway tolerates clock skew when Verify checks exp, nbf and iat
+	Leeway time.Duration
+
+	// JWKSRefreshInterval is how long Verify uses fetched keys before
+	// fetching them again (default: 5m). A token signed by an unknown key
+	// triggers a fetch sooner, at most once a minute.
+	JWKSRefreshInterval time.Duration
+}
+
+// Client exchanges credentials for parsec-issued tokens
+// It is safe for concurrent use.
+type Client struct {
+	url        string
+	exchanger  parsecv1.TokenExchangeServiceClient
+	jwksClient parsecv1.JWKSServiceClient
+	httpClient *http.Client
+	actor      ActorCredentials
+	retry      RetryPolicy
+	verifier   *verify.Verifier
+}
+
+// New creates a client
+func New(cfg Config) (*Client, error) {
+	if cfg.URL == "" && cfg.GRPCConn == nil {
+		return nil, fmt.Errorf("url or gRPC connection is required")
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	retry := DefaultRetryPolicy
+	if cfg.Retry != nil {
+		retry = cfg.Retry.withDefaults()
+	}
+
+	c := &Client{
+		url:        strings.TrimSuffix(cfg.URL, "/"),
+		httpClient: httpClient,
+		actor:      cfg.ActorCredentials,
+		retry:      retry,
+	}
+	if cfg.GRPCConn != nil {
+		c.exchanger = parsecv1.NewTokenExchangeServiceClient(cfg.GRPCConn)
+		c.jwksClient = parsecv1.NewJWKSServiceClient(cfg.GRPCConn)
+	}
+	if cfg.TrustDomain != "" {
+		verifier, err := verify.New(verify.Config{
+			Fetch:           c.fetchJWKS,
+			TrustDomain:     cfg.TrustDomain,
+			Leeway:          cfg.Leeway,
+			RefreshInterval: cfg.JWKSRefreshInterval,
+		})
+		if err != nil {
+			return nil, err
+		}
+		c.verifier = verifier
+	}
+	return c, nil
+}
+
+// ExchangeRequest is a token exchange request (RFC 8693 section 2.1)
+type ExchangeRequest struct {
+	// SubjectToken is the credential of the principal the token is issued for
+	SubjectToken string
+
+	// SubjectTokenType defaults to TokenTypeAccessToken
+	SubjectTokenType string
+
+	// RequestedTokenType defaults to TokenTypeTransactionToken
+	RequestedTokenType string
+
+	// Audience is the trust domain the token is for
+	Audience string
+
+	// Audiences are further audiences the token is for (optional)
+	// parsec only issues for audiences its configuration allows.
+	Audiences []string
+
+	// Scope requested for the token (optional)
+	Scope string
+
+	// Resource the token is for (optional)
+	Resource string
+
+	// RequestContext describes the request being made, e.g. its method and
+	// path, and is encoded as JSON (optional)
+	RequestContext map[string]any
+
+	// ActorToken is sent in the request body instead of the configured
+	// ActorCredentials header (optional)
+	ActorToken     string
+	ActorTokenType string
+}
+
+// Token is an issued token (RFC 8693 section 2.2.1)
+type Token struct {
+	// Value is the issued token
+	Value string
+
+	// IssuedTokenType is the type of the issued token
+	IssuedTokenType string
+
+	// TokenType is how the token is presented, e.g. "N_A" or "Bearer"
+	TokenType string
+
+	// ExpiresIn is the lifetime of the token
+	ExpiresIn time.Duration
+
+	// ExpiresAt is when the token expires, from the time it was received
+	ExpiresAt time.Time
+
+	// Scope of the token, when it differs from the requested scope
+	Scope string
+
+	// RejectedClaims are request context claims parsec dropped
+	RejectedClaims []string
+}
+
+// Claims decodes the token's claims without verifying it
+// Use Client.Verify for tokens received from others.
+func (t *Token) Claims() (*Claims, error) {
+	return ParseClaims(t.Value)
+}
+
+// Exchange exchanges a subject credential for a token
+func (c *Client) Exchange(ctx context.Context, req ExchangeRequest) (*Token, error) {
+	msg, err := req.message()
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *parsecv1.ExchangeResponse
+	err = c.withRetry(ctx, func(ctx context.Context) error {
+		var err error
+		if c.exchanger != nil {
+			resp, err = c.exchangeGRPC(ctx, msg)
+		} else {
+			resp, err = c.exchangeHTTP(ctx, msg)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	expiresIn := time.Duration(resp.GetExpiresIn()) * time.Second
+	return &Token{
+		Value:           resp.GetAccessToken(),
+		IssuedTokenType: resp.GetIssuedTokenType(),
+		TokenType:       resp.GetTokenType(),
+		ExpiresIn:       expiresIn,
+		ExpiresAt:       time.Now().Add(expiresIn),
+		Scope:           resp.GetScope(),
+		RejectedClaims:  resp.GetRejectedClaims(),
+	}, nil
+}
+
+// audience returns the audience parameter: Audience and Audiences, space-separated
+func (req ExchangeRequest) audience() string {
+	audiences := req.Audiences
+	if req.Audience != "" {
+		audiences = append([]string{req.Audience}, audiences...)
+	}
+	return strings.Join(audiences, " ")
+}
+
+// message builds the exchange request message, filling in defaults
+func (req ExchangeRequest) message() (*parsecv1.ExchangeRequest, error) {
+	if req.SubjectToken == "" {
+		return nil, fmt.Errorf("subject token is required")
+	}
+	msg := &parsecv1.ExchangeRequest{
+		GrantType:          GrantTypeTokenExchange,
+		SubjectToken:       req.SubjectToken,
+		SubjectTokenType:   req.SubjectTokenType,
+		RequestedTokenType: req.RequestedTokenType,
+		Audience:           req.audience(),
+		Scope:              req.Scope,
+		Resource:           req.Resource,
+		ActorToken:         req.ActorToken,
+		ActorTokenType:     req.ActorTokenType,
+	}
+	if msg.SubjectTokenType == "" {
+		msg.SubjectTokenType = TokenTypeAccessToken
+	}
+	if msg.RequestedTokenType == "" {
+		msg.RequestedTokenType = TokenTypeTransactionToken
+	}
+	if len(req.RequestContext) > 0 {
+		data, err := json.Marshal(req.RequestContext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request context: %w", err)
+		}
+		msg.RequestContext = string(data)
+	}
+	return msg, nil
+}
+
+func (c *Client) exchangeGRPC(ctx context.Context, msg *parsecv1.ExchangeRequest) (*parsecv1.ExchangeResponse, error) {
+	ctx, err := c.outgoingContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.exchanger.Exchange(ctx, msg)
+	if err != nil {
+		return nil, errorFromGRPC(err)
+	}
+	return resp, nil
+}
+
+func (c *Client) exchangeHTTP(ctx context.Context, msg *parsecv1.ExchangeRequest) (*parsecv1.ExchangeResponse, error) {
+	form := url.Values{}
+	for name, value := range map[string]string{
+		"grant_type":           msg.GrantType,
+		"subject_token":        msg.SubjectToken,
+		"subject_token_type":   msg.SubjectTokenType,
+		"requested_token_type": msg.RequestedTokenType,
+		"audience":             msg.Audience,
+		"scope":                msg.Scope,
+		"resource":             msg.Resource,
+		"actor_token":          msg.ActorToken,
+		"actor_token_type":     msg.ActorTokenType,
+		"request_context":      msg.RequestContext,
+	} {
+		if value != "" {
+			form.Set(name, value)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/v1/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if err := c.authorize(ctx, req.Header); err != nil {
+		return nil, err
+	}
+
+	var resp parsecv1.ExchangeResponse
+	if err := c.doHTTP(req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// authorize sets the actor credential on an HTTP request
+func (c *Client) authorize(ctx context.Context, header http.Header) error {
+	if c.actor == nil {
+		return nil
+	}
+	token, err := c.actor.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get actor credential: %w", err)
+	}
+	header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// outgoingContext attaches the actor credential to gRPC metadata
+func (c *Client) outgoingContext(ctx context.Context) (context.Context, error) {
+	if c.actor == nil {
+		return ctx, nil
+	}
+	token, err := c.actor.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get actor credential: %w", err)
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token), nil
+}
+
+// doHTTP sends a request and decodes a protobuf JSON response body
+// Responses other than 200 OK are returned as *Error.
+func (c *Client) doHTTP(req *http.Request, into proto.Message) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errorFromHTTP(resp, body)
+	}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(bytes.TrimSpace(body), into); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	parsecv1 "github.com/project-kessel/parsec/api/gen/parsec/v1"
+)
+
+// fastRetry retries without slowing tests down
+var fastRetry = &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+func TestClient_ExchangeHTTP(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/v1/token" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer actor-token" {
+			t.Errorf("expected the actor credential, got %q", got)
+		}
+		if got := r.PostForm.Get("grant_type"); got != GrantTypeTokenExchange {
+			t.Errorf("unexpected grant_type %q", got)
+		}
+		if got := r.PostForm.Get("requested_token_type"); got != TokenTypeTransactionToken {
+			t.Errorf("expected a transaction token by default, got %q", got)
+		}
+		if got := r.PostForm.Get("request_context"); got != `{"path":"/orders"}` {
+			t.Errorf("expected JSON request context, got %q", got)
+		}
+
+		switch r.PostForm.Get("subject_token") {
+		case "flaky":
+			if requests.Load() < 3 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(`{"error":"temporarily_unavailable","error_description":"signer unavailable"}`))
+				return
+			}
+		case "bad":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_request","error_description":"subject token is invalid"}`))
+			return
+		}
+
+		// grpc-gateway encodes int64 as a JSON string
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"issued","issued_token_type":"urn:ietf:params:oauth:token-type:txn_token","token_type":"N_A","expires_in":"300"}`))
+	}))
+	defer server.Close()
+
+	c, err := New(Config{URL: server.URL + "/", ActorCredentials: StaticToken("actor-token"), Retry: fastRetry})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	request := func(subject string) ExchangeRequest {
+		return ExchangeRequest{SubjectToken: subject, Audience: "parsec.test", RequestContext: map[string]any{"path": "/orders"}}
+	}
+
+	t.Run("exchange", func(t *testing.T) {
+		requests.Store(0)
+		token, err := c.Exchange(context.Background(), request("user-token"))
+		if err != nil {
+			t.Fatalf("exchange failed: %v", err)
+		}
+		if token.Value != "issued" || token.IssuedTokenType != TokenTypeTransactionToken || token.ExpiresIn != 5*time.Minute {
+			t.Errorf("unexpected token: %+v", token)
+		}
+	})
+
+	t.Run("retries temporary failures", func(t *testing.T) {
+		requests.Store(0)
+		if _, err := c.Exchange(context.Background(), request("flaky")); err != nil {
+			t.Fatalf("expected the third attempt to succeed: %v", err)
+		}
+		if requests.Load() != 3 {
+			t.Errorf("expected 3 attempts, got %d", requests.Load())
+		}
+	})
+
+	t.Run("does not retry rejections", func(t *testing.T) {
+		requests.Store(0)
+		_, err := c.Exchange(context.Background(), request("bad"))
+		var parsecErr *Error
+		if !errors.As(err, &parsecErr) {
+			t.Fatalf("expected *Error, got %v", err)
+		}
+		if parsecErr.HTTPStatus != http.StatusUnauthorized || parsecErr.OAuthError != "invalid_request" || parsecErr.Description != "subject token is invalid" {
+			t.Errorf("unexpected error: %+v", parsecErr)
+		}
+		if requests.Load() != 1 {
+			t.Errorf("expected a single attempt, got %d", requests.Load())
+		}
+	})
+}
+
+// fakeExchangeServer answers Exchange with a token, failing the first
+// failures calls as unavailable
+type fakeExchangeServer struct {
+	parsecv1.UnimplementedTokenExchangeServiceServer
+	failures atomic.Int32
+	actor    atomic.Value
+}
+
+func (s *fakeExchangeServer) Exchange(ctx context.Context, req *parsecv1.ExchangeRequest) (*parsecv1.ExchangeResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("authorization"); len(values) > 0 {
+		s.actor.Store(values[0])
+	}
+	if req.GetSubjectToken() == "denied" {
+		st, _ := status.New(codes.PermissionDenied, "delegation denied").
+			WithDetails(&errdetails.ErrorInfo{Reason: "delegation_denied", Domain: errorDomain})
+		return nil, st.Err()
+	}
+	if s.failures.Add(-1) >= 0 {
+		return nil, status.Error(codes.Unavailable, "issuer unavailable")
+	}
+	return &parsecv1.ExchangeResponse{AccessToken: "issued", IssuedTokenType: req.GetRequestedTokenType(), ExpiresIn: 60}, nil
+}
+
+func TestClient_ExchangeGRPC(t *testing.T) {
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	fake := &fakeExchangeServer{}
+	parsecv1.RegisterTokenExchangeServiceServer(grpcServer, fake)
+	go func() { _ = grpcServer.Serve(listener) }()
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(Config{GRPCConn: conn, ActorCredentials: TokenFile(tokenFile), Retry: fastRetry})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	fake.failures.Store(2)
+	token, err := c.Exchange(context.Background(), ExchangeRequest{SubjectToken: "user-token"})
+	if err != nil {
+		t.Fatalf("expected the exchange to succeed after retries: %v", err)
+	}
+	if token.Value != "issued" || token.ExpiresIn != time.Minute {
+		t.Errorf("unexpected token: %+v", token)
+	}
+	if fake.actor.Load() != "Bearer first" {
+		t.Errorf("expected the actor credential in metadata, got %v", fake.actor.Load())
+	}
+
+	// A rotated token file is read on the next request
+	if err := os.WriteFile(tokenFile, []byte("second"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Exchange(context.Background(), ExchangeRequest{SubjectToken: "denied"})
+	if !IsReason(err, "delegation_denied") {
+		t.Errorf("expected the parsec reason, got %v", err)
+	}
+	if fake.actor.Load() != "Bearer second" {
+		t.Errorf("expected the rotated credential, got %v", fake.actor.Load())
+	}
+}
+
+func TestClient_Verify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jwksFetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/jwks.json" {
+			http.NotFound(w, r)
+			return
+		}
+		jwksFetches.Add(1)
+		encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+		x, y := make([]byte, 32), make([]byte, 32)
+		key.X.FillBytes(x)
+		key.Y.FillBytes(y)
+		// The gateway's default marshaler uses JSON (camelCase) field names
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]any{{
+			"kty": "EC", "crv": "P-256", "x": encode(x), "y": encode(y), "kid": "key-1", "alg": "ES256", "use": "sig",
+		}}})
+	}))
+	defer server.Close()
+
+	sign := func(t *testing.T, kid string, build func(*jwt.Builder) *jwt.Builder) string {
+		t.Helper()
+		token, err := build(jwt.NewBuilder().
+			Issuer("https://parsec.test").
+			Subject("alice").
+			Audience([]string{"parsec.test"}).
+			IssuedAt(time.Now()).
+			Expiration(time.Now().Add(time.Minute)).
+			Claim("txn", "txn-1").
+			Claim("tctx", map[string]any{"roles": []string{"admin"}})).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers := jws.NewHeaders()
+		_ = headers.Set(jws.KeyIDKey, kid)
+		_ = headers.Set(jws.TypeKey, "txntoken+jwt")
+		signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256(), key, jws.WithProtectedHeaders(headers)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(signed)
+	}
+	same := func(b *jwt.Builder) *jwt.Builder { return b }
+
+	c, err := New(Config{URL: server.URL, TrustDomain: "parsec.test", Retry: fastRetry})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	ctx := context.Background()
+
+	t.Run("valid token", func(t *testing.T) {
+		claims, err := c.Verify(ctx, sign(t, "key-1", same))
+		if err != nil {
+			t.Fatalf("expected the token to verify: %v", err)
+		}
+		if claims.Subject != "alice" || claims.TransactionID != "txn-1" {
+			t.Errorf("unexpected claims: %+v", claims)
+		}
+		var tctx struct {
+			Roles []string `json:"roles"`
+		}
+		if err := claims.Decode("tctx", &tctx); err != nil || len(tctx.Roles) != 1 || tctx.Roles[0] != "admin" {
+			t.Errorf("expected typed tctx, got %+v (%v)", tctx, err)
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		expired := sign(t, "key-1", func(b *jwt.Builder) *jwt.Builder {
+			return b.IssuedAt(time.Now().Add(-time.Hour)).Expiration(time.Now().Add(-time.Minute))
+		})
+		if _, err := c.Verify(ctx, expired); err == nil {
+			t.Error("expected an expired token to be rejected")
+		}
+	})
+
+	t.Run("other trust domain", func(t *testing.T) {
+		other := sign(t, "key-1", func(b *jwt.Builder) *jwt.Builder { return b.Audience([]string{"other.test"}) })
+		if _, err := c.Verify(ctx, other); err == nil {
+			t.Error("expected a token for another trust domain to be rejected")
+		}
+	})
+
+	t.Run("unknown key is not refetched immediately", func(t *testing.T) {
+		before := jwksFetches.Load()
+		if _, err := c.Verify(ctx, sign(t, "key-2", same)); err == nil {
+			t.Error("expected a token signed by an unpublished key ID to be rejected")
+		}
+		if jwksFetches.Load() != before {
+			t.Errorf("expected unknown keys not to refetch within a minute of the last fetch")
+		}
+	})
+}
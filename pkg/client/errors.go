@@ -0,0 +1,121 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain is the ErrorInfo domain parsec attaches its error codes under
+const errorDomain = "parsec.kessel.project"
+
+// Error is a request parsec answered with an error
+type Error struct {
+	// HTTPStatus is the response status (HTTP only)
+	HTTPStatus int
+
+	// GRPCCode is the status code (gRPC only)
+	GRPCCode codes.Code
+
+	// OAuthError is the OAuth 2.0 error value, e.g. "invalid_request" (HTTP only)
+	OAuthError string
+
+	// Reason is parsec's error code, e.g. "invalid_subject_token", when it
+	// reported one
+	Reason string
+
+	// Description explains the error
+	Description string
+
+	// RetryAfter is how long parsec asked the client to wait (HTTP only)
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	kind := e.Reason
+	if kind == "" {
+		kind = e.OAuthError
+	}
+	switch {
+	case e.HTTPStatus != 0 && kind != "":
+		return fmt.Sprintf("parsec returned %d %s: %s", e.HTTPStatus, kind, e.Description)
+	case e.HTTPStatus != 0:
+		return fmt.Sprintf("parsec returned %d: %s", e.HTTPStatus, e.Description)
+	case kind != "":
+		return fmt.Sprintf("parsec returned %s (%s): %s", e.GRPCCode, kind, e.Description)
+	default:
+		return fmt.Sprintf("parsec returned %s: %s", e.GRPCCode, e.Description)
+	}
+}
+
+// Temporary reports whether the request may succeed if retried
+func (e *Error) Temporary() bool {
+	if e.HTTPStatus != 0 {
+		switch e.HTTPStatus {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return e.OAuthError == "temporarily_unavailable"
+	}
+	switch e.GRPCCode {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// errorFromHTTP reads an error response
+// parsec answers token exchange errors with an OAuth 2.0 error body
+// (RFC 6749 section 5.2); other bodies are kept as the description.
+func errorFromHTTP(resp *http.Response, body []byte) *Error {
+	e := &Error{HTTPStatus: resp.StatusCode}
+
+	var oauth struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+		Message          string `json:"message"`
+	}
+	if json.Unmarshal(body, &oauth) == nil && (oauth.Error != "" || oauth.Message != "") {
+		e.OAuthError = oauth.Error
+		e.Description = oauth.ErrorDescription
+		if e.Description == "" {
+			e.Description = oauth.Message
+		}
+	} else {
+		e.Description = string(body)
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return e
+}
+
+// errorFromGRPC converts a gRPC error
+// Errors without a status, such as a closed connection, are returned as is.
+func errorFromGRPC(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	e := &Error{GRPCCode: st.Code(), Description: st.Message()}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetDomain() == errorDomain {
+			e.Reason = info.GetReason()
+		}
+	}
+	return e
+}
+
+// IsReason reports whether err is a parsec error with the reason
+func IsReason(err error, reason string) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Reason == reason
+}
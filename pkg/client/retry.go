@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"time"
+)
+
+// RetryPolicy controls retries of transient failures
+// Failures are retried when parsec reports it is temporarily unavailable or
+// the request never reached it. Requests parsec rejected are not retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first (default: 3)
+	// 1 disables retries.
+	MaxAttempts int
+
+	// InitialBackoff is the wait before the first retry (default: 100ms)
+	// Each retry doubles it, with jitter.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between attempts (default: 2s)
+	// It also caps a Retry-After sent by parsec.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy retries twice, backing off from 100ms
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+// withDefaults fills in unset fields from DefaultRetryPolicy
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DefaultRetryPolicy.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+	}
+	return p
+}
+
+// backoff returns the wait before retry n (starting at 1)
+// The wait is drawn from the upper half of the exponential backoff, so
+// concurrent clients spread out without retrying immediately.
+func (p RetryPolicy) backoff(n int, err error) time.Duration {
+	wait := p.InitialBackoff << (n - 1)
+	if wait <= 0 || wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	wait = wait/2 + rand.N(wait/2+1)
+
+	var parsecErr *Error
+	if errors.As(err, &parsecErr) && parsecErr.RetryAfter > wait {
+		wait = min(parsecErr.RetryAfter, p.MaxBackoff)
+	}
+	return wait
+}
+
+// withRetry calls fn until it succeeds, fails permanently, runs out of
+// attempts or ctx is done
+func (c *Client) withRetry(ctx context.Context, fn func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn(ctx)
+		if err == nil || attempt >= c.retry.MaxAttempts || !retryable(ctx, err) {
+			return err
+		}
+
+		timer := time.NewTimer(c.retry.backoff(attempt, err))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether a failed attempt may succeed if repeated
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var parsecErr *Error
+	if errors.As(err, &parsecErr) {
+		return parsecErr.Temporary()
+	}
+	// Connection failures never reached parsec
+	var netErr net.Error
+	var opErr *net.OpError
+	return errors.As(err, &opErr) || (errors.As(err, &netErr) && netErr.Timeout())
+}
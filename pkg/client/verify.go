@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	parsecv1 "github.com/project-kessel/parsec/api/gen/parsec/v1"
+	"github.com/project-kessel/parsec/pkg/verify"
+)
+
+// Claims are the claims of a parsec-issued JWT
+type Claims = verify.Claims
+
+// ParseClaims decodes a JWT's claims without verifying it
+func ParseClaims(token string) (*Claims, error) {
+	return verify.ParseClaims(token)
+}
+
+// Verify checks a transaction token against parsec's published keys and
+// Config.TrustDomain, and returns its claims (see verify.Verifier)
+func (c *Client) Verify(ctx context.Context, token string) (*Claims, error) {
+	if c.verifier == nil {
+		return nil, fmt.Errorf("trust domain is required to verify tokens")
+	}
+	return c.verifier.Verify(ctx, token)
+}
+
+// Verifier returns the verifier Verify uses, e.g. for its middleware
+// Returns nil when Config.TrustDomain is not set.
+func (c *Client) Verifier() *verify.Verifier {
+	return c.verifier
+}
+
+// PublicKeys returns parsec's published keys
+func (c *Client) PublicKeys(ctx context.Context) (jwk.Set, error) {
+	if c.verifier != nil {
+		return c.verifier.PublicKeys(ctx)
+	}
+	return c.fetchJWKS(ctx)
+}
+
+// fetchJWKS fetches parsec's published keys over gRPC or HTTP
+func (c *Client) fetchJWKS(ctx context.Context) (jwk.Set, error) {
+	var resp *parsecv1.GetJWKSResponse
+	err := c.withRetry(ctx, func(ctx context.Context) error {
+		if c.jwksClient != nil {
+			r, err := c.jwksClient.GetJWKS(ctx, &parsecv1.GetJWKSRequest{})
+			if err != nil {
+				return errorFromGRPC(err)
+			}
+			resp = r
+			return nil
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+"/v1/jwks.json", nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/json")
+		var r parsecv1.GetJWKSResponse
+		if err := c.doHTTP(req, &r); err != nil {
+			return err
+		}
+		resp = &r
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	// Proto field names are the JWK parameter names
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JWKS: %w", err)
+	}
+	set, err := jwk.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+	return set, nil
+}
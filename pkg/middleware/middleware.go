@@ -0,0 +1,105 @@
+// Package middleware provides gRPC server interceptors that verify the
+// transaction tokens parsec issues, so Go services adopt them with two lines:
+//
+//	mw, err := middleware.New(middleware.Config{Config: verify.Config{JWKSURL: jwksURL, TrustDomain: "parsec.example.com"}})
+//	server := grpc.NewServer(mw.ServerOptions()...)
+package middleware
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+
+	"github.com/project-kessel/parsec/pkg/verify"
+)
+
+// Config configures the interceptors
+type Config struct {
+	// Config configures the verifier created by New, unless Verifier is set
+	verify.Config
+
+	// Verifier verifies tokens instead of one created from Config (optional)
+	Verifier *verify.Verifier
+
+	// Header is the metadata key carrying the token (default: verify.DefaultHeader)
+	Header string
+
+	// Audiences are this service's names; a token's audience must include one
+	// of them, in addition to the trust domain (optional)
+	Audiences []string
+
+	// SkipMethods are full method names called without a token, e.g.
+	// "/grpc.health.v1.Health/Check" (optional)
+	SkipMethods []string
+}
+
+// Interceptors verify the transaction token of every gRPC call
+// They are the verifier's interceptors (see verify.Verifier.UnaryServerInterceptor),
+// configured from Config.
+type Interceptors struct {
+	unary  grpc.UnaryServerInterceptor
+	stream grpc.StreamServerInterceptor
+}
+
+// New creates the interceptors
+func New(cfg Config) (*Interceptors, error) {
+	verifier := cfg.Verifier
+	if verifier == nil {
+		var err error
+		verifier, err = verify.New(cfg.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create verifier: %w", err)
+		}
+	}
+	opts := []verify.InterceptorOption{
+		verify.WithAudiences(cfg.Audiences...),
+		verify.WithSkipMethods(cfg.SkipMethods...),
+	}
+	return &Interceptors{
+		unary:  verifier.UnaryServerInterceptor(cfg.Header, opts...),
+		stream: verifier.StreamServerInterceptor(cfg.Header, opts...),
+	}, nil
+}
+
+// ServerOptions returns the options installing both interceptors
+// They are chained, so other interceptors can still be added.
+func (i *Interceptors) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(i.Unary()),
+		grpc.ChainStreamInterceptor(i.Stream()),
+	}
+}
+
+// Unary returns the interceptor for unary calls
+// Calls without a valid token fail with Unauthenticated, and calls with a
+// token for another audience with PermissionDenied.
+func (i *Interceptors) Unary() grpc.UnaryServerInterceptor {
+	return i.unary
+}
+
+// Stream returns the interceptor for streaming calls
+func (i *Interceptors) Stream() grpc.StreamServerInterceptor {
+	return i.stream
+}
+
+// Claims returns the claims of the call's verified token
+func Claims(ctx context.Context) (*verify.Claims, bool) {
+	return verify.FromContext(ctx)
+}
+
+// TransactionContext returns the "tctx" claim of the call's verified token
+func TransactionContext(ctx context.Context) map[string]any {
+	if claims, ok := verify.FromContext(ctx); ok {
+		return claims.TransactionContext
+	}
+	return nil
+}
+
+// RequestContext returns the "req_ctx" claim of the call's verified token
+func RequestContext(ctx context.Context) map[string]any {
+	if claims, ok := verify.FromContext(ctx); ok {
+		return claims.RequestContext
+	}
+	return nil
+}
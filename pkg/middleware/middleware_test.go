@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/project-kessel/parsec/pkg/verify"
+)
+
+var testNow = time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+
+// testSigner signs transaction tokens with one key and serves it as a JWKS
+type testSigner struct {
+	t   *testing.T
+	key *ecdsa.PrivateKey
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testSigner{t: t, key: key}
+}
+
+func (s *testSigner) fetch(ctx context.Context) (jwk.Set, error) {
+	pub, err := jwk.Import(s.key.Public())
+	if err != nil {
+		return nil, err
+	}
+	_ = pub.Set(jwk.KeyIDKey, "key-1")
+	_ = pub.Set(jwk.AlgorithmKey, jwa.ES256())
+	set := jwk.NewSet()
+	_ = set.AddKey(pub)
+	return set, nil
+}
+
+// sign signs a transaction token for the audiences, valid at testNow
+func (s *testSigner) sign(audiences ...string) string {
+	s.t.Helper()
+	token, err := jwt.NewBuilder().
+		Issuer("https://parsec.test").
+		Subject("alice").
+		Audience(audiences).
+		IssuedAt(testNow).
+		Expiration(testNow.Add(5*time.Minute)).
+		Claim("txn", "txn-1").
+		Claim("tctx", map[string]any{"org_id": "42"}).
+		Claim("req_ctx", map[string]any{"method": "GET"}).
+		Build()
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	headers := jws.NewHeaders()
+	_ = headers.Set(jws.KeyIDKey, "key-1")
+	_ = headers.Set(jws.TypeKey, verify.TypeTransactionToken)
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256(), s.key, jws.WithProtectedHeaders(headers)))
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return string(signed)
+}
+
+// fakeStream is a ServerStream carrying a context
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestInterceptors(t *testing.T) {
+	signer := newTestSigner(t)
+	mw, err := New(Config{
+		Config: verify.Config{
+			Fetch:       signer.fetch,
+			TrustDomain: "parsec.test",
+			Now:         func() time.Time { return testNow },
+		},
+		Audiences:   []string{"orders"},
+		SkipMethods: []string{"/grpc.health.v1.Health/Check"},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	unary := mw.Unary()
+	info := &grpc.UnaryServerInfo{FullMethod: "/orders.v1.Orders/Get"}
+	handler := func(ctx context.Context, req any) (any, error) {
+		claims, ok := Claims(ctx)
+		if !ok {
+			return nil, errors.New("no claims")
+		}
+		if !reflect.DeepEqual(TransactionContext(ctx), map[string]any{"org_id": "42"}) ||
+			!reflect.DeepEqual(RequestContext(ctx), map[string]any{"method": "GET"}) {
+			return nil, errors.New("unexpected contexts")
+		}
+		return claims.Subject, nil
+	}
+	incoming := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("transaction-token", token))
+	}
+
+	t.Run("valid token reaches the handler", func(t *testing.T) {
+		resp, err := unary(incoming(signer.sign("parsec.test", "orders")), nil, info, handler)
+		if err != nil || resp != "alice" {
+			t.Errorf("expected the handler to see the claims, got %v (%v)", resp, err)
+		}
+	})
+
+	t.Run("missing or invalid token is unauthenticated", func(t *testing.T) {
+		for _, ctx := range []context.Context{context.Background(), incoming("not-a-token")} {
+			if _, err := unary(ctx, nil, info, handler); status.Code(err) != codes.Unauthenticated {
+				t.Errorf("expected Unauthenticated, got %v", err)
+			}
+		}
+	})
+
+	t.Run("token for another service is denied", func(t *testing.T) {
+		_, err := unary(incoming(signer.sign("parsec.test", "billing")), nil, info, handler)
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied, got %v", err)
+		}
+	})
+
+	t.Run("skipped methods need no token", func(t *testing.T) {
+		healthInfo := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+		resp, err := unary(context.Background(), nil, healthInfo, func(ctx context.Context, req any) (any, error) {
+			return "SERVING", nil
+		})
+		if err != nil || resp != "SERVING" {
+			t.Errorf("expected the health check to be served, got %v (%v)", resp, err)
+		}
+	})
+
+	t.Run("streams see the claims", func(t *testing.T) {
+		stream := &fakeStream{ctx: incoming(signer.sign("parsec.test", "orders"))}
+		streamInfo := &grpc.StreamServerInfo{FullMethod: "/orders.v1.Orders/Watch"}
+		err := mw.Stream()(nil, stream, streamInfo, func(srv any, ss grpc.ServerStream) error {
+			if claims, ok := Claims(ss.Context()); !ok || claims.TransactionID != "txn-1" {
+				return errors.New("no claims")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("expected the stream to be served, got %v", err)
+		}
+	})
+}
+
+func TestNew_RequiresVerifierConfig(t *testing.T) {
+	if _, err := New(Config{}); err == nil {
+		t.Error("expected error without a trust domain")
+	}
+}
@@ -0,0 +1,243 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// ClientConfig configures the connection to a plugin
+type ClientConfig struct {
+	// Command is the plugin executable parsec starts (one of Command or Address)
+	Command string
+
+	// Args are the arguments of Command
+	Args []string
+
+	// Env are extra "KEY=value" environment variables for Command, on top of
+	// parsec's own
+	Env []string
+
+	// Address is the TCP address of a plugin running on its own (one of
+	// Command or Address)
+	Address string
+
+	// StartTimeout bounds how long a started plugin may take to complete the
+	// handshake (default: 10 seconds)
+	StartTimeout time.Duration
+
+	// Stderr receives the plugin's stderr and any stdout after the handshake
+	// (default: os.Stderr)
+	Stderr io.Writer
+}
+
+// Client talks to one plugin. A plugin started from a command is started by
+// the first call, and started again by the next call if it exits.
+type Client struct {
+	cfg ClientConfig
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	exited chan struct{}
+	conn   *grpc.ClientConn
+	closed bool
+}
+
+// NewClient creates a client for a plugin, without starting it
+func NewClient(cfg ClientConfig) (*Client, error) {
+	if (cfg.Command == "") == (cfg.Address == "") {
+		return nil, errors.New("plugin requires one of command or address")
+	}
+	if cfg.StartTimeout <= 0 {
+		cfg.StartTimeout = 10 * time.Second
+	}
+	if cfg.Stderr == nil {
+		cfg.Stderr = os.Stderr
+	}
+	return &Client{cfg: cfg}, nil
+}
+
+// Start starts the plugin, if it isn't running, and checks that it is healthy
+func (c *Client) Start(ctx context.Context) error {
+	if _, err := c.connection(ctx); err != nil {
+		return err
+	}
+	return c.Health(ctx)
+}
+
+// Health returns an error unless the plugin reports that it is serving
+func (c *Client) Health(ctx context.Context) error {
+	conn, err := c.connection(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return fmt.Errorf("plugin health check failed: %w", err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("plugin is %s", resp.GetStatus())
+	}
+	return nil
+}
+
+// Close stops the plugin, if parsec started it, and closes the connection
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	c.stop()
+	return nil
+}
+
+// invoke calls a plugin method
+func (c *Client) invoke(ctx context.Context, method string, in, out proto.Message) error {
+	conn, err := c.connection(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Invoke(ctx, method, in, out)
+}
+
+// connection returns the connection to the plugin, starting it if needed
+func (c *Client) connection(ctx context.Context) (*grpc.ClientConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, errors.New("plugin client is closed")
+	}
+	if c.conn != nil && !c.hasExited() {
+		return c.conn, nil
+	}
+	c.stop()
+
+	target := c.cfg.Address
+	if c.cfg.Command != "" {
+		h, err := c.startProcess(ctx)
+		if err != nil {
+			return nil, err
+		}
+		target = h.address
+		if h.network == "unix" {
+			target = "unix://" + h.address
+		}
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		c.stop()
+		return nil, fmt.Errorf("failed to connect to plugin: %w", err)
+	}
+	c.conn = conn
+	return conn, nil
+}
+
+// startProcess starts the plugin command and reads its handshake; c.mu must be held
+func (c *Client) startProcess(ctx context.Context) (handshake, error) {
+	cmd := exec.Command(c.cfg.Command, c.cfg.Args...)
+	cmd.Env = append(append(os.Environ(), c.cfg.Env...), MagicCookieKey+"="+MagicCookieValue)
+	cmd.Stderr = c.cfg.Stderr
+	// Children the plugin left behind may hold stderr open after it exits
+	cmd.WaitDelay = time.Second
+	// stdout is a pipe of our own rather than cmd.StdoutPipe, so that the
+	// process is seen to exit even while such children hold stdout
+	stdout, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return handshake{}, fmt.Errorf("failed to start plugin %s: %w", c.cfg.Command, err)
+	}
+	cmd.Stdout = stdoutWriter
+	err = cmd.Start()
+	_ = stdoutWriter.Close()
+	if err != nil {
+		_ = stdout.Close()
+		return handshake{}, fmt.Errorf("failed to start plugin %s: %w", c.cfg.Command, err)
+	}
+	c.cmd = cmd
+	c.exited = make(chan struct{})
+	exited := c.exited
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+
+	lines := make(chan string, 1)
+	go func() {
+		defer func() { _ = stdout.Close() }()
+		reader := bufio.NewReader(stdout)
+		line, _ := reader.ReadString('\n')
+		lines <- line
+		// Keep draining stdout so the plugin doesn't block writing to it
+		_, _ = io.Copy(c.cfg.Stderr, reader)
+	}()
+
+	timer := time.NewTimer(c.cfg.StartTimeout)
+	defer timer.Stop()
+	select {
+	case line := <-lines:
+		if line == "" {
+			c.stop()
+			return handshake{}, fmt.Errorf("plugin %s exited before the handshake", c.cfg.Command)
+		}
+		h, err := parseHandshake(line)
+		if err != nil {
+			c.stop()
+			return handshake{}, err
+		}
+		return h, nil
+	case <-timer.C:
+		c.stop()
+		return handshake{}, fmt.Errorf("plugin %s did not complete the handshake within %s", c.cfg.Command, c.cfg.StartTimeout)
+	case <-ctx.Done():
+		c.stop()
+		return handshake{}, ctx.Err()
+	}
+}
+
+// hasExited reports whether the started plugin process exited; c.mu must be held
+func (c *Client) hasExited() bool {
+	if c.exited == nil {
+		return false
+	}
+	select {
+	case <-c.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop closes the connection and stops the plugin process; c.mu must be held
+func (c *Client) stop() {
+	if c.conn != nil {
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+	if c.cmd == nil {
+		return
+	}
+	_ = c.cmd.Process.Signal(syscall.SIGTERM)
+	select {
+	case <-c.exited:
+	case <-time.After(5 * time.Second):
+		_ = c.cmd.Process.Kill()
+		<-c.exited
+	}
+	c.cmd = nil
+	c.exited = nil
+}
+
+// invalidResponse describes a plugin response that could not be decoded
+func invalidResponse(method string, err error) error {
+	return fmt.Errorf("invalid plugin %s response: %w", method, err)
+}
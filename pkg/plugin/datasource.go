@@ -0,0 +1,104 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// DataSourceServiceName is the gRPC service implemented by data source plugins
+const DataSourceServiceName = "parsec.plugin.v1.DataSource"
+
+// DataSource fetches data that enriches tokens, e.g. from a system parsec
+// can't reach with its own data sources
+type DataSource interface {
+	// Fetch returns the data for the request
+	// A response without data means the data source has nothing to contribute;
+	// errors fail token issuance.
+	Fetch(ctx context.Context, req *FetchRequest) (*FetchResponse, error)
+}
+
+// FetchRequest asks a data source plugin for data
+type FetchRequest struct {
+	// DataSource is the name of the data source in parsec's configuration, so
+	// one plugin can serve several data sources
+	DataSource string `json:"data_source"`
+
+	// Subject is the identity tokens are issued for
+	Subject *Identity `json:"subject,omitempty"`
+
+	// Actor is the identity of the workload requesting tokens, if any
+	Actor *Identity `json:"actor,omitempty"`
+
+	// RequestAttributes describe the request being authorized, if any
+	RequestAttributes *RequestAttributes `json:"request_attributes,omitempty"`
+}
+
+// FetchResponse is the data fetched by a plugin
+type FetchResponse struct {
+	// Data is the JSON data (empty if there is none)
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// dataSourceServer is the gRPC handler type of the data source service
+type dataSourceServer interface {
+	fetch(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+}
+
+// dataSourceAdapter serves a DataSource over gRPC
+type dataSourceAdapter struct {
+	dataSource DataSource
+}
+
+func (a *dataSourceAdapter) fetch(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var req FetchRequest
+	if err := fromStruct(in, &req); err != nil {
+		return nil, invalidArgument("invalid fetch request: %v", err)
+	}
+	resp, err := a.dataSource.Fetch(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		resp = &FetchResponse{}
+	}
+	return toStruct(resp)
+}
+
+var dataSourceServiceDesc = grpc.ServiceDesc{
+	ServiceName: DataSourceServiceName,
+	HandlerType: (*dataSourceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Fetch",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := new(structpb.Struct)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				return intercept(ctx, in, srv, "/"+DataSourceServiceName+"/Fetch", interceptor, func(ctx context.Context, req any) (any, error) {
+					return srv.(dataSourceServer).fetch(ctx, req.(*structpb.Struct))
+				})
+			},
+		},
+	},
+}
+
+// Fetch asks the plugin for data
+func (c *Client) Fetch(ctx context.Context, req *FetchRequest) (*FetchResponse, error) {
+	in, err := toStruct(req)
+	if err != nil {
+		return nil, err
+	}
+	out := new(structpb.Struct)
+	if err := c.invoke(ctx, "/"+DataSourceServiceName+"/Fetch", in, out); err != nil {
+		return nil, err
+	}
+	var resp FetchResponse
+	if err := fromStruct(out, &resp); err != nil {
+		return nil, invalidResponse("fetch", err)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,192 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// IssuerServiceName is the gRPC service implemented by issuer plugins
+const IssuerServiceName = "parsec.plugin.v1.Issuer"
+
+// Issuer issues tokens of a format parsec doesn't support itself
+type Issuer interface {
+	// Issue creates a token for the request
+	// Errors carrying a gRPC status are passed to parsec with their code.
+	Issue(ctx context.Context, req *IssueRequest) (*IssueResponse, error)
+
+	// PublicKeys returns the keys verifying the plugin's tokens, which parsec
+	// publishes in its JWKS (empty for unsigned tokens)
+	PublicKeys(ctx context.Context) (*PublicKeysResponse, error)
+}
+
+// Identity is a validated subject or actor
+type Identity struct {
+	Subject     string         `json:"subject"`
+	Issuer      string         `json:"issuer"`
+	TrustDomain string         `json:"trust_domain"`
+	Claims      map[string]any `json:"claims,omitempty"`
+	ExpiresAt   time.Time      `json:"expires_at"`
+	IssuedAt    time.Time      `json:"issued_at"`
+	Audience    []string       `json:"audience,omitempty"`
+	Scope       string         `json:"scope,omitempty"`
+}
+
+// RequestAttributes describes the request a token is issued for
+type RequestAttributes struct {
+	Method     string            `json:"method,omitempty"`
+	Path       string            `json:"path,omitempty"`
+	IPAddress  string            `json:"ip_address,omitempty"`
+	UserAgent  string            `json:"user_agent,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Additional map[string]any    `json:"additional"`
+}
+
+// IssueRequest asks an issuer plugin for a token
+type IssueRequest struct {
+	// TokenType is the token type URN being issued
+	TokenType string `json:"token_type"`
+
+	// Subject is the identity the token is issued for
+	Subject *Identity `json:"subject,omitempty"`
+
+	// Actor is the identity of the workload requesting the token, if any
+	Actor *Identity `json:"actor,omitempty"`
+
+	// RequestAttributes describe the request being authorized, if any
+	RequestAttributes *RequestAttributes `json:"request_attributes,omitempty"`
+
+	// Audience, Scope and Purpose are the requested audience, scope and purpose
+	Audience string `json:"audience,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	Purpose  string `json:"purpose,omitempty"`
+
+	// Audiences are all the requested audiences; Audience is the first
+	Audiences []string `json:"audiences,omitempty"`
+
+	// Claims are the claims produced by the issuer's claim mappers in parsec
+	Claims map[string]any `json:"claims,omitempty"`
+
+	// IssuedAt is when the token is issued
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+// IssueResponse is a token issued by a plugin
+type IssueResponse struct {
+	// Token is the encoded token
+	Token string `json:"token"`
+
+	// ExpiresAt is when the token expires
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// IssuedAt is when the token was issued (default: IssueRequest.IssuedAt)
+	IssuedAt time.Time `json:"issued_at,omitzero"`
+
+	// TransactionID is the transaction ID of a transaction token (optional)
+	TransactionID string `json:"transaction_id,omitempty"`
+}
+
+// PublicKeysResponse holds the public keys of an issuer plugin
+type PublicKeysResponse struct {
+	// Keys are public JWKs, each with a "kid"
+	Keys []json.RawMessage `json:"keys"`
+}
+
+// issuerServer is the gRPC handler type of the issuer service
+type issuerServer interface {
+	issue(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+	publicKeys(ctx context.Context, req *emptypb.Empty) (*structpb.Struct, error)
+}
+
+// issuerAdapter serves an Issuer over gRPC
+type issuerAdapter struct {
+	issuer Issuer
+}
+
+func (a *issuerAdapter) issue(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var req IssueRequest
+	if err := fromStruct(in, &req); err != nil {
+		return nil, invalidArgument("invalid issue request: %v", err)
+	}
+	resp, err := a.issuer.Issue(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+	return toStruct(resp)
+}
+
+func (a *issuerAdapter) publicKeys(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	resp, err := a.issuer.PublicKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Keys == nil {
+		resp.Keys = []json.RawMessage{}
+	}
+	return toStruct(resp)
+}
+
+var issuerServiceDesc = grpc.ServiceDesc{
+	ServiceName: IssuerServiceName,
+	HandlerType: (*issuerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Issue",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := new(structpb.Struct)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				return intercept(ctx, in, srv, "/"+IssuerServiceName+"/Issue", interceptor, func(ctx context.Context, req any) (any, error) {
+					return srv.(issuerServer).issue(ctx, req.(*structpb.Struct))
+				})
+			},
+		},
+		{
+			MethodName: "PublicKeys",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := new(emptypb.Empty)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				return intercept(ctx, in, srv, "/"+IssuerServiceName+"/PublicKeys", interceptor, func(ctx context.Context, req any) (any, error) {
+					return srv.(issuerServer).publicKeys(ctx, req.(*emptypb.Empty))
+				})
+			},
+		},
+	},
+}
+
+// Issue asks the plugin for a token
+func (c *Client) Issue(ctx context.Context, req *IssueRequest) (*IssueResponse, error) {
+	in, err := toStruct(req)
+	if err != nil {
+		return nil, err
+	}
+	out := new(structpb.Struct)
+	if err := c.invoke(ctx, "/"+IssuerServiceName+"/Issue", in, out); err != nil {
+		return nil, err
+	}
+	var resp IssueResponse
+	if err := fromStruct(out, &resp); err != nil {
+		return nil, invalidResponse("issue", err)
+	}
+	return &resp, nil
+}
+
+// PublicKeys asks the plugin for its public keys
+func (c *Client) PublicKeys(ctx context.Context) (*PublicKeysResponse, error) {
+	out := new(structpb.Struct)
+	if err := c.invoke(ctx, "/"+IssuerServiceName+"/PublicKeys", &emptypb.Empty{}, out); err != nil {
+		return nil, err
+	}
+	var resp PublicKeysResponse
+	if err := fromStruct(out, &resp); err != nil {
+		return nil, invalidResponse("public keys", err)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,109 @@
+// Package plugin runs parsec extensions out of process, so teams can add
+// proprietary token formats and enrichment sources, in any language, without
+// changing parsec itself.
+//
+// A plugin is an executable that parsec starts and talks to over gRPC. It
+// implements an issuer, a data source or both, and its main function hands
+// them to Serve:
+//
+//	func main() {
+//		plugin.Serve(plugin.ServeConfig{Issuer: &myIssuer{}, DataSource: &myDataSource{}})
+//	}
+//
+// Serve listens on a local socket and writes the handshake line
+//
+//	1|unix|/tmp/parsec-plugin-123/plugin.sock
+//
+// (the protocol version, network and address) to stdout, where parsec reads
+// it before connecting. Plugins may also run on their own, e.g. as a sidecar,
+// by serving on ServeConfig.Address; parsec then connects to that address
+// instead of starting a process.
+//
+// The gRPC services exchange google.protobuf.Struct messages holding the JSON
+// encoding of this package's types, so plugins can be written in any language
+// with gRPC support:
+//
+//	service parsec.plugin.v1.Issuer {
+//	  rpc Issue(google.protobuf.Struct) returns (google.protobuf.Struct);       // IssueRequest, IssueResponse
+//	  rpc PublicKeys(google.protobuf.Empty) returns (google.protobuf.Struct);   // PublicKeysResponse
+//	}
+//
+//	service parsec.plugin.v1.DataSource {
+//	  rpc Fetch(google.protobuf.Struct) returns (google.protobuf.Struct);       // FetchRequest, FetchResponse
+//	}
+//
+// Plugins also serve the standard grpc.health.v1.Health service.
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ProtocolVersion is the version of the plugin protocol in the handshake
+const ProtocolVersion = 1
+
+// Environment variables parsec sets when starting a plugin
+const (
+	// MagicCookieKey and MagicCookieValue tell a plugin it was started by
+	// parsec rather than by hand
+	MagicCookieKey   = "PARSEC_PLUGIN_MAGIC_COOKIE"
+	MagicCookieValue = "b1d7a5a0-parsec-plugin"
+)
+
+// handshake is the line a plugin writes to stdout once it is serving
+type handshake struct {
+	version int
+	network string
+	address string
+}
+
+func (h handshake) String() string {
+	return fmt.Sprintf("%d|%s|%s", h.version, h.network, h.address)
+}
+
+// parseHandshake parses the handshake line of a plugin
+func parseHandshake(line string) (handshake, error) {
+	parts := strings.Split(strings.TrimSpace(line), "|")
+	if len(parts) != 3 {
+		return handshake{}, fmt.Errorf("invalid plugin handshake %q", line)
+	}
+	version, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return handshake{}, fmt.Errorf("invalid plugin handshake %q", line)
+	}
+	if version != ProtocolVersion {
+		return handshake{}, fmt.Errorf("plugin speaks protocol version %d, expected %d", version, ProtocolVersion)
+	}
+	if parts[1] != "unix" && parts[1] != "tcp" {
+		return handshake{}, fmt.Errorf("unsupported plugin network: %s", parts[1])
+	}
+	return handshake{version: version, network: parts[1], address: parts[2]}, nil
+}
+
+// toStruct encodes v as JSON in a protobuf Struct
+func toStruct(v any) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	s := &structpb.Struct{}
+	if err := protojson.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// fromStruct decodes the JSON held by a protobuf Struct into v
+func fromStruct(s *structpb.Struct, v any) error {
+	data, err := protojson.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
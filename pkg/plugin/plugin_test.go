@@ -0,0 +1,250 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testPluginEnv makes the test binary serve testIssuer instead of running the tests
+const testPluginEnv = "PARSEC_PLUGIN_TEST"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnv) == "issuer" {
+		Serve(ServeConfig{Issuer: testIssuer{}})
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// testIssuer issues "<subject>:<claims>" tokens
+type testIssuer struct{}
+
+func (testIssuer) Issue(ctx context.Context, req *IssueRequest) (*IssueResponse, error) {
+	if req.Subject == nil {
+		return nil, status.Error(codes.InvalidArgument, "subject required")
+	}
+	claims, err := json.Marshal(req.Claims)
+	if err != nil {
+		return nil, err
+	}
+	return &IssueResponse{
+		Token:     req.Subject.Subject + ":" + string(claims),
+		ExpiresAt: req.IssuedAt.Add(time.Minute),
+	}, nil
+}
+
+func (testIssuer) PublicKeys(ctx context.Context) (*PublicKeysResponse, error) {
+	return &PublicKeysResponse{Keys: []json.RawMessage{json.RawMessage(`{"kty":"oct","kid":"k1"}`)}}, nil
+}
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	client, err := NewClient(ClientConfig{
+		Command: os.Args[0],
+		Env:     []string{testPluginEnv + "=issuer"},
+		Stderr:  io.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestClient_Issuer(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	if err := client.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	issuedAt := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	resp, err := client.Issue(ctx, &IssueRequest{
+		TokenType: "urn:example:token",
+		Subject:   &Identity{Subject: "alice"},
+		Claims:    map[string]any{"org": "42"},
+		IssuedAt:  issuedAt,
+	})
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if resp.Token != `alice:{"org":"42"}` || !resp.ExpiresAt.Equal(issuedAt.Add(time.Minute)) {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	_, err = client.Issue(ctx, &IssueRequest{TokenType: "urn:example:token"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected the plugin's InvalidArgument, got %v", err)
+	}
+
+	keys, err := client.PublicKeys(ctx)
+	if err != nil {
+		t.Fatalf("PublicKeys failed: %v", err)
+	}
+	if len(keys.Keys) != 1 || !strings.Contains(string(keys.Keys[0]), `"kid":"k1"`) {
+		t.Errorf("unexpected keys: %s", keys.Keys)
+	}
+}
+
+func TestClient_RestartsExitedPlugin(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	if err := client.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	client.mu.Lock()
+	_ = client.cmd.Process.Kill()
+	<-client.exited
+	client.mu.Unlock()
+
+	if err := client.Health(ctx); err != nil {
+		t.Errorf("expected the plugin to be restarted, got %v", err)
+	}
+}
+
+func TestClient_Close(t *testing.T) {
+	client := newTestClient(t)
+	if err := client.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	_ = client.Close()
+	if err := client.Health(context.Background()); err == nil {
+		t.Error("expected an error from a closed client")
+	}
+}
+
+func TestClient_HandshakeFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		wantErr string
+	}{
+		{name: "exits", script: "exit 1", wantErr: "exited before the handshake"},
+		{name: "wrong version", script: "echo '2|unix|/tmp/x.sock'", wantErr: "protocol version 2"},
+		{name: "garbage", script: "echo hello", wantErr: "invalid plugin handshake"},
+		{name: "too slow", script: "sleep 5", wantErr: "did not complete the handshake"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(ClientConfig{
+				Command:      "/bin/sh",
+				Args:         []string{"-c", tt.script},
+				StartTimeout: 200 * time.Millisecond,
+				Stderr:       io.Discard,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = client.Close() }()
+			err = client.Start(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewClient_RequiresCommandOrAddress(t *testing.T) {
+	for _, cfg := range []ClientConfig{{}, {Command: "plugin", Address: "localhost:1234"}} {
+		if _, err := NewClient(cfg); err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+}
+
+func TestServe_RequiresParsec(t *testing.T) {
+	t.Setenv(MagicCookieKey, "")
+	err := serve(context.Background(), ServeConfig{Issuer: testIssuer{}})
+	if err == nil || !strings.Contains(err.Error(), "parsec plugin") {
+		t.Errorf("expected the plugin to refuse to run, got %v", err)
+	}
+}
+
+func TestServe_Address(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := lis.Addr().String()
+	_ = lis.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- serve(ctx, ServeConfig{Issuer: testIssuer{}, Address: address}) }()
+
+	client, err := NewClient(ClientConfig{Address: address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }()
+	resp, err := client.Issue(ctx, &IssueRequest{Subject: &Identity{Subject: "bob"}})
+	if err != nil || !strings.HasPrefix(resp.Token, "bob:") {
+		t.Errorf("expected a token from the plugin, got %v (%v)", resp, err)
+	}
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Errorf("serve failed: %v", err)
+	}
+}
+
+// testDataSource returns the roles of "alice" and nothing for anyone else
+type testDataSource struct{}
+
+func (testDataSource) Fetch(ctx context.Context, req *FetchRequest) (*FetchResponse, error) {
+	if req.DataSource != "roles" {
+		return nil, status.Errorf(codes.NotFound, "unknown data source %s", req.DataSource)
+	}
+	if req.Subject == nil || req.Subject.Subject != "alice" {
+		return nil, nil
+	}
+	return &FetchResponse{Data: json.RawMessage(`{"roles":["admin"]}`)}, nil
+}
+
+func TestClient_DataSource(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	Register(srv, ServeConfig{DataSource: testDataSource{}})
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	client, err := NewClient(ClientConfig{Address: lis.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	resp, err := client.Fetch(ctx, &FetchRequest{DataSource: "roles", Subject: &Identity{Subject: "alice"}})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	var data map[string][]string
+	if err := json.Unmarshal(resp.Data, &data); err != nil || len(data["roles"]) != 1 || data["roles"][0] != "admin" {
+		t.Errorf("unexpected data %s (%v)", resp.Data, err)
+	}
+
+	resp, err = client.Fetch(ctx, &FetchRequest{DataSource: "roles", Subject: &Identity{Subject: "bob"}})
+	if err != nil || resp.Data != nil {
+		t.Errorf("expected no data, got %s (%v)", resp.Data, err)
+	}
+
+	_, err = client.Fetch(ctx, &FetchRequest{DataSource: "groups"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected the plugin's NotFound, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// ServeConfig configures a plugin server
+type ServeConfig struct {
+	// Issuer implements the issuer service (optional)
+	Issuer Issuer
+
+	// DataSource implements the data source service (optional)
+	DataSource DataSource
+
+	// Address is a TCP address to serve on without the handshake, for plugins
+	// run on their own rather than started by parsec (optional)
+	Address string
+}
+
+// Serve serves the plugin until parsec stops it, and exits the process if
+// serving fails. It is meant to be called from a plugin's main function.
+func Serve(cfg ServeConfig) {
+	if err := serve(context.Background(), cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "plugin: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// serve serves the plugin until ctx is done or the process is signalled
+func serve(ctx context.Context, cfg ServeConfig) error {
+	if cfg.Issuer == nil && cfg.DataSource == nil {
+		return errors.New("no plugin services configured")
+	}
+
+	var lis net.Listener
+	if cfg.Address != "" {
+		var err error
+		if lis, err = net.Listen("tcp", cfg.Address); err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", cfg.Address, err)
+		}
+	} else {
+		if os.Getenv(MagicCookieKey) != MagicCookieValue {
+			return errors.New("this is a parsec plugin: configure it in parsec rather than running it directly")
+		}
+		dir, err := os.MkdirTemp("", "parsec-plugin-")
+		if err != nil {
+			return fmt.Errorf("failed to create socket directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(dir) }()
+		if lis, err = net.Listen("unix", filepath.Join(dir, "plugin.sock")); err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+	}
+
+	srv := grpc.NewServer()
+	Register(srv, cfg)
+	healthSrv := health.NewServer()
+	healthpb.RegisterHealthServer(srv, healthSrv)
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		healthSrv.Shutdown()
+		srv.GracefulStop()
+	}()
+
+	if cfg.Address == "" {
+		h := handshake{version: ProtocolVersion, network: lis.Addr().Network(), address: lis.Addr().String()}
+		fmt.Println(h)
+	}
+	if err := srv.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// Register registers the plugin services of cfg on srv, for plugins serving
+// alongside other gRPC services on their own server. Address is ignored, and
+// the health service is left to the caller.
+func Register(srv grpc.ServiceRegistrar, cfg ServeConfig) {
+	if cfg.Issuer != nil {
+		srv.RegisterService(&issuerServiceDesc, &issuerAdapter{issuer: cfg.Issuer})
+	}
+	if cfg.DataSource != nil {
+		srv.RegisterService(&dataSourceServiceDesc, &dataSourceAdapter{dataSource: cfg.DataSource})
+	}
+}
+
+// intercept calls handler through the server's interceptor, if any
+func intercept(ctx context.Context, in, srv any, method string, interceptor grpc.UnaryServerInterceptor, handler grpc.UnaryHandler) (any, error) {
+	if interceptor == nil {
+		return handler(ctx, in)
+	}
+	return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: method}, handler)
+}
+
+func invalidArgument(format string, args ...any) error {
+	return status.Errorf(codes.InvalidArgument, format, args...)
+}
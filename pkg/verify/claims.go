@@ -0,0 +1,140 @@
+package verify
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Claims are the claims of a parsec-issued JWT
+// Claims a token type does not use are left empty.
+type Claims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	IssuedAt  time.Time
+	NotBefore time.Time
+	ExpiresAt time.Time
+	ID        string
+
+	// TransactionID is the "txn" claim of a transaction token
+	TransactionID string
+
+	// Purpose is the "purp" claim of a transaction token
+	Purpose string
+
+	// Scope is the space-separated "scope" claim
+	Scope string
+
+	// ClientID is the "client_id" claim of an access token
+	ClientID string
+
+	// TransactionContext is the "tctx" claim of a transaction token
+	TransactionContext map[string]any
+
+	// RequestContext is the "req_ctx" claim of a transaction token
+	RequestContext map[string]any
+
+	// Actor is the "act" claim, present when an actor acts for the subject
+	Actor map[string]any
+
+	// Raw holds every claim, including those above
+	Raw map[string]any
+}
+
+// Decode decodes a claim into v, e.g. a struct for the transaction context
+// Returns an error if the claim is absent.
+func (c *Claims) Decode(name string, v any) error {
+	value, ok := c.Raw[name]
+	if !ok {
+		return fmt.Errorf("token has no %q claim", name)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to decode claim %q: %w", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode claim %q: %w", name, err)
+	}
+	return nil
+}
+
+// DecodeTransactionContext decodes the "tctx" claim into v
+func (c *Claims) DecodeTransactionContext(v any) error {
+	return c.Decode("tctx", v)
+}
+
+// DecodeRequestContext decodes the "req_ctx" claim into v
+func (c *Claims) DecodeRequestContext(v any) error {
+	return c.Decode("req_ctx", v)
+}
+
+// HasScope reports whether the token's scope includes s
+func (c *Claims) HasScope(s string) bool {
+	for scope := range strings.FieldsSeq(c.Scope) {
+		if scope == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseClaims decodes a JWT's claims without verifying it
+// Use a Verifier for tokens received from others.
+func ParseClaims(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("token is not a compact JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode token payload: %w", err)
+	}
+	return claimsFromJSON(payload)
+}
+
+// claimsFromJSON decodes a JWT payload
+func claimsFromJSON(payload []byte) (*Claims, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode token claims: %w", err)
+	}
+
+	c := &Claims{Raw: raw}
+	c.Issuer, _ = raw["iss"].(string)
+	c.Subject, _ = raw["sub"].(string)
+	c.ID, _ = raw["jti"].(string)
+	c.TransactionID, _ = raw["txn"].(string)
+	c.Purpose, _ = raw["purp"].(string)
+	c.Scope, _ = raw["scope"].(string)
+	c.ClientID, _ = raw["client_id"].(string)
+	c.TransactionContext, _ = raw["tctx"].(map[string]any)
+	c.RequestContext, _ = raw["req_ctx"].(map[string]any)
+	c.Actor, _ = raw["act"].(map[string]any)
+	c.IssuedAt = numericDate(raw["iat"])
+	c.NotBefore = numericDate(raw["nbf"])
+	c.ExpiresAt = numericDate(raw["exp"])
+
+	switch aud := raw["aud"].(type) {
+	case string:
+		c.Audience = []string{aud}
+	case []any:
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				c.Audience = append(c.Audience, s)
+			}
+		}
+	}
+	return c, nil
+}
+
+// numericDate converts a JSON NumericDate, or returns the zero time
+func numericDate(value any) time.Time {
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(seconds), 0)
+}
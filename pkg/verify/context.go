@@ -0,0 +1,17 @@
+package verify
+
+import "context"
+
+type claimsKey struct{}
+
+// NewContext returns a context carrying a verified token's claims
+func NewContext(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// FromContext returns the claims put in the context by the middleware or
+// interceptors
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,143 @@
+package verify
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultHeader is the header parsec's ext_authz server sets the transaction
+// token in
+const DefaultHeader = "Transaction-Token"
+
+// Middleware returns HTTP middleware that verifies the transaction token in
+// header (default: DefaultHeader) and puts its claims in the request context.
+// Requests without a valid token are answered 401 Unauthorized.
+func (v *Verifier) Middleware(header string) func(http.Handler) http.Handler {
+	if header == "" {
+		header = DefaultHeader
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := v.Verify(r.Context(), r.Header.Get(header))
+			if err != nil {
+				http.Error(w, "invalid transaction token", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), claims)))
+		})
+	}
+}
+
+// InterceptorOption configures the gRPC interceptors
+type InterceptorOption func(*interceptorConfig)
+
+type interceptorConfig struct {
+	audiences   []string
+	skipMethods []string
+}
+
+// WithAudiences requires a token's audience to include one of this service's
+// names, in addition to the trust domain. Calls with a token for another
+// audience fail with PermissionDenied.
+func WithAudiences(audiences ...string) InterceptorOption {
+	return func(c *interceptorConfig) {
+		c.audiences = append(c.audiences, audiences...)
+	}
+}
+
+// WithSkipMethods serves the given full method names, e.g.
+// "/grpc.health.v1.Health/Check", without a token
+func WithSkipMethods(methods ...string) InterceptorOption {
+	return func(c *interceptorConfig) {
+		c.skipMethods = append(c.skipMethods, methods...)
+	}
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor that verifies the
+// transaction token in the metadata key header (default: DefaultHeader) and
+// puts its claims in the context. Calls without a valid token fail with
+// Unauthenticated.
+func (v *Verifier) UnaryServerInterceptor(header string, opts ...InterceptorOption) grpc.UnaryServerInterceptor {
+	key, cfg := metadataKey(header), newInterceptorConfig(opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if slices.Contains(cfg.skipMethods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, err := v.verifyIncoming(ctx, key, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls
+func (v *Verifier) StreamServerInterceptor(header string, opts ...InterceptorOption) grpc.StreamServerInterceptor {
+	key, cfg := metadataKey(header), newInterceptorConfig(opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if slices.Contains(cfg.skipMethods, info.FullMethod) {
+			return handler(srv, ss)
+		}
+		ctx, err := v.verifyIncoming(ss.Context(), key, cfg)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func newInterceptorConfig(opts []InterceptorOption) *interceptorConfig {
+	cfg := &interceptorConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// verifyIncoming verifies the token in the incoming metadata
+func (v *Verifier) verifyIncoming(ctx context.Context, key string, cfg *interceptorConfig) (context.Context, error) {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(key); len(values) > 0 {
+			token = values[0]
+		}
+	}
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing transaction token")
+	}
+	claims, err := v.Verify(ctx, token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid transaction token")
+	}
+	if len(cfg.audiences) > 0 && !slices.ContainsFunc(claims.Audience, func(aud string) bool {
+		return slices.Contains(cfg.audiences, aud)
+	}) {
+		return nil, status.Error(codes.PermissionDenied, "transaction token is not for this service")
+	}
+	return NewContext(ctx, claims), nil
+}
+
+// metadataKey returns the gRPC metadata key for a header name
+func metadataKey(header string) string {
+	if header == "" {
+		header = DefaultHeader
+	}
+	return strings.ToLower(header)
+}
+
+// contextStream is a ServerStream with a replaced context
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
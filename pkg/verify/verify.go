@@ -0,0 +1,302 @@
+// Package verify verifies parsec-issued transaction tokens in downstream
+// services.
+//
+// A Verifier checks a token's signature against the keys parsec publishes,
+// refreshing them as parsec rotates keys, and checks that the token is a
+// transaction token for the service's trust domain:
+//
+//	v, err := verify.New(verify.Config{
+//		JWKSURL:     "https://parsec.example.com/v1/jwks.json",
+//		TrustDomain: "parsec.example.com",
+//	})
+//	claims, err := v.Verify(ctx, r.Header.Get("Transaction-Token"))
+//
+// Middleware and interceptors verify the token of every request and put its
+// claims in the request context (see FromContext).
+package verify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+)
+
+// TypeTransactionToken is the JWS "typ" of transaction tokens
+const TypeTransactionToken = "txntoken+jwt"
+
+// minUnknownKeyRefresh limits fetches triggered by tokens signed with keys
+// the verifier has not seen, so a flood of forged tokens cannot make it
+// hammer parsec
+const minUnknownKeyRefresh = time.Minute
+
+// refreshFailureBackoff is how long a failed fetch is not retried, so an
+// unreachable parsec is not fetched from on every request
+const refreshFailureBackoff = 30 * time.Second
+
+// defaultFetchTimeout bounds a fetch of parsec's keys with the default HTTP
+// client, and every fetch once the request that started it is done
+const defaultFetchTimeout = 10 * time.Second
+
+// Config configures a Verifier
+type Config struct {
+	// JWKSURL is parsec's JWKS endpoint, e.g. https://parsec.example.com/v1/jwks.json
+	// Required unless Fetch is set.
+	JWKSURL string
+
+	// Fetch fetches parsec's keys instead of JWKSURL, e.g. over gRPC (optional)
+	Fetch func(ctx context.Context) (jwk.Set, error)
+
+	// HTTPClient fetches JWKSURL (default: a client with a 10s timeout)
+	HTTPClient *http.Client
+
+	// TrustDomain is the audience tokens must carry (required)
+	TrustDomain string
+
+	// Issuer is the "iss" tokens must carry (optional)
+	Issuer string
+
+	// Types are the accepted JWS "typ" values (default: TypeTransactionToken)
+	Types []string
+
+	// Leeway tolerates clock skew when checking exp, nbf and iat
+	Leeway time.Duration
+
+	// RefreshInterval is how long fetched keys are used before they are
+	// fetched again (default: 5m). A token signed by an unknown key triggers a
+	// fetch sooner, at most once a minute. A failed fetch is retried after 30s;
+	// until then the keys already fetched keep being used.
+	RefreshInterval time.Duration
+
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// Verifier verifies transaction tokens
+// It is safe for concurrent use.
+type Verifier struct {
+	trustDomain string
+	issuer      string
+	types       []string
+	leeway      time.Duration
+	now         func() time.Time
+	keys        *keyCache
+}
+
+// New creates a verifier
+func New(cfg Config) (*Verifier, error) {
+	if cfg.TrustDomain == "" {
+		return nil, fmt.Errorf("trust domain is required")
+	}
+	fetch := cfg.Fetch
+	if fetch == nil {
+		if cfg.JWKSURL == "" {
+			return nil, fmt.Errorf("JWKS URL is required")
+		}
+		httpClient := cfg.HTTPClient
+		if httpClient == nil {
+			httpClient = &http.Client{Timeout: defaultFetchTimeout}
+		}
+		fetch = fetchJWKS(httpClient, cfg.JWKSURL)
+	}
+	types := cfg.Types
+	if len(types) == 0 {
+		types = []string{TypeTransactionToken}
+	}
+	refresh := cfg.RefreshInterval
+	if refresh <= 0 {
+		refresh = 5 * time.Minute
+	}
+	now := cfg.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	return &Verifier{
+		trustDomain: cfg.TrustDomain,
+		issuer:      cfg.Issuer,
+		types:       types,
+		leeway:      cfg.Leeway,
+		now:         now,
+		keys:        &keyCache{fetch: fetch, refreshInterval: refresh, now: now},
+	}, nil
+}
+
+// Verify checks a token and returns its claims
+// The token must be signed by one of parsec's published keys, carry an
+// accepted typ, include the trust domain in its audience, and be within its
+// validity period.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	if token == "" {
+		return nil, fmt.Errorf("token is empty")
+	}
+	msg, err := jws.Parse([]byte(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token: %w", err)
+	}
+	if len(msg.Signatures()) != 1 {
+		return nil, fmt.Errorf("expected one signature, got %d", len(msg.Signatures()))
+	}
+	headers := msg.Signatures()[0].ProtectedHeaders()
+	typ, _ := headers.Type()
+	if !slices.ContainsFunc(v.types, func(t string) bool { return strings.EqualFold(t, typ) }) {
+		return nil, fmt.Errorf("token type %q is not accepted", typ)
+	}
+	kid, _ := headers.KeyID()
+
+	keys, err := v.keys.get(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []jwt.ParseOption{
+		jwt.WithKeySet(keys, jws.WithInferAlgorithmFromKey(true)),
+		jwt.WithValidate(true),
+		jwt.WithAcceptableSkew(v.leeway),
+		jwt.WithClock(jwt.ClockFunc(v.now)),
+		jwt.WithAudience(v.trustDomain),
+	}
+	if v.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.issuer))
+	}
+	if _, err := jwt.Parse([]byte(token), opts...); err != nil {
+		return nil, fmt.Errorf("token is invalid: %w", err)
+	}
+	return claimsFromJSON(msg.Payload())
+}
+
+// PublicKeys returns parsec's published keys, fetching them if the cached
+// keys are stale
+func (v *Verifier) PublicKeys(ctx context.Context) (jwk.Set, error) {
+	return v.keys.get(ctx, "")
+}
+
+// fetchJWKS returns a function fetching a JWKS over HTTP
+func fetchJWKS(httpClient *http.Client, url string) func(ctx context.Context) (jwk.Set, error) {
+	return func(ctx context.Context) (jwk.Set, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch JWKS: %s returned %s", url, resp.Status)
+		}
+		set, err := jwk.Parse(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+		}
+		return set, nil
+	}
+}
+
+// keyCache holds parsec's published keys between fetches
+// Concurrent callers needing fresh keys share one fetch, made without holding
+// the lock, so cached keys are served throughout.
+type keyCache struct {
+	fetch           func(ctx context.Context) (jwk.Set, error)
+	refreshInterval time.Duration
+	now             func() time.Time
+
+	mu        sync.Mutex
+	keys      jwk.Set
+	fetchedAt time.Time
+	failedAt  time.Time
+	lastErr   error
+	refresh   *keyRefresh // in flight, if any
+}
+
+// keyRefresh is a fetch of parsec's keys; done is closed once it completes
+type keyRefresh struct {
+	done chan struct{}
+}
+
+// get returns the cached keys, fetching them when they are stale or do not
+// include kid
+func (k *keyCache) get(ctx context.Context, kid string) (jwk.Set, error) {
+	k.mu.Lock()
+	now := k.now()
+	age := now.Sub(k.fetchedAt)
+	stale := k.keys == nil || age >= k.refreshInterval
+	if !stale && kid != "" && age >= minUnknownKeyRefresh {
+		_, known := k.keys.LookupKeyID(kid)
+		stale = !known
+	}
+	if stale && k.failedAt.After(k.fetchedAt) && now.Sub(k.failedAt) < refreshFailureBackoff {
+		// Keep verifying with the keys we have while parsec is unreachable
+		stale = false
+	}
+	if !stale {
+		keys, err := k.keys, k.lastErr
+		k.mu.Unlock()
+		if keys == nil {
+			return nil, err
+		}
+		return keys, nil
+	}
+	refresh := k.refresh
+	if refresh == nil {
+		refresh = &keyRefresh{done: make(chan struct{})}
+		k.refresh = refresh
+		// The fetch outlives a caller that gives up waiting, so it is bounded
+		// on its own
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), defaultFetchTimeout)
+		go func() {
+			defer cancel()
+			k.update(fetchCtx, refresh)
+		}()
+	}
+	k.mu.Unlock()
+
+	select {
+	case <-refresh.done:
+	case <-ctx.Done():
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.keys != nil {
+		// Stale keys are still used when the fetch failed
+		return k.keys, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, k.lastErr
+}
+
+// update fetches the keys for refresh and records the outcome
+func (k *keyCache) update(ctx context.Context, refresh *keyRefresh) {
+	keys, err := k.fetch(ctx)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err != nil {
+		k.failedAt = k.now()
+		k.lastErr = err
+	} else {
+		k.keys = keys
+		k.fetchedAt = k.now()
+		k.lastErr = nil
+	}
+	k.refresh = nil
+	close(refresh.done)
+}
@@ -0,0 +1,413 @@
+package verify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var testNow = time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+
+// testIssuer signs tokens and serves its keys as a JWKS
+type testIssuer struct {
+	t       *testing.T
+	keys    map[string]*ecdsa.PrivateKey
+	fetches atomic.Int32
+	down    atomic.Bool
+}
+
+func newTestIssuer(t *testing.T, kids ...string) *testIssuer {
+	i := &testIssuer{t: t, keys: map[string]*ecdsa.PrivateKey{}}
+	for _, kid := range kids {
+		i.addKey(kid)
+	}
+	return i
+}
+
+func (i *testIssuer) addKey(kid string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		i.t.Fatal(err)
+	}
+	i.keys[kid] = key
+}
+
+func (i *testIssuer) fetch(ctx context.Context) (jwk.Set, error) {
+	i.fetches.Add(1)
+	if i.down.Load() {
+		return nil, errors.New("parsec is down")
+	}
+	set := jwk.NewSet()
+	for kid, key := range i.keys {
+		pub, err := jwk.Import(key.Public())
+		if err != nil {
+			return nil, err
+		}
+		_ = pub.Set(jwk.KeyIDKey, kid)
+		_ = pub.Set(jwk.AlgorithmKey, jwa.ES256())
+		_ = set.AddKey(pub)
+	}
+	return set, nil
+}
+
+// sign signs a transaction token valid at testNow; edit adjusts the claims
+// and headers
+func (i *testIssuer) sign(kid string, edit func(b *jwt.Builder, h jws.Headers)) string {
+	i.t.Helper()
+	b := jwt.NewBuilder().
+		Issuer("https://parsec.test").
+		Subject("alice").
+		Audience([]string{"parsec.test"}).
+		IssuedAt(testNow).
+		Expiration(testNow.Add(5*time.Minute)).
+		Claim("txn", "txn-1").
+		Claim("tctx", map[string]any{"roles": []string{"admin"}}).
+		Claim("req_ctx", map[string]any{"method": "GET", "path": "/orders"})
+	headers := jws.NewHeaders()
+	_ = headers.Set(jws.KeyIDKey, kid)
+	_ = headers.Set(jws.TypeKey, TypeTransactionToken)
+	if edit != nil {
+		edit(b, headers)
+	}
+	token, err := b.Build()
+	if err != nil {
+		i.t.Fatal(err)
+	}
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256(), i.keys[kid], jws.WithProtectedHeaders(headers)))
+	if err != nil {
+		i.t.Fatal(err)
+	}
+	return string(signed)
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	issuer := newTestIssuer(t, "key-1")
+	now := testNow
+	v, err := New(Config{
+		Fetch:       issuer.fetch,
+		TrustDomain: "parsec.test",
+		Issuer:      "https://parsec.test",
+		Leeway:      30 * time.Second,
+		Now:         func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	ctx := context.Background()
+
+	t.Run("valid token with typed contexts", func(t *testing.T) {
+		claims, err := v.Verify(ctx, issuer.sign("key-1", nil))
+		if err != nil {
+			t.Fatalf("expected the token to verify: %v", err)
+		}
+		if claims.Subject != "alice" || claims.TransactionID != "txn-1" {
+			t.Errorf("unexpected claims: %+v", claims)
+		}
+		var tctx struct {
+			Roles []string `json:"roles"`
+		}
+		if err := claims.DecodeTransactionContext(&tctx); err != nil || len(tctx.Roles) != 1 {
+			t.Errorf("expected typed tctx, got %+v (%v)", tctx, err)
+		}
+		var reqCtx struct {
+			Method string `json:"method"`
+			Path   string `json:"path"`
+		}
+		if err := claims.DecodeRequestContext(&reqCtx); err != nil || reqCtx.Path != "/orders" {
+			t.Errorf("expected typed req_ctx, got %+v (%v)", reqCtx, err)
+		}
+	})
+
+	rejected := map[string]func(b *jwt.Builder, h jws.Headers){
+		"wrong typ":           func(b *jwt.Builder, h jws.Headers) { _ = h.Set(jws.TypeKey, "JWT") },
+		"other trust domain":  func(b *jwt.Builder, h jws.Headers) { b.Audience([]string{"other.test"}) },
+		"other issuer":        func(b *jwt.Builder, h jws.Headers) { b.Issuer("https://evil.test") },
+		"expired past leeway": func(b *jwt.Builder, h jws.Headers) { b.Expiration(testNow.Add(-time.Minute)) },
+	}
+	for name, edit := range rejected {
+		t.Run(name, func(t *testing.T) {
+			if _, err := v.Verify(ctx, issuer.sign("key-1", edit)); err == nil {
+				t.Error("expected the token to be rejected")
+			}
+		})
+	}
+
+	t.Run("expired within leeway", func(t *testing.T) {
+		token := issuer.sign("key-1", func(b *jwt.Builder, h jws.Headers) { b.Expiration(testNow.Add(-10 * time.Second)) })
+		if _, err := v.Verify(ctx, token); err != nil {
+			t.Errorf("expected the leeway to tolerate skew: %v", err)
+		}
+	})
+
+	t.Run("empty token", func(t *testing.T) {
+		if _, err := v.Verify(ctx, ""); err == nil {
+			t.Error("expected an empty token to be rejected")
+		}
+	})
+}
+
+func TestVerifier_KeyRotation(t *testing.T) {
+	issuer := newTestIssuer(t, "key-1")
+	now := testNow
+	v, err := New(Config{
+		Fetch:           issuer.fetch,
+		TrustDomain:     "parsec.test",
+		RefreshInterval: 10 * time.Minute,
+		Now:             func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := v.Verify(ctx, issuer.sign("key-1", nil)); err != nil {
+		t.Fatalf("expected the token to verify: %v", err)
+	}
+
+	// parsec rotates to a new key; tokens signed with it are accepted once
+	// the unknown-key refetch is allowed
+	issuer.addKey("key-2")
+	token := issuer.sign("key-2", nil)
+	if _, err := v.Verify(ctx, token); err == nil {
+		t.Error("expected an unknown key not to be refetched right after a fetch")
+	}
+	now = now.Add(minUnknownKeyRefresh)
+	if _, err := v.Verify(ctx, token); err != nil {
+		t.Errorf("expected the rotated key to be fetched: %v", err)
+	}
+	if issuer.fetches.Load() != 2 {
+		t.Errorf("expected 2 fetches, got %d", issuer.fetches.Load())
+	}
+
+	// Stale keys keep verifying while parsec is unreachable
+	issuer.down.Store(true)
+	now = now.Add(time.Hour)
+	if _, err := v.Verify(ctx, issuer.sign("key-1", func(b *jwt.Builder, h jws.Headers) {
+		b.IssuedAt(now).Expiration(now.Add(time.Minute))
+	})); err != nil {
+		t.Errorf("expected cached keys to be used when a refresh fails: %v", err)
+	}
+}
+
+func TestVerifier_KeyRefresh(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("concurrent callers share one fetch", func(t *testing.T) {
+		issuer := newTestIssuer(t, "key-1")
+		release := make(chan struct{})
+		v, err := New(Config{
+			Fetch: func(ctx context.Context) (jwk.Set, error) {
+				<-release
+				return issuer.fetch(ctx)
+			},
+			TrustDomain: "parsec.test",
+			Now:         func() time.Time { return testNow },
+		})
+		if err != nil {
+			t.Fatalf("failed to create verifier: %v", err)
+		}
+
+		token := issuer.sign("key-1", nil)
+		var wg sync.WaitGroup
+		errs := make(chan error, 5)
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := v.Verify(ctx, token)
+				errs <- err
+			}()
+		}
+
+		// A caller giving up does not wait for the fetch, or hold up others
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := v.PublicKeys(canceled); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected a canceled caller to give up, got %v", err)
+		}
+
+		close(release)
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Errorf("expected the token to verify: %v", err)
+			}
+		}
+		if n := issuer.fetches.Load(); n != 1 {
+			t.Errorf("expected 1 fetch, got %d", n)
+		}
+	})
+
+	t.Run("failed fetches back off", func(t *testing.T) {
+		issuer := newTestIssuer(t, "key-1")
+		issuer.down.Store(true)
+		now := testNow
+		v, err := New(Config{Fetch: issuer.fetch, TrustDomain: "parsec.test", Now: func() time.Time { return now }})
+		if err != nil {
+			t.Fatalf("failed to create verifier: %v", err)
+		}
+
+		token := issuer.sign("key-1", nil)
+		for range 3 {
+			if _, err := v.Verify(ctx, token); err == nil {
+				t.Fatal("expected verification to fail without keys")
+			}
+		}
+		if n := issuer.fetches.Load(); n != 1 {
+			t.Errorf("expected failed fetches not to be retried right away, got %d fetches", n)
+		}
+
+		issuer.down.Store(false)
+		now = now.Add(refreshFailureBackoff)
+		if _, err := v.Verify(ctx, token); err != nil {
+			t.Errorf("expected the fetch to be retried after the backoff: %v", err)
+		}
+		if n := issuer.fetches.Load(); n != 2 {
+			t.Errorf("expected 2 fetches, got %d", n)
+		}
+	})
+}
+
+func TestVerifier_JWKSURL(t *testing.T) {
+	issuer := newTestIssuer(t, "key-1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		set, err := issuer.fetch(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	defer server.Close()
+
+	v, err := New(Config{JWKSURL: server.URL, TrustDomain: "parsec.test", Now: func() time.Time { return testNow }})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	if _, err := v.Verify(context.Background(), issuer.sign("key-1", nil)); err != nil {
+		t.Errorf("expected the token to verify with keys from the JWKS URL: %v", err)
+	}
+
+	if _, err := New(Config{JWKSURL: server.URL}); err == nil {
+		t.Error("expected the trust domain to be required")
+	}
+}
+
+func TestVerifier_Middleware(t *testing.T) {
+	issuer := newTestIssuer(t, "key-1")
+	v, err := New(Config{Fetch: issuer.fetch, TrustDomain: "parsec.test", Now: func() time.Time { return testNow }})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	token := issuer.sign("key-1", nil)
+
+	t.Run("http", func(t *testing.T) {
+		handler := v.Middleware("")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := FromContext(r.Context())
+			if !ok {
+				t.Error("expected claims in the request context")
+				return
+			}
+			_, _ = w.Write([]byte(claims.Subject))
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.Header.Set(DefaultHeader, token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Body.String() != "alice" {
+			t.Errorf("expected the handler to see the subject, got %d %q", rec.Code, rec.Body.String())
+		}
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401 without a token, got %d", rec.Code)
+		}
+	})
+
+	t.Run("grpc", func(t *testing.T) {
+		interceptor := v.UnaryServerInterceptor("")
+		handler := func(ctx context.Context, req any) (any, error) {
+			claims, ok := FromContext(ctx)
+			if !ok {
+				return nil, errors.New("no claims")
+			}
+			return claims.Subject, nil
+		}
+		info := &grpc.UnaryServerInfo{FullMethod: "/orders.v1.Orders/Get"}
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("transaction-token", token))
+		resp, err := interceptor(ctx, nil, info, handler)
+		if err != nil || resp != "alice" {
+			t.Errorf("expected the handler to see the subject, got %v (%v)", resp, err)
+		}
+
+		_, err = interceptor(context.Background(), nil, info, handler)
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("expected Unauthenticated without a token, got %v", err)
+		}
+	})
+
+	t.Run("grpc audiences and skipped methods", func(t *testing.T) {
+		handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("transaction-token", token))
+		info := &grpc.UnaryServerInfo{FullMethod: "/orders.v1.Orders/Get"}
+
+		if _, err := v.UnaryServerInterceptor("", WithAudiences("orders"))(ctx, nil, info, handler); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied for another audience, got %v", err)
+		}
+		if _, err := v.UnaryServerInterceptor("", WithAudiences("orders", "parsec.test"))(ctx, nil, info, handler); err != nil {
+			t.Errorf("expected a token for one of the audiences to be accepted, got %v", err)
+		}
+
+		health := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+		if _, err := v.UnaryServerInterceptor("", WithSkipMethods(health.FullMethod))(context.Background(), nil, health, handler); err != nil {
+			t.Errorf("expected a skipped method to be served without a token, got %v", err)
+		}
+	})
+}
+
+func TestParseClaims(t *testing.T) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"alice","aud":"parsec.test","exp":1718446200,"scope":"orders.read orders.write","req_ctx":{"path":"/orders"}}`))
+	claims, err := ParseClaims("e30." + payload + ".sig")
+	if err != nil {
+		t.Fatalf("failed to parse claims: %v", err)
+	}
+	if len(claims.Audience) != 1 || claims.Audience[0] != "parsec.test" {
+		t.Errorf("expected a string audience as a one-element list, got %v", claims.Audience)
+	}
+	if !claims.ExpiresAt.Equal(time.Unix(1718446200, 0)) {
+		t.Errorf("unexpected expiry %v", claims.ExpiresAt)
+	}
+	if !claims.HasScope("orders.write") || claims.HasScope("orders") {
+		t.Errorf("unexpected scope matching for %q", claims.Scope)
+	}
+	if claims.RequestContext["path"] != "/orders" {
+		t.Errorf("unexpected request context %v", claims.RequestContext)
+	}
+
+	if _, err := ParseClaims("not-a-jwt"); err == nil {
+		t.Error("expected an error for a non-JWT")
+	}
+}
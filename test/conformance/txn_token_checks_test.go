@@ -0,0 +1,190 @@
+package conformance
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jws"
+)
+
+// Requirement IDs reported by checkTransactionToken
+// Each names one rule a transaction token must follow to be accepted by
+// services in the trust domain (draft-ietf-oauth-transaction-tokens).
+const (
+	reqFormat    = "format"    // a compact JWS: header.payload.signature
+	reqTyp       = "typ"       // header typ is "txntoken+jwt"
+	reqAlg       = "alg"       // signed with an asymmetric algorithm, never "none" or HMAC
+	reqKid       = "kid"       // header kid names one of the issuer's published keys
+	reqSignature = "signature" // the signature verifies with that key
+	reqIat       = "iat"       // issued-at is present and not in the future
+	reqExp       = "exp"       // expiry is present, after iat and not yet passed
+	reqAud       = "aud"       // aud is present and is exactly the trust domain
+	reqSub       = "sub"       // sub identifies the principal the transaction acts for
+	reqTxn       = "txn"       // txn carries a transaction identifier
+	reqTctx      = "tctx"      // tctx, when present, is a JSON object
+	reqReqCtx    = "req_ctx"   // req_ctx, when present, is a JSON object
+	reqPurp      = "purp"      // purp, when present, is a string
+)
+
+// violation is a requirement a token failed
+type violation struct {
+	Requirement string
+	Detail      string
+}
+
+func (v violation) String() string {
+	return v.Requirement + ": " + v.Detail
+}
+
+// txnTokenCheck holds what a transaction token is checked against
+type txnTokenCheck struct {
+	// Keys are the issuer's published verification keys by key ID
+	Keys map[string]crypto.PublicKey
+
+	// TrustDomain is the audience every token must carry
+	TrustDomain string
+
+	// Now is the time the token is checked at
+	Now time.Time
+}
+
+// checkTransactionToken returns every requirement the token fails
+// Checks continue past the first failure so a report lists everything wrong
+// with a token at once.
+func checkTransactionToken(token string, check txnTokenCheck) []violation {
+	var violations []violation
+	fail := func(requirement, format string, args ...any) {
+		violations = append(violations, violation{Requirement: requirement, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		fail(reqFormat, "expected a compact JWS with 3 parts, got %d", len(parts))
+		return violations
+	}
+
+	var header map[string]any
+	if err := decodeSegment(parts[0], &header); err != nil {
+		fail(reqFormat, "header: %v", err)
+		return violations
+	}
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		fail(reqFormat, "payload: %v", err)
+		return violations
+	}
+
+	if typ, _ := header["typ"].(string); !strings.EqualFold(typ, "txntoken+jwt") {
+		fail(reqTyp, "expected typ txntoken+jwt, got %q", typ)
+	}
+
+	algName, _ := header["alg"].(string)
+	alg, known := jwa.LookupSignatureAlgorithm(algName)
+	verifiable := false
+	switch {
+	case algName == "" || algName == "none":
+		fail(reqAlg, "token is unsigned (alg %q)", algName)
+	case strings.HasPrefix(algName, "HS"):
+		fail(reqAlg, "symmetric algorithm %s cannot be verified by other services", algName)
+	case !known:
+		fail(reqAlg, "unknown algorithm %q", algName)
+	default:
+		verifiable = true
+	}
+
+	kid, _ := header["kid"].(string)
+	key, published := check.Keys[kid]
+	switch {
+	case kid == "":
+		fail(reqKid, "header has no kid")
+	case !published:
+		fail(reqKid, "kid %q is not a published key", kid)
+	case verifiable:
+		if _, err := jws.Verify([]byte(token), jws.WithKey(alg, key)); err != nil {
+			fail(reqSignature, "%v", err)
+		}
+	}
+
+	iat, hasIat := numericDate(claims["iat"])
+	if !hasIat {
+		fail(reqIat, "missing or not a NumericDate")
+	} else if iat.After(check.Now) {
+		fail(reqIat, "issued in the future (%v > %v)", iat, check.Now)
+	}
+
+	if exp, ok := numericDate(claims["exp"]); !ok {
+		fail(reqExp, "missing or not a NumericDate")
+	} else {
+		if hasIat && !exp.After(iat) {
+			fail(reqExp, "expires (%v) before it was issued (%v)", exp, iat)
+		}
+		if !exp.After(check.Now) {
+			fail(reqExp, "already expired at %v", exp)
+		}
+	}
+
+	// A transaction token is valid in exactly one trust domain; a string and
+	// a one-element array are equivalent
+	switch aud := claims["aud"].(type) {
+	case nil:
+		fail(reqAud, "missing")
+	case string:
+		if aud != check.TrustDomain {
+			fail(reqAud, "expected %q, got %q", check.TrustDomain, aud)
+		}
+	case []any:
+		if len(aud) != 1 || aud[0] != check.TrustDomain {
+			fail(reqAud, "expected exactly [%q], got %v", check.TrustDomain, aud)
+		}
+	default:
+		fail(reqAud, "expected a string or an array of strings, got %T", aud)
+	}
+
+	if sub, _ := claims["sub"].(string); sub == "" {
+		fail(reqSub, "missing or empty")
+	}
+	if txn, _ := claims["txn"].(string); txn == "" {
+		fail(reqTxn, "missing or empty")
+	}
+
+	for _, name := range []string{reqTctx, reqReqCtx} {
+		if value, ok := claims[name]; ok {
+			if _, isObject := value.(map[string]any); !isObject {
+				fail(name, "expected a JSON object, got %T", value)
+			}
+		}
+	}
+	if value, ok := claims["purp"]; ok {
+		if _, isString := value.(string); !isString {
+			fail(reqPurp, "expected a string, got %T", value)
+		}
+	}
+
+	return violations
+}
+
+// decodeSegment decodes a base64url JSON segment of a compact JWS
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("not base64url: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("not a JSON object: %w", err)
+	}
+	return nil
+}
+
+// numericDate reads a JSON NumericDate (seconds since the epoch)
+func numericDate(value any) (time.Time, bool) {
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
@@ -0,0 +1,216 @@
+package conformance
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jws"
+
+	"github.com/project-kessel/parsec/internal/config"
+	"github.com/project-kessel/parsec/internal/request"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// configEnv names a parsec configuration file to check instead of the default
+const configEnv = "PARSEC_CONFORMANCE_CONFIG"
+
+// txnTokenVector is a token in testdata/txn_token and the requirements it fails
+type txnTokenVector struct {
+	Description string         `json:"description"`
+	Header      map[string]any `json:"header"`
+	Claims      map[string]any `json:"claims"`
+
+	// SigningKey "other" signs with a key that is not published under the kid
+	SigningKey string `json:"signing_key"`
+
+	Violations []string `json:"violations"`
+}
+
+// TestTransactionTokenVectors checks the checks: every vector must fail
+// exactly the requirements it lists
+func TestTransactionTokenVectors(t *testing.T) {
+	publishedKey := newVectorKey(t)
+	otherKey := newVectorKey(t)
+	check := txnTokenCheck{
+		Keys:        map[string]crypto.PublicKey{"conformance-key": publishedKey.Public()},
+		TrustDomain: "parsec.example.com",
+		Now:         time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC),
+	}
+
+	paths, err := filepath.Glob("testdata/txn_token/*.json")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no vectors found: %v", err)
+	}
+	for _, path := range paths {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".json"), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read vector: %v", err)
+			}
+			var vector txnTokenVector
+			if err := json.Unmarshal(data, &vector); err != nil {
+				t.Fatalf("failed to parse vector: %v", err)
+			}
+
+			key := publishedKey
+			if vector.SigningKey == "other" {
+				key = otherKey
+			}
+			token := signVector(t, vector, key)
+
+			var got []string
+			for _, v := range checkTransactionToken(token, check) {
+				got = append(got, v.Requirement)
+			}
+			if !slices.Equal(got, vector.Violations) {
+				t.Errorf("%s\nexpected violations %v, got %v", vector.Description, vector.Violations, checkTransactionToken(token, check))
+			}
+		})
+	}
+}
+
+// TestTransactionTokenConformance issues a transaction token from every
+// configured transaction token issuer and checks it against the requirements
+func TestTransactionTokenConformance(t *testing.T) {
+	path := os.Getenv(configEnv)
+	if path == "" {
+		path = "testdata/parsec-conformance.yaml"
+	}
+	loader, err := config.NewLoader(path)
+	if err != nil {
+		t.Fatalf("failed to load %s: %v", path, err)
+	}
+	cfg, err := loader.Get()
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", path, err)
+	}
+
+	provider := config.NewProvider(cfg)
+	registry, err := provider.IssuerRegistry()
+	if err != nil {
+		t.Fatalf("failed to create issuers: %v", err)
+	}
+	if signers, err := provider.SignerRegistry(); err == nil {
+		t.Cleanup(func() { _ = signers.Stop(context.Background()) })
+	}
+	if !slices.Contains(registry.ListTokenTypes(), service.TokenTypeTransactionToken) {
+		t.Skipf("%s configures no transaction token issuer", path)
+	}
+	tokenService, err := provider.TokenService()
+	if err != nil {
+		t.Fatalf("failed to create token service: %v", err)
+	}
+	clk, err := provider.Clock()
+	if err != nil {
+		t.Fatalf("failed to create clock: %v", err)
+	}
+
+	ctx := context.Background()
+	tokens, err := tokenService.IssueTokens(ctx, &service.IssueRequest{
+		Subject: &trust.Result{
+			Subject:     "conformance-user",
+			Issuer:      "https://idp.example.com",
+			TrustDomain: "idp.example.com",
+		},
+		RequestAttributes: &request.RequestAttributes{
+			Method: "GET",
+			Path:   "/conformance",
+		},
+		TokenTypes: []service.TokenType{service.TokenTypeTransactionToken},
+		Scope:      "conformance",
+	})
+	if err != nil {
+		t.Fatalf("failed to issue a transaction token: %v", err)
+	}
+	token := tokens[service.TokenTypeTransactionToken]
+
+	if token.Type != string(service.TokenTypeTransactionToken) {
+		t.Errorf("expected token type %s, got %s", service.TokenTypeTransactionToken, token.Type)
+	}
+
+	publicKeys, err := registry.GetAllPublicKeys(ctx)
+	if err != nil {
+		t.Fatalf("failed to get published keys: %v", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(publicKeys))
+	for _, key := range publicKeys {
+		keys[key.KeyID] = key.Key
+	}
+
+	for _, v := range checkTransactionToken(token.Value, txnTokenCheck{
+		Keys:        keys,
+		TrustDomain: provider.TrustDomain(),
+		Now:         clk.Now(),
+	}) {
+		t.Errorf("%s", v)
+	}
+}
+
+func newVectorKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key
+}
+
+// signVector encodes a vector as a compact JWS
+// Unsigned and HMAC vectors are encoded as they would arrive from a
+// non-conforming issuer, since the published keys cannot sign them.
+func signVector(t *testing.T, vector txnTokenVector, key *ecdsa.PrivateKey) string {
+	t.Helper()
+
+	payload, err := json.Marshal(vector.Claims)
+	if err != nil {
+		t.Fatalf("failed to encode claims: %v", err)
+	}
+
+	switch alg, _ := vector.Header["alg"].(string); alg {
+	case "none":
+		header, err := json.Marshal(vector.Header)
+		if err != nil {
+			t.Fatalf("failed to encode header: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+	case "HS256":
+		return signCompact(t, vector.Header, payload, jwa.HS256(), []byte("shared-secret-shared-secret-1234"))
+	case "ES256":
+		return signCompact(t, vector.Header, payload, jwa.ES256(), key)
+	default:
+		t.Fatalf("vectors cannot use alg %q", alg)
+		return ""
+	}
+}
+
+func signCompact(t *testing.T, header map[string]any, payload []byte, alg jwa.SignatureAlgorithm, key any) string {
+	t.Helper()
+
+	headers := jws.NewHeaders()
+	for name, value := range header {
+		if name == "alg" {
+			continue
+		}
+		if err := headers.Set(name, value); err != nil {
+			t.Fatalf("failed to set header %s: %v", name, err)
+		}
+	}
+	signed, err := jws.Sign(payload, jws.WithKey(alg, key, jws.WithProtectedHeaders(headers)))
+	if err != nil {
+		t.Fatalf("failed to sign vector: %v", err)
+	}
+	return string(signed)
+}
@@ -12,6 +12,7 @@ import (
 
 	parsecv1 "github.com/project-kessel/parsec/api/gen/parsec/v1"
 	"github.com/project-kessel/parsec/internal/clock"
+	"github.com/project-kessel/parsec/internal/config"
 	"github.com/project-kessel/parsec/internal/datasource"
 	"github.com/project-kessel/parsec/internal/httpfixture"
 	"github.com/project-kessel/parsec/internal/issuer"
@@ -32,9 +33,7 @@ import (
 // - Uses fixtures for all I/O (JWKS, HTTP APIs, time)
 //
 // Note: This test manually constructs fixtures via the Go API. For config-driven
-// hermetic testing using top-level fixtures, see configs/examples/parsec-hermetic.yaml
-// which demonstrates HTTP rule fixtures that can be loaded from configuration.
-// JWKS and clock fixtures will be added to the config schema in the future.
+// hermetic testing using top-level fixtures, see TestHermeticTokenExchangeFromConfig.
 func TestHermeticTokenExchange(t *testing.T) {
 	// ============================================================
 	// 1. Setup Fixtures (All I/O Control)
@@ -375,3 +374,120 @@ end`,
 	})
 
 }
+
+// TestHermeticTokenExchangeFromConfig runs the same black-box contract against
+// configs/examples/parsec-hermetic.yaml, where JWKS, HTTP and clock fixtures are
+// all declared in configuration rather than constructed in Go.
+func TestHermeticTokenExchangeFromConfig(t *testing.T) {
+	// ============================================================
+	// 1. Load Configuration (Fixtures Included)
+	// ============================================================
+	loader, err := config.NewLoader("../../configs/examples/parsec-hermetic.yaml")
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg, err := loader.Get()
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+
+	provider := config.NewProvider(cfg)
+	if err := config.Validate(cfg, provider.HTTPTransport()); err != nil {
+		t.Fatalf("config is invalid: %v", err)
+	}
+
+	trustStore, err := provider.TrustStore()
+	if err != nil {
+		t.Fatalf("failed to create trust store: %v", err)
+	}
+	tokenService, err := provider.TokenService()
+	if err != nil {
+		t.Fatalf("failed to create token service: %v", err)
+	}
+	claimsFilterRegistry, err := provider.ExchangeServerClaimsFilterRegistry()
+	if err != nil {
+		t.Fatalf("failed to create claims filter registry: %v", err)
+	}
+	exchangeServer := server.NewExchangeServer(trustStore, tokenService, claimsFilterRegistry, nil)
+
+	// ============================================================
+	// 2. Reach the Configured Fixtures
+	// ============================================================
+	fixtures, ok := provider.HTTPFixtureProvider().(*httpfixture.CompositeFixtureProvider)
+	if !ok {
+		t.Fatalf("expected composite fixture provider, got %T", provider.HTTPFixtureProvider())
+	}
+	actorJWKS := fixtures.JWKSFixture("https://auth.internal.example.com")
+	subjectJWKS := fixtures.JWKSFixture("https://idp.customer.example.com")
+	if actorJWKS == nil || subjectJWKS == nil {
+		t.Fatal("expected JWKS fixtures for both configured issuers")
+	}
+
+	clk, err := provider.Clock()
+	if err != nil {
+		t.Fatalf("failed to get clock: %v", err)
+	}
+	fixtureClock, ok := clk.(*clock.FixtureClock)
+	if !ok {
+		t.Fatalf("expected fixture clock, got %T", clk)
+	}
+	if want := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC); !fixtureClock.Now().Equal(want) {
+		t.Errorf("expected clock to start at %v, got %v", want, fixtureClock.Now())
+	}
+
+	// ============================================================
+	// 3. TEST: Token Exchange API Contract
+	// ============================================================
+	actorToken, err := actorJWKS.CreateAndSignToken(map[string]interface{}{"sub": "api-gateway"})
+	if err != nil {
+		t.Fatalf("failed to create actor token: %v", err)
+	}
+	subjectToken, err := subjectJWKS.CreateAndSignToken(map[string]interface{}{"sub": "alice"})
+	if err != nil {
+		t.Fatalf("failed to create subject token: %v", err)
+	}
+
+	exchange := func() (*parsecv1.ExchangeResponse, error) {
+		ctx := metadata.NewIncomingContext(
+			context.Background(),
+			metadata.Pairs("authorization", "Bearer "+actorToken),
+		)
+		return exchangeServer.Exchange(ctx, &parsecv1.ExchangeRequest{
+			GrantType:          "urn:ietf:params:oauth:grant-type:token-exchange",
+			Audience:           "parsec.example.com",
+			RequestedTokenType: string(service.TokenTypeTransactionToken),
+			SubjectToken:       subjectToken,
+			SubjectTokenType:   "urn:ietf:params:oauth:token-type:jwt",
+		})
+	}
+
+	t.Run("successful token exchange", func(t *testing.T) {
+		resp, err := exchange()
+		if err != nil {
+			t.Fatalf("Exchange RPC failed: %v", err)
+		}
+
+		tokenJSON, err := base64.StdEncoding.DecodeString(resp.AccessToken)
+		if err != nil {
+			t.Fatalf("failed to decode token: %v", err)
+		}
+		var claims map[string]interface{}
+		if err := json.Unmarshal(tokenJSON, &claims); err != nil {
+			t.Fatalf("failed to parse token JSON: %v", err)
+		}
+
+		userProfile, ok := claims["user_profile"].(map[string]interface{})
+		if !ok || userProfile["department"] != "engineering" {
+			t.Errorf("expected user_profile from HTTP fixture, got %v", claims["user_profile"])
+		}
+	})
+
+	t.Run("expired credentials are rejected once the clock advances", func(t *testing.T) {
+		fixtureClock.Advance(24 * time.Hour)
+		defer fixtureClock.Rewind(24 * time.Hour)
+
+		if _, err := exchange(); err == nil {
+			t.Error("expected exchange with expired tokens to fail")
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/project-kessel/parsec/internal/issuer"
+	"github.com/project-kessel/parsec/internal/keys"
+	"github.com/project-kessel/parsec/internal/server"
+	"github.com/project-kessel/parsec/internal/service"
+	"github.com/project-kessel/parsec/internal/trust"
+)
+
+// TestServerSetHandlers tests that handlers are replaced behind running listeners
+func TestServerSetHandlers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	trustStore := trust.NewStubStore()
+	newConfig := func(issuerRegistry service.Registry) server.Config {
+		tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+		return server.Config{
+			GRPCPort:       19095,
+			HTTPPort:       18085,
+			AuthzServer:    server.NewAuthzServer(trustStore, tokenService, nil, nil),
+			ExchangeServer: server.NewExchangeServer(trustStore, tokenService, server.NewStubClaimsFilterRegistry(), nil),
+			JWKSServer:     server.NewJWKSServer(server.JWKSServerConfig{IssuerRegistry: issuerRegistry, Logger: slog.Default()}),
+		}
+	}
+
+	// Start without any signing issuer
+	srv := server.New(newConfig(service.NewSimpleRegistry()))
+	if err := srv.Start(ctx); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer func() { _ = srv.Stop(ctx) }()
+	waitForServer(t, 18085, 5*time.Second)
+
+	if n := countJWKSKeys(t, "http://localhost:18085/v1/jwks.json"); n != 0 {
+		t.Fatalf("Expected no keys before reload, got %d", n)
+	}
+
+	// Swap in handlers with a signing issuer
+	signer := keys.NewDualSlotRotatingSigner(keys.DualSlotRotatingSignerConfig{
+		Namespace:           string(service.TokenTypeTransactionToken),
+		KeyProviderID:       "test-provider",
+		KeyProviderRegistry: map[string]keys.KeyProvider{"test-provider": keys.NewInMemoryKeyProvider(keys.KeyTypeECP256, "ES256")},
+		SlotStore:           keys.NewInMemoryKeySlotStore(),
+	})
+	if err := signer.Start(ctx); err != nil {
+		t.Fatalf("Failed to start signer: %v", err)
+	}
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+		Signer:    signer,
+	}))
+
+	if err := srv.SetHandlers(newConfig(issuerRegistry)); err != nil {
+		t.Fatalf("Failed to set handlers: %v", err)
+	}
+	if n := countJWKSKeys(t, "http://localhost:18085/v1/jwks.json"); n == 0 {
+		t.Error("Expected keys from the new handlers after reload")
+	}
+
+	// Ports belong to the running listeners
+	moved := newConfig(issuerRegistry)
+	moved.HTTPPort = 18086
+	if err := srv.SetHandlers(moved); err == nil {
+		t.Error("Expected changing ports to be rejected")
+	}
+}
+
+// TestServerCachePeers tests that cache peers serve and run with the server
+func TestServerCachePeers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	trustStore := trust.NewStubStore()
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), service.NewSimpleRegistry(), nil)
+	newConfig := func(peers *fakeCachePeers) server.Config {
+		return server.Config{
+			GRPCPort:             19096,
+			HTTPPort:             18086,
+			DistributedCachePort: 18087,
+			AuthzServer:          server.NewAuthzServer(trustStore, tokenService, nil, nil),
+			ExchangeServer:       server.NewExchangeServer(trustStore, tokenService, server.NewStubClaimsFilterRegistry(), nil),
+			JWKSServer:           server.NewJWKSServer(server.JWKSServerConfig{IssuerRegistry: service.NewSimpleRegistry(), Logger: slog.Default()}),
+			DistributedCache:     peers,
+			DistributedCachePath: "/_groupcache/",
+		}
+	}
+
+	first := &fakeCachePeers{}
+	srv := server.New(newConfig(first))
+	if err := srv.Start(ctx); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	waitForServer(t, 18086, 5*time.Second)
+
+	if !first.running.Load() {
+		t.Error("Expected cache peers to start with the server")
+	}
+	waitForServer(t, 18087, 5*time.Second)
+	resp, err := http.Get("http://localhost:18087/_groupcache/group/key")
+	if err != nil {
+		t.Fatalf("Failed to reach cache peers: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusTeapot {
+		t.Errorf("Expected peer request to reach the cache peers, got status %d", resp.StatusCode)
+	}
+
+	// The public HTTP port does not serve peer requests
+	resp, err = http.Get("http://localhost:18086/_groupcache/group/key")
+	if err != nil {
+		t.Fatalf("Failed to reach HTTP server: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusTeapot {
+		t.Error("Expected peer requests not to be served on the HTTP port")
+	}
+
+	// Replacement peers start before the previous ones stop
+	second := &fakeCachePeers{}
+	if err := srv.SetHandlers(newConfig(second)); err != nil {
+		t.Fatalf("Failed to set handlers: %v", err)
+	}
+	if first.running.Load() || !second.running.Load() {
+		t.Errorf("Expected only the new cache peers to run, got first=%v second=%v", first.running.Load(), second.running.Load())
+	}
+
+	if err := srv.Stop(ctx); err != nil {
+		t.Fatalf("Failed to stop server: %v", err)
+	}
+	if second.running.Load() {
+		t.Error("Expected cache peers to stop with the server")
+	}
+}
+
+// fakeCachePeers answers peer requests with 418 and records whether it runs
+type fakeCachePeers struct {
+	running atomic.Bool
+}
+
+func (p *fakeCachePeers) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusTeapot)
+}
+
+func (p *fakeCachePeers) Start(ctx context.Context) error {
+	p.running.Store(true)
+	return nil
+}
+
+func (p *fakeCachePeers) Stop() {
+	p.running.Store(false)
+}
+
+func countJWKSKeys(t *testing.T, url string) int {
+	t.Helper()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var jwks struct {
+		Keys []map[string]any `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		t.Fatalf("Failed to parse JWKS JSON: %v", err)
+	}
+	return len(jwks.Keys)
+}